@@ -87,12 +87,17 @@ func (f *debugFlags) runDebugConfigCommand(cmd *cobra.Command, args []string) (c
 		return err
 	}
 
-	cfg, err := config.Load(cmd.Context(), agentSource)
+	cfg, err := config.Load(cmd.Context(), agentSource, f.runConfig.LoadOpts()...)
 	if err != nil {
 		return err
 	}
 
-	return yaml.NewEncoder(cmd.OutOrStdout()).Encode(cfg)
+	redacted, err := config.Redacted(cfg)
+	if err != nil {
+		return err
+	}
+
+	return yaml.NewEncoder(cmd.OutOrStdout()).Encode(redacted)
 }
 
 func (f *debugFlags) runDebugToolsetsCommand(cmd *cobra.Command, args []string) (commandErr error) {
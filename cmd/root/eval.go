@@ -10,11 +10,11 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
-	"golang.org/x/term"
 
 	"github.com/docker/docker-agent/pkg/config"
 	"github.com/docker/docker-agent/pkg/evaluation"
 	"github.com/docker/docker-agent/pkg/telemetry"
+	"github.com/docker/docker-agent/pkg/termcap"
 )
 
 const defaultJudgeModel = "anthropic/claude-opus-4-5-20251101"
@@ -24,14 +24,25 @@ type evalFlags struct {
 
 	runConfig config.RuntimeConfig
 	outputDir string
+
+	// Suite mode
+	junitPath       string
+	caseTimeout     time.Duration
+	temperatureZero bool
 }
 
 func newEvalCmd() *cobra.Command {
 	var flags evalFlags
 
 	cmd := &cobra.Command{
-		Use:     "eval <agent-file>|<registry-ref> [<eval-dir>|./evals]",
-		Short:   "Run evaluations for an agent",
+		Use:   "eval <agent-file>|<registry-ref>|<suite-file> [<eval-dir>|./evals]",
+		Short: "Run evaluations for an agent",
+		Long: `Run evaluations for an agent.
+
+With an agent and a directory of recorded sessions, each session is replayed
+in a container and scored. With a single suite file, a YAML file with a
+top-level "cases" key, each case runs in-process against the suite's agent
+and is checked against its assertions.`,
 		GroupID: "advanced",
 		Args:    cobra.RangeArgs(1, 2),
 		RunE:    flags.runEvalCommand,
@@ -46,6 +57,9 @@ func newEvalCmd() *cobra.Command {
 	cmd.Flags().BoolVar(&flags.KeepContainers, "keep-containers", false, "Keep containers after evaluation (don't use --rm)")
 	cmd.Flags().StringSliceVarP(&flags.EnvVars, "env", "e", nil, "Environment variables to pass to container (KEY or KEY=VALUE)")
 	cmd.Flags().IntVar(&flags.Repeat, "repeat", 1, "Number of times to repeat each evaluation (useful for computing baselines)")
+	cmd.Flags().StringVar(&flags.junitPath, "junit", "", "Write the results of a suite run as a JUnit XML report to this file")
+	cmd.Flags().DurationVar(&flags.caseTimeout, "timeout", 5*time.Minute, "Timeout of each case of a suite run, unless the suite sets one")
+	cmd.Flags().BoolVar(&flags.temperatureZero, "temperature-zero", false, "Force temperature 0 on every model in a suite run")
 
 	return cmd
 }
@@ -56,6 +70,10 @@ func (f *evalFlags) runEvalCommand(cmd *cobra.Command, args []string) (commandEr
 		telemetry.TrackCommandError(cmd.Context(), "eval", args, commandErr)
 	}()
 
+	if len(args) == 1 && evaluation.IsSuiteFile(args[0]) {
+		return f.runSuite(cmd, args[0])
+	}
+
 	ctx := cmd.Context()
 	agentFilename := args[0]
 	evalsDir := "./evals"
@@ -110,7 +128,7 @@ func (f *evalFlags) runEvalCommand(cmd *cobra.Command, args []string) (commandEr
 	isTTY := false
 	if file, ok := consoleOut.(*os.File); ok {
 		f.TTYFd = int(file.Fd())
-		isTTY = term.IsTerminal(f.TTYFd)
+		isTTY = termcap.SupportsANSI(file)
 	}
 
 	// Set remaining config fields
@@ -144,3 +162,41 @@ func (f *evalFlags) runEvalCommand(cmd *cobra.Command, args []string) (commandEr
 
 	return evalErr
 }
+
+func (f *evalFlags) runSuite(cmd *cobra.Command, suitePath string) error {
+	suite, err := evaluation.LoadSuite(suitePath)
+	if err != nil {
+		return err
+	}
+
+	cfg := evaluation.SuiteConfig{
+		Concurrency: f.Concurrency,
+		Timeout:     f.caseTimeout,
+	}
+	if f.temperatureZero {
+		cfg.Temperature = new(float64)
+	}
+
+	out := cmd.OutOrStdout()
+	run, err := evaluation.RunSuite(cmd.Context(), out, suite, &f.runConfig, cfg)
+	if err != nil {
+		return err
+	}
+
+	if f.junitPath != "" {
+		file, err := os.Create(f.junitPath)
+		if err != nil {
+			return fmt.Errorf("creating JUnit report: %w", err)
+		}
+		defer file.Close()
+		if err := evaluation.WriteJUnit(file, run); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "JUnit report: %s\n", f.junitPath)
+	}
+
+	if failed := run.Failed(); failed > 0 {
+		return fmt.Errorf("%d of %d cases failed", failed, len(run.Results))
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package root
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/docker/docker-agent/pkg/cli"
+	"github.com/docker/docker-agent/pkg/config"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/teamloader"
+	"github.com/docker/docker-agent/pkg/telemetry"
+)
+
+type inspectFlags struct {
+	json                 bool
+	dot                  bool
+	noTools              bool
+	maxInstructionLength int
+	runConfig            config.RuntimeConfig
+}
+
+func newInspectCmd() *cobra.Command {
+	var flags inspectFlags
+
+	cmd := &cobra.Command{
+		Use:   "inspect <agent-file>|<registry-ref>",
+		Short: "Describe the agents of a configuration",
+		Long: "Describe the agents of a configuration: their models, instructions, toolsets and tools, " +
+			"and how they delegate to each other. The toolsets are started to list their tools.",
+		Example: `  docker-agent inspect ./agent.yaml
+  docker-agent inspect ./agent.yaml --json
+  docker-agent inspect ./agent.yaml --dot | dot -Tsvg > team.svg`,
+		GroupID: "core",
+		Args:    cobra.ExactArgs(1),
+		RunE:    flags.runInspectCommand,
+	}
+
+	cmd.Flags().BoolVar(&flags.json, "json", false, "Print the description as JSON")
+	cmd.Flags().BoolVar(&flags.dot, "dot", false, "Print the delegation graph in the DOT language of Graphviz")
+	cmd.Flags().BoolVar(&flags.noTools, "no-tools", false, "Don't start the toolsets to list their tools")
+	cmd.Flags().IntVar(&flags.maxInstructionLength, "max-instruction-length", 200, "Truncate the instructions to this many characters, 0 to keep them whole")
+	cmd.MarkFlagsMutuallyExclusive("json", "dot")
+	addRuntimeConfigFlags(cmd, &flags.runConfig)
+
+	return cmd
+}
+
+func (f *inspectFlags) runInspectCommand(cmd *cobra.Command, args []string) (commandErr error) {
+	telemetry.TrackCommand(cmd.Context(), "inspect", args)
+	defer func() { // do not inline this defer so that commandErr is not resolved early
+		telemetry.TrackCommandError(cmd.Context(), "inspect", args, commandErr)
+	}()
+
+	ctx := cmd.Context()
+
+	agentSource, err := config.Resolve(args[0], f.runConfig.EnvProvider())
+	if err != nil {
+		return err
+	}
+
+	t, err := teamloader.Load(ctx, agentSource, &f.runConfig)
+	if err != nil {
+		return err
+	}
+	defer stopToolSets(t)
+
+	opts := []team.DescribeOpt{team.WithMaxInstructionLength(f.maxInstructionLength)}
+	if f.noTools || f.dot {
+		opts = append(opts, team.WithoutTools())
+	}
+	desc := t.Describe(ctx, opts...)
+
+	out := cli.NewPrinter(cmd.OutOrStdout())
+	switch {
+	case f.json:
+		buf, err := json.MarshalIndent(desc, "", "  ")
+		if err != nil {
+			return err
+		}
+		out.Println(string(buf))
+	case f.dot:
+		out.Printf("%s", desc.DOT())
+	default:
+		printDescription(out, desc)
+	}
+
+	return nil
+}
+
+// printDescription prints a human-readable summary of a team.
+func printDescription(out *cli.Printer, desc team.Description) {
+	for i, a := range desc.Agents {
+		if i > 0 {
+			out.Println()
+		}
+
+		name := a.Name
+		if a.Name == desc.DefaultAgent {
+			name += " (default)"
+		}
+		out.Println(name)
+		if a.Description != "" {
+			out.Printf("  description: %s\n", a.Description)
+		}
+		if a.Model != "" {
+			out.Printf("  model:       %s\n", a.Model)
+		}
+		if len(a.FallbackModels) > 0 {
+			out.Printf("  fallbacks:   %s\n", strings.Join(a.FallbackModels, ", "))
+		}
+		if len(a.SubAgents) > 0 {
+			out.Printf("  sub-agents:  %s\n", strings.Join(a.SubAgents, ", "))
+		}
+		if len(a.Handoffs) > 0 {
+			out.Printf("  handoffs:    %s\n", strings.Join(a.Handoffs, ", "))
+		}
+		if len(a.RAG) > 0 {
+			out.Printf("  rag:         %s\n", strings.Join(a.RAG, ", "))
+		}
+		if a.Instruction != "" {
+			out.Printf("  instruction: %s\n", strings.ReplaceAll(strings.TrimSpace(a.Instruction), "\n", "\n               "))
+		}
+
+		for _, ts := range a.ToolSets {
+			label := ts.Description
+			if ts.Type != "" {
+				label = ts.Type + ": " + label
+			}
+			if ts.Name != "" {
+				label += " (" + ts.Name + ")"
+			}
+			out.Printf("  toolset %s\n", label)
+			if ts.Error != "" {
+				out.Printf("    error: %s\n", ts.Error)
+			}
+			for _, tool := range ts.Tools {
+				line := "    - " + tool.Name
+				if tool.Category != "" {
+					line += " [" + tool.Category + "]"
+				}
+				if tool.ReadOnly {
+					line += " (read-only)"
+				}
+				out.Println(line)
+			}
+		}
+	}
+}
@@ -154,6 +154,8 @@ We collect anonymous usage data to help improve docker agent. To disable:
 	cmd.AddCommand(
 		newVersionCmd(),
 		newRunCmd(),
+		newValidateCmd(),
+		newInspectCmd(),
 		newNewCmd(),
 		newEvalCmd(),
 		newShareCmd(),
@@ -11,7 +11,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 	"go.opentelemetry.io/otel"
 
@@ -24,9 +23,10 @@ import (
 	"github.com/docker/docker-agent/pkg/profiling"
 	"github.com/docker/docker-agent/pkg/runtime"
 	"github.com/docker/docker-agent/pkg/session"
-	"github.com/docker/docker-agent/pkg/sessiontitle"
+	"github.com/docker/docker-agent/pkg/team"
 	"github.com/docker/docker-agent/pkg/teamloader"
 	"github.com/docker/docker-agent/pkg/telemetry"
+	"github.com/docker/docker-agent/pkg/termcap"
 	"github.com/docker/docker-agent/pkg/tui"
 	"github.com/docker/docker-agent/pkg/tui/styles"
 	"github.com/docker/docker-agent/pkg/userconfig"
@@ -43,6 +43,7 @@ type runExecFlags struct {
 	runConfig         config.RuntimeConfig
 	sessionDB         string
 	sessionID         string
+	condensedSession  bool
 	recordPath        string
 	fakeResponses     string
 	fakeStreamDelay   int
@@ -58,6 +59,10 @@ type runExecFlags struct {
 	exec          bool
 	hideToolCalls bool
 	outputJSON    bool
+	outputFormat  string
+	approveAll    bool
+	rejectAll     bool
+	approveTools  []string
 
 	// Run only
 	hideToolResults bool
@@ -106,6 +111,7 @@ func addRunOrExecFlags(cmd *cobra.Command, flags *runExecFlags) {
 	cmd.PersistentFlags().StringVar(&flags.remoteAddress, "remote", "", "Use remote runtime with specified address")
 	cmd.PersistentFlags().StringVarP(&flags.sessionDB, "session-db", "s", filepath.Join(paths.GetHomeDir(), ".cagent", "session.db"), "Path to the session database")
 	cmd.PersistentFlags().StringVar(&flags.sessionID, "session", "", "Continue from a previous session by ID or relative offset (e.g., -1 for last session)")
+	cmd.PersistentFlags().BoolVar(&flags.condensedSession, "condensed", false, "Only load the summaries and the recent messages of the --session")
 	cmd.PersistentFlags().StringVar(&flags.fakeResponses, "fake", "", "Replay AI responses from cassette file (for testing)")
 	cmd.PersistentFlags().IntVar(&flags.fakeStreamDelay, "fake-stream", 0, "Simulate streaming with delay in ms between chunks (default 15ms if no value given)")
 	cmd.Flag("fake-stream").NoOptDefVal = "15" // --fake-stream without value uses 15ms
@@ -129,6 +135,13 @@ func addRunOrExecFlags(cmd *cobra.Command, flags *runExecFlags) {
 	cmd.PersistentFlags().BoolVar(&flags.exec, "exec", false, "Execute without a TUI")
 	cmd.PersistentFlags().BoolVar(&flags.hideToolCalls, "hide-tool-calls", false, "Hide the tool calls in the output")
 	cmd.PersistentFlags().BoolVar(&flags.outputJSON, "json", false, "Output results in JSON format")
+	cmd.PersistentFlags().StringVar(&flags.outputFormat, "output", string(cli.OutputFormatText), "Output format: text or json (one JSON record per event, then a result record)")
+	cmd.PersistentFlags().BoolVar(&flags.approveAll, "approve-all", false, "Approve all tool calls without prompting")
+	cmd.PersistentFlags().BoolVar(&flags.rejectAll, "reject-all", false, "Reject all tool calls that need a confirmation")
+	cmd.PersistentFlags().StringSliceVar(&flags.approveTools, "approve", nil, "Approve the calls to these tools without prompting (comma-separated)")
+	cmd.MarkFlagsMutuallyExclusive("approve-all", "reject-all")
+	cmd.MarkFlagsMutuallyExclusive("yolo", "reject-all")
+	cmd.MarkFlagsMutuallyExclusive("json", "output")
 }
 
 func (f *runExecFlags) runRunCommand(cmd *cobra.Command, args []string) (commandErr error) {
@@ -146,13 +159,20 @@ func (f *runExecFlags) runRunCommand(cmd *cobra.Command, args []string) (command
 		}()
 	}
 
+	switch cli.OutputFormat(f.outputFormat) {
+	case cli.OutputFormatText, cli.OutputFormatJSON:
+	default:
+		return fmt.Errorf("invalid output format %q: must be text or json", f.outputFormat)
+	}
+
 	if f.sandbox {
 		return runInSandbox(ctx, cmd, args, &f.runConfig, f.sandboxTemplate, f.sbx)
 	}
 
 	out := cli.NewPrinter(cmd.OutOrStdout())
 
-	useTUI := !f.exec && (f.forceTUI || isatty.IsTerminal(os.Stdout.Fd()))
+	// Terminals that can't interpret ANSI escape sequences get the plain text CLI.
+	useTUI := !f.exec && (f.forceTUI || termcap.SupportsANSI(os.Stdout))
 	return f.runOrExec(ctx, out, args, useTUI)
 }
 
@@ -182,7 +202,7 @@ func (f *runExecFlags) runOrExec(ctx context.Context, out *cli.Printer, args []s
 		f.hideToolResults = true
 		slog.Debug("Applying user settings", "hide_tool_results", true)
 	}
-	if userSettings.YOLO && !f.autoApprove {
+	if userSettings.YOLO && !f.autoApprove && !f.rejectAll {
 		f.autoApprove = true
 		slog.Debug("Applying user settings", "YOLO", true)
 	}
@@ -191,7 +211,7 @@ func (f *runExecFlags) runOrExec(ctx context.Context, out *cli.Printer, args []s
 	// Alias options only apply if the flag wasn't explicitly set by the user
 	if alias := config.ResolveAlias(agentFileName); alias != nil {
 		slog.Debug("Applying alias options", "yolo", alias.Yolo, "model", alias.Model, "hide_tool_results", alias.HideToolResults)
-		if alias.Yolo && !f.autoApprove {
+		if alias.Yolo && !f.autoApprove && !f.rejectAll {
 			f.autoApprove = true
 		}
 		if alias.Model != "" && len(f.modelOverrides) == 0 {
@@ -247,24 +267,52 @@ func (f *runExecFlags) runOrExec(ctx context.Context, out *cli.Printer, args []s
 		return err
 	}
 
-	loadResult, err := f.loadAgentFrom(ctx, agentSource)
+	// Local agent files are reloaded when they change while the TUI is running.
+	// The toolset cache keeps unchanged MCP servers running across reloads.
+	watchConfig := useTUI && !f.dryRun && config.IsLocalFile(agentSource)
+	var loadOpts []teamloader.Opt
+	if watchConfig {
+		loadOpts = append(loadOpts, teamloader.WithToolsetCache(teamloader.NewToolsetCache()))
+	}
+
+	loadResult, err := f.loadAgentFrom(ctx, agentSource, loadOpts...)
 	if err != nil {
 		return err
 	}
 
-	rt, sess, err := f.createLocalRuntimeAndSession(ctx, loadResult)
+	var rtOpts []runtime.Opt
+	if watchConfig {
+		rtOpts = append(rtOpts, runtime.WithConfigReload(func() (*team.Team, error) {
+			loadResult, err := f.loadAgentFrom(ctx, agentSource, loadOpts...)
+			if err != nil {
+				return nil, err
+			}
+			f.mergeGlobalPermissions(loadResult.Team)
+			return loadResult.Team, nil
+		}))
+	}
+
+	rt, sess, err := f.createLocalRuntimeAndSession(ctx, loadResult, rtOpts...)
 	if err != nil {
 		return err
 	}
 	defer func() {
-		if err := rt.Close(); err != nil {
+		closeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+		if err := rt.Close(closeCtx); err != nil {
 			slog.Error("Failed to close runtime", "error", err)
 		}
 	}()
 	var initialTeamCleanupOnce sync.Once
 	initialTeamCleanup := func() {
 		initialTeamCleanupOnce.Do(func() {
-			stopToolSets(loadResult.Team)
+			// Stop the team currently in use, which differs from the
+			// initial one after a configuration reload.
+			t := loadResult.Team
+			if pr, ok := rt.(*runtime.PersistentRuntime); ok {
+				t = pr.Team()
+			}
+			stopToolSets(t)
 		})
 	}
 	defer initialTeamCleanup()
@@ -284,14 +332,23 @@ func (f *runExecFlags) runOrExec(ctx context.Context, out *cli.Printer, args []s
 		return err
 	}
 
+	if reloader, ok := rt.(runtime.ConfigReloader); ok && watchConfig {
+		watcher, err := config.NewWatcher(agentSource, func() { reloader.ReloadConfig(ctx) })
+		if err != nil {
+			slog.Warn("Not watching the agent file for changes", "error", err)
+		} else {
+			defer watcher.Close()
+		}
+	}
+
 	sessStore := rt.SessionStore()
 	return runTUI(ctx, rt, sess, f.createSessionSpawner(agentSource, sessStore), initialTeamCleanup, f.tuiOpts(), opts...)
 }
 
-func (f *runExecFlags) loadAgentFrom(ctx context.Context, agentSource config.Source) (*teamloader.LoadResult, error) {
-	opts := []teamloader.Opt{
+func (f *runExecFlags) loadAgentFrom(ctx context.Context, agentSource config.Source, extraOpts ...teamloader.Opt) (*teamloader.LoadResult, error) {
+	opts := append([]teamloader.Opt{
 		teamloader.WithModelOverrides(f.modelOverrides),
-	}
+	}, extraOpts...)
 	if len(f.promptFiles) > 0 {
 		opts = append(opts, teamloader.WithPromptFiles(f.promptFiles))
 	}
@@ -325,14 +382,9 @@ func (f *runExecFlags) createRemoteRuntimeAndSession(ctx context.Context, origin
 	return remoteRt, sess, nil
 }
 
-func (f *runExecFlags) createLocalRuntimeAndSession(ctx context.Context, loadResult *teamloader.LoadResult) (runtime.Runtime, *session.Session, error) {
+func (f *runExecFlags) createLocalRuntimeAndSession(ctx context.Context, loadResult *teamloader.LoadResult, extraOpts ...runtime.Opt) (runtime.Runtime, *session.Session, error) {
 	t := loadResult.Team
-
-	// Merge user-level global permissions into the team's checker so the
-	// runtime receives a single, already-merged permission set.
-	if f.globalPermissions != nil && !f.globalPermissions.IsEmpty() {
-		t.SetPermissions(permissions.Merge(t.Permissions(), f.globalPermissions))
-	}
+	f.mergeGlobalPermissions(t)
 
 	agt, err := t.Agent(f.agentName)
 	if err != nil {
@@ -359,12 +411,13 @@ func (f *runExecFlags) createLocalRuntimeAndSession(ctx context.Context, loadRes
 		AgentDefaultModels: loadResult.AgentDefaultModels,
 	}
 
-	localRt, err := runtime.New(t,
+	localRt, err := runtime.New(t, append([]runtime.Opt{
 		runtime.WithSessionStore(sessStore),
 		runtime.WithCurrentAgent(f.agentName),
 		runtime.WithTracer(otel.Tracer(AppName)),
+		runtime.WithMeterProvider(otel.GetMeterProvider()),
 		runtime.WithModelSwitcherConfig(modelSwitcherCfg),
-	)
+	}, extraOpts...)...)
 	if err != nil {
 		return nil, nil, fmt.Errorf("creating runtime: %w", err)
 	}
@@ -378,7 +431,11 @@ func (f *runExecFlags) createLocalRuntimeAndSession(ctx context.Context, loadRes
 		}
 
 		// Load existing session
-		sess, err = sessStore.GetSession(ctx, resolvedID)
+		getSession := sessStore.GetSession
+		if f.condensedSession {
+			getSession = sessStore.GetCondensedSession
+		}
+		sess, err = getSession(ctx, resolvedID)
 		if err != nil {
 			return nil, nil, fmt.Errorf("loading session %q: %w", resolvedID, err)
 		}
@@ -408,6 +465,14 @@ func (f *runExecFlags) createLocalRuntimeAndSession(ctx context.Context, loadRes
 	return localRt, sess, nil
 }
 
+// mergeGlobalPermissions merges user-level global permissions into the team's
+// checker so the runtime receives a single, already-merged permission set.
+func (f *runExecFlags) mergeGlobalPermissions(t *team.Team) {
+	if f.globalPermissions != nil && !f.globalPermissions.IsEmpty() {
+		t.SetPermissions(permissions.Merge(t.Permissions(), f.globalPermissions))
+	}
+}
+
 func (f *runExecFlags) handleExecMode(ctx context.Context, out *cli.Printer, rt runtime.Runtime, sess *session.Session, args []string) error {
 	// args[0] is the agent file; args[1:] are user messages for multi-turn conversation
 	var userMessages []string
@@ -420,7 +485,11 @@ func (f *runExecFlags) handleExecMode(ctx context.Context, out *cli.Printer, rt
 		AttachmentPath: f.attachmentPath,
 		HideToolCalls:  f.hideToolCalls,
 		OutputJSON:     f.outputJSON,
+		OutputFormat:   cli.OutputFormat(f.outputFormat),
 		AutoApprove:    f.autoApprove,
+		ApproveAll:     f.approveAll,
+		RejectAll:      f.rejectAll,
+		ApproveTools:   f.approveTools,
 	}, rt, sess, userMessages)
 	if cliErr, ok := errors.AsType[cli.RuntimeError](err); ok {
 		return RuntimeError{Err: cliErr.Err}
@@ -555,6 +624,7 @@ func (f *runExecFlags) createSessionSpawner(agentSource config.Source, sessStore
 			runtime.WithSessionStore(sessStore),
 			runtime.WithCurrentAgent(f.agentName),
 			runtime.WithTracer(otel.Tracer(AppName)),
+			runtime.WithMeterProvider(otel.GetMeterProvider()),
 			runtime.WithModelSwitcherConfig(modelSwitcherCfg),
 		)
 		if err != nil {
@@ -571,10 +641,8 @@ func (f *runExecFlags) createSessionSpawner(agentSource config.Source, sessStore
 
 		// Create the app
 		var appOpts []app.Opt
-		if pr, ok := localRt.(*runtime.PersistentRuntime); ok {
-			if model := pr.CurrentAgent().Model(); model != nil {
-				appOpts = append(appOpts, app.WithTitleGenerator(sessiontitle.New(model)))
-			}
+		if gen := localRt.TitleGenerator(); gen != nil {
+			appOpts = append(appOpts, app.WithTitleGenerator(gen))
 		}
 
 		a := app.New(spawnCtx, localRt, newSess, appOpts...)
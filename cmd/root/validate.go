@@ -0,0 +1,62 @@
+package root
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/docker/docker-agent/pkg/cli"
+	"github.com/docker/docker-agent/pkg/config"
+	"github.com/docker/docker-agent/pkg/telemetry"
+)
+
+type validateFlags struct {
+	runConfig config.RuntimeConfig
+}
+
+func newValidateCmd() *cobra.Command {
+	var flags validateFlags
+
+	cmd := &cobra.Command{
+		Use:   "validate <agent-file>|<registry-ref>",
+		Short: "Check an agent configuration for errors",
+		Long:  "Check an agent configuration for errors without running it. Exits with a non-zero status if the configuration has errors.",
+		Example: `  docker-agent validate ./agent.yaml
+  docker-agent validate agentcatalog/pirate`,
+		GroupID: "core",
+		Args:    cobra.ExactArgs(1),
+		RunE:    flags.runValidateCommand,
+	}
+
+	addRuntimeConfigFlags(cmd, &flags.runConfig)
+
+	return cmd
+}
+
+func (f *validateFlags) runValidateCommand(cmd *cobra.Command, args []string) (commandErr error) {
+	telemetry.TrackCommand(cmd.Context(), "validate", args)
+	defer func() { // do not inline this defer so that commandErr is not resolved early
+		telemetry.TrackCommandError(cmd.Context(), "validate", args, commandErr)
+	}()
+
+	agentSource, err := config.Resolve(args[0], f.runConfig.EnvProvider())
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(cmd.Context(), agentSource, f.runConfig.LoadOpts()...)
+	if err != nil {
+		return err
+	}
+
+	diagnostics, err := config.Validate(*cfg, config.WithEnvLookup(cmd.Context(), f.runConfig.EnvProvider()), config.WithBaseDir(agentSource.ParentDir()))
+
+	out := cli.NewPrinter(cmd.OutOrStdout())
+	for _, d := range diagnostics {
+		out.Println(d.String())
+	}
+	if err != nil {
+		return err
+	}
+
+	out.Printf("%s is valid\n", args[0])
+	return nil
+}
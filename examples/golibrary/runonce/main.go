@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/config"
+	"github.com/docker/docker-agent/pkg/config/latest"
+	"github.com/docker/docker-agent/pkg/environment"
+	"github.com/docker/docker-agent/pkg/model/provider/openai"
+	"github.com/docker/docker-agent/pkg/runtime"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools/builtin"
+)
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	if err := run(ctx); err != nil {
+		log.Println(err)
+	}
+}
+
+func run(ctx context.Context) error {
+	llm, err := openai.NewClient(
+		ctx,
+		&latest.ModelConfig{
+			Provider: "openai",
+			Model:    "gpt-4o",
+		},
+		environment.NewDefaultProvider(),
+	)
+	if err != nil {
+		return err
+	}
+
+	// The team is shared by all the runs; its toolsets are stopped once
+	// they're done.
+	agents := team.New(
+		team.WithAgents(
+			agent.New(
+				"root",
+				"You are a helpful assistant",
+				agent.WithModel(llm),
+				agent.WithToolSets(builtin.NewShellTool(os.Environ(), &config.RuntimeConfig{Config: config.Config{WorkingDir: "/tmp"}})),
+			),
+		),
+	)
+	defer func() {
+		if err := agents.StopToolSets(context.WithoutCancel(ctx)); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	result, err := runtime.RunOnce(ctx, runtime.RunOnceRequest{
+		Team:        agents,
+		Prompt:      "How many files are in the current directory?",
+		Timeout:     time.Minute,
+		Budget:      0.10,
+		AutoApprove: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, call := range result.ToolCalls {
+		fmt.Printf("%s(%s)\n%s\n\n", call.Name, call.Arguments, call.Output)
+	}
+	fmt.Println(result.Output)
+	fmt.Printf("\n%d input tokens, %d output tokens, $%.4f\n", result.Usage.InputTokens, result.Usage.OutputTokens, result.Usage.Cost)
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/docker/docker-agent/pkg/agent"
 	"github.com/docker/docker-agent/pkg/config/latest"
@@ -50,27 +51,39 @@ func run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	// Close stops the toolsets and cancels the stream if it's still running.
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		if err := rt.Close(closeCtx); err != nil {
+			log.Println(err)
+		}
+	}()
 
 	sess := session.New(session.WithUserMessage("How are you doing?"))
 
-	events := rt.RunStream(ctx, sess)
-	for event := range events {
-		switch e := event.(type) {
-		case *runtime.AgentChoiceEvent:
+	sub := runtime.Events(rt.RunStream(ctx, sess)).
+		OnAgentChoice(func(e *runtime.AgentChoiceEvent) {
 			log.Printf("Agent %s: %s\n", e.AgentName, e.Content)
-		case *runtime.StreamStartedEvent:
-			log.Println("Stream started for session")
-		case *runtime.StreamStoppedEvent:
-			log.Println("Stream stopped for session")
-		case *runtime.ToolCallConfirmationEvent:
-			rt.Resume(ctx, runtime.ResumeRequest{Type: runtime.ResumeTypeApproveSession})
-		case *runtime.ToolCallEvent:
+		}).
+		OnToolCallConfirmation(func(*runtime.ToolCallConfirmationEvent) {
+			if err := rt.Resume(ctx, sess.ID, runtime.ResumeRequest{Type: runtime.ResumeTypeApproveSession}); err != nil {
+				log.Printf("Resume: %v\n", err)
+			}
+		}).
+		OnToolCall(func(e *runtime.ToolCallEvent) {
 			log.Printf("Tool call: %s\n", e.ToolCall.Function.Name)
-		case *runtime.ToolCallResponseEvent:
+		}).
+		OnToolCallResponse(func(e *runtime.ToolCallResponseEvent) {
 			log.Printf("Tool call response: %s\n", e.Response)
-			// etc...
-		}
-	}
+		})
+	// The events without an On method are subscribed to by type.
+	runtime.On(sub, func(*runtime.StreamStartedEvent) {
+		log.Println("Stream started for session")
+	})
+	runtime.On(sub, func(*runtime.StreamStoppedEvent) {
+		log.Println("Stream stopped for session")
+	})
 
-	return nil
+	return sub.Run(ctx)
 }
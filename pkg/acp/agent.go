@@ -408,6 +408,15 @@ func (a *Agent) runAgent(ctx context.Context, acpSess *Session) error {
 				return err
 			}
 
+		case *runtime.AgentThoughtEvent:
+			// Send thoughts recorded with the think tool as agent thoughts too
+			if err := a.conn.SessionUpdate(ctx, acp.SessionNotification{
+				SessionId: acp.SessionId(acpSess.id),
+				Update:    acp.UpdateAgentThoughtText(e.Thought + "\n\n"),
+			}); err != nil {
+				return err
+			}
+
 		case *runtime.ToolCallConfirmationEvent:
 			if err := a.handleToolCallConfirmation(ctx, acpSess, e); err != nil {
 				return err
@@ -470,26 +479,28 @@ func (a *Agent) runAgent(ctx context.Context, acpSess *Session) error {
 func (a *Agent) handleToolCallConfirmation(ctx context.Context, acpSess *Session, e *runtime.ToolCallConfirmationEvent) error {
 	toolCallUpdate := buildToolCallUpdate(e.ToolCall, e.ToolDefinition, acp.ToolCallStatusPending)
 
+	options := []acp.PermissionOption{{
+		Kind:     acp.PermissionOptionKindAllowOnce,
+		Name:     "Allow this action",
+		OptionId: "allow",
+	}}
+	if e.Offers(runtime.ResumeTypeApproveTool) {
+		options = append(options, acp.PermissionOption{
+			Kind:     acp.PermissionOptionKindAllowAlways,
+			Name:     "Always allow " + e.ToolCall.Function.Name,
+			OptionId: "allow-always",
+		})
+	}
+	options = append(options, acp.PermissionOption{
+		Kind:     acp.PermissionOptionKindRejectOnce,
+		Name:     "Skip this action",
+		OptionId: "reject",
+	})
+
 	permResp, err := a.conn.RequestPermission(ctx, acp.RequestPermissionRequest{
 		SessionId: acp.SessionId(acpSess.id),
 		ToolCall:  toolCallUpdate,
-		Options: []acp.PermissionOption{
-			{
-				Kind:     acp.PermissionOptionKindAllowOnce,
-				Name:     "Allow this action",
-				OptionId: "allow",
-			},
-			{
-				Kind:     acp.PermissionOptionKindAllowAlways,
-				Name:     "Allow and remember my choice",
-				OptionId: "allow-always",
-			},
-			{
-				Kind:     acp.PermissionOptionKindRejectOnce,
-				Name:     "Skip this action",
-				OptionId: "reject",
-			},
-		},
+		Options:   options,
 	})
 	if err != nil {
 		return err
@@ -497,8 +508,7 @@ func (a *Agent) handleToolCallConfirmation(ctx context.Context, acpSess *Session
 
 	// Handle permission outcome
 	if permResp.Outcome.Cancelled != nil {
-		acpSess.rt.Resume(ctx, runtime.ResumeRequest{Type: runtime.ResumeTypeReject})
-		return nil
+		return acpSess.rt.Resume(ctx, acpSess.sess.ID, runtime.ResumeRequest{Type: runtime.ResumeTypeReject})
 	}
 
 	if permResp.Outcome.Selected == nil {
@@ -507,16 +517,14 @@ func (a *Agent) handleToolCallConfirmation(ctx context.Context, acpSess *Session
 
 	switch string(permResp.Outcome.Selected.OptionId) {
 	case "allow":
-		acpSess.rt.Resume(ctx, runtime.ResumeRequest{Type: runtime.ResumeTypeApprove})
+		return acpSess.rt.Resume(ctx, acpSess.sess.ID, runtime.ResumeRequest{Type: runtime.ResumeTypeApprove})
 	case "allow-always":
-		acpSess.rt.Resume(ctx, runtime.ResumeRequest{Type: runtime.ResumeTypeApproveSession})
+		return acpSess.rt.Resume(ctx, acpSess.sess.ID, runtime.ResumeApproveTool(e.ToolCall.Function.Name))
 	case "reject":
-		acpSess.rt.Resume(ctx, runtime.ResumeRequest{Type: runtime.ResumeTypeReject})
+		return acpSess.rt.Resume(ctx, acpSess.sess.ID, runtime.ResumeRequest{Type: runtime.ResumeTypeReject})
 	default:
 		return fmt.Errorf("unexpected permission option: %s", permResp.Outcome.Selected.OptionId)
 	}
-
-	return nil
 }
 
 // handleMaxIterationsReached handles max iterations events
@@ -548,12 +556,9 @@ func (a *Agent) handleMaxIterationsReached(ctx context.Context, acpSess *Session
 
 	if permResp.Outcome.Cancelled != nil || permResp.Outcome.Selected == nil ||
 		string(permResp.Outcome.Selected.OptionId) == "stop" {
-		acpSess.rt.Resume(ctx, runtime.ResumeRequest{Type: runtime.ResumeTypeReject})
-	} else {
-		acpSess.rt.Resume(ctx, runtime.ResumeRequest{Type: runtime.ResumeTypeApprove})
+		return acpSess.rt.Resume(ctx, acpSess.sess.ID, runtime.ResumeRequest{Type: runtime.ResumeTypeReject})
 	}
-
-	return nil
+	return acpSess.rt.Resume(ctx, acpSess.sess.ID, runtime.ResumeRequest{Type: runtime.ResumeTypeApprove})
 }
 
 // buildToolCallStart creates a tool call start update
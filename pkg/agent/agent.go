@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"log/slog"
 	"math/rand"
+	"slices"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -20,6 +23,7 @@ type Agent struct {
 	description             string
 	welcomeMessage          string
 	instruction             string
+	instructionFragments    []string // Shared fragments composed before the instruction
 	toolsets                []*tools.StartableToolSet
 	models                  []provider.Provider
 	fallbackModels          []provider.Provider                 // Fallback models to try if primary fails
@@ -32,6 +36,8 @@ type Agent struct {
 	addDate                 bool
 	addEnvironmentInfo      bool
 	addDescriptionParameter bool
+	citeSources             bool
+	requireFinalAnswer      bool
 	maxIterations           int
 	maxConsecutiveToolCalls int
 	maxOldToolCallTokens    int
@@ -41,13 +47,32 @@ type Agent struct {
 	commands                types.Commands
 	pendingWarnings         []string
 	hooks                   *latest.HooksConfig
+	requiredToolSets        []string     // Names of the toolsets whose failures are fatal
+	includeCategories       []string     // Tool categories the agent is restricted to, if any
+	excludeCategories       []string     // Tool categories hidden from the agent
+	disabledBuiltinTools    []string     // Built-in tools the agent doesn't get from its sub-agents or handoffs
+	failedToolSets          atomic.Int64 // Number of toolsets that failed during the last Tools call
+	outputGuards            []OutputGuard
+	outputGuardRetries      int // Number of turns re-run after a rejected answer
+
+	// Tools of each toolset, listed again only when their version changes.
+	toolsCacheMu sync.Mutex
+	toolsCache   map[*tools.StartableToolSet]cachedTools
+
+	// Instruction template, nil if the instruction is plain text.
+	prompt              *promptTemplate
+	renderMu            sync.Mutex
+	rendered            bool
+	renderedInstruction string
+	renderErr           error
 }
 
 // New creates a new agent
 func New(name, prompt string, opts ...Opt) *Agent {
 	agent := &Agent{
-		name:        name,
-		instruction: prompt,
+		name:               name,
+		instruction:        prompt,
+		outputGuardRetries: DefaultOutputGuardRetries,
 	}
 
 	for _, opt := range opts {
@@ -61,15 +86,52 @@ func (a *Agent) Name() string {
 	return a.name
 }
 
-// Instruction returns the agent's instructions
+// Instruction returns the agent's instructions. If the instruction is a
+// template that failed to render, see RenderInstruction, the template itself
+// is returned.
 func (a *Agent) Instruction() string {
-	return a.instruction
+	if a.prompt == nil {
+		return a.composedInstruction()
+	}
+	if err := a.RenderInstruction(context.Background()); err != nil {
+		return a.composedInstruction()
+	}
+	return a.renderedInstruction
+}
+
+// composedInstruction returns the instruction fragments, in the order they
+// were given, followed by the agent's own instruction, separated by blank
+// lines.
+func (a *Agent) composedInstruction() string {
+	if len(a.instructionFragments) == 0 {
+		return a.instruction
+	}
+
+	parts := make([]string, 0, len(a.instructionFragments)+1)
+	for _, part := range append(slices.Clone(a.instructionFragments), a.instruction) {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "\n\n")
 }
 
 func (a *Agent) AddDate() bool {
 	return a.addDate
 }
 
+// CiteSources reports whether the agent is asked to cite the sources
+// retrieved by its RAG tools by index in its answers.
+func (a *Agent) CiteSources() bool {
+	return a.citeSources
+}
+
+// RequireFinalAnswer reports whether the agent must hand over the
+// deliverable of its turns with the final_answer tool.
+func (a *Agent) RequireFinalAnswer() bool {
+	return a.requireFinalAnswer
+}
+
 func (a *Agent) AddEnvironmentInfo() bool {
 	return a.addEnvironmentInfo
 }
@@ -124,6 +186,13 @@ func (a *Agent) HasSubAgents() bool {
 	return len(a.subAgents) > 0
 }
 
+// BuiltinToolEnabled reports whether the agent can use a built-in tool it
+// gets from its configuration, e.g. "transfer_task" for its sub-agents or
+// "handoff" for its handoffs, rather than from a toolset.
+func (a *Agent) BuiltinToolEnabled(name string) bool {
+	return !slices.Contains(a.disabledBuiltinTools, name)
+}
+
 // Model returns the model to use for this agent.
 // If model override(s) are set, it returns one of the overrides (randomly for alloy).
 // Otherwise, it returns a random model from the available models.
@@ -206,10 +275,24 @@ func (a *Agent) Hooks() *latest.HooksConfig {
 	return a.hooks
 }
 
-// Tools returns the tools available to this agent
+// Tools returns the tools available to this agent.
+//
+// Each toolset is started and listed independently: the tools of the
+// toolsets that fail are left out and the failures are recorded as warnings,
+// unless the toolset is required, in which case Tools fails.
 func (a *Agent) Tools(ctx context.Context) ([]tools.Tool, error) {
-	a.ensureToolSetsAreStarted(ctx)
-	return a.collectTools(ctx)
+	startFailures, err := a.ensureToolSetsAreStarted(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	agentTools, listFailures, err := a.collectTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	a.failedToolSets.Store(int64(startFailures + listFailures))
+	return agentTools, nil
 }
 
 // StartedTools returns tools only from toolsets that have already been started,
@@ -217,34 +300,137 @@ func (a *Agent) Tools(ctx context.Context) ([]tools.Tool, error) {
 // notifications (e.g. MCP tool list changes) that should not block on slow
 // toolset startup such as RAG file indexing.
 func (a *Agent) StartedTools(ctx context.Context) ([]tools.Tool, error) {
-	return a.collectTools(ctx)
+	agentTools, _, err := a.collectTools(ctx)
+	return agentTools, err
+}
+
+// FailedToolSets returns the number of toolsets that failed to start or to
+// list their tools during the last call to Tools.
+func (a *Agent) FailedToolSets() int {
+	return int(a.failedToolSets.Load())
+}
+
+// IsRequiredToolSet reports whether a failure of ts is fatal for the agent.
+func (a *Agent) IsRequiredToolSet(ts tools.ToolSet) bool {
+	name := tools.ToolSetName(ts)
+	return name != "" && slices.Contains(a.requiredToolSets, name)
 }
 
 // collectTools gathers tools from all started toolsets plus static tools.
-func (a *Agent) collectTools(ctx context.Context) ([]tools.Tool, error) {
-	var agentTools []tools.Tool
+// It returns the number of toolsets that failed to list their tools.
+func (a *Agent) collectTools(ctx context.Context) ([]tools.Tool, int, error) {
+	var (
+		agentTools []tools.Tool
+		failures   int
+	)
 	for _, toolSet := range a.toolsets {
 		if !toolSet.IsStarted() {
 			// Toolset not started; skip it
 			continue
 		}
-		ta, err := toolSet.Tools(ctx)
+		ta, err := a.toolSetTools(ctx, toolSet)
 		if err != nil {
 			desc := tools.DescribeToolSet(toolSet)
+			if a.IsRequiredToolSet(toolSet) {
+				return nil, 0, fmt.Errorf("required toolset %s list failed: %w", desc, err)
+			}
 			slog.Warn("Toolset listing failed; skipping", "agent", a.Name(), "toolset", desc, "error", err)
 			a.addToolWarning(fmt.Sprintf("%s list failed: %v", desc, err))
+			failures++
 			continue
 		}
 		agentTools = append(agentTools, ta...)
 	}
 
 	agentTools = append(agentTools, a.tools...)
+	agentTools = a.FilterTools(agentTools)
 
 	if a.addDescriptionParameter {
 		agentTools = tools.AddDescriptionParameter(agentTools)
 	}
 
-	return agentTools, nil
+	return agentTools, failures, nil
+}
+
+// cachedTools are the tools of a toolset at a version of its tools.
+type cachedTools struct {
+	version uint64
+	tools   []tools.Tool
+}
+
+// toolSetTools returns the tools of a started toolset. They're only listed
+// again when the version of the toolset's tools changed since the last call,
+// e.g. after an MCP server notified that its tools changed, or after the
+// toolset was restarted.
+func (a *Agent) toolSetTools(ctx context.Context, toolSet *tools.StartableToolSet) ([]tools.Tool, error) {
+	version := toolSet.ToolsVersion()
+
+	a.toolsCacheMu.Lock()
+	cached, ok := a.toolsCache[toolSet]
+	a.toolsCacheMu.Unlock()
+	if ok && cached.version == version {
+		return cached.tools, nil
+	}
+
+	ta, err := toolSet.Tools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tools that changed while being listed may be stale: they're listed
+	// again next time.
+	if toolSet.ToolsVersion() == version {
+		a.toolsCacheMu.Lock()
+		if a.toolsCache == nil {
+			a.toolsCache = make(map[*tools.StartableToolSet]cachedTools)
+		}
+		a.toolsCache[toolSet] = cachedTools{version: version, tools: ta}
+		a.toolsCacheMu.Unlock()
+	}
+
+	return ta, nil
+}
+
+// PreviewToolCall returns what a call to one of the tools of the agent's
+// toolsets would change, or nil if its toolset can't tell, see
+// tools.Previewable.
+func (a *Agent) PreviewToolCall(ctx context.Context, toolCall tools.ToolCall) (*tools.Preview, error) {
+	if toolSet := a.ToolSetOf(ctx, toolCall.Function.Name); toolSet != nil {
+		return tools.PreviewToolCall(ctx, toolSet, toolCall)
+	}
+	return nil, nil
+}
+
+// ToolSetOf returns the started toolset of the agent providing the tool
+// named toolName, or nil if there is none, e.g. for the static tools.
+func (a *Agent) ToolSetOf(ctx context.Context, toolName string) tools.ToolSet {
+	for _, toolSet := range a.toolsets {
+		if !toolSet.IsStarted() {
+			continue
+		}
+		ta, err := a.toolSetTools(ctx, toolSet)
+		if err != nil {
+			continue
+		}
+		if slices.ContainsFunc(ta, func(t tools.Tool) bool { return t.Name == toolName }) {
+			return toolSet
+		}
+	}
+	return nil
+}
+
+// InvalidateTools drops the cached tools of the agent's toolsets, so that
+// they're listed again on the next call to Tools.
+func (a *Agent) InvalidateTools() {
+	a.toolsCacheMu.Lock()
+	defer a.toolsCacheMu.Unlock()
+	a.toolsCache = nil
+}
+
+// FilterTools drops the tools of the categories the agent is not allowed
+// to use, so that the model never sees them.
+func (a *Agent) FilterTools(agentTools []tools.Tool) []tools.Tool {
+	return tools.FilterByCategory(agentTools, a.includeCategories, a.excludeCategories)
 }
 
 func (a *Agent) ToolSets() []tools.ToolSet {
@@ -257,15 +443,24 @@ func (a *Agent) ToolSets() []tools.ToolSet {
 	return toolSets
 }
 
-func (a *Agent) ensureToolSetsAreStarted(ctx context.Context) {
+// ensureToolSetsAreStarted starts the toolsets that are not started yet. It
+// returns the number of toolsets that failed to start, or an error if one of
+// them is required.
+func (a *Agent) ensureToolSetsAreStarted(ctx context.Context) (int, error) {
+	var failures int
 	for _, toolSet := range a.toolsets {
 		if err := toolSet.Start(ctx); err != nil {
 			desc := tools.DescribeToolSet(toolSet)
+			if a.IsRequiredToolSet(toolSet) {
+				return 0, fmt.Errorf("required toolset %s start failed: %w", desc, err)
+			}
 			slog.Warn("Toolset start failed; skipping", "agent", a.Name(), "toolset", desc, "error", err)
 			a.addToolWarning(fmt.Sprintf("%s start failed: %v", desc, err))
+			failures++
 			continue
 		}
 	}
+	return failures, nil
 }
 
 // addToolWarning records a warning generated while loading or starting toolsets.
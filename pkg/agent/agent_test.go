@@ -3,7 +3,10 @@ package agent
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -84,6 +87,7 @@ func TestAgentTools(t *testing.T) {
 
 			require.NoError(t, err)
 			require.Len(t, got, tt.wantToolCount)
+			require.Equal(t, tt.wantWarnings, a.FailedToolSets())
 
 			warnings := a.DrainWarnings()
 			if tt.wantWarnings == 0 {
@@ -95,6 +99,162 @@ func TestAgentTools(t *testing.T) {
 	}
 }
 
+// namedToolSet is a stubToolSet with a user-defined name.
+type namedToolSet struct {
+	*stubToolSet
+
+	name string
+}
+
+func (n *namedToolSet) Name() string { return n.name }
+
+func TestAgentTools_RequiredToolsets(t *testing.T) {
+	good := newStubToolSet(nil, []tools.Tool{{Name: "good", Parameters: map[string]any{}}}, nil)
+	github := &namedToolSet{stubToolSet: &stubToolSet{startErr: errors.New("boom")}, name: "github"}
+	search := &namedToolSet{stubToolSet: &stubToolSet{listErr: errors.New("list boom")}, name: "search"}
+
+	t.Run("optional failures degrade", func(t *testing.T) {
+		a := New("root", "test", WithToolSets(good, github, search), WithRequiredToolsets("other"))
+		got, err := a.Tools(t.Context())
+
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+		assert.Equal(t, 2, a.FailedToolSets())
+		assert.Len(t, a.DrainWarnings(), 2)
+	})
+
+	t.Run("required start failure is fatal", func(t *testing.T) {
+		a := New("root", "test", WithToolSets(good, github), WithRequiredToolsets("github"))
+		_, err := a.Tools(t.Context())
+
+		require.ErrorContains(t, err, "required toolset")
+		require.ErrorContains(t, err, "boom")
+	})
+
+	t.Run("required list failure is fatal", func(t *testing.T) {
+		a := New("root", "test", WithToolSets(good, search), WithRequiredToolsets("search"))
+		_, err := a.Tools(t.Context())
+
+		require.ErrorContains(t, err, "list boom")
+	})
+}
+
+// countingToolSet counts how many times its tools are listed.
+type countingToolSet struct {
+	tools   []tools.Tool
+	version atomic.Uint64
+	lists   atomic.Int32
+	listErr error
+}
+
+func (c *countingToolSet) Tools(context.Context) ([]tools.Tool, error) {
+	c.lists.Add(1)
+	if c.listErr != nil {
+		return nil, c.listErr
+	}
+	return c.tools, nil
+}
+
+func (c *countingToolSet) ToolsVersion() uint64 { return c.version.Load() }
+
+func TestAgentTools_Cache(t *testing.T) {
+	toolSet := &countingToolSet{tools: []tools.Tool{{Name: "search", Parameters: map[string]any{}}}}
+	a := New("root", "test", WithToolSets(toolSet))
+	startable := a.ToolSets()[0].(*tools.StartableToolSet)
+
+	listTools := func() {
+		t.Helper()
+		got, err := a.Tools(t.Context())
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+	}
+
+	listTools()
+	listTools()
+	assert.Equal(t, int32(1), toolSet.lists.Load(), "unchanged tools are cached")
+
+	toolSet.version.Add(1)
+	listTools()
+	assert.Equal(t, int32(2), toolSet.lists.Load(), "a new version is listed")
+
+	startable.Invalidate()
+	listTools()
+	assert.Equal(t, int32(3), toolSet.lists.Load(), "invalidated tools are listed")
+
+	require.NoError(t, a.StopToolSets(t.Context()))
+	listTools()
+	assert.Equal(t, int32(4), toolSet.lists.Load(), "restarted toolsets are listed")
+
+	a.InvalidateTools()
+	listTools()
+	assert.Equal(t, int32(5), toolSet.lists.Load(), "the agent's cache can be dropped")
+
+	// The cache is per agent.
+	other := New("other", "test")
+	other.toolsets = a.toolsets
+	_, err := other.Tools(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, int32(6), toolSet.lists.Load())
+}
+
+func TestAgentTools_FailuresAreNotCached(t *testing.T) {
+	toolSet := &countingToolSet{listErr: errors.New("boom")}
+	a := New("root", "test", WithToolSets(toolSet))
+
+	for range 2 {
+		_, err := a.Tools(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, 1, a.FailedToolSets())
+	}
+	assert.Equal(t, int32(2), toolSet.lists.Load())
+}
+
+// schemaToolSet builds its tools and their schemas on each call, like most
+// toolsets.
+type schemaToolSet struct{ count int }
+
+func (s *schemaToolSet) Tools(context.Context) ([]tools.Tool, error) {
+	type args struct {
+		Path    string `json:"path" jsonschema:"The path of the file"`
+		Pattern string `json:"pattern,omitempty" jsonschema:"A pattern to match"`
+	}
+
+	toolList := make([]tools.Tool, s.count)
+	for i := range toolList {
+		toolList[i] = tools.Tool{
+			Name:        fmt.Sprintf("tool_%d", i),
+			Description: strings.Repeat("A long description. ", 20),
+			Parameters:  tools.MustSchemaFor[args](),
+		}
+	}
+	return toolList, nil
+}
+
+// BenchmarkAgentTools measures the overhead of listing the tools of an agent
+// with 50 tools, as done on each iteration of the runtime loop.
+func BenchmarkAgentTools(b *testing.B) {
+	a := New("root", "test", WithToolSets(&schemaToolSet{count: 50}))
+
+	for b.Loop() {
+		if _, err := a.Tools(b.Context()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkAgentTools_Uncached is BenchmarkAgentTools with the cache dropped
+// before each call, for comparison.
+func BenchmarkAgentTools_Uncached(b *testing.B) {
+	a := New("root", "test", WithToolSets(&schemaToolSet{count: 50}))
+
+	for b.Loop() {
+		a.InvalidateTools()
+		if _, err := a.Tools(b.Context()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 // mockProvider implements provider.Provider for testing
 type mockProvider struct {
 	id string
@@ -17,6 +17,15 @@ func WithInstruction(instruction string) Opt {
 	}
 }
 
+// WithInstructionFragments adds shared instruction fragments, e.g. coding
+// conventions or safety rules, composed in the order they're given before
+// the agent's own instruction. It can be given several times.
+func WithInstructionFragments(fragments ...string) Opt {
+	return func(a *Agent) {
+		a.instructionFragments = append(a.instructionFragments, fragments...)
+	}
+}
+
 func WithToolSets(toolSet ...tools.ToolSet) Opt {
 	var startableToolSet []*tools.StartableToolSet
 	for _, ts := range toolSet {
@@ -109,6 +118,21 @@ func WithAddEnvironmentInfo(addEnvironmentInfo bool) Opt {
 	}
 }
 
+func WithCiteSources(citeSources bool) Opt {
+	return func(a *Agent) {
+		a.citeSources = citeSources
+	}
+}
+
+// WithRequireFinalAnswer makes the agent hand over the deliverable of its
+// turns with the final_answer tool: the runtime reminds it to when it stops
+// without calling it.
+func WithRequireFinalAnswer(require bool) Opt {
+	return func(a *Agent) {
+		a.requireFinalAnswer = require
+	}
+}
+
 func WithAddDescriptionParameter(addDescriptionParameter bool) Opt {
 	return func(a *Agent) {
 		a.addDescriptionParameter = addDescriptionParameter
@@ -172,3 +196,50 @@ func WithHooks(hooks *latest.HooksConfig) Opt {
 		a.hooks = hooks
 	}
 }
+
+// WithRequiredToolsets makes the toolsets with the given names required:
+// when one of them fails to start or to list its tools, the agent fails
+// instead of running without its tools.
+func WithRequiredToolsets(names ...string) Opt {
+	return func(a *Agent) {
+		a.requiredToolSets = append(a.requiredToolSets, names...)
+	}
+}
+
+// WithDisabledBuiltinTools disables the built-in tools the agent would get
+// from its configuration, e.g. "handoff" for an agent with handoffs that
+// should only transfer tasks to its sub-agents.
+func WithDisabledBuiltinTools(names ...string) Opt {
+	return func(a *Agent) {
+		a.disabledBuiltinTools = names
+	}
+}
+
+// WithToolCategories restricts the tools of the agent to the given
+// categories, when include is not empty, and hides the tools of the
+// excluded ones.
+func WithToolCategories(include, exclude []string) Opt {
+	return func(a *Agent) {
+		a.includeCategories = include
+		a.excludeCategories = exclude
+	}
+}
+
+// WithOutputGuards adds guards checking the final answers of the agent, see
+// OutputGuard.
+func WithOutputGuards(guards ...OutputGuard) Opt {
+	return func(a *Agent) {
+		a.outputGuards = append(a.outputGuards, guards...)
+	}
+}
+
+// WithOutputGuardRetries sets how many times the turn of the agent is re-run
+// after its answer was rejected by an output guard. Defaults to
+// DefaultOutputGuardRetries. Negative values are ignored.
+func WithOutputGuardRetries(retries int) Opt {
+	return func(a *Agent) {
+		if retries >= 0 {
+			a.outputGuardRetries = retries
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultOutputGuardRetries is the number of times the turn of an agent is
+// re-run after its answer was rejected by an output guard.
+const DefaultOutputGuardRetries = 2
+
+// OutputGuard checks the final answer of an agent before it reaches the user
+// or the parent agent, e.g. that it doesn't contain secrets or that it's
+// valid JSON. It returns an error describing the violation to reject the
+// answer: the error is sent to the model so that it can fix its answer.
+type OutputGuard func(content string) error
+
+// DenyPattern returns a guard rejecting the answers matching pattern, e.g.
+// secrets. The matches aren't included in the error, not to repeat them.
+func DenyPattern(pattern *regexp.Regexp, description string) OutputGuard {
+	return func(content string) error {
+		if pattern.MatchString(content) {
+			return fmt.Errorf("the answer must not contain %s", description)
+		}
+		return nil
+	}
+}
+
+// RequireJSON returns a guard rejecting the answers that aren't valid JSON.
+// Surrounding whitespace and a markdown code fence are tolerated.
+func RequireJSON() OutputGuard {
+	return func(content string) error {
+		content = strings.TrimSpace(content)
+		if fenced, ok := strings.CutPrefix(content, "```"); ok {
+			_, fenced, _ = strings.Cut(fenced, "\n")
+			content = strings.TrimSuffix(strings.TrimSpace(fenced), "```")
+		}
+		var v any
+		if err := json.Unmarshal([]byte(content), &v); err != nil {
+			return fmt.Errorf("the answer must be valid JSON: %w", err)
+		}
+		return nil
+	}
+}
+
+// MaxLength returns a guard rejecting the answers longer than n characters.
+func MaxLength(n int) OutputGuard {
+	return func(content string) error {
+		if length := utf8.RuneCountInString(content); length > n {
+			return fmt.Errorf("the answer must be at most %d characters long, it is %d characters long", n, length)
+		}
+		return nil
+	}
+}
+
+// CheckOutput runs the output guards of the agent on content and returns the
+// violations, joined, or nil if the content is accepted.
+func (a *Agent) CheckOutput(content string) error {
+	var errs []error
+	for _, guard := range a.outputGuards {
+		if err := guard(content); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// HasOutputGuards reports whether the final answers of the agent are checked.
+func (a *Agent) HasOutputGuards() bool {
+	return len(a.outputGuards) > 0
+}
+
+// OutputGuardRetries returns how many times the turn of the agent is re-run
+// after its answer was rejected by an output guard.
+func (a *Agent) OutputGuardRetries() int {
+	return a.outputGuardRetries
+}
@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputGuards(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		guard   OutputGuard
+		content string
+		wantErr string
+	}{
+		{name: "no secret", guard: DenyPattern(regexp.MustCompile(`sk-\w+`), "API keys"), content: "No key here"},
+		{name: "secret", guard: DenyPattern(regexp.MustCompile(`sk-\w+`), "API keys"), content: "The key is sk-abc", wantErr: "the answer must not contain API keys"},
+		{name: "json", guard: RequireJSON(), content: ` {"a": 1} `},
+		{name: "fenced json", guard: RequireJSON(), content: "```json\n{\"a\": 1}\n```"},
+		{name: "not json", guard: RequireJSON(), content: `Here: {"a": 1}`, wantErr: "the answer must be valid JSON"},
+		{name: "short", guard: MaxLength(5), content: "héllo"},
+		{name: "too long", guard: MaxLength(5), content: "hello!", wantErr: "at most 5 characters long, it is 6"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.guard(tt.content)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckOutput(t *testing.T) {
+	t.Parallel()
+
+	a := New("root", "")
+	assert.False(t, a.HasOutputGuards())
+	assert.Equal(t, DefaultOutputGuardRetries, a.OutputGuardRetries())
+	require.NoError(t, a.CheckOutput("anything"))
+
+	errTooLong := errors.New("too long")
+	errNotJSON := errors.New("not JSON")
+	a = New("root", "",
+		WithOutputGuards(func(string) error { return errTooLong }),
+		WithOutputGuards(func(string) error { return nil }, func(string) error { return errNotJSON }),
+		WithOutputGuardRetries(0),
+	)
+	assert.True(t, a.HasOutputGuards())
+	assert.Equal(t, 0, a.OutputGuardRetries())
+
+	err := a.CheckOutput("anything")
+	require.ErrorIs(t, err, errTooLong)
+	require.ErrorIs(t, err, errNotJSON)
+}
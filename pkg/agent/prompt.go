@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/docker/docker-agent/pkg/environment"
+)
+
+// maxIncludeDepth bounds the nesting of {{include}} calls, to catch cycles.
+const maxIncludeDepth = 10
+
+// promptTemplate holds what's needed to render an instruction template.
+type promptTemplate struct {
+	vars       map[string]any
+	env        environment.Provider
+	baseDir    string
+	missingKey string
+}
+
+// WithPromptVars makes the instruction a Go text/template template rendered
+// with vars. It can be given several times; later values win.
+func WithPromptVars(vars map[string]any) Opt {
+	return func(a *Agent) {
+		p := a.ensurePromptTemplate()
+		if p.vars == nil {
+			p.vars = make(map[string]any, len(vars))
+		}
+		maps.Copy(p.vars, vars)
+	}
+}
+
+// WithPromptEnv sets the provider the env function of instruction templates
+// reads environment variables from.
+func WithPromptEnv(env environment.Provider) Opt {
+	return func(a *Agent) {
+		a.ensurePromptTemplate().env = env
+	}
+}
+
+// WithPromptBaseDir sets the directory the include function of instruction
+// templates resolves relative paths against, usually the directory of the
+// configuration file.
+func WithPromptBaseDir(dir string) Opt {
+	return func(a *Agent) {
+		a.ensurePromptTemplate().baseDir = dir
+	}
+}
+
+// WithPromptMissingKey sets what instruction templates do with undefined
+// variables: "error" (the default), "zero" or "default", as with the
+// missingkey option of text/template.
+func WithPromptMissingKey(policy string) Opt {
+	return func(a *Agent) {
+		a.ensurePromptTemplate().missingKey = policy
+	}
+}
+
+func (a *Agent) ensurePromptTemplate() *promptTemplate {
+	if a.prompt == nil {
+		a.prompt = &promptTemplate{}
+	}
+	return a.prompt
+}
+
+// RenderInstruction renders the instruction template, if the agent has one.
+// The instruction is rendered only once: later calls return the result of
+// the first one.
+func (a *Agent) RenderInstruction(ctx context.Context) error {
+	if a.prompt == nil {
+		return nil
+	}
+
+	a.renderMu.Lock()
+	defer a.renderMu.Unlock()
+
+	if !a.rendered {
+		a.renderedInstruction, a.renderErr = a.prompt.render(ctx, a.composedInstruction())
+		if a.renderErr != nil {
+			a.renderErr = fmt.Errorf("agent '%s': rendering instruction: %w", a.name, a.renderErr)
+		}
+		a.rendered = true
+	}
+	return a.renderErr
+}
+
+func (p *promptTemplate) render(ctx context.Context, text string) (string, error) {
+	return p.renderFile(ctx, "instruction", text, p.baseDir, nil)
+}
+
+// renderFile renders text, with include paths resolved against dir. stack
+// holds the files being included, to report cycles.
+func (p *promptTemplate) renderFile(ctx context.Context, name, text, dir string, stack []string) (string, error) {
+	missingKey := p.missingKey
+	if missingKey == "" {
+		missingKey = "error"
+	}
+
+	funcs := template.FuncMap{
+		"env": func(key string) (string, error) {
+			if p.env != nil {
+				if value, ok := p.env.Get(ctx, key); ok {
+					return value, nil
+				}
+			}
+			if missingKey == "error" {
+				return "", fmt.Errorf("environment variable %s is not set", key)
+			}
+			return "", nil
+		},
+		"include": func(path string) (string, error) {
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(dir, path)
+			}
+			if slices.Contains(stack, path) {
+				return "", fmt.Errorf("%s includes itself: %s", path, strings.Join(append(stack, path), " -> "))
+			}
+			if len(stack) >= maxIncludeDepth {
+				return "", errors.New("too many nested includes")
+			}
+			buf, err := os.ReadFile(path)
+			if err != nil {
+				return "", err
+			}
+			return p.renderFile(ctx, path, string(buf), filepath.Dir(path), append(stack, path))
+		},
+	}
+
+	tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=" + missingKey).Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, p.vars); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,148 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/environment"
+)
+
+func TestInstructionWithoutTemplate(t *testing.T) {
+	t.Parallel()
+
+	a := New("root", "Use {{.project}} literally")
+
+	require.NoError(t, a.RenderInstruction(t.Context()))
+	assert.Equal(t, "Use {{.project}} literally", a.Instruction())
+}
+
+func TestInstructionTemplate(t *testing.T) {
+	t.Parallel()
+
+	a := New("root", "Work on {{.project}} ({{env \"TEAM\"}}), {{.standards.lang}} style.",
+		WithPromptVars(map[string]any{"project": "cagent", "standards": map[string]any{"lang": "Go"}}),
+		WithPromptEnv(environment.NewMapEnvProvider(map[string]string{"TEAM": "core"})),
+	)
+
+	require.NoError(t, a.RenderInstruction(t.Context()))
+	assert.Equal(t, "Work on cagent (core), Go style.", a.Instruction())
+}
+
+func TestWithPromptVarsMerges(t *testing.T) {
+	t.Parallel()
+
+	a := New("root", "{{.a}} {{.b}}",
+		WithPromptVars(map[string]any{"a": "config", "b": "config"}),
+		WithPromptVars(map[string]any{"b": "code"}),
+	)
+
+	assert.Equal(t, "config code", a.Instruction())
+}
+
+func TestInstructionTemplateMissingVariable(t *testing.T) {
+	t.Parallel()
+
+	a := New("root", "Work on {{.project}}", WithPromptVars(map[string]any{}))
+
+	err := a.RenderInstruction(t.Context())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `map has no entry for key "project"`)
+	assert.Equal(t, "Work on {{.project}}", a.Instruction())
+}
+
+func TestInstructionTemplateMissingEnv(t *testing.T) {
+	t.Parallel()
+
+	a := New("root", `Team {{env "TEAM"}}`,
+		WithPromptVars(map[string]any{}),
+		WithPromptEnv(environment.NewNoEnvProvider()),
+	)
+
+	err := a.RenderInstruction(t.Context())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "environment variable TEAM is not set")
+}
+
+func TestInstructionTemplateMissingKeyPolicy(t *testing.T) {
+	t.Parallel()
+
+	a := New("root", `Work on {{.project}}{{env "TEAM"}}`,
+		WithPromptVars(map[string]any{}),
+		WithPromptMissingKey("default"),
+	)
+
+	require.NoError(t, a.RenderInstruction(t.Context()))
+	assert.Equal(t, "Work on <no value>", a.Instruction())
+}
+
+func TestInstructionTemplateNestedIncludes(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "shared", "standards"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "shared", "common.md"), []byte(`Project {{.project}}. {{include "standards/go.md"}}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "shared", "standards", "go.md"), []byte(`Format {{.project}} with gofmt.`), 0o644))
+
+	a := New("root", `{{include "shared/common.md"}} Be brief.`,
+		WithPromptVars(map[string]any{"project": "cagent"}),
+		WithPromptBaseDir(dir),
+	)
+
+	require.NoError(t, a.RenderInstruction(t.Context()))
+	assert.Equal(t, "Project cagent. Format cagent with gofmt. Be brief.", a.Instruction())
+}
+
+func TestInstructionTemplateIncludeCycle(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.md"), []byte(`{{include "b.md"}}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.md"), []byte(`{{include "a.md"}}`), 0o644))
+
+	a := New("root", `{{include "a.md"}}`,
+		WithPromptVars(map[string]any{}),
+		WithPromptBaseDir(dir),
+	)
+
+	err := a.RenderInstruction(t.Context())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "includes itself")
+}
+
+func TestInstructionTemplateMissingInclude(t *testing.T) {
+	t.Parallel()
+
+	a := New("root", `{{include "missing.md"}}`,
+		WithPromptVars(map[string]any{}),
+		WithPromptBaseDir(t.TempDir()),
+	)
+
+	require.ErrorIs(t, a.RenderInstruction(t.Context()), os.ErrNotExist)
+}
+
+func TestInstructionFragments(t *testing.T) {
+	t.Parallel()
+
+	a := New("root", "Review the pull request.\n",
+		WithInstructionFragments("Follow the Go conventions.\n", ""),
+		WithInstructionFragments("Be concise."),
+	)
+
+	assert.Equal(t, "Follow the Go conventions.\n\nBe concise.\n\nReview the pull request.", a.Instruction())
+}
+
+func TestInstructionFragmentsTemplate(t *testing.T) {
+	t.Parallel()
+
+	a := New("root", "Work on {{.project}}.",
+		WithInstructionFragments("Write {{.lang}} code."),
+		WithPromptVars(map[string]any{"project": "cagent", "lang": "Go"}),
+	)
+
+	require.NoError(t, a.RenderInstruction(t.Context()))
+	assert.Equal(t, "Write Go code.\n\nWork on cagent.", a.Instruction())
+}
@@ -0,0 +1,172 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker-agent/pkg/runtime"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// FromRuntime converts a runtime event to its envelope in version v of the
+// wire format. The runtime events the format has no type for are sent as
+// passthrough events. The time of the envelope is the one of the event, or
+// now when the event has none.
+func FromRuntime(event runtime.Event, v int, now time.Time) (Envelope, error) {
+	if _, ok := versions[v]; !ok {
+		return Envelope{}, fmt.Errorf("%w: %d", ErrUnsupportedVersion, v)
+	}
+
+	ts := now
+	if e, ok := event.(interface{ GetTimestamp() time.Time }); ok && !e.GetTimestamp().IsZero() {
+		ts = e.GetTimestamp()
+	}
+
+	eventType, data, err := convert(event)
+	if err != nil {
+		return Envelope{}, err
+	}
+	return Envelope{V: v, Type: eventType, TS: ts, Data: data}, nil
+}
+
+// convert returns the type and the data of a runtime event in version 1.
+func convert(event runtime.Event) (string, any, error) {
+	switch e := event.(type) {
+	case *runtime.UserMessageEvent:
+		return TypeUserMessage, UserMessageEvent{SessionID: e.SessionID, Message: e.Message}, nil
+	case *runtime.StreamStartedEvent:
+		return TypeStreamStarted, StreamEvent{SessionID: e.SessionID, Agent: e.AgentName}, nil
+	case *runtime.StreamStoppedEvent:
+		return TypeStreamStopped, StreamEvent{SessionID: e.SessionID, Agent: e.AgentName}, nil
+	case *runtime.AgentChoiceEvent:
+		return TypeAgentChoice, AgentChoiceEvent{SessionID: e.SessionID, Agent: e.AgentName, Content: e.Content}, nil
+	case *runtime.AgentChoiceReasoningEvent:
+		return TypeAgentChoiceReasoning, AgentChoiceEvent{SessionID: e.SessionID, Agent: e.AgentName, Content: e.Content}, nil
+	case *runtime.AgentMessageCompletedEvent:
+		data := AgentMessageCompletedEvent{
+			SessionID:        e.SessionID,
+			Agent:            e.AgentName,
+			Content:          e.Content,
+			ReasoningContent: e.ReasoningContent,
+			FinishReason:     string(e.FinishReason),
+		}
+		for _, toolCall := range e.ToolCalls {
+			data.ToolCalls = append(data.ToolCalls, convertToolCall(toolCall))
+		}
+		if e.Usage != nil {
+			data.Usage = &Usage{
+				InputTokens:       e.Usage.InputTokens,
+				OutputTokens:      e.Usage.OutputTokens,
+				CachedInputTokens: e.Usage.CachedInputTokens,
+				CacheWriteTokens:  e.Usage.CacheWriteTokens,
+				ReasoningTokens:   e.Usage.ReasoningTokens,
+			}
+		}
+		return TypeAgentMessageCompleted, data, nil
+	case *runtime.ToolCallEvent:
+		return TypeToolCall, ToolCallEvent{
+			Agent:    e.AgentName,
+			ToolCall: convertToolCall(e.ToolCall),
+			Tool:     convertTool(e.ToolDefinition),
+		}, nil
+	case *runtime.ToolCallConfirmationEvent:
+		data := ToolCallConfirmationEvent{
+			Agent:    e.AgentName,
+			ToolCall: convertToolCall(e.ToolCall),
+			Tool:     convertTool(e.ToolDefinition),
+		}
+		for _, option := range e.Options {
+			data.Options = append(data.Options, string(option))
+		}
+		if e.Preview != nil {
+			data.Preview = &Preview{Diff: e.Preview.Diff, Summary: e.Preview.Summary}
+		}
+		return TypeToolCallConfirmation, data, nil
+	case *runtime.ToolCallResponseEvent:
+		data := ToolCallResponseEvent{
+			Agent:      e.AgentName,
+			ToolCallID: e.ToolCallID,
+			Tool:       convertTool(e.ToolDefinition),
+			Output:     e.Response,
+		}
+		if e.Result != nil {
+			data.IsError = e.Result.IsError
+		}
+		return TypeToolCallResponse, data, nil
+	case *runtime.FileChangedEvent:
+		return TypeFileChanged, FileChangedEvent{
+			SessionID:  e.SessionID,
+			Agent:      e.AgentName,
+			ToolCallID: e.ToolCallID,
+			Path:       e.Change.Path,
+			Change:     string(e.Change.Type),
+			OldPath:    e.Change.OldPath,
+		}, nil
+	case *runtime.ErrorEvent:
+		return TypeError, ProblemEvent{
+			Agent:     e.AgentName,
+			Message:   e.Error,
+			Code:      string(e.Code),
+			Component: string(e.Component),
+			Hint:      e.Hint,
+		}, nil
+	case *runtime.WarningEvent:
+		return TypeWarning, ProblemEvent{
+			Agent:     e.AgentName,
+			Message:   e.Message,
+			Code:      string(e.Code),
+			Component: string(e.Component),
+			Hint:      e.Hint,
+		}, nil
+	case *runtime.TokenUsageEvent:
+		data := TokenUsageEvent{SessionID: e.SessionID, Agent: e.AgentName}
+		if e.Usage != nil {
+			data.InputTokens = e.Usage.InputTokens
+			data.OutputTokens = e.Usage.OutputTokens
+			data.ContextLength = e.Usage.ContextLength
+			data.ContextLimit = e.Usage.ContextLimit
+			data.Cost = e.Usage.Cost
+		}
+		return TypeTokenUsage, data, nil
+	case *runtime.SessionTitleEvent:
+		return TypeSessionTitle, SessionTitleEvent{SessionID: e.SessionID, Title: e.Title}, nil
+	case *runtime.ElicitationRequestEvent:
+		return TypeElicitationRequest, ElicitationRequestEvent{
+			Agent:         e.AgentName,
+			ElicitationID: e.ElicitationID,
+			Message:       e.Message,
+			Mode:          e.Mode,
+			Schema:        e.Schema,
+			URL:           e.URL,
+		}, nil
+	case *runtime.MaxIterationsReachedEvent:
+		return TypeMaxIterationsReached, MaxIterationsReachedEvent{MaxIterations: e.MaxIterations}, nil
+	default:
+		return passthrough(event)
+	}
+}
+
+// passthrough returns a passthrough event carrying a runtime event.
+func passthrough(event runtime.Event) (string, any, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	var base struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(payload, &base); err != nil {
+		return "", nil, fmt.Errorf("failed to read the type of event: %w", err)
+	}
+	return TypePassthrough, PassthroughEvent{Event: base.Type, Payload: payload}, nil
+}
+
+func convertToolCall(toolCall tools.ToolCall) ToolCall {
+	return ToolCall{ID: toolCall.ID, Name: toolCall.Function.Name, Arguments: toolCall.Function.Arguments}
+}
+
+func convertTool(tool tools.Tool) Tool {
+	return Tool{Name: tool.Name, Category: tool.Category, Description: tool.Description}
+}
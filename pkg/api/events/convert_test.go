@@ -0,0 +1,122 @@
+package events
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/runtime"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+func TestFromRuntime(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	toolCall := tools.ToolCall{ID: "call_1", Function: tools.FunctionCall{Name: "edit_file", Arguments: `{"path":"a.md"}`}}
+	tool := tools.Tool{Name: "edit_file", Category: "filesystem", Parameters: map[string]any{"type": "object"}}
+
+	tests := []struct {
+		name     string
+		event    runtime.Event
+		wantType string
+		wantData any
+	}{
+		{
+			name:     "agent choice",
+			event:    runtime.AgentChoice("root", "sess", "Hi"),
+			wantType: TypeAgentChoice,
+			wantData: AgentChoiceEvent{SessionID: "sess", Agent: "root", Content: "Hi"},
+		},
+		{
+			name: "message completed",
+			event: runtime.AgentMessageCompleted("root", "sess", "Done", "", []tools.ToolCall{toolCall}, chat.FinishReasonToolCalls,
+				&chat.Usage{InputTokens: 10, OutputTokens: 2}, nil),
+			wantType: TypeAgentMessageCompleted,
+			wantData: AgentMessageCompletedEvent{
+				SessionID:    "sess",
+				Agent:        "root",
+				Content:      "Done",
+				ToolCalls:    []ToolCall{{ID: "call_1", Name: "edit_file", Arguments: `{"path":"a.md"}`}},
+				FinishReason: "tool_calls",
+				Usage:        &Usage{InputTokens: 10, OutputTokens: 2},
+			},
+		},
+		{
+			name:     "tool call confirmation",
+			event:    runtime.ToolCallConfirmation(toolCall, tool, &tools.Preview{Diff: "+x"}, "root", runtime.ResumeTypeApprove, runtime.ResumeTypeReject),
+			wantType: TypeToolCallConfirmation,
+			wantData: ToolCallConfirmationEvent{
+				Agent:    "root",
+				ToolCall: ToolCall{ID: "call_1", Name: "edit_file", Arguments: `{"path":"a.md"}`},
+				Tool:     Tool{Name: "edit_file", Category: "filesystem"},
+				Options:  []string{"approve", "reject"},
+				Preview:  &Preview{Diff: "+x"},
+			},
+		},
+		{
+			name:     "tool call response",
+			event:    runtime.ToolCallResponse("call_1", tool, tools.ResultError("no such file"), "no such file", "root"),
+			wantType: TypeToolCallResponse,
+			wantData: ToolCallResponseEvent{Agent: "root", ToolCallID: "call_1", Tool: Tool{Name: "edit_file", Category: "filesystem"}, Output: "no such file", IsError: true},
+		},
+		{
+			name:     "error",
+			event:    runtime.ErrorWithCode(runtime.ErrorCodeProviderAuthFailed, "invalid API key", "Set the key"),
+			wantType: TypeError,
+			wantData: ProblemEvent{Message: "invalid API key", Code: "provider.auth_failed", Component: "model", Hint: "Set the key"},
+		},
+		{
+			name:     "token usage",
+			event:    runtime.NewTokenUsageEvent("sess", "root", &runtime.Usage{InputTokens: 10, OutputTokens: 2, ContextLength: 12, ContextLimit: 100, Cost: 0.1}),
+			wantType: TypeTokenUsage,
+			wantData: TokenUsageEvent{SessionID: "sess", Agent: "root", InputTokens: 10, OutputTokens: 2, ContextLength: 12, ContextLimit: 100, Cost: 0.1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envelope, err := FromRuntime(tt.event, V1, now)
+			require.NoError(t, err)
+
+			assert.Equal(t, V1, envelope.V)
+			assert.Equal(t, tt.wantType, envelope.Type)
+			assert.Equal(t, tt.wantData, envelope.Data)
+		})
+	}
+}
+
+func TestFromRuntime_Timestamp(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// The time of the event is kept.
+	event := runtime.AgentChoice("root", "sess", "Hi")
+	envelope, err := FromRuntime(event, V1, now)
+	require.NoError(t, err)
+	assert.Equal(t, event.(*runtime.AgentChoiceEvent).Timestamp, envelope.TS)
+
+	// Events without a time get the current one.
+	envelope, err = FromRuntime(runtime.SessionTitle("sess", "Greetings"), V1, now)
+	require.NoError(t, err)
+	assert.Equal(t, now, envelope.TS)
+}
+
+func TestFromRuntime_Passthrough(t *testing.T) {
+	envelope, err := FromRuntime(runtime.AgentThought("root", "sess", "Hmm"), V1, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, TypePassthrough, envelope.Type)
+
+	data, ok := envelope.Data.(PassthroughEvent)
+	require.True(t, ok)
+	assert.Equal(t, "agent_thought", data.Event)
+
+	var payload map[string]any
+	require.NoError(t, json.Unmarshal(data.Payload, &payload))
+	assert.Equal(t, "Hmm", payload["thought"])
+}
+
+func TestFromRuntime_UnsupportedVersion(t *testing.T) {
+	_, err := FromRuntime(runtime.AgentChoice("root", "sess", "Hi"), 2, time.Now())
+	require.ErrorIs(t, err, ErrUnsupportedVersion)
+}
@@ -0,0 +1,237 @@
+// Package events defines the wire format of the events the API server
+// streams to its clients, decoupled from the events of the runtime.
+//
+// Each event is sent in an Envelope telling the version of the format, the
+// type of the event and when it happened. The data of each type is an
+// explicit struct of this package, so that the runtime can change its own
+// events without breaking the clients. A version is frozen once published:
+// fields may only be added to it, and other changes need a new version.
+package events
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// V1 is the first version of the wire format.
+const V1 = 1
+
+// Latest is the latest version of the wire format.
+const Latest = V1
+
+// Envelope wraps the data of an event.
+type Envelope struct {
+	// V is the version of the wire format.
+	V int `json:"v"`
+	// Type is the type of the event, telling the type of Data.
+	Type string `json:"type"`
+	// TS is the time of the event.
+	TS time.Time `json:"ts"`
+	// Data holds the fields of the event.
+	Data any `json:"data"`
+}
+
+// Event types of version 1.
+const (
+	TypeUserMessage           = "user_message"
+	TypeStreamStarted         = "stream_started"
+	TypeStreamStopped         = "stream_stopped"
+	TypeAgentChoice           = "agent_choice"
+	TypeAgentChoiceReasoning  = "agent_choice_reasoning"
+	TypeAgentMessageCompleted = "agent_message_completed"
+	TypeToolCall              = "tool_call"
+	TypeToolCallConfirmation  = "tool_call_confirmation"
+	TypeToolCallResponse      = "tool_call_response"
+	TypeFileChanged           = "file_changed"
+	TypeError                 = "error"
+	TypeWarning               = "warning"
+	TypeTokenUsage            = "token_usage"
+	TypeSessionTitle          = "session_title"
+	TypeElicitationRequest    = "elicitation_request"
+	TypeMaxIterationsReached  = "max_iterations_reached"
+	TypePassthrough           = "passthrough"
+)
+
+// v1Events maps the event types of version 1 to their data.
+var v1Events = map[string]any{
+	TypeUserMessage:           UserMessageEvent{},
+	TypeStreamStarted:         StreamEvent{},
+	TypeStreamStopped:         StreamEvent{},
+	TypeAgentChoice:           AgentChoiceEvent{},
+	TypeAgentChoiceReasoning:  AgentChoiceEvent{},
+	TypeAgentMessageCompleted: AgentMessageCompletedEvent{},
+	TypeToolCall:              ToolCallEvent{},
+	TypeToolCallConfirmation:  ToolCallConfirmationEvent{},
+	TypeToolCallResponse:      ToolCallResponseEvent{},
+	TypeFileChanged:           FileChangedEvent{},
+	TypeError:                 ProblemEvent{},
+	TypeWarning:               ProblemEvent{},
+	TypeTokenUsage:            TokenUsageEvent{},
+	TypeSessionTitle:          SessionTitleEvent{},
+	TypeElicitationRequest:    ElicitationRequestEvent{},
+	TypeMaxIterationsReached:  MaxIterationsReachedEvent{},
+	TypePassthrough:           PassthroughEvent{},
+}
+
+// versions maps the versions of the wire format to their event types.
+var versions = map[int]map[string]any{
+	V1: v1Events,
+}
+
+// UserMessageEvent is the data of a user_message event: a message of the
+// user was added to the session.
+type UserMessageEvent struct {
+	SessionID string `json:"session_id"`
+	Message   string `json:"message"`
+}
+
+// StreamEvent is the data of the stream_started and stream_stopped events,
+// sent when an agent starts and stops working on a session.
+type StreamEvent struct {
+	SessionID string `json:"session_id,omitempty"`
+	Agent     string `json:"agent,omitempty"`
+}
+
+// AgentChoiceEvent is the data of the agent_choice and
+// agent_choice_reasoning events: a part of the text, or of the reasoning,
+// of an answer being streamed.
+type AgentChoiceEvent struct {
+	SessionID string `json:"session_id,omitempty"`
+	Agent     string `json:"agent,omitempty"`
+	Content   string `json:"content"`
+}
+
+// AgentMessageCompletedEvent is the data of an agent_message_completed
+// event, sent once an answer has been fully streamed.
+type AgentMessageCompletedEvent struct {
+	SessionID        string     `json:"session_id,omitempty"`
+	Agent            string     `json:"agent,omitempty"`
+	Content          string     `json:"content"`
+	ReasoningContent string     `json:"reasoning_content,omitempty"`
+	ToolCalls        []ToolCall `json:"tool_calls,omitempty"`
+	FinishReason     string     `json:"finish_reason,omitempty"`
+	Usage            *Usage     `json:"usage,omitempty"`
+}
+
+// ToolCallEvent is the data of a tool_call event: the agent calls a tool.
+type ToolCallEvent struct {
+	Agent    string   `json:"agent,omitempty"`
+	ToolCall ToolCall `json:"tool_call"`
+	Tool     Tool     `json:"tool"`
+}
+
+// ToolCallConfirmationEvent is the data of a tool_call_confirmation event:
+// a tool call waits for the user to answer with one of the options.
+type ToolCallConfirmationEvent struct {
+	Agent    string   `json:"agent,omitempty"`
+	ToolCall ToolCall `json:"tool_call"`
+	Tool     Tool     `json:"tool"`
+	Options  []string `json:"options,omitempty"`
+	Preview  *Preview `json:"preview,omitempty"`
+}
+
+// ToolCallResponseEvent is the data of a tool_call_response event: the
+// result of a tool call.
+type ToolCallResponseEvent struct {
+	Agent      string `json:"agent,omitempty"`
+	ToolCallID string `json:"tool_call_id"`
+	Tool       Tool   `json:"tool"`
+	Output     string `json:"output"`
+	IsError    bool   `json:"is_error,omitempty"`
+}
+
+// FileChangedEvent is the data of a file_changed event: a tool created,
+// modified, deleted or renamed a file.
+type FileChangedEvent struct {
+	SessionID  string `json:"session_id,omitempty"`
+	Agent      string `json:"agent,omitempty"`
+	ToolCallID string `json:"tool_call_id"`
+	Path       string `json:"path"`
+	Change     string `json:"change"`
+	OldPath    string `json:"old_path,omitempty"`
+}
+
+// ProblemEvent is the data of the error and warning events. Code and
+// Component classify the problem, and are empty when it isn't classified.
+type ProblemEvent struct {
+	Agent     string `json:"agent,omitempty"`
+	Message   string `json:"message"`
+	Code      string `json:"code,omitempty"`
+	Component string `json:"component,omitempty"`
+	Hint      string `json:"hint,omitempty"`
+}
+
+// TokenUsageEvent is the data of a token_usage event: the size of the
+// context of a session and what it cost so far, in dollars.
+type TokenUsageEvent struct {
+	SessionID     string  `json:"session_id"`
+	Agent         string  `json:"agent,omitempty"`
+	InputTokens   int64   `json:"input_tokens"`
+	OutputTokens  int64   `json:"output_tokens"`
+	ContextLength int64   `json:"context_length"`
+	ContextLimit  int64   `json:"context_limit"`
+	Cost          float64 `json:"cost"`
+}
+
+// SessionTitleEvent is the data of a session_title event: the session got
+// a title.
+type SessionTitleEvent struct {
+	SessionID string `json:"session_id"`
+	Title     string `json:"title"`
+}
+
+// ElicitationRequestEvent is the data of an elicitation_request event: a
+// tool asks the user for input, with a form described by Schema or at URL.
+type ElicitationRequestEvent struct {
+	Agent         string `json:"agent,omitempty"`
+	ElicitationID string `json:"elicitation_id,omitempty"`
+	Message       string `json:"message"`
+	Mode          string `json:"mode,omitempty"`
+	Schema        any    `json:"schema,omitempty"`
+	URL           string `json:"url,omitempty"`
+}
+
+// MaxIterationsReachedEvent is the data of a max_iterations_reached event:
+// the agent stopped after its maximum number of iterations.
+type MaxIterationsReachedEvent struct {
+	MaxIterations int `json:"max_iterations"`
+}
+
+// PassthroughEvent is the data of a passthrough event, carrying a runtime
+// event the wire format has no type for. Payload is the runtime event as
+// is: unlike the other types, its shape isn't stable across releases.
+type PassthroughEvent struct {
+	Event   string          `json:"event"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// ToolCall is a call of a tool by the model. Arguments is the JSON object
+// of the arguments of the call.
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// Tool describes a tool.
+type Tool struct {
+	Name        string `json:"name"`
+	Category    string `json:"category,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Preview shows what a tool call would change: a unified diff of the files,
+// or a summary in words.
+type Preview struct {
+	Diff    string `json:"diff,omitempty"`
+	Summary string `json:"summary,omitempty"`
+}
+
+// Usage is the token usage of a model call.
+type Usage struct {
+	InputTokens       int64 `json:"input_tokens"`
+	OutputTokens      int64 `json:"output_tokens"`
+	CachedInputTokens int64 `json:"cached_input_tokens,omitempty"`
+	CacheWriteTokens  int64 `json:"cache_write_tokens,omitempty"`
+	ReasoningTokens   int64 `json:"reasoning_tokens,omitempty"`
+}
@@ -0,0 +1,108 @@
+package events
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/golden"
+)
+
+// v1Fixtures returns an envelope of each event type of version 1, with all
+// their fields set.
+func v1Fixtures() []Envelope {
+	ts := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	toolCall := ToolCall{ID: "call_1", Name: "read_file", Arguments: `{"path":"README.md"}`}
+	tool := Tool{Name: "read_file", Category: "filesystem", Description: "Read a file"}
+
+	return []Envelope{
+		{V1, TypeUserMessage, ts, UserMessageEvent{SessionID: "sess", Message: "Hello"}},
+		{V1, TypeStreamStarted, ts, StreamEvent{SessionID: "sess", Agent: "root"}},
+		{V1, TypeStreamStopped, ts, StreamEvent{SessionID: "sess", Agent: "root"}},
+		{V1, TypeAgentChoice, ts, AgentChoiceEvent{SessionID: "sess", Agent: "root", Content: "Hi"}},
+		{V1, TypeAgentChoiceReasoning, ts, AgentChoiceEvent{SessionID: "sess", Agent: "root", Content: "Thinking"}},
+		{V1, TypeAgentMessageCompleted, ts, AgentMessageCompletedEvent{
+			SessionID:        "sess",
+			Agent:            "root",
+			Content:          "Hi",
+			ReasoningContent: "Thinking",
+			ToolCalls:        []ToolCall{toolCall},
+			FinishReason:     "tool_calls",
+			Usage:            &Usage{InputTokens: 100, OutputTokens: 20, CachedInputTokens: 50, CacheWriteTokens: 10, ReasoningTokens: 5},
+		}},
+		{V1, TypeToolCall, ts, ToolCallEvent{Agent: "root", ToolCall: toolCall, Tool: tool}},
+		{V1, TypeToolCallConfirmation, ts, ToolCallConfirmationEvent{
+			Agent:    "root",
+			ToolCall: toolCall,
+			Tool:     tool,
+			Options:  []string{"approve", "reject"},
+			Preview:  &Preview{Diff: "--- a\n+++ b\n", Summary: "Edits a"},
+		}},
+		{V1, TypeToolCallResponse, ts, ToolCallResponseEvent{Agent: "root", ToolCallID: "call_1", Tool: tool, Output: "# Project", IsError: true}},
+		{V1, TypeFileChanged, ts, FileChangedEvent{SessionID: "sess", Agent: "root", ToolCallID: "call_1", Path: "b.md", Change: "renamed", OldPath: "a.md"}},
+		{V1, TypeError, ts, ProblemEvent{Agent: "root", Message: "invalid API key", Code: "provider.auth_failed", Component: "model", Hint: "Set OPENAI_API_KEY"}},
+		{V1, TypeWarning, ts, ProblemEvent{Agent: "root", Message: "toolset failed", Code: "toolset.start_failed", Component: "tool", Hint: "Check the command"}},
+		{V1, TypeTokenUsage, ts, TokenUsageEvent{SessionID: "sess", Agent: "root", InputTokens: 100, OutputTokens: 20, ContextLength: 120, ContextLimit: 128000, Cost: 0.5}},
+		{V1, TypeSessionTitle, ts, SessionTitleEvent{SessionID: "sess", Title: "Greetings"}},
+		{V1, TypeElicitationRequest, ts, ElicitationRequestEvent{
+			Agent:         "root",
+			ElicitationID: "elicit_1",
+			Message:       "Pick a color",
+			Mode:          "form",
+			Schema:        map[string]any{"type": "object"},
+			URL:           "https://example.com",
+		}},
+		{V1, TypeMaxIterationsReached, ts, MaxIterationsReachedEvent{MaxIterations: 10}},
+		{V1, TypePassthrough, ts, PassthroughEvent{Event: "agent_thought", Payload: json.RawMessage(`{"type":"agent_thought","thought":"Hmm"}`)}},
+	}
+}
+
+func TestV1FixturesCoverAllTypes(t *testing.T) {
+	var types []string
+	for _, envelope := range v1Fixtures() {
+		types = append(types, envelope.Type)
+	}
+	assert.ElementsMatch(t, slices.Collect(maps.Keys(v1Events)), types)
+}
+
+// TestV1WireFormat fails when the marshaling of version 1 changes. Version 1
+// is frozen: only add fields to it, and regenerate the golden file with
+// -update. Other changes need a new version.
+func TestV1WireFormat(t *testing.T) {
+	var b strings.Builder
+	for _, envelope := range v1Fixtures() {
+		line, err := json.Marshal(envelope)
+		require.NoError(t, err)
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	golden.Assert(t, b.String(), "v1_events.golden")
+}
+
+func TestV1Schema(t *testing.T) {
+	schema, err := Schema(V1)
+	require.NoError(t, err)
+
+	content, err := json.MarshalIndent(schema, "", "  ")
+	require.NoError(t, err)
+	golden.Assert(t, string(content)+"\n", "v1.schema.json")
+
+	// Every event of version 1 matches the schema.
+	resolved, err := schema.Resolve(nil)
+	require.NoError(t, err)
+	for _, envelope := range v1Fixtures() {
+		line, err := json.Marshal(envelope)
+		require.NoError(t, err)
+		var instance any
+		require.NoError(t, json.Unmarshal(line, &instance))
+		assert.NoError(t, resolved.Validate(instance), envelope.Type)
+	}
+
+	_, err = Schema(2)
+	require.ErrorIs(t, err, ErrUnsupportedVersion)
+}
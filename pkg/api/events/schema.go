@@ -0,0 +1,72 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// Schema returns the JSON Schema of the envelopes of version v of the wire
+// format: one of the event types, each with the schema of its data. The
+// objects accept properties the schema doesn't list, since fields may be
+// added to a version.
+func Schema(v int) (*jsonschema.Schema, error) {
+	types, ok := versions[v]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, v)
+	}
+
+	opts := &jsonschema.ForOptions{
+		TypeSchemas: map[reflect.Type]*jsonschema.Schema{
+			// The payload of passthrough events can be any JSON value.
+			reflect.TypeFor[json.RawMessage](): {},
+		},
+	}
+
+	schema := &jsonschema.Schema{
+		Schema: "https://json-schema.org/draft/2020-12/schema",
+		Title:  fmt.Sprintf("docker-agent events v%d", v),
+		Defs:   map[string]*jsonschema.Schema{},
+	}
+	for _, name := range slices.Sorted(maps.Keys(types)) {
+		dataType := reflect.TypeOf(types[name])
+		if _, ok := schema.Defs[dataType.Name()]; !ok {
+			data, err := jsonschema.ForType(dataType, opts)
+			if err != nil {
+				return nil, fmt.Errorf("schema of %s: %w", name, err)
+			}
+			openObjects(data)
+			schema.Defs[dataType.Name()] = data
+		}
+
+		schema.OneOf = append(schema.OneOf, &jsonschema.Schema{
+			Type: "object",
+			Properties: map[string]*jsonschema.Schema{
+				"v":    {Const: new(any(v))},
+				"type": {Const: new(any(name))},
+				"ts":   {Type: "string", Format: "date-time"},
+				"data": {Ref: "#/$defs/" + dataType.Name()},
+			},
+			Required:      []string{"v", "type", "ts", "data"},
+			PropertyOrder: []string{"v", "type", "ts", "data"},
+		})
+	}
+	return schema, nil
+}
+
+// openObjects lets the objects described by s, and by its properties and
+// items, have properties s doesn't list.
+func openObjects(s *jsonschema.Schema) {
+	if s == nil || s.Properties == nil {
+		return
+	}
+	s.AdditionalProperties = nil
+	for _, property := range s.Properties {
+		openObjects(property)
+		openObjects(property.Items)
+	}
+}
@@ -0,0 +1,46 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedVersion is returned for a version of the wire format that
+// doesn't exist.
+var ErrUnsupportedVersion = errors.New("unsupported version of the events wire format")
+
+// ParseVersion parses a version of the wire format, e.g. "1" or "v1".
+func ParseVersion(s string) (int, error) {
+	v, err := strconv.Atoi(strings.TrimPrefix(s, "v"))
+	if err != nil || versions[v] == nil {
+		return 0, fmt.Errorf("%w: %q, the latest version is %d", ErrUnsupportedVersion, s, Latest)
+	}
+	return v, nil
+}
+
+// NegotiateVersion returns the version of the wire format a client asks for,
+// with the v query parameter of its request or the version parameter of the
+// text/event-stream media type it accepts, e.g.
+// "Accept: text/event-stream; version=1". The query parameter wins. It
+// returns 0 when the client asks for no version: the runtime events are
+// then streamed as they are, in a format that isn't stable.
+func NegotiateVersion(r *http.Request) (int, error) {
+	if v := r.URL.Query().Get("v"); v != "" {
+		return ParseVersion(v)
+	}
+
+	for accept := range strings.SplitSeq(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil || mediaType != "text/event-stream" {
+			continue
+		}
+		if v, ok := params["version"]; ok {
+			return ParseVersion(v)
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,47 @@
+package events
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateVersion(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		accept string
+		want   int
+	}{
+		{name: "none", target: "/", accept: "text/event-stream"},
+		{name: "query", target: "/?v=1", want: V1},
+		{name: "query with prefix", target: "/?v=v1", want: V1},
+		{name: "accept", target: "/", accept: "application/json, text/event-stream; version=1", want: V1},
+		{name: "query wins", target: "/?v=1", accept: "text/event-stream; version=7", want: V1},
+		{name: "other media type", target: "/", accept: "application/json; version=7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.target, http.NoBody)
+			req.Header.Set("Accept", tt.accept)
+
+			v, err := NegotiateVersion(req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, v)
+		})
+	}
+}
+
+func TestNegotiateVersion_Unsupported(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/?v=2", http.NoBody)
+	_, err := NegotiateVersion(req)
+	require.ErrorIs(t, err, ErrUnsupportedVersion)
+
+	req = httptest.NewRequest(http.MethodPost, "/", http.NoBody)
+	req.Header.Set("Accept", "text/event-stream; version=latest")
+	_, err = NegotiateVersion(req)
+	require.ErrorIs(t, err, ErrUnsupportedVersion)
+}
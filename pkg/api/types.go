@@ -1,6 +1,7 @@
 package api
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/docker/docker-agent/pkg/chat"
@@ -147,6 +148,9 @@ type ResumeSessionRequest struct {
 	Confirmation string `json:"confirmation"`
 	Reason       string `json:"reason,omitempty"`    // e.g reason for tool call rejection
 	ToolName     string `json:"tool_name,omitempty"` // tool name for approve-tool confirmation
+	// Arguments replace the arguments of the tool call for an
+	// approve-edited confirmation.
+	Arguments json.RawMessage `json:"arguments,omitempty"`
 }
 
 // DesktopTokenResponse represents the response from getting a desktop token
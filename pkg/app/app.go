@@ -9,6 +9,7 @@ import (
 	"log/slog"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"slices"
 	"strings"
 	"sync/atomic"
@@ -128,6 +129,17 @@ func New(ctx context.Context, rt runtime.Runtime, sess *session.Session, opts ..
 		})
 	}
 
+	// Show the outcome of agent configuration reloads, which happen
+	// between turns.
+	if cr, ok := rt.(runtime.ConfigReloader); ok {
+		cr.OnConfigReload(func(event runtime.Event) {
+			select {
+			case app.events <- event:
+			case <-ctx.Done():
+			}
+		})
+	}
+
 	return app
 }
 
@@ -261,6 +273,11 @@ func (a *App) CurrentMCPPrompts(ctx context.Context) map[string]mcptools.PromptI
 	return a.runtime.CurrentMCPPrompts(ctx)
 }
 
+// CurrentMCPResources returns the available MCP resources for the active agent
+func (a *App) CurrentMCPResources(ctx context.Context) map[string]mcptools.ResourceInfo {
+	return a.runtime.CurrentMCPResources(ctx)
+}
+
 // ExecuteMCPPrompt executes an MCP prompt with provided arguments and returns the content
 func (a *App) ExecuteMCPPrompt(ctx context.Context, promptName string, arguments map[string]string) (string, error) {
 	return a.runtime.ExecuteMCPPrompt(ctx, promptName, arguments)
@@ -532,14 +549,16 @@ func (a *App) SubscribeWith(ctx context.Context, send func(tea.Msg)) {
 	}
 }
 
-// Resume resumes the runtime with the given confirmation request
+// Resume resumes the session with the given confirmation request
 func (a *App) Resume(req runtime.ResumeRequest) {
-	a.runtime.Resume(context.Background(), req)
+	if err := a.runtime.Resume(context.Background(), a.session.ID, req); err != nil {
+		slog.Debug("Failed to resume session", "session_id", a.session.ID, "error", err)
+	}
 }
 
-// ResumeElicitation resumes an elicitation request with the given action and content
+// ResumeElicitation resumes an elicitation request of the session with the given action and content
 func (a *App) ResumeElicitation(ctx context.Context, action tools.ElicitationAction, content map[string]any) error {
-	return a.runtime.ResumeElicitation(ctx, action, content)
+	return a.runtime.ResumeElicitation(ctx, a.session.ID, action, content)
 }
 
 func (a *App) NewSession() {
@@ -790,6 +809,20 @@ func (a *App) SupportsModelSwitching() bool {
 	return ok
 }
 
+// ErrCostEstimateUnsupported is returned by EstimateNextTurnCost when the
+// runtime can't estimate costs, e.g. remote runtimes.
+var ErrCostEstimateUnsupported = errors.New("cost estimates are not supported by this runtime")
+
+// EstimateNextTurnCost estimates the cost of the next turn of the session if
+// draft was sent now.
+func (a *App) EstimateNextTurnCost(ctx context.Context, draft string) (session.CostEstimate, error) {
+	estimator, ok := a.runtime.(runtime.TurnCostEstimator)
+	if !ok {
+		return session.CostEstimate{}, ErrCostEstimateUnsupported
+	}
+	return estimator.EstimateNextTurnCost(ctx, a.session, draft)
+}
+
 // ShouldExitAfterFirstResponse returns true if the app is configured to exit
 // after the first assistant response completes.
 func (a *App) ShouldExitAfterFirstResponse() bool {
@@ -817,6 +850,62 @@ func (a *App) CompactSession(ctx context.Context, additionalPrompt string) {
 	}()
 }
 
+// ErrCheckpointsUnsupported is returned by CreateCheckpoint and
+// RollbackSession when the runtime doesn't implement runtime.Checkpointer.
+var ErrCheckpointsUnsupported = errors.New("checkpoints are not supported by this runtime")
+
+// CreateCheckpoint tags the current point of the session as a checkpoint.
+// label is optional.
+func (a *App) CreateCheckpoint(ctx context.Context, label string) (session.Checkpoint, error) {
+	checkpointer, ok := a.runtime.(runtime.Checkpointer)
+	if !ok {
+		return session.Checkpoint{}, ErrCheckpointsUnsupported
+	}
+
+	events := make(chan runtime.Event, 10)
+	checkpoint, err := checkpointer.Checkpoint(ctx, a.session, label, events)
+	close(events)
+	a.forwardEvents(ctx, events)
+	return checkpoint, err
+}
+
+// RollbackSession rolls the session back to the checkpoint ref, an ID or a
+// label, or to the latest checkpoint if ref is empty. With revertFiles, the
+// files changed since the checkpoint are restored too.
+func (a *App) RollbackSession(ctx context.Context, ref string, revertFiles bool) error {
+	checkpointer, ok := a.runtime.(runtime.Checkpointer)
+	if !ok {
+		return ErrCheckpointsUnsupported
+	}
+
+	checkpoint, ok := a.session.FindCheckpoint(ref)
+	if !ok {
+		if ref == "" {
+			return fmt.Errorf("no checkpoint yet: %w", session.ErrCheckpointNotFound)
+		}
+		return fmt.Errorf("checkpoint %q: %w", ref, session.ErrCheckpointNotFound)
+	}
+
+	events := make(chan runtime.Event, 10)
+	err := checkpointer.Rollback(ctx, a.session, checkpoint.ID, revertFiles, events)
+	close(events)
+	a.forwardEvents(ctx, events)
+	return err
+}
+
+// forwardEvents sends the buffered events to the UI without blocking the
+// caller, which may be the UI itself.
+func (a *App) forwardEvents(ctx context.Context, events <-chan runtime.Event) {
+	go func() {
+		for event := range events {
+			if ctx.Err() != nil {
+				return
+			}
+			a.sendEvent(ctx, event)
+		}
+	}()
+}
+
 func (a *App) PlainTextTranscript() string {
 	return transcript.PlainText(a.session)
 }
@@ -827,6 +916,13 @@ func (a *App) SessionStore() session.Store {
 	return a.runtime.SessionStore()
 }
 
+// ForgetSession drops what the runtime keeps about a deleted session.
+func (a *App) ForgetSession(sessionID string) {
+	if forgetter, ok := a.runtime.(interface{ ForgetSession(sessionID string) }); ok {
+		forgetter.ForgetSession(sessionID)
+	}
+}
+
 // ReplaceSession replaces the current session with the given session.
 // This is used when loading a past session. It also re-emits startup info
 // so the sidebar displays the agent and tool information.
@@ -993,6 +1089,10 @@ func (a *App) mergeEvents(events []tea.Msg) []tea.Msg {
 			latest := ev
 			for i+1 < len(events) {
 				if next, ok := events[i+1].(*runtime.PartialToolCallEvent); ok && next.ToolCall.ID == ev.ToolCall.ID {
+					preview := next.ArgumentsPreview
+					if preview == nil {
+						preview = latest.ArgumentsPreview
+					}
 					latest = &runtime.PartialToolCallEvent{
 						Type: ev.Type,
 						ToolCall: tools.ToolCall{
@@ -1003,8 +1103,9 @@ func (a *App) mergeEvents(events []tea.Msg) []tea.Msg {
 								Arguments: latest.ToolCall.Function.Arguments + next.ToolCall.Function.Arguments,
 							},
 						},
-						ToolDefinition: cmp.Or(latest.ToolDefinition, next.ToolDefinition),
-						AgentContext:   ev.AgentContext,
+						ToolDefinition:   cmp.Or(latest.ToolDefinition, next.ToolDefinition),
+						ArgumentsPreview: preview,
+						AgentContext:     ev.AgentContext,
 					}
 					i++
 				} else {
@@ -1029,6 +1130,28 @@ func (a *App) ExportHTML(ctx context.Context, filename string) (string, error) {
 	return export.SessionToFile(a.session, agentInfo.Description, filename)
 }
 
+// ExportTranscript writes the session to filename as a Markdown or JSON
+// transcript and returns the absolute path of the file.
+func (a *App) ExportTranscript(filename string, format session.ExportFormat) (string, error) {
+	if a.session == nil {
+		return "", errors.New("no session to export")
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	if err := a.session.Export(f, format); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return filepath.Abs(filename)
+}
+
 // ErrTitleGenerating is returned when attempting to set a title while generation is in progress.
 var ErrTitleGenerating = errors.New("title generation in progress, please wait")
 
@@ -1124,10 +1247,10 @@ func (a *App) RegenerateSessionTitle(ctx context.Context) error {
 	if a.titleGen != nil {
 		a.titleGenerating.Store(true)
 
-		// Collect user messages for title generation
+		// Collect user messages for title generation, not the system reminders
 		var userMessages []string
 		for _, msg := range a.session.GetAllMessages() {
-			if msg.Message.Role == chat.MessageRoleUser {
+			if msg.Message.Role == chat.MessageRoleUser && !msg.IsSystemReminder() {
 				userMessages = append(userMessages, msg.Message.Content)
 			}
 		}
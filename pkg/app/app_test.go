@@ -2,12 +2,17 @@ package app
 
 import (
 	"context"
+	"io"
+	"sync"
 	"testing"
+	"time"
 
 	tea "charm.land/bubbletea/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/model/provider/base"
 	"github.com/docker/docker-agent/pkg/runtime"
 	"github.com/docker/docker-agent/pkg/session"
 	"github.com/docker/docker-agent/pkg/sessiontitle"
@@ -40,8 +45,17 @@ func (m *mockRuntime) RunStream(ctx context.Context, sess *session.Session) <-ch
 func (m *mockRuntime) Run(ctx context.Context, sess *session.Session) ([]session.Message, error) {
 	return nil, nil
 }
-func (m *mockRuntime) Resume(ctx context.Context, req runtime.ResumeRequest) {}
-func (m *mockRuntime) ResumeElicitation(ctx context.Context, action tools.ElicitationAction, content map[string]any) error {
+func (m *mockRuntime) ContinueStream(ctx context.Context, sess *session.Session, userMessage string) <-chan runtime.Event {
+	return m.RunStream(ctx, sess)
+}
+
+func (m *mockRuntime) Continue(ctx context.Context, sess *session.Session, userMessage string) ([]session.Message, error) {
+	return nil, nil
+}
+func (m *mockRuntime) Resume(ctx context.Context, sessionID string, req runtime.ResumeRequest) error {
+	return nil
+}
+func (m *mockRuntime) ResumeElicitation(ctx context.Context, sessionID string, action tools.ElicitationAction, content map[string]any) error {
 	return nil
 }
 func (m *mockRuntime) SessionStore() session.Store { return nil }
@@ -56,6 +70,10 @@ func (m *mockRuntime) CurrentMCPPrompts(context.Context) map[string]mcptools.Pro
 	return make(map[string]mcptools.PromptInfo)
 }
 
+func (m *mockRuntime) CurrentMCPResources(context.Context) map[string]mcptools.ResourceInfo {
+	return make(map[string]mcptools.ResourceInfo)
+}
+
 func (m *mockRuntime) ExecuteMCPPrompt(context.Context, string, map[string]string) (string, error) {
 	return "", nil
 }
@@ -65,7 +83,7 @@ func (m *mockRuntime) UpdateSessionTitle(_ context.Context, sess *session.Sessio
 	return nil
 }
 func (m *mockRuntime) TitleGenerator() *sessiontitle.Generator { return nil }
-func (m *mockRuntime) Close() error                            { return nil }
+func (m *mockRuntime) Close(context.Context) error             { return nil }
 func (m *mockRuntime) Stop()                                   {}
 func (m *mockRuntime) Steer(_ runtime.QueuedMessage) error     { return nil }
 func (m *mockRuntime) FollowUp(_ runtime.QueuedMessage) error  { return nil }
@@ -280,3 +298,65 @@ func TestApp_RegenerateSessionTitle(t *testing.T) {
 		require.ErrorIs(t, err, ErrTitleGenerating)
 	})
 }
+
+// hangingTitleProvider starts title streams that only end when closed.
+type hangingTitleProvider struct {
+	started chan struct{}
+}
+
+func (p *hangingTitleProvider) ID() string { return "test/hanging" }
+
+func (p *hangingTitleProvider) CreateChatCompletionStream(context.Context, []chat.Message, []tools.Tool) (chat.MessageStream, error) {
+	close(p.started)
+	return &hangingStream{closed: make(chan struct{})}, nil
+}
+
+func (p *hangingTitleProvider) BaseConfig() base.Config { return base.Config{} }
+
+type hangingStream struct {
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (s *hangingStream) Recv() (chat.MessageStreamResponse, error) {
+	<-s.closed
+	return chat.MessageStreamResponse{}, io.ErrUnexpectedEOF
+}
+
+func (s *hangingStream) Close() { s.closeOnce.Do(func() { close(s.closed) }) }
+
+func TestApp_GenerateTitle_Cancelled(t *testing.T) {
+	t.Parallel()
+
+	prov := &hangingTitleProvider{started: make(chan struct{})}
+	sess := session.New()
+	app := &App{
+		runtime:  &mockRuntime{},
+		session:  sess,
+		events:   make(chan tea.Msg, 16),
+		titleGen: sessiontitle.New(prov),
+	}
+	app.titleGenerating.Store(true)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		app.generateTitle(ctx, []string{"hello"})
+	}()
+
+	<-prov.started
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("title generation should stop when cancelled")
+	}
+
+	// The session is left usable: no title, and it can be renamed.
+	assert.False(t, app.IsTitleGenerating())
+	assert.Empty(t, sess.Title)
+	require.NoError(t, app.UpdateSessionTitle(t.Context(), "Manual title"))
+	assert.Equal(t, "Manual title", sess.Title)
+}
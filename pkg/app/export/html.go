@@ -70,6 +70,7 @@ type Message struct {
 	ToolCalls        []ToolCall
 	AgentName        string
 	Implicit         bool
+	SystemReminder   bool
 }
 
 // ToolCall represents a tool invocation.
@@ -111,6 +112,7 @@ func sessionToData(sess *session.Session) SessionData {
 			ToolCalls:        toolCalls,
 			AgentName:        msg.AgentName,
 			Implicit:         msg.Implicit,
+			SystemReminder:   msg.IsSystemReminder(),
 		}
 	}
 	return SessionData{
@@ -378,6 +380,9 @@ func Generate(data SessionData) (string, error) {
 }
 
 func getSender(msg Message) string {
+	if msg.SystemReminder {
+		return "reminder"
+	}
 	if msg.Role == chat.MessageRoleUser {
 		return "you"
 	}
@@ -405,6 +410,9 @@ func formatCost(cost float64) string {
 }
 
 func renderMessage(msg Message, toolResults map[string]string, showLabel bool) (string, error) {
+	if msg.SystemReminder {
+		return renderSystemReminder(msg, showLabel)
+	}
 	switch msg.Role {
 	case chat.MessageRoleUser:
 		return renderUserMessage(msg, showLabel)
@@ -435,6 +443,27 @@ func renderUserMessage(msg Message, showLabel bool) (string, error) {
 	return buf.String(), nil
 }
 
+// renderSystemReminder renders a reminder injected for the model, muted to
+// set it apart from the user messages.
+func renderSystemReminder(msg Message, showLabel bool) (string, error) {
+	content := template.HTMLEscapeString(msg.Content)
+	content = strings.ReplaceAll(content, "\n", "<br>")
+
+	data := messageViewData{
+		IsUser:       true,
+		LabelName:    "reminder",
+		LabelClasses: "bg-tui-purple/5 text-tui-purple",
+		ShowLabel:    showLabel,
+		ContentHTML:  template.HTML(`<span class="text-muted-foreground">` + content + `</span>`), //nolint:gosec // Content is escaped above
+	}
+
+	var buf bytes.Buffer
+	if err := messageTemplate.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
 func renderAssistantMessage(msg Message, toolResults map[string]string, showLabel bool) (string, error) {
 	agentName := msg.AgentName
 	if agentName == "" {
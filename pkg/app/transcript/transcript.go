@@ -20,12 +20,14 @@ func PlainText(sess *session.Session) string {
 			continue
 		}
 
-		switch msg.Message.Role {
-		case chat.MessageRoleUser:
+		switch {
+		case msg.IsSystemReminder():
+			fmt.Fprintf(&builder, "\n## System reminder\n\n%s\n", msg.Message.Content)
+		case msg.Message.Role == chat.MessageRoleUser:
 			writeUserMessage(&builder, msg)
-		case chat.MessageRoleAssistant:
+		case msg.Message.Role == chat.MessageRoleAssistant:
 			writeAssistantMessage(&builder, msg)
-		case chat.MessageRoleTool:
+		case msg.Message.Role == chat.MessageRoleTool:
 			writeToolMessage(&builder, msg)
 		}
 	}
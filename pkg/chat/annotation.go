@@ -0,0 +1,42 @@
+package chat
+
+import "fmt"
+
+// Annotation attributes an assistant message to one of the sources it
+// draws on: a document chunk returned by a RAG tool during the turn, or a
+// web page cited by the built-in tools of the provider.
+type Annotation struct {
+	// Index is the 1-based index of the source among the sources of the
+	// turn, the one the model cites it with: [Index].
+	Index int `json:"index,omitempty"`
+	// RAGName is the name of the RAG source the chunk was retrieved from.
+	RAGName string `json:"rag_name,omitempty"`
+	// Path is the path of the document.
+	Path string `json:"path"`
+	// StartLine and EndLine are the 1-based lines of the chunk in the
+	// document, when they're known.
+	StartLine int `json:"start_line,omitempty"`
+	EndLine   int `json:"end_line,omitempty"`
+	// Score is the relevance of the chunk to the query it was retrieved for.
+	Score float64 `json:"score,omitempty"`
+	// URL and Title are the address and the title of a web page cited by
+	// the provider.
+	URL   string `json:"url,omitempty"`
+	Title string `json:"title,omitempty"`
+}
+
+// Location returns the path of the document, followed by the lines of the
+// chunk when they're known, e.g. "docs/install.md:10-24", or the URL of a
+// web page.
+func (a Annotation) Location() string {
+	switch {
+	case a.URL != "":
+		return a.URL
+	case a.StartLine == 0:
+		return a.Path
+	case a.EndLine <= a.StartLine:
+		return fmt.Sprintf("%s:%d", a.Path, a.StartLine)
+	default:
+		return fmt.Sprintf("%s:%d-%d", a.Path, a.StartLine, a.EndLine)
+	}
+}
@@ -78,6 +78,14 @@ type Message struct {
 	// IsError indicates the tool call failed (only for Role=tool messages).
 	IsError bool `json:"is_error,omitempty"`
 
+	// Truncation records how the tool output was truncated before being sent
+	// to the model (only for Role=tool messages).
+	Truncation *tools.OutputTruncation `json:"truncation,omitempty"`
+
+	// ArgumentsEdit records the arguments the user substituted for the ones
+	// of the tool call before it ran (only for Role=tool messages).
+	ArgumentsEdit *tools.ArgumentsEdit `json:"arguments_edit,omitempty"`
+
 	CreatedAt string `json:"created_at,omitempty"`
 
 	// Usage tracks token usage for this message (only set for assistant messages)
@@ -94,8 +102,17 @@ type Message struct {
 	// Only set for assistant messages.
 	FinishReason FinishReason `json:"finish_reason,omitempty"`
 
+	// Annotations lists the sources the message draws on (only set for
+	// assistant messages).
+	Annotations []Annotation `json:"annotations,omitempty"`
+
 	// CacheControl indicates whether this message is a cached message (only used by anthropic)
 	CacheControl bool `json:"cache_control,omitempty"`
+
+	// SystemReminder marks a user message injected into the conversation to
+	// remind the model of something, rather than written by the user. See
+	// SystemReminderMessage.
+	SystemReminder bool `json:"system_reminder,omitempty"`
 }
 
 // MessageFile represents a file attachment that can be uploaded to a provider's file storage.
@@ -135,6 +152,8 @@ type MessageDelta struct {
 	ThoughtSignature  []byte              `json:"thought_signature,omitempty"`
 	FunctionCall      *tools.FunctionCall `json:"function_call,omitempty"`
 	ToolCalls         []tools.ToolCall    `json:"tool_calls,omitempty"`
+	ProviderTools     []ProviderToolUse   `json:"provider_tools,omitempty"`
+	Annotations       []Annotation        `json:"annotations,omitempty"`
 }
 
 // MessageStreamChoice represents a choice in a streaming response
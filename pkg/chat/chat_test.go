@@ -208,3 +208,37 @@ func TestDetectMimeType_ContentSniffing(t *testing.T) {
 		})
 	}
 }
+
+func TestSystemReminders(t *testing.T) {
+	t.Parallel()
+
+	messages := []Message{
+		{Role: MessageRoleSystem, Content: "You are helpful"},
+		{Role: MessageRoleUser, Content: "hello"},
+		SystemReminderMessage("Keep it short"),
+		{Role: MessageRoleAssistant, Content: "hi"},
+	}
+
+	asSystem := SystemReminders(messages, true)
+	assert.Equal(t, MessageRoleSystem, asSystem[2].Role)
+	assert.Equal(t, "Keep it short", asSystem[2].Content)
+
+	asUser := SystemReminders(messages, false)
+	assert.Equal(t, MessageRoleUser, asUser[2].Role)
+	assert.Equal(t, "<system-reminder>\nKeep it short\n</system-reminder>", asUser[2].Content)
+
+	// The other messages keep their place and the input is left unchanged.
+	for _, mapped := range [][]Message{asSystem, asUser} {
+		require.Len(t, mapped, 4)
+		assert.Equal(t, messages[1], mapped[1])
+		assert.Equal(t, messages[3], mapped[3])
+	}
+	assert.Equal(t, SystemReminderMessage("Keep it short"), messages[2])
+}
+
+func TestSystemReminders_NoReminders(t *testing.T) {
+	t.Parallel()
+
+	messages := []Message{{Role: MessageRoleUser, Content: "hello"}}
+	assert.Same(t, &messages[0], &SystemReminders(messages, false)[0])
+}
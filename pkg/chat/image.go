@@ -10,6 +10,7 @@ import (
 	"image/png"
 	"log/slog"
 	"net/http"
+	"strings"
 
 	"golang.org/x/image/draw"
 	// Register WebP decoder for image.Decode.
@@ -218,6 +219,21 @@ func FormatDimensionNote(r *ImageResizeResult) string {
 		r.OriginalWidth, r.OriginalHeight, r.Width, r.Height, scaleX, scaleY)
 }
 
+// ImagePlaceholder returns the text standing for a base64-encoded image in
+// messages to models that can't see it, e.g. "[image returned: 1920x1080 png]".
+func ImagePlaceholder(b64Data, mimeType string) string {
+	format := strings.TrimPrefix(mimeType, "image/")
+	data, err := base64.StdEncoding.DecodeString(b64Data)
+	if err != nil {
+		return fmt.Sprintf("[image returned: %s]", format)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Sprintf("[image returned: %s]", format)
+	}
+	return fmt.Sprintf("[image returned: %dx%d %s]", cfg.Width, cfg.Height, format)
+}
+
 func abs(x float64) float64 {
 	if x < 0 {
 		return -x
@@ -195,3 +195,16 @@ func TestResizeImageBase64(t *testing.T) {
 	// Base64 result is returned separately
 	assert.Equal(t, b64, b64Result)
 }
+
+func TestImagePlaceholder(t *testing.T) {
+	t.Parallel()
+
+	b64 := base64.StdEncoding.EncodeToString(createTestPNG(t, 192, 108))
+	assert.Equal(t, "[image returned: 192x108 png]", ImagePlaceholder(b64, "image/png"))
+
+	b64 = base64.StdEncoding.EncodeToString(createTestJPEG(t, 10, 20))
+	assert.Equal(t, "[image returned: 10x20 jpeg]", ImagePlaceholder(b64, "image/jpeg"))
+
+	assert.Equal(t, "[image returned: png]", ImagePlaceholder("not base64!", "image/png"))
+	assert.Equal(t, "[image returned: png]", ImagePlaceholder(base64.StdEncoding.EncodeToString([]byte("garbage")), "image/png"))
+}
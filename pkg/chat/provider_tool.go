@@ -0,0 +1,19 @@
+package chat
+
+// ProviderToolUse describes the use of a built-in tool by the provider,
+// e.g. a web search, which runs on its side rather than as a tool call.
+type ProviderToolUse struct {
+	// ID identifies the use of the tool in the response of the provider.
+	ID string `json:"id,omitempty"`
+	// Tool is the name of the built-in tool, e.g. "web_search".
+	Tool string `json:"tool"`
+	// Queries are the search queries the provider issued.
+	Queries []string `json:"queries,omitempty"`
+	// URLs are the web pages the provider opened or found.
+	URLs []string `json:"urls,omitempty"`
+	// Code is the code the provider ran, and Output what it printed.
+	Code   string `json:"code,omitempty"`
+	Output string `json:"output,omitempty"`
+	// Error is the error the tool failed with, if any.
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,49 @@
+package chat
+
+// SystemReminderMessage returns a system reminder: a message injected into
+// the conversation, between turns, to tell the model something on behalf of
+// the runtime or the application rather than the user.
+//
+// It's a user message, so that it keeps its place in the conversation, until
+// providers send it with SystemReminders.
+func SystemReminderMessage(text string) Message {
+	return Message{
+		Role:           MessageRoleUser,
+		Content:        text,
+		SystemReminder: true,
+	}
+}
+
+// SystemReminders returns messages with their system reminders in the form
+// a provider accepts. APIs accepting system messages anywhere in the
+// conversation get them as system messages (systemRole). The others, which
+// move system messages before the conversation or only accept one, get them
+// as user messages with the text in <system-reminder> tags, so that the model
+// doesn't take them for the words of the user.
+//
+// messages is left unchanged.
+func SystemReminders(messages []Message, systemRole bool) []Message {
+	var mapped []Message
+	for i := range messages {
+		if !messages[i].SystemReminder {
+			continue
+		}
+		if mapped == nil {
+			mapped = make([]Message, len(messages))
+			copy(mapped, messages)
+		}
+
+		msg := &mapped[i]
+		if systemRole {
+			msg.Role = MessageRoleSystem
+			continue
+		}
+		msg.Role = MessageRoleUser
+		msg.Content = "<system-reminder>\n" + msg.Content + "\n</system-reminder>"
+	}
+
+	if mapped == nil {
+		return messages
+	}
+	return mapped
+}
@@ -0,0 +1,233 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/docker/docker-agent/pkg/config"
+	"github.com/docker/docker-agent/pkg/runtime"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// OutputFormat is the format of the output of a non-interactive run.
+type OutputFormat string
+
+const (
+	// OutputFormatText renders the conversation as text.
+	OutputFormatText OutputFormat = "text"
+	// OutputFormatJSON writes one JSON record per runtime event, followed by
+	// a "result" record holding the final assistant message and exit code.
+	OutputFormatJSON OutputFormat = "json"
+)
+
+// resultRecordType is the type of the record that terminates the JSON output.
+const resultRecordType = "result"
+
+// jsonRecord is a line of the JSON output.
+type jsonRecord struct {
+	Type      string    `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+	Payload   any       `json:"payload"`
+}
+
+// jsonResult is the payload of the record that terminates the JSON output.
+type jsonResult struct {
+	Content  string `json:"content"`
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error,omitempty"`
+}
+
+// toolConfirmation returns how the configuration answers the confirmation of
+// a call to toolName, if it does.
+func (cfg *Config) toolConfirmation(toolName string) (ConfirmationResult, bool) {
+	switch {
+	case cfg.AutoApprove, cfg.ApproveAll, slices.Contains(cfg.ApproveTools, toolName):
+		return ConfirmationApprove, true
+	case cfg.RejectAll:
+		return ConfirmationReject, true
+	default:
+		return "", false
+	}
+}
+
+// runJSONEvents runs the agent loop, writing each event as a JSON record.
+// Tool calls that the configuration doesn't approve are rejected, since
+// there's nobody to ask. It returns an error if the run failed or stopped at
+// the maximum number of iterations.
+func runJSONEvents(ctx context.Context, out *Printer, cfg *Config, rt runtime.Runtime, sess *session.Session) error {
+	autoExtensions := 0
+	var runErr error
+
+	for event := range rt.RunStream(ctx, sess) {
+		if err := writeJSONEvent(out, event); err != nil {
+			return err
+		}
+
+		switch e := event.(type) {
+		case *runtime.ToolCallConfirmationEvent:
+			if result, ok := cfg.toolConfirmation(e.ToolCall.Function.Name); ok && result == ConfirmationApprove {
+				_ = rt.Resume(ctx, sess.ID, runtime.ResumeApprove())
+			} else {
+				_ = rt.Resume(ctx, sess.ID, runtime.ResumeReject(""))
+			}
+		case *runtime.ElicitationRequestEvent:
+			_ = rt.ResumeElicitation(ctx, sess.ID, "decline", nil)
+		case *runtime.MaxIterationsReachedEvent:
+			if handleMaxIterationsAutoApprove(cfg.AutoApprove, &autoExtensions, e.MaxIterations) == maxIterContinue {
+				_ = rt.Resume(ctx, sess.ID, runtime.ResumeApprove())
+			} else {
+				_ = rt.Resume(ctx, sess.ID, runtime.ResumeReject(""))
+				runErr = fmt.Errorf("maximum number of iterations (%d) reached", e.MaxIterations)
+			}
+		case *runtime.ErrorEvent:
+			runErr = errors.New(e.Error)
+		}
+	}
+
+	return runErr
+}
+
+// writeJSONEvent writes event as a JSON record, with the secrets of the tool
+// call arguments redacted.
+func writeJSONEvent(out *Printer, event runtime.Event) error {
+	payload, err := json.Marshal(redactEvent(event))
+	if err != nil {
+		return err
+	}
+
+	var header struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(payload, &header); err != nil {
+		return err
+	}
+
+	return writeJSONRecord(out, header.Type, json.RawMessage(payload))
+}
+
+// writeJSONResult writes the record that terminates the JSON output.
+func writeJSONResult(out *Printer, sess *session.Session, runErr error) error {
+	result := jsonResult{Content: sess.GetLastAssistantMessageContent()}
+	if runErr != nil {
+		result.ExitCode = 1
+		result.Error = runErr.Error()
+	}
+	return writeJSONRecord(out, resultRecordType, result)
+}
+
+func writeJSONRecord(out *Printer, recordType string, payload any) error {
+	buf, err := json.Marshal(jsonRecord{
+		Type:      recordType,
+		Timestamp: time.Now().UTC(),
+		Payload:   payload,
+	})
+	if err != nil {
+		return err
+	}
+	out.Println(string(buf))
+	return nil
+}
+
+// redactEvent returns a copy of event where the secrets of the tool call
+// arguments are redacted.
+func redactEvent(event runtime.Event) runtime.Event {
+	switch e := event.(type) {
+	case *runtime.PartialToolCallEvent:
+		redacted := *e
+		redacted.ToolCall = redactToolCall(e.ToolCall)
+		redacted.ArgumentsPreview = redactPreview(e.ArgumentsPreview)
+		return &redacted
+	case *runtime.ToolCallEvent:
+		redacted := *e
+		redacted.ToolCall = redactToolCall(e.ToolCall)
+		return &redacted
+	case *runtime.ToolCallConfirmationEvent:
+		redacted := *e
+		redacted.ToolCall = redactToolCall(e.ToolCall)
+		return &redacted
+	case *runtime.ToolCallValidationFailedEvent:
+		redacted := *e
+		redacted.ToolCall = redactToolCall(e.ToolCall)
+		return &redacted
+	case *runtime.ToolCallTimeoutEvent:
+		redacted := *e
+		redacted.ToolCall = redactToolCall(e.ToolCall)
+		return &redacted
+	case *runtime.ToolCallQueuedEvent:
+		redacted := *e
+		redacted.ToolCall = redactToolCall(e.ToolCall)
+		return &redacted
+	default:
+		return event
+	}
+}
+
+func redactToolCall(toolCall tools.ToolCall) tools.ToolCall {
+	toolCall.Function.Arguments = redactArguments(toolCall.Function.Arguments)
+	return toolCall
+}
+
+// redactArguments replaces the values of the secret-ish keys of JSON tool
+// call arguments, at any depth. Arguments that aren't valid JSON, e.g. the
+// partial arguments of a streamed tool call, are returned unchanged.
+func redactArguments(arguments string) string {
+	var args any
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return arguments
+	}
+	if !redactSecrets(args) {
+		return arguments
+	}
+	buf, err := json.Marshal(args)
+	if err != nil {
+		return arguments
+	}
+	return string(buf)
+}
+
+// redactPreview returns a copy of the preview of the arguments of a streamed
+// tool call, where the values of the secret-ish keys are redacted.
+func redactPreview(preview map[string]any) map[string]any {
+	if preview == nil {
+		return nil
+	}
+	buf, err := json.Marshal(preview)
+	if err != nil {
+		return nil
+	}
+	var redacted map[string]any
+	if err := json.Unmarshal(buf, &redacted); err != nil {
+		return nil
+	}
+	redactSecrets(redacted)
+	return redacted
+}
+
+// redactSecrets redacts, in place, the string values of the secret-ish keys
+// of v. It reports whether anything was redacted.
+func redactSecrets(v any) bool {
+	redacted := false
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if s, ok := value.(string); ok {
+				if r := config.RedactValue(key, s); r != s {
+					v[key] = r
+					redacted = true
+				}
+				continue
+			}
+			redacted = redactSecrets(value) || redacted
+		}
+	case []any:
+		for _, value := range v {
+			redacted = redactSecrets(value) || redacted
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,232 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/runtime"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+type testRecord struct {
+	Type      string          `json:"type"`
+	Timestamp time.Time       `json:"timestamp"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+func parseRecords(t *testing.T, output string) []testRecord {
+	t.Helper()
+
+	var records []testRecord
+	for line := range strings.SplitSeq(strings.TrimSpace(output), "\n") {
+		var record testRecord
+		assert.NilError(t, json.Unmarshal([]byte(line), &record), line)
+		records = append(records, record)
+	}
+	return records
+}
+
+func parseResult(t *testing.T, record testRecord) jsonResult {
+	t.Helper()
+
+	assert.Equal(t, record.Type, resultRecordType)
+	var result jsonResult
+	assert.NilError(t, json.Unmarshal(record.Payload, &result))
+	return result
+}
+
+func confirmationEvent(toolName, arguments string) *runtime.ToolCallConfirmationEvent {
+	return &runtime.ToolCallConfirmationEvent{
+		Type: "tool_call_confirmation",
+		ToolCall: tools.ToolCall{
+			ID:       "call_" + toolName,
+			Function: tools.FunctionCall{Name: toolName, Arguments: arguments},
+		},
+	}
+}
+
+func TestJSONOutputRecords(t *testing.T) {
+	t.Parallel()
+
+	rt := &mockRuntime{
+		events: []runtime.Event{
+			&runtime.AgentChoiceEvent{Type: "agent_choice", Content: "Hello"},
+			confirmationEvent("shell", `{"cmd":"ls"}`),
+		},
+	}
+
+	var buf bytes.Buffer
+	sess := session.New()
+	sess.AddMessage(session.NewAgentMessage("root", &chat.Message{Role: chat.MessageRoleAssistant, Content: "Done"}))
+	cfg := Config{OutputFormat: OutputFormatJSON, ApproveTools: []string{"shell"}}
+
+	err := Run(t.Context(), NewPrinter(&buf), cfg, rt, sess, []string{"hello"})
+	assert.NilError(t, err)
+
+	records := parseRecords(t, buf.String())
+	assert.Equal(t, len(records), 3)
+	assert.Equal(t, records[0].Type, "agent_choice")
+	assert.Equal(t, records[1].Type, "tool_call_confirmation")
+	for _, record := range records {
+		assert.Assert(t, !record.Timestamp.IsZero())
+	}
+
+	var choice runtime.AgentChoiceEvent
+	assert.NilError(t, json.Unmarshal(records[0].Payload, &choice))
+	assert.Equal(t, choice.Content, "Hello")
+
+	result := parseResult(t, records[2])
+	assert.Equal(t, result.ExitCode, 0)
+	assert.Equal(t, result.Content, "Done")
+
+	resumes := rt.getResumes()
+	assert.Equal(t, len(resumes), 1)
+	assert.Equal(t, resumes[0].Type, runtime.ResumeTypeApprove)
+}
+
+func TestJSONOutputConfirmations(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		cfg  Config
+		want []runtime.ResumeType
+	}{
+		{
+			name: "rejected by default",
+			cfg:  Config{},
+			want: []runtime.ResumeType{runtime.ResumeTypeReject, runtime.ResumeTypeReject},
+		},
+		{
+			name: "approve list",
+			cfg:  Config{ApproveTools: []string{"read_file"}},
+			want: []runtime.ResumeType{runtime.ResumeTypeApprove, runtime.ResumeTypeReject},
+		},
+		{
+			name: "approve all",
+			cfg:  Config{ApproveAll: true},
+			want: []runtime.ResumeType{runtime.ResumeTypeApprove, runtime.ResumeTypeApprove},
+		},
+		{
+			name: "reject all but the approve list",
+			cfg:  Config{RejectAll: true, ApproveTools: []string{"shell"}},
+			want: []runtime.ResumeType{runtime.ResumeTypeReject, runtime.ResumeTypeApprove},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rt := &mockRuntime{
+				events: []runtime.Event{
+					confirmationEvent("read_file", `{"path":"a.txt"}`),
+					confirmationEvent("shell", `{"cmd":"rm -rf /"}`),
+				},
+			}
+			tt.cfg.OutputFormat = OutputFormatJSON
+
+			var buf bytes.Buffer
+			err := Run(t.Context(), NewPrinter(&buf), tt.cfg, rt, session.New(), []string{"hello"})
+			assert.NilError(t, err)
+
+			var got []runtime.ResumeType
+			for _, resume := range rt.getResumes() {
+				got = append(got, resume.Type)
+			}
+			assert.DeepEqual(t, got, tt.want)
+		})
+	}
+}
+
+func TestJSONOutputErrorExitCode(t *testing.T) {
+	t.Parallel()
+
+	rt := &mockRuntime{
+		events: []runtime.Event{
+			&runtime.ErrorEvent{Type: "error", Error: "model unavailable"},
+		},
+	}
+
+	var buf bytes.Buffer
+	cfg := Config{OutputFormat: OutputFormatJSON}
+
+	err := Run(t.Context(), NewPrinter(&buf), cfg, rt, session.New(), []string{"hello"})
+	_, ok := errors.AsType[RuntimeError](err)
+	assert.Assert(t, ok)
+
+	records := parseRecords(t, buf.String())
+	assert.Equal(t, len(records), 2)
+	assert.Equal(t, records[0].Type, "error")
+
+	result := parseResult(t, records[1])
+	assert.Equal(t, result.ExitCode, 1)
+	assert.Equal(t, result.Error, "model unavailable")
+}
+
+func TestJSONOutputMaxIterationsExitCode(t *testing.T) {
+	t.Parallel()
+
+	rt := &mockRuntime{events: []runtime.Event{maxIterEvent(20)}}
+
+	var buf bytes.Buffer
+	cfg := Config{OutputFormat: OutputFormatJSON}
+
+	err := Run(t.Context(), NewPrinter(&buf), cfg, rt, session.New(), []string{"hello"})
+	assert.ErrorContains(t, err, "maximum number of iterations (20) reached")
+
+	records := parseRecords(t, buf.String())
+	result := parseResult(t, records[len(records)-1])
+	assert.Equal(t, result.ExitCode, 1)
+
+	resumes := rt.getResumes()
+	assert.Equal(t, len(resumes), 1)
+	assert.Equal(t, resumes[0].Type, runtime.ResumeTypeReject)
+}
+
+func TestJSONOutputRedactsToolArguments(t *testing.T) {
+	t.Parallel()
+
+	rt := &mockRuntime{
+		events: []runtime.Event{
+			confirmationEvent("fetch", `{"url":"https://example.com","headers":{"Authorization":"Bearer s3cr3t"}}`),
+		},
+	}
+
+	var buf bytes.Buffer
+	cfg := Config{OutputFormat: OutputFormatJSON}
+
+	err := Run(t.Context(), NewPrinter(&buf), cfg, rt, session.New(), []string{"hello"})
+	assert.NilError(t, err)
+	assert.Assert(t, !strings.Contains(buf.String(), "s3cr3t"))
+
+	var event runtime.ToolCallConfirmationEvent
+	assert.NilError(t, json.Unmarshal(parseRecords(t, buf.String())[0].Payload, &event))
+	assert.Equal(t, event.ToolCall.Function.Arguments, `{"headers":{"Authorization":"REDACTED"},"url":"https://example.com"}`)
+}
+
+func TestRedactArguments(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, redactArguments(`{"path":"a.txt"}`), `{"path":"a.txt"}`)
+	assert.Equal(t, redactArguments(`{"env":[{"api_key":"abc"}]}`), `{"env":[{"api_key":"REDACTED"}]}`)
+	assert.Equal(t, redactArguments(`{"max_tokens":"10"}`), `{"max_tokens":"10"}`)
+	assert.Equal(t, redactArguments(`{"token":"ab`), `{"token":"ab`)
+}
+
+func TestRedactPreview(t *testing.T) {
+	t.Parallel()
+
+	preview := map[string]any{"url": "https://example.com", "headers": map[string]any{"Authorization": "Bearer s3cr3t"}}
+	assert.DeepEqual(t, redactPreview(preview), map[string]any{"url": "https://example.com", "headers": map[string]any{"Authorization": "REDACTED"}})
+	assert.Equal(t, preview["headers"].(map[string]any)["Authorization"], "Bearer s3cr3t", "the preview is copied")
+	assert.Assert(t, redactPreview(nil) == nil)
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 	"strings"
+	"sync"
 
 	"github.com/fatih/color"
 	"github.com/mattn/go-isatty"
@@ -14,6 +15,7 @@ import (
 	"golang.org/x/term"
 
 	"github.com/docker/docker-agent/pkg/input"
+	"github.com/docker/docker-agent/pkg/termcap"
 	"github.com/docker/docker-agent/pkg/tools"
 )
 
@@ -27,7 +29,22 @@ const (
 	ConfirmationAbort          ConfirmationResult = "abort"
 )
 
-var bold = color.New(color.Bold).SprintfFunc()
+var boldColor = color.New(color.Bold)
+
+// stdoutColor tells whether stdout, where printers write, supports styled
+// text.
+var stdoutColor = sync.OnceValue(func() bool {
+	return termcap.SupportsColor(os.Stdout)
+})
+
+// bold formats text in bold, or returns it as is when stdout doesn't support
+// styling.
+func bold(text string) string {
+	if !stdoutColor() {
+		return text
+	}
+	return boldColor.Sprint(text)
+}
 
 type Printer struct {
 	out      io.Writer
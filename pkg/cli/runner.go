@@ -75,12 +75,37 @@ type Config struct {
 	AutoApprove    bool
 	HideToolCalls  bool
 	OutputJSON     bool
+	// OutputFormat is the format of the output. OutputFormatJSON supersedes
+	// OutputJSON, which writes the raw runtime events.
+	OutputFormat OutputFormat
+	// ApproveAll approves the tool calls that need a confirmation. Unlike
+	// AutoApprove, it doesn't extend the maximum number of iterations.
+	ApproveAll bool
+	// RejectAll rejects the tool calls that need a confirmation, unless
+	// they're approved by ApproveTools.
+	RejectAll bool
+	// ApproveTools lists the tools whose calls are approved without prompting.
+	ApproveTools []string
 }
 
 // Run executes an agent in non-TUI mode, handling user input and runtime events.
 // userMessages contains the user messages to send. If a single message is "-",
 // input is read from stdin. If empty, an interactive prompt loop is started.
+//
+// With OutputFormatJSON, the output ends with a "result" record holding the
+// last assistant message and the exit code of the run.
 func Run(ctx context.Context, out *Printer, cfg Config, rt runtime.Runtime, sess *session.Session, userMessages []string) error {
+	err := run(ctx, out, cfg, rt, sess, userMessages)
+	if cfg.OutputFormat != OutputFormatJSON {
+		return err
+	}
+	if resultErr := writeJSONResult(out, sess, err); resultErr != nil && err == nil {
+		return resultErr
+	}
+	return err
+}
+
+func run(ctx context.Context, out *Printer, cfg Config, rt runtime.Runtime, sess *session.Session, userMessages []string) error {
 	// Create a cancellable context for this agentic loop and wire Ctrl+C to cancel it
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -106,21 +131,29 @@ func Run(ctx context.Context, out *Printer, cfg Config, rt runtime.Runtime, sess
 
 		sess.AddMessage(PrepareUserMessage(ctx, rt, userInput, cfg.AttachmentPath))
 
+		if cfg.OutputFormat == OutputFormatJSON {
+			if err := runJSONEvents(ctx, out, &cfg, rt, sess); err != nil {
+				lastErr = err
+				return RuntimeError{Err: err}
+			}
+			return nil
+		}
+
 		if cfg.OutputJSON {
 			for event := range rt.RunStream(ctx, sess) {
 				switch e := event.(type) {
 				case *runtime.ToolCallConfirmationEvent:
 					if !cfg.AutoApprove {
-						rt.Resume(ctx, runtime.ResumeReject(""))
+						_ = rt.Resume(ctx, sess.ID, runtime.ResumeReject(""))
 					}
 				case *runtime.ElicitationRequestEvent:
-					_ = rt.ResumeElicitation(ctx, "decline", nil)
+					_ = rt.ResumeElicitation(ctx, sess.ID, "decline", nil)
 				case *runtime.MaxIterationsReachedEvent:
 					switch handleMaxIterationsAutoApprove(cfg.AutoApprove, &autoExtensions, e.MaxIterations) {
 					case maxIterContinue:
-						rt.Resume(ctx, runtime.ResumeApprove())
+						_ = rt.Resume(ctx, sess.ID, runtime.ResumeApprove())
 					default: // maxIterStop or maxIterPrompt (no interactive prompt in JSON mode)
-						rt.Resume(ctx, runtime.ResumeReject(""))
+						_ = rt.Resume(ctx, sess.ID, runtime.ResumeReject(""))
 						return nil
 					}
 				case *runtime.ErrorEvent:
@@ -156,7 +189,12 @@ func Run(ctx context.Context, out *Printer, cfg Config, rt runtime.Runtime, sess
 			case *runtime.AgentChoiceReasoningEvent:
 				out.Print(e.Content)
 			case *runtime.ToolCallConfirmationEvent:
-				result := out.PrintToolCallWithConfirmation(ctx, e.ToolCall, rd)
+				result, decided := cfg.toolConfirmation(e.ToolCall.Function.Name)
+				if decided {
+					out.PrintToolCall(e.ToolCall)
+				} else {
+					result = out.PrintToolCallWithConfirmation(ctx, e.ToolCall, rd)
+				}
 				// If interrupted, skip resuming; the runtime will notice context cancellation and stop
 				if ctx.Err() != nil {
 					continue
@@ -164,12 +202,12 @@ func Run(ctx context.Context, out *Printer, cfg Config, rt runtime.Runtime, sess
 				lastConfirmedToolCallID = e.ToolCall.ID // Store the ID to avoid duplicate printing
 				switch result {
 				case ConfirmationApprove:
-					rt.Resume(ctx, runtime.ResumeApprove())
+					_ = rt.Resume(ctx, sess.ID, runtime.ResumeApprove())
 				case ConfirmationApproveSession:
 					sess.ToolsApproved = true
-					rt.Resume(ctx, runtime.ResumeApproveSession())
+					_ = rt.Resume(ctx, sess.ID, runtime.ResumeApproveSession())
 				case ConfirmationReject:
-					rt.Resume(ctx, runtime.ResumeReject(""))
+					_ = rt.Resume(ctx, sess.ID, runtime.ResumeReject(""))
 					lastConfirmedToolCallID = "" // Clear on reject since tool won't execute
 				case ConfirmationAbort:
 					// Stop the agent loop immediately
@@ -204,20 +242,20 @@ func Run(ctx context.Context, out *Printer, cfg Config, rt runtime.Runtime, sess
 			case *runtime.MaxIterationsReachedEvent:
 				switch handleMaxIterationsAutoApprove(cfg.AutoApprove, &autoExtensions, e.MaxIterations) {
 				case maxIterContinue:
-					rt.Resume(ctx, runtime.ResumeApprove())
+					_ = rt.Resume(ctx, sess.ID, runtime.ResumeApprove())
 				case maxIterStop:
-					rt.Resume(ctx, runtime.ResumeReject(""))
+					_ = rt.Resume(ctx, sess.ID, runtime.ResumeReject(""))
 					return nil
 				case maxIterPrompt:
 					result := out.PromptMaxIterationsContinue(ctx, e.MaxIterations)
 					switch result {
 					case ConfirmationApprove:
-						rt.Resume(ctx, runtime.ResumeApprove())
+						_ = rt.Resume(ctx, sess.ID, runtime.ResumeApprove())
 					case ConfirmationReject:
-						rt.Resume(ctx, runtime.ResumeReject(""))
+						_ = rt.Resume(ctx, sess.ID, runtime.ResumeReject(""))
 						return nil
 					case ConfirmationAbort:
-						rt.Resume(ctx, runtime.ResumeReject(""))
+						_ = rt.Resume(ctx, sess.ID, runtime.ResumeReject(""))
 						return nil
 					}
 				}
@@ -225,7 +263,7 @@ func Run(ctx context.Context, out *Printer, cfg Config, rt runtime.Runtime, sess
 				serverURL, ok := e.Meta["cagent/server_url"].(string)
 				if !ok || serverURL == "" {
 					slog.Warn("Skipping elicitation: missing or invalid server_url (non-interactive session?)")
-					_ = rt.ResumeElicitation(ctx, "decline", nil)
+					_ = rt.ResumeElicitation(ctx, sess.ID, "decline", nil)
 					return nil
 				}
 
@@ -237,9 +275,9 @@ func Run(ctx context.Context, out *Printer, cfg Config, rt runtime.Runtime, sess
 
 				switch result {
 				case ConfirmationApprove:
-					_ = rt.ResumeElicitation(ctx, "accept", nil)
+					_ = rt.ResumeElicitation(ctx, sess.ID, "accept", nil)
 				case ConfirmationReject:
-					_ = rt.ResumeElicitation(ctx, "decline", nil)
+					_ = rt.ResumeElicitation(ctx, sess.ID, "decline", nil)
 					return errors.New("OAuth authorization rejected by user")
 				}
 			}
@@ -39,7 +39,15 @@ func (m *mockRuntime) Run(context.Context, *session.Session) ([]session.Message,
 	return nil, nil
 }
 
-func (m *mockRuntime) ResumeElicitation(_ context.Context, action tools.ElicitationAction, _ map[string]any) error {
+func (m *mockRuntime) ContinueStream(ctx context.Context, sess *session.Session, _ string) <-chan runtime.Event {
+	return m.RunStream(ctx, sess)
+}
+
+func (m *mockRuntime) Continue(context.Context, *session.Session, string) ([]session.Message, error) {
+	return nil, nil
+}
+
+func (m *mockRuntime) ResumeElicitation(_ context.Context, _ string, action tools.ElicitationAction, _ map[string]any) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.elicitationDeclines++
@@ -54,20 +62,25 @@ func (m *mockRuntime) CurrentMCPPrompts(context.Context) map[string]mcptools.Pro
 	return nil
 }
 
+func (m *mockRuntime) CurrentMCPResources(context.Context) map[string]mcptools.ResourceInfo {
+	return nil
+}
+
 func (m *mockRuntime) ExecuteMCPPrompt(context.Context, string, map[string]string) (string, error) {
 	return "", nil
 }
 func (m *mockRuntime) UpdateSessionTitle(context.Context, *session.Session, string) error    { return nil }
 func (m *mockRuntime) TitleGenerator() *sessiontitle.Generator                               { return nil }
-func (m *mockRuntime) Close() error                                                          { return nil }
+func (m *mockRuntime) Close(context.Context) error                                           { return nil }
 func (m *mockRuntime) Steer(runtime.QueuedMessage) error                                     { return nil }
 func (m *mockRuntime) FollowUp(runtime.QueuedMessage) error                                  { return nil }
 func (m *mockRuntime) RegenerateTitle(context.Context, *session.Session, chan runtime.Event) {}
 
-func (m *mockRuntime) Resume(_ context.Context, req runtime.ResumeRequest) {
+func (m *mockRuntime) Resume(_ context.Context, _ string, req runtime.ResumeRequest) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.resumes = append(m.resumes, req)
+	return nil
 }
 
 func (m *mockRuntime) RunStream(_ context.Context, _ *session.Session) <-chan runtime.Event {
@@ -163,3 +163,56 @@ func TestShouldCompact(t *testing.T) {
 		})
 	}
 }
+
+func TestEstimateMessageTokensBPE(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		msg      chat.Message
+		expected int64
+	}{
+		{
+			name:     "empty message returns overhead only",
+			msg:      chat.Message{},
+			expected: 5,
+		},
+		{
+			name:     "words and punctuation",
+			msg:      chat.Message{Content: "Hello, world!"}, // "Hello" "," " world" "!"
+			expected: 9,
+		},
+		{
+			name:     "long words are split",
+			msg:      chat.Message{Content: "internationalization"}, // 20 bytes → 4 tokens
+			expected: 9,
+		},
+		{
+			name: "code in tool call arguments",
+			msg: chat.Message{
+				ToolCalls: []tools.ToolCall{
+					{Function: tools.FunctionCall{Name: "shell", Arguments: "func main() { fmt.Println(12345) }"}},
+				},
+			},
+			// 13 tokens for the arguments, 1 for the name
+			expected: 19,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, EstimateMessageTokensBPE(&tt.msg))
+		})
+	}
+}
+
+func TestEstimatorFor(t *testing.T) {
+	t.Parallel()
+
+	msg := &chat.Message{Content: "internationalization"}
+
+	assert.Equal(t, EstimateMessageTokensBPE(msg), EstimatorFor("openai/gpt-4o")(msg))
+	assert.Equal(t, EstimateMessageTokens(msg), EstimatorFor("anthropic/claude-sonnet-4-0")(msg))
+	assert.Equal(t, EstimateMessageTokens(msg), EstimatorFor("gpt-4o")(msg))
+}
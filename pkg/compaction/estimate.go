@@ -0,0 +1,77 @@
+package compaction
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// TokenEstimator estimates the number of tokens a message takes in the
+// context window of a model.
+type TokenEstimator func(msg *chat.Message) int64
+
+// preTokenPattern splits text the way BPE tokenizers such as OpenAI's
+// cl100k_base do before merging bytes into tokens: contractions, words,
+// groups of up to three digits, punctuation runs and whitespace.
+var preTokenPattern = regexp.MustCompile(`(?i:'s|'t|'re|'ve|'m|'ll|'d)| ?\pL+| ?\pN{1,3}| ?[^\s\pL\pN]+|\s+`)
+
+// bytesPerMergedToken is the length of the tokens a long pre-token is
+// assumed to be merged into. Most short words, with their leading space,
+// are a single token.
+const bytesPerMergedToken = 6
+
+// EstimatorFor returns the token estimator suited to a model, given as
+// provider/model: a tokenizer-like estimator for OpenAI models and the
+// EstimateMessageTokens heuristic for the others.
+func EstimatorFor(modelID string) TokenEstimator {
+	provider, _, _ := strings.Cut(modelID, "/")
+	if provider == "openai" {
+		return EstimateMessageTokensBPE
+	}
+	return EstimateMessageTokens
+}
+
+// EstimateMessageTokensBPE estimates the tokens of a message by splitting
+// its text like a BPE tokenizer does: each pre-token is one token, or one
+// per six bytes for longer ones. It is closer to the actual count than
+// EstimateMessageTokens for code and non-English text.
+func EstimateMessageTokensBPE(msg *chat.Message) int64 {
+	// perMessageOverhead: role, ToolCallID, delimiters, etc.
+	const perMessageOverhead = 5
+
+	tokens := int64(perMessageOverhead)
+	tokens += estimateTextTokensBPE(msg.Content)
+	for _, part := range msg.MultiContent {
+		tokens += estimateTextTokensBPE(part.Text)
+	}
+	tokens += estimateTextTokensBPE(msg.ReasoningContent)
+	for _, tc := range msg.ToolCalls {
+		tokens += estimateTextTokensBPE(tc.Function.Arguments)
+		tokens += estimateTextTokensBPE(tc.Function.Name)
+	}
+	return tokens
+}
+
+func estimateTextTokensBPE(text string) int64 {
+	var tokens int64
+	for _, piece := range preTokenPattern.FindAllString(text, -1) {
+		tokens += int64(max(1, (len(piece)+bytesPerMergedToken-1)/bytesPerMergedToken))
+	}
+	return tokens
+}
+
+// EstimateToolTokens returns a rough estimate of the tokens the definitions
+// of tools take in the context window, from the length of their JSON schema.
+// Providers send them with every request, on top of the messages.
+func EstimateToolTokens(toolDefs []tools.Tool) int64 {
+	var tokens int64
+	for _, tool := range toolDefs {
+		if buf, err := json.Marshal(tool); err == nil {
+			tokens += int64(len(buf)) / 4
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/docker/docker-agent/pkg/config/latest"
+)
+
+// CompactionPrompt returns the instructions of the summarization replacing
+// the default ones, read from the prompt_override file of the compaction
+// configuration, relative to baseDir. It returns an empty string when the
+// configuration has none. Remote configurations have no baseDir and can't
+// override the prompt.
+func CompactionPrompt(cfg *latest.Config, baseDir string) (string, error) {
+	if cfg.Compaction == nil || cfg.Compaction.PromptOverride == "" {
+		return "", nil
+	}
+	if baseDir == "" {
+		return "", errors.New("compaction.prompt_override reads a file, which is only allowed in local agent files")
+	}
+
+	buf, err := os.ReadFile(includePath(cfg.Compaction.PromptOverride, baseDir))
+	if err != nil {
+		return "", fmt.Errorf("reading compaction.prompt_override: %w", err)
+	}
+	return string(buf), nil
+}
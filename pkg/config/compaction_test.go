@@ -0,0 +1,60 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompactionPrompt(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "prompts"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "prompts", "summary.md"), []byte("Summarize for the release notes.\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "agent.yaml"), []byte(`
+compaction:
+  model: openai/gpt-4o-mini
+  prompt_override: prompts/summary.md
+  keep_last_messages: 6
+agents:
+  root:
+    model: openai/gpt-4o
+    instruction: Review the pull request.
+`), 0o644))
+
+	source := NewFileSource(filepath.Join(dir, "agent.yaml"))
+	cfg, err := Load(t.Context(), source)
+	require.NoError(t, err)
+	require.NotNil(t, cfg.Compaction)
+	assert.Equal(t, "openai/gpt-4o-mini", cfg.Compaction.Model)
+	assert.Equal(t, 6, cfg.Compaction.KeepLastMessages)
+
+	// The prompt file is relative to the configuration file.
+	prompt, err := CompactionPrompt(cfg, source.ParentDir())
+	require.NoError(t, err)
+	assert.Equal(t, "Summarize for the release notes.\n", prompt)
+
+	// Remote configurations can't read files.
+	_, err = CompactionPrompt(cfg, "")
+	require.Error(t, err)
+}
+
+func TestCompaction_NegativeKeepLastMessages(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "agent.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+compaction:
+  keep_last_messages: -1
+agents:
+  root:
+    model: openai/gpt-4o
+`), 0o644))
+
+	_, err := Load(t.Context(), NewFileSource(path))
+	require.ErrorContains(t, err, "compaction.keep_last_messages must be >= 0")
+}
@@ -17,12 +17,28 @@ import (
 	"github.com/docker/docker-agent/pkg/environment"
 )
 
-func Load(ctx context.Context, source Source) (*latest.Config, error) {
+func Load(ctx context.Context, source Source, opts ...LoadOpt) (*latest.Config, error) {
+	var loadOpts loadOptions
+	for _, opt := range opts {
+		opt(&loadOpts)
+	}
+
 	data, err := source.Read(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	if !loadOpts.noInterpolate {
+		env := loadOpts.env
+		if env == nil {
+			env = environment.NewOsEnvProvider()
+		}
+		data, err = interpolate(ctx, data, source, env)
+		if err != nil {
+			return nil, fmt.Errorf("interpolating config: %w", err)
+		}
+	}
+
 	var raw struct {
 		Version string `yaml:"version,omitempty"`
 	}
@@ -133,6 +149,14 @@ func validateConfig(cfg *latest.Config) error {
 		allNames[agent.Name] = true
 	}
 
+	snippetNames := map[string]bool{}
+	for _, snippet := range cfg.Snippets {
+		if snippetNames[snippet.Name] {
+			return fmt.Errorf("snippet '%s' is defined more than once", snippet.Name)
+		}
+		snippetNames[snippet.Name] = true
+	}
+
 	for _, agent := range cfg.Agents {
 		for _, subAgentRef := range agent.SubAgents {
 			if _, exists := allNames[subAgentRef]; !exists && !IsExternalReference(subAgentRef) {
@@ -158,6 +182,12 @@ func validateConfig(cfg *latest.Config) error {
 			}
 		}
 
+		for _, ref := range agent.InstructionRefs {
+			if !snippetNames[ref] {
+				return fmt.Errorf("agent '%s' references non-existent snippet '%s'", agent.Name, ref)
+			}
+		}
+
 		if err := validateSkillsConfiguration(agent.Name, &agent); err != nil {
 			return err
 		}
@@ -21,7 +21,8 @@ func collectExamples(t *testing.T) []string {
 		if err != nil {
 			return err
 		}
-		if !d.IsDir() && filepath.Ext(path) == ".yaml" {
+		// eval-suite.yaml is an eval test suite, not an agent.
+		if !d.IsDir() && filepath.Ext(path) == ".yaml" && d.Name() != "eval-suite.yaml" {
 			files = append(files, path)
 		}
 		return nil
@@ -56,7 +57,7 @@ func TestParseExamples(t *testing.T) {
 				require.NotEmpty(t, model.Provider)
 				require.NotEmpty(t, model.Model)
 				// Skip providers that don't have entries in models.dev
-				if model.Provider == "dmr" || model.Provider == "chatgpt" {
+				if model.Provider == "dmr" || model.Provider == "ollama" || model.Provider == "chatgpt" {
 					continue
 				}
 				// Skip models with routing rules - they use multiple providers
@@ -66,16 +66,15 @@ func GatherEnvVarsForModels(cfg *latest.Config) []string {
 }
 
 // gatherEnvVarsForModel collects required environment variables for a single model,
-// including any models referenced in its routing rules.
+// including any models referenced in its routing rules or router candidates.
 func gatherEnvVarsForModel(cfg *latest.Config, modelName string, requiredEnv map[string]bool) {
 	model := cfg.Models[modelName]
 
 	// Add env vars for the model itself
 	addEnvVarsForModelConfig(&model, cfg.Providers, requiredEnv)
 
-	// If the model has routing rules, also check all referenced models
-	for _, rule := range model.Routing {
-		ruleModelName := rule.Model
+	// If the model routes to other models, also check all referenced models
+	for _, ruleModelName := range routedModels(&model) {
 		if ruleModel, exists := cfg.Models[ruleModelName]; exists {
 			// Model reference - add its env vars
 			addEnvVarsForModelConfig(&ruleModel, cfg.Providers, requiredEnv)
@@ -87,6 +86,19 @@ func gatherEnvVarsForModel(cfg *latest.Config, modelName string, requiredEnv map
 	}
 }
 
+// routedModels returns the models a model routes to: the targets of its
+// routing rules and its router candidates.
+func routedModels(model *latest.ModelConfig) []string {
+	var names []string
+	for _, rule := range model.Routing {
+		names = append(names, rule.Model)
+	}
+	if model.Router != nil {
+		names = append(names, model.Router.Candidates...)
+	}
+	return names
+}
+
 // addEnvVarsForModelConfig adds required environment variables for a model config.
 // It checks custom providers first, then built-in aliases, then hardcoded fallbacks.
 func addEnvVarsForModelConfig(model *latest.ModelConfig, customProviders map[string]latest.ProviderConfig, requiredEnv map[string]bool) {
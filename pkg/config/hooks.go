@@ -79,6 +79,9 @@ func MergeHooks(base, cli *latest.HooksConfig) *latest.HooksConfig {
 		SessionStart: append(append([]latest.HookDefinition{}, base.SessionStart...), cli.SessionStart...),
 		SessionEnd:   append(append([]latest.HookDefinition{}, base.SessionEnd...), cli.SessionEnd...),
 		OnUserInput:  append(append([]latest.HookDefinition{}, base.OnUserInput...), cli.OnUserInput...),
+		Stop:         append(append([]latest.HookDefinition{}, base.Stop...), cli.Stop...),
+		TurnEnd:      append(append([]latest.HookDefinition{}, base.TurnEnd...), cli.TurnEnd...),
+		Notification: append(append([]latest.HookDefinition{}, base.Notification...), cli.Notification...),
 	}
 	return merged
 }
@@ -95,6 +95,7 @@ func TestMergeHooks_BothNonNil(t *testing.T) {
 			Matcher: "shell",
 			Hooks:   []latest.HookDefinition{{Type: "command", Command: "echo base-pre"}},
 		}},
+		TurnEnd: []latest.HookDefinition{{Type: "command", Command: "go build ./...", Inject: true}},
 	}
 	cli := &latest.HooksConfig{
 		SessionStart: []latest.HookDefinition{{Type: "command", Command: "echo cli"}},
@@ -117,6 +118,9 @@ func TestMergeHooks_BothNonNil(t *testing.T) {
 	assert.Equal(t, "echo base-pre", result.PreToolUse[0].Hooks[0].Command)
 	assert.Empty(t, result.PreToolUse[1].Matcher)
 	assert.Equal(t, "echo cli-pre", result.PreToolUse[1].Hooks[0].Command)
+
+	// Turn end hooks should be kept
+	assert.Equal(t, base.TurnEnd, result.TurnEnd)
 }
 
 func TestMergeHooks_DoesNotMutateOriginals(t *testing.T) {
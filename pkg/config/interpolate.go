@@ -0,0 +1,216 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+
+	"github.com/docker/docker-agent/pkg/environment"
+	"github.com/docker/docker-agent/pkg/path"
+)
+
+// interpolationPattern matches the ${env:VAR} and ${file:/path} references
+// that can be used in any string value of an agent configuration. A reference
+// preceded by another $, e.g. $${env:VAR}, is escaped and kept as ${env:VAR}.
+var interpolationPattern = regexp.MustCompile(`(\$?)\$\{(env|file):([^}]*)\}`)
+
+type loadOptions struct {
+	env           environment.Provider
+	noInterpolate bool
+}
+
+// LoadOpt configures how an agent configuration is loaded.
+type LoadOpt func(*loadOptions)
+
+// WithEnvProvider sets the provider used to resolve ${env:VAR} references.
+// Defaults to the OS environment.
+func WithEnvProvider(env environment.Provider) LoadOpt {
+	return func(opts *loadOptions) {
+		opts.env = env
+	}
+}
+
+// WithoutInterpolation keeps ${env:VAR} and ${file:/path} references as literal values.
+func WithoutInterpolation() LoadOpt {
+	return func(opts *loadOptions) {
+		opts.noInterpolate = true
+	}
+}
+
+// interpolate resolves the ${env:VAR} and ${file:/path} references of a raw
+// YAML configuration. It runs before the configuration is parsed so that
+// defaults and validation apply to the resolved values.
+func interpolate(ctx context.Context, data []byte, source Source, env environment.Provider) ([]byte, error) {
+	if !interpolationPattern.Match(data) {
+		return data, nil
+	}
+
+	var doc any
+	if err := yaml.UnmarshalWithOptions(data, &doc, yaml.UseOrderedMap()); err != nil {
+		return nil, fmt.Errorf("parsing config file\n%s", yaml.FormatError(err, true, true))
+	}
+
+	i := interpolator{
+		env:     env,
+		baseDir: source.ParentDir(),
+		remote:  isRemoteSource(source),
+	}
+	doc, err := walkStrings(doc, "", "", func(yamlPath, key, value string) (string, error) {
+		return i.expand(ctx, yamlPath, key, value)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(doc)
+}
+
+type interpolator struct {
+	env     environment.Provider
+	baseDir string
+	remote  bool
+}
+
+// expand resolves the references found in the value at yamlPath.
+func (i *interpolator) expand(ctx context.Context, yamlPath, key, value string) (string, error) {
+	if !strings.Contains(value, "${") {
+		return value, nil
+	}
+
+	var err error
+	expanded := interpolationPattern.ReplaceAllStringFunc(value, func(ref string) string {
+		if err != nil {
+			return ref
+		}
+
+		match := interpolationPattern.FindStringSubmatch(ref)
+		if match[1] != "" {
+			return ref[1:]
+		}
+
+		var resolved string
+		switch match[2] {
+		case "env":
+			resolved, err = i.resolveEnv(ctx, match[3])
+		case "file":
+			resolved, err = i.resolveFile(match[3])
+		}
+		if err != nil {
+			err = fmt.Errorf("%s: %w", yamlPath, err)
+		}
+		return resolved
+	})
+	if err != nil {
+		return "", err
+	}
+
+	slog.Debug("Interpolated config value", "path", yamlPath, "value", RedactValue(key, expanded))
+	return expanded, nil
+}
+
+func (i *interpolator) resolveEnv(ctx context.Context, name string) (string, error) {
+	if name == "" {
+		return "", errors.New("empty variable name in ${env:}")
+	}
+	value, found := i.env.Get(ctx, name)
+	if !found {
+		return "", fmt.Errorf("environment variable %q is not set", name)
+	}
+	return value, nil
+}
+
+func (i *interpolator) resolveFile(name string) (string, error) {
+	if i.remote {
+		return "", fmt.Errorf("${file:%s} is only allowed in local agent files", name)
+	}
+	if name == "" {
+		return "", errors.New("empty path in ${file:}")
+	}
+
+	buf, err := os.ReadFile(includePath(name, i.baseDir))
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", name, err)
+	}
+
+	// Secret files usually end with a newline that isn't part of the value.
+	return strings.TrimRight(string(buf), "\r\n"), nil
+}
+
+// includePath returns the path of the file referenced by ${file:name}.
+// Relative paths are relative to the directory of the agent file.
+func includePath(name, baseDir string) string {
+	filePath := path.ExpandPath(name)
+	if !filepath.IsAbs(filePath) && baseDir != "" {
+		filePath = filepath.Join(baseDir, filePath)
+	}
+	return filePath
+}
+
+// includedFiles returns the paths of the files referenced with ${file:path}
+// in a raw YAML configuration.
+func includedFiles(data []byte, baseDir string) []string {
+	var files []string
+	for _, match := range interpolationPattern.FindAllSubmatch(data, -1) {
+		if len(match[1]) == 0 && string(match[2]) == "file" && len(match[3]) > 0 {
+			files = append(files, includePath(string(match[3]), baseDir))
+		}
+	}
+	return files
+}
+
+// isRemoteSource returns true for configurations that were not written by
+// the user on this machine and must not be able to read local files.
+func isRemoteSource(source Source) bool {
+	switch source.(type) {
+	case ociSource, urlSource, *urlSource:
+		return true
+	default:
+		return false
+	}
+}
+
+// walkStrings calls fn on every string value of a YAML document decoded with
+// yaml.UseOrderedMap, and replaces it with fn's result. fn receives the path
+// of the value (e.g. "agents.root.toolsets[0].env.TOKEN") and the name of the
+// closest mapping key.
+func walkStrings(node any, yamlPath, key string, fn func(yamlPath, key, value string) (string, error)) (any, error) {
+	switch v := node.(type) {
+	case yaml.MapSlice:
+		for idx := range v {
+			name := fmt.Sprint(v[idx].Key)
+			value, err := walkStrings(v[idx].Value, joinYAMLPath(yamlPath, name), name, fn)
+			if err != nil {
+				return nil, err
+			}
+			v[idx].Value = value
+		}
+		return v, nil
+	case []any:
+		for idx := range v {
+			value, err := walkStrings(v[idx], fmt.Sprintf("%s[%d]", yamlPath, idx), key, fn)
+			if err != nil {
+				return nil, err
+			}
+			v[idx] = value
+		}
+		return v, nil
+	case string:
+		return fn(yamlPath, key, v)
+	default:
+		return node, nil
+	}
+}
+
+func joinYAMLPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/environment"
+)
+
+func interpolationEnv() environment.Provider {
+	return environment.NewMapEnvProvider(map[string]string{
+		"PROJECT_NAME":  "docker-agent",
+		"GITHUB_HOST":   "github.example.com",
+		"MODEL_NAME":    "qwen3",
+		"MODEL_HOST":    "gpu-box",
+		"MODEL_API_KEY": "sk-local",
+	})
+}
+
+func TestLoad_Interpolation(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := Load(t.Context(), NewFileSource("testdata/interpolation/agent.yaml"), WithEnvProvider(interpolationEnv()))
+	require.NoError(t, err)
+
+	root := cfg.Agents.First()
+	assert.Equal(t, "You work on docker-agent.", root.Instruction)
+	require.Len(t, root.Toolsets, 1)
+	assert.Equal(t, map[string]string{
+		"GITHUB_TOKEN": "ghp_fromfile",
+		"GITHUB_HOST":  "https://github.example.com",
+	}, root.Toolsets[0].Env)
+
+	model := cfg.Models["local"]
+	assert.Equal(t, "qwen3", model.Model)
+	assert.Equal(t, "http://gpu-box:8080/v1", model.BaseURL)
+	assert.Equal(t, "Bearer sk-local", model.ExtraHeaders["Authorization"])
+}
+
+func TestLoad_InterpolationMissingVariable(t *testing.T) {
+	t.Parallel()
+
+	env := environment.NewMapEnvProvider(map[string]string{
+		"PROJECT_NAME": "docker-agent",
+		"GITHUB_HOST":  "github.example.com",
+		"MODEL_NAME":   "qwen3",
+		"MODEL_HOST":   "gpu-box",
+	})
+
+	_, err := Load(t.Context(), NewFileSource("testdata/interpolation/agent.yaml"), WithEnvProvider(env))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `models.local.extra_headers.Authorization: environment variable "MODEL_API_KEY" is not set`)
+}
+
+func TestLoad_InterpolationMissingFile(t *testing.T) {
+	t.Parallel()
+
+	source := NewBytesSource("agent.yaml", []byte(`
+agents:
+  root:
+    model: openai/gpt-4o
+    toolsets:
+      - type: shell
+        env:
+          API_TOKEN: ${file:/does/not/exist}
+`))
+
+	_, err := Load(t.Context(), source, WithEnvProvider(interpolationEnv()))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "agents.root.toolsets[0].env.API_TOKEN: reading /does/not/exist")
+}
+
+func TestLoad_InterpolationEscaped(t *testing.T) {
+	t.Parallel()
+
+	source := NewBytesSource("agent.yaml", []byte(`
+agents:
+  root:
+    model: openai/gpt-4o
+    instruction: Work on ${env:PROJECT_NAME}, read secrets with $${env:NAME} or $${file:/path}.
+`))
+
+	cfg, err := Load(t.Context(), source, WithEnvProvider(interpolationEnv()))
+	require.NoError(t, err)
+	assert.Equal(t, "Work on docker-agent, read secrets with ${env:NAME} or ${file:/path}.", cfg.Agents.First().Instruction)
+}
+
+func TestLoad_NoInterpolation(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := Load(t.Context(), NewFileSource("testdata/interpolation/agent.yaml"), WithoutInterpolation())
+	require.NoError(t, err)
+
+	assert.Equal(t, "${env:MODEL_NAME}", cfg.Models["local"].Model)
+	assert.Equal(t, "${file:github_token}", cfg.Agents.First().Toolsets[0].Env["GITHUB_TOKEN"])
+}
+
+func TestLoad_InterpolationFileNotAllowedInRemoteSources(t *testing.T) {
+	t.Parallel()
+
+	i := interpolator{remote: true}
+	_, err := i.resolveFile("/etc/passwd")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "only allowed in local agent files")
+}
+
+func TestIsSecretKey(t *testing.T) {
+	t.Parallel()
+
+	for _, key := range []string{"token_key", "api_key", "GITHUB_TOKEN", "client-secret", "password", "apiKey", "Authorization"} {
+		assert.True(t, IsSecretKey(key), key)
+	}
+	for _, key := range []string{"max_tokens", "model", "keep_alive", "base_url", "keyboard"} {
+		assert.False(t, IsSecretKey(key), key)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := Load(t.Context(), NewFileSource("testdata/interpolation/agent.yaml"), WithEnvProvider(interpolationEnv()))
+	require.NoError(t, err)
+
+	doc, err := Redacted(cfg)
+	require.NoError(t, err)
+
+	out, err := yaml.Marshal(doc)
+	require.NoError(t, err)
+
+	assert.NotContains(t, string(out), "ghp_fromfile")
+	assert.NotContains(t, string(out), "sk-local")
+	assert.Contains(t, string(out), "GITHUB_TOKEN: "+redacted)
+	assert.Contains(t, string(out), "github.example.com")
+	assert.Contains(t, string(out), "qwen3")
+}
@@ -28,11 +28,64 @@ type Config struct {
 	RAG         map[string]RAGToolset     `json:"rag,omitempty"`
 	Metadata    Metadata                  `json:"metadata"`
 	Permissions *PermissionsConfig        `json:"permissions,omitempty"`
+	// Snippets are instruction fragments shared by the agents referencing
+	// them in instruction_refs.
+	Snippets Snippets `json:"snippets,omitempty"`
+	// Vars are the variables of the agent instruction templates. When set,
+	// instructions are rendered as Go text/template templates.
+	Vars map[string]any `json:"vars,omitempty"`
+	// VarsMissingKey controls what instruction templates do with undefined
+	// variables: "error" (the default) fails, "zero" and "default" render
+	// them as text/template does with the missingkey option of that name.
+	VarsMissingKey string `json:"vars_missing_key,omitempty"`
+	// Compaction configures the compaction of the sessions of the agents:
+	// the model summarizing them, its instructions and the messages kept.
+	Compaction *CompactionConfig `json:"compaction,omitempty"`
+	// Redaction configures the masking of the secrets found in the tool
+	// outputs, before they're sent to the model and stored in the session.
+	Redaction *RedactionConfig `json:"redaction,omitempty"`
+}
+
+// CompactionConfig configures the compaction of the sessions, when their
+// conversation gets too long for the context of the model.
+type CompactionConfig struct {
+	// Model generates the summaries, e.g. a cheaper model than the agents'
+	// ones: the name of a model of the configuration or a "provider/model"
+	// reference. Defaults to the model of the agent, which is also used
+	// when this one fails.
+	Model string `json:"model,omitempty"`
+	// PromptOverride is the path of a file replacing the instructions of
+	// the summarization, relative to the configuration file.
+	PromptOverride string `json:"prompt_override,omitempty"`
+	// KeepLastMessages is the number of most recent messages kept verbatim
+	// after the summary. Defaults to the messages of the last 20,000 tokens.
+	KeepLastMessages int `json:"keep_last_messages,omitempty"`
+}
+
+// RedactionConfig configures the masking of the secrets found in the tool
+// outputs. The common token formats (AWS keys, GitHub tokens, JWTs and
+// private keys) are always detected, unless Disabled.
+type RedactionConfig struct {
+	// Disabled keeps the tool outputs as they are.
+	Disabled bool `json:"disabled,omitempty"`
+	// Patterns detect more secrets, on top of the built-in ones.
+	Patterns []RedactionPattern `json:"patterns,omitempty"`
+	// Replacement replaces the secrets, {type} standing for their type.
+	// Defaults to "[REDACTED:{type}]".
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// RedactionPattern detects one type of secret with a regular expression.
+// When it has a capture group, only the text of the first one is masked.
+type RedactionPattern struct {
+	// Type names the secrets, e.g. in the replacement. Defaults to "custom".
+	Type  string `json:"type,omitempty"`
+	Regex string `json:"regex"`
 }
 
 // MCPToolset is a reusable MCP server definition stored in the top-level
 // "mcps" section. It is identical to a Toolset but skips the normal
-// Toolset.validate() call during YAML unmarshaling because the "type"
+// Toolset.Validate() call during YAML unmarshaling because the "type"
 // field is implicit (always "mcp") and the source (command/remote/ref)
 // is validated later during config resolution.
 type MCPToolset struct {
@@ -49,12 +102,12 @@ func (m *MCPToolset) UnmarshalYAML(unmarshal func(any) error) error {
 	}
 	m.Toolset = Toolset(tmp)
 	m.Type = "mcp"
-	return m.validate()
+	return m.Validate()
 }
 
 // RAGToolset is a reusable RAG source definition stored in the top-level
 // "rag" section. It is identical to a Toolset but skips the normal
-// Toolset.validate() call during YAML unmarshaling because the "type"
+// Toolset.Validate() call during YAML unmarshaling because the "type"
 // field is implicit (always "rag") and the RAG config is validated
 // during config resolution.
 type RAGToolset struct {
@@ -214,6 +267,74 @@ func (c *Agents) Update(name string, update func(a *AgentConfig)) bool {
 	return false
 }
 
+// Snippets are named instruction fragments, in the order they're defined.
+type Snippets []Snippet
+
+// Snippet is an instruction fragment, given inline or read from a file.
+// In YAML, a snippet given as a string is an inline instruction.
+type Snippet struct {
+	Name string `json:"-"`
+	// Instruction is the text of the snippet.
+	Instruction string `json:"instruction,omitempty"`
+	// File is the file holding the text of the snippet, relative to the
+	// configuration file.
+	File string `json:"file,omitempty"`
+}
+
+func (c *Snippets) UnmarshalYAML(unmarshal func(any) error) error {
+	var items yaml.MapSlice
+	if err := unmarshal(&items); err != nil {
+		return err
+	}
+
+	snippets := make([]Snippet, 0, len(items))
+	for _, item := range items {
+		name, ok := item.Key.(string)
+		if !ok {
+			return errors.New("snippet name must be a string")
+		}
+
+		snippet := Snippet{Name: name}
+		if instruction, ok := item.Value.(string); ok {
+			snippet.Instruction = instruction
+		} else {
+			valueBytes, err := yaml.Marshal(item.Value)
+			if err != nil {
+				return fmt.Errorf("failed to marshal snippet %s: %w", name, err)
+			}
+			if err := yaml.UnmarshalWithOptions(valueBytes, &snippet, yaml.DisallowUnknownField()); err != nil {
+				return fmt.Errorf("failed to unmarshal snippet %s: %w", name, err)
+			}
+			snippet.Name = name
+		}
+		snippets = append(snippets, snippet)
+	}
+
+	*c = snippets
+	return nil
+}
+
+func (c Snippets) MarshalYAML() (any, error) {
+	mapSlice := make(yaml.MapSlice, 0, len(c))
+	for _, snippet := range c {
+		mapSlice = append(mapSlice, yaml.MapItem{
+			Key:   snippet.Name,
+			Value: snippet,
+		})
+	}
+	return mapSlice, nil
+}
+
+// Lookup returns the snippet named name.
+func (c Snippets) Lookup(name string) (Snippet, bool) {
+	for _, snippet := range c {
+		if snippet.Name == name {
+			return snippet, true
+		}
+	}
+	return Snippet{}, false
+}
+
 // ProviderConfig represents a reusable provider definition.
 // It allows users to define providers with default settings that models can inherit.
 // Models referencing a provider by name will inherit any settings not explicitly overridden.
@@ -222,7 +343,7 @@ func (c *Agents) Update(name string, update func(a *AgentConfig)) bool {
 // "google", "amazon-bedrock"). When not set, it defaults to "openai" for backward compatibility.
 type ProviderConfig struct {
 	// Provider specifies the underlying provider type. Supported values include:
-	// "openai", "anthropic", "google", "amazon-bedrock", "dmr", and any built-in alias.
+	// "openai", "anthropic", "google", "amazon-bedrock", "dmr", "ollama", and any built-in alias.
 	// Defaults to "openai" when not set, preserving backward compatibility.
 	Provider string `json:"provider,omitempty"`
 	// APIType specifies which API schema to use. Only applicable for OpenAI-compatible providers.
@@ -365,13 +486,22 @@ type AgentConfig struct {
 	WelcomeMessage string          `json:"welcome_message,omitempty"`
 	Toolsets       []Toolset       `json:"toolsets,omitempty"`
 	Instruction    string          `json:"instruction,omitempty"`
-	SubAgents      []string        `json:"sub_agents,omitempty"`
-	Handoffs       []string        `json:"handoffs,omitempty"`
+	// InstructionRefs names the snippets composed, in this order, before
+	// the instruction of the agent.
+	InstructionRefs []string `json:"instruction_refs,omitempty"`
+	SubAgents       []string `json:"sub_agents,omitempty"`
+	Handoffs        []string `json:"handoffs,omitempty"`
+	// DisableBuiltinTools lists the built-in tools the agent doesn't get
+	// from its configuration: "transfer_task" for its sub-agents and
+	// "handoff" for its handoffs.
+	DisableBuiltinTools []string `json:"disable_builtin_tools,omitempty"`
 
 	AddDate                 bool              `json:"add_date,omitempty"`
 	AddEnvironmentInfo      bool              `json:"add_environment_info,omitempty"`
 	CodeModeTools           bool              `json:"code_mode_tools,omitempty"`
 	AddDescriptionParameter bool              `json:"add_description_parameter,omitempty"`
+	CiteSources             bool              `json:"cite_sources,omitempty"`
+	RequireFinalAnswer      bool              `json:"require_final_answer,omitempty"`
 	MaxIterations           int               `json:"max_iterations,omitempty"`
 	MaxConsecutiveToolCalls int               `json:"max_consecutive_tool_calls,omitempty"`
 	MaxOldToolCallTokens    int               `json:"max_old_tool_call_tokens,omitempty"`
@@ -381,6 +511,18 @@ type AgentConfig struct {
 	StructuredOutput        *StructuredOutput `json:"structured_output,omitempty"`
 	Skills                  SkillsConfig      `json:"skills,omitzero"`
 	Hooks                   *HooksConfig      `json:"hooks,omitempty"`
+	Tools                   AgentToolsConfig  `json:"tools,omitzero"`
+}
+
+// AgentToolsConfig selects the tools of an agent by category (e.g.
+// "filesystem", "lsp"), across all its toolsets. Tools of other categories
+// are never sent to the model.
+type AgentToolsConfig struct {
+	// IncludeCategories, when set, restricts the agent to the tools of these
+	// categories.
+	IncludeCategories []string `json:"include_categories,omitempty"`
+	// ExcludeCategories hides the tools of these categories from the agent.
+	ExcludeCategories []string `json:"exclude_categories,omitempty"`
 }
 
 const SkillSourceLocal = "local"
@@ -517,6 +659,20 @@ type ModelConfig struct {
 	BaseURL           string   `json:"base_url,omitempty"`
 	ParallelToolCalls *bool    `json:"parallel_tool_calls,omitempty"`
 	TokenKey          string   `json:"token_key,omitempty"`
+	// ExtraHeaders are additional HTTP headers sent with every request to the model API.
+	ExtraHeaders map[string]string `json:"extra_headers,omitempty"`
+	// Compat tunes requests for OpenAI-compatible servers (llama.cpp, vLLM, ...)
+	// that only implement part of the OpenAI API.
+	Compat *CompatConfig `json:"compat,omitempty"`
+	// Validate makes client construction fail fast when the model API is unreachable.
+	Validate bool `json:"validate,omitempty"`
+	// PromptCache controls where prompt caching breakpoints are placed.
+	// Only honored by the Anthropic provider.
+	PromptCache *PromptCacheConfig `json:"prompt_cache,omitempty"`
+	// BuiltinTools enables tools hosted by the provider, e.g. web_search or
+	// code_execution, which the provider maps to its native tools. Only the
+	// openai and anthropic providers support them.
+	BuiltinTools []string `json:"builtin_tools,omitempty"`
 	// ProviderOpts allows provider-specific options.
 	ProviderOpts map[string]any `json:"provider_opts,omitempty"`
 	TrackUsage   *bool          `json:"track_usage,omitempty"`
@@ -525,6 +681,10 @@ type ModelConfig struct {
 	// See [effort.ValidNames] for the full list of accepted strings.
 	// Provider-specific mappings are in the effort package.
 	ThinkingBudget *ThinkingBudget `json:"thinking_budget,omitempty"`
+	// Reasoning configures reasoning the same way for every provider; each
+	// provider maps it to its native parameters. ThinkingBudget takes
+	// precedence when both are set.
+	Reasoning *ReasoningConfig `json:"reasoning,omitempty"`
 	// TaskBudget caps the total tokens a model can spend across an agentic task.
 	// Forwarded to Anthropic as `output_config.task_budget` for every Claude
 	// model — docker-agent does not gate by model name. At the time of writing,
@@ -536,6 +696,50 @@ type ModelConfig struct {
 	// - The provider/model fields define the fallback model
 	// - Each routing rule maps to a different model based on examples
 	Routing []RoutingRule `json:"routing,omitempty"`
+	// Router configures the models whose provider is "router": each request
+	// goes to the cheapest of their candidates that the turn needs.
+	Router *RouterConfig `json:"router,omitempty"`
+}
+
+// CompatConfig disables parts of the OpenAI API that some compatible servers reject.
+type CompatConfig struct {
+	// DisableStreamOptions omits stream_options from streaming requests.
+	// Usage is then reported only if the server sends it unprompted.
+	DisableStreamOptions bool `json:"disable_stream_options,omitempty"`
+	// DisableParallelToolCalls never sends parallel_tool_calls, even when configured.
+	DisableParallelToolCalls bool `json:"disable_parallel_tool_calls,omitempty"`
+	// ToolsMode controls how tools are sent to the server:
+	// - "openai" (default): send tool definitions using the OpenAI function schema
+	// - "none": never send tool definitions
+	ToolsMode string `json:"tools_mode,omitempty"`
+	// UserRoleReminders sends the system reminders injected into the
+	// conversation as user messages, for servers that only accept a system
+	// message at the start of the conversation.
+	UserRoleReminders bool `json:"user_role_reminders,omitempty"`
+}
+
+const (
+	ToolsModeOpenAI = "openai"
+	ToolsModeNone   = "none"
+)
+
+// Tools hosted by the model providers, see ModelConfig.BuiltinTools.
+const (
+	BuiltinToolWebSearch     = "web_search"
+	BuiltinToolCodeExecution = "code_execution"
+)
+
+// PromptCacheConfig controls which parts of a request get a cache_control breakpoint.
+// Anthropic accepts at most 4 breakpoints per request; message breakpoints are
+// dropped first when the configuration asks for more.
+type PromptCacheConfig struct {
+	// System caches the system prompt. Defaults to true.
+	System *bool `json:"system,omitempty"`
+	// Tools caches the tool definitions. Defaults to false.
+	Tools *bool `json:"tools,omitempty"`
+	// LastNMessages caches the trailing N messages of the conversation.
+	// Defaults to 2. Use 0 to disable.
+	LastNMessages *int `json:"last_n_messages,omitempty"`
 }
 
 // Clone returns a deep copy of the ModelConfig.
@@ -606,11 +810,18 @@ func (f *FlexibleModelConfig) isShorthandOnly() bool {
 		f.BaseURL == "" &&
 		f.ParallelToolCalls == nil &&
 		f.TokenKey == "" &&
+		len(f.ExtraHeaders) == 0 &&
+		f.Compat == nil &&
+		!f.Validate &&
+		f.PromptCache == nil &&
+		len(f.BuiltinTools) == 0 &&
 		len(f.ProviderOpts) == 0 &&
 		f.TrackUsage == nil &&
 		f.ThinkingBudget == nil &&
+		f.Reasoning == nil &&
 		f.TaskBudget == nil &&
-		len(f.Routing) == 0
+		len(f.Routing) == 0 &&
+		f.Router == nil
 }
 
 // RoutingRule defines a single routing rule for model selection.
@@ -622,6 +833,21 @@ type RoutingRule struct {
 	Examples []string `json:"examples"`
 }
 
+// RouterConfig defines the candidates of an escalating router and when it
+// escalates from one to the next.
+type RouterConfig struct {
+	// Candidates are references to other models in the models section or
+	// inline model specs (e.g., "openai/gpt-4o"), from the cheapest to the
+	// strongest. The first one answers unless the turn needs a stronger one.
+	Candidates []string `json:"candidates"`
+	// MaxPromptTokens escalates the requests whose prompt is estimated to be
+	// larger than this many tokens to the second candidate. 0 means no limit.
+	MaxPromptTokens int64 `json:"max_prompt_tokens,omitempty"`
+	// MaxTools sends the requests with more tools than this to the strongest
+	// candidate. 0 means no limit.
+	MaxTools int `json:"max_tools,omitempty"`
+}
+
 type Metadata struct {
 	Author      string `json:"author,omitempty"`
 	License     string `json:"license,omitempty"`
@@ -692,12 +918,43 @@ type Toolset struct {
 
 	Defer DeferConfig `json:"defer" yaml:"defer,omitempty"`
 
+	// OutputLimit overrides the runtime's limit on the size of the tool
+	// outputs sent to the model.
+	OutputLimit *ToolOutputLimit `json:"output_limit,omitempty"`
+
+	// ToolTimeout overrides the runtime's limit on how long a call to one
+	// of the toolset's tools may run, in seconds. -1 disables the limit.
+	ToolTimeout int `json:"tool_timeout,omitempty"`
+
+	// Concurrency overrides how many calls to the toolset's tools may run
+	// at once, whichever the agents and sessions making them: 1 serializes
+	// them, -1 lifts the limit the toolset declares.
+	Concurrency int `json:"concurrency,omitempty"`
+
+	// NoResultCache keeps the results of the toolset's read-only tools out
+	// of the runtime's tool result cache, for tools whose results change on
+	// their own, like the time or a web search.
+	NoResultCache bool `json:"no_result_cache,omitempty"`
+
+	// NoRedaction keeps the outputs of the toolset's tools as they are, for
+	// tools that legitimately handle secrets, instead of masking the secrets
+	// found in them.
+	NoRedaction bool `json:"no_redaction,omitempty"`
+
 	// For the `mcp` tool
 	Command string   `json:"command,omitempty"`
 	Args    []string `json:"args,omitempty"`
 	Ref     string   `json:"ref,omitempty"`
 	Remote  Remote   `json:"remote"`
 	Config  any      `json:"config,omitempty"`
+	// Roots are the directories the MCP server may operate on, declared to the
+	// server during initialization. Relative paths are resolved against the
+	// working directory. Defaults to the working directory.
+	Roots []string `json:"roots,omitempty"`
+	// Sampling lets the MCP server request completions from the model of
+	// the agent, e.g. to summarize what it scraped. Servers may not sample
+	// without it.
+	Sampling *MCPSamplingConfig `json:"sampling,omitempty"`
 
 	// For `mcp` and `lsp` tools - version/package reference for auto-installation.
 	// Format: "owner/repo" or "owner/repo@version"
@@ -705,18 +962,35 @@ type Toolset struct {
 	// Set to "false" or "off" to disable auto-install for this toolset.
 	Version string `json:"version,omitempty"`
 
-	// For the `a2a` and `openapi` tools
+	// For the `a2a`, `openapi` and `agent` tools
 	Name    string            `json:"name,omitempty"`
 	URL     string            `json:"url,omitempty"`
 	Headers map[string]string `json:"headers,omitempty"`
 
 	// For `shell`, `script`, `mcp` or `lsp` tools
 	Env map[string]string `json:"env,omitempty"`
+	// EnvPassthrough scopes the environment of the toolset's subprocesses to
+	// Env and the variables it lists, by name or glob (e.g. "AWS_*"), instead
+	// of the whole process environment.
+	EnvPassthrough []string `json:"env_passthrough,omitempty"`
 
 	// For the `todo` tool
 	Shared bool `json:"shared,omitempty"`
 
-	// For the `memory` and `tasks` tools
+	// For the `think` tool: keep the thoughts of the previous turns in the
+	// conversation sent to the model. By default only the current turn's are.
+	KeepThoughts bool `json:"keep_thoughts,omitempty"`
+
+	// For the `shell` and `script` tools: report the files the commands
+	// change, found by comparing the files of the working directory before
+	// and after each call.
+	TrackFileChanges bool `json:"track_file_changes,omitempty"`
+
+	// For the `shell` tool: run the commands in a single shell that
+	// persists across calls, keeping the working directory and variables.
+	PersistentSession bool `json:"persistent_session,omitempty"`
+
+	// For the `memory`, `tasks` and `git` tools
 	Path string `json:"path,omitempty"`
 
 	// For the `script` tool
@@ -730,10 +1004,18 @@ type Toolset struct {
 	// For the `filesystem` tool - VCS integration
 	IgnoreVCS *bool `json:"ignore_vcs,omitempty"`
 
+	// For the `lsp` tool: a well-known language server (e.g. "go" for
+	// gopls) providing the defaults of the command, args and file types, or
+	// "auto" for the ones detected in the working directory.
+	Preset string `json:"preset,omitempty"`
 	// For the `lsp` tool
 	FileTypes []string `json:"file_types,omitempty"`
+	// For the `lsp` tool: the roots of the workspace, relative to the
+	// working directory. Defaults to the working directory.
+	WorkspaceFolders []string `json:"workspace_folders,omitempty"`
 
-	// For the `fetch` tool
+	// For the `fetch` tool, and the `ask_user` tool: how long to wait for an
+	// answer, in seconds
 	Timeout int `json:"timeout,omitempty"`
 
 	// For the `rag` tool
@@ -741,6 +1023,14 @@ type Toolset struct {
 
 	// For the `model_picker` tool
 	Models []string `json:"models,omitempty"`
+
+	// For the `agent` tool: the agent to run, the description and JSON
+	// schema of the tool's parameters, and the template of the prompt given
+	// to the agent, rendered with the arguments (e.g. "Review {{.file}}").
+	Agent       string         `json:"agent,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
+	Prompt      string         `json:"prompt,omitempty"`
 }
 
 func (t *Toolset) UnmarshalYAML(unmarshal func(any) error) error {
@@ -750,7 +1040,7 @@ func (t *Toolset) UnmarshalYAML(unmarshal func(any) error) error {
 		return err
 	}
 	*t = Toolset(tmp)
-	return t.validate()
+	return t.Validate()
 }
 
 type Remote struct {
@@ -769,6 +1059,29 @@ type RemoteOAuthConfig struct {
 	Scopes       []string `json:"scopes,omitempty"`
 }
 
+// MCPSamplingConfig is the policy of the sampling requests of an MCP server.
+// Their usage counts towards the cost of the session.
+type MCPSamplingConfig struct {
+	// Model runs the requests instead of the model of the agent, e.g. a
+	// cheaper one. Value can be a model name from the models section or
+	// "provider/model".
+	Model string `json:"model,omitempty"`
+	// MaxTokens caps the number of tokens a request may generate, whatever
+	// the server asks for.
+	MaxTokens int64 `json:"max_tokens,omitempty"`
+	// Confirm asks the user before running each request.
+	Confirm bool `json:"confirm,omitempty"`
+}
+
+// ToolOutputLimit limits the size of the outputs of a toolset's tools that
+// are sent to the model. Longer outputs are truncated according to Truncate:
+// "head" keeps the beginning, "tail" the end and "headtail" both ends.
+// A MaxBytes of -1 disables the limit; zero keeps the runtime's limit.
+type ToolOutputLimit struct {
+	MaxBytes int    `json:"max_bytes,omitempty"`
+	Truncate string `json:"truncate,omitempty"`
+}
+
 // DeferConfig represents the deferred loading configuration for a toolset.
 // It can be either a boolean (true to defer all tools) or a slice of strings
 // (list of tool names to defer).
@@ -812,6 +1125,19 @@ func (d DeferConfig) MarshalYAML() (any, error) {
 	return d.Tools, nil
 }
 
+// ReasoningConfig is a provider-independent reasoning configuration.
+// Anthropic maps it to a thinking budget_tokens, OpenAI to reasoning_effort,
+// and Google to a thinkingBudget (a thinkingLevel for Gemini 3). It is
+// ignored, with a warning, for the models that don't reason.
+type ReasoningConfig struct {
+	// Effort is the reasoning effort: low, medium or high.
+	Effort string `json:"effort,omitempty"`
+	// MaxThinkingTokens is the token budget of the providers with
+	// token-based thinking. It is derived from Effort when not set, and
+	// Effort from it for the providers with effort levels.
+	MaxThinkingTokens int `json:"max_thinking_tokens,omitempty"`
+}
+
 // ThinkingBudget represents reasoning budget configuration.
 // It accepts either a string effort level (see [effort.ValidNames]) or an
 // integer token budget.
@@ -1561,6 +1887,9 @@ type HooksConfig struct {
 	// Stop hooks run when the model finishes responding and is about to hand control back to the user
 	Stop []HookDefinition `json:"stop,omitempty" yaml:"stop,omitempty"`
 
+	// TurnEnd hooks run when the agent's turn ends, with the files changed and the tools used during the turn
+	TurnEnd []HookDefinition `json:"turn_end,omitempty" yaml:"turn_end,omitempty"`
+
 	// Notification hooks run when the agent sends a notification (error, warning) to the user
 	Notification []HookDefinition `json:"notification,omitempty" yaml:"notification,omitempty"`
 }
@@ -1576,6 +1905,7 @@ func (h *HooksConfig) IsEmpty() bool {
 		len(h.SessionEnd) == 0 &&
 		len(h.OnUserInput) == 0 &&
 		len(h.Stop) == 0 &&
+		len(h.TurnEnd) == 0 &&
 		len(h.Notification) == 0
 }
 
@@ -1592,14 +1922,26 @@ type HookMatcherConfig struct {
 
 // HookDefinition represents a single hook configuration
 type HookDefinition struct {
-	// Type specifies the hook type (currently only "command" is supported)
+	// Type specifies the hook type: "command", or "tool" for the
+	// session_start, turn_end and session_end hooks
 	Type string `json:"type" yaml:"type"`
 
 	// Command is the shell command to execute
 	Command string `json:"command,omitempty" yaml:"command,omitempty"`
 
+	// Tool is the name of the agent's tool to call, for tool hooks
+	Tool string `json:"tool,omitempty" yaml:"tool,omitempty"`
+
+	// Args are the fixed arguments of the tool call, for tool hooks
+	Args map[string]any `json:"args,omitempty" yaml:"args,omitempty"`
+
 	// Timeout is the execution timeout in seconds (default: 60)
 	Timeout int `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+
+	// Inject adds the output of the hook, or its failure, to the session as
+	// a system reminder for the next turn instead of only logging it. Only
+	// for the session_start, turn_end and session_end hooks.
+	Inject bool `json:"inject,omitempty" yaml:"inject,omitempty"`
 }
 
 // validate validates the HooksConfig
@@ -1620,35 +1962,42 @@ func (h *HooksConfig) validate() error {
 
 	// Validate SessionStart hooks
 	for i, hook := range h.SessionStart {
-		if err := hook.validate("session_start", i); err != nil {
+		if err := hook.validate("session_start", i, true); err != nil {
 			return err
 		}
 	}
 
 	// Validate SessionEnd hooks
 	for i, hook := range h.SessionEnd {
-		if err := hook.validate("session_end", i); err != nil {
+		if err := hook.validate("session_end", i, true); err != nil {
 			return err
 		}
 	}
 
 	// Validate OnUserInput hooks
 	for i, hook := range h.OnUserInput {
-		if err := hook.validate("on_user_input", i); err != nil {
+		if err := hook.validate("on_user_input", i, false); err != nil {
 			return err
 		}
 	}
 
 	// Validate Stop hooks
 	for i, hook := range h.Stop {
-		if err := hook.validate("stop", i); err != nil {
+		if err := hook.validate("stop", i, false); err != nil {
+			return err
+		}
+	}
+
+	// Validate TurnEnd hooks
+	for i, hook := range h.TurnEnd {
+		if err := hook.validate("turn_end", i, true); err != nil {
 			return err
 		}
 	}
 
 	// Validate Notification hooks
 	for i, hook := range h.Notification {
-		if err := hook.validate("notification", i); err != nil {
+		if err := hook.validate("notification", i, false); err != nil {
 			return err
 		}
 	}
@@ -1663,7 +2012,7 @@ func (m *HookMatcherConfig) validate(eventType string, index int) error {
 	}
 
 	for i, hook := range m.Hooks {
-		if err := hook.validate(fmt.Sprintf("%s[%d].hooks", eventType, index), i); err != nil {
+		if err := hook.validate(fmt.Sprintf("%s[%d].hooks", eventType, index), i, false); err != nil {
 			return err
 		}
 	}
@@ -1671,18 +2020,32 @@ func (m *HookMatcherConfig) validate(eventType string, index int) error {
 	return nil
 }
 
-// validate validates a HookDefinition
-func (h *HookDefinition) validate(prefix string, index int) error {
+// validate validates a HookDefinition. Tool hooks and injected outputs are
+// only supported by the lifecycle hooks: session_start, turn_end and
+// session_end.
+func (h *HookDefinition) validate(prefix string, index int, lifecycle bool) error {
 	if h.Type == "" {
 		return fmt.Errorf("hooks.%s[%d]: type is required", prefix, index)
 	}
 
-	if h.Type != "command" {
-		return fmt.Errorf("hooks.%s[%d]: unsupported hook type '%s' (only 'command' is supported)", prefix, index, h.Type)
+	switch h.Type {
+	case "command":
+		if h.Command == "" {
+			return fmt.Errorf("hooks.%s[%d]: command is required for command hooks", prefix, index)
+		}
+	case "tool":
+		if !lifecycle {
+			return fmt.Errorf("hooks.%s[%d]: tool hooks are only supported by session_start, turn_end and session_end hooks", prefix, index)
+		}
+		if h.Tool == "" {
+			return fmt.Errorf("hooks.%s[%d]: tool is required for tool hooks", prefix, index)
+		}
+	default:
+		return fmt.Errorf("hooks.%s[%d]: unsupported hook type '%s' (only 'command' and 'tool' are supported)", prefix, index, h.Type)
 	}
 
-	if h.Command == "" {
-		return fmt.Errorf("hooks.%s[%d]: command is required for command hooks", prefix, index)
+	if h.Inject && !lifecycle {
+		return fmt.Errorf("hooks.%s[%d]: inject is only supported by session_start, turn_end and session_end hooks", prefix, index)
 	}
 
 	return nil
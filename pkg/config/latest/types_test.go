@@ -420,3 +420,30 @@ func toFloat64(v any) float64 {
 		return 0
 	}
 }
+
+func TestFlexibleModelConfig_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]ModelConfig{
+		"extra_headers": {ExtraHeaders: map[string]string{"X-Team": "agents"}},
+		"compat":        {Compat: &CompatConfig{DisableStreamOptions: true}},
+		"validate":      {Validate: true},
+		"prompt_cache":  {PromptCache: &PromptCacheConfig{Tools: new(true)}},
+		"builtin_tools": {BuiltinTools: []string{"web_search"}},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg.Provider = "openai"
+			cfg.Model = "gpt-4o"
+			data, err := yaml.Marshal(FlexibleModelConfig{ModelConfig: cfg})
+			require.NoError(t, err)
+			require.Contains(t, string(data), name+":")
+
+			var loaded FlexibleModelConfig
+			require.NoError(t, yaml.Unmarshal(data, &loaded))
+			require.Equal(t, cfg, loaded.ModelConfig)
+		})
+	}
+}
@@ -2,6 +2,10 @@ package latest
 
 import (
 	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
 )
 
 func (t *Config) UnmarshalYAML(unmarshal func(any) error) error {
@@ -15,6 +19,51 @@ func (t *Config) UnmarshalYAML(unmarshal func(any) error) error {
 }
 
 func (t *Config) validate() error {
+	for name, model := range t.Models {
+		if err := model.validateCompat(); err != nil {
+			return fmt.Errorf("model '%s': %w", name, err)
+		}
+		if err := model.validateReasoning(); err != nil {
+			return fmt.Errorf("model '%s': %w", name, err)
+		}
+		if err := model.validateRouter(); err != nil {
+			return fmt.Errorf("model '%s': %w", name, err)
+		}
+		if err := model.validateBuiltinTools(); err != nil {
+			return fmt.Errorf("model '%s': %w", name, err)
+		}
+		if pc := model.PromptCache; pc != nil && pc.LastNMessages != nil && *pc.LastNMessages < 0 {
+			return fmt.Errorf("model '%s': prompt_cache.last_n_messages must be >= 0", name)
+		}
+	}
+
+	switch t.VarsMissingKey {
+	case "", "error", "zero", "default":
+	default:
+		return fmt.Errorf("vars_missing_key must be one of 'error', 'zero' or 'default', got '%s'", t.VarsMissingKey)
+	}
+
+	if c := t.Compaction; c != nil && c.KeepLastMessages < 0 {
+		return errors.New("compaction.keep_last_messages must be >= 0")
+	}
+
+	if r := t.Redaction; r != nil {
+		for i, pattern := range r.Patterns {
+			if pattern.Regex == "" {
+				return fmt.Errorf("redaction.patterns[%d]: regex is required", i)
+			}
+			if _, err := regexp.Compile(pattern.Regex); err != nil {
+				return fmt.Errorf("redaction.patterns[%d]: invalid regex: %w", i, err)
+			}
+		}
+	}
+
+	for _, snippet := range t.Snippets {
+		if err := snippet.validate(); err != nil {
+			return err
+		}
+	}
+
 	for i := range t.Agents {
 		agent := &t.Agents[i]
 
@@ -24,10 +73,20 @@ func (t *Config) validate() error {
 		}
 
 		for j := range agent.Toolsets {
-			if err := agent.Toolsets[j].validate(); err != nil {
+			if err := agent.Toolsets[j].Validate(); err != nil {
 				return err
 			}
 		}
+		for _, category := range agent.Tools.ExcludeCategories {
+			if slices.Contains(agent.Tools.IncludeCategories, category) {
+				return fmt.Errorf("agent '%s': tool category '%s' can't be both included and excluded", agent.Name, category)
+			}
+		}
+		for _, name := range agent.DisableBuiltinTools {
+			if !slices.Contains(disableableBuiltinTools, name) {
+				return fmt.Errorf("agent '%s': disable_builtin_tools: unknown built-in tool '%s', expected one of: transfer_task, handoff", agent.Name, name)
+			}
+		}
 		if agent.Hooks != nil {
 			if err := agent.Hooks.validate(); err != nil {
 				return err
@@ -38,6 +97,19 @@ func (t *Config) validate() error {
 	return nil
 }
 
+// disableableBuiltinTools are the built-in tools agents get from their
+// configuration rather than from a toolset.
+var disableableBuiltinTools = []string{"transfer_task", "handoff"}
+
+// validate checks that the snippet has either an inline instruction or a
+// file.
+func (s *Snippet) validate() error {
+	if (s.Instruction == "") == (s.File == "") {
+		return fmt.Errorf("snippet '%s' must have either an instruction or a file", s.Name)
+	}
+	return nil
+}
+
 // validateFallback validates the fallback configuration for an agent
 func (a *AgentConfig) validateFallback() error {
 	if a.Fallback == nil {
@@ -55,13 +127,75 @@ func (a *AgentConfig) validateFallback() error {
 	return nil
 }
 
-func (t *Toolset) validate() error {
+// validateCompat validates the OpenAI compatibility settings of a model
+func (m *ModelConfig) validateCompat() error {
+	if m.Compat == nil {
+		return nil
+	}
+
+	switch m.Compat.ToolsMode {
+	case "", ToolsModeOpenAI, ToolsModeNone:
+		return nil
+	default:
+		return fmt.Errorf("compat.tools_mode must be one of '%s' or '%s', got '%s'", ToolsModeOpenAI, ToolsModeNone, m.Compat.ToolsMode)
+	}
+}
+
+// validateReasoning validates the provider-independent reasoning settings of a model
+func (m *ModelConfig) validateReasoning() error {
+	if m.Reasoning == nil {
+		return nil
+	}
+
+	switch m.Reasoning.Effort {
+	case "", "low", "medium", "high":
+	default:
+		return fmt.Errorf("reasoning.effort must be one of 'low', 'medium' or 'high', got '%s'", m.Reasoning.Effort)
+	}
+	if m.Reasoning.MaxThinkingTokens < 0 {
+		return errors.New("reasoning.max_thinking_tokens must be >= 0")
+	}
+	if m.Reasoning.Effort == "" && m.Reasoning.MaxThinkingTokens == 0 {
+		return errors.New("reasoning requires an effort or max_thinking_tokens")
+	}
+
+	return nil
+}
+
+// validateRouter validates the candidates and escalation thresholds of an
+// escalating router
+func (m *ModelConfig) validateRouter() error {
+	if m.Provider != "router" {
+		if m.Router != nil {
+			return errors.New("router is only supported by models with provider 'router'")
+		}
+		return nil
+	}
+
+	if m.Router == nil || len(m.Router.Candidates) < 2 {
+		return errors.New("provider 'router' requires at least two router.candidates")
+	}
+	if len(m.Routing) > 0 {
+		return errors.New("provider 'router' can't be combined with routing rules")
+	}
+	if m.Router.MaxPromptTokens < 0 {
+		return errors.New("router.max_prompt_tokens must be >= 0")
+	}
+	if m.Router.MaxTools < 0 {
+		return errors.New("router.max_tools must be >= 0")
+	}
+
+	return nil
+}
+
+// Validate checks that the fields set on the toolset are consistent with its type.
+func (t *Toolset) Validate() error {
 	// Attributes used on the wrong toolset type.
 	if len(t.Shell) > 0 && t.Type != "script" {
 		return errors.New("shell can only be used with type 'script'")
 	}
-	if t.Path != "" && t.Type != "memory" && t.Type != "tasks" {
-		return errors.New("path can only be used with type 'memory' or 'tasks'")
+	if t.Path != "" && t.Type != "memory" && t.Type != "tasks" && t.Type != "git" {
+		return errors.New("path can only be used with type 'memory', 'tasks' or 'git'")
 	}
 	if len(t.PostEdit) > 0 && t.Type != "filesystem" {
 		return errors.New("post_edit can only be used with type 'filesystem'")
@@ -72,15 +206,57 @@ func (t *Toolset) validate() error {
 	if len(t.Env) > 0 && (t.Type != "shell" && t.Type != "script" && t.Type != "mcp" && t.Type != "lsp") {
 		return errors.New("env can only be used with type 'shell', 'script', 'mcp' or 'lsp'")
 	}
+	if t.EnvPassthrough != nil && (t.Type != "shell" && t.Type != "script" && t.Type != "mcp" && t.Type != "lsp") {
+		return errors.New("env_passthrough can only be used with type 'shell', 'script', 'mcp' or 'lsp'")
+	}
+	for _, pattern := range t.EnvPassthrough {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("env_passthrough: invalid pattern '%s'", pattern)
+		}
+	}
+	if t.Preset != "" && t.Type != "lsp" {
+		return errors.New("preset can only be used with type 'lsp'")
+	}
+	if t.Preset == "auto" && (t.Command != "" || len(t.Args) > 0 || len(t.FileTypes) > 0) {
+		return errors.New("the 'auto' lsp preset can't be used with command, args or file_types")
+	}
 	if len(t.FileTypes) > 0 && t.Type != "lsp" {
 		return errors.New("file_types can only be used with type 'lsp'")
 	}
+	if len(t.WorkspaceFolders) > 0 && t.Type != "lsp" {
+		return errors.New("workspace_folders can only be used with type 'lsp'")
+	}
 	if len(t.Models) > 0 && t.Type != "model_picker" {
 		return errors.New("models can only be used with type 'model_picker'")
 	}
 	if t.Shared && t.Type != "todo" {
 		return errors.New("shared can only be used with type 'todo'")
 	}
+	if l := t.OutputLimit; l != nil {
+		if l.MaxBytes < -1 {
+			return errors.New("output_limit.max_bytes must be >= -1 (use -1 for no limit)")
+		}
+		switch l.Truncate {
+		case "", "head", "tail", "headtail":
+		default:
+			return fmt.Errorf("output_limit.truncate must be one of 'head', 'tail' or 'headtail', got '%s'", l.Truncate)
+		}
+	}
+	if t.ToolTimeout < -1 {
+		return errors.New("tool_timeout must be >= -1 (use -1 for no timeout)")
+	}
+	if t.Concurrency < -1 {
+		return errors.New("concurrency must be >= -1 (use -1 for no limit)")
+	}
+	if t.KeepThoughts && t.Type != "think" {
+		return errors.New("keep_thoughts can only be used with type 'think'")
+	}
+	if t.TrackFileChanges && t.Type != "shell" && t.Type != "script" {
+		return errors.New("track_file_changes can only be used with type 'shell' or 'script'")
+	}
+	if t.PersistentSession && t.Type != "shell" {
+		return errors.New("persistent_session can only be used with type 'shell'")
+	}
 	if t.Version != "" && t.Type != "mcp" && t.Type != "lsp" {
 		return errors.New("version can only be used with type 'mcp' or 'lsp'")
 	}
@@ -105,11 +281,25 @@ func (t *Toolset) validate() error {
 	if t.Config != nil && t.Type != "mcp" {
 		return errors.New("config can only be used with type 'mcp'")
 	}
+	if len(t.Roots) > 0 && t.Type != "mcp" {
+		return errors.New("roots can only be used with type 'mcp'")
+	}
+	if t.Sampling != nil {
+		if t.Type != "mcp" {
+			return errors.New("sampling can only be used with type 'mcp'")
+		}
+		if t.Sampling.MaxTokens < 0 {
+			return errors.New("sampling.max_tokens must be positive")
+		}
+	}
 	if t.URL != "" && t.Type != "a2a" && t.Type != "openapi" {
 		return errors.New("url can only be used with type 'a2a' or 'openapi'")
 	}
-	if t.Name != "" && (t.Type != "mcp" && t.Type != "a2a" && t.Type != "rag") {
-		return errors.New("name can only be used with type 'mcp', 'a2a', or 'rag'")
+	if t.Name != "" && (t.Type != "mcp" && t.Type != "a2a" && t.Type != "rag" && t.Type != "agent") {
+		return errors.New("name can only be used with type 'mcp', 'a2a', 'rag', or 'agent'")
+	}
+	if (t.Agent != "" || t.Description != "" || len(t.Parameters) > 0 || t.Prompt != "") && t.Type != "agent" {
+		return errors.New("agent, description, parameters and prompt can only be used with type 'agent'")
 	}
 	if t.RAGConfig != nil && t.Type != "rag" {
 		return errors.New("rag_config can only be used with type 'rag'")
@@ -155,8 +345,8 @@ func (t *Toolset) validate() error {
 			return errors.New("a2a toolset requires a url to be set")
 		}
 	case "lsp":
-		if t.Command == "" {
-			return errors.New("lsp toolset requires a command to be set")
+		if t.Command == "" && t.Preset == "" {
+			return errors.New("lsp toolset requires a command or a preset to be set")
 		}
 	case "openapi":
 		if t.URL == "" {
@@ -171,9 +361,37 @@ func (t *Toolset) validate() error {
 		if t.Ref == "" && t.RAGConfig == nil {
 			return errors.New("rag toolset requires either ref or rag_config")
 		}
-	case "background_agents":
+	case "agent":
+		if t.Agent == "" {
+			return errors.New("agent toolset requires an agent to be set")
+		}
+	case "background_agents", "shared_context", "final_answer":
 		// no additional validation needed
 	}
 
 	return nil
 }
+
+// builtinToolProviders lists the providers supporting each built-in tool.
+var builtinToolProviders = map[string][]string{
+	BuiltinToolWebSearch:     {"openai", "anthropic"},
+	BuiltinToolCodeExecution: {"openai", "anthropic"},
+}
+
+// validateBuiltinTools checks that the provider of a model supports the
+// built-in tools it enables: they would otherwise be silently ignored.
+func (m *ModelConfig) validateBuiltinTools() error {
+	for _, name := range m.BuiltinTools {
+		providers, ok := builtinToolProviders[name]
+		if !ok {
+			return fmt.Errorf("builtin_tools: unknown tool '%s', expected one of: %s, %s", name, BuiltinToolWebSearch, BuiltinToolCodeExecution)
+		}
+		if !slices.Contains(providers, m.Provider) {
+			return fmt.Errorf("builtin_tools: provider '%s' doesn't support '%s'", m.Provider, name)
+		}
+	}
+	if len(m.BuiltinTools) > 0 && m.ProviderOpts["api_type"] == "openai_chatcompletions" {
+		return errors.New("builtin_tools require the Responses API, not api_type 'openai_chatcompletions'")
+	}
+	return nil
+}
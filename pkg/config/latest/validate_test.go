@@ -38,7 +38,7 @@ agents:
     toolsets:
       - type: lsp
 `,
-			wantErr: "lsp toolset requires a command to be set",
+			wantErr: "lsp toolset requires a command or a preset to be set",
 		},
 		{
 			name: "lsp with args",
@@ -84,6 +84,47 @@ agents:
 `,
 			wantErr: "",
 		},
+		{
+			name: "lsp with preset",
+			config: `
+version: "5"
+agents:
+  root:
+    model: "openai/gpt-4"
+    toolsets:
+      - type: lsp
+        preset: go
+`,
+			wantErr: "",
+		},
+		{
+			name: "auto lsp preset with command",
+			config: `
+version: "5"
+agents:
+  root:
+    model: "openai/gpt-4"
+    toolsets:
+      - type: lsp
+        preset: auto
+        command: gopls
+`,
+			wantErr: "the 'auto' lsp preset can't be used with command, args or file_types",
+		},
+		{
+			name: "preset on non-lsp toolset",
+			config: `
+version: "5"
+agents:
+  root:
+    model: "openai/gpt-4"
+    toolsets:
+      - type: mcp
+        command: server
+        preset: go
+`,
+			wantErr: "preset can only be used with type 'lsp'",
+		},
 		{
 			name: "file_types on non-lsp toolset",
 			config: `
@@ -97,6 +138,236 @@ agents:
 `,
 			wantErr: "file_types can only be used with type 'lsp'",
 		},
+		{
+			name: "workspace_folders on non-lsp toolset",
+			config: `
+version: "5"
+agents:
+  root:
+    model: "openai/gpt-4"
+    toolsets:
+      - type: shell
+        workspace_folders: [api, web]
+`,
+			wantErr: "workspace_folders can only be used with type 'lsp'",
+		},
+		{
+			name: "git with path",
+			config: `
+version: "5"
+agents:
+  root:
+    model: "openai/gpt-4"
+    toolsets:
+      - type: git
+        path: ./repo
+`,
+			wantErr: "",
+		},
+		{
+			name: "path on shell toolset",
+			config: `
+version: "5"
+agents:
+  root:
+    model: "openai/gpt-4"
+    toolsets:
+      - type: shell
+        path: ./repo
+`,
+			wantErr: "path can only be used with type 'memory', 'tasks' or 'git'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cfg Config
+			err := yaml.Unmarshal([]byte(tt.config), &cfg)
+
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestToolset_Validate_OutputLimit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		limit   string
+		wantErr string
+	}{
+		{name: "max_bytes and truncate", limit: "{max_bytes: 100000, truncate: tail}"},
+		{name: "no limit", limit: "{max_bytes: -1}"},
+		{name: "invalid max_bytes", limit: "{max_bytes: -2}", wantErr: "output_limit.max_bytes must be >= -1"},
+		{name: "invalid truncate", limit: "{truncate: middle}", wantErr: "output_limit.truncate must be one of 'head', 'tail' or 'headtail', got 'middle'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := `
+version: "5"
+agents:
+  root:
+    model: "openai/gpt-4"
+    toolsets:
+      - type: shell
+        output_limit: ` + tt.limit + `
+`
+			var cfg Config
+			err := yaml.Unmarshal([]byte(config), &cfg)
+
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestToolset_Validate_ToolTimeout(t *testing.T) {
+	t.Parallel()
+
+	for _, timeout := range []int{-1, 0, 600} {
+		toolset := Toolset{Type: "mcp", Command: "server", ToolTimeout: timeout}
+		require.NoError(t, toolset.Validate())
+	}
+
+	toolset := Toolset{Type: "mcp", Command: "server", ToolTimeout: -2}
+	require.ErrorContains(t, toolset.Validate(), "tool_timeout must be >= -1")
+}
+
+func TestToolset_Validate_Concurrency(t *testing.T) {
+	t.Parallel()
+
+	for _, concurrency := range []int{-1, 0, 1, 4} {
+		toolset := Toolset{Type: "mcp", Command: "server", Concurrency: concurrency}
+		require.NoError(t, toolset.Validate())
+	}
+
+	toolset := Toolset{Type: "mcp", Command: "server", Concurrency: -2}
+	require.ErrorContains(t, toolset.Validate(), "concurrency must be >= -1")
+}
+
+func TestToolset_Validate_TrackFileChanges(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, (&Toolset{Type: "shell", TrackFileChanges: true}).Validate())
+
+	toolset := Toolset{Type: "filesystem", TrackFileChanges: true}
+	require.ErrorContains(t, toolset.Validate(), "track_file_changes can only be used with type 'shell' or 'script'")
+}
+
+func TestToolset_Validate_PersistentSession(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, (&Toolset{Type: "shell", PersistentSession: true}).Validate())
+
+	toolset := Toolset{Type: "script", PersistentSession: true}
+	require.ErrorContains(t, toolset.Validate(), "persistent_session can only be used with type 'shell'")
+}
+
+func TestToolset_Validate_Sampling(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, (&Toolset{Type: "mcp", Command: "server", Sampling: &MCPSamplingConfig{Model: "openai/gpt-4o-mini", MaxTokens: 1000}}).Validate())
+
+	toolset := Toolset{Type: "fetch", Sampling: &MCPSamplingConfig{}}
+	require.ErrorContains(t, toolset.Validate(), "sampling can only be used with type 'mcp'")
+
+	toolset = Toolset{Type: "mcp", Command: "server", Sampling: &MCPSamplingConfig{MaxTokens: -1}}
+	require.ErrorContains(t, toolset.Validate(), "sampling.max_tokens must be positive")
+}
+
+func TestToolset_Validate_EnvPassthrough(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		toolset string
+		wantErr string
+	}{
+		{name: "shell", toolset: "{type: shell, env_passthrough: [PATH, 'AWS_*']}"},
+		{name: "mcp", toolset: "{type: mcp, command: server, env_passthrough: []}"},
+		{name: "wrong type", toolset: "{type: fetch, env_passthrough: [PATH]}", wantErr: "env_passthrough can only be used with type 'shell', 'script', 'mcp' or 'lsp'"},
+		{name: "invalid pattern", toolset: "{type: lsp, command: gopls, env_passthrough: ['GO[']}", wantErr: "env_passthrough: invalid pattern 'GO['"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := `
+version: "5"
+agents:
+  root:
+    model: "openai/gpt-4"
+    toolsets:
+      - ` + tt.toolset + `
+`
+			var cfg Config
+			err := yaml.Unmarshal([]byte(config), &cfg)
+
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestModelConfig_Validate_Compat(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{
+			name: "valid tools_mode",
+			config: `
+models:
+  local:
+    provider: openai
+    model: qwen
+    base_url: http://localhost:8080/v1
+    compat:
+      tools_mode: none
+      disable_stream_options: true
+agents:
+  root:
+    model: local
+`,
+		},
+		{
+			name: "invalid tools_mode",
+			config: `
+models:
+  local:
+    provider: openai
+    model: qwen
+    compat:
+      tools_mode: xml
+agents:
+  root:
+    model: local
+`,
+			wantErr: "model 'local': compat.tools_mode must be one of 'openai' or 'none', got 'xml'",
+		},
 	}
 
 	for _, tt := range tests {
@@ -115,3 +386,289 @@ agents:
 		})
 	}
 }
+
+func TestModelConfig_Validate_Reasoning(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		reasoning string
+		wantErr   string
+	}{
+		{
+			name:      "effort and tokens",
+			reasoning: "{effort: high, max_thinking_tokens: 16384}",
+		},
+		{
+			name:      "tokens only",
+			reasoning: "{max_thinking_tokens: 4096}",
+		},
+		{
+			name:      "invalid effort",
+			reasoning: "{effort: extreme}",
+			wantErr:   "model 'm': reasoning.effort must be one of 'low', 'medium' or 'high', got 'extreme'",
+		},
+		{
+			name:      "negative tokens",
+			reasoning: "{effort: low, max_thinking_tokens: -1}",
+			wantErr:   "model 'm': reasoning.max_thinking_tokens must be >= 0",
+		},
+		{
+			name:      "empty",
+			reasoning: "{}",
+			wantErr:   "model 'm': reasoning requires an effort or max_thinking_tokens",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := `
+models:
+  m:
+    provider: anthropic
+    model: claude-sonnet-4-5
+    reasoning: ` + tt.reasoning + `
+agents:
+  root:
+    model: m
+`
+			var cfg Config
+			err := yaml.Unmarshal([]byte(config), &cfg)
+
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestModelConfig_Validate_Router(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		model   string
+		wantErr string
+	}{
+		{
+			name:  "candidates and thresholds",
+			model: "{provider: router, router: {candidates: [anthropic/claude-haiku-4-5, anthropic/claude-sonnet-4-5], max_prompt_tokens: 30000, max_tools: 20}}",
+		},
+		{
+			name:    "single candidate",
+			model:   "{provider: router, router: {candidates: [anthropic/claude-haiku-4-5]}}",
+			wantErr: "model 'm': provider 'router' requires at least two router.candidates",
+		},
+		{
+			name:    "no router",
+			model:   "{provider: router}",
+			wantErr: "model 'm': provider 'router' requires at least two router.candidates",
+		},
+		{
+			name:    "negative threshold",
+			model:   "{provider: router, router: {candidates: [a/b, c/d], max_tools: -1}}",
+			wantErr: "model 'm': router.max_tools must be >= 0",
+		},
+		{
+			name:    "router on another provider",
+			model:   "{provider: openai, model: gpt-4o, router: {candidates: [a/b, c/d]}}",
+			wantErr: "model 'm': router is only supported by models with provider 'router'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := `
+models:
+  m: ` + tt.model + `
+agents:
+  root:
+    model: m
+`
+			var cfg Config
+			err := yaml.Unmarshal([]byte(config), &cfg)
+
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestModelConfig_Validate_BuiltinTools(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		model   string
+		wantErr string
+	}{
+		{
+			name:  "anthropic",
+			model: "{provider: anthropic, model: claude-sonnet-4-5, builtin_tools: [web_search, code_execution]}",
+		},
+		{
+			name:  "openai",
+			model: "{provider: openai, model: gpt-5, builtin_tools: [web_search]}",
+		},
+		{
+			name:    "unknown tool",
+			model:   "{provider: openai, model: gpt-5, builtin_tools: [browser]}",
+			wantErr: "model 'm': builtin_tools: unknown tool 'browser'",
+		},
+		{
+			name:    "unsupported provider",
+			model:   "{provider: google, model: gemini-2.5-flash, builtin_tools: [web_search]}",
+			wantErr: "model 'm': builtin_tools: provider 'google' doesn't support 'web_search'",
+		},
+		{
+			name:    "chat completions",
+			model:   "{provider: openai, model: gpt-5, builtin_tools: [code_execution], provider_opts: {api_type: openai_chatcompletions}}",
+			wantErr: "model 'm': builtin_tools require the Responses API",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := `
+models:
+  m: ` + tt.model + `
+agents:
+  root:
+    model: m
+`
+			var cfg Config
+			err := yaml.Unmarshal([]byte(config), &cfg)
+
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_VarsMissingKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{
+			name: "vars without policy",
+			config: `
+vars:
+  project: cagent
+  standards:
+    lang: go
+agents:
+  root:
+    model: openai/gpt-4o
+    instruction: Work on {{.project}}
+`,
+		},
+		{
+			name: "valid policy",
+			config: `
+vars:
+  project: cagent
+vars_missing_key: zero
+agents:
+  root:
+    model: openai/gpt-4o
+`,
+		},
+		{
+			name: "invalid policy",
+			config: `
+vars_missing_key: ignore
+agents:
+  root:
+    model: openai/gpt-4o
+`,
+			wantErr: "vars_missing_key must be one of 'error', 'zero' or 'default', got 'ignore'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cfg Config
+			err := yaml.Unmarshal([]byte(tt.config), &cfg)
+
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				require.NotNil(t, cfg.Vars)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_ToolCategories(t *testing.T) {
+	t.Parallel()
+
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+agents:
+  root:
+    model: openai/gpt-4o
+    tools:
+      include_categories: [filesystem, lsp]
+      exclude_categories: [shell]
+`), &cfg))
+	require.Equal(t, []string{"filesystem", "lsp"}, cfg.Agents[0].Tools.IncludeCategories)
+	require.Equal(t, []string{"shell"}, cfg.Agents[0].Tools.ExcludeCategories)
+
+	err := yaml.Unmarshal([]byte(`
+agents:
+  root:
+    model: openai/gpt-4o
+    tools:
+      include_categories: [lsp]
+      exclude_categories: [lsp]
+`), &cfg)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "agent 'root': tool category 'lsp' can't be both included and excluded")
+}
+
+func TestConfig_Validate_DisableBuiltinTools(t *testing.T) {
+	t.Parallel()
+
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+agents:
+  root:
+    model: openai/gpt-4o
+    disable_builtin_tools: [handoff]
+`), &cfg))
+	require.Equal(t, []string{"handoff"}, cfg.Agents[0].DisableBuiltinTools)
+
+	err := yaml.Unmarshal([]byte(`
+agents:
+  root:
+    model: openai/gpt-4o
+    disable_builtin_tools: [shell]
+`), &cfg)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "agent 'root': disable_builtin_tools: unknown built-in tool 'shell'")
+}
@@ -74,6 +74,12 @@ func applyMCPDefaults(ts, def *latest.Toolset) {
 	if ts.Config == nil {
 		ts.Config = def.Config
 	}
+	if len(ts.Roots) == 0 {
+		ts.Roots = def.Roots
+	}
+	if ts.EnvPassthrough == nil {
+		ts.EnvPassthrough = def.EnvPassthrough
+	}
 	if ts.Name == "" {
 		ts.Name = def.Name
 	}
@@ -42,6 +42,17 @@ func ResolveModelAliases(ctx context.Context, cfg *latest.Config, store *modelsd
 				}
 			}
 		}
+
+		// Resolve model aliases in router candidates
+		if modelCfg.Router != nil {
+			for i, candidate := range modelCfg.Router.Candidates {
+				if provider, model, ok := strings.Cut(candidate, "/"); ok {
+					if resolved := store.ResolveModelAlias(ctx, provider, model); resolved != model {
+						modelCfg.Router.Candidates[i] = provider + "/" + resolved
+					}
+				}
+			}
+		}
 		cfg.Models[name] = modelCfg
 	}
 }
@@ -107,6 +107,13 @@ func ensureModelsExist(cfg *latest.Config) error {
 				return err
 			}
 		}
+		if modelCfg.Router != nil {
+			for i, candidate := range modelCfg.Router.Candidates {
+				if err := ensureSingleModelExists(cfg, candidate, fmt.Sprintf("router candidate %d in model '%s'", i, modelName)); err != nil {
+					return err
+				}
+			}
+		}
 	}
 
 	// Ensure models referenced by RAG strategies exist
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/goccy/go-yaml"
+
+	"github.com/docker/docker-agent/pkg/config/latest"
+)
+
+// redacted replaces secret values in logs and config dumps.
+const redacted = "REDACTED"
+
+// secretKeyWords are the words that mark a config key as holding a secret.
+var secretKeyWords = []string{"token", "key", "secret", "password", "apikey", "authorization"}
+
+// IsSecretKey returns true if a config key looks like it holds a secret,
+// i.e. if one of its words is token, key, secret, password or authorization
+// (api_key, GITHUB_TOKEN, client-secret, ...). max_tokens is not a secret.
+func IsSecretKey(key string) bool {
+	words := strings.FieldsFunc(strings.ToLower(key), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return slices.ContainsFunc(words, func(word string) bool {
+		return slices.Contains(secretKeyWords, word)
+	})
+}
+
+// RedactValue returns value, or a placeholder if key holds a secret.
+func RedactValue(key, value string) string {
+	if value != "" && IsSecretKey(key) {
+		return redacted
+	}
+	return value
+}
+
+// Redacted returns a YAML document of the configuration where the values of
+// secret-ish keys are replaced with a placeholder.
+func Redacted(cfg *latest.Config) (any, error) {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc any
+	if err := yaml.UnmarshalWithOptions(data, &doc, yaml.UseOrderedMap()); err != nil {
+		return nil, err
+	}
+
+	return walkStrings(doc, "", "", func(_, key, value string) (string, error) {
+		return RedactValue(key, value), nil
+	})
+}
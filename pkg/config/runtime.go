@@ -24,6 +24,7 @@ type Config struct {
 	DefaultModel   *latest.ModelConfig
 	GlobalCodeMode bool
 	WorkingDir     string
+	NoInterpolate  bool
 	Models         map[string]latest.ModelConfig
 	Providers      map[string]latest.ProviderConfig
 
@@ -64,6 +65,14 @@ func (runConfig *RuntimeConfig) EnvProvider() environment.Provider {
 	return env
 }
 
+// LoadOpts returns the options used to load agent configurations with this runtime config.
+func (runConfig *RuntimeConfig) LoadOpts() []LoadOpt {
+	if runConfig.NoInterpolate {
+		return []LoadOpt{WithoutInterpolation()}
+	}
+	return []LoadOpt{WithEnvProvider(runConfig.EnvProvider())}
+}
+
 func (runConfig *RuntimeConfig) computedEnvProvider() environment.Provider {
 	defaultEnv := environment.NewDefaultProvider()
 
@@ -89,6 +89,7 @@ func TestSchemaMatchesGoTypes(t *testing.T) {
 		"HookMatcherConfig":     reflect.TypeFor[latest.HookMatcherConfig](),
 		"HookDefinition":        reflect.TypeFor[latest.HookDefinition](),
 		"RoutingRule":           reflect.TypeFor[latest.RoutingRule](),
+		"RouterConfig":          reflect.TypeFor[latest.RouterConfig](),
 		"ApiConfig":             reflect.TypeFor[latest.APIToolConfig](),
 	}
 
@@ -110,6 +111,7 @@ func TestSchemaMatchesGoTypes(t *testing.T) {
 
 	inlines := []inlineEntry{
 		{reflect.TypeFor[latest.StructuredOutput](), []string{"AgentConfig", "structured_output"}, "StructuredOutput (AgentConfig.structured_output)"},
+		{reflect.TypeFor[latest.ToolOutputLimit](), []string{"Toolset", "output_limit"}, "ToolOutputLimit (Toolset.output_limit)"},
 		{reflect.TypeFor[latest.RAGConfig](), []string{"RAGConfig"}, "RAGConfig"},
 		{reflect.TypeFor[latest.RAGToolConfig](), []string{"RAGConfig", "tool"}, "RAGToolConfig (RAGConfig.tool)"},
 		{reflect.TypeFor[latest.RAGResultsConfig](), []string{"RAGConfig", "results"}, "RAGResultsConfig (RAGConfig.results)"},
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/docker/docker-agent/pkg/config/latest"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// defaultSecretType is the type of the secrets detected by the redaction
+// patterns that don't name one.
+const defaultSecretType = "custom"
+
+// SecretRedactor returns the Redactor masking the secrets in the tool
+// outputs, from the redaction section of the configuration: the built-in
+// patterns, and the configured ones. It returns a Redactor masking nothing
+// when redaction is disabled.
+func SecretRedactor(cfg *latest.Config) (*tools.Redactor, error) {
+	rc := cfg.Redaction
+	if rc == nil {
+		return tools.DefaultRedactor(), nil
+	}
+	if rc.Disabled {
+		return &tools.Redactor{}, nil
+	}
+
+	patterns := make([]tools.SecretPattern, 0, len(rc.Patterns))
+	for i, pattern := range rc.Patterns {
+		re, err := regexp.Compile(pattern.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("redaction.patterns[%d]: invalid regex: %w", i, err)
+		}
+		typ := pattern.Type
+		if typ == "" {
+			typ = defaultSecretType
+		}
+		patterns = append(patterns, tools.SecretPattern{Type: typ, Regexp: re})
+	}
+
+	return tools.NewRedactor(true, patterns, rc.Replacement), nil
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/docker/docker-agent/pkg/config/latest"
+)
+
+// InstructionFragments returns the text of the snippets agent references in
+// instruction_refs, in the order it references them. The files of file
+// snippets are read relative to baseDir, usually the directory of the
+// configuration file. Remote configurations have no baseDir and can't use
+// file snippets.
+func InstructionFragments(cfg *latest.Config, agent *latest.AgentConfig, baseDir string) ([]string, error) {
+	fragments := make([]string, 0, len(agent.InstructionRefs))
+	for _, ref := range agent.InstructionRefs {
+		snippet, ok := cfg.Snippets.Lookup(ref)
+		if !ok {
+			return nil, fmt.Errorf("agent '%s' references non-existent snippet '%s'", agent.Name, ref)
+		}
+		if snippet.File == "" {
+			fragments = append(fragments, snippet.Instruction)
+			continue
+		}
+
+		if baseDir == "" {
+			return nil, fmt.Errorf("agent '%s': snippet '%s' reads a file, which is only allowed in local agent files", agent.Name, ref)
+		}
+		buf, err := os.ReadFile(includePath(snippet.File, baseDir))
+		if err != nil {
+			return nil, fmt.Errorf("agent '%s': reading snippet '%s': %w", agent.Name, ref, err)
+		}
+		fragments = append(fragments, string(buf))
+	}
+	return fragments, nil
+}
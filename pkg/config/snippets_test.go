@@ -0,0 +1,60 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstructionFragments(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "prompts"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "prompts", "conventions.md"), []byte("Follow the Go conventions.\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "agent.yaml"), []byte(`
+snippets:
+  tone: Be concise.
+  conventions:
+    file: prompts/conventions.md
+agents:
+  root:
+    model: openai/gpt-4o
+    instruction: Review the pull request.
+    instruction_refs: [tone, conventions]
+`), 0o644))
+
+	// Snippet files are relative to the configuration file, not to the
+	// working directory.
+	source := NewFileSource(filepath.Join(dir, "agent.yaml"))
+	cfg, err := Load(t.Context(), source)
+	require.NoError(t, err)
+
+	agent, ok := cfg.Agents.Lookup("root")
+	require.True(t, ok)
+
+	fragments, err := InstructionFragments(cfg, &agent, source.ParentDir())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Be concise.", "Follow the Go conventions.\n"}, fragments)
+
+	_, err = InstructionFragments(cfg, &agent, "")
+	require.ErrorContains(t, err, "only allowed in local agent files")
+
+	_, err = InstructionFragments(cfg, &agent, t.TempDir())
+	require.ErrorContains(t, err, "reading snippet 'conventions'")
+}
+
+func TestLoad_UndefinedSnippet(t *testing.T) {
+	t.Parallel()
+
+	_, err := Load(t.Context(), NewBytesSource("agent.yaml", []byte(`
+agents:
+  root:
+    model: openai/gpt-4o
+    instruction_refs: [missing]
+`)))
+	require.ErrorContains(t, err, "agent 'root' references non-existent snippet 'missing'")
+}
@@ -0,0 +1,426 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/docker/docker-agent/pkg/config/latest"
+	"github.com/docker/docker-agent/pkg/environment"
+)
+
+// Severity is the severity of a configuration Diagnostic.
+type Severity string
+
+const (
+	// SeverityError marks a configuration that can't be run.
+	SeverityError Severity = "error"
+	// SeverityWarning marks a configuration that runs but is probably not what the user meant.
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a problem found in an agent configuration.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	// Path is the YAML path of the offending value, e.g. "agents.root.sub_agents[1]".
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	if d.Path == "" {
+		return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", d.Severity, d.Path, d.Message)
+}
+
+// ValidationError is returned by Validate when a configuration has errors.
+type ValidationError struct {
+	Diagnostics []Diagnostic
+}
+
+func (e *ValidationError) Error() string {
+	var errs []string
+	for _, d := range e.Diagnostics {
+		if d.Severity == SeverityError {
+			errs = append(errs, d.String())
+		}
+	}
+	if len(errs) == 1 {
+		return "invalid agent configuration: " + errs[0]
+	}
+	return fmt.Sprintf("invalid agent configuration, %d errors:\n  %s", len(errs), strings.Join(errs, "\n  "))
+}
+
+// knownToolsetTypes are the toolset types built into docker-agent.
+var knownToolsetTypes = []string{
+	"a2a",
+	"agent",
+	"api",
+	"ask_user",
+	"background_agents",
+	"fetch",
+	"filesystem",
+	"final_answer",
+	"git",
+	"lsp",
+	"mcp",
+	"memory",
+	"model_picker",
+	"openapi",
+	"rag",
+	"script",
+	"shared_context",
+	"shell",
+	"tasks",
+	"think",
+	"todo",
+	"user_prompt",
+}
+
+type validateOptions struct {
+	toolsetTypes []string
+	lookupEnv    func(name string) bool
+	baseDir      string
+}
+
+// ValidateOpt configures Validate.
+type ValidateOpt func(*validateOptions)
+
+// WithToolsetTypes declares additional toolset types, registered in a custom
+// toolset registry, as known.
+func WithToolsetTypes(types ...string) ValidateOpt {
+	return func(opts *validateOptions) {
+		opts.toolsetTypes = append(opts.toolsetTypes, types...)
+	}
+}
+
+// WithEnvLookup checks that the variables required by toolsets with a scoped
+// environment, the ones listed in env_passthrough and the ones referenced in
+// env, are set in env.
+func WithEnvLookup(ctx context.Context, env environment.Provider) ValidateOpt {
+	return func(opts *validateOptions) {
+		opts.lookupEnv = func(name string) bool {
+			_, ok := env.Get(ctx, name)
+			return ok
+		}
+	}
+}
+
+// WithBaseDir checks that the files of file snippets exist, relative to
+// dir, usually the directory of the configuration file.
+func WithBaseDir(dir string) ValidateOpt {
+	return func(opts *validateOptions) {
+		opts.baseDir = dir
+	}
+}
+
+// Validate checks the consistency of an agent configuration and returns all
+// the problems it finds, rather than stopping at the first one.
+//
+// It checks that:
+//   - at least one agent is defined, and each one only once,
+//   - sub_agents, handoffs and agent toolsets reference defined agents,
+//   - snippets are defined once and instruction_refs reference them,
+//   - with WithBaseDir, the files of file snippets exist,
+//   - model references resolve,
+//   - toolsets have a known type and their required fields,
+//   - with WithEnvLookup, the variables of scoped toolset environments are set,
+//   - sub-agents, and agents run as tools, don't form a cycle.
+//
+// The returned error is a *ValidationError if at least one diagnostic is an error.
+func Validate(cfg latest.Config, opts ...ValidateOpt) ([]Diagnostic, error) {
+	validateOpts := validateOptions{toolsetTypes: slices.Clone(knownToolsetTypes)}
+	for _, opt := range opts {
+		opt(&validateOpts)
+	}
+
+	v := validator{
+		cfg:          &cfg,
+		toolsetTypes: validateOpts.toolsetTypes,
+		lookupEnv:    validateOpts.lookupEnv,
+		baseDir:      validateOpts.baseDir,
+	}
+	v.validateSnippets()
+	v.validateAgents()
+	v.validateModels()
+	v.validateCycles()
+
+	if slices.ContainsFunc(v.diagnostics, func(d Diagnostic) bool { return d.Severity == SeverityError }) {
+		return v.diagnostics, &ValidationError{Diagnostics: v.diagnostics}
+	}
+	return v.diagnostics, nil
+}
+
+type validator struct {
+	cfg          *latest.Config
+	toolsetTypes []string
+	lookupEnv    func(name string) bool
+	baseDir      string
+	diagnostics  []Diagnostic
+}
+
+func (v *validator) errorf(path, format string, args ...any) {
+	v.diagnostics = append(v.diagnostics, Diagnostic{Severity: SeverityError, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) warnf(path, format string, args ...any) {
+	v.diagnostics = append(v.diagnostics, Diagnostic{Severity: SeverityWarning, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// rootAgent returns the name of the agent that runs by default: the one named
+// "root", or the first one.
+func (v *validator) rootAgent() string {
+	if _, ok := v.cfg.Agents.Lookup("root"); ok {
+		return "root"
+	}
+	return v.cfg.Agents[0].Name
+}
+
+func (v *validator) validateAgents() {
+	if len(v.cfg.Agents) == 0 {
+		v.errorf("agents", "no agent is defined")
+		return
+	}
+
+	defined := map[string]bool{}
+	for _, agent := range v.cfg.Agents {
+		if defined[agent.Name] {
+			v.errorf("agents."+agent.Name, "agent '%s' is defined more than once", agent.Name)
+		}
+		defined[agent.Name] = true
+	}
+
+	for _, agent := range v.cfg.Agents {
+		path := "agents." + agent.Name
+
+		v.validateAgentRefs(path+".sub_agents", agent.Name, "sub-agent", agent.SubAgents, defined)
+		v.validateAgentRefs(path+".handoffs", agent.Name, "handoff agent", agent.Handoffs, defined)
+
+		for i, ref := range agent.InstructionRefs {
+			if _, ok := v.cfg.Snippets.Lookup(ref); !ok {
+				v.errorf(fmt.Sprintf("%s.instruction_refs[%d]", path, i), "agent '%s' references non-existent snippet '%s'", agent.Name, ref)
+			}
+		}
+
+		for modelRef := range strings.SplitSeq(agent.Model, ",") {
+			v.validateModelRef(path+".model", modelRef, fmt.Sprintf("agent '%s'", agent.Name))
+		}
+		for i, modelRef := range agent.GetFallbackModels() {
+			v.validateModelRef(fmt.Sprintf("%s.fallback.models[%d]", path, i), modelRef, fmt.Sprintf("agent '%s'", agent.Name))
+		}
+
+		for i := range agent.Toolsets {
+			toolsetPath := fmt.Sprintf("%s.toolsets[%d]", path, i)
+			v.validateToolset(toolsetPath, &agent.Toolsets[i])
+			if toolset := agent.Toolsets[i]; toolset.Type == "agent" && toolset.Agent != "" && !defined[toolset.Agent] {
+				v.errorf(toolsetPath+".agent", "agent '%s' references non-existent agent '%s' in an agent toolset", agent.Name, toolset.Agent)
+			}
+		}
+	}
+
+	// Agents that are neither the root nor reachable from it only run with --agent.
+	root := v.rootAgent()
+	reachable := map[string]bool{root: true}
+	queue := []string{root}
+	for len(queue) > 0 {
+		agent, _ := v.cfg.Agents.Lookup(queue[0])
+		queue = queue[1:]
+		for _, ref := range slices.Concat(agent.SubAgents, agent.Handoffs, agentToolRefs(&agent)) {
+			if defined[ref] && !reachable[ref] {
+				reachable[ref] = true
+				queue = append(queue, ref)
+			}
+		}
+	}
+	for _, agent := range v.cfg.Agents {
+		if !reachable[agent.Name] {
+			v.warnf("agents."+agent.Name, "agent '%s' is not reachable from the root agent '%s' and only runs with --agent %s", agent.Name, root, agent.Name)
+		}
+	}
+}
+
+func (v *validator) validateSnippets() {
+	defined := map[string]bool{}
+	for _, snippet := range v.cfg.Snippets {
+		path := "snippets." + snippet.Name
+		if defined[snippet.Name] {
+			v.errorf(path, "snippet '%s' is defined more than once", snippet.Name)
+		}
+		defined[snippet.Name] = true
+
+		switch {
+		case (snippet.Instruction == "") == (snippet.File == ""):
+			v.errorf(path, "snippet '%s' must have either an instruction or a file", snippet.Name)
+		case snippet.File != "" && v.baseDir != "":
+			if _, err := os.Stat(includePath(snippet.File, v.baseDir)); err != nil {
+				v.errorf(path+".file", "snippet '%s': %v", snippet.Name, err)
+			}
+		}
+	}
+}
+
+func (v *validator) validateAgentRefs(path, agentName, kind string, refs []string, defined map[string]bool) {
+	for i, ref := range refs {
+		refPath := fmt.Sprintf("%s[%d]", path, i)
+		if IsExternalReference(ref) {
+			if name, _ := ParseExternalAgentRef(ref); defined[name] {
+				v.errorf(refPath, "agent '%s': external %s '%s' resolves to name '%s' which conflicts with a locally-defined agent", agentName, kind, ref, name)
+			}
+			continue
+		}
+		if !defined[ref] {
+			v.errorf(refPath, "agent '%s' references non-existent %s '%s'", agentName, kind, ref)
+		}
+	}
+}
+
+func (v *validator) validateModelRef(path, modelRef, context string) {
+	modelRef = strings.TrimSpace(modelRef)
+	if modelRef == "" || modelRef == "auto" {
+		return
+	}
+	if _, exists := v.cfg.Models[modelRef]; exists {
+		return
+	}
+	if _, err := latest.ParseModelRef(modelRef); err != nil {
+		v.errorf(path, "%s references non-existent model '%s' (define it in the models section or use the 'provider/model' format)", context, modelRef)
+	}
+}
+
+func (v *validator) validateToolset(path string, toolset *latest.Toolset) {
+	if !slices.Contains(v.toolsetTypes, toolset.Type) {
+		v.errorf(path+".type", "unknown toolset type '%s' (must be one of: %s)", toolset.Type, strings.Join(v.toolsetTypes, ", "))
+		return
+	}
+	if err := toolset.Validate(); err != nil {
+		v.errorf(path, "%s toolset: %v", toolset.Type, err)
+	}
+	if toolset.Model != "" {
+		v.validateModelRef(path+".model", toolset.Model, toolset.Type+" toolset")
+	}
+	if toolset.Sampling != nil && toolset.Sampling.Model != "" {
+		v.validateModelRef(path+".sampling.model", toolset.Sampling.Model, "sampling of "+toolset.Type+" toolset")
+	}
+	if toolset.EnvPassthrough != nil && v.lookupEnv != nil {
+		v.validateToolsetEnv(path, toolset)
+	}
+}
+
+// validateToolsetEnv reports the variables a toolset with a scoped
+// environment requires but that are not set, rather than letting the toolset
+// fail, or run without them, later. Passed through variables are often
+// optional, e.g. LANG, so they are only warned about.
+func (v *validator) validateToolsetEnv(path string, toolset *latest.Toolset) {
+	for i, name := range toolset.EnvPassthrough {
+		if !environment.IsPattern(name) && !v.lookupEnv(name) {
+			v.warnf(fmt.Sprintf("%s.env_passthrough[%d]", path, i), "%s toolset: environment variable '%s' is not set", toolset.Type, name)
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(toolset.Env)) {
+		os.Expand(toolset.Env[key], func(name string) string {
+			if !v.lookupEnv(name) {
+				v.errorf(path+".env."+key, "%s toolset: environment variable '%s' is not set", toolset.Type, name)
+			}
+			return ""
+		})
+	}
+}
+
+func (v *validator) validateModels() {
+	for _, name := range slices.Sorted(maps.Keys(v.cfg.Models)) {
+		for i, rule := range v.cfg.Models[name].Routing {
+			v.validateModelRef(fmt.Sprintf("models.%s.routing[%d].model", name, i), rule.Model, fmt.Sprintf("routing rule %d in model '%s'", i, name))
+		}
+		if router := v.cfg.Models[name].Router; router != nil {
+			for i, candidate := range router.Candidates {
+				v.validateModelRef(fmt.Sprintf("models.%s.router.candidates[%d]", name, i), candidate, fmt.Sprintf("router candidate %d in model '%s'", i, name))
+			}
+		}
+	}
+}
+
+// agentEdge is a reference from an agent to another one.
+type agentEdge struct {
+	path   string
+	target string
+}
+
+func subAgentEdges(agent *latest.AgentConfig) []agentEdge {
+	var edges []agentEdge
+	for i, sub := range agent.SubAgents {
+		edges = append(edges, agentEdge{path: fmt.Sprintf("agents.%s.sub_agents[%d]", agent.Name, i), target: sub})
+	}
+	return edges
+}
+
+func agentToolEdges(agent *latest.AgentConfig) []agentEdge {
+	var edges []agentEdge
+	for i, toolset := range agent.Toolsets {
+		if toolset.Type == "agent" && toolset.Agent != "" {
+			edges = append(edges, agentEdge{path: fmt.Sprintf("agents.%s.toolsets[%d].agent", agent.Name, i), target: toolset.Agent})
+		}
+	}
+	return edges
+}
+
+// agentToolRefs returns the names of the agents an agent runs as tools.
+func agentToolRefs(agent *latest.AgentConfig) []string {
+	var refs []string
+	for _, edge := range agentToolEdges(agent) {
+		refs = append(refs, edge.target)
+	}
+	return refs
+}
+
+// validateCycles reports sub-agent graphs, and agent tool graphs, that loop
+// back on themselves, with the path of the cycle.
+func (v *validator) validateCycles() {
+	v.validateCyclesOf("circular sub-agents", subAgentEdges)
+	v.validateCyclesOf("circular agent tools", agentToolEdges)
+}
+
+func (v *validator) validateCyclesOf(problem string, edgesOf func(*latest.AgentConfig) []agentEdge) {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := map[string]int{}
+	reported := map[string]bool{}
+
+	var visit func(name string, stack []string)
+	visit = func(name string, stack []string) {
+		state[name] = visiting
+		stack = append(stack, name)
+
+		agent, _ := v.cfg.Agents.Lookup(name)
+		for _, edge := range edgesOf(&agent) {
+			if _, ok := v.cfg.Agents.Lookup(edge.target); !ok {
+				continue
+			}
+			switch state[edge.target] {
+			case visiting:
+				cycle := append(slices.Clone(stack[slices.Index(stack, edge.target):]), edge.target)
+				key := strings.Join(cycle, " -> ")
+				if !reported[key] {
+					reported[key] = true
+					v.errorf(edge.path, "%s: %s", problem, key)
+				}
+			case unvisited:
+				visit(edge.target, stack)
+			}
+		}
+
+		state[name] = done
+	}
+
+	for _, agent := range v.cfg.Agents {
+		if state[agent.Name] == unvisited {
+			visit(agent.Name, nil)
+		}
+	}
+}
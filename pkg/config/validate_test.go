@@ -0,0 +1,240 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/config/latest"
+	"github.com/docker/docker-agent/pkg/environment"
+)
+
+func TestValidate_Valid(t *testing.T) {
+	t.Parallel()
+
+	diagnostics, err := Validate(latest.Config{
+		Models: map[string]latest.ModelConfig{
+			"fast": {Provider: "openai", Model: "gpt-4o-mini"},
+		},
+		Agents: latest.Agents{
+			{Name: "root", Model: "openai/gpt-4o", SubAgents: []string{"helper"}},
+			{Name: "helper", Model: "fast", Toolsets: []latest.Toolset{{Type: "shell"}}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, diagnostics)
+}
+
+func TestValidate_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		agents  latest.Agents
+		path    string
+		message string
+	}{
+		{
+			name:    "no agents",
+			path:    "agents",
+			message: "no agent is defined",
+		},
+		{
+			name:    "duplicate agent",
+			agents:  latest.Agents{{Name: "root", Model: "openai/gpt-4o"}, {Name: "root", Model: "openai/gpt-4o"}},
+			path:    "agents.root",
+			message: "agent 'root' is defined more than once",
+		},
+		{
+			name:    "missing sub-agent",
+			agents:  latest.Agents{{Name: "root", Model: "openai/gpt-4o", SubAgents: []string{"missing"}}},
+			path:    "agents.root.sub_agents[0]",
+			message: "agent 'root' references non-existent sub-agent 'missing'",
+		},
+		{
+			name:    "missing handoff",
+			agents:  latest.Agents{{Name: "root", Model: "openai/gpt-4o", Handoffs: []string{"missing"}}},
+			path:    "agents.root.handoffs[0]",
+			message: "agent 'root' references non-existent handoff agent 'missing'",
+		},
+		{
+			name:    "unknown model",
+			agents:  latest.Agents{{Name: "root", Model: "smart"}},
+			path:    "agents.root.model",
+			message: "agent 'root' references non-existent model 'smart'",
+		},
+		{
+			name:    "unknown toolset type",
+			agents:  latest.Agents{{Name: "root", Model: "openai/gpt-4o", Toolsets: []latest.Toolset{{Type: "browser"}}}},
+			path:    "agents.root.toolsets[0].type",
+			message: "unknown toolset type 'browser'",
+		},
+		{
+			name:    "missing toolset field",
+			agents:  latest.Agents{{Name: "root", Model: "openai/gpt-4o", Toolsets: []latest.Toolset{{Type: "lsp"}}}},
+			path:    "agents.root.toolsets[0]",
+			message: "lsp toolset requires a command or a preset to be set",
+		},
+		{
+			name: "circular sub-agents",
+			agents: latest.Agents{
+				{Name: "root", Model: "openai/gpt-4o", SubAgents: []string{"a"}},
+				{Name: "a", Model: "openai/gpt-4o", SubAgents: []string{"b"}},
+				{Name: "b", Model: "openai/gpt-4o", SubAgents: []string{"a"}},
+			},
+			path:    "agents.b.sub_agents[0]",
+			message: "circular sub-agents: a -> b -> a",
+		},
+		{
+			name:    "missing agent tool",
+			agents:  latest.Agents{{Name: "root", Model: "openai/gpt-4o", Toolsets: []latest.Toolset{{Type: "agent", Agent: "missing"}}}},
+			path:    "agents.root.toolsets[0].agent",
+			message: "agent 'root' references non-existent agent 'missing' in an agent toolset",
+		},
+		{
+			name:    "agent tool running itself",
+			agents:  latest.Agents{{Name: "root", Model: "openai/gpt-4o", Toolsets: []latest.Toolset{{Type: "agent", Agent: "root"}}}},
+			path:    "agents.root.toolsets[0].agent",
+			message: "circular agent tools: root -> root",
+		},
+		{
+			name: "circular agent tools",
+			agents: latest.Agents{
+				{Name: "root", Model: "openai/gpt-4o", Toolsets: []latest.Toolset{{Type: "agent", Agent: "reviewer"}}},
+				{Name: "reviewer", Model: "openai/gpt-4o", Toolsets: []latest.Toolset{{Type: "agent", Agent: "root"}}},
+			},
+			path:    "agents.reviewer.toolsets[0].agent",
+			message: "circular agent tools: root -> reviewer -> root",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			diagnostics, err := Validate(latest.Config{Agents: tt.agents})
+
+			var validationErr *ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			require.Len(t, diagnostics, 1)
+			assert.Equal(t, SeverityError, diagnostics[0].Severity)
+			assert.Equal(t, tt.path, diagnostics[0].Path)
+			assert.Contains(t, diagnostics[0].Message, tt.message)
+		})
+	}
+}
+
+func TestValidate_UnreachableAgentWarning(t *testing.T) {
+	t.Parallel()
+
+	diagnostics, err := Validate(latest.Config{
+		Agents: latest.Agents{
+			{Name: "root", Model: "openai/gpt-4o"},
+			{Name: "reviewer", Model: "openai/gpt-4o"},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, diagnostics, 1)
+	assert.Equal(t, SeverityWarning, diagnostics[0].Severity)
+	assert.Equal(t, "agents.reviewer", diagnostics[0].Path)
+}
+
+func TestValidate_CustomToolsetTypes(t *testing.T) {
+	t.Parallel()
+
+	cfg := latest.Config{
+		Agents: latest.Agents{
+			{Name: "root", Model: "openai/gpt-4o", Toolsets: []latest.Toolset{{Type: "browser"}}},
+		},
+	}
+
+	_, err := Validate(cfg)
+	require.Error(t, err)
+
+	_, err = Validate(cfg, WithToolsetTypes("browser"))
+	require.NoError(t, err)
+}
+
+func TestValidate_ScopedToolsetEnv(t *testing.T) {
+	t.Parallel()
+
+	cfg := latest.Config{
+		Agents: latest.Agents{
+			{Name: "root", Model: "openai/gpt-4o", Toolsets: []latest.Toolset{{
+				Type:           "mcp",
+				Command:        "github-mcp-server",
+				Env:            map[string]string{"GITHUB_TOKEN": "${GH_TOKEN}"},
+				EnvPassthrough: []string{"PATH", "GITHUB_*", "GITHUB_HOST"},
+			}}},
+		},
+	}
+	env := environment.NewMapEnvProvider(map[string]string{"PATH": "/usr/bin"})
+
+	// Without an env lookup, only the static checks run.
+	_, err := Validate(cfg)
+	require.NoError(t, err)
+
+	diagnostics, err := Validate(cfg, WithEnvLookup(t.Context(), env))
+	require.Error(t, err)
+	require.Len(t, diagnostics, 2)
+	assert.Equal(t, SeverityWarning, diagnostics[0].Severity)
+	assert.Equal(t, "agents.root.toolsets[0].env_passthrough[2]", diagnostics[0].Path)
+	assert.Equal(t, "mcp toolset: environment variable 'GITHUB_HOST' is not set", diagnostics[0].Message)
+	assert.Equal(t, SeverityError, diagnostics[1].Severity)
+	assert.Equal(t, "agents.root.toolsets[0].env.GITHUB_TOKEN", diagnostics[1].Path)
+	assert.Equal(t, "mcp toolset: environment variable 'GH_TOKEN' is not set", diagnostics[1].Message)
+
+	env = environment.NewMapEnvProvider(map[string]string{"PATH": "/usr/bin", "GITHUB_HOST": "github.com", "GH_TOKEN": "ghp"})
+	_, err = Validate(cfg, WithEnvLookup(t.Context(), env))
+	require.NoError(t, err)
+}
+
+func TestValidate_Snippets(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "conventions.md"), []byte("Follow the Go conventions."), 0o644))
+
+	cfg := latest.Config{
+		Snippets: latest.Snippets{
+			{Name: "conventions", File: "conventions.md"},
+			{Name: "tone", Instruction: "Be concise."},
+			{Name: "tone", Instruction: "Be thorough."},
+			{Name: "empty"},
+			{Name: "safety", File: "safety.md"},
+		},
+		Agents: latest.Agents{
+			{Name: "root", Model: "openai/gpt-4o", InstructionRefs: []string{"conventions", "missing"}},
+		},
+	}
+
+	// Without a base directory, snippet files aren't checked.
+	diagnostics, err := Validate(cfg)
+	require.Error(t, err)
+	require.Len(t, diagnostics, 3)
+	assert.Equal(t, Diagnostic{Severity: SeverityError, Path: "snippets.tone", Message: "snippet 'tone' is defined more than once"}, diagnostics[0])
+	assert.Equal(t, Diagnostic{Severity: SeverityError, Path: "snippets.empty", Message: "snippet 'empty' must have either an instruction or a file"}, diagnostics[1])
+	assert.Equal(t, Diagnostic{Severity: SeverityError, Path: "agents.root.instruction_refs[1]", Message: "agent 'root' references non-existent snippet 'missing'"}, diagnostics[2])
+
+	diagnostics, err = Validate(cfg, WithBaseDir(dir))
+	require.Error(t, err)
+	require.Len(t, diagnostics, 4)
+	assert.Equal(t, "snippets.safety.file", diagnostics[2].Path)
+	assert.Contains(t, diagnostics[2].Message, "snippet 'safety'")
+}
+
+func TestValidationError_MultipleErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := Validate(latest.Config{
+		Agents: latest.Agents{
+			{Name: "root", Model: "smart", SubAgents: []string{"missing"}},
+		},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "2 errors")
+	assert.Contains(t, err.Error(), "error: agents.root.sub_agents[0]: agent 'root' references non-existent sub-agent 'missing'")
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce groups the bursts of events editors produce when saving a file.
+const watchDebounce = 500 * time.Millisecond
+
+// IsLocalFile returns true if the agent configuration is read from a file on
+// this machine, i.e. if it can be watched for changes.
+func IsLocalFile(source Source) bool {
+	_, ok := source.(fileSource)
+	return ok
+}
+
+// Watcher watches a local agent file, and the files it includes with
+// ${file:path}, and calls a function when one of them changes.
+type Watcher struct {
+	mu       sync.Mutex
+	watcher  *fsnotify.Watcher
+	path     string
+	files    map[string]bool
+	dirs     map[string]bool
+	onChange func()
+	timer    *time.Timer
+	done     chan struct{}
+	closed   bool
+}
+
+// NewWatcher starts watching the given agent file. onChange is called, from
+// another goroutine, after one of the watched files is modified. The set of
+// included files is updated every time the agent file changes.
+func NewWatcher(source Source, onChange func()) (*Watcher, error) {
+	fs, ok := source.(fileSource)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a local agent file", source.Name())
+	}
+
+	absPath, err := filepath.Abs(fs.path)
+	if err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		watcher:  watcher,
+		path:     absPath,
+		dirs:     map[string]bool{},
+		onChange: onChange,
+		done:     make(chan struct{}),
+	}
+	w.refresh()
+
+	go w.watchLoop()
+
+	slog.Debug("Started watching agent file", "path", absPath)
+	return w, nil
+}
+
+// Close stops watching.
+func (w *Watcher) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	close(w.done)
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	return w.watcher.Close()
+}
+
+// refresh updates the set of watched files from the current content of the
+// agent file. Directories are watched rather than files so that atomic saves
+// (write to a temp file, then rename) are seen.
+func (w *Watcher) refresh() {
+	files := map[string]bool{w.path: true}
+	if data, err := os.ReadFile(w.path); err == nil {
+		for _, file := range includedFiles(data, filepath.Dir(w.path)) {
+			if absFile, err := filepath.Abs(file); err == nil {
+				files[absFile] = true
+			}
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.files = files
+	for file := range files {
+		dir := filepath.Dir(file)
+		if w.dirs[dir] {
+			continue
+		}
+		if err := w.watcher.Add(dir); err != nil {
+			slog.Debug("Failed to watch directory", "dir", dir, "error", err)
+			continue
+		}
+		w.dirs[dir] = true
+	}
+}
+
+func (w *Watcher) watchLoop() {
+	for {
+		select {
+		case <-w.done:
+			return
+
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) == 0 {
+				continue
+			}
+
+			w.mu.Lock()
+			if w.files[filepath.Clean(event.Name)] && !w.closed {
+				if w.timer != nil {
+					w.timer.Stop()
+				}
+				w.timer = time.AfterFunc(watchDebounce, w.changed)
+			}
+			w.mu.Unlock()
+
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			slog.Warn("Agent file watcher error", "error", err)
+		}
+	}
+}
+
+func (w *Watcher) changed() {
+	select {
+	case <-w.done:
+		return
+	default:
+	}
+
+	// Editors may delete the file before writing it back.
+	if _, err := os.Stat(w.path); err != nil {
+		return
+	}
+
+	slog.Debug("Agent file changed", "path", w.path)
+	w.refresh()
+	w.onChange()
+}
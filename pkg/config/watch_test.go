@@ -0,0 +1,68 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcher(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	agentFile := filepath.Join(dir, "agent.yaml")
+	promptFile := filepath.Join(dir, "prompt.md")
+	require.NoError(t, os.WriteFile(promptFile, []byte("Be helpful."), 0o644))
+	require.NoError(t, os.WriteFile(agentFile, []byte(`
+agents:
+  root:
+    model: openai/gpt-4o
+    instruction: ${file:prompt.md}
+`), 0o644))
+
+	changes := make(chan struct{}, 10)
+	w, err := NewWatcher(NewFileSource(agentFile), func() { changes <- struct{}{} })
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = w.Close() })
+
+	waitForChange := func() {
+		t.Helper()
+		select {
+		case <-changes:
+		case <-time.After(5 * time.Second):
+			t.Fatal("no change notification")
+		}
+	}
+
+	require.NoError(t, os.WriteFile(promptFile, []byte("Be concise."), 0o644))
+	waitForChange()
+
+	require.NoError(t, os.WriteFile(agentFile, []byte(`
+agents:
+  root:
+    model: openai/gpt-4o
+    instruction: Be brief.
+`), 0o644))
+	waitForChange()
+
+	// The prompt file is no longer included.
+	require.NoError(t, os.WriteFile(promptFile, []byte("Be verbose."), 0o644))
+	select {
+	case <-changes:
+		t.Fatal("unexpected change notification")
+	case <-time.After(2 * watchDebounce):
+	}
+}
+
+func TestNewWatcher_RemoteSource(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewWatcher(NewBytesSource("agent.yaml", []byte("agents: {}")), func() {})
+	require.Error(t, err)
+	assert.False(t, IsLocalFile(NewBytesSource("agent.yaml", nil)))
+	assert.True(t, IsLocalFile(NewFileSource("agent.yaml")))
+}
@@ -107,6 +107,12 @@ func ForAnthropic(l Level) (string, bool) {
 // BedrockTokens maps l to a token budget for Bedrock Claude, which only
 // supports token-based thinking budgets.
 func BedrockTokens(l Level) (int, bool) {
+	return Tokens(l)
+}
+
+// Tokens maps l to a thinking token budget, for the providers configured
+// with token budgets.
+func Tokens(l Level) (int, bool) {
 	switch l {
 	case Minimal:
 		return 1024, true
@@ -123,6 +129,19 @@ func BedrockTokens(l Level) (int, bool) {
 	}
 }
 
+// FromTokens returns the low, medium or high level closest to a thinking
+// token budget, for the providers configured with effort levels.
+func FromTokens(tokens int) Level {
+	switch {
+	case tokens <= 2048:
+		return Low
+	case tokens <= 8192:
+		return Medium
+	default:
+		return High
+	}
+}
+
 // ForGemini3 returns the Gemini 3 thinking-level string for l.
 // Gemini 3 accepts: minimal, low, medium, high.
 func ForGemini3(l Level) (string, bool) {
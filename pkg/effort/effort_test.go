@@ -140,6 +140,23 @@ func TestBedrockTokens(t *testing.T) {
 	}
 }
 
+func TestFromTokens(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		tokens int
+		want   Level
+	}{
+		{1024, Low},
+		{2048, Low},
+		{4096, Medium},
+		{8192, Medium},
+		{16384, High},
+	} {
+		assert.Equal(t, tt.want, FromTokens(tt.tokens), "tokens: %d", tt.tokens)
+	}
+}
+
 func TestForGemini3(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,60 @@
+package environment
+
+import (
+	"context"
+	"os"
+	"path"
+	"strings"
+)
+
+// IsPattern reports whether an env_passthrough entry is a glob, e.g. "AWS_*",
+// rather than the name of a single variable.
+func IsPattern(name string) bool {
+	return strings.ContainsAny(name, `*?[\`)
+}
+
+// Filter returns the NAME=value entries of environ whose name matches one of
+// the patterns. Patterns are variable names or globs in the path.Match syntax.
+// The result is never nil so that it can be used as the complete environment
+// of a subprocess.
+func Filter(environ, patterns []string) []string {
+	filtered := []string{}
+	for _, e := range environ {
+		name, _, ok := strings.Cut(e, "=")
+		if ok && matchesAny(name, patterns) {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+// Passthrough returns the NAME=value entries of the variables listed in
+// patterns. Variable names are resolved with env, so that they can come from
+// any of its sources, while globs match the variables of the process
+// environment. Variables that are not set are skipped.
+func Passthrough(ctx context.Context, patterns []string, env Provider) []string {
+	var globs []string
+	passthrough := []string{}
+	for _, pattern := range patterns {
+		if IsPattern(pattern) {
+			globs = append(globs, pattern)
+			continue
+		}
+		if v, ok := env.Get(ctx, pattern); ok {
+			passthrough = append(passthrough, pattern+"="+v)
+		}
+	}
+	if len(globs) > 0 {
+		passthrough = append(Filter(os.Environ(), globs), passthrough...)
+	}
+	return passthrough
+}
+
+func matchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
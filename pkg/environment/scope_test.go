@@ -0,0 +1,35 @@
+package environment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter(t *testing.T) {
+	t.Parallel()
+
+	environ := []string{"AWS_REGION=eu-west-1", "AWS_PROFILE=dev", "HOME=/home/me", "GITHUB_TOKEN=secret"}
+
+	assert.Equal(t, []string{"AWS_REGION=eu-west-1", "AWS_PROFILE=dev", "HOME=/home/me"}, Filter(environ, []string{"AWS_*", "HOME"}))
+	assert.Equal(t, []string{}, Filter(environ, nil))
+}
+
+func TestPassthrough(t *testing.T) {
+	t.Setenv("SCOPE_TEST_A", "a")
+	t.Setenv("SCOPE_TEST_B", "b")
+	t.Setenv("OTHER_SCOPE_TEST", "other")
+
+	env := NewMultiProvider(NewMapEnvProvider(map[string]string{"API_KEY": "from-provider"}), NewOsEnvProvider())
+	passthrough := Passthrough(t.Context(), []string{"SCOPE_TEST_*", "API_KEY", "NOT_SET"}, env)
+
+	assert.ElementsMatch(t, []string{"SCOPE_TEST_A=a", "SCOPE_TEST_B=b", "API_KEY=from-provider"}, passthrough)
+}
+
+func TestIsPattern(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, IsPattern("AWS_*"))
+	assert.True(t, IsPattern("LC_[A-Z]"))
+	assert.False(t, IsPattern("PATH"))
+}
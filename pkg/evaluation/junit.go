@@ -0,0 +1,120 @@
+package evaluation
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name       `xml:"testsuites"`
+	Name     string         `xml:"name,attr"`
+	Tests    int            `xml:"tests,attr"`
+	Failures int            `xml:"failures,attr"`
+	Errors   int            `xml:"errors,attr"`
+	Time     string         `xml:"time,attr"`
+	Suites   []junitTestSet `xml:"testsuite"`
+}
+
+type junitTestSet struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the results of a suite run as a JUnit XML report, with
+// a test case per suite case. Failed assertions are reported as failures,
+// and cases that couldn't run as errors.
+func WriteJUnit(w io.Writer, run *SuiteRun) error {
+	name := run.Suite.Path
+	set := junitTestSet{
+		Name:  name,
+		Tests: len(run.Results),
+		Time:  junitSeconds(run.Duration),
+	}
+
+	for i := range run.Results {
+		result := &run.Results[i]
+		tc := junitTestCase{
+			Name:      result.Name,
+			ClassName: name,
+			Time:      junitSeconds(result.Duration),
+			SystemOut: result.Answer,
+		}
+		switch {
+		case result.Error != "":
+			set.Errors++
+			tc.Error = &junitMessage{Message: result.Error, Body: result.Error}
+		case len(result.Failures) > 0:
+			set.Failures++
+			tc.Failure = &junitMessage{
+				Message: result.Failures[0],
+				Body:    strings.Join(result.Failures, "\n"),
+			}
+		}
+		set.TestCases = append(set.TestCases, tc)
+	}
+
+	report := junitTestSuites{
+		Name:     name,
+		Tests:    set.Tests,
+		Failures: set.Failures,
+		Errors:   set.Errors,
+		Time:     set.Time,
+		Suites:   []junitTestSet{set},
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return fmt.Errorf("encoding JUnit report: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// printCaseResult prints the outcome of a case, with the failed assertions
+// and the final answer when it didn't pass.
+func printCaseResult(out io.Writer, result *CaseResult) {
+	if result.Passed() {
+		fmt.Fprintf(out, "✓ %s (%s, %d iterations, $%.6f)\n", result.Name, result.Duration.Round(time.Millisecond), result.Iterations, result.Cost)
+		return
+	}
+
+	fmt.Fprintf(out, "✗ %s (%s, %d iterations, $%.6f)\n", result.Name, result.Duration.Round(time.Millisecond), result.Iterations, result.Cost)
+	if result.Error != "" {
+		fmt.Fprintf(out, "  ✗ error: %s\n", result.Error)
+	}
+	for _, f := range result.Failures {
+		fmt.Fprintf(out, "  ✗ %s\n", f)
+	}
+	if result.Answer != "" {
+		fmt.Fprintf(out, "  answer:\n    %s\n", strings.ReplaceAll(strings.TrimSpace(result.Answer), "\n", "\n    "))
+	}
+}
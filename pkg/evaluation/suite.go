@@ -0,0 +1,191 @@
+package evaluation
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/goccy/go-yaml"
+
+	"github.com/docker/docker-agent/pkg/config/latest"
+)
+
+// Suite is a YAML-defined test suite: a list of cases, each running a
+// prompt against an agent in a fresh session and checking the outcome.
+//
+//	agent: ./agent.yaml
+//	cases:
+//	  - name: reads the readme
+//	    input: What is this project about?
+//	    files:
+//	      README.md: A tool to water plants.
+//	    assert:
+//	      contains: [plants]
+//	      tool_calls:
+//	        - name: read_file
+//	          args: {path: README.md}
+//	      max_iterations: 5
+type Suite struct {
+	// Agent is the agent file or registry reference the cases run against.
+	// Relative paths are resolved from the directory of the suite file.
+	Agent string `yaml:"agent"`
+	// AgentName is the agent of the team that receives the input. Defaults
+	// to the team's default agent.
+	AgentName string `yaml:"agent_name,omitempty"`
+	// Timeout is the default timeout of each case.
+	Timeout latest.Duration `yaml:"timeout,omitempty"`
+	Cases   []SuiteCase     `yaml:"cases"`
+
+	// Path is the path of the suite file (not serialized).
+	Path string `yaml:"-"`
+}
+
+// SuiteCase is a single case of a Suite.
+type SuiteCase struct {
+	Name  string `yaml:"name"`
+	Input string `yaml:"input"`
+	// Files are fixture files, by path relative to the case's working
+	// directory, written before the case runs.
+	Files map[string]string `yaml:"files,omitempty"`
+	// Timeout overrides the suite's timeout for this case.
+	Timeout latest.Duration `yaml:"timeout,omitempty"`
+	// Script, when set, replaces the models of the team with a scripted
+	// provider playing these turns, so that the case runs offline.
+	Script []ScriptedTurn `yaml:"script,omitempty"`
+	Assert Assertions     `yaml:"assert"`
+}
+
+// ScriptedTurn is a model response of an offline case: some content and/or
+// tool calls. Turns are played in order, whichever agent makes the request.
+type ScriptedTurn struct {
+	Content   string           `yaml:"content,omitempty"`
+	ToolCalls []ToolCallAssert `yaml:"tool_calls,omitempty"`
+}
+
+// Assertions are the checks made on the outcome of a case.
+type Assertions struct {
+	// Contains lists substrings the final answer must contain.
+	Contains []string `yaml:"contains,omitempty"`
+	// Matches lists regular expressions the final answer must match.
+	Matches []string `yaml:"matches,omitempty"`
+	// ToolCalls lists tool calls that must have been made, in any order.
+	ToolCalls []ToolCallAssert `yaml:"tool_calls,omitempty"`
+	// MaxIterations fails the case, and stops it, when the agent needs more
+	// model requests than this.
+	MaxIterations int `yaml:"max_iterations,omitempty"`
+	// MaxCost fails the case when the session costs more than this, in dollars.
+	MaxCost float64 `yaml:"max_cost,omitempty"`
+}
+
+// ToolCallAssert is a call to the named tool. Args, when set, must be a
+// subset of the call's arguments.
+type ToolCallAssert struct {
+	Name string         `yaml:"name"`
+	Args map[string]any `yaml:"args,omitempty"`
+}
+
+// LoadSuite reads and validates a suite file.
+func LoadSuite(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var suite Suite
+	if err := yaml.UnmarshalWithOptions(data, &suite, yaml.Strict()); err != nil {
+		return nil, fmt.Errorf("parsing suite %s: %w", path, err)
+	}
+	suite.Path = path
+
+	if err := suite.validate(); err != nil {
+		return nil, fmt.Errorf("invalid suite %s: %w", path, err)
+	}
+	return &suite, nil
+}
+
+// IsSuiteFile reports whether path is a suite file, i.e. a YAML file with a
+// top-level "cases" key, rather than an agent file.
+func IsSuiteFile(path string) bool {
+	ext := filepath.Ext(path)
+	if ext != ".yaml" && ext != ".yml" {
+		return false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	var top map[string]any
+	if err := yaml.Unmarshal(data, &top); err != nil {
+		return false
+	}
+	_, ok := top["cases"]
+	return ok
+}
+
+// AgentPath returns the agent of the suite, resolved from the directory of
+// the suite file when it's a relative path to an existing file.
+func (s *Suite) AgentPath() string {
+	if s.Agent == "" || filepath.IsAbs(s.Agent) || s.Path == "" {
+		return s.Agent
+	}
+	candidate := filepath.Join(filepath.Dir(s.Path), s.Agent)
+	if _, err := os.Stat(candidate); err == nil {
+		return candidate
+	}
+	return s.Agent
+}
+
+func (s *Suite) validate() error {
+	var errs []error
+	if s.Agent == "" {
+		errs = append(errs, errors.New("agent is required"))
+	}
+	if len(s.Cases) == 0 {
+		errs = append(errs, errors.New("at least one case is required"))
+	}
+
+	names := make(map[string]bool)
+	for i, c := range s.Cases {
+		prefix := fmt.Sprintf("cases[%d]", i)
+		if c.Name == "" {
+			errs = append(errs, fmt.Errorf("%s: name is required", prefix))
+		} else if names[c.Name] {
+			errs = append(errs, fmt.Errorf("%s: duplicate case name %q", prefix, c.Name))
+		}
+		names[c.Name] = true
+
+		if c.Input == "" {
+			errs = append(errs, fmt.Errorf("%s: input is required", prefix))
+		}
+		for _, pattern := range c.Assert.Matches {
+			if _, err := regexp.Compile(pattern); err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid pattern %q: %w", prefix, pattern, err))
+			}
+		}
+		for j, tc := range c.Assert.ToolCalls {
+			if tc.Name == "" {
+				errs = append(errs, fmt.Errorf("%s.assert.tool_calls[%d]: name is required", prefix, j))
+			}
+		}
+		for j, turn := range c.Script {
+			if turn.Content == "" && len(turn.ToolCalls) == 0 {
+				errs = append(errs, fmt.Errorf("%s.script[%d]: content or tool_calls is required", prefix, j))
+			}
+		}
+		for name := range c.Files {
+			if !filepath.IsLocal(name) {
+				errs = append(errs, fmt.Errorf("%s: fixture file %q must be a relative path inside the working directory", prefix, name))
+			}
+		}
+		if c.Assert.MaxIterations < 0 {
+			errs = append(errs, fmt.Errorf("%s: max_iterations must be positive", prefix))
+		}
+		if c.Assert.MaxCost < 0 {
+			errs = append(errs, fmt.Errorf("%s: max_cost must be positive", prefix))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,438 @@
+package evaluation
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/config"
+	"github.com/docker/docker-agent/pkg/model/provider/fake"
+	"github.com/docker/docker-agent/pkg/model/provider/options"
+	"github.com/docker/docker-agent/pkg/runtime"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/teamloader"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// SuiteConfig configures a suite run.
+type SuiteConfig struct {
+	Concurrency int           // Number of cases run in parallel
+	Timeout     time.Duration // Timeout of each case, unless the suite or the case sets one
+	Temperature *float64      // Temperature forced on every model of the team, or nil to keep the configured ones
+}
+
+// CaseResult is the outcome of a suite case.
+type CaseResult struct {
+	Name       string               `json:"name"`
+	Answer     string               `json:"answer"`
+	ToolCalls  []tools.FunctionCall `json:"tool_calls,omitempty"`
+	Iterations int                  `json:"iterations"`
+	Cost       float64              `json:"cost"`
+	Duration   time.Duration        `json:"duration"`
+	Failures   []string             `json:"failures,omitempty"`
+	Error      string               `json:"error,omitempty"`
+}
+
+// Passed reports whether the case ran and all its assertions held.
+func (r *CaseResult) Passed() bool {
+	return r.Error == "" && len(r.Failures) == 0
+}
+
+// SuiteRun is the outcome of a suite.
+type SuiteRun struct {
+	Suite    *Suite
+	Results  []CaseResult
+	Duration time.Duration
+}
+
+// Failed returns the number of cases that didn't pass.
+func (r *SuiteRun) Failed() int {
+	failed := 0
+	for i := range r.Results {
+		if !r.Results[i].Passed() {
+			failed++
+		}
+	}
+	return failed
+}
+
+// suiteRunner runs the cases of a suite.
+type suiteRunner struct {
+	suite *Suite
+	cfg   SuiteConfig
+
+	// loadTeam loads a fresh team whose tools work in workingDir.
+	loadTeam func(ctx context.Context, workingDir string) (*team.Team, error)
+	// runtimeOpts are added to the options of each case's runtime.
+	runtimeOpts []runtime.Opt
+}
+
+// RunSuite runs the cases of a suite against its agent, each with a fresh
+// team and session in a temporary working directory, and prints a line per
+// case to out as they complete. A failing case is not an error: check
+// SuiteRun.Failed.
+func RunSuite(ctx context.Context, out io.Writer, suite *Suite, runConfig *config.RuntimeConfig, cfg SuiteConfig) (*SuiteRun, error) {
+	agentSource, err := config.Resolve(suite.AgentPath(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("resolving agent: %w", err)
+	}
+
+	runner := &suiteRunner{
+		suite: suite,
+		cfg:   cfg,
+		loadTeam: func(ctx context.Context, workingDir string) (*team.Team, error) {
+			caseConfig := runConfig.Clone()
+			caseConfig.WorkingDir = workingDir
+			return teamloader.Load(ctx, agentSource, caseConfig)
+		},
+	}
+	return runner.run(ctx, out), nil
+}
+
+func (r *suiteRunner) run(ctx context.Context, out io.Writer) *SuiteRun {
+	concurrency := max(r.cfg.Concurrency, 1)
+	fmt.Fprintf(out, "Running %d cases of %s with concurrency %d\n\n", len(r.suite.Cases), r.suite.Path, concurrency)
+
+	start := time.Now()
+	results := make([]CaseResult, len(r.suite.Cases))
+
+	work := make(chan int, len(r.suite.Cases))
+	for i := range r.suite.Cases {
+		work <- i
+	}
+	close(work)
+
+	var (
+		wg    sync.WaitGroup
+		outMu sync.Mutex
+	)
+	for range concurrency {
+		wg.Go(func() {
+			for i := range work {
+				result := r.runCase(ctx, &r.suite.Cases[i])
+				if result.Error != "" {
+					slog.Error("Suite case failed to run", "case", result.Name, "error", result.Error)
+				}
+				results[i] = result
+
+				outMu.Lock()
+				printCaseResult(out, &result)
+				outMu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+
+	run := &SuiteRun{
+		Suite:    r.suite,
+		Results:  results,
+		Duration: time.Since(start),
+	}
+	fmt.Fprintf(out, "\n%d/%d cases passed in %s\n", len(results)-run.Failed(), len(results), run.Duration.Round(time.Millisecond))
+	return run
+}
+
+// caseOutcome is what a case run produced, checked against its assertions.
+type caseOutcome struct {
+	answer               string
+	toolCalls            []tools.FunctionCall
+	cost                 float64
+	maxIterations        int
+	maxIterationsReached bool
+}
+
+func (r *suiteRunner) runCase(ctx context.Context, c *SuiteCase) (result CaseResult) {
+	result.Name = c.Name
+	start := time.Now()
+	defer func() { result.Duration = time.Since(start) }()
+
+	if ctx.Err() != nil {
+		result.Error = ctx.Err().Error()
+		return result
+	}
+
+	workingDir, err := os.MkdirTemp("", "docker-agent-eval-")
+	if err != nil {
+		result.Error = fmt.Sprintf("creating working directory: %v", err)
+		return result
+	}
+	defer os.RemoveAll(workingDir)
+
+	if err := writeFixtures(workingDir, c.Files); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	timeout := cmp.Or(c.Timeout.Duration, r.suite.Timeout.Duration, r.cfg.Timeout)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	t, err := r.loadTeam(ctx, workingDir)
+	if err != nil {
+		result.Error = fmt.Sprintf("loading agent: %v", err)
+		return result
+	}
+	defer func() {
+		if err := t.StopToolSets(context.WithoutCancel(ctx)); err != nil {
+			slog.Error("Failed to stop tool sets", "case", c.Name, "error", err)
+		}
+	}()
+
+	var script *scriptLog
+	var prov *fake.ScriptedProvider
+	if len(c.Script) > 0 {
+		script = &scriptLog{}
+		prov = fake.NewScriptedProvider(script, "fake/scripted", scriptTurns(c.Script)...)
+		for _, name := range t.AgentNames() {
+			if a, err := t.Agent(name); err == nil {
+				a.SetModelOverride(prov)
+			}
+		}
+	}
+
+	ag, err := r.entryAgent(t)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	rtOpts := []runtime.Opt{
+		runtime.WithCurrentAgent(ag.Name()),
+		runtime.WithWorkingDir(workingDir),
+		runtime.WithTitleGeneration(false),
+	}
+	if r.cfg.Temperature != nil {
+		for _, name := range t.AgentNames() {
+			rtOpts = append(rtOpts, runtime.WithModelOverrides(name, options.WithTemperature(*r.cfg.Temperature)))
+		}
+	}
+	rt, err := runtime.NewLocalRuntime(t, append(rtOpts, r.runtimeOpts...)...)
+	if err != nil {
+		result.Error = fmt.Sprintf("creating runtime: %v", err)
+		return result
+	}
+
+	outcome := caseOutcome{maxIterations: cmp.Or(c.Assert.MaxIterations, ag.MaxIterations())}
+	sess := session.New(
+		session.WithTitle(c.Name),
+		session.WithUserMessage(c.Input),
+		session.WithMaxIterations(outcome.maxIterations),
+		session.WithMaxConsecutiveToolCalls(ag.MaxConsecutiveToolCalls()),
+		session.WithWorkingDir(workingDir),
+		session.WithToolsApproved(true),
+		session.WithNonInteractive(true),
+	)
+
+	for event := range rt.RunStream(ctx, sess) {
+		switch e := event.(type) {
+		case *runtime.ToolCallEvent:
+			outcome.toolCalls = append(outcome.toolCalls, e.ToolCall.Function)
+		case *runtime.MaxIterationsReachedEvent:
+			outcome.maxIterationsReached = true
+		case *runtime.ErrorEvent:
+			result.Error = e.Error
+		}
+	}
+
+	outcome.answer = sess.GetLastAssistantMessageContent()
+	outcome.cost = sess.TotalCost()
+
+	result.Answer = outcome.answer
+	result.ToolCalls = outcome.toolCalls
+	result.Cost = outcome.cost
+	result.Iterations = countAssistantMessages(sess)
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		result.Error = fmt.Sprintf("timed out after %s", timeout)
+		return result
+	}
+
+	result.Failures = checkAssertions(c.Assert, &outcome)
+	if script != nil {
+		prov.AssertDone()
+		result.Failures = append(result.Failures, script.errors()...)
+	}
+	return result
+}
+
+func (r *suiteRunner) entryAgent(t *team.Team) (*agent.Agent, error) {
+	if r.suite.AgentName != "" {
+		return t.Agent(r.suite.AgentName)
+	}
+	return t.DefaultAgent()
+}
+
+// checkAssertions returns a description of each assertion that doesn't hold,
+// with what was expected and what was got.
+func checkAssertions(assert Assertions, outcome *caseOutcome) []string {
+	var failures []string
+
+	if outcome.maxIterationsReached {
+		failures = append(failures, fmt.Sprintf("reached max_iterations (%d) before answering", outcome.maxIterations))
+	}
+
+	for _, s := range assert.Contains {
+		if !strings.Contains(outcome.answer, s) {
+			failures = append(failures, fmt.Sprintf("answer does not contain %q", s))
+		}
+	}
+	for _, pattern := range assert.Matches {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("invalid pattern %q: %v", pattern, err))
+			continue
+		}
+		if !re.MatchString(outcome.answer) {
+			failures = append(failures, fmt.Sprintf("answer does not match %q", pattern))
+		}
+	}
+
+	for _, expected := range assert.ToolCalls {
+		if !slices.ContainsFunc(outcome.toolCalls, expected.matches) {
+			failures = append(failures, fmt.Sprintf("no call to %s, got: %s", describeExpectedCall(expected), describeCalls(outcome.toolCalls)))
+		}
+	}
+
+	if assert.MaxCost > 0 && outcome.cost > assert.MaxCost {
+		failures = append(failures, fmt.Sprintf("cost $%.6f exceeds max_cost $%.6f", outcome.cost, assert.MaxCost))
+	}
+
+	return failures
+}
+
+// matches reports whether call is a call to the expected tool whose
+// arguments include the expected ones.
+func (a ToolCallAssert) matches(call tools.FunctionCall) bool {
+	if call.Name != a.Name {
+		return false
+	}
+	if len(a.Args) == 0 {
+		return true
+	}
+
+	var actual map[string]any
+	if err := json.Unmarshal([]byte(cmp.Or(call.Arguments, "{}")), &actual); err != nil {
+		return false
+	}
+	for key, value := range a.Args {
+		got, ok := actual[key]
+		if !ok || !reflect.DeepEqual(normalizeJSON(value), got) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeJSON converts a value decoded from YAML to the types it would have
+// if it were decoded from JSON, e.g. float64 for numbers.
+func normalizeJSON(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var normalized any
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return v
+	}
+	return normalized
+}
+
+func describeExpectedCall(a ToolCallAssert) string {
+	if len(a.Args) == 0 {
+		return a.Name
+	}
+	args, _ := json.Marshal(a.Args)
+	return a.Name + " " + string(args)
+}
+
+func describeCalls(calls []tools.FunctionCall) string {
+	if len(calls) == 0 {
+		return "no tool calls"
+	}
+	descriptions := make([]string, len(calls))
+	for i, call := range calls {
+		descriptions[i] = call.Name + " " + cmp.Or(call.Arguments, "{}")
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+func countAssistantMessages(sess *session.Session) int {
+	count := 0
+	for _, msg := range sess.GetAllMessages() {
+		if msg.Message.Role == chat.MessageRoleAssistant {
+			count++
+		}
+	}
+	return count
+}
+
+func writeFixtures(workingDir string, files map[string]string) error {
+	for name, content := range files {
+		path := filepath.Join(workingDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("writing fixture %s: %w", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			return fmt.Errorf("writing fixture %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func scriptTurns(script []ScriptedTurn) []*fake.Turn {
+	turns := make([]*fake.Turn, len(script))
+	for i, st := range script {
+		turn := fake.NewTurn()
+		if st.Content != "" {
+			turn.Content(st.Content)
+		}
+		for j, tc := range st.ToolCalls {
+			args, err := json.Marshal(tc.Args)
+			if err != nil || tc.Args == nil {
+				args = []byte("{}")
+			}
+			turn.ToolCall(fmt.Sprintf("call_%d_%d", i+1, j+1), tc.Name, string(args))
+		}
+		turns[i] = turn
+	}
+	return turns
+}
+
+// scriptLog collects the errors reported by the scripted provider of an
+// offline case, e.g. a request with no turn left.
+type scriptLog struct {
+	mu   sync.Mutex
+	errs []string
+}
+
+func (s *scriptLog) Helper() {}
+
+func (s *scriptLog) Errorf(format string, args ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errs = append(s.errs, fmt.Sprintf(format, args...))
+}
+
+func (s *scriptLog) errors() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.errs...)
+}
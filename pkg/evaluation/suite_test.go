@@ -0,0 +1,268 @@
+package evaluation
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/model/provider/fake"
+	"github.com/docker/docker-agent/pkg/modelsdev"
+	"github.com/docker/docker-agent/pkg/runtime"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+func writeSuite(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "suite.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoadSuite(t *testing.T) {
+	t.Parallel()
+
+	path := writeSuite(t, `
+agent: ./agent.yaml
+timeout: 30s
+cases:
+  - name: reads the readme
+    input: What is this project about?
+    files:
+      README.md: A tool to water plants.
+    assert:
+      contains: [plants]
+      matches: ["(?i)water"]
+      tool_calls:
+        - name: read_file
+          args: {path: README.md}
+      max_iterations: 5
+      max_cost: 0.01
+`)
+
+	suite, err := LoadSuite(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, 30*time.Second, suite.Timeout.Duration)
+	require.Len(t, suite.Cases, 1)
+	c := suite.Cases[0]
+	assert.Equal(t, "reads the readme", c.Name)
+	assert.Equal(t, map[string]string{"README.md": "A tool to water plants."}, c.Files)
+	assert.Equal(t, []string{"plants"}, c.Assert.Contains)
+	assert.Equal(t, 5, c.Assert.MaxIterations)
+	assert.InDelta(t, 0.01, c.Assert.MaxCost, 1e-9)
+	require.Len(t, c.Assert.ToolCalls, 1)
+	assert.Equal(t, "read_file", c.Assert.ToolCalls[0].Name)
+	assert.True(t, IsSuiteFile(path))
+}
+
+func TestLoadSuite_Invalid(t *testing.T) {
+	t.Parallel()
+
+	path := writeSuite(t, `
+cases:
+  - name: one
+    input: hi
+    files:
+      ../outside.txt: nope
+    assert:
+      matches: ["("]
+  - name: one
+`)
+
+	_, err := LoadSuite(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "agent is required")
+	assert.Contains(t, err.Error(), `cases[0]: invalid pattern "("`)
+	assert.Contains(t, err.Error(), `cases[0]: fixture file "../outside.txt"`)
+	assert.Contains(t, err.Error(), `cases[1]: duplicate case name "one"`)
+	assert.Contains(t, err.Error(), "cases[1]: input is required")
+}
+
+func TestIsSuiteFile(t *testing.T) {
+	t.Parallel()
+
+	agentFile := filepath.Join(t.TempDir(), "agent.yaml")
+	require.NoError(t, os.WriteFile(agentFile, []byte("agents:\n  root:\n    model: openai/gpt-4o\n"), 0o644))
+
+	assert.False(t, IsSuiteFile(agentFile))
+	assert.False(t, IsSuiteFile("docker/agent"))
+}
+
+func TestCheckAssertions(t *testing.T) {
+	t.Parallel()
+
+	outcome := &caseOutcome{
+		answer: "The project waters plants.",
+		toolCalls: []tools.FunctionCall{
+			{Name: "read_file", Arguments: `{"path":"README.md","limit":10}`},
+		},
+		cost:          0.02,
+		maxIterations: 5,
+	}
+
+	assert.Empty(t, checkAssertions(Assertions{
+		Contains: []string{"plants"},
+		Matches:  []string{`(?i)^the project`},
+		ToolCalls: []ToolCallAssert{
+			{Name: "read_file"},
+			{Name: "read_file", Args: map[string]any{"path": "README.md", "limit": uint64(10)}},
+		},
+		MaxCost: 0.05,
+	}, outcome))
+
+	failures := checkAssertions(Assertions{
+		Contains:  []string{"cats"},
+		Matches:   []string{`^plants`},
+		ToolCalls: []ToolCallAssert{{Name: "read_file", Args: map[string]any{"path": "main.go"}}},
+		MaxCost:   0.01,
+	}, outcome)
+	assert.Equal(t, []string{
+		`answer does not contain "cats"`,
+		`answer does not match "^plants"`,
+		`no call to read_file {"path":"main.go"}, got: read_file {"path":"README.md","limit":10}`,
+		"cost $0.020000 exceeds max_cost $0.010000",
+	}, failures)
+
+	outcome.maxIterationsReached = true
+	assert.Equal(t, []string{"reached max_iterations (5) before answering"}, checkAssertions(Assertions{}, outcome))
+}
+
+type suiteModelStore struct {
+	runtime.ModelStore
+}
+
+func (suiteModelStore) GetModel(context.Context, string) (*modelsdev.Model, error) {
+	return nil, nil
+}
+
+func newSuiteRunner(t *testing.T, suite *Suite) *suiteRunner {
+	t.Helper()
+
+	return &suiteRunner{
+		suite: suite,
+		cfg:   SuiteConfig{Concurrency: 2, Timeout: 10 * time.Second},
+		loadTeam: func(_ context.Context, workingDir string) (*team.Team, error) {
+			readFile := tools.Tool{
+				Name:       "read_file",
+				Parameters: map[string]any{},
+				Handler: tools.NewHandler(func(_ context.Context, args struct {
+					Path string `json:"path"`
+				},
+				) (*tools.ToolCallResult, error) {
+					content, err := os.ReadFile(filepath.Join(workingDir, args.Path))
+					if err != nil {
+						return tools.ResultError(err.Error()), nil
+					}
+					return tools.ResultSuccess(string(content)), nil
+				}),
+			}
+			root := agent.New("root", "You are a test agent",
+				agent.WithModel(fake.NewScriptedProvider(t, "test/unused")),
+				agent.WithTools(readFile),
+			)
+			return team.New(team.WithAgents(root)), nil
+		},
+		runtimeOpts: []runtime.Opt{
+			runtime.WithSessionCompaction(false),
+			runtime.WithModelStore(suiteModelStore{}),
+		},
+	}
+}
+
+func TestRunSuite_Scripted(t *testing.T) {
+	t.Parallel()
+
+	readReadme := []ToolCallAssert{{Name: "read_file", Args: map[string]any{"path": "README.md"}}}
+	suite := &Suite{
+		Path: "suite.yaml",
+		Cases: []SuiteCase{
+			{
+				Name:  "passes",
+				Input: "What is this project about?",
+				Files: map[string]string{"README.md": "A tool to water plants."},
+				Script: []ScriptedTurn{
+					{ToolCalls: readReadme},
+					{Content: "It waters plants."},
+				},
+				Assert: Assertions{Contains: []string{"plants"}, ToolCalls: readReadme},
+			},
+			{
+				Name:   "fails",
+				Input:  "What is this project about?",
+				Script: []ScriptedTurn{{Content: "No idea."}},
+				Assert: Assertions{Contains: []string{"plants"}, ToolCalls: readReadme},
+			},
+		},
+	}
+
+	var out bytes.Buffer
+	run := newSuiteRunner(t, suite).run(t.Context(), &out)
+
+	require.Len(t, run.Results, 2)
+	passed, failed := run.Results[0], run.Results[1]
+
+	assert.True(t, passed.Passed(), "failures: %v, error: %s", passed.Failures, passed.Error)
+	assert.Equal(t, "It waters plants.", passed.Answer)
+	assert.Equal(t, 2, passed.Iterations)
+
+	assert.False(t, failed.Passed())
+	assert.Equal(t, []string{
+		`answer does not contain "plants"`,
+		`no call to read_file {"path":"README.md"}, got: no tool calls`,
+	}, failed.Failures)
+
+	assert.Equal(t, 1, run.Failed())
+	assert.Contains(t, out.String(), "✓ passes")
+	assert.Contains(t, out.String(), "✗ fails")
+	assert.Contains(t, out.String(), "1/2 cases passed")
+}
+
+func TestRunSuite_ScriptExhausted(t *testing.T) {
+	t.Parallel()
+
+	suite := &Suite{
+		Path: "suite.yaml",
+		Cases: []SuiteCase{{
+			Name:   "loops",
+			Input:  "Read it",
+			Script: []ScriptedTurn{{ToolCalls: []ToolCallAssert{{Name: "read_file", Args: map[string]any{"path": "README.md"}}}}},
+		}},
+	}
+
+	run := newSuiteRunner(t, suite).run(t.Context(), &bytes.Buffer{})
+
+	require.Len(t, run.Results, 1)
+	assert.False(t, run.Results[0].Passed())
+}
+
+func TestWriteJUnit(t *testing.T) {
+	t.Parallel()
+
+	run := &SuiteRun{
+		Suite:    &Suite{Path: "suite.yaml"},
+		Duration: 1500 * time.Millisecond,
+		Results: []CaseResult{
+			{Name: "passes", Answer: "ok", Duration: time.Second},
+			{Name: "fails", Failures: []string{`answer does not contain "x"`, "cost too high"}},
+			{Name: "errors", Error: "loading agent: boom"},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteJUnit(&buf, run))
+
+	report := buf.String()
+	assert.Contains(t, report, `<testsuites name="suite.yaml" tests="3" failures="1" errors="1" time="1.500">`)
+	assert.Contains(t, report, `<testcase name="passes" classname="suite.yaml" time="1.000">`)
+	assert.Contains(t, report, `<failure message="answer does not contain &#34;x&#34;">answer does not contain &#34;x&#34;&#xA;cost too high</failure>`)
+	assert.Contains(t, report, `<error message="loading agent: boom">loading agent: boom</error>`)
+}
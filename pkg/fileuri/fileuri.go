@@ -0,0 +1,85 @@
+// Package fileuri converts between file paths and file URIs (RFC 8089), as
+// exchanged with LSP and MCP servers. Windows paths, with drive letters,
+// backslashes or UNC shares, are handled the same way on all platforms, so
+// that their conversions can be tested anywhere.
+package fileuri
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// windows tells whether the paths of the current platform are Windows paths.
+const windows = filepath.Separator == '\\'
+
+// FromPath converts an absolute file path to a percent-encoded file URI:
+// /home/me/my project/main.go becomes file:///home/me/my%20project/main.go,
+// C:\src\main.go becomes file:///C:/src/main.go and, on Windows, the UNC path
+// \\server\share\main.go becomes file://server/share/main.go.
+func FromPath(path string) string {
+	return fromPath(path, windows)
+}
+
+// ToPath converts a file URI to a file path, undoing FromPath. It also
+// accepts the URIs with a percent-encoded drive letter, e.g.
+// file:///c%3A/src/main.go, or without the slash before it, e.g.
+// file://C:/src/main.go, that some servers send.
+func ToPath(uri string) (string, error) {
+	return toPath(uri, windows)
+}
+
+func fromPath(path string, windows bool) string {
+	if windows || IsDrivePath(path) {
+		path = strings.ReplaceAll(path, `\`, "/")
+	}
+
+	u := url.URL{Scheme: "file"}
+	switch {
+	case IsDrivePath(path):
+		u.Path = "/" + path
+	case windows && strings.HasPrefix(path, "//"):
+		host, rest, _ := strings.Cut(path[2:], "/")
+		u.Host = host
+		u.Path = "/" + rest
+	default:
+		u.Path = path
+	}
+	return u.String()
+}
+
+func toPath(uri string, windows bool) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("not a file URI: %s", uri)
+	}
+
+	path := u.Path
+	switch {
+	case len(path) > 1 && path[0] == '/' && IsDrivePath(path[1:]):
+		path = path[1:]
+	case IsDrivePath(u.Host + "/"):
+		path = u.Host + path
+	case u.Host != "" && u.Host != "localhost":
+		path = "//" + u.Host + path
+	}
+
+	if windows {
+		path = strings.ReplaceAll(path, "/", `\`)
+	}
+	return path, nil
+}
+
+// IsDrivePath reports whether path starts with a Windows drive letter, such
+// as C:\ or C:/.
+func IsDrivePath(path string) bool {
+	if len(path) < 3 || path[1] != ':' || (path[2] != '\\' && path[2] != '/') {
+		return false
+	}
+	c := path[0]
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
@@ -0,0 +1,95 @@
+package fileuri
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromPath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		path    string
+		windows bool
+		want    string
+	}{
+		{name: "unix", path: "/home/me/main.go", want: "file:///home/me/main.go"},
+		{name: "unix with spaces", path: "/home/me/my project/main.go", want: "file:///home/me/my%20project/main.go"},
+		{name: "unix with reserved characters", path: "/tmp/a#b?c%d.go", want: "file:///tmp/a%23b%3Fc%25d.go"},
+		{name: "unix with non-ASCII", path: "/tmp/é.go", want: "file:///tmp/%C3%A9.go"},
+		{name: "unix backslash is a file name character", path: `/tmp/a\b`, want: "file:///tmp/a%5Cb"},
+		{name: "drive letter", path: `C:\src\main.go`, windows: true, want: "file:///C:/src/main.go"},
+		{name: "drive letter with forward slashes", path: "d:/main.go", windows: true, want: "file:///d:/main.go"},
+		{name: "drive letter with spaces", path: `C:\My Files\main.go`, windows: true, want: "file:///C:/My%20Files/main.go"},
+		{name: "drive letter on unix", path: `C:\src\main.go`, want: "file:///C:/src/main.go"},
+		{name: "UNC", path: `\\server\share\main.go`, windows: true, want: "file://server/share/main.go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, fromPath(tt.path, tt.windows))
+		})
+	}
+}
+
+func TestToPath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		uri     string
+		windows bool
+		want    string
+	}{
+		{name: "unix", uri: "file:///home/me/main.go", want: "/home/me/main.go"},
+		{name: "unix with spaces", uri: "file:///home/me/my%20project/main.go", want: "/home/me/my project/main.go"},
+		{name: "unix with reserved characters", uri: "file:///tmp/a%23b%3Fc%25d.go", want: "/tmp/a#b?c%d.go"},
+		{name: "localhost", uri: "file://localhost/etc/hosts", want: "/etc/hosts"},
+		{name: "drive letter", uri: "file:///C:/src/main.go", windows: true, want: `C:\src\main.go`},
+		{name: "encoded drive letter", uri: "file:///c%3A/src/main.go", windows: true, want: `c:\src\main.go`},
+		{name: "drive letter without slash", uri: "file://C:/src/main.go", windows: true, want: `C:\src\main.go`},
+		{name: "drive letter with spaces", uri: "file:///C:/My%20Files/main.go", windows: true, want: `C:\My Files\main.go`},
+		{name: "drive letter on unix", uri: "file:///C:/src/main.go", want: "C:/src/main.go"},
+		{name: "UNC", uri: "file://server/share/main.go", windows: true, want: `\\server\share\main.go`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path, err := toPath(tt.uri, tt.windows)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, path)
+		})
+	}
+}
+
+func TestToPath_Invalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := ToPath("https://example.com/main.go")
+	require.Error(t, err)
+
+	_, err = ToPath("file:///%zz")
+	require.Error(t, err)
+}
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range []string{`C:\src\my project\main.go`, `\\server\share\a#b.go`, `D:\`} {
+		path2, err := toPath(fromPath(path, true), true)
+		require.NoError(t, err)
+		assert.Equal(t, path, path2)
+	}
+	for _, path := range []string{"/home/me/my project/main.go", "/tmp/100%/é?.go", "/"} {
+		path2, err := toPath(fromPath(path, false), false)
+		require.NoError(t, err)
+		assert.Equal(t, path, path2)
+	}
+}
@@ -19,11 +19,7 @@ func FromConfig(cfg *latest.HooksConfig) *Config {
 			Hooks:   make([]Hook, 0, len(matcher.Hooks)),
 		}
 		for _, h := range matcher.Hooks {
-			mc.Hooks = append(mc.Hooks, Hook{
-				Type:    HookType(h.Type),
-				Command: h.Command,
-				Timeout: h.Timeout,
-			})
+			mc.Hooks = append(mc.Hooks, fromDefinition(h))
 		}
 		result.PreToolUse = append(result.PreToolUse, mc)
 	}
@@ -35,59 +31,52 @@ func FromConfig(cfg *latest.HooksConfig) *Config {
 			Hooks:   make([]Hook, 0, len(matcher.Hooks)),
 		}
 		for _, h := range matcher.Hooks {
-			mc.Hooks = append(mc.Hooks, Hook{
-				Type:    HookType(h.Type),
-				Command: h.Command,
-				Timeout: h.Timeout,
-			})
+			mc.Hooks = append(mc.Hooks, fromDefinition(h))
 		}
 		result.PostToolUse = append(result.PostToolUse, mc)
 	}
 
 	// Convert SessionStart
 	for _, h := range cfg.SessionStart {
-		result.SessionStart = append(result.SessionStart, Hook{
-			Type:    HookType(h.Type),
-			Command: h.Command,
-			Timeout: h.Timeout,
-		})
+		result.SessionStart = append(result.SessionStart, fromDefinition(h))
 	}
 
 	// Convert SessionEnd
 	for _, h := range cfg.SessionEnd {
-		result.SessionEnd = append(result.SessionEnd, Hook{
-			Type:    HookType(h.Type),
-			Command: h.Command,
-			Timeout: h.Timeout,
-		})
+		result.SessionEnd = append(result.SessionEnd, fromDefinition(h))
 	}
 
 	// Convert OnUserInput
 	for _, h := range cfg.OnUserInput {
-		result.OnUserInput = append(result.OnUserInput, Hook{
-			Type:    HookType(h.Type),
-			Command: h.Command,
-			Timeout: h.Timeout,
-		})
+		result.OnUserInput = append(result.OnUserInput, fromDefinition(h))
 	}
 
 	// Convert Stop
 	for _, h := range cfg.Stop {
-		result.Stop = append(result.Stop, Hook{
-			Type:    HookType(h.Type),
-			Command: h.Command,
-			Timeout: h.Timeout,
-		})
+		result.Stop = append(result.Stop, fromDefinition(h))
+	}
+
+	// Convert TurnEnd
+	for _, h := range cfg.TurnEnd {
+		result.TurnEnd = append(result.TurnEnd, fromDefinition(h))
 	}
 
 	// Convert Notification
 	for _, h := range cfg.Notification {
-		result.Notification = append(result.Notification, Hook{
-			Type:    HookType(h.Type),
-			Command: h.Command,
-			Timeout: h.Timeout,
-		})
+		result.Notification = append(result.Notification, fromDefinition(h))
 	}
 
 	return result
 }
+
+// fromDefinition converts a latest.HookDefinition to a Hook
+func fromDefinition(h latest.HookDefinition) Hook {
+	return Hook{
+		Type:    HookType(h.Type),
+		Command: h.Command,
+		Tool:    h.Tool,
+		Args:    h.Args,
+		Timeout: h.Timeout,
+		Inject:  h.Inject,
+	}
+}
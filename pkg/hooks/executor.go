@@ -12,6 +12,7 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/docker/docker-agent/pkg/shellpath"
 )
@@ -26,6 +27,9 @@ type Executor struct {
 	shell           string
 	shellArgsPrefix []string
 
+	// toolRunner calls the tools of the tool hooks
+	toolRunner ToolRunner
+
 	// Cached compiled regexes
 	preToolUseMatchers  []compiledMatcher
 	postToolUseMatchers []compiledMatcher
@@ -36,8 +40,24 @@ type compiledMatcher struct {
 	pattern *regexp.Regexp
 }
 
+// ToolRunner calls the tool named name with args for a tool hook, and
+// returns its output. An error result of the tool is returned as an error.
+type ToolRunner func(ctx context.Context, name string, args map[string]any) (string, error)
+
+// ExecutorOpt configures an Executor
+type ExecutorOpt func(*Executor)
+
+// WithToolRunner sets how the tools of the tool hooks are called. Without
+// it, tool hooks fail.
+func WithToolRunner(runner ToolRunner) ExecutorOpt {
+	return func(e *Executor) {
+		e.toolRunner = runner
+	}
+}
+
 // hookResult represents the result of executing a single hook
 type hookResult struct {
+	hook     Hook
 	output   *Output
 	stdout   string
 	stderr   string
@@ -46,7 +66,7 @@ type hookResult struct {
 }
 
 // NewExecutor creates a new hook executor
-func NewExecutor(config *Config, workingDir string, env []string) *Executor {
+func NewExecutor(config *Config, workingDir string, env []string, opts ...ExecutorOpt) *Executor {
 	if config == nil {
 		config = &Config{}
 	}
@@ -56,6 +76,9 @@ func NewExecutor(config *Config, workingDir string, env []string) *Executor {
 		workingDir: workingDir,
 		env:        env,
 	}
+	for _, opt := range opts {
+		opt(e)
+	}
 
 	e.initShell()
 	e.compileMatchers()
@@ -199,6 +222,17 @@ func (e *Executor) ExecuteStop(ctx context.Context, input *Input) (*Result, erro
 	return e.executeHooks(ctx, e.config.Stop, input, EventStop)
 }
 
+// ExecuteTurnEnd runs turn end hooks when the agent's turn ends
+func (e *Executor) ExecuteTurnEnd(ctx context.Context, input *Input) (*Result, error) {
+	if e.config == nil || len(e.config.TurnEnd) == 0 {
+		return &Result{Allowed: true}, nil
+	}
+
+	input.HookEventName = EventTurnEnd
+
+	return e.executeHooks(ctx, e.config.TurnEnd, input, EventTurnEnd)
+}
+
 // ExecuteNotification runs notification hooks when the agent emits a notification
 func (e *Executor) ExecuteNotification(ctx context.Context, input *Input) (*Result, error) {
 	if e.config == nil || len(e.config.Notification) == 0 {
@@ -212,11 +246,11 @@ func (e *Executor) ExecuteNotification(ctx context.Context, input *Input) (*Resu
 
 // executeHooks runs a list of hooks in parallel and aggregates results
 func (e *Executor) executeHooks(ctx context.Context, hooks []Hook, input *Input, eventType EventType) (*Result, error) {
-	// Deduplicate hooks by command
+	// Deduplicate hooks by command or tool call
 	seen := make(map[string]bool)
 	var uniqueHooks []Hook
 	for _, h := range hooks {
-		key := fmt.Sprintf("%s:%s", h.Type, h.Command)
+		key := fmt.Sprintf("%s:%s:%s:%v", h.Type, h.Command, h.Tool, h.Args)
 		if !seen[key] {
 			seen[key] = true
 			uniqueHooks = append(uniqueHooks, h)
@@ -241,6 +275,7 @@ func (e *Executor) executeHooks(ctx context.Context, hooks []Hook, input *Input,
 		wg.Go(func() {
 			output, stdout, stderr, exitCode, err := e.executeHook(ctx, hook, inputJSON)
 			results[i] = hookResult{
+				hook:     hook,
 				output:   output,
 				stdout:   stdout,
 				stderr:   stderr,
@@ -258,7 +293,11 @@ func (e *Executor) executeHooks(ctx context.Context, hooks []Hook, input *Input,
 
 // executeHook runs a single hook and returns its output
 func (e *Executor) executeHook(ctx context.Context, hook Hook, inputJSON []byte) (*Output, string, string, int, error) {
-	if hook.Type != HookTypeCommand {
+	switch hook.Type {
+	case HookTypeCommand:
+	case HookTypeTool:
+		return e.executeToolHook(ctx, hook)
+	default:
 		return nil, "", "", 0, fmt.Errorf("unsupported hook type: %s", hook.Type)
 	}
 
@@ -270,6 +309,8 @@ func (e *Executor) executeHook(ctx context.Context, hook Hook, inputJSON []byte)
 	cmd := exec.CommandContext(timeoutCtx, e.shell, append(e.shellArgsPrefix, hook.Command)...)
 	cmd.Dir = e.workingDir
 	cmd.Env = e.env
+	// Don't wait for the children of a killed command holding its output
+	cmd.WaitDelay = time.Second
 
 	// Provide input via stdin
 	cmd.Stdin = bytes.NewReader(inputJSON)
@@ -280,6 +321,9 @@ func (e *Executor) executeHook(ctx context.Context, hook Hook, inputJSON []byte)
 
 	// Run command
 	err := cmd.Run()
+	if errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+		return nil, stdout.String(), stderr.String(), -1, fmt.Errorf("timed out after %s", hook.GetTimeout())
+	}
 
 	exitCode := 0
 	if err != nil {
@@ -306,6 +350,27 @@ func (e *Executor) executeHook(ctx context.Context, hook Hook, inputJSON []byte)
 	return output, stdout.String(), stderr.String(), exitCode, nil
 }
 
+// executeToolHook calls the tool of a tool hook. Its output is handled like
+// the plain text stdout of a command; an error result like a command
+// exiting with code 1.
+func (e *Executor) executeToolHook(ctx context.Context, hook Hook) (*Output, string, string, int, error) {
+	if e.toolRunner == nil {
+		return nil, "", "", 0, fmt.Errorf("tool hooks aren't supported here: can't call %s", hook.Tool)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, hook.GetTimeout())
+	defer cancel()
+
+	output, err := e.toolRunner(timeoutCtx, hook.Tool, hook.Args)
+	if errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+		return nil, output, "", -1, fmt.Errorf("timed out after %s", hook.GetTimeout())
+	}
+	if err != nil {
+		return nil, output, err.Error(), 1, nil
+	}
+	return nil, output, "", 0, nil
+}
+
 // aggregateResults combines results from multiple hooks
 func (e *Executor) aggregateResults(results []hookResult, eventType EventType) (*Result, error) {
 	finalResult := &Result{
@@ -315,10 +380,15 @@ func (e *Executor) aggregateResults(results []hookResult, eventType EventType) (
 	var messages []string
 	var additionalContexts []string
 	var systemMessages []string
+	var reminders []string
 
 	for _, r := range results {
 		if r.err != nil {
-			slog.Warn("Hook execution error", "error", r.err)
+			slog.Warn("Hook execution error", "hook", r.hook.String(), "error", r.err)
+			finalResult.Failures = append(finalResult.Failures, fmt.Sprintf("%s hook %q failed: %v", eventType, r.hook.String(), r.err))
+			if r.hook.Inject {
+				reminders = append(reminders, fmt.Sprintf("The %s hook %q failed: %v", eventType, r.hook.String(), r.err))
+			}
 			continue
 		}
 
@@ -336,6 +406,10 @@ func (e *Executor) aggregateResults(results []hookResult, eventType EventType) (
 		// Non-zero, non-2 exit codes are non-blocking errors
 		if r.exitCode != 0 {
 			slog.Debug("Hook returned non-zero exit code", "exit_code", r.exitCode, "stderr", r.stderr)
+			finalResult.Failures = append(finalResult.Failures, fmt.Sprintf("%s hook %q failed with exit code %d", eventType, r.hook.String(), r.exitCode))
+			if r.hook.Inject {
+				reminders = append(reminders, failureReminder(eventType, r))
+			}
 			continue
 		}
 
@@ -393,6 +467,11 @@ func (e *Executor) aggregateResults(results []hookResult, eventType EventType) (
 					additionalContexts = append(additionalContexts, hso.AdditionalContext)
 				}
 			}
+		} else if r.hook.Inject {
+			// Plain text stdout of the hooks to inject is a reminder
+			if text := strings.TrimSpace(r.stdout); text != "" {
+				reminders = append(reminders, text)
+			}
 		} else if r.stdout != "" {
 			// Plain text stdout is added as context for some events
 			if eventType == EventSessionStart || eventType == EventPostToolUse || eventType == EventStop {
@@ -411,10 +490,25 @@ func (e *Executor) aggregateResults(results []hookResult, eventType EventType) (
 	if len(systemMessages) > 0 {
 		finalResult.SystemMessage = strings.Join(systemMessages, "\n")
 	}
+	if len(reminders) > 0 {
+		finalResult.Reminder = strings.Join(reminders, "\n\n")
+	}
 
 	return finalResult, nil
 }
 
+// failureReminder tells the agent that a hook exited with an error, with
+// its output.
+func failureReminder(eventType EventType, r hookResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "The %s hook %q failed with exit code %d", eventType, r.hook.String(), r.exitCode)
+	if output := strings.TrimSpace(r.stdout + "\n" + r.stderr); output != "" {
+		b.WriteString(":\n")
+		b.WriteString(output)
+	}
+	return b.String()
+}
+
 // HasPreToolUseHooks returns true if there are any pre-tool-use hooks configured
 func (e *Executor) HasPreToolUseHooks() bool {
 	return e.config != nil && len(e.preToolUseMatchers) > 0
@@ -445,6 +539,11 @@ func (e *Executor) HasStopHooks() bool {
 	return e.config != nil && len(e.config.Stop) > 0
 }
 
+// HasTurnEndHooks returns true if there are any turn end hooks configured
+func (e *Executor) HasTurnEndHooks() bool {
+	return e.config != nil && len(e.config.TurnEnd) > 0
+}
+
 // HasNotificationHooks returns true if there are any notification hooks configured
 func (e *Executor) HasNotificationHooks() bool {
 	return e.config != nil && len(e.config.Notification) > 0
@@ -3,6 +3,7 @@ package hooks
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"testing"
 	"time"
 
@@ -635,3 +636,81 @@ func TestExecuteHooksWithContextCancellation(t *testing.T) {
 	// Should be allowed because the hook timed out (non-blocking error)
 	assert.True(t, result.Allowed)
 }
+
+func TestExecuteTurnEndReceivesActivity(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{
+		TurnEnd: []Hook{
+			{Type: HookTypeCommand, Command: "cat | jq -r '.files_changed[0] + \" \" + .tools_used[0]'", Timeout: 5, Inject: true},
+		},
+	}
+
+	exec := NewExecutor(config, t.TempDir(), nil)
+	input := &Input{
+		SessionID:    "test-session",
+		FilesChanged: []string{"main.go"},
+		ToolsUsed:    []string{"edit_file"},
+	}
+
+	result, err := exec.ExecuteTurnEnd(t.Context(), input)
+	require.NoError(t, err)
+	assert.Equal(t, "main.go edit_file", result.Reminder)
+	assert.Empty(t, result.AdditionalContext)
+	assert.Empty(t, result.Failures)
+}
+
+func TestExecuteTurnEndFailures(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{
+		TurnEnd: []Hook{
+			{Type: HookTypeCommand, Command: "echo 'main.go:3: undefined: x' >&2; exit 1", Timeout: 5, Inject: true},
+			{Type: HookTypeCommand, Command: "echo logged only; exit 3", Timeout: 5},
+			{Type: HookTypeCommand, Command: "sleep 10", Timeout: 1},
+		},
+	}
+
+	exec := NewExecutor(config, t.TempDir(), nil)
+	result, err := exec.ExecuteTurnEnd(t.Context(), &Input{SessionID: "test-session"})
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.ElementsMatch(t, []string{
+		`turn_end hook "echo 'main.go:3: undefined: x' >&2; exit 1" failed with exit code 1`,
+		`turn_end hook "echo logged only; exit 3" failed with exit code 3`,
+		`turn_end hook "sleep 10" failed: timed out after 1s`,
+	}, result.Failures)
+	assert.Equal(t, "The turn_end hook \"echo 'main.go:3: undefined: x' >&2; exit 1\" failed with exit code 1:\nmain.go:3: undefined: x", result.Reminder)
+}
+
+func TestExecuteToolHook(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{
+		SessionStart: []Hook{
+			{Type: HookTypeTool, Tool: "read_file", Args: map[string]any{"path": "TODO.md"}, Inject: true},
+			{Type: HookTypeTool, Tool: "fetch", Inject: true},
+		},
+	}
+
+	var calls []string
+	runner := func(_ context.Context, name string, args map[string]any) (string, error) {
+		if name == "fetch" {
+			return "", errors.New("network unreachable")
+		}
+		calls = append(calls, name+" "+args["path"].(string))
+		return "- write the docs", nil
+	}
+
+	exec := NewExecutor(config, t.TempDir(), nil, WithToolRunner(runner))
+	result, err := exec.ExecuteSessionStart(t.Context(), &Input{SessionID: "test-session"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read_file TODO.md"}, calls)
+	assert.Equal(t, "- write the docs\n\nThe session_start hook \"fetch\" failed with exit code 1:\nnetwork unreachable", result.Reminder)
+	assert.Equal(t, []string{`session_start hook "fetch" failed with exit code 1`}, result.Failures)
+
+	// Without a tool runner, tool hooks fail.
+	result, err = NewExecutor(config, t.TempDir(), nil).ExecuteSessionStart(t.Context(), &Input{SessionID: "test-session"})
+	require.NoError(t, err)
+	assert.Len(t, result.Failures, 2)
+}
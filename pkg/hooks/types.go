@@ -38,6 +38,11 @@ const (
 	// logging, or cleanup.
 	EventStop EventType = "stop"
 
+	// EventTurnEnd is triggered when the agent's turn ends, after the stop
+	// hooks. Receives the files changed and the tools used during the turn,
+	// e.g. to build the project and feed the failures back to the agent.
+	EventTurnEnd EventType = "turn_end"
+
 	// EventNotification is triggered when the agent emits a notification to the user,
 	// such as errors or warnings. Can send external notifications or log events.
 	EventNotification EventType = "notification"
@@ -49,18 +54,41 @@ type HookType string
 const (
 	// HookTypeCommand executes a shell command
 	HookTypeCommand HookType = "command"
+
+	// HookTypeTool calls a tool of the agent with fixed arguments
+	HookTypeTool HookType = "tool"
 )
 
 // Hook represents a single hook configuration
 type Hook struct {
-	// Type specifies whether this is a command or prompt hook
+	// Type specifies whether this is a command or tool hook
 	Type HookType `json:"type" yaml:"type"`
 
 	// Command is the shell command to execute (for command hooks)
 	Command string `json:"command,omitempty" yaml:"command,omitempty"`
 
+	// Tool is the name of the tool to call (for tool hooks)
+	Tool string `json:"tool,omitempty" yaml:"tool,omitempty"`
+
+	// Args are the arguments of the tool call (for tool hooks)
+	Args map[string]any `json:"args,omitempty" yaml:"args,omitempty"`
+
 	// Timeout is the execution timeout in seconds (default: 60)
 	Timeout int `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+
+	// Inject adds the output of the hook, or its failure, to the session as
+	// a system reminder for the next turn instead of only logging it
+	// (session_start, turn_end and session_end hooks)
+	Inject bool `json:"inject,omitempty" yaml:"inject,omitempty"`
+}
+
+// String returns the command or the tool the hook runs, for logs and
+// warnings
+func (h *Hook) String() string {
+	if h.Type == HookTypeTool {
+		return h.Tool
+	}
+	return h.Command
 }
 
 // GetTimeout returns the timeout duration, defaulting to 60 seconds
@@ -101,6 +129,9 @@ type Config struct {
 	// Stop hooks run when the model finishes responding
 	Stop []Hook `json:"stop,omitempty" yaml:"stop,omitempty"`
 
+	// TurnEnd hooks run when the agent's turn ends
+	TurnEnd []Hook `json:"turn_end,omitempty" yaml:"turn_end,omitempty"`
+
 	// Notification hooks run when the agent sends a notification (error, warning) to the user
 	Notification []Hook `json:"notification,omitempty" yaml:"notification,omitempty"`
 }
@@ -113,9 +144,30 @@ func (c *Config) IsEmpty() bool {
 		len(c.SessionEnd) == 0 &&
 		len(c.OnUserInput) == 0 &&
 		len(c.Stop) == 0 &&
+		len(c.TurnEnd) == 0 &&
 		len(c.Notification) == 0
 }
 
+// Merge returns the hooks of all the configs, in order. Nil configs are
+// skipped; the configs aren't modified.
+func Merge(configs ...*Config) *Config {
+	merged := &Config{}
+	for _, c := range configs {
+		if c == nil {
+			continue
+		}
+		merged.PreToolUse = append(merged.PreToolUse, c.PreToolUse...)
+		merged.PostToolUse = append(merged.PostToolUse, c.PostToolUse...)
+		merged.SessionStart = append(merged.SessionStart, c.SessionStart...)
+		merged.SessionEnd = append(merged.SessionEnd, c.SessionEnd...)
+		merged.OnUserInput = append(merged.OnUserInput, c.OnUserInput...)
+		merged.Stop = append(merged.Stop, c.Stop...)
+		merged.TurnEnd = append(merged.TurnEnd, c.TurnEnd...)
+		merged.Notification = append(merged.Notification, c.Notification...)
+	}
+	return merged
+}
+
 // Input represents the JSON input passed to hooks via stdin
 type Input struct {
 	// Common fields for all hooks
@@ -140,6 +192,10 @@ type Input struct {
 	// Stop specific
 	StopResponse string `json:"stop_response,omitempty"` // The model's final response content
 
+	// TurnEnd specific
+	FilesChanged []string `json:"files_changed,omitempty"` // The files the tools changed during the turn
+	ToolsUsed    []string `json:"tools_used,omitempty"`    // The tools called during the turn
+
 	// Notification specific
 	NotificationLevel   string `json:"notification_level,omitempty"`   // "error" or "warning"
 	NotificationMessage string `json:"notification_message,omitempty"` // The notification content
@@ -237,4 +293,12 @@ type Result struct {
 
 	// Stderr contains any error output from the hook
 	Stderr string
+
+	// Reminder is the output, or the failure, of the hooks with Inject set,
+	// to add to the session as a system reminder
+	Reminder string
+
+	// Failures describes the hooks that failed to run, timed out or exited
+	// with an error other than a blocking one
+	Failures []string
 }
@@ -13,8 +13,9 @@ import (
 )
 
 type HTTPOptions struct {
-	Header http.Header
-	Query  url.Values
+	Header    http.Header
+	Query     url.Values
+	Transport http.RoundTripper
 }
 
 type Opt func(*HTTPOptions)
@@ -34,7 +35,10 @@ func NewHTTPClient(ctx context.Context, opts ...Opt) *http.Client {
 	// Disable automatic gzip: Go's default transport transparently compresses
 	// and decompresses responses, which is incompatible with SSE streaming.
 	// See https://github.com/docker/docker-agent/issues/1956
-	rt := newTransport(ctx)
+	rt := httpOptions.Transport
+	if rt == nil {
+		rt = newTransport(ctx)
+	}
 
 	return &http.Client{
 		Transport: &userAgentTransport{
@@ -97,6 +101,14 @@ func WithQuery(query url.Values) Opt {
 	}
 }
 
+// WithTransport sends the requests with rt rather than with the default
+// transport. A nil rt keeps the default one.
+func WithTransport(rt http.RoundTripper) Opt {
+	return func(o *HTTPOptions) {
+		o.Transport = rt
+	}
+}
+
 // newTransport returns an HTTP transport with automatic gzip compression disabled and using Docker Desktop proxy if available.
 func newTransport(ctx context.Context) http.RoundTripper {
 	// Get the base transport with Desktop proxy support from remote package
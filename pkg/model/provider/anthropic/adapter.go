@@ -1,6 +1,7 @@
 package anthropic
 
 import (
+	"cmp"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -22,6 +23,9 @@ type streamAdapter struct {
 	trackUsage bool
 	toolCall   bool
 	toolID     string
+	// startUsage holds the input and cache token counts reported by
+	// message_start, used when message_delta omits them.
+	startUsage chat.Usage
 }
 
 func (c *Client) newStreamAdapter(stream *ssestream.Stream[anthropic.MessageStreamEventUnion], trackUsage bool) *streamAdapter {
@@ -133,13 +137,19 @@ func (a *streamAdapter) Recv() (chat.MessageStreamResponse, error) {
 		default:
 			return response, fmt.Errorf("unknown delta type: %T", deltaVariant)
 		}
+	case anthropic.MessageStartEvent:
+		a.startUsage = chat.Usage{
+			InputTokens:       eventVariant.Message.Usage.InputTokens,
+			CachedInputTokens: eventVariant.Message.Usage.CacheReadInputTokens,
+			CacheWriteTokens:  eventVariant.Message.Usage.CacheCreationInputTokens,
+		}
 	case anthropic.MessageDeltaEvent:
 		if a.trackUsage {
 			response.Usage = &chat.Usage{
-				InputTokens:       eventVariant.Usage.InputTokens,
+				InputTokens:       cmp.Or(eventVariant.Usage.InputTokens, a.startUsage.InputTokens),
 				OutputTokens:      eventVariant.Usage.OutputTokens,
-				CachedInputTokens: eventVariant.Usage.CacheReadInputTokens,
-				CacheWriteTokens:  eventVariant.Usage.CacheCreationInputTokens,
+				CachedInputTokens: cmp.Or(eventVariant.Usage.CacheReadInputTokens, a.startUsage.CachedInputTokens),
+				CacheWriteTokens:  cmp.Or(eventVariant.Usage.CacheCreationInputTokens, a.startUsage.CacheWriteTokens),
 			}
 		}
 	case anthropic.MessageStopEvent:
@@ -1,8 +1,10 @@
 package anthropic
 
 import (
+	"cmp"
 	"fmt"
 	"log/slog"
+	"strings"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/packages/ssestream"
@@ -18,6 +20,13 @@ type betaStreamAdapter struct {
 	trackUsage bool
 	toolCall   bool
 	toolID     string
+	// startUsage holds the input and cache token counts reported by
+	// message_start, used when message_delta omits them.
+	startUsage chat.Usage
+	// serverTool is the server tool whose input is being streamed, and
+	// serverTools the ones whose results are awaited, by ID.
+	serverTool  *serverToolUse
+	serverTools map[string]*serverToolUse
 }
 
 // newBetaStreamAdapter creates a new Beta stream adapter
@@ -74,6 +83,23 @@ func (a *betaStreamAdapter) Recv() (chat.MessageStreamResponse, error) {
 			if block.Signature != "" {
 				response.Choices[0].Delta.ThinkingSignature = block.Signature
 			}
+		case anthropic.BetaServerToolUseBlock:
+			// Server tools run on Anthropic's side: they aren't tool calls.
+			a.serverTool = &serverToolUse{id: block.ID, name: string(block.Name)}
+		default:
+			if strings.HasSuffix(eventVariant.ContentBlock.Type, "_tool_result") {
+				if use, ok := providerToolResult(eventVariant.ContentBlock.RawJSON(), a.serverTools); ok {
+					response.Choices[0].Delta.ProviderTools = []chat.ProviderToolUse{use}
+				}
+			}
+		}
+	case anthropic.BetaRawContentBlockStopEvent:
+		if a.serverTool != nil {
+			if a.serverTools == nil {
+				a.serverTools = make(map[string]*serverToolUse)
+			}
+			a.serverTools[a.serverTool.id] = a.serverTool
+			a.serverTool = nil
 		}
 	case anthropic.BetaRawContentBlockDeltaEvent:
 		switch deltaVariant := eventVariant.Delta.AsAny().(type) {
@@ -82,6 +108,10 @@ func (a *betaStreamAdapter) Recv() (chat.MessageStreamResponse, error) {
 		case anthropic.BetaThinkingDelta:
 			response.Choices[0].Delta.ReasoningContent = deltaVariant.Thinking
 		case anthropic.BetaInputJSONDelta:
+			if a.serverTool != nil {
+				a.serverTool.input.WriteString(deltaVariant.PartialJSON)
+				break
+			}
 			inputBytes := deltaVariant.PartialJSON
 			toolCall := tools.ToolCall{
 				ID:   a.toolID,
@@ -94,16 +124,26 @@ func (a *betaStreamAdapter) Recv() (chat.MessageStreamResponse, error) {
 		case anthropic.BetaSignatureDelta:
 			// Signature delta is for thinking blocks - capture it so we can replay thinking in history
 			response.Choices[0].Delta.ThinkingSignature = deltaVariant.Signature
+		case anthropic.BetaCitationsDelta:
+			if citation, ok := webCitation(deltaVariant.Citation.RawJSON()); ok {
+				response.Choices[0].Delta.Annotations = []chat.Annotation{citation}
+			}
 		default:
 			return response, fmt.Errorf("unknown delta type: %T", deltaVariant)
 		}
+	case anthropic.BetaRawMessageStartEvent:
+		a.startUsage = chat.Usage{
+			InputTokens:       eventVariant.Message.Usage.InputTokens,
+			CachedInputTokens: eventVariant.Message.Usage.CacheReadInputTokens,
+			CacheWriteTokens:  eventVariant.Message.Usage.CacheCreationInputTokens,
+		}
 	case anthropic.BetaRawMessageDeltaEvent:
 		if a.trackUsage {
 			response.Usage = &chat.Usage{
-				InputTokens:       eventVariant.Usage.InputTokens,
+				InputTokens:       cmp.Or(eventVariant.Usage.InputTokens, a.startUsage.InputTokens),
 				OutputTokens:      eventVariant.Usage.OutputTokens,
-				CachedInputTokens: eventVariant.Usage.CacheReadInputTokens,
-				CacheWriteTokens:  eventVariant.Usage.CacheCreationInputTokens,
+				CachedInputTokens: cmp.Or(eventVariant.Usage.CacheReadInputTokens, a.startUsage.CachedInputTokens),
+				CacheWriteTokens:  cmp.Or(eventVariant.Usage.CacheCreationInputTokens, a.startUsage.CacheWriteTokens),
 			}
 		}
 	case anthropic.BetaRawMessageStopEvent:
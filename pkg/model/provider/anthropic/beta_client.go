@@ -52,6 +52,7 @@ func (c *Client) createBetaStream(
 	}
 
 	sys := extractBetaSystemBlocks(messages)
+	applyBetaPromptCache(promptCachePolicy(c.ModelConfig.PromptCache), sys, allTools, converted)
 
 	// Check if messages contain file attachments to include the files-api beta header
 	needsFilesAPI := hasFileAttachments(messages)
@@ -97,6 +98,9 @@ func (c *Client) createBetaStream(
 	// models will reject the field — docker-agent does not gate by model.
 	configureTaskBudget(&params, c.ModelConfig.TaskBudget)
 
+	// Add the server tools the provider hosts, e.g. web search.
+	configureBuiltinTools(&params, c.ModelConfig.BuiltinTools)
+
 	if len(requestTools) > 0 {
 		slog.Debug("Anthropic Beta API: Adding tools to request", "tool_count", len(requestTools))
 	}
@@ -23,6 +23,8 @@ import (
 // blocks from the same assistant message MUST be grouped into a single user message.
 func (c *Client) convertBetaMessages(ctx context.Context, messages []chat.Message) ([]anthropic.BetaMessageParam, error) {
 	var betaMessages []anthropic.BetaMessageParam
+	// System messages are moved before the conversation, reminders stay in place.
+	messages = chat.SystemReminders(messages, false)
 
 	for i := 0; i < len(messages); i++ {
 		msg := &messages[i]
@@ -126,9 +128,6 @@ func (c *Client) convertBetaMessages(ctx context.Context, messages []chat.Messag
 		}
 	}
 
-	// Add ephemeral cache to last 2 messages' last content block
-	applyBetaMessageCacheControl(betaMessages)
-
 	return betaMessages, nil
 }
 
@@ -355,9 +354,9 @@ func convertBetaTools(t []tools.Tool) ([]anthropic.BetaToolUnionParam, error) {
 }
 
 // applyBetaMessageCacheControl adds ephemeral cache control to the last content block
-// of the last 2 messages for prompt caching.
-func applyBetaMessageCacheControl(messages []anthropic.BetaMessageParam) {
-	for i := len(messages) - 1; i >= 0 && i >= len(messages)-2; i-- {
+// of the last n messages for prompt caching.
+func applyBetaMessageCacheControl(messages []anthropic.BetaMessageParam, n int) {
+	for i := len(messages) - 1; i >= 0 && i >= len(messages)-n; i-- {
 		msg := &messages[i]
 		if len(msg.Content) == 0 {
 			continue
@@ -0,0 +1,121 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/config/latest"
+)
+
+// codeExecutionBeta is the Anthropic beta header required by the code
+// execution tool.
+const codeExecutionBeta anthropic.AnthropicBeta = "code-execution-2025-05-22"
+
+// configureBuiltinTools mutates params so the request carries the server
+// tools mapped from the built-in tools of the model configuration, along
+// with the beta headers they need.
+func configureBuiltinTools(params *anthropic.BetaMessageNewParams, names []string) {
+	for _, name := range names {
+		switch name {
+		case latest.BuiltinToolWebSearch:
+			params.Tools = append(params.Tools, anthropic.BetaToolUnionParam{
+				OfWebSearchTool20250305: &anthropic.BetaWebSearchTool20250305Param{},
+			})
+		case latest.BuiltinToolCodeExecution:
+			params.Tools = append(params.Tools, anthropic.BetaToolUnionParam{
+				OfCodeExecutionTool20250522: &anthropic.BetaCodeExecutionTool20250522Param{},
+			})
+			params.Betas = append(params.Betas, codeExecutionBeta)
+		default:
+			slog.Warn("Ignoring unsupported built-in tool", "tool", name)
+			continue
+		}
+		slog.Debug("Anthropic Beta API using built-in tool", "tool", name)
+	}
+}
+
+// serverToolUse is a use of a server tool, whose input is streamed before
+// its result comes back in a content block of its own.
+type serverToolUse struct {
+	id    string
+	name  string
+	input strings.Builder
+}
+
+// providerToolResult returns what a server tool did, from the raw JSON of
+// the content block of its result and the use it answers, if known.
+func providerToolResult(raw string, uses map[string]*serverToolUse) (chat.ProviderToolUse, bool) {
+	var block struct {
+		Type      string          `json:"type"`
+		ToolUseID string          `json:"tool_use_id"`
+		Content   json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal([]byte(raw), &block); err != nil {
+		return chat.ProviderToolUse{}, false
+	}
+
+	var input struct {
+		Query string `json:"query"`
+		Code  string `json:"code"`
+	}
+	if use, ok := uses[block.ToolUseID]; ok {
+		_ = json.Unmarshal([]byte(use.input.String()), &input)
+	}
+
+	// The content is a list of results, or an object: the result of the
+	// code or an error.
+	var results []struct {
+		URL string `json:"url"`
+	}
+	var result struct {
+		Type       string `json:"type"`
+		ErrorCode  string `json:"error_code"`
+		Stdout     string `json:"stdout"`
+		Stderr     string `json:"stderr"`
+		ReturnCode int    `json:"return_code"`
+	}
+	if json.Unmarshal(block.Content, &results) != nil {
+		_ = json.Unmarshal(block.Content, &result)
+	}
+
+	use := chat.ProviderToolUse{ID: block.ToolUseID, Error: result.ErrorCode}
+	switch block.Type {
+	case "web_search_tool_result":
+		use.Tool = latest.BuiltinToolWebSearch
+		if input.Query != "" {
+			use.Queries = []string{input.Query}
+		}
+		for _, r := range results {
+			use.URLs = append(use.URLs, r.URL)
+		}
+	case "code_execution_tool_result":
+		use.Tool = latest.BuiltinToolCodeExecution
+		use.Code = input.Code
+		use.Output = result.Stdout + result.Stderr
+		if use.Error == "" && result.ReturnCode != 0 {
+			use.Error = "exit code " + strconv.Itoa(result.ReturnCode)
+		}
+	default:
+		return chat.ProviderToolUse{}, false
+	}
+	return use, true
+}
+
+// webCitation returns the web page cited by a citation of the text, from
+// its raw JSON.
+func webCitation(raw string) (chat.Annotation, bool) {
+	var citation struct {
+		Type  string `json:"type"`
+		URL   string `json:"url"`
+		Title string `json:"title"`
+	}
+	if err := json.Unmarshal([]byte(raw), &citation); err != nil || citation.Type != "web_search_result_location" || citation.URL == "" {
+		return chat.Annotation{}, false
+	}
+	return chat.Annotation{URL: citation.URL, Title: citation.Title}, true
+}
@@ -0,0 +1,149 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/config/latest"
+)
+
+func TestConfigureBuiltinTools(t *testing.T) {
+	t.Parallel()
+
+	params := anthropic.BetaMessageNewParams{Betas: []anthropic.AnthropicBeta{"existing"}}
+	configureBuiltinTools(&params, []string{latest.BuiltinToolWebSearch, latest.BuiltinToolCodeExecution})
+
+	assert.Equal(t, []anthropic.AnthropicBeta{"existing", codeExecutionBeta}, params.Betas)
+
+	data, err := json.Marshal(params.Tools)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"type": "web_search_20250305", "name": "web_search"},
+		{"type": "code_execution_20250522", "name": "code_execution"}
+	]`, string(data))
+}
+
+func TestBuiltinTools_RoutesToBetaAPI(t *testing.T) {
+	t.Parallel()
+
+	var gotBetas []string
+	var gotBody []byte
+	srv := anthropicTestServer(t, func(r *http.Request, body []byte) {
+		gotBetas = r.Header.Values("anthropic-beta")
+		gotBody = body
+	})
+
+	client := newTestClient(srv, latest.ModelConfig{
+		Provider:     "anthropic",
+		Model:        "claude-sonnet-4-5",
+		BuiltinTools: []string{latest.BuiltinToolCodeExecution},
+		ProviderOpts: map[string]any{"interleaved_thinking": false},
+	})
+
+	stream, err := client.CreateChatCompletionStream(t.Context(), []chat.Message{{Role: chat.MessageRoleUser, Content: "hi"}}, nil)
+	require.NoError(t, err)
+	drain(stream)
+
+	assert.Contains(t, gotBetas, string(codeExecutionBeta))
+	var body struct {
+		Tools []map[string]any `json:"tools"`
+	}
+	require.NoError(t, json.Unmarshal(gotBody, &body), "raw body: %s", string(gotBody))
+	require.Len(t, body.Tools, 1)
+	assert.Equal(t, "code_execution_20250522", body.Tools[0]["type"])
+}
+
+func TestBetaStreamAdapter_BuiltinTools(t *testing.T) {
+	t.Parallel()
+
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","content":[],"usage":{"input_tokens":10,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"server_tool_use","id":"srvtoolu_1","name":"web_search","input":{}}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"query\": \"docker"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":" agent\"}"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"web_search_tool_result","tool_use_id":"srvtoolu_1","content":[{"type":"web_search_result","url":"https://docs.docker.com","title":"Docker Docs","encrypted_content":"x"}]}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"content_block_start","index":2,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":2,"delta":{"type":"citations_delta","citation":{"type":"web_search_result_location","url":"https://docs.docker.com","title":"Docker Docs","cited_text":"Docker Agent","encrypted_index":"x"}}}`,
+		`{"type":"content_block_delta","index":2,"delta":{"type":"text_delta","text":"Docker Agent runs agents."}}`,
+		`{"type":"content_block_stop","index":2}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":20}}`,
+		`{"type":"message_stop"}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("content-type", "text/event-stream")
+		for _, event := range events {
+			var base struct {
+				Type string `json:"type"`
+			}
+			_ = json.Unmarshal([]byte(event), &base)
+			_, _ = io.WriteString(w, "event: "+base.Type+"\ndata: "+event+"\n\n")
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	client := newTestClient(srv, latest.ModelConfig{
+		Provider:     "anthropic",
+		Model:        "claude-sonnet-4-5",
+		BuiltinTools: []string{latest.BuiltinToolWebSearch},
+	})
+	stream, err := client.CreateChatCompletionStream(t.Context(), []chat.Message{{Role: chat.MessageRoleUser, Content: "What is Docker Agent?"}}, nil)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	var content strings.Builder
+	var delta chat.MessageDelta
+	var finishReason chat.FinishReason
+	for {
+		chunk, err := stream.Recv()
+		if err != nil {
+			require.ErrorIs(t, err, io.EOF)
+			break
+		}
+		for _, choice := range chunk.Choices {
+			content.WriteString(choice.Delta.Content)
+			assert.Empty(t, choice.Delta.ToolCalls)
+			delta.ProviderTools = append(delta.ProviderTools, choice.Delta.ProviderTools...)
+			delta.Annotations = append(delta.Annotations, choice.Delta.Annotations...)
+			if choice.FinishReason != "" {
+				finishReason = choice.FinishReason
+			}
+		}
+	}
+
+	assert.Equal(t, "Docker Agent runs agents.", content.String())
+	assert.Equal(t, []chat.ProviderToolUse{{ID: "srvtoolu_1", Tool: "web_search", Queries: []string{"docker agent"}, URLs: []string{"https://docs.docker.com"}}}, delta.ProviderTools)
+	assert.Equal(t, []chat.Annotation{{URL: "https://docs.docker.com", Title: "Docker Docs"}}, delta.Annotations)
+	assert.Equal(t, chat.FinishReasonStop, finishReason)
+}
+
+func TestProviderToolResult_CodeExecution(t *testing.T) {
+	t.Parallel()
+
+	use := &serverToolUse{id: "srvtoolu_1", name: "code_execution"}
+	use.input.WriteString(`{"code": "print(6*7)"}`)
+	uses := map[string]*serverToolUse{use.id: use}
+
+	got, ok := providerToolResult(`{"type":"code_execution_tool_result","tool_use_id":"srvtoolu_1","content":{"type":"code_execution_result","stdout":"42\n","stderr":"","return_code":0,"content":[]}}`, uses)
+	require.True(t, ok)
+	assert.Equal(t, chat.ProviderToolUse{ID: "srvtoolu_1", Tool: "code_execution", Code: "print(6*7)", Output: "42\n"}, got)
+
+	got, ok = providerToolResult(`{"type":"code_execution_tool_result","tool_use_id":"srvtoolu_2","content":{"type":"code_execution_result","stdout":"","stderr":"boom","return_code":1,"content":[]}}`, uses)
+	require.True(t, ok)
+	assert.Equal(t, "exit code 1", got.Error)
+	assert.Equal(t, "boom", got.Output)
+
+	got, ok = providerToolResult(`{"type":"web_search_tool_result","tool_use_id":"srvtoolu_3","content":{"type":"web_search_tool_result_error","error_code":"max_uses_exceeded"}}`, uses)
+	require.True(t, ok)
+	assert.Equal(t, chat.ProviderToolUse{ID: "srvtoolu_3", Tool: "web_search", Error: "max_uses_exceeded"}, got)
+}
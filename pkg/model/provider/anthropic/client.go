@@ -75,7 +75,7 @@ func NewClient(ctx context.Context, cfg *latest.ModelConfig, env environment.Pro
 		slog.Debug("Anthropic API key found, creating client")
 		requestOptions := []option.RequestOption{
 			option.WithAPIKey(authToken),
-			option.WithHTTPClient(httpclient.NewHTTPClient(ctx)),
+			option.WithHTTPClient(httpclient.NewHTTPClient(ctx, httpclient.WithTransport(globalOptions.HTTPTransport()))),
 		}
 		if cfg.BaseURL != "" {
 			requestOptions = append(requestOptions, option.WithBaseURL(cfg.BaseURL))
@@ -112,6 +112,7 @@ func NewClient(ctx context.Context, cfg *latest.ModelConfig, env environment.Pro
 				httpclient.WithModel(cfg.Model),
 				httpclient.WithModelName(cfg.Name),
 				httpclient.WithQuery(url.Query()),
+				httpclient.WithTransport(globalOptions.HTTPTransport()),
 			}
 			if globalOptions.GeneratingTitle() {
 				httpOptions = append(httpOptions, httpclient.WithHeader("X-Cagent-GeneratingTitle", "1"))
@@ -182,10 +183,12 @@ func (c *Client) CreateChatCompletionStream(
 	//  - structured output (requires beta header)
 	//  - file attachments (Files API is Beta-only)
 	//  - task_budget (requires the task-budgets beta header)
+	//  - built-in tools (server tools and their results are streamed as Beta blocks)
 	if c.interleavedThinkingEnabled() ||
 		c.ModelOptions.StructuredOutput() != nil ||
 		hasFileAttachments(messages) ||
-		!c.ModelConfig.TaskBudget.IsZero() {
+		!c.ModelConfig.TaskBudget.IsZero() ||
+		len(c.ModelConfig.BuiltinTools) > 0 {
 		return c.createBetaStream(ctx, client, messages, requestTools, maxTokens)
 	}
 
@@ -204,6 +207,7 @@ func (c *Client) CreateChatCompletionStream(
 		return nil, errors.New("no messages to send after conversion: all messages were filtered out")
 	}
 	sys := extractSystemBlocks(messages)
+	applyPromptCache(promptCachePolicy(c.ModelConfig.PromptCache), sys, allTools, converted)
 
 	params := anthropic.MessageNewParams{
 		Model:     c.ModelConfig.Model,
@@ -284,6 +288,8 @@ func (c *Client) CreateChatCompletionStream(
 
 func (c *Client) convertMessages(ctx context.Context, messages []chat.Message) ([]anthropic.MessageParam, error) {
 	var anthropicMessages []anthropic.MessageParam
+	// System messages are moved before the conversation, reminders stay in place.
+	messages = chat.SystemReminders(messages, false)
 	// Track whether the last appended assistant message included tool_use blocks
 	// so we can ensure the immediate next message is the grouped tool_result user message.
 	pendingAssistantToolUse := false
@@ -392,9 +398,6 @@ func (c *Client) convertMessages(ctx context.Context, messages []chat.Message) (
 		}
 	}
 
-	// Add ephemeral cache to last 2 messages' last content block
-	applyMessageCacheControl(anthropicMessages)
-
 	return anthropicMessages, nil
 }
 
@@ -538,9 +541,9 @@ func createFileContentBlock(fileID, mimeType string) (anthropic.ContentBlockPara
 }
 
 // applyMessageCacheControl adds ephemeral cache control to the last content block
-// of the last 2 messages for prompt caching.
-func applyMessageCacheControl(messages []anthropic.MessageParam) {
-	for i := len(messages) - 1; i >= 0 && i >= len(messages)-2; i-- {
+// of the last n messages for prompt caching.
+func applyMessageCacheControl(messages []anthropic.MessageParam, n int) {
+	for i := len(messages) - 1; i >= 0 && i >= len(messages)-n; i-- {
 		msg := &messages[i]
 		if len(msg.Content) == 0 {
 			continue
@@ -135,6 +135,34 @@ func TestConvertMessages_UserMultiContent_SkipEmptyText_KeepImage(t *testing.T)
 	assert.Equal(t, "image", cb["type"])
 }
 
+func TestConvertMessages_SystemReminderIsUserMessage(t *testing.T) {
+	msgs := []chat.Message{
+		{Role: chat.MessageRoleSystem, Content: "You are helpful"},
+		{Role: chat.MessageRoleUser, Content: "hello"},
+		{Role: chat.MessageRoleAssistant, Content: "hi"},
+		chat.SystemReminderMessage("Keep it short"),
+	}
+
+	out, err := testClient().convertMessages(t.Context(), msgs)
+	require.NoError(t, err)
+	require.Len(t, out, 3)
+
+	b, err := json.Marshal(out[2])
+	require.NoError(t, err)
+	var m map[string]any
+	require.NoError(t, json.Unmarshal(b, &m))
+	assert.Equal(t, "user", m["role"])
+	content, ok := m["content"].([]any)
+	require.True(t, ok)
+	require.Len(t, content, 1)
+	cb, ok := content[0].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "<system-reminder>\nKeep it short\n</system-reminder>", cb["text"])
+
+	// Only the real system message goes to the top-level system blocks.
+	assert.Len(t, extractSystemBlocks(msgs), 1)
+}
+
 func TestConvertMessages_SkipEmptyAssistantText_NoToolCalls(t *testing.T) {
 	msgs := []chat.Message{{
 		Role:    chat.MessageRoleAssistant,
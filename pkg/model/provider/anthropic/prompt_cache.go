@@ -0,0 +1,89 @@
+package anthropic
+
+import (
+	"github.com/anthropics/anthropic-sdk-go"
+
+	"github.com/docker/docker-agent/pkg/config/latest"
+)
+
+// maxCacheBreakpoints is the maximum number of cache_control blocks
+// Anthropic accepts in a single request.
+const maxCacheBreakpoints = 4
+
+// cachePolicy is the resolved prompt_cache configuration of a model.
+type cachePolicy struct {
+	system        bool
+	tools         bool
+	lastNMessages int
+}
+
+// promptCachePolicy resolves the model's prompt_cache configuration,
+// applying the defaults: cache the system prompt and the last 2 messages.
+func promptCachePolicy(cfg *latest.PromptCacheConfig) cachePolicy {
+	policy := cachePolicy{
+		system:        true,
+		lastNMessages: 2,
+	}
+	if cfg == nil {
+		return policy
+	}
+
+	if cfg.System != nil {
+		policy.system = *cfg.System
+	}
+	if cfg.Tools != nil {
+		policy.tools = *cfg.Tools
+	}
+	if cfg.LastNMessages != nil {
+		policy.lastNMessages = max(*cfg.LastNMessages, 0)
+	}
+	return policy
+}
+
+// messageBreakpoints returns how many trailing messages can be cached once
+// the system prompt and tool breakpoints are accounted for.
+func (p cachePolicy) messageBreakpoints(systemBreakpoints int, hasTools bool) int {
+	used := systemBreakpoints
+	if p.tools && hasTools {
+		used++
+	}
+	return max(min(p.lastNMessages, maxCacheBreakpoints-used), 0)
+}
+
+// applyPromptCache places the cache_control breakpoints of a standard API request.
+func applyPromptCache(policy cachePolicy, system []anthropic.TextBlockParam, allTools []anthropic.ToolUnionParam, messages []anthropic.MessageParam) {
+	systemBreakpoints := 0
+	for i := range system {
+		if !policy.system {
+			system[i].CacheControl = anthropic.CacheControlEphemeralParam{}
+		} else if system[i].CacheControl.Type != "" {
+			systemBreakpoints++
+		}
+	}
+
+	hasTools := len(allTools) > 0 && allTools[len(allTools)-1].OfTool != nil
+	if policy.tools && hasTools {
+		allTools[len(allTools)-1].OfTool.CacheControl = anthropic.NewCacheControlEphemeralParam()
+	}
+
+	applyMessageCacheControl(messages, policy.messageBreakpoints(systemBreakpoints, hasTools))
+}
+
+// applyBetaPromptCache places the cache_control breakpoints of a Beta API request.
+func applyBetaPromptCache(policy cachePolicy, system []anthropic.BetaTextBlockParam, allTools []anthropic.BetaToolUnionParam, messages []anthropic.BetaMessageParam) {
+	systemBreakpoints := 0
+	for i := range system {
+		if !policy.system {
+			system[i].CacheControl = anthropic.BetaCacheControlEphemeralParam{}
+		} else if system[i].CacheControl.Type != "" {
+			systemBreakpoints++
+		}
+	}
+
+	hasTools := len(allTools) > 0 && allTools[len(allTools)-1].OfTool != nil
+	if policy.tools && hasTools {
+		allTools[len(allTools)-1].OfTool.CacheControl = anthropic.NewBetaCacheControlEphemeralParam()
+	}
+
+	applyBetaMessageCacheControl(messages, policy.messageBreakpoints(systemBreakpoints, hasTools))
+}
@@ -0,0 +1,186 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/config/latest"
+	"github.com/docker/docker-agent/pkg/model/provider/base"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// recordingClient returns a Client whose requests are captured in *body and
+// answered with the recorded prompt caching stream from testdata.
+func recordingClient(t *testing.T, promptCache *latest.PromptCacheConfig) (*Client, *map[string]any) {
+	t.Helper()
+
+	recorded, err := os.ReadFile("testdata/prompt_cache_stream.sse")
+	require.NoError(t, err)
+
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &body)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write(recorded)
+	}))
+	t.Cleanup(server.Close)
+
+	client := &Client{
+		Config: base.Config{
+			ModelConfig: latest.ModelConfig{
+				Provider:    "anthropic",
+				Model:       "claude-sonnet-4-5-20250929",
+				PromptCache: promptCache,
+			},
+		},
+		clientFn: func(context.Context) (anthropic.Client, error) {
+			return anthropic.NewClient(
+				option.WithAPIKey("test-key"),
+				option.WithBaseURL(server.URL),
+			), nil
+		},
+	}
+
+	return client, &body
+}
+
+func promptCacheConversation() []chat.Message {
+	return []chat.Message{
+		{Role: chat.MessageRoleSystem, Content: "You are helpful.", CacheControl: true},
+		{Role: chat.MessageRoleUser, Content: "first question"},
+		{Role: chat.MessageRoleAssistant, Content: "first answer"},
+		{Role: chat.MessageRoleUser, Content: "second question"},
+	}
+}
+
+func promptCacheTools() []tools.Tool {
+	return []tools.Tool{
+		{Name: "read_file", Description: "Read a file", Parameters: map[string]any{"type": "object", "properties": map[string]any{}}},
+		{Name: "write_file", Description: "Write a file", Parameters: map[string]any{"type": "object", "properties": map[string]any{}}},
+	}
+}
+
+func drainUsage(t *testing.T, stream chat.MessageStream) *chat.Usage {
+	t.Helper()
+	defer stream.Close()
+
+	var usage *chat.Usage
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			require.ErrorIs(t, err, io.EOF)
+			return usage
+		}
+		if resp.Usage != nil {
+			usage = resp.Usage
+		}
+	}
+}
+
+func hasCacheControl(v any) bool {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return false
+	}
+	_, ok = m["cache_control"]
+	return ok
+}
+
+// cachedMessages returns the indexes of the messages whose last block has a cache breakpoint.
+func cachedMessages(body map[string]any) []int {
+	var indexes []int
+	messages, _ := body["messages"].([]any)
+	for i, msg := range messages {
+		content := contentArray(msg.(map[string]any))
+		if len(content) > 0 && hasCacheControl(content[len(content)-1]) {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+func TestPromptCache_Defaults(t *testing.T) {
+	t.Parallel()
+
+	client, body := recordingClient(t, nil)
+	stream, err := client.CreateChatCompletionStream(t.Context(), promptCacheConversation(), promptCacheTools())
+	require.NoError(t, err)
+	drainUsage(t, stream)
+
+	system := (*body)["system"].([]any)
+	assert.True(t, hasCacheControl(system[0]), "system prompt should be cached by default")
+
+	requestTools := (*body)["tools"].([]any)
+	for _, tool := range requestTools {
+		assert.False(t, hasCacheControl(tool), "tools should not be cached by default")
+	}
+
+	assert.Equal(t, []int{1, 2}, cachedMessages(*body))
+}
+
+func TestPromptCache_Configured(t *testing.T) {
+	t.Parallel()
+
+	client, body := recordingClient(t, &latest.PromptCacheConfig{
+		System:        new(false),
+		Tools:         new(true),
+		LastNMessages: new(1),
+	})
+	stream, err := client.CreateChatCompletionStream(t.Context(), promptCacheConversation(), promptCacheTools())
+	require.NoError(t, err)
+	drainUsage(t, stream)
+
+	system := (*body)["system"].([]any)
+	assert.False(t, hasCacheControl(system[0]))
+
+	requestTools := (*body)["tools"].([]any)
+	assert.False(t, hasCacheControl(requestTools[0]))
+	assert.True(t, hasCacheControl(requestTools[1]), "the last tool definition should be cached")
+
+	assert.Equal(t, []int{2}, cachedMessages(*body))
+}
+
+func TestPromptCache_BreakpointLimit(t *testing.T) {
+	t.Parallel()
+
+	// 1 system + 1 tools breakpoint leaves room for 2 messages out of the 5 requested.
+	client, body := recordingClient(t, &latest.PromptCacheConfig{
+		Tools:         new(true),
+		LastNMessages: new(5),
+	})
+	stream, err := client.CreateChatCompletionStream(t.Context(), promptCacheConversation(), promptCacheTools())
+	require.NoError(t, err)
+	drainUsage(t, stream)
+
+	assert.Equal(t, []int{1, 2}, cachedMessages(*body))
+}
+
+func TestPromptCache_RecordedUsage(t *testing.T) {
+	t.Parallel()
+
+	client, _ := recordingClient(t, nil)
+	stream, err := client.CreateChatCompletionStream(t.Context(), promptCacheConversation(), nil)
+	require.NoError(t, err)
+
+	// message_delta only reports output tokens: input and cache counts
+	// must come from message_start.
+	usage := drainUsage(t, stream)
+	require.NotNil(t, usage)
+	assert.Equal(t, int64(12), usage.InputTokens)
+	assert.Equal(t, int64(5), usage.OutputTokens)
+	assert.Equal(t, int64(4096), usage.CachedInputTokens)
+	assert.Equal(t, int64(0), usage.CacheWriteTokens)
+}
@@ -22,6 +22,13 @@ type streamAdapter struct {
 	currentToolID   string
 	currentToolName string
 
+	// structuredOutput is set when the model answers through the
+	// structured output tool, whose input is streamed as content.
+	structuredOutput bool
+	answering        bool
+	answered         bool
+	calledTools      bool
+
 	// Buffered state for proper event ordering
 	// Bedrock sends MessageStop before Metadata, but runtime expects usage before FinishReason
 	pendingFinishReason chat.FinishReason
@@ -29,11 +36,12 @@ type streamAdapter struct {
 	metadataReceived    bool
 }
 
-func newStreamAdapter(stream *bedrockruntime.ConverseStreamEventStream, model string, trackUsage bool) *streamAdapter {
+func newStreamAdapter(stream *bedrockruntime.ConverseStreamEventStream, model string, trackUsage, structuredOutput bool) *streamAdapter {
 	return &streamAdapter{
-		stream:     stream,
-		model:      model,
-		trackUsage: trackUsage,
+		stream:           stream,
+		model:            model,
+		trackUsage:       trackUsage,
+		structuredOutput: structuredOutput,
 	}
 }
 
@@ -119,7 +127,15 @@ func (a *streamAdapter) Recv() (chat.MessageStreamResponse, error) {
 
 	case *types.ConverseStreamOutputMemberContentBlockStart:
 		// Handle content block start - tool use or text
+		a.answering = false
 		if start, ok := ev.Value.Start.(*types.ContentBlockStartMemberToolUse); ok {
+			if a.structuredOutput && derefString(start.Value.Name) == structuredOutputToolName {
+				// The input of the structured output tool is the answer.
+				a.answering = true
+				a.answered = true
+				break
+			}
+			a.calledTools = true
 			a.currentToolID = derefString(start.Value.ToolUseId)
 			a.currentToolName = derefString(start.Value.Name)
 
@@ -141,6 +157,10 @@ func (a *streamAdapter) Recv() (chat.MessageStreamResponse, error) {
 				response.Choices[0].Delta.Content = delta.Value
 
 			case *types.ContentBlockDeltaMemberToolUse:
+				if a.answering {
+					response.Choices[0].Delta.Content = derefString(delta.Value.Input)
+					break
+				}
 				// Emit partial tool call with input delta
 				response.Choices[0].Delta.ToolCalls = []tools.ToolCall{{
 					ID:   a.currentToolID,
@@ -175,6 +195,9 @@ func (a *streamAdapter) Recv() (chat.MessageStreamResponse, error) {
 		switch stopReason {
 		case types.StopReasonToolUse:
 			a.pendingFinishReason = chat.FinishReasonToolCalls
+			if a.answered && !a.calledTools {
+				a.pendingFinishReason = chat.FinishReasonStop
+			}
 		case types.StopReasonEndTurn, types.StopReasonStopSequence:
 			a.pendingFinishReason = chat.FinishReasonStop
 		case types.StopReasonMaxTokens:
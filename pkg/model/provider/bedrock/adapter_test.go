@@ -0,0 +1,119 @@
+package bedrock
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/chat"
+)
+
+// fakeEventReader replays the events of a ConverseStream response.
+type fakeEventReader struct {
+	events chan types.ConverseStreamOutput
+}
+
+func newFakeEventStream(events ...types.ConverseStreamOutput) *bedrockruntime.ConverseStreamEventStream {
+	ch := make(chan types.ConverseStreamOutput, len(events))
+	for _, ev := range events {
+		ch <- ev
+	}
+	close(ch)
+
+	return bedrockruntime.NewConverseStreamEventStream(func(s *bedrockruntime.ConverseStreamEventStream) {
+		s.Reader = &fakeEventReader{events: ch}
+	})
+}
+
+func (r *fakeEventReader) Events() <-chan types.ConverseStreamOutput { return r.events }
+func (r *fakeEventReader) Close() error                              { return nil }
+func (r *fakeEventReader) Err() error                                { return nil }
+
+func toolUseStart(id, name string) types.ConverseStreamOutput {
+	return &types.ConverseStreamOutputMemberContentBlockStart{Value: types.ContentBlockStartEvent{
+		Start: &types.ContentBlockStartMemberToolUse{Value: types.ToolUseBlockStart{ToolUseId: aws.String(id), Name: aws.String(name)}},
+	}}
+}
+
+func toolUseDelta(input string) types.ConverseStreamOutput {
+	return &types.ConverseStreamOutputMemberContentBlockDelta{Value: types.ContentBlockDeltaEvent{
+		Delta: &types.ContentBlockDeltaMemberToolUse{Value: types.ToolUseBlockDelta{Input: aws.String(input)}},
+	}}
+}
+
+func messageStop(reason types.StopReason) types.ConverseStreamOutput {
+	return &types.ConverseStreamOutputMemberMessageStop{Value: types.MessageStopEvent{StopReason: reason}}
+}
+
+func metadata(input, output int32) types.ConverseStreamOutput {
+	return &types.ConverseStreamOutputMemberMetadata{Value: types.ConverseStreamMetadataEvent{
+		Usage: &types.TokenUsage{InputTokens: aws.Int32(input), OutputTokens: aws.Int32(output)},
+	}}
+}
+
+func drain(t *testing.T, adapter *streamAdapter) (content string, toolCalls int, finish chat.FinishReason, usage *chat.Usage) {
+	t.Helper()
+
+	for {
+		resp, err := adapter.Recv()
+		if errors.Is(err, io.EOF) {
+			return content, toolCalls, finish, usage
+		}
+		require.NoError(t, err)
+
+		content += resp.Choices[0].Delta.Content
+		toolCalls += len(resp.Choices[0].Delta.ToolCalls)
+		if resp.Choices[0].FinishReason != "" {
+			finish = resp.Choices[0].FinishReason
+		}
+		if resp.Usage != nil {
+			usage = resp.Usage
+		}
+	}
+}
+
+func TestStreamAdapter_StructuredOutput(t *testing.T) {
+	t.Parallel()
+
+	stream := newFakeEventStream(
+		toolUseStart("tooluse_1", structuredOutputToolName),
+		toolUseDelta(`{"city":`),
+		toolUseDelta(`"Paris"}`),
+		messageStop(types.StopReasonToolUse),
+		metadata(120, 15),
+	)
+
+	content, toolCalls, finish, usage := drain(t, newStreamAdapter(stream, "anthropic.claude-sonnet-4-5-20250929-v1:0", true, true))
+
+	// The input of the output tool is the answer, not a tool call.
+	assert.JSONEq(t, `{"city":"Paris"}`, content)
+	assert.Zero(t, toolCalls)
+	assert.Equal(t, chat.FinishReasonStop, finish)
+	require.NotNil(t, usage)
+	assert.Equal(t, int64(120), usage.InputTokens)
+	assert.Equal(t, int64(15), usage.OutputTokens)
+}
+
+func TestStreamAdapter_ToolCallWithStructuredOutput(t *testing.T) {
+	t.Parallel()
+
+	stream := newFakeEventStream(
+		toolUseStart("tooluse_1", "get_weather"),
+		toolUseDelta(`{"city":"Paris"}`),
+		messageStop(types.StopReasonToolUse),
+		metadata(120, 15),
+	)
+
+	content, toolCalls, finish, _ := drain(t, newStreamAdapter(stream, "anthropic.claude-sonnet-4-5-20250929-v1:0", true, true))
+
+	// Other tools are still called before answering.
+	assert.Empty(t, content)
+	assert.Equal(t, 2, toolCalls)
+	assert.Equal(t, chat.FinishReasonToolCalls, finish)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -225,7 +226,8 @@ func (c *Client) CreateChatCompletionStream(
 	}
 
 	trackUsage := c.ModelConfig.TrackUsage == nil || *c.ModelConfig.TrackUsage
-	return newStreamAdapter(output.GetStream(), c.ModelConfig.Model, trackUsage), nil
+	structuredOutput := c.ModelOptions.StructuredOutput() != nil
+	return newStreamAdapter(output.GetStream(), c.ModelConfig.Model, trackUsage, structuredOutput), nil
 }
 
 func (c *Client) buildConverseStreamInput(messages []chat.Message, requestTools []tools.Tool) *bedrockruntime.ConverseStreamInput {
@@ -247,10 +249,21 @@ func (c *Client) buildConverseStreamInput(messages []chat.Message, requestTools
 	// Set inference configuration (temp/topP are suppressed when thinking is on).
 	input.InferenceConfig = c.buildInferenceConfig(c.isThinkingEnabled())
 
+	// The Converse API has no response format: a structured output is
+	// requested as the input of a tool the model answers with.
+	structuredOutput := c.ModelOptions.StructuredOutput()
+	if structuredOutput != nil {
+		slog.Debug("Bedrock request using structured output", "name", structuredOutput.Name)
+		requestTools = append(slices.Clone(requestTools), structuredOutputTool(structuredOutput))
+	}
+
 	// Convert and set tools
 	if len(requestTools) > 0 {
 		input.ToolConfig = convertToolConfig(requestTools, enableCaching)
 	}
+	if structuredOutput != nil {
+		input.ToolConfig.ToolChoice = structuredOutputToolChoice(len(requestTools) == 1, c.isThinkingEnabled())
+	}
 
 	return input
 }
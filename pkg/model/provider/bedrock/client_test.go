@@ -14,6 +14,7 @@ import (
 	"github.com/docker/docker-agent/pkg/config/latest"
 	"github.com/docker/docker-agent/pkg/environment"
 	"github.com/docker/docker-agent/pkg/model/provider/base"
+	"github.com/docker/docker-agent/pkg/model/provider/options"
 	"github.com/docker/docker-agent/pkg/tools"
 )
 
@@ -55,6 +56,35 @@ func TestConvertMessages_SystemExtraction(t *testing.T) {
 	assert.Equal(t, "Be helpful", systemBlock.Value)
 }
 
+func TestConvertMessages_SystemReminder(t *testing.T) {
+	t.Parallel()
+
+	msgs := []chat.Message{
+		{Role: chat.MessageRoleSystem, Content: "Be helpful"},
+		{Role: chat.MessageRoleUser, Content: "Hi"},
+		chat.SystemReminderMessage("Keep it short"),
+		{Role: chat.MessageRoleAssistant, Content: "Hello"},
+		chat.SystemReminderMessage("Answer in French"),
+	}
+
+	bedrockMsgs, system := convertMessages(msgs, false)
+
+	require.Len(t, system, 1)
+	require.Len(t, bedrockMsgs, 3)
+
+	// The first reminder joins the user message it follows.
+	assert.Equal(t, types.ConversationRoleUser, bedrockMsgs[0].Role)
+	require.Len(t, bedrockMsgs[0].Content, 2)
+	textBlock, ok := bedrockMsgs[0].Content[1].(*types.ContentBlockMemberText)
+	require.True(t, ok)
+	assert.Equal(t, "<system-reminder>\nKeep it short\n</system-reminder>", textBlock.Value)
+
+	assert.Equal(t, types.ConversationRoleUser, bedrockMsgs[2].Role)
+	textBlock, ok = bedrockMsgs[2].Content[0].(*types.ContentBlockMemberText)
+	require.True(t, ok)
+	assert.Equal(t, "<system-reminder>\nAnswer in French\n</system-reminder>", textBlock.Value)
+}
+
 func TestConvertMessages_AssistantWithToolCalls(t *testing.T) {
 	t.Parallel()
 
@@ -1323,3 +1353,62 @@ func TestConvertMessages_ToolResultWithCaching(t *testing.T) {
 	_, isCachePoint = secondLastContent.(*types.ContentBlockMemberCachePoint)
 	assert.True(t, isCachePoint, "assistant tool call message should have cache point")
 }
+
+func TestBuildConverseStreamInput_StructuredOutput(t *testing.T) {
+	t.Parallel()
+
+	var modelOptions options.ModelOptions
+	options.WithStructuredOutput(&latest.StructuredOutput{
+		Name:   "weather",
+		Schema: map[string]any{"type": "object", "properties": map[string]any{"city": map[string]any{"type": "string"}}},
+	})(&modelOptions)
+
+	client := &Client{
+		Config: base.Config{
+			ModelConfig:  latest.ModelConfig{Provider: "amazon-bedrock", Model: "anthropic.claude-sonnet-4-5-20250929-v1:0"},
+			ModelOptions: modelOptions,
+		},
+	}
+	messages := []chat.Message{{Role: chat.MessageRoleUser, Content: "Weather in Paris?"}}
+
+	// Without other tools, the model must answer with the output tool.
+	input := client.buildConverseStreamInput(messages, nil)
+	require.NotNil(t, input.ToolConfig)
+	require.Len(t, input.ToolConfig.Tools, 1)
+	spec, ok := input.ToolConfig.Tools[0].(*types.ToolMemberToolSpec)
+	require.True(t, ok)
+	assert.Equal(t, structuredOutputToolName, *spec.Value.Name)
+	choice, ok := input.ToolConfig.ToolChoice.(*types.ToolChoiceMemberTool)
+	require.True(t, ok)
+	assert.Equal(t, structuredOutputToolName, *choice.Value.Name)
+
+	// With other tools, it must call one of them.
+	requestTools := []tools.Tool{{Name: "get_weather", Description: "Get the weather"}}
+	input = client.buildConverseStreamInput(messages, requestTools)
+	require.Len(t, input.ToolConfig.Tools, 2)
+	assert.IsType(t, &types.ToolChoiceMemberAny{}, input.ToolConfig.ToolChoice)
+	assert.Len(t, requestTools, 1)
+}
+
+func TestBuildConverseStreamInput_StructuredOutputWithThinking(t *testing.T) {
+	t.Parallel()
+
+	var modelOptions options.ModelOptions
+	options.WithStructuredOutput(&latest.StructuredOutput{Name: "answer", Schema: map[string]any{"type": "object"}})(&modelOptions)
+
+	client := &Client{
+		Config: base.Config{
+			ModelConfig: latest.ModelConfig{
+				Provider:       "amazon-bedrock",
+				Model:          "anthropic.claude-sonnet-4-5-20250929-v1:0",
+				ThinkingBudget: &latest.ThinkingBudget{Tokens: 2048},
+			},
+			ModelOptions: modelOptions,
+		},
+	}
+
+	input := client.buildConverseStreamInput([]chat.Message{{Role: chat.MessageRoleUser, Content: "Hi"}}, nil)
+
+	// Extended thinking doesn't allow forcing a tool.
+	assert.IsType(t, &types.ToolChoiceMemberAuto{}, input.ToolConfig.ToolChoice)
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
 
 	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/config/latest"
 	"github.com/docker/docker-agent/pkg/tools"
 )
 
@@ -20,6 +21,8 @@ import (
 func convertMessages(messages []chat.Message, enableCaching bool) ([]types.Message, []types.SystemContentBlock) {
 	var bedrockMessages []types.Message
 	var systemBlocks []types.SystemContentBlock
+	// System messages are moved before the conversation, reminders stay in place.
+	messages = chat.SystemReminders(messages, false)
 
 	for i := 0; i < len(messages); i++ {
 		msg := &messages[i]
@@ -43,7 +46,12 @@ func convertMessages(messages []chat.Message, enableCaching bool) ([]types.Messa
 
 		case chat.MessageRoleUser:
 			contentBlocks := convertUserContent(msg)
-			if len(contentBlocks) > 0 {
+			last := len(bedrockMessages) - 1
+			if msg.SystemReminder && last >= 0 && bedrockMessages[last].Role == types.ConversationRoleUser {
+				// Roles must alternate: a reminder joins the user message
+				// or the tool results it follows.
+				bedrockMessages[last].Content = append(bedrockMessages[last].Content, contentBlocks...)
+			} else if len(contentBlocks) > 0 {
 				bedrockMessages = append(bedrockMessages, types.Message{
 					Role:    types.ConversationRoleUser,
 					Content: contentBlocks,
@@ -279,6 +287,39 @@ func convertToolConfig(requestTools []tools.Tool, enableCaching bool) *types.Too
 	}
 }
 
+// structuredOutputToolName is the name of the tool Bedrock models answer
+// with when a structured output is requested.
+const structuredOutputToolName = "structured_output"
+
+// structuredOutputTool returns the tool whose input schema is the schema of
+// the structured output.
+func structuredOutputTool(output *latest.StructuredOutput) tools.Tool {
+	description := "Give your final answer by calling this tool with it."
+	if output.Description != "" {
+		description += " " + output.Description
+	}
+	return tools.Tool{
+		Name:        structuredOutputToolName,
+		Description: description,
+		Parameters:  output.Schema,
+	}
+}
+
+// structuredOutputToolChoice makes the model call the structured output tool
+// when it's its only tool, or any tool otherwise, so that it can still use
+// its tools before answering. Extended thinking only allows the model to
+// choose, so the tool is left optional then.
+func structuredOutputToolChoice(onlyTool, thinking bool) types.ToolChoice {
+	switch {
+	case thinking:
+		return &types.ToolChoiceMemberAuto{Value: types.AutoToolChoice{}}
+	case onlyTool:
+		return &types.ToolChoiceMemberTool{Value: types.SpecificToolChoice{Name: aws.String(structuredOutputToolName)}}
+	default:
+		return &types.ToolChoiceMemberAny{Value: types.AnyToolChoice{}}
+	}
+}
+
 func convertToolSchema(params any) document.Interface {
 	schema, err := tools.SchemaToMap(params)
 	if err != nil {
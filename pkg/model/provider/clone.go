@@ -17,9 +17,9 @@ func CloneWithOptions(ctx context.Context, base Provider, opts ...options.Opt) P
 	baseOpts := options.FromModelOptions(config.ModelOptions)
 	mergedOpts := append(baseOpts, opts...)
 
-	// Apply max_tokens override if present in options
-	// We need to apply it to the ModelConfig itself since that's what providers use
-	// Only update MaxTokens if an option explicitly sets it (non-zero value)
+	// Apply max_tokens, temperature and top_p overrides if present in options
+	// We need to apply them to the ModelConfig itself since that's what providers use
+	// Only update them if an option explicitly sets them
 	modelConfig := config.ModelConfig
 	for _, opt := range mergedOpts {
 		tempOpts := &options.ModelOptions{}
@@ -27,8 +27,15 @@ func CloneWithOptions(ctx context.Context, base Provider, opts ...options.Opt) P
 		if mt := tempOpts.MaxTokens(); mt != 0 {
 			modelConfig.MaxTokens = &mt
 		}
+		if t := tempOpts.Temperature(); t != nil {
+			modelConfig.Temperature = t
+		}
+		if p := tempOpts.TopP(); p != nil {
+			modelConfig.TopP = p
+		}
 		if tempOpts.NoThinking() {
 			modelConfig.ThinkingBudget = nil
+			modelConfig.Reasoning = nil
 		}
 	}
 
@@ -212,3 +212,49 @@ func TestCloneWithOptions_OverridesMaxTokens(t *testing.T) {
 	assert.Equal(t, newMaxTokens, *clonedConfig.ModelConfig.MaxTokens,
 		"MaxTokens should be updated to the new value")
 }
+
+func TestCloneWithOptions_OverridesSamplingParameters(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	temperature := 0.7
+	cfg := &latest.ModelConfig{
+		Provider:    "openai",
+		Model:       "gpt-4o",
+		BaseURL:     server.URL,
+		Temperature: &temperature,
+	}
+
+	env := newCloneTestEnv(map[string]string{
+		"OPENAI_API_KEY": "test-key",
+	})
+
+	provider, err := New(t.Context(), cfg, env)
+	require.NoError(t, err)
+
+	cloned := CloneWithOptions(t.Context(), provider, options.WithTemperature(0), options.WithTopP(0.5))
+	require.NotSame(t, provider, cloned)
+	assert.Equal(t, provider.ID(), cloned.ID())
+
+	clonedConfig := cloned.BaseConfig()
+	require.NotNil(t, clonedConfig.ModelConfig.Temperature)
+	assert.InDelta(t, 0.0, *clonedConfig.ModelConfig.Temperature, 0)
+	require.NotNil(t, clonedConfig.ModelConfig.TopP)
+	assert.InDelta(t, 0.5, *clonedConfig.ModelConfig.TopP, 0)
+
+	// The original provider keeps its configuration.
+	originalConfig := provider.BaseConfig()
+	require.NotNil(t, originalConfig.ModelConfig.Temperature)
+	assert.InDelta(t, 0.7, *originalConfig.ModelConfig.Temperature, 0)
+	assert.Nil(t, originalConfig.ModelConfig.TopP)
+
+	// Cloning the clone keeps the overrides.
+	again := CloneWithOptions(t.Context(), cloned, options.WithGeneratingTitle())
+	require.NotNil(t, again.BaseConfig().ModelConfig.Temperature)
+	assert.InDelta(t, 0.0, *again.BaseConfig().ModelConfig.Temperature, 0)
+}
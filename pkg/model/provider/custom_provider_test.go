@@ -452,6 +452,20 @@ func TestResolveProviderTypeFromConfig(t *testing.T) {
 			},
 			expected: "openai",
 		},
+		{
+			name: "ollama-openai keeps the OpenAI-compatible endpoint",
+			config: &latest.ModelConfig{
+				Provider: "ollama-openai",
+			},
+			expected: "openai",
+		},
+		{
+			name: "ollama uses its native client",
+			config: &latest.ModelConfig{
+				Provider: "ollama",
+			},
+			expected: "ollama",
+		},
 		{
 			name: "provider name is fallback",
 			config: &latest.ModelConfig{
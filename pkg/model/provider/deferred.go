@@ -0,0 +1,141 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/model/provider/base"
+	"github.com/docker/docker-agent/pkg/rag/types"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// deferredProvider creates the client of a model with its first request,
+// see options.WithDeferredClient. Until then, it answers from the
+// configuration of the model.
+//
+// The capabilities of the client, e.g. embeddings, are only known once it's
+// created: the deferred provider has them all, and fails the requests the
+// client doesn't support.
+type deferredProvider struct {
+	config base.Config
+	create func(ctx context.Context) (Provider, error)
+
+	mu     sync.Mutex
+	client Provider
+}
+
+var (
+	_ BatchEmbeddingProvider = (*deferredProvider)(nil)
+	_ RerankingProvider      = (*deferredProvider)(nil)
+)
+
+func newDeferredProvider(config base.Config, create func(ctx context.Context) (Provider, error)) *deferredProvider {
+	return &deferredProvider{
+		config: config,
+		create: create,
+	}
+}
+
+func (p *deferredProvider) ID() string {
+	return p.config.ID()
+}
+
+func (p *deferredProvider) BaseConfig() base.Config {
+	if client := p.created(); client != nil {
+		return client.BaseConfig()
+	}
+	return p.config
+}
+
+func (p *deferredProvider) CreateChatCompletionStream(ctx context.Context, messages []chat.Message, availableTools []tools.Tool) (chat.MessageStream, error) {
+	client, err := p.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.CreateChatCompletionStream(ctx, messages, availableTools)
+}
+
+func (p *deferredProvider) CreateEmbedding(ctx context.Context, text string) (*base.EmbeddingResult, error) {
+	client, err := p.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	embeddingClient, ok := client.(EmbeddingProvider)
+	if !ok {
+		return nil, fmt.Errorf("provider %s does not support embeddings", p.ID())
+	}
+	return embeddingClient.CreateEmbedding(ctx, text)
+}
+
+// CreateBatchEmbedding embeds the texts one by one with clients that don't
+// batch them.
+func (p *deferredProvider) CreateBatchEmbedding(ctx context.Context, texts []string) (*base.BatchEmbeddingResult, error) {
+	client, err := p.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if batchClient, ok := client.(BatchEmbeddingProvider); ok {
+		return batchClient.CreateBatchEmbedding(ctx, texts)
+	}
+
+	result := &base.BatchEmbeddingResult{Embeddings: make([][]float64, 0, len(texts))}
+	for _, text := range texts {
+		embedding, err := p.CreateEmbedding(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		result.Embeddings = append(result.Embeddings, embedding.Embedding)
+		result.InputTokens += embedding.InputTokens
+		result.TotalTokens += embedding.TotalTokens
+		result.Cost += embedding.Cost
+	}
+	return result, nil
+}
+
+func (p *deferredProvider) Rerank(ctx context.Context, query string, documents []types.Document, criteria string) ([]float64, error) {
+	client, err := p.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rerankingClient, ok := client.(RerankingProvider)
+	if !ok {
+		return nil, fmt.Errorf("model %s does not support reranking operation", p.ID())
+	}
+	return rerankingClient.Rerank(ctx, query, documents, criteria)
+}
+
+// LastSelectedModelID returns the model the client last routed a request
+// to, or "" if it doesn't route them or wasn't created yet.
+func (p *deferredProvider) LastSelectedModelID() string {
+	if router, ok := p.created().(interface{ LastSelectedModelID() string }); ok {
+		return router.LastSelectedModelID()
+	}
+	return ""
+}
+
+// created returns the client, or nil if it wasn't created yet.
+func (p *deferredProvider) created() Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.client
+}
+
+// getClient returns the client, creating it the first time. A failure isn't
+// kept: the next request tries again, e.g. once the user signed in.
+func (p *deferredProvider) getClient(ctx context.Context) (Provider, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.client == nil {
+		// The client serves the next requests too: it mustn't be canceled
+		// with the one creating it.
+		client, err := p.create(context.WithoutCancel(ctx))
+		if err != nil {
+			return nil, err
+		}
+		p.client = client
+	}
+	return p.client, nil
+}
@@ -0,0 +1,157 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/config/latest"
+	"github.com/docker/docker-agent/pkg/environment"
+	"github.com/docker/docker-agent/pkg/model/provider/base"
+	"github.com/docker/docker-agent/pkg/model/provider/options"
+	"github.com/docker/docker-agent/pkg/rag/types"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+type stubClient struct {
+	base.Config
+}
+
+func (c *stubClient) CreateChatCompletionStream(context.Context, []chat.Message, []tools.Tool) (chat.MessageStream, error) {
+	return nil, nil
+}
+
+// embeddingClient is a client embedding texts, one at a time, and reranking
+// documents.
+type embeddingClient struct {
+	stubClient
+	lastSelected string
+}
+
+func (c *embeddingClient) CreateEmbedding(_ context.Context, text string) (*base.EmbeddingResult, error) {
+	return &base.EmbeddingResult{Embedding: []float64{float64(len(text))}, InputTokens: 1, TotalTokens: 1}, nil
+}
+
+func (c *embeddingClient) Rerank(_ context.Context, _ string, documents []types.Document, _ string) ([]float64, error) {
+	return make([]float64, len(documents)), nil
+}
+
+func (c *embeddingClient) LastSelectedModelID() string {
+	return c.lastSelected
+}
+
+// unauthorizedTransport answers every request with a 401.
+type unauthorizedTransport struct{}
+
+func (unauthorizedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusUnauthorized,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"error":{"message":"invalid api key"}}`)),
+		Request:    req,
+	}, nil
+}
+
+func TestDeferredProvider(t *testing.T) {
+	t.Parallel()
+
+	config := base.Config{ModelConfig: latest.ModelConfig{Provider: "openai", Model: "gpt-4o"}}
+	created := 0
+	fail := true
+	p := newDeferredProvider(config, func(ctx context.Context) (Provider, error) {
+		require.NoError(t, ctx.Err())
+		created++
+		if fail {
+			return nil, errors.New("no credentials")
+		}
+		return &stubClient{Config: config}, nil
+	})
+
+	// Nothing is created before the first request.
+	assert.Equal(t, "openai/gpt-4o", p.ID())
+	assert.Equal(t, config, p.BaseConfig())
+	assert.Equal(t, 0, created)
+
+	// A failure is returned, and retried with the next request.
+	ctx, cancel := context.WithCancel(t.Context())
+	_, err := p.CreateChatCompletionStream(ctx, nil, nil)
+	require.ErrorContains(t, err, "no credentials")
+
+	// The client outlives the request creating it.
+	fail = false
+	cancel()
+	_, err = p.CreateChatCompletionStream(ctx, nil, nil)
+	require.NoError(t, err)
+	_, err = p.CreateChatCompletionStream(t.Context(), nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, created)
+}
+
+func TestNew_DeferredClient(t *testing.T) {
+	t.Parallel()
+
+	p, err := New(t.Context(), &latest.ModelConfig{Provider: "openai", Model: "gpt-4o"}, nil, options.WithDeferredClient())
+	require.NoError(t, err)
+	assert.IsType(t, &deferredProvider{}, p)
+	assert.Equal(t, "openai/gpt-4o", p.ID())
+
+	// The configuration is still checked upfront.
+	_, err = New(t.Context(), &latest.ModelConfig{Provider: "unknown", Model: "model"}, nil, options.WithDeferredClient())
+	require.ErrorContains(t, err, "unknown provider type: unknown")
+}
+
+func TestNew_DeferredClientValidated(t *testing.T) {
+	t.Parallel()
+
+	// Models to validate are created, and checked, upfront.
+	env := environment.NewMapEnvProvider(map[string]string{"OPENAI_API_KEY": "dummy"})
+	_, err := New(t.Context(), &latest.ModelConfig{Provider: "openai", Model: "gpt-4o", Validate: true}, env,
+		options.WithDeferredClient(), options.WithHTTPTransport(unauthorizedTransport{}))
+	require.ErrorContains(t, err, "/models is unreachable")
+}
+
+func TestDeferredProvider_Capabilities(t *testing.T) {
+	t.Parallel()
+
+	config := base.Config{ModelConfig: latest.ModelConfig{Provider: "openai", Model: "text-embedding-3-small"}}
+	client := &embeddingClient{stubClient: stubClient{Config: config}, lastSelected: "openai/gpt-4o-mini"}
+	p := newDeferredProvider(config, func(context.Context) (Provider, error) {
+		return client, nil
+	})
+
+	var prov Provider = p
+	require.Implements(t, (*BatchEmbeddingProvider)(nil), prov)
+	require.Implements(t, (*RerankingProvider)(nil), prov)
+	assert.Empty(t, p.LastSelectedModelID(), "the client wasn't created yet")
+
+	embedding, err := p.CreateEmbedding(t.Context(), "hello")
+	require.NoError(t, err)
+	assert.Equal(t, []float64{5}, embedding.Embedding)
+
+	// The client doesn't batch: the texts are embedded one by one.
+	batch, err := p.CreateBatchEmbedding(t.Context(), []string{"a", "abc"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float64{{1}, {3}}, batch.Embeddings)
+	assert.Equal(t, int64(2), batch.TotalTokens)
+
+	scores, err := p.Rerank(t.Context(), "query", []types.Document{{}, {}}, "")
+	require.NoError(t, err)
+	assert.Len(t, scores, 2)
+	assert.Equal(t, "openai/gpt-4o-mini", p.LastSelectedModelID())
+
+	// The requests a chat client doesn't support fail.
+	chatOnly := newDeferredProvider(config, func(context.Context) (Provider, error) {
+		return &stubClient{Config: config}, nil
+	})
+	_, err = chatOnly.CreateEmbedding(t.Context(), "hello")
+	require.ErrorContains(t, err, "does not support embeddings")
+	_, err = chatOnly.Rerank(t.Context(), "query", nil, "")
+	require.ErrorContains(t, err, "does not support reranking")
+}
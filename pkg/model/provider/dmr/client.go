@@ -134,8 +134,10 @@ func NewClient(ctx context.Context, cfg *latest.ModelConfig, opts ...options.Opt
 
 // convertMessages converts chat messages to OpenAI format and merges consecutive
 // system/user messages, which is needed by some local models run by DMR.
+// System reminders are sent as user messages, since the chat templates of
+// many local models only accept a system message at the start.
 func convertMessages(messages []chat.Message) []openai.ChatCompletionMessageParamUnion {
-	openaiMessages := oaistream.ConvertMessages(messages)
+	openaiMessages := oaistream.ConvertMessages(chat.SystemReminders(messages, false))
 	return oaistream.MergeConsecutiveMessages(openaiMessages)
 }
 
@@ -0,0 +1,177 @@
+// Package fake provides a model provider that plays back a scripted sequence
+// of turns, so that agent flows can be tested without network access.
+//
+// Each call to CreateChatCompletionStream consumes the next turn of the
+// script, checks the request against the turn's expectations, and streams the
+// turn's chunks:
+//
+//	provider := fake.NewScriptedProvider(t, "fake/model",
+//		fake.NewTurn().
+//			ToolCall("call_1", "read_file", `{"path":`, `"main.go"}`).
+//			Expect(fake.ToolNames("read_file", "write_file")),
+//		fake.NewTurn().
+//			Content("The file ", "looks good.").
+//			Expect(fake.LastMessage(chat.MessageRoleTool, "package main")),
+//	)
+package fake
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/model/provider/base"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// TestingT is the subset of *testing.T used by ScriptedProvider.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// Request is what the provider received for a turn.
+type Request struct {
+	Messages []chat.Message
+	Tools    []tools.Tool
+}
+
+// ScriptedProvider is a provider.Provider that plays back a programmed
+// sequence of turns. It's safe for concurrent use.
+type ScriptedProvider struct {
+	t  TestingT
+	id string
+
+	mu       sync.Mutex
+	turns    []*Turn
+	requests []Request
+}
+
+// NewScriptedProvider creates a provider that answers each request with the
+// next of the given turns. id is the "provider/model" ID of the model.
+//
+// If t has a Cleanup method, like *testing.T, the test fails at cleanup when
+// some turns were not played.
+func NewScriptedProvider(t TestingT, id string, turns ...*Turn) *ScriptedProvider {
+	p := &ScriptedProvider{
+		t:     t,
+		id:    id,
+		turns: turns,
+	}
+	if c, ok := t.(interface{ Cleanup(func()) }); ok {
+		c.Cleanup(p.AssertDone)
+	}
+	return p
+}
+
+// ID returns the "provider/model" ID given to NewScriptedProvider.
+func (p *ScriptedProvider) ID() string {
+	return p.id
+}
+
+// BaseConfig returns an empty configuration, so that cloning the provider,
+// e.g. for session compaction, keeps using the script instead of creating a
+// real provider.
+func (p *ScriptedProvider) BaseConfig() base.Config {
+	return base.Config{}
+}
+
+// MaxTokens returns 0: the output of scripted turns isn't limited.
+func (p *ScriptedProvider) MaxTokens() int {
+	return 0
+}
+
+// CreateChatCompletionStream checks the request against the expectations of
+// the next turn and returns a stream of its chunks.
+func (p *ScriptedProvider) CreateChatCompletionStream(_ context.Context, messages []chat.Message, availableTools []tools.Tool) (chat.MessageStream, error) {
+	p.mu.Lock()
+	req := Request{Messages: messages, Tools: availableTools}
+	p.requests = append(p.requests, req)
+	index := len(p.requests) - 1
+	var turn *Turn
+	if index < len(p.turns) {
+		turn = p.turns[index]
+	}
+	p.mu.Unlock()
+
+	p.t.Helper()
+
+	if turn == nil {
+		p.t.Errorf("fake provider %s: unexpected request %d, only %d turns were scripted%s", p.id, index+1, len(p.turns), describeLastMessage(messages))
+		return nil, fmt.Errorf("fake provider %s: no turn left for request %d", p.id, index+1)
+	}
+
+	for _, expect := range turn.expectations {
+		expect(&turnT{t: p.t, prefix: fmt.Sprintf("fake provider %s, turn %d: ", p.id, index+1)}, req)
+	}
+
+	if turn.err != nil {
+		return nil, turn.err
+	}
+	return &stream{chunks: turn.chunks(), err: turn.streamErr}, nil
+}
+
+// Requests returns the requests received so far.
+func (p *ScriptedProvider) Requests() []Request {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Request(nil), p.requests...)
+}
+
+// AssertDone fails the test if some turns were not played.
+func (p *ScriptedProvider) AssertDone() {
+	p.mu.Lock()
+	played, scripted := len(p.requests), len(p.turns)
+	p.mu.Unlock()
+
+	p.t.Helper()
+	if played < scripted {
+		p.t.Errorf("fake provider %s: %d of %d scripted turns were played", p.id, played, scripted)
+	}
+}
+
+func describeLastMessage(messages []chat.Message) string {
+	if len(messages) == 0 {
+		return ""
+	}
+	last := messages[len(messages)-1]
+	return fmt.Sprintf("; last message (%s): %q", last.Role, last.Content)
+}
+
+// turnT prefixes the failures of expectations with the provider and turn.
+type turnT struct {
+	t      TestingT
+	prefix string
+}
+
+func (t *turnT) Helper() {
+	t.t.Helper()
+}
+
+func (t *turnT) Errorf(format string, args ...any) {
+	t.t.Helper()
+	t.t.Errorf(t.prefix+format, args...)
+}
+
+// stream plays back the chunks of a turn.
+type stream struct {
+	chunks []chat.MessageStreamResponse
+	err    error
+	next   int
+}
+
+func (s *stream) Recv() (chat.MessageStreamResponse, error) {
+	if s.next >= len(s.chunks) {
+		if s.err != nil {
+			return chat.MessageStreamResponse{}, s.err
+		}
+		return chat.MessageStreamResponse{}, io.EOF
+	}
+	chunk := s.chunks[s.next]
+	s.next++
+	return chunk, nil
+}
+
+func (s *stream) Close() {}
@@ -0,0 +1,169 @@
+package fake
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// recordingT records failures instead of failing the test.
+type recordingT struct {
+	errors []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func readAll(t *testing.T, s chat.MessageStream) ([]chat.MessageStreamResponse, error) {
+	t.Helper()
+	var chunks []chat.MessageStreamResponse
+	for {
+		chunk, err := s.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return chunks, nil
+			}
+			return chunks, err
+		}
+		chunks = append(chunks, chunk)
+	}
+}
+
+func TestScriptedProvider_PlaysTurns(t *testing.T) {
+	p := NewScriptedProvider(t, "fake/model",
+		NewTurn().
+			Reasoning("Let me ", "look").
+			ToolCall("call_1", "read_file", `{"path":`, `"main.go"}`).
+			Usage(10, 5),
+		NewTurn().
+			Content("All ", "good"),
+	)
+
+	s, err := p.CreateChatCompletionStream(t.Context(), nil, nil)
+	require.NoError(t, err)
+	chunks, err := readAll(t, s)
+	require.NoError(t, err)
+	require.Len(t, chunks, 6)
+	assert.Equal(t, "Let me ", chunks[0].Choices[0].Delta.ReasoningContent)
+	assert.Equal(t, "look", chunks[1].Choices[0].Delta.ReasoningContent)
+	assert.Equal(t, "call_1", chunks[2].Choices[0].Delta.ToolCalls[0].ID)
+	assert.Equal(t, "read_file", chunks[2].Choices[0].Delta.ToolCalls[0].Function.Name)
+	assert.Equal(t, `{"path":`, chunks[3].Choices[0].Delta.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, `"main.go"}`, chunks[4].Choices[0].Delta.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, chat.FinishReasonToolCalls, chunks[5].Choices[0].FinishReason)
+	assert.Equal(t, &chat.Usage{InputTokens: 10, OutputTokens: 5}, chunks[5].Usage)
+
+	s, err = p.CreateChatCompletionStream(t.Context(), nil, nil)
+	require.NoError(t, err)
+	chunks, err = readAll(t, s)
+	require.NoError(t, err)
+	require.Len(t, chunks, 3)
+	assert.Equal(t, "All ", chunks[0].Choices[0].Delta.Content)
+	assert.Equal(t, "good", chunks[1].Choices[0].Delta.Content)
+	assert.Equal(t, chat.FinishReasonStop, chunks[2].Choices[0].FinishReason)
+	assert.Nil(t, chunks[2].Usage)
+
+	assert.Len(t, p.Requests(), 2)
+}
+
+func TestScriptedProvider_Errors(t *testing.T) {
+	requestErr := errors.New("rate limited")
+	streamErr := errors.New("connection reset")
+	p := NewScriptedProvider(t, "fake/model",
+		NewTurn().Error(requestErr),
+		NewTurn().Content("partial").StreamError(streamErr),
+	)
+
+	_, err := p.CreateChatCompletionStream(t.Context(), nil, nil)
+	require.ErrorIs(t, err, requestErr)
+
+	s, err := p.CreateChatCompletionStream(t.Context(), nil, nil)
+	require.NoError(t, err)
+	chunks, err := readAll(t, s)
+	require.ErrorIs(t, err, streamErr)
+	require.Len(t, chunks, 1)
+	assert.Equal(t, "partial", chunks[0].Choices[0].Delta.Content)
+}
+
+func TestScriptedProvider_Expectations(t *testing.T) {
+	rt := &recordingT{}
+	p := NewScriptedProvider(rt, "fake/model",
+		NewTurn().Content("ok").Expect(
+			LastMessage(chat.MessageRoleUser, "hello"),
+			HasMessage(chat.MessageRoleSystem, "helpful"),
+			Roles(chat.MessageRoleSystem, chat.MessageRoleUser),
+			ToolNames("write_file", "read_file"),
+		),
+	)
+
+	messages := []chat.Message{
+		{Role: chat.MessageRoleSystem, Content: "You are a helpful agent"},
+		{Role: chat.MessageRoleUser, Content: "hello there"},
+	}
+	availableTools := []tools.Tool{{Name: "read_file"}, {Name: "write_file"}}
+
+	_, err := p.CreateChatCompletionStream(t.Context(), messages, availableTools)
+	require.NoError(t, err)
+	assert.Empty(t, rt.errors)
+}
+
+func TestScriptedProvider_ExpectationFailures(t *testing.T) {
+	rt := &recordingT{}
+	p := NewScriptedProvider(rt, "fake/model",
+		NewTurn().Content("ok").Expect(
+			LastMessage(chat.MessageRoleTool, "result"),
+			HasMessage(chat.MessageRoleSystem, "expert"),
+			ToolNames("read_file"),
+		),
+	)
+
+	messages := []chat.Message{{Role: chat.MessageRoleUser, Content: "hello"}}
+	availableTools := []tools.Tool{{Name: "read_file"}, {Name: "shell"}}
+
+	_, err := p.CreateChatCompletionStream(t.Context(), messages, availableTools)
+	require.NoError(t, err)
+
+	require.Len(t, rt.errors, 4)
+	for _, e := range rt.errors {
+		assert.Contains(t, e, "fake provider fake/model, turn 1: ")
+	}
+	assert.Contains(t, rt.errors[0], "role of the last message")
+	assert.Contains(t, rt.errors[1], "content of the last message")
+	assert.Contains(t, rt.errors[2], `expected a system message containing "expert", got:`)
+	assert.Contains(t, rt.errors[2], "user: hello")
+	assert.Contains(t, rt.errors[3], "names of the tools")
+	assert.Contains(t, rt.errors[3], `"shell"`)
+}
+
+func TestScriptedProvider_UnexpectedRequest(t *testing.T) {
+	rt := &recordingT{}
+	p := NewScriptedProvider(rt, "fake/model")
+
+	_, err := p.CreateChatCompletionStream(t.Context(), []chat.Message{{Role: chat.MessageRoleUser, Content: "again"}}, nil)
+	require.Error(t, err)
+	require.Len(t, rt.errors, 1)
+	assert.Contains(t, rt.errors[0], "unexpected request 1, only 0 turns were scripted")
+	assert.Contains(t, rt.errors[0], `last message (user): "again"`)
+}
+
+func TestScriptedProvider_AssertDone(t *testing.T) {
+	rt := &recordingT{}
+	p := NewScriptedProvider(rt, "fake/model", NewTurn().Content("one"), NewTurn().Content("two"))
+
+	_, err := p.CreateChatCompletionStream(t.Context(), nil, nil)
+	require.NoError(t, err)
+
+	p.AssertDone()
+	require.Len(t, rt.errors, 1)
+	assert.Equal(t, "fake provider fake/model: 1 of 2 scripted turns were played", rt.errors[0])
+}
@@ -0,0 +1,194 @@
+package fake
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// Turn is one scripted response of the model: the chunks it streams and the
+// expectations on the request it answers.
+type Turn struct {
+	deltas       []chat.MessageDelta
+	usage        *chat.Usage
+	finishReason chat.FinishReason
+	hasToolCalls bool
+	err          error
+	streamErr    error
+	expectations []Expectation
+}
+
+// NewTurn creates an empty turn. Without chunks, the turn streams nothing
+// but its finish reason.
+func NewTurn() *Turn {
+	return &Turn{}
+}
+
+// Content streams each part as a separate content delta.
+func (t *Turn) Content(parts ...string) *Turn {
+	for _, part := range parts {
+		t.deltas = append(t.deltas, chat.MessageDelta{Content: part})
+	}
+	return t
+}
+
+// Reasoning streams each part as a separate reasoning delta.
+func (t *Turn) Reasoning(parts ...string) *Turn {
+	for _, part := range parts {
+		t.deltas = append(t.deltas, chat.MessageDelta{ReasoningContent: part})
+	}
+	return t
+}
+
+// ToolCall streams a tool call: a first delta with its ID and name, then one
+// delta per chunk of arguments.
+func (t *Turn) ToolCall(id, name string, argumentChunks ...string) *Turn {
+	t.hasToolCalls = true
+	t.deltas = append(t.deltas, chat.MessageDelta{ToolCalls: []tools.ToolCall{{
+		ID:       id,
+		Type:     "function",
+		Function: tools.FunctionCall{Name: name},
+	}}})
+	for _, chunk := range argumentChunks {
+		t.deltas = append(t.deltas, chat.MessageDelta{ToolCalls: []tools.ToolCall{{
+			ID:       id,
+			Type:     "function",
+			Function: tools.FunctionCall{Arguments: chunk},
+		}}})
+	}
+	return t
+}
+
+// Usage sets the token usage sent with the last chunk.
+func (t *Turn) Usage(inputTokens, outputTokens int64) *Turn {
+	t.usage = &chat.Usage{InputTokens: inputTokens, OutputTokens: outputTokens}
+	return t
+}
+
+// FinishReason sets the finish reason of the last chunk. It defaults to
+// "tool_calls" for turns with tool calls, and to "stop" otherwise.
+func (t *Turn) FinishReason(reason chat.FinishReason) *Turn {
+	t.finishReason = reason
+	return t
+}
+
+// Error makes the request fail with err instead of returning a stream.
+func (t *Turn) Error(err error) *Turn {
+	t.err = err
+	return t
+}
+
+// StreamError makes the stream fail with err after the chunks of the turn,
+// in place of the finish reason.
+func (t *Turn) StreamError(err error) *Turn {
+	t.streamErr = err
+	return t
+}
+
+// Expect adds expectations on the request the turn answers.
+func (t *Turn) Expect(expectations ...Expectation) *Turn {
+	t.expectations = append(t.expectations, expectations...)
+	return t
+}
+
+func (t *Turn) chunks() []chat.MessageStreamResponse {
+	var chunks []chat.MessageStreamResponse
+	for _, delta := range t.deltas {
+		chunks = append(chunks, chat.MessageStreamResponse{
+			Choices: []chat.MessageStreamChoice{{Delta: delta}},
+		})
+	}
+	if t.streamErr != nil {
+		return chunks
+	}
+
+	finishReason := t.finishReason
+	if finishReason == "" {
+		finishReason = chat.FinishReasonStop
+		if t.hasToolCalls {
+			finishReason = chat.FinishReasonToolCalls
+		}
+	}
+	return append(chunks, chat.MessageStreamResponse{
+		Choices: []chat.MessageStreamChoice{{FinishReason: finishReason}},
+		Usage:   t.usage,
+	})
+}
+
+// Expectation checks the request answered by a turn, and reports mismatches
+// with t.Errorf.
+type Expectation func(t TestingT, req Request)
+
+// LastMessage expects the last message of the request to have the given
+// role and to contain the given text.
+func LastMessage(role chat.MessageRole, contains string) Expectation {
+	return func(t TestingT, req Request) {
+		t.Helper()
+		if len(req.Messages) == 0 {
+			t.Errorf("expected a last %s message containing %q, got no messages", role, contains)
+			return
+		}
+		last := req.Messages[len(req.Messages)-1]
+		assert.Equal(t, role, last.Role, "role of the last message")
+		assert.Contains(t, last.Content, contains, "content of the last message")
+	}
+}
+
+// HasMessage expects one of the messages of the request to have the given
+// role and to contain the given text.
+func HasMessage(role chat.MessageRole, contains string) Expectation {
+	return func(t TestingT, req Request) {
+		t.Helper()
+		if !slices.ContainsFunc(req.Messages, func(m chat.Message) bool {
+			return m.Role == role && strings.Contains(m.Content, contains)
+		}) {
+			t.Errorf("expected a %s message containing %q, got:\n%s", role, contains, describeMessages(req.Messages))
+		}
+	}
+}
+
+// Roles expects the messages of the request to have exactly these roles.
+func Roles(roles ...chat.MessageRole) Expectation {
+	return func(t TestingT, req Request) {
+		t.Helper()
+		var actual []chat.MessageRole
+		for _, m := range req.Messages {
+			actual = append(actual, m.Role)
+		}
+		assert.Equal(t, roles, actual, "roles of the messages")
+	}
+}
+
+// ToolNames expects the request to offer exactly these tools, in any order.
+func ToolNames(names ...string) Expectation {
+	return func(t TestingT, req Request) {
+		t.Helper()
+		var actual []string
+		for _, tool := range req.Tools {
+			actual = append(actual, tool.Name)
+		}
+		assert.Equal(t, slices.Sorted(slices.Values(names)), slices.Sorted(slices.Values(actual)), "names of the tools")
+	}
+}
+
+func describeMessages(messages []chat.Message) string {
+	var sb strings.Builder
+	for i, m := range messages {
+		content := m.Content
+		if len(content) > 200 {
+			content = content[:200] + "..."
+		}
+		sb.WriteString("  ")
+		sb.WriteString(string(m.Role))
+		sb.WriteString(": ")
+		sb.WriteString(strings.ReplaceAll(content, "\n", `\n`))
+		if i < len(messages)-1 {
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
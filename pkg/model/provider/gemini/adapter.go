@@ -215,7 +215,12 @@ func (g *StreamAdapter) Recv() (chat.MessageStreamResponse, error) {
 			toolCalls := make([]tools.ToolCall, 0, len(funcs))
 			for _, fc := range funcs {
 				argsJSON, _ := json.Marshal(fc.Args)
-				id := "call_" + uuid.New().String()
+				// Keep Gemini's ID so the functionResponse can reference it,
+				// and synthesize one when the API omits it.
+				id := fc.ID
+				if id == "" {
+					id = syntheticToolCallIDPrefix + uuid.New().String()
+				}
 				slog.Debug("Gemini: Function call", "name", fc.Name, "args", string(argsJSON), "id", id)
 				toolCalls = append(toolCalls, tools.ToolCall{
 					ID:   id,
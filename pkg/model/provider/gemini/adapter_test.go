@@ -1,6 +1,7 @@
 package gemini
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -53,4 +54,32 @@ func TestStreamAdapter_FunctionCalls(t *testing.T) {
 		// Should NOT include tool calls in final message (to avoid duplication)
 		require.Empty(t, finalResp.Choices[0].Delta.ToolCalls)
 	})
+
+	t.Run("tool call IDs", func(t *testing.T) {
+		mockResp := &genai.GenerateContentResponse{
+			Candidates: []*genai.Candidate{{
+				Content: &genai.Content{
+					Parts: []*genai.Part{
+						{FunctionCall: &genai.FunctionCall{ID: "gemini-id", Name: "with_id"}},
+						{FunctionCall: &genai.FunctionCall{Name: "without_id"}},
+					},
+				},
+			}},
+		}
+
+		iter := func(fn func(*genai.GenerateContentResponse, error) bool) {
+			fn(mockResp, nil)
+		}
+
+		adapter := NewStreamAdapter(iter, "test-model", true)
+		defer adapter.Close()
+
+		resp, err := adapter.Recv()
+		require.NoError(t, err)
+
+		toolCalls := resp.Choices[0].Delta.ToolCalls
+		require.Len(t, toolCalls, 2)
+		require.Equal(t, "gemini-id", toolCalls[0].ID, "IDs sent by Gemini must be kept")
+		require.True(t, strings.HasPrefix(toolCalls[1].ID, syntheticToolCallIDPrefix), "missing IDs must be synthesized")
+	})
 }
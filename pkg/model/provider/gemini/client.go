@@ -98,7 +98,7 @@ func NewClient(ctx context.Context, cfg *latest.ModelConfig, env environment.Pro
 			}
 
 			backend = genai.BackendGeminiAPI
-			httpClient = httpclient.NewHTTPClient(ctx)
+			httpClient = httpclient.NewHTTPClient(ctx, httpclient.WithTransport(globalOptions.HTTPTransport()))
 		}
 
 		client, err := genai.NewClient(ctx, &genai.ClientConfig{
@@ -145,6 +145,7 @@ func NewClient(ctx context.Context, cfg *latest.ModelConfig, env environment.Pro
 				httpclient.WithModel(cfg.Model),
 				httpclient.WithModelName(cfg.Name),
 				httpclient.WithQuery(url.Query()),
+				httpclient.WithTransport(globalOptions.HTTPTransport()),
 			}
 			if globalOptions.GeneratingTitle() {
 				httpOptions = append(httpOptions, httpclient.WithHeader("X-Cagent-GeneratingTitle", "1"))
@@ -191,10 +192,31 @@ func thoughtSignatureOrDefault(sig []byte) []byte {
 	return defaultThoughtSignature
 }
 
+// syntheticToolCallIDPrefix prefixes the tool call IDs synthesized by the
+// stream adapter when Gemini omits them. Those IDs are only meaningful to
+// docker-agent and are never sent back to the API. The prefix is specific
+// to Gemini: IDs from other providers, e.g. OpenAI's "call_..." ones in a
+// session that switched models, are sent as is.
+const syntheticToolCallIDPrefix = "gemini_synth_"
+
+// geminiToolCallID returns the ID to send to Gemini for a tool call,
+// or an empty string when the ID was synthesized locally.
+func geminiToolCallID(id string) string {
+	if strings.HasPrefix(id, syntheticToolCallIDPrefix) {
+		return ""
+	}
+	return id
+}
+
 // convertMessagesToGemini converts chat.Messages into Gemini Contents
 func convertMessagesToGemini(messages []chat.Message) []*genai.Content {
 	contents := make([]*genai.Content, 0, len(messages))
-	for i := range messages {
+	// toolNames maps tool call IDs to function names: Gemini keys
+	// functionResponse parts by name, while tool messages only carry the ID.
+	toolNames := make(map[string]string)
+	// Gemini has no system role in the conversation.
+	messages = chat.SystemReminders(messages, false)
+	for i := 0; i < len(messages); i++ {
 		msg := &messages[i]
 
 		// Skip empty messages
@@ -204,32 +226,16 @@ func convertMessagesToGemini(messages []chat.Message) []*genai.Content {
 
 		role := messageRoleToGemini(msg.Role)
 
-		// Handle tool responses
+		// Handle tool responses. Consecutive tool messages answer the same
+		// model turn and are grouped into a single content, as Gemini
+		// expects as many functionResponse parts as there were functionCall parts.
 		if msg.Role == chat.MessageRoleTool && msg.ToolCallID != "" {
-			response := map[string]any{"result": msg.Content}
-
-			// Check for image content in MultiContent
-			var imageParts []*genai.FunctionResponsePart
-			for _, mc := range msg.MultiContent {
-				if mc.Type == chat.MessagePartTypeImageURL && mc.ImageURL != nil && strings.HasPrefix(mc.ImageURL.URL, "data:") {
-					urlParts := strings.SplitN(mc.ImageURL.URL, ",", 2)
-					if len(urlParts) == 2 {
-						mimeType := extractMimeType(urlParts[0])
-						data, err := base64.StdEncoding.DecodeString(urlParts[1])
-						if err == nil {
-							imageParts = append(imageParts, genai.NewFunctionResponsePartFromBytes(data, mimeType))
-						}
-					}
-				}
+			var parts []*genai.Part
+			for ; i < len(messages) && messages[i].Role == chat.MessageRoleTool && messages[i].ToolCallID != ""; i++ {
+				parts = append(parts, convertToolResponseToGemini(&messages[i], toolNames[messages[i].ToolCallID]))
 			}
-
-			var part *genai.Part
-			if len(imageParts) > 0 {
-				part = genai.NewPartFromFunctionResponseWithParts(msg.ToolCallID, response, imageParts)
-			} else {
-				part = genai.NewPartFromFunctionResponse(msg.ToolCallID, response)
-			}
-			contents = append(contents, genai.NewContentFromParts([]*genai.Part{part}, role))
+			i--
+			contents = append(contents, genai.NewContentFromParts(parts, role))
 			continue
 		}
 
@@ -247,8 +253,10 @@ func convertMessagesToGemini(messages []chat.Message) []*genai.Content {
 					_ = json.Unmarshal([]byte(tc.Function.Arguments), &args)
 				}
 				fc := genai.NewPartFromFunctionCall(tc.Function.Name, args)
+				fc.FunctionCall.ID = geminiToolCallID(tc.ID)
 				fc.ThoughtSignature = sig
 				parts = append(parts, fc)
+				toolNames[tc.ID] = tc.Function.Name
 			}
 
 			contents = append(contents, genai.NewContentFromParts(parts, role))
@@ -269,6 +277,40 @@ func convertMessagesToGemini(messages []chat.Message) []*genai.Content {
 	return contents
 }
 
+// convertToolResponseToGemini converts a tool message into a functionResponse
+// part for the function called under the same ID.
+func convertToolResponseToGemini(msg *chat.Message, name string) *genai.Part {
+	response := map[string]any{"result": msg.Content}
+
+	// Check for image content in MultiContent
+	var imageParts []*genai.FunctionResponsePart
+	for _, mc := range msg.MultiContent {
+		if mc.Type == chat.MessagePartTypeImageURL && mc.ImageURL != nil && strings.HasPrefix(mc.ImageURL.URL, "data:") {
+			urlParts := strings.SplitN(mc.ImageURL.URL, ",", 2)
+			if len(urlParts) == 2 {
+				mimeType := extractMimeType(urlParts[0])
+				data, err := base64.StdEncoding.DecodeString(urlParts[1])
+				if err == nil {
+					imageParts = append(imageParts, genai.NewFunctionResponsePartFromBytes(data, mimeType))
+				}
+			}
+		}
+	}
+
+	// Orphaned tool results (e.g. when compaction dropped the call) fall
+	// back to the ID, the best name we have.
+	name = cmp.Or(name, msg.ToolCallID)
+
+	var part *genai.Part
+	if len(imageParts) > 0 {
+		part = genai.NewPartFromFunctionResponseWithParts(name, response, imageParts)
+	} else {
+		part = genai.NewPartFromFunctionResponse(name, response)
+	}
+	part.FunctionResponse.ID = geminiToolCallID(msg.ToolCallID)
+	return part
+}
+
 // messageRoleToGemini converts chat.MessageRole to genai.Role
 func messageRoleToGemini(role chat.MessageRole) genai.Role {
 	if role == chat.MessageRoleAssistant {
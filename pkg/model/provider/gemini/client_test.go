@@ -379,6 +379,124 @@ func TestConvertMessagesToGemini_ThoughtSignature(t *testing.T) {
 	}
 }
 
+func TestConvertMessagesToGemini_ToolTurnsRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	sig1 := []byte("signature-turn-1")
+	sig2 := []byte("signature-turn-2")
+
+	contents := convertMessagesToGemini([]chat.Message{
+		{Role: chat.MessageRoleSystem, Content: "You are helpful."},
+		{Role: chat.MessageRoleUser, Content: "What's the weather in Paris and Rome?"},
+		{
+			Role:             chat.MessageRoleAssistant,
+			ThoughtSignature: sig1,
+			ToolCalls: []tools.ToolCall{
+				{ID: "gemini-id-1", Function: tools.FunctionCall{Name: "weather", Arguments: `{"city":"Paris"}`}},
+				{ID: "gemini-id-2", Function: tools.FunctionCall{Name: "weather", Arguments: `{"city":"Rome"}`}},
+			},
+		},
+		{Role: chat.MessageRoleTool, ToolCallID: "gemini-id-1", Content: "sunny"},
+		{Role: chat.MessageRoleTool, ToolCallID: "gemini-id-2", Content: "rainy"},
+		{
+			Role:             chat.MessageRoleAssistant,
+			ThoughtSignature: sig2,
+			ToolCalls: []tools.ToolCall{
+				// Synthesized by the stream adapter: Gemini omitted the ID.
+				{ID: syntheticToolCallIDPrefix + "1234", Function: tools.FunctionCall{Name: "forecast", Arguments: `{"city":"Rome"}`}},
+			},
+		},
+		{Role: chat.MessageRoleTool, ToolCallID: syntheticToolCallIDPrefix + "1234", Content: "sunny tomorrow"},
+		{Role: chat.MessageRoleAssistant, Content: "Paris is sunny, Rome is rainy but sunny tomorrow."},
+	})
+
+	// system, user, model(2 calls), user(2 responses), model(1 call), user(1 response), model(text)
+	require.Len(t, contents, 7)
+
+	firstCalls := contents[2]
+	assert.Equal(t, genai.RoleModel, firstCalls.Role)
+	require.Len(t, firstCalls.Parts, 2)
+	for i, wantID := range []string{"gemini-id-1", "gemini-id-2"} {
+		require.NotNil(t, firstCalls.Parts[i].FunctionCall)
+		assert.Equal(t, wantID, firstCalls.Parts[i].FunctionCall.ID)
+		assert.Equal(t, "weather", firstCalls.Parts[i].FunctionCall.Name)
+		assert.Equal(t, sig1, firstCalls.Parts[i].ThoughtSignature)
+	}
+
+	firstResponses := contents[3]
+	assert.Equal(t, genai.RoleUser, firstResponses.Role)
+	require.Len(t, firstResponses.Parts, 2, "parallel tool results must be grouped in a single content")
+	for i, wantID := range []string{"gemini-id-1", "gemini-id-2"} {
+		require.NotNil(t, firstResponses.Parts[i].FunctionResponse)
+		assert.Equal(t, wantID, firstResponses.Parts[i].FunctionResponse.ID)
+		assert.Equal(t, "weather", firstResponses.Parts[i].FunctionResponse.Name)
+	}
+	assert.Equal(t, map[string]any{"result": "rainy"}, firstResponses.Parts[1].FunctionResponse.Response)
+
+	secondCall := contents[4]
+	require.Len(t, secondCall.Parts, 1)
+	assert.Empty(t, secondCall.Parts[0].FunctionCall.ID, "synthesized IDs must not be sent to Gemini")
+	assert.Equal(t, sig2, secondCall.Parts[0].ThoughtSignature)
+
+	secondResponse := contents[5]
+	require.Len(t, secondResponse.Parts, 1)
+	assert.Equal(t, "forecast", secondResponse.Parts[0].FunctionResponse.Name)
+	assert.Empty(t, secondResponse.Parts[0].FunctionResponse.ID)
+}
+
+func TestConvertMessagesToGemini_MixedProviderHistory(t *testing.T) {
+	t.Parallel()
+
+	// The first turn was answered by an OpenAI model, the second one by
+	// Gemini, which omitted the ID of its tool call.
+	contents := convertMessagesToGemini([]chat.Message{
+		{Role: chat.MessageRoleUser, Content: "What's the weather in Paris?"},
+		{
+			Role: chat.MessageRoleAssistant,
+			ToolCalls: []tools.ToolCall{
+				{ID: "call_abc123", Function: tools.FunctionCall{Name: "weather", Arguments: `{"city":"Paris"}`}},
+			},
+		},
+		{Role: chat.MessageRoleTool, ToolCallID: "call_abc123", Content: "sunny"},
+		{Role: chat.MessageRoleUser, Content: "And in Rome?"},
+		{
+			Role: chat.MessageRoleAssistant,
+			ToolCalls: []tools.ToolCall{
+				{ID: syntheticToolCallIDPrefix + "1234", Function: tools.FunctionCall{Name: "weather", Arguments: `{"city":"Rome"}`}},
+			},
+		},
+		{Role: chat.MessageRoleTool, ToolCallID: syntheticToolCallIDPrefix + "1234", Content: "rainy"},
+	})
+
+	// user, model(call), user(response), user, model(call), user(response)
+	require.Len(t, contents, 6)
+
+	require.NotNil(t, contents[1].Parts[0].FunctionCall)
+	assert.Equal(t, "call_abc123", contents[1].Parts[0].FunctionCall.ID, "other providers' IDs must be kept")
+	require.NotNil(t, contents[2].Parts[0].FunctionResponse)
+	assert.Equal(t, "call_abc123", contents[2].Parts[0].FunctionResponse.ID)
+
+	require.NotNil(t, contents[4].Parts[0].FunctionCall)
+	assert.Empty(t, contents[4].Parts[0].FunctionCall.ID, "synthesized IDs must not be sent to Gemini")
+	require.NotNil(t, contents[5].Parts[0].FunctionResponse)
+	assert.Empty(t, contents[5].Parts[0].FunctionResponse.ID)
+}
+
+func TestConvertMessagesToGemini_SystemReminder(t *testing.T) {
+	t.Parallel()
+
+	contents := convertMessagesToGemini([]chat.Message{
+		{Role: chat.MessageRoleUser, Content: "Hi"},
+		{Role: chat.MessageRoleAssistant, Content: "Hello"},
+		chat.SystemReminderMessage("Keep it short"),
+	})
+
+	require.Len(t, contents, 3)
+	assert.Equal(t, genai.RoleUser, contents[2].Role)
+	require.Len(t, contents[2].Parts, 1)
+	assert.Equal(t, "<system-reminder>\nKeep it short\n</system-reminder>", contents[2].Parts[0].Text)
+}
+
 func TestBuiltInTools(t *testing.T) {
 	t.Parallel()
 
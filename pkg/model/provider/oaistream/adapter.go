@@ -5,6 +5,7 @@ This is a shared adapter for OpenAI-compatible streams.
 */
 
 import (
+	"cmp"
 	"encoding/json"
 	"io"
 
@@ -21,6 +22,11 @@ type StreamAdapter struct {
 	lastFinishReason chat.FinishReason
 	toolCalls        map[int]string
 	trackUsage       bool
+	// withheldFinishReason is a stop/length finish reason held back while
+	// waiting for the trailing usage chunk. Some OpenAI-compatible servers
+	// never send usage, so it's flushed when the stream ends.
+	withheldFinishReason chat.FinishReason
+	usageSeen            bool
 }
 
 func NewStreamAdapter(stream *ssestream.Stream[openai.ChatCompletionChunk], trackUsage bool) *StreamAdapter {
@@ -38,6 +44,15 @@ func (a *StreamAdapter) Recv() (chat.MessageStreamResponse, error) {
 		if err != nil {
 			return chat.MessageStreamResponse{}, WrapOpenAIError(err)
 		}
+		if a.withheldFinishReason != "" && !a.usageSeen {
+			// The server omitted usage: report the finish reason without
+			// usage so the cost is unknown rather than zero.
+			finishReason := a.withheldFinishReason
+			a.withheldFinishReason = ""
+			return chat.MessageStreamResponse{
+				Choices: []chat.MessageStreamChoice{{FinishReason: finishReason}},
+			}, nil
+		}
 		return chat.MessageStreamResponse{}, io.EOF
 	}
 
@@ -58,6 +73,7 @@ func (a *StreamAdapter) Recv() (chat.MessageStreamResponse, error) {
 
 		finishReasonStr := choice.FinishReason
 		if a.trackUsage && (finishReasonStr == "stop" || finishReasonStr == "length") {
+			a.withheldFinishReason = chat.FinishReason(finishReasonStr)
 			finishReasonStr = ""
 		}
 
@@ -116,6 +132,7 @@ func (a *StreamAdapter) Recv() (chat.MessageStreamResponse, error) {
 						Name:      toolCall.Function.Name,
 						Arguments: toolCall.Function.Arguments,
 					},
+					Index: new(index),
 				}
 			}
 		}
@@ -123,8 +140,10 @@ func (a *StreamAdapter) Recv() (chat.MessageStreamResponse, error) {
 
 	// Check if Usage field is present using the JSON metadata
 	if openaiResponse.JSON.Usage.Valid() {
-		if a.trackUsage {
-			usage := openaiResponse.Usage
+		a.usageSeen = true
+		// Servers that don't track usage may send an all-zero usage object;
+		// treat it as missing instead of resetting the session counters.
+		if usage := openaiResponse.Usage; a.trackUsage && (usage.PromptTokens > 0 || usage.CompletionTokens > 0) {
 			response.Usage = &chat.Usage{
 				InputTokens:  usage.PromptTokens,
 				OutputTokens: usage.CompletionTokens,
@@ -141,8 +160,9 @@ func (a *StreamAdapter) Recv() (chat.MessageStreamResponse, error) {
 		// Use the tracked finish reason instead of hardcoding stop
 		finishReason := a.lastFinishReason
 		if finishReason == chat.FinishReasonNull || finishReason == "" {
-			finishReason = chat.FinishReasonStop
+			finishReason = cmp.Or(a.withheldFinishReason, chat.FinishReasonStop)
 		}
+		a.withheldFinishReason = ""
 		// OPENAI returns the usage without a finish reason or a choice, so we fake it here
 		// and create a new choice for the last event in the stream
 		if len(openaiResponse.Choices) == 0 {
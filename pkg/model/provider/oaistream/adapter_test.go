@@ -101,3 +101,69 @@ data: [DONE]
 	assert.Equal(t, "Hi", resp.Choices[0].Delta.Content)
 	assert.Empty(t, resp.Choices[0].Delta.ReasoningContent)
 }
+
+func TestStreamAdapter_TrackUsageWithoutUsageChunk(t *testing.T) {
+	t.Parallel()
+
+	// Some OpenAI-compatible servers (llama.cpp, vLLM, ...) never send a
+	// usage chunk. The withheld finish reason must still be reported.
+	sseData := `data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"test","choices":[{"index":0,"delta":{"content":"Hello!"},"finish_reason":null}]}
+
+data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"test","choices":[{"index":0,"delta":{},"finish_reason":"length"}]}
+
+data: [DONE]
+
+`
+
+	stream := newTestStream(t, sseData)
+	adapter := NewStreamAdapter(stream, true)
+	defer adapter.Close()
+
+	resp, err := adapter.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "Hello!", resp.Choices[0].Delta.Content)
+
+	// The finish reason is held back while waiting for usage.
+	resp, err = adapter.Recv()
+	require.NoError(t, err)
+	require.Len(t, resp.Choices, 1)
+	assert.Empty(t, resp.Choices[0].FinishReason)
+
+	// The stream ended without usage: the finish reason is flushed, without usage.
+	resp, err = adapter.Recv()
+	require.NoError(t, err)
+	require.Len(t, resp.Choices, 1)
+	assert.Equal(t, "length", string(resp.Choices[0].FinishReason))
+	assert.Nil(t, resp.Usage)
+
+	_, err = adapter.Recv()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestStreamAdapter_ZeroUsageIsIgnored(t *testing.T) {
+	t.Parallel()
+
+	sseData := `data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"test","choices":[{"index":0,"delta":{"content":"Hello!"},"finish_reason":"stop"}]}
+
+data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"test","choices":[],"usage":{"prompt_tokens":0,"completion_tokens":0,"total_tokens":0}}
+
+data: [DONE]
+
+`
+
+	stream := newTestStream(t, sseData)
+	adapter := NewStreamAdapter(stream, true)
+	defer adapter.Close()
+
+	_, err := adapter.Recv()
+	require.NoError(t, err)
+
+	resp, err := adapter.Recv()
+	require.NoError(t, err)
+	require.Len(t, resp.Choices, 1)
+	assert.Equal(t, "stop", string(resp.Choices[0].FinishReason))
+	assert.Nil(t, resp.Usage, "an all-zero usage must not reset the session counters")
+
+	_, err = adapter.Recv()
+	assert.ErrorIs(t, err, io.EOF)
+}
@@ -0,0 +1,105 @@
+package ollama
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/google/uuid"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// streamAdapter adapts Ollama's newline-delimited JSON stream to chat.MessageStream
+type streamAdapter struct {
+	body         io.ReadCloser
+	decoder      *json.Decoder
+	trackUsage   bool
+	hasToolCalls bool
+	done         bool
+}
+
+func newStreamAdapter(body io.ReadCloser, trackUsage bool) *streamAdapter {
+	return &streamAdapter{
+		body:       body,
+		decoder:    json.NewDecoder(body),
+		trackUsage: trackUsage,
+	}
+}
+
+// Recv gets the next completion chunk
+func (a *streamAdapter) Recv() (chat.MessageStreamResponse, error) {
+	if a.done {
+		return chat.MessageStreamResponse{}, io.EOF
+	}
+
+	var chunk chatResponse
+	if err := a.decoder.Decode(&chunk); err != nil {
+		if errors.Is(err, io.EOF) {
+			return chat.MessageStreamResponse{}, io.EOF
+		}
+		return chat.MessageStreamResponse{}, err
+	}
+	if chunk.Error != "" {
+		return chat.MessageStreamResponse{}, errors.New(chunk.Error)
+	}
+
+	choice := chat.MessageStreamChoice{
+		Delta: chat.MessageDelta{
+			Role:             string(chat.MessageRoleAssistant),
+			Content:          chunk.Message.Content,
+			ReasoningContent: chunk.Message.Thinking,
+		},
+	}
+
+	// Ollama sends complete tool calls, never partial ones.
+	for _, tc := range chunk.Message.ToolCalls {
+		a.hasToolCalls = true
+		id := tc.ID
+		if id == "" {
+			id = "call_" + uuid.New().String()
+		}
+		choice.Delta.ToolCalls = append(choice.Delta.ToolCalls, tools.ToolCall{
+			ID:   id,
+			Type: "function",
+			Function: tools.FunctionCall{
+				Name:      tc.Function.Name,
+				Arguments: string(tc.Function.Arguments),
+			},
+		})
+	}
+
+	response := chat.MessageStreamResponse{
+		Object:  "chat.completion.chunk",
+		Model:   chunk.Model,
+		Choices: []chat.MessageStreamChoice{choice},
+	}
+
+	if chunk.Done {
+		a.done = true
+
+		switch {
+		case a.hasToolCalls:
+			response.Choices[0].FinishReason = chat.FinishReasonToolCalls
+		case chunk.DoneReason == "length":
+			response.Choices[0].FinishReason = chat.FinishReasonLength
+		default:
+			response.Choices[0].FinishReason = chat.FinishReasonStop
+		}
+
+		if a.trackUsage {
+			response.Usage = &chat.Usage{
+				InputTokens:  chunk.PromptEvalCount,
+				OutputTokens: chunk.EvalCount,
+			}
+		}
+	}
+
+	return response, nil
+}
+
+// Close closes the stream
+func (a *streamAdapter) Close() {
+	_ = a.body.Close()
+}
@@ -0,0 +1,238 @@
+package ollama
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/config/latest"
+	"github.com/docker/docker-agent/pkg/environment"
+	"github.com/docker/docker-agent/pkg/httpclient"
+	"github.com/docker/docker-agent/pkg/model/provider/base"
+	"github.com/docker/docker-agent/pkg/model/provider/options"
+	"github.com/docker/docker-agent/pkg/model/provider/providerutil"
+	"github.com/docker/docker-agent/pkg/modelerrors"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// DefaultBaseURL is the address of a local Ollama server.
+const DefaultBaseURL = "http://localhost:11434"
+
+// Client represents a native Ollama client wrapper.
+// It implements the provider.Provider interface
+type Client struct {
+	base.Config
+
+	httpClient *http.Client
+	baseURL    string
+	authToken  string
+}
+
+// NewClient creates a new Ollama client from the provided configuration
+func NewClient(ctx context.Context, cfg *latest.ModelConfig, env environment.Provider, opts ...options.Opt) (*Client, error) {
+	if cfg == nil {
+		slog.Error("Ollama client creation failed", "error", "model configuration is required")
+		return nil, errors.New("model configuration is required")
+	}
+
+	var globalOptions options.ModelOptions
+	for _, opt := range opts {
+		opt(&globalOptions)
+	}
+
+	// Ollama doesn't need auth, but it's often exposed behind a reverse proxy that does.
+	var authToken string
+	if cfg.TokenKey != "" {
+		authToken, _ = env.Get(ctx, cfg.TokenKey)
+		if authToken == "" {
+			return nil, fmt.Errorf("%s environment variable is required", cfg.TokenKey)
+		}
+	}
+
+	baseURL := normalizeBaseURL(cmp.Or(cfg.BaseURL, DefaultBaseURL))
+
+	slog.Debug("Ollama client created successfully", "model", cfg.Model, "base_url", baseURL)
+
+	return &Client{
+		Config: base.Config{
+			ModelConfig:  *cfg,
+			ModelOptions: globalOptions,
+			Env:          env,
+		},
+		httpClient: httpclient.NewHTTPClient(ctx, httpclient.WithHeaders(cfg.ExtraHeaders), httpclient.WithTransport(globalOptions.HTTPTransport())),
+		baseURL:    baseURL,
+		authToken:  authToken,
+	}, nil
+}
+
+// normalizeBaseURL strips the trailing slash and the OpenAI-compatible /v1
+// suffix so that base URLs written for the former `ollama` alias keep working.
+func normalizeBaseURL(baseURL string) string {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	return strings.TrimSuffix(baseURL, "/v1")
+}
+
+// CreateChatCompletionStream creates a streaming chat completion request
+// It returns a stream that can be iterated over to get completion chunks
+func (c *Client) CreateChatCompletionStream(ctx context.Context, messages []chat.Message, requestTools []tools.Tool) (chat.MessageStream, error) {
+	slog.Debug("Creating Ollama chat completion stream",
+		"model", c.ModelConfig.Model,
+		"message_count", len(messages),
+		"tool_count", len(requestTools),
+		"base_url", c.baseURL,
+	)
+
+	if len(messages) == 0 {
+		slog.Error("Ollama stream creation failed", "error", "at least one message is required")
+		return nil, errors.New("at least one message is required")
+	}
+
+	request, err := c.buildRequest(messages, requestTools)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Ollama request: %w", err)
+	}
+	slog.Debug("Ollama chat request", "request", string(body))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Ollama request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ollama request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, modelerrors.WrapHTTPError(resp.StatusCode, resp, readError(resp.Body))
+	}
+
+	trackUsage := c.ModelConfig.TrackUsage == nil || *c.ModelConfig.TrackUsage
+
+	slog.Debug("Ollama chat completion stream created successfully", "model", c.ModelConfig.Model, "base_url", c.baseURL)
+	return newStreamAdapter(resp.Body, trackUsage), nil
+}
+
+// buildRequest converts the conversation and the tools into an /api/chat request.
+func (c *Client) buildRequest(messages []chat.Message, requestTools []tools.Tool) (*chatRequest, error) {
+	request := &chatRequest{
+		Model:     c.ModelConfig.Model,
+		Messages:  convertMessages(messages),
+		Stream:    true,
+		Options:   requestOptions(&c.ModelConfig),
+		KeepAlive: keepAlive(c.ModelConfig.ProviderOpts),
+	}
+
+	for _, t := range requestTools {
+		parameters, err := tools.SchemaToMap(t.Parameters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert tool parameters for tool %s: %w", t.Name, err)
+		}
+		request.Tools = append(request.Tools, tool{
+			Type: "function",
+			Function: toolFunction{
+				Name:        t.Name,
+				Description: t.Description,
+				Parameters:  parameters,
+			},
+		})
+	}
+
+	if structuredOutput := c.ModelOptions.StructuredOutput(); structuredOutput != nil {
+		format, err := json.Marshal(structuredOutput.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal structured output schema: %w", err)
+		}
+		request.Format = format
+	}
+
+	return request, nil
+}
+
+// requestOptions maps the model configuration to Ollama's runtime options.
+func requestOptions(cfg *latest.ModelConfig) map[string]any {
+	opts := map[string]any{}
+
+	if cfg.Temperature != nil {
+		opts["temperature"] = *cfg.Temperature
+	}
+	if cfg.TopP != nil {
+		opts["top_p"] = *cfg.TopP
+	}
+	if cfg.FrequencyPenalty != nil {
+		opts["frequency_penalty"] = *cfg.FrequencyPenalty
+	}
+	if cfg.PresencePenalty != nil {
+		opts["presence_penalty"] = *cfg.PresencePenalty
+	}
+	if cfg.MaxTokens != nil {
+		opts["num_predict"] = *cfg.MaxTokens
+	}
+	if numCtx, ok := providerutil.GetProviderOptInt64(cfg.ProviderOpts, "num_ctx"); ok {
+		opts["num_ctx"] = numCtx
+	}
+	if topK, ok := providerutil.GetProviderOptInt64(cfg.ProviderOpts, "top_k"); ok {
+		opts["top_k"] = topK
+	}
+	if seed, ok := providerutil.GetProviderOptInt64(cfg.ProviderOpts, "seed"); ok {
+		opts["seed"] = seed
+	}
+	if minP, ok := providerutil.GetProviderOptFloat64(cfg.ProviderOpts, "min_p"); ok {
+		opts["min_p"] = minP
+	}
+	if penalty, ok := providerutil.GetProviderOptFloat64(cfg.ProviderOpts, "repetition_penalty"); ok {
+		opts["repeat_penalty"] = penalty
+	}
+
+	if len(opts) == 0 {
+		return nil
+	}
+	return opts
+}
+
+// keepAlive returns the keep_alive provider option: either a duration string
+// ("10m", "1h") or a number of seconds, where a negative value keeps the model
+// loaded forever and 0 unloads it right after the request.
+func keepAlive(opts map[string]any) any {
+	if v, ok := opts["keep_alive"].(string); ok {
+		if v != "" {
+			return v
+		}
+		return nil
+	}
+	if seconds, ok := providerutil.GetProviderOptInt64(opts, "keep_alive"); ok {
+		return seconds
+	}
+	if seconds, ok := providerutil.GetProviderOptFloat64(opts, "keep_alive"); ok {
+		return seconds
+	}
+	return nil
+}
+
+// readError extracts the error message of a failed Ollama request.
+func readError(body io.Reader) error {
+	data, _ := io.ReadAll(io.LimitReader(body, 64*1024))
+
+	var resp chatResponse
+	if err := json.Unmarshal(data, &resp); err == nil && resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	return errors.New(cmp.Or(strings.TrimSpace(string(data)), "unknown error"))
+}
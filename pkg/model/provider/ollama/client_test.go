@@ -0,0 +1,224 @@
+package ollama
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/config/latest"
+	"github.com/docker/docker-agent/pkg/environment"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// fakeServer answers /api/chat with the recorded stream from testdata and
+// captures the request body in *body.
+func fakeServer(t *testing.T, recording string) (*httptest.Server, *map[string]any) {
+	t.Helper()
+
+	recorded, err := os.ReadFile(recording)
+	require.NoError(t, err)
+
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/chat", r.URL.Path)
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &body)
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = w.Write(recorded)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &body
+}
+
+func TestCreateChatCompletionStream_ToolCalls(t *testing.T) {
+	t.Parallel()
+
+	server, body := fakeServer(t, "testdata/tool_call_stream.ndjson")
+
+	client, err := NewClient(t.Context(), &latest.ModelConfig{
+		Provider:    "ollama",
+		Model:       "qwen2.5-coder",
+		BaseURL:     server.URL,
+		Temperature: new(0.2),
+		ProviderOpts: map[string]any{
+			"num_ctx":    32768,
+			"keep_alive": "30m",
+		},
+	}, environment.NewMapEnvProvider(nil))
+	require.NoError(t, err)
+
+	stream, err := client.CreateChatCompletionStream(t.Context(),
+		[]chat.Message{{Role: chat.MessageRoleUser, Content: "What's the weather in Paris?"}},
+		[]tools.Tool{{
+			Name:        "get_weather",
+			Description: "Get the weather of a city",
+			Parameters: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"city": map[string]any{"type": "string"}},
+				"required":   []string{"city"},
+			},
+		}},
+	)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	var (
+		content, reasoning strings.Builder
+		toolCalls          []tools.ToolCall
+		finishReason       chat.FinishReason
+		usage              *chat.Usage
+	)
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		choice := resp.Choices[0]
+		content.WriteString(choice.Delta.Content)
+		reasoning.WriteString(choice.Delta.ReasoningContent)
+		toolCalls = append(toolCalls, choice.Delta.ToolCalls...)
+		if choice.FinishReason != "" {
+			finishReason = choice.FinishReason
+		}
+		if resp.Usage != nil {
+			usage = resp.Usage
+		}
+	}
+
+	assert.Equal(t, "Let me check.", content.String())
+	assert.Equal(t, "The user wants the weather.", reasoning.String())
+	require.Len(t, toolCalls, 1)
+	assert.NotEmpty(t, toolCalls[0].ID)
+	assert.Equal(t, "get_weather", toolCalls[0].Function.Name)
+	assert.JSONEq(t, `{"city":"Paris"}`, toolCalls[0].Function.Arguments)
+	assert.Equal(t, chat.FinishReasonToolCalls, finishReason)
+	require.NotNil(t, usage)
+	assert.Equal(t, int64(42), usage.InputTokens)
+	assert.Equal(t, int64(7), usage.OutputTokens)
+
+	// The request uses Ollama's native schema.
+	assert.Equal(t, true, (*body)["stream"])
+	assert.Equal(t, "30m", (*body)["keep_alive"])
+	assert.Equal(t, map[string]any{"num_ctx": float64(32768), "temperature": 0.2}, (*body)["options"])
+	requestTools := (*body)["tools"].([]any)
+	require.Len(t, requestTools, 1)
+	function := requestTools[0].(map[string]any)["function"].(map[string]any)
+	assert.Equal(t, "get_weather", function["name"])
+	assert.Equal(t, "object", function["parameters"].(map[string]any)["type"])
+}
+
+func TestCreateChatCompletionStream_Error(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"error":"model \"missing\" not found, try pulling it first"}`)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(t.Context(), &latest.ModelConfig{
+		Provider: "ollama",
+		Model:    "missing",
+		BaseURL:  server.URL,
+	}, environment.NewMapEnvProvider(nil))
+	require.NoError(t, err)
+
+	_, err = client.CreateChatCompletionStream(t.Context(), []chat.Message{{Role: chat.MessageRoleUser, Content: "hi"}}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "try pulling it first")
+}
+
+func TestConvertMessages_ToolTurns(t *testing.T) {
+	t.Parallel()
+
+	converted := convertMessages([]chat.Message{
+		{Role: chat.MessageRoleUser, Content: "What's the weather in Paris?"},
+		{
+			Role: chat.MessageRoleAssistant,
+			ToolCalls: []tools.ToolCall{{
+				ID:       "call_1",
+				Type:     "function",
+				Function: tools.FunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`},
+			}},
+		},
+		{Role: chat.MessageRoleTool, ToolCallID: "call_1", Content: "Sunny"},
+	})
+
+	require.Len(t, converted, 3)
+	require.Len(t, converted[1].ToolCalls, 1)
+	assert.Equal(t, "get_weather", converted[1].ToolCalls[0].Function.Name)
+	assert.JSONEq(t, `{"city":"Paris"}`, string(converted[1].ToolCalls[0].Function.Arguments))
+	assert.Equal(t, "tool", converted[2].Role)
+	assert.Equal(t, "get_weather", converted[2].ToolName)
+	assert.Equal(t, "Sunny", converted[2].Content)
+}
+
+func TestConvertMessages_SystemReminder(t *testing.T) {
+	t.Parallel()
+
+	converted := convertMessages([]chat.Message{
+		{Role: chat.MessageRoleUser, Content: "Hi"},
+		chat.SystemReminderMessage("Keep it short"),
+	})
+
+	require.Len(t, converted, 2)
+	assert.Equal(t, "user", converted[1].Role)
+	assert.Equal(t, "<system-reminder>\nKeep it short\n</system-reminder>", converted[1].Content)
+}
+
+func TestNormalizeBaseURL(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "http://localhost:11434", normalizeBaseURL("http://localhost:11434"))
+	assert.Equal(t, "http://localhost:11434", normalizeBaseURL("http://localhost:11434/"))
+	assert.Equal(t, "http://gpu-box:11434", normalizeBaseURL("http://gpu-box:11434/v1"))
+}
+
+func TestRequestOptions_FromYAML(t *testing.T) {
+	t.Parallel()
+
+	// goccy/go-yaml decodes positive integers as uint64, not int.
+	var cfg latest.ModelConfig
+	require.NoError(t, yaml.Unmarshal([]byte(`
+provider: ollama
+model: qwen2.5-coder
+provider_opts:
+  num_ctx: 32768
+  top_k: 40
+  seed: -1
+  min_p: 0.05
+  keep_alive: 600
+`), &cfg))
+
+	assert.Equal(t, map[string]any{
+		"num_ctx": int64(32768),
+		"top_k":   int64(40),
+		"seed":    int64(-1),
+		"min_p":   0.05,
+	}, requestOptions(&cfg))
+	assert.Equal(t, int64(600), keepAlive(cfg.ProviderOpts))
+}
+
+func TestKeepAlive(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "30m", keepAlive(map[string]any{"keep_alive": "30m"}))
+	assert.Equal(t, int64(-1), keepAlive(map[string]any{"keep_alive": -1}))
+	assert.Equal(t, int64(0), keepAlive(map[string]any{"keep_alive": uint64(0)}))
+	assert.InDelta(t, 1.5, keepAlive(map[string]any{"keep_alive": 1.5}), 0)
+	assert.Nil(t, keepAlive(map[string]any{"keep_alive": ""}))
+	assert.Nil(t, keepAlive(nil))
+}
@@ -0,0 +1,84 @@
+package ollama
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/docker/docker-agent/pkg/chat"
+)
+
+// convertMessages converts chat messages to Ollama's format.
+//
+// Ollama identifies tool results by the name of the tool rather than by call
+// ID, so tool messages are matched back to the assistant tool call they answer.
+func convertMessages(messages []chat.Message) []message {
+	toolNames := make(map[string]string)
+	// The chat templates of many local models only accept a system message
+	// at the start.
+	messages = chat.SystemReminders(messages, false)
+
+	converted := make([]message, 0, len(messages))
+	for i := range messages {
+		msg := &messages[i]
+
+		m := message{
+			Role:    string(msg.Role),
+			Content: msg.Content,
+		}
+
+		for _, part := range msg.MultiContent {
+			switch part.Type {
+			case chat.MessagePartTypeText:
+				if m.Content != "" {
+					m.Content += "\n"
+				}
+				m.Content += part.Text
+			case chat.MessagePartTypeImageURL:
+				if part.ImageURL != nil {
+					if data, ok := base64Image(part.ImageURL.URL); ok {
+						m.Images = append(m.Images, data)
+					}
+				}
+			}
+		}
+
+		switch msg.Role {
+		case chat.MessageRoleAssistant:
+			m.Thinking = msg.ReasoningContent
+			for _, tc := range msg.ToolCalls {
+				toolNames[tc.ID] = tc.Function.Name
+				m.ToolCalls = append(m.ToolCalls, toolCall{
+					Function: functionCall{
+						Name:      tc.Function.Name,
+						Arguments: toolArguments(tc.Function.Arguments),
+					},
+				})
+			}
+		case chat.MessageRoleTool:
+			m.ToolName = toolNames[msg.ToolCallID]
+		}
+
+		converted = append(converted, m)
+	}
+
+	return converted
+}
+
+// toolArguments returns the JSON arguments of a tool call, which Ollama
+// expects as an object rather than as an encoded string.
+func toolArguments(arguments string) json.RawMessage {
+	if !json.Valid([]byte(arguments)) {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(arguments)
+}
+
+// base64Image returns the base64 payload of a data URL. Ollama only
+// accepts inline images.
+func base64Image(url string) (string, bool) {
+	if !strings.HasPrefix(url, "data:") {
+		return "", false
+	}
+	_, data, ok := strings.Cut(url, ";base64,")
+	return data, ok
+}
@@ -0,0 +1,83 @@
+package openai
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/openai/openai-go/v3/option"
+
+	"github.com/docker/docker-agent/pkg/config/latest"
+	"github.com/docker/docker-agent/pkg/environment"
+)
+
+const (
+	// azureAPIKeyEnv holds the API key of the Azure OpenAI resource, unless
+	// token_key names another variable.
+	azureAPIKeyEnv = "AZURE_OPENAI_API_KEY"
+	// azureADTokenEnv holds a Microsoft Entra ID (AAD) token, used when
+	// there's no API key. It's read for every request, so that it can be
+	// refreshed.
+	azureADTokenEnv = "AZURE_OPENAI_AD_TOKEN"
+	// azureEndpointEnv holds the endpoint of the Azure OpenAI resource when
+	// base_url isn't set.
+	azureEndpointEnv = "AZURE_OPENAI_ENDPOINT"
+
+	defaultAzureAPIVersion = "2024-10-21"
+)
+
+// isAzureOpenAI reports whether cfg targets a deployment of Azure OpenAI.
+func isAzureOpenAI(cfg *latest.ModelConfig) bool {
+	return cfg.Provider == "azure-openai"
+}
+
+// azureClientOptions returns the options of a client sending its requests
+// to an Azure OpenAI deployment, at
+// {endpoint}/openai/deployments/{deployment}/chat/completions?api-version=...
+//
+// The deployment defaults to the model name and the API version to
+// defaultAzureAPIVersion. Requests are authenticated with the API key of
+// the resource, or with a Microsoft Entra ID token when there's no key.
+func azureClientOptions(ctx context.Context, cfg *latest.ModelConfig, env environment.Provider) ([]option.RequestOption, error) {
+	endpoint := cfg.BaseURL
+	if endpoint == "" {
+		endpoint, _ = env.Get(ctx, azureEndpointEnv)
+	}
+	if endpoint == "" {
+		return nil, fmt.Errorf("azure-openai models require a base_url or the %s environment variable", azureEndpointEnv)
+	}
+
+	deployment := cmp.Or(azureProviderOpt(cfg, "deployment"), cfg.Model)
+	apiVersion := cmp.Or(azureProviderOpt(cfg, "api_version"), defaultAzureAPIVersion)
+
+	opts := []option.RequestOption{
+		option.WithBaseURL(strings.TrimSuffix(endpoint, "/") + "/openai/deployments/" + url.PathEscape(deployment) + "/"),
+		option.WithQueryAdd("api-version", apiVersion),
+	}
+
+	keyEnv := cmp.Or(cfg.TokenKey, azureAPIKeyEnv)
+	if key, _ := env.Get(ctx, keyEnv); key != "" {
+		// Azure expects keys in the api-key header, and no OPENAI_API_KEY.
+		return append(opts, option.WithHeaderDel("authorization"), option.WithHeader("api-key", key)), nil
+	}
+	if token, _ := env.Get(ctx, azureADTokenEnv); token == "" {
+		return nil, fmt.Errorf("%s or %s environment variable is required", keyEnv, azureADTokenEnv)
+	}
+
+	return append(opts, option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		token, _ := env.Get(req.Context(), azureADTokenEnv)
+		if token == "" {
+			return nil, fmt.Errorf("%s environment variable is empty", azureADTokenEnv)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return next(req)
+	})), nil
+}
+
+func azureProviderOpt(cfg *latest.ModelConfig, key string) string {
+	v, _ := cfg.ProviderOpts[key].(string)
+	return v
+}
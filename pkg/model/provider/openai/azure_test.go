@@ -0,0 +1,153 @@
+package openai
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/config/latest"
+	"github.com/docker/docker-agent/pkg/environment"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// azureRequest is what a fake Azure OpenAI endpoint received.
+type azureRequest struct {
+	path   string
+	query  url.Values
+	header http.Header
+	body   map[string]any
+}
+
+// fakeAzureEndpoint answers chat completion requests with a recorded
+// Azure OpenAI stream: a tool call followed by the usage of the request.
+func fakeAzureEndpoint(t *testing.T) (*httptest.Server, *azureRequest) {
+	t.Helper()
+
+	var received azureRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.path = r.URL.Path
+		received.query = r.URL.Query()
+		received.header = r.Header.Clone()
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &received.body)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, `data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o-2024-11-20","choices":[],"prompt_filter_results":[{"prompt_index":0,"content_filter_results":{}}]}`+"\n\n")
+		_, _ = io.WriteString(w, `data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o-2024-11-20","choices":[{"index":0,"delta":{"role":"assistant","tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}}]},"finish_reason":null}]}`+"\n\n")
+		_, _ = io.WriteString(w, `data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o-2024-11-20","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`+"\n\n")
+		_, _ = io.WriteString(w, `data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o-2024-11-20","choices":[],"usage":{"prompt_tokens":42,"completion_tokens":7,"total_tokens":49}}`+"\n\n")
+		_, _ = io.WriteString(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &received
+}
+
+func streamAzure(t *testing.T, cfg *latest.ModelConfig, env map[string]string) (toolCalls []tools.ToolCall, usage *chat.Usage) {
+	t.Helper()
+
+	client, err := NewClient(t.Context(), cfg, environment.NewMapEnvProvider(env))
+	require.NoError(t, err)
+
+	requestTools := []tools.Tool{{
+		Name:        "get_weather",
+		Description: "Get the weather of a city",
+		Parameters: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"city": map[string]any{"type": "string"}},
+		},
+	}}
+	stream, err := client.CreateChatCompletionStream(t.Context(), []chat.Message{{Role: chat.MessageRoleUser, Content: "Weather in Paris?"}}, requestTools)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return toolCalls, usage
+		}
+		for _, choice := range resp.Choices {
+			toolCalls = append(toolCalls, choice.Delta.ToolCalls...)
+		}
+		if resp.Usage != nil {
+			usage = resp.Usage
+		}
+	}
+}
+
+func TestAzureOpenAI_APIKey(t *testing.T) {
+	t.Parallel()
+
+	server, received := fakeAzureEndpoint(t)
+
+	toolCalls, usage := streamAzure(t, &latest.ModelConfig{
+		Provider:     "azure-openai",
+		Model:        "gpt-4o",
+		BaseURL:      server.URL,
+		ProviderOpts: map[string]any{"deployment": "gpt-4o-prod", "api_version": "2024-12-01-preview"},
+	}, map[string]string{"AZURE_OPENAI_API_KEY": "secret"})
+
+	assert.Equal(t, "/openai/deployments/gpt-4o-prod/chat/completions", received.path)
+	assert.Equal(t, "2024-12-01-preview", received.query.Get("api-version"))
+	assert.Equal(t, "secret", received.header.Get("Api-Key"))
+	assert.Empty(t, received.header.Get("Authorization"))
+	assert.NotEmpty(t, received.body["tools"])
+
+	require.NotEmpty(t, toolCalls)
+	assert.Equal(t, "get_weather", toolCalls[0].Function.Name)
+	require.NotNil(t, usage)
+	assert.Equal(t, int64(42), usage.InputTokens)
+	assert.Equal(t, int64(7), usage.OutputTokens)
+}
+
+func TestAzureOpenAI_EntraIDToken(t *testing.T) {
+	t.Parallel()
+
+	server, received := fakeAzureEndpoint(t)
+
+	streamAzure(t, &latest.ModelConfig{
+		Provider: "azure-openai",
+		Model:    "gpt-4o",
+	}, map[string]string{
+		"AZURE_OPENAI_ENDPOINT": server.URL + "/",
+		"AZURE_OPENAI_AD_TOKEN": "entra-token",
+	})
+
+	// The deployment and the API version have defaults.
+	assert.Equal(t, "/openai/deployments/gpt-4o/chat/completions", received.path)
+	assert.Equal(t, defaultAzureAPIVersion, received.query.Get("api-version"))
+	assert.Equal(t, "Bearer entra-token", received.header.Get("Authorization"))
+	assert.Empty(t, received.header.Get("Api-Key"))
+}
+
+func TestAzureOpenAI_TokenKey(t *testing.T) {
+	t.Parallel()
+
+	server, received := fakeAzureEndpoint(t)
+
+	streamAzure(t, &latest.ModelConfig{
+		Provider: "azure-openai",
+		Model:    "gpt-4o",
+		BaseURL:  server.URL,
+		TokenKey: "TEAM_AZURE_KEY",
+	}, map[string]string{"TEAM_AZURE_KEY": "team-secret"})
+
+	assert.Equal(t, "team-secret", received.header.Get("Api-Key"))
+}
+
+func TestAzureOpenAI_MissingConfig(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewClient(t.Context(), &latest.ModelConfig{Provider: "azure-openai", Model: "gpt-4o"}, environment.NewMapEnvProvider(nil))
+	require.ErrorContains(t, err, "AZURE_OPENAI_ENDPOINT")
+
+	_, err = NewClient(t.Context(), &latest.ModelConfig{Provider: "azure-openai", Model: "gpt-4o", BaseURL: "https://example.openai.azure.com"}, environment.NewMapEnvProvider(nil))
+	require.ErrorContains(t, err, "AZURE_OPENAI_API_KEY or AZURE_OPENAI_AD_TOKEN")
+}
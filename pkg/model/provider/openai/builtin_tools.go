@@ -0,0 +1,128 @@
+package openai
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"github.com/openai/openai-go/v3/responses"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/config/latest"
+)
+
+// builtinToolParams maps the built-in tools of a model configuration to the
+// hosted tools of the Responses API.
+func builtinToolParams(names []string) []responses.ToolUnionParam {
+	var params []responses.ToolUnionParam
+	for _, name := range names {
+		switch name {
+		case latest.BuiltinToolWebSearch:
+			params = append(params, responses.ToolUnionParam{
+				OfWebSearch: &responses.WebSearchToolParam{Type: responses.WebSearchToolTypeWebSearch},
+			})
+		case latest.BuiltinToolCodeExecution:
+			params = append(params, responses.ToolUnionParam{
+				OfCodeInterpreter: &responses.ToolCodeInterpreterParam{
+					Container: responses.ToolCodeInterpreterContainerUnionParam{
+						OfCodeInterpreterToolAuto: &responses.ToolCodeInterpreterContainerCodeInterpreterContainerAutoParam{},
+					},
+				},
+			})
+		default:
+			slog.Warn("Ignoring unsupported built-in tool", "tool", name)
+		}
+	}
+	return params
+}
+
+// builtinToolIncludes returns the extra output the Responses API must
+// include for the built-in tools to be reported: the sources of the web
+// searches and the output of the code.
+func builtinToolIncludes(names []string) []responses.ResponseIncludable {
+	var includes []responses.ResponseIncludable
+	for _, name := range names {
+		switch name {
+		case latest.BuiltinToolWebSearch:
+			includes = append(includes, responses.ResponseIncludable("web_search_call.action.sources"))
+		case latest.BuiltinToolCodeExecution:
+			includes = append(includes, responses.ResponseIncludable("code_interpreter_call.outputs"))
+		}
+	}
+	return includes
+}
+
+// hostedToolItem is an output item of a hosted tool of the Responses API.
+type hostedToolItem struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	Status string `json:"status"`
+	Action struct {
+		Type    string   `json:"type"`
+		Query   string   `json:"query"`
+		Queries []string `json:"queries"`
+		URL     string   `json:"url"`
+		Sources []struct {
+			URL string `json:"url"`
+		} `json:"sources"`
+	} `json:"action"`
+	Code    string `json:"code"`
+	Outputs []struct {
+		Type string `json:"type"`
+		Logs string `json:"logs"`
+	} `json:"outputs"`
+}
+
+// providerToolUse returns what a hosted tool did from the raw JSON of its
+// output item, or false for the items of other types.
+func providerToolUse(raw string) (chat.ProviderToolUse, bool) {
+	var item hostedToolItem
+	if err := json.Unmarshal([]byte(raw), &item); err != nil {
+		return chat.ProviderToolUse{}, false
+	}
+
+	use := chat.ProviderToolUse{ID: item.ID}
+	switch item.Type {
+	case "web_search_call":
+		use.Tool = latest.BuiltinToolWebSearch
+		if item.Action.Query != "" {
+			use.Queries = append(use.Queries, item.Action.Query)
+		}
+		use.Queries = append(use.Queries, item.Action.Queries...)
+		if item.Action.URL != "" {
+			use.URLs = append(use.URLs, item.Action.URL)
+		}
+		for _, source := range item.Action.Sources {
+			use.URLs = append(use.URLs, source.URL)
+		}
+	case "code_interpreter_call":
+		use.Tool = latest.BuiltinToolCodeExecution
+		use.Code = item.Code
+		for _, output := range item.Outputs {
+			if output.Type == "logs" {
+				use.Output += output.Logs
+			}
+		}
+	default:
+		return chat.ProviderToolUse{}, false
+	}
+	if item.Status == "failed" {
+		use.Error = item.Type + " failed"
+	}
+	return use, true
+}
+
+// urlCitation returns the web page cited by an annotation of the output
+// text, from the raw JSON of its event.
+func urlCitation(raw string) (chat.Annotation, bool) {
+	var event struct {
+		Annotation struct {
+			Type  string `json:"type"`
+			URL   string `json:"url"`
+			Title string `json:"title"`
+		} `json:"annotation"`
+	}
+	if err := json.Unmarshal([]byte(raw), &event); err != nil || event.Annotation.Type != "url_citation" || event.Annotation.URL == "" {
+		return chat.Annotation{}, false
+	}
+	return chat.Annotation{URL: event.Annotation.URL, Title: event.Annotation.Title}, true
+}
@@ -0,0 +1,148 @@
+package openai
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/config/latest"
+	"github.com/docker/docker-agent/pkg/environment"
+)
+
+func TestBuiltinToolParams(t *testing.T) {
+	t.Parallel()
+
+	params := builtinToolParams([]string{latest.BuiltinToolWebSearch, latest.BuiltinToolCodeExecution})
+
+	data, err := json.Marshal(params)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"type": "web_search"},
+		{"type": "code_interpreter", "container": {"type": "auto"}}
+	]`, string(data))
+
+	assert.Empty(t, builtinToolParams(nil))
+}
+
+func TestBuiltinTools_Request(t *testing.T) {
+	t.Parallel()
+
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &body)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "event: response.completed\ndata: {\"type\":\"response.completed\",\"response\":{\"id\":\"resp_1\",\"output\":[]}}\n\n")
+	}))
+	t.Cleanup(server.Close)
+
+	// The built-in tools need the Responses API, even for a model which
+	// would otherwise use Chat Completions.
+	stream := builtinToolsStream(t, &latest.ModelConfig{
+		Provider:     "openai",
+		Model:        "gpt-4o",
+		BaseURL:      server.URL,
+		BuiltinTools: []string{latest.BuiltinToolWebSearch},
+	})
+	for {
+		if _, err := stream.Recv(); err != nil {
+			break
+		}
+	}
+
+	require.NotNil(t, body)
+	assert.Equal(t, []any{map[string]any{"type": "web_search"}}, body["tools"])
+	assert.Equal(t, []any{"web_search_call.action.sources"}, body["include"])
+}
+
+func TestProviderToolUse(t *testing.T) {
+	t.Parallel()
+
+	use, ok := providerToolUse(`{"id":"ws_1","type":"web_search_call","status":"completed","action":{"type":"search","query":"docker agent","sources":[{"type":"url","url":"https://docs.docker.com"}]}}`)
+	require.True(t, ok)
+	assert.Equal(t, chat.ProviderToolUse{ID: "ws_1", Tool: "web_search", Queries: []string{"docker agent"}, URLs: []string{"https://docs.docker.com"}}, use)
+
+	use, ok = providerToolUse(`{"id":"ci_1","type":"code_interpreter_call","status":"completed","code":"print(6*7)","outputs":[{"type":"logs","logs":"42\n"}]}`)
+	require.True(t, ok)
+	assert.Equal(t, chat.ProviderToolUse{ID: "ci_1", Tool: "code_execution", Code: "print(6*7)", Output: "42\n"}, use)
+
+	use, ok = providerToolUse(`{"id":"ws_2","type":"web_search_call","status":"failed","action":{"type":"open_page","url":"https://example.com"}}`)
+	require.True(t, ok)
+	assert.Equal(t, "web_search_call failed", use.Error)
+	assert.Equal(t, []string{"https://example.com"}, use.URLs)
+
+	_, ok = providerToolUse(`{"id":"msg_1","type":"message"}`)
+	assert.False(t, ok)
+}
+
+func TestURLCitation(t *testing.T) {
+	t.Parallel()
+
+	citation, ok := urlCitation(`{"type":"response.output_text.annotation.added","annotation":{"type":"url_citation","url":"https://docs.docker.com","title":"Docker Docs","start_index":0,"end_index":10}}`)
+	require.True(t, ok)
+	assert.Equal(t, chat.Annotation{URL: "https://docs.docker.com", Title: "Docker Docs"}, citation)
+
+	_, ok = urlCitation(`{"type":"response.output_text.annotation.added","annotation":{"type":"file_citation","file_id":"f_1"}}`)
+	assert.False(t, ok)
+}
+
+func TestResponseStream_BuiltinTools(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range []string{
+			`{"type":"response.output_item.done","output_index":0,"item":{"id":"ws_1","type":"web_search_call","status":"completed","action":{"type":"search","query":"docker agent"}}}`,
+			`{"type":"response.output_text.delta","item_id":"msg_1","delta":"Docker Agent runs agents."}`,
+			`{"type":"response.output_text.annotation.added","item_id":"msg_1","annotation":{"type":"url_citation","url":"https://docs.docker.com","title":"Docker Docs"}}`,
+			`{"type":"response.completed","response":{"id":"resp_1","output":[]}}`,
+		} {
+			_, _ = io.WriteString(w, "data: "+event+"\n\n")
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	stream := builtinToolsStream(t, &latest.ModelConfig{
+		Provider:     "openai",
+		Model:        "gpt-5",
+		BaseURL:      server.URL,
+		BuiltinTools: []string{latest.BuiltinToolWebSearch},
+	})
+
+	var delta chat.MessageDelta
+	for {
+		chunk, err := stream.Recv()
+		if err != nil {
+			break
+		}
+		for _, choice := range chunk.Choices {
+			delta.Content += choice.Delta.Content
+			delta.ProviderTools = append(delta.ProviderTools, choice.Delta.ProviderTools...)
+			delta.Annotations = append(delta.Annotations, choice.Delta.Annotations...)
+		}
+	}
+
+	assert.Equal(t, "Docker Agent runs agents.", delta.Content)
+	assert.Equal(t, []chat.ProviderToolUse{{ID: "ws_1", Tool: "web_search", Queries: []string{"docker agent"}}}, delta.ProviderTools)
+	assert.Equal(t, []chat.Annotation{{URL: "https://docs.docker.com", Title: "Docker Docs"}}, delta.Annotations)
+}
+
+func builtinToolsStream(t *testing.T, cfg *latest.ModelConfig) chat.MessageStream {
+	t.Helper()
+
+	cfg.TokenKey = "OPENAI_API_KEY"
+	client, err := NewClient(t.Context(), cfg, environment.NewMapEnvProvider(map[string]string{"OPENAI_API_KEY": "test-key"}))
+	require.NoError(t, err)
+
+	stream, err := client.CreateChatCompletionStream(t.Context(), []chat.Message{{Role: chat.MessageRoleUser, Content: "hello"}}, nil)
+	require.NoError(t, err)
+	t.Cleanup(stream.Close)
+	return stream
+}
@@ -60,7 +60,14 @@ func NewClient(ctx context.Context, cfg *latest.ModelConfig, env environment.Pro
 	if gateway := globalOptions.Gateway(); gateway == "" {
 		var clientOptions []option.RequestOption
 
-		if cfg.TokenKey != "" {
+		if isAzureOpenAI(cfg) {
+			azureOptions, err := azureClientOptions(ctx, cfg, env)
+			if err != nil {
+				slog.Error("Azure OpenAI client creation failed", "error", err)
+				return nil, err
+			}
+			clientOptions = azureOptions
+		} else if cfg.TokenKey != "" {
 			// Explicit token_key configured - use that env var
 			authToken, _ := env.Get(ctx, cfg.TokenKey)
 			if authToken == "" {
@@ -96,14 +103,20 @@ func NewClient(ctx context.Context, cfg *latest.ModelConfig, env environment.Pro
 					}
 				}
 			}
-		} else if cfg.BaseURL != "" {
+		} else if cfg.BaseURL != "" && !isAzureOpenAI(cfg) {
 			clientOptions = append(clientOptions, option.WithBaseURL(cfg.BaseURL))
 		}
 
-		httpClient := httpclient.NewHTTPClient(ctx)
+		httpClient := httpclient.NewHTTPClient(ctx, httpclient.WithHeaders(cfg.ExtraHeaders), httpclient.WithTransport(globalOptions.HTTPTransport()))
 		clientOptions = append(clientOptions, option.WithHTTPClient(httpClient))
 
 		client := openai.NewClient(clientOptions...)
+		if cfg.Validate {
+			if err := validateEndpoint(ctx, &client, cfg); err != nil {
+				slog.Error("OpenAI client validation failed", "error", err)
+				return nil, err
+			}
+		}
 		clientFn = func(context.Context) (*openai.Client, error) {
 			return &client, nil
 		}
@@ -135,6 +148,8 @@ func NewClient(ctx context.Context, cfg *latest.ModelConfig, env environment.Pro
 				httpclient.WithModel(cfg.Model),
 				httpclient.WithModelName(cfg.Name),
 				httpclient.WithQuery(url.Query()),
+				httpclient.WithTransport(globalOptions.HTTPTransport()),
+				httpclient.WithHeaders(cfg.ExtraHeaders),
 			}
 			if globalOptions.GeneratingTitle() {
 				httpOptions = append(httpOptions, httpclient.WithHeader("X-Cagent-GeneratingTitle", "1"))
@@ -213,7 +228,8 @@ func (c *Client) CreateChatCompletionStream(
 	default:
 		// Auto-detect based on model name for OpenAI provider
 		// Use Responses API for newer models that support it (gpt-4.1+, o-series, gpt-5)
-		if c.ModelConfig.Provider == "openai" && isResponsesModel(c.ModelConfig.Model) {
+		// The built-in tools are only hosted by the Responses API.
+		if c.ModelConfig.Provider == "openai" && (isResponsesModel(c.ModelConfig.Model) || len(c.ModelConfig.BuiltinTools) > 0) {
 			slog.Debug("Auto-selecting Responses API", "model", c.ModelConfig.Model)
 			return c.CreateResponseStream(ctx, messages, requestTools)
 		}
@@ -225,13 +241,16 @@ func (c *Client) CreateChatCompletionStream(
 	}
 
 	trackUsage := c.ModelConfig.TrackUsage == nil || *c.ModelConfig.TrackUsage
+	compat := compatConfig(&c.ModelConfig)
 
 	params := openai.ChatCompletionNewParams{
 		Model:    c.ModelConfig.Model,
-		Messages: convertMessages(messages),
-		StreamOptions: openai.ChatCompletionStreamOptionsParam{
+		Messages: convertMessages(chat.SystemReminders(messages, systemRoleReminders(&c.ModelConfig))),
+	}
+	if !compat.DisableStreamOptions {
+		params.StreamOptions = openai.ChatCompletionStreamOptionsParam{
 			IncludeUsage: openai.Bool(trackUsage),
-		},
+		}
 	}
 
 	if c.ModelConfig.Temperature != nil {
@@ -257,6 +276,11 @@ func (c *Client) CreateChatCompletionStream(
 		}
 	}
 
+	if compat.ToolsMode == latest.ToolsModeNone && len(requestTools) > 0 {
+		slog.Debug("Not sending tools to OpenAI-compatible server", "tools_mode", compat.ToolsMode, "tool_count", len(requestTools))
+		requestTools = nil
+	}
+
 	if len(requestTools) > 0 {
 		slog.Debug("Adding tools to OpenAI request", "tool_count", len(requestTools))
 		toolsParam := make([]openai.ChatCompletionToolUnionParam, len(requestTools))
@@ -277,7 +301,7 @@ func (c *Client) CreateChatCompletionStream(
 		}
 		params.Tools = toolsParam
 
-		if c.ModelConfig.ParallelToolCalls != nil {
+		if c.ModelConfig.ParallelToolCalls != nil && !compat.DisableParallelToolCalls {
 			params.ParallelToolCalls = openai.Bool(*c.ModelConfig.ParallelToolCalls)
 		}
 	}
@@ -363,7 +387,7 @@ func (c *Client) CreateResponseStream(
 		return nil, errors.New("at least one message is required")
 	}
 
-	input := convertMessagesToResponseInput(messages, isChatGPT)
+	input := convertMessagesToResponseInput(chat.SystemReminders(messages, systemRoleReminders(&c.ModelConfig)), isChatGPT)
 
 	params := responses.ResponseNewParams{
 		Model: c.ModelConfig.Model,
@@ -389,6 +413,12 @@ func (c *Client) CreateResponseStream(
 		slog.Debug("OpenAI responses request configured with max output tokens", "max_output_tokens", maxTokens)
 	}
 
+	compat := compatConfig(&c.ModelConfig)
+	if compat.ToolsMode == latest.ToolsModeNone && len(requestTools) > 0 {
+		slog.Debug("Not sending tools to OpenAI-compatible server", "tools_mode", compat.ToolsMode, "tool_count", len(requestTools))
+		requestTools = nil
+	}
+
 	if len(requestTools) > 0 {
 		slog.Debug("Adding tools to OpenAI responses request", "tool_count", len(requestTools))
 		toolsParam := make([]responses.ToolUnionParam, len(requestTools))
@@ -412,11 +442,17 @@ func (c *Client) CreateResponseStream(
 		}
 		params.Tools = toolsParam
 
-		if c.ModelConfig.ParallelToolCalls != nil {
+		if c.ModelConfig.ParallelToolCalls != nil && !compat.DisableParallelToolCalls {
 			params.ParallelToolCalls = param.NewOpt(*c.ModelConfig.ParallelToolCalls)
 		}
 	}
 
+	if len(c.ModelConfig.BuiltinTools) > 0 {
+		slog.Debug("Adding built-in tools to OpenAI responses request", "tools", c.ModelConfig.BuiltinTools)
+		params.Tools = append(params.Tools, builtinToolParams(c.ModelConfig.BuiltinTools)...)
+		params.Include = append(params.Include, builtinToolIncludes(c.ModelConfig.BuiltinTools)...)
+	}
+
 	// Configure reasoning for models that support it (o-series, gpt-5).
 	// Reasoning models always reason internally; omitting the reasoning param
 	// does NOT disable reasoning — it just uses the model's default effort.
@@ -1079,6 +1115,12 @@ func isResponsesModel(model string) bool {
 		strings.Contains(m, "-codex")
 }
 
+// IsReasoningModel reports whether model is a reasoning model, configured
+// with a reasoning effort.
+func IsReasoningModel(model string) bool {
+	return isOpenAIReasoningModel(model)
+}
+
 func isOpenAIReasoningModel(model string) bool {
 	m := strings.ToLower(model)
 
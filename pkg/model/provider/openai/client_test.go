@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/config/latest"
 	"github.com/docker/docker-agent/pkg/tools"
 )
 
@@ -26,7 +27,7 @@ func TestConvertMessagesToResponseInput_OrphanedFunctionCall(t *testing.T) {
 		// call_2 has no result — orphaned
 	}
 
-	input := convertMessagesToResponseInput(messages)
+	input := convertMessagesToResponseInput(messages, false)
 
 	// Count function calls and outputs
 	var callIDs, outputIDs []string
@@ -61,7 +62,7 @@ func TestConvertMessagesToResponseInput_AssistantTextWithToolCalls(t *testing.T)
 		{Role: chat.MessageRoleTool, Content: "result", ToolCallID: "call_1"},
 	}
 
-	input := convertMessagesToResponseInput(messages)
+	input := convertMessagesToResponseInput(messages, false)
 
 	// We expect: user message, assistant text message, function call, function call output.
 	var foundAssistantText bool
@@ -94,7 +95,7 @@ func TestConvertMessagesToResponseInput_NoOrphans(t *testing.T) {
 		{Role: chat.MessageRoleTool, Content: "result a", ToolCallID: "call_1"},
 	}
 
-	input := convertMessagesToResponseInput(messages)
+	input := convertMessagesToResponseInput(messages, false)
 
 	var outputCount int
 	for _, item := range input {
@@ -104,3 +105,24 @@ func TestConvertMessagesToResponseInput_NoOrphans(t *testing.T) {
 	}
 	assert.Equal(t, 1, outputCount, "should not inject extra outputs when all calls have results")
 }
+
+func TestConvertMessagesToResponseInput_SystemReminder(t *testing.T) {
+	messages := []chat.Message{
+		{Role: chat.MessageRoleUser, Content: "hello"},
+		chat.SystemReminderMessage("Keep it short"),
+	}
+
+	input := convertMessagesToResponseInput(chat.SystemReminders(messages, true), false)
+
+	require.Len(t, input, 2)
+	require.NotNil(t, input[1].OfInputMessage)
+	assert.EqualValues(t, "system", input[1].OfInputMessage.Role)
+	require.Len(t, input[1].OfInputMessage.Content, 1)
+	assert.Equal(t, "Keep it short", input[1].OfInputMessage.Content[0].OfInputText.Text)
+}
+
+func TestSystemRoleReminders(t *testing.T) {
+	assert.True(t, systemRoleReminders(&latest.ModelConfig{Provider: "openai"}))
+	assert.False(t, systemRoleReminders(&latest.ModelConfig{Provider: "chatgpt"}))
+	assert.False(t, systemRoleReminders(&latest.ModelConfig{Provider: "openai", Compat: &latest.CompatConfig{UserRoleReminders: true}}))
+}
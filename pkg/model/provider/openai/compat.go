@@ -0,0 +1,49 @@
+package openai
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+
+	"github.com/docker/docker-agent/pkg/config/latest"
+)
+
+// validateTimeout bounds the /models probe done when `validate: true` is set,
+// so that an unreachable local server doesn't hang startup.
+const validateTimeout = 10 * time.Second
+
+// compatConfig returns the OpenAI compatibility settings of a model,
+// or the zero value when none are configured.
+func compatConfig(cfg *latest.ModelConfig) latest.CompatConfig {
+	if cfg == nil || cfg.Compat == nil {
+		return latest.CompatConfig{}
+	}
+	return *cfg.Compat
+}
+
+// systemRoleReminders reports whether the system reminders of a conversation
+// are sent as system messages, which OpenAI accepts anywhere in the
+// conversation. The ChatGPT backend moves them to its instructions, and some
+// OpenAI-compatible servers only accept a system message at the start.
+func systemRoleReminders(cfg *latest.ModelConfig) bool {
+	return cfg.Provider != "chatgpt" && !compatConfig(cfg).UserRoleReminders
+}
+
+// validateEndpoint checks that the model API answers on its /models endpoint.
+// It is used to fail fast on misconfigured OpenAI-compatible servers (llama.cpp,
+// vLLM, ...) instead of failing on the first chat request.
+func validateEndpoint(ctx context.Context, client *openai.Client, cfg *latest.ModelConfig) error {
+	ctx, cancel := context.WithTimeout(ctx, validateTimeout)
+	defer cancel()
+
+	if _, err := client.Models.List(ctx); err != nil {
+		baseURL := strings.TrimSuffix(cmp.Or(cfg.BaseURL, "https://api.openai.com/v1"), "/")
+		return fmt.Errorf("model %q: %s/models is unreachable, check base_url and that the server is running: %w", cfg.Model, baseURL, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,212 @@
+package openai
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/config/latest"
+	"github.com/docker/docker-agent/pkg/environment"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// captureChatRequest starts a server that records the headers and JSON body of
+// the chat completion request and answers with a minimal SSE stream.
+func captureChatRequest(t *testing.T) (*httptest.Server, *http.Header, *map[string]any) {
+	t.Helper()
+
+	var headers http.Header
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &body)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, `data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"test","choices":[{"index":0,"delta":{"content":"hi"},"finish_reason":"stop"}]}`+"\n\n")
+		_, _ = io.WriteString(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &headers, &body
+}
+
+func streamOnce(t *testing.T, cfg *latest.ModelConfig, requestTools []tools.Tool) {
+	t.Helper()
+	streamMessages(t, cfg, []chat.Message{{Role: chat.MessageRoleUser, Content: "hello"}}, requestTools)
+}
+
+func streamMessages(t *testing.T, cfg *latest.ModelConfig, messages []chat.Message, requestTools []tools.Tool) {
+	t.Helper()
+
+	client, err := NewClient(t.Context(), cfg, environment.NewMapEnvProvider(nil))
+	require.NoError(t, err)
+
+	stream, err := client.CreateChatCompletionStream(t.Context(), messages, requestTools)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	for {
+		if _, err := stream.Recv(); err != nil {
+			break
+		}
+	}
+}
+
+func TestCompat_ExtraHeaders(t *testing.T) {
+	t.Parallel()
+
+	server, headers, _ := captureChatRequest(t)
+
+	streamOnce(t, &latest.ModelConfig{
+		Provider:     "llamacpp",
+		Model:        "qwen",
+		BaseURL:      server.URL,
+		ProviderOpts: map[string]any{"api_type": "openai_chatcompletions"},
+		ExtraHeaders: map[string]string{"X-Tenant": "team-a"},
+	}, nil)
+
+	assert.Equal(t, "team-a", headers.Get("X-Tenant"))
+}
+
+func TestCompat_DisableStreamOptionsAndTools(t *testing.T) {
+	t.Parallel()
+
+	server, _, body := captureChatRequest(t)
+
+	streamOnce(t, &latest.ModelConfig{
+		Provider:          "llamacpp",
+		Model:             "qwen",
+		BaseURL:           server.URL,
+		ProviderOpts:      map[string]any{"api_type": "openai_chatcompletions"},
+		ParallelToolCalls: new(true),
+		Compat: &latest.CompatConfig{
+			DisableStreamOptions: true,
+			ToolsMode:            latest.ToolsModeNone,
+		},
+	}, []tools.Tool{{Name: "search", Parameters: map[string]any{"type": "object"}}})
+
+	assert.NotContains(t, *body, "stream_options")
+	assert.NotContains(t, *body, "tools")
+	assert.NotContains(t, *body, "parallel_tool_calls")
+}
+
+func TestCompat_DisableParallelToolCalls(t *testing.T) {
+	t.Parallel()
+
+	server, _, body := captureChatRequest(t)
+
+	streamOnce(t, &latest.ModelConfig{
+		Provider:          "vllm",
+		Model:             "qwen",
+		BaseURL:           server.URL,
+		ProviderOpts:      map[string]any{"api_type": "openai_chatcompletions"},
+		ParallelToolCalls: new(true),
+		Compat:            &latest.CompatConfig{DisableParallelToolCalls: true},
+	}, []tools.Tool{{Name: "search", Parameters: map[string]any{"type": "object"}}})
+
+	assert.Contains(t, *body, "tools")
+	assert.Contains(t, *body, "stream_options")
+	assert.NotContains(t, *body, "parallel_tool_calls")
+}
+
+func TestCompat_UserRoleReminders(t *testing.T) {
+	t.Parallel()
+
+	messages := []chat.Message{
+		{Role: chat.MessageRoleUser, Content: "hello"},
+		{Role: chat.MessageRoleAssistant, Content: "hi"},
+		chat.SystemReminderMessage("Keep it short"),
+	}
+
+	tests := []struct {
+		name        string
+		compat      *latest.CompatConfig
+		wantRole    string
+		wantContent string
+	}{
+		{
+			name:        "system role by default",
+			wantRole:    "system",
+			wantContent: "Keep it short",
+		},
+		{
+			name:        "user role for servers that need it",
+			compat:      &latest.CompatConfig{UserRoleReminders: true},
+			wantRole:    "user",
+			wantContent: "<system-reminder>\nKeep it short\n</system-reminder>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server, _, body := captureChatRequest(t)
+
+			streamMessages(t, &latest.ModelConfig{
+				Provider:     "llamacpp",
+				Model:        "qwen",
+				BaseURL:      server.URL,
+				ProviderOpts: map[string]any{"api_type": "openai_chatcompletions"},
+				Compat:       tt.compat,
+			}, messages, nil)
+
+			sent, ok := (*body)["messages"].([]any)
+			require.True(t, ok)
+			require.Len(t, sent, 3)
+			reminder, ok := sent[2].(map[string]any)
+			require.True(t, ok)
+			assert.Equal(t, tt.wantRole, reminder["role"])
+			assert.Equal(t, tt.wantContent, reminder["content"])
+		})
+	}
+}
+
+func TestCompat_Validate(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reachable", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/models", r.URL.Path)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `{"object":"list","data":[{"id":"qwen","object":"model"}]}`)
+		}))
+		t.Cleanup(server.Close)
+
+		_, err := NewClient(t.Context(), &latest.ModelConfig{
+			Provider:     "llamacpp",
+			Model:        "qwen",
+			BaseURL:      server.URL,
+			ProviderOpts: map[string]any{"api_type": "openai_chatcompletions"},
+			Validate:     true,
+		}, environment.NewMapEnvProvider(nil))
+		require.NoError(t, err)
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.NotFoundHandler())
+		url := server.URL
+		server.Close()
+
+		_, err := NewClient(t.Context(), &latest.ModelConfig{
+			Provider:     "llamacpp",
+			Model:        "qwen",
+			BaseURL:      url,
+			ProviderOpts: map[string]any{"api_type": "openai_chatcompletions"},
+			Validate:     true,
+		}, environment.NewMapEnvProvider(nil))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), url+"/models is unreachable")
+	})
+}
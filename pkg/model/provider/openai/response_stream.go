@@ -219,6 +219,21 @@ func (a *ResponseStreamAdapter) Recv() (chat.MessageStreamResponse, error) {
 				}
 			}
 		}
+		// The hosted tools ran on the provider's side: report what they did.
+		if use, ok := providerToolUse(event.Item.RawJSON()); ok {
+			response.Choices = append(response.Choices, chat.MessageStreamChoice{
+				Delta: chat.MessageDelta{ProviderTools: []chat.ProviderToolUse{use}},
+			})
+		}
+
+	case "response.output_text.annotation.added":
+		if citation, ok := urlCitation(event.RawJSON()); ok {
+			response.Choices = []chat.MessageStreamChoice{
+				{
+					Delta: chat.MessageDelta{Annotations: []chat.Annotation{citation}},
+				},
+			}
+		}
 
 	case "response.done", "response.completed":
 		slog.Info("Response done received", "event_type", event.Type)
@@ -1,6 +1,9 @@
 package options
 
 import (
+	"io"
+	"net/http"
+
 	"github.com/docker/docker-agent/pkg/config/latest"
 )
 
@@ -10,7 +13,13 @@ type ModelOptions struct {
 	generatingTitle  bool
 	noThinking       bool
 	maxTokens        int64
+	temperature      *float64
+	topP             *float64
 	providers        map[string]latest.ProviderConfig
+	requestLogger    io.Writer
+	redactions       []string
+	deferredClient   bool
+	httpTransport    http.RoundTripper
 }
 
 func (c *ModelOptions) Gateway() string {
@@ -29,6 +38,17 @@ func (c *ModelOptions) MaxTokens() int64 {
 	return c.maxTokens
 }
 
+// Temperature returns the temperature override, or nil to keep the
+// configured one.
+func (c *ModelOptions) Temperature() *float64 {
+	return c.temperature
+}
+
+// TopP returns the top_p override, or nil to keep the configured one.
+func (c *ModelOptions) TopP() *float64 {
+	return c.topP
+}
+
 func (c *ModelOptions) NoThinking() bool {
 	return c.noThinking
 }
@@ -37,6 +57,30 @@ func (c *ModelOptions) Providers() map[string]latest.ProviderConfig {
 	return c.providers
 }
 
+// RequestLogger returns where the requests sent to the model, and their
+// responses, are logged, or nil to not log them.
+func (c *ModelOptions) RequestLogger() io.Writer {
+	return c.requestLogger
+}
+
+// Redactions returns the strings hidden from the request logs, on top of
+// the API keys.
+func (c *ModelOptions) Redactions() []string {
+	return c.redactions
+}
+
+// DeferredClient reports whether the client of the model is created with
+// its first request rather than with the provider, see WithDeferredClient.
+func (c *ModelOptions) DeferredClient() bool {
+	return c.deferredClient
+}
+
+// HTTPTransport returns the transport the client of the model sends its
+// requests with, or nil to use the default one.
+func (c *ModelOptions) HTTPTransport() http.RoundTripper {
+	return c.httpTransport
+}
+
 type Opt func(*ModelOptions)
 
 func WithGateway(gateway string) Opt {
@@ -63,6 +107,18 @@ func WithMaxTokens(maxTokens int64) Opt {
 	}
 }
 
+func WithTemperature(temperature float64) Opt {
+	return func(cfg *ModelOptions) {
+		cfg.temperature = &temperature
+	}
+}
+
+func WithTopP(topP float64) Opt {
+	return func(cfg *ModelOptions) {
+		cfg.topP = &topP
+	}
+}
+
 func WithNoThinking() Opt {
 	return func(cfg *ModelOptions) {
 		cfg.noThinking = true
@@ -75,6 +131,59 @@ func WithProviders(providers map[string]latest.ProviderConfig) Opt {
 	}
 }
 
+// WithRequestLogger logs each request sent to the model, with its response,
+// as a line of JSON written to w.
+func WithRequestLogger(w io.Writer) Opt {
+	return func(cfg *ModelOptions) {
+		cfg.requestLogger = w
+	}
+}
+
+// WithRedactions hides the given strings, e.g. personal data, from the
+// request logs.
+func WithRedactions(redactions ...string) Opt {
+	return func(cfg *ModelOptions) {
+		cfg.redactions = append(cfg.redactions, redactions...)
+	}
+}
+
+// WithDeferredClient creates the client of the model with its first request
+// rather than with the provider, e.g. for the providers created for each
+// request of a server. The configuration is still checked upfront, but the
+// credentials and the connectivity are only with the first request.
+func WithDeferredClient() Opt {
+	return func(cfg *ModelOptions) {
+		cfg.deferredClient = true
+	}
+}
+
+// WithHTTPTransport sends the requests of the model with rt, e.g. to record
+// them in tests.
+func WithHTTPTransport(rt http.RoundTripper) Opt {
+	return func(cfg *ModelOptions) {
+		cfg.httpTransport = rt
+	}
+}
+
+// WithoutMaxTokens returns opts without the ones setting max tokens, for the
+// models a router delegates to: they may have different token limits than
+// the router.
+func WithoutMaxTokens(opts []Opt) []Opt {
+	var filtered []Opt
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		var probe ModelOptions
+		opt(&probe)
+		if probe.MaxTokens() != 0 {
+			continue
+		}
+		filtered = append(filtered, opt)
+	}
+	return filtered
+}
+
 // FromModelOptions converts a concrete ModelOptions value into a slice of
 // Opt configuration functions. Later Opts override earlier ones when applied.
 func FromModelOptions(m ModelOptions) []Opt {
@@ -94,8 +203,26 @@ func FromModelOptions(m ModelOptions) []Opt {
 	if m.maxTokens != 0 {
 		out = append(out, WithMaxTokens(m.maxTokens))
 	}
+	if m.temperature != nil {
+		out = append(out, WithTemperature(*m.temperature))
+	}
+	if m.topP != nil {
+		out = append(out, WithTopP(*m.topP))
+	}
 	if len(m.providers) > 0 {
 		out = append(out, WithProviders(m.providers))
 	}
+	if m.requestLogger != nil {
+		out = append(out, WithRequestLogger(m.requestLogger))
+	}
+	if len(m.redactions) > 0 {
+		out = append(out, WithRedactions(m.redactions...))
+	}
+	if m.deferredClient {
+		out = append(out, WithDeferredClient())
+	}
+	if m.httpTransport != nil {
+		out = append(out, WithHTTPTransport(m.httpTransport))
+	}
 	return out
 }
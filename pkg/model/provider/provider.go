@@ -17,8 +17,10 @@ import (
 	"github.com/docker/docker-agent/pkg/model/provider/bedrock"
 	"github.com/docker/docker-agent/pkg/model/provider/dmr"
 	"github.com/docker/docker-agent/pkg/model/provider/gemini"
+	"github.com/docker/docker-agent/pkg/model/provider/ollama"
 	"github.com/docker/docker-agent/pkg/model/provider/openai"
 	"github.com/docker/docker-agent/pkg/model/provider/options"
+	"github.com/docker/docker-agent/pkg/model/provider/router"
 	"github.com/docker/docker-agent/pkg/model/provider/rulebased"
 	"github.com/docker/docker-agent/pkg/model/provider/vertexai"
 	"github.com/docker/docker-agent/pkg/rag/types"
@@ -40,6 +42,7 @@ var CoreProviders = []string{
 	"google",
 	"dmr",
 	"amazon-bedrock",
+	"ollama",
 }
 
 // AllProviders returns all known provider names (core providers + aliases),
@@ -102,6 +105,10 @@ var Aliases = map[string]Alias{
 		APIType:     "openai",
 		TokenEnvVar: "AZURE_API_KEY",
 	},
+	// azure-openai reads its API key, or its Entra ID token, itself.
+	"azure-openai": {
+		APIType: "openai",
+	},
 	"xai": {
 		APIType:     "openai",
 		BaseURL:     "https://api.x.ai/v1",
@@ -117,15 +124,17 @@ var Aliases = map[string]Alias{
 		BaseURL:     "https://api.mistral.ai/v1",
 		TokenEnvVar: "MISTRAL_API_KEY",
 	},
-	"ollama": {
-		APIType: "openai",
-		BaseURL: "http://localhost:11434/v1",
-	},
 	"minimax": {
 		APIType:     "openai",
 		BaseURL:     "https://api.minimax.io/v1",
 		TokenEnvVar: "MINIMAX_API_KEY",
 	},
+	// ollama-openai talks to Ollama through its OpenAI-compatible endpoint,
+	// which is what the ollama provider did before it got a native client.
+	"ollama-openai": {
+		APIType: "openai",
+		BaseURL: "http://localhost:11434/v1",
+	},
 	"github-copilot": {
 		APIType:     "openai",
 		BaseURL:     "https://api.githubcopilot.com",
@@ -195,40 +204,58 @@ func NewWithModels(ctx context.Context, cfg *latest.ModelConfig, models map[stri
 	if len(cfg.Routing) > 0 {
 		return createRuleBasedRouter(ctx, cfg, models, env, opts...)
 	}
+	if cfg.Provider == router.ProviderType {
+		return createEscalatingRouter(ctx, cfg, models, env, opts...)
+	}
 
 	return createDirectProvider(ctx, cfg, env, opts...)
 }
 
 // createRuleBasedRouter creates a rule-based routing provider.
 func createRuleBasedRouter(ctx context.Context, cfg *latest.ModelConfig, models map[string]latest.ModelConfig, env environment.Provider, opts ...options.Opt) (Provider, error) {
-	// Create a provider factory that can resolve model references
 	factory := func(ctx context.Context, modelSpec string, models map[string]latest.ModelConfig, env environment.Provider, factoryOpts ...options.Opt) (rulebased.Provider, error) {
-		// Check if modelSpec is a reference to a model in the models map
-		if modelCfg, exists := models[modelSpec]; exists {
-			// Prevent infinite recursion - referenced models cannot have routing rules
-			if len(modelCfg.Routing) > 0 {
-				return nil, fmt.Errorf("model %q has routing rules and cannot be used as a routing target", modelSpec)
-			}
-			p, err := createDirectProvider(ctx, &modelCfg, env, factoryOpts...)
-			if err != nil {
-				return nil, err
-			}
-			return p, nil
+		p, err := createRouteTarget(ctx, modelSpec, models, env, factoryOpts...)
+		if err != nil {
+			return nil, err
 		}
+		return p, nil
+	}
 
-		// Otherwise, treat as an inline model spec (e.g., "openai/gpt-4o")
-		inlineCfg, parseErr := latest.ParseModelRef(modelSpec)
-		if parseErr != nil {
-			return nil, fmt.Errorf("invalid model spec %q: expected 'provider/model' format or a model reference", modelSpec)
-		}
-		p, err := createDirectProvider(ctx, &inlineCfg, env, factoryOpts...)
+	return rulebased.NewClient(ctx, cfg, models, env, factory, opts...)
+}
+
+// createEscalatingRouter creates a router escalating from cheap models to
+// stronger ones.
+func createEscalatingRouter(ctx context.Context, cfg *latest.ModelConfig, models map[string]latest.ModelConfig, env environment.Provider, opts ...options.Opt) (Provider, error) {
+	factory := func(ctx context.Context, modelSpec string, models map[string]latest.ModelConfig, env environment.Provider, factoryOpts ...options.Opt) (router.Provider, error) {
+		p, err := createRouteTarget(ctx, modelSpec, models, env, factoryOpts...)
 		if err != nil {
 			return nil, err
 		}
 		return p, nil
 	}
 
-	return rulebased.NewClient(ctx, cfg, models, env, factory, opts...)
+	return router.NewClient(ctx, cfg, models, env, factory, opts...)
+}
+
+// createRouteTarget creates the provider a router routes to, from a reference
+// to a model in the models map or an inline model spec.
+func createRouteTarget(ctx context.Context, modelSpec string, models map[string]latest.ModelConfig, env environment.Provider, opts ...options.Opt) (Provider, error) {
+	// Check if modelSpec is a reference to a model in the models map
+	if modelCfg, exists := models[modelSpec]; exists {
+		// Prevent infinite recursion - referenced models cannot be routers
+		if len(modelCfg.Routing) > 0 || modelCfg.Provider == router.ProviderType {
+			return nil, fmt.Errorf("model %q is a router and cannot be used as a routing target", modelSpec)
+		}
+		return createDirectProvider(ctx, &modelCfg, env, opts...)
+	}
+
+	// Otherwise, treat as an inline model spec (e.g., "openai/gpt-4o")
+	inlineCfg, parseErr := latest.ParseModelRef(modelSpec)
+	if parseErr != nil {
+		return nil, fmt.Errorf("invalid model spec %q: expected 'provider/model' format or a model reference", modelSpec)
+	}
+	return createDirectProvider(ctx, &inlineCfg, env, opts...)
 }
 
 // createDirectProvider creates a provider without routing (direct model access).
@@ -243,26 +270,75 @@ func createDirectProvider(ctx context.Context, cfg *latest.ModelConfig, env envi
 
 	providerType := resolveProviderType(enhancedCfg)
 
-	switch providerType {
-	case "openai", "openai_chatcompletions", "openai_responses":
-		return openai.NewClient(ctx, enhancedCfg, env, opts...)
-	case "anthropic":
-		return anthropic.NewClient(ctx, enhancedCfg, env, opts...)
-	case "google":
+	create := func(ctx context.Context) (Provider, error) {
+		p, err := newClient(ctx, providerType, enhancedCfg, env, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return withRequestLog(p, newRequestLogger(ctx, enhancedCfg, env, &globalOptions)), nil
+	}
+
+	// The models asking for their API to be validated are created upfront,
+	// for the validation to fail fast.
+	if globalOptions.DeferredClient() && !enhancedCfg.Validate {
+		if _, ok := clientConstructors[providerType]; !ok {
+			return nil, unknownProviderTypeError(providerType)
+		}
+		return newDeferredProvider(base.Config{
+			ModelConfig:  *enhancedCfg,
+			ModelOptions: globalOptions,
+			Env:          env,
+		}, create), nil
+	}
+	return create(ctx)
+}
+
+// clientConstructor creates the client of a provider type.
+type clientConstructor func(ctx context.Context, cfg *latest.ModelConfig, env environment.Provider, opts ...options.Opt) (Provider, error)
+
+// clientConstructors holds the constructors of the clients, by provider type.
+var clientConstructors = map[string]clientConstructor{
+	"openai":                 newOpenAIClient,
+	"openai_chatcompletions": newOpenAIClient,
+	"openai_responses":       newOpenAIClient,
+	"anthropic": func(ctx context.Context, cfg *latest.ModelConfig, env environment.Provider, opts ...options.Opt) (Provider, error) {
+		return anthropic.NewClient(ctx, cfg, env, opts...)
+	},
+	"google": func(ctx context.Context, cfg *latest.ModelConfig, env environment.Provider, opts ...options.Opt) (Provider, error) {
 		// Route non-Gemini models on Vertex AI (Model Garden) through the
 		// OpenAI-compatible endpoint instead of the Gemini SDK.
-		if vertexai.IsModelGardenConfig(enhancedCfg) {
-			return vertexai.NewClient(ctx, enhancedCfg, env, opts...)
+		if vertexai.IsModelGardenConfig(cfg) {
+			return vertexai.NewClient(ctx, cfg, env, opts...)
 		}
-		return gemini.NewClient(ctx, enhancedCfg, env, opts...)
-	case "dmr":
-		return dmr.NewClient(ctx, enhancedCfg, opts...)
-	case "amazon-bedrock":
-		return bedrock.NewClient(ctx, enhancedCfg, env, opts...)
-	default:
+		return gemini.NewClient(ctx, cfg, env, opts...)
+	},
+	"dmr": func(ctx context.Context, cfg *latest.ModelConfig, _ environment.Provider, opts ...options.Opt) (Provider, error) {
+		return dmr.NewClient(ctx, cfg, opts...)
+	},
+	"amazon-bedrock": func(ctx context.Context, cfg *latest.ModelConfig, env environment.Provider, opts ...options.Opt) (Provider, error) {
+		return bedrock.NewClient(ctx, cfg, env, opts...)
+	},
+	"ollama": func(ctx context.Context, cfg *latest.ModelConfig, env environment.Provider, opts ...options.Opt) (Provider, error) {
+		return ollama.NewClient(ctx, cfg, env, opts...)
+	},
+}
+
+func newOpenAIClient(ctx context.Context, cfg *latest.ModelConfig, env environment.Provider, opts ...options.Opt) (Provider, error) {
+	return openai.NewClient(ctx, cfg, env, opts...)
+}
+
+func unknownProviderTypeError(providerType string) error {
+	return fmt.Errorf("unknown provider type: %s", providerType)
+}
+
+// newClient creates the client of a provider type.
+func newClient(ctx context.Context, providerType string, enhancedCfg *latest.ModelConfig, env environment.Provider, opts ...options.Opt) (Provider, error) {
+	newProviderClient, ok := clientConstructors[providerType]
+	if !ok {
 		slog.Error("Unknown provider type", "type", providerType)
-		return nil, fmt.Errorf("unknown provider type: %s", providerType)
+		return nil, unknownProviderTypeError(providerType)
 	}
+	return newProviderClient(ctx, enhancedCfg, env, opts...)
 }
 
 // ---------------------------------------------------------------------------
@@ -419,6 +495,8 @@ func applyProviderDefaults(cfg *latest.ModelConfig, customProviders map[string]l
 //   - thinking_budget: 0  or  thinking_budget: none  →  thinking is off (nil).
 //   - thinking_budget explicitly set to a real value  →  kept as-is; interleaved_thinking
 //     is auto-enabled for Anthropic/Bedrock-Claude.
+//   - reasoning set without thinking_budget  →  mapped to the provider's thinking_budget
+//     (see reasoningThinkingBudget), ignored for models that don't reason.
 //   - thinking_budget NOT set:
 //   - Thinking-only models (OpenAI o-series) get "medium".
 //   - All other models get no thinking.
@@ -435,6 +513,12 @@ func applyModelDefaults(cfg *latest.ModelConfig) {
 
 	providerType := resolveProviderType(cfg)
 
+	// The provider-independent reasoning setting becomes the provider's
+	// thinking_budget, unless one is set.
+	if cfg.ThinkingBudget == nil && cfg.Reasoning != nil {
+		cfg.ThinkingBudget = reasoningThinkingBudget(cfg, providerType)
+	}
+
 	// User already set a real thinking_budget — just apply side-effects.
 	if cfg.ThinkingBudget != nil {
 		ensureInterleavedThinking(cfg, providerType)
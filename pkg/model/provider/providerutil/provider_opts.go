@@ -7,7 +7,8 @@ import (
 )
 
 // GetProviderOptFloat64 extracts a float64 value from provider opts.
-// YAML may parse numbers as float64 or int, so this handles both.
+// YAML may parse numbers as floats or as any integer kind (goccy/go-yaml
+// decodes positive integers as uint64), so this handles all of them.
 func GetProviderOptFloat64(opts map[string]any, key string) (float64, bool) {
 	if opts == nil {
 		return 0, false
@@ -23,8 +24,24 @@ func GetProviderOptFloat64(opts map[string]any, key string) (float64, bool) {
 		return float64(n), true
 	case int:
 		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
 	case int64:
 		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
 	default:
 		slog.Debug("provider_opts type mismatch, ignoring",
 			"key", key,
@@ -36,7 +53,8 @@ func GetProviderOptFloat64(opts map[string]any, key string) (float64, bool) {
 }
 
 // GetProviderOptInt64 extracts an int64 value from provider opts.
-// YAML may parse numbers as float64 or int, so this handles both.
+// YAML may parse numbers as floats or as any integer kind (goccy/go-yaml
+// decodes positive integers as uint64), so this handles all of them.
 func GetProviderOptInt64(opts map[string]any, key string) (int64, bool) {
 	if opts == nil {
 		return 0, false
@@ -48,8 +66,34 @@ func GetProviderOptInt64(opts map[string]any, key string) (int64, bool) {
 	switch n := v.(type) {
 	case int:
 		return int64(n), true
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
 	case int64:
 		return n, true
+	case uint8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case uint:
+		if uint64(n) <= math.MaxInt64 {
+			return int64(n), true
+		}
+		slog.Debug("provider_opts: integer value overflows int64",
+			"key", key, "value", v)
+		return 0, false
+	case uint64:
+		if n <= math.MaxInt64 {
+			return int64(n), true
+		}
+		slog.Debug("provider_opts: integer value overflows int64",
+			"key", key, "value", v)
+		return 0, false
 	case float64:
 		if n == math.Trunc(n) && n >= math.MinInt64 && n <= math.MaxInt64 {
 			return int64(n), true
@@ -1,6 +1,7 @@
 package providerutil
 
 import (
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -19,6 +20,8 @@ func TestGetProviderOptFloat64(t *testing.T) {
 		{"float64 value", map[string]any{"top_k": 40.0}, "top_k", 40.0, true},
 		{"int value", map[string]any{"top_k": 40}, "top_k", 40.0, true},
 		{"int64 value", map[string]any{"top_k": int64(40)}, "top_k", 40.0, true},
+		{"uint64 value", map[string]any{"top_k": uint64(40)}, "top_k", 40.0, true},
+		{"int32 value", map[string]any{"top_k": int32(40)}, "top_k", 40.0, true},
 		{"float32 value", map[string]any{"top_k": float32(40.5)}, "top_k", float64(float32(40.5)), true},
 		{"string value", map[string]any{"top_k": "40"}, "top_k", 0, false},
 	}
@@ -71,6 +74,9 @@ func TestGetProviderOptInt64(t *testing.T) {
 		{"missing key", map[string]any{}, "seed", 0, false},
 		{"int value", map[string]any{"seed": 42}, "seed", 42, true},
 		{"int64 value", map[string]any{"seed": int64(42)}, "seed", 42, true},
+		{"uint64 value", map[string]any{"seed": uint64(42)}, "seed", 42, true},
+		{"uint64 overflow", map[string]any{"seed": uint64(math.MaxUint64)}, "seed", 0, false},
+		{"int32 value", map[string]any{"seed": int32(-42)}, "seed", -42, true},
 		{"float64 whole number", map[string]any{"seed": 42.0}, "seed", 42, true},
 		{"float64 fractional", map[string]any{"seed": 42.5}, "seed", 0, false},
 		{"string value", map[string]any{"seed": "42"}, "seed", 0, false},
@@ -0,0 +1,60 @@
+package provider
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/docker/docker-agent/pkg/config/latest"
+	"github.com/docker/docker-agent/pkg/effort"
+	"github.com/docker/docker-agent/pkg/model/provider/openai"
+)
+
+// minClaudeThinkingTokens is the smallest thinking budget Claude accepts.
+const minClaudeThinkingTokens = 1024
+
+// reasoningThinkingBudget maps the provider-independent reasoning
+// configuration of cfg to the thinking budget its provider turns into native
+// parameters:
+//   - Anthropic and Bedrock Claude: a token budget (budget_tokens).
+//   - OpenAI reasoning models: an effort level (reasoning_effort).
+//   - Gemini 2.5: a token budget (thinkingBudget).
+//   - Gemini 3: an effort level (thinkingLevel).
+//
+// Missing tokens are derived from the effort and the other way around. It
+// returns nil, with a warning, for the models that don't reason.
+func reasoningThinkingBudget(cfg *latest.ModelConfig, providerType string) *latest.ThinkingBudget {
+	reasoning := cfg.Reasoning
+
+	level, hasEffort := effort.Parse(reasoning.Effort)
+	tokens := reasoning.MaxThinkingTokens
+	if tokens == 0 && hasEffort {
+		tokens, _ = effort.Tokens(level)
+	}
+	if !hasEffort {
+		level = effort.FromTokens(tokens)
+	}
+
+	model := strings.ToLower(cfg.Model)
+	switch {
+	case (providerType == "anthropic" || providerType == "amazon-bedrock" && isBedrockClaudeModel(model)) && claudeSupportsThinking(model):
+		return &latest.ThinkingBudget{Tokens: max(tokens, minClaudeThinkingTokens)}
+	case isOpenAICompatibleProvider(providerType) && openai.IsReasoningModel(model):
+		return &latest.ThinkingBudget{Effort: string(level)}
+	case providerType == "google" && gemini3Family(model) != "":
+		return &latest.ThinkingBudget{Effort: string(level)}
+	case providerType == "google" && strings.HasPrefix(model, "gemini-2.5"):
+		return &latest.ThinkingBudget{Tokens: tokens}
+	}
+
+	slog.Warn("Ignoring the reasoning configuration of a model that doesn't support reasoning",
+		"provider", cfg.Provider, "model", cfg.Model)
+	return nil
+}
+
+// claudeSupportsThinking reports whether a Claude model supports extended
+// thinking: Claude 3.7 and later. Bedrock model IDs are prefixed, e.g.
+// "anthropic.claude-3-7-sonnet-...".
+func claudeSupportsThinking(model string) bool {
+	_, name, _ := strings.Cut(model, "claude-")
+	return !strings.HasPrefix(name, "3-") || strings.HasPrefix(name, "3-7")
+}
@@ -0,0 +1,198 @@
+package provider
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/config/latest"
+	"github.com/docker/docker-agent/pkg/environment"
+)
+
+func TestApplyModelDefaults_Reasoning(t *testing.T) {
+	t.Parallel()
+
+	high := &latest.ReasoningConfig{Effort: "high"}
+	tokensOnly := &latest.ReasoningConfig{MaxThinkingTokens: 4096}
+	both := &latest.ReasoningConfig{Effort: "low", MaxThinkingTokens: 12000}
+
+	tests := []struct {
+		name       string
+		config     *latest.ModelConfig
+		wantBudget *latest.ThinkingBudget
+	}{
+		{
+			name:       "anthropic: effort becomes budget_tokens",
+			config:     &latest.ModelConfig{Provider: "anthropic", Model: "claude-sonnet-4-5", Reasoning: high},
+			wantBudget: &latest.ThinkingBudget{Tokens: 16384},
+		},
+		{
+			name:       "anthropic: max_thinking_tokens wins over effort",
+			config:     &latest.ModelConfig{Provider: "anthropic", Model: "claude-sonnet-4-5", Reasoning: both},
+			wantBudget: &latest.ThinkingBudget{Tokens: 12000},
+		},
+		{
+			name:       "anthropic: budget raised to the minimum",
+			config:     &latest.ModelConfig{Provider: "anthropic", Model: "claude-3-7-sonnet-latest", Reasoning: &latest.ReasoningConfig{MaxThinkingTokens: 100}},
+			wantBudget: &latest.ThinkingBudget{Tokens: 1024},
+		},
+		{
+			name:   "anthropic: claude 3.5 doesn't reason",
+			config: &latest.ModelConfig{Provider: "anthropic", Model: "claude-3-5-haiku-latest", Reasoning: high},
+		},
+		{
+			name:       "bedrock claude: effort becomes budget_tokens",
+			config:     &latest.ModelConfig{Provider: "amazon-bedrock", Model: "global.anthropic.claude-sonnet-4-5-20250929-v1:0", Reasoning: high},
+			wantBudget: &latest.ThinkingBudget{Tokens: 16384},
+		},
+		{
+			name:       "openai: effort becomes reasoning_effort",
+			config:     &latest.ModelConfig{Provider: "openai", Model: "gpt-5", Reasoning: high},
+			wantBudget: &latest.ThinkingBudget{Effort: "high"},
+		},
+		{
+			name:       "openai: effort derived from max_thinking_tokens",
+			config:     &latest.ModelConfig{Provider: "openai", Model: "o3-mini", Reasoning: tokensOnly},
+			wantBudget: &latest.ThinkingBudget{Effort: "medium"},
+		},
+		{
+			name:   "openai: gpt-4o doesn't reason",
+			config: &latest.ModelConfig{Provider: "openai", Model: "gpt-4o", Reasoning: high},
+		},
+		{
+			name:       "gemini 2.5: effort becomes thinkingBudget",
+			config:     &latest.ModelConfig{Provider: "google", Model: "gemini-2.5-flash", Reasoning: &latest.ReasoningConfig{Effort: "medium"}},
+			wantBudget: &latest.ThinkingBudget{Tokens: 8192},
+		},
+		{
+			name:       "gemini 3: effort becomes thinkingLevel",
+			config:     &latest.ModelConfig{Provider: "google", Model: "gemini-3-pro-preview", Reasoning: tokensOnly},
+			wantBudget: &latest.ThinkingBudget{Effort: "medium"},
+		},
+		{
+			name:   "gemini 2.0 doesn't reason",
+			config: &latest.ModelConfig{Provider: "google", Model: "gemini-2.0-flash", Reasoning: high},
+		},
+		{
+			name:   "dmr doesn't reason",
+			config: &latest.ModelConfig{Provider: "dmr", Model: "ai/qwen3", Reasoning: high},
+		},
+		{
+			name:       "thinking_budget takes precedence",
+			config:     &latest.ModelConfig{Provider: "anthropic", Model: "claude-sonnet-4-5", ThinkingBudget: &latest.ThinkingBudget{Effort: "adaptive"}, Reasoning: high},
+			wantBudget: &latest.ThinkingBudget{Effort: "adaptive"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			applyModelDefaults(tt.config)
+			assert.Equal(t, tt.wantBudget, tt.config.ThinkingBudget)
+		})
+	}
+}
+
+// captureRequests returns a server recording the JSON body of the requests
+// whose path ends with pathSuffix, and failing them all.
+func captureRequests(t *testing.T, pathSuffix string) (*httptest.Server, func() map[string]any) {
+	t.Helper()
+
+	var (
+		mu   sync.Mutex
+		body map[string]any
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		if strings.HasSuffix(r.URL.Path, pathSuffix) {
+			mu.Lock()
+			_ = json.Unmarshal(data, &body)
+			mu.Unlock()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"message":"captured","type":"invalid_request_error"}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() map[string]any {
+		mu.Lock()
+		defer mu.Unlock()
+		return body
+	}
+}
+
+func TestReasoning_RequestPayloads(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		config     latest.ModelConfig
+		env        map[string]string
+		pathSuffix string
+		// want is the path of the native parameter in the request and its value.
+		path []string
+		want any
+	}{
+		{
+			name:       "anthropic budget_tokens",
+			config:     latest.ModelConfig{Provider: "anthropic", Model: "claude-sonnet-4-5", Reasoning: &latest.ReasoningConfig{Effort: "medium"}},
+			env:        map[string]string{"ANTHROPIC_API_KEY": "test"},
+			pathSuffix: "/messages",
+			path:       []string{"thinking", "budget_tokens"},
+			want:       float64(8192),
+		},
+		{
+			name:       "openai reasoning_effort",
+			config:     latest.ModelConfig{Provider: "openai", Model: "o3-mini", Reasoning: &latest.ReasoningConfig{Effort: "high"}, ProviderOpts: map[string]any{"api_type": "openai_chatcompletions"}},
+			env:        map[string]string{"OPENAI_API_KEY": "test"},
+			pathSuffix: "/chat/completions",
+			path:       []string{"reasoning_effort"},
+			want:       "high",
+		},
+		{
+			name:       "gemini thinkingBudget",
+			config:     latest.ModelConfig{Provider: "google", Model: "gemini-2.5-flash", Reasoning: &latest.ReasoningConfig{MaxThinkingTokens: 4096}},
+			env:        map[string]string{"GOOGLE_API_KEY": "test"},
+			pathSuffix: ":streamGenerateContent",
+			path:       []string{"generationConfig", "thinkingConfig", "thinkingBudget"},
+			want:       float64(4096),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server, body := captureRequests(t, tt.pathSuffix)
+			cfg := tt.config
+			cfg.BaseURL = server.URL
+
+			p, err := New(t.Context(), &cfg, environment.NewMapEnvProvider(tt.env))
+			require.NoError(t, err)
+
+			stream, err := p.CreateChatCompletionStream(t.Context(), []chat.Message{{Role: chat.MessageRoleUser, Content: "Think"}}, nil)
+			if err == nil {
+				drainStream(t, stream)
+				stream.Close()
+			}
+
+			var value any = body()
+			for _, key := range tt.path {
+				m, ok := value.(map[string]any)
+				require.True(t, ok, "no %q in the request", key)
+				value = m[key]
+			}
+			assert.Equal(t, tt.want, value)
+		})
+	}
+}
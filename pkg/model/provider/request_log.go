@@ -0,0 +1,579 @@
+package provider
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/config/latest"
+	"github.com/docker/docker-agent/pkg/environment"
+	"github.com/docker/docker-agent/pkg/model/provider/base"
+	"github.com/docker/docker-agent/pkg/model/provider/options"
+	"github.com/docker/docker-agent/pkg/rag/types"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+const (
+	// requestLogDirEnv names a directory where the requests sent to the
+	// models, and their responses, are logged. CAGENT_DEBUG_PROVIDER_LOG is
+	// still supported.
+	requestLogDirEnv       = "DOCKER_AGENT_DEBUG_PROVIDER_LOG"
+	legacyRequestLogDirEnv = "CAGENT_DEBUG_PROVIDER_LOG"
+	// requestLogRedactEnv is a comma separated list of strings hidden from
+	// the request logs.
+	requestLogRedactEnv = "DOCKER_AGENT_DEBUG_PROVIDER_LOG_REDACT"
+
+	redacted = "[REDACTED]"
+	// minSecretLength is the length under which the values of the API key
+	// environment variables aren't redacted: they can't be real keys, and
+	// would hide unrelated text.
+	minSecretLength = 8
+)
+
+// apiKeyEnvVars are the environment variables holding the API keys of the
+// core providers. Their values are always redacted from the request logs.
+var apiKeyEnvVars = []string{
+	"OPENAI_API_KEY",
+	"ANTHROPIC_API_KEY",
+	"GOOGLE_API_KEY",
+	"GEMINI_API_KEY",
+	"AWS_BEARER_TOKEN_BEDROCK",
+	"AWS_SECRET_ACCESS_KEY",
+	"AWS_SESSION_TOKEN",
+}
+
+// secretNameRe matches the names of headers and provider options holding
+// credentials.
+var secretNameRe = regexp.MustCompile(`(?i)authorization|api[-_]?key|token|secret|password|cookie|credential`)
+
+// requestLogMu serializes the writes of all the request logs, so that the
+// lines of providers sharing a writer don't interleave.
+var requestLogMu sync.Mutex
+
+// requestLogger writes the requests sent to a model, and their responses, as
+// lines of JSON.
+type requestLogger struct {
+	w       io.Writer
+	params  requestLogParams
+	secrets []string
+}
+
+// newRequestLogger returns the logger of the requests sent to a model, or
+// nil if they aren't logged: neither options.WithRequestLogger nor the
+// DOCKER_AGENT_DEBUG_PROVIDER_LOG environment variable is set.
+func newRequestLogger(ctx context.Context, cfg *latest.ModelConfig, env environment.Provider, opts *options.ModelOptions) *requestLogger {
+	w := opts.RequestLogger()
+	if w == nil {
+		dir := cmp.Or(os.Getenv(requestLogDirEnv), os.Getenv(legacyRequestLogDirEnv))
+		if dir == "" {
+			return nil
+		}
+		f, err := requestLogFile(dir)
+		if err != nil {
+			slog.Warn("Failed to open the provider request log", "dir", dir, "error", err)
+			return nil
+		}
+		w = f
+	}
+
+	secrets := slices.Clone(opts.Redactions())
+	if env := os.Getenv(requestLogRedactEnv); env != "" {
+		secrets = append(secrets, strings.Split(env, ",")...)
+	}
+	secrets = append(secrets, apiKeys(ctx, cfg, env)...)
+
+	params, paramSecrets := newRequestLogParams(cfg)
+	secrets = append(secrets, paramSecrets...)
+
+	// Replace the longest secrets first, so that a secret containing
+	// another one is hidden as a whole.
+	secrets = slices.DeleteFunc(secrets, func(s string) bool { return strings.TrimSpace(s) == "" })
+	slices.SortFunc(secrets, func(a, b string) int { return cmp.Compare(len(b), len(a)) })
+
+	return &requestLogger{
+		w:       w,
+		params:  params,
+		secrets: slices.Compact(secrets),
+	}
+}
+
+var (
+	requestLogFilesMu sync.Mutex
+	requestLogFiles   = map[string]*os.File{}
+)
+
+// requestLogFile returns the file, shared by all the providers of the
+// process, where the requests are logged in dir.
+func requestLogFile(dir string) (*os.File, error) {
+	requestLogFilesMu.Lock()
+	defer requestLogFilesMu.Unlock()
+
+	if f, ok := requestLogFiles[dir]; ok {
+		return f, nil
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("provider-requests-%s-%d.jsonl", time.Now().Format("20060102-150405"), os.Getpid())
+	f, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	requestLogFiles[dir] = f
+	return f, nil
+}
+
+// apiKeys returns the values of the environment variables that may hold the
+// API key of the model.
+func apiKeys(ctx context.Context, cfg *latest.ModelConfig, env environment.Provider) []string {
+	if env == nil {
+		return nil
+	}
+
+	names := slices.Clone(apiKeyEnvVars)
+	if cfg.TokenKey != "" {
+		names = append(names, cfg.TokenKey)
+	}
+	if alias, ok := Aliases[cfg.Provider]; ok && alias.TokenEnvVar != "" {
+		names = append(names, alias.TokenEnvVar)
+	}
+
+	var keys []string
+	for _, name := range names {
+		if value, _ := env.Get(ctx, name); len(value) >= minSecretLength {
+			keys = append(keys, value)
+		}
+	}
+	return keys
+}
+
+// requestLogParams are the parameters of the model sent with each request.
+type requestLogParams struct {
+	Model             string                 `json:"model"`
+	BaseURL           string                 `json:"base_url,omitempty"`
+	MaxTokens         *int64                 `json:"max_tokens,omitempty"`
+	Temperature       *float64               `json:"temperature,omitempty"`
+	TopP              *float64               `json:"top_p,omitempty"`
+	FrequencyPenalty  *float64               `json:"frequency_penalty,omitempty"`
+	PresencePenalty   *float64               `json:"presence_penalty,omitempty"`
+	ParallelToolCalls *bool                  `json:"parallel_tool_calls,omitempty"`
+	ThinkingBudget    *latest.ThinkingBudget `json:"thinking_budget,omitempty"`
+	ExtraHeaders      map[string]string      `json:"extra_headers,omitempty"`
+	ProviderOpts      map[string]any         `json:"provider_opts,omitempty"`
+}
+
+// newRequestLogParams returns the parameters of the model, without their
+// credentials, along with the credentials it removed.
+func newRequestLogParams(cfg *latest.ModelConfig) (requestLogParams, []string) {
+	var secrets []string
+
+	baseURL := cfg.BaseURL
+	if u, err := url.Parse(baseURL); err == nil && (u.User != nil || u.RawQuery != "") {
+		if password, ok := u.User.Password(); ok {
+			secrets = append(secrets, password)
+		}
+		u.User = nil
+		query := u.Query()
+		for name, values := range query {
+			if secretNameRe.MatchString(name) || strings.EqualFold(name, "key") {
+				secrets = append(secrets, values...)
+				query[name] = []string{redacted}
+			}
+		}
+		u.RawQuery = query.Encode()
+		baseURL = u.String()
+	}
+
+	var headers map[string]string
+	if len(cfg.ExtraHeaders) > 0 {
+		headers = make(map[string]string, len(cfg.ExtraHeaders))
+		for name, value := range cfg.ExtraHeaders {
+			if secretNameRe.MatchString(name) {
+				secrets = append(secrets, value)
+				// The credentials of "Bearer <token>" are also hidden
+				// on their own.
+				if _, credentials, ok := strings.Cut(value, " "); ok {
+					secrets = append(secrets, credentials)
+				}
+				value = redacted
+			}
+			headers[name] = value
+		}
+	}
+
+	providerOpts, optSecrets := redactSecretValues(cfg.ProviderOpts)
+	secrets = append(secrets, optSecrets...)
+
+	params := requestLogParams{
+		Model:             cfg.Model,
+		BaseURL:           baseURL,
+		MaxTokens:         cfg.MaxTokens,
+		Temperature:       cfg.Temperature,
+		TopP:              cfg.TopP,
+		FrequencyPenalty:  cfg.FrequencyPenalty,
+		PresencePenalty:   cfg.PresencePenalty,
+		ParallelToolCalls: cfg.ParallelToolCalls,
+		ThinkingBudget:    cfg.ThinkingBudget,
+		ExtraHeaders:      headers,
+	}
+	if m, ok := providerOpts.(map[string]any); ok {
+		params.ProviderOpts = m
+	}
+	return params, secrets
+}
+
+// redactSecretValues returns a copy of v where the values of the map keys
+// naming credentials are redacted, along with the redacted strings.
+func redactSecretValues(v any) (any, []string) {
+	var secrets []string
+	var redact func(v any) any
+	redact = func(v any) any {
+		switch v := v.(type) {
+		case map[string]any:
+			if v == nil {
+				return v
+			}
+			out := make(map[string]any, len(v))
+			for key, value := range v {
+				if secretNameRe.MatchString(key) {
+					if s, ok := value.(string); ok {
+						secrets = append(secrets, s)
+					}
+					out[key] = redacted
+					continue
+				}
+				out[key] = redact(value)
+			}
+			return out
+		case []any:
+			out := make([]any, len(v))
+			for i, value := range v {
+				out[i] = redact(value)
+			}
+			return out
+		default:
+			return v
+		}
+	}
+	return redact(v), secrets
+}
+
+// requestLogEntry is a request sent to a model and its response.
+type requestLogEntry struct {
+	Time     time.Time           `json:"time"`
+	Provider string              `json:"provider"`
+	Params   requestLogParams    `json:"params"`
+	Messages []requestLogMessage `json:"messages"`
+	Tools    []requestLogTool    `json:"tools,omitempty"`
+	Response *requestLogResponse `json:"response,omitempty"`
+	Error    string              `json:"error,omitempty"`
+	// FirstChunkMs is the time to the first chunk of the response, in
+	// milliseconds.
+	FirstChunkMs int64 `json:"first_chunk_ms,omitempty"`
+	// LatencyMs is the time to the end of the response, in milliseconds.
+	LatencyMs int64 `json:"latency_ms"`
+}
+
+type requestLogMessage struct {
+	Role             chat.MessageRole   `json:"role"`
+	Content          string             `json:"content,omitempty"`
+	MultiContent     []chat.MessagePart `json:"multi_content,omitempty"`
+	ReasoningContent string             `json:"reasoning_content,omitempty"`
+	ToolCalls        []tools.ToolCall   `json:"tool_calls,omitempty"`
+	ToolCallID       string             `json:"tool_call_id,omitempty"`
+	IsError          bool               `json:"is_error,omitempty"`
+}
+
+type requestLogTool struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+}
+
+// requestLogResponse is the response of a model, with its streamed chunks
+// collapsed.
+type requestLogResponse struct {
+	Content          string            `json:"content,omitempty"`
+	ReasoningContent string            `json:"reasoning_content,omitempty"`
+	ToolCalls        []tools.ToolCall  `json:"tool_calls,omitempty"`
+	FinishReason     chat.FinishReason `json:"finish_reason,omitempty"`
+	Usage            *chat.Usage       `json:"usage,omitempty"`
+	Chunks           int               `json:"chunks"`
+}
+
+func (l *requestLogger) newEntry(providerID string, messages []chat.Message, availableTools []tools.Tool) *requestLogEntry {
+	entry := &requestLogEntry{
+		Time:     time.Now(),
+		Provider: providerID,
+		Params:   l.params,
+	}
+	for _, msg := range messages {
+		entry.Messages = append(entry.Messages, requestLogMessage{
+			Role:             msg.Role,
+			Content:          msg.Content,
+			MultiContent:     collapseInlineData(msg.MultiContent),
+			ReasoningContent: msg.ReasoningContent,
+			ToolCalls:        msg.ToolCalls,
+			ToolCallID:       msg.ToolCallID,
+			IsError:          msg.IsError,
+		})
+	}
+	for _, tool := range availableTools {
+		entry.Tools = append(entry.Tools, requestLogTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			Parameters:  tool.Parameters,
+		})
+	}
+	return entry
+}
+
+// collapseInlineData replaces the data of the inline images with their
+// size: it would make the logs unreadable.
+func collapseInlineData(parts []chat.MessagePart) []chat.MessagePart {
+	if len(parts) == 0 {
+		return nil
+	}
+	out := slices.Clone(parts)
+	for i, part := range out {
+		if part.ImageURL == nil || !strings.HasPrefix(part.ImageURL.URL, "data:") {
+			continue
+		}
+		header, data, ok := strings.Cut(part.ImageURL.URL, ",")
+		if !ok {
+			continue
+		}
+		out[i].ImageURL = &chat.MessageImageURL{
+			URL:    fmt.Sprintf("%s,[%d bytes]", header, len(data)),
+			Detail: part.ImageURL.Detail,
+		}
+	}
+	return out
+}
+
+// write logs an entry as a line of JSON, with the secrets redacted.
+func (l *requestLogger) write(entry *requestLogEntry, start time.Time) {
+	entry.LatencyMs = time.Since(start).Milliseconds()
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(entry); err != nil {
+		slog.Warn("Failed to encode the provider request log", "provider", entry.Provider, "error", err)
+		return
+	}
+	line := l.redact(buf.String())
+
+	requestLogMu.Lock()
+	defer requestLogMu.Unlock()
+	if _, err := io.WriteString(l.w, line); err != nil {
+		slog.Warn("Failed to write the provider request log", "provider", entry.Provider, "error", err)
+	}
+}
+
+// redact hides the secrets from a line of JSON. They're searched for as
+// they're encoded in JSON strings, so that the escaped characters don't
+// let them through.
+func (l *requestLogger) redact(line string) string {
+	for _, secret := range l.secrets {
+		line = strings.ReplaceAll(line, secret, redacted)
+		if encoded := jsonStringContent(secret); encoded != secret {
+			line = strings.ReplaceAll(line, encoded, redacted)
+		}
+	}
+	return line
+}
+
+// jsonStringContent returns s as it's encoded in a JSON string, without the
+// quotes.
+func jsonStringContent(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	encoded := strings.TrimSpace(buf.String())
+	return encoded[1 : len(encoded)-1]
+}
+
+// loggingProvider logs the chat completion requests of a provider, and their
+// responses.
+type loggingProvider struct {
+	Provider
+
+	logger *requestLogger
+}
+
+// withRequestLog returns p logging its requests to logger, or p itself if
+// logger is nil. The embedding and reranking capabilities of p are kept.
+func withRequestLog(p Provider, logger *requestLogger) Provider {
+	if logger == nil {
+		return p
+	}
+
+	lp := &loggingProvider{Provider: p, logger: logger}
+	switch inner := p.(type) {
+	case embeddingRerankingProvider:
+		return &loggingEmbeddingProvider{loggingProvider: lp, inner: inner}
+	case RerankingProvider:
+		return &loggingRerankingProvider{loggingProvider: lp, inner: inner}
+	}
+	return lp
+}
+
+func (p *loggingProvider) CreateChatCompletionStream(ctx context.Context, messages []chat.Message, availableTools []tools.Tool) (chat.MessageStream, error) {
+	entry := p.logger.newEntry(p.ID(), messages, availableTools)
+	start := time.Now()
+
+	stream, err := p.Provider.CreateChatCompletionStream(ctx, messages, availableTools)
+	if err != nil {
+		entry.Error = err.Error()
+		p.logger.write(entry, start)
+		return nil, err
+	}
+	return &loggingStream{
+		MessageStream: stream,
+		logger:        p.logger,
+		entry:         entry,
+		start:         start,
+		toolCallIndex: map[string]int{},
+	}, nil
+}
+
+// loggingStream collapses the chunks of a response, and logs it with its
+// request once it's fully received or closed.
+type loggingStream struct {
+	chat.MessageStream
+
+	logger *requestLogger
+	entry  *requestLogEntry
+	start  time.Time
+
+	response      requestLogResponse
+	content       strings.Builder
+	reasoning     strings.Builder
+	toolCallIndex map[string]int
+	once          sync.Once
+}
+
+func (s *loggingStream) Recv() (chat.MessageStreamResponse, error) {
+	response, err := s.MessageStream.Recv()
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			s.entry.Error = err.Error()
+		}
+		s.flush()
+		return response, err
+	}
+
+	if s.response.Chunks == 0 {
+		s.entry.FirstChunkMs = time.Since(s.start).Milliseconds()
+	}
+	s.response.Chunks++
+	if response.Usage != nil {
+		s.response.Usage = response.Usage
+	}
+	for _, choice := range response.Choices {
+		s.content.WriteString(choice.Delta.Content)
+		s.reasoning.WriteString(choice.Delta.ReasoningContent)
+		for _, delta := range choice.Delta.ToolCalls {
+			s.addToolCallDelta(delta)
+		}
+		if choice.FinishReason != "" {
+			s.response.FinishReason = choice.FinishReason
+		}
+	}
+	return response, nil
+}
+
+// addToolCallDelta merges a chunk of a tool call, by index when the
+// provider sends one and by ID otherwise.
+func (s *loggingStream) addToolCallDelta(delta tools.ToolCall) {
+	key := delta.ID
+	if delta.Index != nil {
+		key = fmt.Sprintf("#%d", *delta.Index)
+	}
+	idx, ok := s.toolCallIndex[key]
+	if !ok {
+		idx = len(s.response.ToolCalls)
+		s.toolCallIndex[key] = idx
+		s.response.ToolCalls = append(s.response.ToolCalls, tools.ToolCall{ID: delta.ID, Type: delta.Type})
+	}
+	tc := &s.response.ToolCalls[idx]
+	if tc.ID == "" {
+		tc.ID = delta.ID
+	}
+	if delta.Type != "" {
+		tc.Type = delta.Type
+	}
+	if delta.Function.Name != "" {
+		tc.Function.Name = delta.Function.Name
+	}
+	tc.Function.Arguments += delta.Function.Arguments
+}
+
+func (s *loggingStream) Close() {
+	s.flush()
+	s.MessageStream.Close()
+}
+
+func (s *loggingStream) flush() {
+	s.once.Do(func() {
+		s.response.Content = s.content.String()
+		s.response.ReasoningContent = s.reasoning.String()
+		s.entry.Response = &s.response
+		s.logger.write(s.entry, s.start)
+	})
+}
+
+// embeddingRerankingProvider is implemented by the providers supporting both
+// embeddings and reranking, e.g. OpenAI and DMR.
+type embeddingRerankingProvider interface {
+	BatchEmbeddingProvider
+	RerankingProvider
+}
+
+// loggingRerankingProvider is a loggingProvider keeping the reranking
+// capability of its provider. Reranking requests aren't logged.
+type loggingRerankingProvider struct {
+	*loggingProvider
+
+	inner RerankingProvider
+}
+
+func (p *loggingRerankingProvider) Rerank(ctx context.Context, query string, documents []types.Document, criteria string) ([]float64, error) {
+	return p.inner.Rerank(ctx, query, documents, criteria)
+}
+
+// loggingEmbeddingProvider is a loggingProvider keeping the embedding and
+// reranking capabilities of its provider. Embedding requests aren't logged.
+type loggingEmbeddingProvider struct {
+	*loggingProvider
+
+	inner embeddingRerankingProvider
+}
+
+func (p *loggingEmbeddingProvider) CreateEmbedding(ctx context.Context, text string) (*base.EmbeddingResult, error) {
+	return p.inner.CreateEmbedding(ctx, text)
+}
+
+func (p *loggingEmbeddingProvider) CreateBatchEmbedding(ctx context.Context, texts []string) (*base.BatchEmbeddingResult, error) {
+	return p.inner.CreateBatchEmbedding(ctx, texts)
+}
+
+func (p *loggingEmbeddingProvider) Rerank(ctx context.Context, query string, documents []types.Document, criteria string) ([]float64, error) {
+	return p.inner.Rerank(ctx, query, documents, criteria)
+}
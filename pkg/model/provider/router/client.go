@@ -0,0 +1,239 @@
+// Package router provides a model router that escalates from cheap models to
+// stronger ones when a turn looks too hard.
+//
+// A model becomes an escalating router when its provider is "router". Its
+// candidates are ordered from the cheapest to the strongest. Each request
+// goes to the first candidate, unless:
+//   - the prompt is larger than max_prompt_tokens: the request goes to the
+//     second candidate at least;
+//   - more tools than max_tools are attached: the request goes to the
+//     strongest candidate;
+//   - the runtime escalated the turn with [Client.Escalate], because the
+//     model returned malformed tool arguments or an answer rejected by the
+//     output guards of its agent: the request goes to the candidate after
+//     the one that failed.
+//
+// An escalation lasts until the end of the turn, when a model answers
+// without calling tools.
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/compaction"
+	"github.com/docker/docker-agent/pkg/config/latest"
+	"github.com/docker/docker-agent/pkg/environment"
+	"github.com/docker/docker-agent/pkg/model/provider/base"
+	"github.com/docker/docker-agent/pkg/model/provider/options"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// ProviderType is the provider of the models that are escalating routers.
+const ProviderType = "router"
+
+// The reasons for routing a request to another candidate than the first one.
+const (
+	ReasonPromptTokens          = "prompt_tokens"
+	ReasonTools                 = "tools"
+	ReasonMalformedToolArgument = "malformed_tool_arguments"
+	ReasonOutputGuard           = "output_guard"
+)
+
+// Provider defines the minimal interface needed for model providers.
+type Provider interface {
+	ID() string
+	CreateChatCompletionStream(
+		ctx context.Context,
+		messages []chat.Message,
+		availableTools []tools.Tool,
+	) (chat.MessageStream, error)
+	BaseConfig() base.Config
+}
+
+// ProviderFactory creates a provider from a model config.
+// The models parameter provides access to all configured models for resolving references.
+type ProviderFactory func(ctx context.Context, modelSpec string, models map[string]latest.ModelConfig, env environment.Provider, opts ...options.Opt) (Provider, error)
+
+// Decision is the routing of a request.
+type Decision struct {
+	// ModelID is the ID of the candidate the request went to.
+	ModelID string
+	// Reason is why the request didn't go to the first candidate, empty
+	// when it did.
+	Reason string
+}
+
+// Client implements the Provider interface for escalating model routing.
+type Client struct {
+	base.Config
+
+	candidates      []Provider
+	maxPromptTokens int64
+	maxTools        int
+
+	mu sync.Mutex
+	// level is the candidate the turn was escalated to, and reason why.
+	level  int
+	reason string
+	// lastIndex is the candidate of the most recent request.
+	lastIndex int
+	last      Decision
+}
+
+// NewClient creates a new escalating routing client from cfg, whose Router
+// field lists the candidates, references to models or inline model specs.
+func NewClient(ctx context.Context, cfg *latest.ModelConfig, models map[string]latest.ModelConfig, env environment.Provider, providerFactory ProviderFactory, opts ...options.Opt) (*Client, error) {
+	slog.Debug("Creating escalating router", "model", cfg.Model)
+
+	if cfg.Router == nil || len(cfg.Router.Candidates) == 0 {
+		return nil, errors.New("no router candidates configured")
+	}
+
+	client := &Client{
+		Config: base.Config{
+			ModelConfig: *cfg,
+			Models:      models,
+			Env:         env,
+		},
+		maxPromptTokens: cfg.Router.MaxPromptTokens,
+		maxTools:        cfg.Router.MaxTools,
+	}
+	// The router is named after its model, e.g. router/auto.
+	if client.ModelConfig.Model == "" {
+		client.ModelConfig.Model = cfg.Name
+	}
+
+	// Candidates may have different token limits than the router.
+	candidateOpts := options.WithoutMaxTokens(opts)
+	for _, spec := range cfg.Router.Candidates {
+		candidate, err := providerFactory(ctx, spec, models, env, candidateOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("creating router candidate %q: %w", spec, err)
+		}
+		client.candidates = append(client.candidates, candidate)
+	}
+
+	return client, nil
+}
+
+// CreateChatCompletionStream routes the request to a candidate and delegates
+// the call. The decision is recorded in LastDecision.
+func (c *Client) CreateChatCompletionStream(
+	ctx context.Context,
+	messages []chat.Message,
+	availableTools []tools.Tool,
+) (chat.MessageStream, error) {
+	c.mu.Lock()
+	index, reason := c.route(messages, availableTools)
+	candidate := c.candidates[index]
+	c.lastIndex = index
+	c.last = Decision{ModelID: candidate.ID(), Reason: reason}
+	c.mu.Unlock()
+
+	slog.Debug("Escalating router selected model",
+		"router", c.ID(),
+		"selected_model", candidate.ID(),
+		"reason", reason,
+	)
+
+	stream, err := candidate.CreateChatCompletionStream(ctx, messages, availableTools)
+	if err != nil {
+		return nil, err
+	}
+	return &turnStream{MessageStream: stream, client: c}, nil
+}
+
+// route returns the candidate a request goes to, and why. c.mu must be held.
+func (c *Client) route(messages []chat.Message, availableTools []tools.Tool) (int, string) {
+	strongest := len(c.candidates) - 1
+	if c.maxTools > 0 && len(availableTools) > c.maxTools {
+		return strongest, ReasonTools
+	}
+
+	index, reason := c.level, c.reason
+	if index == 0 && c.maxPromptTokens > 0 && estimatePromptTokens(messages) > c.maxPromptTokens {
+		index, reason = 1, ReasonPromptTokens
+	}
+	return min(index, strongest), reason
+}
+
+// estimatePromptTokens returns a rough estimate of the tokens of messages.
+func estimatePromptTokens(messages []chat.Message) int64 {
+	var tokens int64
+	for i := range messages {
+		tokens += compaction.EstimateMessageTokens(&messages[i])
+	}
+	return tokens
+}
+
+// Escalate makes the next requests of the turn go to the candidate after the
+// one of the most recent request. It returns false when that was already the
+// strongest candidate.
+func (c *Client) Escalate(reason string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.lastIndex+1 >= len(c.candidates) {
+		return false
+	}
+	c.level = c.lastIndex + 1
+	c.reason = reason
+	slog.Debug("Escalating router escalated the turn", "router", c.ID(), "model", c.candidates[c.level].ID(), "reason", reason)
+	return true
+}
+
+// endTurn goes back to the first candidate once a model answered without
+// calling tools.
+func (c *Client) endTurn() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.level = 0
+	c.reason = ""
+}
+
+// LastDecision returns the routing of the most recent request.
+func (c *Client) LastDecision() Decision {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.last
+}
+
+// LastSelectedModelID returns the ID of the candidate of the most recent
+// request, so that the turn is displayed and priced with that model.
+func (c *Client) LastSelectedModelID() string {
+	return c.LastDecision().ModelID
+}
+
+// BaseConfig returns the base configuration.
+func (c *Client) BaseConfig() base.Config {
+	return c.Config
+}
+
+// turnStream ends the turn of its client when the model answers without
+// calling tools.
+type turnStream struct {
+	chat.MessageStream
+
+	client       *Client
+	hasToolCalls bool
+}
+
+func (s *turnStream) Recv() (chat.MessageStreamResponse, error) {
+	response, err := s.MessageStream.Recv()
+	for _, choice := range response.Choices {
+		if len(choice.Delta.ToolCalls) > 0 || choice.Delta.FunctionCall != nil {
+			s.hasToolCalls = true
+		}
+	}
+	if errors.Is(err, io.EOF) && !s.hasToolCalls {
+		s.client.endTurn()
+	}
+	return response, err
+}
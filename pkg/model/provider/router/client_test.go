@@ -0,0 +1,179 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/config/latest"
+	"github.com/docker/docker-agent/pkg/environment"
+	"github.com/docker/docker-agent/pkg/model/provider/fake"
+	"github.com/docker/docker-agent/pkg/model/provider/options"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// newTestClient creates a router over scripted candidates.
+func newTestClient(t *testing.T, routerCfg latest.RouterConfig, candidates ...*fake.ScriptedProvider) *Client {
+	t.Helper()
+
+	byID := map[string]Provider{}
+	for _, candidate := range candidates {
+		byID[candidate.ID()] = candidate
+		routerCfg.Candidates = append(routerCfg.Candidates, candidate.ID())
+	}
+	factory := func(_ context.Context, modelSpec string, _ map[string]latest.ModelConfig, _ environment.Provider, _ ...options.Opt) (Provider, error) {
+		return byID[modelSpec], nil
+	}
+
+	cfg := &latest.ModelConfig{Name: "auto", Provider: ProviderType, Router: &routerCfg}
+	client, err := NewClient(t.Context(), cfg, nil, nil, factory)
+	require.NoError(t, err)
+	return client
+}
+
+// request sends a request to the router and reads the whole response.
+func request(t *testing.T, c *Client, messages []chat.Message, availableTools []tools.Tool) Decision {
+	t.Helper()
+
+	stream, err := c.CreateChatCompletionStream(t.Context(), messages, availableTools)
+	require.NoError(t, err)
+	defer stream.Close()
+	for {
+		_, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+	}
+	return c.LastDecision()
+}
+
+var hello = []chat.Message{{Role: chat.MessageRoleUser, Content: "Hello"}}
+
+func TestNewClient(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t, latest.RouterConfig{},
+		fake.NewScriptedProvider(t, "test/cheap"),
+		fake.NewScriptedProvider(t, "test/strong"),
+	)
+	assert.Equal(t, "router/auto", client.ID())
+
+	_, err := NewClient(t.Context(), &latest.ModelConfig{Provider: ProviderType}, nil, nil, nil)
+	require.ErrorContains(t, err, "no router candidates configured")
+
+	failing := func(context.Context, string, map[string]latest.ModelConfig, environment.Provider, ...options.Opt) (Provider, error) {
+		return nil, errors.New("unknown provider")
+	}
+	cfg := &latest.ModelConfig{Provider: ProviderType, Router: &latest.RouterConfig{Candidates: []string{"nope/cheap", "nope/strong"}}}
+	_, err = NewClient(t.Context(), cfg, nil, nil, failing)
+	require.ErrorContains(t, err, `creating router candidate "nope/cheap": unknown provider`)
+}
+
+func TestRouter_CheapModelFirst(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t, latest.RouterConfig{MaxPromptTokens: 1000, MaxTools: 5},
+		fake.NewScriptedProvider(t, "test/cheap", fake.NewTurn().Content("Hi!")),
+		fake.NewScriptedProvider(t, "test/strong"),
+	)
+
+	decision := request(t, client, hello, nil)
+	assert.Equal(t, Decision{ModelID: "test/cheap"}, decision)
+	assert.Equal(t, "test/cheap", client.LastSelectedModelID())
+}
+
+func TestRouter_PromptTokens(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t, latest.RouterConfig{MaxPromptTokens: 100},
+		fake.NewScriptedProvider(t, "test/cheap", fake.NewTurn().Content("Short")),
+		fake.NewScriptedProvider(t, "test/medium", fake.NewTurn().Content("Long")),
+		fake.NewScriptedProvider(t, "test/strong"),
+	)
+
+	assert.Equal(t, Decision{ModelID: "test/cheap"}, request(t, client, hello, nil))
+
+	long := []chat.Message{{Role: chat.MessageRoleUser, Content: strings.Repeat("word ", 200)}}
+	assert.Equal(t, Decision{ModelID: "test/medium", Reason: ReasonPromptTokens}, request(t, client, long, nil))
+}
+
+func TestRouter_Tools(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t, latest.RouterConfig{MaxTools: 1},
+		fake.NewScriptedProvider(t, "test/cheap", fake.NewTurn().Content("One tool")),
+		fake.NewScriptedProvider(t, "test/medium"),
+		fake.NewScriptedProvider(t, "test/strong", fake.NewTurn().Content("Two tools")),
+	)
+
+	oneTool := []tools.Tool{{Name: "read_file"}}
+	assert.Equal(t, Decision{ModelID: "test/cheap"}, request(t, client, hello, oneTool))
+
+	twoTools := []tools.Tool{{Name: "read_file"}, {Name: "write_file"}}
+	assert.Equal(t, Decision{ModelID: "test/strong", Reason: ReasonTools}, request(t, client, hello, twoTools))
+}
+
+func TestRouter_EscalationLastsUntilEndOfTurn(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t, latest.RouterConfig{},
+		fake.NewScriptedProvider(t, "test/cheap",
+			fake.NewTurn().ToolCall("call_1", "read_file", `{"path":`),
+			fake.NewTurn().Content("Next turn"),
+		),
+		fake.NewScriptedProvider(t, "test/strong",
+			fake.NewTurn().ToolCall("call_2", "read_file", `{"path":"main.go"}`),
+			fake.NewTurn().Content("Done"),
+		),
+	)
+
+	assert.Equal(t, Decision{ModelID: "test/cheap"}, request(t, client, hello, nil))
+	require.True(t, client.Escalate(ReasonMalformedToolArgument))
+
+	// The escalation lasts while the model calls tools...
+	escalated := Decision{ModelID: "test/strong", Reason: ReasonMalformedToolArgument}
+	assert.Equal(t, escalated, request(t, client, hello, nil))
+	assert.Equal(t, escalated, request(t, client, hello, nil))
+
+	// ...and the next turn starts with the cheap model again.
+	assert.Equal(t, Decision{ModelID: "test/cheap"}, request(t, client, hello, nil))
+}
+
+func TestRouter_EscalateAfterFinalAnswer(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t, latest.RouterConfig{},
+		fake.NewScriptedProvider(t, "test/cheap",
+			fake.NewTurn().Content("Rejected answer"),
+			fake.NewTurn().Content("Next turn"),
+		),
+		fake.NewScriptedProvider(t, "test/strong", fake.NewTurn().Content("Better answer")),
+	)
+
+	// An output guard rejects the final answer of the cheap model.
+	assert.Equal(t, Decision{ModelID: "test/cheap"}, request(t, client, hello, nil))
+	require.True(t, client.Escalate(ReasonOutputGuard))
+	assert.Equal(t, Decision{ModelID: "test/strong", Reason: ReasonOutputGuard}, request(t, client, hello, nil))
+
+	// The next turn starts with the cheap model again.
+	assert.Equal(t, Decision{ModelID: "test/cheap"}, request(t, client, hello, nil))
+}
+
+func TestRouter_EscalateFromStrongest(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t, latest.RouterConfig{MaxTools: 1},
+		fake.NewScriptedProvider(t, "test/cheap"),
+		fake.NewScriptedProvider(t, "test/strong", fake.NewTurn().Content("Two tools")),
+	)
+
+	request(t, client, hello, []tools.Tool{{Name: "read_file"}, {Name: "write_file"}})
+	assert.False(t, client.Escalate(ReasonOutputGuard))
+}
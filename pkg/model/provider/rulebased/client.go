@@ -73,7 +73,8 @@ func NewClient(ctx context.Context, cfg *latest.ModelConfig, models map[string]l
 		}
 	}()
 
-	routeOpts := filterOutMaxTokens(opts)
+	// Child providers may have different token limits than the parent router.
+	routeOpts := options.WithoutMaxTokens(opts)
 
 	// Create fallback provider from the model's provider/model fields.
 	fallbackSpec := cfg.Provider + "/" + cfg.Model
@@ -137,24 +138,6 @@ func createIndex() (bleve.Index, error) {
 	return bleve.NewMemOnly(indexMapping)
 }
 
-// filterOutMaxTokens removes WithMaxTokens options from the slice.
-// Child providers may have different token limits than the parent router.
-func filterOutMaxTokens(opts []options.Opt) []options.Opt {
-	var filtered []options.Opt
-	for _, opt := range opts {
-		if opt == nil {
-			continue
-		}
-		var probe options.ModelOptions
-		opt(&probe)
-		if probe.MaxTokens() != 0 {
-			continue
-		}
-		filtered = append(filtered, opt)
-	}
-	return filtered
-}
-
 // CreateChatCompletionStream selects a provider based on input and delegates the call.
 // The selected provider's ID is recorded in LastSelectedModelID.
 func (c *Client) CreateChatCompletionStream(
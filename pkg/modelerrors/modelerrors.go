@@ -182,6 +182,17 @@ func extractHTTPStatusCode(err error) int {
 	return 0
 }
 
+// IsAuthenticationError reports whether the provider rejected the request
+// because of missing or invalid credentials (HTTP 401 or 403).
+func IsAuthenticationError(err error) bool {
+	switch extractHTTPStatusCode(err) {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return true
+	default:
+		return false
+	}
+}
+
 // isRetryableStatusCode determines if an HTTP status code is retryable.
 // Retryable means we should retry the SAME model with exponential backoff.
 //
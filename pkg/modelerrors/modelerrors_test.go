@@ -157,6 +157,31 @@ func TestIsContextOverflowError(t *testing.T) {
 	}
 }
 
+func TestIsAuthenticationError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "nil error", err: nil, expected: false},
+		{name: "generic error", err: errors.New("something went wrong"), expected: false},
+		{name: "status error 401", err: &StatusError{StatusCode: 401, Err: errors.New("invalid x-api-key")}, expected: true},
+		{name: "status error 403", err: &StatusError{StatusCode: 403, Err: errors.New("forbidden")}, expected: true},
+		{name: "wrapped status error", err: fmt.Errorf("all models failed: %w", &StatusError{StatusCode: 401, Err: errors.New("unauthorized")}), expected: true},
+		{name: "401 in message", err: errors.New(`POST "/v1/chat/completions": 401 Unauthorized`), expected: true},
+		{name: "429 rate limit", err: errors.New("429 too many requests"), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, IsAuthenticationError(tt.err), "IsAuthenticationError(%v)", tt.err)
+		})
+	}
+}
+
 func TestContextOverflowError(t *testing.T) {
 	t.Parallel()
 
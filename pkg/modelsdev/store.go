@@ -21,6 +21,9 @@ const (
 	ModelsDevAPIURL = "https://models.dev/api.json"
 	CacheFileName   = "models_dev.json"
 	refreshInterval = 24 * time.Hour
+	// refreshRetryInterval is how long to wait before refreshing the data
+	// again after a failed refresh.
+	refreshRetryInterval = time.Hour
 )
 
 // Store manages access to the models.dev data.
@@ -29,32 +32,41 @@ const (
 // Use NewStore to obtain the process-wide singleton instance.
 // The database is loaded on first access via GetDatabase and
 // shared across all callers, avoiding redundant disk/network I/O.
+// Once loaded, it is refreshed in the background when it gets stale.
 type Store struct {
 	cacheFile string
+	transport http.RoundTripper
 	mu        sync.Mutex
 	db        *Database
+	etag      string
+	// nextRefresh is when the database gets stale.
+	nextRefresh time.Time
+	refreshing  bool
 }
 
 // NewStore returns the process-wide singleton Store.
 //
 // The database is loaded lazily on the first call to GetDatabase and
 // then cached in memory so that every caller shares one copy.
-// The first call creates the cache directory if it does not exist.
+// NewStore itself does no I/O: it's cheap enough to call for every request.
 var NewStore = sync.OnceValues(func() (*Store, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user home directory: %w", err)
 	}
 
-	cacheDir := filepath.Join(homeDir, ".cagent")
-	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
-		return nil, fmt.Errorf("failed to create cache directory: %w", err)
-	}
+	return NewFileStore(filepath.Join(homeDir, ".cagent", CacheFileName), nil), nil
+})
 
+// NewFileStore creates a Store that caches the database in cacheFile and
+// fetches it with transport, or with the default transport if nil.
+// Like NewStore, it does no I/O until GetDatabase is called.
+func NewFileStore(cacheFile string, transport http.RoundTripper) *Store {
 	return &Store{
-		cacheFile: filepath.Join(cacheDir, CacheFileName),
-	}, nil
-})
+		cacheFile: cacheFile,
+		transport: transport,
+	}
+}
 
 // NewDatabaseStore creates a Store pre-populated with the given database.
 // The returned store serves data entirely from memory and never fetches
@@ -65,21 +77,90 @@ func NewDatabaseStore(db *Database) *Store {
 }
 
 // GetDatabase returns the models.dev database, fetching from cache or API as needed.
+//
+// A stale database is returned as is while a fresher one is fetched in the
+// background, so that only the very first call, without a cache, waits for
+// the API.
 func (s *Store) GetDatabase(ctx context.Context) (*Database, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if s.db != nil {
-		return s.db, nil
+	if s.db == nil {
+		if err := s.load(ctx); err != nil {
+			return nil, err
+		}
 	}
 
-	db, err := loadDatabase(ctx, s.cacheFile)
+	// Stores created from a database are never refreshed.
+	if s.cacheFile != "" && !s.refreshing && !time.Now().Before(s.nextRefresh) {
+		s.refreshing = true
+		go s.refresh(context.WithoutCancel(ctx))
+	}
+
+	return s.db, nil
+}
+
+// load loads the database from the local cache file, whatever its age, or
+// falls back to fetching from the models.dev API.
+// s.mu must be held.
+func (s *Store) load(ctx context.Context) error {
+	cached, err := loadFromCache(s.cacheFile)
+	if err == nil {
+		s.db = &cached.Database
+		s.etag = cached.ETag
+		s.nextRefresh = cached.LastRefresh.Add(refreshInterval)
+		return nil
+	}
+
+	database, etag, err := fetchFromAPI(ctx, s.transport, "")
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to fetch from API and no cached data available: %w", err)
 	}
 
-	s.db = db
-	return db, nil
+	if saveErr := saveToCache(s.cacheFile, database, etag); saveErr != nil {
+		slog.Warn("Failed to save to cache", "error", saveErr)
+	}
+
+	s.db = database
+	s.etag = etag
+	s.nextRefresh = time.Now().Add(refreshInterval)
+	return nil
+}
+
+// refresh does a conditional fetch of the database with its ETag, and
+// replaces it if it changed. On failure, the current database is kept and
+// the fetch is retried after refreshRetryInterval.
+func (s *Store) refresh(ctx context.Context) {
+	s.mu.Lock()
+	current, etag := s.db, s.etag
+	s.mu.Unlock()
+
+	database, newETag, err := fetchFromAPI(ctx, s.transport, etag)
+	nextRefresh := time.Now().Add(refreshInterval)
+	switch {
+	case err != nil:
+		slog.Debug("API fetch failed, keeping stale data", "error", err)
+		nextRefresh = time.Now().Add(refreshRetryInterval)
+	case database == nil:
+		// Not modified: bump LastRefresh so we don't re-check until the next interval.
+		if saveErr := saveToCache(s.cacheFile, current, etag); saveErr != nil {
+			slog.Warn("Failed to update cache timestamp", "error", saveErr)
+		}
+	default:
+		if saveErr := saveToCache(s.cacheFile, database, newETag); saveErr != nil {
+			slog.Warn("Failed to save to cache", "error", saveErr)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if database != nil {
+		s.db = database
+		s.etag = newETag
+	}
+	s.nextRefresh = nextRefresh
+	s.refreshing = false
 }
 
 // getProvider returns a specific provider by ID.
@@ -105,6 +186,9 @@ func (s *Store) GetModel(ctx context.Context, id string) (*Model, error) {
 	}
 	providerID := parts[0]
 	modelID := parts[1]
+	if id, ok := providerIDs[providerID]; ok {
+		providerID = id
+	}
 
 	provider, err := s.getProvider(ctx, providerID)
 	if err != nil {
@@ -116,6 +200,13 @@ func (s *Store) GetModel(ctx context.Context, id string) (*Model, error) {
 	// For amazon-bedrock, try stripping region/inference profile prefixes.
 	// Bedrock uses prefixes for cross-region inference profiles,
 	// but models.dev stores models without these prefixes.
+	if !exists && providerID == "amazon-bedrock" {
+		// Inference profile ARNs end with the ID of the profile.
+		if strings.HasPrefix(modelID, "arn:") {
+			modelID = modelID[strings.LastIndex(modelID, "/")+1:]
+			model, exists = provider.Models[modelID]
+		}
+	}
 	if !exists && providerID == "amazon-bedrock" {
 		if prefix, after, ok := strings.Cut(modelID, "."); ok && bedrockRegionPrefixes[prefix] {
 			model, exists = provider.Models[after]
@@ -129,53 +220,11 @@ func (s *Store) GetModel(ctx context.Context, id string) (*Model, error) {
 	return &model, nil
 }
 
-// loadDatabase loads the database from the local cache file or
-// falls back to fetching from the models.dev API.
-func loadDatabase(ctx context.Context, cacheFile string) (*Database, error) {
-	// Try to load from cache first
-	cached, err := loadFromCache(cacheFile)
-	if err == nil && time.Since(cached.LastRefresh) < refreshInterval {
-		return &cached.Database, nil
-	}
-
-	// Cache is stale or doesn't exist — try a conditional fetch with the ETag.
-	var etag string
-	if cached != nil {
-		etag = cached.ETag
-	}
-
-	database, newETag, fetchErr := fetchFromAPI(ctx, etag)
-	if fetchErr != nil {
-		// If API fetch fails but we have cached data, use it regardless of age.
-		if cached != nil {
-			slog.Debug("API fetch failed, using stale cache", "error", fetchErr)
-			return &cached.Database, nil
-		}
-		return nil, fmt.Errorf("failed to fetch from API and no cached data available: %w", fetchErr)
-	}
-
-	// database is nil when the server returned 304 Not Modified.
-	if database == nil && cached != nil {
-		// Bump LastRefresh so we don't re-check until the next interval.
-		cached.LastRefresh = time.Now()
-		if saveErr := saveToCache(cacheFile, &cached.Database, cached.ETag); saveErr != nil {
-			slog.Warn("Failed to update cache timestamp", "error", saveErr)
-		}
-		return &cached.Database, nil
-	}
-
-	// Save the fresh data to cache.
-	if saveErr := saveToCache(cacheFile, database, newETag); saveErr != nil {
-		slog.Warn("Failed to save to cache", "error", saveErr)
-	}
-
-	return database, nil
-}
-
 // fetchFromAPI fetches the models.dev database.
 // If etag is non-empty it is sent as If-None-Match; a 304 response
 // returns (nil, etag, nil) to indicate no change.
-func fetchFromAPI(ctx context.Context, etag string) (*Database, string, error) {
+// A nil transport uses the default one.
+func fetchFromAPI(ctx context.Context, transport http.RoundTripper, etag string) (*Database, string, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ModelsDevAPIURL, http.NoBody)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to create request: %w", err)
@@ -185,7 +234,10 @@ func fetchFromAPI(ctx context.Context, etag string) (*Database, string, error) {
 		req.Header.Set("If-None-Match", etag)
 	}
 
-	resp, err := (&http.Client{Timeout: 30 * time.Second, Transport: remote.NewTransport(ctx)}).Do(req)
+	if transport == nil {
+		transport = remote.NewTransport(ctx)
+	}
+	resp, err := (&http.Client{Timeout: 30 * time.Second, Transport: transport}).Do(req)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to fetch from API: %w", err)
 	}
@@ -245,6 +297,10 @@ func saveToCache(cacheFile string, database *Database, etag string) error {
 		return fmt.Errorf("failed to marshal cached data: %w", err)
 	}
 
+	if err := os.MkdirAll(filepath.Dir(cacheFile), 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
 	if err := os.WriteFile(cacheFile, data, 0o644); err != nil {
 		return fmt.Errorf("failed to write cache file: %w", err)
 	}
@@ -296,6 +352,12 @@ func (s *Store) ResolveModelAlias(ctx context.Context, providerID, modelName str
 	return modelName
 }
 
+// providerIDs maps the providers to the ID models.dev lists their models
+// under, when it differs.
+var providerIDs = map[string]string{
+	"azure-openai": "azure",
+}
+
 // bedrockRegionPrefixes contains known regional/inference profile prefixes used in Bedrock model IDs.
 // These prefixes should be stripped when looking up models in the database since models.dev
 // stores models without regional prefixes. AWS uses these for cross-region inference profiles.
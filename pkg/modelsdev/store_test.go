@@ -1,9 +1,12 @@
 package modelsdev
 
 import (
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestResolveModelAlias(t *testing.T) {
@@ -84,3 +87,61 @@ func TestDatePattern(t *testing.T) {
 		})
 	}
 }
+
+func TestGetModel(t *testing.T) {
+	t.Parallel()
+
+	store := NewDatabaseStore(&Database{
+		Providers: map[string]Provider{
+			"amazon-bedrock": {
+				Models: map[string]Model{
+					"anthropic.claude-sonnet-4-5-20250929-v1:0": {Name: "Claude Sonnet 4.5"},
+				},
+			},
+			"azure": {
+				Models: map[string]Model{
+					"gpt-4o": {Name: "GPT-4o"},
+				},
+			},
+		},
+	})
+
+	tests := []struct {
+		name string
+		id   string
+		want string
+	}{
+		{"bedrock model", "amazon-bedrock/anthropic.claude-sonnet-4-5-20250929-v1:0", "Claude Sonnet 4.5"},
+		{"bedrock inference profile", "amazon-bedrock/global.anthropic.claude-sonnet-4-5-20250929-v1:0", "Claude Sonnet 4.5"},
+		{"bedrock inference profile ARN", "amazon-bedrock/arn:aws:bedrock:us-east-1:123456789012:inference-profile/us.anthropic.claude-sonnet-4-5-20250929-v1:0", "Claude Sonnet 4.5"},
+		{"azure openai", "azure-openai/gpt-4o", "GPT-4o"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model, err := store.GetModel(t.Context(), tt.id)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, model.Name)
+		})
+	}
+
+	_, err := store.GetModel(t.Context(), "azure-openai/gpt-5")
+	assert.Error(t, err)
+}
+
+func TestGetDatabase_FreshCache(t *testing.T) {
+	t.Parallel()
+
+	cacheFile := filepath.Join(t.TempDir(), "cache", CacheFileName)
+	db := &Database{Providers: map[string]Provider{"openai": {Models: map[string]Model{"gpt-4o": {Name: "GPT-4o"}}}}}
+	require.NoError(t, saveToCache(cacheFile, db, `"etag"`))
+
+	// A fresh cache is served without fetching or refreshing the data.
+	store := &Store{cacheFile: cacheFile}
+	got, err := store.GetDatabase(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, db, got)
+	assert.Equal(t, `"etag"`, store.etag)
+	assert.False(t, store.refreshing)
+	assert.True(t, store.nextRefresh.After(time.Now()))
+}
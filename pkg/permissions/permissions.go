@@ -41,6 +41,10 @@ func (d Decision) String() string {
 	}
 }
 
+// categoryPrefix marks the patterns that match the category of the tools
+// rather than their names, e.g. "category:filesystem".
+const categoryPrefix = "category:"
+
 // Checker evaluates tool permissions based on configured patterns
 type Checker struct {
 	allowPatterns []string
@@ -67,7 +71,14 @@ func (c *Checker) Check(toolName string) Decision {
 	return c.CheckWithArgs(toolName, nil)
 }
 
-// CheckWithArgs evaluates the permission for a given tool name and its arguments.
+// CheckWithArgs evaluates the permission for a given tool name and its
+// arguments, for a tool without category. See CheckTool.
+func (c *Checker) CheckWithArgs(toolName string, args map[string]any) Decision {
+	return c.CheckTool(toolName, "", args)
+}
+
+// CheckTool evaluates the permission for a given tool, of the given
+// category, and its arguments.
 // Evaluation order: Deny (checked first), then Allow, then Ask (explicit), then Ask (default).
 //
 // The toolName can be a simple name like "shell" or a qualified name like
@@ -77,24 +88,25 @@ func (c *Checker) Check(toolName string) Decision {
 // - Simple tool names: "shell", "read_*"
 // - Argument matching: "shell:cmd=ls*" matches shell tool with cmd argument starting with "ls"
 // - Multiple arguments: "shell:cmd=ls*:cwd=/home/*" matches both conditions
+// - Tool categories: "category:lsp" matches all the tools of the lsp category
 // - Glob patterns in both tool names and argument values
 //
 // Returns ForceAsk when an explicit ask pattern matches. ForceAsk means the
 // tool must always be confirmed, even when it would normally be auto-approved
 // (e.g. read-only tools). Note that --yolo mode takes precedence over ForceAsk.
-func (c *Checker) CheckWithArgs(toolName string, args map[string]any) Decision {
+func (c *Checker) CheckTool(toolName, category string, args map[string]any) Decision {
 	// Deny patterns are checked first - they take priority
-	if matchAny(c.denyPatterns, toolName, args) {
+	if matchAny(c.denyPatterns, toolName, category, args) {
 		return Deny
 	}
 
 	// Allow patterns are checked second
-	if matchAny(c.allowPatterns, toolName, args) {
+	if matchAny(c.allowPatterns, toolName, category, args) {
 		return Allow
 	}
 
 	// Explicit ask patterns override auto-approval (e.g. read-only hints)
-	if matchAny(c.askPatterns, toolName, args) {
+	if matchAny(c.askPatterns, toolName, category, args) {
 		return ForceAsk
 	}
 
@@ -102,10 +114,11 @@ func (c *Checker) CheckWithArgs(toolName string, args map[string]any) Decision {
 	return Ask
 }
 
-// matchAny reports whether any pattern in the list matches the tool name and args.
-func matchAny(patterns []string, toolName string, args map[string]any) bool {
+// matchAny reports whether any pattern in the list matches the tool name,
+// category and args.
+func matchAny(patterns []string, toolName, category string, args map[string]any) bool {
 	for _, pattern := range patterns {
-		if matchToolPattern(pattern, toolName, args) {
+		if matchToolPattern(pattern, toolName, category, args) {
 			return true
 		}
 	}
@@ -182,11 +195,16 @@ func parsePattern(pattern string) (toolPattern string, argPatterns map[string]st
 // The pattern can be:
 // - Simple: "shell" - matches tool name only
 // - With args: "shell:cmd=ls*" - matches tool name AND argument value
-func matchToolPattern(pattern, toolName string, args map[string]any) bool {
+// - Category: "category:lsp" - matches the tool's category instead of its name
+func matchToolPattern(pattern, toolName, category string, args map[string]any) bool {
 	toolPattern, argPatterns := parsePattern(pattern)
 
-	// First check if the tool name matches
-	if !matchGlob(toolPattern, toolName) {
+	// First check if the tool name, or category, matches
+	if categoryPattern, ok := strings.CutPrefix(toolPattern, categoryPrefix); ok {
+		if category == "" || !matchGlob(categoryPattern, category) {
+			return false
+		}
+	} else if !matchGlob(toolPattern, toolName) {
 		return false
 	}
 
@@ -459,6 +459,25 @@ func TestChecker_ForceAsk(t *testing.T) {
 	}
 }
 
+func TestChecker_CheckTool(t *testing.T) {
+	t.Parallel()
+
+	checker := NewChecker(&latest.PermissionsConfig{
+		Allow: []string{"category:lsp"},
+		Ask:   []string{"category:filesystem"},
+		Deny:  []string{"category:filesystem:path=/etc/*", "category"},
+	})
+
+	assert.Equal(t, Allow, checker.CheckTool("lsp_hover", "lsp", nil))
+	assert.Equal(t, ForceAsk, checker.CheckTool("read_file", "filesystem", map[string]any{"path": "README.md"}))
+	assert.Equal(t, Deny, checker.CheckTool("read_file", "filesystem", map[string]any{"path": "/etc/passwd"}))
+	assert.Equal(t, Ask, checker.CheckTool("shell", "shell", nil))
+	// Category patterns never match tools without a category.
+	assert.Equal(t, Ask, checker.CheckTool("lsp_hover", "", nil))
+	// A tool may still be named like a category.
+	assert.Equal(t, Deny, checker.CheckTool("category", "", nil))
+}
+
 func TestParsePattern(t *testing.T) {
 	t.Parallel()
 
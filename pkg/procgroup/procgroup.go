@@ -0,0 +1,29 @@
+// Package procgroup runs commands in their own process group so that they
+// can be terminated along with the processes they spawn: a language server
+// started through a wrapper script, an MCP server started with npx, or a
+// shell command that backgrounds a child would otherwise survive the
+// termination of the direct child as orphans.
+package procgroup
+
+import (
+	"os/exec"
+	"time"
+)
+
+// GracePeriod is how long a process group has to exit after being asked to
+// terminate before it is killed.
+var GracePeriod = 3 * time.Second
+
+// Setup makes cmd start in its own process group and, when the context of
+// cmd is done, terminate the whole group instead of the direct child only.
+// Wait returns at most twice the GracePeriod after the context is done, even
+// when a descendant keeps the output pipes open.
+//
+// Setup must be called before cmd is started.
+func Setup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = SysProcAttr()
+	cmd.Cancel = func() error {
+		return Terminate(cmd.Process)
+	}
+	cmd.WaitDelay = 2 * GracePeriod
+}
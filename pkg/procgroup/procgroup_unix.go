@@ -0,0 +1,58 @@
+//go:build !windows
+
+package procgroup
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"time"
+)
+
+// pollInterval is how often Terminate checks whether the group has exited.
+const pollInterval = 50 * time.Millisecond
+
+// SysProcAttr returns the attributes that start a process as the leader of
+// a new process group.
+func SysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{
+		Setpgid: true,
+	}
+}
+
+// Terminate sends SIGTERM to the process group led by proc and, if any
+// process of the group is still running after the GracePeriod, SIGKILL. It
+// doesn't wait for the group to exit.
+func Terminate(proc *os.Process) error {
+	pgid := proc.Pid
+	if err := syscall.Kill(-pgid, syscall.SIGTERM); err != nil {
+		if errors.Is(err, syscall.ESRCH) {
+			return os.ErrProcessDone
+		}
+		return err
+	}
+
+	go func() {
+		deadline := time.Now().Add(GracePeriod)
+		for time.Now().Before(deadline) {
+			time.Sleep(pollInterval)
+			if syscall.Kill(-pgid, 0) != nil {
+				return
+			}
+		}
+		_ = syscall.Kill(-pgid, syscall.SIGKILL)
+	}()
+
+	return nil
+}
+
+// Kill sends SIGKILL to the process group led by proc.
+func Kill(proc *os.Process) error {
+	if err := syscall.Kill(-proc.Pid, syscall.SIGKILL); err != nil {
+		if errors.Is(err, syscall.ESRCH) {
+			return os.ErrProcessDone
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+//go:build !windows
+
+package procgroup
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetup_TerminatesGrandchildren(t *testing.T) {
+	gracePeriod := GracePeriod
+	GracePeriod = 200 * time.Millisecond
+	t.Cleanup(func() { GracePeriod = gracePeriod })
+
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{
+			name:   "exits on SIGTERM",
+			script: `sleep 60 & echo $!; wait`,
+		},
+		{
+			// The shell and its child ignore SIGTERM: they are killed
+			// once the grace period is over.
+			name:   "ignores SIGTERM",
+			script: `trap "" TERM; sleep 60 & echo $!; wait`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+
+			cmd := exec.CommandContext(ctx, "sh", "-c", tt.script)
+			Setup(cmd)
+			stdout, err := cmd.StdoutPipe()
+			require.NoError(t, err)
+			require.NoError(t, cmd.Start())
+
+			line, err := bufio.NewReader(stdout).ReadString('\n')
+			require.NoError(t, err)
+			grandchild, err := strconv.Atoi(strings.TrimSpace(line))
+			require.NoError(t, err)
+			require.True(t, alive(grandchild))
+
+			cancel()
+			start := time.Now()
+			require.Error(t, cmd.Wait())
+			assert.Less(t, time.Since(start), 2*GracePeriod+time.Second)
+
+			assert.False(t, alive(cmd.Process.Pid))
+			assert.Eventually(t, func() bool {
+				return !alive(grandchild)
+			}, 5*time.Second, 20*time.Millisecond)
+		})
+	}
+}
+
+func TestKill_ExitedGroup(t *testing.T) {
+	cmd := exec.Command("true")
+	cmd.SysProcAttr = SysProcAttr()
+	require.NoError(t, cmd.Run())
+
+	assert.ErrorIs(t, Kill(cmd.Process), os.ErrProcessDone)
+	assert.ErrorIs(t, Terminate(cmd.Process), os.ErrProcessDone)
+}
+
+// alive tells whether the process is running. Zombies, which are left when
+// the process reaping the orphans doesn't, aren't running.
+func alive(pid int) bool {
+	if syscall.Kill(pid, 0) != nil {
+		return false
+	}
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return true
+	}
+	_, state, _ := strings.Cut(string(stat), ") ")
+	return !strings.HasPrefix(state, "Z")
+}
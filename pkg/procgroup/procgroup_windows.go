@@ -0,0 +1,38 @@
+package procgroup
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// SysProcAttr returns the attributes that start a process in a new process
+// group.
+func SysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{
+		CreationFlags: windows.CREATE_NEW_PROCESS_GROUP,
+	}
+}
+
+// Terminate kills the process tree of proc. Windows has no equivalent of
+// SIGTERM for console processes, so this is the same as Kill.
+func Terminate(proc *os.Process) error {
+	return Kill(proc)
+}
+
+// Kill kills the process tree of proc.
+func Kill(proc *os.Process) error {
+	// taskkill walks the descendants of the process, which aren't tracked
+	// by the process group on Windows. It isn't resolved through PATH.
+	if root := os.Getenv("SystemRoot"); root != "" {
+		taskkill := filepath.Join(root, "System32", "taskkill.exe")
+		if err := exec.Command(taskkill, "/T", "/F", "/PID", strconv.Itoa(proc.Pid)).Run(); err == nil {
+			return nil
+		}
+	}
+	return proc.Kill()
+}
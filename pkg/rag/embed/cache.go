@@ -0,0 +1,194 @@
+package embed
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/docker/docker-agent/pkg/rag/types"
+	"github.com/docker/docker-agent/pkg/sqliteutil"
+)
+
+var (
+	_ Embeddings    = (*Cache)(nil)
+	_ UsageReporter = (*Cache)(nil)
+)
+
+// Cache is an Embeddings keeping the embeddings of the texts of EmbedBatch
+// in a SQLite database, keyed by the model and the hash of the text, so
+// that the same text is embedded only once by a model, across runs.
+// Single texts, e.g. search queries, aren't cached.
+type Cache struct {
+	embeddings Embeddings
+	model      string
+	db         *sql.DB
+
+	statsMu sync.Mutex
+	stats   types.CacheStats
+}
+
+// NewCache returns a Cache of the embeddings generated by embeddings with
+// the given model, stored in the database at path, created if needed.
+func NewCache(embeddings Embeddings, model, path string) (*Cache, error) {
+	db, err := sqliteutil.OpenDB(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open embeddings cache: %w", err)
+	}
+
+	if _, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS embeddings (
+		model TEXT NOT NULL,
+		content_hash TEXT NOT NULL,
+		embedding BLOB NOT NULL,
+		PRIMARY KEY (model, content_hash)
+	);`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create embeddings cache schema: %w", err)
+	}
+
+	return &Cache{
+		embeddings: embeddings,
+		model:      model,
+		db:         db,
+	}, nil
+}
+
+// SetUsageHandler sets the usage handler of the cached embeddings, if they
+// report their usage. Only the texts missing from the cache are accounted.
+func (c *Cache) SetUsageHandler(handler func(tokens int64, cost float64)) {
+	if reporter, ok := c.embeddings.(UsageReporter); ok {
+		reporter.SetUsageHandler(handler)
+	}
+}
+
+// Embed returns the embedding of text, without caching it.
+func (c *Cache) Embed(ctx context.Context, text string) ([]float64, error) {
+	return c.embeddings.Embed(ctx, text)
+}
+
+// EmbedBatch returns the embeddings of texts, embedding only the ones that
+// aren't in the cache yet.
+func (c *Cache) EmbedBatch(ctx context.Context, texts []string) ([][]float64, error) {
+	embeddings := make([][]float64, len(texts))
+
+	var (
+		missing []string
+		misses  = make(map[string][]int) // Hash of the missing texts -> their indexes in texts
+		hashes  []string
+	)
+	for i, text := range texts {
+		hash := contentHash(text)
+		if indexes, ok := misses[hash]; ok {
+			misses[hash] = append(indexes, i)
+			continue
+		}
+
+		embedding, err := c.get(ctx, hash)
+		if err != nil {
+			slog.Warn("Failed to read embeddings cache", "model", c.model, "error", err)
+		}
+		if embedding != nil {
+			embeddings[i] = embedding
+			continue
+		}
+
+		missing = append(missing, text)
+		hashes = append(hashes, hash)
+		misses[hash] = []int{i}
+	}
+
+	c.statsMu.Lock()
+	c.stats.Hits += len(texts) - len(missing)
+	c.stats.Misses += len(missing)
+	c.statsMu.Unlock()
+
+	if len(missing) == 0 {
+		return embeddings, nil
+	}
+
+	computed, err := c.embeddings.EmbedBatch(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	if len(computed) != len(missing) {
+		return nil, fmt.Errorf("embedding count mismatch: got %d embeddings for %d texts", len(computed), len(missing))
+	}
+
+	for i, hash := range hashes {
+		for _, index := range misses[hash] {
+			embeddings[index] = computed[i]
+		}
+	}
+
+	if err := c.put(ctx, hashes, computed); err != nil {
+		slog.Warn("Failed to write embeddings cache", "model", c.model, "error", err)
+	}
+
+	return embeddings, nil
+}
+
+// Stats returns the number of texts of EmbedBatch found in the cache and
+// the number of texts that were embedded, since the cache was created.
+func (c *Cache) Stats() types.CacheStats {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+	return c.stats
+}
+
+// Close closes the database of the cache.
+func (c *Cache) Close() error {
+	return c.db.Close()
+}
+
+func (c *Cache) get(ctx context.Context, hash string) ([]float64, error) {
+	var data []byte
+	err := c.db.QueryRowContext(ctx,
+		`SELECT embedding FROM embeddings WHERE model = ? AND content_hash = ?`,
+		c.model, hash).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var embedding []float64
+	if err := json.Unmarshal(data, &embedding); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal embedding: %w", err)
+	}
+	return embedding, nil
+}
+
+func (c *Cache) put(ctx context.Context, hashes []string, embeddings [][]float64) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for i, hash := range hashes {
+		data, err := json.Marshal(embeddings[i])
+		if err != nil {
+			return fmt.Errorf("failed to marshal embedding: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO embeddings (model, content_hash, embedding) VALUES (?, ?, ?)
+			 ON CONFLICT(model, content_hash) DO UPDATE SET embedding = excluded.embedding`,
+			c.model, hash, data); err != nil {
+			return fmt.Errorf("failed to store embedding: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func contentHash(text string) string {
+	hash := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,77 @@
+package embed
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/rag/types"
+)
+
+// countingEmbeddings embeds a text as its length, counting the texts it's
+// asked to embed.
+type countingEmbeddings struct {
+	mu    sync.Mutex
+	texts []string
+}
+
+func (e *countingEmbeddings) Embed(_ context.Context, text string) ([]float64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.texts = append(e.texts, text)
+	return []float64{float64(len(text))}, nil
+}
+
+func (e *countingEmbeddings) EmbedBatch(ctx context.Context, texts []string) ([][]float64, error) {
+	embeddings := make([][]float64, len(texts))
+	for i, text := range texts {
+		embeddings[i], _ = e.Embed(ctx, text)
+	}
+	return embeddings, nil
+}
+
+func TestCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "embeddings.db")
+	embeddings := &countingEmbeddings{}
+
+	cache, err := NewCache(embeddings, "openai/small", path)
+	require.NoError(t, err)
+
+	got, err := cache.EmbedBatch(t.Context(), []string{"a", "bb", "a"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float64{{1}, {2}, {1}}, got)
+	assert.Equal(t, []string{"a", "bb"}, embeddings.texts, "the same text is embedded once")
+	assert.Equal(t, types.CacheStats{Hits: 1, Misses: 2}, cache.Stats())
+	require.NoError(t, cache.Close())
+
+	// The embeddings are persisted.
+	embeddings.texts = nil
+	cache, err = NewCache(embeddings, "openai/small", path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = cache.Close() })
+
+	got, err = cache.EmbedBatch(t.Context(), []string{"bb", "ccc", "a"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float64{{2}, {3}, {1}}, got)
+	assert.Equal(t, []string{"ccc"}, embeddings.texts)
+	assert.Equal(t, types.CacheStats{Hits: 2, Misses: 1}, cache.Stats())
+
+	// The queries aren't cached.
+	_, err = cache.Embed(t.Context(), "a")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ccc", "a"}, embeddings.texts)
+
+	// The embeddings of another model aren't reused.
+	embeddings.texts = nil
+	other, err := NewCache(embeddings, "openai/large", path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = other.Close() })
+
+	_, err = other.EmbedBatch(t.Context(), []string{"a"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, embeddings.texts)
+}
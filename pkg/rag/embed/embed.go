@@ -11,6 +11,28 @@ import (
 	"github.com/docker/docker-agent/pkg/model/provider"
 )
 
+// Embeddings generates vector embeddings for text, independently of the
+// model behind them. It's implemented by Embedder, on top of the model
+// providers supporting embeddings (provider.EmbeddingProvider), and by
+// Cache. Library users can reuse it for their own similarity features.
+type Embeddings interface {
+	// Embed returns the embedding of text.
+	Embed(ctx context.Context, text string) ([]float64, error)
+	// EmbedBatch returns the embeddings of texts, in the same order.
+	EmbedBatch(ctx context.Context, texts []string) ([][]float64, error)
+}
+
+// UsageReporter is implemented by the Embeddings reporting the tokens and
+// cost of the embeddings they generate.
+type UsageReporter interface {
+	SetUsageHandler(handler func(tokens int64, cost float64))
+}
+
+var (
+	_ Embeddings    = (*Embedder)(nil)
+	_ UsageReporter = (*Embedder)(nil)
+)
+
 // Embedder generates vector embeddings for text
 type Embedder struct {
 	provider       provider.Provider
@@ -64,7 +64,7 @@ func NewChunkedEmbeddingsFromConfig(ctx context.Context, cfg latest.RAGStrategyC
 	}
 
 	// Create embedder
-	embedder := CreateEmbedder(embeddingCfg.Provider, batchSize, maxConcurrency)
+	embedder := CreateCachedEmbedder(embeddingCfg, dbPath, batchSize, maxConcurrency)
 
 	// Set default limit if not provided
 	limit := cmp.Or(cfg.Limit, 5)
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"path/filepath"
+	"strings"
 
 	"github.com/docker/docker-agent/pkg/config"
 	"github.com/docker/docker-agent/pkg/config/latest"
@@ -98,3 +100,28 @@ func createAutoEmbeddingModel(ctx context.Context, buildCtx BuildContext) (provi
 func CreateEmbedder(embedModel provider.Provider, batchSize, maxConcurrency int) *embed.Embedder {
 	return embed.New(embedModel, embed.WithBatchSize(batchSize), embed.WithMaxConcurrency(maxConcurrency))
 }
+
+// CreateCachedEmbedder creates an embedder like CreateEmbedder, whose
+// embeddings are cached next to the index at dbPath, see
+// EmbeddingsCachePath: the chunks that didn't change are never embedded
+// again by the same model. The embeddings aren't cached if the cache can't
+// be opened.
+func CreateCachedEmbedder(embeddingCfg *EmbeddingConfig, dbPath string, batchSize, maxConcurrency int) embed.Embeddings {
+	embedder := CreateEmbedder(embeddingCfg.Provider, batchSize, maxConcurrency)
+
+	cachePath := EmbeddingsCachePath(dbPath)
+	cache, err := embed.NewCache(embedder, embeddingCfg.ModelID, cachePath)
+	if err != nil {
+		slog.Warn("Failed to open embeddings cache, embeddings won't be cached",
+			"path", cachePath,
+			"error", err)
+		return embedder
+	}
+	return cache
+}
+
+// EmbeddingsCachePath returns the path of the embeddings cache of the
+// index at dbPath, e.g. "docs_embeddings_cache.db" for "docs.db".
+func EmbeddingsCachePath(dbPath string) string {
+	return strings.TrimSuffix(dbPath, filepath.Ext(dbPath)) + "_embeddings_cache.db"
+}
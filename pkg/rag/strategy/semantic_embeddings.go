@@ -134,7 +134,7 @@ func NewSemanticEmbeddingsFromConfig(ctx context.Context, cfg latest.RAGStrategy
 	}
 
 	// Create embedder
-	embedder := CreateEmbedder(embeddingCfg.Provider, batchSize, maxConcurrency)
+	embedder := CreateCachedEmbedder(embeddingCfg, dbPath, batchSize, maxConcurrency)
 
 	// Set default limit if not provided
 	limit := cmp.Or(cfg.Limit, 5)
@@ -3,6 +3,7 @@ package strategy
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -49,7 +50,7 @@ type VectorSearchResultData struct {
 type VectorStore struct {
 	name         string
 	db           vectorStoreDB
-	embedder     *embed.Embedder
+	embedder     embed.Embeddings
 	docProcessor chunk.DocumentProcessor
 	fileHashes   map[string]string
 	fileHashesMu sync.Mutex // Protects fileHashes map for concurrent access
@@ -111,7 +112,7 @@ func (d DefaultEmbeddingInputBuilder) BuildEmbeddingInput(_ context.Context, _ s
 type VectorStoreConfig struct {
 	Name                 string
 	Database             vectorStoreDB
-	Embedder             *embed.Embedder
+	Embedder             embed.Embeddings
 	Events               chan<- types.Event
 	SimilarityMetric     string
 	ModelID              string
@@ -150,10 +151,12 @@ func NewVectorStore(cfg VectorStoreConfig) *VectorStore {
 
 	// Set usage handler to calculate cost from models.dev and emit events with CUMULATIVE totals
 	// This matches how chat completions calculate cost in runtime.go
-	cfg.Embedder.SetUsageHandler(func(tokens int64, _ float64) {
-		cost := s.calculateCost(tokens)
-		s.recordUsage(tokens, cost)
-	})
+	if reporter, ok := cfg.Embedder.(embed.UsageReporter); ok {
+		reporter.SetUsageHandler(func(tokens int64, _ float64) {
+			cost := s.calculateCost(tokens)
+			s.recordUsage(tokens, cost)
+		})
+	}
 
 	return s
 }
@@ -306,6 +309,7 @@ func (s *VectorStore) Initialize(ctx context.Context, docPaths []string, chunkin
 	}
 
 	s.emitEvent(types.Event{Type: types.EventTypeIndexingStarted})
+	cacheStatsBefore, _ := s.cacheStats()
 
 	// Index files that need it in parallel
 	var indexed int
@@ -363,7 +367,14 @@ func (s *VectorStore) Initialize(ctx context.Context, docPaths []string, chunkin
 		slog.Error("Failed to cleanup orphaned documents", "error", err)
 	}
 
-	s.emitEvent(types.Event{Type: types.EventTypeIndexingComplete})
+	complete := types.Event{Type: types.EventTypeIndexingComplete}
+	if stats, ok := s.cacheStats(); ok {
+		stats = stats.Sub(cacheStatsBefore)
+		complete.Cache = &stats
+		complete.Message = fmt.Sprintf("embeddings cache hit rate: %.0f%% (%d of %d chunks)",
+			100*stats.HitRate(), stats.Hits, stats.Hits+stats.Misses)
+	}
+	s.emitEvent(complete)
 
 	slog.Info("Vector store initialization completed",
 		"name", s.name,
@@ -482,6 +493,16 @@ func (s *VectorStore) Close() error {
 		s.watcher = nil
 	}
 
+	// Close embeddings cache
+	if closer, ok := s.embedder.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			slog.Warn("Failed to close embeddings cache", "strategy", s.name, "error", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
 	// Close database connection
 	if s.db != nil {
 		if err := s.db.Close(); err != nil {
@@ -502,6 +523,16 @@ func (s *VectorStore) GetIndexingUsage() (tokens int64, cost float64) {
 
 // Helper methods
 
+// cacheStats returns the stats of the embeddings cache, if the embeddings
+// are cached.
+func (s *VectorStore) cacheStats() (types.CacheStats, bool) {
+	cache, ok := s.embedder.(*embed.Cache)
+	if !ok {
+		return types.CacheStats{}, false
+	}
+	return cache.Stats(), true
+}
+
 func (s *VectorStore) loadExistingHashes(ctx context.Context) error {
 	metadata, err := s.db.GetAllFileMetadata(ctx)
 	if err != nil {
@@ -0,0 +1,123 @@
+package strategy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/rag/embed"
+	"github.com/docker/docker-agent/pkg/rag/types"
+)
+
+// fakeEmbeddings embeds texts as three dimensional vectors, counting the
+// embedding requests.
+type fakeEmbeddings struct {
+	mu       sync.Mutex
+	requests int
+}
+
+func (e *fakeEmbeddings) Embed(ctx context.Context, text string) ([]float64, error) {
+	embeddings, err := e.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+func (e *fakeEmbeddings) EmbedBatch(_ context.Context, texts []string) ([][]float64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.requests++
+
+	embeddings := make([][]float64, len(texts))
+	for i, text := range texts {
+		embeddings[i] = []float64{float64(len(text)), float64(strings.Count(text, " ")), 1}
+	}
+	return embeddings, nil
+}
+
+func (e *fakeEmbeddings) counts() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.requests
+}
+
+// newCachedVectorStore returns a chunked-embeddings vector store with its
+// index at dbPath, whose embeddings are cached next to cachedIndex.
+func newCachedVectorStore(t *testing.T, embeddings embed.Embeddings, dbPath, cachedIndex string, events chan<- types.Event) *VectorStore {
+	t.Helper()
+
+	db, err := newChunkedVectorDB(dbPath, 3, "chunked-embeddings")
+	require.NoError(t, err)
+	cache, err := embed.NewCache(embeddings, "test/embeddings", EmbeddingsCachePath(cachedIndex))
+	require.NoError(t, err)
+
+	store := NewVectorStore(VectorStoreConfig{
+		Name:                 "chunked-embeddings",
+		Database:             db,
+		Embedder:             cache,
+		Events:               events,
+		FileIndexConcurrency: 2,
+		Chunking:             ChunkingConfig{Size: 40},
+	})
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func TestVectorStore_EmbeddingsCache(t *testing.T) {
+	docs := t.TempDir()
+	for name, content := range map[string]string{
+		"a.md": strings.Repeat("The quick brown fox jumps over the lazy dog. ", 4),
+		"b.md": strings.Repeat("Pack my box with five dozen liquor jugs. ", 3),
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(docs, name), []byte(content), 0o644))
+	}
+
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "index.db")
+	chunking := ChunkingConfig{Size: 40}
+	embeddings := &fakeEmbeddings{}
+
+	store := newCachedVectorStore(t, embeddings, dbPath, dbPath, nil)
+	require.NoError(t, store.Initialize(t.Context(), []string{docs}, chunking))
+	requests := embeddings.counts()
+	require.Positive(t, requests)
+	require.NoError(t, store.Close())
+
+	// Initializing again over the same corpus performs no embedding
+	// request, even with a new index.
+	events := make(chan types.Event, 100)
+	store = newCachedVectorStore(t, embeddings, filepath.Join(dir, "new.db"), dbPath, events)
+	require.NoError(t, store.Initialize(t.Context(), []string{docs}, chunking))
+	assert.Equal(t, requests, embeddings.counts())
+
+	close(events)
+	var complete *types.Event
+	for event := range events {
+		if event.Type == types.EventTypeIndexingComplete {
+			complete = &event
+		}
+	}
+	require.NotNil(t, complete)
+	require.NotNil(t, complete.Cache)
+	assert.Positive(t, complete.Cache.Hits)
+	assert.Zero(t, complete.Cache.Misses)
+	assert.Contains(t, complete.Message, "hit rate: 100%")
+
+	// Nor with the same index, whose files are up to date.
+	require.NoError(t, store.Close())
+	store = newCachedVectorStore(t, embeddings, dbPath, dbPath, nil)
+	require.NoError(t, store.Initialize(t.Context(), []string{docs}, chunking))
+	assert.Equal(t, requests, embeddings.counts())
+
+	// The index is complete: the documents can be searched.
+	results, err := store.Query(t.Context(), "The quick brown fox jumps over the lazy dog.", 10, 0)
+	require.NoError(t, err)
+	assert.NotEmpty(t, results)
+}
@@ -28,8 +28,30 @@ type Event struct {
 	Message      string
 	Progress     *Progress
 	Error        error
-	TotalTokens  int64   // For usage events
-	Cost         float64 // For usage events
+	TotalTokens  int64       // For usage events
+	Cost         float64     // For usage events
+	Cache        *CacheStats // For indexing_complete events of the strategies caching their embeddings
+}
+
+// CacheStats counts the texts whose embeddings were found in a cache (Hits)
+// and the ones that had to be embedded (Misses).
+type CacheStats struct {
+	Hits   int
+	Misses int
+}
+
+// HitRate returns the ratio of the texts found in the cache, 0 when there
+// weren't any.
+func (s CacheStats) HitRate() float64 {
+	if total := s.Hits + s.Misses; total > 0 {
+		return float64(s.Hits) / float64(total)
+	}
+	return 0
+}
+
+// Sub returns the stats counted since before.
+func (s CacheStats) Sub(before CacheStats) CacheStats {
+	return CacheStats{Hits: s.Hits - before.Hits, Misses: s.Misses - before.Misses}
 }
 
 // Progress represents progress within a multi-step operation (e.g., indexing, reranking).
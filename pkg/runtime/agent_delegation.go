@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"slices"
 	"strings"
+	"unicode"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -75,11 +76,14 @@ type SubSessionConfig struct {
 	AgentName string
 	// Title is a human-readable label for the sub-session (e.g. "Transferred task").
 	Title string
+	// ToolCallID is the ID of the tool call of the parent session that
+	// spawns the sub-session, if any.
+	ToolCallID string
 	// ToolsApproved overrides whether tools are pre-approved in the child session.
 	ToolsApproved bool
 	// PinAgent, when true, pins the child session to AgentName via
 	// session.WithAgentName. This is required for concurrent background
-	// tasks that must not share the runtime's mutable currentAgent field.
+	// tasks that must not share the agent of the session's run.
 	PinAgent bool
 	// ImplicitUserMessage, when non-empty, overrides the default "Please proceed."
 	// user message sent to the child session. This allows callers like skill
@@ -89,6 +93,9 @@ type SubSessionConfig struct {
 	// tool list for the child session. This prevents recursive tool calls
 	// (e.g. run_skill calling itself in a skill sub-session).
 	ExcludedTools []string
+	// SharedContext, when non-empty, is appended to the system message so
+	// that the child agent starts with the team's shared context.
+	SharedContext string
 }
 
 // newSubSession builds a *session.Session from a SubSessionConfig and a parent
@@ -99,6 +106,9 @@ func newSubSession(parent *session.Session, cfg SubSessionConfig, childAgent *ag
 	if sysMsg == "" {
 		sysMsg = buildTaskSystemMessage(cfg.Task, cfg.ExpectedOutput)
 	}
+	if cfg.SharedContext != "" {
+		sysMsg += "\n\n" + cfg.SharedContext
+	}
 
 	userMsg := cfg.ImplicitUserMessage
 	if userMsg == "" {
@@ -115,6 +125,9 @@ func newSubSession(parent *session.Session, cfg SubSessionConfig, childAgent *ag
 		session.WithToolsApproved(cfg.ToolsApproved),
 		session.WithSendUserMessage(false),
 		session.WithParentID(parent.ID),
+		session.WithToolCallID(cfg.ToolCallID),
+		// The tools approved for the parent session stay approved.
+		session.WithPermissions(parent.PermissionsSnapshot()),
 	}
 	if cfg.PinAgent {
 		opts = append(opts, session.WithAgentName(cfg.AgentName))
@@ -126,6 +139,9 @@ func newSubSession(parent *session.Session, cfg SubSessionConfig, childAgent *ag
 	if len(excludedTools) > 0 {
 		opts = append(opts, session.WithExcludedTools(excludedTools))
 	}
+	for agentName, modelOpts := range parent.ModelOptions {
+		opts = append(opts, session.WithModelOverrides(agentName, modelOpts...))
+	}
 	return session.New(opts...)
 }
 
@@ -154,7 +170,8 @@ func mergeExcludedTools(parent, child []string) []string {
 
 // runSubSessionForwarding runs a child session within the parent, forwarding all
 // events to the caller's event channel and propagating tool approval state
-// back to the parent when done.
+// back to the parent when done. The result is the final answer of the child,
+// or else its last message, see subSessionResult.
 //
 // This is the "interactive" path used by transfer_task where the parent agent
 // loop is blocked while the child executes.
@@ -175,20 +192,27 @@ func (r *LocalRuntime) runSubSessionForwarding(ctx context.Context, parent, chil
 	}
 
 	parent.ToolsApproved = child.ToolsApproved
+	var approved bool
+	for _, name := range child.ApprovedTools() {
+		approved = parent.ApproveTool(name) || approved
+	}
+	if approved {
+		r.persistToolApprovals(ctx, parent)
+	}
 
 	parent.AddSubSession(child)
 	evts <- SubSessionCompleted(parent.ID, child, callerAgent)
 
 	span.SetStatus(codes.Ok, "sub-session completed")
-	return tools.ResultSuccess(child.GetLastAssistantMessageContent()), nil
+	return tools.ResultSuccess(subSessionResult(child)), nil
 }
 
 // runSubSessionCollecting runs a child session, collecting output via an
 // optional content callback instead of forwarding events. This is the path
 // used by background agents and other non-interactive callers.
 //
-// It returns a RunResult containing either the final assistant message or
-// an error message.
+// It returns a RunResult containing either the final answer of the child,
+// or else its last assistant message, or an error message.
 func (r *LocalRuntime) runSubSessionCollecting(ctx context.Context, parent, child *session.Session, onContent func(string)) *agenttool.RunResult {
 	var errMsg string
 	events := r.RunStream(ctx, child)
@@ -215,7 +239,7 @@ func (r *LocalRuntime) runSubSessionCollecting(ctx context.Context, parent, chil
 		return &agenttool.RunResult{ErrMsg: errMsg}
 	}
 
-	result := child.GetLastAssistantMessageContent()
+	result := subSessionResult(child)
 	parent.AddSubSession(child)
 	return &agenttool.RunResult{Result: result}
 }
@@ -232,7 +256,7 @@ func (r *LocalRuntime) CurrentAgentSubAgentNames() []string {
 // RunAgent implements agenttool.Runner. It starts a sub-agent synchronously and
 // blocks until completion or cancellation.
 func (r *LocalRuntime) RunAgent(ctx context.Context, params agenttool.RunParams) *agenttool.RunResult {
-	child, err := r.team.Agent(params.AgentName)
+	child, err := r.Team().Agent(params.AgentName)
 	if err != nil {
 		return &agenttool.RunResult{ErrMsg: fmt.Sprintf("agent %q not found: %s", params.AgentName, err)}
 	}
@@ -272,7 +296,7 @@ func (r *LocalRuntime) handleTaskTransfer(ctx context.Context, sess *session.Ses
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
 
-	a := r.CurrentAgent()
+	a := r.resolveSessionAgent(sess)
 
 	// Validate that the target agent is in the current agent's sub-agents list
 	if errResult := validateAgentInList(a.Name(), params.Agent, "transfer task to", "sub-agents list", a.SubAgents()); errResult != nil {
@@ -291,9 +315,16 @@ func (r *LocalRuntime) handleTaskTransfer(ctx context.Context, sess *session.Ses
 	// Emit agent switching start event
 	evts <- AgentSwitching(true, a.Name(), params.Agent)
 
-	r.setCurrentAgent(params.Agent)
+	// The sub-session shares the run of the session, which is at the
+	// agent the task is transferred to until the sub-session ends.
+	run := sessionRunFromContext(ctx)
+	if run == nil {
+		run = newSessionRun(sess.ID, a.Name())
+		ctx = withSessionRun(ctx, run)
+	}
+	run.setAgent(params.Agent)
 	defer func() {
-		r.setCurrentAgent(a.Name())
+		run.setAgent(a.Name())
 
 		// Emit agent switching end event
 		evts <- AgentSwitching(false, params.Agent, a.Name())
@@ -303,7 +334,7 @@ func (r *LocalRuntime) handleTaskTransfer(ctx context.Context, sess *session.Ses
 	}()
 
 	// Emit agent info for the new agent
-	child, err := r.team.Agent(params.Agent)
+	child, err := r.Team().Agent(params.Agent)
 	if err != nil {
 		return nil, err
 	}
@@ -311,42 +342,195 @@ func (r *LocalRuntime) handleTaskTransfer(ctx context.Context, sess *session.Ses
 
 	slog.Debug("Creating new session with parent session", "parent_session_id", sess.ID, "tools_approved", sess.ToolsApproved)
 
+	title := "Transferred task"
+	if r.subSessionTitles {
+		title = subSessionTitle(params.Agent, params.Task)
+	}
+
 	cfg := SubSessionConfig{
 		Task:           params.Task,
 		ExpectedOutput: params.ExpectedOutput,
 		AgentName:      params.Agent,
-		Title:          "Transferred task",
+		Title:          title,
+		ToolCallID:     toolCall.ID,
 		ToolsApproved:  sess.ToolsApproved,
+		SharedContext:  r.Team().Blackboard().Prompt(),
+	}
+
+	s := newSubSession(sess, cfg, child)
+
+	result, err := r.runSubSessionForwarding(ctx, sess, s, span, evts, a.Name())
+	return r.guardSubAgentResult(sess, a, child.Name(), result, evts), err
+}
+
+// subSessionTitleWords is the maximum number of words of the task in the
+// title of a sub-session, see subSessionTitle.
+const subSessionTitleWords = 8
+
+// subSessionTitle returns the title of the sub-session of a task transferred
+// to agentName: the first words of the task, on a single line and without
+// control characters or Markdown emphasis, prefixed with the agent name.
+func subSessionTitle(agentName, task string) string {
+	var words []string
+	for word := range strings.FieldsSeq(task) {
+		word = strings.Trim(strings.Map(printable, word), "*_`#>")
+		if word == "" {
+			continue
+		}
+		words = append(words, word)
+		if len(words) == subSessionTitleWords {
+			break
+		}
+	}
+	if len(words) == 0 {
+		return agentName
+	}
+	return agentName + ": " + strings.TrimRight(strings.Join(words, " "), ".,;:")
+}
+
+// printable drops the runes that aren't printable, for strings.Map.
+func printable(r rune) rune {
+	if !unicode.IsPrint(r) {
+		return -1
+	}
+	return r
+}
+
+// agentToolCallersKey is the context key of the agents that are waiting for
+// an agent tool call to return, used to detect recursive calls.
+type agentToolCallersKey struct{}
+
+// runtimeToolHandler returns the runtime handler of a tool of agent a:
+// either a built-in handler, or the handler of one of a's agent tools.
+func (r *LocalRuntime) runtimeToolHandler(a *agent.Agent, toolName string) (ToolHandlerFunc, bool) {
+	if handler, exists := r.toolMap[toolName]; exists && hasBuiltinTool(a, toolName) {
+		return handler, true
+	}
+
+	for _, ts := range a.ToolSets() {
+		if at, ok := tools.As[*builtin.AgentTool](ts); ok && at.ToolName() == toolName {
+			return func(ctx context.Context, sess *session.Session, toolCall tools.ToolCall, evts chan Event) (*tools.ToolCallResult, error) {
+				return r.handleAgentTool(ctx, sess, toolCall, evts, at)
+			}, true
+		}
+	}
+	return nil, false
+}
+
+// hasBuiltinTool reports whether a has the built-in tool handled by the
+// runtime. transfer_task and handoff come with the sub-agents and the
+// handoffs of the agent, unless they're disabled: a tool with the same name
+// from another toolset isn't handled by the runtime.
+func hasBuiltinTool(a *agent.Agent, toolName string) bool {
+	switch toolName {
+	case builtin.ToolNameTransferTask:
+		return a.HasSubAgents() && a.BuiltinToolEnabled(toolName) && hasToolSet[*builtin.TransferTaskTool](a)
+	case builtin.ToolNameHandoff:
+		return len(a.Handoffs()) > 0 && a.BuiltinToolEnabled(toolName) && hasToolSet[*builtin.HandoffTool](a)
+	default:
+		return true
+	}
+}
+
+// hasToolSet reports whether one of the toolsets of a is a T.
+func hasToolSet[T any](a *agent.Agent) bool {
+	for _, ts := range a.ToolSets() {
+		if _, ok := tools.As[T](ts); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// handleAgentTool runs the agent wrapped by an agent tool in a new session,
+// with the prompt rendered from the call arguments, and returns its final
+// answer or its last message. The events of the agent are forwarded with its
+// name.
+func (r *LocalRuntime) handleAgentTool(ctx context.Context, sess *session.Session, toolCall tools.ToolCall, evts chan Event, at *builtin.AgentTool) (*tools.ToolCallResult, error) {
+	caller := r.resolveSessionAgent(sess)
+
+	child, err := r.Team().Agent(at.AgentName())
+	if err != nil {
+		return tools.ResultError(fmt.Sprintf("agent %q not found: %s", at.AgentName(), err)), nil
+	}
+
+	// Configurations are checked for cycles when they're loaded; this catches
+	// teams built in code.
+	callers, _ := ctx.Value(agentToolCallersKey{}).([]string)
+	callers = append(slices.Clone(callers), caller.Name())
+	if slices.Contains(callers, child.Name()) {
+		return tools.ResultError(fmt.Sprintf("recursive agent tool call: %s -> %s", strings.Join(callers, " -> "), child.Name())), nil
+	}
+	ctx = context.WithValue(ctx, agentToolCallersKey{}, callers)
+
+	task, err := at.RenderPrompt(toolCall.Function.Arguments)
+	if err != nil {
+		return tools.ResultError(err.Error()), nil
+	}
+
+	ctx, span := r.startSpan(ctx, "runtime.agent_tool", trace.WithAttributes(
+		attribute.String("from.agent", caller.Name()),
+		attribute.String("to.agent", child.Name()),
+		attribute.String("tool", at.ToolName()),
+		attribute.String("session.id", sess.ID),
+	))
+	defer span.End()
+
+	slog.Debug("Running agent as tool", "from_agent", caller.Name(), "to_agent", child.Name(), "tool", at.ToolName())
+
+	cfg := SubSessionConfig{
+		Task:          task,
+		AgentName:     child.Name(),
+		Title:         "Agent tool: " + at.ToolName(),
+		ToolCallID:    toolCall.ID,
+		ToolsApproved: sess.ToolsApproved,
+		PinAgent:      true,
+		SharedContext: r.Team().Blackboard().Prompt(),
 	}
 
 	s := newSubSession(sess, cfg, child)
 
-	return r.runSubSessionForwarding(ctx, sess, s, span, evts, a.Name())
+	result, err := r.runSubSessionForwarding(ctx, sess, s, span, evts, caller.Name())
+	return r.guardSubAgentResult(sess, caller, child.Name(), result, evts), err
 }
 
-func (r *LocalRuntime) handleHandoff(_ context.Context, _ *session.Session, toolCall tools.ToolCall, _ chan Event) (*tools.ToolCallResult, error) {
+func (r *LocalRuntime) handleHandoff(ctx context.Context, sess *session.Session, toolCall tools.ToolCall, events chan Event) (*tools.ToolCallResult, error) {
 	var params builtin.HandoffArgs
 	if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &params); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
 
-	ca := r.CurrentAgentName()
-	currentAgent, err := r.team.Agent(ca)
-	if err != nil {
-		return nil, fmt.Errorf("current agent not found: %w", err)
-	}
+	currentAgent := r.resolveSessionAgent(sess)
+	ca := currentAgent.Name()
 
 	// Validate that the target agent is in the current agent's handoffs list
 	if errResult := validateAgentInList(ca, params.Agent, "hand off to", "handoffs list", currentAgent.Handoffs()); errResult != nil {
 		return errResult, nil
 	}
 
-	next, err := r.team.Agent(params.Agent)
+	next, err := r.Team().Agent(params.Agent)
 	if err != nil {
 		return nil, err
 	}
 
-	r.setCurrentAgent(next.Name())
+	repeats := r.recentHandoffRepeats(sess, ca, next.Name())
+	if r.maxHandoffRepeats > 0 && repeats >= r.maxHandoffRepeats {
+		slog.Warn("Blocked handoff loop", "from", ca, "to", next.Name(), "repeats", repeats, "session_id", sess.ID)
+		events <- HandoffLoopDetected(ca, next.Name(), sess.ID, repeats, r.handoffLoopWindow)
+		return tools.ResultError(fmt.Sprintf(
+			"Handoff to %s blocked: %s already handed off to %s %d times recently, which looks like a loop. "+
+				"Do not hand off to %s again. Make progress on the task yourself, hand off to a different agent, or respond to the user.",
+			next.Name(), ca, next.Name(), repeats, next.Name())), nil
+	}
+
+	sess.AddHandoff(ca, next.Name())
+	events <- AgentHandoff(ca, next.Name(), sess.ID, sess.Handoffs())
+
+	// Only the session's run moves to the next agent: the other sessions of
+	// the runtime stay where they are.
+	if run := sessionRunFromContext(ctx); run != nil {
+		run.setAgent(next.Name())
+	}
 	handoffMessage := "The agent " + ca + " handed off the conversation to you. " +
 		"Your available handoff agents and tools are specified in the system messages that follow. " +
 		"Only use those capabilities - do not attempt to use tools or hand off to agents that you see " +
@@ -357,3 +541,20 @@ func (r *LocalRuntime) handleHandoff(_ context.Context, _ *session.Session, tool
 		"(if any are available to you), or respond directly to the user if you are the final agent."
 	return tools.ResultSuccess(handoffMessage), nil
 }
+
+// recentHandoffRepeats returns how many times from handed off to to within
+// the last handoffLoopWindow handoffs of the session.
+func (r *LocalRuntime) recentHandoffRepeats(sess *session.Session, from, to string) int {
+	handoffs := sess.Handoffs()
+	if r.handoffLoopWindow > 0 && len(handoffs) > r.handoffLoopWindow {
+		handoffs = handoffs[len(handoffs)-r.handoffLoopWindow:]
+	}
+
+	repeats := 0
+	for _, h := range handoffs {
+		if h.From == from && h.To == to {
+			repeats++
+		}
+	}
+	return repeats
+}
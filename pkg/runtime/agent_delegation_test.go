@@ -72,12 +72,14 @@ func TestNewSubSession(t *testing.T) {
 			ExpectedOutput: "passing tests",
 			AgentName:      "worker",
 			Title:          "Test task",
+			ToolCallID:     "call_1",
 			ToolsApproved:  true,
 		}
 
 		s := newSubSession(parent, cfg, childAgent)
 
 		assert.Equal(t, parent.ID, s.ParentID)
+		assert.Equal(t, "call_1", s.ToolCallID)
 		assert.Equal(t, "Test task", s.Title)
 		assert.True(t, s.ToolsApproved)
 		assert.False(t, s.SendUserMessage)
@@ -141,6 +143,22 @@ func TestNewSubSession(t *testing.T) {
 	})
 }
 
+func TestSubSessionTitle(t *testing.T) {
+	tests := []struct {
+		task string
+		want string
+	}{
+		{task: "compare quota APIs", want: "researcher: compare quota APIs"},
+		{task: "Compare the quota APIs of the providers.", want: "researcher: Compare the quota APIs of the providers"},
+		{task: "**Research** the\n\tfollowing topics in depth and write a report", want: "researcher: Research the following topics in depth and write"},
+		{task: "# Fix \x1bthe bug", want: "researcher: Fix the bug"},
+		{task: "  \n ", want: "researcher"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, subSessionTitle("researcher", tt.task), tt.task)
+	}
+}
+
 func TestSubSessionConfig_DefaultValues(t *testing.T) {
 	// Verify zero-value SubSessionConfig produces a valid session
 	parent := session.New(session.WithUserMessage("hello"))
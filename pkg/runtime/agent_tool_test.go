@@ -0,0 +1,97 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
+	"github.com/docker/docker-agent/pkg/tools/builtin"
+)
+
+func TestAgentTool_RunsChildAgent(t *testing.T) {
+	reviewerProv := &recordingProvider{queueProvider: queueProvider{id: "test/mock-model", streams: []chat.MessageStream{
+		newStreamBuilder().AddContent("No issues found.").AddStopWithUsage(5, 5).Build(),
+	}}}
+	reviewer := agent.New("reviewer", "Reviews code", agent.WithModel(reviewerProv))
+
+	reviewTool, err := builtin.NewAgentTool("reviewer", "review_file", "Review a file.", map[string]any{"type": "object"}, "Review {{.file}}.")
+	require.NoError(t, err)
+	root := agent.New("root", "Root agent",
+		agent.WithModel(&mockProvider{id: "test/mock-model", stream: &mockStream{}}),
+		agent.WithToolSets(reviewTool),
+	)
+
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root, reviewer)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	handler, ok := rt.runtimeToolHandler(root, "review_file")
+	require.True(t, ok)
+	_, ok = rt.runtimeToolHandler(root, "unknown_tool")
+	require.False(t, ok)
+
+	sess := session.New(session.WithUserMessage("Test"), session.WithToolsApproved(true))
+	evts := make(chan Event, 128)
+
+	result, err := handler(t.Context(), sess, tools.ToolCall{
+		ID:       "call_1",
+		Type:     "function",
+		Function: tools.FunctionCall{Name: "review_file", Arguments: `{"file":"main.go"}`},
+	}, evts)
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	assert.Equal(t, "No issues found.", result.Output)
+
+	// The child got the rendered prompt.
+	require.NotEmpty(t, reviewerProv.requests)
+	var systemMessages string
+	for _, msg := range reviewerProv.requests[0] {
+		if msg.Role == chat.MessageRoleSystem {
+			systemMessages += msg.Content
+		}
+	}
+	assert.Contains(t, systemMessages, "<task>\nReview main.go.\n</task>")
+
+	// The current agent didn't change, and the child's events carry its name.
+	assert.Equal(t, "root", rt.CurrentAgentName())
+	close(evts)
+	var childContent, completed bool
+	for event := range evts {
+		switch event := event.(type) {
+		case *AgentChoiceEvent:
+			childContent = true
+			assert.Equal(t, "reviewer", event.AgentName)
+		case *SubSessionCompletedEvent:
+			completed = true
+		}
+	}
+	assert.True(t, childContent)
+	assert.True(t, completed)
+}
+
+func TestAgentTool_RejectsRecursion(t *testing.T) {
+	selfTool, err := builtin.NewAgentTool("root", "ask_root", "", nil, "")
+	require.NoError(t, err)
+	root := agent.New("root", "Root agent",
+		agent.WithModel(&mockProvider{id: "test/mock-model", stream: &mockStream{}}),
+		agent.WithToolSets(selfTool),
+	)
+
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Test"))
+	result, err := rt.handleAgentTool(t.Context(), sess, tools.ToolCall{
+		ID:       "call_1",
+		Type:     "function",
+		Function: tools.FunctionCall{Name: "ask_root", Arguments: `{"task":"loop"}`},
+	}, make(chan Event, 10), selfTool)
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.Output, "recursive agent tool call: root -> root")
+}
@@ -0,0 +1,114 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/model/provider"
+	"github.com/docker/docker-agent/pkg/modelsdev"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+const (
+	// continuePrompt asks the model to continue a response cut by the output
+	// token limit.
+	continuePrompt = "Your previous response was cut off because it reached the output token limit. " +
+		"Continue exactly where you left off, do not repeat anything."
+
+	// retryToolCallsPrompt asks the model to make its tool calls again when
+	// the output token limit cut one of them: stitching the arguments of a
+	// tool call is too likely to corrupt them.
+	retryToolCallsPrompt = "Your previous response was cut off in the middle of a tool call because it reached the output token limit. " +
+		"Make your tool calls again, with their complete arguments, and keep them shorter if you can."
+)
+
+// continueTruncatedResponse sends continuation requests while the model
+// stops because of the output token limit, at most r.maxContinuations times,
+// and stitches the responses together into one. The continuation requests
+// aren't recorded in the session, only the stitched response is.
+func (r *LocalRuntime) continueTruncatedResponse(
+	ctx context.Context,
+	a *agent.Agent,
+	model provider.Provider,
+	messages []chat.Message,
+	agentTools []tools.Tool,
+	sess *session.Session,
+	m *modelsdev.Model,
+	res streamResult,
+	events chan Event,
+) streamResult {
+	for continuation := 1; continuation <= r.maxContinuations; continuation++ {
+		if res.FinishReason != chat.FinishReasonLength {
+			return res
+		}
+
+		retryToolCalls := hasTruncatedToolCall(res.Calls)
+		if len(res.Calls) > 0 && !retryToolCalls {
+			// The tool calls are complete, they can be made.
+			return res
+		}
+
+		prompt := continuePrompt
+		if retryToolCalls {
+			prompt = retryToolCallsPrompt
+		}
+		slog.Debug("Continuing a response cut by the output token limit", "agent", a.Name(), "continuation", continuation, "retry_tool_calls", retryToolCalls)
+
+		continued := slices.Clone(messages)
+		if res.Content != "" {
+			continued = append(continued, chat.Message{Role: chat.MessageRoleAssistant, Content: res.Content})
+		}
+		continued = append(continued, chat.SystemReminderMessage(prompt))
+
+		next, _, err := r.tryModelWithFallback(ctx, a, model, continued, agentTools, sess, m, events)
+		if err != nil {
+			slog.Warn("Failed to continue a response cut by the output token limit", "agent", a.Name(), "error", err)
+			return res
+		}
+		res = stitchResponses(res, next)
+	}
+	return res
+}
+
+// hasTruncatedToolCall reports whether the arguments of a tool call aren't
+// valid JSON, as when the output token limit cut them.
+func hasTruncatedToolCall(calls []tools.ToolCall) bool {
+	return slices.ContainsFunc(calls, func(call tools.ToolCall) bool {
+		return call.Function.Arguments != "" && !json.Valid([]byte(call.Function.Arguments))
+	})
+}
+
+// stitchResponses merges a response with its continuation. The tool calls
+// are the ones of the continuation: the ones of a truncated response are
+// made again. The reasoning is the one of the first response, since its
+// signature only covers it.
+func stitchResponses(prev, next streamResult) streamResult {
+	next.Content = prev.Content + next.Content
+	if prev.ReasoningContent != "" || prev.ThinkingSignature != "" {
+		next.ReasoningContent = prev.ReasoningContent
+		next.ThinkingSignature = prev.ThinkingSignature
+	}
+	if len(next.ThoughtSignature) == 0 {
+		next.ThoughtSignature = prev.ThoughtSignature
+	}
+
+	switch {
+	case prev.Usage == nil:
+	case next.Usage == nil:
+		next.Usage = prev.Usage
+	default:
+		usage := *next.Usage
+		usage.InputTokens += prev.Usage.InputTokens
+		usage.OutputTokens += prev.Usage.OutputTokens
+		usage.CachedInputTokens += prev.Usage.CachedInputTokens
+		usage.CacheWriteTokens += prev.Usage.CacheWriteTokens
+		usage.ReasoningTokens += prev.Usage.ReasoningTokens
+		next.Usage = &usage
+	}
+	return next
+}
@@ -0,0 +1,105 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/model/provider/fake"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
+)
+
+func TestScripted_AutoContinueText(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().
+			Content("The answer is ", "forty").
+			Usage(10, 5).
+			FinishReason(chat.FinishReasonLength),
+		fake.NewTurn().
+			Content("-two.").
+			Usage(20, 3).
+			Expect(
+				fake.HasMessage(chat.MessageRoleAssistant, "The answer is forty"),
+				fake.LastMessage(chat.MessageRoleUser, "Continue exactly where you left off"),
+			),
+	)
+
+	root := agent.New("root", "You are a test agent", agent.WithModel(prov))
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)),
+		WithSessionCompaction(false),
+		WithModelStore(mockModelStore{}),
+		WithAutoContinue(2),
+	)
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("What's the answer?"))
+	runScripted(t, rt, sess, ResumeApprove())
+
+	messages := sess.GetAllMessages()
+	require.Len(t, messages, 2, "the continuation request isn't recorded")
+	assert.Equal(t, chat.MessageRoleAssistant, messages[1].Message.Role)
+	assert.Equal(t, "The answer is forty-two.", messages[1].Message.Content)
+	assert.Equal(t, &chat.Usage{InputTokens: 30, OutputTokens: 8}, messages[1].Message.Usage)
+}
+
+func TestScripted_AutoContinueToolCall(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().
+			ToolCall("call_1", "shell", `{"cmd":`, `"l`).
+			FinishReason(chat.FinishReasonLength),
+		fake.NewTurn().
+			ToolCall("call_2", "shell", `{"cmd":"ls"}`).
+			Expect(fake.LastMessage(chat.MessageRoleUser, "Make your tool calls again")),
+		fake.NewTurn().
+			Content("There are two files.").
+			Expect(fake.LastMessage(chat.MessageRoleTool, "file1.txt file2.txt")),
+	)
+
+	var executed bool
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(newShellAgent(prov, &executed))),
+		WithSessionCompaction(false),
+		WithModelStore(mockModelStore{}),
+		WithAutoContinue(2),
+	)
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("List the files"))
+	runScripted(t, rt, sess, ResumeApprove())
+
+	assert.True(t, executed)
+	for _, msg := range sess.GetAllMessages() {
+		for _, call := range msg.Message.ToolCalls {
+			assert.Equal(t, "call_2", call.ID, "the truncated tool call is discarded")
+		}
+	}
+	assert.Equal(t, "There are two files.", sess.GetLastAssistantMessageContent())
+}
+
+func TestScripted_AutoContinueLimit(t *testing.T) {
+	truncated := func(content string) *fake.Turn {
+		return fake.NewTurn().Content(content).FinishReason(chat.FinishReasonLength)
+	}
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		truncated("one, "),
+		truncated("two, "),
+		truncated("three, "),
+	)
+
+	root := agent.New("root", "You are a test agent", agent.WithModel(prov))
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)),
+		WithSessionCompaction(false),
+		WithModelStore(mockModelStore{}),
+		WithAutoContinue(2),
+	)
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Count"))
+	runScripted(t, rt, sess, ResumeApprove())
+
+	assert.Len(t, prov.Requests(), 3)
+	assert.Equal(t, "one, two, three,", sess.GetLastAssistantMessageContent())
+}
@@ -0,0 +1,101 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/docker/docker-agent/pkg/session"
+)
+
+// ErrSessionRunning is returned when checkpointing or rolling back a session
+// that is running.
+var ErrSessionRunning = errors.New("session is running")
+
+// Checkpointer is an optional interface for runtimes that can tag points of
+// a session as checkpoints and roll the session back to them. The TUI uses
+// it for /checkpoint and /rollback.
+type Checkpointer interface {
+	// Checkpoint tags the current point of sess as a checkpoint and
+	// persists it. label is optional.
+	Checkpoint(ctx context.Context, sess *session.Session, label string, events chan Event) (session.Checkpoint, error)
+	// Rollback rolls sess back to the checkpoint checkpointID and persists
+	// it. With revertFiles, the files changed since the checkpoint are
+	// restored to their content at the checkpoint too.
+	Rollback(ctx context.Context, sess *session.Session, checkpointID string, revertFiles bool, events chan Event) error
+}
+
+var _ Checkpointer = (*LocalRuntime)(nil)
+
+// Checkpoint tags the current point of sess as a checkpoint and sends a
+// CheckpointCreatedEvent. Sessions can't be checkpointed while they are
+// running: the run would append messages past the checkpoint meanwhile.
+func (r *LocalRuntime) Checkpoint(ctx context.Context, sess *session.Session, label string, events chan Event) (session.Checkpoint, error) {
+	if _, running := r.sessionRun(sess.ID); running {
+		return session.Checkpoint{}, fmt.Errorf("checkpointing session %s: %w", sess.ID, ErrSessionRunning)
+	}
+
+	checkpoint, _ := sess.FindCheckpoint(sess.Checkpoint(label))
+	if r.sessionStore != nil && !sess.IsSubSession() {
+		if err := r.sessionStore.UpdateSession(ctx, sess); err != nil {
+			return session.Checkpoint{}, fmt.Errorf("saving checkpoint: %w", err)
+		}
+	}
+
+	events <- CheckpointCreated(sess.ID, checkpoint, r.resolveSessionAgent(sess).Name())
+	return checkpoint, nil
+}
+
+// Rollback rolls sess back to the checkpoint checkpointID: the messages,
+// handoffs, sub-sessions and checkpoints after it are dropped and the token
+// usage and cost are restored. It sends a SessionRolledBackEvent and a
+// TokenUsageEvent. Sessions can't be rolled back while they are running.
+//
+// The files are only reverted once the rolled back session is saved, so
+// that they aren't reverted for a transcript that wasn't.
+func (r *LocalRuntime) Rollback(ctx context.Context, sess *session.Session, checkpointID string, revertFiles bool, events chan Event) error {
+	if _, running := r.sessionRun(sess.ID); running {
+		return fmt.Errorf("rolling back session %s: %w", sess.ID, ErrSessionRunning)
+	}
+
+	checkpoint, ok := sess.FindCheckpoint(checkpointID)
+	if !ok || checkpoint.ID != checkpointID {
+		return fmt.Errorf("rolling back session %s to %q: %w", sess.ID, checkpointID, session.ErrCheckpointNotFound)
+	}
+
+	a := r.resolveSessionAgent(sess)
+	persist := r.sessionStore != nil && !sess.IsSubSession()
+
+	if err := sess.Rollback(checkpointID); err != nil {
+		return err
+	}
+	if persist {
+		if err := r.sessionStore.RollbackSession(ctx, sess); err != nil {
+			return fmt.Errorf("saving rolled back session: %w", err)
+		}
+	}
+
+	var revertedFiles []string
+	if revertFiles {
+		var err error
+		revertedFiles, err = sess.RevertFilesToCheckpoint(checkpointID)
+		if err != nil {
+			events <- Warning(fmt.Sprintf("Some files could not be reverted: %v", err), a.Name())
+		}
+		// Reverting the files updates the file change ledger.
+		if persist {
+			if err := r.sessionStore.UpdateSession(ctx, sess); err != nil {
+				return fmt.Errorf("saving reverted file changes: %w", err)
+			}
+		}
+	}
+
+	events <- SessionRolledBack(sess.ID, checkpoint, revertedFiles, a.Name())
+
+	var contextLimit int64
+	if m, err := r.modelsStore.GetModel(ctx, r.getEffectiveModelID(a)); err == nil && m != nil {
+		contextLimit = int64(m.Limit.Context)
+	}
+	events <- NewTokenUsageEvent(sess.ID, a.Name(), SessionUsage(sess, contextLimit))
+	return nil
+}
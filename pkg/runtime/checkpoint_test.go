@@ -0,0 +1,114 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
+)
+
+func TestCheckpointAndRollback(t *testing.T) {
+	t.Parallel()
+
+	root := agent.New("root", "You are a test agent", agent.WithModel(&mockProvider{id: "test/mock-model"}))
+	store := session.NewInMemorySessionStore()
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)),
+		WithSessionCompaction(false),
+		WithModelStore(mockModelStore{}),
+		WithSessionStore(store),
+	)
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Hello"))
+	require.NoError(t, store.AddSession(t.Context(), sess))
+
+	events := make(chan Event, 10)
+	checkpoint, err := rt.Checkpoint(t.Context(), sess, "greeted", events)
+	require.NoError(t, err)
+	assert.Equal(t, "greeted", checkpoint.Label)
+	created, ok := (<-events).(*CheckpointCreatedEvent)
+	require.True(t, ok)
+	assert.Equal(t, checkpoint.ID, created.Checkpoint.ID)
+
+	sess.AddMessage(session.UserMessage("Tell me a joke"))
+	require.NoError(t, store.UpdateSession(t.Context(), sess))
+
+	require.ErrorIs(t, rt.Rollback(t.Context(), sess, "cp42", false, events), session.ErrCheckpointNotFound)
+
+	require.NoError(t, rt.Rollback(t.Context(), sess, checkpoint.ID, false, events))
+	rolledBack, ok := (<-events).(*SessionRolledBackEvent)
+	require.True(t, ok)
+	assert.Equal(t, sess.ID, rolledBack.SessionID)
+	assert.Empty(t, rolledBack.RevertedFiles)
+	_, ok = (<-events).(*TokenUsageEvent)
+	assert.True(t, ok)
+
+	// The rollback is stored with the session.
+	stored, err := store.GetSession(t.Context(), sess.ID)
+	require.NoError(t, err)
+	assert.Len(t, stored.GetAllMessages(), 1)
+	assert.Len(t, stored.ListCheckpoints(), 1)
+}
+
+// failingRollbackStore is a session store that fails to save rollbacks.
+type failingRollbackStore struct {
+	session.Store
+}
+
+func (failingRollbackStore) RollbackSession(context.Context, *session.Session) error {
+	return errors.New("disk full")
+}
+
+func TestRollback_KeepsFilesWhenTheSessionIsNotSaved(t *testing.T) {
+	t.Parallel()
+
+	root := agent.New("root", "You are a test agent", agent.WithModel(&mockProvider{id: "test/mock-model"}))
+	store := failingRollbackStore{Store: session.NewInMemorySessionStore()}
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)),
+		WithSessionCompaction(false),
+		WithModelStore(mockModelStore{}),
+		WithSessionStore(store),
+	)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	require.NoError(t, os.WriteFile(path, []byte("before"), 0o644))
+
+	sess := session.New(session.WithUserMessage("Hello"))
+	require.NoError(t, store.AddSession(t.Context(), sess))
+	events := make(chan Event, 10)
+	checkpoint, err := rt.Checkpoint(t.Context(), sess, "", events)
+	require.NoError(t, err)
+
+	sess.SnapshotFile(path, 1024)
+	require.NoError(t, os.WriteFile(path, []byte("after"), 0o644))
+
+	require.ErrorContains(t, rt.Rollback(t.Context(), sess, checkpoint.ID, true, events), "disk full")
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "after", string(content))
+}
+
+func TestCheckpoint_SessionRunning(t *testing.T) {
+	t.Parallel()
+
+	root := agent.New("root", "You are a test agent", agent.WithModel(&mockProvider{id: "test/mock-model"}))
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Hello"))
+	run := rt.startSessionRun(t.Context(), sess)
+	defer rt.endSessionRun(sess, run)
+
+	_, err = rt.Checkpoint(t.Context(), sess, "", make(chan Event, 10))
+	require.ErrorIs(t, err, ErrSessionRunning)
+	assert.Empty(t, sess.ListCheckpoints())
+}
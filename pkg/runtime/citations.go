@@ -0,0 +1,120 @@
+package runtime
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker-agent/pkg/chat"
+)
+
+// citationPattern matches the citations of sources by index in an answer:
+// [1], or [1, 3] for several sources.
+var citationPattern = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+// addSources records the sources retrieved by a RAG tool for the session
+// sessionID during the run and returns them with their index among the
+// sources of the turn. A source retrieved again keeps its index.
+func (run *sessionRun) addSources(sessionID string, sources []chat.Annotation) []chat.Annotation {
+	run.sourcesMu.Lock()
+	defer run.sourcesMu.Unlock()
+
+	if run.sources == nil {
+		run.sources = make(map[string][]chat.Annotation)
+	}
+	turn := run.sources[sessionID]
+
+	indexed := make([]chat.Annotation, 0, len(sources))
+	for _, source := range sources {
+		i := slices.IndexFunc(turn, func(s chat.Annotation) bool {
+			return s.RAGName == source.RAGName && s.Location() == source.Location()
+		})
+		if i < 0 {
+			source.Index = len(turn) + 1
+			turn = append(turn, source)
+		} else {
+			turn[i].Score = max(turn[i].Score, source.Score)
+			source.Index = turn[i].Index
+		}
+		if !slices.ContainsFunc(indexed, func(s chat.Annotation) bool { return s.Index == source.Index }) {
+			indexed = append(indexed, source)
+		}
+	}
+	run.sources[sessionID] = turn
+
+	return indexed
+}
+
+// turnSources returns the sources retrieved for the session sessionID
+// during the run.
+func (run *sessionRun) turnSources(sessionID string) []chat.Annotation {
+	run.sourcesMu.Lock()
+	defer run.sourcesMu.Unlock()
+	return slices.Clone(run.sources[sessionID])
+}
+
+// answerAnnotations returns the annotations of an answer drawing on the
+// sources of the turn: all of them, or only the ones it cites by index
+// when the agent was asked to cite its sources.
+func answerAnnotations(sources []chat.Annotation, content string, cite bool) []chat.Annotation {
+	if len(sources) == 0 || strings.TrimSpace(content) == "" {
+		return nil
+	}
+	if !cite {
+		return sources
+	}
+
+	var cited []chat.Annotation
+	for _, index := range citedIndexes(content) {
+		if index >= 1 && index <= len(sources) {
+			cited = append(cited, sources[index-1])
+		}
+	}
+	return cited
+}
+
+// appendProviderSources appends the web pages cited by the provider to the
+// annotations of an answer, once each, numbered after the other sources.
+func appendProviderSources(annotations, sources []chat.Annotation) []chat.Annotation {
+	next := 1
+	for _, a := range annotations {
+		next = max(next, a.Index+1)
+	}
+	for _, source := range sources {
+		if source.URL == "" || slices.ContainsFunc(annotations, func(a chat.Annotation) bool { return a.URL == source.URL }) {
+			continue
+		}
+		source.Index = next
+		next++
+		annotations = append(annotations, source)
+	}
+	return annotations
+}
+
+// citedIndexes returns the indexes cited in content, sorted, without
+// duplicates.
+func citedIndexes(content string) []int {
+	var indexes []int
+	for _, match := range citationPattern.FindAllStringSubmatch(content, -1) {
+		for field := range strings.SplitSeq(match[1], ",") {
+			if index, err := strconv.Atoi(strings.TrimSpace(field)); err == nil {
+				indexes = append(indexes, index)
+			}
+		}
+	}
+	slices.Sort(indexes)
+	return slices.Compact(indexes)
+}
+
+// citationInstruction asks the model to cite sources by index, listing
+// the ones of a RAG tool result.
+func citationInstruction(sources []chat.Annotation) string {
+	var b strings.Builder
+	b.WriteString("Cite the sources your answer draws on by their index, e.g. [1] or [1, 3]:")
+	for _, source := range sources {
+		fmt.Fprintf(&b, "\n[%d] %s", source.Index, source.Location())
+	}
+	return b.String()
+}
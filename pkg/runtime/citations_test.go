@@ -0,0 +1,209 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/model/provider/fake"
+	"github.com/docker/docker-agent/pkg/rag"
+	"github.com/docker/docker-agent/pkg/rag/database"
+	"github.com/docker/docker-agent/pkg/rag/strategy"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools/builtin"
+)
+
+// fakeStrategy is a retrieval strategy returning the same results for any
+// query.
+type fakeStrategy struct {
+	results []database.SearchResult
+}
+
+func (s *fakeStrategy) Initialize(context.Context, []string, strategy.ChunkingConfig) error {
+	return nil
+}
+
+func (s *fakeStrategy) Query(context.Context, string, int, float64) ([]database.SearchResult, error) {
+	return s.results, nil
+}
+
+func (s *fakeStrategy) CheckAndReindexChangedFiles(context.Context, []string, strategy.ChunkingConfig) error {
+	return nil
+}
+
+func (s *fakeStrategy) StartFileWatcher(context.Context, []string, strategy.ChunkingConfig) error {
+	return nil
+}
+
+func (s *fakeStrategy) Close() error { return nil }
+
+// newRAGAgent returns an agent with a "docs" RAG tool finding a chunk of
+// install.md and one of README.md, whose paths it returns.
+func newRAGAgent(t *testing.T, prov *fake.ScriptedProvider, opts ...agent.Opt) (a *agent.Agent, install, readme string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	install = filepath.Join(dir, "install.md")
+	readme = filepath.Join(dir, "README.md")
+	require.NoError(t, os.WriteFile(install, []byte("# Install\n\nRun the installer.\n"), 0o644))
+	require.NoError(t, os.WriteFile(readme, []byte("# Project\n"), 0o644))
+
+	manager, err := rag.New(t.Context(), "docs", rag.Config{
+		StrategyConfigs: []strategy.Config{{
+			Name: "fake",
+			Strategy: &fakeStrategy{results: []database.SearchResult{
+				{Document: database.Document{SourcePath: install, Content: "Run the installer."}, Similarity: 0.9},
+				{Document: database.Document{SourcePath: readme, Content: "A project."}, Similarity: 0.5},
+			}},
+		}},
+	}, nil)
+	require.NoError(t, err)
+
+	opts = append([]agent.Opt{
+		agent.WithModel(prov),
+		agent.WithToolSets(builtin.NewRAGTool(manager, "docs")),
+	}, opts...)
+	return agent.New("root", "You are a test agent", opts...), install, readme
+}
+
+// completedAnswer returns the event of the last completed message.
+func completedAnswer(t *testing.T, events []Event) *AgentMessageCompletedEvent {
+	t.Helper()
+
+	var completed *AgentMessageCompletedEvent
+	for _, event := range events {
+		if e, ok := event.(*AgentMessageCompletedEvent); ok {
+			completed = e
+		}
+	}
+	require.NotNil(t, completed)
+	return completed
+}
+
+func TestCitations_Implicit(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().
+			ToolCall("call_1", "docs", `{"query":"install"}`),
+		fake.NewTurn().
+			Content("Run the installer.").
+			Expect(fake.LastMessage(chat.MessageRoleTool, "Run the installer.")),
+	)
+
+	root, install, readme := newRAGAgent(t, prov)
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("How do I install it?"))
+	events := runScripted(t, rt, sess, ResumeApprove())
+
+	// The answer is annotated with all the sources of the turn.
+	want := []chat.Annotation{
+		{Index: 1, RAGName: "docs", Path: install, StartLine: 3, EndLine: 3, Score: 0.9},
+		{Index: 2, RAGName: "docs", Path: readme, Score: 0.5},
+	}
+	assert.Equal(t, want, completedAnswer(t, events).Annotations)
+
+	messages := sess.GetAllMessages()
+	assert.Equal(t, want, messages[len(messages)-1].Message.Annotations)
+}
+
+func TestCitations_Explicit(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().
+			ToolCall("call_1", "docs", `{"query":"install"}`),
+		fake.NewTurn().
+			Content("Run the installer [1].").
+			Expect(fake.LastMessage(chat.MessageRoleTool, "Cite the sources your answer draws on by their index")),
+	)
+
+	root, install, readme := newRAGAgent(t, prov, agent.WithCiteSources(true))
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("How do I install it?"))
+	events := runScripted(t, rt, sess, ResumeApprove())
+
+	// The model was given the index of each source.
+	requests := prov.Requests()
+	last := requests[len(requests)-1].Messages
+	assert.Contains(t, last[len(last)-1].Content, "[1] "+install+":3\n[2] "+readme)
+
+	// The answer is only annotated with the sources it cites.
+	want := []chat.Annotation{
+		{Index: 1, RAGName: "docs", Path: install, StartLine: 3, EndLine: 3, Score: 0.9},
+	}
+	assert.Equal(t, want, completedAnswer(t, events).Annotations)
+}
+
+func TestCitations_ProviderTools(t *testing.T) {
+	search := chat.ProviderToolUse{ID: "ws_1", Tool: "web_search", Queries: []string{"docker agent"}, URLs: []string{"https://docs.docker.com"}}
+	page := chat.Annotation{URL: "https://docs.docker.com", Title: "Docker Docs"}
+	stream := &mockStream{responses: []chat.MessageStreamResponse{
+		{Choices: []chat.MessageStreamChoice{{Delta: chat.MessageDelta{ProviderTools: []chat.ProviderToolUse{search}}}}},
+		{Choices: []chat.MessageStreamChoice{{Delta: chat.MessageDelta{Content: "Docker Agent runs agents.", Annotations: []chat.Annotation{page}}}}},
+		{Choices: []chat.MessageStreamChoice{{Delta: chat.MessageDelta{Content: " Really.", Annotations: []chat.Annotation{page}}}}},
+		{Choices: []chat.MessageStreamChoice{{FinishReason: chat.FinishReasonStop}}},
+	}}
+
+	events := runSession(t, session.New(session.WithUserMessage("What is Docker Agent?")), stream)
+
+	var used []chat.ProviderToolUse
+	for _, event := range events {
+		if e, ok := event.(*ProviderToolEvent); ok {
+			used = append(used, e.ProviderTool)
+		}
+	}
+	assert.Equal(t, []chat.ProviderToolUse{search}, used)
+
+	want := []chat.Annotation{{Index: 1, URL: "https://docs.docker.com", Title: "Docker Docs"}}
+	assert.Equal(t, want, completedAnswer(t, events).Annotations)
+}
+
+func TestAppendProviderSources(t *testing.T) {
+	annotations := []chat.Annotation{{Index: 1, Path: "a.md"}, {Index: 3, Path: "c.md"}}
+	sources := []chat.Annotation{{URL: "https://a.example"}, {URL: "https://b.example"}, {URL: "https://a.example"}, {Title: "No URL"}}
+
+	got := appendProviderSources(annotations, sources)
+	assert.Equal(t, []int{1, 3, 4, 5}, indexesOf(got))
+	assert.Equal(t, "https://b.example", got[3].Location())
+
+	assert.Empty(t, appendProviderSources(nil, nil))
+}
+
+func TestCitedIndexes(t *testing.T) {
+	assert.Equal(t, []int{1, 2, 4}, citedIndexes("See [2] and [4, 1], or [2]. Not [x] nor [^1]."))
+	assert.Empty(t, citedIndexes("No citations."))
+}
+
+func TestSessionRunSources(t *testing.T) {
+	run := newSessionRun("sess", "root")
+
+	first := run.addSources("sess", []chat.Annotation{{Path: "a.md", StartLine: 1, EndLine: 4, Score: 0.4}, {Path: "b.md"}})
+	assert.Equal(t, []int{1, 2}, indexesOf(first))
+
+	// A source retrieved again keeps its index and its best score.
+	second := run.addSources("sess", []chat.Annotation{{Path: "c.md"}, {Path: "a.md", StartLine: 1, EndLine: 4, Score: 0.8}})
+	assert.Equal(t, []int{3, 1}, indexesOf(second))
+
+	sources := run.turnSources("sess")
+	assert.Equal(t, []int{1, 2, 3}, indexesOf(sources))
+	assert.InDelta(t, 0.8, sources[0].Score, 1e-9)
+
+	// The sources of sub-sessions are their own.
+	assert.Empty(t, run.turnSources("sub"))
+}
+
+func indexesOf(annotations []chat.Annotation) []int {
+	indexes := make([]int, 0, len(annotations))
+	for _, a := range annotations {
+		indexes = append(indexes, a.Index)
+	}
+	return indexes
+}
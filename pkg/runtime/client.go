@@ -16,6 +16,7 @@ import (
 	"github.com/docker/docker-agent/pkg/api"
 	"github.com/docker/docker-agent/pkg/config/latest"
 	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
 	"github.com/docker/docker-agent/pkg/tools"
 )
 
@@ -59,35 +60,53 @@ func NewClient(baseURL string, opts ...ClientOption) (*Client, error) {
 			Timeout: 30 * time.Second,
 		},
 		registry: map[string]func() Event{
-			"user_message":           func() Event { return &UserMessageEvent{} },
-			"tool_call":              func() Event { return &ToolCallEvent{} },
-			"tool_call_response":     func() Event { return &ToolCallResponseEvent{} },
-			"tool_call_confirmation": func() Event { return &ToolCallConfirmationEvent{} },
-			"token_usage":            func() Event { return &TokenUsageEvent{} },
-			"stream_stopped":         func() Event { return &StreamStoppedEvent{} },
-			"stream_started":         func() Event { return &StreamStartedEvent{} },
-			"shell":                  func() Event { return &ShellOutputEvent{} },
-			"session_title":          func() Event { return &SessionTitleEvent{} },
-			"session_summary":        func() Event { return &SessionSummaryEvent{} },
-			"session_compaction":     func() Event { return &SessionCompactionEvent{} },
-			"partial_tool_call":      func() Event { return &PartialToolCallEvent{} },
-			"max_iterations_reached": func() Event { return &MaxIterationsReachedEvent{} },
-			"error":                  func() Event { return &ErrorEvent{} },
-			"elicitation_request":    func() Event { return &ElicitationRequestEvent{} },
-			"authorization_event":    func() Event { return &AuthorizationEvent{} },
-			"agent_choice":           func() Event { return &AgentChoiceEvent{} },
-			"agent_choice_reasoning": func() Event { return &AgentChoiceReasoningEvent{} },
-			"mcp_init_started":       func() Event { return &MCPInitStartedEvent{} },
-			"mcp_init_finished":      func() Event { return &MCPInitFinishedEvent{} },
-			"agent_info":             func() Event { return &AgentInfoEvent{} },
-			"team_info":              func() Event { return &TeamInfoEvent{} },
-			"toolset_info":           func() Event { return &ToolsetInfoEvent{} },
-			"agent_switching":        func() Event { return &AgentSwitchingEvent{} },
-			"warning":                func() Event { return &WarningEvent{} },
-			"hook_blocked":           func() Event { return &HookBlockedEvent{} },
-			"rag_indexing_started":   func() Event { return &RAGIndexingStartedEvent{} },
-			"rag_indexing_progress":  func() Event { return &RAGIndexingProgressEvent{} },
-			"rag_indexing_completed": func() Event { return &RAGIndexingCompletedEvent{} },
+			"user_message":                func() Event { return &UserMessageEvent{} },
+			"tool_call":                   func() Event { return &ToolCallEvent{} },
+			"tool_call_response":          func() Event { return &ToolCallResponseEvent{} },
+			"tool_call_confirmation":      func() Event { return &ToolCallConfirmationEvent{} },
+			"token_usage":                 func() Event { return &TokenUsageEvent{} },
+			"throttled":                   func() Event { return &ThrottledEvent{} },
+			"model_routed":                func() Event { return &ModelRoutedEvent{} },
+			"stream_stopped":              func() Event { return &StreamStoppedEvent{} },
+			"run_completed":               func() Event { return &RunCompletedEvent{} },
+			"stream_started":              func() Event { return &StreamStartedEvent{} },
+			"shell":                       func() Event { return &ShellOutputEvent{} },
+			"session_title":               func() Event { return &SessionTitleEvent{} },
+			"session_summary":             func() Event { return &SessionSummaryEvent{} },
+			"session_compaction":          func() Event { return &SessionCompactionEvent{} },
+			"checkpoint_created":          func() Event { return &CheckpointCreatedEvent{} },
+			"session_rolled_back":         func() Event { return &SessionRolledBackEvent{} },
+			"partial_tool_call":           func() Event { return &PartialToolCallEvent{} },
+			"max_iterations_reached":      func() Event { return &MaxIterationsReachedEvent{} },
+			"error":                       func() Event { return &ErrorEvent{} },
+			"elicitation_request":         func() Event { return &ElicitationRequestEvent{} },
+			"authorization_event":         func() Event { return &AuthorizationEvent{} },
+			"agent_choice":                func() Event { return &AgentChoiceEvent{} },
+			"agent_choice_reasoning":      func() Event { return &AgentChoiceReasoningEvent{} },
+			"agent_thought":               func() Event { return &AgentThoughtEvent{} },
+			"agent_message_completed":     func() Event { return &AgentMessageCompletedEvent{} },
+			"provider_tool":               func() Event { return &ProviderToolEvent{} },
+			"mcp_init_started":            func() Event { return &MCPInitStartedEvent{} },
+			"mcp_init_finished":           func() Event { return &MCPInitFinishedEvent{} },
+			"mcp_sampling":                func() Event { return &MCPSamplingEvent{} },
+			"agent_info":                  func() Event { return &AgentInfoEvent{} },
+			"team_info":                   func() Event { return &TeamInfoEvent{} },
+			"toolset_info":                func() Event { return &ToolsetInfoEvent{} },
+			"agent_switching":             func() Event { return &AgentSwitchingEvent{} },
+			"agent_handoff":               func() Event { return &AgentHandoffEvent{} },
+			"handoff_loop_detected":       func() Event { return &HandoffLoopDetectedEvent{} },
+			"guard_rejected":              func() Event { return &GuardRejectedEvent{} },
+			"config_reloaded":             func() Event { return &ConfigReloadedEvent{} },
+			"blackboard_updated":          func() Event { return &BlackboardUpdatedEvent{} },
+			"warning":                     func() Event { return &WarningEvent{} },
+			"hook_blocked":                func() Event { return &HookBlockedEvent{} },
+			"tool_call_validation_failed": func() Event { return &ToolCallValidationFailedEvent{} },
+			"tool_call_timeout":           func() Event { return &ToolCallTimeoutEvent{} },
+			"tool_call_queued":            func() Event { return &ToolCallQueuedEvent{} },
+			"file_changed":                func() Event { return &FileChangedEvent{} },
+			"rag_indexing_started":        func() Event { return &RAGIndexingStartedEvent{} },
+			"rag_indexing_progress":       func() Event { return &RAGIndexingProgressEvent{} },
+			"rag_indexing_completed":      func() Event { return &RAGIndexingCompletedEvent{} },
 		},
 	}
 
@@ -168,6 +187,14 @@ func (c *Client) GetAgent(ctx context.Context, id string) (*latest.Config, error
 	return &config, err
 }
 
+// DescribeAgent describes the team of an agent by ID: its agents, their
+// toolsets and how they delegate to each other.
+func (c *Client) DescribeAgent(ctx context.Context, id string) (*team.Description, error) {
+	var desc team.Description
+	err := c.doRequest(ctx, http.MethodGet, "/api/agents/"+id+"/describe", nil, &desc)
+	return &desc, err
+}
+
 // CreateAgent creates a new agent using a prompt
 func (c *Client) CreateAgent(ctx context.Context, prompt string) (*api.CreateAgentResponse, error) {
 	req := api.CreateAgentRequest{Prompt: prompt}
@@ -261,8 +288,8 @@ func (c *Client) CreateSession(ctx context.Context, sessTemplate *session.Sessio
 }
 
 // ResumeSession resumes a session by ID with optional rejection reason or tool name
-func (c *Client) ResumeSession(ctx context.Context, id, confirmation, reason, toolName string) error {
-	req := api.ResumeSessionRequest{Confirmation: confirmation, Reason: reason, ToolName: toolName}
+func (c *Client) ResumeSession(ctx context.Context, id, confirmation, reason, toolName, arguments string) error {
+	req := api.ResumeSessionRequest{Confirmation: confirmation, Reason: reason, ToolName: toolName, Arguments: json.RawMessage(arguments)}
 	return c.doRequest(ctx, http.MethodPost, "/api/sessions/"+id+"/resume", req, nil)
 }
 
@@ -0,0 +1,108 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// errRuntimeClosed is reported by the streams started after Close.
+var errRuntimeClosed = errors.New("runtime is closed")
+
+// closeState tracks the streams in flight so that Close can cancel them and
+// wait for them to end.
+type closeState struct {
+	mu     sync.Mutex
+	closed bool
+	// ctx is cancelled by Close; the streams are cancelled with it.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// streams counts the RunStream goroutines that are still running.
+	streams sync.WaitGroup
+}
+
+// trackStream registers a new stream. It returns the context the stream must
+// run with, cancelled when the runtime is closed, and a function to call once
+// the stream's events channel is closed. ok is false if the runtime is
+// already closed.
+func (r *LocalRuntime) trackStream(ctx context.Context) (_ context.Context, done func(), ok bool) {
+	s := &r.closeState
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ctx, nil, false
+	}
+	if s.ctx == nil {
+		s.ctx, s.cancel = context.WithCancel(context.Background())
+	}
+	s.streams.Add(1)
+
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(s.ctx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+		s.streams.Done()
+	}, true
+}
+
+// Close shuts the runtime down. It cancels the streams in flight and waits
+// for their events channels to be closed, then stops the background agents
+// and the toolsets of all the agents (MCP and LSP servers, RAG indexers and
+// their file watchers...), and closes the session store.
+//
+// Waiting for the streams is bounded by ctx: when ctx is done first, the
+// rest of the teardown still happens and ctx's error is returned along with
+// the other failures. Close is safe to call while RunStream is running, and
+// more than once.
+func (r *LocalRuntime) Close(ctx context.Context) error {
+	return r.shutdown(ctx, true)
+}
+
+// shutdown is Close. The toolsets are only stopped when stopToolSets is
+// true: runtimes created on a team they don't own, like the ones of RunOnce,
+// leave them running for the other runtimes sharing the team.
+func (r *LocalRuntime) shutdown(ctx context.Context, stopToolSets bool) error {
+	s := &r.closeState
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.mu.Unlock()
+
+	var errs []error
+
+	streamsDone := make(chan struct{})
+	go func() {
+		s.streams.Wait()
+		close(streamsDone)
+	}()
+	select {
+	case <-streamsDone:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("waiting for running streams: %w", ctx.Err()))
+	}
+
+	r.bgAgents.StopAll()
+
+	if stopToolSets {
+		if err := r.Team().StopToolSets(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if r.sessionStore != nil {
+		if err := r.sessionStore.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("closing session store: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
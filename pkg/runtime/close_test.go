@@ -0,0 +1,163 @@
+package runtime
+
+import (
+	"context"
+	goruntime "runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/model/provider/base"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// countingStopToolSet counts how many times it is stopped.
+type countingStopToolSet struct {
+	stops atomic.Int32
+}
+
+func (s *countingStopToolSet) Start(context.Context) error { return nil }
+func (s *countingStopToolSet) Stop(context.Context) error {
+	s.stops.Add(1)
+	return nil
+}
+func (s *countingStopToolSet) Tools(context.Context) ([]tools.Tool, error) { return nil, nil }
+
+// blockingProvider returns streams that block until their context is
+// cancelled, or until release is closed when the context is ignored.
+type blockingProvider struct {
+	id        string
+	ignoreCtx bool
+	release   chan struct{}
+}
+
+func (p *blockingProvider) ID() string { return p.id }
+func (p *blockingProvider) CreateChatCompletionStream(ctx context.Context, _ []chat.Message, _ []tools.Tool) (chat.MessageStream, error) {
+	return &blockingStream{ctx: ctx, provider: p}, nil
+}
+func (p *blockingProvider) BaseConfig() base.Config { return base.Config{} }
+func (p *blockingProvider) MaxTokens() int          { return 0 }
+
+type blockingStream struct {
+	ctx      context.Context
+	provider *blockingProvider
+}
+
+func (s *blockingStream) Recv() (chat.MessageStreamResponse, error) {
+	if s.provider.ignoreCtx {
+		<-s.provider.release
+		return chat.MessageStreamResponse{}, context.Canceled
+	}
+	<-s.ctx.Done()
+	return chat.MessageStreamResponse{}, s.ctx.Err()
+}
+
+func (s *blockingStream) Close() {}
+
+// waitForStreamStarted consumes events until the stream has started.
+func waitForStreamStarted(t *testing.T, events <-chan Event) {
+	t.Helper()
+	for ev := range events {
+		if _, ok := ev.(*StreamStartedEvent); ok {
+			return
+		}
+	}
+	t.Fatal("stream ended before starting")
+}
+
+func TestClose_CancelsRunningStream(t *testing.T) {
+	before := goruntime.NumGoroutine()
+
+	shared := &countingStopToolSet{}
+	prov := &blockingProvider{id: "test/blocking"}
+	root := agent.New("root", "You are a test agent", agent.WithModel(prov), agent.WithToolSets(shared))
+	helper := agent.New("helper", "You are a test agent", agent.WithModel(prov), agent.WithToolSets(shared))
+
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root, helper)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	// Both agents started the toolset they share.
+	_, err = helper.Tools(t.Context())
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Hi"))
+	sess.Title = "Close Test"
+	events := rt.RunStream(t.Context(), sess)
+	waitForStreamStarted(t, events)
+
+	drained := make(chan struct{})
+	go func() {
+		for range events {
+		}
+		close(drained)
+	}()
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, rt.Close(ctx))
+
+	select {
+	case <-drained:
+	case <-time.After(time.Second):
+		t.Fatal("events channel still open after Close")
+	}
+	assert.Equal(t, int32(1), shared.stops.Load(), "a shared toolset must be stopped once")
+
+	// Closing again is a no-op.
+	require.NoError(t, rt.Close(t.Context()))
+	assert.Equal(t, int32(1), shared.stops.Load())
+
+	// Not polled with assert.Eventually: it runs the condition in a goroutine
+	// of its own.
+	for deadline := time.Now().Add(2 * time.Second); goruntime.NumGoroutine() > before && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, goruntime.NumGoroutine(), before, "goroutines leaked")
+}
+
+func TestClose_BoundedByContext(t *testing.T) {
+	prov := &blockingProvider{id: "test/blocking", ignoreCtx: true, release: make(chan struct{})}
+	root := agent.New("root", "You are a test agent", agent.WithModel(prov))
+
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Hi"))
+	sess.Title = "Close Test"
+	events := rt.RunStream(t.Context(), sess)
+	waitForStreamStarted(t, events)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	err = rt.Close(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Let the stream end so that the test doesn't leak it.
+	close(prov.release)
+	for range events {
+	}
+}
+
+func TestRunStream_AfterClose(t *testing.T) {
+	root := agent.New("root", "You are a test agent", agent.WithModel(&mockProvider{id: "test/mock-model"}))
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+	require.NoError(t, rt.Close(t.Context()))
+
+	var got []Event
+	for ev := range rt.RunStream(t.Context(), session.New(session.WithUserMessage("Hi"))) {
+		got = append(got, ev)
+	}
+	require.Len(t, got, 1)
+	errEvent, ok := got[0].(*ErrorEvent)
+	require.True(t, ok)
+	assert.Equal(t, "runtime is closed", errEvent.Error)
+	assert.Equal(t, ErrorCodeRuntimeClosed, errEvent.Code)
+}
@@ -44,8 +44,15 @@ func (m *mockRuntime) RunStream(context.Context, *session.Session) <-chan Event
 func (m *mockRuntime) Run(context.Context, *session.Session) ([]session.Message, error) {
 	return nil, nil
 }
-func (m *mockRuntime) Resume(context.Context, ResumeRequest) {}
-func (m *mockRuntime) ResumeElicitation(context.Context, tools.ElicitationAction, map[string]any) error {
+func (m *mockRuntime) ContinueStream(context.Context, *session.Session, string) <-chan Event {
+	return nil
+}
+
+func (m *mockRuntime) Continue(context.Context, *session.Session, string) ([]session.Message, error) {
+	return nil, nil
+}
+func (m *mockRuntime) Resume(context.Context, string, ResumeRequest) error { return nil }
+func (m *mockRuntime) ResumeElicitation(context.Context, string, tools.ElicitationAction, map[string]any) error {
 	return nil
 }
 func (m *mockRuntime) SessionStore() session.Store { return nil }
@@ -60,6 +67,10 @@ func (m *mockRuntime) CurrentMCPPrompts(context.Context) map[string]mcptools.Pro
 	return make(map[string]mcptools.PromptInfo)
 }
 
+func (m *mockRuntime) CurrentMCPResources(context.Context) map[string]mcptools.ResourceInfo {
+	return make(map[string]mcptools.ResourceInfo)
+}
+
 func (m *mockRuntime) ExecuteMCPPrompt(context.Context, string, map[string]string) (string, error) {
 	return "", nil
 }
@@ -68,7 +79,7 @@ func (m *mockRuntime) UpdateSessionTitle(context.Context, *session.Session, stri
 	return nil
 }
 func (m *mockRuntime) TitleGenerator() *sessiontitle.Generator { return nil }
-func (m *mockRuntime) Close() error                            { return nil }
+func (m *mockRuntime) Close(context.Context) error             { return nil }
 func (m *mockRuntime) Steer(QueuedMessage) error               { return nil }
 func (m *mockRuntime) FollowUp(QueuedMessage) error            { return nil }
 
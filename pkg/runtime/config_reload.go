@@ -0,0 +1,214 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
+	mcptools "github.com/docker/docker-agent/pkg/tools/mcp"
+)
+
+// ConfigReloader is implemented by runtimes that can reload their agent
+// configuration while a session is running.
+type ConfigReloader interface {
+	// ReloadConfig rebuilds the team from the agent configuration. The new
+	// team replaces the current one between turns, never while a stream is
+	// running. If the configuration is invalid, the current team is kept.
+	ReloadConfig(ctx context.Context)
+	// OnConfigReload registers a handler that receives the events describing
+	// the outcome of each reload: a ConfigReloadedEvent followed by the new
+	// agent, team and toolset information, or a WarningEvent.
+	OnConfigReload(handler func(Event))
+}
+
+var _ ConfigReloader = (*LocalRuntime)(nil)
+
+type configReloadState struct {
+	loader func() (*team.Team, error)
+
+	// reloading serializes reloads.
+	reloading sync.Mutex
+
+	mu      sync.Mutex
+	handler func(Event)
+	// activeStreams counts the RunStream calls in progress, including
+	// those of sub-sessions and background agents.
+	activeStreams int
+	// pending is the last team loaded while a stream was running.
+	pending *team.Team
+}
+
+// WithConfigReload enables ReloadConfig. loader is called on each reload to
+// build a new team from the current agent configuration; it should validate
+// the configuration and return an error describing the problems if it's
+// invalid.
+func WithConfigReload(loader func() (*team.Team, error)) Opt {
+	return func(r *LocalRuntime) {
+		r.configReload.loader = loader
+	}
+}
+
+// OnConfigReload registers the handler that receives the events of config reloads.
+func (r *LocalRuntime) OnConfigReload(handler func(Event)) {
+	r.configReload.mu.Lock()
+	defer r.configReload.mu.Unlock()
+	r.configReload.handler = handler
+}
+
+// ReloadConfig rebuilds the team with the loader given to WithConfigReload.
+// The new team is swapped in right away if no stream is running, otherwise
+// when the last running stream ends.
+func (r *LocalRuntime) ReloadConfig(ctx context.Context) {
+	if r.configReload.loader == nil {
+		return
+	}
+
+	r.configReload.reloading.Lock()
+	defer r.configReload.reloading.Unlock()
+
+	newTeam, err := r.configReload.loader()
+	if err == nil {
+		_, err = newTeam.DefaultAgent()
+	}
+	if err != nil {
+		slog.Warn("Agent configuration not reloaded", "error", err)
+		r.notifyConfigReload(ctx, nil, WarningWithCode(ErrorCodeAgentConfigReloadFailed, fmt.Sprintf("Agent configuration not reloaded, keeping the previous one: %v", err), "", r.CurrentAgentName()))
+		return
+	}
+
+	r.configReload.mu.Lock()
+	r.configReload.pending = newTeam
+	idle := r.configReload.activeStreams == 0
+	r.configReload.mu.Unlock()
+
+	if idle {
+		r.applyPendingTeam(ctx)
+	} else {
+		slog.Debug("Agent configuration reloaded; waiting for the current turn to end")
+	}
+}
+
+// streamStarted records that a stream is running so that reloads wait for
+// it to end. It returns a function to call when the stream ends.
+func (r *LocalRuntime) streamStarted(ctx context.Context) func() {
+	r.configReload.mu.Lock()
+	r.configReload.activeStreams++
+	r.configReload.mu.Unlock()
+
+	return func() {
+		r.configReload.mu.Lock()
+		r.configReload.activeStreams--
+		apply := r.configReload.activeStreams == 0 && r.configReload.pending != nil
+		r.configReload.mu.Unlock()
+
+		if apply {
+			r.applyPendingTeam(context.WithoutCancel(ctx))
+		}
+	}
+}
+
+// applyPendingTeam swaps the team loaded by the last reload in.
+func (r *LocalRuntime) applyPendingTeam(ctx context.Context) {
+	r.configReload.mu.Lock()
+	newTeam := r.configReload.pending
+	r.configReload.pending = nil
+	r.configReload.mu.Unlock()
+
+	if newTeam == nil {
+		return
+	}
+
+	r.teamMu.Lock()
+	oldTeam := r.team
+	// What the agents wrote to the shared context is kept.
+	newTeam.SetBlackboard(oldTeam.Blackboard())
+	r.team = newTeam
+	r.teamMu.Unlock()
+
+	// The current agent might have been renamed or removed.
+	if _, err := newTeam.Agent(r.CurrentAgentName()); err != nil {
+		defaultAgent, _ := newTeam.DefaultAgent()
+		r.setCurrentAgent(defaultAgent.Name())
+	}
+
+	// Models might have changed, forget about previous failures.
+	r.fallbackCooldownsMux.Lock()
+	r.fallbackCooldowns = make(map[string]*fallbackCooldownState)
+	r.fallbackCooldownsMux.Unlock()
+
+	if r.onToolsChanged != nil {
+		r.setToolsChangedHandlers(newTeam)
+	}
+
+	stopReplacedToolSets(ctx, oldTeam, newTeam)
+
+	slog.Debug("Agent configuration reloaded", "agent", r.CurrentAgentName(), "available_agents", newTeam.Size())
+
+	r.notifyConfigReload(ctx, r.CurrentAgent(), ConfigReloaded(r.CurrentAgentName()))
+}
+
+// notifyConfigReload sends event to the registered handler. After a
+// successful reload, a is the new current agent, and the information shown
+// at startup is sent again.
+func (r *LocalRuntime) notifyConfigReload(ctx context.Context, a *agent.Agent, event Event) {
+	r.configReload.mu.Lock()
+	handler := r.configReload.handler
+	r.configReload.mu.Unlock()
+
+	if handler == nil {
+		return
+	}
+
+	handler(event)
+	if a == nil {
+		return
+	}
+
+	handler(AgentInfo(a.Name(), r.getEffectiveModelID(a), a.Description(), a.WelcomeMessage()))
+	handler(TeamInfo(r.agentDetailsFromTeam(), a.Name()))
+	r.emitAgentWarnings(a, handler)
+	r.emitToolsProgressively(ctx, a, func(e Event) bool {
+		handler(e)
+		return true
+	})
+}
+
+// stopReplacedToolSets stops the toolsets of the old team, except for the
+// MCP toolsets that were carried over to the new team.
+func stopReplacedToolSets(ctx context.Context, oldTeam, newTeam *team.Team) {
+	kept := map[*mcptools.Toolset]bool{}
+	forEachToolSet(newTeam, func(ts tools.ToolSet) {
+		if mcpToolset, ok := tools.As[*mcptools.Toolset](ts); ok {
+			kept[mcpToolset] = true
+		}
+	})
+
+	forEachToolSet(oldTeam, func(ts tools.ToolSet) {
+		if mcpToolset, ok := tools.As[*mcptools.Toolset](ts); ok && kept[mcpToolset] {
+			return
+		}
+		startable, ok := ts.(*tools.StartableToolSet)
+		if !ok || !startable.IsStarted() {
+			return
+		}
+		if err := startable.Stop(ctx); err != nil {
+			slog.Warn("Failed to stop toolset after reloading the agent configuration", "toolset", tools.DescribeToolSet(ts), "error", err)
+		}
+	})
+}
+
+func forEachToolSet(t *team.Team, fn func(tools.ToolSet)) {
+	for _, name := range t.AgentNames() {
+		a, err := t.Agent(name)
+		if err != nil {
+			continue
+		}
+		for _, ts := range a.ToolSets() {
+			fn(ts)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package runtime
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+type reloadEvents struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *reloadEvents) handle(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *reloadEvents) snapshot() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.events)
+}
+
+func newReloadTestTeam(agentNames ...string) *team.Team {
+	var agents []*agent.Agent
+	for _, name := range agentNames {
+		agents = append(agents, agent.New(name, "You are a test agent",
+			agent.WithModel(&mockProvider{id: "test/mock-model"}),
+			agent.WithToolSets(newStubToolSet(nil, []tools.Tool{{Name: name + "_tool"}}, nil)),
+		))
+	}
+	return team.New(team.WithAgents(agents...))
+}
+
+func TestReloadConfig_SwapsTeam(t *testing.T) {
+	initial := newReloadTestTeam("root", "helper")
+	reloaded := newReloadTestTeam("main")
+
+	rt, err := NewLocalRuntime(initial,
+		WithCurrentAgent("helper"),
+		WithModelStore(mockModelStore{}),
+		WithConfigReload(func() (*team.Team, error) { return reloaded, nil }),
+	)
+	require.NoError(t, err)
+
+	var events reloadEvents
+	rt.OnConfigReload(events.handle)
+
+	helper, err := initial.Agent("helper")
+	require.NoError(t, err)
+	_, err = helper.Tools(t.Context())
+	require.NoError(t, err)
+
+	rt.ReloadConfig(t.Context())
+
+	assert.Same(t, reloaded, rt.Team())
+	// The current agent no longer exists, the default agent is used instead.
+	assert.Equal(t, "main", rt.CurrentAgentName())
+	assert.True(t, hasEventType(t, events.snapshot(), &ConfigReloadedEvent{}))
+
+	// The toolsets of the previous team are stopped.
+	for _, ts := range helper.ToolSets() {
+		assert.False(t, ts.(*tools.StartableToolSet).IsStarted())
+	}
+}
+
+func TestReloadConfig_InvalidConfigKeepsTeam(t *testing.T) {
+	initial := newReloadTestTeam("root")
+
+	rt, err := NewLocalRuntime(initial,
+		WithModelStore(mockModelStore{}),
+		WithConfigReload(func() (*team.Team, error) {
+			return nil, errors.New("agents.root.sub_agents[0]: agent 'root' references non-existent sub-agent 'missing'")
+		}),
+	)
+	require.NoError(t, err)
+
+	var events reloadEvents
+	rt.OnConfigReload(events.handle)
+
+	rt.ReloadConfig(t.Context())
+
+	assert.Same(t, initial, rt.Team())
+	assert.True(t, hasEventType(t, events.snapshot(), &WarningEvent{}))
+	assert.False(t, hasEventType(t, events.snapshot(), &ConfigReloadedEvent{}))
+}
+
+func TestReloadConfig_WaitsForRunningStream(t *testing.T) {
+	initial := newReloadTestTeam("root")
+	reloaded := newReloadTestTeam("root")
+
+	rt, err := NewLocalRuntime(initial,
+		WithModelStore(mockModelStore{}),
+		WithConfigReload(func() (*team.Team, error) { return reloaded, nil }),
+	)
+	require.NoError(t, err)
+
+	streamEnded := rt.streamStarted(t.Context())
+	rt.ReloadConfig(t.Context())
+	assert.Same(t, initial, rt.Team())
+
+	streamEnded()
+	assert.Same(t, reloaded, rt.Team())
+}
@@ -0,0 +1,91 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/config/latest"
+	"github.com/docker/docker-agent/pkg/environment"
+	"github.com/docker/docker-agent/pkg/model/provider"
+	"github.com/docker/docker-agent/pkg/model/provider/options"
+	"github.com/docker/docker-agent/pkg/modelsdev"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// countingTransport fails, and counts, the requests sent over HTTP.
+type countingTransport struct {
+	requests atomic.Int32
+}
+
+func (t *countingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	t.requests.Add(1)
+	return nil, errors.New("unexpected HTTP request")
+}
+
+// recordingToolSet records whether it was started.
+type recordingToolSet struct {
+	started atomic.Bool
+}
+
+func (s *recordingToolSet) Start(context.Context) error {
+	s.started.Store(true)
+	return nil
+}
+func (s *recordingToolSet) Stop(context.Context) error                  { return nil }
+func (s *recordingToolSet) Tools(context.Context) ([]tools.Tool, error) { return nil, nil }
+
+// newPerRequestRuntime builds a team and a runtime the way a server does for
+// each request. The model sends its requests with transport.
+func newPerRequestRuntime(ctx context.Context, modelsStore ModelStore, transport http.RoundTripper, toolSets ...tools.ToolSet) (Runtime, error) {
+	env := environment.NewMapEnvProvider(map[string]string{"OPENAI_API_KEY": "dummy"})
+	model, err := provider.New(ctx, &latest.ModelConfig{Provider: "openai", Model: "gpt-4o"}, env, options.WithDeferredClient(), options.WithHTTPTransport(transport))
+	if err != nil {
+		return nil, err
+	}
+
+	root := agent.New("root", "You are a test agent", agent.WithModel(model), agent.WithToolSets(toolSets...))
+	return New(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(modelsStore))
+}
+
+func TestNew_NoNetworkCalls(t *testing.T) {
+	t.Parallel()
+
+	transport := &countingTransport{}
+	toolSet := &recordingToolSet{}
+	// Without a cache, loading the models catalog would fetch it: that's
+	// left to the first run.
+	modelsStore := modelsdev.NewFileStore(filepath.Join(t.TempDir(), modelsdev.CacheFileName), transport)
+	_, err := newPerRequestRuntime(t.Context(), modelsStore, transport, toolSet)
+	require.NoError(t, err)
+
+	assert.Zero(t, transport.requests.Load(), "building a runtime shouldn't send HTTP requests")
+	assert.False(t, toolSet.started.Load(), "building a runtime shouldn't start the toolsets")
+}
+
+// BenchmarkNew measures the construction of a team and of its runtime, as
+// done for each request, with the models catalog already loaded. It's
+// expected to stay well under a millisecond.
+func BenchmarkNew(b *testing.B) {
+	modelsStore := modelsdev.NewDatabaseStore(&modelsdev.Database{
+		Providers: map[string]modelsdev.Provider{
+			"openai": {Models: map[string]modelsdev.Model{"gpt-4o": {Name: "GPT-4o"}}},
+		},
+	})
+	_, err := modelsStore.GetDatabase(b.Context())
+	require.NoError(b, err)
+
+	for b.Loop() {
+		if _, err := newPerRequestRuntime(b.Context(), modelsStore, &countingTransport{}, &recordingToolSet{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker-agent/pkg/session"
+)
+
+// ContinueStream appends a user message to a session that already ran, e.g.
+// with Run, and re-enters the agent's interaction loop, like the initial run
+// did: StreamStarted and StreamStopped are emitted again, and so is a
+// UserMessage event when the session has SendUserMessage set.
+//
+// The runtime keeps its state between runs: the toolsets started by the
+// previous runs are reused as they are, and the tools approved for the
+// session stay approved. If the session was compacted, the follow-up is sent
+// after the summary and the messages kept aside.
+//
+// It must not be called while another stream of the session is running.
+func (r *LocalRuntime) ContinueStream(ctx context.Context, sess *session.Session, userMessage string) <-chan Event {
+	sess.AddMessage(session.UserMessage(userMessage))
+	return r.RunStream(ctx, sess)
+}
+
+// Continue is the blocking counterpart of ContinueStream: it appends a user
+// message to the session, runs the agent loop and returns the final session
+// messages.
+func (r *LocalRuntime) Continue(ctx context.Context, sess *session.Session, userMessage string) ([]session.Message, error) {
+	return drainRun(r.ContinueStream(ctx, sess, userMessage), sess)
+}
+
+// ContinueStream appends a user message to the session and re-enters the
+// agent loop, persisting the session like RunStream does.
+func (r *PersistentRuntime) ContinueStream(ctx context.Context, sess *session.Session, userMessage string) <-chan Event {
+	sess.AddMessage(session.UserMessage(userMessage))
+	return r.RunStream(ctx, sess)
+}
+
+// Continue is the blocking counterpart of ContinueStream.
+func (r *PersistentRuntime) Continue(ctx context.Context, sess *session.Session, userMessage string) ([]session.Message, error) {
+	return drainRun(r.ContinueStream(ctx, sess, userMessage), sess)
+}
+
+// ContinueStream appends a user message to the session and runs the agent
+// on the remote server with the whole session.
+func (r *RemoteRuntime) ContinueStream(ctx context.Context, sess *session.Session, userMessage string) <-chan Event {
+	sess.AddMessage(session.UserMessage(userMessage))
+	return r.RunStream(ctx, sess)
+}
+
+// Continue is the blocking counterpart of ContinueStream.
+func (r *RemoteRuntime) Continue(ctx context.Context, sess *session.Session, userMessage string) ([]session.Message, error) {
+	return drainRun(r.ContinueStream(ctx, sess, userMessage), sess)
+}
+
+// drainRun consumes the events of a run and returns the session's messages
+// once it's over, or the first error reported by the run.
+func drainRun(events <-chan Event, sess *session.Session) ([]session.Message, error) {
+	var err error
+	for event := range events {
+		if errEvent, ok := event.(*ErrorEvent); ok && err == nil {
+			err = fmt.Errorf("%s", errEvent.Error)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sess.GetAllMessages(), nil
+}
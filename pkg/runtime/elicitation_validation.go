@@ -0,0 +1,213 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ElicitationViolation describes why a field of an elicitation response
+// doesn't match the requested schema.
+type ElicitationViolation struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ElicitationValidationError is returned by ResumeElicitation when the
+// submitted content doesn't match the requested schema. The elicitation
+// request stays pending, so the client can prompt the user again.
+type ElicitationValidationError struct {
+	Violations []ElicitationViolation `json:"violations"`
+}
+
+func (e *ElicitationValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.Field + ": " + v.Message
+	}
+	return "invalid elicitation content: " + strings.Join(msgs, "; ")
+}
+
+// validateElicitationContent checks content against the schema of an
+// elicitation request. Only the flat object schemas of the MCP specification
+// are checked: required fields, primitive types, enums, string lengths,
+// number ranges and the email, uri, date and date-time formats. Any content
+// is valid when there is no such schema.
+func validateElicitationContent(schema any, content map[string]any) []ElicitationViolation {
+	root := schemaToMap(schema)
+	if root == nil {
+		return nil
+	}
+	if typ, _ := root["type"].(string); typ != "" && typ != "object" {
+		return nil
+	}
+	properties, _ := root["properties"].(map[string]any)
+
+	var violations []ElicitationViolation
+	if required, ok := root["required"].([]any); ok {
+		for _, r := range required {
+			name, ok := r.(string)
+			if !ok {
+				continue
+			}
+			if value, ok := content[name]; !ok || value == nil {
+				violations = append(violations, ElicitationViolation{Field: name, Message: "is required"})
+			}
+		}
+	}
+
+	for name, value := range content {
+		prop, ok := properties[name].(map[string]any)
+		if !ok || value == nil {
+			continue
+		}
+		if msg := validateElicitationValue(prop, value); msg != "" {
+			violations = append(violations, ElicitationViolation{Field: name, Message: msg})
+		}
+	}
+
+	slices.SortStableFunc(violations, func(a, b ElicitationViolation) int {
+		return strings.Compare(a.Field, b.Field)
+	})
+	return violations
+}
+
+// validateElicitationValue returns why value doesn't match the property
+// schema prop, or an empty string if it does.
+func validateElicitationValue(prop map[string]any, value any) string {
+	typ, _ := prop["type"].(string)
+	enum, _ := prop["enum"].([]any)
+	if oneOf, ok := prop["oneOf"].([]any); ok {
+		for _, entry := range oneOf {
+			if e, ok := entry.(map[string]any); ok && e["const"] != nil {
+				enum = append(enum, e["const"])
+			}
+		}
+	}
+
+	switch {
+	case typ == "string" || (typ == "" && len(enum) > 0):
+		s, ok := value.(string)
+		if !ok {
+			return "must be a string"
+		}
+		return validateElicitationString(prop, enum, s)
+
+	case typ == "number" || typ == "integer":
+		n, ok := toFloat(value)
+		if !ok {
+			return "must be a number"
+		}
+		if typ == "integer" && n != math.Trunc(n) {
+			return "must be an integer"
+		}
+		if minimum, ok := toFloat(prop["minimum"]); ok && n < minimum {
+			return fmt.Sprintf("must be at least %g", minimum)
+		}
+		if maximum, ok := toFloat(prop["maximum"]); ok && n > maximum {
+			return fmt.Sprintf("must be at most %g", maximum)
+		}
+
+	case typ == "boolean":
+		if _, ok := value.(bool); !ok {
+			return "must be a boolean"
+		}
+
+	case typ == "array":
+		items, ok := value.([]any)
+		if !ok {
+			return "must be an array"
+		}
+		itemSchema, _ := prop["items"].(map[string]any)
+		for _, item := range items {
+			if msg := validateElicitationValue(itemSchema, item); msg != "" {
+				return "items " + msg
+			}
+		}
+	}
+
+	return ""
+}
+
+func validateElicitationString(prop map[string]any, enum []any, s string) string {
+	if len(enum) > 0 && !slices.Contains(enum, any(s)) {
+		options := make([]string, len(enum))
+		for i, e := range enum {
+			options[i] = fmt.Sprint(e)
+		}
+		return "must be one of " + strings.Join(options, ", ")
+	}
+
+	length := float64(utf8.RuneCountInString(s))
+	if minLength, ok := toFloat(prop["minLength"]); ok && length < minLength {
+		return fmt.Sprintf("must be at least %g characters", minLength)
+	}
+	if maxLength, ok := toFloat(prop["maxLength"]); ok && length > maxLength {
+		return fmt.Sprintf("must be at most %g characters", maxLength)
+	}
+
+	format, _ := prop["format"].(string)
+	switch format {
+	case "email":
+		if addr, err := mail.ParseAddress(s); err != nil || addr.Address != s {
+			return "must be a valid email address"
+		}
+	case "uri":
+		if u, err := url.Parse(s); err != nil || u.Scheme == "" {
+			return "must be a valid URI"
+		}
+	case "date":
+		if _, err := time.Parse(time.DateOnly, s); err != nil {
+			return "must be a date (YYYY-MM-DD)"
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			return "must be a date-time (RFC 3339)"
+		}
+	}
+
+	return ""
+}
+
+// schemaToMap converts a requested schema, whatever its Go type, to its JSON
+// object representation, so that lists are []any and numbers float64.
+func schemaToMap(schema any) map[string]any {
+	if schema == nil {
+		return nil
+	}
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil
+	}
+	return m
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
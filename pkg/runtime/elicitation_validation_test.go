@@ -0,0 +1,146 @@
+package runtime
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+var testElicitationSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"name":      map[string]any{"type": "string", "minLength": 2},
+		"email":     map[string]any{"type": "string", "format": "email"},
+		"age":       map[string]any{"type": "integer", "minimum": 0, "maximum": 150},
+		"subscribe": map[string]any{"type": "boolean"},
+		"plan":      map[string]any{"type": "string", "enum": []string{"free", "pro"}},
+		"birthday":  map[string]any{"type": "string", "format": "date"},
+	},
+	"required": []string{"name", "email"},
+}
+
+func TestValidateElicitationContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  any
+		content map[string]any
+		want    []ElicitationViolation
+	}{
+		{
+			name:   "valid",
+			schema: testElicitationSchema,
+			content: map[string]any{
+				"name":      "Ada",
+				"email":     "ada@example.com",
+				"age":       float64(36),
+				"subscribe": true,
+				"plan":      "pro",
+				"birthday":  "1815-12-10",
+			},
+		},
+		{
+			name:    "missing required fields",
+			schema:  testElicitationSchema,
+			content: map[string]any{"age": float64(36), "email": nil},
+			want: []ElicitationViolation{
+				{Field: "email", Message: "is required"},
+				{Field: "name", Message: "is required"},
+			},
+		},
+		{
+			name:   "type mismatches",
+			schema: testElicitationSchema,
+			content: map[string]any{
+				"name":      float64(42),
+				"email":     "ada@example.com",
+				"age":       "thirty-six",
+				"subscribe": "yes",
+			},
+			want: []ElicitationViolation{
+				{Field: "age", Message: "must be a number"},
+				{Field: "name", Message: "must be a string"},
+				{Field: "subscribe", Message: "must be a boolean"},
+			},
+		},
+		{
+			name:   "constraints",
+			schema: testElicitationSchema,
+			content: map[string]any{
+				"name":     "A",
+				"email":    "not an email",
+				"age":      float64(36.5),
+				"plan":     "enterprise",
+				"birthday": "10/12/1815",
+			},
+			want: []ElicitationViolation{
+				{Field: "age", Message: "must be an integer"},
+				{Field: "birthday", Message: "must be a date (YYYY-MM-DD)"},
+				{Field: "email", Message: "must be a valid email address"},
+				{Field: "name", Message: "must be at least 2 characters"},
+				{Field: "plan", Message: "must be one of free, pro"},
+			},
+		},
+		{
+			name:    "out of range",
+			schema:  testElicitationSchema,
+			content: map[string]any{"name": "Ada", "email": "ada@example.com", "age": float64(200)},
+			want:    []ElicitationViolation{{Field: "age", Message: "must be at most 150"}},
+		},
+		{
+			name: "oneOf options",
+			schema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"color": map[string]any{
+						"type": "string",
+						"oneOf": []any{
+							map[string]any{"const": "red", "title": "Red"},
+							map[string]any{"const": "blue", "title": "Blue"},
+						},
+					},
+				},
+			},
+			content: map[string]any{"color": "green"},
+			want:    []ElicitationViolation{{Field: "color", Message: "must be one of red, blue"}},
+		},
+		{
+			name:    "unknown fields are ignored",
+			schema:  testElicitationSchema,
+			content: map[string]any{"name": "Ada", "email": "ada@example.com", "extra": float64(1)},
+		},
+		{
+			name:    "no schema",
+			content: map[string]any{"anything": []any{"goes"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, validateElicitationContent(tt.schema, tt.content))
+		})
+	}
+}
+
+func TestResumeElicitation_InvalidContent(t *testing.T) {
+	run := newSessionRun("session-1", "root")
+	run.elicitationRequestCh = make(chan ElicitationResult, 1)
+	run.setElicitationSchema(testElicitationSchema)
+	r := &LocalRuntime{runs: map[string]*sessionRun{"session-1": run}}
+
+	err := r.ResumeElicitation(t.Context(), "session-1", tools.ElicitationActionAccept, map[string]any{"name": "Ada"})
+	verr, ok := errors.AsType[*ElicitationValidationError](err)
+	require.True(t, ok, "unexpected error: %v", err)
+	assert.Equal(t, []ElicitationViolation{{Field: "email", Message: "is required"}}, verr.Violations)
+	assert.Empty(t, run.elicitationRequestCh, "an invalid answer must not be delivered")
+
+	// Declining doesn't require valid content.
+	require.NoError(t, r.ResumeElicitation(t.Context(), "session-1", tools.ElicitationActionDecline, nil))
+	assert.Len(t, run.elicitationRequestCh, 1)
+
+	// The other sessions have no elicitation request in progress.
+	require.Error(t, r.ResumeElicitation(t.Context(), "session-2", tools.ElicitationActionDecline, nil))
+}
@@ -0,0 +1,149 @@
+package runtime
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker-agent/pkg/model/provider"
+	"github.com/docker/docker-agent/pkg/modelerrors"
+)
+
+// ErrorCode classifies the errors and warnings sent by the runtime, so that
+// clients can tell them apart without parsing their messages. Codes are
+// stable: new ones can be added but existing ones aren't renamed.
+type ErrorCode string
+
+const (
+	// ErrorCodeProviderAuthFailed is sent when the model provider rejects
+	// the credentials, e.g. a missing or invalid API key.
+	ErrorCodeProviderAuthFailed ErrorCode = "provider.auth_failed"
+	// ErrorCodeProviderRateLimited is sent when the model provider keeps
+	// rate limiting the requests.
+	ErrorCodeProviderRateLimited ErrorCode = "provider.rate_limited"
+	// ErrorCodeProviderContextOverflow is sent when the conversation doesn't
+	// fit in the context window of the model.
+	ErrorCodeProviderContextOverflow ErrorCode = "provider.context_overflow"
+	// ErrorCodeProviderRequestFailed is sent for the other model failures.
+	ErrorCodeProviderRequestFailed ErrorCode = "provider.request_failed"
+
+	// ErrorCodeToolsetStartFailed is sent when the tools of an agent can't
+	// be listed, or when some of its toolsets failed to start.
+	ErrorCodeToolsetStartFailed ErrorCode = "toolset.start_failed"
+	// ErrorCodeToolsetInstructionsFailed is sent when a toolset fails to
+	// build its instructions for a turn. The turn goes on without them.
+	ErrorCodeToolsetInstructionsFailed ErrorCode = "toolset.instructions_failed"
+
+	// ErrorCodeToolOutputRedacted is sent the first time secrets are masked
+	// in the output of a tool in a session.
+	ErrorCodeToolOutputRedacted ErrorCode = "tool.output_redacted"
+
+	// ErrorCodeAgentInstructionFailed is sent when the instruction of an
+	// agent can't be rendered.
+	ErrorCodeAgentInstructionFailed ErrorCode = "agent.instruction_failed"
+	// ErrorCodeAgentLoopDetected is sent when an agent is stopped for
+	// repeating the same tool call.
+	ErrorCodeAgentLoopDetected ErrorCode = "agent.loop_detected"
+	// ErrorCodeAgentMaxIterations is sent when a run is stopped after
+	// reaching the max_iterations limit of the agent.
+	ErrorCodeAgentMaxIterations ErrorCode = "agent.max_iterations"
+	// ErrorCodeAgentOutputRejected is sent when the answer of an agent is
+	// still rejected by its output guards after the retries.
+	ErrorCodeAgentOutputRejected ErrorCode = "agent.output_rejected"
+	// ErrorCodeAgentConfigReloadFailed is sent when a changed agent
+	// configuration can't be loaded.
+	ErrorCodeAgentConfigReloadFailed ErrorCode = "agent.config_reload_failed"
+
+	// ErrorCodeSessionCompactionFailed is sent when the session can't be
+	// summarized.
+	ErrorCodeSessionCompactionFailed ErrorCode = "session.compaction_failed"
+	// ErrorCodeSessionCompacting is sent when the session is compacted
+	// because it overflowed the context window.
+	ErrorCodeSessionCompacting ErrorCode = "session.compacting"
+	// ErrorCodeSessionRepaired is sent when an interrupted session was
+	// repaired before resuming it.
+	ErrorCodeSessionRepaired ErrorCode = "session.repaired"
+
+	// ErrorCodeRAGFailed is sent when a RAG source fails, e.g. to index.
+	ErrorCodeRAGFailed ErrorCode = "rag.failed"
+
+	// ErrorCodeRuntimeClosed is sent when a run is started on a closed
+	// runtime.
+	ErrorCodeRuntimeClosed ErrorCode = "runtime.closed"
+	// ErrorCodeRuntimeRemoteFailed is sent when a run can't be started on a
+	// remote runtime.
+	ErrorCodeRuntimeRemoteFailed ErrorCode = "runtime.remote_failed"
+	// ErrorCodeRuntimeSlowConsumer is sent when the events of a run queue up
+	// because the client doesn't read them fast enough.
+	ErrorCodeRuntimeSlowConsumer ErrorCode = "runtime.slow_consumer"
+)
+
+// Component is the part of the system an error or a warning comes from.
+type Component string
+
+const (
+	ComponentAgent   Component = "agent"
+	ComponentModel   Component = "model"
+	ComponentTool    Component = "tool"
+	ComponentSession Component = "session"
+	ComponentRAG     Component = "rag"
+	ComponentRuntime Component = "runtime"
+)
+
+// componentsByPrefix maps the prefixes of the error codes to the component
+// they're about.
+var componentsByPrefix = map[string]Component{
+	"provider": ComponentModel,
+	"toolset":  ComponentTool,
+	"tool":     ComponentTool,
+	"agent":    ComponentAgent,
+	"session":  ComponentSession,
+	"rag":      ComponentRAG,
+	"runtime":  ComponentRuntime,
+}
+
+// Component returns the component the code is about, or "" for unknown
+// codes.
+func (c ErrorCode) Component() Component {
+	prefix, _, _ := strings.Cut(string(c), ".")
+	return componentsByPrefix[prefix]
+}
+
+// coreProviderAPIKeys are the environment variables holding the API keys of
+// the core providers. Aliases have their own in provider.Aliases.
+var coreProviderAPIKeys = map[string]string{
+	"openai":    "OPENAI_API_KEY",
+	"anthropic": "ANTHROPIC_API_KEY",
+	"google":    "GOOGLE_API_KEY",
+}
+
+// classifyModelError returns the code of an error returned by a model, along
+// with a hint to fix it, if any. modelID is the "provider/model" ID of the
+// model that was called.
+func classifyModelError(err error, modelID string) (ErrorCode, string) {
+	if modelerrors.IsContextOverflowError(err) {
+		return ErrorCodeProviderContextOverflow, "Run /compact to reduce the conversation size, or start a new session."
+	}
+
+	if modelerrors.IsAuthenticationError(err) {
+		providerName, _, _ := strings.Cut(modelID, "/")
+		if envVar := apiKeyEnvVar(providerName); envVar != "" {
+			return ErrorCodeProviderAuthFailed, fmt.Sprintf("Check that %s is set to a valid API key.", envVar)
+		}
+		return ErrorCodeProviderAuthFailed, fmt.Sprintf("Check the credentials configured for the %q provider.", providerName)
+	}
+
+	if _, rateLimited, _ := modelerrors.ClassifyModelError(err); rateLimited {
+		return ErrorCodeProviderRateLimited, "Wait a moment before retrying, or configure fallback models."
+	}
+
+	return ErrorCodeProviderRequestFailed, ""
+}
+
+// apiKeyEnvVar returns the environment variable holding the API key of a
+// provider, or "" if it isn't known.
+func apiKeyEnvVar(providerName string) string {
+	if alias, ok := provider.Aliases[providerName]; ok {
+		return alias.TokenEnvVar
+	}
+	return coreProviderAPIKeys[providerName]
+}
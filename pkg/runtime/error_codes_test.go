@@ -0,0 +1,92 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/docker/docker-agent/pkg/modelerrors"
+)
+
+func TestClassifyModelError(t *testing.T) {
+	t.Parallel()
+
+	unauthorized := &modelerrors.StatusError{StatusCode: 401, Err: errors.New("invalid api key")}
+
+	tests := []struct {
+		name    string
+		err     error
+		modelID string
+		code    ErrorCode
+		hint    string
+	}{
+		{
+			name:    "core provider auth",
+			err:     fmt.Errorf("all models failed: %w", unauthorized),
+			modelID: "openai/gpt-4o",
+			code:    ErrorCodeProviderAuthFailed,
+			hint:    "Check that OPENAI_API_KEY is set to a valid API key.",
+		},
+		{
+			name:    "alias provider auth",
+			err:     unauthorized,
+			modelID: "mistral/mistral-small-latest",
+			code:    ErrorCodeProviderAuthFailed,
+			hint:    "Check that MISTRAL_API_KEY is set to a valid API key.",
+		},
+		{
+			name:    "custom provider auth",
+			err:     unauthorized,
+			modelID: "my_gateway/model",
+			code:    ErrorCodeProviderAuthFailed,
+			hint:    `Check the credentials configured for the "my_gateway" provider.`,
+		},
+		{
+			name:    "rate limited",
+			err:     &modelerrors.StatusError{StatusCode: 429, Err: errors.New("slow down")},
+			modelID: "anthropic/claude-sonnet-4-5",
+			code:    ErrorCodeProviderRateLimited,
+			hint:    "Wait a moment before retrying, or configure fallback models.",
+		},
+		{
+			name:    "context overflow",
+			err:     modelerrors.NewContextOverflowError(errors.New("prompt is too long")),
+			modelID: "anthropic/claude-sonnet-4-5",
+			code:    ErrorCodeProviderContextOverflow,
+			hint:    "Run /compact to reduce the conversation size, or start a new session.",
+		},
+		{
+			name:    "other failure",
+			err:     errors.New("connection reset"),
+			modelID: "openai/gpt-4o",
+			code:    ErrorCodeProviderRequestFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			code, hint := classifyModelError(tt.err, tt.modelID)
+			assert.Equal(t, tt.code, code)
+			assert.Equal(t, tt.hint, hint)
+		})
+	}
+}
+
+func TestErrorWithCode(t *testing.T) {
+	t.Parallel()
+
+	event := ErrorWithCode(ErrorCodeToolsetStartFailed, "failed to get tools", "").(*ErrorEvent)
+	assert.Equal(t, "error", event.Type)
+	assert.Equal(t, ErrorCodeToolsetStartFailed, event.Code)
+	assert.Equal(t, ComponentTool, event.Component)
+
+	warning := WarningWithCode(ErrorCodeSessionRepaired, "repaired", "", "root").(*WarningEvent)
+	assert.Equal(t, ComponentSession, warning.Component)
+	assert.Equal(t, "root", warning.AgentName)
+
+	assert.Equal(t, Component(""), ErrorCode("unknown").Component())
+}
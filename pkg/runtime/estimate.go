@@ -0,0 +1,85 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/compaction"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// ErrNoPricing is returned by cost estimates for models models.dev has no
+// pricing for.
+var ErrNoPricing = errors.New("no pricing information")
+
+// TurnCostEstimator is an optional interface for runtimes that can estimate
+// the cost of the next turn of a session before running it. The TUI and the
+// API server use it to show the cost of a message before it's sent.
+type TurnCostEstimator interface {
+	// EstimateNextTurnCost estimates the cost of the next turn of sess if
+	// draft, which can be empty, was sent now.
+	EstimateNextTurnCost(ctx context.Context, sess *session.Session, draft string, opts ...session.EstimateOpt) (session.CostEstimate, error)
+}
+
+var _ TurnCostEstimator = (*LocalRuntime)(nil)
+
+// EstimateTurnCost estimates the cost of sending messages and the definitions
+// of agentTools to the model modelID, given as provider/model, with the
+// pricing of models.dev. The tokens are counted with the estimator suited to
+// the model.
+func EstimateTurnCost(ctx context.Context, store ModelStore, modelID string, messages []chat.Message, agentTools []tools.Tool, opts ...session.EstimateOpt) (session.CostEstimate, error) {
+	m, err := store.GetModel(ctx, modelID)
+	if err != nil {
+		return session.CostEstimate{}, err
+	}
+	if m == nil || m.Cost == nil {
+		return session.CostEstimate{}, fmt.Errorf("%w for %s", ErrNoPricing, modelID)
+	}
+
+	opts = append([]session.EstimateOpt{session.WithEstimator(compaction.EstimatorFor(modelID))}, opts...)
+	return session.EstimateTurnCost(m, messages, agentTools, opts...), nil
+}
+
+// EstimateNextTurnCost estimates the cost of the next turn of the agent of
+// sess, with the messages the run loop would send: the system prompts,
+// with the instructions of the toolsets, the conversation, packed into the
+// context window and pruned the same way, and the tool definitions. The
+// per-turn instructions of the toolsets aren't included. Listing the tools
+// starts the toolsets of the agent if needed.
+func (r *LocalRuntime) EstimateNextTurnCost(ctx context.Context, sess *session.Session, draft string, opts ...session.EstimateOpt) (session.CostEstimate, error) {
+	a := r.resolveSessionAgent(sess)
+	modelID := r.agentModel(ctx, sess, a).ID()
+
+	m, err := r.modelsStore.GetModel(ctx, modelID)
+	if err != nil {
+		return session.CostEstimate{}, err
+	}
+	if m == nil || m.Cost == nil {
+		return session.CostEstimate{}, fmt.Errorf("%w for %s", ErrNoPricing, modelID)
+	}
+
+	agentTools, err := a.Tools(ctx)
+	if err != nil {
+		return session.CostEstimate{}, fmt.Errorf("failed to get tools: %w", err)
+	}
+	agentTools = filterExcludedTools(agentTools, sess.ExcludedTools)
+
+	estimate := compaction.EstimatorFor(modelID)
+	messagesOpts := []session.MessagesOpt{session.WithToolResultPruning(r.toolResultPruning)}
+	if contextLimit := int64(m.Limit.Context); contextLimit > 0 {
+		messagesOpts = append(messagesOpts,
+			session.WithTokenBudget(messagesTokenBudget(contextLimit, m.Limit.Output, agentTools)),
+			session.WithTokenEstimator(estimate),
+		)
+	}
+
+	opts = append([]session.EstimateOpt{
+		session.WithEstimator(estimate),
+		session.WithDraft(draft),
+		session.WithMessagesOpts(messagesOpts...),
+	}, opts...)
+	return sess.EstimatedNextTurnCost(a, m, agentTools, opts...), nil
+}
@@ -0,0 +1,57 @@
+package runtime
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/modelsdev"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+func TestEstimateNextTurnCost(t *testing.T) {
+	t.Parallel()
+
+	toolDefs := []tools.Tool{{Name: "search", Description: strings.Repeat("Search the web. ", 50)}}
+	root := agent.New("root", "You are a test agent",
+		agent.WithModel(&mockProvider{id: "test/mock-model"}),
+		agent.WithToolSets(newStubToolSet(nil, toolDefs, nil)),
+	)
+	tm := team.New(team.WithAgents(root))
+
+	rt, err := NewLocalRuntime(tm, WithSessionCompaction(false), WithModelStore(pricedModelStore{costs: map[string]*modelsdev.Cost{
+		"test/mock-model": {Input: 3, Output: 15},
+	}}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Hello"))
+
+	est, err := rt.EstimateNextTurnCost(t.Context(), sess, "", session.WithAssumedOutputTokens(100, 1000))
+	require.NoError(t, err)
+	assert.Positive(t, est.ToolTokens)
+	assert.Greater(t, est.InputTokens, est.ToolTokens)
+	assert.Positive(t, est.MinCost)
+	assert.Greater(t, est.MaxCost, est.MinCost)
+
+	withDraft, err := rt.EstimateNextTurnCost(t.Context(), sess, strings.Repeat("Tell me more. ", 100), session.WithAssumedOutputTokens(100, 1000))
+	require.NoError(t, err)
+	assert.Greater(t, withDraft.InputTokens, est.InputTokens)
+}
+
+func TestEstimateNextTurnCost_NoPricing(t *testing.T) {
+	t.Parallel()
+
+	root := agent.New("root", "You are a test agent", agent.WithModel(&mockProvider{id: "test/mock-model"}))
+	tm := team.New(team.WithAgents(root))
+
+	rt, err := NewLocalRuntime(tm, WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	_, err = rt.EstimateNextTurnCost(t.Context(), session.New(session.WithUserMessage("Hello")), "")
+	require.ErrorIs(t, err, ErrNoPricing)
+}
@@ -2,11 +2,13 @@ package runtime
 
 import (
 	"cmp"
+	"slices"
 	"time"
 
 	"github.com/docker/docker-agent/pkg/chat"
 	"github.com/docker/docker-agent/pkg/config/types"
 	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
 	"github.com/docker/docker-agent/pkg/tools"
 )
 
@@ -30,6 +32,10 @@ type AgentContext struct {
 // GetAgentName returns the agent name for events embedding AgentContext.
 func (a AgentContext) GetAgentName() string { return a.AgentName }
 
+// GetTimestamp returns the time of events embedding AgentContext, zero when
+// it wasn't recorded.
+func (a AgentContext) GetTimestamp() time.Time { return a.Timestamp }
+
 // newAgentContext creates a new AgentContext with the current timestamp.
 func newAgentContext(agentName string) AgentContext {
 	return AgentContext{AgentName: agentName, Timestamp: time.Now()}
@@ -68,19 +74,23 @@ type PartialToolCallEvent struct {
 	Type           string         `json:"type"`
 	ToolCall       tools.ToolCall `json:"tool_call"`
 	ToolDefinition *tools.Tool    `json:"tool_definition,omitempty"`
+	// ArgumentsPreview is a best-effort preview of the arguments received so
+	// far, see tools.PreviewArgs. ToolCall only holds the new arguments.
+	ArgumentsPreview map[string]any `json:"arguments_preview,omitempty"`
 }
 
-func PartialToolCall(toolCall tools.ToolCall, toolDefinition tools.Tool, agentName string) Event {
+func PartialToolCall(toolCall tools.ToolCall, toolDefinition tools.Tool, argumentsPreview map[string]any, agentName string) Event {
 	var toolDef *tools.Tool
 	if toolDefinition.Name != "" {
 		def := toolDefinition
 		toolDef = &def
 	}
 	return &PartialToolCallEvent{
-		Type:           "partial_tool_call",
-		ToolCall:       toolCall,
-		ToolDefinition: toolDef,
-		AgentContext:   newAgentContext(agentName),
+		Type:             "partial_tool_call",
+		ToolCall:         toolCall,
+		ToolDefinition:   toolDef,
+		ArgumentsPreview: argumentsPreview,
+		AgentContext:     newAgentContext(agentName),
 	}
 }
 
@@ -91,13 +101,20 @@ type ToolCallEvent struct {
 	Type           string         `json:"type"`
 	ToolCall       tools.ToolCall `json:"tool_call"`
 	ToolDefinition tools.Tool     `json:"tool_definition"`
+	// ArgumentsEdit is set when the user edited the arguments of the call
+	// when approving it. ToolCall has the edited arguments.
+	ArgumentsEdit *tools.ArgumentsEdit `json:"arguments_edit,omitempty"`
+	// Cached is set when the result of the call is served from the cache,
+	// see WithToolResultCache.
+	Cached bool `json:"cached,omitempty"`
 }
 
-func ToolCall(toolCall tools.ToolCall, toolDefinition tools.Tool, agentName string) Event {
+func ToolCall(toolCall tools.ToolCall, toolDefinition tools.Tool, argumentsEdit *tools.ArgumentsEdit, agentName string) Event {
 	return &ToolCallEvent{
 		Type:           "tool_call",
 		ToolCall:       toolCall,
 		ToolDefinition: toolDefinition,
+		ArgumentsEdit:  argumentsEdit,
 		AgentContext:   newAgentContext(agentName),
 	}
 }
@@ -108,17 +125,31 @@ type ToolCallConfirmationEvent struct {
 	Type           string         `json:"type"`
 	ToolCall       tools.ToolCall `json:"tool_call"`
 	ToolDefinition tools.Tool     `json:"tool_definition"`
+	// Preview shows what the call would change, e.g. the diff of a file
+	// edit, when its toolset can tell. See tools.Previewable.
+	Preview *tools.Preview `json:"preview,omitempty"`
+	// Options are the answers the runtime accepts for this confirmation,
+	// e.g. to allow the call once or to always allow the tool.
+	Options []ResumeType `json:"options,omitempty"`
 }
 
-func ToolCallConfirmation(toolCall tools.ToolCall, toolDefinition tools.Tool, agentName string) Event {
+func ToolCallConfirmation(toolCall tools.ToolCall, toolDefinition tools.Tool, preview *tools.Preview, agentName string, options ...ResumeType) Event {
 	return &ToolCallConfirmationEvent{
 		Type:           "tool_call_confirmation",
 		ToolCall:       toolCall,
 		ToolDefinition: toolDefinition,
+		Preview:        preview,
+		Options:        options,
 		AgentContext:   newAgentContext(agentName),
 	}
 }
 
+// Offers reports whether the confirmation can be answered with t. Events
+// that don't list their options, e.g. from older servers, accept them all.
+func (e *ToolCallConfirmationEvent) Offers(t ResumeType) bool {
+	return len(e.Options) == 0 || slices.Contains(e.Options, t)
+}
+
 type ToolCallResponseEvent struct {
 	AgentContext
 
@@ -127,6 +158,8 @@ type ToolCallResponseEvent struct {
 	ToolDefinition tools.Tool            `json:"tool_definition"`
 	Response       string                `json:"response"`
 	Result         *tools.ToolCallResult `json:"result,omitempty"`
+	// Cached is set when the result was served from the cache.
+	Cached bool `json:"cached,omitempty"`
 }
 
 func ToolCallResponse(toolCallID string, toolDefinition tools.Tool, result *tools.ToolCallResult, response, agentName string) Event {
@@ -193,11 +226,94 @@ func AgentChoiceReasoning(agentName, sessionID, content string) Event {
 	}
 }
 
+// AgentThoughtEvent is sent when an agent records a thought with the think
+// tool, so that clients can show it along with the reasoning content.
+type AgentThoughtEvent struct {
+	AgentContext
+
+	Type      string `json:"type"`
+	Thought   string `json:"thought"`
+	SessionID string `json:"session_id,omitempty"`
+}
+
+func (e *AgentThoughtEvent) GetSessionID() string { return e.SessionID }
+
+func AgentThought(agentName, sessionID, thought string) Event {
+	return &AgentThoughtEvent{
+		Type:         "agent_thought",
+		Thought:      thought,
+		SessionID:    sessionID,
+		AgentContext: newAgentContext(agentName),
+	}
+}
+
+// ProviderToolEvent is sent when the provider used one of its built-in
+// tools, e.g. a web search, so that clients can show what it did.
+type ProviderToolEvent struct {
+	AgentContext
+
+	Type         string               `json:"type"`
+	ProviderTool chat.ProviderToolUse `json:"provider_tool"`
+	SessionID    string               `json:"session_id,omitempty"`
+}
+
+func (e *ProviderToolEvent) GetSessionID() string { return e.SessionID }
+
+func ProviderTool(agentName, sessionID string, use chat.ProviderToolUse) Event {
+	return &ProviderToolEvent{
+		Type:         "provider_tool",
+		ProviderTool: use,
+		SessionID:    sessionID,
+		AgentContext: newAgentContext(agentName),
+	}
+}
+
+// AgentMessageCompletedEvent is sent once an assistant message has been fully
+// streamed. It carries the complete text, reasoning and tool calls of the
+// message, so that clients don't have to accumulate AgentChoice deltas, and
+// the token usage of the model call that produced it.
+type AgentMessageCompletedEvent struct {
+	AgentContext
+
+	Type             string            `json:"type"`
+	SessionID        string            `json:"session_id,omitempty"`
+	Content          string            `json:"content"`
+	ReasoningContent string            `json:"reasoning_content,omitempty"`
+	ToolCalls        []tools.ToolCall  `json:"tool_calls,omitempty"`
+	FinishReason     chat.FinishReason `json:"finish_reason,omitempty"`
+	Usage            *chat.Usage       `json:"usage,omitempty"`
+	// Annotations lists the sources retrieved by RAG tools the message
+	// draws on, followed by the web pages the provider cited.
+	Annotations []chat.Annotation `json:"annotations,omitempty"`
+}
+
+func (e *AgentMessageCompletedEvent) GetSessionID() string { return e.SessionID }
+
+func AgentMessageCompleted(agentName, sessionID, content, reasoningContent string, toolCalls []tools.ToolCall, finishReason chat.FinishReason, usage *chat.Usage, annotations []chat.Annotation) Event {
+	return &AgentMessageCompletedEvent{
+		Type:             "agent_message_completed",
+		SessionID:        sessionID,
+		Content:          content,
+		ReasoningContent: reasoningContent,
+		ToolCalls:        toolCalls,
+		FinishReason:     finishReason,
+		Usage:            usage,
+		Annotations:      annotations,
+		AgentContext:     newAgentContext(agentName),
+	}
+}
+
 type ErrorEvent struct {
 	AgentContext
 
 	Type  string `json:"type"`
 	Error string `json:"error"`
+	// Code classifies the error, and Component tells where it comes from.
+	// Both are empty for errors that aren't classified.
+	Code      ErrorCode `json:"code,omitempty"`
+	Component Component `json:"component,omitempty"`
+	// Hint tells the user how to fix the error, when known.
+	Hint string `json:"hint,omitempty"`
 }
 
 func Error(msg string) Event {
@@ -207,6 +323,18 @@ func Error(msg string) Event {
 	}
 }
 
+// ErrorWithCode is like Error, for an error classified by code, with an
+// optional hint telling the user how to fix it.
+func ErrorWithCode(code ErrorCode, msg, hint string) Event {
+	return &ErrorEvent{
+		Type:      "error",
+		Error:     msg,
+		Code:      code,
+		Component: code.Component(),
+		Hint:      hint,
+	}
+}
+
 type ShellOutputEvent struct {
 	AgentContext
 
@@ -227,6 +355,12 @@ type WarningEvent struct {
 
 	Type    string `json:"type"`
 	Message string `json:"message"`
+	// Code classifies the warning, and Component tells where it comes from.
+	// Both are empty for warnings that aren't classified.
+	Code      ErrorCode `json:"code,omitempty"`
+	Component Component `json:"component,omitempty"`
+	// Hint tells the user what to do about the warning, when known.
+	Hint string `json:"hint,omitempty"`
 }
 
 func Warning(message, agentName string) Event {
@@ -237,6 +371,19 @@ func Warning(message, agentName string) Event {
 	}
 }
 
+// WarningWithCode is like Warning, for a warning classified by code, with
+// an optional hint telling the user what to do about it.
+func WarningWithCode(code ErrorCode, message, hint, agentName string) Event {
+	return &WarningEvent{
+		Type:         "warning",
+		Message:      message,
+		Code:         code,
+		Component:    code.Component(),
+		Hint:         hint,
+		AgentContext: newAgentContext(agentName),
+	}
+}
+
 // ModelFallbackEvent is emitted when the runtime switches to a fallback model
 // after the previous model in the chain fails. This can happen due to:
 // - Retryable errors (5xx, timeouts) after exhausting retries
@@ -265,6 +412,59 @@ func ModelFallback(agentName, failedModel, fallbackModel, reason string, attempt
 	}
 }
 
+// ModelRoutedEvent is emitted when an escalating router picked the model of
+// a request. Reason is why it didn't pick its first candidate, empty when it
+// did.
+type ModelRoutedEvent struct {
+	AgentContext
+
+	Type      string `json:"type"`
+	Router    string `json:"router"`
+	Model     string `json:"model"`
+	Reason    string `json:"reason,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+}
+
+func (e *ModelRoutedEvent) GetSessionID() string { return e.SessionID }
+
+func ModelRouted(agentName, sessionID, router, model, reason string) Event {
+	return &ModelRoutedEvent{
+		Type:         "model_routed",
+		Router:       router,
+		Model:        model,
+		Reason:       reason,
+		SessionID:    sessionID,
+		AgentContext: newAgentContext(agentName),
+	}
+}
+
+// ThrottledEvent is emitted when a request to a model is delayed by the limits
+// set with WithRateLimit or WithMaxConcurrentStreams. Wait is zero when the
+// request waits for another stream on the same model to end.
+type ThrottledEvent struct {
+	AgentContext
+
+	Type      string        `json:"type"`
+	SessionID string        `json:"session_id,omitempty"`
+	Model     string        `json:"model"`
+	Reason    string        `json:"reason"`
+	Wait      time.Duration `json:"wait,omitempty"`
+}
+
+func (e *ThrottledEvent) GetSessionID() string { return e.SessionID }
+
+// Throttled creates a new ThrottledEvent.
+func Throttled(agentName, sessionID, model, reason string, wait time.Duration) Event {
+	return &ThrottledEvent{
+		Type:         "throttled",
+		SessionID:    sessionID,
+		Model:        model,
+		Reason:       reason,
+		Wait:         wait,
+		AgentContext: newAgentContext(agentName),
+	}
+}
+
 type TokenUsageEvent struct {
 	AgentContext
 
@@ -273,17 +473,27 @@ type TokenUsageEvent struct {
 	Usage     *Usage `json:"usage"`
 }
 
+// Usage is the token usage reported by a TokenUsageEvent. InputTokens and
+// OutputTokens are those of the last request, i.e. the size of the context.
+// LastMessage holds the usage of the turn that was just completed and Totals
+// the running totals of all the turns of the session.
 type Usage struct {
-	InputTokens   int64         `json:"input_tokens"`
-	OutputTokens  int64         `json:"output_tokens"`
-	ContextLength int64         `json:"context_length"`
-	ContextLimit  int64         `json:"context_limit"`
-	Cost          float64       `json:"cost"`
-	LastMessage   *MessageUsage `json:"last_message,omitempty"`
+	InputTokens   int64   `json:"input_tokens"`
+	OutputTokens  int64   `json:"output_tokens"`
+	ContextLength int64   `json:"context_length"`
+	ContextLimit  int64   `json:"context_limit"`
+	Cost          float64 `json:"cost"`
+	// CompactionCost is the part of Cost spent compacting the session,
+	// with the compaction model.
+	CompactionCost float64              `json:"compaction_cost,omitempty"`
+	LastMessage    *MessageUsage        `json:"last_message,omitempty"`
+	Totals         *session.UsageTotals `json:"totals,omitempty"`
 }
 
 // MessageUsage contains per-message usage data to include in TokenUsageEvent.
-// It embeds chat.Usage and adds Cost, Model, and FinishReason fields.
+// It embeds chat.Usage and adds Cost, Model, and FinishReason fields. Cost is
+// computed with the pricing of Model, the model that answered, which may be a
+// fallback model.
 type MessageUsage struct {
 	chat.Usage
 
@@ -303,14 +513,17 @@ func NewTokenUsageEvent(sessionID, agentName string, usage *Usage) Event {
 }
 
 // SessionUsage builds a Usage from the session's current token counts, the
-// model's context limit, and the session's own cost.
+// model's context limit, and the session's own cost and turn totals.
 func SessionUsage(sess *session.Session, contextLimit int64) *Usage {
+	totals := sess.OwnUsage()
 	return &Usage{
-		InputTokens:   sess.InputTokens,
-		OutputTokens:  sess.OutputTokens,
-		ContextLength: sess.InputTokens + sess.OutputTokens,
-		ContextLimit:  contextLimit,
-		Cost:          sess.OwnCost(),
+		InputTokens:    sess.InputTokens,
+		OutputTokens:   sess.OutputTokens,
+		ContextLength:  sess.InputTokens + sess.OutputTokens,
+		ContextLimit:   contextLimit,
+		Cost:           sess.OwnCost(),
+		CompactionCost: sess.CompactionCost(),
+		Totals:         &totals,
 	}
 }
 
@@ -337,14 +550,18 @@ type SessionSummaryEvent struct {
 	SessionID      string `json:"session_id"`
 	Summary        string `json:"summary"`
 	FirstKeptEntry int    `json:"first_kept_entry,omitempty"`
+	// Info describes the summary: the messages it covers, the model that
+	// generated it.
+	Info *session.SummaryInfo `json:"info,omitempty"`
 }
 
-func SessionSummary(sessionID, summary, agentName string, firstKeptEntry int) Event {
+func SessionSummary(sessionID, summary, agentName string, firstKeptEntry int, info *session.SummaryInfo) Event {
 	return &SessionSummaryEvent{
 		Type:           "session_summary",
 		SessionID:      sessionID,
 		Summary:        summary,
 		FirstKeptEntry: firstKeptEntry,
+		Info:           info,
 		AgentContext:   newAgentContext(agentName),
 	}
 }
@@ -355,6 +572,11 @@ type SessionCompactionEvent struct {
 	Type      string `json:"type"`
 	SessionID string `json:"session_id"`
 	Status    string `json:"status"`
+	// Covered is the range of the items of the session the summary
+	// condensed, set when the compaction completed with a summary.
+	Covered *session.MessageRange `json:"covered,omitempty"`
+	// CoveredMessages is the number of messages in Covered.
+	CoveredMessages int `json:"covered_messages,omitempty"`
 }
 
 func SessionCompaction(sessionID, status, agentName string) Event {
@@ -366,6 +588,68 @@ func SessionCompaction(sessionID, status, agentName string) Event {
 	}
 }
 
+// SessionCompactionCompleted returns the event of a compaction that
+// completed with summary, nil when it produced none.
+func SessionCompactionCompleted(sessionID, agentName string, summary *session.SummaryItem) Event {
+	event := &SessionCompactionEvent{
+		Type:         "session_compaction",
+		SessionID:    sessionID,
+		Status:       "completed",
+		AgentContext: newAgentContext(agentName),
+	}
+	if summary != nil {
+		event.Covered = &summary.Covered
+		event.CoveredMessages = summary.Messages
+	}
+	return event
+}
+
+// CheckpointCreatedEvent is sent when a point of a session is tagged as a
+// checkpoint it can be rolled back to.
+type CheckpointCreatedEvent struct {
+	AgentContext
+
+	Type       string             `json:"type"`
+	SessionID  string             `json:"session_id"`
+	Checkpoint session.Checkpoint `json:"checkpoint"`
+}
+
+func (e *CheckpointCreatedEvent) GetSessionID() string { return e.SessionID }
+
+func CheckpointCreated(sessionID string, checkpoint session.Checkpoint, agentName string) Event {
+	return &CheckpointCreatedEvent{
+		Type:         "checkpoint_created",
+		SessionID:    sessionID,
+		Checkpoint:   checkpoint,
+		AgentContext: newAgentContext(agentName),
+	}
+}
+
+// SessionRolledBackEvent is sent when a session was rolled back to a
+// checkpoint: the clients reload its messages.
+type SessionRolledBackEvent struct {
+	AgentContext
+
+	Type       string             `json:"type"`
+	SessionID  string             `json:"session_id"`
+	Checkpoint session.Checkpoint `json:"checkpoint"`
+	// RevertedFiles are the paths of the files restored to their content
+	// at the checkpoint, when the rollback reverted the files too.
+	RevertedFiles []string `json:"reverted_files,omitempty"`
+}
+
+func (e *SessionRolledBackEvent) GetSessionID() string { return e.SessionID }
+
+func SessionRolledBack(sessionID string, checkpoint session.Checkpoint, revertedFiles []string, agentName string) Event {
+	return &SessionRolledBackEvent{
+		Type:          "session_rolled_back",
+		SessionID:     sessionID,
+		Checkpoint:    checkpoint,
+		RevertedFiles: revertedFiles,
+		AgentContext:  newAgentContext(agentName),
+	}
+}
+
 type StreamStoppedEvent struct {
 	AgentContext
 
@@ -381,6 +665,25 @@ func StreamStopped(sessionID, agentName string) Event {
 	}
 }
 
+// RunCompletedEvent is sent at the end of each RunStream, right before
+// StreamStoppedEvent, with the statistics of the run.
+type RunCompletedEvent struct {
+	AgentContext
+
+	Type      string `json:"type"`
+	SessionID string `json:"session_id,omitempty"`
+	Stats     Stats  `json:"stats"`
+}
+
+func RunCompleted(sessionID, agentName string, stats Stats) Event {
+	return &RunCompletedEvent{
+		Type:         "run_completed",
+		SessionID:    sessionID,
+		Stats:        stats,
+		AgentContext: newAgentContext(agentName),
+	}
+}
+
 // ElicitationRequestEvent is sent when an elicitation request is received from an MCP server
 type ElicitationRequestEvent struct {
 	AgentContext
@@ -463,6 +766,33 @@ func MCPInitFinished(agentName string) Event {
 	}
 }
 
+// MCPSamplingEvent is sent when an MCP server sampled a model, so that the
+// users see the servers spending their tokens.
+type MCPSamplingEvent struct {
+	AgentContext
+
+	Type      string      `json:"type"`
+	SessionID string      `json:"session_id,omitempty"`
+	Toolset   string      `json:"toolset"`
+	Model     string      `json:"model"`
+	Usage     *chat.Usage `json:"usage,omitempty"`
+	Cost      float64     `json:"cost"`
+}
+
+func (e *MCPSamplingEvent) GetSessionID() string { return e.SessionID }
+
+func MCPSampling(sessionID, toolset, model string, usage *chat.Usage, cost float64, agentName string) Event {
+	return &MCPSamplingEvent{
+		Type:         "mcp_sampling",
+		SessionID:    sessionID,
+		Toolset:      toolset,
+		Model:        model,
+		Usage:        usage,
+		Cost:         cost,
+		AgentContext: newAgentContext(agentName),
+	}
+}
+
 // AgentInfoEvent is sent when agent information is available or changes
 type AgentInfoEvent struct {
 	AgentContext
@@ -532,25 +862,158 @@ func AgentSwitching(switching bool, fromAgent, toAgent string) Event {
 	}
 }
 
+// AgentHandoffEvent is sent when an agent hands the conversation off to
+// another agent. Handoffs holds the whole handoff history of the session.
+type AgentHandoffEvent struct {
+	AgentContext
+
+	Type      string            `json:"type"`
+	FromAgent string            `json:"from_agent"`
+	ToAgent   string            `json:"to_agent"`
+	Handoffs  []session.Handoff `json:"handoffs"`
+	SessionID string            `json:"session_id,omitempty"`
+}
+
+func (e *AgentHandoffEvent) GetSessionID() string { return e.SessionID }
+
+func AgentHandoff(fromAgent, toAgent, sessionID string, handoffs []session.Handoff) Event {
+	return &AgentHandoffEvent{
+		Type:         "agent_handoff",
+		FromAgent:    fromAgent,
+		ToAgent:      toAgent,
+		Handoffs:     handoffs,
+		SessionID:    sessionID,
+		AgentContext: newAgentContext(fromAgent),
+	}
+}
+
+// HandoffLoopDetectedEvent is sent when a handoff is blocked because the
+// agent already handed off to the same agent too many times recently.
+type HandoffLoopDetectedEvent struct {
+	AgentContext
+
+	Type      string `json:"type"`
+	FromAgent string `json:"from_agent"`
+	ToAgent   string `json:"to_agent"`
+	Repeats   int    `json:"repeats"`
+	Window    int    `json:"window"`
+	SessionID string `json:"session_id,omitempty"`
+}
+
+func (e *HandoffLoopDetectedEvent) GetSessionID() string { return e.SessionID }
+
+func HandoffLoopDetected(fromAgent, toAgent, sessionID string, repeats, window int) Event {
+	return &HandoffLoopDetectedEvent{
+		Type:         "handoff_loop_detected",
+		FromAgent:    fromAgent,
+		ToAgent:      toAgent,
+		Repeats:      repeats,
+		Window:       window,
+		SessionID:    sessionID,
+		AgentContext: newAgentContext(fromAgent),
+	}
+}
+
+// GuardRejectedEvent is sent when an output guard of an agent rejected its
+// final answer, or the result of one of its sub-agents.
+type GuardRejectedEvent struct {
+	AgentContext
+
+	Type string `json:"type"`
+	// SubAgent is the sub-agent whose result was rejected, if any.
+	SubAgent string `json:"sub_agent,omitempty"`
+	Reason   string `json:"reason"`
+	// Attempt counts the consecutive rejected answers of the agent. The
+	// turn is re-run while it's at most MaxRetries.
+	Attempt    int    `json:"attempt,omitempty"`
+	MaxRetries int    `json:"max_retries,omitempty"`
+	SessionID  string `json:"session_id,omitempty"`
+}
+
+func (e *GuardRejectedEvent) GetSessionID() string { return e.SessionID }
+
+func GuardRejected(agentName, subAgent, sessionID, reason string, attempt, maxRetries int) Event {
+	return &GuardRejectedEvent{
+		Type:         "guard_rejected",
+		SubAgent:     subAgent,
+		Reason:       reason,
+		Attempt:      attempt,
+		MaxRetries:   maxRetries,
+		SessionID:    sessionID,
+		AgentContext: newAgentContext(agentName),
+	}
+}
+
+// ConfigReloadedEvent is sent when the agent configuration was reloaded and
+// the new team replaced the previous one.
+type ConfigReloadedEvent struct {
+	AgentContext
+
+	Type string `json:"type"`
+}
+
+func ConfigReloaded(agentName string) Event {
+	return &ConfigReloadedEvent{
+		Type:         "config_reloaded",
+		AgentContext: newAgentContext(agentName),
+	}
+}
+
+// BlackboardEntry is an entry of the team's shared context, as sent to the
+// clients, which don't depend on the team package.
+type BlackboardEntry = team.BlackboardEntry
+
+// BlackboardUpdatedEvent is sent when an agent writes to the shared context
+// of the team. Entries holds the whole shared context after the update.
+type BlackboardUpdatedEvent struct {
+	AgentContext
+
+	Type    string            `json:"type"`
+	Key     string            `json:"key"`
+	Entries []BlackboardEntry `json:"entries"`
+}
+
+func BlackboardUpdated(key string, entries []BlackboardEntry, agentName string) Event {
+	return &BlackboardUpdatedEvent{
+		Type:         "blackboard_updated",
+		Key:          key,
+		Entries:      entries,
+		AgentContext: newAgentContext(agentName),
+	}
+}
+
 // ToolsetInfoEvent is sent when toolset information is available
 // When Loading is true, more tools may still be loading (e.g., MCP servers starting)
+// FailedToolsets counts the toolsets whose tools are missing because they failed
 type ToolsetInfoEvent struct {
 	AgentContext
 
 	Type           string `json:"type"`
 	AvailableTools int    `json:"available_tools"`
+	FailedToolsets int    `json:"failed_toolsets,omitempty"`
 	Loading        bool   `json:"loading"`
+	// Categories counts the available tools by category.
+	Categories map[string]int `json:"categories,omitempty"`
 }
 
-func ToolsetInfo(availableTools int, loading bool, agentName string) Event {
+func ToolsetInfo(availableTools, failedToolsets int, loading bool, agentName string) Event {
 	return &ToolsetInfoEvent{
 		Type:           "toolset_info",
 		AvailableTools: availableTools,
+		FailedToolsets: failedToolsets,
 		Loading:        loading,
 		AgentContext:   newAgentContext(agentName),
 	}
 }
 
+// toolsetInfo is a ToolsetInfo event for the given tools of an agent, with
+// their counts by category.
+func toolsetInfo(agentTools []tools.Tool, failedToolsets int, loading bool, agentName string) Event {
+	event := ToolsetInfo(len(agentTools), failedToolsets, loading, agentName).(*ToolsetInfoEvent)
+	event.Categories = tools.CountByCategory(agentTools)
+	return event
+}
+
 // RAGIndexingStartedEvent is for RAG lifecycle events
 type RAGIndexingStartedEvent struct {
 	AgentContext
@@ -596,6 +1059,10 @@ type RAGIndexingCompletedEvent struct {
 	Type         string `json:"type"`
 	RAGName      string `json:"rag_name"`
 	StrategyName string `json:"strategy_name"`
+	// CacheHits and CacheMisses count the chunks whose embeddings were
+	// found in the embeddings cache, and the ones that were embedded.
+	CacheHits   int `json:"cache_hits,omitempty"`
+	CacheMisses int `json:"cache_misses,omitempty"`
 }
 
 func RAGIndexingCompleted(ragName, strategyName string) Event {
@@ -627,6 +1094,97 @@ func HookBlocked(toolCall tools.ToolCall, toolDefinition tools.Tool, message, ag
 	}
 }
 
+// ToolCallValidationFailedEvent is sent when the arguments of a tool call
+// don't match the parameters of the tool. The tool isn't called and the
+// violations are sent back to the model, or, when ArgumentsEdit is set, to
+// the user who edited the arguments, who's asked for a confirmation again.
+type ToolCallValidationFailedEvent struct {
+	AgentContext
+
+	Type           string               `json:"type"`
+	ToolCall       tools.ToolCall       `json:"tool_call"`
+	ToolDefinition tools.Tool           `json:"tool_definition"`
+	Violations     []string             `json:"violations"`
+	ArgumentsEdit  *tools.ArgumentsEdit `json:"arguments_edit,omitempty"`
+}
+
+func ToolCallValidationFailed(toolCall tools.ToolCall, toolDefinition tools.Tool, violations []string, argumentsEdit *tools.ArgumentsEdit, agentName string) Event {
+	return &ToolCallValidationFailedEvent{
+		Type:           "tool_call_validation_failed",
+		ToolCall:       toolCall,
+		ToolDefinition: toolDefinition,
+		Violations:     violations,
+		ArgumentsEdit:  argumentsEdit,
+		AgentContext:   newAgentContext(agentName),
+	}
+}
+
+// ToolCallTimeoutEvent is sent when a tool call runs longer than its
+// timeout. The call is abandoned and the model gets an error result.
+type ToolCallTimeoutEvent struct {
+	AgentContext
+
+	Type           string         `json:"type"`
+	ToolCall       tools.ToolCall `json:"tool_call"`
+	ToolDefinition tools.Tool     `json:"tool_definition"`
+	Timeout        time.Duration  `json:"timeout"`
+}
+
+func ToolCallTimeout(toolCall tools.ToolCall, toolDefinition tools.Tool, timeout time.Duration, agentName string) Event {
+	return &ToolCallTimeoutEvent{
+		Type:           "tool_call_timeout",
+		ToolCall:       toolCall,
+		ToolDefinition: toolDefinition,
+		Timeout:        timeout,
+		AgentContext:   newAgentContext(agentName),
+	}
+}
+
+// ToolCallQueuedEvent is sent when a tool call waits for the other calls to
+// its toolset to finish, because the toolset can't serve more than
+// Concurrency calls at once, see tools.ConcurrencyDeclarer.
+type ToolCallQueuedEvent struct {
+	AgentContext
+
+	Type           string         `json:"type"`
+	ToolCall       tools.ToolCall `json:"tool_call"`
+	ToolDefinition tools.Tool     `json:"tool_definition"`
+	Concurrency    int            `json:"concurrency"`
+}
+
+func ToolCallQueued(toolCall tools.ToolCall, toolDefinition tools.Tool, concurrency int, agentName string) Event {
+	return &ToolCallQueuedEvent{
+		Type:           "tool_call_queued",
+		ToolCall:       toolCall,
+		ToolDefinition: toolDefinition,
+		Concurrency:    concurrency,
+		AgentContext:   newAgentContext(agentName),
+	}
+}
+
+// FileChangedEvent is sent for each change a tool made to a file. The
+// changes are recorded in the session, see Session.FileChanges.
+type FileChangedEvent struct {
+	AgentContext
+
+	Type       string           `json:"type"`
+	ToolCallID string           `json:"tool_call_id"`
+	Change     tools.FileChange `json:"change"`
+	SessionID  string           `json:"session_id,omitempty"`
+}
+
+func (e *FileChangedEvent) GetSessionID() string { return e.SessionID }
+
+func FileChanged(toolCallID string, change tools.FileChange, sessionID, agentName string) Event {
+	return &FileChangedEvent{
+		Type:         "file_changed",
+		ToolCallID:   toolCallID,
+		Change:       change,
+		SessionID:    sessionID,
+		AgentContext: newAgentContext(agentName),
+	}
+}
+
 // MessageAddedEvent is emitted when a message is added to the session.
 // This event is used by the PersistentRuntime wrapper to persist messages.
 type MessageAddedEvent struct {
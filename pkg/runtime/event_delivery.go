@@ -0,0 +1,168 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	// coalesceThreshold is the number of events waiting for a slow consumer
+	// above which the deltas streamed by the model are merged into the
+	// previous event, so that the queue doesn't grow with each token.
+	coalesceThreshold = 256
+	// slowConsumerThreshold is the number of events waiting for a consumer
+	// above which it's warned that it's slow.
+	slowConsumerThreshold = 1024
+	// maxQueuedEvents is the number of events waiting for a consumer above
+	// which the sender waits for it, so that the queue doesn't grow without
+	// bound when the consumer stops reading.
+	maxQueuedEvents = 4096
+	// abandonedConsumerTimeout is how long the consumer of a cancelled run
+	// may go without reading an event before it's deemed gone.
+	abandonedConsumerTimeout = 10 * time.Second
+)
+
+// deliverEvents forwards the events of in to the returned channel until in
+// is closed, in order, without blocking the sender on a slow consumer: the
+// events the consumer isn't ready for are queued. When the consumer falls
+// behind, the consecutive deltas of the model (the AgentChoiceEvent,
+// AgentChoiceReasoningEvent and PartialToolCallEvent of the same agent) are
+// merged into one, and it gets a warning. The state changes, confirmations,
+// errors, stream start and stop, are always delivered as they are. Past
+// maxQueuedEvents, the sender waits for the consumer.
+//
+// Once ctx is cancelled, the consumer of the run may be gone: when it reads
+// nothing for abandonedConsumerTimeout, the remaining events are dropped, so
+// that neither the sender nor the delivery wait for it forever.
+func deliverEvents(ctx context.Context, sessionID string, in <-chan Event) <-chan Event {
+	out := make(chan Event)
+
+	go func() {
+		defer close(out)
+
+		var (
+			queue     []Event
+			warned    bool
+			cancelled = ctx.Done()
+			// abandon measures how long the consumer of a cancelled run
+			// hasn't read an event it could.
+			abandon *time.Timer
+		)
+		defer func() {
+			if abandon != nil {
+				abandon.Stop()
+			}
+		}()
+		for in != nil || len(queue) > 0 {
+			// Sending on, or receiving from, a nil channel blocks: nothing
+			// is sent while the queue is empty, and nothing is received
+			// while it's full.
+			var (
+				send      chan<- Event
+				next      Event
+				abandoned <-chan time.Time
+			)
+			if len(queue) > 0 {
+				send, next = out, queue[0]
+				if abandon != nil {
+					abandoned = abandon.C
+				}
+			}
+			receive := in
+			if len(queue) >= maxQueuedEvents {
+				receive = nil
+			}
+
+			select {
+			case event, ok := <-receive:
+				if !ok {
+					in = nil
+					continue
+				}
+				if len(queue) == 0 && abandon != nil {
+					abandon.Reset(abandonedConsumerTimeout)
+				}
+				queue = enqueueEvent(queue, event)
+				if len(queue) >= slowConsumerThreshold && !warned {
+					slog.Warn("Slow event consumer, events are queued", "session_id", sessionID, "queued", len(queue))
+					queue = append(queue, WarningWithCode(ErrorCodeRuntimeSlowConsumer,
+						fmt.Sprintf("%d events are waiting to be read: the client doesn't read them fast enough.", len(queue)), "", ""))
+					warned = true
+				}
+			case send <- next:
+				queue[0] = nil
+				queue = queue[1:]
+				if len(queue) == 0 {
+					warned = false
+				}
+				if abandon != nil {
+					abandon.Reset(abandonedConsumerTimeout)
+				}
+			case <-cancelled:
+				cancelled = nil
+				abandon = time.NewTimer(abandonedConsumerTimeout)
+			case <-abandoned:
+				slog.Debug("Consumer of a cancelled run is gone, dropping its events", "session_id", sessionID, "dropped", len(queue))
+				discardEvents(in)
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// discardEvents drops the events of in until it's closed.
+func discardEvents(in <-chan Event) {
+	if in == nil {
+		return
+	}
+	for range in {
+	}
+}
+
+// enqueueEvent appends event to queue, merging it into the last event of
+// the queue when the consumer is behind and both are deltas of the same
+// stream.
+func enqueueEvent(queue []Event, event Event) []Event {
+	if len(queue) >= coalesceThreshold {
+		if merged, ok := coalesceEvents(queue[len(queue)-1], event); ok {
+			queue[len(queue)-1] = merged
+			return queue
+		}
+	}
+	return append(queue, event)
+}
+
+// coalesceEvents returns the event equivalent to last followed by event,
+// if they're deltas of the same stream. The events aren't modified: the
+// sender may still hold them.
+func coalesceEvents(last, event Event) (Event, bool) {
+	switch e := event.(type) {
+	case *AgentChoiceEvent:
+		if l, ok := last.(*AgentChoiceEvent); ok && l.AgentName == e.AgentName && l.SessionID == e.SessionID {
+			merged := *l
+			merged.Content += e.Content
+			return &merged, true
+		}
+	case *AgentChoiceReasoningEvent:
+		if l, ok := last.(*AgentChoiceReasoningEvent); ok && l.AgentName == e.AgentName && l.SessionID == e.SessionID {
+			merged := *l
+			merged.Content += e.Content
+			return &merged, true
+		}
+	case *PartialToolCallEvent:
+		if l, ok := last.(*PartialToolCallEvent); ok && l.AgentName == e.AgentName && l.ToolCall.ID == e.ToolCall.ID {
+			merged := *l
+			merged.ToolCall.Function.Arguments += e.ToolCall.Function.Arguments
+			merged.ArgumentsPreview = e.ArgumentsPreview
+			if merged.ToolDefinition == nil {
+				merged.ToolDefinition = e.ToolDefinition
+			}
+			return &merged, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,250 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/model/provider/fake"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+func TestDeliverEvents_SlowConsumer(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		in := make(chan Event)
+		out := deliverEvents(t.Context(), "session-1", in)
+
+		// The sender never waits for the consumer.
+		in <- StreamStarted("session-1", "root")
+		var want strings.Builder
+		for i := range 5000 {
+			content := fmt.Sprintf("%d ", i)
+			want.WriteString(content)
+			in <- AgentChoice("root", "session-1", content)
+			if i%1000 == 999 {
+				in <- ToolCallConfirmation(tools.ToolCall{ID: fmt.Sprintf("call_%d", i)}, tools.Tool{}, nil, "root")
+			}
+		}
+		in <- StreamStopped("session-1", "root")
+		close(in)
+
+		var (
+			received      []Event
+			content       strings.Builder
+			confirmations []string
+		)
+		for event := range out {
+			time.Sleep(time.Millisecond)
+			received = append(received, event)
+			switch e := event.(type) {
+			case *AgentChoiceEvent:
+				content.WriteString(e.Content)
+			case *ToolCallConfirmationEvent:
+				confirmations = append(confirmations, e.ToolCall.ID)
+			}
+		}
+
+		// The deltas are merged, nothing is lost or reordered.
+		assert.Less(t, len(received), 5000)
+		assert.Equal(t, want.String(), content.String())
+		assert.Equal(t, []string{"call_999", "call_1999", "call_2999", "call_3999", "call_4999"}, confirmations)
+		assert.IsType(t, &StreamStartedEvent{}, received[0])
+		assert.IsType(t, &StreamStoppedEvent{}, received[len(received)-1])
+	})
+}
+
+func TestDeliverEvents_NoMergeWhenConsumerKeepsUp(t *testing.T) {
+	in := make(chan Event)
+	out := deliverEvents(t.Context(), "session-1", in)
+
+	go func() {
+		defer close(in)
+		for _, content := range []string{"a", "b", "c"} {
+			in <- AgentChoice("root", "session-1", content)
+		}
+	}()
+
+	var contents []string
+	for event := range out {
+		contents = append(contents, event.(*AgentChoiceEvent).Content)
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, contents)
+}
+
+func TestDeliverEvents_BoundedQueue(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		in := make(chan Event)
+		out := deliverEvents(t.Context(), "session-1", in)
+
+		// The events that can't be merged queue up until the sender waits
+		// for the consumer.
+		const total = 2 * maxQueuedEvents
+		var sent atomic.Int32
+		go func() {
+			defer close(in)
+			for i := range total {
+				in <- ToolCallConfirmation(tools.ToolCall{ID: fmt.Sprintf("call_%d", i)}, tools.Tool{}, nil, "root")
+				sent.Add(1)
+			}
+		}()
+		synctest.Wait()
+		assert.Less(t, int(sent.Load()), total)
+
+		// The consumer is warned, and gets all the events in order.
+		var ids []string
+		var warnings []*WarningEvent
+		for event := range out {
+			switch e := event.(type) {
+			case *ToolCallConfirmationEvent:
+				ids = append(ids, e.ToolCall.ID)
+			case *WarningEvent:
+				warnings = append(warnings, e)
+			}
+		}
+		require.Len(t, ids, total)
+		for i, id := range ids {
+			require.Equal(t, fmt.Sprintf("call_%d", i), id)
+		}
+		require.NotEmpty(t, warnings)
+		assert.Equal(t, ErrorCodeRuntimeSlowConsumer, warnings[0].Code)
+	})
+}
+
+func TestDeliverEvents_CancelledRunWithoutConsumer(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		in := make(chan Event)
+		out := deliverEvents(ctx, "session-1", in)
+
+		// The run is cancelled and nobody reads its events anymore.
+		cancel()
+		sent := make(chan struct{})
+		go func() {
+			defer close(sent)
+			defer close(in)
+			for i := range 2 * maxQueuedEvents {
+				in <- ToolCallConfirmation(tools.ToolCall{ID: fmt.Sprintf("call_%d", i)}, tools.Tool{}, nil, "root")
+			}
+		}()
+
+		// The events are dropped: neither the sender nor the delivery
+		// wait forever.
+		time.Sleep(abandonedConsumerTimeout + time.Second)
+		synctest.Wait()
+		select {
+		case <-sent:
+		default:
+			t.Fatal("the sender still waits for the consumer")
+		}
+		_, ok := <-out
+		assert.False(t, ok)
+	})
+}
+
+func TestDeliverEvents_CancelledRunWithConsumer(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		in := make(chan Event)
+		out := deliverEvents(ctx, "session-1", in)
+
+		// The consumer still gets the events of a cancelled run, e.g. its
+		// stream stop, when it keeps reading.
+		cancel()
+		go func() {
+			defer close(in)
+			time.Sleep(2 * abandonedConsumerTimeout)
+			in <- StreamStopped("session-1", "root")
+		}()
+
+		var received []Event
+		for event := range out {
+			received = append(received, event)
+		}
+		require.Len(t, received, 1)
+		assert.IsType(t, &StreamStoppedEvent{}, received[0])
+	})
+}
+
+func TestCoalesceEvents(t *testing.T) {
+	first := PartialToolCall(tools.ToolCall{ID: "call_1", Function: tools.FunctionCall{Name: "shell", Arguments: `{"cmd":`}}, tools.Tool{Name: "shell"}, nil, "root")
+	second := PartialToolCall(tools.ToolCall{ID: "call_1", Function: tools.FunctionCall{Name: "shell", Arguments: `"ls"}`}}, tools.Tool{}, map[string]any{"cmd": "ls"}, "root")
+
+	merged, ok := coalesceEvents(first, second)
+	require.True(t, ok)
+	partial := merged.(*PartialToolCallEvent)
+	assert.JSONEq(t, `{"cmd":"ls"}`, partial.ToolCall.Function.Arguments)
+	assert.Equal(t, "shell", partial.ToolDefinition.Name)
+	assert.Equal(t, map[string]any{"cmd": "ls"}, partial.ArgumentsPreview)
+	assert.Equal(t, `{"cmd":`, first.(*PartialToolCallEvent).ToolCall.Function.Arguments, "the events aren't modified")
+
+	other := PartialToolCall(tools.ToolCall{ID: "call_2"}, tools.Tool{}, nil, "root")
+	_, ok = coalesceEvents(first, other)
+	assert.False(t, ok)
+	_, ok = coalesceEvents(AgentChoice("root", "session-1", "a"), AgentChoice("helper", "session-1", "b"))
+	assert.False(t, ok)
+	_, ok = coalesceEvents(AgentChoice("root", "session-1", "a"), AgentChoiceReasoning("root", "session-1", "b"))
+	assert.False(t, ok)
+}
+
+func TestScripted_SlowConsumer(t *testing.T) {
+	const rounds = 10
+	chunks := make([]string, 300)
+	for i := range chunks {
+		chunks[i] = "x"
+	}
+
+	var turns []*fake.Turn
+	for i := range rounds {
+		turns = append(turns, fake.NewTurn().
+			Content(chunks...).
+			ToolCall(fmt.Sprintf("call_%d", i), "shell", fmt.Sprintf(`{"cmd":"ls %d"}`, i)))
+	}
+	turns = append(turns, fake.NewTurn().Content("Done."))
+	prov := fake.NewScriptedProvider(t, "test/scripted", turns...)
+
+	var executed int
+	shell := []tools.Tool{{
+		Name:       "shell",
+		Parameters: map[string]any{},
+		Handler: func(context.Context, tools.ToolCall) (*tools.ToolCallResult, error) {
+			executed++
+			return tools.ResultSuccess("file1.txt"), nil
+		},
+	}}
+	root := agent.New("root", "You are a test agent", agent.WithModel(prov), agent.WithToolSets(newStubToolSet(nil, shell, nil)))
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("List the files, many times"))
+
+	// The consumer is slow, and answers the confirmations as soon as it
+	// gets them, whether the loop waits for the answers yet or not.
+	var content strings.Builder
+	var confirmations int
+	for event := range rt.RunStream(t.Context(), sess) {
+		time.Sleep(100 * time.Microsecond)
+		switch e := event.(type) {
+		case *AgentChoiceEvent:
+			content.WriteString(e.Content)
+		case *ToolCallConfirmationEvent:
+			confirmations++
+			require.NoError(t, rt.Resume(t.Context(), sess.ID, ResumeApprove()))
+		}
+	}
+
+	assert.Equal(t, rounds, confirmations)
+	assert.Equal(t, rounds, executed)
+	assert.Equal(t, strings.Repeat("x", rounds*len(chunks))+"Done.", content.String())
+
+	require.ErrorIs(t, rt.Resume(t.Context(), sess.ID, ResumeApprove()), ErrSessionNotRunning)
+}
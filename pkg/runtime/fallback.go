@@ -11,6 +11,7 @@ import (
 	"github.com/docker/docker-agent/pkg/backoff"
 	"github.com/docker/docker-agent/pkg/chat"
 	"github.com/docker/docker-agent/pkg/model/provider"
+	"github.com/docker/docker-agent/pkg/model/provider/router"
 	"github.com/docker/docker-agent/pkg/modelerrors"
 	"github.com/docker/docker-agent/pkg/modelsdev"
 	"github.com/docker/docker-agent/pkg/session"
@@ -256,14 +257,22 @@ func (r *LocalRuntime) tryModelWithFallback(
 				"in_cooldown", inCooldown,
 				"attempt", attempt+1)
 
+			release, err := r.waitForModel(ctx, a, sess, modelEntry.provider.ID(), events)
+			if err != nil {
+				return streamResult{}, nil, err
+			}
+
+			requestStart := time.Now()
 			stream, err := modelEntry.provider.CreateChatCompletionStream(ctx, messages, agentTools)
 			if err != nil {
+				release()
 				lastErr = err
 
 				// Context cancellation is never retryable
 				if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 					return streamResult{}, nil, err
 				}
+				r.metrics.recordModelRequest(ctx, a.Name(), modelEntry.provider.ID(), time.Since(requestStart), nil, err)
 
 				decision := r.handleModelError(ctx, err, a, modelEntry, attempt, hasFallbacks, &primaryFailedWithNonRetryable)
 				if decision == retryDecisionReturn {
@@ -284,8 +293,13 @@ func (r *LocalRuntime) tryModelWithFallback(
 					events <- AgentInfo(a.Name(), selected, a.Description(), a.WelcomeMessage())
 				}
 			}
+			if rc, ok := modelEntry.provider.(*router.Client); ok {
+				decision := rc.LastDecision()
+				events <- ModelRouted(a.Name(), sess.ID, rc.ID(), decision.ModelID, decision.Reason)
+			}
 
 			res, err := r.handleStream(ctx, stream, a, agentTools, sess, m, events)
+			release()
 			if err != nil {
 				lastErr = err
 
@@ -293,6 +307,7 @@ func (r *LocalRuntime) tryModelWithFallback(
 				if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 					return streamResult{}, nil, err
 				}
+				r.metrics.recordModelRequest(ctx, a.Name(), modelEntry.provider.ID(), time.Since(requestStart), nil, err)
 
 				decision := r.handleModelError(ctx, err, a, modelEntry, attempt, hasFallbacks, &primaryFailedWithNonRetryable)
 				if decision == retryDecisionReturn {
@@ -304,6 +319,8 @@ func (r *LocalRuntime) tryModelWithFallback(
 			}
 
 			// Success!
+			r.metrics.recordModelRequest(ctx, a.Name(), modelEntry.provider.ID(), time.Since(requestStart), res.Usage, nil)
+
 			// Handle cooldown state based on which model succeeded
 			switch {
 			case modelEntry.isFallback && primaryFailedWithNonRetryable:
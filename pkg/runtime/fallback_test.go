@@ -15,6 +15,7 @@ import (
 	"github.com/docker/docker-agent/pkg/model/provider"
 	"github.com/docker/docker-agent/pkg/model/provider/base"
 	"github.com/docker/docker-agent/pkg/modelerrors"
+	"github.com/docker/docker-agent/pkg/modelsdev"
 	"github.com/docker/docker-agent/pkg/session"
 	"github.com/docker/docker-agent/pkg/team"
 	"github.com/docker/docker-agent/pkg/tools"
@@ -128,6 +129,60 @@ func TestFallbackOrder(t *testing.T) {
 	})
 }
 
+// pricedModelStore prices each model with the given per-million token costs,
+// and gives them a context of limit tokens.
+type pricedModelStore struct {
+	ModelStore
+
+	costs map[string]*modelsdev.Cost
+	limit int
+}
+
+func (m pricedModelStore) GetModel(_ context.Context, id string) (*modelsdev.Model, error) {
+	return &modelsdev.Model{Cost: m.costs[id], Limit: modelsdev.Limit{Context: m.limit}}, nil
+}
+
+func TestFallbackTurnPricedWithFallbackModel(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		primary := &failingProvider{id: "primary/expensive", err: errors.New("500 internal server error")}
+		successStream := newStreamBuilder().
+			AddContent("Answer from the fallback").
+			AddStopWithUsage(1000, 500).
+			Build()
+		fallback := &mockProvider{id: "fallback/cheap", stream: successStream}
+
+		root := agent.New("root", "test",
+			agent.WithModel(primary),
+			agent.WithFallbackModel(fallback),
+			agent.WithFallbackRetries(0),
+		)
+
+		tm := team.New(team.WithAgents(root))
+		rt, err := NewLocalRuntime(tm, WithSessionCompaction(false), WithModelStore(pricedModelStore{costs: map[string]*modelsdev.Cost{
+			"primary/expensive": {Input: 100, Output: 100},
+			"fallback/cheap":    {Input: 1, Output: 2},
+		}}))
+		require.NoError(t, err)
+
+		sess := session.New(session.WithUserMessage("test"))
+		sess.Title = "Fallback Pricing Test"
+
+		var usage *Usage
+		for ev := range rt.RunStream(t.Context(), sess) {
+			if ev, ok := ev.(*TokenUsageEvent); ok && ev.Usage.LastMessage != nil {
+				usage = ev.Usage
+			}
+		}
+
+		require.NotNil(t, usage)
+		assert.Equal(t, "fallback/cheap", usage.LastMessage.Model)
+		assert.InDelta(t, 0.002, usage.LastMessage.Cost, 1e-9)
+		require.NotNil(t, usage.Totals)
+		assert.InDelta(t, 0.002, usage.Totals.Cost, 1e-9)
+		assert.InDelta(t, 0.002, sess.UsageByAgent()["root"].Cost, 1e-9)
+	})
+}
+
 func TestFallbackNoRetryOnNonRetryableError(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
 		primary := &failingProvider{id: "primary/auth-fail", err: errors.New("401 unauthorized")}
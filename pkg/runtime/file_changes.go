@@ -0,0 +1,53 @@
+package runtime
+
+import (
+	"sync"
+
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// fileChangeRecorder is the tools.FileChangeReporter of a tool call. It
+// records the changes in the session right away, and holds them until the
+// call returns to send them as events: a handler abandoned after its timeout
+// mustn't send events once the run is over.
+type fileChangeRecorder struct {
+	sess             *session.Session
+	maxSnapshotBytes int64
+
+	mu      sync.Mutex
+	changes []tools.FileChange
+}
+
+var _ tools.FileChangeReporter = (*fileChangeRecorder)(nil)
+
+func (r *LocalRuntime) newFileChangeRecorder(sess *session.Session) *fileChangeRecorder {
+	return &fileChangeRecorder{
+		sess:             sess,
+		maxSnapshotBytes: r.maxSnapshotBytes,
+	}
+}
+
+func (f *fileChangeRecorder) WillChange(path string) {
+	if f.maxSnapshotBytes > 0 {
+		f.sess.SnapshotFile(path, f.maxSnapshotBytes)
+	}
+}
+
+func (f *fileChangeRecorder) Changed(change tools.FileChange) {
+	f.sess.AddFileChange(change)
+
+	f.mu.Lock()
+	f.changes = append(f.changes, change)
+	f.mu.Unlock()
+}
+
+// flush returns the changes recorded since the last call.
+func (f *fileChangeRecorder) flush() []tools.FileChange {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	changes := f.changes
+	f.changes = nil
+	return changes
+}
@@ -0,0 +1,104 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/model/provider/fake"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+func TestScripted_FileChanges(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.go")
+	b := filepath.Join(dir, "b.go")
+	require.NoError(t, os.WriteFile(a, []byte("func oldName() {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(b, []byte("oldName()\n"), 0o644))
+
+	// rename rewrites the symbol in both files, as an LSP rename would.
+	rename := []tools.Tool{{
+		Name:       "rename",
+		Parameters: map[string]any{},
+		Handler: func(ctx context.Context, toolCall tools.ToolCall) (*tools.ToolCallResult, error) {
+			var args struct {
+				From string `json:"from"`
+				To   string `json:"to"`
+			}
+			if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
+				return nil, err
+			}
+			for _, path := range []string{a, b} {
+				content, err := os.ReadFile(path)
+				if err != nil {
+					return nil, err
+				}
+				tools.WillChangeFile(ctx, path)
+				renamed := strings.ReplaceAll(string(content), args.From, args.To)
+				if err := os.WriteFile(path, []byte(renamed), 0o644); err != nil {
+					return nil, err
+				}
+				tools.ReportFileChange(ctx, tools.FileChange{Path: path, Type: tools.FileModified})
+			}
+			return tools.ResultSuccess("renamed"), nil
+		},
+	}}
+
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().ToolCall("call_1", "rename", `{"from":"oldName","to":"newName"}`),
+		fake.NewTurn().ToolCall("call_2", "rename", `{"from":"newName","to":"finalName"}`),
+		fake.NewTurn().
+			Content("Renamed.").
+			Expect(fake.LastMessage(chat.MessageRoleTool, "renamed")),
+	)
+	root := agent.New("root", "You are a test agent", agent.WithModel(prov), agent.WithToolSets(newStubToolSet(nil, rename, nil)))
+
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)),
+		WithSessionCompaction(false),
+		WithModelStore(mockModelStore{}),
+		WithFileSnapshots(1024),
+	)
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Rename oldName"))
+	events := runScripted(t, rt, sess, ResumeApprove())
+
+	var changed []string
+	for _, event := range events {
+		if e, ok := event.(*FileChangedEvent); ok {
+			assert.Equal(t, sess.ID, e.SessionID)
+			changed = append(changed, e.ToolCallID+":"+filepath.Base(e.Change.Path))
+		}
+	}
+	assert.Equal(t, []string{"call_1:a.go", "call_1:b.go", "call_2:a.go", "call_2:b.go"}, changed)
+
+	// The ledger holds each file once.
+	assert.Equal(t, []tools.FileChange{
+		{Path: a, Type: tools.FileModified},
+		{Path: b, Type: tools.FileModified},
+	}, sess.FileChanges())
+
+	content, err := os.ReadFile(b)
+	require.NoError(t, err)
+	assert.Equal(t, "finalName()\n", string(content))
+
+	// The files are restored to their content before the first rename.
+	reverted, err := sess.RevertFileChanges()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{a, b}, reverted)
+
+	content, err = os.ReadFile(b)
+	require.NoError(t, err)
+	assert.Equal(t, "oldName()\n", string(content))
+	assert.Empty(t, sess.FileChanges())
+}
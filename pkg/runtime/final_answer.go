@@ -0,0 +1,97 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/tools/builtin"
+)
+
+// maxFinalAnswerReminders is the number of times in a row an agent that
+// requires a final answer is reminded to call final_answer before its turn
+// ends without one.
+const maxFinalAnswerReminders = 2
+
+// finalAnswerPrompt asks the model for the final answer it didn't give.
+const finalAnswerPrompt = "You stopped without calling final_answer. Call final_answer with the deliverable of your task, without explanations around it."
+
+// FinalAnswer is the deliverable an agent handed over with the final_answer
+// tool, apart from the prose of its messages.
+type FinalAnswer struct {
+	Content string `json:"content"`
+	// Format is the format of Content the agent announced, e.g. "json".
+	Format string `json:"format,omitempty"`
+}
+
+// FinalAnswerOf returns the final answer of the last turn of sess, from its
+// last successful final_answer call since the last message of the user.
+// The messages of sub-sessions aren't searched.
+func FinalAnswerOf(sess *session.Session) (FinalAnswer, bool) {
+	failed := make(map[string]bool)
+	for i := len(sess.Messages) - 1; i >= 0; i-- {
+		item := sess.Messages[i]
+		if !item.IsMessage() {
+			continue
+		}
+		msg := item.Message.Message
+		switch {
+		case msg.Role == chat.MessageRoleUser && !item.Message.IsSystemReminder():
+			return FinalAnswer{}, false
+		case msg.Role == chat.MessageRoleTool && msg.IsError:
+			failed[msg.ToolCallID] = true
+		case msg.Role == chat.MessageRoleAssistant:
+			for j := len(msg.ToolCalls) - 1; j >= 0; j-- {
+				call := msg.ToolCalls[j]
+				if call.Function.Name != builtin.ToolNameFinalAnswer || failed[call.ID] {
+					continue
+				}
+				var args builtin.FinalAnswerArgs
+				if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil || strings.TrimSpace(args.Content) == "" {
+					continue
+				}
+				return FinalAnswer{Content: args.Content, Format: args.Format}, true
+			}
+		}
+	}
+	return FinalAnswer{}, false
+}
+
+// subSessionResult returns the result of a sub-session for its caller: the
+// final answer of its agent, or else its last message.
+func subSessionResult(child *session.Session) string {
+	if answer, ok := FinalAnswerOf(child); ok {
+		return answer.Content
+	}
+	return child.GetLastAssistantMessageContent()
+}
+
+// checkFinalAnswer checks that agent a, when it requires a final answer,
+// called final_answer during its turn. When it didn't, the model is reminded
+// to and checkFinalAnswer returns true for the turn to be re-run, at most
+// maxFinalAnswerReminders times in a row. After that, the turn ends without
+// a final answer, with a warning.
+func (r *LocalRuntime) checkFinalAnswer(sess *session.Session, a *agent.Agent, reminders *int, events chan Event) bool {
+	if !a.RequireFinalAnswer() {
+		return false
+	}
+	if _, ok := FinalAnswerOf(sess); ok {
+		*reminders = 0
+		return false
+	}
+
+	*reminders++
+	if *reminders > maxFinalAnswerReminders {
+		*reminders = 0
+		slog.Warn("Agent stopped without a final answer", "agent", a.Name(), "session_id", sess.ID)
+		events <- Warning(fmt.Sprintf("%s stopped without calling final_answer: its last message is the result of the turn.", a.Name()), a.Name())
+		return false
+	}
+
+	addSystemReminder(sess, a, finalAnswerPrompt, events, session.WithReminderHidden())
+	return true
+}
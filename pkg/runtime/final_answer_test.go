@@ -0,0 +1,152 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/model/provider/fake"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
+	"github.com/docker/docker-agent/pkg/tools/builtin"
+)
+
+func TestFinalAnswer_TaskTransfer(t *testing.T) {
+	rootProv := fake.NewScriptedProvider(t, "test/root",
+		fake.NewTurn().
+			ToolCall("call_1", builtin.ToolNameTransferTask, `{"agent":"librarian","task":"find the chapter about sandworms","expected_output":"a chapter"}`),
+		fake.NewTurn().
+			Content("The librarian found chapter 3.").
+			Expect(fake.LastMessage(chat.MessageRoleTool, "chapter 3")),
+	)
+	childProv := fake.NewScriptedProvider(t, "test/librarian",
+		fake.NewTurn().
+			ToolCall("call_2", builtin.ToolNameFinalAnswer, `{"content":"chapter 3","format":"text"}`),
+		fake.NewTurn().
+			Content("I looked the sandworms up in the index of the book."),
+	)
+
+	librarian := agent.New("librarian", "Library agent",
+		agent.WithModel(childProv),
+		agent.WithToolSets(builtin.NewFinalAnswerTool()),
+	)
+	root := agent.New("root", "Root agent",
+		agent.WithModel(rootProv),
+		agent.WithToolSets(builtin.NewTransferTaskTool()),
+	)
+	agent.WithSubAgents(librarian)(root)
+
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root, librarian)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Which chapter is about sandworms?"), session.WithToolsApproved(true))
+	runScripted(t, rt, sess, ResumeApprove())
+
+	// The root agent gets the final answer of the librarian, without the
+	// prose of its last message.
+	requests := rootProv.Requests()
+	require.Len(t, requests, 2)
+	last := requests[1].Messages
+	assert.Equal(t, "chapter 3", last[len(last)-1].Content)
+	assert.Equal(t, "The librarian found chapter 3.", sess.GetLastAssistantMessageContent())
+}
+
+func TestFinalAnswer_Required(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		// The agent stops without a final answer...
+		fake.NewTurn().
+			Content("The answer is 42, as computed by Deep Thought."),
+		// ...and is reminded to give one.
+		fake.NewTurn().
+			ToolCall("call_1", builtin.ToolNameFinalAnswer, `{"content":"42","format":"text"}`).
+			Expect(fake.LastMessage(chat.MessageRoleUser, "You stopped without calling final_answer")),
+		fake.NewTurn().
+			Content("Done."),
+	)
+	root := agent.New("root", "You are a test agent",
+		agent.WithModel(prov),
+		agent.WithToolSets(builtin.NewFinalAnswerTool()),
+		agent.WithRequireFinalAnswer(true),
+	)
+
+	result, err := RunOnce(t.Context(), RunOnceRequest{
+		Team:    team.New(team.WithAgents(root)),
+		Prompt:  "What is the answer?",
+		Options: []Opt{WithSessionCompaction(false), WithModelStore(mockModelStore{})},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Done.", result.Output)
+	assert.Equal(t, &FinalAnswer{Content: "42", Format: "text"}, result.FinalAnswer)
+}
+
+func TestFinalAnswer_RequiredGivesUp(t *testing.T) {
+	var turns []*fake.Turn
+	for range maxFinalAnswerReminders + 1 {
+		turns = append(turns, fake.NewTurn().Content("The answer is 42."))
+	}
+	prov := fake.NewScriptedProvider(t, "test/scripted", turns...)
+	root := agent.New("root", "You are a test agent",
+		agent.WithModel(prov),
+		agent.WithToolSets(builtin.NewFinalAnswerTool()),
+		agent.WithRequireFinalAnswer(true),
+	)
+
+	result, err := RunOnce(t.Context(), RunOnceRequest{
+		Team:    team.New(team.WithAgents(root)),
+		Prompt:  "What is the answer?",
+		Options: []Opt{WithSessionCompaction(false), WithModelStore(mockModelStore{})},
+	})
+	require.NoError(t, err)
+
+	// The last message is the result, with a warning.
+	assert.Equal(t, "The answer is 42.", result.Output)
+	assert.Nil(t, result.FinalAnswer)
+
+	var warned bool
+	for _, event := range result.Events {
+		if e, ok := event.(*WarningEvent); ok {
+			warned = warned || e.Message == "root stopped without calling final_answer: its last message is the result of the turn."
+		}
+	}
+	assert.True(t, warned)
+}
+
+func TestFinalAnswerOf(t *testing.T) {
+	call := func(id, args string) *session.Message {
+		return &session.Message{Message: chat.Message{
+			Role:      chat.MessageRoleAssistant,
+			ToolCalls: []tools.ToolCall{{ID: id, Function: tools.FunctionCall{Name: builtin.ToolNameFinalAnswer, Arguments: args}}},
+		}}
+	}
+	response := func(id string, isError bool) *session.Message {
+		return &session.Message{Message: chat.Message{Role: chat.MessageRoleTool, ToolCallID: id, IsError: isError}}
+	}
+
+	sess := session.New(session.WithUserMessage("First"))
+	sess.AddMessage(call("call_1", `{"content":"first"}`))
+	sess.AddMessage(response("call_1", false))
+
+	answer, ok := FinalAnswerOf(sess)
+	require.True(t, ok)
+	assert.Equal(t, FinalAnswer{Content: "first"}, answer)
+
+	// A new message of the user starts a turn without a final answer.
+	sess.AddMessage(session.UserMessage("Second"))
+	_, ok = FinalAnswerOf(sess)
+	assert.False(t, ok)
+
+	// Failed calls don't count.
+	sess.AddMessage(call("call_2", `{"content":"second","format":"json"}`))
+	sess.AddMessage(response("call_2", false))
+	sess.AddMessage(call("call_3", `{"content":"rejected"}`))
+	sess.AddMessage(response("call_3", true))
+
+	answer, ok = FinalAnswerOf(sess)
+	require.True(t, ok)
+	assert.Equal(t, FinalAnswer{Content: "second", Format: "json"}, answer)
+}
@@ -0,0 +1,134 @@
+package runtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/model/provider/fake"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
+	"github.com/docker/docker-agent/pkg/tools/builtin"
+)
+
+func handoffTo(id, agentName string) *fake.Turn {
+	return fake.NewTurn().ToolCall(id, builtin.ToolNameHandoff, `{"agent":"`+agentName+`"}`)
+}
+
+func TestScripted_HandoffLoopDetection(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		handoffTo("call_1", "helper"),
+		handoffTo("call_2", "root"),
+		handoffTo("call_3", "helper"),
+		handoffTo("call_4", "root"),
+		// root already handed off to helper twice: the third time is blocked.
+		handoffTo("call_5", "helper"),
+		fake.NewTurn().
+			Content("I'll answer myself.").
+			Expect(fake.LastMessage(chat.MessageRoleTool, "Handoff to helper blocked")),
+	)
+
+	root := agent.New("root", "You are the root agent", agent.WithModel(prov), agent.WithToolSets(builtin.NewHandoffTool()))
+	helper := agent.New("helper", "You are the helper agent", agent.WithModel(prov), agent.WithToolSets(builtin.NewHandoffTool()))
+	agent.WithHandoffs(helper)(root)
+	agent.WithHandoffs(root)(helper)
+
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root, helper)),
+		WithSessionCompaction(false),
+		WithModelStore(mockModelStore{}),
+		WithHandoffLoopDetection(2, 10),
+	)
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Help me"))
+	events := runScripted(t, rt, sess, ResumeApprove())
+
+	assert.Equal(t, "I'll answer myself.", sess.GetLastAssistantMessageContent())
+	assert.Equal(t, "root", rt.CurrentAgentName())
+
+	handoffs := sess.Handoffs()
+	require.Len(t, handoffs, 4, "the blocked handoff isn't recorded")
+	assert.Equal(t, session.Handoff{From: "root", To: "helper", AtMessageIndex: 2}, handoffs[0])
+	assert.Equal(t, session.Handoff{From: "helper", To: "root", AtMessageIndex: 4}, handoffs[1])
+
+	var (
+		handoffEvents []*AgentHandoffEvent
+		loops         []*HandoffLoopDetectedEvent
+	)
+	for _, event := range events {
+		switch e := event.(type) {
+		case *AgentHandoffEvent:
+			handoffEvents = append(handoffEvents, e)
+		case *HandoffLoopDetectedEvent:
+			loops = append(loops, e)
+		}
+	}
+	require.Len(t, handoffEvents, 4)
+	assert.Equal(t, handoffs, handoffEvents[3].Handoffs)
+
+	require.Len(t, loops, 1)
+	assert.Equal(t, "root", loops[0].FromAgent)
+	assert.Equal(t, "helper", loops[0].ToAgent)
+	assert.Equal(t, 2, loops[0].Repeats)
+	assert.Equal(t, sess.ID, loops[0].GetSessionID())
+}
+
+func TestRecentHandoffRepeats(t *testing.T) {
+	sess := session.New()
+	for range 3 {
+		sess.AddHandoff("root", "helper")
+		sess.AddHandoff("helper", "root")
+	}
+	sess.AddHandoff("root", "reviewer")
+
+	r := &LocalRuntime{handoffLoopWindow: 10}
+	assert.Equal(t, 3, r.recentHandoffRepeats(sess, "root", "helper"))
+	assert.Equal(t, 1, r.recentHandoffRepeats(sess, "root", "reviewer"))
+	assert.Equal(t, 0, r.recentHandoffRepeats(sess, "reviewer", "root"))
+
+	r.handoffLoopWindow = 3
+	assert.Equal(t, 1, r.recentHandoffRepeats(sess, "root", "helper"))
+
+	r.handoffLoopWindow = 0
+	assert.Equal(t, 3, r.recentHandoffRepeats(sess, "root", "helper"))
+}
+
+func TestScripted_HandoffToolFromAnotherToolSet(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		handoffTo("call_1", "helper"),
+		fake.NewTurn().
+			Content("Done.").
+			Expect(fake.LastMessage(chat.MessageRoleTool, "ticket handed off")),
+	)
+
+	// The agent has no handoffs: its own "handoff" tool isn't the built-in one.
+	var executed bool
+	handoff := []tools.Tool{{
+		Name:       builtin.ToolNameHandoff,
+		Parameters: map[string]any{},
+		Handler: func(context.Context, tools.ToolCall) (*tools.ToolCallResult, error) {
+			executed = true
+			return tools.ResultSuccess("ticket handed off"), nil
+		},
+	}}
+	root := agent.New("root", "You are the root agent", agent.WithModel(prov), agent.WithToolSets(newStubToolSet(nil, handoff, nil)))
+	helper := agent.New("helper", "You are the helper agent", agent.WithModel(prov))
+
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root, helper)),
+		WithSessionCompaction(false),
+		WithModelStore(mockModelStore{}),
+	)
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Hand off the ticket"))
+	runScripted(t, rt, sess, ResumeApprove())
+
+	assert.True(t, executed)
+	assert.Equal(t, "root", rt.CurrentAgentName())
+	assert.Empty(t, sess.Handoffs())
+}
@@ -0,0 +1,105 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/hooks"
+	"github.com/docker/docker-agent/pkg/model/provider/fake"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+func TestLifecycleHooks_TurnEnd(t *testing.T) {
+	dir := t.TempDir()
+	mainFile := filepath.Join(dir, "main.go")
+
+	agentTools := []tools.Tool{
+		{
+			Name:       "write",
+			Parameters: map[string]any{},
+			Handler: func(ctx context.Context, _ tools.ToolCall) (*tools.ToolCallResult, error) {
+				if err := os.WriteFile(mainFile, []byte("package main\n\nfunc main() {\n"), 0o644); err != nil {
+					return nil, err
+				}
+				tools.ReportFileChange(ctx, tools.FileChange{Path: mainFile, Type: tools.FileCreated})
+				return tools.ResultSuccess("written"), nil
+			},
+		},
+		{
+			Name:       "lint",
+			Parameters: map[string]any{},
+			Handler: func(_ context.Context, toolCall tools.ToolCall) (*tools.ToolCallResult, error) {
+				return tools.ResultSuccess("lint " + toolCall.Function.Arguments), nil
+			},
+		},
+	}
+
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().ToolCall("call_1", "write", `{}`),
+		fake.NewTurn().
+			Content("Done.").
+			Expect(fake.LastMessage(chat.MessageRoleTool, "written")),
+		// The next turn gets the outputs of the hooks to inject.
+		fake.NewTurn().
+			Content("Fixed.").
+			Expect(
+				fake.HasMessage(chat.MessageRoleUser, "main.go:4: expected '}'"),
+				fake.HasMessage(chat.MessageRoleUser, `lint {"strict":true}`),
+			),
+	)
+	root := agent.New("root", "You are a test agent", agent.WithModel(prov), agent.WithToolSets(newStubToolSet(nil, agentTools, nil)))
+
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)),
+		WithSessionCompaction(false),
+		WithModelStore(mockModelStore{}),
+		WithWorkingDir(dir),
+		WithLifecycleHooks(&hooks.Config{TurnEnd: []hooks.Hook{
+			{Type: hooks.HookTypeCommand, Command: "cat > turn.json; echo \"main.go:4: expected '}'\" >&2; exit 1", Inject: true, Timeout: 5},
+			{Type: hooks.HookTypeTool, Tool: "lint", Args: map[string]any{"strict": true}, Inject: true, Timeout: 5},
+		}}),
+	)
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Write main.go"))
+	events := runScripted(t, rt, sess, ResumeApprove())
+
+	// The failure of the build is a warning, the run goes on.
+	var warnings []string
+	for _, event := range events {
+		if e, ok := event.(*WarningEvent); ok {
+			warnings = append(warnings, e.Message)
+		}
+	}
+	assert.Contains(t, warnings, `turn_end hook "cat > turn.json; echo \"main.go:4: expected '}'\" >&2; exit 1" failed with exit code 1`)
+	assert.Equal(t, "Done.", sess.GetLastAssistantMessageContent())
+
+	// The hook received what the tools did during the turn.
+	content, err := os.ReadFile(filepath.Join(dir, "turn.json"))
+	require.NoError(t, err)
+	var input hooks.Input
+	require.NoError(t, json.Unmarshal(content, &input))
+	assert.Equal(t, hooks.EventTurnEnd, input.HookEventName)
+	assert.Equal(t, []string{mainFile}, input.FilesChanged)
+	assert.Equal(t, []string{"write"}, input.ToolsUsed)
+
+	sess.AddMessage(session.UserMessage("Fix it"))
+	runScripted(t, rt, sess, ResumeApprove())
+	assert.Equal(t, "Fixed.", sess.GetLastAssistantMessageContent())
+
+	// The turn without tool calls didn't change any file.
+	content, err = os.ReadFile(filepath.Join(dir, "turn.json"))
+	require.NoError(t, err)
+	input = hooks.Input{}
+	require.NoError(t, json.Unmarshal(content, &input))
+	assert.Empty(t, input.FilesChanged)
+}
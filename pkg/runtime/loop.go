@@ -7,7 +7,6 @@ import (
 	"log/slog"
 	"slices"
 	"strings"
-	"time"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -16,6 +15,7 @@ import (
 	"github.com/docker/docker-agent/pkg/agent"
 	"github.com/docker/docker-agent/pkg/chat"
 	"github.com/docker/docker-agent/pkg/compaction"
+	"github.com/docker/docker-agent/pkg/model/provider/router"
 	"github.com/docker/docker-agent/pkg/modelerrors"
 	"github.com/docker/docker-agent/pkg/modelsdev"
 	"github.com/docker/docker-agent/pkg/session"
@@ -26,13 +26,16 @@ import (
 )
 
 // registerDefaultTools wires up the built-in tool handlers (delegation,
-// background agents, model switching) into the runtime's tool dispatch map.
+// background agents, model switching, shared context) into the runtime's tool dispatch map.
 func (r *LocalRuntime) registerDefaultTools() {
 	r.toolMap[builtin.ToolNameTransferTask] = r.handleTaskTransfer
 	r.toolMap[builtin.ToolNameHandoff] = r.handleHandoff
 	r.toolMap[builtin.ToolNameChangeModel] = r.handleChangeModel
 	r.toolMap[builtin.ToolNameRevertModel] = r.handleRevertModel
 	r.toolMap[builtin.ToolNameRunSkill] = r.handleRunSkill
+	r.toolMap[builtin.ToolNameContextSet] = r.handleContextSet
+	r.toolMap[builtin.ToolNameContextGet] = r.handleContextGet
+	r.toolMap[builtin.ToolNameContextList] = r.handleContextList
 
 	r.bgAgents.RegisterHandlers(func(name string, fn func(context.Context, *session.Session, tools.ToolCall) (*tools.ToolCallResult, error)) {
 		r.toolMap[name] = func(ctx context.Context, sess *session.Session, tc tools.ToolCall, _ chan Event) (*tools.ToolCallResult, error) {
@@ -42,22 +45,29 @@ func (r *LocalRuntime) registerDefaultTools() {
 }
 
 // finalizeEventChannel performs cleanup at the end of a RunStream goroutine:
-// restores the previous elicitation channel, emits the StreamStopped event,
-// fires hooks, and closes the events channel.
-func (r *LocalRuntime) finalizeEventChannel(ctx context.Context, sess *session.Session, prevElicitationCh, events chan Event) {
+// restores the previous elicitation channel of the run, emits the
+// RunCompleted and StreamStopped events, fires hooks, and closes the events
+// channel.
+func (r *LocalRuntime) finalizeEventChannel(ctx context.Context, sess *session.Session, run *sessionRun, prevElicitationCh, events chan Event, prevSession *session.Session) {
 	// Swap back the parent's elicitation channel before closing this
 	// stream's channel. This prevents a send-on-closed-channel panic
 	// and restores elicitation for the parent session.
-	r.swapElicitationEventsChannel(prevElicitationCh)
+	run.swapEvents(prevElicitationCh)
+	run.swapSession(prevSession)
 
 	defer close(events)
 
 	a := r.resolveSessionAgent(sess)
 
+	// The next run of the session can start as soon as the client sees
+	// the stream stop.
+	r.endSessionRun(sess, run)
+
 	// Execute session end hooks with a context that won't be cancelled so
 	// cleanup hooks run even when the stream was interrupted (e.g. Ctrl+C).
-	r.executeSessionEndHooks(context.WithoutCancel(ctx), sess, a)
+	r.executeSessionEndHooks(context.WithoutCancel(ctx), sess, a, events)
 
+	events <- RunCompleted(sess.ID, a.Name(), r.metrics.recordRun(ctx, a.Name()))
 	events <- StreamStopped(sess.ID, a.Name())
 
 	r.executeOnUserInputHooks(ctx, sess.ID, "stream stopped")
@@ -70,24 +80,53 @@ func (r *LocalRuntime) finalizeEventChannel(ctx context.Context, sess *session.S
 // context cancellation). Each iteration: sends messages to the model, streams
 // the response, executes any tool calls, and loops until the model signals stop
 // or the iteration limit is reached.
+//
+// The loop doesn't wait for the consumer of the events: they're queued, and
+// the deltas of the model merged when the consumer falls behind, see
+// deliverEvents.
+//
+// Each session runs with its own sessionRun, so several sessions can run
+// concurrently: their handoffs, confirmations and elicitations don't mix.
 func (r *LocalRuntime) RunStream(ctx context.Context, sess *session.Session) <-chan Event {
-	slog.Debug("Starting runtime stream", "agent", r.CurrentAgentName(), "session_id", sess.ID)
 	events := make(chan Event, 128)
 
+	// Close cancels the stream and waits for events to be closed.
+	ctx, streamDone, ok := r.trackStream(ctx)
+	if !ok {
+		events <- ErrorWithCode(ErrorCodeRuntimeClosed, errRuntimeClosed.Error(), "")
+		close(events)
+		return events
+	}
+
+	// Agent configuration reloads wait for the stream to end.
+	streamEnded := r.streamStarted(ctx)
+
+	run := r.startSessionRun(ctx, sess)
+	ctx = withSessionRun(ctx, run)
+	slog.Debug("Starting runtime stream", "agent", run.agentName(), "session_id", sess.ID)
+
 	go func() {
-		telemetry.RecordSessionStart(ctx, r.CurrentAgentName(), sess.ID)
+		defer streamDone()
+		defer streamEnded()
+		defer r.endSessionRun(sess, run)
+
+		telemetry.RecordSessionStart(ctx, run.agentName(), sess.ID)
 
 		ctx, sessionSpan := r.startSpan(ctx, "runtime.session", trace.WithAttributes(
-			attribute.String("agent", r.CurrentAgentName()),
+			attribute.String("agent", run.agentName()),
 			attribute.String("session.id", sess.ID),
 		))
 		defer sessionSpan.End()
 
+		// Count what happens during this run for the RunCompleted event.
+		ctx = withRunStats(ctx, &statsRecorder{})
+
 		// Swap in this stream's events channel for elicitation and save the
 		// previous one so it can be restored on teardown. This allows nested
 		// RunStream calls to temporarily own elicitation without losing the
 		// parent's channel.
-		prevElicitationCh := r.swapElicitationEventsChannel(events)
+		prevElicitationCh := run.swapEvents(events)
+		prevSession := run.swapSession(sess)
 
 		a := r.resolveSessionAgent(sess)
 
@@ -98,16 +137,22 @@ func (r *LocalRuntime) RunStream(ctx context.Context, sess *session.Session) <-c
 		events <- TeamInfo(r.agentDetailsFromTeam(), a.Name())
 
 		r.emitAgentWarnings(a, chanSend(events))
-		r.configureToolsetHandlers(a, events)
+		r.repairSession(sess, a, chanSend(events))
+		r.configureToolsetHandlers(a)
 
 		agentTools, err := r.getTools(ctx, a, sessionSpan, events)
 		if err != nil {
-			events <- Error(fmt.Sprintf("failed to get tools: %v", err))
+			events <- ErrorWithCode(ErrorCodeToolsetStartFailed, fmt.Sprintf("failed to get tools: %v", err), "")
 			return
 		}
 		agentTools = filterExcludedTools(agentTools, sess.ExcludedTools)
 
-		events <- ToolsetInfo(len(agentTools), false, a.Name())
+		events <- toolsetInfo(agentTools, a.FailedToolSets(), false, a.Name())
+
+		if err := a.RenderInstruction(ctx); err != nil {
+			events <- ErrorWithCode(ErrorCodeAgentInstructionFailed, err.Error(), "")
+			return
+		}
 
 		messages := sess.GetMessages(a)
 		if sess.SendUserMessage && len(messages) > 0 {
@@ -117,7 +162,7 @@ func (r *LocalRuntime) RunStream(ctx context.Context, sess *session.Session) <-c
 
 		events <- StreamStarted(sess.ID, a.Name())
 
-		defer r.finalizeEventChannel(ctx, sess, prevElicitationCh, events)
+		defer r.finalizeEventChannel(ctx, sess, run, prevElicitationCh, events, prevSession)
 
 		iteration := 0
 		// Use a runtime copy of maxIterations so we don't modify the session's persistent config
@@ -150,6 +195,14 @@ func (r *LocalRuntime) RunStream(ctx context.Context, sess *session.Session) <-c
 		var toolModelOverride string
 		var prevAgentName string
 
+		// guardRejections counts the consecutive answers rejected by the
+		// output guards of the agent.
+		var guardRejections int
+
+		// finalAnswerReminders counts the consecutive answers without a
+		// final answer of an agent that requires one.
+		var finalAnswerReminders int
+
 		for {
 			a = r.resolveSessionAgent(sess)
 
@@ -157,15 +210,17 @@ func (r *LocalRuntime) RunStream(ctx context.Context, sess *session.Session) <-c
 			// leak from one agent's toolset into another agent's turn.
 			if a.Name() != prevAgentName {
 				toolModelOverride = ""
+				guardRejections = 0
+				finalAnswerReminders = 0
 				prevAgentName = a.Name()
 			}
 
 			r.emitAgentWarnings(a, chanSend(events))
-			r.configureToolsetHandlers(a, events)
+			r.configureToolsetHandlers(a)
 
 			agentTools, err := r.getTools(ctx, a, sessionSpan, events)
 			if err != nil {
-				events <- Error(fmt.Sprintf("failed to get tools: %v", err))
+				events <- ErrorWithCode(ErrorCodeToolsetStartFailed, fmt.Sprintf("failed to get tools: %v", err), "")
 				return
 			}
 			agentTools = filterExcludedTools(agentTools, sess.ExcludedTools)
@@ -173,7 +228,7 @@ func (r *LocalRuntime) RunStream(ctx context.Context, sess *session.Session) <-c
 			// Emit updated tool count. After a ToolListChanged MCP notification
 			// the cache is invalidated, so getTools above re-fetches from the
 			// server and may return a different count.
-			events <- ToolsetInfo(len(agentTools), false, a.Name())
+			events <- toolsetInfo(agentTools, a.FailedToolSets(), false, a.Name())
 
 			// Check iteration limit
 			if runtimeMaxIterations > 0 && iteration >= runtimeMaxIterations {
@@ -184,6 +239,7 @@ func (r *LocalRuntime) RunStream(ctx context.Context, sess *session.Session) <-c
 					"max", runtimeMaxIterations,
 				)
 
+				run.discardResume()
 				events <- MaxIterationsReached(runtimeMaxIterations)
 
 				maxIterMsg := fmt.Sprintf("Maximum iterations reached (%d)", runtimeMaxIterations)
@@ -194,39 +250,20 @@ func (r *LocalRuntime) RunStream(ctx context.Context, sess *session.Session) <-c
 				// blocking forever waiting for user input.
 				if sess.NonInteractive {
 					slog.Debug("Auto-stopping after max iterations (non-interactive)", "agent", a.Name())
-
-					assistantMessage := chat.Message{
-						Role: chat.MessageRoleAssistant,
-						Content: fmt.Sprintf(
-							"Execution stopped after reaching the configured max_iterations limit (%d).",
-							runtimeMaxIterations,
-						),
-						CreatedAt: time.Now().Format(time.RFC3339),
-					}
-
-					addAgentMessage(sess, a, &assistantMessage, events)
+					events <- WarningWithCode(ErrorCodeAgentMaxIterations, maxIterMsg, "Raise max_iterations in the agent configuration to let it run longer.", a.Name())
+					addSystemReminder(sess, a, maxIterationsReminder(runtimeMaxIterations), events)
 					return
 				}
 
 				// Wait for user decision (resume / reject)
 				select {
-				case req := <-r.resumeChan:
+				case req := <-run.resumeChan:
 					if req.Type == ResumeTypeApprove {
 						slog.Debug("User chose to continue after max iterations", "agent", a.Name())
 						runtimeMaxIterations = iteration + 10
 					} else {
 						slog.Debug("User rejected continuation", "agent", a.Name())
-
-						assistantMessage := chat.Message{
-							Role: chat.MessageRoleAssistant,
-							Content: fmt.Sprintf(
-								"Execution stopped after reaching the configured max_iterations limit (%d).",
-								runtimeMaxIterations,
-							),
-							CreatedAt: time.Now().Format(time.RFC3339),
-						}
-
-						addAgentMessage(sess, a, &assistantMessage, events)
+						addSystemReminder(sess, a, maxIterationsReminder(runtimeMaxIterations), events)
 						return
 					}
 
@@ -247,6 +284,7 @@ func (r *LocalRuntime) RunStream(ctx context.Context, sess *session.Session) <-c
 				slog.Debug("Runtime stream context cancelled, stopping loop", "agent", a.Name(), "session_id", sess.ID)
 				return
 			}
+			r.metrics.recordIteration(ctx)
 			slog.Debug("Starting conversation loop iteration", "agent", a.Name())
 
 			streamCtx, streamSpan := r.startSpan(ctx, "runtime.stream", trace.WithAttributes(
@@ -254,7 +292,7 @@ func (r *LocalRuntime) RunStream(ctx context.Context, sess *session.Session) <-c
 				attribute.String("session.id", sess.ID),
 			))
 
-			model := a.Model()
+			model := r.agentModel(ctx, sess, a)
 
 			// Per-tool model routing: use a cheaper model for this turn
 			// if the previous tool calls specified one, then reset.
@@ -294,8 +332,24 @@ func (r *LocalRuntime) RunStream(ctx context.Context, sess *session.Session) <-c
 				}
 			}
 
-			messages := sess.GetMessages(a)
-			slog.Debug("Retrieved messages for processing", "agent", a.Name(), "message_count", len(messages))
+			// Pack the messages into the context window, so that small windows
+			// don't overflow before compaction kicks in.
+			messagesOpts := []session.MessagesOpt{
+				session.WithTurnInstructions(turnInstructions(ctx, sess, a, events)),
+				session.WithToolResultPruning(r.toolResultPruning),
+			}
+			if contextLimit > 0 {
+				messagesOpts = append(messagesOpts,
+					session.WithTokenBudget(messagesTokenBudget(contextLimit, m.Limit.Output, agentTools)),
+					session.WithTokenEstimator(compaction.EstimatorFor(modelID)),
+				)
+			}
+			messages := sess.GetMessages(a, messagesOpts...)
+			slog.Debug("Retrieved messages for processing", "agent", a.Name(), "message_count", len(messages), "trimmed_messages", sess.TrimmedMessages())
+
+			if !keepsPastThoughts(a) {
+				messages = stripPastThoughts(messages)
+			}
 
 			// Strip image content from messages if the model doesn't support image input.
 			// This prevents API errors when conversation history contains images (e.g. from
@@ -330,8 +384,10 @@ func (r *LocalRuntime) RunStream(ctx context.Context, sess *session.Session) <-c
 						"context_limit", contextLimit,
 						"attempt", overflowCompactions,
 					)
-					events <- Warning(
+					events <- WarningWithCode(
+						ErrorCodeSessionCompacting,
 						"The conversation has exceeded the model's context window. Automatically compacting the conversation history...",
+						"",
 						a.Name(),
 					)
 					r.Summarize(ctx, sess, "", events)
@@ -349,7 +405,8 @@ func (r *LocalRuntime) RunStream(ctx context.Context, sess *session.Session) <-c
 				// Track error in telemetry
 				telemetry.RecordError(ctx, err.Error())
 				errMsg := modelerrors.FormatError(err)
-				events <- Error(errMsg)
+				code, hint := classifyModelError(err, model.ID())
+				events <- ErrorWithCode(code, errMsg, hint)
 				r.executeNotificationHooks(ctx, a, sess.ID, "error", errMsg)
 				streamSpan.End()
 				return
@@ -358,9 +415,34 @@ func (r *LocalRuntime) RunStream(ctx context.Context, sess *session.Session) <-c
 			// A successful model call resets the overflow compaction counter.
 			overflowCompactions = 0
 
+			if r.maxContinuations > 0 {
+				res = r.continueTruncatedResponse(streamCtx, a, model, messages, agentTools, sess, m, res, events)
+			}
+
+			// Price the turn with the model that actually answered it.
+			msgModelID, msgModel := modelID, m
 			if usedModel != nil && usedModel.ID() != model.ID() {
 				slog.Info("Used fallback model", "agent", a.Name(), "primary", model.ID(), "used", usedModel.ID())
 				events <- AgentInfo(a.Name(), usedModel.ID(), a.Description(), a.WelcomeMessage())
+
+				msgModelID = usedModel.ID()
+				fallbackDef, err := r.modelsStore.GetModel(ctx, msgModelID)
+				if err != nil {
+					slog.Debug("Failed to get fallback model definition", "model_id", msgModelID, "error", err)
+				}
+				msgModel = fallbackDef
+			}
+			answeredBy := model
+			if usedModel != nil {
+				answeredBy = usedModel
+			}
+			if routed, routedModel := r.routedModel(ctx, answeredBy); routed != "" && routed != msgModelID {
+				msgModelID, msgModel = routed, routedModel
+			}
+			// Malformed tool arguments make an escalating router answer the
+			// rest of the turn with a stronger model.
+			if hasTruncatedToolCall(res.Calls) {
+				escalateModel(a, sess, answeredBy, router.ReasonMalformedToolArgument)
 			}
 			streamSpan.SetAttributes(
 				attribute.Int("tool.calls", len(res.Calls)),
@@ -370,7 +452,7 @@ func (r *LocalRuntime) RunStream(ctx context.Context, sess *session.Session) <-c
 			streamSpan.End()
 			slog.Debug("Stream processed", "agent", a.Name(), "tool_calls", len(res.Calls), "content_length", len(res.Content), "stopped", res.Stopped)
 
-			msgUsage := r.recordAssistantMessage(sess, a, res, agentTools, modelID, m, events)
+			msgUsage := r.recordAssistantMessage(sess, a, res, agentTools, msgModelID, msgModel, events)
 
 			usage := SessionUsage(sess, contextLimit)
 			usage.LastMessage = msgUsage
@@ -395,7 +477,7 @@ func (r *LocalRuntime) RunStream(ctx context.Context, sess *session.Session) <-c
 					"Agent terminated: detected %d consecutive identical calls to %s. "+
 						"This indicates a degenerate loop where the model is not making progress.",
 					loopDetector.consecutive, toolName)
-				events <- Error(errMsg)
+				events <- ErrorWithCode(ErrorCodeAgentLoopDetected, errMsg, "")
 				r.executeNotificationHooks(ctx, a, sess.ID, "error", errMsg)
 				loopDetector.reset()
 				return
@@ -424,8 +506,30 @@ func (r *LocalRuntime) RunStream(ctx context.Context, sess *session.Session) <-c
 			}
 
 			if res.Stopped {
+				// The final answer goes through the output guards of the
+				// agent before the turn ends.
+				retry, err := r.checkOutputGuards(sess, a, res.Content, &guardRejections, events)
+				if err != nil {
+					events <- ErrorWithCode(ErrorCodeAgentOutputRejected, err.Error(), "")
+					r.executeNotificationHooks(ctx, a, sess.ID, "error", err.Error())
+					return
+				}
+				if retry {
+					escalateModel(a, sess, answeredBy, router.ReasonOutputGuard)
+					r.compactIfNeeded(ctx, sess, a, m, contextLimit, messageCountBeforeTools, events)
+					continue
+				}
+
+				// An agent requiring a final answer is reminded to give
+				// one.
+				if r.checkFinalAnswer(sess, a, &finalAnswerReminders, events) {
+					r.compactIfNeeded(ctx, sess, a, m, contextLimit, messageCountBeforeTools, events)
+					continue
+				}
+
 				slog.Debug("Conversation stopped", "agent", a.Name())
 				r.executeStopHooks(ctx, sess, a, res.Content, events)
+				r.executeTurnEndHooks(ctx, sess, a, events)
 
 				// --- FOLLOW-UP: end-of-turn injection ---
 				// Pop exactly one follow-up message. Unlike steered
@@ -448,7 +552,8 @@ func (r *LocalRuntime) RunStream(ctx context.Context, sess *session.Session) <-c
 		}
 	}()
 
-	return events
+	// The loop must not wait for a slow consumer, e.g. a TUI busy rendering.
+	return deliverEvents(ctx, sess.ID, events)
 }
 
 // Run executes the agent loop synchronously and returns the final session
@@ -514,11 +619,11 @@ func (r *LocalRuntime) recordAssistantMessage(
 		ThoughtSignature:  res.ThoughtSignature,
 		ToolCalls:         res.Calls,
 		ToolDefinitions:   toolDefs,
-		CreatedAt:         time.Now().Format(time.RFC3339),
 		Usage:             res.Usage,
 		Model:             messageModel,
 		Cost:              messageCost,
 		FinishReason:      res.FinishReason,
+		Annotations:       res.Annotations,
 	}
 
 	addAgentMessage(sess, a, &assistantMessage, events)
@@ -575,6 +680,25 @@ func (r *LocalRuntime) compactIfNeeded(
 	r.Summarize(ctx, sess, "", events)
 }
 
+// maxIterationsReminder tells the model why its run stopped, so that it can
+// pick up the task if the user asks it to continue.
+func maxIterationsReminder(maxIterations int) string {
+	return fmt.Sprintf("Execution stopped after reaching the configured max_iterations limit (%d).", maxIterations)
+}
+
+// messagesTokenBudget returns how many tokens of the context window are left
+// for the messages once the model's output and the tool definitions are
+// accounted for. The output reserve is capped at a quarter of the window, so
+// that models advertising a large output limit still get room for messages.
+func messagesTokenBudget(contextLimit, outputLimit int64, agentTools []tools.Tool) int64 {
+	reserve := contextLimit / 8
+	if outputLimit > 0 {
+		reserve = min(outputLimit, contextLimit/4)
+	}
+
+	return max(0, contextLimit-reserve-compaction.EstimateToolTokens(agentTools))
+}
+
 // getTools executes tool retrieval with automatic OAuth handling
 func (r *LocalRuntime) getTools(ctx context.Context, a *agent.Agent, sessionSpan trace.Span, events chan Event) ([]tools.Tool, error) {
 	shouldEmitMCPInit := len(a.ToolSets()) > 0
@@ -587,6 +711,12 @@ func (r *LocalRuntime) getTools(ctx context.Context, a *agent.Agent, sessionSpan
 		}
 	}()
 
+	// The toolsets may prompt for OAuth while they start: the prompts are
+	// for this run.
+	if run := sessionRunFromContext(ctx); run != nil {
+		r.useToolSets(run, a.ToolSets())
+	}
+
 	agentTools, err := a.Tools(ctx)
 	if err != nil {
 		slog.Error("Failed to get agent tools", "agent", a.Name(), "error", err)
@@ -600,18 +730,32 @@ func (r *LocalRuntime) getTools(ctx context.Context, a *agent.Agent, sessionSpan
 	return agentTools, nil
 }
 
-// configureToolsetHandlers sets up elicitation and OAuth handlers for all toolsets of an agent.
-func (r *LocalRuntime) configureToolsetHandlers(a *agent.Agent, events chan Event) {
+// configureToolsetHandlers sets up elicitation, sampling and OAuth handlers for all toolsets of an agent.
+// The toolsets are shared by the sessions: the handlers send each request to
+// the run of the session it's for, see toolsetRun.
+func (r *LocalRuntime) configureToolsetHandlers(a *agent.Agent) {
 	for _, toolset := range a.ToolSets() {
 		tools.ConfigureHandlers(toolset,
-			r.elicitationHandler,
-			func() { events <- Authorization(tools.ElicitationActionAccept, a.Name()) },
+			r.toolsetElicitationHandler(toolset),
+			func() {
+				if run, err := r.toolsetRun(context.Background(), toolset); err == nil {
+					run.emit(Authorization(tools.ElicitationActionAccept, a.Name()))
+				}
+			},
 			r.managedOAuth,
 		)
+		if s, ok := tools.As[tools.Samplable](toolset); ok {
+			s.SetSamplingHandler(r.toolsetSamplingHandler(toolset, a))
+		}
 
-		// Wire RAG event forwarding so the TUI shows indexing progress.
+		// Wire RAG event forwarding so the TUI shows indexing progress and the
+		// embedding usage counts towards the token usage.
 		if ragTool, ok := tools.As[*builtin.RAGTool](toolset); ok {
-			ragTool.SetEventCallback(ragEventForwarder(ragTool.Name(), r, chanSend(events)))
+			ragTool.SetEventCallback(ragEventForwarder(ragTool.Name(), a.Name(), func(event Event) {
+				if run, err := r.toolsetRun(context.Background(), toolset); err == nil {
+					run.tryEmit(event)
+				}
+			}))
 		}
 	}
 }
@@ -624,7 +768,28 @@ func (r *LocalRuntime) emitAgentWarnings(a *agent.Agent, send func(Event)) {
 	}
 
 	slog.Warn("Tool setup partially failed; continuing", "agent", a.Name(), "warnings", warnings)
-	send(Warning(formatToolWarning(a, warnings), a.Name()))
+	send(WarningWithCode(ErrorCodeToolsetStartFailed, formatToolWarning(a, warnings), "", a.Name()))
+}
+
+// repairSession fixes what an interrupted run left at the end of the
+// session, e.g. tool calls without results, so that it can be resumed.
+func (r *LocalRuntime) repairSession(sess *session.Session, a *agent.Agent, send func(Event)) {
+	repairs := sess.Repair()
+	if len(repairs) == 0 {
+		return
+	}
+
+	slog.Warn("Repaired interrupted session", "session_id", sess.ID, "repairs", repairs)
+	send(WarningWithCode(ErrorCodeSessionRepaired, formatRepairWarning(repairs), "", a.Name()))
+}
+
+func formatRepairWarning(repairs []string) string {
+	var builder strings.Builder
+	builder.WriteString("The previous run was interrupted. The session was repaired before resuming.\n\nDetails:\n\n")
+	for _, repair := range repairs {
+		fmt.Fprintf(&builder, "- %s\n", repair)
+	}
+	return strings.TrimSuffix(builder.String(), "\n")
 }
 
 func formatToolWarning(a *agent.Agent, warnings []string) string {
@@ -655,11 +820,9 @@ func filterExcludedTools(agentTools []tools.Tool, excluded []string) []tools.Too
 	return filtered
 }
 
-// chanSend wraps a channel as a func(Event) for use with emitAgentWarnings
-// and RAG event forwarding. The send is non-blocking: if the channel is full
-// or closed, the event is silently dropped. This prevents a panic when a
-// long-lived goroutine (e.g. RAG file watcher) tries to forward an event
-// after the per-message events channel has been closed.
+// chanSend wraps a channel as a func(Event) for use with emitAgentWarnings.
+// The send is non-blocking: if the channel is full or closed, the event is
+// silently dropped.
 func chanSend(ch chan Event) func(Event) {
 	return func(e Event) {
 		defer func() { recover() }() //nolint:errcheck // swallow send-on-closed-channel panic
@@ -0,0 +1,308 @@
+package runtime
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+
+	"github.com/docker/docker-agent/pkg/chat"
+)
+
+// meterName is the instrumentation scope of the runtime metrics.
+const meterName = "github.com/docker/docker-agent/pkg/runtime"
+
+// Metric attributes. Their values are bounded by the configuration: model,
+// agent and tool names, and an outcome. Session and tool call IDs are left
+// out on purpose, they belong to traces.
+const (
+	attrAgent     = "agent"
+	attrModel     = "model"
+	attrToolName  = "tool.name"
+	attrTokenType = "token.type"
+	attrOutcome   = "outcome"
+)
+
+// Stats is a snapshot of the work done by a runtime, or by a single run.
+type Stats struct {
+	// Runs is the number of RunStream calls that completed.
+	Runs int64 `json:"runs,omitempty"`
+	// Iterations is the number of turns of the agent loop.
+	Iterations    int64 `json:"iterations"`
+	ModelRequests int64 `json:"model_requests"`
+	ModelErrors   int64 `json:"model_errors"`
+	InputTokens   int64 `json:"input_tokens"`
+	OutputTokens  int64 `json:"output_tokens"`
+	ToolCalls     int64 `json:"tool_calls"`
+	ToolErrors    int64 `json:"tool_errors"`
+	Compactions   int64 `json:"compactions"`
+	// CompactionCost is the cost of the summaries of the compactions.
+	CompactionCost float64 `json:"compaction_cost,omitempty"`
+	// Models and Tools break the counts down by model ID and tool name.
+	Models map[string]ModelStats `json:"models,omitempty"`
+	Tools  map[string]ToolStats  `json:"tools,omitempty"`
+}
+
+// ModelStats are the requests sent to a model. Duration is their total
+// duration, from the request to the end of the streamed response.
+type ModelStats struct {
+	Requests     int64         `json:"requests"`
+	Errors       int64         `json:"errors"`
+	InputTokens  int64         `json:"input_tokens"`
+	OutputTokens int64         `json:"output_tokens"`
+	Duration     time.Duration `json:"duration"`
+}
+
+// ToolStats are the calls to a tool. Duration is their total duration.
+type ToolStats struct {
+	Calls    int64         `json:"calls"`
+	Errors   int64         `json:"errors"`
+	Duration time.Duration `json:"duration"`
+}
+
+// StatsProvider is implemented by runtimes that keep in-process statistics,
+// for library users who don't run an OpenTelemetry pipeline.
+type StatsProvider interface {
+	Stats() Stats
+}
+
+var _ StatsProvider = (*LocalRuntime)(nil)
+
+// WithMeterProvider records the runtime metrics with the meter provider:
+// model request durations, tokens per model, tool call durations and
+// outcomes, iterations per run and compactions. Without it, only Stats is
+// kept up to date.
+func WithMeterProvider(mp metric.MeterProvider) Opt {
+	return func(r *LocalRuntime) {
+		r.meterProvider = mp
+	}
+}
+
+// Stats returns a snapshot of the work done by the runtime since it was
+// created.
+func (r *LocalRuntime) Stats() Stats {
+	if r.metrics == nil {
+		return Stats{}
+	}
+	return r.metrics.total.snapshot()
+}
+
+// statsRecorder accumulates Stats.
+type statsRecorder struct {
+	mu    sync.Mutex
+	stats Stats
+}
+
+func (s *statsRecorder) update(fn func(*Stats)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&s.stats)
+}
+
+func (s *statsRecorder) snapshot() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.stats
+	stats.Models = maps.Clone(s.stats.Models)
+	stats.Tools = maps.Clone(s.stats.Tools)
+	return stats
+}
+
+type runStatsKey struct{}
+
+// withRunStats returns a context carrying the statistics of a run, so that
+// what happens during the run is also counted in them.
+func withRunStats(ctx context.Context, run *statsRecorder) context.Context {
+	return context.WithValue(ctx, runStatsKey{}, run)
+}
+
+func runStatsFromContext(ctx context.Context) *statsRecorder {
+	run, _ := ctx.Value(runStatsKey{}).(*statsRecorder)
+	return run
+}
+
+// runtimeMetrics records the OpenTelemetry metrics and the in-process
+// statistics of a runtime. A nil *runtimeMetrics records nothing.
+type runtimeMetrics struct {
+	total statsRecorder
+
+	modelDuration metric.Float64Histogram
+	tokens        metric.Int64Counter
+	toolDuration  metric.Float64Histogram
+	toolCalls     metric.Int64Counter
+	iterations    metric.Int64Histogram
+	compactions   metric.Int64Counter
+}
+
+func newRuntimeMetrics(mp metric.MeterProvider) *runtimeMetrics {
+	if mp == nil {
+		mp = noop.NewMeterProvider()
+	}
+	meter := mp.Meter(meterName)
+
+	return &runtimeMetrics{
+		modelDuration: float64Histogram(meter, "agent.model.request.duration", "Duration of the requests to the models, until the end of the streamed response.", "s"),
+		tokens:        int64Counter(meter, "agent.model.tokens", "Tokens sent to and received from the models.", "{token}"),
+		toolDuration:  float64Histogram(meter, "agent.tool.call.duration", "Duration of the tool calls.", "s"),
+		toolCalls:     int64Counter(meter, "agent.tool.calls", "Tool calls, by outcome.", "{call}"),
+		iterations:    int64Histogram(meter, "agent.run.iterations", "Iterations of the agent loop per run.", "{iteration}"),
+		compactions:   int64Counter(meter, "agent.session.compactions", "Session compactions performed.", "{compaction}"),
+	}
+}
+
+func float64Histogram(meter metric.Meter, name, description, unit string) metric.Float64Histogram {
+	h, err := meter.Float64Histogram(name, metric.WithDescription(description), metric.WithUnit(unit))
+	if err != nil {
+		slog.Warn("Failed to create metric", "name", name, "error", err)
+		return noop.Float64Histogram{}
+	}
+	return h
+}
+
+func int64Histogram(meter metric.Meter, name, description, unit string) metric.Int64Histogram {
+	h, err := meter.Int64Histogram(name, metric.WithDescription(description), metric.WithUnit(unit))
+	if err != nil {
+		slog.Warn("Failed to create metric", "name", name, "error", err)
+		return noop.Int64Histogram{}
+	}
+	return h
+}
+
+func int64Counter(meter metric.Meter, name, description, unit string) metric.Int64Counter {
+	c, err := meter.Int64Counter(name, metric.WithDescription(description), metric.WithUnit(unit))
+	if err != nil {
+		slog.Warn("Failed to create metric", "name", name, "error", err)
+		return noop.Int64Counter{}
+	}
+	return c
+}
+
+func outcome(failed bool) attribute.KeyValue {
+	if failed {
+		return attribute.String(attrOutcome, "error")
+	}
+	return attribute.String(attrOutcome, "ok")
+}
+
+// update applies fn to the runtime statistics and to the statistics of the
+// run in ctx, if any.
+func (m *runtimeMetrics) update(ctx context.Context, fn func(*Stats)) {
+	m.total.update(fn)
+	if run := runStatsFromContext(ctx); run != nil {
+		run.update(fn)
+	}
+}
+
+// recordModelRequest records a request to a model, with the usage reported
+// by the model, if any.
+func (m *runtimeMetrics) recordModelRequest(ctx context.Context, agentName, modelID string, duration time.Duration, usage *chat.Usage, err error) {
+	if m == nil {
+		return
+	}
+
+	var inputTokens, outputTokens int64
+	if usage != nil {
+		inputTokens = usage.InputTokens + usage.CachedInputTokens + usage.CacheWriteTokens
+		outputTokens = usage.OutputTokens
+	}
+
+	m.modelDuration.Record(ctx, duration.Seconds(), metric.WithAttributes(
+		attribute.String(attrAgent, agentName),
+		attribute.String(attrModel, modelID),
+		outcome(err != nil),
+	))
+	if usage != nil {
+		m.tokens.Add(ctx, inputTokens, metric.WithAttributes(attribute.String(attrModel, modelID), attribute.String(attrTokenType, "input")))
+		m.tokens.Add(ctx, outputTokens, metric.WithAttributes(attribute.String(attrModel, modelID), attribute.String(attrTokenType, "output")))
+	}
+
+	m.update(ctx, func(s *Stats) {
+		model := s.Models[modelID]
+		model.Requests++
+		model.Duration += duration
+		model.InputTokens += inputTokens
+		model.OutputTokens += outputTokens
+		s.ModelRequests++
+		s.InputTokens += inputTokens
+		s.OutputTokens += outputTokens
+		if err != nil {
+			model.Errors++
+			s.ModelErrors++
+		}
+		if s.Models == nil {
+			s.Models = make(map[string]ModelStats)
+		}
+		s.Models[modelID] = model
+	})
+}
+
+// recordToolCall records a tool call. failed is true when the tool returned
+// an error or an error result.
+func (m *runtimeMetrics) recordToolCall(ctx context.Context, agentName, toolName string, duration time.Duration, failed bool) {
+	if m == nil {
+		return
+	}
+
+	attrs := metric.WithAttributes(
+		attribute.String(attrAgent, agentName),
+		attribute.String(attrToolName, toolName),
+		outcome(failed),
+	)
+	m.toolDuration.Record(ctx, duration.Seconds(), attrs)
+	m.toolCalls.Add(ctx, 1, attrs)
+
+	m.update(ctx, func(s *Stats) {
+		tool := s.Tools[toolName]
+		tool.Calls++
+		tool.Duration += duration
+		s.ToolCalls++
+		if failed {
+			tool.Errors++
+			s.ToolErrors++
+		}
+		if s.Tools == nil {
+			s.Tools = make(map[string]ToolStats)
+		}
+		s.Tools[toolName] = tool
+	})
+}
+
+func (m *runtimeMetrics) recordIteration(ctx context.Context) {
+	if m == nil {
+		return
+	}
+	m.update(ctx, func(s *Stats) { s.Iterations++ })
+}
+
+func (m *runtimeMetrics) recordCompaction(ctx context.Context, agentName string, cost float64) {
+	if m == nil {
+		return
+	}
+	m.compactions.Add(ctx, 1, metric.WithAttributes(attribute.String(attrAgent, agentName)))
+	m.update(ctx, func(s *Stats) {
+		s.Compactions++
+		s.CompactionCost += cost
+	})
+}
+
+// recordRun records the end of the run in ctx and returns its statistics.
+func (m *runtimeMetrics) recordRun(ctx context.Context, agentName string) Stats {
+	if m == nil {
+		return Stats{}
+	}
+
+	var stats Stats
+	if run := runStatsFromContext(ctx); run != nil {
+		run.update(func(s *Stats) { s.Runs++ })
+		stats = run.snapshot()
+	}
+	m.total.update(func(s *Stats) { s.Runs++ })
+	m.iterations.Record(ctx, stats.Iterations, metric.WithAttributes(attribute.String(attrAgent, agentName)))
+	return stats
+}
@@ -0,0 +1,127 @@
+package runtime
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/model/provider/fake"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+func TestRunCompleted_Stats(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().
+			ToolCall("call_1", "lookup", `{}`).
+			Usage(10, 5),
+		fake.NewTurn().
+			Content("Nothing found.").
+			Usage(20, 3),
+	)
+
+	lookup := []tools.Tool{{
+		Name:       "lookup",
+		Parameters: map[string]any{},
+		Handler: func(context.Context, tools.ToolCall) (*tools.ToolCallResult, error) {
+			return tools.ResultError("not found"), nil
+		},
+	}}
+	root := agent.New("root", "You are a test agent",
+		agent.WithModel(prov),
+		agent.WithToolSets(newStubToolSet(nil, lookup, nil)),
+	)
+
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Look it up"), session.WithToolsApproved(true))
+
+	var completed *RunCompletedEvent
+	var stopped bool
+	for event := range rt.RunStream(t.Context(), sess) {
+		switch e := event.(type) {
+		case *RunCompletedEvent:
+			assert.False(t, stopped, "RunCompleted should come before StreamStopped")
+			completed = e
+		case *StreamStoppedEvent:
+			stopped = true
+		}
+	}
+	require.NotNil(t, completed)
+	assert.Equal(t, sess.ID, completed.SessionID)
+
+	stats := completed.Stats
+	assert.Equal(t, int64(1), stats.Runs)
+	assert.Equal(t, int64(2), stats.Iterations)
+	assert.Equal(t, int64(2), stats.ModelRequests)
+	assert.Equal(t, int64(30), stats.InputTokens)
+	assert.Equal(t, int64(8), stats.OutputTokens)
+	assert.Equal(t, int64(1), stats.ToolCalls)
+	assert.Equal(t, int64(1), stats.ToolErrors)
+
+	require.Contains(t, stats.Models, "test/scripted")
+	assert.Equal(t, int64(2), stats.Models["test/scripted"].Requests)
+	require.Contains(t, stats.Tools, "lookup")
+	assert.Equal(t, int64(1), stats.Tools["lookup"].Calls)
+	assert.Equal(t, int64(1), stats.Tools["lookup"].Errors)
+
+	// The runtime statistics add up all the runs.
+	assert.Equal(t, stats, rt.Stats())
+}
+
+func TestRuntimeMetrics_RunStats(t *testing.T) {
+	t.Parallel()
+
+	m := newRuntimeMetrics(nil)
+
+	// Outside of a run, only the runtime statistics are updated.
+	m.recordToolCall(t.Context(), "root", "shell", time.Second, false)
+
+	ctx := withRunStats(t.Context(), &statsRecorder{})
+	m.recordIteration(ctx)
+	m.recordModelRequest(ctx, "root", "openai/gpt-4o", 2*time.Second, &chat.Usage{InputTokens: 10, CachedInputTokens: 5, OutputTokens: 3}, nil)
+	m.recordModelRequest(ctx, "root", "openai/gpt-4o", time.Second, nil, assert.AnError)
+	m.recordCompaction(ctx, "root", 0.25)
+
+	run := m.recordRun(ctx, "root")
+	assert.Equal(t, Stats{
+		Runs:           1,
+		Iterations:     1,
+		ModelRequests:  2,
+		ModelErrors:    1,
+		InputTokens:    15,
+		OutputTokens:   3,
+		Compactions:    1,
+		CompactionCost: 0.25,
+		Models: map[string]ModelStats{
+			"openai/gpt-4o": {Requests: 2, Errors: 1, InputTokens: 15, OutputTokens: 3, Duration: 3 * time.Second},
+		},
+	}, run)
+
+	total := m.total.snapshot()
+	assert.Equal(t, int64(1), total.ToolCalls)
+	assert.Equal(t, ToolStats{Calls: 1, Duration: time.Second}, total.Tools["shell"])
+	assert.Equal(t, run.Models, total.Models)
+
+	// Snapshots don't share their maps with the recorder.
+	total.Models["openai/gpt-4o"] = ModelStats{}
+	assert.Equal(t, int64(2), m.total.snapshot().Models["openai/gpt-4o"].Requests)
+}
+
+func TestRuntimeMetrics_Nil(t *testing.T) {
+	t.Parallel()
+
+	var m *runtimeMetrics
+	m.recordIteration(t.Context())
+	m.recordToolCall(t.Context(), "root", "shell", time.Second, true)
+	assert.Equal(t, Stats{}, m.recordRun(t.Context(), "root"))
+
+	assert.Equal(t, Stats{}, (&LocalRuntime{}).Stats())
+}
@@ -0,0 +1,43 @@
+package runtime
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/model/provider"
+	"github.com/docker/docker-agent/pkg/model/provider/options"
+	"github.com/docker/docker-agent/pkg/session"
+)
+
+// WithModelOverrides applies model options, e.g. options.WithTemperature, to
+// the model of the named agent for every request made by this runtime. The
+// agent's model is cloned with the options at each turn and is never
+// modified, so runtimes sharing a team don't see each other's overrides.
+// Use session.WithModelOverrides for a single session.
+func WithModelOverrides(agentName string, opts ...options.Opt) Opt {
+	return func(r *LocalRuntime) {
+		if r.modelOverrides == nil {
+			r.modelOverrides = make(map[string][]options.Opt)
+		}
+		r.modelOverrides[agentName] = append(r.modelOverrides[agentName], opts...)
+	}
+}
+
+// agentModel returns the model the agent uses for a turn of the session:
+// the agent's model, cloned with the runtime's and then the session's
+// overrides for the agent, if any. The clone keeps the provider and model,
+// so its ID, and the usage and cost computed from it, are the same.
+func (r *LocalRuntime) agentModel(ctx context.Context, sess *session.Session, a *agent.Agent) provider.Provider {
+	model := a.Model()
+
+	var opts []options.Opt
+	opts = append(opts, r.modelOverrides[a.Name()]...)
+	opts = append(opts, sess.ModelOptions[a.Name()]...)
+	if len(opts) == 0 {
+		return model
+	}
+
+	slog.Debug("Applying model overrides", "agent", a.Name(), "model", model.ID(), "options", len(opts))
+	return provider.CloneWithOptions(ctx, model, opts...)
+}
@@ -0,0 +1,97 @@
+package runtime
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/config/latest"
+	"github.com/docker/docker-agent/pkg/environment"
+	"github.com/docker/docker-agent/pkg/model/provider"
+	"github.com/docker/docker-agent/pkg/model/provider/options"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
+)
+
+// newOverridesTeam returns a team with a single agent using a real OpenAI
+// provider, so that it can be cloned, with a temperature of 0.7.
+func newOverridesTeam(t *testing.T) (*team.Team, *agent.Agent) {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	t.Cleanup(server.Close)
+
+	temperature := 0.7
+	model, err := provider.New(t.Context(), &latest.ModelConfig{
+		Provider:    "openai",
+		Model:       "gpt-4o",
+		BaseURL:     server.URL,
+		Temperature: &temperature,
+	}, environment.NewMapEnvProvider(map[string]string{"OPENAI_API_KEY": "test-key"}))
+	require.NoError(t, err)
+
+	root := agent.New("root", "You are a test agent", agent.WithModel(model))
+	return team.New(team.WithAgents(root)), root
+}
+
+func temperatureOf(t *testing.T, model provider.Provider) float64 {
+	t.Helper()
+
+	temperature := model.BaseConfig().ModelConfig.Temperature
+	require.NotNil(t, temperature)
+	return *temperature
+}
+
+func TestAgentModel_RuntimeOverrides(t *testing.T) {
+	tm, root := newOverridesTeam(t)
+	original := root.Model()
+
+	rt, err := NewLocalRuntime(tm, WithModelStore(mockModelStore{}), WithModelOverrides("root", options.WithTemperature(0)))
+	require.NoError(t, err)
+
+	model := rt.agentModel(t.Context(), session.New(), root)
+	require.NotSame(t, original, model)
+	assert.InDelta(t, 0.0, temperatureOf(t, model), 0)
+	assert.Equal(t, original.ID(), model.ID(), "the usage and cost are computed for the same model")
+
+	// The agent's model is untouched.
+	assert.Same(t, original, root.Model())
+	assert.InDelta(t, 0.7, temperatureOf(t, root.Model()), 0)
+
+	// Another runtime sharing the team doesn't see the overrides.
+	other, err := NewLocalRuntime(tm, WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+	assert.Same(t, original, other.agentModel(t.Context(), session.New(), root))
+}
+
+func TestAgentModel_SessionOverrides(t *testing.T) {
+	tm, root := newOverridesTeam(t)
+
+	rt, err := NewLocalRuntime(tm, WithModelStore(mockModelStore{}), WithModelOverrides("root", options.WithTemperature(0.2), options.WithTopP(0.9)))
+	require.NoError(t, err)
+
+	// The session's overrides take precedence over the runtime's.
+	sess := session.New(session.WithModelOverrides("root", options.WithTemperature(0)))
+	model := rt.agentModel(t.Context(), sess, root)
+	assert.InDelta(t, 0.0, temperatureOf(t, model), 0)
+	require.NotNil(t, model.BaseConfig().ModelConfig.TopP)
+	assert.InDelta(t, 0.9, *model.BaseConfig().ModelConfig.TopP, 0)
+
+	// Overrides for other agents don't apply.
+	plain, err := NewLocalRuntime(tm, WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+	sess = session.New(session.WithModelOverrides("other", options.WithTemperature(0)))
+	assert.Same(t, root.Model(), plain.agentModel(t.Context(), sess, root))
+
+	// Sub-sessions inherit the overrides.
+	parent := session.New(session.WithModelOverrides("root", options.WithTemperature(0)))
+	child := newSubSession(parent, SubSessionConfig{Task: "task"}, root)
+	assert.InDelta(t, 0.0, temperatureOf(t, plain.agentModel(t.Context(), child, root)), 0)
+}
@@ -17,7 +17,7 @@ import (
 // toolsets, or nil if the agent has no model_picker configured.
 func (r *LocalRuntime) findModelPickerTool() *builtin.ModelPickerTool {
 	currentName := r.CurrentAgentName()
-	a, err := r.team.Agent(currentName)
+	a, err := r.Team().Agent(currentName)
 	if err != nil {
 		return nil
 	}
@@ -70,7 +70,7 @@ func (r *LocalRuntime) setModelAndEmitInfo(ctx context.Context, modelRef string,
 		return tools.ResultError(fmt.Sprintf("failed to set model: %v", err)), nil
 	}
 
-	if a, err := r.team.Agent(currentName); err == nil {
+	if a, err := r.Team().Agent(currentName); err == nil {
 		events <- AgentInfo(a.Name(), r.getEffectiveModelID(a), a.Description(), a.WelcomeMessage())
 	} else {
 		slog.Warn("Failed to retrieve agent after model change; UI may not reflect the update", "agent", currentName, "error", err)
@@ -0,0 +1,44 @@
+package runtime
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/model/provider"
+	"github.com/docker/docker-agent/pkg/model/provider/router"
+	"github.com/docker/docker-agent/pkg/modelsdev"
+	"github.com/docker/docker-agent/pkg/session"
+)
+
+// routedModel returns the ID and definition of the model a router picked
+// for the last request, so that the turn is recorded and priced with it. The
+// ID is empty when model isn't a router.
+func (r *LocalRuntime) routedModel(ctx context.Context, model provider.Provider) (string, *modelsdev.Model) {
+	rp, ok := model.(interface{ LastSelectedModelID() string })
+	if !ok {
+		return "", nil
+	}
+	selected := rp.LastSelectedModelID()
+	if selected == "" {
+		return "", nil
+	}
+
+	m, err := r.modelsStore.GetModel(ctx, selected)
+	if err != nil {
+		slog.Debug("Failed to get routed model definition", "model_id", selected, "error", err)
+	}
+	return selected, m
+}
+
+// escalateModel makes an escalating router answer the rest of the turn with
+// a stronger model, after its model failed for reason.
+func escalateModel(a *agent.Agent, sess *session.Session, model provider.Provider, reason string) {
+	rc, ok := model.(*router.Client)
+	if !ok {
+		return
+	}
+	if rc.Escalate(reason) {
+		slog.Info("Escalating the turn to a stronger model", "agent", a.Name(), "session_id", sess.ID, "router", rc.ID(), "reason", reason)
+	}
+}
@@ -0,0 +1,124 @@
+package runtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/config/latest"
+	"github.com/docker/docker-agent/pkg/environment"
+	"github.com/docker/docker-agent/pkg/model/provider/fake"
+	"github.com/docker/docker-agent/pkg/model/provider/options"
+	"github.com/docker/docker-agent/pkg/model/provider/router"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// newScriptedRouter creates an escalating router over scripted candidates.
+func newScriptedRouter(t *testing.T, candidates ...*fake.ScriptedProvider) *router.Client {
+	t.Helper()
+
+	byID := map[string]router.Provider{}
+	routerCfg := &latest.RouterConfig{}
+	for _, candidate := range candidates {
+		byID[candidate.ID()] = candidate
+		routerCfg.Candidates = append(routerCfg.Candidates, candidate.ID())
+	}
+	factory := func(_ context.Context, modelSpec string, _ map[string]latest.ModelConfig, _ environment.Provider, _ ...options.Opt) (router.Provider, error) {
+		return byID[modelSpec], nil
+	}
+
+	cfg := &latest.ModelConfig{Name: "auto", Provider: router.ProviderType, Router: routerCfg}
+	client, err := router.NewClient(t.Context(), cfg, nil, nil, factory)
+	require.NoError(t, err)
+	return client
+}
+
+func modelRoutes(events []Event) []*ModelRoutedEvent {
+	var routes []*ModelRoutedEvent
+	for _, event := range events {
+		if e, ok := event.(*ModelRoutedEvent); ok {
+			routes = append(routes, e)
+		}
+	}
+	return routes
+}
+
+func TestScripted_RouterEscalatesOnMalformedToolArguments(t *testing.T) {
+	cheap := fake.NewScriptedProvider(t, "test/cheap",
+		fake.NewTurn().ToolCall("call_1", "shell", `{"cmd":`),
+	)
+	strong := fake.NewScriptedProvider(t, "test/strong",
+		fake.NewTurn().
+			Content("I couldn't list the files.").
+			Expect(fake.LastMessage(chat.MessageRoleTool, "don't match its parameters")),
+	)
+
+	var executed bool
+	shell := []tools.Tool{{
+		Name:       "shell",
+		Parameters: map[string]any{},
+		Handler: func(_ context.Context, _ tools.ToolCall) (*tools.ToolCallResult, error) {
+			executed = true
+			return tools.ResultSuccess("file1.txt file2.txt"), nil
+		},
+	}}
+	root := agent.New("root", "You are a test agent",
+		agent.WithModel(newScriptedRouter(t, cheap, strong)),
+		agent.WithToolSets(newStubToolSet(nil, shell, nil)),
+	)
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("List the files"))
+	events := runScripted(t, rt, sess, ResumeApprove())
+
+	assert.False(t, executed)
+	routes := modelRoutes(events)
+	require.Len(t, routes, 2)
+	assert.Equal(t, "router/auto", routes[0].Router)
+	assert.Equal(t, "test/cheap", routes[0].Model)
+	assert.Empty(t, routes[0].Reason)
+	assert.Equal(t, "test/strong", routes[1].Model)
+	assert.Equal(t, router.ReasonMalformedToolArgument, routes[1].Reason)
+	assert.Equal(t, sess.ID, routes[1].GetSessionID())
+
+	// The turn is recorded with the model that answered it.
+	messages := sess.GetAllMessages()
+	assert.Equal(t, "test/strong", messages[len(messages)-1].Message.Model)
+}
+
+func TestScripted_RouterEscalatesOnOutputGuard(t *testing.T) {
+	cheap := fake.NewScriptedProvider(t, "test/cheap",
+		fake.NewTurn().Content(`Sure! {"answer": 42}`),
+	)
+	strong := fake.NewScriptedProvider(t, "test/strong",
+		fake.NewTurn().
+			Content(`{"answer": 42}`).
+			Expect(fake.LastMessage(chat.MessageRoleUser, "Your answer was rejected")),
+	)
+
+	root := agent.New("root", "Answer in JSON",
+		agent.WithModel(newScriptedRouter(t, cheap, strong)),
+		agent.WithOutputGuards(agent.RequireJSON()),
+	)
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("What's the answer?"))
+	events := runScripted(t, rt, sess, ResumeApprove())
+
+	assert.False(t, hasEventType(t, events, &ErrorEvent{}))
+	assert.Equal(t, `{"answer": 42}`, sess.GetLastAssistantMessageContent())
+
+	routes := modelRoutes(events)
+	require.Len(t, routes, 2)
+	assert.Equal(t, "test/cheap", routes[0].Model)
+	assert.Equal(t, "test/strong", routes[1].Model)
+	assert.Equal(t, router.ReasonOutputGuard, routes[1].Reason)
+}
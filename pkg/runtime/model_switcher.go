@@ -71,7 +71,7 @@ func (r *LocalRuntime) SetAgentModel(ctx context.Context, agentName, modelRef st
 		return errors.New("model switching not configured for this runtime")
 	}
 
-	a, err := r.team.Agent(agentName)
+	a, err := r.Team().Agent(agentName)
 	if err != nil {
 		return fmt.Errorf("agent not found: %w", err)
 	}
@@ -0,0 +1,64 @@
+package runtime
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// outputGuardPrompt asks the model for a new answer after its answer was
+// rejected by the output guards of its agent.
+func outputGuardPrompt(violation error) string {
+	return fmt.Sprintf("Your answer was rejected: %s.\n\nAnswer again, fixing these problems.", violation)
+}
+
+// checkOutputGuards checks the final answer of agent a with its output
+// guards. When the answer is rejected, the model is asked for a new one and
+// checkOutputGuards returns true for the turn to be re-run, at most
+// a.OutputGuardRetries() times in a row, counted by rejections. After that,
+// it returns an error.
+func (r *LocalRuntime) checkOutputGuards(sess *session.Session, a *agent.Agent, content string, rejections *int, events chan Event) (bool, error) {
+	if !a.HasOutputGuards() {
+		return false, nil
+	}
+
+	violation := a.CheckOutput(content)
+	if violation == nil {
+		*rejections = 0
+		return false, nil
+	}
+
+	*rejections++
+	attempt := *rejections
+	slog.Warn("Output guard rejected the answer", "agent", a.Name(), "session_id", sess.ID, "attempt", attempt, "error", violation)
+	events <- GuardRejected(a.Name(), "", sess.ID, violation.Error(), attempt, a.OutputGuardRetries())
+
+	if attempt > a.OutputGuardRetries() {
+		*rejections = 0
+		return false, fmt.Errorf("the answer of %s was rejected by its output guards: %w", a.Name(), violation)
+	}
+
+	addSystemReminder(sess, a, outputGuardPrompt(violation), events, session.WithReminderHidden())
+	return true, nil
+}
+
+// guardSubAgentResult checks the result of a sub-agent with the output
+// guards of the caller, so that a sub-agent can't return what the caller
+// isn't allowed to answer. A rejected result is replaced by an error.
+func (r *LocalRuntime) guardSubAgentResult(sess *session.Session, caller *agent.Agent, subAgent string, result *tools.ToolCallResult, evts chan Event) *tools.ToolCallResult {
+	if result == nil || result.IsError || !caller.HasOutputGuards() {
+		return result
+	}
+
+	violation := caller.CheckOutput(result.Output)
+	if violation == nil {
+		return result
+	}
+
+	slog.Warn("Output guard rejected the result of a sub-agent", "agent", caller.Name(), "sub_agent", subAgent, "session_id", sess.ID, "error", violation)
+	evts <- GuardRejected(caller.Name(), subAgent, sess.ID, violation.Error(), 0, 0)
+	return tools.ResultError(fmt.Sprintf("The result of %s was rejected: %s.", subAgent, violation))
+}
@@ -0,0 +1,125 @@
+package runtime
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/model/provider/fake"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools/builtin"
+)
+
+func guardRejections(events []Event) []*GuardRejectedEvent {
+	var rejections []*GuardRejectedEvent
+	for _, event := range events {
+		if e, ok := event.(*GuardRejectedEvent); ok {
+			rejections = append(rejections, e)
+		}
+	}
+	return rejections
+}
+
+func TestScripted_OutputGuardRetry(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().Content(`Sure! {"answer": 42}`),
+		fake.NewTurn().
+			Content(`{"answer": 42}`).
+			Expect(fake.LastMessage(chat.MessageRoleUser, "Your answer was rejected: the answer must be valid JSON")),
+	)
+
+	root := agent.New("root", "Answer in JSON", agent.WithModel(prov), agent.WithOutputGuards(agent.RequireJSON()))
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)),
+		WithSessionCompaction(false),
+		WithModelStore(mockModelStore{}),
+	)
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("What's the answer?"))
+	events := runScripted(t, rt, sess, ResumeApprove())
+
+	assert.False(t, hasEventType(t, events, &ErrorEvent{}))
+	assert.Equal(t, `{"answer": 42}`, sess.GetLastAssistantMessageContent())
+
+	rejections := guardRejections(events)
+	require.Len(t, rejections, 1)
+	assert.Equal(t, "root", rejections[0].AgentName)
+	assert.Empty(t, rejections[0].SubAgent)
+	assert.Equal(t, 1, rejections[0].Attempt)
+	assert.Equal(t, agent.DefaultOutputGuardRetries, rejections[0].MaxRetries)
+	assert.Equal(t, sess.ID, rejections[0].GetSessionID())
+}
+
+func TestScripted_OutputGuardRetriesExhausted(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().Content("A very long answer"),
+		fake.NewTurn().Content("Another long answer"),
+	)
+
+	root := agent.New("root", "Be brief",
+		agent.WithModel(prov),
+		agent.WithOutputGuards(agent.MaxLength(5)),
+		agent.WithOutputGuardRetries(1),
+	)
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)),
+		WithSessionCompaction(false),
+		WithModelStore(mockModelStore{}),
+	)
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("What's the answer?"))
+	events := runScripted(t, rt, sess, ResumeApprove())
+
+	assert.Len(t, guardRejections(events), 2)
+
+	var errEvent *ErrorEvent
+	for _, event := range events {
+		if e, ok := event.(*ErrorEvent); ok {
+			errEvent = e
+		}
+	}
+	require.NotNil(t, errEvent)
+	assert.Equal(t, ErrorCodeAgentOutputRejected, errEvent.Code)
+	assert.Contains(t, errEvent.Error, "at most 5 characters")
+}
+
+func TestScripted_OutputGuardSubAgentResult(t *testing.T) {
+	rootProv := fake.NewScriptedProvider(t, "test/root",
+		fake.NewTurn().ToolCall("call_1", builtin.ToolNameTransferTask, `{"agent":"librarian","task":"find the key","expected_output":""}`),
+		fake.NewTurn().
+			Content("I can't share that.").
+			Expect(fake.LastMessage(chat.MessageRoleTool, "The result of librarian was rejected: the answer must not contain API keys")),
+	)
+	childProv := fake.NewScriptedProvider(t, "test/librarian",
+		fake.NewTurn().Content("The key is sk-abc123"),
+	)
+
+	librarian := agent.New("librarian", "Library agent", agent.WithModel(childProv))
+	root := agent.New("root", "Root agent",
+		agent.WithModel(rootProv),
+		agent.WithToolSets(builtin.NewTransferTaskTool()),
+		agent.WithOutputGuards(agent.DenyPattern(regexp.MustCompile(`sk-[a-z0-9]+`), "API keys")),
+	)
+	agent.WithSubAgents(librarian)(root)
+
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root, librarian)),
+		WithSessionCompaction(false),
+		WithModelStore(mockModelStore{}),
+	)
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("What's the key?"), session.WithToolsApproved(true))
+	events := runScripted(t, rt, sess, ResumeApprove())
+
+	assert.Equal(t, "I can't share that.", sess.GetLastAssistantMessageContent())
+
+	rejections := guardRejections(events)
+	require.Len(t, rejections, 1)
+	assert.Equal(t, "root", rejections[0].AgentName)
+	assert.Equal(t, "librarian", rejections[0].SubAgent)
+}
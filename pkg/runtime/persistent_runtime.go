@@ -128,10 +128,16 @@ func (r *PersistentRuntime) handleEvent(ctx context.Context, sess *session.Sessi
 			if err := r.sessionStore.AddSubSession(ctx, e.ParentSessionID, subSess); err != nil {
 				slog.Warn("Failed to persist sub-session", "parent_id", e.ParentSessionID, "error", err)
 			}
+			// The file changes of the sub-session were merged into the session.
+			if e.ParentSessionID == sess.ID && len(subSess.FileChanges()) > 0 {
+				if err := r.sessionStore.UpdateSession(ctx, sess); err != nil {
+					slog.Warn("Failed to persist file changes", "session_id", sess.ID, "error", err)
+				}
+			}
 		}
 
 	case *SessionSummaryEvent:
-		if err := r.sessionStore.AddSummary(ctx, e.SessionID, e.Summary, e.FirstKeptEntry); err != nil {
+		if err := r.sessionStore.AddSummary(ctx, e.SessionID, session.Item{Summary: e.Summary, FirstKeptEntry: e.FirstKeptEntry, SummaryInfo: e.Info}); err != nil {
 			slog.Warn("Failed to persist summary", "session_id", e.SessionID, "error", err)
 		}
 
@@ -149,6 +155,16 @@ func (r *PersistentRuntime) handleEvent(ctx context.Context, sess *session.Sessi
 		if err := r.sessionStore.UpdateSessionTitle(ctx, sess.ID, e.Title); err != nil {
 			slog.Warn("Failed to persist session title", "session_id", sess.ID, "error", err)
 		}
+
+	case *AgentHandoffEvent:
+		if err := r.sessionStore.UpdateSession(ctx, sess); err != nil {
+			slog.Warn("Failed to persist handoff history", "session_id", sess.ID, "error", err)
+		}
+
+	case *FileChangedEvent:
+		if err := r.sessionStore.UpdateSession(ctx, sess); err != nil {
+			slog.Warn("Failed to persist file changes", "session_id", sess.ID, "error", err)
+		}
 	}
 }
 
@@ -9,9 +9,8 @@ import (
 )
 
 // ragEventForwarder returns a callback that converts RAG manager events to runtime events.
-func ragEventForwarder(ragName string, r *LocalRuntime, sendEvent func(Event)) builtin.RAGEventCallback {
+func ragEventForwarder(ragName, agentName string, sendEvent func(Event)) builtin.RAGEventCallback {
 	return func(ragEvent ragtypes.Event) {
-		agentName := r.CurrentAgentName()
 		slog.Debug("Forwarding RAG event", "type", ragEvent.Type, "rag", ragName, "agent", agentName)
 
 		switch ragEvent.Type {
@@ -22,7 +21,11 @@ func ragEventForwarder(ragName string, r *LocalRuntime, sendEvent func(Event)) b
 				sendEvent(RAGIndexingProgress(ragName, ragEvent.StrategyName, ragEvent.Progress.Current, ragEvent.Progress.Total, agentName))
 			}
 		case ragtypes.EventTypeIndexingComplete:
-			sendEvent(RAGIndexingCompleted(ragName, ragEvent.StrategyName))
+			event := RAGIndexingCompleted(ragName, ragEvent.StrategyName).(*RAGIndexingCompletedEvent)
+			if ragEvent.Cache != nil {
+				event.CacheHits, event.CacheMisses = ragEvent.Cache.Hits, ragEvent.Cache.Misses
+			}
+			sendEvent(event)
 		case ragtypes.EventTypeUsage:
 			sendEvent(NewTokenUsageEvent("", agentName, &Usage{
 				InputTokens:   ragEvent.TotalTokens,
@@ -31,7 +34,7 @@ func ragEventForwarder(ragName string, r *LocalRuntime, sendEvent func(Event)) b
 			}))
 		case ragtypes.EventTypeError:
 			if ragEvent.Error != nil {
-				sendEvent(Error(fmt.Sprintf("RAG %s error: %v", ragName, ragEvent.Error)))
+				sendEvent(ErrorWithCode(ErrorCodeRAGFailed, fmt.Sprintf("RAG %s error: %v", ragName, ragEvent.Error), ""))
 			}
 		default:
 			slog.Debug("Unhandled RAG event type", "type", ragEvent.Type, "rag", ragName)
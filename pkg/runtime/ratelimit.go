@@ -0,0 +1,166 @@
+package runtime
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/backoff"
+	"github.com/docker/docker-agent/pkg/session"
+)
+
+// Reasons reported by a ThrottledEvent.
+const (
+	ThrottleReasonRateLimit  = "rate_limit"
+	ThrottleReasonMaxStreams = "max_concurrent_streams"
+)
+
+// rateLimits configures how the runtime throttles the requests sent to each
+// model.
+type rateLimits struct {
+	rps        float64 // Requests per second; 0 disables rate limiting
+	burst      int     // Requests that can be sent at once before rps applies
+	maxStreams int     // Streams open at the same time; 0 means unlimited
+}
+
+func (l rateLimits) enabled() bool {
+	return l.rps > 0 || l.maxStreams > 0
+}
+
+// modelLimiter throttles the requests sent to a single model with a token
+// bucket, and limits the number of streams open at the same time.
+type modelLimiter struct {
+	mu     sync.Mutex
+	rps    float64
+	burst  float64
+	tokens float64
+	last   time.Time
+
+	// streams holds one element per open stream; nil when unlimited.
+	streams chan struct{}
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) bool
+}
+
+func newModelLimiter(limits rateLimits) *modelLimiter {
+	l := &modelLimiter{
+		rps:   limits.rps,
+		burst: float64(max(limits.burst, 1)),
+		now:   time.Now,
+		sleep: backoff.SleepWithContext,
+	}
+	l.tokens = l.burst
+	if limits.maxStreams > 0 {
+		l.streams = make(chan struct{}, limits.maxStreams)
+	}
+	return l
+}
+
+// reserve takes a token from the bucket and returns how long the caller must
+// wait before sending its request.
+func (l *modelLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rps <= 0 {
+		return 0
+	}
+
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rps)
+	}
+	l.last = now
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rps * float64(time.Second))
+}
+
+// unreserve gives back a token taken by a caller that stopped waiting.
+func (l *modelLimiter) unreserve() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rps > 0 {
+		l.tokens = min(l.burst, l.tokens+1)
+	}
+}
+
+// acquire blocks until a request can be sent to the model, calling onWait
+// before it starts waiting. The wait is zero when it is unknown, i.e. when
+// waiting for another stream to end. The returned function must be called
+// once the stream is done.
+func (l *modelLimiter) acquire(ctx context.Context, onWait func(reason string, wait time.Duration)) (func(), error) {
+	release := func() {}
+	if l.streams != nil {
+		select {
+		case l.streams <- struct{}{}:
+		default:
+			onWait(ThrottleReasonMaxStreams, 0)
+			select {
+			case l.streams <- struct{}{}:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		release = sync.OnceFunc(func() { <-l.streams })
+	}
+
+	if wait := l.reserve(); wait > 0 {
+		onWait(ThrottleReasonRateLimit, wait)
+		if !l.sleep(ctx, wait) {
+			l.unreserve()
+			release()
+			return nil, ctx.Err()
+		}
+	}
+
+	return release, nil
+}
+
+type modelLimiterKey struct {
+	modelID string
+	limits  rateLimits
+}
+
+// modelLimiters is shared by all the runtimes of the process, so that
+// concurrent sessions calling the same model with the same limits share a
+// single bucket.
+var modelLimiters = struct {
+	sync.Mutex
+	m map[modelLimiterKey]*modelLimiter
+}{m: make(map[modelLimiterKey]*modelLimiter)}
+
+func limiterFor(modelID string, limits rateLimits) *modelLimiter {
+	modelLimiters.Lock()
+	defer modelLimiters.Unlock()
+
+	key := modelLimiterKey{modelID: modelID, limits: limits}
+	l, ok := modelLimiters.m[key]
+	if !ok {
+		l = newModelLimiter(limits)
+		modelLimiters.m[key] = l
+	}
+	return l
+}
+
+// waitForModel applies the limits set with WithRateLimit and
+// WithMaxConcurrentStreams before a request is sent to the given model,
+// emitting a ThrottledEvent whenever the request has to wait. The returned
+// function must be called once the stream is done.
+func (r *LocalRuntime) waitForModel(ctx context.Context, a *agent.Agent, sess *session.Session, modelID string, events chan Event) (func(), error) {
+	if !r.rateLimits.enabled() {
+		return func() {}, nil
+	}
+
+	return limiterFor(modelID, r.rateLimits).acquire(ctx, func(reason string, wait time.Duration) {
+		slog.Debug("Throttling model request", "agent", a.Name(), "model", modelID, "reason", reason, "wait", wait)
+		events <- Throttled(a.Name(), sess.ID, modelID, reason, wait)
+	})
+}
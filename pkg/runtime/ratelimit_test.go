@@ -0,0 +1,120 @@
+package runtime
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a clock whose sleeps advance the time instantly.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	c.now = c.now.Add(d)
+	return true
+}
+
+func newFakeClockLimiter(limits rateLimits) (*modelLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := newModelLimiter(limits)
+	l.now = clock.Now
+	l.sleep = clock.Sleep
+	return l, clock
+}
+
+func TestModelLimiter_RequestSpacing(t *testing.T) {
+	l, clock := newFakeClockLimiter(rateLimits{rps: 2, burst: 2})
+	start := clock.now
+
+	var sent []time.Duration
+	var waits []time.Duration
+	for range 5 {
+		release, err := l.acquire(t.Context(), func(reason string, wait time.Duration) {
+			assert.Equal(t, ThrottleReasonRateLimit, reason)
+			waits = append(waits, wait)
+		})
+		require.NoError(t, err)
+		release()
+		sent = append(sent, clock.now.Sub(start))
+	}
+
+	// The burst goes out at once, then requests are spaced by 1/rps.
+	assert.Equal(t, []time.Duration{0, 0, 500 * time.Millisecond, time.Second, 1500 * time.Millisecond}, sent)
+	assert.Equal(t, []time.Duration{500 * time.Millisecond, 500 * time.Millisecond, 500 * time.Millisecond}, waits)
+
+	// An idle period refills the bucket up to the burst.
+	clock.now = clock.now.Add(time.Minute)
+	assert.Zero(t, l.reserve())
+	assert.Zero(t, l.reserve())
+	assert.Equal(t, 500*time.Millisecond, l.reserve())
+}
+
+func TestModelLimiter_CancelWhileWaiting(t *testing.T) {
+	l, clock := newFakeClockLimiter(rateLimits{rps: 1, burst: 1, maxStreams: 1})
+
+	release, err := l.acquire(t.Context(), func(string, time.Duration) {})
+	require.NoError(t, err)
+	release()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	_, err = l.acquire(ctx, func(string, time.Duration) {})
+	require.ErrorIs(t, err, context.Canceled)
+
+	// The aborted request gave back its token and its stream slot.
+	clock.now = clock.now.Add(time.Second)
+	release, err = l.acquire(t.Context(), func(reason string, wait time.Duration) {
+		t.Errorf("unexpected wait: %s %s", reason, wait)
+	})
+	require.NoError(t, err)
+	release()
+}
+
+func TestModelLimiter_MaxConcurrentStreams(t *testing.T) {
+	l, _ := newFakeClockLimiter(rateLimits{maxStreams: 1})
+
+	release, err := l.acquire(t.Context(), func(string, time.Duration) {})
+	require.NoError(t, err)
+
+	waiting := make(chan string, 1)
+	acquired := make(chan error, 1)
+	go func() {
+		release, err := l.acquire(t.Context(), func(reason string, _ time.Duration) {
+			waiting <- reason
+		})
+		if err == nil {
+			release()
+		}
+		acquired <- err
+	}()
+
+	assert.Equal(t, ThrottleReasonMaxStreams, <-waiting)
+	select {
+	case <-acquired:
+		t.Fatal("second stream started while the first was open")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	release()
+	release() // Releasing twice is a no-op.
+	require.NoError(t, <-acquired)
+}
+
+func TestLimiterFor_SharedAcrossRuntimes(t *testing.T) {
+	limits := rateLimits{rps: 10, burst: 1}
+
+	l := limiterFor("test/shared-model", limits)
+	assert.Same(t, l, limiterFor("test/shared-model", limits))
+	assert.NotSame(t, l, limiterFor("test/other-model", limits))
+	assert.NotSame(t, l, limiterFor("test/shared-model", rateLimits{rps: 5, burst: 1}))
+}
@@ -0,0 +1,79 @@
+package runtime
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// defaultRedactor masks the secrets in the tool outputs when neither the
+// runtime nor the team sets how.
+var defaultRedactor = tools.DefaultRedactor()
+
+// secretRedactor returns how the secrets in the tool outputs are masked:
+// the runtime's redactor, or else the team's, or else the default one.
+func (r *LocalRuntime) secretRedactor() *tools.Redactor {
+	if r.redactor != nil {
+		return r.redactor
+	}
+	if redactor := r.Team().Redactor(); redactor != nil {
+		return redactor
+	}
+	return defaultRedactor
+}
+
+// redactToolResult masks the secrets in the result of a call to tool,
+// unless its toolset opted out, before the result is cached, sent in the
+// events or stored in the session. The first time secrets are masked in a
+// session, the user is warned.
+func (r *LocalRuntime) redactToolResult(sess *session.Session, a *agent.Agent, tool tools.Tool, toolCall tools.ToolCall, res *tools.ToolCallResult, events chan Event) *tools.ToolCallResult {
+	if res == nil || tool.NoRedaction {
+		return res
+	}
+
+	res, redactions := r.secretRedactor().RedactResult(res)
+	if redactions == nil {
+		return res
+	}
+
+	slog.Debug("Masked secrets in tool output", "tool", toolCall.Function.Name, "agent", a.Name(), "session_id", sess.ID, "count", redactions.Total())
+	if r.markRedactionWarned(sess.ID) {
+		events <- WarningWithCode(ErrorCodeToolOutputRedacted, redactionWarning(toolCall.Function.Name, redactions),
+			"Set no_redaction on the toolset if its tools legitimately handle secrets.", a.Name())
+	}
+	return res
+}
+
+// markRedactionWarned records that the user was warned about masked secrets
+// in session sessionID, and returns whether it's the first time.
+func (r *LocalRuntime) markRedactionWarned(sessionID string) bool {
+	r.redactionWarnedMu.Lock()
+	defer r.redactionWarnedMu.Unlock()
+
+	if r.redactionWarned[sessionID] {
+		return false
+	}
+	r.redactionWarned[sessionID] = true
+	return true
+}
+
+// redactionWarning tells the user the secrets masked in the output of a tool.
+func redactionWarning(toolName string, redactions tools.Redactions) string {
+	types := make([]string, 0, len(redactions))
+	for typ, n := range redactions {
+		types = append(types, fmt.Sprintf("%s: %d", typ, n))
+	}
+	slices.Sort(types)
+
+	noun := "secrets"
+	if redactions.Total() == 1 {
+		noun = "secret"
+	}
+	return fmt.Sprintf("Masked %d %s in the output of %s (%s) before sending it to the model. Further masked secrets in this session won't be reported.",
+		redactions.Total(), noun, toolName, strings.Join(types, ", "))
+}
@@ -19,7 +19,7 @@ type RemoteClient interface {
 	CreateSession(ctx context.Context, sessTemplate *session.Session) (*session.Session, error)
 
 	// ResumeSession resumes a paused session with optional rejection reason or tool name
-	ResumeSession(ctx context.Context, id, confirmation, reason, toolName string) error
+	ResumeSession(ctx context.Context, id, confirmation, reason, toolName, arguments string) error
 
 	// ResumeElicitation sends an elicitation response
 	ResumeElicitation(ctx context.Context, sessionID string, action tools.ElicitationAction, content map[string]any) error
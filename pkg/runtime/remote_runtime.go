@@ -108,7 +108,7 @@ func (r *RemoteRuntime) EmitStartupInfo(ctx context.Context, _ *session.Session,
 
 	// Emit a loading indicator while we fetch the real tool count from the server.
 	if len(cfg.Toolsets) > 0 {
-		events <- ToolsetInfo(0, true, r.currentAgent)
+		events <- ToolsetInfo(0, 0, true, r.currentAgent)
 	}
 
 	toolCount, err := r.client.GetAgentToolCount(ctx, r.agentFilename, r.currentAgent)
@@ -117,7 +117,7 @@ func (r *RemoteRuntime) EmitStartupInfo(ctx context.Context, _ *session.Session,
 		return
 	}
 
-	events <- ToolsetInfo(toolCount, false, r.currentAgent)
+	events <- ToolsetInfo(toolCount, 0, false, r.currentAgent)
 }
 
 func (r *RemoteRuntime) agentDetailsFromConfig(ctx context.Context) []AgentDetails {
@@ -183,7 +183,7 @@ func (r *RemoteRuntime) RunStream(ctx context.Context, sess *session.Session) <-
 		}
 
 		if err != nil {
-			events <- Error(fmt.Sprintf("failed to start remote agent: %v", err))
+			events <- ErrorWithCode(ErrorCodeRuntimeRemoteFailed, fmt.Sprintf("failed to start remote agent: %v", err), "")
 			return
 		}
 
@@ -232,24 +232,26 @@ func (r *RemoteRuntime) FollowUp(msg QueuedMessage) error {
 	})
 }
 
-// Resume allows resuming execution after user confirmation
-func (r *RemoteRuntime) Resume(ctx context.Context, req ResumeRequest) {
+// Resume allows resuming execution after user confirmation. The remote
+// runtime runs a single session: the confirmation goes to its remote session.
+func (r *RemoteRuntime) Resume(ctx context.Context, _ string, req ResumeRequest) error {
 	slog.Debug("Resuming remote runtime", "agent", r.currentAgent, "type", req.Type, "reason", req.Reason, "tool_name", req.ToolName, "session_id", r.sessionID)
 
 	if r.sessionID == "" {
-		slog.Error("Cannot resume: no session ID available")
-		return
+		return fmt.Errorf("resuming: %w", ErrSessionNotRunning)
 	}
 
-	if err := r.client.ResumeSession(ctx, r.sessionID, string(req.Type), req.Reason, req.ToolName); err != nil {
+	if err := r.client.ResumeSession(ctx, r.sessionID, string(req.Type), req.Reason, req.ToolName, req.Arguments); err != nil {
 		slog.Error("Failed to resume remote session", "error", err, "session_id", r.sessionID)
+		return err
 	}
+	return nil
 }
 
 // Summarize generates a summary for the session
 func (r *RemoteRuntime) Summarize(_ context.Context, sess *session.Session, _ string, events chan Event) {
 	slog.Debug("Summarize not yet implemented for remote runtime", "session_id", r.sessionID)
-	events <- SessionSummary(sess.ID, "Summary generation not yet implemented for remote runtime", r.currentAgent, 0)
+	events <- SessionSummary(sess.ID, "Summary generation not yet implemented for remote runtime", r.currentAgent, 0, nil)
 }
 
 func (r *RemoteRuntime) convertSessionMessages(sess *session.Session) []api.Message {
@@ -269,7 +271,8 @@ func (r *RemoteRuntime) convertSessionMessages(sess *session.Session) []api.Mess
 }
 
 // ResumeElicitation sends an elicitation response back to a waiting elicitation request
-func (r *RemoteRuntime) ResumeElicitation(ctx context.Context, action tools.ElicitationAction, content map[string]any) error {
+// of the remote session
+func (r *RemoteRuntime) ResumeElicitation(ctx context.Context, _ string, action tools.ElicitationAction, content map[string]any) error {
 	slog.Debug("Resuming remote runtime with elicitation response", "agent", r.currentAgent, "action", action, "session_id", r.sessionID)
 
 	err := r.handleOAuthElicitation(ctx, r.pendingOAuthElicitation)
@@ -476,6 +479,11 @@ func (r *RemoteRuntime) CurrentMCPPrompts(context.Context) map[string]mcp.Prompt
 	return make(map[string]mcp.PromptInfo)
 }
 
+// CurrentMCPResources is not supported on remote runtimes.
+func (r *RemoteRuntime) CurrentMCPResources(context.Context) map[string]mcp.ResourceInfo {
+	return make(map[string]mcp.ResourceInfo)
+}
+
 // ExecuteMCPPrompt is not supported on remote runtimes.
 func (r *RemoteRuntime) ExecuteMCPPrompt(context.Context, string, map[string]string) (string, error) {
 	return "", errors.New("MCP prompts are not supported by remote runtimes")
@@ -487,7 +495,7 @@ func (r *RemoteRuntime) TitleGenerator() *sessiontitle.Generator {
 }
 
 // Close is a no-op for remote runtimes.
-func (r *RemoteRuntime) Close() error {
+func (r *RemoteRuntime) Close(context.Context) error {
 	return nil
 }
 
@@ -6,6 +6,7 @@ func IsValidResumeType(t ResumeType) bool {
 	case ResumeTypeApprove,
 		ResumeTypeApproveSession,
 		ResumeTypeApproveTool,
+		ResumeTypeApproveEdited,
 		ResumeTypeReject:
 		return true
 	default:
@@ -19,6 +20,7 @@ func ValidResumeTypes() []ResumeType {
 		ResumeTypeApprove,
 		ResumeTypeApproveSession,
 		ResumeTypeApproveTool,
+		ResumeTypeApproveEdited,
 		ResumeTypeReject,
 	}
 }
@@ -0,0 +1,221 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// DefaultRunOnceToolOutputLimit is the default maximum size, in bytes, of
+// the tool outputs recorded in a RunOnceResult.
+const DefaultRunOnceToolOutputLimit = 4 * 1024
+
+// ErrBudgetExceeded is returned by RunOnce when the run costs more than its
+// budget.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// RunOnceRequest describes a single run of an agent.
+type RunOnceRequest struct {
+	// Team is the team of agents to run. It can be shared by concurrent
+	// runs; its toolsets are left running and are for the caller to stop.
+	Team *team.Team
+	// Agent is the name of the agent to run. Defaults to the default agent
+	// of the team.
+	Agent string
+	// Prompt is the user message sent to the agent.
+	Prompt string
+	// Attachments are the other parts of the user message, e.g. images.
+	Attachments []chat.MessagePart
+
+	// MaxIterations limits the number of model calls. Defaults to the
+	// max_iterations of the agent. The run stops, without error, when it's
+	// reached.
+	MaxIterations int
+	// Budget is the maximum cost of the run, in dollars. The run is stopped
+	// with ErrBudgetExceeded when it costs more. Zero means no budget.
+	Budget float64
+	// Timeout is the maximum duration of the run. Zero means no timeout.
+	Timeout time.Duration
+
+	// AutoApprove runs the tool calls without asking for confirmation.
+	// Otherwise, the tool calls that need a confirmation are rejected.
+	AutoApprove bool
+	// ToolOutputLimit is the maximum size, in bytes, of the tool outputs
+	// recorded in the result. Defaults to DefaultRunOnceToolOutputLimit.
+	ToolOutputLimit int
+
+	// Options are passed to the runtime, e.g. WithModelStore.
+	Options []Opt
+}
+
+// RunOnceResult is what a run of RunOnce produced.
+type RunOnceResult struct {
+	// SessionID is the ID of the session of the run.
+	SessionID string
+	// Output is the last message of the agents.
+	Output string
+	// FinalAnswer is the deliverable the agent handed over with the
+	// final_answer tool, apart from the prose of Output. Nil when it didn't
+	// call final_answer.
+	FinalAnswer *FinalAnswer
+	// ToolCalls are the tool calls made during the run, in order.
+	ToolCalls []RunOnceToolCall
+	// Usage is the token usage and the cost of the run.
+	Usage session.UsageTotals
+	// MaxIterationsReached is true when the run was stopped by its
+	// MaxIterations.
+	MaxIterationsReached bool
+	// Events are all the events sent during the run.
+	Events []Event
+}
+
+// RunOnceToolCall is a tool call made during a run.
+type RunOnceToolCall struct {
+	ID        string
+	Agent     string
+	Name      string
+	Arguments string
+	// Output is the output of the tool, truncated to the ToolOutputLimit
+	// of the request. Truncated tells whether it was.
+	Output    string
+	Truncated bool
+	IsError   bool
+}
+
+// RunError is returned by RunOnce when the run fails with an error event.
+type RunError struct {
+	Message string
+	Code    ErrorCode
+	Hint    string
+}
+
+func (e *RunError) Error() string {
+	return e.Message
+}
+
+// RunOnce runs an agent on a prompt, waits for the run to end and returns
+// what it produced. It's the entry point for embedding agents in services:
+// the runtime is created and closed for each call, and concurrent calls can
+// share the same team.
+//
+// The result is returned along with the error when the run fails, with
+// what the run produced until then.
+func RunOnce(ctx context.Context, req RunOnceRequest) (RunOnceResult, error) {
+	if req.Team == nil {
+		return RunOnceResult{}, errors.New("a team is required")
+	}
+
+	a, err := req.Team.DefaultAgent()
+	if req.Agent != "" {
+		a, err = req.Team.Agent(req.Agent)
+	}
+	if err != nil {
+		return RunOnceResult{}, err
+	}
+
+	maxIterations := req.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = a.MaxIterations()
+	}
+	outputLimit := tools.OutputLimit{MaxBytes: req.ToolOutputLimit, Policy: tools.TruncateHeadTail}
+	if outputLimit.MaxBytes <= 0 {
+		outputLimit.MaxBytes = DefaultRunOnceToolOutputLimit
+	}
+
+	sess := session.New(
+		session.WithMaxIterations(maxIterations),
+		session.WithMaxConsecutiveToolCalls(a.MaxConsecutiveToolCalls()),
+		session.WithMaxOldToolCallTokens(a.MaxOldToolCallTokens()),
+		session.WithToolsApproved(req.AutoApprove),
+		session.WithNonInteractive(true),
+	)
+	sess.AddMessage(session.UserMessage(req.Prompt, req.Attachments...))
+
+	opts := append([]Opt{
+		WithCurrentAgent(a.Name()),
+		WithManagedOAuth(false),
+		WithTitleGeneration(false),
+	}, req.Options...)
+	rt, err := NewLocalRuntime(req.Team, opts...)
+	if err != nil {
+		return RunOnceResult{}, err
+	}
+
+	runCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	if req.Timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		runCtx, cancelTimeout = context.WithTimeoutCause(runCtx, req.Timeout, fmt.Errorf("run timed out after %s: %w", req.Timeout, context.DeadlineExceeded))
+		defer cancelTimeout()
+	}
+
+	result := RunOnceResult{SessionID: sess.ID}
+	calls := make(map[string]int)
+	var runErr error
+
+	// The events are drained until the channel is closed, even after the
+	// run was cancelled, so that no goroutine is left behind.
+	for event := range rt.RunStream(runCtx, sess) {
+		result.Events = append(result.Events, event)
+
+		switch e := event.(type) {
+		case *ToolCallEvent:
+			calls[e.ToolCall.ID] = len(result.ToolCalls)
+			result.ToolCalls = append(result.ToolCalls, RunOnceToolCall{
+				ID:        e.ToolCall.ID,
+				Agent:     e.AgentName,
+				Name:      e.ToolCall.Function.Name,
+				Arguments: e.ToolCall.Function.Arguments,
+			})
+		case *ToolCallResponseEvent:
+			i, ok := calls[e.ToolCallID]
+			if !ok {
+				continue
+			}
+			output, truncation := outputLimit.Truncate(e.Response)
+			result.ToolCalls[i].Output = output
+			result.ToolCalls[i].Truncated = truncation != nil
+			result.ToolCalls[i].IsError = e.Result != nil && e.Result.IsError
+		case *ToolCallConfirmationEvent:
+			_ = rt.Resume(runCtx, sess.ID, ResumeReject("tool calls can't be confirmed in this run"))
+		case *ElicitationRequestEvent:
+			_ = rt.ResumeElicitation(runCtx, sess.ID, tools.ElicitationActionDecline, nil)
+		case *MaxIterationsReachedEvent:
+			result.MaxIterationsReached = true
+		case *TokenUsageEvent:
+			if req.Budget > 0 {
+				if cost := sess.TotalCost(); cost > req.Budget {
+					cancel(fmt.Errorf("%w: the run cost $%.4f, over its $%.4f budget", ErrBudgetExceeded, cost, req.Budget))
+				}
+			}
+		case *ErrorEvent:
+			if runErr == nil {
+				runErr = &RunError{Message: e.Error, Code: e.Code, Hint: e.Hint}
+			}
+		}
+	}
+
+	result.Output = sess.GetLastAssistantMessageContent()
+	if answer, ok := FinalAnswerOf(sess); ok {
+		result.FinalAnswer = &answer
+	}
+	result.Usage = sess.TotalUsage()
+
+	// The cancellation, when it happened, is what failed the run.
+	if err := context.Cause(runCtx); err != nil {
+		runErr = err
+	}
+
+	// The team isn't ours: its toolsets are left running.
+	if err := rt.shutdown(context.WithoutCancel(ctx), false); err != nil {
+		runErr = errors.Join(runErr, err)
+	}
+
+	return result, runErr
+}
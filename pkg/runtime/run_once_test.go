@@ -0,0 +1,207 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/model/provider/fake"
+	"github.com/docker/docker-agent/pkg/modelerrors"
+	"github.com/docker/docker-agent/pkg/modelsdev"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+func TestRunOnce(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().
+			ToolCall("call_1", "shell", `{"cmd":"ls"}`).
+			Usage(10, 5),
+		fake.NewTurn().
+			Content("There are two files.").
+			Usage(20, 5),
+	)
+
+	var executed bool
+	tm := team.New(team.WithAgents(newShellAgent(prov, &executed)))
+
+	result, err := RunOnce(t.Context(), RunOnceRequest{
+		Team:            tm,
+		Prompt:          "List the files",
+		AutoApprove:     true,
+		ToolOutputLimit: 12,
+		Options:         []Opt{WithSessionCompaction(false), WithModelStore(mockModelStore{})},
+	})
+	require.NoError(t, err)
+
+	assert.True(t, executed)
+	assert.Equal(t, "There are two files.", result.Output)
+	assert.NotEmpty(t, result.SessionID)
+	assert.False(t, result.MaxIterationsReached)
+	assert.Equal(t, int64(30), result.Usage.InputTokens)
+	assert.Equal(t, int64(10), result.Usage.OutputTokens)
+	assert.NotEmpty(t, result.Events)
+
+	require.Len(t, result.ToolCalls, 1)
+	call := result.ToolCalls[0]
+	assert.Equal(t, "call_1", call.ID)
+	assert.Equal(t, "root", call.Agent)
+	assert.Equal(t, "shell", call.Name)
+	assert.JSONEq(t, `{"cmd":"ls"}`, call.Arguments)
+	assert.True(t, call.Truncated)
+	assert.True(t, strings.HasPrefix(call.Output, "file1."), call.Output)
+	assert.Contains(t, call.Output, "bytes truncated")
+	assert.False(t, call.IsError)
+}
+
+func TestRunOnce_RejectsUnapprovedToolCalls(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().
+			ToolCall("call_1", "shell", `{"cmd":"rm -rf /"}`),
+		fake.NewTurn().
+			Content("I can't run it.").
+			Expect(fake.LastMessage(chat.MessageRoleTool, "The user rejected the tool call")),
+	)
+
+	var executed bool
+	tm := team.New(team.WithAgents(newShellAgent(prov, &executed)))
+
+	result, err := RunOnce(t.Context(), RunOnceRequest{
+		Team:    tm,
+		Prompt:  "Clean up",
+		Options: []Opt{WithSessionCompaction(false), WithModelStore(mockModelStore{})},
+	})
+	require.NoError(t, err)
+
+	assert.False(t, executed)
+	assert.Equal(t, "I can't run it.", result.Output)
+	assert.True(t, hasEventType(t, result.Events, &ToolCallConfirmationEvent{}))
+}
+
+func TestRunOnce_Budget(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/expensive",
+		fake.NewTurn().
+			Content("That was expensive.").
+			Usage(1000, 1000),
+	)
+
+	tm := team.New(team.WithAgents(agent.New("root", "test", agent.WithModel(prov))))
+
+	result, err := RunOnce(t.Context(), RunOnceRequest{
+		Team:   tm,
+		Prompt: "Hi",
+		// The turn costs $2.
+		Budget: 1,
+		Options: []Opt{WithSessionCompaction(false), WithModelStore(pricedModelStore{costs: map[string]*modelsdev.Cost{
+			"test/expensive": {Input: 1000, Output: 1000},
+		}})},
+	})
+	require.ErrorIs(t, err, ErrBudgetExceeded)
+	assert.InDelta(t, 2.0, result.Usage.Cost, 0.0001)
+}
+
+func TestRunOnce_Timeout(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().ToolCall("call_1", "wait", `{}`),
+	)
+
+	wait := []tools.Tool{{
+		Name:       "wait",
+		Parameters: map[string]any{},
+		Handler: func(ctx context.Context, _ tools.ToolCall) (*tools.ToolCallResult, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}}
+	root := agent.New("root", "test", agent.WithModel(prov), agent.WithToolSets(newStubToolSet(nil, wait, nil)))
+
+	result, err := RunOnce(t.Context(), RunOnceRequest{
+		Team:        team.New(team.WithAgents(root)),
+		Prompt:      "Wait",
+		AutoApprove: true,
+		Timeout:     50 * time.Millisecond,
+		Options:     []Opt{WithSessionCompaction(false), WithModelStore(mockModelStore{})},
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Len(t, result.ToolCalls, 1)
+	assert.Equal(t, "wait", result.ToolCalls[0].Name)
+}
+
+func TestRunOnce_Errors(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().Error(&modelerrors.StatusError{StatusCode: 400, Err: errors.New("bad request")}),
+	)
+	tm := team.New(team.WithAgents(agent.New("root", "test", agent.WithModel(prov))))
+
+	_, err := RunOnce(t.Context(), RunOnceRequest{
+		Team:    tm,
+		Prompt:  "Hi",
+		Options: []Opt{WithSessionCompaction(false), WithModelStore(mockModelStore{})},
+	})
+	var runErr *RunError
+	require.ErrorAs(t, err, &runErr)
+	assert.Equal(t, ErrorCodeProviderRequestFailed, runErr.Code)
+
+	_, err = RunOnce(t.Context(), RunOnceRequest{Team: tm, Agent: "missing"})
+	require.ErrorContains(t, err, "agent not found: missing")
+
+	_, err = RunOnce(t.Context(), RunOnceRequest{})
+	require.Error(t, err)
+}
+
+// stoppedToolSet counts how many times it was stopped.
+type stoppedToolSet struct {
+	stubToolSet
+
+	stops atomic.Int32
+}
+
+func (s *stoppedToolSet) Stop(context.Context) error {
+	s.stops.Add(1)
+	return nil
+}
+
+func TestRunOnce_ConcurrentRunsShareTeam(t *testing.T) {
+	const runs = 8
+
+	turns := make([]*fake.Turn, runs)
+	for i := range turns {
+		turns[i] = fake.NewTurn().Content("Done.")
+	}
+	prov := fake.NewScriptedProvider(t, "test/scripted", turns...)
+
+	toolSet := &stoppedToolSet{}
+	tm := team.New(team.WithAgents(agent.New("root", "test", agent.WithModel(prov), agent.WithToolSets(toolSet))))
+
+	var wg sync.WaitGroup
+	outputs := make([]string, runs)
+	errs := make([]error, runs)
+	for i := range runs {
+		wg.Go(func() {
+			var result RunOnceResult
+			result, errs[i] = RunOnce(t.Context(), RunOnceRequest{
+				Team:    tm,
+				Prompt:  "Hi",
+				Options: []Opt{WithSessionCompaction(false), WithModelStore(mockModelStore{})},
+			})
+			outputs[i] = result.Output
+		})
+	}
+	wg.Wait()
+
+	for i := range runs {
+		require.NoError(t, errs[i])
+		assert.Equal(t, "Done.", outputs[i])
+	}
+	// The toolsets belong to the team's owner.
+	assert.Zero(t, toolSet.stops.Load())
+}
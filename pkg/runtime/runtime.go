@@ -2,21 +2,26 @@ package runtime
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"maps"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 
 	"github.com/docker/docker-agent/pkg/agent"
 	"github.com/docker/docker-agent/pkg/chat"
 	"github.com/docker/docker-agent/pkg/config/types"
 	"github.com/docker/docker-agent/pkg/hooks"
+	"github.com/docker/docker-agent/pkg/model/provider"
+	"github.com/docker/docker-agent/pkg/model/provider/options"
 	"github.com/docker/docker-agent/pkg/modelsdev"
 	"github.com/docker/docker-agent/pkg/session"
 	"github.com/docker/docker-agent/pkg/sessiontitle"
@@ -49,6 +54,7 @@ const (
 	ResumeTypeApprove        ResumeType = "approve"
 	ResumeTypeApproveSession ResumeType = "approve-session"
 	ResumeTypeApproveTool    ResumeType = "approve-tool"
+	ResumeTypeApproveEdited  ResumeType = "approve-edited"
 	ResumeTypeReject         ResumeType = "reject"
 )
 
@@ -58,6 +64,9 @@ type ResumeRequest struct {
 	Type     ResumeType
 	Reason   string // Optional; primarily used with ResumeTypeReject
 	ToolName string // Optional; used with ResumeTypeApproveTool to specify which tool to always allow
+	// Arguments replace the arguments of the tool call; used with
+	// ResumeTypeApproveEdited.
+	Arguments string
 }
 
 // ResumeApprove creates a ResumeRequest to approve a single tool call.
@@ -75,6 +84,12 @@ func ResumeApproveTool(toolName string) ResumeRequest {
 	return ResumeRequest{Type: ResumeTypeApproveTool, ToolName: toolName}
 }
 
+// ResumeApproveEdited creates a ResumeRequest to approve a tool call with the
+// arguments edited by the user.
+func ResumeApproveEdited(arguments string) ResumeRequest {
+	return ResumeRequest{Type: ResumeTypeApproveEdited, Arguments: arguments}
+}
+
 // ResumeReject creates a ResumeRequest to reject a tool call with an optional reason.
 func ResumeReject(reason string) ResumeRequest {
 	return ResumeRequest{Type: ResumeTypeReject, Reason: reason}
@@ -92,7 +107,8 @@ type Runtime interface {
 	CurrentAgentInfo(ctx context.Context) CurrentAgentInfo
 	// CurrentAgentName returns the name of the currently active agent
 	CurrentAgentName() string
-	// SetCurrentAgent sets the currently active agent for subsequent user messages
+	// SetCurrentAgent sets the currently active agent for subsequent user messages,
+	// of all the sessions
 	SetCurrentAgent(agentName string) error
 	// CurrentAgentTools returns the tools for the active agent
 	CurrentAgentTools(ctx context.Context) ([]tools.Tool, error)
@@ -106,11 +122,18 @@ type Runtime interface {
 	RunStream(ctx context.Context, sess *session.Session) <-chan Event
 	// Run starts the agent's interaction loop and returns the final messages
 	Run(ctx context.Context, sess *session.Session) ([]session.Message, error)
-	// Resume allows resuming execution after user confirmation.
+	// ContinueStream appends a user message to a session that already ran
+	// and re-enters the agent's interaction loop, reusing the started toolsets
+	ContinueStream(ctx context.Context, sess *session.Session, userMessage string) <-chan Event
+	// Continue appends a user message to a session that already ran, runs the
+	// agent's interaction loop and returns the final messages
+	Continue(ctx context.Context, sess *session.Session, userMessage string) ([]session.Message, error)
+	// Resume allows resuming the execution of a session after user confirmation.
 	// The ResumeRequest carries the decision type and an optional reason (for rejections).
-	Resume(ctx context.Context, req ResumeRequest)
-	// ResumeElicitation sends an elicitation response back to a waiting elicitation request
-	ResumeElicitation(_ context.Context, action tools.ElicitationAction, content map[string]any) error
+	// It returns an error when the session isn't running.
+	Resume(ctx context.Context, sessionID string, req ResumeRequest) error
+	// ResumeElicitation sends an elicitation response back to a waiting elicitation request of a session
+	ResumeElicitation(_ context.Context, sessionID string, action tools.ElicitationAction, content map[string]any) error
 	// SessionStore returns the session store for browsing/loading past sessions.
 	// Returns nil if no persistent session store is configured.
 	SessionStore() session.Store
@@ -129,6 +152,10 @@ type Runtime interface {
 	// Returns an empty map if no MCP prompts are available.
 	CurrentMCPPrompts(ctx context.Context) map[string]mcptools.PromptInfo
 
+	// CurrentMCPResources returns MCP resources available from the current agent's toolsets,
+	// keyed by URI. Returns an empty map if no MCP resources are available.
+	CurrentMCPResources(ctx context.Context) map[string]mcptools.ResourceInfo
+
 	// ExecuteMCPPrompt executes a named MCP prompt with the given arguments.
 	ExecuteMCPPrompt(ctx context.Context, promptName string, arguments map[string]string) (string, error)
 
@@ -147,8 +174,10 @@ type Runtime interface {
 	// gets a full undivided agent turn. Returns an error if the queue is full.
 	FollowUp(msg QueuedMessage) error
 
-	// Close releases resources held by the runtime (e.g., session store connections).
-	Close() error
+	// Close releases resources held by the runtime (e.g., toolsets and
+	// session store connections), cancelling the streams in flight. Waiting
+	// for them to end is bounded by ctx.
+	Close(ctx context.Context) error
 }
 
 // PermissionsInfo contains the allow, ask, and deny patterns for tool permissions.
@@ -180,22 +209,41 @@ type ToolsChangeSubscriber interface {
 
 // LocalRuntime manages the execution of agents
 type LocalRuntime struct {
-	toolMap                     map[string]ToolHandlerFunc
-	team                        *team.Team
-	currentAgent                string
-	resumeChan                  chan ResumeRequest
-	tracer                      trace.Tracer
-	modelsStore                 ModelStore
-	sessionCompaction           bool
-	managedOAuth                bool
-	startupInfoEmitted          bool                   // Track if startup info has been emitted to avoid unnecessary duplication
-	elicitationRequestCh        chan ElicitationResult // Channel for receiving elicitation responses
-	elicitationEventsChannel    chan Event             // Current events channel for sending elicitation requests
-	elicitationEventsChannelMux sync.RWMutex           // Protects elicitationEventsChannel
-	sessionStore                session.Store
-	workingDir                  string   // Working directory for hooks execution
-	env                         []string // Environment variables for hooks execution
-	modelSwitcherCfg            *ModelSwitcherConfig
+	toolMap           map[string]ToolHandlerFunc
+	team              *team.Team
+	teamMu            sync.RWMutex
+	currentAgent      string
+	tracer            trace.Tracer
+	meterProvider     metric.MeterProvider
+	metrics           *runtimeMetrics
+	modelsStore       ModelStore
+	sessionCompaction bool
+	titleGeneration   bool
+	titleModel        provider.Provider
+	subSessionTitles  bool
+	managedOAuth      bool
+	sessionStore      session.Store
+	workingDir        string   // Working directory for hooks execution
+	env               []string // Environment variables for hooks execution
+	modelSwitcherCfg  *ModelSwitcherConfig
+
+	// runs holds the runs in progress, by session ID, see sessionRun, and
+	// sessionAgents the agent the last run of each session ended with,
+	// which its next run starts with. toolsetUsers holds the runs in
+	// progress that use each toolset, the latest first, see toolsetRun.
+	runs          map[string]*sessionRun
+	sessionAgents map[string]string
+	toolsetUsers  map[tools.ToolSet][]*sessionRun
+	runsMu        sync.Mutex
+
+	// startupInfoEmitted holds the sessions the startup info was emitted
+	// for, to avoid unnecessary duplication.
+	startupInfoEmitted   map[string]bool
+	startupInfoEmittedMu sync.Mutex
+
+	// modelOverrides holds model options applied to the agents' models, by
+	// agent name, see WithModelOverrides.
+	modelOverrides map[string][]options.Opt
 
 	// retryOnRateLimit enables retry-with-backoff for HTTP 429 (rate limit) errors
 	// when no fallback models are configured. When false (default), 429 errors are
@@ -203,6 +251,60 @@ type LocalRuntime struct {
 	// Library consumers can enable this via WithRetryOnRateLimit().
 	retryOnRateLimit bool
 
+	// rateLimits throttles the requests sent to each model, see WithRateLimit
+	// and WithMaxConcurrentStreams.
+	rateLimits rateLimits
+
+	// toolOutputLimit limits the size of the tool outputs sent to the model,
+	// see WithToolOutputLimit. Toolsets can override it.
+	toolOutputLimit tools.OutputLimit
+
+	// toolTimeout limits how long a tool call may run, see WithToolTimeout.
+	// Toolsets and tools can override it.
+	toolTimeout time.Duration
+
+	// maxHandoffRepeats and handoffLoopWindow block handoff loops, see
+	// WithHandoffLoopDetection.
+	maxHandoffRepeats int
+	handoffLoopWindow int
+
+	// maxContinuations is the number of continuation requests sent for a
+	// response cut by the output token limit, see WithAutoContinue.
+	maxContinuations int
+
+	// maxSnapshotBytes is the size limit of the files saved before the
+	// tools change them, see WithFileSnapshots.
+	maxSnapshotBytes int64
+
+	// toolResultPruning sets which tool exchanges are left out of the
+	// messages sent to the model, see WithToolResultPruning.
+	toolResultPruning session.PruningPolicy
+
+	// toolResultCache holds the results of the read-only tools, see
+	// WithToolResultCache. Nil when disabled.
+	toolResultCache *toolResultCache
+
+	// toolSetSlots queues the calls to the toolsets that can't serve any
+	// number of them at once.
+	toolSetSlots toolSetSlots
+
+	// lifecycleHooks are run for every agent, after its own hooks, see
+	// WithLifecycleHooks.
+	lifecycleHooks *hooks.Config
+
+	// compaction overrides the compaction settings of the team, see
+	// WithCompaction.
+	compaction *team.CompactionConfig
+
+	// redactor overrides how the team masks the secrets in the tool
+	// outputs, see WithRedactor.
+	redactor *tools.Redactor
+
+	// redactionWarned holds the sessions the user was warned about masked
+	// secrets in, to warn only once per session.
+	redactionWarned   map[string]bool
+	redactionWarnedMu sync.Mutex
+
 	// fallbackCooldowns tracks per-agent cooldown state for sticky fallback behavior
 	fallbackCooldowns    map[string]*fallbackCooldownState
 	fallbackCooldownsMux sync.RWMutex
@@ -221,6 +323,13 @@ type LocalRuntime struct {
 	onToolsChanged func(Event)
 
 	bgAgents *agenttool.Handler
+
+	// configReload holds the state of agent configuration reloads,
+	// enabled with WithConfigReload.
+	configReload configReloadState
+
+	// closeState tracks the running streams, see Close.
+	closeState closeState
 }
 
 type Opt func(*LocalRuntime)
@@ -266,6 +375,34 @@ func WithSessionCompaction(sessionCompaction bool) Opt {
 	}
 }
 
+// WithTitleGeneration enables or disables the generation of session titles.
+// When disabled, TitleGenerator returns nil so that no title request is ever
+// made, e.g. for batch runs. Enabled by default.
+func WithTitleGeneration(enabled bool) Opt {
+	return func(r *LocalRuntime) {
+		r.titleGeneration = enabled
+	}
+}
+
+// WithTitleModel sets the model used to generate session titles, e.g. a
+// cheaper one. The current agent's models are used as fallbacks. Defaults
+// to the current agent's model.
+func WithTitleModel(model provider.Provider) Opt {
+	return func(r *LocalRuntime) {
+		r.titleModel = model
+	}
+}
+
+// WithSubSessionTitles titles the sub-sessions of task transfers after their
+// task, prefixed with the agent it is transferred to, e.g. "researcher:
+// compare quota APIs", rather than "Transferred task". No model is involved.
+// Disabled by default.
+func WithSubSessionTitles(enabled bool) Opt {
+	return func(r *LocalRuntime) {
+		r.subSessionTitles = enabled
+	}
+}
+
 func WithModelStore(store ModelStore) Opt {
 	return func(r *LocalRuntime) {
 		r.modelsStore = store
@@ -309,6 +446,160 @@ func WithRetryOnRateLimit() Opt {
 	}
 }
 
+// WithRateLimit throttles the requests sent to each model to rps requests per
+// second, allowing bursts of up to burst requests. The limit applies per model
+// and is shared by all the runtimes of the process that use the same limits,
+// which keeps many concurrent sessions (e.g. eval batches) under the
+// provider's rate limits. A ThrottledEvent is emitted when a request waits.
+func WithRateLimit(rps float64, burst int) Opt {
+	return func(r *LocalRuntime) {
+		r.rateLimits.rps = rps
+		r.rateLimits.burst = burst
+	}
+}
+
+// WithMaxConcurrentStreams limits the number of streams open at the same time
+// on each model. Like WithRateLimit, the limit is shared by all the runtimes
+// of the process that use the same limits.
+func WithMaxConcurrentStreams(n int) Opt {
+	return func(r *LocalRuntime) {
+		r.rateLimits.maxStreams = n
+	}
+}
+
+// DefaultToolOutputLimit is the default maximum size, in bytes, of the tool
+// outputs sent to the model.
+const DefaultToolOutputLimit = 48 * 1024
+
+// WithToolOutputLimit limits the size of the tool outputs sent to the model
+// to maxBytes, truncating longer outputs according to policy. A maxBytes of
+// zero or less disables the limit. The full outputs are still reported by
+// the ToolCallResponseEvents. Defaults to DefaultToolOutputLimit bytes,
+// keeping both ends of the outputs; toolsets can override it.
+func WithToolOutputLimit(maxBytes int, policy tools.TruncatePolicy) Opt {
+	return func(r *LocalRuntime) {
+		r.toolOutputLimit = tools.OutputLimit{MaxBytes: maxBytes, Policy: policy}
+	}
+}
+
+// DefaultToolTimeout is the default maximum duration of a tool call.
+const DefaultToolTimeout = 120 * time.Second
+
+// WithToolTimeout limits how long a tool call may run. Calls that run longer
+// get an error result telling the model about the timeout, and the agent
+// carries on. Zero or less disables the limit. Defaults to
+// DefaultToolTimeout; toolsets and tools can override it.
+func WithToolTimeout(timeout time.Duration) Opt {
+	return func(r *LocalRuntime) {
+		r.toolTimeout = timeout
+	}
+}
+
+const (
+	// DefaultMaxHandoffRepeats is the default number of times an agent can
+	// hand off to the same agent within the handoff loop window.
+	DefaultMaxHandoffRepeats = 3
+	// DefaultHandoffLoopWindow is the default number of most recent handoffs
+	// looked at to detect handoff loops.
+	DefaultHandoffLoopWindow = 10
+)
+
+// WithHandoffLoopDetection blocks a handoff from an agent to another once it
+// was already made maxRepeats times within the last window handoffs of the
+// session, so that agents can't ping-pong the conversation forever. A
+// maxRepeats of zero or less disables the detection, a window of zero or
+// less looks at the whole history. Defaults to
+// DefaultMaxHandoffRepeats and DefaultHandoffLoopWindow.
+func WithHandoffLoopDetection(maxRepeats, window int) Opt {
+	return func(r *LocalRuntime) {
+		r.maxHandoffRepeats = maxRepeats
+		r.handoffLoopWindow = window
+	}
+}
+
+// WithAutoContinue continues the responses cut by the output token limit:
+// the runtime asks the model to continue where it left off, at most
+// maxContinuations times, and records the pieces as one assistant message.
+// When the limit cut a tool call, the model is asked to make its tool calls
+// again instead. Zero or less, the default, disables it.
+func WithAutoContinue(maxContinuations int) Opt {
+	return func(r *LocalRuntime) {
+		r.maxContinuations = maxContinuations
+	}
+}
+
+// WithFileSnapshots saves the content of the files before the tools first
+// change them, up to maxBytes per file, so that Session.RevertFileChanges
+// can restore them. The snapshots are kept in memory only. Zero or less, the
+// default, disables it: the changes are still recorded in the session.
+func WithFileSnapshots(maxBytes int64) Opt {
+	return func(r *LocalRuntime) {
+		r.maxSnapshotBytes = maxBytes
+	}
+}
+
+// WithToolResultPruning leaves the tool exchanges that don't inform the
+// model anymore, like failed calls that were corrected since or old file
+// reads, out of the messages sent to the model, according to policy. The
+// session keeps them, so they're still stored and exported. Disabled by
+// default.
+func WithToolResultPruning(policy session.PruningPolicy) Opt {
+	return func(r *LocalRuntime) {
+		r.toolResultPruning = policy
+	}
+}
+
+// WithToolResultCache serves the calls to the read-only tools made again
+// with the same arguments in the same session from a cache, for ttl (forever when zero or less),
+// keeping at most maxEntries results. The calls served from the cache are
+// reported with Cached set in their ToolCallEvent and ToolCallResponseEvent.
+//
+// The calls to the other tools evict the results they may have made stale:
+// the ones about the files they changed or, when they don't report the files
+// they change, all of them. Tools whose results change on their own, like
+// the time or a web search, opt out with the toolset's no_result_cache.
+// Zero or less maxEntries, the default, disables the cache.
+func WithToolResultCache(ttl time.Duration, maxEntries int) Opt {
+	return func(r *LocalRuntime) {
+		r.toolResultCache = nil
+		if maxEntries > 0 {
+			r.toolResultCache = newToolResultCache(ttl, maxEntries)
+		}
+	}
+}
+
+// WithLifecycleHooks adds hooks to the ones of every agent, run after them,
+// like the hooks of the agents' configuration: e.g. a turn_end hook building
+// the project, with Inject set to feed the failures back to the agent. Tool
+// hooks call the tools of the agent the hook runs for.
+func WithLifecycleHooks(config *hooks.Config) Opt {
+	return func(r *LocalRuntime) {
+		r.lifecycleHooks = config
+	}
+}
+
+// WithCompaction sets how sessions are compacted, overriding the compaction
+// settings of the team: e.g. a cheaper model summarizing the sessions of all
+// the agents. Unset fields keep the defaults: the model of the agent, the
+// built-in prompt and the recent messages that fit in 20k tokens.
+func WithCompaction(config team.CompactionConfig) Opt {
+	return func(r *LocalRuntime) {
+		r.compaction = &config
+	}
+}
+
+// WithRedactor sets how the secrets found in the tool outputs are masked,
+// before the outputs are sent to the model, stored in the session or sent
+// in the events, overriding the redaction settings of the team. By default,
+// the common token formats (AWS keys, GitHub tokens, JWTs and private keys)
+// are masked. A Redactor with no patterns masks nothing. Toolsets opt out
+// with no_redaction.
+func WithRedactor(redactor *tools.Redactor) Opt {
+	return func(r *LocalRuntime) {
+		r.redactor = redactor
+	}
+}
+
 // NewLocalRuntime creates a new LocalRuntime without the persistence wrapper.
 // This is useful for testing or when persistence is handled externally.
 func NewLocalRuntime(agents *team.Team, opts ...Opt) (*LocalRuntime, error) {
@@ -318,17 +609,25 @@ func NewLocalRuntime(agents *team.Team, opts ...Opt) (*LocalRuntime, error) {
 	}
 
 	r := &LocalRuntime{
-		toolMap:              make(map[string]ToolHandlerFunc),
-		team:                 agents,
-		currentAgent:         defaultAgent.Name(),
-		resumeChan:           make(chan ResumeRequest),
-		elicitationRequestCh: make(chan ElicitationResult),
-		steerQueue:           NewInMemoryMessageQueue(defaultSteerQueueCapacity),
-		followUpQueue:        NewInMemoryMessageQueue(defaultFollowUpQueueCapacity),
-		sessionCompaction:    true,
-		managedOAuth:         true,
-		sessionStore:         session.NewInMemorySessionStore(),
-		fallbackCooldowns:    make(map[string]*fallbackCooldownState),
+		toolMap:            make(map[string]ToolHandlerFunc),
+		team:               agents,
+		currentAgent:       defaultAgent.Name(),
+		runs:               make(map[string]*sessionRun),
+		sessionAgents:      make(map[string]string),
+		toolsetUsers:       make(map[tools.ToolSet][]*sessionRun),
+		startupInfoEmitted: make(map[string]bool),
+		redactionWarned:    make(map[string]bool),
+		steerQueue:         NewInMemoryMessageQueue(defaultSteerQueueCapacity),
+		followUpQueue:      NewInMemoryMessageQueue(defaultFollowUpQueueCapacity),
+		sessionCompaction:  true,
+		titleGeneration:    true,
+		managedOAuth:       true,
+		sessionStore:       session.NewInMemorySessionStore(),
+		fallbackCooldowns:  make(map[string]*fallbackCooldownState),
+		toolOutputLimit:    tools.OutputLimit{MaxBytes: DefaultToolOutputLimit, Policy: tools.TruncateHeadTail},
+		toolTimeout:        DefaultToolTimeout,
+		maxHandoffRepeats:  DefaultMaxHandoffRepeats,
+		handoffLoopWindow:  DefaultHandoffLoopWindow,
 	}
 	r.bgAgents = agenttool.NewHandler(r)
 
@@ -336,6 +635,8 @@ func NewLocalRuntime(agents *team.Team, opts ...Opt) (*LocalRuntime, error) {
 		opt(r)
 	}
 
+	r.metrics = newRuntimeMetrics(r.meterProvider)
+
 	if r.modelsStore == nil {
 		modelsStore, err := modelsdev.NewStore()
 		if err != nil {
@@ -346,7 +647,7 @@ func NewLocalRuntime(agents *team.Team, opts ...Opt) (*LocalRuntime, error) {
 
 	// Validate that the current agent exists and has a model
 	// (currentAgent might have been changed by options)
-	defaultAgent, err = r.team.Agent(r.currentAgent)
+	defaultAgent, err = r.Team().Agent(r.currentAgent)
 	if err != nil {
 		return nil, err
 	}
@@ -365,6 +666,23 @@ func NewLocalRuntime(agents *team.Team, opts ...Opt) (*LocalRuntime, error) {
 	return r, nil
 }
 
+// Team returns the team of agents run by the runtime. The team can be
+// replaced when the agent configuration is reloaded.
+func (r *LocalRuntime) Team() *team.Team {
+	r.teamMu.RLock()
+	defer r.teamMu.RUnlock()
+	return r.team
+}
+
+// compactionConfig returns the compaction settings of the runtime, or else
+// the ones of the team.
+func (r *LocalRuntime) compactionConfig() team.CompactionConfig {
+	if r.compaction != nil {
+		return *r.compaction
+	}
+	return r.Team().Compaction()
+}
+
 func (r *LocalRuntime) CurrentAgentName() string {
 	r.currentAgentMu.RLock()
 	defer r.currentAgentMu.RUnlock()
@@ -389,10 +707,16 @@ func (r *LocalRuntime) CurrentAgentInfo(context.Context) CurrentAgentInfo {
 
 func (r *LocalRuntime) SetCurrentAgent(agentName string) error {
 	// Validate that the agent exists in the team
-	if _, err := r.team.Agent(agentName); err != nil {
+	if _, err := r.Team().Agent(agentName); err != nil {
 		return err
 	}
 	r.setCurrentAgent(agentName)
+
+	// The sessions don't go back to the agents their runs ended with.
+	r.runsMu.Lock()
+	clear(r.sessionAgents)
+	r.runsMu.Unlock()
+
 	slog.Debug("Switched current agent", "agent", agentName)
 	return nil
 }
@@ -473,29 +797,66 @@ func (r *LocalRuntime) discoverMCPPrompts(ctx context.Context, toolset *mcptools
 	return prompts
 }
 
+// CurrentMCPResources returns the available MCP resources from all the MCP
+// toolsets of the current agent whose server supports resources, keyed by URI.
+func (r *LocalRuntime) CurrentMCPResources(ctx context.Context) map[string]mcptools.ResourceInfo {
+	resources := make(map[string]mcptools.ResourceInfo)
+
+	currentAgent := r.CurrentAgent()
+	if currentAgent == nil {
+		slog.Warn("No current agent available for MCP resource discovery")
+		return resources
+	}
+
+	for _, toolset := range currentAgent.ToolSets() {
+		mcpToolset, ok := tools.As[*mcptools.Toolset](toolset)
+		if !ok || !mcpToolset.HasResources() {
+			continue
+		}
+
+		mcpResources, err := mcpToolset.ListResources(ctx)
+		if err != nil {
+			slog.Warn("Failed to list MCP resources from toolset", "error", err)
+			continue
+		}
+		for _, resource := range mcpResources {
+			resources[resource.URI] = resource
+		}
+	}
+
+	slog.Debug("Discovered MCP resources", "agent", currentAgent.Name(), "resource_count", len(resources))
+	return resources
+}
+
 // CurrentAgent returns the current agent
 func (r *LocalRuntime) CurrentAgent() *agent.Agent {
 	// We validated already that the agent exists
-	current, _ := r.team.Agent(r.CurrentAgentName())
+	current, _ := r.Team().Agent(r.CurrentAgentName())
 	return current
 }
 
 // resolveSessionAgent returns the agent for the given session. When the session
 // is pinned to a specific agent (e.g. background agent tasks), it returns that
-// agent directly instead of reading the shared currentAgent field, which may
-// point to a different agent.
+// agent directly instead of the agent the session is at, see sessionAgentName.
 func (r *LocalRuntime) resolveSessionAgent(sess *session.Session) *agent.Agent {
 	if sess.AgentName != "" {
-		if a, err := r.team.Agent(sess.AgentName); err == nil {
+		if a, err := r.Team().Agent(sess.AgentName); err == nil {
 			return a
 		}
 	}
+	if a, err := r.Team().Agent(r.sessionAgentName(sess.ID)); err == nil {
+		return a
+	}
 	return r.CurrentAgent()
 }
 
 // CurrentAgentSkillsToolset returns the skills toolset for the current agent, or nil if not enabled.
 func (r *LocalRuntime) CurrentAgentSkillsToolset() *builtin.SkillsToolset {
-	a := r.CurrentAgent()
+	return skillsToolset(r.CurrentAgent())
+}
+
+// skillsToolset returns the skills toolset of a, or nil if not enabled.
+func skillsToolset(a *agent.Agent) *builtin.SkillsToolset {
 	if a == nil {
 		return nil
 	}
@@ -551,8 +912,12 @@ func (r *LocalRuntime) ExecuteMCPPrompt(ctx context.Context, promptName string,
 	return "", fmt.Errorf("MCP prompt '%s' not found in any active toolset", promptName)
 }
 
-// TitleGenerator returns a title generator for automatic session title generation.
+// TitleGenerator returns a title generator for automatic session title generation,
+// or nil when title generation is disabled, see WithTitleGeneration.
 func (r *LocalRuntime) TitleGenerator() *sessiontitle.Generator {
+	if !r.titleGeneration {
+		return nil
+	}
 	a := r.CurrentAgent()
 	if a == nil {
 		return nil
@@ -561,16 +926,65 @@ func (r *LocalRuntime) TitleGenerator() *sessiontitle.Generator {
 	if model == nil {
 		return nil
 	}
+	if r.titleModel != nil {
+		return sessiontitle.New(r.titleModel, append([]provider.Provider{model}, a.FallbackModels()...)...)
+	}
 	return sessiontitle.New(model, a.FallbackModels()...)
 }
 
-// getHooksExecutor creates a hooks executor for the given agent
+// getHooksExecutor creates a hooks executor for the given agent, running
+// its hooks and the ones of WithLifecycleHooks
 func (r *LocalRuntime) getHooksExecutor(a *agent.Agent) *hooks.Executor {
-	hooksCfg := hooks.FromConfig(a.Hooks())
-	if hooksCfg == nil || hooksCfg.IsEmpty() {
+	hooksCfg := hooks.Merge(hooks.FromConfig(a.Hooks()), r.lifecycleHooks)
+	if hooksCfg.IsEmpty() {
 		return nil
 	}
-	return hooks.NewExecutor(hooksCfg, r.workingDir, r.env)
+	return hooks.NewExecutor(hooksCfg, r.workingDir, r.env, hooks.WithToolRunner(hookToolRunner(a)))
+}
+
+// hookToolRunner calls the tools of agent a for its tool hooks. The calls
+// don't ask for confirmation: the user configured them.
+func hookToolRunner(a *agent.Agent) hooks.ToolRunner {
+	return func(ctx context.Context, name string, args map[string]any) (string, error) {
+		agentTools, err := a.Tools(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to get the tools of agent %s: %w", a.Name(), err)
+		}
+		i := slices.IndexFunc(agentTools, func(t tools.Tool) bool { return t.Name == name })
+		if i < 0 || agentTools[i].Handler == nil {
+			return "", fmt.Errorf("agent %s has no tool %s", a.Name(), name)
+		}
+
+		arguments, err := json.Marshal(args)
+		if err != nil {
+			return "", fmt.Errorf("invalid arguments for tool %s: %w", name, err)
+		}
+		res, err := agentTools[i].Handler(ctx, tools.ToolCall{
+			ID:       "hook_" + name,
+			Type:     "function",
+			Function: tools.FunctionCall{Name: name, Arguments: string(arguments)},
+		})
+		if err != nil {
+			return "", err
+		}
+		if res.IsError {
+			return res.Output, errors.New(res.Output)
+		}
+		return res.Output, nil
+	}
+}
+
+// reportHookResult emits a warning for each hook that failed, and adds the
+// output of the hooks to inject to the session as a system reminder for
+// the next turn.
+func (r *LocalRuntime) reportHookResult(sess *session.Session, a *agent.Agent, result *hooks.Result, events chan Event) {
+	for _, failure := range result.Failures {
+		events <- Warning(failure, a.Name())
+	}
+	if result.Reminder != "" {
+		reminder, _ := r.toolOutputLimit.Truncate(result.Reminder)
+		addSystemReminder(sess, a, reminder, events)
+	}
 }
 
 // executeSessionStartHooks executes session start hooks for the given agent.
@@ -601,10 +1015,12 @@ func (r *LocalRuntime) executeSessionStartHooks(ctx context.Context, sess *sessi
 		slog.Debug("Session start hook provided additional context", "context", result.AdditionalContext)
 		sess.AddMessage(session.SystemMessage(result.AdditionalContext))
 	}
+	r.reportHookResult(sess, a, result, events)
 }
 
 // executeSessionEndHooks executes session end hooks for the given agent.
-func (r *LocalRuntime) executeSessionEndHooks(ctx context.Context, sess *session.Session, a *agent.Agent) {
+// The output of the hooks to inject is for the next run of the session.
+func (r *LocalRuntime) executeSessionEndHooks(ctx context.Context, sess *session.Session, a *agent.Agent, events chan Event) {
 	hooksExec := r.getHooksExecutor(a)
 	if hooksExec == nil || !hooksExec.HasSessionEndHooks() {
 		return
@@ -617,10 +1033,12 @@ func (r *LocalRuntime) executeSessionEndHooks(ctx context.Context, sess *session
 		Reason:    "stream_ended",
 	}
 
-	_, err := hooksExec.ExecuteSessionEnd(ctx, input)
+	result, err := hooksExec.ExecuteSessionEnd(ctx, input)
 	if err != nil {
 		slog.Error("Session end hook execution failed", "agent", a.Name(), "error", err)
+		return
 	}
+	r.reportHookResult(sess, a, result, events)
 }
 
 // executeStopHooks executes stop hooks when the model finishes responding.
@@ -649,6 +1067,40 @@ func (r *LocalRuntime) executeStopHooks(ctx context.Context, sess *session.Sessi
 	}
 }
 
+// executeTurnEndHooks executes turn end hooks when the agent's turn ends,
+// with the files changed and the tools used during the turn. A failing hook
+// is reported with a warning and doesn't end the run.
+func (r *LocalRuntime) executeTurnEndHooks(ctx context.Context, sess *session.Session, a *agent.Agent, events chan Event) {
+	var activity turnActivity
+	if run := sessionRunFromContext(ctx); run != nil {
+		activity = run.takeTurnActivity(sess.ID)
+	}
+
+	hooksExec := r.getHooksExecutor(a)
+	if hooksExec == nil || !hooksExec.HasTurnEndHooks() {
+		return
+	}
+
+	slog.Debug("Executing turn end hooks", "agent", a.Name(), "session_id", sess.ID, "files_changed", len(activity.filesChanged))
+	input := &hooks.Input{
+		SessionID:    sess.ID,
+		Cwd:          r.workingDir,
+		FilesChanged: activity.filesChanged,
+		ToolsUsed:    activity.toolsUsed,
+	}
+
+	result, err := hooksExec.ExecuteTurnEnd(ctx, input)
+	if err != nil {
+		slog.Warn("Turn end hook execution failed", "agent", a.Name(), "error", err)
+		return
+	}
+
+	if result.SystemMessage != "" {
+		events <- Warning(result.SystemMessage, a.Name())
+	}
+	r.reportHookResult(sess, a, result, events)
+}
+
 // executeNotificationHooks executes notification hooks when the agent emits a user-facing
 // notification (e.g., errors or warnings). Hook output is logged but does not affect the
 // notification itself. Individual hooks are subject to their configured timeout.
@@ -681,9 +1133,9 @@ func (r *LocalRuntime) executeNotificationHooks(ctx context.Context, a *agent.Ag
 	}
 }
 
-// executeOnUserInputHooks executes on-user-input hooks for the current agent
+// executeOnUserInputHooks executes on-user-input hooks for the agent the session is at
 func (r *LocalRuntime) executeOnUserInputHooks(ctx context.Context, sessionID, logContext string) {
-	a, _ := r.team.Agent(r.CurrentAgentName())
+	a, _ := r.Team().Agent(r.sessionAgentName(sessionID))
 	if a == nil {
 		return
 	}
@@ -734,7 +1186,8 @@ func (r *LocalRuntime) getEffectiveModelID(a *agent.Agent) string {
 // It accounts for active fallback cooldowns, returning the effective model
 // instead of the configured model when a fallback is in effect.
 func (r *LocalRuntime) agentDetailsFromTeam() []AgentDetails {
-	agentsInfo := r.team.AgentsInfo()
+	t := r.Team()
+	agentsInfo := t.AgentsInfo()
 	details := make([]AgentDetails, len(agentsInfo))
 	for i, info := range agentsInfo {
 		providerName := info.Provider
@@ -744,7 +1197,7 @@ func (r *LocalRuntime) agentDetailsFromTeam() []AgentDetails {
 		cooldownState := r.getCooldownState(info.Name)
 		if cooldownState != nil {
 			// Get the agent to access fallback models
-			if a, err := r.team.Agent(info.Name); err == nil && a != nil {
+			if a, err := t.Agent(info.Name); err == nil && a != nil {
 				fallbacks := a.FallbackModels()
 				if cooldownState.fallbackIndex >= 0 && cooldownState.fallbackIndex < len(fallbacks) {
 					fb := fallbacks[cooldownState.fallbackIndex]
@@ -776,15 +1229,6 @@ func (r *LocalRuntime) SessionStore() session.Store {
 	return r.sessionStore
 }
 
-// Close releases resources held by the runtime, including the session store.
-func (r *LocalRuntime) Close() error {
-	r.bgAgents.StopAll()
-	if r.sessionStore != nil {
-		return r.sessionStore.Close()
-	}
-	return nil
-}
-
 // UpdateSessionTitle persists the session title via the session store.
 func (r *LocalRuntime) UpdateSessionTitle(ctx context.Context, sess *session.Session, title string) error {
 	sess.Title = title
@@ -797,7 +1241,7 @@ func (r *LocalRuntime) UpdateSessionTitle(ctx context.Context, sess *session.Ses
 // PermissionsInfo returns the team-level permission patterns.
 // Returns nil if no permissions are configured.
 func (r *LocalRuntime) PermissionsInfo() *PermissionsInfo {
-	permChecker := r.team.Permissions()
+	permChecker := r.Team().Permissions()
 	if permChecker == nil || permChecker.IsEmpty() {
 		return nil
 	}
@@ -808,11 +1252,13 @@ func (r *LocalRuntime) PermissionsInfo() *PermissionsInfo {
 	}
 }
 
-// ResetStartupInfo resets the startup info emission flag.
+// ResetStartupInfo resets the startup info emission flags of all the sessions.
 // This should be called when replacing a session to allow re-emission of
 // agent, team, and toolset info to the UI.
 func (r *LocalRuntime) ResetStartupInfo() {
-	r.startupInfoEmitted = false
+	r.startupInfoEmittedMu.Lock()
+	defer r.startupInfoEmittedMu.Unlock()
+	clear(r.startupInfoEmitted)
 }
 
 // OnToolsChanged registers a handler that is called when an MCP toolset
@@ -821,8 +1267,14 @@ func (r *LocalRuntime) ResetStartupInfo() {
 func (r *LocalRuntime) OnToolsChanged(handler func(Event)) {
 	r.onToolsChanged = handler
 
-	for _, name := range r.team.AgentNames() {
-		a, err := r.team.Agent(name)
+	r.setToolsChangedHandlers(r.Team())
+}
+
+// setToolsChangedHandlers registers emitToolsChanged on the toolsets of the
+// team that can report tool list changes.
+func (r *LocalRuntime) setToolsChangedHandlers(t *team.Team) {
+	for _, name := range t.AgentNames() {
+		a, err := t.Agent(name)
 		if err != nil {
 			continue
 		}
@@ -847,20 +1299,28 @@ func (r *LocalRuntime) emitToolsChanged() {
 	if err != nil {
 		return
 	}
-	r.onToolsChanged(ToolsetInfo(len(agentTools), false, r.CurrentAgentName()))
+	r.onToolsChanged(toolsetInfo(agentTools, a.FailedToolSets(), false, r.CurrentAgentName()))
 }
 
 // EmitStartupInfo emits initial agent, team, and toolset information for immediate sidebar display.
 // When sess is non-nil and contains token data, a TokenUsageEvent is also emitted so that the
 // sidebar can display context usage percentage on session restore.
 func (r *LocalRuntime) EmitStartupInfo(ctx context.Context, sess *session.Session, events chan Event) {
+	a := r.CurrentAgent()
+	var sessionID string
+	if sess != nil {
+		sessionID = sess.ID
+		a = r.resolveSessionAgent(sess)
+	}
+
 	// Prevent duplicate emissions
-	if r.startupInfoEmitted {
+	r.startupInfoEmittedMu.Lock()
+	emitted := r.startupInfoEmitted[sessionID]
+	r.startupInfoEmitted[sessionID] = true
+	r.startupInfoEmittedMu.Unlock()
+	if emitted {
 		return
 	}
-	r.startupInfoEmitted = true
-
-	a := r.CurrentAgent()
 
 	// Helper to send events with context check
 	send := func(event Event) bool {
@@ -878,7 +1338,7 @@ func (r *LocalRuntime) EmitStartupInfo(ctx context.Context, sess *session.Sessio
 	if !send(AgentInfo(a.Name(), modelID, a.Description(), a.WelcomeMessage())) {
 		return
 	}
-	if !send(TeamInfo(r.agentDetailsFromTeam(), r.CurrentAgentName())) {
+	if !send(TeamInfo(r.agentDetailsFromTeam(), a.Name())) {
 		return
 	}
 
@@ -897,6 +1357,8 @@ func (r *LocalRuntime) EmitStartupInfo(ctx context.Context, sess *session.Sessio
 		}
 		usage := SessionUsage(sess, contextLimit)
 		usage.Cost = sess.TotalCost()
+		totals := sess.TotalUsage()
+		usage.Totals = &totals
 
 		// Reconstruct LastMessage from the parent session's last assistant
 		// message so that FinishReason (and other per-message fields) are
@@ -904,7 +1366,10 @@ func (r *LocalRuntime) EmitStartupInfo(ctx context.Context, sess *session.Sessio
 		// sess.Messages (not GetAllMessages) so the result reflects the
 		// parent agent's state: this event carries the parent session_id,
 		// and sub-agents emit their own token_usage events with their own
-		// session_id during live streaming.
+		// session_id during live streaming. The event is attributed to the
+		// agent that answered that message, which isn't necessarily the
+		// current agent.
+		agentName := a.Name()
 		for i := len(sess.Messages) - 1; i >= 0; i-- {
 			item := &sess.Messages[i]
 			if !item.IsMessage() || item.Message.Message.Role != chat.MessageRoleAssistant {
@@ -920,10 +1385,13 @@ func (r *LocalRuntime) EmitStartupInfo(ctx context.Context, sess *session.Sessio
 				lm.Usage = *msg.Usage
 			}
 			usage.LastMessage = lm
+			if item.Message.AgentName != "" {
+				agentName = item.Message.AgentName
+			}
 			break
 		}
 
-		send(NewTokenUsageEvent(sess.ID, r.CurrentAgentName(), usage))
+		send(NewTokenUsageEvent(sess.ID, agentName, usage))
 	}
 
 	// Emit agent warnings (if any) - these are quick
@@ -943,17 +1411,20 @@ func (r *LocalRuntime) emitToolsProgressively(ctx context.Context, a *agent.Agen
 
 	// If no toolsets, emit final state immediately
 	if totalToolsets == 0 {
-		send(ToolsetInfo(0, false, r.CurrentAgentName()))
+		send(ToolsetInfo(0, 0, false, a.Name()))
 		return
 	}
 
 	// Emit initial loading state
-	if !send(ToolsetInfo(0, true, r.CurrentAgentName())) {
+	if !send(ToolsetInfo(0, 0, true, a.Name())) {
 		return
 	}
 
 	// Load tools from each toolset and emit progress
-	var totalTools int
+	var (
+		agentTools     []tools.Tool
+		failedToolsets int
+	)
 	for i, toolset := range toolsets {
 		// Check context before potentially slow operations
 		if ctx.Err() != nil {
@@ -966,7 +1437,8 @@ func (r *LocalRuntime) emitToolsProgressively(ctx context.Context, a *agent.Agen
 		if startable, ok := toolset.(*tools.StartableToolSet); ok {
 			if !startable.IsStarted() {
 				if err := startable.Start(ctx); err != nil {
-					slog.Warn("Toolset start failed; skipping", "agent", a.Name(), "toolset", fmt.Sprintf("%T", startable.ToolSet), "error", err)
+					slog.Warn("Toolset start failed; skipping", "agent", a.Name(), "toolset", tools.DescribeToolSet(startable), "error", err)
+					failedToolsets++
 					continue
 				}
 			}
@@ -975,24 +1447,35 @@ func (r *LocalRuntime) emitToolsProgressively(ctx context.Context, a *agent.Agen
 		// Get tools from this toolset
 		ts, err := toolset.Tools(ctx)
 		if err != nil {
-			slog.Warn("Failed to get tools from toolset", "agent", a.Name(), "error", err)
+			slog.Warn("Failed to get tools from toolset", "agent", a.Name(), "toolset", tools.DescribeToolSet(toolset), "error", err)
+			failedToolsets++
 			continue
 		}
 
-		totalTools += len(ts)
+		agentTools = append(agentTools, a.FilterTools(ts)...)
 
 		// Emit progress update - still loading unless this is the last toolset
-		if !send(ToolsetInfo(totalTools, !isLast, r.CurrentAgentName())) {
+		if !send(toolsetInfo(agentTools, failedToolsets, !isLast, a.Name())) {
 			return
 		}
 	}
 
 	// Emit final state (not loading)
-	send(ToolsetInfo(totalTools, false, r.CurrentAgentName()))
+	send(toolsetInfo(agentTools, failedToolsets, false, a.Name()))
 }
 
-func (r *LocalRuntime) Resume(_ context.Context, req ResumeRequest) {
-	slog.Debug("Resuming runtime", "agent", r.CurrentAgentName(), "type", req.Type, "reason", req.Reason)
+// ErrSessionNotRunning is returned when answering a confirmation or an
+// elicitation of a session that isn't running.
+var ErrSessionNotRunning = errors.New("session not running")
+
+// Resume answers the pending tool call confirmation of the session
+// sessionID. The answer is kept until the run waits for it, so it can be
+// sent as soon as the ToolCallConfirmationEvent is received. Only one
+// answer is kept: the ones sent while another is waiting are dropped. An
+// error wrapping ErrSessionNotRunning is returned when the session isn't
+// running.
+func (r *LocalRuntime) Resume(_ context.Context, sessionID string, req ResumeRequest) error {
+	slog.Debug("Resuming runtime", "session_id", sessionID, "type", req.Type, "reason", req.Reason)
 
 	// Defensive validation:
 	//
@@ -1003,33 +1486,53 @@ func (r *LocalRuntime) Resume(_ context.Context, req ResumeRequest) {
 	if !IsValidResumeType(req.Type) {
 		slog.Warn(
 			"Invalid resume type received; ignoring resume request",
-			"agent", r.CurrentAgentName(),
+			"session_id", sessionID,
 			"confirmation_type", req.Type,
 			"valid_types", ValidResumeTypes(),
 		)
-		return
+		return fmt.Errorf("invalid resume type %q", req.Type)
+	}
+
+	run, ok := r.sessionRun(sessionID)
+	if !ok {
+		slog.Debug("Session not running; resume signal dropped", "session_id", sessionID, "confirmation_type", req.Type)
+		return fmt.Errorf("resuming session %s: %w", sessionID, ErrSessionNotRunning)
 	}
 
-	// Attempt to deliver the resume signal to the execution loop.
-	//
-	// The channel is non-blocking by design to avoid deadlocks if the runtime
-	// is not currently waiting for a confirmation (e.g. already resumed,
-	// canceled, or shutting down).
 	select {
-	case r.resumeChan <- req:
-		slog.Debug("Resume signal sent", "agent", r.CurrentAgentName())
+	case run.resumeChan <- req:
+		slog.Debug("Resume signal sent", "agent", run.agentName(), "session_id", sessionID)
 	default:
 		slog.Debug(
-			"Resume channel not ready; resume signal dropped",
-			"agent", r.CurrentAgentName(),
+			"Another resume signal is pending; resume signal dropped",
+			"agent", run.agentName(),
+			"session_id", sessionID,
 			"confirmation_type", req.Type,
 		)
 	}
+	return nil
 }
 
 // ResumeElicitation sends an elicitation response back to a waiting elicitation request
-func (r *LocalRuntime) ResumeElicitation(ctx context.Context, action tools.ElicitationAction, content map[string]any) error {
-	slog.Debug("Resuming runtime with elicitation response", "agent", r.CurrentAgentName(), "action", action)
+// of the session sessionID. Accepted content is validated against the requested schema
+// first: when it doesn't match, an *ElicitationValidationError is returned and the
+// request stays pending. As with Resume, the response is kept until the request waits
+// for it, and an error wrapping ErrSessionNotRunning is returned when the session
+// isn't running.
+func (r *LocalRuntime) ResumeElicitation(ctx context.Context, sessionID string, action tools.ElicitationAction, content map[string]any) error {
+	slog.Debug("Resuming runtime with elicitation response", "session_id", sessionID, "action", action)
+
+	run, ok := r.sessionRun(sessionID)
+	if !ok {
+		return fmt.Errorf("no elicitation request in progress for session %s: %w", sessionID, ErrSessionNotRunning)
+	}
+
+	if action == tools.ElicitationActionAccept {
+		if violations := validateElicitationContent(run.getElicitationSchema(), content); len(violations) > 0 {
+			slog.Debug("Elicitation response doesn't match the requested schema", "violations", violations)
+			return &ElicitationValidationError{Violations: violations}
+		}
+	}
 
 	result := ElicitationResult{
 		Action:  action,
@@ -1040,13 +1543,12 @@ func (r *LocalRuntime) ResumeElicitation(ctx context.Context, action tools.Elici
 	case <-ctx.Done():
 		slog.Debug("Context cancelled while sending elicitation response")
 		return ctx.Err()
-	case r.elicitationRequestCh <- result:
+	case run.elicitationRequestCh <- result:
 		slog.Debug("Elicitation response sent successfully", "action", action)
-		return nil
 	default:
-		slog.Debug("Elicitation channel not ready")
-		return errors.New("no elicitation request in progress")
+		slog.Debug("Another elicitation response is pending; elicitation response dropped", "session_id", sessionID, "action", action)
 	}
+	return nil
 }
 
 // Steer enqueues a user message for urgent mid-turn injection into the
@@ -1096,50 +1598,56 @@ func (r *LocalRuntime) Summarize(ctx context.Context, sess *session.Session, add
 	events <- NewTokenUsageEvent(sess.ID, a.Name(), SessionUsage(sess, contextLimit))
 }
 
-// swapElicitationEventsChannel atomically replaces the current elicitation
-// events channel and returns the previous one. Each RunStream call swaps in
-// its own channel on entry and swaps the previous one back on exit, so nested
-// streams (sub-sessions, background agents) don't lose the parent's channel.
-func (r *LocalRuntime) swapElicitationEventsChannel(ch chan Event) chan Event {
-	r.elicitationEventsChannelMux.Lock()
-	defer r.elicitationEventsChannelMux.Unlock()
-	prev := r.elicitationEventsChannel
-	r.elicitationEventsChannel = ch
-	return prev
+// toolsetElicitationHandler handles the elicitation requests of the
+// toolset ts with the run of the session each one is for, see toolsetRun.
+func (r *LocalRuntime) toolsetElicitationHandler(ts tools.ToolSet) tools.ElicitationHandler {
+	return func(ctx context.Context, req *mcp.ElicitParams) (tools.ElicitationResult, error) {
+		run, err := r.toolsetRun(ctx, ts)
+		if err != nil {
+			return tools.ElicitationResult{}, err
+		}
+		return r.elicitationHandler(run)(ctx, req)
+	}
 }
 
 // elicitationHandler creates an elicitation handler that can be used by MCP clients
-// This handler propagates elicitation requests to the runtime's client via events
-func (r *LocalRuntime) elicitationHandler(ctx context.Context, req *mcp.ElicitParams) (tools.ElicitationResult, error) {
-	slog.Debug("Elicitation request received from MCP server", "message", req.Message)
+// This handler propagates elicitation requests to the client of run via events
+func (r *LocalRuntime) elicitationHandler(run *sessionRun) tools.ElicitationHandler {
+	return func(ctx context.Context, req *mcp.ElicitParams) (tools.ElicitationResult, error) {
+		slog.Debug("Elicitation request received from MCP server", "message", req.Message, "session_id", run.sessionID)
+
+		// Hold the read lock while sending to the channel to prevent a race
+		// with swapEvents / close(events).
+		run.eventsMu.RLock()
+		eventsChannel := run.events
+		if eventsChannel == nil {
+			run.eventsMu.RUnlock()
+			return tools.ElicitationResult{}, errors.New("no events channel available for elicitation")
+		}
 
-	// Hold the read lock while sending to the channel to prevent a race
-	// with swapElicitationEventsChannel / close(events).
-	r.elicitationEventsChannelMux.RLock()
-	eventsChannel := r.elicitationEventsChannel
-	if eventsChannel == nil {
-		r.elicitationEventsChannelMux.RUnlock()
-		return tools.ElicitationResult{}, errors.New("no events channel available for elicitation")
-	}
+		r.executeOnUserInputHooks(ctx, run.sessionID, "elicitation")
 
-	r.executeOnUserInputHooks(ctx, "", "elicitation")
+		run.setElicitationSchema(req.RequestedSchema)
+		defer run.setElicitationSchema(nil)
+		run.discardElicitationResult()
 
-	slog.Debug("Sending elicitation request event to client", "message", req.Message, "mode", req.Mode, "requested_schema", req.RequestedSchema, "url", req.URL)
-	slog.Debug("Elicitation request meta", "meta", req.Meta)
+		slog.Debug("Sending elicitation request event to client", "message", req.Message, "mode", req.Mode, "requested_schema", req.RequestedSchema, "url", req.URL)
+		slog.Debug("Elicitation request meta", "meta", req.Meta)
 
-	// Send elicitation request event to the runtime's client
-	eventsChannel <- ElicitationRequest(req.Message, req.Mode, req.RequestedSchema, req.URL, req.ElicitationID, req.Meta, r.CurrentAgentName())
-	r.elicitationEventsChannelMux.RUnlock()
+		// Send elicitation request event to the runtime's client
+		eventsChannel <- ElicitationRequest(req.Message, req.Mode, req.RequestedSchema, req.URL, req.ElicitationID, req.Meta, run.agentName())
+		run.eventsMu.RUnlock()
 
-	// Wait for response from the client
-	select {
-	case result := <-r.elicitationRequestCh:
-		return tools.ElicitationResult{
-			Action:  result.Action,
-			Content: result.Content,
-		}, nil
-	case <-ctx.Done():
-		slog.Debug("Context cancelled while waiting for elicitation response")
-		return tools.ElicitationResult{}, ctx.Err()
+		// Wait for response from the client
+		select {
+		case result := <-run.elicitationRequestCh:
+			return tools.ElicitationResult{
+				Action:  result.Action,
+				Content: result.Content,
+			}, nil
+		case <-ctx.Done():
+			slog.Debug("Context cancelled while waiting for elicitation response")
+			return tools.ElicitationResult{}, ctx.Err()
+		}
 	}
 }
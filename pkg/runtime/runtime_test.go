@@ -16,6 +16,7 @@ import (
 	"github.com/docker/docker-agent/pkg/chat"
 	"github.com/docker/docker-agent/pkg/config/latest"
 	"github.com/docker/docker-agent/pkg/model/provider/base"
+	"github.com/docker/docker-agent/pkg/model/provider/fake"
 	"github.com/docker/docker-agent/pkg/modelerrors"
 	"github.com/docker/docker-agent/pkg/modelsdev"
 	"github.com/docker/docker-agent/pkg/permissions"
@@ -207,8 +208,8 @@ func hasEventType(t *testing.T, events []Event, target Event) bool {
 	return false
 }
 
-// assertEventsEqual compares two event slices, ignoring timestamps.
-// Timestamps are inherently non-deterministic in tests.
+// assertEventsEqual compares two event slices, ignoring timestamps and the
+// durations in run statistics. Both are inherently non-deterministic in tests.
 func assertEventsEqual(t *testing.T, expected, actual []Event) {
 	t.Helper()
 
@@ -219,9 +220,11 @@ func assertEventsEqual(t *testing.T, expected, actual []Event) {
 		actualType := reflect.TypeOf(actual[i])
 		assert.Equal(t, expectedType, actualType, "event type mismatch at index %d", i)
 
-		// Clear timestamps for comparison
+		// Clear timestamps and durations for comparison
 		clearTimestamps(expected[i])
 		clearTimestamps(actual[i])
+		clearDurations(expected[i])
+		clearDurations(actual[i])
 
 		assert.Equal(t, expected[i], actual[i], "event content mismatch at index %d", i)
 	}
@@ -253,6 +256,38 @@ func clearTimestamps(event Event) {
 	}
 }
 
+// clearDurations sets the durations in the statistics of a RunCompletedEvent
+// to zero.
+func clearDurations(event Event) {
+	e, ok := event.(*RunCompletedEvent)
+	if !ok {
+		return
+	}
+	for id, model := range e.Stats.Models {
+		model.Duration = 0
+		e.Stats.Models[id] = model
+	}
+	for name, tool := range e.Stats.Tools {
+		tool.Duration = 0
+		e.Stats.Tools[name] = tool
+	}
+}
+
+// singleRequestStats are the statistics of a run with a single request to
+// the mock model.
+func singleRequestStats(inputTokens, outputTokens int64) Stats {
+	return Stats{
+		Runs:          1,
+		Iterations:    1,
+		ModelRequests: 1,
+		InputTokens:   inputTokens,
+		OutputTokens:  outputTokens,
+		Models: map[string]ModelStats{
+			"test/mock-model": {Requests: 1, InputTokens: inputTokens, OutputTokens: outputTokens},
+		},
+	}
+}
+
 func TestSimple(t *testing.T) {
 	stream := newStreamBuilder().
 		AddContent("Hello").
@@ -265,26 +300,31 @@ func TestSimple(t *testing.T) {
 
 	// Extract the actual message from MessageAddedEvent to use in comparison
 	// (it contains dynamic fields like CreatedAt that we can't predict)
-	require.Len(t, events, 10)
-	msgAdded := events[7].(*MessageAddedEvent)
+	require.Len(t, events, 12)
+	msgAdded := events[8].(*MessageAddedEvent)
 	require.NotNil(t, msgAdded.Message)
 	require.Equal(t, "Hello", msgAdded.Message.Message.Content)
 	require.Equal(t, chat.MessageRoleAssistant, msgAdded.Message.Message.Role)
 
 	expectedEvents := []Event{
 		TeamInfo([]AgentDetails{{Name: "root", Provider: "test", Model: "mock-model"}}, "root"),
-		ToolsetInfo(0, false, "root"),
+		ToolsetInfo(0, 0, false, "root"),
 		UserMessage("Hi", sess.ID, nil, 0),
 		StreamStarted(sess.ID, "root"),
-		ToolsetInfo(0, false, "root"),
+		ToolsetInfo(0, 0, false, "root"),
 		AgentInfo("root", "test/mock-model", "", ""),
 		AgentChoice("root", sess.ID, "Hello"),
+		AgentMessageCompleted("root", sess.ID, "Hello", "", nil, chat.FinishReasonStop, &chat.Usage{InputTokens: 3, OutputTokens: 2}, nil),
 		MessageAdded(sess.ID, msgAdded.Message, "root"),
 		NewTokenUsageEvent(sess.ID, "root", &Usage{InputTokens: 3, OutputTokens: 2, ContextLength: 5, LastMessage: &MessageUsage{
 			Usage:        chat.Usage{InputTokens: 3, OutputTokens: 2},
 			Model:        "test/mock-model",
 			FinishReason: chat.FinishReasonStop,
+		}, Totals: &session.UsageTotals{
+			Usage: chat.Usage{InputTokens: 3, OutputTokens: 2},
+			Turns: 1,
 		}}),
+		RunCompleted(sess.ID, "root", singleRequestStats(3, 2)),
 		StreamStopped(sess.ID, "root"),
 	}
 
@@ -307,28 +347,33 @@ func TestMultipleContentChunks(t *testing.T) {
 
 	// Extract the actual message from MessageAddedEvent to use in comparison
 	// (it contains dynamic fields like CreatedAt that we can't predict)
-	require.Len(t, events, 14)
-	msgAdded := events[11].(*MessageAddedEvent)
+	require.Len(t, events, 16)
+	msgAdded := events[12].(*MessageAddedEvent)
 	require.NotNil(t, msgAdded.Message)
 
 	expectedEvents := []Event{
 		TeamInfo([]AgentDetails{{Name: "root", Provider: "test", Model: "mock-model"}}, "root"),
-		ToolsetInfo(0, false, "root"),
+		ToolsetInfo(0, 0, false, "root"),
 		UserMessage("Please greet me", sess.ID, nil, 0),
 		StreamStarted(sess.ID, "root"),
-		ToolsetInfo(0, false, "root"),
+		ToolsetInfo(0, 0, false, "root"),
 		AgentInfo("root", "test/mock-model", "", ""),
 		AgentChoice("root", sess.ID, "Hello "),
 		AgentChoice("root", sess.ID, "there, "),
 		AgentChoice("root", sess.ID, "how "),
 		AgentChoice("root", sess.ID, "are "),
 		AgentChoice("root", sess.ID, "you?"),
+		AgentMessageCompleted("root", sess.ID, "Hello there, how are you?", "", nil, chat.FinishReasonStop, &chat.Usage{InputTokens: 8, OutputTokens: 12}, nil),
 		MessageAdded(sess.ID, msgAdded.Message, "root"),
 		NewTokenUsageEvent(sess.ID, "root", &Usage{InputTokens: 8, OutputTokens: 12, ContextLength: 20, LastMessage: &MessageUsage{
 			Usage:        chat.Usage{InputTokens: 8, OutputTokens: 12},
 			Model:        "test/mock-model",
 			FinishReason: chat.FinishReasonStop,
+		}, Totals: &session.UsageTotals{
+			Usage: chat.Usage{InputTokens: 8, OutputTokens: 12},
+			Turns: 1,
 		}}),
+		RunCompleted(sess.ID, "root", singleRequestStats(8, 12)),
 		StreamStopped(sess.ID, "root"),
 	}
 
@@ -349,26 +394,31 @@ func TestWithReasoning(t *testing.T) {
 
 	// Extract the actual message from MessageAddedEvent to use in comparison
 	// (it contains dynamic fields like CreatedAt that we can't predict)
-	require.Len(t, events, 12)
-	msgAdded := events[9].(*MessageAddedEvent)
+	require.Len(t, events, 14)
+	msgAdded := events[10].(*MessageAddedEvent)
 	require.NotNil(t, msgAdded.Message)
 
 	expectedEvents := []Event{
 		TeamInfo([]AgentDetails{{Name: "root", Provider: "test", Model: "mock-model"}}, "root"),
-		ToolsetInfo(0, false, "root"),
+		ToolsetInfo(0, 0, false, "root"),
 		UserMessage("Hi", sess.ID, nil, 0),
 		StreamStarted(sess.ID, "root"),
-		ToolsetInfo(0, false, "root"),
+		ToolsetInfo(0, 0, false, "root"),
 		AgentInfo("root", "test/mock-model", "", ""),
 		AgentChoiceReasoning("root", sess.ID, "Let me think about this..."),
 		AgentChoiceReasoning("root", sess.ID, " I should respond politely."),
 		AgentChoice("root", sess.ID, "Hello, how can I help you?"),
+		AgentMessageCompleted("root", sess.ID, "Hello, how can I help you?", "Let me think about this... I should respond politely.", nil, chat.FinishReasonStop, &chat.Usage{InputTokens: 10, OutputTokens: 15}, nil),
 		MessageAdded(sess.ID, msgAdded.Message, "root"),
 		NewTokenUsageEvent(sess.ID, "root", &Usage{InputTokens: 10, OutputTokens: 15, ContextLength: 25, LastMessage: &MessageUsage{
 			Usage:        chat.Usage{InputTokens: 10, OutputTokens: 15},
 			Model:        "test/mock-model",
 			FinishReason: chat.FinishReasonStop,
+		}, Totals: &session.UsageTotals{
+			Usage: chat.Usage{InputTokens: 10, OutputTokens: 15},
+			Turns: 1,
 		}}),
+		RunCompleted(sess.ID, "root", singleRequestStats(10, 15)),
 		StreamStopped(sess.ID, "root"),
 	}
 
@@ -390,27 +440,32 @@ func TestMixedContentAndReasoning(t *testing.T) {
 
 	// Extract the actual message from MessageAddedEvent to use in comparison
 	// (it contains dynamic fields like CreatedAt that we can't predict)
-	require.Len(t, events, 13)
-	msgAdded := events[10].(*MessageAddedEvent)
+	require.Len(t, events, 15)
+	msgAdded := events[11].(*MessageAddedEvent)
 	require.NotNil(t, msgAdded.Message)
 
 	expectedEvents := []Event{
 		TeamInfo([]AgentDetails{{Name: "root", Provider: "test", Model: "mock-model"}}, "root"),
-		ToolsetInfo(0, false, "root"),
+		ToolsetInfo(0, 0, false, "root"),
 		UserMessage("Hi there", sess.ID, nil, 0),
 		StreamStarted(sess.ID, "root"),
-		ToolsetInfo(0, false, "root"),
+		ToolsetInfo(0, 0, false, "root"),
 		AgentInfo("root", "test/mock-model", "", ""),
 		AgentChoiceReasoning("root", sess.ID, "The user wants a greeting"),
 		AgentChoice("root", sess.ID, "Hello!"),
 		AgentChoiceReasoning("root", sess.ID, " I should be friendly"),
 		AgentChoice("root", sess.ID, " How can I help you today?"),
+		AgentMessageCompleted("root", sess.ID, "Hello! How can I help you today?", "The user wants a greeting I should be friendly", nil, chat.FinishReasonStop, &chat.Usage{InputTokens: 15, OutputTokens: 20}, nil),
 		MessageAdded(sess.ID, msgAdded.Message, "root"),
 		NewTokenUsageEvent(sess.ID, "root", &Usage{InputTokens: 15, OutputTokens: 20, ContextLength: 35, LastMessage: &MessageUsage{
 			Usage:        chat.Usage{InputTokens: 15, OutputTokens: 20},
 			Model:        "test/mock-model",
 			FinishReason: chat.FinishReasonStop,
+		}, Totals: &session.UsageTotals{
+			Usage: chat.Usage{InputTokens: 15, OutputTokens: 20},
+			Turns: 1,
 		}}),
+		RunCompleted(sess.ID, "root", singleRequestStats(15, 20)),
 		StreamStopped(sess.ID, "root"),
 	}
 
@@ -453,7 +508,7 @@ func TestErrorEvent(t *testing.T) {
 		events = append(events, ev)
 	}
 
-	require.Len(t, events, 8)
+	require.Len(t, events, 9)
 	require.IsType(t, &TeamInfoEvent{}, events[0])
 	require.IsType(t, &ToolsetInfoEvent{}, events[1])
 	require.IsType(t, &UserMessageEvent{}, events[2])
@@ -461,10 +516,13 @@ func TestErrorEvent(t *testing.T) {
 	require.IsType(t, &ToolsetInfoEvent{}, events[4])
 	require.IsType(t, &AgentInfoEvent{}, events[5])
 	require.IsType(t, &ErrorEvent{}, events[6])
-	require.IsType(t, &StreamStoppedEvent{}, events[7])
+	require.IsType(t, &RunCompletedEvent{}, events[7])
+	require.IsType(t, &StreamStoppedEvent{}, events[8])
 
 	errorEvent := events[6].(*ErrorEvent)
 	require.Contains(t, errorEvent.Error, "simulated error")
+	assert.Equal(t, ErrorCodeProviderRequestFailed, errorEvent.Code)
+	assert.Equal(t, ComponentModel, errorEvent.Component)
 }
 
 func TestContextCancellation(t *testing.T) {
@@ -861,7 +919,7 @@ func TestEmitStartupInfo(t *testing.T) {
 			{Name: "startup-test-agent", Description: "This is a startup test agent", Provider: "test", Model: "startup-model"},
 			{Name: "other-agent", Description: "This is another agent", Provider: "test", Model: "startup-model"},
 		}, "startup-test-agent"),
-		ToolsetInfo(0, false, "startup-test-agent"), // No tools configured
+		ToolsetInfo(0, 0, false, "startup-test-agent"), // No tools configured
 	}
 
 	assertEventsEqual(t, expectedEvents, collectedEvents)
@@ -1160,6 +1218,57 @@ func TestPermissions_AllowAutoApprovesTool(t *testing.T) {
 	require.True(t, executed, "expected tool to be auto-approved and executed")
 }
 
+func TestProcessToolCalls_InvalidArguments(t *testing.T) {
+	var executed bool
+	agentTools := []tools.Tool{{
+		Name: "goto_line",
+		Parameters: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"line": map[string]any{"type": "integer"}},
+			"required":   []string{"line"},
+		},
+		Annotations: tools.ToolAnnotations{ReadOnlyHint: true},
+		Handler: func(ctx context.Context, tc tools.ToolCall) (*tools.ToolCallResult, error) {
+			executed = true
+			return tools.ResultSuccess("executed"), nil
+		},
+	}}
+
+	prov := &mockProvider{id: "test/mock-model", stream: &mockStream{}}
+	root := agent.New("root", "You are a test agent", agent.WithModel(prov))
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Test"))
+	calls := []tools.ToolCall{{
+		ID:       "call_1",
+		Type:     "function",
+		Function: tools.FunctionCall{Name: "goto_line", Arguments: `{"line":"12"}`},
+	}}
+
+	events := make(chan Event, 10)
+	rt.processToolCalls(t.Context(), sess, calls, agentTools, events)
+	close(events)
+
+	var validationFailed *ToolCallValidationFailedEvent
+	var toolResponse *ToolCallResponseEvent
+	for ev := range events {
+		switch ev := ev.(type) {
+		case *ToolCallValidationFailedEvent:
+			validationFailed = ev
+		case *ToolCallResponseEvent:
+			toolResponse = ev
+		}
+	}
+
+	require.False(t, executed, "the handler must not be called with invalid arguments")
+	require.NotNil(t, validationFailed)
+	assert.Equal(t, []string{"property 'line': expected integer, got string"}, validationFailed.Violations)
+	require.NotNil(t, toolResponse)
+	assert.True(t, toolResponse.Result.IsError)
+	assert.Contains(t, toolResponse.Response, "property 'line': expected integer, got string")
+}
+
 func TestPermissions_DenyTakesPriorityOverAllow(t *testing.T) {
 	// Test that deny patterns take priority over allow patterns
 	permChecker := permissions.NewChecker(&latest.PermissionsConfig{
@@ -1388,10 +1497,11 @@ func TestToolRejectionWithReason(t *testing.T) {
 	}}
 
 	events := make(chan Event, 10)
+	run := newSessionRun(sess.ID, "root")
 
 	// Run in goroutine since it will block waiting for confirmation
 	go func() {
-		rt.processToolCalls(t.Context(), sess, calls, agentTools, events)
+		rt.processToolCalls(withSessionRun(t.Context(), run), sess, calls, agentTools, events)
 		close(events)
 	}()
 
@@ -1400,7 +1510,7 @@ func TestToolRejectionWithReason(t *testing.T) {
 	for ev := range events {
 		if _, ok := ev.(*ToolCallConfirmationEvent); ok {
 			// Send rejection with a specific reason
-			rt.resumeChan <- ResumeReject("The arguments provided are incorrect.")
+			run.resumeChan <- ResumeReject("The arguments provided are incorrect.")
 		}
 		if resp, ok := ev.(*ToolCallResponseEvent); ok {
 			toolResponse = resp
@@ -1444,10 +1554,11 @@ func TestToolRejectionWithoutReason(t *testing.T) {
 	}}
 
 	events := make(chan Event, 10)
+	run := newSessionRun(sess.ID, "root")
 
 	// Run in goroutine since it will block waiting for confirmation
 	go func() {
-		rt.processToolCalls(t.Context(), sess, calls, agentTools, events)
+		rt.processToolCalls(withSessionRun(t.Context(), run), sess, calls, agentTools, events)
 		close(events)
 	}()
 
@@ -1456,7 +1567,7 @@ func TestToolRejectionWithoutReason(t *testing.T) {
 	for ev := range events {
 		if _, ok := ev.(*ToolCallConfirmationEvent); ok {
 			// Send rejection without a reason
-			rt.resumeChan <- ResumeReject("")
+			run.resumeChan <- ResumeReject("")
 		}
 		if resp, ok := ev.(*ToolCallResponseEvent); ok {
 			toolResponse = resp
@@ -1541,6 +1652,54 @@ func TestTransferTaskAllowsSubAgent(t *testing.T) {
 	assert.False(t, result.IsError, "transfer to valid sub-agent should succeed")
 }
 
+func TestTransferTaskSubSessionTitles(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Opt
+		wantTitle string
+	}{
+		{name: "default", wantTitle: "Transferred task"},
+		{name: "enabled", opts: []Opt{WithSubSessionTitles(true)}, wantTitle: "librarian: Find a book about quota APIs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prov := &mockProvider{id: "test/mock-model", stream: newStreamBuilder().AddContent("done").AddStopWithUsage(10, 5).Build()}
+
+			librarian := agent.New("librarian", "Library agent", agent.WithModel(prov))
+			root := agent.New("root", "Root agent", agent.WithModel(prov))
+			agent.WithSubAgents(librarian)(root)
+
+			opts := append([]Opt{WithSessionCompaction(false), WithModelStore(mockModelStore{})}, tt.opts...)
+			rt, err := NewLocalRuntime(team.New(team.WithAgents(root, librarian)), opts...)
+			require.NoError(t, err)
+
+			sess := session.New(session.WithUserMessage("Test"), session.WithToolsApproved(true))
+			toolCall := tools.ToolCall{
+				ID:   "call_1",
+				Type: "function",
+				Function: tools.FunctionCall{
+					Name:      "transfer_task",
+					Arguments: `{"agent":"librarian","task":"Find a **book** about quota APIs.","expected_output":""}`,
+				},
+			}
+
+			_, err = rt.handleTaskTransfer(t.Context(), sess, toolCall, make(chan Event, 128))
+			require.NoError(t, err)
+
+			var child *session.Session
+			for _, item := range sess.Messages {
+				if item.IsSubSession() {
+					child = item.SubSession
+				}
+			}
+			require.NotNil(t, child)
+			assert.Equal(t, tt.wantTitle, child.Title)
+			assert.Equal(t, sess.ID, child.ParentID)
+			assert.Equal(t, "call_1", child.ToolCallID)
+		})
+	}
+}
+
 func TestYoloMode_OverridesPermissionsDeny(t *testing.T) {
 	// Test that --yolo flag takes precedence over deny permissions
 	permChecker := permissions.NewChecker(&latest.PermissionsConfig{
@@ -1698,7 +1857,7 @@ func TestStripImageContent(t *testing.T) {
 			},
 		},
 		{
-			name: "strips image URL parts from tool result",
+			name: "replaces image URL parts from tool result with placeholders",
 			messages: []chat.Message{
 				{
 					Role:    chat.MessageRoleTool,
@@ -1715,6 +1874,7 @@ func TestStripImageContent(t *testing.T) {
 					Content: "Read image file",
 					MultiContent: []chat.MessagePart{
 						{Type: chat.MessagePartTypeText, Text: "Read image file"},
+						{Type: chat.MessagePartTypeText, Text: "[image returned: png]"},
 					},
 				},
 			},
@@ -1779,6 +1939,7 @@ func TestStripImageContent(t *testing.T) {
 					Role: chat.MessageRoleTool,
 					MultiContent: []chat.MessagePart{
 						{Type: chat.MessagePartTypeText, Text: "tool output"},
+						{Type: chat.MessagePartTypeText, Text: "[image returned: jpeg]"},
 					},
 				},
 				{Role: chat.MessageRoleAssistant, Content: "got it"},
@@ -1795,6 +1956,58 @@ func TestStripImageContent(t *testing.T) {
 	}
 }
 
+func TestToolResultImages(t *testing.T) {
+	image := tools.MediaContent{Data: "bm90IGFuIGltYWdl", MimeType: "image/png"}
+	agentTools := []tools.Tool{{
+		Name:       "screenshot",
+		Parameters: map[string]any{},
+		Handler: func(context.Context, tools.ToolCall) (*tools.ToolCallResult, error) {
+			return &tools.ToolCallResult{
+				Output: "Took a screenshot",
+				Images: []tools.MediaContent{image},
+				Contents: []tools.ContentBlock{
+					{Type: tools.ContentBlockTypeText, Text: "Took a screenshot"},
+					{Type: tools.ContentBlockTypeImage, Image: &image},
+				},
+			}, nil
+		},
+	}}
+
+	prov := &mockProvider{id: "test/mock-model", stream: &mockStream{}}
+	root := agent.New("root", "You are a test agent", agent.WithModel(prov))
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Test"), session.WithToolsApproved(true))
+	calls := []tools.ToolCall{{
+		ID:       "call_1",
+		Type:     "function",
+		Function: tools.FunctionCall{Name: "screenshot", Arguments: "{}"},
+	}}
+
+	events := make(chan Event, 10)
+	rt.processToolCalls(t.Context(), sess, calls, agentTools, events)
+	close(events)
+
+	// The blocks are passed to the clients.
+	var toolResponse *ToolCallResponseEvent
+	for ev := range events {
+		if tr, ok := ev.(*ToolCallResponseEvent); ok {
+			toolResponse = tr
+		}
+	}
+	require.NotNil(t, toolResponse)
+	assert.Len(t, toolResponse.Result.Contents, 2)
+
+	// The model gets the image, with a placeholder for text-only tool results.
+	messages := sess.GetAllMessages()
+	msg := messages[len(messages)-1].Message
+	assert.Equal(t, "Took a screenshot\n[image returned: png]", msg.Content)
+	require.Len(t, msg.MultiContent, 2)
+	assert.Equal(t, "Took a screenshot", msg.MultiContent[0].Text)
+	assert.Equal(t, "data:image/png;base64,bm90IGFuIGltYWdl", msg.MultiContent[1].ImageURL.URL)
+}
+
 // TestResolveSessionAgent_PinnedAgent verifies that resolveSessionAgent returns
 // the session-pinned agent when AgentName is set, even though the runtime's
 // currentAgent points elsewhere (root). Before the fix, the shared currentAgent
@@ -1981,3 +2194,25 @@ func TestRunStream_EmptyMessages_SendUserMessage(t *testing.T) {
 	}
 	require.NotEmpty(t, events)
 }
+
+func TestTitleGenerator(t *testing.T) {
+	// The agent's model has no scripted turn: any title request made with it
+	// fails the test.
+	agentModel := fake.NewScriptedProvider(t, "test/agent")
+	root := agent.New("root", "You are a test agent", agent.WithModel(agentModel))
+	tm := team.New(team.WithAgents(root))
+
+	disabled, err := NewLocalRuntime(tm, WithModelStore(mockModelStore{}), WithTitleGeneration(false))
+	require.NoError(t, err)
+	assert.Nil(t, disabled.TitleGenerator())
+
+	titleModel := fake.NewScriptedProvider(t, "test/title", fake.NewTurn().Content("Cheap title"))
+	rt, err := NewLocalRuntime(tm, WithModelStore(mockModelStore{}), WithTitleModel(titleModel))
+	require.NoError(t, err)
+
+	gen := rt.TitleGenerator()
+	require.NotNil(t, gen)
+	title, err := gen.Generate(t.Context(), "sess-1", []string{"hello"})
+	require.NoError(t, err)
+	assert.Equal(t, "Cheap title", title)
+}
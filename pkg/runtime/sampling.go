@@ -0,0 +1,216 @@
+package runtime
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/model/provider"
+	"github.com/docker/docker-agent/pkg/model/provider/options"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// samplingConfirmationSchema asks the user, with an elicitation, whether an
+// MCP server may sample a model.
+var samplingConfirmationSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"allow": map[string]any{
+			"type":        "boolean",
+			"title":       "Allow",
+			"description": "Let the server use the model for this request",
+			"default":     true,
+		},
+	},
+	"required": []string{"allow"},
+}
+
+// toolsetSamplingHandler handles the sampling requests of the toolset ts
+// of agent a with the run of the session each one is for, see toolsetRun.
+// They're billed to the session of the innermost stream of the run.
+func (r *LocalRuntime) toolsetSamplingHandler(ts tools.ToolSet, a *agent.Agent) tools.SamplingHandler {
+	return func(ctx context.Context, req *tools.SamplingRequest) (*mcp.CreateMessageResult, error) {
+		run, err := r.toolsetRun(ctx, ts)
+		if err != nil {
+			return nil, err
+		}
+		sess := run.session()
+		if sess == nil {
+			return nil, errors.New("no session is running")
+		}
+		return r.samplingHandler(run, sess, a)(ctx, req)
+	}
+}
+
+// samplingHandler returns the handler of the sampling requests of the MCP
+// servers of a, on behalf of sess, which runs with run. The requests run
+// against the model of the agent, or the one of their toolset's policy, and
+// their cost is added to the session.
+func (r *LocalRuntime) samplingHandler(run *sessionRun, sess *session.Session, a *agent.Agent) tools.SamplingHandler {
+	return func(ctx context.Context, req *tools.SamplingRequest) (*mcp.CreateMessageResult, error) {
+		if req.Confirm {
+			if err := r.confirmSampling(ctx, run, req); err != nil {
+				return nil, err
+			}
+		}
+
+		model := r.agentModel(ctx, sess, a)
+		if req.Model != "" {
+			var err error
+			if model, err = r.resolveModelRef(ctx, req.Model); err != nil {
+				return nil, fmt.Errorf("resolving sampling model %q: %w", req.Model, err)
+			}
+		}
+
+		messages, err := samplingMessages(req.Params)
+		if err != nil {
+			return nil, err
+		}
+
+		content, usage, err := sample(ctx, model, messages, req.Params)
+		if err != nil {
+			return nil, fmt.Errorf("sampling %s: %w", model.ID(), err)
+		}
+
+		var cost float64
+		if m, err := r.modelsStore.GetModel(ctx, model.ID()); err == nil && m != nil && m.Cost != nil && usage != nil {
+			cost = (float64(usage.InputTokens)*m.Cost.Input +
+				float64(usage.OutputTokens)*m.Cost.Output +
+				float64(usage.CachedInputTokens)*m.Cost.CacheRead +
+				float64(usage.CacheWriteTokens)*m.Cost.CacheWrite) / 1e6
+		}
+		sess.AddCost(cost)
+
+		slog.Debug("MCP server sampled a model", "toolset", req.Toolset, "model", model.ID(), "cost", cost)
+
+		var contextLimit int64
+		if m, err := r.modelsStore.GetModel(ctx, r.getEffectiveModelID(a)); err == nil && m != nil {
+			contextLimit = int64(m.Limit.Context)
+		}
+		// The sampling requests come outside of the run loop.
+		run.emit(MCPSampling(sess.ID, req.Toolset, model.ID(), usage, cost, a.Name()))
+		run.emit(NewTokenUsageEvent(sess.ID, a.Name(), SessionUsage(sess, contextLimit)))
+
+		return &mcp.CreateMessageResult{
+			Content:    &mcp.TextContent{Text: content},
+			Model:      model.ID(),
+			Role:       "assistant",
+			StopReason: "endTurn",
+		}, nil
+	}
+}
+
+// confirmSampling asks the user whether the sampling request may run, over
+// the elicitation events of run, and returns an error if it may not.
+func (r *LocalRuntime) confirmSampling(ctx context.Context, run *sessionRun, req *tools.SamplingRequest) error {
+	message := fmt.Sprintf("The MCP server %s wants to use the model", req.Toolset)
+	if req.Params.MaxTokens > 0 {
+		message += fmt.Sprintf(" to generate up to %d tokens", req.Params.MaxTokens)
+	}
+	message += "."
+	if req.Params.SystemPrompt != "" {
+		message += "\n\nSystem prompt: " + req.Params.SystemPrompt
+	}
+
+	result, err := r.elicitationHandler(run)(ctx, &mcp.ElicitParams{
+		Message:         message,
+		RequestedSchema: samplingConfirmationSchema,
+		Meta: map[string]any{
+			"cagent/type":  "mcp_sampling",
+			"cagent/title": "Sampling request",
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("confirming sampling request: %w", err)
+	}
+	if allow, _ := result.Content["allow"].(bool); result.Action != tools.ElicitationActionAccept || !allow {
+		return errors.New("sampling request declined by the user")
+	}
+	return nil
+}
+
+// samplingMessages converts the messages of a sampling request into chat
+// messages, preceded by its system prompt.
+func samplingMessages(params *mcp.CreateMessageParams) ([]chat.Message, error) {
+	var messages []chat.Message
+	if params.SystemPrompt != "" {
+		messages = append(messages, chat.Message{Role: chat.MessageRoleSystem, Content: params.SystemPrompt})
+	}
+
+	for _, msg := range params.Messages {
+		if msg == nil {
+			continue
+		}
+		role := chat.MessageRoleUser
+		if msg.Role == "assistant" {
+			role = chat.MessageRoleAssistant
+		}
+
+		switch c := msg.Content.(type) {
+		case *mcp.TextContent:
+			messages = append(messages, chat.Message{Role: role, Content: c.Text})
+		case *mcp.ImageContent:
+			messages = append(messages, chat.Message{Role: role, MultiContent: []chat.MessagePart{{
+				Type:     chat.MessagePartTypeImageURL,
+				ImageURL: &chat.MessageImageURL{URL: "data:" + c.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(c.Data)},
+			}}})
+		default:
+			return nil, fmt.Errorf("unsupported sampling content %T", msg.Content)
+		}
+	}
+
+	if len(messages) == 0 {
+		return nil, errors.New("the sampling request has no messages")
+	}
+	return messages, nil
+}
+
+// sample runs a one-shot completion of messages, with the max tokens and
+// the temperature of the request, and returns its content and usage.
+func sample(ctx context.Context, model provider.Provider, messages []chat.Message, params *mcp.CreateMessageParams) (string, *chat.Usage, error) {
+	opts := []options.Opt{
+		options.WithStructuredOutput(nil),
+		options.WithNoThinking(),
+	}
+	if params.MaxTokens > 0 {
+		opts = append(opts, options.WithMaxTokens(params.MaxTokens))
+	}
+	if params.Temperature > 0 {
+		opts = append(opts, options.WithTemperature(params.Temperature))
+	}
+	model = provider.CloneWithOptions(ctx, model, opts...)
+
+	stream, err := model.CreateChatCompletionStream(ctx, messages, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	defer stream.Close()
+
+	var content strings.Builder
+	var usage *chat.Usage
+	for {
+		response, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		if response.Usage != nil {
+			usage = response.Usage
+		}
+		if len(response.Choices) > 0 {
+			content.WriteString(response.Choices[0].Delta.Content)
+		}
+	}
+	return content.String(), usage, nil
+}
@@ -0,0 +1,120 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/modelsdev"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+func newSamplingRuntime(t *testing.T, stream *mockStream) (*LocalRuntime, *agent.Agent) {
+	t.Helper()
+
+	root := agent.New("root", "You are a test agent", agent.WithModel(&mockProvider{id: "test/mock-model", stream: stream}))
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(pricedModelStore{costs: map[string]*modelsdev.Cost{
+		"test/mock-model": {Input: 1, Output: 2},
+	}}))
+	require.NoError(t, err)
+	return rt, root
+}
+
+func samplingRequest(confirm bool) *tools.SamplingRequest {
+	return &tools.SamplingRequest{
+		Toolset: "scraper",
+		Confirm: confirm,
+		Params: &mcp.CreateMessageParams{
+			SystemPrompt: "Summarize web pages.",
+			MaxTokens:    200,
+			Messages:     []*mcp.SamplingMessage{{Role: "user", Content: &mcp.TextContent{Text: "<html>...</html>"}}},
+		},
+	}
+}
+
+func TestSamplingHandler(t *testing.T) {
+	t.Parallel()
+
+	rt, root := newSamplingRuntime(t, newStreamBuilder().AddContent("A summary").AddStopWithUsage(1000, 100).Build())
+	events := make(chan Event, 10)
+	sess := session.New()
+	run := newSessionRun(sess.ID, root.Name())
+	run.swapEvents(events)
+
+	result, err := rt.samplingHandler(run, sess, root)(t.Context(), samplingRequest(false))
+	require.NoError(t, err)
+
+	text, ok := result.Content.(*mcp.TextContent)
+	require.True(t, ok)
+	assert.Equal(t, "A summary", text.Text)
+	assert.Equal(t, "test/mock-model", result.Model)
+
+	// The usage counts towards the cost of the session.
+	wantCost := (1000*1 + 100*2) / 1e6
+	assert.InDelta(t, wantCost, sess.TotalCost(), 1e-12)
+
+	sampling, ok := (<-events).(*MCPSamplingEvent)
+	require.True(t, ok)
+	assert.Equal(t, "scraper", sampling.Toolset)
+	assert.Equal(t, "test/mock-model", sampling.Model)
+	assert.InDelta(t, wantCost, sampling.Cost, 1e-12)
+
+	usage, ok := (<-events).(*TokenUsageEvent)
+	require.True(t, ok)
+	assert.InDelta(t, wantCost, usage.Usage.Cost, 1e-12)
+}
+
+func TestSamplingHandler_Declined(t *testing.T) {
+	t.Parallel()
+
+	rt, root := newSamplingRuntime(t, newStreamBuilder().AddContent("A summary").AddStopWithUsage(1000, 100).Build())
+	events := make(chan Event, 10)
+	sess := session.New()
+	run := newSessionRun(sess.ID, root.Name())
+	run.swapEvents(events)
+
+	go func() {
+		for event := range events {
+			if _, ok := event.(*ElicitationRequestEvent); ok {
+				run.elicitationRequestCh <- ElicitationResult{Action: tools.ElicitationActionDecline}
+				return
+			}
+		}
+	}()
+
+	_, err := rt.samplingHandler(run, sess, root)(t.Context(), samplingRequest(true))
+	require.ErrorContains(t, err, "declined")
+	assert.Zero(t, sess.TotalCost())
+}
+
+func TestSamplingMessages(t *testing.T) {
+	t.Parallel()
+
+	messages, err := samplingMessages(&mcp.CreateMessageParams{
+		SystemPrompt: "Be brief.",
+		Messages: []*mcp.SamplingMessage{
+			{Role: "user", Content: &mcp.TextContent{Text: "What's this?"}},
+			{Role: "user", Content: &mcp.ImageContent{Data: []byte("png"), MIMEType: "image/png"}},
+			{Role: "assistant", Content: &mcp.TextContent{Text: "A logo."}},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, messages, 4)
+
+	assert.Equal(t, chat.Message{Role: chat.MessageRoleSystem, Content: "Be brief."}, messages[0])
+	assert.Equal(t, chat.MessageRoleUser, messages[1].Role)
+	require.Len(t, messages[2].MultiContent, 1)
+	assert.Equal(t, "data:image/png;base64,cG5n", messages[2].MultiContent[0].ImageURL.URL)
+	assert.Equal(t, chat.Message{Role: chat.MessageRoleAssistant, Content: "A logo."}, messages[3])
+
+	_, err = samplingMessages(&mcp.CreateMessageParams{
+		Messages: []*mcp.SamplingMessage{{Role: "user", Content: &mcp.AudioContent{Data: []byte("wav"), MIMEType: "audio/wav"}}},
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,796 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/compaction"
+	"github.com/docker/docker-agent/pkg/model/provider/fake"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
+	"github.com/docker/docker-agent/pkg/tools/builtin"
+)
+
+// runScripted runs the session to completion, answering each tool call
+// confirmation with resume, and returns the events.
+func runScripted(t *testing.T, rt *LocalRuntime, sess *session.Session, resume ResumeRequest) []Event {
+	t.Helper()
+
+	var events []Event
+	for event := range rt.RunStream(t.Context(), sess) {
+		events = append(events, event)
+		if _, ok := event.(*ToolCallConfirmationEvent); ok {
+			resumeScripted(t, rt, sess, resume)
+		}
+	}
+	return events
+}
+
+// resumeScripted answers the pending confirmation of the run of sess with
+// resume.
+func resumeScripted(t *testing.T, rt *LocalRuntime, sess *session.Session, resume ResumeRequest) {
+	t.Helper()
+
+	require.NoError(t, rt.Resume(t.Context(), sess.ID, resume))
+}
+
+func newShellAgent(prov *fake.ScriptedProvider, executed *bool) *agent.Agent {
+	shell := []tools.Tool{{
+		Name:       "shell",
+		Parameters: map[string]any{},
+		Handler: func(_ context.Context, _ tools.ToolCall) (*tools.ToolCallResult, error) {
+			*executed = true
+			return tools.ResultSuccess("file1.txt file2.txt"), nil
+		},
+	}}
+	return agent.New("root", "You are a test agent",
+		agent.WithModel(prov),
+		agent.WithToolSets(newStubToolSet(nil, shell, nil)),
+	)
+}
+
+func TestScripted_ConfirmationApproved(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().
+			ToolCall("call_1", "shell", `{"cmd":`, `"ls"}`).
+			Usage(10, 5).
+			Expect(
+				fake.LastMessage(chat.MessageRoleUser, "List the files"),
+				fake.ToolNames("shell"),
+			),
+		fake.NewTurn().
+			Content("There are ", "two files.").
+			Usage(20, 5).
+			Expect(fake.LastMessage(chat.MessageRoleTool, "file1.txt file2.txt")),
+	)
+
+	var executed bool
+	tm := team.New(team.WithAgents(newShellAgent(prov, &executed)))
+	rt, err := NewLocalRuntime(tm, WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("List the files"))
+	events := runScripted(t, rt, sess, ResumeApprove())
+
+	assert.True(t, executed)
+	assert.True(t, hasEventType(t, events, &ToolCallConfirmationEvent{}))
+	assert.Equal(t, "There are two files.", sess.GetLastAssistantMessageContent())
+}
+
+func TestScripted_ConfirmationRejected(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().
+			ToolCall("call_1", "shell", `{"cmd":"rm -rf /"}`),
+		fake.NewTurn().
+			Content("Understood, I won't run it.").
+			Expect(fake.LastMessage(chat.MessageRoleTool, "The user rejected the tool call. Reason: too dangerous")),
+	)
+
+	var executed bool
+	tm := team.New(team.WithAgents(newShellAgent(prov, &executed)))
+	rt, err := NewLocalRuntime(tm, WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Clean up"))
+	runScripted(t, rt, sess, ResumeReject("too dangerous"))
+
+	assert.False(t, executed)
+	assert.Equal(t, "Understood, I won't run it.", sess.GetLastAssistantMessageContent())
+}
+
+func TestScripted_ConfirmationApprovedEdited(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().
+			ToolCall("call_1", "shell", `{"cmd":"rm -rf *"}`),
+		fake.NewTurn().
+			Content("Done.").
+			Expect(fake.LastMessage(chat.MessageRoleTool, "The user changed the arguments of the call to: {\"cmd\":\"rm -rf build\"}\n\nfile1.txt file2.txt")),
+	)
+
+	var received string
+	shell := []tools.Tool{{
+		Name:       "shell",
+		Parameters: map[string]any{},
+		Handler: func(_ context.Context, toolCall tools.ToolCall) (*tools.ToolCallResult, error) {
+			received = toolCall.Function.Arguments
+			return tools.ResultSuccess("file1.txt file2.txt"), nil
+		},
+	}}
+	root := agent.New("root", "You are a test agent",
+		agent.WithModel(prov),
+		agent.WithToolSets(newStubToolSet(nil, shell, nil)),
+	)
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Clean up"))
+	events := runScripted(t, rt, sess, ResumeApproveEdited(`{"cmd":"rm -rf build"}`))
+
+	assert.JSONEq(t, `{"cmd":"rm -rf build"}`, received)
+
+	want := &tools.ArgumentsEdit{Original: `{"cmd":"rm -rf *"}`, Edited: `{"cmd":"rm -rf build"}`}
+	var toolCalls []*ToolCallEvent
+	for _, event := range events {
+		if e, ok := event.(*ToolCallEvent); ok {
+			toolCalls = append(toolCalls, e)
+		}
+	}
+	require.Len(t, toolCalls, 1)
+	assert.Equal(t, want, toolCalls[0].ArgumentsEdit)
+	assert.Equal(t, want.Edited, toolCalls[0].ToolCall.Function.Arguments)
+
+	// Both arguments are recorded with the result of the call, the model's
+	// call keeps the original ones.
+	var toolMessage *chat.Message
+	for _, msg := range sess.GetAllMessages() {
+		if msg.Message.Role == chat.MessageRoleTool {
+			toolMessage = &msg.Message
+		}
+		if msg.Message.Role == chat.MessageRoleAssistant && len(msg.Message.ToolCalls) > 0 {
+			assert.Equal(t, want.Original, msg.Message.ToolCalls[0].Function.Arguments)
+		}
+	}
+	require.NotNil(t, toolMessage)
+	assert.Equal(t, want, toolMessage.ArgumentsEdit)
+}
+
+func TestScripted_ConfirmationInvalidEditRejected(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().
+			ToolCall("call_1", "read", `{"path":"*"}`),
+		fake.NewTurn().
+			Content("Done.").
+			Expect(fake.LastMessage(chat.MessageRoleTool, "The user changed the arguments of the call to: {\"path\":\"main.go\"}\n\ncontent")),
+	)
+
+	var received []string
+	read := []tools.Tool{{
+		Name: "read",
+		Parameters: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"path": map[string]any{"type": "string"}},
+			"required":   []any{"path"},
+		},
+		Handler: func(_ context.Context, toolCall tools.ToolCall) (*tools.ToolCallResult, error) {
+			received = append(received, toolCall.Function.Arguments)
+			return tools.ResultSuccess("content"), nil
+		},
+	}}
+	root := agent.New("root", "You are a test agent",
+		agent.WithModel(prov),
+		agent.WithToolSets(newStubToolSet(nil, read, nil)),
+	)
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	// The first edit misses the path, the second one isn't even JSON, the
+	// third one is valid.
+	answers := []ResumeRequest{
+		ResumeApproveEdited(`{"file":"main.go"}`),
+		ResumeApproveEdited(`{"path":`),
+		ResumeApproveEdited(`{"path":"main.go"}`),
+	}
+	sess := session.New(session.WithUserMessage("Read main.go"))
+
+	var confirmations int
+	var failures []*ToolCallValidationFailedEvent
+	for event := range rt.RunStream(t.Context(), sess) {
+		switch e := event.(type) {
+		case *ToolCallConfirmationEvent:
+			resumeScripted(t, rt, sess, answers[confirmations])
+			confirmations++
+		case *ToolCallValidationFailedEvent:
+			failures = append(failures, e)
+		}
+	}
+
+	// The invalid edits are reported back and the user is asked again.
+	assert.Equal(t, 3, confirmations)
+	require.Len(t, failures, 2)
+	require.NotNil(t, failures[0].ArgumentsEdit)
+	assert.Equal(t, `{"path":"*"}`, failures[0].ArgumentsEdit.Original)
+	assert.Equal(t, `{"file":"main.go"}`, failures[0].ArgumentsEdit.Edited)
+	assert.NotEmpty(t, failures[0].Violations)
+	require.NotNil(t, failures[1].ArgumentsEdit)
+	assert.Contains(t, failures[1].Violations[0], "not a valid JSON object")
+
+	// Only the valid edit is run.
+	assert.Equal(t, []string{`{"path":"main.go"}`}, received)
+}
+
+func TestScripted_ConfirmationPreview(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("Hello World\n"), 0o644))
+
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().
+			ToolCall("call_1", builtin.ToolNameEditFile, `{"path":"hello.txt","edits":[{"oldText":"World","newText":"Gophers"}]}`),
+		fake.NewTurn().
+			ToolCall("call_2", builtin.ToolNameEditFile, `{"path":"hello.txt","edits":[{"oldText":"World","newText":"Gophers"}]}`),
+		fake.NewTurn().
+			Content("Done.").
+			Expect(fake.LastMessage(chat.MessageRoleTool, "old text not found")),
+	)
+	root := agent.New("root", "You are a test agent",
+		agent.WithModel(prov),
+		agent.WithToolSets(builtin.NewFilesystemTool(dir)),
+	)
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Greet the gophers"))
+	events := runScripted(t, rt, sess, ResumeApprove())
+
+	var confirmations []*ToolCallConfirmationEvent
+	for _, event := range events {
+		if e, ok := event.(*ToolCallConfirmationEvent); ok {
+			confirmations = append(confirmations, e)
+		}
+	}
+	require.Len(t, confirmations, 2)
+	require.NotNil(t, confirmations[0].Preview)
+	assert.Equal(t, "--- hello.txt\n+++ hello.txt\n@@ -1 +1 @@\n-Hello World\n+Hello Gophers\n", confirmations[0].Preview.Diff)
+	// The second edit can't be previewed: it's still confirmed, with its
+	// arguments only.
+	assert.Nil(t, confirmations[1].Preview)
+}
+
+func TestScripted_MessageCompleted(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().
+			Reasoning("I should ", "list them.").
+			Content("Let me ", "check.").
+			ToolCall("call_1", "shell", `{"cmd":`, `"ls"}`).
+			Usage(10, 5),
+		fake.NewTurn().
+			Content("There are ", "two files.").
+			Usage(20, 5),
+	)
+
+	var executed bool
+	tm := team.New(team.WithAgents(newShellAgent(prov, &executed)))
+	rt, err := NewLocalRuntime(tm, WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("List the files"))
+	events := runScripted(t, rt, sess, ResumeApprove())
+
+	var completed []*AgentMessageCompletedEvent
+	for _, event := range events {
+		if e, ok := event.(*AgentMessageCompletedEvent); ok {
+			completed = append(completed, e)
+		}
+	}
+	require.Len(t, completed, 2)
+
+	first := completed[0]
+	assert.Equal(t, "root", first.AgentName)
+	assert.Equal(t, sess.ID, first.GetSessionID())
+	assert.Equal(t, "Let me check.", first.Content)
+	assert.Equal(t, "I should list them.", first.ReasoningContent)
+	require.Len(t, first.ToolCalls, 1)
+	assert.Equal(t, "shell", first.ToolCalls[0].Function.Name)
+	assert.JSONEq(t, `{"cmd":"ls"}`, first.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, chat.FinishReasonToolCalls, first.FinishReason)
+	assert.Equal(t, &chat.Usage{InputTokens: 10, OutputTokens: 5}, first.Usage)
+
+	second := completed[1]
+	assert.Equal(t, "There are two files.", second.Content)
+	assert.Empty(t, second.ToolCalls)
+	assert.Equal(t, chat.FinishReasonStop, second.FinishReason)
+	assert.Equal(t, &chat.Usage{InputTokens: 20, OutputTokens: 5}, second.Usage)
+}
+
+func TestScripted_TaskTransfer(t *testing.T) {
+	rootProv := fake.NewScriptedProvider(t, "test/root",
+		fake.NewTurn().
+			ToolCall("call_1", builtin.ToolNameTransferTask,
+				`{"agent":"librarian",`,
+				`"task":"find the chapter about sandworms",`,
+				`"expected_output":"a chapter"}`,
+			).
+			Expect(fake.ToolNames(builtin.ToolNameTransferTask)),
+		fake.NewTurn().
+			Content("The librarian found chapter 3.").
+			Expect(fake.LastMessage(chat.MessageRoleTool, "chapter 3")),
+	)
+	childProv := fake.NewScriptedProvider(t, "test/librarian",
+		fake.NewTurn().
+			Reasoning("Sandworms appear in ", "chapter 3.").
+			Content("chapter 3").
+			Expect(fake.HasMessage(chat.MessageRoleSystem, "find the chapter about sandworms")),
+	)
+
+	librarian := agent.New("librarian", "Library agent", agent.WithModel(childProv))
+	root := agent.New("root", "Root agent",
+		agent.WithModel(rootProv),
+		agent.WithToolSets(builtin.NewTransferTaskTool()),
+	)
+	agent.WithSubAgents(librarian)(root)
+
+	tm := team.New(team.WithAgents(root, librarian))
+	rt, err := NewLocalRuntime(tm, WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Which chapter is about sandworms?"), session.WithToolsApproved(true))
+	events := runScripted(t, rt, sess, ResumeApprove())
+
+	assert.True(t, hasEventType(t, events, &SubSessionCompletedEvent{}))
+	assert.Equal(t, "The librarian found chapter 3.", sess.GetLastAssistantMessageContent())
+	assert.Equal(t, "root", rt.currentAgent)
+}
+
+func TestScripted_Compaction(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		// The first answer fills the context, whose limit is 100 tokens...
+		fake.NewTurn().
+			Content("Hello there").
+			Usage(101, 0),
+		// ...so the next run starts by summarizing the session...
+		fake.NewTurn().
+			Content("The user greeted the agent.").
+			Usage(1, 1).
+			Expect(fake.LastMessage(chat.MessageRoleUser, compaction.UserPrompt)),
+		// ...and answers with the summary in context.
+		fake.NewTurn().
+			Content("Hello again").
+			Expect(fake.HasMessage(chat.MessageRoleUser, "Session Summary: The user greeted the agent.")),
+		// The summary is then regenerated with more instructions.
+		fake.NewTurn().
+			Content("The user greeted the agent twice.").
+			Expect(fake.LastMessage(chat.MessageRoleUser, compaction.UserPrompt+"\n\nCount the greetings")),
+	)
+
+	tm := team.New(team.WithAgents(agent.New("root", "You are a test agent", agent.WithModel(prov))))
+	rt, err := NewLocalRuntime(tm, WithSessionCompaction(true), WithModelStore(mockModelStoreWithLimit{limit: 100}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Hello"))
+	runScripted(t, rt, sess, ResumeApprove())
+
+	sess.AddMessage(session.UserMessage("Hello again"))
+	events := runScripted(t, rt, sess, ResumeApprove())
+
+	var summary *SessionSummaryEvent
+	var completed *SessionCompactionEvent
+	for _, event := range events {
+		switch e := event.(type) {
+		case *SessionSummaryEvent:
+			summary = e
+		case *SessionCompactionEvent:
+			if e.Status == "completed" {
+				completed = e
+			}
+		}
+	}
+	require.NotNil(t, summary, "expected a session summary")
+	assert.Equal(t, "The user greeted the agent.", summary.Summary)
+	assert.Equal(t, "Hello again", sess.GetLastAssistantMessageContent())
+
+	// The summary covers the three messages that preceded it.
+	require.NotNil(t, summary.Info)
+	assert.Equal(t, session.MessageRange{Start: 0, End: 3}, summary.Info.Covered)
+	require.NotNil(t, completed)
+	assert.Equal(t, &session.MessageRange{Start: 0, End: 3}, completed.Covered)
+	assert.Equal(t, 3, completed.CoveredMessages)
+
+	regenerated, err := rt.Resummarize(t.Context(), sess, ResummarizeRequest{Summary: -1, AdditionalPrompt: "Count the greetings"})
+	require.NoError(t, err)
+	assert.Equal(t, "The user greeted the agent twice.", regenerated.Summary)
+	assert.Equal(t, summary.Info.Covered, regenerated.Covered)
+
+	summaries := sess.Summaries()
+	require.Len(t, summaries, 1)
+	assert.Equal(t, regenerated, summaries[0])
+
+	_, err = rt.Resummarize(t.Context(), sess, ResummarizeRequest{Summary: 1})
+	require.Error(t, err)
+}
+
+func TestScripted_ToolOutputLimit(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().
+			ToolCall("call_1", "shell", `{"cmd":"ls"}`),
+		fake.NewTurn().
+			Content("Done.").
+			Expect(fake.LastMessage(chat.MessageRoleTool, "file1.txt \n\n[... 9 of 19 bytes truncated.")),
+	)
+
+	var executed bool
+	tm := team.New(team.WithAgents(newShellAgent(prov, &executed)))
+	rt, err := NewLocalRuntime(tm, WithSessionCompaction(false), WithModelStore(mockModelStore{}), WithToolOutputLimit(10, tools.TruncateHead))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("List the files"))
+	events := runScripted(t, rt, sess, ResumeApprove())
+
+	// The UI still gets the full output.
+	var response *ToolCallResponseEvent
+	for _, event := range events {
+		if e, ok := event.(*ToolCallResponseEvent); ok {
+			response = e
+		}
+	}
+	require.NotNil(t, response)
+	assert.Equal(t, "file1.txt file2.txt", response.Response)
+
+	var truncation *tools.OutputTruncation
+	for _, msg := range sess.GetAllMessages() {
+		if msg.Message.ToolCallID == "call_1" {
+			truncation = msg.Message.Truncation
+		}
+	}
+	assert.Equal(t, &tools.OutputTruncation{Policy: tools.TruncateHead, OriginalBytes: 19, KeptBytes: 10}, truncation)
+}
+
+func TestScripted_ToolTimeout(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().
+			ToolCall("call_1", "search", `{}`),
+		fake.NewTurn().
+			Content("The search is stuck.").
+			Expect(fake.LastMessage(chat.MessageRoleTool, "The tool call timed out after 50ms")),
+	)
+
+	// The handler ignores its context, like a call to a hung server.
+	search := []tools.Tool{{
+		Name:        "search",
+		Parameters:  map[string]any{},
+		Annotations: tools.ToolAnnotations{ReadOnlyHint: true},
+		Handler: func(context.Context, tools.ToolCall) (*tools.ToolCallResult, error) {
+			time.Sleep(time.Second)
+			return tools.ResultSuccess("too late"), nil
+		},
+	}}
+	root := agent.New("root", "You are a test agent", agent.WithModel(prov), agent.WithToolSets(newStubToolSet(nil, search, nil)))
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}), WithToolTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Search"))
+	start := time.Now()
+	events := runScripted(t, rt, sess, ResumeApprove())
+	assert.Less(t, time.Since(start), time.Second, "the run must not wait for the handler")
+
+	var timeout *ToolCallTimeoutEvent
+	for _, event := range events {
+		if e, ok := event.(*ToolCallTimeoutEvent); ok {
+			timeout = e
+		}
+	}
+	require.NotNil(t, timeout)
+	assert.Equal(t, "call_1", timeout.ToolCall.ID)
+	assert.Equal(t, 50*time.Millisecond, timeout.Timeout)
+	assert.Equal(t, "The search is stuck.", sess.GetLastAssistantMessageContent())
+}
+
+func TestToolTimeoutFor(t *testing.T) {
+	r := &LocalRuntime{toolTimeout: DefaultToolTimeout}
+
+	assert.Equal(t, DefaultToolTimeout, r.toolTimeoutFor(tools.Tool{}))
+	assert.Equal(t, 10*time.Second, r.toolTimeoutFor(tools.Tool{Timeout: 10 * time.Second}))
+	assert.Equal(t, tools.NoTimeout, r.toolTimeoutFor(tools.Tool{Timeout: tools.NoTimeout}))
+}
+
+func TestToolOutputLimitFor(t *testing.T) {
+	r := &LocalRuntime{toolOutputLimit: tools.OutputLimit{MaxBytes: DefaultToolOutputLimit, Policy: tools.TruncateHeadTail}}
+
+	assert.Equal(t, r.toolOutputLimit, r.toolOutputLimitFor(tools.Tool{}))
+	assert.Equal(t, tools.OutputLimit{MaxBytes: 1024, Policy: tools.TruncateHeadTail},
+		r.toolOutputLimitFor(tools.Tool{OutputLimit: &tools.OutputLimit{MaxBytes: 1024}}))
+	assert.Equal(t, tools.OutputLimit{MaxBytes: -1, Policy: tools.TruncateTail},
+		r.toolOutputLimitFor(tools.Tool{OutputLimit: &tools.OutputLimit{MaxBytes: -1, Policy: tools.TruncateTail}}))
+}
+
+func TestScripted_ResumesInterruptedSession(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().
+			Content("Done.").
+			Usage(10, 5).
+			Expect(fake.LastMessage(chat.MessageRoleUser, "Go on")),
+	)
+
+	var executed bool
+	tm := team.New(team.WithAgents(newShellAgent(prov, &executed)))
+	rt, err := NewLocalRuntime(tm, WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	// The previous run stopped before the tool call returned.
+	sess := session.New(session.WithUserMessage("List the files"))
+	sess.AddMessage(session.NewAgentMessage("root", &chat.Message{
+		Role:      chat.MessageRoleAssistant,
+		ToolCalls: []tools.ToolCall{{ID: "call_1", Type: "function", Function: tools.FunctionCall{Name: "shell", Arguments: `{"cmd":"ls"}`}}},
+	}))
+	sess.AddMessage(session.NewAgentMessage("root", &chat.Message{Role: chat.MessageRoleAssistant}))
+	sess.AddMessage(session.UserMessage("Go on"))
+
+	events := runScripted(t, rt, sess, ResumeApprove())
+
+	assert.False(t, executed)
+	assert.True(t, hasEventType(t, events, &WarningEvent{}))
+
+	requests := prov.Requests()
+	require.Len(t, requests, 1)
+	var roles []chat.MessageRole
+	for _, msg := range requests[0].Messages {
+		if msg.Role != chat.MessageRoleSystem {
+			roles = append(roles, msg.Role)
+		}
+	}
+	assert.Equal(t, []chat.MessageRole{chat.MessageRoleUser, chat.MessageRoleAssistant, chat.MessageRoleTool, chat.MessageRoleUser}, roles)
+
+	result := sess.GetAllMessages()[2].Message
+	assert.Equal(t, "call_1", result.ToolCallID)
+	assert.True(t, result.IsError)
+	assert.Equal(t, "Done.", sess.GetLastAssistantMessageContent())
+}
+
+// countingToolSet counts how many times it's started.
+type countingToolSet struct {
+	stubToolSet
+
+	starts int
+}
+
+func (c *countingToolSet) Start(context.Context) error {
+	c.starts++
+	return nil
+}
+
+func TestScripted_Continue(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().
+			ToolCall("call_1", "shell", `{"cmd":"ls"}`).
+			Expect(fake.LastMessage(chat.MessageRoleUser, "List the files")),
+		fake.NewTurn().
+			Content("There are two files."),
+		// The follow-up is sent with the whole conversation.
+		fake.NewTurn().
+			Content("file1.txt is the first one.").
+			Expect(
+				fake.HasMessage(chat.MessageRoleTool, "file1.txt file2.txt"),
+				fake.LastMessage(chat.MessageRoleUser, "Which one comes first?"),
+			),
+	)
+
+	toolSet := &countingToolSet{stubToolSet: stubToolSet{tools: []tools.Tool{{
+		Name:       "shell",
+		Parameters: map[string]any{},
+		Handler: func(context.Context, tools.ToolCall) (*tools.ToolCallResult, error) {
+			return tools.ResultSuccess("file1.txt file2.txt"), nil
+		},
+	}}}}
+	root := agent.New("root", "You are a test agent", agent.WithModel(prov), agent.WithToolSets(toolSet))
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(
+		session.WithUserMessage("List the files"),
+		session.WithToolsApproved(true),
+		session.WithSendUserMessage(true),
+	)
+	runScripted(t, rt, sess, ResumeApprove())
+	require.Equal(t, "There are two files.", sess.GetLastAssistantMessageContent())
+
+	var events []Event
+	for event := range rt.ContinueStream(t.Context(), sess, "Which one comes first?") {
+		events = append(events, event)
+	}
+
+	assert.True(t, hasEventType(t, events, &StreamStartedEvent{}))
+	assert.True(t, hasEventType(t, events, &UserMessageEvent{}))
+	assert.True(t, hasEventType(t, events, &StreamStoppedEvent{}))
+	assert.False(t, hasEventType(t, events, &ToolCallConfirmationEvent{}))
+	assert.Equal(t, 1, toolSet.starts, "toolsets must not be restarted")
+	assert.Equal(t, "file1.txt is the first one.", sess.GetLastAssistantMessageContent())
+}
+
+func TestScripted_ContinueAfterCompaction(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().
+			Content("Hello there"),
+		fake.NewTurn().
+			Content("The user greeted the agent.").
+			Expect(fake.LastMessage(chat.MessageRoleUser, compaction.UserPrompt)),
+		fake.NewTurn().
+			Content("Yes, still here.").
+			Expect(
+				fake.HasMessage(chat.MessageRoleUser, "Session Summary: The user greeted the agent."),
+				fake.LastMessage(chat.MessageRoleUser, "Are you still there?"),
+			),
+	)
+
+	tm := team.New(team.WithAgents(agent.New("root", "You are a test agent", agent.WithModel(prov))))
+	rt, err := NewLocalRuntime(tm, WithSessionCompaction(false), WithModelStore(mockModelStoreWithLimit{limit: 100000}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Hello"))
+	runScripted(t, rt, sess, ResumeApprove())
+
+	summaryEvents := make(chan Event, 16)
+	rt.Summarize(t.Context(), sess, "", summaryEvents)
+	close(summaryEvents)
+
+	messages, err := rt.Continue(t.Context(), sess, "Are you still there?")
+	require.NoError(t, err)
+	require.NotEmpty(t, messages)
+	assert.Equal(t, "Yes, still here.", messages[len(messages)-1].Message.Content)
+}
+
+func newCountingTools(names ...string) (ts []tools.Tool, calls map[string]int) {
+	calls = map[string]int{}
+	for _, name := range names {
+		ts = append(ts, tools.Tool{
+			Name:       name,
+			Parameters: map[string]any{},
+			Handler: func(context.Context, tools.ToolCall) (*tools.ToolCallResult, error) {
+				calls[name]++
+				return tools.ResultSuccess(name + " done"), nil
+			},
+		})
+	}
+	return ts, calls
+}
+
+func TestScripted_ApproveToolForTheSession(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().
+			ToolCall("call_1", "read", `{}`).
+			ToolCall("call_2", "write", `{}`).
+			ToolCall("call_3", "delete", `{}`),
+		fake.NewTurn().
+			ToolCall("call_4", "read", `{}`).
+			ToolCall("call_5", "write", `{}`),
+		fake.NewTurn().
+			Content("Done."),
+	)
+
+	toolList, calls := newCountingTools("read", "write", "delete")
+	root := agent.New("root", "You are a test agent",
+		agent.WithModel(prov),
+		agent.WithToolSets(newStubToolSet(nil, toolList, nil)),
+	)
+	store := session.NewInMemorySessionStore()
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)),
+		WithSessionCompaction(false),
+		WithModelStore(mockModelStore{}),
+		WithSessionStore(store),
+	)
+	require.NoError(t, err)
+
+	// Always allow read, allow write once and reject delete.
+	answers := map[string]ResumeRequest{
+		"read":   ResumeApproveTool("read"),
+		"write":  ResumeApprove(),
+		"delete": ResumeReject(""),
+	}
+	sess := session.New(session.WithUserMessage("Go"))
+	require.NoError(t, store.AddSession(t.Context(), sess))
+
+	var asked []string
+	for event := range rt.RunStream(t.Context(), sess) {
+		if confirmation, ok := event.(*ToolCallConfirmationEvent); ok {
+			name := confirmation.ToolCall.Function.Name
+			asked = append(asked, name)
+			assert.Equal(t, []ResumeType{ResumeTypeApprove, ResumeTypeApproveTool, ResumeTypeApproveSession, ResumeTypeApproveEdited, ResumeTypeReject}, confirmation.Options)
+			resumeScripted(t, rt, sess, answers[name])
+		}
+	}
+
+	// The second call to read isn't confirmed anymore, the second call to
+	// write still is.
+	assert.Equal(t, []string{"read", "write", "delete", "write"}, asked)
+	assert.Equal(t, map[string]int{"read": 2, "write": 2}, calls)
+	assert.Equal(t, []string{"read"}, sess.ApprovedTools())
+
+	// The approval is stored with the session.
+	stored, err := store.GetSession(t.Context(), sess.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read"}, stored.ApprovedTools())
+}
+
+func TestScripted_AskPatternCantBeApprovedForTheSession(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().
+			ToolCall("call_1", "shell", `{"cmd":"ls"}`),
+		fake.NewTurn().
+			ToolCall("call_2", "shell", `{"cmd":"ls"}`),
+		fake.NewTurn().
+			Content("Done."),
+	)
+
+	var executed bool
+	tm := team.New(team.WithAgents(newShellAgent(prov, &executed)))
+	rt, err := NewLocalRuntime(tm, WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(
+		session.WithUserMessage("List the files"),
+		session.WithPermissions(&session.PermissionsConfig{Ask: []string{"shell"}}),
+	)
+
+	var confirmations []*ToolCallConfirmationEvent
+	for event := range rt.RunStream(t.Context(), sess) {
+		if confirmation, ok := event.(*ToolCallConfirmationEvent); ok {
+			confirmations = append(confirmations, confirmation)
+			resumeScripted(t, rt, sess, ResumeApproveTool("shell"))
+		}
+	}
+
+	// Approving the tool for the session only approves the call: the tool
+	// is asked for again.
+	require.Len(t, confirmations, 2)
+	assert.False(t, confirmations[0].Offers(ResumeTypeApproveTool))
+	assert.True(t, confirmations[0].Offers(ResumeTypeApprove))
+	assert.True(t, executed)
+	assert.Empty(t, sess.ApprovedTools())
+}
+
+func TestScripted_MaxIterationsSystemReminder(t *testing.T) {
+	reminder := "Execution stopped after reaching the configured max_iterations limit (1)."
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().
+			ToolCall("call_1", "shell", `{"cmd":"ls"}`).
+			Expect(fake.LastMessage(chat.MessageRoleUser, "List the files")),
+		fake.NewTurn().
+			Content("There are two files.").
+			Expect(
+				fake.HasMessage(chat.MessageRoleUser, reminder),
+				fake.LastMessage(chat.MessageRoleUser, "Go on"),
+			),
+	)
+
+	var executed bool
+	tm := team.New(team.WithAgents(newShellAgent(prov, &executed)))
+	rt, err := NewLocalRuntime(tm, WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(
+		session.WithUserMessage("List the files"),
+		session.WithMaxIterations(1),
+		session.WithNonInteractive(true),
+		session.WithToolsApproved(true),
+	)
+	events := runScripted(t, rt, sess, ResumeApprove())
+
+	// The run stops with a reminder for the model, not a made-up answer.
+	assert.True(t, executed)
+	assert.True(t, hasEventType(t, events, &MaxIterationsReachedEvent{}))
+	last := sess.GetAllMessages()[len(sess.GetAllMessages())-1]
+	assert.True(t, last.IsSystemReminder())
+	assert.Equal(t, reminder, last.Message.Content)
+	assert.Empty(t, sess.GetLastAssistantMessageContent())
+
+	// The model gets it with the next user message.
+	sess.AddMessage(session.UserMessage("Go on"))
+	runScripted(t, rt, sess, ResumeApprove())
+	assert.Equal(t, "There are two files.", sess.GetLastAssistantMessageContent())
+}
@@ -1,9 +1,12 @@
 package runtime
 
 import (
+	"cmp"
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"slices"
 	"time"
 
 	"github.com/docker/docker-agent/pkg/agent"
@@ -24,77 +27,252 @@ const maxKeepTokens = 20_000
 
 // doCompact runs compaction on a session and applies the result (events,
 // persistence, token count updates). The agent is used to extract the
-// conversation from the session and to obtain the model for summarization.
+// conversation from the session. The summary is generated with the
+// compaction model of the runtime or the team, see WithCompaction, or else
+// with the agent's model, which is also used when the compaction model
+// fails.
 func (r *LocalRuntime) doCompact(ctx context.Context, sess *session.Session, a *agent.Agent, additionalPrompt string, events chan Event) {
 	slog.Debug("Generating summary for session", "session_id", sess.ID)
 	events <- SessionCompaction(sess.ID, "started", a.Name())
+	var added *session.SummaryItem
 	defer func() {
-		events <- SessionCompaction(sess.ID, "completed", a.Name())
+		events <- SessionCompactionCompleted(sess.ID, a.Name(), added)
 	}()
 
+	cfg := r.compactionConfig()
+
+	var result *compactionResult
+	var err error
+	if cfg.Model != nil {
+		result, err = r.compact(ctx, sess, cfg.Model, cfg, additionalPrompt)
+		if err != nil {
+			slog.Warn("Failed to generate session summary with the compaction model", "model", cfg.Model.ID(), "error", err)
+			events <- Warning(fmt.Sprintf("Compaction with %s failed: %v; using the model of agent %s", cfg.Model.ID(), err, a.Name()), a.Name())
+		}
+	}
+	if result == nil {
+		result, err = r.compact(ctx, sess, a.Model(), cfg, additionalPrompt)
+	}
+	if err != nil {
+		slog.Error("Failed to generate session summary", "error", err)
+		events <- ErrorWithCode(ErrorCodeSessionCompactionFailed, err.Error(), "")
+		return
+	}
+
+	if result.summary == "" {
+		return
+	}
+
+	// Update the session.
+	sess.InputTokens = result.tokens
+	sess.OutputTokens = 0
+	item := sess.AddSummary(session.Item{
+		Summary:        result.summary,
+		FirstKeptEntry: result.firstKeptEntry,
+		Cost:           result.cost,
+		SummaryInfo: &session.SummaryInfo{
+			CreatedAt: time.Now(),
+			Model:     result.model,
+			Tokens:    result.tokens,
+		},
+	})
+	added = &item
+	_ = r.sessionStore.UpdateSession(ctx, sess)
+
+	r.metrics.recordCompaction(ctx, a.Name(), result.cost)
+
+	slog.Debug("Generated session summary", "session_id", sess.ID, "model", result.model, "summary_length", len(result.summary), "covered_messages", item.Messages)
+	events <- SessionSummary(sess.ID, result.summary, a.Name(), result.firstKeptEntry, &item.SummaryInfo)
+}
+
+// compactionResult is a summary generated by compact.
+type compactionResult struct {
+	summary string
+	// firstKeptEntry is the index, in the session's messages, of the first
+	// message kept verbatim after the summary.
+	firstKeptEntry int
+	// model is the ID of the model that generated the summary.
+	model  string
+	tokens int64
+	cost   float64
+}
+
+// compact summarizes sess with model, following cfg.
+func (r *LocalRuntime) compact(ctx context.Context, sess *session.Session, model provider.Provider, cfg team.CompactionConfig, additionalPrompt string) (*compactionResult, error) {
 	// Build a model just for compaction.
-	summaryModel := provider.CloneWithOptions(ctx, a.Model(),
+	summaryModel := provider.CloneWithOptions(ctx, model,
 		options.WithStructuredOutput(nil),
 		options.WithMaxTokens(maxSummaryTokens),
 	)
 	m, err := r.modelsStore.GetModel(ctx, summaryModel.ID())
 	if err != nil {
-		slog.Error("Failed to generate session summary", "error", errors.New("failed to get model definition"))
-		events <- Error("Failed to get model definition")
-		return
+		return nil, errors.New("failed to get model definition")
 	}
 
-	compactionAgent := agent.New("root", compaction.SystemPrompt, agent.WithModel(summaryModel))
+	compactionAgent := agent.New("root", cmp.Or(cfg.Prompt, compaction.SystemPrompt), agent.WithModel(summaryModel))
 
 	// Compute the messages to compact, keeping recent messages aside.
-	messages, firstKeptEntry := extractMessagesToCompact(sess, compactionAgent, int64(m.Limit.Context), additionalPrompt)
+	messages, firstKeptEntry := extractMessagesToCompact(sess, compactionAgent, int64(m.Limit.Context), additionalPrompt, cfg.KeepLastMessages)
 
 	// Run the compaction.
+	compactionSession, err := r.summarize(ctx, compactionAgent, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	return &compactionResult{
+		summary:        compactionSession.GetLastAssistantMessageContent(),
+		firstKeptEntry: firstKeptEntry,
+		model:          summaryModel.ID(),
+		tokens:         compactionSession.OutputTokens,
+		cost:           compactionSession.TotalCost(),
+	}, nil
+}
+
+// summarize runs the compaction agent on messages, prepared by
+// compactionMessages, and returns its session: the summary is its last
+// message.
+func (r *LocalRuntime) summarize(ctx context.Context, compactionAgent *agent.Agent, messages []chat.Message) (*session.Session, error) {
 	compactionSession := session.New(
 		session.WithTitle("Generating summary"),
 		session.WithMessages(toItems(messages)),
 	)
 
 	t := team.New(team.WithAgents(compactionAgent))
-	rt, err := New(t, WithSessionCompaction(false))
+	rt, err := New(t, WithSessionCompaction(false), WithMeterProvider(r.meterProvider), WithModelStore(r.modelsStore))
 	if err != nil {
-		slog.Error("Failed to generate session summary", "error", err)
-		events <- Error(err.Error())
-		return
+		return nil, err
 	}
-	if _, err = rt.Run(ctx, compactionSession); err != nil {
-		slog.Error("Failed to generate session summary", "error", err)
-		events <- Error(err.Error())
-		return
+	if _, err := rt.Run(ctx, compactionSession); err != nil {
+		return nil, err
+	}
+	return compactionSession, nil
+}
+
+// ResummarizeRequest selects the summary Resummarize regenerates, and how.
+type ResummarizeRequest struct {
+	// Summary is the index of the summary in the session's Summaries.
+	// Negative indexes count from the end: -1 is the last summary.
+	Summary int
+	// Model generates the summary: the name of a model of the configuration
+	// or a "provider/model" reference. The compaction model of the runtime
+	// or the team, or else the model of the session's agent, is used when
+	// it's empty.
+	Model string
+	// AdditionalPrompt is added to the instructions of the summarization,
+	// as with Summarize.
+	AdditionalPrompt string
+}
+
+// Resummarize regenerates a summary of sess, e.g. one that left out
+// something that matters, from the messages it covers and the summary
+// before it. The new summary replaces the old one in place and is
+// persisted: the summaries that follow, built on the old one, are left
+// unchanged.
+//
+// The messages must be in the session: the summaries of a condensed
+// session (see session.Store.GetCondensedSession) can't be regenerated.
+func (r *LocalRuntime) Resummarize(ctx context.Context, sess *session.Session, req ResummarizeRequest) (session.SummaryItem, error) {
+	summaries := sess.Summaries()
+	index := req.Summary
+	if index < 0 {
+		index += len(summaries)
+	}
+	if index < 0 || index >= len(summaries) {
+		return session.SummaryItem{}, fmt.Errorf("no summary %d: the session has %d summaries", req.Summary, len(summaries))
+	}
+	old := summaries[index]
+	if old.Covered.Len() == 0 {
+		return session.SummaryItem{}, errors.New("the messages covered by the summary aren't loaded")
 	}
 
+	cfg := r.compactionConfig()
+	model := cfg.Model
+	if model == nil {
+		model = r.resolveSessionAgent(sess).Model()
+	}
+	if req.Model != "" {
+		var err error
+		if model, err = r.resolveModelRef(ctx, req.Model); err != nil {
+			return session.SummaryItem{}, err
+		}
+	}
+	summaryModel := provider.CloneWithOptions(ctx, model,
+		options.WithStructuredOutput(nil),
+		options.WithMaxTokens(maxSummaryTokens),
+	)
+	m, err := r.modelsStore.GetModel(ctx, summaryModel.ID())
+	if err != nil {
+		return session.SummaryItem{}, fmt.Errorf("getting the definition of model %s: %w", summaryModel.ID(), err)
+	}
+
+	// The session as it was up to the first message kept verbatim: the
+	// previous summary and the messages the summary covers.
+	covered := session.New(session.WithMessages(slices.Clone(sess.Messages[:old.Covered.End])))
+	compactionAgent := agent.New("root", cmp.Or(cfg.Prompt, compaction.SystemPrompt), agent.WithModel(summaryModel))
+	messages := compactionMessages(conversationMessages(covered, compactionAgent), compactionAgent.Instruction(), int64(m.Limit.Context), req.AdditionalPrompt)
+
+	compactionSession, err := r.summarize(ctx, compactionAgent, messages)
+	if err != nil {
+		return session.SummaryItem{}, err
+	}
 	summary := compactionSession.GetLastAssistantMessageContent()
 	if summary == "" {
-		return
+		return session.SummaryItem{}, errors.New("the model generated an empty summary")
 	}
 
-	// Update the session.
-	sess.InputTokens = compactionSession.OutputTokens
-	sess.OutputTokens = 0
-	sess.Messages = append(sess.Messages, session.Item{
-		Summary:        summary,
-		FirstKeptEntry: firstKeptEntry,
-		Cost:           compactionSession.TotalCost(),
+	item, err := sess.ReplaceSummary(old.Position, session.Item{
+		Summary: summary,
+		Cost:    compactionSession.TotalCost(),
+		SummaryInfo: &session.SummaryInfo{
+			CreatedAt: time.Now(),
+			Model:     summaryModel.ID(),
+			Tokens:    compactionSession.OutputTokens,
+		},
 	})
-	_ = r.sessionStore.UpdateSession(ctx, sess)
+	if err != nil {
+		return session.SummaryItem{}, err
+	}
+	if err := r.sessionStore.UpdateSummary(ctx, sess.ID, index, session.Item{Summary: item.Summary, SummaryInfo: &item.SummaryInfo}); err != nil {
+		slog.Warn("Failed to persist regenerated summary", "session_id", sess.ID, "error", err)
+	}
 
-	slog.Debug("Generated session summary", "session_id", sess.ID, "summary_length", len(summary))
-	events <- SessionSummary(sess.ID, summary, a.Name(), firstKeptEntry)
+	slog.Debug("Regenerated session summary", "session_id", sess.ID, "summary", index, "model", item.Model)
+	return item, nil
 }
 
 // extractMessagesToCompact returns the messages to send to the compaction model
 // and the index (into sess.Messages) of the first message that was kept aside.
-// Recent messages (up to maxKeepTokens) are excluded from compaction so they
-// can be preserved verbatim in the session after summarization.
-func extractMessagesToCompact(sess *session.Session, compactionAgent *agent.Agent, contextLimit int64, additionalPrompt string) ([]chat.Message, int) {
-	// Add all the existing messages.
+// Recent messages (the last keepLastMessages, or up to maxKeepTokens when
+// zero) are excluded from compaction so they can be preserved verbatim in the
+// session after summarization. The instructions of the compaction agent are
+// the ones of the summarization.
+func extractMessagesToCompact(sess *session.Session, compactionAgent *agent.Agent, contextLimit int64, additionalPrompt string, keepLastMessages int) ([]chat.Message, int) {
+	messages := conversationMessages(sess, compactionAgent)
+
+	// Split: keep the last messages aside so the LLM retains recent context
+	// after compaction.
+	var splitIdx int
+	if keepLastMessages > 0 {
+		splitIdx = splitIndexForKeepCount(messages, keepLastMessages)
+	} else {
+		splitIdx = splitIndexForKeep(messages, maxKeepTokens)
+	}
+	messagesToCompact := messages[:splitIdx]
+	// Compute firstKeptEntry: index into sess.Messages of the first kept message.
+	// The kept messages start at splitIdx in the non-system filtered list. We
+	// need to map this back to the original sess.Messages index.
+	firstKeptEntry := mapToSessionIndex(sess, splitIdx)
+
+	return compactionMessages(messagesToCompact, compactionAgent.Instruction(), contextLimit, additionalPrompt), firstKeptEntry
+}
+
+// conversationMessages returns the conversation of sess to summarize.
+func conversationMessages(sess *session.Session, compactionAgent *agent.Agent) []chat.Message {
+	// Add all the existing messages, but the system reminders: they were
+	// meant for the model at the time, not part of the conversation.
 	var messages []chat.Message
-	for _, msg := range sess.GetMessages(compactionAgent) {
+	for _, msg := range sess.GetMessages(compactionAgent, session.WithoutSystemReminders()) {
 		if msg.Role == chat.MessageRoleSystem {
 			continue
 		}
@@ -104,22 +282,17 @@ func extractMessagesToCompact(sess *session.Session, compactionAgent *agent.Agen
 
 		messages = append(messages, msg)
 	}
+	return messages
+}
 
-	// Split: keep the last N tokens of messages aside so the LLM retains
-	// recent context after compaction.
-	splitIdx := splitIndexForKeep(messages, maxKeepTokens)
-	messagesToCompact := messages[:splitIdx]
-	// Compute firstKeptEntry: index into sess.Messages of the first kept message.
-	// The kept messages start at splitIdx in the non-system filtered list. We
-	// need to map this back to the original sess.Messages index.
-	firstKeptEntry := mapToSessionIndex(sess, splitIdx)
-
-	messages = messagesToCompact
-
+// compactionMessages returns the messages to send to the compaction model to
+// summarize messages: the instructions, systemPrompt, then the most recent of
+// messages that fit in the context limit, then the request for the summary.
+func compactionMessages(messages []chat.Message, systemPrompt string, contextLimit int64, additionalPrompt string) []chat.Message {
 	// Prepare the first (system) message.
 	systemPromptMessage := chat.Message{
 		Role:      chat.MessageRoleSystem,
-		Content:   compaction.SystemPrompt,
+		Content:   systemPrompt,
 		CreatedAt: time.Now().Format(time.RFC3339),
 	}
 	systemPromptMessageLen := compaction.EstimateMessageTokens(&systemPromptMessage)
@@ -152,7 +325,7 @@ func extractMessagesToCompact(sess *session.Session, compactionAgent *agent.Agen
 	// Append the last (user) message.
 	messages = append(messages, userPromptMessage)
 
-	return messages, firstKeptEntry
+	return messages
 }
 
 // splitIndexForKeep returns the index that splits messages into [0:idx] (to
@@ -180,13 +353,33 @@ func splitIndexForKeep(messages []chat.Message, maxTokens int64) int {
 	return len(messages)
 }
 
+// splitIndexForKeepCount returns the index that splits messages into [0:idx]
+// (to compact) and [idx:] (to keep), keeping at least the last count
+// messages: the split is moved back to a user/assistant boundary, so that
+// tool results stay with their calls. When that leaves nothing to compact,
+// everything is compacted.
+func splitIndexForKeepCount(messages []chat.Message, count int) int {
+	idx := len(messages) - count
+	for idx > 0 {
+		role := messages[idx].Role
+		if role == chat.MessageRoleUser || role == chat.MessageRoleAssistant {
+			break
+		}
+		idx--
+	}
+	if idx <= 0 {
+		return len(messages)
+	}
+	return idx
+}
+
 // mapToSessionIndex maps an index in the non-system-filtered message list back
 // to the corresponding index in sess.Messages. It counts only message items
-// that are not system messages.
+// that are neither system messages nor system reminders.
 func mapToSessionIndex(sess *session.Session, filteredIdx int) int {
 	count := 0
 	for i, item := range sess.Messages {
-		if item.IsMessage() && item.Message.Message.Role != chat.MessageRoleSystem {
+		if item.IsMessage() && item.Message.Message.Role != chat.MessageRoleSystem && !item.Message.IsSystemReminder() {
 			if count == filteredIdx {
 				return i
 			}
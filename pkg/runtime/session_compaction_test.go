@@ -1,6 +1,7 @@
 package runtime
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
@@ -10,7 +11,12 @@ import (
 	"github.com/docker/docker-agent/pkg/agent"
 	"github.com/docker/docker-agent/pkg/chat"
 	"github.com/docker/docker-agent/pkg/compaction"
+	"github.com/docker/docker-agent/pkg/model/provider/fake"
+	"github.com/docker/docker-agent/pkg/modelerrors"
+	"github.com/docker/docker-agent/pkg/modelsdev"
 	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
 )
 
 func TestExtractMessagesToCompact(t *testing.T) {
@@ -97,8 +103,8 @@ func TestExtractMessagesToCompact(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			sess := session.New(session.WithMessages(tt.messages))
 
-			a := agent.New("test", "test prompt")
-			result, _ := extractMessagesToCompact(sess, a, tt.contextLimit, tt.additionalPrompt)
+			a := agent.New("test", compaction.SystemPrompt)
+			result, _ := extractMessagesToCompact(sess, a, tt.contextLimit, tt.additionalPrompt, 0)
 
 			assert.GreaterOrEqual(t, len(result), tt.wantConversationMsgCount+2)
 			assert.Equal(t, chat.MessageRoleSystem, result[0].Role)
@@ -173,6 +179,28 @@ func TestSplitIndexForKeep(t *testing.T) {
 	}
 }
 
+func TestSplitIndexForKeepCount(t *testing.T) {
+	msg := func(role chat.MessageRole) chat.Message {
+		return chat.Message{Role: role, Content: "content"}
+	}
+	messages := []chat.Message{
+		msg(chat.MessageRoleUser),
+		msg(chat.MessageRoleAssistant),
+		msg(chat.MessageRoleTool),
+		msg(chat.MessageRoleAssistant),
+		msg(chat.MessageRoleUser),
+	}
+
+	assert.Equal(t, 4, splitIndexForKeepCount(messages, 1))
+	assert.Equal(t, 3, splitIndexForKeepCount(messages, 2))
+	// The tool result stays with its call.
+	assert.Equal(t, 1, splitIndexForKeepCount(messages, 3))
+	// Keeping all the messages leaves nothing to compact: everything is.
+	assert.Equal(t, 5, splitIndexForKeepCount(messages, 5))
+	assert.Equal(t, 5, splitIndexForKeepCount(messages, 10))
+	assert.Equal(t, 0, splitIndexForKeepCount(nil, 2))
+}
+
 func TestExtractMessagesToCompact_KeepsRecentMessages(t *testing.T) {
 	// Create a session with many messages, some large enough that the last
 	// ~20k tokens are kept aside.
@@ -194,7 +222,7 @@ func TestExtractMessagesToCompact_KeepsRecentMessages(t *testing.T) {
 	sess := session.New(session.WithMessages(items))
 	a := agent.New("test", "test prompt")
 
-	result, firstKeptEntry := extractMessagesToCompact(sess, a, 200_000, "")
+	result, firstKeptEntry := extractMessagesToCompact(sess, a, 200_000, "", 0)
 
 	// The kept messages should not appear in the compaction result
 	// (only system + compacted messages + user prompt).
@@ -289,3 +317,143 @@ func TestSessionGetMessages_SummaryWithoutFirstKeptEntry(t *testing.T) {
 	assert.Contains(t, conversationMessages[0].Content, "Session Summary:")
 	assert.Equal(t, "m3", conversationMessages[1].Content)
 }
+
+func TestMessagesTokenBudget(t *testing.T) {
+	t.Parallel()
+
+	// The output reserve is capped at a quarter of the context window.
+	assert.Equal(t, int64(96_000), messagesTokenBudget(128_000, 32_000, nil))
+	assert.Equal(t, int64(120_000), messagesTokenBudget(128_000, 8_000, nil))
+	// Without an output limit, an eighth of the window is reserved.
+	assert.Equal(t, int64(7_000), messagesTokenBudget(8_000, 0, nil))
+
+	tool := tools.Tool{Name: "read_file", Description: strings.Repeat("x", 4000)}
+	withTools := messagesTokenBudget(128_000, 8_000, []tools.Tool{tool})
+	assert.Less(t, withTools, int64(120_000-1000))
+	assert.Zero(t, messagesTokenBudget(1_000, 0, []tools.Tool{tool}))
+}
+
+// conversation returns a session with two exchanges between the user and
+// the agent.
+func conversation() *session.Session {
+	return session.New(session.WithMessages([]session.Item{
+		session.NewMessageItem(session.UserMessage("Hello")),
+		session.NewMessageItem(&session.Message{Message: chat.Message{Role: chat.MessageRoleAssistant, Content: "Hi"}}),
+		session.NewMessageItem(session.UserMessage("How are you?")),
+		session.NewMessageItem(&session.Message{Message: chat.Message{Role: chat.MessageRoleAssistant, Content: "Fine"}}),
+	}))
+}
+
+// summarizeEvents runs Summarize on sess and returns its events.
+func summarizeEvents(t *testing.T, rt *LocalRuntime, sess *session.Session) []Event {
+	t.Helper()
+
+	events := make(chan Event, 32)
+	rt.Summarize(t.Context(), sess, "", events)
+	close(events)
+
+	var all []Event
+	for event := range events {
+		all = append(all, event)
+	}
+	return all
+}
+
+func TestSummarize_CompactionModel(t *testing.T) {
+	// The agent's model isn't asked for the summary.
+	agentProv := fake.NewScriptedProvider(t, "test/agent")
+	compactionProv := fake.NewScriptedProvider(t, "test/compaction",
+		fake.NewTurn().
+			Content("The user greeted the agent.").
+			Usage(1000, 100).
+			Expect(
+				fake.HasMessage(chat.MessageRoleSystem, "Summarize for the release notes."),
+				fake.LastMessage(chat.MessageRoleUser, compaction.UserPrompt),
+			),
+	)
+
+	root := agent.New("root", "You are a test agent", agent.WithModel(agentProv))
+	tm := team.New(team.WithAgents(root), team.WithCompaction(team.CompactionConfig{
+		Model:            compactionProv,
+		Prompt:           "Summarize for the release notes.",
+		KeepLastMessages: 2,
+	}))
+	rt, err := NewLocalRuntime(tm, WithSessionCompaction(false), WithModelStore(pricedModelStore{
+		costs: map[string]*modelsdev.Cost{"test/compaction": {Input: 1, Output: 10}},
+		limit: 100_000,
+	}))
+	require.NoError(t, err)
+
+	sess := conversation()
+	events := summarizeEvents(t, rt, sess)
+
+	// Only the first exchange is summarized, the last two messages are kept.
+	requests := compactionProv.Requests()
+	require.Len(t, requests, 1)
+	var contents []string
+	for _, msg := range requests[0].Messages {
+		contents = append(contents, msg.Content)
+	}
+	assert.Contains(t, contents, "Hello")
+	assert.NotContains(t, contents, "How are you?")
+
+	summaries := sess.Summaries()
+	require.Len(t, summaries, 1)
+	assert.Equal(t, "The user greeted the agent.", summaries[0].Summary)
+	assert.Equal(t, "test/compaction", summaries[0].Model)
+	assert.Equal(t, session.MessageRange{Start: 0, End: 2}, summaries[0].Covered)
+
+	// The cost of the summary is attributed to the compaction.
+	const cost = (1000*1 + 100*10) / 1e6
+	assert.InDelta(t, cost, sess.CompactionCost(), 1e-12)
+	assert.InDelta(t, cost, rt.Stats().CompactionCost, 1e-12)
+
+	var usage *TokenUsageEvent
+	for _, event := range events {
+		if e, ok := event.(*TokenUsageEvent); ok {
+			usage = e
+		}
+	}
+	require.NotNil(t, usage)
+	assert.InDelta(t, cost, usage.Usage.CompactionCost, 1e-12)
+}
+
+func TestSummarize_CompactionModelFallback(t *testing.T) {
+	agentProv := fake.NewScriptedProvider(t, "test/agent",
+		fake.NewTurn().
+			Content("The user greeted the agent twice.").
+			Expect(fake.HasMessage(chat.MessageRoleSystem, compaction.SystemPrompt)),
+	)
+	compactionProv := fake.NewScriptedProvider(t, "test/compaction",
+		fake.NewTurn().Error(&modelerrors.StatusError{StatusCode: 400, Err: errors.New("bad request")}),
+	)
+
+	root := agent.New("root", "You are a test agent", agent.WithModel(agentProv))
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)),
+		WithSessionCompaction(false),
+		WithModelStore(mockModelStoreWithLimit{limit: 100_000}),
+		WithCompaction(team.CompactionConfig{Model: compactionProv}),
+	)
+	require.NoError(t, err)
+
+	sess := conversation()
+	events := summarizeEvents(t, rt, sess)
+
+	var warning *WarningEvent
+	for _, event := range events {
+		switch e := event.(type) {
+		case *WarningEvent:
+			warning = e
+		case *ErrorEvent:
+			t.Errorf("unexpected error: %s", e.Error)
+		}
+	}
+	require.NotNil(t, warning)
+	assert.Contains(t, warning.Message, "Compaction with test/compaction failed")
+	assert.Contains(t, warning.Message, "using the model of agent root")
+
+	summaries := sess.Summaries()
+	require.Len(t, summaries, 1)
+	assert.Equal(t, "The user greeted the agent twice.", summaries[0].Summary)
+	assert.Equal(t, "test/agent", summaries[0].Model)
+}
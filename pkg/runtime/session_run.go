@@ -0,0 +1,350 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// sessionRun is the state of the run of a session: the agent the session
+// is at, which handoffs move, and the channels its confirmations and
+// elicitations are answered on. RunStream creates one per session, so that
+// a runtime can run several sessions concurrently.
+//
+// The sub-sessions share the run of the session they're started from: the
+// client answers their confirmations for the session it started.
+type sessionRun struct {
+	// sessionID is the ID of the session the run was created for.
+	sessionID string
+
+	agentMu sync.RWMutex
+	agent   string
+
+	// resumeChan and elicitationRequestCh hold an answer each, so that the
+	// answers sent before the loop waits for them aren't lost. The ones
+	// sent while nothing was asked are discarded before the next question,
+	// see discardResume and discardElicitationResult.
+	resumeChan           chan ResumeRequest
+	elicitationRequestCh chan ElicitationResult
+
+	// events is the events channel of the innermost stream of the run, the
+	// elicitation requests are sent to it. sess is the session of that
+	// stream, the sampling requests are billed to it.
+	eventsMu sync.RWMutex
+	events   chan Event
+	sess     *session.Session
+
+	// elicitationSchema is the schema of the pending elicitation request,
+	// used to validate the responses.
+	elicitationSchemaMu sync.Mutex
+	elicitationSchema   any
+
+	// sources are the sources retrieved by the RAG tools of each session
+	// during the run, which its answers are annotated with.
+	sourcesMu sync.Mutex
+	sources   map[string][]chat.Annotation
+
+	// activity is what the tools of each session did during its current
+	// turn, which the turn end hooks receive.
+	activityMu sync.Mutex
+	activity   map[string]turnActivity
+}
+
+// turnActivity is the tools used and the files changed during a turn, in
+// the order they were first used or changed.
+type turnActivity struct {
+	toolsUsed    []string
+	filesChanged []string
+}
+
+func newSessionRun(sessionID, agentName string) *sessionRun {
+	return &sessionRun{
+		sessionID:            sessionID,
+		agent:                agentName,
+		resumeChan:           make(chan ResumeRequest, 1),
+		elicitationRequestCh: make(chan ElicitationResult, 1),
+	}
+}
+
+// recordToolCall records a call to the tool named name, which made changes,
+// in the activity of the current turn of the session sessionID.
+func (run *sessionRun) recordToolCall(sessionID, name string, changes []tools.FileChange) {
+	run.activityMu.Lock()
+	defer run.activityMu.Unlock()
+
+	if run.activity == nil {
+		run.activity = make(map[string]turnActivity)
+	}
+	activity := run.activity[sessionID]
+	if !slices.Contains(activity.toolsUsed, name) {
+		activity.toolsUsed = append(activity.toolsUsed, name)
+	}
+	for _, change := range changes {
+		if !slices.Contains(activity.filesChanged, change.Path) {
+			activity.filesChanged = append(activity.filesChanged, change.Path)
+		}
+	}
+	run.activity[sessionID] = activity
+}
+
+// takeTurnActivity returns the activity of the current turn of the session
+// sessionID, and starts a new turn.
+func (run *sessionRun) takeTurnActivity(sessionID string) turnActivity {
+	run.activityMu.Lock()
+	defer run.activityMu.Unlock()
+
+	activity := run.activity[sessionID]
+	delete(run.activity, sessionID)
+	return activity
+}
+
+// discardResume drops the answer sent while no confirmation was pending.
+// It must be called before the confirmation request is sent, so that the
+// client can't answer it yet.
+func (run *sessionRun) discardResume() {
+	select {
+	case req := <-run.resumeChan:
+		slog.Debug("Discarding stale resume signal", "session_id", run.sessionID, "type", req.Type)
+	default:
+	}
+}
+
+// discardElicitationResult drops the answer sent while no elicitation was
+// pending, see discardResume.
+func (run *sessionRun) discardElicitationResult() {
+	select {
+	case result := <-run.elicitationRequestCh:
+		slog.Debug("Discarding stale elicitation response", "session_id", run.sessionID, "action", result.Action)
+	default:
+	}
+}
+
+func (run *sessionRun) agentName() string {
+	run.agentMu.RLock()
+	defer run.agentMu.RUnlock()
+	return run.agent
+}
+
+func (run *sessionRun) setAgent(name string) {
+	run.agentMu.Lock()
+	defer run.agentMu.Unlock()
+	run.agent = name
+}
+
+// swapEvents replaces the events channel of the run and returns the
+// previous one. Each RunStream call swaps in its own channel on entry and
+// swaps the previous one back on exit, so nested streams (sub-sessions,
+// background agents) don't lose the parent's channel.
+func (run *sessionRun) swapEvents(ch chan Event) chan Event {
+	run.eventsMu.Lock()
+	defer run.eventsMu.Unlock()
+	prev := run.events
+	run.events = ch
+	return prev
+}
+
+// swapSession replaces the session of the innermost stream of the run and
+// returns the previous one, see swapEvents.
+func (run *sessionRun) swapSession(sess *session.Session) *session.Session {
+	run.eventsMu.Lock()
+	defer run.eventsMu.Unlock()
+	prev := run.sess
+	run.sess = sess
+	return prev
+}
+
+// session returns the session of the innermost stream of the run.
+func (run *sessionRun) session() *session.Session {
+	run.eventsMu.RLock()
+	defer run.eventsMu.RUnlock()
+	return run.sess
+}
+
+// emit sends event to the events of the innermost stream of the run, if
+// it's still running.
+func (run *sessionRun) emit(event Event) {
+	run.eventsMu.RLock()
+	defer run.eventsMu.RUnlock()
+
+	if run.events != nil {
+		run.events <- event
+	}
+}
+
+// tryEmit is emit for the events the run can go without, e.g. progress
+// reports: it drops event rather than waiting for the client.
+func (run *sessionRun) tryEmit(event Event) {
+	run.eventsMu.RLock()
+	defer run.eventsMu.RUnlock()
+
+	if run.events != nil {
+		select {
+		case run.events <- event:
+		default:
+		}
+	}
+}
+
+func (run *sessionRun) setElicitationSchema(schema any) {
+	run.elicitationSchemaMu.Lock()
+	defer run.elicitationSchemaMu.Unlock()
+	run.elicitationSchema = schema
+}
+
+func (run *sessionRun) getElicitationSchema() any {
+	run.elicitationSchemaMu.Lock()
+	defer run.elicitationSchemaMu.Unlock()
+	return run.elicitationSchema
+}
+
+type sessionRunKey struct{}
+
+// withSessionRun returns a context carrying run, so that the tool handlers
+// and the sub-sessions started during the run find it.
+func withSessionRun(ctx context.Context, run *sessionRun) context.Context {
+	return context.WithValue(ctx, sessionRunKey{}, run)
+}
+
+func sessionRunFromContext(ctx context.Context) *sessionRun {
+	run, _ := ctx.Value(sessionRunKey{}).(*sessionRun)
+	return run
+}
+
+// startSessionRun returns the run of a RunStream call for sess: the run ctx
+// comes from for the sub-sessions, or a new one that starts with the agent
+// the session's previous run ended with.
+func (r *LocalRuntime) startSessionRun(ctx context.Context, sess *session.Session) *sessionRun {
+	run := sessionRunFromContext(ctx)
+	if run == nil {
+		run = newSessionRun(sess.ID, r.sessionAgentName(sess.ID))
+	}
+
+	r.runsMu.Lock()
+	defer r.runsMu.Unlock()
+	r.runs[sess.ID] = run
+	return run
+}
+
+// endSessionRun removes the run of sess, if it's still registered: a new
+// run of the session may have started since. The agent the session ended
+// with is remembered for its next run. The sub-sessions don't run again:
+// what the runtime knows about them is forgotten.
+func (r *LocalRuntime) endSessionRun(sess *session.Session, run *sessionRun) {
+	r.runsMu.Lock()
+	if r.runs[sess.ID] != run {
+		r.runsMu.Unlock()
+		return
+	}
+	delete(r.runs, sess.ID)
+	subSession := run.sessionID != sess.ID
+	if !subSession {
+		r.sessionAgents[sess.ID] = run.agentName()
+		for ts, users := range r.toolsetUsers {
+			users = slices.DeleteFunc(users, func(user *sessionRun) bool { return user == run })
+			if len(users) == 0 {
+				delete(r.toolsetUsers, ts)
+			} else {
+				r.toolsetUsers[ts] = users
+			}
+		}
+	}
+	r.runsMu.Unlock()
+
+	if subSession {
+		r.ForgetSession(sess.ID)
+	}
+}
+
+// ForgetSession drops what the runtime keeps about the session sessionID
+// between its runs, e.g. the agent it's at, for sessions that won't run
+// again, e.g. deleted ones. It must not be called while the session runs.
+func (r *LocalRuntime) ForgetSession(sessionID string) {
+	r.runsMu.Lock()
+	delete(r.sessionAgents, sessionID)
+	r.runsMu.Unlock()
+
+	r.startupInfoEmittedMu.Lock()
+	delete(r.startupInfoEmitted, sessionID)
+	r.startupInfoEmittedMu.Unlock()
+
+	r.redactionWarnedMu.Lock()
+	delete(r.redactionWarned, sessionID)
+	r.redactionWarnedMu.Unlock()
+}
+
+// useToolSets records that run uses the toolsets toolSets, e.g. at the
+// start of each of its turns, which makes it the run their requests made
+// outside of a tool call are for, see toolsetRun.
+func (r *LocalRuntime) useToolSets(run *sessionRun, toolSets []tools.ToolSet) {
+	r.runsMu.Lock()
+	defer r.runsMu.Unlock()
+
+	for _, ts := range toolSets {
+		users := slices.DeleteFunc(r.toolsetUsers[ts], func(user *sessionRun) bool { return user == run })
+		r.toolsetUsers[ts] = slices.Insert(users, 0, run)
+	}
+}
+
+// toolsetRun returns the run of the session a request of the toolset ts,
+// e.g. an elicitation, is for. The toolsets are shared by the sessions: the
+// run is the one of ctx, for the requests made during a tool call, or else
+// the run in progress that used ts last, see useToolSets. Before any run
+// used ts, it's a run in progress of an agent with ts.
+func (r *LocalRuntime) toolsetRun(ctx context.Context, ts tools.ToolSet) (*sessionRun, error) {
+	if run := sessionRunFromContext(ctx); run != nil {
+		return run, nil
+	}
+
+	r.runsMu.Lock()
+	if users := r.toolsetUsers[ts]; len(users) > 0 {
+		r.runsMu.Unlock()
+		return users[0], nil
+	}
+	runs := slices.SortedFunc(maps.Values(r.runs), func(a, b *sessionRun) int {
+		return strings.Compare(a.sessionID, b.sessionID)
+	})
+	r.runsMu.Unlock()
+
+	for _, run := range runs {
+		if a, err := r.Team().Agent(run.agentName()); err == nil && slices.Contains(a.ToolSets(), ts) {
+			return run, nil
+		}
+	}
+	return nil, errors.New("no session is running")
+}
+
+// sessionRun returns the run in progress of the session sessionID.
+func (r *LocalRuntime) sessionRun(sessionID string) (*sessionRun, bool) {
+	r.runsMu.Lock()
+	defer r.runsMu.Unlock()
+	run, ok := r.runs[sessionID]
+	return run, ok
+}
+
+// sessionAgentName returns the name of the agent the session sessionID is
+// at: the one of its run in progress, or the one its last run ended with,
+// or the current agent of the runtime.
+func (r *LocalRuntime) sessionAgentName(sessionID string) string {
+	r.runsMu.Lock()
+	run, running := r.runs[sessionID]
+	name, ended := r.sessionAgents[sessionID]
+	r.runsMu.Unlock()
+
+	if running {
+		return run.agentName()
+	}
+	if ended {
+		if _, err := r.Team().Agent(name); err == nil {
+			return name
+		}
+	}
+	return r.CurrentAgentName()
+}
@@ -0,0 +1,307 @@
+package runtime
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/model/provider/fake"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
+	"github.com/docker/docker-agent/pkg/tools/builtin"
+)
+
+// streamConcurrently runs sess in the background, sending its tool call
+// confirmations to confirmations, and returns a channel receiving its
+// events once it's done.
+func streamConcurrently(t *testing.T, rt *LocalRuntime, sess *session.Session, confirmations chan<- *ToolCallConfirmationEvent) <-chan []Event {
+	t.Helper()
+
+	done := make(chan []Event, 1)
+	go func() {
+		var events []Event
+		for event := range rt.RunStream(t.Context(), sess) {
+			events = append(events, event)
+			if confirmation, ok := event.(*ToolCallConfirmationEvent); ok {
+				confirmations <- confirmation
+			}
+		}
+		done <- events
+	}()
+	return done
+}
+
+func toolResponses(events []Event) []*ToolCallResponseEvent {
+	var responses []*ToolCallResponseEvent
+	for _, event := range events {
+		if response, ok := event.(*ToolCallResponseEvent); ok {
+			responses = append(responses, response)
+		}
+	}
+	return responses
+}
+
+func TestScripted_ConcurrentSessionsConfirmations(t *testing.T) {
+	// Both sessions ask for the same call: the order the turns are taken in
+	// doesn't matter.
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().ToolCall("call_1", "shell", `{"cmd":"ls"}`),
+		fake.NewTurn().ToolCall("call_1", "shell", `{"cmd":"ls"}`),
+		fake.NewTurn().Content("Done."),
+		fake.NewTurn().Content("Done."),
+	)
+
+	var executed bool
+	tm := team.New(team.WithAgents(newShellAgent(prov, &executed)))
+	rt, err := NewLocalRuntime(tm, WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	first := session.New(session.WithUserMessage("List the files"))
+	second := session.New(session.WithUserMessage("List the files"))
+
+	confirmations := make(chan *ToolCallConfirmationEvent, 2)
+	firstDone := streamConcurrently(t, rt, first, confirmations)
+	secondDone := streamConcurrently(t, rt, second, confirmations)
+
+	// Both sessions wait for their confirmation at the same time.
+	<-confirmations
+	<-confirmations
+
+	// Each answer goes to the session it's for, whatever the order.
+	resumeScripted(t, rt, second, ResumeReject(""))
+	resumeScripted(t, rt, first, ResumeApprove())
+
+	firstResponses := toolResponses(<-firstDone)
+	secondResponses := toolResponses(<-secondDone)
+
+	assert.True(t, executed)
+	require.Len(t, firstResponses, 1)
+	assert.False(t, firstResponses[0].Result.IsError)
+	require.Len(t, secondResponses, 1)
+	assert.True(t, secondResponses[0].Result.IsError)
+	assert.Contains(t, secondResponses[0].Response, "The user rejected the tool call.")
+
+	// The runs are over.
+	_, running := rt.sessionRun(first.ID)
+	assert.False(t, running)
+	_, running = rt.sessionRun(second.ID)
+	assert.False(t, running)
+}
+
+// elicitingToolSet has an ask tool eliciting its answer from the user, the
+// way the MCP servers do during their tool calls.
+type elicitingToolSet struct {
+	mu      sync.Mutex
+	handler tools.ElicitationHandler
+}
+
+func (s *elicitingToolSet) SetElicitationHandler(handler tools.ElicitationHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handler = handler
+}
+
+func (s *elicitingToolSet) Tools(context.Context) ([]tools.Tool, error) {
+	return []tools.Tool{{
+		Name:       "ask",
+		Parameters: map[string]any{},
+		Handler: func(ctx context.Context, _ tools.ToolCall) (*tools.ToolCallResult, error) {
+			s.mu.Lock()
+			handler := s.handler
+			s.mu.Unlock()
+
+			result, err := handler(ctx, &mcp.ElicitParams{Message: "What's your name?"})
+			if err != nil {
+				return tools.ResultError(err.Error()), nil
+			}
+			name, _ := result.Content["name"].(string)
+			return tools.ResultSuccess(name), nil
+		},
+	}}, nil
+}
+
+func TestScripted_ConcurrentSessionsElicitations(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().ToolCall("call_1", "ask", `{}`),
+		fake.NewTurn().ToolCall("call_1", "ask", `{}`),
+		fake.NewTurn().Content("Done."),
+		fake.NewTurn().Content("Done."),
+	)
+
+	toolSet := &elicitingToolSet{}
+	root := agent.New("root", "You are a test agent", agent.WithModel(prov), agent.WithToolSets(toolSet))
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	stream := func(sess *session.Session, elicitations chan<- *ElicitationRequestEvent) <-chan []Event {
+		done := make(chan []Event, 1)
+		go func() {
+			var events []Event
+			for event := range rt.RunStream(t.Context(), sess) {
+				events = append(events, event)
+				if elicitation, ok := event.(*ElicitationRequestEvent); ok {
+					elicitations <- elicitation
+				}
+			}
+			done <- events
+		}()
+		return done
+	}
+
+	first := session.New(session.WithUserMessage("Ask my name"), session.WithToolsApproved(true))
+	second := session.New(session.WithUserMessage("Ask my name"), session.WithToolsApproved(true))
+
+	firstElicitations := make(chan *ElicitationRequestEvent, 1)
+	secondElicitations := make(chan *ElicitationRequestEvent, 1)
+	firstDone := stream(first, firstElicitations)
+	secondDone := stream(second, secondElicitations)
+
+	// The toolset is shared, but each session gets the elicitation of its
+	// own tool call, and both wait for their answer at the same time.
+	<-firstElicitations
+	<-secondElicitations
+
+	// While they do, the requests made outside of their tool calls go to
+	// one of them rather than fail.
+	run, err := rt.toolsetRun(t.Context(), root.ToolSets()[0])
+	require.NoError(t, err)
+	assert.Contains(t, []string{first.ID, second.ID}, run.sessionID)
+
+	require.NoError(t, rt.ResumeElicitation(t.Context(), second.ID, tools.ElicitationActionAccept, map[string]any{"name": "second"}))
+	require.NoError(t, rt.ResumeElicitation(t.Context(), first.ID, tools.ElicitationActionAccept, map[string]any{"name": "first"}))
+
+	firstResponses := toolResponses(<-firstDone)
+	secondResponses := toolResponses(<-secondDone)
+	require.Len(t, firstResponses, 1)
+	assert.Equal(t, "first", firstResponses[0].Response)
+	require.Len(t, secondResponses, 1)
+	assert.Equal(t, "second", secondResponses[0].Response)
+}
+
+func TestToolsetRun(t *testing.T) {
+	t.Parallel()
+
+	prov := fake.NewScriptedProvider(t, "test/scripted")
+	root := agent.New("root", "You are a test agent", agent.WithModel(prov), agent.WithToolSets(&elicitingToolSet{}, &elicitingToolSet{}))
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+	toolSet, other := root.ToolSets()[0], root.ToolSets()[1]
+
+	_, err = rt.toolsetRun(t.Context(), toolSet)
+	require.Error(t, err)
+
+	first := session.New()
+	second := session.New()
+	firstRun := rt.startSessionRun(t.Context(), first)
+	secondRun := rt.startSessionRun(t.Context(), second)
+
+	// The requests made during a tool call are for the run of the call.
+	run, err := rt.toolsetRun(withSessionRun(t.Context(), firstRun), toolSet)
+	require.NoError(t, err)
+	assert.Same(t, firstRun, run)
+
+	// The other ones are for the run that used the toolset last.
+	rt.useToolSets(secondRun, root.ToolSets())
+	rt.useToolSets(firstRun, []tools.ToolSet{toolSet})
+	run, err = rt.toolsetRun(t.Context(), toolSet)
+	require.NoError(t, err)
+	assert.Same(t, firstRun, run)
+	run, err = rt.toolsetRun(t.Context(), other)
+	require.NoError(t, err)
+	assert.Same(t, secondRun, run)
+
+	// Or, once it's done, the one that used it before.
+	rt.endSessionRun(first, firstRun)
+	run, err = rt.toolsetRun(t.Context(), toolSet)
+	require.NoError(t, err)
+	assert.Same(t, secondRun, run)
+
+	rt.endSessionRun(second, secondRun)
+	assert.Empty(t, rt.toolsetUsers)
+}
+
+func TestForgetSession(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted", fake.NewTurn().Content("Done."))
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(agent.New("root", "You are a test agent", agent.WithModel(prov)))),
+		WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Hello"))
+	runScripted(t, rt, sess, ResumeApprove())
+	rt.EmitStartupInfo(t.Context(), sess, make(chan Event, 100))
+	assert.Contains(t, rt.sessionAgents, sess.ID)
+	assert.Contains(t, rt.startupInfoEmitted, sess.ID)
+
+	rt.ForgetSession(sess.ID)
+	assert.NotContains(t, rt.sessionAgents, sess.ID)
+	assert.NotContains(t, rt.startupInfoEmitted, sess.ID)
+}
+
+func TestScripted_HandoffOnlyMovesItsSession(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		handoffTo("call_1", "helper"),
+		fake.NewTurn().ToolCall("call_2", "shell", `{"cmd":"ls"}`),
+		// The other session starts while the first one waits for its
+		// confirmation.
+		fake.NewTurn().
+			Content("Root here.").
+			Expect(fake.LastMessage(chat.MessageRoleUser, "Who are you?")),
+		fake.NewTurn().
+			Content("Helper done.").
+			Expect(fake.LastMessage(chat.MessageRoleTool, "file1.txt")),
+	)
+
+	shell := []tools.Tool{{
+		Name:       "shell",
+		Parameters: map[string]any{},
+		Handler: func(context.Context, tools.ToolCall) (*tools.ToolCallResult, error) {
+			return tools.ResultSuccess("file1.txt"), nil
+		},
+	}}
+	root := agent.New("root", "You are the root agent", agent.WithModel(prov), agent.WithToolSets(builtin.NewHandoffTool()))
+	helper := agent.New("helper", "You are the helper agent", agent.WithModel(prov), agent.WithToolSets(builtin.NewHandoffTool(), newStubToolSet(nil, shell, nil)))
+	agent.WithHandoffs(helper)(root)
+
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root, helper)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	handedOff := session.New(session.WithUserMessage("List the files"))
+	confirmations := make(chan *ToolCallConfirmationEvent, 1)
+	handedOffDone := streamConcurrently(t, rt, handedOff, confirmations)
+
+	confirmation := <-confirmations
+	assert.Equal(t, "helper", confirmation.AgentName)
+	assert.Equal(t, "helper", rt.sessionAgentName(handedOff.ID))
+
+	other := session.New(session.WithUserMessage("Who are you?"))
+	var otherAgents []string
+	for _, event := range runScripted(t, rt, other, ResumeApprove()) {
+		if choice, ok := event.(*AgentChoiceEvent); ok {
+			otherAgents = append(otherAgents, choice.AgentName)
+		}
+	}
+	assert.Equal(t, []string{"root"}, otherAgents)
+
+	resumeScripted(t, rt, handedOff, ResumeApprove())
+	<-handedOffDone
+
+	assert.Equal(t, "Helper done.", handedOff.GetLastAssistantMessageContent())
+	assert.Equal(t, "Root here.", other.GetLastAssistantMessageContent())
+
+	// The next run of each session starts with the agent its last run ended
+	// with, the runtime's current agent is unchanged.
+	assert.Equal(t, "helper", rt.sessionAgentName(handedOff.ID))
+	assert.Equal(t, "root", rt.sessionAgentName(other.ID))
+	assert.Equal(t, "root", rt.CurrentAgentName())
+
+	// Switching the current agent applies to all the sessions.
+	require.NoError(t, rt.SetCurrentAgent("root"))
+	assert.Equal(t, "root", rt.sessionAgentName(handedOff.ID))
+}
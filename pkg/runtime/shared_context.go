@@ -0,0 +1,66 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/tools"
+	"github.com/docker/docker-agent/pkg/tools/builtin"
+)
+
+// handleContextSet handles the context_set tool call by writing a value to
+// the team's shared context.
+func (r *LocalRuntime) handleContextSet(_ context.Context, sess *session.Session, toolCall tools.ToolCall, events chan Event) (*tools.ToolCallResult, error) {
+	var params builtin.ContextSetArgs
+	if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if params.TTLSeconds < 0 {
+		return tools.ResultError("ttl_seconds must not be negative"), nil
+	}
+
+	author := r.resolveSessionAgent(sess).Name()
+	blackboard := r.Team().Blackboard()
+
+	entry, err := blackboard.Set(params.Key, params.Value, author, time.Duration(params.TTLSeconds)*time.Second)
+	if err != nil {
+		return tools.ResultError(fmt.Sprintf("failed to set %q: %v", params.Key, err)), nil
+	}
+
+	events <- BlackboardUpdated(entry.Key, blackboard.List(), author)
+
+	return tools.ResultSuccess(fmt.Sprintf("Shared context %q updated.", entry.Key)), nil
+}
+
+// handleContextGet handles the context_get tool call by reading a value from
+// the team's shared context.
+func (r *LocalRuntime) handleContextGet(_ context.Context, _ *session.Session, toolCall tools.ToolCall, _ chan Event) (*tools.ToolCallResult, error) {
+	var params builtin.ContextGetArgs
+	if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	entry, ok := r.Team().Blackboard().Get(params.Key)
+	if !ok {
+		return tools.ResultError(fmt.Sprintf("no value for key %q in the shared context", params.Key)), nil
+	}
+	return tools.ResultSuccess(entry.Value), nil
+}
+
+// handleContextList handles the context_list tool call by returning all the
+// entries of the team's shared context.
+func (r *LocalRuntime) handleContextList(context.Context, *session.Session, tools.ToolCall, chan Event) (*tools.ToolCallResult, error) {
+	entries := r.Team().Blackboard().List()
+	if len(entries) == 0 {
+		return tools.ResultSuccess("The shared context is empty."), nil
+	}
+
+	out, err := json.Marshal(entries)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling shared context: %w", err)
+	}
+	return tools.ResultSuccess(string(out)), nil
+}
@@ -0,0 +1,141 @@
+package runtime
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
+	"github.com/docker/docker-agent/pkg/tools/builtin"
+)
+
+// recordingProvider is a queueProvider that records the messages of each request.
+type recordingProvider struct {
+	queueProvider
+
+	recordMu sync.Mutex
+	requests [][]chat.Message
+}
+
+func (p *recordingProvider) CreateChatCompletionStream(ctx context.Context, messages []chat.Message, availableTools []tools.Tool) (chat.MessageStream, error) {
+	p.recordMu.Lock()
+	p.requests = append(p.requests, messages)
+	p.recordMu.Unlock()
+	return p.queueProvider.CreateChatCompletionStream(ctx, messages, availableTools)
+}
+
+func contextToolCall(name, arguments string) tools.ToolCall {
+	return tools.ToolCall{
+		ID:       "call_" + name,
+		Type:     "function",
+		Function: tools.FunctionCall{Name: name, Arguments: arguments},
+	}
+}
+
+func TestSharedContext_RootAndChildShareValues(t *testing.T) {
+	rootProv := &mockProvider{id: "test/mock-model", stream: &mockStream{}}
+	childProv := &recordingProvider{queueProvider: queueProvider{id: "test/mock-model", streams: []chat.MessageStream{
+		// The child writes its result to the shared context...
+		newStreamBuilder().
+			AddToolCallName("call_1", builtin.ToolNameContextSet).
+			AddToolCallArguments("call_1", `{"key":"result","value":"chapter 3"}`).
+			AddStopWithUsage(5, 5).
+			Build(),
+		// ...then answers.
+		newStreamBuilder().AddContent("done").AddStopWithUsage(5, 5).Build(),
+	}}}
+
+	librarian := agent.New("librarian", "Library agent",
+		agent.WithModel(childProv),
+		agent.WithToolSets(builtin.NewSharedContextTool()),
+	)
+	root := agent.New("root", "Root agent",
+		agent.WithModel(rootProv),
+		agent.WithToolSets(builtin.NewSharedContextTool()),
+	)
+	agent.WithSubAgents(librarian)(root)
+
+	tm := team.New(team.WithAgents(root, librarian))
+	rt, err := NewLocalRuntime(tm, WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Test"), session.WithToolsApproved(true))
+	evts := make(chan Event, 128)
+
+	// The root writes to the shared context before transferring the task.
+	result, err := rt.handleContextSet(t.Context(), sess, contextToolCall(builtin.ToolNameContextSet, `{"key":"book","value":"Dune"}`), evts)
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+
+	result, err = rt.handleTaskTransfer(t.Context(), sess, contextToolCall(builtin.ToolNameTransferTask, `{"agent":"librarian","task":"find the chapter about sandworms"}`), evts)
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+
+	// The child started with what the root wrote.
+	require.NotEmpty(t, childProv.requests)
+	assert.True(t, slices.ContainsFunc(childProv.requests[0], func(m chat.Message) bool {
+		return m.Role == chat.MessageRoleSystem && strings.Contains(m.Content, "<shared_context key=\"book\">\nDune\n</shared_context>")
+	}))
+
+	// The root reads what the child wrote.
+	result, err = rt.handleContextGet(t.Context(), sess, contextToolCall(builtin.ToolNameContextGet, `{"key":"result"}`), evts)
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	assert.Equal(t, "chapter 3", result.Output)
+
+	entry, ok := tm.Blackboard().Get("result")
+	require.True(t, ok)
+	assert.Equal(t, "librarian", entry.Author)
+
+	// Both writes were reported.
+	close(evts)
+	var updates []*BlackboardUpdatedEvent
+	for event := range evts {
+		if update, ok := event.(*BlackboardUpdatedEvent); ok {
+			updates = append(updates, update)
+		}
+	}
+	require.Len(t, updates, 2)
+	assert.Equal(t, "book", updates[0].Key)
+	assert.Equal(t, "root", updates[0].AgentName)
+	assert.Equal(t, "result", updates[1].Key)
+	assert.Equal(t, "librarian", updates[1].AgentName)
+	assert.Len(t, updates[1].Entries, 2)
+}
+
+func TestSharedContext_Errors(t *testing.T) {
+	prov := &mockProvider{id: "test/mock-model", stream: &mockStream{}}
+	tm := team.New(team.WithAgents(agent.New("root", "Root agent", agent.WithModel(prov))))
+	rt, err := NewLocalRuntime(tm, WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New()
+	evts := make(chan Event, 10)
+
+	result, err := rt.handleContextGet(t.Context(), sess, contextToolCall(builtin.ToolNameContextGet, `{"key":"missing"}`), evts)
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+
+	result, err = rt.handleContextSet(t.Context(), sess, contextToolCall(builtin.ToolNameContextSet, `{"key":"","value":"x"}`), evts)
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+
+	result, err = rt.handleContextSet(t.Context(), sess, contextToolCall(builtin.ToolNameContextSet, `{"key":"k","value":"x","ttl_seconds":-1}`), evts)
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+
+	result, err = rt.handleContextList(t.Context(), sess, contextToolCall(builtin.ToolNameContextList, `{}`), evts)
+	require.NoError(t, err)
+	assert.Equal(t, "The shared context is empty.", result.Output)
+
+	assert.Empty(t, evts)
+}
@@ -28,7 +28,8 @@ func (r *LocalRuntime) handleRunSkill(ctx context.Context, sess *session.Session
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
 
-	st := r.CurrentAgentSkillsToolset()
+	a := r.resolveSessionAgent(sess)
+	st := skillsToolset(a)
 	if st == nil {
 		return tools.ResultError("no skills are available for the current agent"), nil
 	}
@@ -51,7 +52,6 @@ func (r *LocalRuntime) handleRunSkill(ctx context.Context, sess *session.Session
 		return tools.ResultError(fmt.Sprintf("failed to read skill content: %s", err)), nil
 	}
 
-	a := r.CurrentAgent()
 	ca := a.Name()
 
 	ctx, span := r.startSpan(ctx, "runtime.run_skill", trace.WithAttributes(
@@ -28,6 +28,7 @@ type streamResult struct {
 	Stopped           bool
 	FinishReason      chat.FinishReason
 	Usage             *chat.Usage
+	Annotations       []chat.Annotation
 }
 
 // handleStream reads a chat.MessageStream to completion, emitting streaming
@@ -41,12 +42,12 @@ func (r *LocalRuntime) handleStream(ctx context.Context, stream chat.MessageStre
 	var fullReasoningContent strings.Builder
 	var thinkingSignature string
 	var thoughtSignature []byte
-	var toolCalls []tools.ToolCall
+	var toolCalls toolCallAccumulator
 	var messageUsage *chat.Usage
 	var providerFinishReason chat.FinishReason
+	var providerSources []chat.Annotation
 
-	toolCallIndex := make(map[string]int)   // toolCallID -> index in toolCalls slice
-	emittedPartial := make(map[string]bool) // toolCallID -> whether we've emitted a partial event
+	emittedPartial := make(map[int]bool) // position of the tool call -> whether we've emitted a partial event
 	toolDefMap := make(map[string]tools.Tool, len(agentTools))
 	for _, t := range agentTools {
 		toolDefMap[t.Name] = t
@@ -71,6 +72,21 @@ func (r *LocalRuntime) handleStream(ctx context.Context, stream chat.MessageStre
 		telemetry.RecordTokenUsage(ctx, modelName, sess.InputTokens, sess.OutputTokens, sess.TotalCost())
 	}
 
+	// complete tells clients that the assistant message is fully streamed
+	// before handing the aggregated result back to the caller. An answer
+	// is annotated with the sources retrieved during the turn and the web
+	// pages the provider cited.
+	complete := func(res streamResult) (streamResult, error) {
+		if run := sessionRunFromContext(ctx); run != nil && len(res.Calls) == 0 {
+			res.Annotations = answerAnnotations(run.turnSources(sess.ID), res.Content, a.CiteSources())
+		}
+		if len(res.Calls) == 0 {
+			res.Annotations = appendProviderSources(res.Annotations, providerSources)
+		}
+		events <- AgentMessageCompleted(a.Name(), sess.ID, res.Content, res.ReasoningContent, res.Calls, res.FinishReason, res.Usage, res.Annotations)
+		return res, nil
+	}
+
 	for {
 		response, err := stream.Recv()
 		if errors.Is(err, io.EOF) {
@@ -96,10 +112,17 @@ func (r *LocalRuntime) handleStream(ctx context.Context, stream chat.MessageStre
 			thoughtSignature = choice.Delta.ThoughtSignature
 		}
 
+		// The built-in tools of the provider run on its side: report what
+		// they did and keep the pages they cited.
+		for _, use := range choice.Delta.ProviderTools {
+			events <- ProviderTool(a.Name(), sess.ID, use)
+		}
+		providerSources = append(providerSources, choice.Delta.Annotations...)
+
 		if choice.FinishReason == chat.FinishReasonStop || choice.FinishReason == chat.FinishReasonLength {
 			recordUsage()
-			return streamResult{
-				Calls:             toolCalls,
+			return complete(streamResult{
+				Calls:             toolCalls.calls,
 				Content:           fullContent.String(),
 				ReasoningContent:  fullReasoningContent.String(),
 				ThinkingSignature: thinkingSignature,
@@ -107,7 +130,7 @@ func (r *LocalRuntime) handleStream(ctx context.Context, stream chat.MessageStre
 				Stopped:           true,
 				FinishReason:      choice.FinishReason,
 				Usage:             messageUsage,
-			}, nil
+			})
 		}
 
 		// Track the provider's explicit finish reason (e.g. tool_calls) so we
@@ -121,52 +144,30 @@ func (r *LocalRuntime) handleStream(ctx context.Context, stream chat.MessageStre
 		if len(choice.Delta.ToolCalls) > 0 {
 			// Process each tool call delta
 			for _, delta := range choice.Delta.ToolCalls {
-				idx, exists := toolCallIndex[delta.ID]
-				if !exists {
-					idx = len(toolCalls)
-					toolCallIndex[delta.ID] = idx
-					toolCalls = append(toolCalls, tools.ToolCall{
-						ID:   delta.ID,
-						Type: delta.Type,
-					})
-				}
-
-				tc := &toolCalls[idx]
-
-				// Track if we're learning the name for the first time
-				learningName := delta.Function.Name != "" && tc.Function.Name == ""
-
-				// Update fields from delta
-				if delta.Type != "" {
-					tc.Type = delta.Type
-				}
-				if delta.Function.Name != "" {
-					tc.Function.Name = delta.Function.Name
-				}
-				if delta.Function.Arguments != "" {
-					tc.Function.Arguments += delta.Function.Arguments
-				}
+				pos, learningName, newArguments := toolCalls.add(delta)
+				tc := &toolCalls.calls[pos]
 
 				// Emit PartialToolCall once we have a name, and on subsequent argument deltas.
 				// Only the newly received argument bytes are sent, not the full
 				// accumulated arguments, to avoid re-transmitting the entire payload
 				// on every token.
-				if tc.Function.Name != "" && (learningName || delta.Function.Arguments != "") {
-					if !emittedPartial[delta.ID] || delta.Function.Arguments != "" {
+				if tc.Function.Name != "" && (learningName || newArguments != "") {
+					if !emittedPartial[pos] || newArguments != "" {
 						partial := tools.ToolCall{
 							ID:   tc.ID,
 							Type: tc.Type,
 							Function: tools.FunctionCall{
 								Name:      tc.Function.Name,
-								Arguments: delta.Function.Arguments,
+								Arguments: newArguments,
 							},
 						}
 						toolDef := tools.Tool{}
-						if !emittedPartial[delta.ID] {
+						if !emittedPartial[pos] {
 							toolDef = toolDefMap[tc.Function.Name]
 						}
-						events <- PartialToolCall(partial, toolDef, a.Name())
-						emittedPartial[delta.ID] = true
+						preview, _ := tools.PreviewArgs(tc.Function.Arguments)
+						events <- PartialToolCall(partial, toolDef, preview, a.Name())
+						emittedPartial[pos] = true
 					}
 				}
 			}
@@ -193,7 +194,7 @@ func (r *LocalRuntime) handleStream(ctx context.Context, stream chat.MessageStre
 
 	// If the stream completed without producing any content or tool calls, likely because of a token limit, stop to avoid breaking the request loop
 	// NOTE(krissetto): this can likely be removed once compaction works properly with all providers (aka dmr)
-	stoppedDueToNoOutput := fullContent.Len() == 0 && len(toolCalls) == 0
+	stoppedDueToNoOutput := fullContent.Len() == 0 && len(toolCalls.calls) == 0
 
 	// Prefer the provider's explicit finish reason when available (e.g.
 	// tool_calls).  Only fall back to inference when no explicit reason was
@@ -204,7 +205,7 @@ func (r *LocalRuntime) handleStream(ctx context.Context, stream chat.MessageStre
 	finishReason := providerFinishReason
 	if finishReason == "" {
 		switch {
-		case len(toolCalls) > 0:
+		case len(toolCalls.calls) > 0:
 			finishReason = chat.FinishReasonToolCalls
 		case fullContent.Len() > 0:
 			finishReason = chat.FinishReasonStop
@@ -214,14 +215,14 @@ func (r *LocalRuntime) handleStream(ctx context.Context, stream chat.MessageStre
 	}
 	// Ensure finish reason agrees with the actual stream output.
 	switch {
-	case finishReason == chat.FinishReasonToolCalls && len(toolCalls) == 0:
+	case finishReason == chat.FinishReasonToolCalls && len(toolCalls.calls) == 0:
 		finishReason = chat.FinishReasonNull
-	case finishReason == chat.FinishReasonStop && len(toolCalls) > 0:
+	case finishReason == chat.FinishReasonStop && len(toolCalls.calls) > 0:
 		finishReason = chat.FinishReasonToolCalls
 	}
 
-	return streamResult{
-		Calls:             toolCalls,
+	return complete(streamResult{
+		Calls:             toolCalls.calls,
 		Content:           fullContent.String(),
 		ReasoningContent:  fullReasoningContent.String(),
 		ThinkingSignature: thinkingSignature,
@@ -229,13 +230,14 @@ func (r *LocalRuntime) handleStream(ctx context.Context, stream chat.MessageStre
 		Stopped:           stoppedDueToNoOutput,
 		FinishReason:      finishReason,
 		Usage:             messageUsage,
-	}, nil
+	})
 }
 
 // stripImageContent returns a copy of messages with all image-related content
 // removed. This is used when the target model doesn't support image input to
 // prevent API errors. Text content is preserved; image parts in MultiContent
-// are filtered out, and file attachments with image MIME types are dropped.
+// are filtered out, or replaced by a placeholder in tool results, and file
+// attachments with image MIME types are dropped.
 func stripImageContent(messages []chat.Message) []chat.Message {
 	result := make([]chat.Message, len(messages))
 	for i, msg := range messages {
@@ -245,25 +247,49 @@ func stripImageContent(messages []chat.Message) []chat.Message {
 			continue
 		}
 
-		var filtered []chat.MessagePart
+		var (
+			filtered []chat.MessagePart
+			stripped bool
+		)
 		for _, part := range msg.MultiContent {
 			switch part.Type {
 			case chat.MessagePartTypeImageURL:
-				// Drop image URL parts entirely.
+				stripped = true
+				// Images returned by tools are replaced by a placeholder, so
+				// that the model knows the tool returned one.
+				if msg.Role == chat.MessageRoleTool && part.ImageURL != nil {
+					if mimeType, data, ok := parseDataURL(part.ImageURL.URL); ok {
+						filtered = append(filtered, chat.MessagePart{
+							Type: chat.MessagePartTypeText,
+							Text: chat.ImagePlaceholder(data, mimeType),
+						})
+					}
+				}
 				continue
 			case chat.MessagePartTypeFile:
 				// Drop file parts that are images.
 				if part.File != nil && chat.IsImageMimeType(part.File.MimeType) {
+					stripped = true
 					continue
 				}
 			}
 			filtered = append(filtered, part)
 		}
 
-		if len(filtered) != len(msg.MultiContent) {
+		if stripped {
 			result[i].MultiContent = filtered
 			slog.Debug("Stripped image content from message", "role", msg.Role, "original_parts", len(msg.MultiContent), "remaining_parts", len(filtered))
 		}
 	}
 	return result
 }
+
+// parseDataURL splits a base64 data URL into its MIME type and data.
+func parseDataURL(url string) (mimeType, data string, ok bool) {
+	rest, ok := strings.CutPrefix(url, "data:")
+	if !ok {
+		return "", "", false
+	}
+	mimeType, data, ok = strings.Cut(rest, ";base64,")
+	return mimeType, data, ok
+}
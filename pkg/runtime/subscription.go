@@ -0,0 +1,139 @@
+package runtime
+
+import "context"
+
+// Subscription dispatches the events of a stream to the callbacks
+// registered for their types, so that clients don't have to switch over all
+// the events to handle the few they're interested in:
+//
+//	text, err := runtime.Events(rt.RunStream(ctx, sess)).
+//		OnAgentChoice(func(e *runtime.AgentChoiceEvent) { fmt.Print(e.Content) }).
+//		OnToolCall(func(e *runtime.ToolCallEvent) { log.Println(e.ToolCall.Function.Name) }).
+//		Collect(ctx)
+//
+// The callbacks are called one at a time, on the goroutine running the
+// subscription, in the order of the events and, for each event, in the
+// order they were registered.
+//
+// A callback that blocks blocks the subscription: the events that follow
+// are buffered by the stream (RunStream buffers 128 of them), then the run
+// itself waits. That's how the callbacks of confirmations are expected to
+// wait for the user, and how slow clients slow the run down rather than
+// lose events. Callbacks that do long work should hand it to another
+// goroutine.
+type Subscription struct {
+	events   <-chan Event
+	handlers []func(Event)
+}
+
+// Events returns a subscription to events, typically the channel returned
+// by RunStream. Nothing is read from it until Run or Collect is called.
+func Events(events <-chan Event) *Subscription {
+	return &Subscription{events: events}
+}
+
+// On registers fn for the events of type T, e.g. *TokenUsageEvent, for the
+// types that have no On method.
+func On[T Event](s *Subscription, fn func(T)) *Subscription {
+	s.handlers = append(s.handlers, func(event Event) {
+		if e, ok := event.(T); ok {
+			fn(e)
+		}
+	})
+	return s
+}
+
+// OnEvent registers fn for all the events.
+func (s *Subscription) OnEvent(fn func(Event)) *Subscription {
+	return On(s, fn)
+}
+
+// OnAgentChoice registers fn for the chunks of the messages of the agents.
+func (s *Subscription) OnAgentChoice(fn func(*AgentChoiceEvent)) *Subscription {
+	return On(s, fn)
+}
+
+// OnAgentMessageCompleted registers fn for the fully streamed messages of
+// the agents.
+func (s *Subscription) OnAgentMessageCompleted(fn func(*AgentMessageCompletedEvent)) *Subscription {
+	return On(s, fn)
+}
+
+// OnToolCall registers fn for the tool calls, sent when they're run.
+func (s *Subscription) OnToolCall(fn func(*ToolCallEvent)) *Subscription {
+	return On(s, fn)
+}
+
+// OnToolCallResponse registers fn for the results of the tool calls.
+func (s *Subscription) OnToolCallResponse(fn func(*ToolCallResponseEvent)) *Subscription {
+	return On(s, fn)
+}
+
+// OnToolCallConfirmation registers fn for the tool calls waiting for a
+// confirmation. fn, or the code it hands the confirmation to, must answer
+// with Resume: the run waits until then.
+func (s *Subscription) OnToolCallConfirmation(fn func(*ToolCallConfirmationEvent)) *Subscription {
+	return On(s, fn)
+}
+
+// OnElicitationRequest registers fn for the elicitation requests. Like the
+// confirmations, they must be answered, with ResumeElicitation.
+func (s *Subscription) OnElicitationRequest(fn func(*ElicitationRequestEvent)) *Subscription {
+	return On(s, fn)
+}
+
+// OnError registers fn for the errors of the run.
+func (s *Subscription) OnError(fn func(*ErrorEvent)) *Subscription {
+	return On(s, fn)
+}
+
+// Run dispatches the events until the stream is closed. It returns the
+// first error of the run, as a *RunError, or the cause of the cancellation
+// of ctx.
+//
+// Once ctx is done, the callbacks aren't called anymore but the stream is
+// still drained until it's closed, so that the run isn't left blocked on
+// it: ctx should be the context of the stream, or one it's derived from.
+func (s *Subscription) Run(ctx context.Context) error {
+	var runErr error
+	for event := range s.events {
+		if e, ok := event.(*ErrorEvent); ok && runErr == nil {
+			runErr = &RunError{Message: e.Error, Code: e.Code, Hint: e.Hint}
+		}
+		if ctx.Err() != nil {
+			continue
+		}
+		for _, handler := range s.handlers {
+			handler(event)
+		}
+	}
+
+	if err := context.Cause(ctx); err != nil {
+		return err
+	}
+	return runErr
+}
+
+// Collect is Run for the clients that only want the answer: it returns the
+// last message of the agents of the session the stream is for, the
+// messages of the sub-sessions being left out.
+//
+// The run blocks on the confirmations and the elicitation requests no
+// callback answers: run sessions that need none, e.g. with their tools
+// approved, or register callbacks for them.
+func (s *Subscription) Collect(ctx context.Context) (string, error) {
+	var sessionID, output string
+	On(s, func(e *StreamStartedEvent) {
+		if sessionID == "" {
+			sessionID = e.SessionID
+		}
+	})
+	s.OnAgentMessageCompleted(func(e *AgentMessageCompletedEvent) {
+		if e.SessionID == sessionID && e.Content != "" {
+			output = e.Content
+		}
+	})
+
+	err := s.Run(ctx)
+	return output, err
+}
@@ -0,0 +1,132 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/model/provider/fake"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+func eventStream(events ...Event) <-chan Event {
+	ch := make(chan Event, len(events))
+	for _, event := range events {
+		ch <- event
+	}
+	close(ch)
+	return ch
+}
+
+func TestSubscription_Dispatch(t *testing.T) {
+	t.Parallel()
+
+	toolCall := tools.ToolCall{ID: "call_1", Function: tools.FunctionCall{Name: "shell"}}
+	events := eventStream(
+		StreamStarted("session-1", "root"),
+		AgentChoice("root", "session-1", "Hello"),
+		ToolCall(toolCall, tools.Tool{Name: "shell"}, nil, "root"),
+		AgentChoice("root", "session-1", " world"),
+		NewTokenUsageEvent("session-1", "root", &Usage{InputTokens: 1, OutputTokens: 2}),
+		StreamStopped("session-1", "root"),
+	)
+
+	var calls []string
+	err := On(Events(events).
+		OnAgentChoice(func(e *AgentChoiceEvent) { calls = append(calls, "choice:"+e.Content) }).
+		OnToolCall(func(e *ToolCallEvent) { calls = append(calls, "tool:"+e.ToolCall.Function.Name) }).
+		OnEvent(func(Event) { calls = append(calls, "event") }),
+		func(*TokenUsageEvent) { calls = append(calls, "usage") }).
+		Run(t.Context())
+	require.NoError(t, err)
+
+	// The events are dispatched in order, each to its callbacks in the
+	// order they were registered.
+	assert.Equal(t, []string{
+		"event",
+		"choice:Hello", "event",
+		"tool:shell", "event",
+		"choice: world", "event",
+		"event", "usage",
+		"event",
+	}, calls)
+}
+
+func TestSubscription_RunError(t *testing.T) {
+	t.Parallel()
+
+	var errorEvents []*ErrorEvent
+	err := Events(eventStream(
+		ErrorWithCode(ErrorCodeProviderRateLimited, "rate limited", "retry later"),
+		Error("second error"),
+	)).OnError(func(e *ErrorEvent) { errorEvents = append(errorEvents, e) }).Run(t.Context())
+
+	// All the errors are dispatched, the first one is returned.
+	assert.Len(t, errorEvents, 2)
+	runErr, ok := errors.AsType[*RunError](err)
+	require.True(t, ok, "unexpected error: %v", err)
+	assert.Equal(t, &RunError{Message: "rate limited", Code: ErrorCodeProviderRateLimited, Hint: "retry later"}, runErr)
+}
+
+func TestSubscription_Cancelled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancelCause(t.Context())
+	cause := errors.New("stopped by the user")
+
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		events <- AgentChoice("root", "session-1", "Hello")
+		events <- AgentChoice("root", "session-1", " world")
+	}()
+
+	var choices []string
+	err := Events(events).OnAgentChoice(func(e *AgentChoiceEvent) {
+		choices = append(choices, e.Content)
+		cancel(cause)
+	}).Run(ctx)
+
+	// The stream is drained, but the callbacks aren't called anymore.
+	require.ErrorIs(t, err, cause)
+	assert.Equal(t, []string{"Hello"}, choices)
+	_, open := <-events
+	assert.False(t, open)
+}
+
+func TestScripted_SubscriptionCollect(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().
+			Content("Let me look.").
+			ToolCall("call_1", "shell", `{"cmd":"ls"}`),
+		fake.NewTurn().
+			Content("There are ", "two files.").
+			Expect(fake.LastMessage(chat.MessageRoleTool, "file1.txt file2.txt")),
+	)
+
+	var executed bool
+	tm := team.New(team.WithAgents(newShellAgent(prov, &executed)))
+	rt, err := NewLocalRuntime(tm, WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("List the files"))
+
+	var confirmations int
+	output, err := Events(rt.RunStream(t.Context(), sess)).
+		OnToolCallConfirmation(func(*ToolCallConfirmationEvent) {
+			confirmations++
+			resumeScripted(t, rt, sess, ResumeApprove())
+		}).
+		Collect(t.Context())
+	require.NoError(t, err)
+
+	assert.True(t, executed)
+	assert.Equal(t, 1, confirmations)
+	assert.Equal(t, "There are two files.", output)
+}
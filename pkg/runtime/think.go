@@ -0,0 +1,101 @@
+package runtime
+
+import (
+	"encoding/json"
+	"log/slog"
+	"slices"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/tools"
+	"github.com/docker/docker-agent/pkg/tools/builtin"
+)
+
+// keepsPastThoughts reports whether the agent's think tool is configured to
+// keep the thoughts of the previous turns in the conversation sent to the
+// model. Agents without a think tool have no thoughts to strip.
+func keepsPastThoughts(a *agent.Agent) bool {
+	for _, ts := range a.ToolSets() {
+		if tt, ok := tools.As[*builtin.ThinkTool](ts); ok {
+			return tt.KeepThoughts()
+		}
+	}
+	return true
+}
+
+// stripPastThoughts returns a copy of messages without the think tool calls,
+// and their results, made before the last user message. Thoughts are a
+// scratchpad for the turn they're made in: sending them back on every later
+// turn only costs tokens. The thoughts of the current turn are kept so that
+// the model sees the tool calls it made. Assistant messages left with
+// nothing in them are dropped.
+func stripPastThoughts(messages []chat.Message) []chat.Message {
+	lastUser := -1
+	for i, msg := range slices.Backward(messages) {
+		if msg.Role == chat.MessageRoleUser {
+			lastUser = i
+			break
+		}
+	}
+	if lastUser <= 0 {
+		return messages
+	}
+
+	stripped := map[string]bool{}
+	result := make([]chat.Message, 0, len(messages))
+	for i, msg := range messages {
+		if i >= lastUser {
+			result = append(result, msg)
+			continue
+		}
+
+		switch msg.Role {
+		case chat.MessageRoleAssistant:
+			if !slices.ContainsFunc(msg.ToolCalls, isThinkToolCall) {
+				break
+			}
+			var (
+				calls []tools.ToolCall
+				defs  []tools.Tool
+			)
+			for j, tc := range msg.ToolCalls {
+				if isThinkToolCall(tc) {
+					stripped[tc.ID] = true
+					continue
+				}
+				calls = append(calls, tc)
+				if j < len(msg.ToolDefinitions) {
+					defs = append(defs, msg.ToolDefinitions[j])
+				}
+			}
+			if len(calls) == 0 && msg.Content == "" && len(msg.MultiContent) == 0 {
+				continue
+			}
+			msg.ToolCalls = calls
+			msg.ToolDefinitions = defs
+		case chat.MessageRoleTool:
+			if stripped[msg.ToolCallID] {
+				continue
+			}
+		}
+		result = append(result, msg)
+	}
+
+	if len(stripped) > 0 {
+		slog.Debug("Stripped past thoughts from messages", "thoughts", len(stripped))
+	}
+	return result
+}
+
+func isThinkToolCall(tc tools.ToolCall) bool {
+	return tc.Function.Name == builtin.ToolNameThink
+}
+
+// thoughtFromArguments returns the thought of a think tool call.
+func thoughtFromArguments(arguments string) string {
+	var args builtin.ThinkArgs
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return ""
+	}
+	return args.Thought
+}
@@ -0,0 +1,127 @@
+package runtime
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/model/provider/fake"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
+	"github.com/docker/docker-agent/pkg/tools/builtin"
+)
+
+func thinkCall(id, thought string) tools.ToolCall {
+	return tools.ToolCall{ID: id, Function: tools.FunctionCall{Name: builtin.ToolNameThink, Arguments: `{"thought":"` + thought + `"}`}}
+}
+
+func TestStripPastThoughts(t *testing.T) {
+	shell := tools.ToolCall{ID: "call_shell", Function: tools.FunctionCall{Name: "shell", Arguments: `{"cmd":"ls"}`}}
+
+	messages := []chat.Message{
+		{Role: chat.MessageRoleSystem, Content: "You are a test agent"},
+		{Role: chat.MessageRoleUser, Content: "List the files"},
+		{Role: chat.MessageRoleAssistant, ToolCalls: []tools.ToolCall{thinkCall("call_1", "Use the shell")}},
+		{Role: chat.MessageRoleTool, ToolCallID: "call_1", Content: "Thought recorded."},
+		{
+			Role:            chat.MessageRoleAssistant,
+			Content:         "Let me check.",
+			ToolCalls:       []tools.ToolCall{thinkCall("call_2", "ls is enough"), shell},
+			ToolDefinitions: []tools.Tool{{Name: builtin.ToolNameThink}, {Name: "shell"}},
+		},
+		{Role: chat.MessageRoleTool, ToolCallID: "call_2", Content: "Thought recorded."},
+		{Role: chat.MessageRoleTool, ToolCallID: "call_shell", Content: "file1.txt"},
+		{Role: chat.MessageRoleAssistant, Content: "There is one file."},
+		{Role: chat.MessageRoleUser, Content: "Thanks"},
+		{Role: chat.MessageRoleAssistant, ToolCalls: []tools.ToolCall{thinkCall("call_3", "Nothing to do")}},
+		{Role: chat.MessageRoleTool, ToolCallID: "call_3", Content: "Thought recorded."},
+	}
+
+	got := stripPastThoughts(messages)
+
+	want := []chat.Message{
+		messages[0],
+		messages[1],
+		{
+			Role:            chat.MessageRoleAssistant,
+			Content:         "Let me check.",
+			ToolCalls:       []tools.ToolCall{shell},
+			ToolDefinitions: []tools.Tool{{Name: "shell"}},
+		},
+		messages[6],
+		messages[7],
+		messages[8],
+		messages[9],
+		messages[10],
+	}
+	assert.Equal(t, want, got)
+	assert.Len(t, messages[4].ToolCalls, 2, "the session's messages must not be modified")
+}
+
+func TestStripPastThoughts_NoPreviousTurn(t *testing.T) {
+	messages := []chat.Message{
+		{Role: chat.MessageRoleUser, Content: "Hi"},
+		{Role: chat.MessageRoleAssistant, ToolCalls: []tools.ToolCall{thinkCall("call_1", "Greet back")}},
+		{Role: chat.MessageRoleTool, ToolCallID: "call_1", Content: "Thought recorded."},
+	}
+	assert.Equal(t, messages, stripPastThoughts(messages))
+}
+
+func TestKeepsPastThoughts(t *testing.T) {
+	assert.True(t, keepsPastThoughts(agent.New("root", "")))
+	assert.False(t, keepsPastThoughts(agent.New("root", "", agent.WithToolSets(builtin.NewThinkTool()))))
+	assert.True(t, keepsPastThoughts(agent.New("root", "", agent.WithToolSets(builtin.NewThinkTool(builtin.WithKeepThoughts(true))))))
+}
+
+// noThinkToolCalls expects the request not to contain any think tool call.
+func noThinkToolCalls(t fake.TestingT, req fake.Request) {
+	t.Helper()
+	for _, m := range req.Messages {
+		if slices.ContainsFunc(m.ToolCalls, isThinkToolCall) {
+			t.Errorf("unexpected think tool call in the request")
+		}
+	}
+}
+
+func TestScripted_Thoughts(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().
+			ToolCall("call_1", builtin.ToolNameThink, `{"thought":`, `"The user says hi"}`),
+		// The thoughts of the current turn are sent back...
+		fake.NewTurn().
+			Content("Hello!").
+			Expect(fake.LastMessage(chat.MessageRoleTool, "Thought recorded.")),
+		// ...but not the ones of the previous turns.
+		fake.NewTurn().
+			Content("Bye!").
+			Expect(noThinkToolCalls, fake.LastMessage(chat.MessageRoleUser, "Bye")),
+	)
+
+	root := agent.New("root", "You are a test agent", agent.WithModel(prov), agent.WithToolSets(builtin.NewThinkTool()))
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Hi"))
+	events := runScripted(t, rt, sess, ResumeApprove())
+
+	var thoughts []*AgentThoughtEvent
+	for _, event := range events {
+		if e, ok := event.(*AgentThoughtEvent); ok {
+			thoughts = append(thoughts, e)
+		}
+	}
+	require.Len(t, thoughts, 1)
+	assert.Equal(t, "The user says hi", thoughts[0].Thought)
+	assert.Equal(t, "root", thoughts[0].AgentName)
+	assert.Equal(t, sess.ID, thoughts[0].GetSessionID())
+	assert.False(t, hasEventType(t, events, &ToolCallConfirmationEvent{}), "thinking must not require a confirmation")
+
+	sess.AddMessage(session.UserMessage("Bye"))
+	runScripted(t, rt, sess, ResumeApprove())
+	assert.Equal(t, "Bye!", sess.GetLastAssistantMessageContent())
+}
@@ -0,0 +1,281 @@
+package runtime
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// toolResultCache holds the results of the calls to the read-only tools, by
+// session, agent, tool name and arguments, see WithToolResultCache. The least recently used
+// entries are evicted first.
+type toolResultCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type toolCacheEntry struct {
+	key      string
+	toolName string
+	// paths are the string arguments of the call resolved as paths, the
+	// files the result may depend on.
+	paths   []string
+	result  *tools.ToolCallResult
+	expires time.Time
+}
+
+func newToolResultCache(ttl time.Duration, maxEntries int) *toolResultCache {
+	return &toolResultCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// toolCacheKey returns the cache key of a call to a tool of the agent
+// agentName in the session sessionID: the session, since sessions may have
+// different working directories and permissions, the agent, since agents
+// may have different tools by the same name, the tool name and the
+// arguments, canonicalized so that the order of the keys and the spacing
+// don't matter. It also returns the decoded arguments, and false for
+// arguments that aren't valid JSON.
+func toolCacheKey(sessionID, agentName string, toolCall tools.ToolCall) (string, any, bool) {
+	arguments := toolCall.Function.Arguments
+	if strings.TrimSpace(arguments) == "" {
+		arguments = "{}"
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(arguments))
+	decoder.UseNumber()
+	var args any
+	if err := decoder.Decode(&args); err != nil {
+		return "", nil, false
+	}
+
+	// The maps are encoded with sorted keys.
+	var canonical bytes.Buffer
+	encoder := json.NewEncoder(&canonical)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(args); err != nil {
+		return "", nil, false
+	}
+	return sessionID + "\x00" + agentName + "\x00" + toolCall.Function.Name + "\x00" + strings.TrimSpace(canonical.String()), args, true
+}
+
+// argumentPaths returns the string values of args, resolved as paths
+// against workingDir.
+func argumentPaths(args any, workingDir string) []string {
+	var paths []string
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case string:
+			if v != "" {
+				paths = append(paths, resolvePath(v, workingDir))
+			}
+		case []any:
+			for _, item := range v {
+				walk(item)
+			}
+		case map[string]any:
+			for _, item := range v {
+				walk(item)
+			}
+		}
+	}
+	walk(args)
+	return paths
+}
+
+func resolvePath(path, workingDir string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workingDir, path)
+	}
+	return filepath.Clean(path)
+}
+
+func (c *toolResultCache) get(key string) (*tools.ToolCallResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*toolCacheEntry)
+	if c.ttl > 0 && time.Now().After(entry.expires) {
+		c.remove(elem)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return cloneToolResult(entry.result), true
+}
+
+func (c *toolResultCache) put(key, toolName string, paths []string, result *tools.ToolCallResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+	c.entries[key] = c.lru.PushFront(&toolCacheEntry{
+		key:      key,
+		toolName: toolName,
+		paths:    paths,
+		result:   cloneToolResult(result),
+		expires:  time.Now().Add(c.ttl),
+	})
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+// cloneToolResult returns a copy of res, so that the results handed out by
+// the cache, which hooks and the dispatcher may change, don't share the
+// cached one. Meta and StructuredContent are shared: they are only read.
+func cloneToolResult(res *tools.ToolCallResult) *tools.ToolCallResult {
+	clone := *res
+	clone.Images = slices.Clone(res.Images)
+	clone.Audios = slices.Clone(res.Audios)
+	clone.Contents = slices.Clone(res.Contents)
+	for i, block := range clone.Contents {
+		if block.Image != nil {
+			image := *block.Image
+			clone.Contents[i].Image = &image
+		}
+		if block.Resource != nil {
+			resource := *block.Resource
+			clone.Contents[i].Resource = &resource
+		}
+	}
+	return &clone
+}
+
+// remove must be called with mu held.
+func (c *toolResultCache) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*toolCacheEntry).key)
+}
+
+// evict removes the entries for which match returns true, and returns how
+// many it removed.
+func (c *toolResultCache) evict(match func(*toolCacheEntry) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var evicted int
+	for elem := c.lru.Front(); elem != nil; {
+		next := elem.Next()
+		if match(elem.Value.(*toolCacheEntry)) {
+			c.remove(elem)
+			evicted++
+		}
+		elem = next
+	}
+	return evicted
+}
+
+// evictChanged removes the entries that may depend on the changed files:
+// the ones with an argument that is one of the files, or one of their
+// directories, and the ones without arguments to tell, e.g. git_status.
+// Without changes, all the entries are removed: the tool may have changed
+// anything. Relative paths are resolved against workingDir.
+func (c *toolResultCache) evictChanged(changes []tools.FileChange, workingDir string) int {
+	if len(changes) == 0 {
+		return c.evict(func(*toolCacheEntry) bool { return true })
+	}
+
+	var changed []string
+	for _, change := range changes {
+		changed = append(changed, resolvePath(change.Path, workingDir))
+		if change.OldPath != "" {
+			changed = append(changed, resolvePath(change.OldPath, workingDir))
+		}
+	}
+
+	return c.evict(func(entry *toolCacheEntry) bool {
+		if len(entry.paths) == 0 {
+			return true
+		}
+		for _, path := range entry.paths {
+			for _, file := range changed {
+				if related(path, file) {
+					return true
+				}
+			}
+		}
+		return false
+	})
+}
+
+// related reports whether a and b are the same path, or one is a directory
+// of the other.
+func related(a, b string) bool {
+	sep := string(filepath.Separator)
+	return a == b ||
+		strings.HasPrefix(b, strings.TrimSuffix(a, sep)+sep) ||
+		strings.HasPrefix(a, strings.TrimSuffix(b, sep)+sep)
+}
+
+// cacheableTool reports whether the results of the calls to tool can be
+// served from the cache: it must be read-only and not opted out.
+func cacheableTool(tool tools.Tool) bool {
+	return tool.Annotations.ReadOnlyHint && !tool.NoResultCache
+}
+
+// cachedToolResult returns the cached result of a call to tool by the agent
+// agentName in the session sessionID, or the function storing its result
+// when it isn't cached yet. Both are nil when the call isn't cacheable.
+func (r *LocalRuntime) cachedToolResult(sessionID, agentName string, tool tools.Tool, toolCall tools.ToolCall) (*tools.ToolCallResult, func(*tools.ToolCallResult)) {
+	if r.toolResultCache == nil || !cacheableTool(tool) {
+		return nil, nil
+	}
+	key, args, ok := toolCacheKey(sessionID, agentName, toolCall)
+	if !ok {
+		return nil, nil
+	}
+	if res, ok := r.toolResultCache.get(key); ok {
+		return res, nil
+	}
+	return nil, func(res *tools.ToolCallResult) {
+		// Errors, e.g. a file not found yet, aren't cached.
+		if res != nil && !res.IsError {
+			r.toolResultCache.put(key, toolCall.Function.Name, argumentPaths(args, r.workingDir), res)
+		}
+	}
+}
+
+// invalidateToolResults removes the cached results the call to tool may
+// have made stale: a tool that isn't read-only may change the files, or
+// anything else.
+func (r *LocalRuntime) invalidateToolResults(tool tools.Tool, changes []tools.FileChange) {
+	if r.toolResultCache == nil || tool.Annotations.ReadOnlyHint {
+		return
+	}
+	r.toolResultCache.evictChanged(changes, r.workingDir)
+}
+
+// InvalidateToolCache removes the cached results of the tools whose name
+// starts with prefix, e.g. after an outside change the runtime can't know
+// about. An empty prefix removes all of them. It returns how many results
+// were removed.
+func (r *LocalRuntime) InvalidateToolCache(prefix string) int {
+	if r.toolResultCache == nil {
+		return 0
+	}
+	return r.toolResultCache.evict(func(entry *toolCacheEntry) bool {
+		return strings.HasPrefix(entry.toolName, prefix)
+	})
+}
@@ -0,0 +1,242 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/model/provider/fake"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+func TestToolCacheKey(t *testing.T) {
+	call := func(name, arguments string) tools.ToolCall {
+		return tools.ToolCall{Function: tools.FunctionCall{Name: name, Arguments: arguments}}
+	}
+	key := func(agentName string, toolCall tools.ToolCall) string {
+		k, _, ok := toolCacheKey("session-1", agentName, toolCall)
+		require.True(t, ok)
+		return k
+	}
+
+	// The order of the keys and the spacing don't matter.
+	assert.Equal(t,
+		key("root", call("search", `{"query":"foo","limit":10}`)),
+		key("root", call("search", `{ "limit": 10, "query": "foo" }`)))
+	assert.Equal(t, key("root", call("status", "")), key("root", call("status", "{}")))
+
+	assert.NotEqual(t, key("root", call("search", `{"limit":10}`)), key("root", call("search", `{"limit":10.0}`)))
+	assert.NotEqual(t, key("root", call("search", `{}`)), key("root", call("list", `{}`)))
+	assert.NotEqual(t, key("root", call("search", `{}`)), key("helper", call("search", `{}`)))
+
+	other, _, ok := toolCacheKey("session-2", "root", call("search", `{}`))
+	require.True(t, ok)
+	assert.NotEqual(t, key("root", call("search", `{}`)), other)
+
+	_, _, ok = toolCacheKey("session-1", "root", call("search", `{"query":`))
+	assert.False(t, ok)
+}
+
+func TestToolResultCache_Copies(t *testing.T) {
+	cache := newToolResultCache(0, 2)
+	result := tools.ResultSuccess("a")
+	result.Contents = []tools.ContentBlock{{Type: tools.ContentBlockTypeImage, Image: &tools.MediaContent{Data: "abc"}}}
+	cache.put("a", "read_file", nil, result)
+
+	// Neither the stored result nor the ones handed out share the cache's.
+	result.Output = "changed"
+	res, ok := cache.get("a")
+	require.True(t, ok)
+	assert.Equal(t, "a", res.Output)
+	res.Output = "changed"
+	res.Contents[0].Image.Data = "changed"
+
+	res, ok = cache.get("a")
+	require.True(t, ok)
+	assert.Equal(t, "a", res.Output)
+	assert.Equal(t, "abc", res.Contents[0].Image.Data)
+}
+
+func TestToolResultCache_LRU(t *testing.T) {
+	cache := newToolResultCache(0, 2)
+	cache.put("a", "read_file", nil, tools.ResultSuccess("a"))
+	cache.put("b", "read_file", nil, tools.ResultSuccess("b"))
+
+	// Reading a makes b the least recently used.
+	_, ok := cache.get("a")
+	require.True(t, ok)
+	cache.put("c", "read_file", nil, tools.ResultSuccess("c"))
+
+	_, ok = cache.get("b")
+	assert.False(t, ok)
+	res, ok := cache.get("a")
+	require.True(t, ok)
+	assert.Equal(t, "a", res.Output)
+	_, ok = cache.get("c")
+	assert.True(t, ok)
+}
+
+func TestToolResultCache_TTL(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		cache := newToolResultCache(time.Minute, 10)
+		cache.put("a", "read_file", nil, tools.ResultSuccess("a"))
+
+		time.Sleep(59 * time.Second)
+		_, ok := cache.get("a")
+		assert.True(t, ok)
+
+		time.Sleep(2 * time.Second)
+		_, ok = cache.get("a")
+		assert.False(t, ok)
+	})
+}
+
+func TestToolResultCache_EvictChanged(t *testing.T) {
+	fill := func() *toolResultCache {
+		cache := newToolResultCache(0, 10)
+		cache.put("main", "read_file", []string{"/work/main.go"}, tools.ResultSuccess(""))
+		cache.put("pkg", "list_directory", []string{"/work/pkg"}, tools.ResultSuccess(""))
+		cache.put("readme", "read_file", []string{"/work/README.md"}, tools.ResultSuccess(""))
+		cache.put("status", "git_status", nil, tools.ResultSuccess(""))
+		return cache
+	}
+
+	cache := fill()
+	assert.Equal(t, 2, cache.evictChanged([]tools.FileChange{{Path: "pkg/util.go", Type: tools.FileCreated}}, "/work"))
+	_, ok := cache.get("pkg")
+	assert.False(t, ok, "the directory of the file changed")
+	_, ok = cache.get("status")
+	assert.False(t, ok, "the call has no path")
+	_, ok = cache.get("main")
+	assert.True(t, ok)
+
+	// Renames change both paths.
+	cache = fill()
+	assert.Equal(t, 2, cache.evictChanged([]tools.FileChange{{Path: "/work/main_test.go", OldPath: "/work/main.go", Type: tools.FileRenamed}}, "/work"))
+	_, ok = cache.get("main")
+	assert.False(t, ok)
+	_, ok = cache.get("readme")
+	assert.True(t, ok)
+
+	// A tool that doesn't report its changes may have changed anything.
+	cache = fill()
+	assert.Equal(t, 4, cache.evictChanged(nil, "/work"))
+}
+
+func TestInvalidateToolCache(t *testing.T) {
+	r := &LocalRuntime{}
+	assert.Zero(t, r.InvalidateToolCache(""), "the cache is disabled")
+
+	WithToolResultCache(0, 10)(r)
+	r.toolResultCache.put("a", "mcp_github_get_issue", nil, tools.ResultSuccess(""))
+	r.toolResultCache.put("b", "mcp_github_list_prs", nil, tools.ResultSuccess(""))
+	r.toolResultCache.put("c", "read_file", nil, tools.ResultSuccess(""))
+
+	assert.Equal(t, 2, r.InvalidateToolCache("mcp_github_"))
+	assert.Equal(t, 1, r.InvalidateToolCache(""))
+}
+
+func TestScripted_ToolResultCache(t *testing.T) {
+	readFile := func(id, path string) *fake.Turn {
+		return fake.NewTurn().ToolCall(id, "read_file", `{"path":"`+path+`"}`)
+	}
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		readFile("call_1", "a.txt").
+			ToolCall("call_2", "read_file", `{"path":"b.txt"}`).
+			ToolCall("call_3", "read_file", `{ "path": "a.txt" }`).
+			ToolCall("call_4", "now", `{}`),
+		fake.NewTurn().
+			ToolCall("call_5", "write_file", `{"path":"a.txt"}`).
+			ToolCall("call_6", "now", `{}`),
+		readFile("call_7", "a.txt").
+			ToolCall("call_8", "read_file", `{"path":"b.txt"}`),
+		fake.NewTurn().
+			Content("Done."),
+	)
+
+	calls := map[string]int{}
+	readOnly := tools.ToolAnnotations{ReadOnlyHint: true}
+	agentTools := []tools.Tool{
+		{
+			Name:        "read_file",
+			Parameters:  map[string]any{},
+			Annotations: readOnly,
+			Handler: func(_ context.Context, toolCall tools.ToolCall) (*tools.ToolCallResult, error) {
+				var args struct{ Path string }
+				if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
+					return nil, err
+				}
+				calls["read_file "+args.Path]++
+				return tools.ResultSuccess("content of " + args.Path), nil
+			},
+		},
+		{
+			Name:       "write_file",
+			Parameters: map[string]any{},
+			Handler: func(ctx context.Context, _ tools.ToolCall) (*tools.ToolCallResult, error) {
+				calls["write_file"]++
+				tools.ReportFileChange(ctx, tools.FileChange{Path: "a.txt", Type: tools.FileModified})
+				return tools.ResultSuccess("written"), nil
+			},
+		},
+		{
+			Name:          "now",
+			Parameters:    map[string]any{},
+			Annotations:   readOnly,
+			NoResultCache: true,
+			Handler: func(context.Context, tools.ToolCall) (*tools.ToolCallResult, error) {
+				calls["now"]++
+				return tools.ResultSuccess("12:00"), nil
+			},
+		},
+	}
+	root := agent.New("root", "You are a test agent", agent.WithModel(prov), agent.WithToolSets(newStubToolSet(nil, agentTools, nil)))
+
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)),
+		WithSessionCompaction(false),
+		WithModelStore(mockModelStore{}),
+		WithWorkingDir("/work"),
+		WithToolResultCache(time.Hour, 100),
+	)
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Edit a.txt"), session.WithToolsApproved(true))
+	events := runScripted(t, rt, sess, ResumeApprove())
+
+	// b.txt is read once, a.txt is read again after it was written, the
+	// opted-out tool is always called.
+	assert.Equal(t, map[string]int{
+		"read_file a.txt": 2,
+		"read_file b.txt": 1,
+		"write_file":      1,
+		"now":             2,
+	}, calls)
+
+	var cachedCalls []string
+	cachedResponses := map[string]string{}
+	for _, event := range events {
+		switch e := event.(type) {
+		case *ToolCallEvent:
+			if e.Cached {
+				cachedCalls = append(cachedCalls, e.ToolCall.ID)
+			}
+		case *ToolCallResponseEvent:
+			if e.Cached {
+				cachedResponses[e.ToolCallID] = e.Response
+			}
+		}
+	}
+	assert.Equal(t, []string{"call_3", "call_8"}, cachedCalls)
+	assert.Equal(t, map[string]string{
+		"call_3": "content of a.txt",
+		"call_8": "content of b.txt",
+	}, cachedResponses)
+}
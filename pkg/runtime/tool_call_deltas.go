@@ -0,0 +1,105 @@
+package runtime
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// toolCallAccumulator merges the streamed deltas of the tool calls of a
+// response. Deltas are matched to their call by index when the provider
+// sends one, and by ID otherwise: some OpenAI-compatible servers, e.g. vLLM,
+// only send the ID with the first delta of a call, or never.
+type toolCallAccumulator struct {
+	calls []tools.ToolCall
+	// ids are the IDs sent by the provider for each call, "" when it sent
+	// none and the call was given a synthetic ID.
+	ids     []string
+	byIndex map[int]int
+	byID    map[string]int
+}
+
+// add merges a delta into its tool call. It returns the position of the
+// call, whether the delta gave the call its name, and the arguments it
+// added.
+func (acc *toolCallAccumulator) add(delta tools.ToolCall) (pos int, learnedName bool, newArguments string) {
+	if acc.byIndex == nil {
+		acc.byIndex = make(map[int]int)
+		acc.byID = make(map[string]int)
+	}
+
+	pos, ok := acc.lookup(delta)
+	if !ok {
+		pos = len(acc.calls)
+		id := delta.ID
+		if id == "" {
+			// The results of the calls are matched to them by ID.
+			id = "call_" + uuid.New().String()
+		}
+		acc.calls = append(acc.calls, tools.ToolCall{ID: id, Type: delta.Type})
+		acc.ids = append(acc.ids, delta.ID)
+	}
+	if delta.Index != nil {
+		acc.byIndex[*delta.Index] = pos
+	}
+	if delta.ID != "" || delta.Index == nil {
+		acc.byID[delta.ID] = pos
+	}
+
+	tc := &acc.calls[pos]
+	if delta.ID != "" && acc.ids[pos] == "" {
+		// The ID came after the first delta.
+		acc.ids[pos] = delta.ID
+		tc.ID = delta.ID
+	}
+	if delta.Type != "" {
+		tc.Type = delta.Type
+	}
+	learnedName = delta.Function.Name != "" && tc.Function.Name == ""
+	if delta.Function.Name != "" {
+		tc.Function.Name = delta.Function.Name
+	}
+	if delta.Function.Arguments != "" {
+		merged := mergeToolCallArguments(tc.Function.Arguments, delta.Function.Arguments)
+		newArguments = strings.TrimPrefix(merged, tc.Function.Arguments)
+		tc.Function.Arguments = merged
+	}
+	return pos, learnedName, newArguments
+}
+
+// lookup returns the position of the call a delta belongs to.
+func (acc *toolCallAccumulator) lookup(delta tools.ToolCall) (int, bool) {
+	if delta.Index != nil {
+		if pos, ok := acc.byIndex[*delta.Index]; ok {
+			// Some servers give the same index to all the calls: a
+			// delta with another ID starts a new call.
+			if delta.ID == "" || acc.ids[pos] == "" || acc.ids[pos] == delta.ID {
+				return pos, true
+			}
+		}
+		// A delta without an ID at a new index is a new call, not a part
+		// of the previous call without an ID.
+		if delta.ID == "" {
+			return 0, false
+		}
+	}
+	pos, ok := acc.byID[delta.ID]
+	return pos, ok
+}
+
+// mergeToolCallArguments appends a chunk of arguments to the arguments
+// received so far. Some providers resend the full arguments in the last
+// chunk: when the chunk starts with the arguments received so far and is
+// itself valid JSON, while appending it wouldn't be, it replaces them.
+func mergeToolCallArguments(current, chunk string) string {
+	if current != "" &&
+		strings.HasPrefix(chunk, current) &&
+		json.Valid([]byte(chunk)) &&
+		!json.Valid([]byte(current+chunk)) {
+		return chunk
+	}
+	return current + chunk
+}
@@ -0,0 +1,199 @@
+package runtime
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+func toolCallDelta(id string, index *int, name, arguments string) tools.ToolCall {
+	return tools.ToolCall{
+		ID:       id,
+		Type:     "function",
+		Function: tools.FunctionCall{Name: name, Arguments: arguments},
+		Index:    index,
+	}
+}
+
+func TestHandleStream_ToolCallDeltas(t *testing.T) {
+	t.Parallel()
+
+	first, second := new(0), new(1)
+
+	tests := []struct {
+		name   string
+		deltas []tools.ToolCall
+		// want are the tool calls of the response. An empty ID stands for
+		// a synthetic one.
+		want []tools.ToolCall
+	}{
+		{
+			name: "IDs on every delta",
+			deltas: []tools.ToolCall{
+				toolCallDelta("call_a", nil, "read", ""),
+				toolCallDelta("call_a", nil, "", `{"path":"a"}`),
+				toolCallDelta("call_b", nil, "write", ""),
+				toolCallDelta("call_b", nil, "", `{"path":"b"}`),
+			},
+			want: []tools.ToolCall{
+				toolCallDelta("call_a", nil, "read", `{"path":"a"}`),
+				toolCallDelta("call_b", nil, "write", `{"path":"b"}`),
+			},
+		},
+		{
+			name: "IDs only on the first delta of each call",
+			deltas: []tools.ToolCall{
+				toolCallDelta("call_a", first, "read", ""),
+				toolCallDelta("", first, "", `{"path":`),
+				toolCallDelta("", first, "", `"a"}`),
+				toolCallDelta("call_b", second, "write", ""),
+				toolCallDelta("", second, "", `{"path":`),
+				toolCallDelta("", second, "", `"b"}`),
+			},
+			want: []tools.ToolCall{
+				toolCallDelta("call_a", nil, "read", `{"path":"a"}`),
+				toolCallDelta("call_b", nil, "write", `{"path":"b"}`),
+			},
+		},
+		{
+			name: "no IDs at all",
+			deltas: []tools.ToolCall{
+				toolCallDelta("", first, "read", ""),
+				toolCallDelta("", first, "", `{"path":"a"}`),
+				toolCallDelta("", second, "write", ""),
+				toolCallDelta("", second, "", `{"path":"b"}`),
+			},
+			want: []tools.ToolCall{
+				toolCallDelta("", nil, "read", `{"path":"a"}`),
+				toolCallDelta("", nil, "write", `{"path":"b"}`),
+			},
+		},
+		{
+			name: "interleaved calls without IDs",
+			deltas: []tools.ToolCall{
+				toolCallDelta("", first, "read", `{"path":`),
+				toolCallDelta("", second, "write", `{"path":`),
+				toolCallDelta("", first, "", `"a"}`),
+				toolCallDelta("", second, "", `"b"}`),
+			},
+			want: []tools.ToolCall{
+				toolCallDelta("", nil, "read", `{"path":"a"}`),
+				toolCallDelta("", nil, "write", `{"path":"b"}`),
+			},
+		},
+		{
+			name: "same index for every call",
+			deltas: []tools.ToolCall{
+				toolCallDelta("call_a", first, "read", `{"path":"a"}`),
+				toolCallDelta("call_b", first, "write", `{"path":"b"}`),
+			},
+			want: []tools.ToolCall{
+				toolCallDelta("call_a", nil, "read", `{"path":"a"}`),
+				toolCallDelta("call_b", nil, "write", `{"path":"b"}`),
+			},
+		},
+		{
+			name: "ID after the first delta",
+			deltas: []tools.ToolCall{
+				toolCallDelta("", first, "read", ""),
+				toolCallDelta("call_a", first, "", `{"path":"a"}`),
+			},
+			want: []tools.ToolCall{
+				toolCallDelta("call_a", nil, "read", `{"path":"a"}`),
+			},
+		},
+		{
+			name: "full arguments resent in the last delta",
+			deltas: []tools.ToolCall{
+				toolCallDelta("call_a", first, "read", ""),
+				toolCallDelta("", first, "", `{"path":`),
+				toolCallDelta("", first, "", `"a"}`),
+				toolCallDelta("", first, "", `{"path":"a"}`),
+			},
+			want: []tools.ToolCall{
+				toolCallDelta("call_a", nil, "read", `{"path":"a"}`),
+			},
+		},
+		{
+			name: "full arguments sent before the previous ones are complete",
+			deltas: []tools.ToolCall{
+				toolCallDelta("call_a", nil, "read", `{"path":"a`),
+				toolCallDelta("call_a", nil, "", `{"path":"a.txt"}`),
+			},
+			want: []tools.ToolCall{
+				toolCallDelta("call_a", nil, "read", `{"path":"a.txt"}`),
+			},
+		},
+		{
+			name: "nested object starting like the arguments",
+			deltas: []tools.ToolCall{
+				toolCallDelta("call_a", nil, "read", `{"a":`),
+				toolCallDelta("call_a", nil, "", `{"a":1}}`),
+			},
+			want: []tools.ToolCall{
+				toolCallDelta("call_a", nil, "read", `{"a":{"a":1}}`),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var responses []chat.MessageStreamResponse
+			for _, delta := range tt.deltas {
+				responses = append(responses, chat.MessageStreamResponse{
+					Choices: []chat.MessageStreamChoice{{Delta: chat.MessageDelta{ToolCalls: []tools.ToolCall{delta}}}},
+				})
+			}
+
+			events := make(chan Event, 100)
+			var r LocalRuntime
+			res, err := r.handleStream(t.Context(), &mockStream{responses: responses}, agent.New("root", ""), nil, session.New(), nil, events)
+			require.NoError(t, err)
+			close(events)
+
+			require.Len(t, res.Calls, len(tt.want))
+			ids := map[string]bool{}
+			for i, want := range tt.want {
+				got := res.Calls[i]
+				if want.ID == "" {
+					assert.True(t, strings.HasPrefix(got.ID, "call_"), "synthetic ID %q", got.ID)
+				} else {
+					assert.Equal(t, want.ID, got.ID)
+				}
+				ids[got.ID] = true
+				assert.Equal(t, want.Function, got.Function)
+				assert.Nil(t, got.Index)
+			}
+			assert.Len(t, ids, len(tt.want), "the IDs are unique")
+			assert.Equal(t, chat.FinishReasonToolCalls, res.FinishReason)
+
+			// The partial tool calls add up to the full arguments, without
+			// the resent ones, and the last one previews them.
+			partials := map[string]string{}
+			previews := map[string]map[string]any{}
+			for event := range events {
+				if partial, ok := event.(*PartialToolCallEvent); ok {
+					partials[partial.ToolCall.Function.Name] += partial.ToolCall.Function.Arguments
+					previews[partial.ToolCall.Function.Name] = partial.ArgumentsPreview
+				}
+			}
+			for _, call := range res.Calls {
+				assert.Equal(t, call.Function.Arguments, partials[call.Function.Name])
+
+				var args map[string]any
+				require.NoError(t, json.Unmarshal([]byte(call.Function.Arguments), &args))
+				assert.Equal(t, args, previews[call.Function.Name])
+			}
+		})
+	}
+}
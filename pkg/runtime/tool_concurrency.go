@@ -0,0 +1,71 @@
+package runtime
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// toolSetSlots limits the calls to the tools of each toolset that run at
+// once to the concurrency it declares, see tools.ConcurrencyDeclarer. The
+// toolsets are told apart by the toolset they wrap, so that the calls of
+// all the agents and sessions sharing one count together.
+type toolSetSlots struct {
+	mu    sync.Mutex
+	slots map[tools.ToolSet]chan struct{}
+}
+
+// semaphore returns the slots of the calls to the tools of ts, or nil if
+// their number isn't limited.
+func (s *toolSetSlots) semaphore(ts tools.ToolSet) chan struct{} {
+	concurrency := tools.ToolSetConcurrency(ts)
+	if concurrency == tools.Concurrent {
+		return nil
+	}
+
+	key := tools.Innermost(ts)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.slots == nil {
+		s.slots = make(map[tools.ToolSet]chan struct{})
+	}
+	sem, ok := s.slots[key]
+	if !ok {
+		sem = make(chan struct{}, concurrency)
+		s.slots[key] = sem
+	}
+	return sem
+}
+
+// acquireToolSlot waits for a slot of the toolset of tool to call it,
+// telling clients that the call is queued when it has to wait. It returns
+// the function releasing the slot, nil when the calls to the toolset
+// aren't limited.
+func (r *LocalRuntime) acquireToolSlot(ctx context.Context, tool tools.Tool, toolCall tools.ToolCall, events chan Event, a *agent.Agent) (func(), error) {
+	toolSet := a.ToolSetOf(ctx, tool.Name)
+	if toolSet == nil {
+		return nil, nil
+	}
+	sem := r.toolSetSlots.semaphore(toolSet)
+	if sem == nil {
+		return nil, nil
+	}
+
+	select {
+	case sem <- struct{}{}:
+	default:
+		slog.Debug("Tool call queued behind the other calls to its toolset", "tool", toolCall.Function.Name, "agent", a.Name(), "concurrency", cap(sem))
+		events <- ToolCallQueued(toolCall, tool, cap(sem), a.Name())
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return func() { <-sem }, nil
+}
@@ -0,0 +1,159 @@
+package runtime
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/model/provider/fake"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// serialToolSet is a toolset serving one call at a time.
+type serialToolSet struct {
+	tools.ToolSet
+}
+
+func (s *serialToolSet) Concurrency() tools.Concurrency { return tools.Serial }
+
+func TestScripted_SerialToolSetAcrossSessions(t *testing.T) {
+	// Stays under the threshold of the loop detection, which stops sessions
+	// repeating the same call.
+	const callsPerSession = 4
+
+	// Both sessions make the same calls: the order the turns are taken in
+	// doesn't matter.
+	var turns []*fake.Turn
+	for range 2 * callsPerSession {
+		turns = append(turns, fake.NewTurn().ToolCall("call_1", "lookup", `{}`))
+	}
+	turns = append(turns, fake.NewTurn().Content("Done."), fake.NewTurn().Content("Done."))
+	prov := fake.NewScriptedProvider(t, "test/scripted", turns...)
+
+	var (
+		mu          sync.Mutex
+		inFlight    int
+		maxInFlight int
+		trace       []string
+	)
+	record := func(step string, delta int) {
+		mu.Lock()
+		defer mu.Unlock()
+		inFlight += delta
+		maxInFlight = max(maxInFlight, inFlight)
+		trace = append(trace, step)
+	}
+	lookup := []tools.Tool{{
+		Name:        "lookup",
+		Parameters:  map[string]any{},
+		Annotations: tools.ToolAnnotations{ReadOnlyHint: true},
+		Handler: func(context.Context, tools.ToolCall) (*tools.ToolCallResult, error) {
+			record("start", 1)
+			time.Sleep(5 * time.Millisecond)
+			record("end", -1)
+			return tools.ResultSuccess("found"), nil
+		},
+	}}
+
+	toolSet := &serialToolSet{ToolSet: newStubToolSet(nil, lookup, nil)}
+	root := agent.New("root", "You are a test agent", agent.WithModel(prov), agent.WithToolSets(toolSet))
+	rt, err := NewLocalRuntime(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	confirmations := make(chan *ToolCallConfirmationEvent, 2)
+	firstDone := streamConcurrently(t, rt, session.New(session.WithUserMessage("Look up")), confirmations)
+	secondDone := streamConcurrently(t, rt, session.New(session.WithUserMessage("Look up")), confirmations)
+
+	responses := len(toolResponses(<-firstDone)) + len(toolResponses(<-secondDone))
+	assert.Equal(t, 2*callsPerSession, responses)
+
+	// The calls never overlap: each one ends before the next starts.
+	assert.Equal(t, 1, maxInFlight)
+	require.Len(t, trace, 4*callsPerSession)
+	for i, step := range trace {
+		if i%2 == 0 {
+			assert.Equal(t, "start", step, "step %d", i)
+		} else {
+			assert.Equal(t, "end", step, "step %d", i)
+		}
+	}
+}
+
+func TestAcquireToolSlot_QueuesBehindBusyToolSet(t *testing.T) {
+	lookup := tools.Tool{Name: "lookup", Parameters: map[string]any{}}
+	toolSet := &serialToolSet{ToolSet: newStubToolSet(nil, []tools.Tool{lookup}, nil)}
+	a := agent.New("root", "You are a test agent", agent.WithToolSets(toolSet))
+	_, err := a.Tools(t.Context())
+	require.NoError(t, err)
+
+	r := &LocalRuntime{}
+	toolCall := tools.ToolCall{ID: "call_1", Function: tools.FunctionCall{Name: "lookup"}}
+	events := make(chan Event, 1)
+
+	release, err := r.acquireToolSlot(t.Context(), lookup, toolCall, events, a)
+	require.NoError(t, err)
+	require.NotNil(t, release)
+	assert.Empty(t, events, "a free toolset doesn't queue the call")
+
+	acquired := make(chan func(), 1)
+	go func() {
+		second, err := r.acquireToolSlot(t.Context(), lookup, toolCall, events, a)
+		assert.NoError(t, err)
+		acquired <- second
+	}()
+
+	queued, ok := (<-events).(*ToolCallQueuedEvent)
+	require.True(t, ok)
+	assert.Equal(t, "call_1", queued.ToolCall.ID)
+	assert.Equal(t, 1, queued.Concurrency)
+
+	select {
+	case <-acquired:
+		t.Fatal("the call must wait for the first one to finish")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	release()
+	second := <-acquired
+	require.NotNil(t, second)
+	second()
+
+	// A queued call gives up when its context is canceled.
+	release, err = r.acquireToolSlot(t.Context(), lookup, toolCall, events, a)
+	require.NoError(t, err)
+	defer release()
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	_, err = r.acquireToolSlot(ctx, lookup, toolCall, events, a)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestAcquireToolSlot_ConcurrentToolSet(t *testing.T) {
+	lookup := tools.Tool{Name: "lookup", Parameters: map[string]any{}}
+	a := agent.New("root", "You are a test agent", agent.WithToolSets(newStubToolSet(nil, []tools.Tool{lookup}, nil)))
+	_, err := a.Tools(t.Context())
+	require.NoError(t, err)
+
+	r := &LocalRuntime{}
+	release, err := r.acquireToolSlot(t.Context(), lookup, tools.ToolCall{Function: tools.FunctionCall{Name: "lookup"}}, make(chan Event, 1), a)
+	require.NoError(t, err)
+	assert.Nil(t, release)
+}
+
+func TestToolSetSlots_SharedByWrappers(t *testing.T) {
+	inner := &serialToolSet{ToolSet: newStubToolSet(nil, nil, nil)}
+
+	var slots toolSetSlots
+	first := slots.semaphore(tools.NewStartable(inner))
+	second := slots.semaphore(tools.NewStartable(inner))
+	require.NotNil(t, first)
+	assert.Equal(t, first, second)
+	assert.Equal(t, 1, cap(first))
+}
@@ -1,6 +1,7 @@
 package runtime
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"errors"
@@ -22,6 +23,7 @@ import (
 	"github.com/docker/docker-agent/pkg/session"
 	"github.com/docker/docker-agent/pkg/telemetry"
 	"github.com/docker/docker-agent/pkg/tools"
+	"github.com/docker/docker-agent/pkg/tools/builtin"
 )
 
 // processToolCalls handles the execution of tool calls for an agent
@@ -60,13 +62,35 @@ func (r *LocalRuntime) processToolCalls(ctx context.Context, sess *session.Sessi
 			continue
 		}
 
-		// Pick the handler: runtime-managed tools (transfer_task, handoff)
-		// have dedicated handlers; everything else goes through the toolset.
-		var runTool func()
-		if handler, exists := r.toolMap[toolCall.Function.Name]; exists {
-			runTool = func() { r.runAgentTool(callCtx, handler, sess, toolCall, tool, events, a) }
+		// Check the arguments before asking for a confirmation or calling
+		// the tool: listing the violations lets the model fix its call,
+		// where the handler would fail with an unmarshaling error.
+		if err := tools.ValidateArguments(tool, toolCall.Function.Arguments); err != nil {
+			var violations []string
+			if argsErr, ok := errors.AsType[*tools.ArgumentsError](err); ok {
+				violations = argsErr.Violations
+			}
+			slog.Debug("Invalid tool call arguments", "agent", a.Name(), "tool", toolCall.Function.Name, "violations", violations, "session_id", sess.ID)
+			events <- ToolCallValidationFailed(toolCall, tool, violations, nil, a.Name())
+			r.addToolErrorResponse(ctx, sess, toolCall, tool, events, a, fmt.Sprintf("The arguments of the call to '%s' don't match its parameters, fix them and try again.\n%v", toolCall.Function.Name, err))
+			callSpan.SetStatus(codes.Error, "invalid tool arguments")
+			callSpan.End()
+			continue
+		}
+
+		// Pick the handler: runtime-managed tools (transfer_task, handoff,
+		// agent tools) have dedicated handlers; everything else goes through
+		// the toolset. The arguments may be edited by the user when
+		// approving the call.
+		var runTool func(edit *tools.ArgumentsEdit)
+		if handler, exists := r.runtimeToolHandler(a, toolCall.Function.Name); exists {
+			runTool = func(edit *tools.ArgumentsEdit) {
+				r.runAgentTool(callCtx, handler, sess, edit.Apply(toolCall), tool, edit, events, a)
+			}
 		} else {
-			runTool = func() { r.runTool(callCtx, tool, toolCall, events, sess, a) }
+			runTool = func(edit *tools.ArgumentsEdit) {
+				r.runTool(callCtx, tool, edit.Apply(toolCall), edit, events, sess, a)
+			}
 		}
 
 		// Execute tool with approval check
@@ -107,14 +131,14 @@ func (r *LocalRuntime) executeWithApproval(
 	tool tools.Tool,
 	events chan Event,
 	a *agent.Agent,
-	runTool func(),
+	runTool func(edit *tools.ArgumentsEdit),
 ) (canceled bool) {
 	toolName := toolCall.Function.Name
 
 	// --yolo flag takes absolute precedence: auto-approve everything.
 	if sess.ToolsApproved {
 		slog.Debug("Tool auto-approved by --yolo flag", "tool", toolName, "session_id", sess.ID)
-		runTool()
+		runTool(nil)
 		return false
 	}
 
@@ -131,18 +155,20 @@ func (r *LocalRuntime) executeWithApproval(
 	checkers := r.permissionCheckers(sess)
 
 	for _, pc := range checkers {
-		switch pc.checker.CheckWithArgs(toolName, toolArgs) {
+		switch pc.checker.CheckTool(toolName, tool.Category, toolArgs) {
 		case permissions.Deny:
 			slog.Debug("Tool denied by permissions", "tool", toolName, "source", pc.source, "session_id", sess.ID)
 			r.addToolErrorResponse(ctx, sess, toolCall, tool, events, a, fmt.Sprintf("Tool '%s' is denied by %s.", toolName, pc.source))
 			return false
 		case permissions.Allow:
 			slog.Debug("Tool auto-approved by permissions", "tool", toolName, "source", pc.source, "session_id", sess.ID)
-			runTool()
+			runTool(nil)
 			return false
 		case permissions.ForceAsk:
 			slog.Debug("Tool requires confirmation (ask pattern)", "tool", toolName, "source", pc.source, "session_id", sess.ID)
-			return r.askUserForConfirmation(ctx, sess, toolCall, tool, events, a, runTool)
+			// The tool must always be asked for: it can't be approved for
+			// the rest of the session.
+			return r.askUserForConfirmation(ctx, sess, toolCall, tool, events, a, runTool, askPatternConfirmationOptions)
 		case permissions.Ask:
 			// No explicit match at this level; fall through to next checker
 		}
@@ -150,14 +176,24 @@ func (r *LocalRuntime) executeWithApproval(
 
 	// No permission rule matched. Auto-approve if the tool is read-only.
 	if tool.Annotations.ReadOnlyHint {
-		runTool()
+		runTool(nil)
 		return false
 	}
 
 	// Default: ask the user for confirmation
-	return r.askUserForConfirmation(ctx, sess, toolCall, tool, events, a, runTool)
+	return r.askUserForConfirmation(ctx, sess, toolCall, tool, events, a, runTool, defaultConfirmationOptions)
 }
 
+var (
+	// defaultConfirmationOptions are the answers to a tool call
+	// confirmation: allow once, always allow the tool, allow all the tools,
+	// allow once with edited arguments or reject.
+	defaultConfirmationOptions = []ResumeType{ResumeTypeApprove, ResumeTypeApproveTool, ResumeTypeApproveSession, ResumeTypeApproveEdited, ResumeTypeReject}
+	// askPatternConfirmationOptions are the answers to the confirmation of
+	// a tool matching an ask permission pattern.
+	askPatternConfirmationOptions = []ResumeType{ResumeTypeApprove, ResumeTypeApproveSession, ResumeTypeApproveEdited, ResumeTypeReject}
+)
+
 // permissionChecker pairs a checker with a human-readable source label.
 type permissionChecker struct {
 	checker *permissions.Checker
@@ -167,17 +203,17 @@ type permissionChecker struct {
 // permissionCheckers returns the ordered list of permission checkers to evaluate.
 func (r *LocalRuntime) permissionCheckers(sess *session.Session) []permissionChecker {
 	var checkers []permissionChecker
-	if sess.Permissions != nil {
+	if perms := sess.PermissionsSnapshot(); perms != nil {
 		checkers = append(checkers, permissionChecker{
 			checker: permissions.NewChecker(&latest.PermissionsConfig{
-				Allow: sess.Permissions.Allow,
-				Ask:   sess.Permissions.Ask,
-				Deny:  sess.Permissions.Deny,
+				Allow: perms.Allow,
+				Ask:   perms.Ask,
+				Deny:  perms.Deny,
 			}),
 			source: "session permissions",
 		})
 	}
-	if tc := r.team.Permissions(); tc != nil {
+	if tc := r.Team().Permissions(); tc != nil {
 		checkers = append(checkers, permissionChecker{
 			checker: tc,
 			source:  "permissions configuration",
@@ -195,60 +231,129 @@ func (r *LocalRuntime) askUserForConfirmation(
 	tool tools.Tool,
 	events chan Event,
 	a *agent.Agent,
-	runTool func(),
+	runTool func(edit *tools.ArgumentsEdit),
+	options []ResumeType,
 ) (canceled bool) {
 	toolName := toolCall.Function.Name
-	slog.Debug("Tools not approved, waiting for resume", "tool", toolName, "session_id", sess.ID)
-	events <- ToolCallConfirmation(toolCall, tool, a.Name())
+	run := sessionRunFromContext(ctx)
+	for {
+		slog.Debug("Tools not approved, waiting for resume", "tool", toolName, "session_id", sess.ID)
+		run.discardResume()
+		events <- ToolCallConfirmation(toolCall, tool, previewToolCall(ctx, sess, a, toolCall), a.Name(), options...)
+
+		r.executeOnUserInputHooks(ctx, sess.ID, "tool confirmation")
+
+		select {
+		case req := <-run.resumeChan:
+			switch req.Type {
+			case ResumeTypeApprove:
+				slog.Debug("Resume signal received, approving tool", "tool", toolName, "session_id", sess.ID)
+				runTool(nil)
+			case ResumeTypeApproveSession:
+				slog.Debug("Resume signal received, approving session", "tool", toolName, "session_id", sess.ID)
+				sess.ToolsApproved = true
+				runTool(nil)
+			case ResumeTypeApproveTool:
+				if !slices.Contains(options, ResumeTypeApproveTool) {
+					slog.Debug("Resume signal received, the tool can't be approved permanently: approving once", "tool", toolName, "session_id", sess.ID)
+					runTool(nil)
+					break
+				}
+				// Add the tool to session's allow list for future auto-approval
+				approvedTool := cmp.Or(req.ToolName, toolName)
+				if sess.ApproveTool(approvedTool) {
+					r.persistToolApprovals(ctx, sess)
+				}
+				slog.Debug("Resume signal received, approving tool permanently", "tool", approvedTool, "session_id", sess.ID)
+				runTool(nil)
+			case ResumeTypeApproveEdited:
+				edit := &tools.ArgumentsEdit{Original: toolCall.Function.Arguments, Edited: req.Arguments}
+				// The edited arguments are checked like the ones of the
+				// model: the user is asked again when they don't fit.
+				if violations := r.editedArgumentsViolations(sess, tool, req.Arguments); len(violations) > 0 {
+					slog.Debug("Resume signal received, the edited arguments are invalid", "tool", toolName, "session_id", sess.ID, "violations", violations)
+					events <- ToolCallValidationFailed(edit.Apply(toolCall), tool, violations, edit, a.Name())
+					continue
+				}
+				slog.Debug("Resume signal received, approving tool with edited arguments", "tool", toolName, "session_id", sess.ID)
+				runTool(edit)
+			case ResumeTypeReject:
+				slog.Debug("Resume signal received, rejecting tool", "tool", toolName, "session_id", sess.ID, "reason", req.Reason)
+				rejectMsg := "The user rejected the tool call."
+				if strings.TrimSpace(req.Reason) != "" {
+					rejectMsg += " Reason: " + strings.TrimSpace(req.Reason)
+				}
+				r.addToolErrorResponse(ctx, sess, toolCall, tool, events, a, rejectMsg)
+			}
+			return false
+		case <-ctx.Done():
+			slog.Debug("Context cancelled while waiting for resume", "tool", toolName, "session_id", sess.ID)
+			r.addToolErrorResponse(ctx, sess, toolCall, tool, events, a, "The tool call was canceled by the user.")
+			return true
+		}
+	}
+}
 
-	r.executeOnUserInputHooks(ctx, sess.ID, "tool confirmation")
+// editedArgumentsViolations returns why the arguments edited by the user
+// can't be used for a call to tool: they must be a JSON object matching
+// the parameters of the tool, and must not be denied by the permissions.
+func (r *LocalRuntime) editedArgumentsViolations(sess *session.Session, tool tools.Tool, arguments string) []string {
+	var args map[string]any
+	if err := json.Unmarshal([]byte(cmp.Or(strings.TrimSpace(arguments), "{}")), &args); err != nil {
+		return []string{fmt.Sprintf("arguments are not a valid JSON object: %v", err)}
+	}
 
-	select {
-	case req := <-r.resumeChan:
-		switch req.Type {
-		case ResumeTypeApprove:
-			slog.Debug("Resume signal received, approving tool", "tool", toolName, "session_id", sess.ID)
-			runTool()
-		case ResumeTypeApproveSession:
-			slog.Debug("Resume signal received, approving session", "tool", toolName, "session_id", sess.ID)
-			sess.ToolsApproved = true
-			runTool()
-		case ResumeTypeApproveTool:
-			// Add the tool to session's allow list for future auto-approval
-			approvedTool := req.ToolName
-			if approvedTool == "" {
-				approvedTool = toolName
-			}
-			if sess.Permissions == nil {
-				sess.Permissions = &session.PermissionsConfig{}
-			}
-			if !slices.Contains(sess.Permissions.Allow, approvedTool) {
-				sess.Permissions.Allow = append(sess.Permissions.Allow, approvedTool)
-			}
-			slog.Debug("Resume signal received, approving tool permanently", "tool", approvedTool, "session_id", sess.ID)
-			runTool()
-		case ResumeTypeReject:
-			slog.Debug("Resume signal received, rejecting tool", "tool", toolName, "session_id", sess.ID, "reason", req.Reason)
-			rejectMsg := "The user rejected the tool call."
-			if strings.TrimSpace(req.Reason) != "" {
-				rejectMsg += " Reason: " + strings.TrimSpace(req.Reason)
-			}
-			r.addToolErrorResponse(ctx, sess, toolCall, tool, events, a, rejectMsg)
+	if err := tools.ValidateArguments(tool, arguments); err != nil {
+		if argsErr, ok := errors.AsType[*tools.ArgumentsError](err); ok {
+			return argsErr.Violations
 		}
-		return false
-	case <-ctx.Done():
-		slog.Debug("Context cancelled while waiting for resume", "tool", toolName, "session_id", sess.ID)
-		r.addToolErrorResponse(ctx, sess, toolCall, tool, events, a, "The tool call was canceled by the user.")
-		return true
+		return []string{err.Error()}
+	}
+
+	for _, pc := range r.permissionCheckers(sess) {
+		if pc.checker.CheckTool(tool.Name, tool.Category, args) == permissions.Deny {
+			return []string{fmt.Sprintf("the call is denied by %s", pc.source)}
+		}
+	}
+	return nil
+}
+
+// previewToolCall returns what a tool call would change, for its
+// confirmation. A failed preview doesn't prevent the confirmation: the
+// arguments of the call are shown instead.
+func previewToolCall(ctx context.Context, sess *session.Session, a *agent.Agent, toolCall tools.ToolCall) *tools.Preview {
+	preview, err := a.PreviewToolCall(ctx, toolCall)
+	if err != nil {
+		slog.Debug("Failed to preview the tool call", "tool", toolCall.Function.Name, "session_id", sess.ID, "error", err)
+		return nil
+	}
+	if preview == nil || *preview == (tools.Preview{}) {
+		return nil
+	}
+	return preview
+}
+
+// persistToolApprovals saves the tools approved for the session, so that
+// they're still approved when it's resumed. Sub-sessions aren't stored on
+// their own: their approvals go to the parent session when they complete.
+func (r *LocalRuntime) persistToolApprovals(ctx context.Context, sess *session.Session) {
+	if sess.IsSubSession() {
+		return
+	}
+	if err := r.sessionStore.UpdateSession(ctx, sess); err != nil {
+		slog.Warn("Failed to persist the tool approvals", "session_id", sess.ID, "error", err)
 	}
 }
 
 // executeToolWithHandler is a common helper that handles tool execution, error handling,
 // event emission, and session updates. It reduces duplication between runTool and runAgentTool.
+// cached tells the events that execute serves the result from the cache.
 func (r *LocalRuntime) executeToolWithHandler(
 	ctx context.Context,
 	toolCall tools.ToolCall,
 	tool tools.Tool,
+	edit *tools.ArgumentsEdit,
+	cached bool,
 	events chan Event,
 	sess *session.Session,
 	a *agent.Agent,
@@ -263,11 +368,28 @@ func (r *LocalRuntime) executeToolWithHandler(
 	))
 	defer span.End()
 
-	events <- ToolCall(toolCall, tool, a.Name())
+	events <- &ToolCallEvent{
+		Type:           "tool_call",
+		ToolCall:       toolCall,
+		ToolDefinition: tool,
+		ArgumentsEdit:  edit,
+		Cached:         cached,
+		AgentContext:   newAgentContext(a.Name()),
+	}
 
-	res, duration, err := execute(ctx)
+	fileChanges := r.newFileChangeRecorder(sess)
+	res, duration, err := execute(tools.WithFileChangeReporter(ctx, fileChanges))
+	changes := fileChanges.flush()
+	for _, change := range changes {
+		events <- FileChanged(toolCall.ID, change, sess.ID, a.Name())
+	}
+	r.invalidateToolResults(tool, changes)
+	if run := sessionRunFromContext(ctx); run != nil {
+		run.recordToolCall(sess.ID, toolCall.Function.Name, changes)
+	}
 
 	telemetry.RecordToolCall(ctx, toolCall.Function.Name, sess.ID, a.Name(), duration, err)
+	r.metrics.recordToolCall(ctx, a.Name(), toolCall.Function.Name, duration, err != nil || (res != nil && res.IsError))
 
 	if err != nil {
 		if errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled) {
@@ -285,7 +407,15 @@ func (r *LocalRuntime) executeToolWithHandler(
 		slog.Debug("Tool call completed", "tool", toolCall.Function.Name, "output_length", len(res.Output))
 	}
 
-	events <- ToolCallResponse(toolCall.ID, tool, res, res.Output, a.Name())
+	events <- &ToolCallResponseEvent{
+		Type:           "tool_call_response",
+		ToolCallID:     toolCall.ID,
+		ToolDefinition: tool,
+		Response:       res.Output,
+		Result:         res,
+		Cached:         cached,
+		AgentContext:   newAgentContext(a.Name()),
+	}
 
 	// Ensure tool response content is not empty for API compatibility
 	content := res.Output
@@ -293,16 +423,50 @@ func (r *LocalRuntime) executeToolWithHandler(
 		content = "(no output)"
 	}
 
+	// The model only gets a truncated version of long outputs, the events
+	// above carry the full output.
+	content, truncation := r.toolOutputLimitFor(tool).Truncate(content)
+	if truncation != nil {
+		slog.Debug("Truncated tool output", "tool", toolCall.Function.Name, "policy", truncation.Policy, "original_bytes", truncation.OriginalBytes, "kept_bytes", truncation.KeptBytes)
+	}
+
+	// The model is told about the edited arguments: its tool call still
+	// has the ones it chose.
+	if edit != nil {
+		content = fmt.Sprintf("The user changed the arguments of the call to: %s\n\n%s", edit.Edited, content)
+	}
+
+	// The sources retrieved by RAG tools are recorded for the annotations
+	// of the answer. The model is asked to cite them by index if the agent
+	// cites its sources.
+	if meta, ok := res.Meta.(builtin.RAGQueryMeta); ok && !res.IsError && len(meta.Sources) > 0 {
+		if run := sessionRunFromContext(ctx); run != nil {
+			sources := run.addSources(sess.ID, meta.Sources)
+			if a.CiteSources() {
+				content += "\n\n" + citationInstruction(sources)
+			}
+		}
+	}
+
 	toolResponseMsg := chat.Message{
-		Role:       chat.MessageRoleTool,
-		Content:    content,
-		ToolCallID: toolCall.ID,
-		IsError:    res.IsError,
-		CreatedAt:  time.Now().Format(time.RFC3339),
+		Role:          chat.MessageRoleTool,
+		Content:       content,
+		ToolCallID:    toolCall.ID,
+		IsError:       res.IsError,
+		Truncation:    truncation,
+		ArgumentsEdit: edit,
 	}
 
-	// If the tool result contains images, attach them as MultiContent
+	// If the tool result contains images, attach them as MultiContent. The
+	// text content stands for the images with placeholders, for the
+	// providers that only send the text of tool results.
 	if len(res.Images) > 0 {
+		placeholders := make([]string, 0, len(res.Images))
+		for _, img := range res.Images {
+			placeholders = append(placeholders, chat.ImagePlaceholder(img.Data, img.MimeType))
+		}
+		toolResponseMsg.Content = content + "\n" + strings.Join(placeholders, "\n")
+
 		multiContent := []chat.MessagePart{
 			{
 				Type: chat.MessagePartTypeText,
@@ -324,8 +488,69 @@ func (r *LocalRuntime) executeToolWithHandler(
 	addAgentMessage(sess, a, &toolResponseMsg, events)
 }
 
+// toolOutputLimitFor returns the limit on the size of the tool's outputs sent
+// to the model: the runtime's limit, overridden by the tool's toolset.
+func (r *LocalRuntime) toolOutputLimitFor(tool tools.Tool) tools.OutputLimit {
+	limit := r.toolOutputLimit
+	if override := tool.OutputLimit; override != nil {
+		if override.MaxBytes != 0 {
+			limit.MaxBytes = override.MaxBytes
+		}
+		if override.Policy != "" {
+			limit.Policy = override.Policy
+		}
+	}
+	return limit
+}
+
+// toolTimeoutFor returns how long a call to the tool may run: the runtime's
+// limit, overridden by the tool or its toolset. Zero or less means no limit.
+func (r *LocalRuntime) toolTimeoutFor(tool tools.Tool) time.Duration {
+	if tool.Timeout != 0 {
+		return tool.Timeout
+	}
+	return r.toolTimeout
+}
+
+// errToolTimeout is returned by callWithTimeout when the handler runs longer
+// than the timeout.
+var errToolTimeout = errors.New("tool call timed out")
+
+// callWithTimeout calls handler, giving up after timeout. The handler's
+// context is canceled then, but a handler that ignores its context, e.g.
+// one waiting on a hung server, is left running in the background rather
+// than blocking the agent.
+func callWithTimeout(ctx context.Context, handler tools.ToolHandler, toolCall tools.ToolCall, timeout time.Duration) (*tools.ToolCallResult, error) {
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type handlerResult struct {
+		res *tools.ToolCallResult
+		err error
+	}
+	done := make(chan handlerResult, 1)
+	go func() {
+		res, err := handler(callCtx, toolCall)
+		done <- handlerResult{res: res, err: err}
+	}()
+
+	select {
+	case result := <-done:
+		// Handlers that honor their context fail when it expires.
+		if result.err != nil && ctx.Err() == nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+			return nil, errToolTimeout
+		}
+		return result.res, result.err
+	case <-callCtx.Done():
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errToolTimeout
+	}
+}
+
 // runTool executes agent tools from toolsets (MCP, filesystem, etc.).
-func (r *LocalRuntime) runTool(ctx context.Context, tool tools.Tool, toolCall tools.ToolCall, events chan Event, sess *session.Session, a *agent.Agent) {
+func (r *LocalRuntime) runTool(ctx context.Context, tool tools.Tool, toolCall tools.ToolCall, edit *tools.ArgumentsEdit, events chan Event, sess *session.Session, a *agent.Agent) {
 	hooksExec := r.getHooksExecutor(a)
 
 	// Execute pre-tool hooks if configured.
@@ -337,18 +562,73 @@ func (r *LocalRuntime) runTool(ctx context.Context, tool tools.Tool, toolCall to
 		toolCall = modifiedTC
 	}
 
-	r.executeToolWithHandler(ctx, toolCall, tool, events, sess, a, "runtime.tool.handler",
+	// The read-only tools called again with the same arguments are served
+	// from the cache, when enabled.
+	cached, storeResult := r.cachedToolResult(sess.ID, a.Name(), tool, toolCall)
+	if cached != nil {
+		slog.Debug("Tool result served from the cache", "tool", toolCall.Function.Name, "agent", a.Name(), "session_id", sess.ID)
+	}
+
+	r.executeToolWithHandler(ctx, toolCall, tool, edit, cached != nil, events, sess, a, "runtime.tool.handler",
 		func(ctx context.Context) (*tools.ToolCallResult, time.Duration, error) {
-			res, err := tool.Handler(ctx, toolCall)
+			if cached != nil {
+				return cached, 0, nil
+			}
+
+			// The secrets are masked before the result is cached: the
+			// original output is never kept.
+			res, err := r.callToolHandler(ctx, tool, toolCall, events, sess, a)
+			res = r.redactToolResult(sess, a, tool, toolCall, res, events)
+			if err == nil && storeResult != nil {
+				storeResult(res)
+			}
 			return res, 0, err
 		})
 
+	if isThinkToolCall(toolCall) {
+		if thought := thoughtFromArguments(toolCall.Function.Arguments); thought != "" {
+			events <- AgentThought(a.Name(), sess.ID, thought)
+		}
+	}
+
 	// Execute post-tool hooks if configured.
 	if hooksExec != nil && hooksExec.HasPostToolUseHooks() {
 		r.executePostToolHook(ctx, hooksExec, sess, toolCall, events, a)
 	}
 }
 
+// callToolHandler calls the handler of tool, within the tool's timeout,
+// once its toolset can take one more call.
+func (r *LocalRuntime) callToolHandler(ctx context.Context, tool tools.Tool, toolCall tools.ToolCall, events chan Event, sess *session.Session, a *agent.Agent) (*tools.ToolCallResult, error) {
+	release, err := r.acquireToolSlot(ctx, tool, toolCall, events, a)
+	if err != nil {
+		return nil, err
+	}
+
+	// The slot is released when the handler returns, even after a timeout:
+	// an abandoned call still keeps its toolset busy.
+	handler := tool.Handler
+	if release != nil {
+		handler = func(ctx context.Context, toolCall tools.ToolCall) (*tools.ToolCallResult, error) {
+			defer release()
+			return tool.Handler(ctx, toolCall)
+		}
+	}
+
+	timeout := r.toolTimeoutFor(tool)
+	if timeout <= 0 {
+		return handler(ctx, toolCall)
+	}
+
+	res, err := callWithTimeout(ctx, handler, toolCall, timeout)
+	if errors.Is(err, errToolTimeout) {
+		slog.Warn("Tool call timed out", "tool", toolCall.Function.Name, "timeout", timeout, "agent", a.Name(), "session_id", sess.ID)
+		events <- ToolCallTimeout(toolCall, tool, timeout, a.Name())
+		return tools.ResultError(fmt.Sprintf("The tool call timed out after %s and was abandoned. Try a smaller or different operation.", timeout)), nil
+	}
+	return res, err
+}
+
 // newHooksInput builds a hooks.Input from the common tool-call fields.
 func (r *LocalRuntime) newHooksInput(sess *session.Session, toolCall tools.ToolCall) *hooks.Input {
 	return &hooks.Input{
@@ -422,12 +702,13 @@ func parseToolInput(arguments string) map[string]any {
 	return result
 }
 
-func (r *LocalRuntime) runAgentTool(ctx context.Context, handler ToolHandlerFunc, sess *session.Session, toolCall tools.ToolCall, tool tools.Tool, events chan Event, a *agent.Agent) {
-	r.executeToolWithHandler(ctx, toolCall, tool, events, sess, a, "runtime.tool.handler.runtime",
+func (r *LocalRuntime) runAgentTool(ctx context.Context, handler ToolHandlerFunc, sess *session.Session, toolCall tools.ToolCall, tool tools.Tool, edit *tools.ArgumentsEdit, events chan Event, a *agent.Agent) {
+	r.executeToolWithHandler(ctx, toolCall, tool, edit, false, events, sess, a, "runtime.tool.handler.runtime",
 		func(ctx context.Context) (*tools.ToolCallResult, time.Duration, error) {
 			start := time.Now()
 			res, err := handler(ctx, sess, toolCall, events)
-			return res, time.Since(start), err
+			duration := time.Since(start)
+			return r.redactToolResult(sess, a, tool, toolCall, res, events), duration, err
 		})
 }
 
@@ -437,6 +718,13 @@ func addAgentMessage(sess *session.Session, a *agent.Agent, msg *chat.Message, e
 	events <- MessageAdded(sess.ID, agentMsg, a.Name())
 }
 
+// addSystemReminder adds a system reminder for agent a to the session and
+// emits the event.
+func addSystemReminder(sess *session.Session, a *agent.Agent, text string, events chan Event, opts ...session.ReminderOpt) {
+	reminder := sess.AddSystemReminder(text, append([]session.ReminderOpt{session.WithReminderAgent(a.Name())}, opts...)...)
+	events <- MessageAdded(sess.ID, reminder, a.Name())
+}
+
 // addToolErrorResponse adds a tool error response to the session and emits the event.
 // This consolidates the common pattern used by validation, rejection, and cancellation responses.
 func (r *LocalRuntime) addToolErrorResponse(_ context.Context, sess *session.Session, toolCall tools.ToolCall, tool tools.Tool, events chan Event, a *agent.Agent, errorMsg string) {
@@ -447,7 +735,6 @@ func (r *LocalRuntime) addToolErrorResponse(_ context.Context, sess *session.Ses
 		Content:    errorMsg,
 		ToolCallID: toolCall.ID,
 		IsError:    true,
-		CreatedAt:  time.Now().Format(time.RFC3339),
 	}
 	addAgentMessage(sess, a, &toolResponseMsg, events)
 }
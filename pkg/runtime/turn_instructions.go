@@ -0,0 +1,90 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+const (
+	// maxTurnInstructionsTokens is the maximum size, in estimated tokens, of
+	// the section a toolset adds to the instructions of a turn.
+	maxTurnInstructionsTokens = 1000
+	// turnInstructionsTimeout is how long a toolset is given to build its
+	// section, so that a slow one doesn't hold the turn.
+	turnInstructionsTimeout = 5 * time.Second
+)
+
+// turnInstructions returns the sections the toolsets of the agent add to the
+// instructions of the turn. A toolset that fails to build its section is
+// left out, with a warning: the turn goes on without it.
+func turnInstructions(ctx context.Context, sess *session.Session, a *agent.Agent, events chan Event) []string {
+	turn := tools.TurnInfo{
+		SessionID:  sess.ID,
+		AgentName:  a.Name(),
+		WorkingDir: sess.WorkingDir,
+	}
+
+	var sections []string
+	for _, toolSet := range a.ToolSets() {
+		// The toolsets that failed to start have no state to report.
+		if s, ok := toolSet.(*tools.StartableToolSet); ok && !s.IsStarted() {
+			continue
+		}
+		dynamic, ok := tools.As[tools.DynamicInstructable](toolSet)
+		if !ok {
+			continue
+		}
+
+		section, err := instructionsForTurn(ctx, dynamic, turn)
+		if err != nil {
+			desc := tools.DescribeToolSet(toolSet)
+			slog.Warn("Failed to get the turn instructions of a toolset", "agent", a.Name(), "toolset", desc, "error", err)
+			events <- WarningWithCode(
+				ErrorCodeToolsetInstructionsFailed,
+				fmt.Sprintf("The instructions of toolset %s couldn't be refreshed: %s", desc, err),
+				"",
+				a.Name(),
+			)
+			continue
+		}
+
+		if section = strings.TrimSpace(section); section != "" {
+			sections = append(sections, capTurnInstructions(section, maxTurnInstructionsTokens))
+		}
+	}
+	return sections
+}
+
+func instructionsForTurn(ctx context.Context, dynamic tools.DynamicInstructable, turn tools.TurnInfo) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, turnInstructionsTimeout)
+	defer cancel()
+
+	return dynamic.InstructionsForTurn(ctx, turn)
+}
+
+// capTurnInstructions cuts a section to about maxTokens tokens, at a line
+// boundary when possible.
+func capTurnInstructions(section string, maxTokens int) string {
+	// The same 4 characters per token as compaction.EstimateMessageTokens.
+	maxBytes := maxTokens * 4
+	if len(section) <= maxBytes {
+		return section
+	}
+
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(section[cut]) {
+		cut--
+	}
+	if i := strings.LastIndexByte(section[:cut], '\n'); i >= cut/2 {
+		cut = i
+	}
+	return strings.TrimRight(section[:cut], "\n") + "\n[... truncated]"
+}
@@ -0,0 +1,114 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/model/provider/fake"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// stepToolSet reports its current step in the instructions of each turn,
+// and moves to the next one when its tool is called.
+type stepToolSet struct {
+	stubToolSet
+
+	step atomic.Int32
+}
+
+func newStepToolSet() *stepToolSet {
+	s := &stepToolSet{}
+	s.tools = []tools.Tool{{
+		Name:       "advance",
+		Parameters: map[string]any{},
+		Handler: func(context.Context, tools.ToolCall) (*tools.ToolCallResult, error) {
+			s.step.Add(1)
+			return tools.ResultSuccess("ok"), nil
+		},
+	}}
+	return s
+}
+
+func (s *stepToolSet) InstructionsForTurn(_ context.Context, turn tools.TurnInfo) (string, error) {
+	return fmt.Sprintf("Agent %s is at step %d", turn.AgentName, s.step.Load()), nil
+}
+
+// failingInstructionsToolSet fails to build its instructions.
+type failingInstructionsToolSet struct {
+	stubToolSet
+}
+
+func (*failingInstructionsToolSet) InstructionsForTurn(context.Context, tools.TurnInfo) (string, error) {
+	return "", errors.New("state unavailable")
+}
+
+func TestTurnInstructions(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().
+			Expect(fake.HasMessage(chat.MessageRoleSystem, "<turn_context>\nAgent root is at step 0\n</turn_context>")).
+			ToolCall("call_1", "advance", `{}`),
+		fake.NewTurn().
+			Expect(fake.HasMessage(chat.MessageRoleSystem, "<turn_context>\nAgent root is at step 1\n</turn_context>")).
+			Content("Done."),
+	)
+
+	root := agent.New("root", "Be helpful.", agent.WithModel(prov), agent.WithToolSets(newStepToolSet(), &failingInstructionsToolSet{}))
+
+	result, err := RunOnce(t.Context(), RunOnceRequest{
+		Team:        team.New(team.WithAgents(root)),
+		Prompt:      "Advance",
+		AutoApprove: true,
+		Options:     []Opt{WithSessionCompaction(false), WithModelStore(mockModelStore{})},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Done.", result.Output)
+
+	// The sections come after the instructions of the agent, and are
+	// refreshed: no request has the sections of another turn.
+	for i, req := range prov.Requests() {
+		var system []string
+		for _, msg := range req.Messages {
+			if msg.Role == chat.MessageRoleSystem {
+				system = append(system, msg.Content)
+			}
+		}
+		instructions := strings.Join(system, "\n")
+		assert.Less(t, strings.Index(instructions, "Be helpful."), strings.Index(instructions, "<turn_context>"))
+		assert.Equal(t, 1, strings.Count(instructions, "<turn_context>"), "request %d", i)
+	}
+
+	// The failing toolset only warns, once per turn.
+	var warnings int
+	for _, event := range result.Events {
+		if w, ok := event.(*WarningEvent); ok && w.Code == ErrorCodeToolsetInstructionsFailed {
+			warnings++
+			assert.Contains(t, w.Message, "state unavailable")
+		}
+	}
+	assert.Equal(t, 2, warnings)
+}
+
+func TestCapTurnInstructions(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "short", capTurnInstructions("short", 10))
+
+	section := strings.Repeat("line of text\n", 10)
+	capped := capTurnInstructions(section, 10)
+	assert.Equal(t, "line of text\nline of text\nline of text\n[... truncated]", capped)
+
+	// Multi-byte characters aren't split.
+	capped = capTurnInstructions(strings.Repeat("é", 30), 10)
+	assert.True(t, strings.HasSuffix(capped, "\n[... truncated]"))
+	assert.Equal(t, strings.Repeat("é", 20), strings.TrimSuffix(capped, "\n[... truncated]"))
+}
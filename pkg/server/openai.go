@@ -0,0 +1,445 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/config"
+	"github.com/docker/docker-agent/pkg/runtime"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// openAIHandler serves an OpenAI-compatible chat completions API, so that
+// tools that only speak the OpenAI API can talk to the agents of the server.
+//
+// The model of a request selects the agent: "<source>/<agent>", "<source>"
+// for the default agent of a source, or "<agent>" when the server has a
+// single source. Requests are stateless: each one runs the agent in a new
+// session built from the messages of the request.
+type openAIHandler struct {
+	sources config.Sources
+	// loadTeam loads the team of an agent source.
+	loadTeam func(ctx context.Context, source string) (*team.Team, error)
+	// runtimeOpts are added to the options of the runtimes.
+	runtimeOpts []runtime.Opt
+}
+
+func (h *openAIHandler) register(e *echo.Echo) {
+	e.GET("/v1/models", h.listModels)
+	e.POST("/v1/chat/completions", h.chatCompletions)
+}
+
+type openAIModel struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+type openAIModelList struct {
+	Object string        `json:"object"`
+	Data   []openAIModel `json:"data"`
+}
+
+type openAIChatMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+type openAIChatRequest struct {
+	Model    string              `json:"model"`
+	Messages []openAIChatMessage `json:"messages"`
+	Stream   bool                `json:"stream"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+type openAIResponseMessage struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+type openAIChoice struct {
+	Index        int                    `json:"index"`
+	Message      *openAIResponseMessage `json:"message,omitempty"`
+	Delta        *openAIResponseMessage `json:"delta,omitempty"`
+	FinishReason *string                `json:"finish_reason"`
+}
+
+type openAIChatResponse struct {
+	ID      string         `json:"id"`
+	Object  string         `json:"object"`
+	Created int64          `json:"created"`
+	Model   string         `json:"model"`
+	Choices []openAIChoice `json:"choices"`
+	Usage   *openAIUsage   `json:"usage,omitempty"`
+}
+
+type openAIErrorBody struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    string `json:"code,omitempty"`
+}
+
+type openAIError struct {
+	Error openAIErrorBody `json:"error"`
+}
+
+func openAIErrorResponse(c echo.Context, status int, errType, code, message string) error {
+	return c.JSON(status, openAIError{Error: openAIErrorBody{Message: message, Type: errType, Code: code}})
+}
+
+// errModelNotFound is returned when the model of a request matches no agent.
+var errModelNotFound = errors.New("model not found")
+
+func (h *openAIHandler) listModels(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	models := []openAIModel{}
+	for name, source := range h.sources {
+		cfg, err := config.Load(ctx, source)
+		if err != nil {
+			slog.Error("Failed to load config from API source", "key", name, "error", err)
+			continue
+		}
+
+		models = append(models, openAIModel{ID: name, Object: "model", OwnedBy: "docker-agent"})
+		if len(cfg.Agents) > 1 {
+			for _, a := range cfg.Agents {
+				models = append(models, openAIModel{ID: name + "/" + a.Name, Object: "model", OwnedBy: "docker-agent"})
+			}
+		}
+	}
+
+	slices.SortFunc(models, func(a, b openAIModel) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return c.JSON(http.StatusOK, openAIModelList{Object: "list", Data: models})
+}
+
+// resolveModel returns the source and the agent, empty for the default
+// agent, selected by a model name.
+func (h *openAIHandler) resolveModel(model string) (source, agentName string, err error) {
+	if _, ok := h.sources[model]; ok {
+		return model, "", nil
+	}
+	for name := range h.sources {
+		if agentName, ok := strings.CutPrefix(model, name+"/"); ok && agentName != "" {
+			return name, agentName, nil
+		}
+	}
+	if len(h.sources) == 1 {
+		for name := range h.sources {
+			return name, model, nil
+		}
+	}
+	return "", "", errModelNotFound
+}
+
+func (h *openAIHandler) chatCompletions(c echo.Context) error {
+	var req openAIChatRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return openAIErrorResponse(c, http.StatusBadRequest, "invalid_request_error", "", fmt.Sprintf("invalid request body: %v", err))
+	}
+	if len(req.Messages) == 0 {
+		return openAIErrorResponse(c, http.StatusBadRequest, "invalid_request_error", "", "messages must not be empty")
+	}
+
+	sourceName, agentName, err := h.resolveModel(req.Model)
+	if err != nil {
+		return openAIErrorResponse(c, http.StatusNotFound, "invalid_request_error", "model_not_found", fmt.Sprintf("the model %q does not exist", req.Model))
+	}
+
+	ctx := c.Request().Context()
+	t, err := h.loadTeam(ctx, sourceName)
+	if err != nil {
+		return openAIErrorResponse(c, http.StatusInternalServerError, "server_error", "", fmt.Sprintf("failed to load agent: %v", err))
+	}
+	defer func() {
+		if err := t.StopToolSets(context.WithoutCancel(ctx)); err != nil {
+			slog.Error("Failed to stop tool sets", "error", err)
+		}
+	}()
+
+	a, err := t.DefaultAgent()
+	if agentName != "" {
+		a, err = t.Agent(agentName)
+	}
+	if err != nil {
+		return openAIErrorResponse(c, http.StatusNotFound, "invalid_request_error", "model_not_found", fmt.Sprintf("the model %q does not exist", req.Model))
+	}
+
+	sess, err := openAISession(a, req.Messages)
+	if err != nil {
+		return openAIErrorResponse(c, http.StatusBadRequest, "invalid_request_error", "", err.Error())
+	}
+
+	opts := append([]runtime.Opt{
+		runtime.WithCurrentAgent(a.Name()),
+		runtime.WithManagedOAuth(false),
+	}, h.runtimeOpts...)
+	rt, err := runtime.NewLocalRuntime(t, opts...)
+	if err != nil {
+		return openAIErrorResponse(c, http.StatusInternalServerError, "server_error", "", fmt.Sprintf("failed to create runtime: %v", err))
+	}
+
+	completion := &openAICompletion{
+		id:      "chatcmpl-" + sess.ID,
+		created: time.Now().Unix(),
+		model:   req.Model,
+		agent:   a.Name(),
+	}
+
+	events := rt.RunStream(ctx, sess)
+	if req.Stream {
+		return completion.stream(ctx, c, rt, sess, events)
+	}
+	return completion.respond(ctx, c, rt, sess, events)
+}
+
+// openAISession creates a session from the messages of a request. Tool
+// messages are dropped: the tools of the agent are internal to the server.
+func openAISession(a *agent.Agent, messages []openAIChatMessage) (*session.Session, error) {
+	sess := session.New(
+		session.WithMaxIterations(a.MaxIterations()),
+		session.WithMaxConsecutiveToolCalls(a.MaxConsecutiveToolCalls()),
+		session.WithMaxOldToolCallTokens(a.MaxOldToolCallTokens()),
+		session.WithToolsApproved(true),
+		session.WithNonInteractive(true),
+	)
+
+	for _, m := range messages {
+		text, parts, err := openAIMessageContent(m.Content)
+		if err != nil {
+			return nil, fmt.Errorf("invalid content of %s message: %w", m.Role, err)
+		}
+
+		switch m.Role {
+		case "system", "developer":
+			sess.AddMessage(session.SystemMessage(text))
+		case "user":
+			sess.AddMessage(session.UserMessage(text, parts...))
+		case "assistant":
+			sess.AddMessage(session.NewAgentMessage(a.Name(), &chat.Message{
+				Role:    chat.MessageRoleAssistant,
+				Content: text,
+			}))
+		case "tool", "function":
+			continue
+		default:
+			return nil, fmt.Errorf("unsupported message role %q", m.Role)
+		}
+	}
+
+	return sess, nil
+}
+
+// openAIMessageContent decodes the content of a message, either a string or
+// an array of parts. Parts are only returned when some are not text.
+func openAIMessageContent(raw json.RawMessage) (string, []chat.MessagePart, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil, nil
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, nil, nil
+	}
+
+	var parts []chat.MessagePart
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return "", nil, errors.New("content must be a string or an array of parts")
+	}
+
+	var texts []string
+	onlyText := true
+	for _, part := range parts {
+		if part.Type == chat.MessagePartTypeText {
+			texts = append(texts, part.Text)
+		} else {
+			onlyText = false
+		}
+	}
+	if onlyText {
+		parts = nil
+	}
+	return strings.Join(texts, "\n"), parts, nil
+}
+
+// openAICompletion translates the events of a run into an OpenAI chat
+// completion.
+type openAICompletion struct {
+	id      string
+	created int64
+	model   string
+	agent   string
+}
+
+func (o *openAICompletion) response(object string, choice openAIChoice, usage *openAIUsage) openAIChatResponse {
+	return openAIChatResponse{
+		ID:      o.id,
+		Object:  object,
+		Created: o.created,
+		Model:   o.model,
+		Choices: []openAIChoice{choice},
+		Usage:   usage,
+	}
+}
+
+// handleEvent declines the requests for user input, which clients can't
+// answer, and returns the finish reason and the error of the run, if the
+// event sets them.
+func (o *openAICompletion) handleEvent(ctx context.Context, rt runtime.Runtime, sessionID string, event runtime.Event) (finishReason string, runErr *runtime.ErrorEvent) {
+	switch e := event.(type) {
+	case *runtime.ToolCallConfirmationEvent:
+		if err := rt.Resume(ctx, sessionID, runtime.ResumeReject("tool calls can't be confirmed through the OpenAI-compatible API")); err != nil {
+			slog.Warn("Failed to reject tool call", "error", err)
+		}
+	case *runtime.ElicitationRequestEvent:
+		if err := rt.ResumeElicitation(ctx, sessionID, tools.ElicitationActionDecline, nil); err != nil {
+			slog.Warn("Failed to decline elicitation", "error", err)
+		}
+	case *runtime.MaxIterationsReachedEvent:
+		return "length", nil
+	case *runtime.ErrorEvent:
+		return "", e
+	}
+	return "", nil
+}
+
+// errorStatus returns the HTTP status of a failed run, given the code of its
+// error.
+func errorStatus(code runtime.ErrorCode) int {
+	switch code {
+	case runtime.ErrorCodeProviderRateLimited:
+		return http.StatusTooManyRequests
+	case runtime.ErrorCodeProviderContextOverflow:
+		return http.StatusBadRequest
+	case runtime.ErrorCodeProviderAuthFailed, runtime.ErrorCodeProviderRequestFailed:
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func usageOf(sess *session.Session) *openAIUsage {
+	return &openAIUsage{
+		PromptTokens:     sess.InputTokens,
+		CompletionTokens: sess.OutputTokens,
+		TotalTokens:      sess.InputTokens + sess.OutputTokens,
+	}
+}
+
+func (o *openAICompletion) respond(ctx context.Context, c echo.Context, rt runtime.Runtime, sess *session.Session, events <-chan runtime.Event) error {
+	finishReason := "stop"
+	var runErr *runtime.ErrorEvent
+	for event := range events {
+		reason, errEvent := o.handleEvent(ctx, rt, sess.ID, event)
+		if reason != "" {
+			finishReason = reason
+		}
+		if errEvent != nil {
+			runErr = errEvent
+		}
+	}
+
+	if runErr != nil {
+		return openAIErrorResponse(c, errorStatus(runErr.Code), "server_error", string(runErr.Code), runErr.Error)
+	}
+
+	return c.JSON(http.StatusOK, o.response("chat.completion", openAIChoice{
+		Message: &openAIResponseMessage{
+			Role:    "assistant",
+			Content: sess.GetLastAssistantMessageContent(),
+		},
+		FinishReason: &finishReason,
+	}, usageOf(sess)))
+}
+
+func (o *openAICompletion) stream(ctx context.Context, c echo.Context, rt runtime.Runtime, sess *session.Session, events <-chan runtime.Event) error {
+	c.Response().Header().Set("Content-Type", "text/event-stream")
+	c.Response().Header().Set("Cache-Control", "no-cache")
+	c.Response().Header().Set("Connection", "keep-alive")
+	c.Response().WriteHeader(http.StatusOK)
+
+	send := func(v any) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			slog.Error("Failed to marshal chat completion chunk", "error", err)
+			return
+		}
+		fmt.Fprintf(c.Response(), "data: %s\n\n", data)
+		c.Response().Flush()
+	}
+
+	finishReason := "stop"
+	var runErr *runtime.ErrorEvent
+	// role is sent with the first delta only.
+	role := "assistant"
+	// separate is set when a tool call ends a turn of the agent, whose next
+	// answer is separated from the previous one.
+	var sentContent, separate bool
+
+	for event := range events {
+		switch e := event.(type) {
+		case *runtime.AgentChoiceEvent:
+			// Sub-agents answer the agent, not the client.
+			if e.AgentName != o.agent || e.Content == "" {
+				continue
+			}
+			content := e.Content
+			if separate {
+				content = "\n\n" + content
+				separate = false
+			}
+			send(o.response("chat.completion.chunk", openAIChoice{
+				Delta: &openAIResponseMessage{Role: role, Content: content},
+			}, nil))
+			role = ""
+			sentContent = true
+		case *runtime.ToolCallEvent:
+			if e.AgentName == o.agent && sentContent {
+				separate = true
+			}
+		}
+
+		reason, errEvent := o.handleEvent(ctx, rt, sess.ID, event)
+		if reason != "" {
+			finishReason = reason
+		}
+		if errEvent != nil {
+			runErr = errEvent
+		}
+	}
+
+	if runErr != nil {
+		send(openAIError{Error: openAIErrorBody{Message: runErr.Error, Type: "server_error", Code: string(runErr.Code)}})
+	} else {
+		send(o.response("chat.completion.chunk", openAIChoice{
+			Delta:        &openAIResponseMessage{Role: role},
+			FinishReason: &finishReason,
+		}, usageOf(sess)))
+	}
+
+	fmt.Fprint(c.Response(), "data: [DONE]\n\n")
+	c.Response().Flush()
+	return nil
+}
@@ -0,0 +1,242 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/config"
+	"github.com/docker/docker-agent/pkg/model/provider/fake"
+	"github.com/docker/docker-agent/pkg/modelsdev"
+	"github.com/docker/docker-agent/pkg/runtime"
+	"github.com/docker/docker-agent/pkg/team"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+type noModelStore struct {
+	runtime.ModelStore
+}
+
+func (noModelStore) GetModel(context.Context, string) (*modelsdev.Model, error) {
+	return nil, nil
+}
+
+// startOpenAIServer serves the OpenAI-compatible API with a single source
+// named "assistant", whose team is built by newTeam.
+func startOpenAIServer(t *testing.T, newTeam func() *team.Team) string {
+	t.Helper()
+
+	h := &openAIHandler{
+		sources: config.Sources{"assistant": config.NewBytesSource("assistant", nil)},
+		loadTeam: func(_ context.Context, source string) (*team.Team, error) {
+			if source != "assistant" {
+				return nil, errors.New("unexpected source " + source)
+			}
+			return newTeam(), nil
+		},
+		runtimeOpts: []runtime.Opt{runtime.WithSessionCompaction(false), runtime.WithModelStore(noModelStore{})},
+	}
+	e := echo.New()
+	h.register(e)
+
+	srv := httptest.NewServer(e)
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func postChatCompletion(t *testing.T, url, body string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, url+"/v1/chat/completions", strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+// newLookupTeam creates a team whose root agent calls a lookup tool before
+// answering.
+func newLookupTeam(prov *fake.ScriptedProvider) func() *team.Team {
+	lookup := []tools.Tool{{
+		Name:       "lookup",
+		Parameters: map[string]any{},
+		Handler: func(context.Context, tools.ToolCall) (*tools.ToolCallResult, error) {
+			return tools.ResultSuccess("Paris"), nil
+		},
+	}}
+	return func() *team.Team {
+		return team.New(team.WithAgents(agent.New("root", "You are a helpful agent",
+			agent.WithModel(prov),
+			agent.WithToolSets(staticToolSet(lookup)),
+		)))
+	}
+}
+
+type staticToolSet []tools.Tool
+
+func (s staticToolSet) Tools(context.Context) ([]tools.Tool, error) {
+	return s, nil
+}
+
+func TestOpenAI_ChatCompletion(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().
+			ToolCall("call_1", "lookup", `{}`).
+			Usage(10, 2).
+			Expect(
+				fake.HasMessage(chat.MessageRoleSystem, "Answer briefly"),
+				fake.HasMessage(chat.MessageRoleAssistant, "Hello!"),
+				fake.LastMessage(chat.MessageRoleUser, "What is the capital of France?"),
+			),
+		fake.NewTurn().
+			Content("The capital ", "is Paris.").
+			Usage(20, 5).
+			Expect(fake.LastMessage(chat.MessageRoleTool, "Paris")),
+	)
+	url := startOpenAIServer(t, newLookupTeam(prov))
+
+	resp := postChatCompletion(t, url, `{
+		"model": "root",
+		"messages": [
+			{"role": "system", "content": "Answer briefly"},
+			{"role": "user", "content": "Hi"},
+			{"role": "assistant", "content": "Hello!"},
+			{"role": "user", "content": [{"type": "text", "text": "What is the capital of France?"}]}
+		]
+	}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var completion openAIChatResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&completion))
+	assert.Equal(t, "chat.completion", completion.Object)
+	assert.Equal(t, "root", completion.Model)
+	require.Len(t, completion.Choices, 1)
+	assert.Equal(t, "assistant", completion.Choices[0].Message.Role)
+	assert.Equal(t, "The capital is Paris.", completion.Choices[0].Message.Content)
+	assert.Equal(t, "stop", *completion.Choices[0].FinishReason)
+	assert.Equal(t, &openAIUsage{PromptTokens: 20, CompletionTokens: 5, TotalTokens: 25}, completion.Usage)
+}
+
+func TestOpenAI_ChatCompletionStream(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().
+			Content("Let me check.").
+			ToolCall("call_1", "lookup", `{}`),
+		fake.NewTurn().
+			Content("The capital ", "is Paris.").
+			Usage(20, 5),
+	)
+	url := startOpenAIServer(t, newLookupTeam(prov))
+
+	resp := postChatCompletion(t, url, `{
+		"model": "assistant",
+		"stream": true,
+		"messages": [{"role": "user", "content": "What is the capital of France?"}]
+	}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	var chunks []openAIChatResponse
+	var done bool
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+			continue
+		}
+		// Tool calls never leak to the client.
+		assert.NotContains(t, data, "tool_calls")
+		assert.NotContains(t, data, "lookup")
+
+		var chunk openAIChatResponse
+		require.NoError(t, json.Unmarshal([]byte(data), &chunk))
+		chunks = append(chunks, chunk)
+	}
+	require.NoError(t, scanner.Err())
+	require.True(t, done)
+
+	var content strings.Builder
+	for _, chunk := range chunks {
+		assert.Equal(t, "chat.completion.chunk", chunk.Object)
+		require.Len(t, chunk.Choices, 1)
+		content.WriteString(chunk.Choices[0].Delta.Content)
+	}
+	assert.Equal(t, "Let me check.\n\nThe capital is Paris.", content.String())
+	assert.Equal(t, "assistant", chunks[0].Choices[0].Delta.Role)
+
+	last := chunks[len(chunks)-1]
+	assert.Equal(t, "stop", *last.Choices[0].FinishReason)
+	assert.Equal(t, &openAIUsage{PromptTokens: 20, CompletionTokens: 5, TotalTokens: 25}, last.Usage)
+}
+
+func TestOpenAI_Errors(t *testing.T) {
+	prov := fake.NewScriptedProvider(t, "test/scripted",
+		fake.NewTurn().Error(errors.New("simulated failure")),
+	)
+	url := startOpenAIServer(t, newLookupTeam(prov))
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+		errMsg string
+	}{
+		{
+			name:   "invalid body",
+			body:   `{`,
+			status: http.StatusBadRequest,
+			errMsg: "invalid request body",
+		},
+		{
+			name:   "no messages",
+			body:   `{"model": "root", "messages": []}`,
+			status: http.StatusBadRequest,
+			errMsg: "messages must not be empty",
+		},
+		{
+			name:   "unknown agent",
+			body:   `{"model": "assistant/nobody", "messages": [{"role": "user", "content": "Hi"}]}`,
+			status: http.StatusNotFound,
+			code:   "model_not_found",
+			errMsg: `the model "assistant/nobody" does not exist`,
+		},
+		{
+			name:   "failed run",
+			body:   `{"model": "root", "messages": [{"role": "user", "content": "Hi"}]}`,
+			status: http.StatusBadGateway,
+			code:   "provider.request_failed",
+			errMsg: "simulated failure",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := postChatCompletion(t, url, tt.body)
+			assert.Equal(t, tt.status, resp.StatusCode)
+
+			var body openAIError
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Contains(t, body.Error.Message, tt.errMsg)
+			assert.Equal(t, tt.code, body.Error.Code)
+			assert.NotEmpty(t, body.Error.Type)
+		})
+	}
+}
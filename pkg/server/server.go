@@ -10,14 +10,18 @@ import (
 	"net"
 	"net/http"
 	"slices"
+	"strconv"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 
 	"github.com/docker/docker-agent/pkg/api"
+	"github.com/docker/docker-agent/pkg/api/events"
 	"github.com/docker/docker-agent/pkg/config"
+	"github.com/docker/docker-agent/pkg/runtime"
 	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
 	"github.com/docker/docker-agent/pkg/upstream"
 )
 
@@ -42,11 +46,15 @@ func New(ctx context.Context, sessionStore session.Store, runConfig *config.Runt
 	group.GET("/agents", s.getAgents)
 	// Get an agent by id
 	group.GET("/agents/:id", s.getAgentConfig)
+	// Describe the team of an agent: its agents, toolsets and delegations
+	group.GET("/agents/:id/describe", s.describeAgent)
 
 	// List all sessions
 	group.GET("/sessions", s.getSessions)
 	// Get a session by id
 	group.GET("/sessions/:id", s.getSession)
+	// Estimate the cost of the next turn of a session
+	group.GET("/sessions/:id/estimate", s.estimateSessionCost)
 	// Resume a session by id
 	group.POST("/sessions/:id/resume", s.resumeSession)
 	// Toggle YOLO mode for a session
@@ -68,9 +76,21 @@ func New(ctx context.Context, sessionStore session.Store, runConfig *config.Runt
 	// Follow-up: queue messages for end-of-turn processing
 	group.POST("/sessions/:id/followup", s.followUpSession)
 
+	// JSON Schema of a version of the wire format of the streamed events
+	group.GET("/events/schema", s.getEventsSchema)
+
 	// Agent tool count
 	group.GET("/agents/:id/:agent_name/tools/count", s.getAgentToolCount)
 
+	// OpenAI-compatible chat completions API
+	openAI := &openAIHandler{
+		sources: s.sm.Sources,
+		loadTeam: func(ctx context.Context, source string) (*team.Team, error) {
+			return s.sm.loadTeam(ctx, source, s.sm.runConfig)
+		},
+	}
+	openAI.register(e)
+
 	// Health check endpoint
 	group.GET("/ping", func(c echo.Context) error {
 		return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
@@ -206,13 +226,41 @@ func (s *Server) getSession(c echo.Context) error {
 	})
 }
 
+func (s *Server) estimateSessionCost(c echo.Context) error {
+	var opts []session.EstimateOpt
+	if v := c.QueryParam("output_tokens"); v != "" {
+		outputTokens, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || outputTokens < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid output_tokens: %s", v))
+		}
+		opts = append(opts, session.WithAssumedOutputTokens(outputTokens, outputTokens))
+	}
+
+	estimate, err := s.sm.EstimateNextTurnCost(c.Request().Context(), c.Param("id"), c.QueryParam("agent"), cmp.Or(c.QueryParam("agent_name"), "root"), c.QueryParam("draft"), opts...)
+	if err != nil {
+		if errors.Is(err, runtime.ErrNoPricing) {
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to estimate the cost of the session: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, estimate)
+}
+
 func (s *Server) resumeSession(c echo.Context) error {
 	var req api.ResumeSessionRequest
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
 	}
 
-	if err := s.sm.ResumeSession(c.Request().Context(), c.Param("id"), req.Confirmation, req.Reason, req.ToolName); err != nil {
+	if runtime.ResumeType(req.Confirmation) == runtime.ResumeTypeApproveEdited && len(req.Arguments) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "the arguments are required to approve edited arguments")
+	}
+
+	if err := s.sm.ResumeSession(c.Request().Context(), c.Param("id"), req.Confirmation, req.Reason, req.ToolName, string(req.Arguments)); err != nil {
+		if errors.Is(err, runtime.ErrSessionNotRunning) {
+			return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("failed to resume session: %v", err))
+		}
 		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to resume session: %v", err))
 	}
 
@@ -226,6 +274,15 @@ func (s *Server) toggleSessionYolo(c echo.Context) error {
 	return c.JSON(http.StatusOK, nil)
 }
 
+func (s *Server) describeAgent(c echo.Context) error {
+	desc, err := s.sm.DescribeTeam(c.Request().Context(), c.Param("id"), c.QueryParam("tools") != "false")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to describe agent: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, desc)
+}
+
 func (s *Server) getAgentToolCount(c echo.Context) error {
 	count, err := s.sm.GetAgentToolCount(c.Request().Context(), c.Param("id"), c.Param("agent_name"))
 	if err != nil {
@@ -283,6 +340,11 @@ func (s *Server) runAgent(c echo.Context) error {
 
 	slog.Debug("Running agent", "agent_filename", agentFilename, "session_id", sessionID, "current_agent", currentAgent)
 
+	version, err := events.NegotiateVersion(c.Request())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotAcceptable, err.Error())
+	}
+
 	var messages []api.Message
 	if err := json.NewDecoder(c.Request().Body).Decode(&messages); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
@@ -296,12 +358,25 @@ func (s *Server) runAgent(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to run session: %v", err))
 	}
 
-	c.Response().Header().Set("Content-Type", "text/event-stream")
+	contentType := "text/event-stream"
+	if version > 0 {
+		contentType += "; version=" + strconv.Itoa(version)
+	}
+	c.Response().Header().Set("Content-Type", contentType)
 	c.Response().Header().Set("Cache-Control", "no-cache")
 	c.Response().Header().Set("Connection", "keep-alive")
 	c.Response().WriteHeader(http.StatusOK)
 	for event := range streamChan {
-		data, err := json.Marshal(event)
+		// Without a version, the runtime events are sent as they are.
+		var payload any = event
+		if version > 0 {
+			payload, err = events.FromRuntime(event, version, time.Now())
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to convert event: %v", err))
+			}
+		}
+
+		data, err := json.Marshal(payload)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to marshal event: %v", err))
 		}
@@ -312,6 +387,22 @@ func (s *Server) runAgent(c echo.Context) error {
 	return nil
 }
 
+func (s *Server) getEventsSchema(c echo.Context) error {
+	version := events.Latest
+	if v := c.QueryParam("v"); v != "" {
+		var err error
+		if version, err = events.ParseVersion(v); err != nil {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+	}
+
+	schema, err := events.Schema(version)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to generate the schema: %v", err))
+	}
+	return c.JSON(http.StatusOK, schema)
+}
+
 func (s *Server) elicitation(c echo.Context) error {
 	sessionID := c.Param("id")
 	var req api.ResumeElicitationRequest
@@ -320,6 +411,12 @@ func (s *Server) elicitation(c echo.Context) error {
 	}
 
 	if err := s.sm.ResumeElicitation(c.Request().Context(), sessionID, req.Action, req.Content); err != nil {
+		if verr, ok := errors.AsType[*runtime.ElicitationValidationError](err); ok {
+			return c.JSON(http.StatusUnprocessableEntity, map[string]any{
+				"error":      verr.Error(),
+				"violations": verr.Violations,
+			})
+		}
 		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to resume elicitation: %v", err))
 	}
 
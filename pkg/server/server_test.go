@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -18,6 +19,7 @@ import (
 	"github.com/docker/docker-agent/pkg/api"
 	"github.com/docker/docker-agent/pkg/config"
 	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/team"
 )
 
 func TestServer_ListAgents(t *testing.T) {
@@ -55,6 +57,18 @@ func TestServer_EmptyList(t *testing.T) {
 	assert.Equal(t, "[]\n", string(buf)) // We don't want null, but an empty array
 }
 
+func TestServer_EventsSchema(t *testing.T) {
+	ctx := t.Context()
+	lnPath := startServer(t, ctx, prepareAgentsDir(t))
+
+	buf := httpGET(t, ctx, lnPath, "/api/events/schema?v=1")
+
+	var schema map[string]any
+	unmarshal(t, buf, &schema)
+	assert.Equal(t, "docker-agent events v1", schema["title"])
+	assert.Contains(t, schema["$defs"], "ToolCallConfirmationEvent")
+}
+
 func TestServer_ListSessions(t *testing.T) {
 	t.Parallel()
 
@@ -235,3 +249,25 @@ func (s mockStore) GetSessions(context.Context) ([]*session.Session, error) {
 func (s mockStore) GetSessionSummaries(context.Context) ([]session.Summary, error) {
 	return nil, nil
 }
+
+func TestServer_DescribeAgent(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "dummy")
+	t.Setenv("ANTHROPIC_API_KEY", "dummy")
+
+	ctx := t.Context()
+	lnPath := startServer(t, ctx, prepareAgentsDir(t, "multi_agents.yaml"))
+
+	buf := httpGET(t, ctx, lnPath, "/api/agents/multi_agents/describe")
+
+	var desc team.Description
+	unmarshal(t, buf, &desc)
+
+	assert.Equal(t, "root", desc.DefaultAgent)
+	require.Len(t, desc.Agents, 3)
+	i := slices.IndexFunc(desc.Agents, func(a team.AgentDescription) bool { return a.Name == "root" })
+	require.GreaterOrEqual(t, i, 0)
+	root := desc.Agents[i]
+	assert.Equal(t, "openai/gpt-4o", root.Model)
+	assert.Equal(t, []string{"contradict", "pirate"}, root.SubAgents)
+	assert.Equal(t, "Either talk like a pirate or contradict the user.", root.Instruction)
+}
@@ -129,8 +129,13 @@ func (sm *SessionManager) DeleteSession(ctx context.Context, sessionID string) e
 	}
 
 	if sessionRuntime, ok := sm.runtimeSessions.Load(sess.ID); ok {
-		sessionRuntime.cancel()
+		if sessionRuntime.cancel != nil {
+			sessionRuntime.cancel()
+		}
 		sm.runtimeSessions.Delete(sess.ID)
+		if forgetter, ok := sessionRuntime.runtime.(interface{ ForgetSession(sessionID string) }); ok {
+			forgetter.ForgetSession(sess.ID)
+		}
 	}
 
 	return nil
@@ -232,8 +237,45 @@ func (sm *SessionManager) RunSession(ctx context.Context, sessionID, agentFilena
 	return streamChan, nil
 }
 
-// ResumeSession resumes a paused session with an optional rejection reason or tool name.
-func (sm *SessionManager) ResumeSession(ctx context.Context, sessionID, confirmation, reason, toolName string) error {
+// EstimateNextTurnCost estimates the cost of the next turn of a session if
+// draft was sent. Sessions that haven't run yet get the runtime of the
+// agentFilename configuration, with currentAgent as the current agent.
+func (sm *SessionManager) EstimateNextTurnCost(ctx context.Context, sessionID, agentFilename, currentAgent, draft string, opts ...session.EstimateOpt) (session.CostEstimate, error) {
+	var (
+		rt   runtime.Runtime
+		sess *session.Session
+	)
+	if active, exists := sm.runtimeSessions.Load(sessionID); exists && active.runtime != nil {
+		rt, sess = active.runtime, active.session
+	} else {
+		if agentFilename == "" {
+			return session.CostEstimate{}, errors.New("the session hasn't run yet, the agent is required")
+		}
+
+		var err error
+		sess, err = sm.sessionStore.GetSession(ctx, sessionID)
+		if err != nil {
+			return session.CostEstimate{}, err
+		}
+
+		rc := sm.runConfig.Clone()
+		rc.WorkingDir = sess.WorkingDir
+		rt, _, err = sm.runtimeForSession(ctx, sess, agentFilename, currentAgent, rc)
+		if err != nil {
+			return session.CostEstimate{}, err
+		}
+	}
+
+	estimator, ok := rt.(runtime.TurnCostEstimator)
+	if !ok {
+		return session.CostEstimate{}, errors.New("cost estimates are not supported by this runtime")
+	}
+	return estimator.EstimateNextTurnCost(ctx, sess, draft, opts...)
+}
+
+// ResumeSession resumes a paused session with an optional rejection reason,
+// tool name or edited tool call arguments.
+func (sm *SessionManager) ResumeSession(ctx context.Context, sessionID, confirmation, reason, toolName, arguments string) error {
 	sm.mux.Lock()
 	defer sm.mux.Unlock()
 
@@ -243,12 +285,12 @@ func (sm *SessionManager) ResumeSession(ctx context.Context, sessionID, confirma
 		return errors.New("session not found")
 	}
 
-	rt.runtime.Resume(ctx, runtime.ResumeRequest{
-		Type:     runtime.ResumeType(confirmation),
-		Reason:   reason,
-		ToolName: toolName,
+	return rt.runtime.Resume(ctx, sessionID, runtime.ResumeRequest{
+		Type:      runtime.ResumeType(confirmation),
+		Reason:    reason,
+		ToolName:  toolName,
+		Arguments: arguments,
 	})
-	return nil
 }
 
 // SteerSession enqueues user messages for mid-turn injection into a running
@@ -303,7 +345,7 @@ func (sm *SessionManager) ResumeElicitation(ctx context.Context, sessionID, acti
 		return errors.New("session not found")
 	}
 
-	return rt.runtime.ResumeElicitation(ctx, tools.ElicitationAction(action), content)
+	return rt.runtime.ResumeElicitation(ctx, sessionID, tools.ElicitationAction(action), content)
 }
 
 // ToggleToolApproval toggles the tool approval mode for a session.
@@ -424,7 +466,7 @@ func (sm *SessionManager) runtimeForSession(ctx context.Context, sess *session.S
 		return nil, nil, err
 	}
 
-	titleGen := sessiontitle.New(agent.Model(), agent.FallbackModels()...)
+	titleGen := run.TitleGenerator()
 
 	sm.runtimeSessions.Store(sess.ID, &activeRuntimes{
 		runtime:  run,
@@ -446,6 +488,27 @@ func (sm *SessionManager) loadTeam(ctx context.Context, agentFilename string, ru
 	return teamloader.Load(ctx, agentSource, runConfig)
 }
 
+// DescribeTeam loads the agent's team and describes it. When listTools is
+// true, the toolsets are started to list their tools, and stopped after.
+func (sm *SessionManager) DescribeTeam(ctx context.Context, agentFilename string, listTools bool) (*team.Description, error) {
+	t, err := sm.loadTeam(ctx, agentFilename, sm.runConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if stopErr := t.StopToolSets(ctx); stopErr != nil {
+			slog.Error("Failed to stop tool sets", "error", stopErr)
+		}
+	}()
+
+	var opts []team.DescribeOpt
+	if !listTools {
+		opts = append(opts, team.WithoutTools())
+	}
+	desc := t.Describe(ctx, opts...)
+	return &desc, nil
+}
+
 // GetAgentToolCount loads the agent's team and returns the number of
 // tools available to the given agent.
 func (sm *SessionManager) GetAgentToolCount(ctx context.Context, agentFilename, agentName string) (int, error) {
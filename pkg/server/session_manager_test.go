@@ -26,6 +26,7 @@ type fakeRuntime struct {
 	concurrentStreams atomic.Int32
 	maxConcurrent     atomic.Int32
 	streamDelay       time.Duration
+	forgotten         atomic.Value
 }
 
 func (f *fakeRuntime) RunStream(_ context.Context, _ *session.Session) <-chan runtime.Event {
@@ -46,9 +47,15 @@ func (f *fakeRuntime) RunStream(_ context.Context, _ *session.Session) <-chan ru
 	return ch
 }
 
-func (f *fakeRuntime) Resume(_ context.Context, _ runtime.ResumeRequest) {}
+func (f *fakeRuntime) ForgetSession(sessionID string) {
+	f.forgotten.Store(sessionID)
+}
+
+func (f *fakeRuntime) Resume(_ context.Context, _ string, _ runtime.ResumeRequest) error {
+	return nil
+}
 
-func (f *fakeRuntime) ResumeElicitation(_ context.Context, _ tools.ElicitationAction, _ map[string]any) error {
+func (f *fakeRuntime) ResumeElicitation(_ context.Context, _ string, _ tools.ElicitationAction, _ map[string]any) error {
 	return nil
 }
 
@@ -222,3 +229,26 @@ func TestRunSession_DifferentSessionsConcurrently(t *testing.T) {
 	assert.Equal(t, int32(1), fake1.maxConcurrent.Load())
 	assert.Equal(t, int32(1), fake2.maxConcurrent.Load())
 }
+
+// TestDeleteSession_ForgetsSession verifies that deleting a session drops
+// what its runtime keeps about it.
+func TestDeleteSession_ForgetsSession(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	sess := session.New()
+	fake := &fakeRuntime{}
+	sm := newTestSessionManager(t, sess, fake)
+
+	ch, err := sm.RunSession(ctx, sess.ID, "agent", "root", []api.Message{
+		{Content: "hello"},
+	})
+	require.NoError(t, err)
+	for range ch {
+	}
+
+	require.NoError(t, sm.DeleteSession(ctx, sess.ID))
+	assert.Equal(t, sess.ID, fake.forgotten.Load())
+	_, exists := sm.runtimeSessions.Load(sess.ID)
+	assert.False(t, exists)
+}
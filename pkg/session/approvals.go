@@ -0,0 +1,59 @@
+package session
+
+import "slices"
+
+// ApproveTool approves the calls to a tool for the rest of the session, so
+// that they don't ask for confirmation anymore. name is a tool name or a
+// permission pattern, e.g. "shell:cmd=ls*". The approval is recorded in the
+// allow list of the session permissions, so it's persisted with them. It
+// reports whether the tool wasn't approved yet.
+func (s *Session) ApproveTool(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Permissions == nil {
+		s.Permissions = &PermissionsConfig{}
+	}
+	if name == "" || slices.Contains(s.Permissions.Allow, name) {
+		return false
+	}
+	s.Permissions.Allow = append(s.Permissions.Allow, name)
+	return true
+}
+
+// RevokeTool revokes the approval of a tool given by ApproveTool, or by the
+// allow list of the session permissions. It reports whether the tool was
+// approved.
+func (s *Session) RevokeTool(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Permissions == nil || !slices.Contains(s.Permissions.Allow, name) {
+		return false
+	}
+	s.Permissions.Allow = slices.DeleteFunc(slices.Clone(s.Permissions.Allow), func(allowed string) bool {
+		return allowed == name
+	})
+	return true
+}
+
+// ApprovedTools returns the tools approved for the session, in the order
+// they were approved.
+func (s *Session) ApprovedTools() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.Permissions == nil {
+		return nil
+	}
+	return slices.Clone(s.Permissions.Allow)
+}
+
+// PermissionsSnapshot returns a copy of the session permissions, safe to use
+// while tools are being approved, or nil if there are none.
+func (s *Session) PermissionsSnapshot() *PermissionsConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return clonePermissionsConfig(s.Permissions)
+}
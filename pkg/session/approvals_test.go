@@ -0,0 +1,60 @@
+package session
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApproveTool(t *testing.T) {
+	t.Parallel()
+
+	sess := New()
+	assert.Empty(t, sess.ApprovedTools())
+
+	assert.True(t, sess.ApproveTool("read_file"))
+	assert.True(t, sess.ApproveTool("shell:cmd=ls*"))
+	assert.False(t, sess.ApproveTool("read_file"), "already approved")
+	assert.False(t, sess.ApproveTool(""))
+	assert.Equal(t, []string{"read_file", "shell:cmd=ls*"}, sess.ApprovedTools())
+
+	assert.True(t, sess.RevokeTool("read_file"))
+	assert.False(t, sess.RevokeTool("read_file"), "already revoked")
+	assert.Equal(t, []string{"shell:cmd=ls*"}, sess.ApprovedTools())
+}
+
+func TestApproveTool_KeepsOtherPermissions(t *testing.T) {
+	t.Parallel()
+
+	sess := New(WithPermissions(&PermissionsConfig{
+		Allow: []string{"think"},
+		Deny:  []string{"shell:cmd=rm*"},
+	}))
+	snapshot := sess.PermissionsSnapshot()
+
+	sess.ApproveTool("read_file")
+
+	assert.Equal(t, []string{"think", "read_file"}, sess.ApprovedTools())
+	assert.Equal(t, []string{"shell:cmd=rm*"}, sess.Permissions.Deny)
+	// Snapshots aren't changed by later approvals.
+	assert.Equal(t, []string{"think"}, snapshot.Allow)
+}
+
+func TestApproveTool_Persisted(t *testing.T) {
+	store, err := NewSQLiteSessionStore(filepath.Join(t.TempDir(), "approvals.db"))
+	require.NoError(t, err)
+	defer store.(*SQLiteSessionStore).Close()
+
+	sess := &Session{ID: "approvals-session", CreatedAt: time.Now()}
+	require.NoError(t, store.AddSession(t.Context(), sess))
+
+	sess.ApproveTool("read_file")
+	require.NoError(t, store.UpdateSession(t.Context(), sess))
+
+	retrieved, err := store.GetSession(t.Context(), "approvals-session")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read_file"}, retrieved.ApprovedTools())
+}
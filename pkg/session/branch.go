@@ -48,7 +48,14 @@ func cloneSessionItem(item Item) (Item, error) {
 		}
 		return Item{SubSession: clonedSub}, nil
 	case item.Summary != "":
-		return Item{Summary: item.Summary, Cost: item.Cost}, nil
+		summary := Item{Summary: item.Summary, FirstKeptEntry: item.FirstKeptEntry, Cost: item.Cost}
+		if item.SummaryInfo != nil {
+			info := *item.SummaryInfo
+			summary.SummaryInfo = &info
+		}
+		return summary, nil
+	case item.Cost != 0:
+		return Item{Cost: item.Cost}, nil
 	default:
 		return Item{}, errors.New("cannot clone empty session item")
 	}
@@ -62,6 +69,7 @@ func cloneSubSession(src *Session) (*Session, error) {
 	cloned := New()
 	copySessionMetadata(cloned, src, src.Title)
 	cloned.CreatedAt = src.CreatedAt
+	cloned.ToolCallID = src.ToolCallID
 
 	cloned.Messages = make([]Item, 0, len(src.Messages))
 	for _, item := range src.Messages {
@@ -130,6 +138,7 @@ func clonePermissionsConfig(src *PermissionsConfig) *PermissionsConfig {
 	}
 	return &PermissionsConfig{
 		Allow: cloneStringSlice(src.Allow),
+		Ask:   cloneStringSlice(src.Ask),
 		Deny:  cloneStringSlice(src.Deny),
 	}
 }
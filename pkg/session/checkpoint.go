@@ -0,0 +1,226 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// ErrCheckpointNotFound is returned when rolling back to a checkpoint the
+// session doesn't have.
+var ErrCheckpointNotFound = errors.New("checkpoint not found")
+
+// Checkpoint is a point in a session the conversation can be rolled back
+// to, see Session.Checkpoint.
+type Checkpoint struct {
+	ID        string    `json:"id"`
+	Label     string    `json:"label,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// AtMessageIndex is the number of items in the session at the
+	// checkpoint.
+	AtMessageIndex int `json:"at_message_index"`
+	// HandoffCount is the number of handoffs made before the checkpoint.
+	HandoffCount int `json:"handoff_count,omitempty"`
+
+	// The token and cost counters of the session at the checkpoint.
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	Cost         float64 `json:"cost"`
+
+	// FileChanges is the file change ledger of the session at the
+	// checkpoint.
+	FileChanges []tools.FileChange `json:"file_changes,omitempty"`
+
+	// fileSnapshots holds the content the files had at the checkpoint,
+	// saved before the tools first changed them after it. See
+	// RevertFilesToCheckpoint. Not persisted.
+	fileSnapshots map[string]*fileSnapshot
+}
+
+// Checkpoint tags the current point of the session with label and returns
+// the ID of the checkpoint. If the last assistant message is still waiting
+// for the results of its tool calls, the checkpoint is set before it so
+// that rolling back never splits a tool call from its results.
+func (s *Session) Checkpoint(label string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	checkpoint := Checkpoint{
+		ID:             s.nextCheckpointID(),
+		Label:          label,
+		CreatedAt:      time.Now(),
+		AtMessageIndex: checkpointBoundary(s.Messages),
+		HandoffCount:   len(s.HandoffHistory),
+		InputTokens:    s.InputTokens,
+		OutputTokens:   s.OutputTokens,
+		Cost:           s.Cost,
+		FileChanges:    slices.Clone(s.FileChangeLog),
+	}
+	s.Checkpoints = append(s.Checkpoints, checkpoint)
+	return checkpoint.ID
+}
+
+// nextCheckpointID returns a short ID, e.g. "cp3", that no checkpoint of the
+// session has.
+func (s *Session) nextCheckpointID() string {
+	for n := len(s.Checkpoints) + 1; ; n++ {
+		id := fmt.Sprintf("cp%d", n)
+		if !slices.ContainsFunc(s.Checkpoints, func(c Checkpoint) bool { return c.ID == id }) {
+			return id
+		}
+	}
+}
+
+// checkpointBoundary returns the number of items a checkpoint set now
+// keeps: all of them, unless the last assistant message, followed by the
+// results and the sub-sessions of its tool calls, misses some results.
+func checkpointBoundary(items []Item) int {
+	answered := make(map[string]bool)
+	for i := len(items) - 1; i >= 0; i-- {
+		item := items[i]
+		if !item.IsMessage() {
+			continue
+		}
+
+		msg := &item.Message.Message
+		switch msg.Role {
+		case chat.MessageRoleTool:
+			answered[msg.ToolCallID] = true
+			continue
+		case chat.MessageRoleAssistant:
+			for _, tc := range msg.ToolCalls {
+				if !answered[tc.ID] {
+					return i
+				}
+			}
+		}
+		break
+	}
+	return len(items)
+}
+
+// ListCheckpoints returns the checkpoints of the session, oldest first.
+func (s *Session) ListCheckpoints() []Checkpoint {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	checkpoints := make([]Checkpoint, 0, len(s.Checkpoints))
+	for _, checkpoint := range s.Checkpoints {
+		checkpoints = append(checkpoints, checkpoint.clone())
+	}
+	return checkpoints
+}
+
+// FindCheckpoint returns the checkpoint with the ID ref or, failing that,
+// the latest one labeled ref. An empty ref is the latest checkpoint.
+func (s *Session) FindCheckpoint(ref string) (Checkpoint, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.findCheckpoint(ref)
+	if i < 0 {
+		return Checkpoint{}, false
+	}
+	return s.Checkpoints[i].clone(), true
+}
+
+func (s *Session) findCheckpoint(ref string) int {
+	if ref == "" {
+		return len(s.Checkpoints) - 1
+	}
+	if i := slices.IndexFunc(s.Checkpoints, func(c Checkpoint) bool { return c.ID == ref }); i >= 0 {
+		return i
+	}
+	for i, checkpoint := range slices.Backward(s.Checkpoints) {
+		if checkpoint.Label == ref {
+			return i
+		}
+	}
+	return -1
+}
+
+// clone returns a copy of the checkpoint without its file snapshots.
+func (c Checkpoint) clone() Checkpoint {
+	c.FileChanges = slices.Clone(c.FileChanges)
+	c.fileSnapshots = nil
+	return c
+}
+
+// Rollback truncates the session back to a checkpoint: the items added
+// since, sub-sessions included, the handoffs made since and the checkpoints
+// created after it are dropped, and the token and cost counters get the
+// values they had at the checkpoint. The checkpoint is kept, so that the
+// session can be rolled back to it again. The files are left alone, see
+// RevertFilesToCheckpoint. The session mustn't be running.
+func (s *Session) Rollback(checkpointID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.Checkpoints, func(c Checkpoint) bool { return c.ID == checkpointID })
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrCheckpointNotFound, checkpointID)
+	}
+	checkpoint := s.Checkpoints[i]
+	if checkpoint.AtMessageIndex > len(s.Messages) {
+		return fmt.Errorf("checkpoint %s is at item %d but the session only has %d", checkpointID, checkpoint.AtMessageIndex, len(s.Messages))
+	}
+
+	s.Messages = slices.Clip(s.Messages[:checkpoint.AtMessageIndex])
+	s.HandoffHistory = slices.Clip(s.HandoffHistory[:min(checkpoint.HandoffCount, len(s.HandoffHistory))])
+	s.Checkpoints = slices.Clip(s.Checkpoints[:i+1])
+	s.InputTokens = checkpoint.InputTokens
+	s.OutputTokens = checkpoint.OutputTokens
+	s.Cost = checkpoint.Cost
+	return nil
+}
+
+// RevertFilesToCheckpoint restores the files the tools changed since a
+// checkpoint to the content they had at the checkpoint, and removes the
+// ones they created. Only the files saved by SnapshotFile can be restored:
+// the file change ledger keeps the changes of the others. It returns the
+// paths of the restored files.
+func (s *Session) RevertFilesToCheckpoint(checkpointID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.Checkpoints, func(c Checkpoint) bool { return c.ID == checkpointID })
+	if i < 0 {
+		return nil, fmt.Errorf("%w: %s", ErrCheckpointNotFound, checkpointID)
+	}
+	checkpoint := &s.Checkpoints[i]
+
+	var (
+		reverted []string
+		errs     []error
+	)
+	for _, path := range slices.Sorted(maps.Keys(checkpoint.fileSnapshots)) {
+		snapshot := checkpoint.fileSnapshots[path]
+		if snapshot.unrestorable {
+			continue
+		}
+		if err := snapshot.restore(path); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		reverted = append(reverted, path)
+	}
+
+	ledger := slices.Clone(checkpoint.FileChanges)
+	for _, change := range s.FileChangeLog {
+		if slices.Contains(reverted, change.Path) && (change.OldPath == "" || slices.Contains(reverted, change.OldPath)) {
+			continue
+		}
+		if slices.Contains(checkpoint.FileChanges, change) {
+			continue
+		}
+		ledger = tools.MergeFileChange(ledger, change)
+	}
+	s.FileChangeLog = ledger
+
+	return reverted, errors.Join(errs...)
+}
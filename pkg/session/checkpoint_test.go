@@ -0,0 +1,131 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+func TestRollback(t *testing.T) {
+	s := newRepairSession(NewMessageItem(assistantMessage("hi")))
+	s.InputTokens, s.OutputTokens, s.Cost = 100, 10, 0.5
+	s.AddHandoff("root", "writer")
+
+	first := s.Checkpoint("before the fix")
+	assert.Equal(t, "cp1", first)
+
+	s.AddMessage(UserMessage("fix it"))
+	s.AddMessage(toolCallMessage("call_1"))
+	s.AddSubSession(New(WithParentID(s.ID)))
+	s.AddMessage(toolResultMessage("call_1"))
+	s.AddHandoff("writer", "root")
+	s.InputTokens, s.OutputTokens, s.Cost = 300, 30, 1.5
+	second := s.Checkpoint("")
+	s.AddMessage(UserMessage("and test it"))
+
+	require.NoError(t, s.Rollback(first))
+	assert.Equal(t, []string{"user", "assistant"}, transcript(s))
+	assert.Equal(t, []Handoff{{From: "root", To: "writer", AtMessageIndex: 2}}, s.Handoffs())
+	assert.Equal(t, int64(100), s.InputTokens)
+	assert.Equal(t, int64(10), s.OutputTokens)
+	assert.InDelta(t, 0.5, s.Cost, 1e-9)
+
+	// The checkpoint is kept, the later ones are dropped.
+	checkpoints := s.ListCheckpoints()
+	require.Len(t, checkpoints, 1)
+	assert.Equal(t, "before the fix", checkpoints[0].Label)
+	require.ErrorIs(t, s.Rollback(second), ErrCheckpointNotFound)
+
+	s.AddMessage(UserMessage("try again"))
+	require.NoError(t, s.Rollback(first))
+	assert.Equal(t, []string{"user", "assistant"}, transcript(s))
+}
+
+func TestCheckpoint_KeepsToolCallsWithTheirResults(t *testing.T) {
+	s := newRepairSession(
+		NewMessageItem(toolCallMessage("call_1", "call_2")),
+		NewMessageItem(toolResultMessage("call_1")),
+	)
+
+	// The results of the last tool calls aren't all in: the checkpoint is
+	// before the calls.
+	id := s.Checkpoint("")
+	s.AddMessage(toolResultMessage("call_2"))
+	complete := s.Checkpoint("")
+
+	require.NoError(t, s.Rollback(complete))
+	assert.Equal(t, []string{"user", "assistant", "tool:call_1", "tool:call_2"}, transcript(s))
+
+	require.NoError(t, s.Rollback(id))
+	assert.Equal(t, []string{"user"}, transcript(s))
+}
+
+func TestFindCheckpoint(t *testing.T) {
+	s := New()
+
+	_, ok := s.FindCheckpoint("")
+	assert.False(t, ok)
+
+	first := s.Checkpoint("green")
+	s.AddMessage(UserMessage("hello"))
+	second := s.Checkpoint("green")
+	third := s.Checkpoint("")
+
+	for ref, want := range map[string]string{"": third, first: first, "green": second} {
+		checkpoint, ok := s.FindCheckpoint(ref)
+		require.True(t, ok, ref)
+		assert.Equal(t, want, checkpoint.ID, ref)
+	}
+	_, ok = s.FindCheckpoint("red")
+	assert.False(t, ok)
+}
+
+func TestRevertFilesToCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	early := filepath.Join(dir, "early.txt")
+	late := filepath.Join(dir, "late.txt")
+	created := filepath.Join(dir, "created.txt")
+	require.NoError(t, os.WriteFile(early, []byte("v1"), 0o644))
+	require.NoError(t, os.WriteFile(late, []byte("v1"), 0o644))
+
+	s := New()
+	change := func(path, content string, changeType tools.FileChangeType) {
+		s.SnapshotFile(path, 1024)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		s.AddFileChange(tools.FileChange{Path: path, Type: changeType})
+	}
+
+	change(early, "v2", tools.FileModified)
+	id := s.Checkpoint("")
+	change(early, "v3", tools.FileModified)
+	change(late, "v2", tools.FileModified)
+	change(created, "new", tools.FileCreated)
+
+	reverted, err := s.RevertFilesToCheckpoint(id)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{early, late, created}, reverted)
+
+	// The files are back to their content at the checkpoint, not before the
+	// session.
+	content, err := os.ReadFile(early)
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(content))
+	content, err = os.ReadFile(late)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(content))
+	assert.NoFileExists(t, created)
+	assert.Equal(t, []tools.FileChange{{Path: early, Type: tools.FileModified}}, s.FileChanges())
+
+	// The session still reverts the files to their content before it.
+	reverted, err = s.RevertFileChanges()
+	require.NoError(t, err)
+	assert.Equal(t, []string{early}, reverted)
+	content, err = os.ReadFile(early)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(content))
+}
@@ -0,0 +1,137 @@
+package session
+
+import (
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/compaction"
+	"github.com/docker/docker-agent/pkg/modelsdev"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// Default output sizes assumed by cost estimates: from a short answer or a
+// tool call to a long answer.
+const (
+	DefaultMinOutputTokens = 100
+	DefaultMaxOutputTokens = 2000
+)
+
+// CostEstimate is the estimated cost, in dollars, of a turn that hasn't run
+// yet. The input is estimated from the messages and tool definitions sent to
+// the model. The output isn't known, so the cost is a range over an assumed
+// output size. Prompt caching isn't accounted for, so the estimate leans
+// high on long conversations.
+type CostEstimate struct {
+	// InputTokens is the estimated size of the request, tool definitions
+	// included.
+	InputTokens int64 `json:"input_tokens"`
+	// ToolTokens is the part of InputTokens taken by the tool definitions.
+	ToolTokens      int64   `json:"tool_tokens"`
+	MinOutputTokens int64   `json:"min_output_tokens"`
+	MaxOutputTokens int64   `json:"max_output_tokens"`
+	MinCost         float64 `json:"min_cost"`
+	MaxCost         float64 `json:"max_cost"`
+}
+
+// EstimateOpt configures cost estimates.
+type EstimateOpt func(*estimateOptions)
+
+type estimateOptions struct {
+	minOutput    int64
+	maxOutput    int64
+	estimate     compaction.TokenEstimator
+	draft        string
+	messagesOpts []MessagesOpt
+}
+
+// WithAssumedOutputTokens sets the range of output sizes the estimate
+// assumes. Defaults to DefaultMinOutputTokens to DefaultMaxOutputTokens.
+func WithAssumedOutputTokens(minTokens, maxTokens int64) EstimateOpt {
+	return func(o *estimateOptions) {
+		o.minOutput = minTokens
+		o.maxOutput = max(minTokens, maxTokens)
+	}
+}
+
+// WithEstimator sets how the tokens of the messages are counted. Defaults to
+// compaction.EstimateMessageTokens.
+func WithEstimator(estimate compaction.TokenEstimator) EstimateOpt {
+	return func(o *estimateOptions) {
+		o.estimate = estimate
+	}
+}
+
+// WithDraft adds a user message that isn't sent yet, e.g. the one being
+// typed, to the next turn estimated by EstimatedNextTurnCost.
+func WithDraft(content string) EstimateOpt {
+	return func(o *estimateOptions) {
+		o.draft = content
+	}
+}
+
+// WithMessagesOpts sets the options EstimatedNextTurnCost gets the messages
+// of the next turn with, so that they're packed and pruned like the ones
+// the runtime sends.
+func WithMessagesOpts(opts ...MessagesOpt) EstimateOpt {
+	return func(o *estimateOptions) {
+		o.messagesOpts = append(o.messagesOpts, opts...)
+	}
+}
+
+func newEstimateOptions(opts []EstimateOpt) estimateOptions {
+	o := estimateOptions{
+		minOutput: DefaultMinOutputTokens,
+		maxOutput: DefaultMaxOutputTokens,
+		estimate:  compaction.EstimateMessageTokens,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// EstimateTurnCost estimates the cost of sending messages and the
+// definitions of toolDefs to model. The costs are zero when models.dev has
+// no pricing for the model. The assumed output is capped at the output limit
+// of the model.
+func EstimateTurnCost(model *modelsdev.Model, messages []chat.Message, toolDefs []tools.Tool, opts ...EstimateOpt) CostEstimate {
+	o := newEstimateOptions(opts)
+
+	est := CostEstimate{
+		ToolTokens:      compaction.EstimateToolTokens(toolDefs),
+		MinOutputTokens: o.minOutput,
+		MaxOutputTokens: o.maxOutput,
+	}
+	est.InputTokens = est.ToolTokens
+	for i := range messages {
+		est.InputTokens += o.estimate(&messages[i])
+	}
+
+	if model == nil {
+		return est
+	}
+	if limit := model.Limit.Output; limit > 0 {
+		est.MinOutputTokens = min(est.MinOutputTokens, limit)
+		est.MaxOutputTokens = min(est.MaxOutputTokens, limit)
+	}
+	if model.Cost != nil {
+		input := float64(est.InputTokens) * model.Cost.Input
+		est.MinCost = (input + float64(est.MinOutputTokens)*model.Cost.Output) / 1e6
+		est.MaxCost = (input + float64(est.MaxOutputTokens)*model.Cost.Output) / 1e6
+	}
+	return est
+}
+
+// EstimatedNextTurnCost estimates the cost of the next turn of a, given the
+// definitions of its tools, if it ran now. The messages are the ones
+// GetMessages returns: the system prompts, with the instructions of the
+// toolsets, and the conversation, with the tool results such as RAG search
+// results.
+func (s *Session) EstimatedNextTurnCost(a *agent.Agent, model *modelsdev.Model, toolDefs []tools.Tool, opts ...EstimateOpt) CostEstimate {
+	o := newEstimateOptions(opts)
+
+	messages := s.GetMessages(a, o.messagesOpts...)
+	if o.draft != "" {
+		messages = append(messages, chat.Message{Role: chat.MessageRoleUser, Content: o.draft})
+	}
+	return EstimateTurnCost(model, messages, toolDefs, opts...)
+}
@@ -0,0 +1,89 @@
+package session
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/modelsdev"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// charCount counts one token per character, to make estimates predictable.
+func charCount(msg *chat.Message) int64 {
+	return int64(len(msg.Content))
+}
+
+func TestEstimateTurnCost(t *testing.T) {
+	t.Parallel()
+
+	model := &modelsdev.Model{Cost: &modelsdev.Cost{Input: 2, Output: 10}}
+	messages := []chat.Message{{Role: chat.MessageRoleUser, Content: strings.Repeat("a", 1000)}}
+
+	est := EstimateTurnCost(model, messages, nil, WithEstimator(charCount), WithAssumedOutputTokens(100, 500))
+
+	assert.Equal(t, int64(1000), est.InputTokens)
+	assert.Zero(t, est.ToolTokens)
+	assert.InDelta(t, (1000*2+100*10)/1e6, est.MinCost, 1e-12)
+	assert.InDelta(t, (1000*2+500*10)/1e6, est.MaxCost, 1e-12)
+}
+
+func TestEstimateTurnCost_ToolDefinitions(t *testing.T) {
+	t.Parallel()
+
+	toolDefs := []tools.Tool{{
+		Name:        "search",
+		Description: strings.Repeat("Search the web. ", 50),
+		Parameters:  map[string]any{"type": "object"},
+	}}
+
+	est := EstimateTurnCost(nil, nil, toolDefs, WithEstimator(charCount))
+
+	assert.Positive(t, est.ToolTokens)
+	assert.Equal(t, est.ToolTokens, est.InputTokens)
+}
+
+func TestEstimateTurnCost_OutputCappedAtModelLimit(t *testing.T) {
+	t.Parallel()
+
+	model := &modelsdev.Model{
+		Cost:  &modelsdev.Cost{Input: 1, Output: 1},
+		Limit: modelsdev.Limit{Output: 1000},
+	}
+
+	est := EstimateTurnCost(model, nil, nil, WithAssumedOutputTokens(100, 5000))
+
+	assert.Equal(t, int64(100), est.MinOutputTokens)
+	assert.Equal(t, int64(1000), est.MaxOutputTokens)
+}
+
+func TestEstimateTurnCost_NoPricing(t *testing.T) {
+	t.Parallel()
+
+	messages := []chat.Message{{Role: chat.MessageRoleUser, Content: "hello"}}
+
+	est := EstimateTurnCost(&modelsdev.Model{}, messages, nil, WithEstimator(charCount))
+
+	assert.Equal(t, int64(5), est.InputTokens)
+	assert.Zero(t, est.MinCost)
+	assert.Zero(t, est.MaxCost)
+}
+
+func TestEstimatedNextTurnCost(t *testing.T) {
+	t.Parallel()
+
+	a := agent.New("root", strings.Repeat("i", 200))
+	s := New(WithUserMessage(strings.Repeat("u", 50)))
+	model := &modelsdev.Model{Cost: &modelsdev.Cost{Input: 1, Output: 1}}
+
+	withoutDraft := s.EstimatedNextTurnCost(a, model, nil, WithEstimator(charCount))
+	// The system prompt is sent with the conversation.
+	assert.GreaterOrEqual(t, withoutDraft.InputTokens, int64(250))
+
+	withDraft := s.EstimatedNextTurnCost(a, model, nil, WithEstimator(charCount), WithDraft(strings.Repeat("d", 30)))
+	assert.Equal(t, withoutDraft.InputTokens+30, withDraft.InputTokens)
+	assert.Greater(t, withDraft.MinCost, withoutDraft.MinCost)
+}
@@ -0,0 +1,409 @@
+package session
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// ExportFormat is the format of an exported session.
+type ExportFormat string
+
+const (
+	// ExportFormatMarkdown renders the session as a human readable Markdown
+	// document, suitable for sharing.
+	ExportFormatMarkdown ExportFormat = "markdown"
+	// ExportFormatJSON renders the session as a Transcript.
+	ExportFormatJSON ExportFormat = "json"
+)
+
+// Roles of the transcript messages that hold the summary of a compacted
+// conversation, and the system reminders injected into the conversation.
+const (
+	transcriptRoleSummary        = "summary"
+	transcriptRoleSystemReminder = "system_reminder"
+)
+
+// thinkToolName is the name of the think tool. Its calls are exported as the
+// thoughts of the assistant messages rather than as tool calls.
+const thinkToolName = "think"
+
+// Transcript is the stable JSON representation of an exported session.
+type Transcript struct {
+	ID           string              `json:"id"`
+	Title        string              `json:"title,omitempty"`
+	CreatedAt    time.Time           `json:"created_at"`
+	InputTokens  int64               `json:"input_tokens"`
+	OutputTokens int64               `json:"output_tokens"`
+	Cost         float64             `json:"cost"`
+	Messages     []TranscriptMessage `json:"messages"`
+	// FileChanges are the files changed by the tools during the session.
+	FileChanges []tools.FileChange `json:"file_changes,omitempty"`
+}
+
+// TranscriptMessage is a message of a Transcript. Its role is one of the chat
+// message roles, "summary" for the summary of a compacted conversation, or
+// "system_reminder" for the reminders injected into the conversation.
+// Thoughts are the thoughts the agent recorded with the think tool, and
+// Truncation tells how the output of a tool was truncated before being sent
+// to the model. ArgumentsEdit holds the arguments the user substituted for
+// the ones of the tool call when approving it. Annotations are the sources an
+// answer draws on.
+type TranscriptMessage struct {
+	Role             string                  `json:"role"`
+	AgentName        string                  `json:"agent_name,omitempty"`
+	Model            string                  `json:"model,omitempty"`
+	Content          string                  `json:"content,omitempty"`
+	ReasoningContent string                  `json:"reasoning_content,omitempty"`
+	Thoughts         []string                `json:"thoughts,omitempty"`
+	ToolCalls        []TranscriptToolCall    `json:"tool_calls,omitempty"`
+	ToolCallID       string                  `json:"tool_call_id,omitempty"`
+	Truncation       *tools.OutputTruncation `json:"truncation,omitempty"`
+	ArgumentsEdit    *tools.ArgumentsEdit    `json:"arguments_edit,omitempty"`
+	Implicit         bool                    `json:"implicit,omitempty"`
+	CreatedAt        string                  `json:"created_at,omitempty"`
+	Usage            *chat.Usage             `json:"usage,omitempty"`
+	Cost             float64                 `json:"cost,omitempty"`
+	Annotations      []chat.Annotation       `json:"annotations,omitempty"`
+}
+
+// TranscriptToolCall is a tool call of a TranscriptMessage. SubSession holds
+// the session of the agent the task was transferred to, if any.
+type TranscriptToolCall struct {
+	ID         string      `json:"id"`
+	Name       string      `json:"name"`
+	Arguments  string      `json:"arguments"`
+	SubSession *Transcript `json:"sub_session,omitempty"`
+}
+
+// ExportOption configures Session.Export.
+type ExportOption func(*exporter)
+
+// WithRedaction applies redact to the arguments and the outputs of the tool
+// calls, e.g. to keep secrets out of shared transcripts. Redactions are
+// applied in the order they are given.
+func WithRedaction(redact func(string) string) ExportOption {
+	return func(e *exporter) {
+		e.redactions = append(e.redactions, redact)
+	}
+}
+
+type exporter struct {
+	redactions []func(string) string
+}
+
+// Export writes the session, including the sub-sessions created by task
+// transfers, to w in the given format.
+func (s *Session) Export(w io.Writer, format ExportFormat, opts ...ExportOption) error {
+	var e exporter
+	for _, opt := range opts {
+		opt(&e)
+	}
+
+	t := e.transcript(s)
+
+	switch format {
+	case ExportFormatMarkdown:
+		var b strings.Builder
+		writeMarkdownHeader(&b, t)
+		var footnotes int
+		writeMarkdownMessages(&b, t.Messages, 2, &footnotes)
+		writeMarkdownFileChanges(&b, t.FileChanges)
+		_, err := io.WriteString(w, strings.TrimRight(b.String(), "\n")+"\n")
+		return err
+	case ExportFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(t)
+	default:
+		return fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+func (e *exporter) redact(s string) string {
+	for _, redact := range e.redactions {
+		s = redact(s)
+	}
+	return s
+}
+
+// transcript converts the session to a Transcript. Sub-sessions are attached
+// to the tool call that spawned them. Sub-sessions that don't record it, e.g.
+// the ones of older sessions, are attached to the last tool call that didn't
+// get its result yet.
+func (e *exporter) transcript(s *Session) *Transcript {
+	s.mu.RLock()
+	items := slices.Clone(s.Messages)
+	s.mu.RUnlock()
+
+	t := &Transcript{
+		ID:           s.ID,
+		Title:        s.Title,
+		CreatedAt:    s.CreatedAt,
+		InputTokens:  s.InputTokens,
+		OutputTokens: s.OutputTokens,
+		Cost:         s.TotalCost(),
+		Messages:     []TranscriptMessage{},
+		FileChanges:  s.FileChanges(),
+	}
+
+	var pending []*TranscriptToolCall
+	calls := make(map[string]*TranscriptToolCall)
+	thoughts := make(map[string]bool)
+	for _, item := range items {
+		switch {
+		case item.IsMessage():
+			msg := item.Message
+			if msg.Message.Role == chat.MessageRoleTool && thoughts[msg.Message.ToolCallID] {
+				continue
+			}
+			tm := TranscriptMessage{
+				Role:             string(msg.Message.Role),
+				AgentName:        msg.AgentName,
+				Model:            msg.ModelID,
+				Content:          msg.Message.Content,
+				ReasoningContent: msg.Message.ReasoningContent,
+				ToolCallID:       msg.Message.ToolCallID,
+				Truncation:       msg.Message.Truncation,
+				Implicit:         msg.Implicit,
+				CreatedAt:        msg.Message.CreatedAt,
+				Usage:            msg.Message.Usage,
+				Cost:             msg.Message.Cost,
+				Annotations:      msg.Message.Annotations,
+			}
+			if msg.IsSystemReminder() {
+				tm.Role = transcriptRoleSystemReminder
+			}
+			if msg.Message.Role == chat.MessageRoleTool {
+				tm.Content = e.redact(tm.Content)
+				if edit := msg.Message.ArgumentsEdit; edit != nil {
+					tm.ArgumentsEdit = &tools.ArgumentsEdit{
+						Original: e.redact(edit.Original),
+						Edited:   e.redact(edit.Edited),
+					}
+				}
+				pending = slices.DeleteFunc(pending, func(tc *TranscriptToolCall) bool {
+					return tc.ID == msg.Message.ToolCallID
+				})
+			}
+			for _, tc := range msg.Message.ToolCalls {
+				if tc.Function.Name == thinkToolName {
+					var args struct {
+						Thought string `json:"thought"`
+					}
+					if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err == nil {
+						thoughts[tc.ID] = true
+						tm.Thoughts = append(tm.Thoughts, e.redact(args.Thought))
+						continue
+					}
+				}
+				tm.ToolCalls = append(tm.ToolCalls, TranscriptToolCall{
+					ID:        tc.ID,
+					Name:      tc.Function.Name,
+					Arguments: e.redact(tc.Function.Arguments),
+				})
+			}
+			t.Messages = append(t.Messages, tm)
+			for i := range tm.ToolCalls {
+				pending = append(pending, &tm.ToolCalls[i])
+				calls[tm.ToolCalls[i].ID] = &tm.ToolCalls[i]
+			}
+		case item.IsSubSession():
+			sub := e.transcript(item.SubSession)
+			if tc, ok := calls[item.SubSession.ToolCallID]; ok && tc.SubSession == nil {
+				tc.SubSession = sub
+				break
+			}
+			for _, tc := range slices.Backward(pending) {
+				if tc.SubSession == nil {
+					tc.SubSession = sub
+					break
+				}
+			}
+		case item.Summary != "":
+			t.Messages = append(t.Messages, TranscriptMessage{
+				Role:    transcriptRoleSummary,
+				Content: item.Summary,
+			})
+		}
+	}
+
+	return t
+}
+
+func writeMarkdownHeader(b *strings.Builder, t *Transcript) {
+	fmt.Fprintf(b, "# %s\n\n", cmp.Or(t.Title, "Session"))
+	fmt.Fprintf(b, "_%s · %d input tokens · %d output tokens · $%.4f_\n\n",
+		t.CreatedAt.UTC().Format("2006-01-02 15:04 UTC"), t.InputTokens, t.OutputTokens, t.Cost)
+}
+
+// writeMarkdownFileChanges lists the files changed by the tools.
+func writeMarkdownFileChanges(b *strings.Builder, changes []tools.FileChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	b.WriteString("## Files changed\n\n")
+	for _, change := range changes {
+		if change.OldPath != "" {
+			fmt.Fprintf(b, "- %s `%s` → `%s`\n", change.Type, change.OldPath, change.Path)
+		} else {
+			fmt.Fprintf(b, "- %s `%s`\n", change.Type, change.Path)
+		}
+	}
+	b.WriteString("\n")
+}
+
+// writeMarkdownMessages renders messages with headings of the given level.
+// Tool results are rendered with the tool calls they answer. footnotes counts
+// the answers whose sources were rendered as footnotes, so that their labels
+// are unique across the document.
+func writeMarkdownMessages(b *strings.Builder, messages []TranscriptMessage, level int, footnotes *int) {
+	heading := strings.Repeat("#", min(level, 6))
+
+	results := make(map[string]TranscriptMessage)
+	for _, msg := range messages {
+		if msg.Role == string(chat.MessageRoleTool) {
+			results[msg.ToolCallID] = msg
+		}
+	}
+	answered := make(map[string]bool)
+
+	for _, msg := range messages {
+		if msg.Implicit {
+			continue
+		}
+
+		switch msg.Role {
+		case string(chat.MessageRoleUser):
+			fmt.Fprintf(b, "%s User\n\n%s\n\n", heading, msg.Content)
+
+		case string(chat.MessageRoleAssistant):
+			fmt.Fprintf(b, "%s %s\n\n", heading, cmp.Or(msg.AgentName, "Assistant"))
+			if msg.ReasoningContent != "" {
+				b.WriteString(quote(msg.ReasoningContent))
+				b.WriteString("\n\n")
+			}
+			if len(msg.Thoughts) > 0 {
+				b.WriteString("**Thoughts**\n\n")
+				for _, thought := range msg.Thoughts {
+					b.WriteString(quote(thought))
+					b.WriteString("\n\n")
+				}
+			}
+			if msg.Content != "" {
+				b.WriteString(msg.Content)
+				b.WriteString("\n\n")
+			}
+			if len(msg.Annotations) > 0 {
+				*footnotes++
+				writeMarkdownSources(b, msg.Annotations, *footnotes)
+			}
+			for _, tc := range msg.ToolCalls {
+				result, ok := results[tc.ID]
+				answered[tc.ID] = ok
+				writeMarkdownToolCall(b, tc, result, ok, level, footnotes)
+			}
+
+		case string(chat.MessageRoleTool):
+			if answered[msg.ToolCallID] {
+				continue
+			}
+			fmt.Fprintf(b, "<details>\n<summary>Tool output (%s)</summary>\n\n%s%s\n</details>\n\n", msg.ToolCallID, codeBlock(msg.Content), truncationNote(msg.Truncation))
+
+		case transcriptRoleSummary:
+			fmt.Fprintf(b, "%s Summary\n\n_The conversation up to this point was compacted into this summary._\n\n%s\n\n", heading, msg.Content)
+
+		case transcriptRoleSystemReminder:
+			fmt.Fprintf(b, "%s System reminder\n\n%s\n\n", heading, quote(msg.Content))
+		}
+	}
+}
+
+// writeMarkdownSources renders the sources of an answer as footnotes, with
+// labels prefixed by the number of the answer.
+func writeMarkdownSources(b *strings.Builder, annotations []chat.Annotation, answer int) {
+	refs := make([]string, 0, len(annotations))
+	for _, a := range annotations {
+		refs = append(refs, fmt.Sprintf("[^source-%d-%d]", answer, a.Index))
+	}
+	fmt.Fprintf(b, "Sources: %s\n\n", strings.Join(refs, " "))
+
+	for _, a := range annotations {
+		fmt.Fprintf(b, "[^source-%d-%d]: `%s`", answer, a.Index, a.Location())
+		if a.RAGName != "" {
+			fmt.Fprintf(b, " from %s", a.RAGName)
+		}
+		if a.Score != 0 {
+			fmt.Fprintf(b, ", score %.2f", a.Score)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+}
+
+func writeMarkdownToolCall(b *strings.Builder, tc TranscriptToolCall, result TranscriptMessage, hasResult bool, level int, footnotes *int) {
+	fmt.Fprintf(b, "<details>\n<summary>Tool call: %s (%s)</summary>\n\n", tc.Name, tc.ID)
+	fmt.Fprintf(b, "**Arguments**\n\n%s\n", codeBlock(tc.Arguments))
+	if hasResult && result.ArgumentsEdit != nil {
+		fmt.Fprintf(b, "**Arguments edited by the user**\n\n%s\n", codeBlock(result.ArgumentsEdit.Edited))
+	}
+	if hasResult {
+		fmt.Fprintf(b, "**Output**\n\n%s%s\n", codeBlock(result.Content), truncationNote(result.Truncation))
+	}
+	if tc.SubSession != nil {
+		writeMarkdownMessages(b, tc.SubSession.Messages, level+1, footnotes)
+	}
+	b.WriteString("</details>\n\n")
+}
+
+// truncationNote returns a note on how a tool output was truncated, or "" if
+// it wasn't.
+func truncationNote(t *tools.OutputTruncation) string {
+	if t == nil {
+		return ""
+	}
+	return fmt.Sprintf("\n_The output was truncated (%s) to %d of its %d bytes before being sent to the model._\n", t.Policy, t.KeptBytes, t.OriginalBytes)
+}
+
+// codeBlock returns s in a fenced code block, indented if it is JSON. The
+// fence is longer than any run of backticks in s.
+func codeBlock(s string) string {
+	lang := ""
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err == nil {
+		if indented, err := json.MarshalIndent(v, "", "  "); err == nil {
+			s = string(indented)
+			lang = "json"
+		}
+	}
+
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+
+	return fence + lang + "\n" + strings.TrimRight(s, "\n") + "\n" + fence + "\n"
+}
+
+// quote renders s as a Markdown block quote.
+func quote(s string) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("> "+line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
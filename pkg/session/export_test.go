@@ -0,0 +1,233 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/golden"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// newExportSession builds a session where root transfers a task to a
+// researcher, thinks, runs a shell command that prints a secret and is then
+// compacted.
+func newExportSession() *Session {
+	createdAt := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	sub := New(WithID("sub-1"), WithTitle("Release date"))
+	sub.CreatedAt = createdAt
+	sub.AddMessage(&Message{Message: chat.Message{
+		Role:      chat.MessageRoleSystem,
+		Content:   "Find the Go 1.26 release date",
+		CreatedAt: "2025-06-01T10:00:02Z",
+	}})
+	sub.AddMessage(&Message{Implicit: true, Message: chat.Message{
+		Role:      chat.MessageRoleUser,
+		Content:   "Please proceed.",
+		CreatedAt: "2025-06-01T10:00:02Z",
+	}})
+	sub.AddMessage(NewAgentMessage("researcher", &chat.Message{
+		Role:      chat.MessageRoleAssistant,
+		Content:   "Go 1.26 was released in February 2026.",
+		CreatedAt: "2025-06-01T10:00:03Z",
+		Cost:      0.001,
+	}))
+
+	sess := New(WithID("sess-1"), WithTitle("Go release"))
+	sess.CreatedAt = createdAt
+	sess.InputTokens = 1200
+	sess.OutputTokens = 300
+	sess.AddMessage(&Message{Message: chat.Message{
+		Role:      chat.MessageRoleUser,
+		Content:   "When was Go 1.26 released?",
+		CreatedAt: "2025-06-01T10:00:00Z",
+	}})
+	sess.AddMessage(NewAgentMessage("root", &chat.Message{
+		Role:             chat.MessageRoleAssistant,
+		ReasoningContent: "The researcher knows this.\nLet me ask them.",
+		ToolCalls: []tools.ToolCall{{
+			ID:       "call_1",
+			Function: tools.FunctionCall{Name: "transfer_task", Arguments: `{"agent":"researcher","task":"Find the Go 1.26 release date"}`},
+		}},
+		CreatedAt: "2025-06-01T10:00:01Z",
+		Usage:     &chat.Usage{InputTokens: 100, OutputTokens: 20},
+		Cost:      0.002,
+	}))
+	sess.AddSubSession(sub)
+	sess.AddMessage(&Message{Message: chat.Message{
+		Role:       chat.MessageRoleTool,
+		ToolCallID: "call_1",
+		Content:    "Go 1.26 was released in February 2026.",
+		CreatedAt:  "2025-06-01T10:00:04Z",
+	}})
+	sess.AddMessage(NewAgentMessage("root", &chat.Message{
+		Role:    chat.MessageRoleAssistant,
+		Content: "Let me check the API key too.",
+		ToolCalls: []tools.ToolCall{{
+			ID:       "call_think",
+			Function: tools.FunctionCall{Name: "think", Arguments: `{"thought":"The key should be in .env.\nA grep will find it."}`},
+		}, {
+			ID:       "call_2",
+			Function: tools.FunctionCall{Name: "shell", Arguments: `{"cmd":"grep API_KEY .env"}`},
+		}},
+		CreatedAt: "2025-06-01T10:00:05Z",
+	}))
+	sess.AddMessage(&Message{Message: chat.Message{
+		Role:       chat.MessageRoleTool,
+		ToolCallID: "call_think",
+		Content:    "Thought recorded.",
+		CreatedAt:  "2025-06-01T10:00:05Z",
+	}})
+	sess.AddMessage(&Message{Message: chat.Message{
+		Role:       chat.MessageRoleTool,
+		ToolCallID: "call_2",
+		Content:    "API_KEY=sk-secret-123",
+		CreatedAt:  "2025-06-01T10:00:06Z",
+	}})
+	sess.Messages = append(sess.Messages, Item{Summary: "The user asked when Go 1.26 was released."})
+	sess.AddMessage(NewAgentMessage("root", &chat.Message{
+		Role:      chat.MessageRoleAssistant,
+		Content:   "Go 1.26 was released in **February 2026**.",
+		CreatedAt: "2025-06-01T10:00:07Z",
+	}))
+
+	return sess
+}
+
+func redactSecrets(s string) string {
+	return strings.ReplaceAll(s, "sk-secret-123", "[REDACTED]")
+}
+
+func TestExportMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, newExportSession().Export(&buf, ExportFormatMarkdown, WithRedaction(redactSecrets)))
+	golden.Assert(t, buf.String(), "export_markdown.golden")
+}
+
+func TestExportJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, newExportSession().Export(&buf, ExportFormatJSON, WithRedaction(redactSecrets)))
+	golden.Assert(t, buf.String(), "export_json.golden")
+}
+
+func TestExportUnsupportedFormat(t *testing.T) {
+	var buf bytes.Buffer
+	err := newExportSession().Export(&buf, "html")
+	require.ErrorContains(t, err, `unsupported export format "html"`)
+	require.Zero(t, buf.Len())
+}
+
+func TestExportTruncatedToolOutput(t *testing.T) {
+	sess := New(WithUserMessage("Find the TODOs"))
+	sess.AddMessage(NewAgentMessage("root", &chat.Message{
+		Role: chat.MessageRoleAssistant,
+		ToolCalls: []tools.ToolCall{{
+			ID:       "call_1",
+			Function: tools.FunctionCall{Name: "shell", Arguments: `{"cmd":"grep -r TODO ."}`},
+		}},
+	}))
+	sess.AddMessage(&Message{Message: chat.Message{
+		Role:       chat.MessageRoleTool,
+		ToolCallID: "call_1",
+		Content:    "./a.go: TODO\n\n[... 99990 of 100000 bytes truncated ...]",
+		Truncation: &tools.OutputTruncation{Policy: tools.TruncateHead, OriginalBytes: 100000, KeptBytes: 10},
+	}})
+
+	var buf bytes.Buffer
+	require.NoError(t, sess.Export(&buf, ExportFormatMarkdown))
+	require.Contains(t, buf.String(), "_The output was truncated (head) to 10 of its 100000 bytes before being sent to the model._")
+
+	buf.Reset()
+	require.NoError(t, sess.Export(&buf, ExportFormatJSON))
+	require.Contains(t, buf.String(), `"truncation": {
+        "policy": "head",
+        "original_bytes": 100000,
+        "kept_bytes": 10
+      }`)
+}
+
+func TestExportFileChanges(t *testing.T) {
+	sess := New(WithUserMessage("Rename the function"))
+	sess.AddFileChange(tools.FileChange{Path: "/src/a.go", Type: tools.FileModified})
+	sess.AddFileChange(tools.FileChange{Path: "/src/c.go", OldPath: "/src/b.go", Type: tools.FileRenamed})
+
+	var buf bytes.Buffer
+	require.NoError(t, sess.Export(&buf, ExportFormatMarkdown))
+	require.Contains(t, buf.String(), "## Files changed\n\n- modified `/src/a.go`\n- renamed `/src/b.go` → `/src/c.go`\n")
+
+	buf.Reset()
+	require.NoError(t, sess.Export(&buf, ExportFormatJSON))
+	require.Contains(t, buf.String(), `"file_changes": [`)
+}
+
+func TestExportSystemReminder(t *testing.T) {
+	sess := New(WithUserMessage("Summarize the changes"))
+	sess.AddSystemReminder("Answer in JSON")
+
+	var buf bytes.Buffer
+	require.NoError(t, sess.Export(&buf, ExportFormatMarkdown))
+	require.Contains(t, buf.String(), "## System reminder\n\n> Answer in JSON\n")
+	require.NotContains(t, buf.String(), "## User\n\nAnswer in JSON")
+
+	buf.Reset()
+	require.NoError(t, sess.Export(&buf, ExportFormatJSON))
+	require.Contains(t, buf.String(), `"role": "system_reminder"`)
+}
+
+func TestExportAnnotations(t *testing.T) {
+	sess := New(WithUserMessage("How do I install it?"))
+	sess.AddMessage(NewAgentMessage("root", &chat.Message{
+		Role:    chat.MessageRoleAssistant,
+		Content: "Run the installer [1].",
+		Annotations: []chat.Annotation{
+			{Index: 1, RAGName: "docs", Path: "docs/install.md", StartLine: 10, EndLine: 24, Score: 0.875},
+			{Index: 3, Path: "README.md"},
+		},
+	}))
+
+	var buf bytes.Buffer
+	require.NoError(t, sess.Export(&buf, ExportFormatMarkdown))
+	require.Contains(t, buf.String(), "Run the installer [1].\n\nSources: [^source-1-1] [^source-1-3]\n\n"+
+		"[^source-1-1]: `docs/install.md:10-24` from docs, score 0.88\n"+
+		"[^source-1-3]: `README.md`\n")
+
+	buf.Reset()
+	require.NoError(t, sess.Export(&buf, ExportFormatJSON))
+	require.Contains(t, buf.String(), `"rag_name": "docs"`)
+}
+
+func TestExportSubSessionsByToolCall(t *testing.T) {
+	sess := New(WithUserMessage("Compare the providers"))
+	sess.AddMessage(NewAgentMessage("root", &chat.Message{
+		Role: chat.MessageRoleAssistant,
+		ToolCalls: []tools.ToolCall{{
+			ID:       "call_a",
+			Function: tools.FunctionCall{Name: "transfer_task", Arguments: `{"agent":"researcher","task":"Compare quota APIs"}`},
+		}, {
+			ID:       "call_b",
+			Function: tools.FunctionCall{Name: "transfer_task", Arguments: `{"agent":"writer","task":"Draft the report"}`},
+		}},
+	}))
+	// The sub-sessions are attached to the calls that spawned them, not to
+	// the last call still waiting for its result.
+	sess.AddSubSession(New(WithID("sub-a"), WithTitle("researcher: Compare quota APIs"), WithToolCallID("call_a")))
+	sess.AddSubSession(New(WithID("sub-b"), WithTitle("writer: Draft the report"), WithToolCallID("call_b")))
+
+	var buf bytes.Buffer
+	require.NoError(t, sess.Export(&buf, ExportFormatJSON))
+
+	var transcript Transcript
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &transcript))
+	toolCalls := transcript.Messages[1].ToolCalls
+	require.Len(t, toolCalls, 2)
+	require.NotNil(t, toolCalls[0].SubSession)
+	require.Equal(t, "sub-a", toolCalls[0].SubSession.ID)
+	require.NotNil(t, toolCalls[1].SubSession)
+	require.Equal(t, "sub-b", toolCalls[1].SubSession.ID)
+}
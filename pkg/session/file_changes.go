@@ -0,0 +1,205 @@
+package session
+
+import (
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// fileSnapshot is the content of a file before the tools first changed it.
+type fileSnapshot struct {
+	// existed is false when the file didn't exist: reverting removes it.
+	existed bool
+	content []byte
+	mode    fs.FileMode
+	// unrestorable is set when the content couldn't be saved, e.g. because
+	// the file was too large: the file can't be reverted.
+	unrestorable bool
+}
+
+// AddFileChange records a change a tool made to a file. The ledger holds one
+// change per file, see tools.MergeFileChange.
+func (s *Session) AddFileChange(change tools.FileChange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.FileChangeLog = tools.MergeFileChange(s.FileChangeLog, change)
+}
+
+// FileChanges returns the files changed by the tools during the session,
+// including its sub-sessions, in the order they were first changed.
+func (s *Session) FileChanges() []tools.FileChange {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return slices.Clone(s.FileChangeLog)
+}
+
+// SnapshotFile saves the content of a file a tool is about to change, so
+// that RevertFileChanges can restore it. Only the content before the first
+// change is kept, and the one before the first change since each
+// checkpoint, for RevertFilesToCheckpoint. Files larger than maxBytes aren't
+// saved and can't be reverted.
+func (s *Session) SnapshotFile(path string, maxBytes int64) {
+	s.mu.RLock()
+	saved := s.snapshotSaved(path)
+	s.mu.RUnlock()
+	if saved {
+		return
+	}
+
+	snapshot := readFileSnapshot(path, maxBytes)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.saveSnapshot(path, snapshot)
+}
+
+// snapshotSaved reports whether the session and all its checkpoints have
+// the content of a file saved.
+func (s *Session) snapshotSaved(path string) bool {
+	if _, saved := s.fileSnapshots[path]; !saved {
+		return false
+	}
+	for _, checkpoint := range s.Checkpoints {
+		if _, saved := checkpoint.fileSnapshots[path]; !saved {
+			return false
+		}
+	}
+	return true
+}
+
+// saveSnapshot saves the content of a file in the session and in its
+// checkpoints that don't have it yet.
+func (s *Session) saveSnapshot(path string, snapshot *fileSnapshot) {
+	if _, saved := s.fileSnapshots[path]; !saved {
+		if s.fileSnapshots == nil {
+			s.fileSnapshots = make(map[string]*fileSnapshot)
+		}
+		s.fileSnapshots[path] = snapshot
+	}
+	for i := range s.Checkpoints {
+		checkpoint := &s.Checkpoints[i]
+		if _, saved := checkpoint.fileSnapshots[path]; saved {
+			continue
+		}
+		if checkpoint.fileSnapshots == nil {
+			checkpoint.fileSnapshots = make(map[string]*fileSnapshot)
+		}
+		checkpoint.fileSnapshots[path] = snapshot
+	}
+}
+
+func readFileSnapshot(path string, maxBytes int64) *fileSnapshot {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &fileSnapshot{}
+	}
+	if err != nil || !info.Mode().IsRegular() || info.Size() > maxBytes {
+		return &fileSnapshot{unrestorable: true}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return &fileSnapshot{unrestorable: true}
+	}
+	return &fileSnapshot{existed: true, content: content, mode: info.Mode().Perm()}
+}
+
+func (snapshot *fileSnapshot) restore(path string) error {
+	if !snapshot.existed {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, snapshot.content, snapshot.mode)
+}
+
+// RevertFileChanges restores the changed files to the content saved by
+// SnapshotFile, and removes the files the tools created, most recent change
+// first. It can be called once per change: the reverted changes are dropped
+// from the ledger. It returns the paths of the reverted files. The changes of
+// the files without a snapshot are kept in the ledger.
+func (s *Session) RevertFileChanges() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		reverted []string
+		kept     []tools.FileChange
+		errs     []error
+	)
+	for _, change := range slices.Backward(s.FileChangeLog) {
+		paths := []string{change.Path}
+		if change.OldPath != "" {
+			paths = append(paths, change.OldPath)
+		}
+
+		restorable := true
+		for _, path := range paths {
+			if snapshot, ok := s.fileSnapshots[path]; !ok || snapshot.unrestorable {
+				restorable = false
+			}
+		}
+		if !restorable {
+			kept = append(kept, change)
+			continue
+		}
+
+		var failed bool
+		for _, path := range paths {
+			if err := s.fileSnapshots[path].restore(path); err != nil {
+				errs = append(errs, err)
+				failed = true
+			}
+		}
+		if failed {
+			kept = append(kept, change)
+			continue
+		}
+
+		for _, path := range paths {
+			delete(s.fileSnapshots, path)
+		}
+		reverted = append(reverted, change.Path)
+	}
+
+	slices.Reverse(kept)
+	s.FileChangeLog = kept
+	return reverted, errors.Join(errs...)
+}
+
+// mergeFileChanges adds the file changes of a sub-session to the session,
+// with the snapshots of the files the session didn't change before, or
+// didn't change since its checkpoints.
+func (s *Session) mergeFileChanges(sub *Session) {
+	sub.mu.RLock()
+	changes := slices.Clone(sub.FileChangeLog)
+	snapshots := maps.Clone(sub.fileSnapshots)
+	sub.mu.RUnlock()
+
+	if len(changes) == 0 && len(snapshots) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, change := range changes {
+		s.FileChangeLog = tools.MergeFileChange(s.FileChangeLog, change)
+	}
+	for path, snapshot := range snapshots {
+		s.saveSnapshot(path, snapshot)
+	}
+}
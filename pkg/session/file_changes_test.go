@@ -0,0 +1,82 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+func TestRevertFileChanges(t *testing.T) {
+	dir := t.TempDir()
+	modified := filepath.Join(dir, "modified.txt")
+	created := filepath.Join(dir, "created.txt")
+	large := filepath.Join(dir, "large.txt")
+	require.NoError(t, os.WriteFile(modified, []byte("before"), 0o644))
+	require.NoError(t, os.WriteFile(large, []byte("too large"), 0o644))
+
+	sess := New()
+	for _, path := range []string{modified, created, large} {
+		sess.SnapshotFile(path, 8)
+	}
+	require.NoError(t, os.WriteFile(modified, []byte("after"), 0o644))
+	require.NoError(t, os.WriteFile(created, []byte("new"), 0o644))
+	require.NoError(t, os.WriteFile(large, []byte("changed"), 0o644))
+	sess.AddFileChange(tools.FileChange{Path: modified, Type: tools.FileModified})
+	sess.AddFileChange(tools.FileChange{Path: created, Type: tools.FileCreated})
+	sess.AddFileChange(tools.FileChange{Path: large, Type: tools.FileModified})
+
+	// Only the content before the first change is kept.
+	sess.SnapshotFile(modified, 8)
+
+	reverted, err := sess.RevertFileChanges()
+	require.NoError(t, err)
+	assert.Equal(t, []string{created, modified}, reverted)
+
+	content, err := os.ReadFile(modified)
+	require.NoError(t, err)
+	assert.Equal(t, "before", string(content))
+	assert.NoFileExists(t, created)
+
+	// The file too large to be saved is left alone.
+	content, err = os.ReadFile(large)
+	require.NoError(t, err)
+	assert.Equal(t, "changed", string(content))
+	assert.Equal(t, []tools.FileChange{{Path: large, Type: tools.FileModified}}, sess.FileChanges())
+
+	reverted, err = sess.RevertFileChanges()
+	require.NoError(t, err)
+	assert.Empty(t, reverted)
+}
+
+func TestAddSubSession_MergesFileChanges(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+	require.NoError(t, os.WriteFile(path, []byte("before"), 0o644))
+
+	parent := New()
+	parent.AddFileChange(tools.FileChange{Path: filepath.Join(dir, "other.txt"), Type: tools.FileCreated})
+
+	child := New(WithParentID(parent.ID))
+	child.SnapshotFile(path, 1024)
+	require.NoError(t, os.WriteFile(path, []byte("after"), 0o644))
+	child.AddFileChange(tools.FileChange{Path: path, Type: tools.FileModified})
+
+	parent.AddSubSession(child)
+
+	assert.Equal(t, []tools.FileChange{
+		{Path: filepath.Join(dir, "other.txt"), Type: tools.FileCreated},
+		{Path: path, Type: tools.FileModified},
+	}, parent.FileChanges())
+
+	reverted, err := parent.RevertFileChanges()
+	require.NoError(t, err)
+	assert.Equal(t, []string{path}, reverted)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "before", string(content))
+}
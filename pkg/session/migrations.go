@@ -387,6 +387,36 @@ func getAllMigrations() []Migration {
 			Description: "Add first_kept_entry column to session_items for compaction-preserved messages",
 			UpSQL:       `ALTER TABLE session_items ADD COLUMN first_kept_entry INTEGER DEFAULT 0`,
 		},
+		{
+			ID:          22,
+			Name:        "022_add_handoff_history_column",
+			Description: "Add handoff_history column to sessions table for tracking handoffs between agents",
+			UpSQL:       `ALTER TABLE sessions ADD COLUMN handoff_history TEXT DEFAULT '[]'`,
+		},
+		{
+			ID:          23,
+			Name:        "023_add_file_changes_column",
+			Description: "Add file_changes column to sessions table for tracking the files changed by tools",
+			UpSQL:       `ALTER TABLE sessions ADD COLUMN file_changes TEXT DEFAULT '[]'`,
+		},
+		{
+			ID:          24,
+			Name:        "024_add_summary_info_column",
+			Description: "Add summary_info column to session_items for the details of the summaries",
+			UpSQL:       `ALTER TABLE session_items ADD COLUMN summary_info TEXT`,
+		},
+		{
+			ID:          25,
+			Name:        "025_add_tool_call_id_column",
+			Description: "Add tool_call_id column to sessions table for the tool call that spawned a sub-session",
+			UpSQL:       `ALTER TABLE sessions ADD COLUMN tool_call_id TEXT DEFAULT ''`,
+		},
+		{
+			ID:          26,
+			Name:        "026_add_checkpoints_column",
+			Description: "Add checkpoints column to sessions table for the points sessions can be rolled back to",
+			UpSQL:       `ALTER TABLE sessions ADD COLUMN checkpoints TEXT DEFAULT '[]'`,
+		},
 	}
 }
 
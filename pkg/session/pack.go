@@ -0,0 +1,137 @@
+package session
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/compaction"
+)
+
+// MessagesOpt configures GetMessages.
+type MessagesOpt func(*messagesOptions)
+
+type messagesOptions struct {
+	tokenBudget      int64
+	estimate         compaction.TokenEstimator
+	turnInstructions []string
+	withoutReminders bool
+	pruning          PruningPolicy
+}
+
+// WithTokenBudget packs the messages into budget tokens, usually the context
+// window of the model minus a reserve for its output. The oldest messages
+// are left out until the messages fit. Zero or less means no budget.
+func WithTokenBudget(budget int64) MessagesOpt {
+	return func(o *messagesOptions) {
+		o.tokenBudget = budget
+	}
+}
+
+// WithTokenEstimator sets how the tokens of the messages are counted against
+// the budget. Defaults to compaction.EstimateMessageTokens.
+func WithTokenEstimator(estimate compaction.TokenEstimator) MessagesOpt {
+	return func(o *messagesOptions) {
+		o.estimate = estimate
+	}
+}
+
+// WithTurnInstructions adds sections to the system messages, after the
+// instructions of the agent and of its toolsets, e.g. the current state of
+// the toolsets. Unlike those, they're only sent with the current turn.
+func WithTurnInstructions(sections []string) MessagesOpt {
+	return func(o *messagesOptions) {
+		o.turnInstructions = sections
+	}
+}
+
+// TrimmedMessages returns the number of conversation messages the last call
+// to GetMessages left out to fit its token budget.
+func (s *Session) TrimmedMessages() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.trimmedMessages
+}
+
+func (s *Session) setTrimmedMessages(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trimmedMessages = n
+}
+
+// packMessages leaves out the oldest conversation messages until the
+// messages fit in budget tokens. An assistant message and the results of
+// its tool calls are kept or left out together. System messages, the last
+// user message and the last group of messages are always kept, so the
+// result can still exceed the budget. It returns the packed messages and
+// the number of messages left out, which are replaced by a note.
+func packMessages(messages []chat.Message, budget int64, estimate compaction.TokenEstimator) ([]chat.Message, int) {
+	tokens := make([]int64, len(messages))
+	var total int64
+	for i := range messages {
+		tokens[i] = estimate(&messages[i])
+		total += tokens[i]
+	}
+	if total <= budget {
+		return messages, 0
+	}
+
+	// Group the conversation messages: a user or an assistant message starts
+	// a group, tool results belong to the group of the preceding message.
+	var groups [][]int
+	lastUser := -1
+	for i, msg := range messages {
+		switch msg.Role {
+		case chat.MessageRoleSystem:
+			continue
+		case chat.MessageRoleTool:
+			if len(groups) > 0 {
+				groups[len(groups)-1] = append(groups[len(groups)-1], i)
+				continue
+			}
+		case chat.MessageRoleUser:
+			if !msg.SystemReminder {
+				lastUser = i
+			}
+		}
+		groups = append(groups, []int{i})
+	}
+
+	dropped := make(map[int]bool)
+	for g, group := range groups {
+		if total <= budget {
+			break
+		}
+		if g == len(groups)-1 || group[0] == lastUser {
+			continue
+		}
+		for _, i := range group {
+			dropped[i] = true
+			total -= tokens[i]
+		}
+	}
+	if len(dropped) == 0 {
+		return messages, 0
+	}
+
+	note := chat.Message{
+		Role:    chat.MessageRoleSystem,
+		Content: fmt.Sprintf("%d earlier messages of the conversation were left out to fit the context window.", len(dropped)),
+	}
+
+	packed := make([]chat.Message, 0, len(messages)-len(dropped)+1)
+	noted := false
+	for i, msg := range messages {
+		if dropped[i] {
+			continue
+		}
+		if !noted && msg.Role != chat.MessageRoleSystem {
+			packed = append(packed, note)
+			noted = true
+		}
+		packed = append(packed, msg)
+	}
+
+	slog.Debug("Packed messages into the token budget", "budget", budget, "estimated_tokens", total+estimate(&note), "dropped_messages", len(dropped))
+	return packed, len(dropped)
+}
@@ -0,0 +1,132 @@
+package session
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// lengthEstimator counts one token per byte of content.
+func lengthEstimator(msg *chat.Message) int64 {
+	return int64(len(msg.Content))
+}
+
+func TestPackMessages_FitsBudget(t *testing.T) {
+	t.Parallel()
+
+	messages := []chat.Message{
+		{Role: chat.MessageRoleSystem, Content: "system"},
+		{Role: chat.MessageRoleUser, Content: "hello"},
+	}
+
+	packed, dropped := packMessages(messages, 100, lengthEstimator)
+	assert.Equal(t, messages, packed)
+	assert.Zero(t, dropped)
+}
+
+func TestPackMessages_DropsOldestGroups(t *testing.T) {
+	t.Parallel()
+
+	messages := []chat.Message{
+		{Role: chat.MessageRoleSystem, Content: "system"},
+		{Role: chat.MessageRoleUser, Content: strings.Repeat("a", 100)},
+		{Role: chat.MessageRoleAssistant, Content: "calling", ToolCalls: []tools.ToolCall{{ID: "1"}, {ID: "2"}}},
+		{Role: chat.MessageRoleTool, ToolCallID: "1", Content: strings.Repeat("b", 100)},
+		{Role: chat.MessageRoleTool, ToolCallID: "2", Content: strings.Repeat("c", 100)},
+		{Role: chat.MessageRoleUser, Content: "latest question"},
+		{Role: chat.MessageRoleAssistant, Content: "answer"},
+	}
+
+	packed, dropped := packMessages(messages, 50, lengthEstimator)
+	assert.Equal(t, 4, dropped)
+
+	require.Len(t, packed, 4)
+	assert.Equal(t, "system", packed[0].Content)
+	assert.Equal(t, chat.MessageRoleSystem, packed[1].Role)
+	assert.Contains(t, packed[1].Content, "4 earlier messages")
+	assert.Equal(t, "latest question", packed[2].Content)
+	assert.Equal(t, "answer", packed[3].Content)
+}
+
+func TestPackMessages_KeepsToolResultsWithTheirCall(t *testing.T) {
+	t.Parallel()
+
+	messages := []chat.Message{
+		{Role: chat.MessageRoleUser, Content: strings.Repeat("a", 100)},
+		{Role: chat.MessageRoleAssistant, Content: "first", ToolCalls: []tools.ToolCall{{ID: "1"}}},
+		{Role: chat.MessageRoleTool, ToolCallID: "1", Content: strings.Repeat("b", 100)},
+		{Role: chat.MessageRoleAssistant, Content: "second", ToolCalls: []tools.ToolCall{{ID: "2"}}},
+		{Role: chat.MessageRoleTool, ToolCallID: "2", Content: "two"},
+		{Role: chat.MessageRoleAssistant, Content: "done"},
+	}
+
+	// The only user message is kept, so the oldest tool call group goes.
+	packed, dropped := packMessages(messages, 150, lengthEstimator)
+	assert.Equal(t, 2, dropped)
+
+	callIDs := make(map[string]bool)
+	for _, msg := range packed {
+		for _, tc := range msg.ToolCalls {
+			callIDs[tc.ID] = true
+		}
+		if msg.Role == chat.MessageRoleTool {
+			assert.True(t, callIDs[msg.ToolCallID], "tool result %s should follow its call", msg.ToolCallID)
+		}
+	}
+	assert.False(t, callIDs["1"])
+	assert.True(t, callIDs["2"])
+	assert.Equal(t, strings.Repeat("a", 100), packed[1].Content)
+}
+
+func TestPackMessages_KeepsLastUserMessageOverBudget(t *testing.T) {
+	t.Parallel()
+
+	messages := []chat.Message{
+		{Role: chat.MessageRoleUser, Content: strings.Repeat("a", 100)},
+	}
+
+	packed, dropped := packMessages(messages, 10, lengthEstimator)
+	assert.Equal(t, messages, packed)
+	assert.Zero(t, dropped)
+}
+
+func TestGetMessages_TokenBudget(t *testing.T) {
+	testAgent := agent.New("root", "instructions")
+
+	s := New()
+	for range 10 {
+		s.AddMessage(UserMessage(strings.Repeat("question ", 50)))
+		s.AddMessage(NewAgentMessage("root", &chat.Message{
+			Role:    chat.MessageRoleAssistant,
+			Content: strings.Repeat("answer ", 50),
+		}))
+	}
+	s.AddMessage(UserMessage("latest question"))
+
+	all := s.GetMessages(testAgent)
+	assert.Zero(t, s.TrimmedMessages())
+
+	const budget = 500
+	packed := s.GetMessages(testAgent, WithTokenBudget(budget), WithTokenEstimator(lengthEstimator))
+	assert.Less(t, len(packed), len(all))
+	assert.Equal(t, "latest question", packed[len(packed)-1].Content)
+	assert.Equal(t, len(all)-len(packed)+1, s.TrimmedMessages())
+
+	var total int64
+	for i := range packed {
+		if !strings.Contains(packed[i].Content, "earlier messages of the conversation were left out") {
+			total += lengthEstimator(&packed[i])
+		}
+	}
+	assert.LessOrEqual(t, total, int64(budget))
+
+	// Without a budget, nothing is left out.
+	assert.Equal(t, all, s.GetMessages(testAgent))
+	assert.Zero(t, s.TrimmedMessages())
+}
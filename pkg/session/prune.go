@@ -0,0 +1,214 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// defaultReadTools are the tools whose stale outputs are stubbed when the
+// pruning policy doesn't name any.
+var defaultReadTools = []string{"read_file"}
+
+// PruningPolicy sets which tool exchanges GetMessages leaves out of the
+// messages sent to the model because they don't inform it anymore. The
+// session itself keeps them, so they're still stored and exported.
+type PruningPolicy struct {
+	// FailedToolCalls collapses the failed calls of a tool that a later
+	// successful call to the same tool corrected into a short note, e.g.
+	// "3 failed grep attempts omitted.".
+	FailedToolCalls bool
+
+	// StaleReadsAfterTurns replaces the outputs of the file reads made that
+	// many user turns ago or more with a stub keeping the path of the file.
+	// Zero or less keeps them.
+	StaleReadsAfterTurns int
+
+	// ReadTools names the tools whose outputs are stubbed by
+	// StaleReadsAfterTurns. Defaults to read_file.
+	ReadTools []string
+}
+
+// WithToolResultPruning prunes the tool exchanges of the conversation
+// according to policy before the messages are packed. The assistant
+// messages and the results of their tool calls stay paired.
+func WithToolResultPruning(policy PruningPolicy) MessagesOpt {
+	return func(o *messagesOptions) {
+		o.pruning = policy
+	}
+}
+
+// toolExchange is a tool call of the conversation and its result.
+type toolExchange struct {
+	name      string
+	arguments string
+	call      int // index of the assistant message
+	result    int // index of the result, -1 if there's none
+}
+
+// pruneToolResults returns messages pruned according to policy. messages is
+// left unchanged.
+func pruneToolResults(messages []chat.Message, policy PruningPolicy) []chat.Message {
+	if !policy.FailedToolCalls && policy.StaleReadsAfterTurns <= 0 {
+		return messages
+	}
+
+	exchanges := make(map[string]*toolExchange)
+	var order []string
+	for i, msg := range messages {
+		switch msg.Role {
+		case chat.MessageRoleAssistant:
+			for _, tc := range msg.ToolCalls {
+				if tc.ID == "" {
+					continue
+				}
+				exchanges[tc.ID] = &toolExchange{name: tc.Function.Name, arguments: tc.Function.Arguments, call: i, result: -1}
+				order = append(order, tc.ID)
+			}
+		case chat.MessageRoleTool:
+			if ex, ok := exchanges[msg.ToolCallID]; ok && ex.result < 0 {
+				ex.result = i
+			}
+		}
+	}
+	if len(order) == 0 {
+		return messages
+	}
+
+	var (
+		pruned map[string]bool
+		notes  map[int][]string
+		stubs  map[int]string
+	)
+	if policy.FailedToolCalls {
+		pruned, notes = prunableFailedCalls(messages, exchanges, order)
+	}
+	if policy.StaleReadsAfterTurns > 0 {
+		stubs = staleReads(messages, exchanges, order, policy)
+	}
+
+	if len(pruned) == 0 && len(stubs) == 0 {
+		return messages
+	}
+
+	result := make([]chat.Message, 0, len(messages))
+	for i, msg := range messages {
+		if note := notes[i]; len(note) > 0 {
+			result = append(result, chat.SystemReminderMessage(strings.Join(note, " ")))
+		}
+
+		switch msg.Role {
+		case chat.MessageRoleAssistant:
+			kept := slices.DeleteFunc(slices.Clone(msg.ToolCalls), func(tc tools.ToolCall) bool { return pruned[tc.ID] })
+			if len(kept) == len(msg.ToolCalls) {
+				break
+			}
+			if len(kept) == 0 && msg.Content == "" && len(msg.MultiContent) == 0 {
+				continue
+			}
+			msg.ToolCalls = kept
+		case chat.MessageRoleTool:
+			if pruned[msg.ToolCallID] {
+				continue
+			}
+			if stub, ok := stubs[i]; ok {
+				msg.Content = stub
+				msg.MultiContent = nil
+			}
+		}
+		result = append(result, msg)
+	}
+	return result
+}
+
+// prunableFailedCalls returns the failed tool calls a later call to the same
+// tool made up for, and the notes replacing them, by index of the assistant
+// message making the successful call.
+func prunableFailedCalls(messages []chat.Message, exchanges map[string]*toolExchange, order []string) (map[string]bool, map[int][]string) {
+	pruned := make(map[string]bool)
+	notes := make(map[int][]string)
+
+	failed := make(map[string][]string)
+	for _, id := range order {
+		ex := exchanges[id]
+		if ex.result < 0 {
+			continue
+		}
+		if messages[ex.result].IsError {
+			failed[ex.name] = append(failed[ex.name], id)
+			continue
+		}
+
+		// Failures of the same batch of calls weren't corrected by this one.
+		var corrected int
+		for _, failedID := range failed[ex.name] {
+			if exchanges[failedID].call < ex.call {
+				pruned[failedID] = true
+				corrected++
+			}
+		}
+		delete(failed, ex.name)
+		if corrected > 0 {
+			notes[ex.call] = append(notes[ex.call], failedAttemptsNote(corrected, ex.name))
+		}
+	}
+	return pruned, notes
+}
+
+func failedAttemptsNote(n int, toolName string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 failed %s attempt omitted.", toolName)
+	}
+	return fmt.Sprintf("%d failed %s attempts omitted.", n, toolName)
+}
+
+// staleReads returns the stubs replacing the outputs of the reads made
+// policy.StaleReadsAfterTurns user turns ago or more, by index of the
+// output.
+func staleReads(messages []chat.Message, exchanges map[string]*toolExchange, order []string, policy PruningPolicy) map[int]string {
+	readTools := policy.ReadTools
+	if len(readTools) == 0 {
+		readTools = defaultReadTools
+	}
+
+	// turns[i] is the number of user turns started before or at message i.
+	turns := make([]int, len(messages))
+	var turn int
+	for i, msg := range messages {
+		if msg.Role == chat.MessageRoleUser && !msg.SystemReminder {
+			turn++
+		}
+		turns[i] = turn
+	}
+
+	stubs := make(map[int]string)
+	for _, id := range order {
+		ex := exchanges[id]
+		if ex.result < 0 || !slices.Contains(readTools, ex.name) || turn-turns[ex.result] < policy.StaleReadsAfterTurns {
+			continue
+		}
+		output := messages[ex.result]
+		if output.IsError {
+			continue
+		}
+		stub := staleReadStub(ex)
+		if len(stub) < len(output.Content) || len(output.MultiContent) > 0 {
+			stubs[ex.result] = stub
+		}
+	}
+	return stubs
+}
+
+func staleReadStub(ex *toolExchange) string {
+	var args struct {
+		Path string `json:"path"`
+	}
+	if json.Unmarshal([]byte(ex.arguments), &args) != nil || args.Path == "" {
+		return fmt.Sprintf("[Output of an earlier %s call omitted, call it again if needed]", ex.name)
+	}
+	return fmt.Sprintf("[Output of an earlier %s call omitted, read %s again if needed]", ex.name, args.Path)
+}
@@ -0,0 +1,172 @@
+package session
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/compaction"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+func toolCall(id, name, arguments string) tools.ToolCall {
+	return tools.ToolCall{ID: id, Type: "function", Function: tools.FunctionCall{Name: name, Arguments: arguments}}
+}
+
+func callMessage(calls ...tools.ToolCall) chat.Message {
+	return chat.Message{Role: chat.MessageRoleAssistant, ToolCalls: calls}
+}
+
+func resultMessage(id, content string, isError bool) chat.Message {
+	return chat.Message{Role: chat.MessageRoleTool, ToolCallID: id, Content: content, IsError: isError}
+}
+
+// requireToolExchangesPaired checks that every tool call is answered by a
+// result following it, and that every result answers a call, like the
+// providers require.
+func requireToolExchangesPaired(t *testing.T, messages []chat.Message) {
+	t.Helper()
+
+	pending := make(map[string]bool)
+	for _, msg := range messages {
+		switch msg.Role {
+		case chat.MessageRoleTool:
+			require.True(t, pending[msg.ToolCallID], "tool result %s doesn't answer a pending call", msg.ToolCallID)
+			delete(pending, msg.ToolCallID)
+			continue
+		case chat.MessageRoleSystem:
+			continue
+		}
+		require.Empty(t, pending, "tool calls left unanswered")
+		for _, tc := range msg.ToolCalls {
+			pending[tc.ID] = true
+		}
+	}
+	require.Empty(t, pending, "tool calls left unanswered")
+}
+
+func estimateTokens(messages []chat.Message) int64 {
+	var total int64
+	for i := range messages {
+		total += compaction.EstimateMessageTokens(&messages[i])
+	}
+	return total
+}
+
+func TestPruneToolResults_Disabled(t *testing.T) {
+	t.Parallel()
+
+	messages := []chat.Message{
+		{Role: chat.MessageRoleUser, Content: "find it"},
+		callMessage(toolCall("1", "grep", `{"pattern":"(("}`)),
+		resultMessage("1", "invalid pattern", true),
+		callMessage(toolCall("2", "grep", `{"pattern":"foo"}`)),
+		resultMessage("2", "main.go:1: foo", false),
+	}
+
+	assert.Equal(t, messages, pruneToolResults(messages, PruningPolicy{}))
+}
+
+func TestPruneToolResults_CollapsesCorrectedFailures(t *testing.T) {
+	t.Parallel()
+
+	messages := []chat.Message{
+		{Role: chat.MessageRoleUser, Content: "find the handler"},
+		callMessage(toolCall("1", "grep", `{"pattern":"(handler"}`)),
+		resultMessage("1", "error parsing regexp: missing closing ): `(handler`", true),
+		{Role: chat.MessageRoleAssistant, Content: "Let me fix the pattern.", ToolCalls: []tools.ToolCall{toolCall("2", "grep", `{"pattern":"\\(handler"}`)}},
+		resultMessage("2", "error: no such directory: src", true),
+		callMessage(toolCall("3", "grep", `{"pattern":"handler","path":"pkg"}`), toolCall("4", "read_file", `{"path":"missing.go"}`)),
+		resultMessage("3", "pkg/server/handler.go:12: func handler()", false),
+		resultMessage("4", "file not found: missing.go", true),
+		{Role: chat.MessageRoleAssistant, Content: "The handler is in pkg/server/handler.go."},
+	}
+
+	pruned := pruneToolResults(messages, PruningPolicy{FailedToolCalls: true})
+
+	requireToolExchangesPaired(t, pruned)
+	assert.Less(t, estimateTokens(pruned), estimateTokens(messages))
+
+	require.Len(t, pruned, 7)
+	assert.Equal(t, "Let me fix the pattern.", pruned[1].Content)
+	assert.Empty(t, pruned[1].ToolCalls)
+	assert.Equal(t, chat.SystemReminderMessage("2 failed grep attempts omitted."), pruned[2])
+	assert.Equal(t, messages[5], pruned[3])
+	// The failed read wasn't corrected, so the model still sees it.
+	assert.Equal(t, "file not found: missing.go", pruned[5].Content)
+
+	// messages is left unchanged.
+	assert.Len(t, messages[3].ToolCalls, 1)
+}
+
+func TestPruneToolResults_KeepsUncorrectedFailures(t *testing.T) {
+	t.Parallel()
+
+	messages := []chat.Message{
+		{Role: chat.MessageRoleUser, Content: "run the tests"},
+		// A failure and a success of the same batch: the success didn't
+		// correct the failure.
+		callMessage(toolCall("1", "shell", `{"cmd":"go test ./a"}`), toolCall("2", "shell", `{"cmd":"go test ./b"}`)),
+		resultMessage("1", "FAIL", true),
+		resultMessage("2", "ok", false),
+		callMessage(toolCall("3", "shell", `{"cmd":"go test ./c"}`)),
+		resultMessage("3", "FAIL", true),
+	}
+
+	assert.Equal(t, messages, pruneToolResults(messages, PruningPolicy{FailedToolCalls: true}))
+}
+
+func TestPruneToolResults_StubsStaleReads(t *testing.T) {
+	t.Parallel()
+
+	content := strings.Repeat("package main\n", 100)
+	messages := []chat.Message{
+		{Role: chat.MessageRoleUser, Content: "read main.go"},
+		callMessage(toolCall("1", "read_file", `{"path":"main.go"}`)),
+		resultMessage("1", content, false),
+		{Role: chat.MessageRoleAssistant, Content: "It's the main package."},
+		{Role: chat.MessageRoleUser, Content: "and util.go?"},
+		callMessage(toolCall("2", "read_file", `{"path":"util.go"}`)),
+		resultMessage("2", content, false),
+		{Role: chat.MessageRoleAssistant, Content: "Also the main package."},
+		chat.SystemReminderMessage("Reminders don't start a turn"),
+	}
+
+	pruned := pruneToolResults(messages, PruningPolicy{StaleReadsAfterTurns: 1})
+
+	requireToolExchangesPaired(t, pruned)
+	assert.Less(t, estimateTokens(pruned), estimateTokens(messages))
+
+	require.Len(t, pruned, len(messages))
+	assert.Equal(t, "[Output of an earlier read_file call omitted, read main.go again if needed]", pruned[2].Content)
+	assert.Equal(t, content, pruned[6].Content)
+	assert.Equal(t, content, messages[2].Content)
+
+	assert.Equal(t, messages, pruneToolResults(messages, PruningPolicy{StaleReadsAfterTurns: 2}))
+	assert.Equal(t, messages, pruneToolResults(messages, PruningPolicy{StaleReadsAfterTurns: 1, ReadTools: []string{"cat"}}))
+}
+
+func TestGetMessages_WithToolResultPruning(t *testing.T) {
+	t.Parallel()
+
+	s := New(WithUserMessage("find the handler"))
+	s.AddMessage(NewAgentMessage("root", new(callMessage(toolCall("1", "grep", `{"pattern":"(("}`)))))
+	s.AddMessage(NewAgentMessage("root", new(resultMessage("1", "invalid pattern", true))))
+	s.AddMessage(NewAgentMessage("root", new(callMessage(toolCall("2", "grep", `{"pattern":"handler"}`)))))
+	s.AddMessage(NewAgentMessage("root", new(resultMessage("2", "handler.go:12", false))))
+
+	a := agent.New("root", "instructions")
+	all := s.GetMessages(a)
+	pruned := s.GetMessages(a, WithToolResultPruning(PruningPolicy{FailedToolCalls: true}))
+
+	requireToolExchangesPaired(t, pruned)
+	assert.Len(t, pruned, len(all)-1)
+	assert.Less(t, estimateTokens(pruned), estimateTokens(all))
+
+	// The session keeps the pruned exchange.
+	assert.Len(t, s.GetAllMessages(), 5)
+}
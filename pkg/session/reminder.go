@@ -0,0 +1,62 @@
+package session
+
+import "github.com/docker/docker-agent/pkg/chat"
+
+// ReminderOpt configures a system reminder.
+type ReminderOpt func(*Message)
+
+// WithReminderAgent records the agent a system reminder is addressed to.
+func WithReminderAgent(agentName string) ReminderOpt {
+	return func(m *Message) {
+		m.AgentName = agentName
+	}
+}
+
+// WithReminderHidden hides a system reminder from the user: the TUI and the
+// exports leave it out, like the other implicit messages.
+func WithReminderHidden() ReminderOpt {
+	return func(m *Message) {
+		m.Implicit = true
+	}
+}
+
+// SystemReminder returns a system reminder: text injected into the
+// conversation for the model on behalf of the runtime or the application,
+// rather than written by the user. Providers send it as a system message, or
+// as a tagged user message when they don't accept system messages in the
+// conversation. See chat.SystemReminders.
+func SystemReminder(text string, opts ...ReminderOpt) *Message {
+	msg := &Message{Message: chat.SystemReminderMessage(text)}
+	for _, opt := range opts {
+		opt(msg)
+	}
+	return msg
+}
+
+// IsSystemReminder reports whether the message is a system reminder.
+func (m *Message) IsSystemReminder() bool {
+	return m.Message.SystemReminder
+}
+
+// AddSystemReminder adds a system reminder at the end of the conversation
+// and returns it. Reminders keep their place in the conversation sent to the
+// model, but they're not taken for user messages: they're left out of the
+// session titles and of the conversation summarized by compactions, and
+// shown apart in the TUI and the exports.
+//
+// Applications and middleware can use it to tell the agent something between
+// two turns.
+func (s *Session) AddSystemReminder(text string, opts ...ReminderOpt) *Message {
+	msg := SystemReminder(text, opts...)
+	s.AddMessage(msg)
+	return msg
+}
+
+// WithoutSystemReminders leaves the system reminders of the conversation out
+// of the messages, e.g. to summarize it. The summary of the compacted
+// conversation, if any, is kept.
+func WithoutSystemReminders() MessagesOpt {
+	return func(o *messagesOptions) {
+		o.withoutReminders = true
+	}
+}
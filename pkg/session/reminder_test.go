@@ -0,0 +1,85 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+)
+
+func TestAddSystemReminder(t *testing.T) {
+	s := New(WithUserMessage("Fix the tests"))
+	s.AddMessage(NewAgentMessage("root", &chat.Message{Role: chat.MessageRoleAssistant, Content: "Done."}))
+	reminder := s.AddSystemReminder("The tests still fail", WithReminderAgent("root"))
+	s.AddMessage(UserMessage("Thanks"))
+
+	assert.True(t, reminder.IsSystemReminder())
+	assert.Equal(t, "root", reminder.AgentName)
+	assert.False(t, reminder.Implicit)
+	assert.False(t, reminder.CreatedAt.IsZero())
+
+	// The reminder keeps its place in the conversation.
+	messages := conversation(s.GetMessages(agent.New("root", "instructions")))
+	require.Len(t, messages, 4)
+	assert.Equal(t, "Fix the tests", messages[0].Content)
+	assert.Equal(t, "Done.", messages[1].Content)
+	assert.Equal(t, chat.SystemReminderMessage("The tests still fail"), stripCreatedAt(messages[2]))
+	assert.Equal(t, "Thanks", messages[3].Content)
+
+	// It's not a user message.
+	assert.Equal(t, []string{"Fix the tests", "Thanks"}, s.GetLastUserMessages(5))
+	s.AddSystemReminder("Keep it short", WithReminderHidden())
+	assert.Equal(t, "Thanks", s.GetLastUserMessageContent())
+}
+
+func TestGetMessages_WithoutSystemReminders(t *testing.T) {
+	s := New(WithUserMessage("first message"))
+	s.Messages = append(s.Messages, Item{Summary: "The user said hello"})
+	s.AddSystemReminder("Answer in JSON")
+	s.AddMessage(UserMessage("second message"))
+
+	messages := conversation(s.GetMessages(&agent.Agent{}, WithoutSystemReminders()))
+
+	// The summary is still there, the reminder isn't.
+	require.Len(t, messages, 2)
+	assert.True(t, messages[0].SystemReminder)
+	assert.Equal(t, "Session Summary: The user said hello", messages[0].Content)
+	assert.Equal(t, "second message", messages[1].Content)
+}
+
+func TestTrimMessages_KeepsSystemRemindersInPlace(t *testing.T) {
+	messages := []chat.Message{
+		{Role: chat.MessageRoleSystem, Content: "instructions"},
+		{Role: chat.MessageRoleUser, Content: "hello"},
+		{Role: chat.MessageRoleAssistant, Content: "first"},
+		chat.SystemReminderMessage("reminder"),
+		{Role: chat.MessageRoleAssistant, Content: "second"},
+	}
+
+	trimmed := trimMessages(messages, 3)
+
+	require.Len(t, trimmed, 4)
+	assert.Equal(t, "instructions", trimmed[0].Content)
+	assert.Equal(t, "hello", trimmed[1].Content)
+	assert.Equal(t, "reminder", trimmed[2].Content)
+	assert.Equal(t, "second", trimmed[3].Content)
+}
+
+// conversation returns the messages that aren't system messages.
+func conversation(messages []chat.Message) []chat.Message {
+	var conv []chat.Message
+	for _, m := range messages {
+		if m.Role != chat.MessageRoleSystem {
+			conv = append(conv, m)
+		}
+	}
+	return conv
+}
+
+func stripCreatedAt(m chat.Message) chat.Message {
+	m.CreatedAt = ""
+	return m
+}
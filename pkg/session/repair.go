@@ -0,0 +1,155 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// interruptedToolResult is the content of the results synthesized for tool
+// calls that never got one.
+const interruptedToolResult = "The tool call was interrupted before it returned a result."
+
+// Repair fixes the inconsistencies an interrupted run, e.g. a crash or a
+// cancelled context in the middle of a turn, can leave at the end of the
+// session so that the transcript can be sent to a model again:
+//   - tool calls without a result get an error result saying the call was
+//     interrupted, right after the results that were recorded;
+//   - assistant messages without any content or tool call are dropped;
+//   - tool results that don't answer a call of the preceding assistant
+//     message are dropped.
+//
+// Only the messages after the last summary are repaired: older messages are
+// not sent to the model and summaries refer to messages by index. Handoffs
+// and checkpoints are moved along with the messages around them.
+//
+// Repair returns a description of each repair, or nil if the session was
+// consistent. The session is modified in memory only.
+func (s *Session) Repair() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	start := 0
+	for i := len(s.Messages) - 1; i >= 0; i-- {
+		if s.Messages[i].Summary != "" {
+			start = i + 1
+			break
+		}
+	}
+
+	var (
+		repairs   []string
+		out       = append([]Item(nil), s.Messages[:start]...)
+		positions = make([]int, len(s.Messages)+1)
+
+		// The tool calls of the last assistant message, and the ones that got a result.
+		pending   []tools.ToolCall
+		agentName string
+		answered  = make(map[string]bool)
+	)
+	for i := range start {
+		positions[i] = i
+	}
+
+	flushPending := func() {
+		for _, tc := range pending {
+			if tc.ID == "" || answered[tc.ID] {
+				continue
+			}
+			msg := &Message{
+				AgentName: agentName,
+				Message: chat.Message{
+					Role:       chat.MessageRoleTool,
+					ToolCallID: tc.ID,
+					Content:    interruptedToolResult,
+					IsError:    true,
+				},
+			}
+			msg.stamp()
+			out = append(out, NewMessageItem(msg))
+			repairs = append(repairs, fmt.Sprintf("added a result for the interrupted %s tool call %s", tc.Function.Name, tc.ID))
+		}
+		pending = nil
+		answered = make(map[string]bool)
+	}
+
+	for i := start; i < len(s.Messages); i++ {
+		item := s.Messages[i]
+
+		// Sub-sessions, e.g. from transfer_task, sit between a tool call
+		// and its result.
+		if !item.IsMessage() {
+			positions[i] = len(out)
+			out = append(out, item)
+			continue
+		}
+
+		msg := &item.Message.Message
+		switch {
+		case msg.Role == chat.MessageRoleTool:
+			positions[i] = len(out)
+			if !callsTool(pending, msg.ToolCallID) {
+				repairs = append(repairs, fmt.Sprintf("removed a result for the unknown tool call %q", msg.ToolCallID))
+				continue
+			}
+			answered[msg.ToolCallID] = true
+
+		case msg.Role == chat.MessageRoleAssistant && isEmptyAssistantMessage(msg):
+			positions[i] = len(out)
+			repairs = append(repairs, "removed an empty assistant message")
+			continue
+
+		case msg.Role == chat.MessageRoleAssistant || msg.Role == chat.MessageRoleUser:
+			flushPending()
+			positions[i] = len(out)
+			if msg.Role == chat.MessageRoleAssistant {
+				pending = msg.ToolCalls
+				agentName = item.Message.AgentName
+			}
+
+		default:
+			positions[i] = len(out)
+		}
+
+		out = append(out, item)
+	}
+	flushPending()
+	positions[len(s.Messages)] = len(out)
+
+	if len(repairs) == 0 {
+		return nil
+	}
+
+	for i := range s.HandoffHistory {
+		if idx := s.HandoffHistory[i].AtMessageIndex; idx >= 0 && idx < len(positions) {
+			s.HandoffHistory[i].AtMessageIndex = positions[idx]
+		}
+	}
+	for i := range s.Checkpoints {
+		if idx := s.Checkpoints[i].AtMessageIndex; idx >= 0 && idx < len(positions) {
+			s.Checkpoints[i].AtMessageIndex = positions[idx]
+		}
+	}
+	s.Messages = out
+
+	return repairs
+}
+
+func callsTool(calls []tools.ToolCall, id string) bool {
+	for _, tc := range calls {
+		if tc.ID != "" && tc.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmptyAssistantMessage(msg *chat.Message) bool {
+	return strings.TrimSpace(msg.Content) == "" &&
+		len(msg.MultiContent) == 0 &&
+		msg.ReasoningContent == "" &&
+		len(msg.ToolCalls) == 0 &&
+		msg.FunctionCall == nil
+}
@@ -0,0 +1,163 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+func toolCallMessage(ids ...string) *Message {
+	var calls []tools.ToolCall
+	for _, id := range ids {
+		calls = append(calls, tools.ToolCall{ID: id, Type: "function", Function: tools.FunctionCall{Name: "shell"}})
+	}
+	return NewAgentMessage("root", &chat.Message{Role: chat.MessageRoleAssistant, ToolCalls: calls})
+}
+
+func toolResultMessage(id string) *Message {
+	return NewAgentMessage("root", &chat.Message{Role: chat.MessageRoleTool, ToolCallID: id, Content: "ok"})
+}
+
+// newRepairSession returns a session with a user message followed by items.
+func newRepairSession(items ...Item) *Session {
+	return New(WithMessages(append([]Item{NewMessageItem(UserMessage("hello"))}, items...)))
+}
+
+func assistantMessage(content string) *Message {
+	return NewAgentMessage("root", &chat.Message{Role: chat.MessageRoleAssistant, Content: content})
+}
+
+// transcript returns "role[:tool call id]" for each item of the session.
+func transcript(s *Session) []string {
+	var out []string
+	for _, item := range s.Messages {
+		switch {
+		case item.Summary != "":
+			out = append(out, "summary")
+		case item.SubSession != nil:
+			out = append(out, "sub-session")
+		case item.Message.Message.ToolCallID != "":
+			out = append(out, string(item.Message.Message.Role)+":"+item.Message.Message.ToolCallID)
+		default:
+			out = append(out, string(item.Message.Message.Role))
+		}
+	}
+	return out
+}
+
+func TestRepair_ConsistentSession(t *testing.T) {
+	s := newRepairSession(
+		NewMessageItem(toolCallMessage("call_1")),
+		NewMessageItem(toolResultMessage("call_1")),
+		NewMessageItem(assistantMessage("done")),
+	)
+
+	assert.Nil(t, s.Repair())
+	assert.Equal(t, []string{"user", "assistant", "tool:call_1", "assistant"}, transcript(s))
+}
+
+func TestRepair_DanglingToolCalls(t *testing.T) {
+	s := newRepairSession(
+		NewMessageItem(toolCallMessage("call_1", "call_2", "call_3")),
+		NewMessageItem(toolResultMessage("call_1")),
+	)
+	// The run is resumed with a new message.
+	s.AddMessage(UserMessage("go on"))
+
+	repairs := s.Repair()
+	require.Len(t, repairs, 2)
+	assert.Contains(t, repairs[0], "call_2")
+	assert.Contains(t, repairs[1], "call_3")
+
+	assert.Equal(t, []string{"user", "assistant", "tool:call_1", "tool:call_2", "tool:call_3", "user"}, transcript(s))
+	synthesized := s.Messages[3].Message
+	assert.Equal(t, "root", synthesized.AgentName)
+	assert.True(t, synthesized.Message.IsError)
+	assert.Equal(t, interruptedToolResult, synthesized.Message.Content)
+
+	// Repairing is idempotent.
+	assert.Nil(t, s.Repair())
+}
+
+func TestRepair_DanglingToolCallsAtTheEnd(t *testing.T) {
+	s := newRepairSession(NewMessageItem(toolCallMessage("call_1")))
+
+	require.Len(t, s.Repair(), 1)
+	assert.Equal(t, []string{"user", "assistant", "tool:call_1"}, transcript(s))
+}
+
+func TestRepair_EmptyAssistantMessages(t *testing.T) {
+	s := newRepairSession(
+		NewMessageItem(assistantMessage(" \n")),
+		NewMessageItem(toolCallMessage("call_1")),
+		NewMessageItem(toolResultMessage("call_1")),
+		NewMessageItem(assistantMessage("")),
+	)
+
+	assert.Equal(t, []string{"removed an empty assistant message", "removed an empty assistant message"}, s.Repair())
+	assert.Equal(t, []string{"user", "assistant", "tool:call_1"}, transcript(s))
+}
+
+func TestRepair_KeepsReasoningOnlyAssistantMessages(t *testing.T) {
+	s := newRepairSession(NewMessageItem(NewAgentMessage("root", &chat.Message{
+		Role:             chat.MessageRoleAssistant,
+		ReasoningContent: "thinking",
+	})))
+
+	assert.Nil(t, s.Repair())
+	assert.Len(t, s.Messages, 2)
+}
+
+func TestRepair_OrphanToolResults(t *testing.T) {
+	s := newRepairSession(
+		NewMessageItem(toolCallMessage("call_1")),
+		NewMessageItem(toolResultMessage("call_1")),
+		NewMessageItem(assistantMessage("done")),
+		NewMessageItem(toolResultMessage("call_2")),
+	)
+
+	repairs := s.Repair()
+	require.Len(t, repairs, 1)
+	assert.Contains(t, repairs[0], "call_2")
+	assert.Equal(t, []string{"user", "assistant", "tool:call_1", "assistant"}, transcript(s))
+}
+
+func TestRepair_ToolResultsAfterSubSession(t *testing.T) {
+	s := newRepairSession(
+		NewMessageItem(toolCallMessage("call_1")),
+		NewSubSessionItem(New(WithUserMessage("task"))),
+		NewMessageItem(toolResultMessage("call_1")),
+	)
+
+	assert.Nil(t, s.Repair())
+	assert.Equal(t, []string{"user", "assistant", "sub-session", "tool:call_1"}, transcript(s))
+}
+
+func TestRepair_OnlyAfterLastSummary(t *testing.T) {
+	s := newRepairSession(
+		NewMessageItem(toolCallMessage("call_1")),
+		Item{Summary: "summary", FirstKeptEntry: -1},
+		NewMessageItem(assistantMessage("")),
+	)
+
+	require.Len(t, s.Repair(), 1)
+	assert.Equal(t, []string{"user", "assistant", "summary"}, transcript(s))
+}
+
+func TestRepair_MovesHandoffs(t *testing.T) {
+	s := newRepairSession(
+		NewMessageItem(toolCallMessage("call_1", "call_2")),
+		NewMessageItem(toolResultMessage("call_1")),
+	)
+	// The agent handed off after the tool calls, before the next message.
+	s.AddHandoff("root", "other")
+	s.AddMessage(NewAgentMessage("other", &chat.Message{Role: chat.MessageRoleAssistant, Content: "hi"}))
+
+	require.Len(t, s.Repair(), 1)
+	assert.Equal(t, []string{"user", "assistant", "tool:call_1", "tool:call_2", "assistant"}, transcript(s))
+	assert.Equal(t, 4, s.Handoffs()[0].AtMessageIndex)
+}
@@ -14,6 +14,8 @@ import (
 
 	"github.com/docker/docker-agent/pkg/agent"
 	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/compaction"
+	"github.com/docker/docker-agent/pkg/model/provider/options"
 	"github.com/docker/docker-agent/pkg/tools"
 )
 
@@ -45,6 +47,10 @@ type Item struct {
 	// with no summary) means no messages were kept.
 	FirstKeptEntry int `json:"first_kept_entry,omitempty"`
 
+	// SummaryInfo describes the summary, for summary items. It's nil for
+	// the summaries created before it was recorded.
+	SummaryInfo *SummaryInfo `json:"summary_info,omitempty"`
+
 	// Cost tracks the cost of operations associated with this item that
 	// don't produce a regular message (e.g., compaction/summarization).
 	Cost float64 `json:"cost,omitempty"`
@@ -137,15 +143,29 @@ type Session struct {
 	// These are shown in the model picker for easy re-selection.
 	CustomModelsUsed []string `json:"custom_models_used,omitempty"`
 
+	// HandoffHistory records, in order, the handoffs between agents made
+	// during this session. Use Handoffs and AddHandoff to access it.
+	HandoffHistory []Handoff `json:"handoff_history,omitempty"`
+
+	// FileChangeLog records the files changed by the tools during this
+	// session, in the order they were first changed. Use FileChanges and
+	// AddFileChange to access it.
+	FileChangeLog []tools.FileChange `json:"file_changes,omitempty"`
+
+	// Checkpoints holds the points of the session the conversation can be
+	// rolled back to, oldest first. Use Checkpoint, ListCheckpoints and
+	// Rollback to access it.
+	Checkpoints []Checkpoint `json:"checkpoints,omitempty"`
+
 	// ExcludedTools lists tool names that should be filtered out of the agent's
 	// tool list for this session. This is used by skill sub-sessions to prevent
 	// recursive run_skill calls.
 	ExcludedTools []string `json:"-"`
 
 	// AgentName, when set, tells RunStream which agent to use for this session
-	// instead of reading from the shared runtime currentAgent field. This is
-	// required for background agent tasks where multiple sessions may run
-	// concurrently on different agents.
+	// instead of the agent the run it's part of is at. This is required for
+	// background agent tasks where multiple sessions may run concurrently on
+	// different agents.
 	AgentName string `json:"-"`
 
 	// ParentID indicates this is a sub-session created by task transfer.
@@ -153,10 +173,27 @@ type Session struct {
 	// within the parent session's Messages array.
 	ParentID string `json:"-"`
 
+	// ToolCallID is the ID of the tool call of the parent session that
+	// spawned this sub-session, e.g. a transfer_task call.
+	ToolCallID string `json:"-"`
+
+	// ModelOptions holds model options applied on top of the agents' models
+	// for this session only, by agent name. See WithModelOverrides.
+	// Sub-sessions inherit them. Not persisted.
+	ModelOptions map[string][]options.Opt `json:"-"`
+
 	// MessageUsageHistory stores per-message usage data for remote mode.
 	// In remote mode, messages are managed server-side, so we track usage separately.
 	// This is not persisted (json:"-") as it's only needed for the current session display.
 	MessageUsageHistory []MessageUsageRecord `json:"-"`
+
+	// trimmedMessages is the number of conversation messages the last call
+	// to GetMessages left out to fit its token budget.
+	trimmedMessages int
+
+	// fileSnapshots holds the content of the files before the tools first
+	// changed them, by path. See SnapshotFile. Not persisted.
+	fileSnapshots map[string]*fileSnapshot
 }
 
 // MessageUsageRecord stores usage data for a single assistant message.
@@ -168,6 +205,15 @@ type MessageUsageRecord struct {
 	Usage     chat.Usage `json:"usage"`
 }
 
+// Handoff records an agent handing the conversation off to another agent.
+type Handoff struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// AtMessageIndex is the number of items in the session when the
+	// handoff was made.
+	AtMessageIndex int `json:"at_message_index"`
+}
+
 // PermissionsConfig defines session-level tool permission overrides
 // using pattern-based rules (Allow/Ask/Deny arrays).
 type PermissionsConfig struct {
@@ -183,8 +229,13 @@ type PermissionsConfig struct {
 // Message is a message from an agent
 type Message struct {
 	// ID is the database ID of the message (used for persistence tracking)
-	ID        int64        `json:"-"`
-	AgentName string       `json:"agentName"` // TODO: rename to agent_name
+	ID        int64  `json:"-"`
+	AgentName string `json:"agentName"` // TODO: rename to agent_name
+	// ModelID is the "provider/model" ID of the model that produced the
+	// message. Only set for assistant messages.
+	ModelID string `json:"model_id,omitempty"`
+	// CreatedAt is when the message was added to the session.
+	CreatedAt time.Time    `json:"created_at,omitzero"`
 	Message   chat.Message `json:"message"`
 	// Implicit is an optional field to indicate if the message shouldn't be shown to the user. It's needed for special  situations
 	// like when an agent transfers a task to another agent - new session is created with a default user message, but this shouldn't be shown to the user.
@@ -192,6 +243,43 @@ type Message struct {
 	Implicit bool `json:"implicit,omitempty"`
 }
 
+// UnmarshalJSON decodes a message, filling the metadata of messages persisted
+// before it was recorded from their chat message.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	type message Message
+	if err := json.Unmarshal(data, (*message)(m)); err != nil {
+		return err
+	}
+	m.fillMetadata()
+	return nil
+}
+
+// fillMetadata fills the metadata that isn't set from the chat message:
+// CreatedAt from its RFC 3339 created_at and ModelID from its model.
+func (m *Message) fillMetadata() {
+	if m.CreatedAt.IsZero() && m.Message.CreatedAt != "" {
+		if createdAt, err := time.Parse(time.RFC3339, m.Message.CreatedAt); err == nil {
+			m.CreatedAt = createdAt
+		}
+	}
+	if m.ModelID == "" {
+		m.ModelID = m.Message.Model
+	}
+}
+
+// stamp sets the metadata of a message added to a session: its creation
+// time, now unless it's already known, mirrored in the chat message for the
+// code that reads it there, and its model.
+func (m *Message) stamp() {
+	m.fillMetadata()
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now()
+	}
+	if m.Message.CreatedAt == "" {
+		m.Message.CreatedAt = m.CreatedAt.Format(time.RFC3339)
+	}
+}
+
 func ImplicitUserMessage(content string) *Message {
 	msg := UserMessage(content)
 	msg.Implicit = true
@@ -204,7 +292,6 @@ func UserMessage(content string, multiContent ...chat.MessagePart) *Message {
 			Role:         chat.MessageRoleUser,
 			Content:      content,
 			MultiContent: multiContent,
-			CreatedAt:    time.Now().Format(time.RFC3339),
 		},
 	}
 }
@@ -219,9 +306,8 @@ func NewAgentMessage(agentName string, message *chat.Message) *Message {
 func SystemMessage(content string) *Message {
 	return &Message{
 		Message: chat.Message{
-			Role:      chat.MessageRoleSystem,
-			Content:   content,
-			CreatedAt: time.Now().Format(time.RFC3339),
+			Role:    chat.MessageRoleSystem,
+			Content: content,
 		},
 	}
 }
@@ -358,18 +444,53 @@ func deepCopyChatMessage(m chat.Message) chat.Message {
 
 // Session helper methods
 
-// AddMessage adds a message to the session
+// AddMessage adds a message to the session. It sets the message's
+// CreatedAt, unless it's already set, and its ModelID.
 func (s *Session) AddMessage(msg *Message) {
+	msg.stamp()
 	s.mu.Lock()
 	s.Messages = append(s.Messages, NewMessageItem(msg))
 	s.mu.Unlock()
 }
 
+// AddHandoff records a handoff from one agent to another at the current
+// position in the session.
+func (s *Session) AddHandoff(from, to string) Handoff {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	handoff := Handoff{From: from, To: to, AtMessageIndex: len(s.Messages)}
+	s.HandoffHistory = append(s.HandoffHistory, handoff)
+	return handoff
+}
+
+// Handoffs returns the handoffs made during the session, oldest first.
+func (s *Session) Handoffs() []Handoff {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return slices.Clone(s.HandoffHistory)
+}
+
 // AddSubSession adds a sub-session to the session
 func (s *Session) AddSubSession(subSession *Session) {
 	s.mu.Lock()
 	s.Messages = append(s.Messages, NewSubSessionItem(subSession))
 	s.mu.Unlock()
+
+	s.mergeFileChanges(subSession)
+}
+
+// AddCost records the cost of an operation that doesn't produce a message,
+// e.g. an MCP server sampling the model, so that it counts towards the cost
+// of the session.
+func (s *Session) AddCost(cost float64) {
+	if cost <= 0 {
+		return
+	}
+	s.mu.Lock()
+	s.Messages = append(s.Messages, Item{Cost: cost})
+	s.mu.Unlock()
 }
 
 // Duration calculates the duration of the session from message timestamps.
@@ -379,17 +500,15 @@ func (s *Session) Duration() time.Duration {
 		return 0
 	}
 
-	first, err := time.Parse(time.RFC3339, messages[0].Message.CreatedAt)
-	if err != nil {
+	// Messages built without AddMessage may only have the chat timestamp.
+	first, last := messages[0], messages[len(messages)-1]
+	first.fillMetadata()
+	last.fillMetadata()
+	if first.CreatedAt.IsZero() || last.CreatedAt.IsZero() {
 		return 0
 	}
 
-	last, err := time.Parse(time.RFC3339, messages[len(messages)-1].Message.CreatedAt)
-	if err != nil {
-		return 0
-	}
-
-	return last.Sub(first)
+	return last.CreatedAt.Sub(first.CreatedAt)
 }
 
 // AllowedDirectories returns the directories that should be considered safe for tools
@@ -435,7 +554,7 @@ func (s *Session) GetLastUserMessageContent() string {
 }
 
 // GetLastUserMessages returns up to n most recent user messages, ordered from oldest to newest.
-// Returns nil if n <= 0.
+// System reminders aren't user messages. Returns nil if n <= 0.
 func (s *Session) GetLastUserMessages(n int) []string {
 	if n <= 0 {
 		return nil
@@ -443,7 +562,7 @@ func (s *Session) GetLastUserMessages(n int) []string {
 	messages := s.GetAllMessages()
 	var userMessages []string
 	for i := range messages {
-		if messages[i].Message.Role == chat.MessageRoleUser {
+		if messages[i].Message.Role == chat.MessageRoleUser && !messages[i].IsSystemReminder() {
 			content := strings.TrimSpace(messages[i].Message.Content)
 			if content != "" {
 				userMessages = append(userMessages, content)
@@ -456,10 +575,34 @@ func (s *Session) GetLastUserMessages(n int) []string {
 	return userMessages[len(userMessages)-n:]
 }
 
+// MessagesByAgent returns the messages produced by the named agent,
+// including in sub-sessions.
+func (s *Session) MessagesByAgent(agentName string) []Message {
+	var messages []Message
+	for _, msg := range s.GetAllMessages() {
+		if msg.AgentName == agentName {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
+
+// LastMessageFrom returns the last message produced by the named agent,
+// including in sub-sessions, or nil if it produced none.
+func (s *Session) LastMessageFrom(agentName string) *Message {
+	messages := s.GetAllMessages()
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].AgentName == agentName {
+			return &messages[i]
+		}
+	}
+	return nil
+}
+
 func (s *Session) getLastMessageContentByRole(role chat.MessageRole) string {
 	messages := s.GetAllMessages()
 	for i := len(messages) - 1; i >= 0; i-- {
-		if messages[i].Message.Role == role {
+		if messages[i].Message.Role == role && !messages[i].IsSystemReminder() {
 			return strings.TrimSpace(messages[i].Message.Content)
 		}
 	}
@@ -593,6 +736,14 @@ func WithParentID(parentID string) Opt {
 	}
 }
 
+// WithToolCallID records the ID of the tool call of the parent session that
+// spawned this sub-session.
+func WithToolCallID(toolCallID string) Opt {
+	return func(s *Session) {
+		s.ToolCallID = toolCallID
+	}
+}
+
 // WithID sets the session ID. If not set, a UUID will be generated.
 func WithID(id string) Opt {
 	return func(s *Session) {
@@ -609,6 +760,18 @@ func WithExcludedTools(names []string) Opt {
 	}
 }
 
+// WithModelOverrides applies model options, e.g. options.WithTemperature, to
+// the model of the named agent for the requests made in this session. They
+// take precedence over the runtime's overrides for the same agent.
+func WithModelOverrides(agentName string, opts ...options.Opt) Opt {
+	return func(s *Session) {
+		if s.ModelOptions == nil {
+			s.ModelOptions = make(map[string][]options.Opt)
+		}
+		s.ModelOptions[agentName] = append(s.ModelOptions[agentName], opts...)
+	}
+}
+
 // IsSubSession returns true if this session is a sub-session (has a parent).
 func (s *Session) IsSubSession() bool {
 	return s.ParentID != ""
@@ -666,6 +829,105 @@ func (s *Session) OwnCost() float64 {
 	return cost
 }
 
+// CompactionCost returns the cost of the compactions of this session: its
+// item-level costs, which OwnCost includes. Like OwnCost, it excludes
+// sub-sessions.
+func (s *Session) CompactionCost() float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var cost float64
+	for _, item := range s.Messages {
+		cost += item.Cost
+	}
+	return cost
+}
+
+// UsageTotals sums the token usage and the cost of assistant turns.
+type UsageTotals struct {
+	chat.Usage
+
+	Cost  float64 `json:"cost"`
+	Turns int     `json:"turns"`
+}
+
+func (u *UsageTotals) add(cost float64, usage *chat.Usage) {
+	u.InputTokens += usage.InputTokens
+	u.OutputTokens += usage.OutputTokens
+	u.CachedInputTokens += usage.CachedInputTokens
+	u.CacheWriteTokens += usage.CacheWriteTokens
+	u.ReasoningTokens += usage.ReasoningTokens
+	u.Cost += cost
+	u.Turns++
+}
+
+// OwnUsage returns the usage of this session's own assistant turns. Like
+// OwnCost, it excludes sub-sessions, which report their own usage.
+func (s *Session) OwnUsage() UsageTotals {
+	var total UsageTotals
+	s.eachTurn(false, func(_ string, cost float64, usage *chat.Usage) {
+		total.add(cost, usage)
+	})
+	return total
+}
+
+// TotalUsage returns the usage of the assistant turns of the session and its
+// sub-sessions.
+func (s *Session) TotalUsage() UsageTotals {
+	var total UsageTotals
+	s.eachTurn(true, func(_ string, cost float64, usage *chat.Usage) {
+		total.add(cost, usage)
+	})
+	return total
+}
+
+// UsageByAgent returns the usage of the assistant turns of the session and
+// its sub-sessions, keyed by the name of the agent that answered them. Each
+// turn keeps the cost computed with the pricing of the model that answered it.
+func (s *Session) UsageByAgent() map[string]UsageTotals {
+	byAgent := make(map[string]UsageTotals)
+	s.eachTurn(true, func(agentName string, cost float64, usage *chat.Usage) {
+		u := byAgent[agentName]
+		u.add(cost, usage)
+		byAgent[agentName] = u
+	})
+	return byAgent
+}
+
+// eachTurn calls fn for every assistant message that reported its usage,
+// including the ones of sub-sessions when recurse is set. Client-side
+// sessions of a remote runtime don't hold the messages; their turns are read
+// from MessageUsageHistory instead.
+func (s *Session) eachTurn(recurse bool, fn func(agentName string, cost float64, usage *chat.Usage)) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	found := false
+	for _, item := range s.Messages {
+		switch {
+		case item.IsMessage():
+			msg := &item.Message.Message
+			if msg.Role == chat.MessageRoleAssistant && msg.Usage != nil {
+				fn(item.Message.AgentName, msg.Cost, msg.Usage)
+				found = true
+			}
+		case item.IsSubSession() && recurse:
+			if item.SubSession.eachTurn(true, fn) {
+				found = true
+			}
+		}
+	}
+
+	if !found {
+		for i := range s.MessageUsageHistory {
+			record := &s.MessageUsageHistory[i]
+			fn(record.AgentName, record.Cost, &record.Usage)
+			found = true
+		}
+	}
+	return found
+}
+
 // New creates a new agent session
 func New(opts ...Opt) *Session {
 	s := &Session{
@@ -697,7 +959,7 @@ func markLastMessageAsCacheControl(messages []chat.Message) {
 func buildInvariantSystemMessages(a *agent.Agent) []chat.Message {
 	var messages []chat.Message
 
-	if a.HasSubAgents() {
+	if a.HasSubAgents() && a.BuiltinToolEnabled("transfer_task") {
 		subAgents := a.SubAgents()
 
 		var text strings.Builder
@@ -718,7 +980,7 @@ func buildInvariantSystemMessages(a *agent.Agent) []chat.Message {
 		})
 	}
 
-	if handoffs := a.Handoffs(); len(handoffs) > 0 {
+	if handoffs := a.Handoffs(); len(handoffs) > 0 && a.BuiltinToolEnabled("handoff") {
 		var text strings.Builder
 		var validAgentIDs []string
 		for _, agent := range handoffs {
@@ -819,7 +1081,29 @@ func buildContextSpecificSystemMessages(a *agent.Agent, s *Session) []chat.Messa
 	return messages
 }
 
-// buildSessionSummaryMessages builds system messages containing the session summary
+// buildTurnInstructionMessages builds the system message holding the
+// sections added to the instructions of the current turn, each between
+// <turn_context> tags so that the model can tell them apart.
+func buildTurnInstructionMessages(sections []string) []chat.Message {
+	if len(sections) == 0 {
+		return nil
+	}
+
+	var text strings.Builder
+	text.WriteString("The following sections describe the current state of your tools. They're refreshed before each of your turns, so they're more recent than the results of earlier tool calls.")
+	for _, section := range sections {
+		text.WriteString("\n\n<turn_context>\n")
+		text.WriteString(section)
+		text.WriteString("\n</turn_context>")
+	}
+
+	return []chat.Message{{
+		Role:    chat.MessageRoleSystem,
+		Content: text.String(),
+	}}
+}
+
+// buildSessionSummaryMessages builds a system reminder containing the session summary
 // if one exists. Session summaries are context-specific per session and thus should not have a checkpoint (they will be cached alongside the first user message anyway)
 //
 // startIndex is the index in items from which conversation messages should be
@@ -829,9 +1113,22 @@ func buildContextSpecificSystemMessages(a *agent.Agent, s *Session) []chat.Messa
 // 0 when there is no summary.
 func buildSessionSummaryMessages(items []Item) ([]chat.Message, int) {
 	var messages []chat.Message
-	// Find the last summary index to determine where conversation messages start
-	// and to include the summary in session summary messages
-	lastSummaryIndex := -1
+	lastSummaryIndex, startIndex := conversationStart(items)
+	if lastSummaryIndex >= 0 {
+		summary := chat.SystemReminderMessage("Session Summary: " + items[lastSummaryIndex].Summary)
+		summary.CreatedAt = time.Now().Format(time.RFC3339)
+		messages = append(messages, summary)
+	}
+
+	return messages, startIndex
+}
+
+// conversationStart returns the index of the last summary of items, -1
+// without summary, and the index of the first item sent to the model after
+// it: the first message kept verbatim during the compaction, if any, or
+// the item following the summary.
+func conversationStart(items []Item) (lastSummaryIndex, startIndex int) {
+	lastSummaryIndex = -1
 	for i := len(items) - 1; i >= 0; i-- {
 		if items[i].Summary != "" {
 			lastSummaryIndex = i
@@ -839,18 +1136,7 @@ func buildSessionSummaryMessages(items []Item) ([]chat.Message, int) {
 		}
 	}
 
-	if lastSummaryIndex >= 0 && lastSummaryIndex < len(items) {
-		messages = append(messages, chat.Message{
-			Role:      chat.MessageRoleUser,
-			Content:   "Session Summary: " + items[lastSummaryIndex].Summary,
-			CreatedAt: time.Now().Format(time.RFC3339),
-		})
-	}
-
-	// Determine where conversation messages should start.
-	// If the summary has a FirstKeptEntry, we start from there so that
-	// messages kept during compaction are included after the summary.
-	startIndex := lastSummaryIndex + 1
+	startIndex = lastSummaryIndex + 1
 	if lastSummaryIndex >= 0 {
 		kept := items[lastSummaryIndex].FirstKeptEntry
 		if kept > 0 && kept < lastSummaryIndex {
@@ -858,12 +1144,19 @@ func buildSessionSummaryMessages(items []Item) ([]chat.Message, int) {
 		}
 	}
 
-	return messages, startIndex
+	return lastSummaryIndex, startIndex
 }
 
-func (s *Session) GetMessages(a *agent.Agent) []chat.Message {
+// GetMessages returns the messages to send to the model on behalf of a: the
+// system messages of the agent followed by the conversation.
+func (s *Session) GetMessages(a *agent.Agent, opts ...MessagesOpt) []chat.Message {
 	slog.Debug("Getting messages for agent", "agent", a.Name(), "session_id", s.ID)
 
+	options := messagesOptions{estimate: compaction.EstimateMessageTokens}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	// Build invariant system messages (cacheable across sessions/users/projects)
 	invariantMessages := buildInvariantSystemMessages(a)
 	markLastMessageAsCacheControl(invariantMessages)
@@ -872,6 +1165,10 @@ func (s *Session) GetMessages(a *agent.Agent) []chat.Message {
 	contextMessages := buildContextSpecificSystemMessages(a, s)
 	markLastMessageAsCacheControl(contextMessages)
 
+	// Build turn-specific system messages, after the cache checkpoints since
+	// they may change with every turn.
+	turnMessages := buildTurnInstructionMessages(options.turnInstructions)
+
 	// Take a snapshot of Messages under the lock, copying Message structs
 	// to avoid racing with UpdateMessage which may modify the pointed-to objects.
 	s.mu.RLock()
@@ -891,16 +1188,19 @@ func (s *Session) GetMessages(a *agent.Agent) []chat.Message {
 	var messages []chat.Message
 	messages = append(messages, invariantMessages...)
 	messages = append(messages, contextMessages...)
+	messages = append(messages, turnMessages...)
 	messages = append(messages, summaryMessages...)
 
 	// Begin adding conversation messages
 	for i := startIndex; i < len(items); i++ {
 		item := items[i]
-		if item.IsMessage() {
+		if item.IsMessage() && (!options.withoutReminders || !item.Message.IsSystemReminder()) {
 			messages = append(messages, item.Message.Message)
 		}
 	}
 
+	messages = pruneToolResults(messages, options.pruning)
+
 	maxItems := a.NumHistoryItems()
 	if maxItems > 0 {
 		messages = trimMessages(messages, maxItems)
@@ -918,6 +1218,12 @@ func (s *Session) GetMessages(a *agent.Agent) []chat.Message {
 
 	messages = sanitizeToolCalls(messages)
 
+	var trimmed int
+	if options.tokenBudget > 0 {
+		messages, trimmed = packMessages(messages, options.tokenBudget, options.estimate)
+	}
+	s.setTrimmedMessages(trimmed)
+
 	systemCount := 0
 	conversationCount := 0
 	for i := range messages {
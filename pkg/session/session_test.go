@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -219,6 +220,29 @@ func TestGetMessages_CacheControlWithSummary(t *testing.T) {
 	assert.Contains(t, messages[checkpointIndices[1]].Content, "Today's date", "checkpoint #2 should be on date message")
 }
 
+func TestGetMessages_TurnInstructions(t *testing.T) {
+	testAgent := agent.New("root", "instructions", agent.WithAddDate(true))
+
+	s := New()
+	s.AddMessage(UserMessage("hello"))
+	messages := s.GetMessages(testAgent, WithTurnInstructions([]string{"## Plan\n- step 1", "Branch: main"}))
+
+	require.Len(t, messages, 4)
+	assert.Equal(t, "instructions", messages[0].Content)
+	assert.Contains(t, messages[1].Content, "Today's date")
+	assert.True(t, messages[1].CacheControl, "the turn instructions come after the last checkpoint")
+
+	turn := messages[2]
+	assert.Equal(t, chat.MessageRoleSystem, turn.Role)
+	assert.False(t, turn.CacheControl)
+	assert.Contains(t, turn.Content, "<turn_context>\n## Plan\n- step 1\n</turn_context>\n\n<turn_context>\nBranch: main\n</turn_context>")
+
+	assert.Equal(t, "hello", messages[3].Content)
+
+	// Without sections, there's no message.
+	assert.Len(t, s.GetMessages(testAgent, WithTurnInstructions(nil)), 3)
+}
+
 func TestGetLastUserMessages(t *testing.T) {
 	t.Parallel()
 
@@ -574,3 +598,159 @@ func TestTransferTaskPromptExcludesParents(t *testing.T) {
 	assert.Contains(t, subAgentMsg, "librarian", "should list librarian as a valid sub-agent")
 	assert.NotContains(t, subAgentMsg, "planner", "should NOT list parent agent planner as a valid transfer target")
 }
+
+func TestUsageByAgent(t *testing.T) {
+	t.Parallel()
+
+	turn := func(agentName, model string, input, output, cached int64, cost float64) *Message {
+		return NewAgentMessage(agentName, &chat.Message{
+			Role:  chat.MessageRoleAssistant,
+			Model: model,
+			Cost:  cost,
+			Usage: &chat.Usage{InputTokens: input, OutputTokens: output, CachedInputTokens: cached},
+		})
+	}
+
+	sub := New()
+	sub.AddMessage(turn("researcher", "openai/gpt-4o-mini", 50, 10, 0, 0.0001))
+	sub.AddMessage(turn("researcher", "openai/gpt-4o", 70, 20, 30, 0.002))
+
+	sess := New(WithUserMessage("hello"))
+	sess.AddMessage(turn("root", "anthropic/claude-sonnet-4-0", 100, 20, 0, 0.01))
+	sess.AddSubSession(sub)
+	sess.AddMessage(turn("root", "anthropic/claude-sonnet-4-0", 200, 40, 100, 0.02))
+
+	byAgent := sess.UsageByAgent()
+	require.Len(t, byAgent, 2)
+	assert.Equal(t, UsageTotals{
+		Usage: chat.Usage{InputTokens: 300, OutputTokens: 60, CachedInputTokens: 100},
+		Cost:  0.03,
+		Turns: 2,
+	}, byAgent["root"])
+	assert.Equal(t, UsageTotals{
+		Usage: chat.Usage{InputTokens: 120, OutputTokens: 30, CachedInputTokens: 30},
+		Cost:  0.0021,
+		Turns: 2,
+	}, byAgent["researcher"])
+
+	assert.Equal(t, 2, sess.OwnUsage().Turns)
+	assert.InDelta(t, 0.03, sess.OwnUsage().Cost, 1e-9)
+	assert.Equal(t, 4, sess.TotalUsage().Turns)
+	assert.InDelta(t, 0.0321, sess.TotalUsage().Cost, 1e-9)
+}
+
+func TestUsageByAgent_RemoteHistory(t *testing.T) {
+	t.Parallel()
+
+	sess := New()
+	sess.AddMessageUsageRecord("root", "openai/gpt-4o", 0.01, &chat.Usage{InputTokens: 100, OutputTokens: 10})
+	sess.AddMessageUsageRecord("helper", "openai/gpt-4o-mini", 0.001, &chat.Usage{InputTokens: 40, OutputTokens: 5})
+
+	assert.Equal(t, map[string]UsageTotals{
+		"root":   {Usage: chat.Usage{InputTokens: 100, OutputTokens: 10}, Cost: 0.01, Turns: 1},
+		"helper": {Usage: chat.Usage{InputTokens: 40, OutputTokens: 5}, Cost: 0.001, Turns: 1},
+	}, sess.UsageByAgent())
+}
+
+func TestAddMessageSetsMetadata(t *testing.T) {
+	t.Parallel()
+
+	sess := New()
+	before := time.Now()
+	sess.AddMessage(UserMessage("Hello"))
+	sess.AddMessage(NewAgentMessage("root", &chat.Message{
+		Role:    chat.MessageRoleAssistant,
+		Content: "Hi",
+		Model:   "openai/gpt-4o",
+	}))
+	sess.AddMessage(NewAgentMessage("root", &chat.Message{
+		Role:      chat.MessageRoleAssistant,
+		Content:   "Recorded earlier",
+		CreatedAt: "2025-06-01T10:00:00Z",
+	}))
+
+	messages := sess.GetAllMessages()
+	require.Len(t, messages, 3)
+
+	assert.False(t, messages[0].CreatedAt.Before(before.Truncate(time.Second)))
+	assert.Equal(t, messages[0].CreatedAt.Format(time.RFC3339), messages[0].Message.CreatedAt)
+	assert.Empty(t, messages[0].ModelID)
+
+	assert.Equal(t, "root", messages[1].AgentName)
+	assert.Equal(t, "openai/gpt-4o", messages[1].ModelID)
+
+	assert.Equal(t, time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC), messages[2].CreatedAt.UTC())
+}
+
+func TestMessageJSON(t *testing.T) {
+	t.Parallel()
+
+	createdAt := time.Date(2025, 6, 1, 10, 0, 0, 123, time.UTC)
+	msg := Message{
+		AgentName: "root",
+		ModelID:   "openai/gpt-4o",
+		CreatedAt: createdAt,
+		Message:   chat.Message{Role: chat.MessageRoleAssistant, Content: "Hi", Model: "openai/gpt-4o"},
+	}
+
+	data, err := json.Marshal(msg)
+	require.NoError(t, err)
+
+	var decoded Message
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, msg, decoded)
+
+	// Messages persisted before the metadata was recorded only have the
+	// timestamp and the model of their chat message.
+	var legacy Message
+	require.NoError(t, json.Unmarshal([]byte(`{"agentName":"root","message":{"role":"assistant","content":"Hi","created_at":"2025-06-01T10:00:00Z","model":"openai/gpt-4o"}}`), &legacy))
+	assert.Equal(t, time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC), legacy.CreatedAt.UTC())
+	assert.Equal(t, "openai/gpt-4o", legacy.ModelID)
+}
+
+func TestMessagesByAgent(t *testing.T) {
+	t.Parallel()
+
+	sub := New()
+	sub.AddMessage(NewAgentMessage("researcher", &chat.Message{Role: chat.MessageRoleAssistant, Content: "Found it"}))
+
+	sess := New()
+	sess.AddMessage(UserMessage("Find it"))
+	sess.AddMessage(NewAgentMessage("root", &chat.Message{Role: chat.MessageRoleAssistant, Content: "Asking the researcher"}))
+	sess.AddSubSession(sub)
+	sess.AddMessage(NewAgentMessage("root", &chat.Message{Role: chat.MessageRoleAssistant, Content: "It's found"}))
+
+	root := sess.MessagesByAgent("root")
+	require.Len(t, root, 2)
+	assert.Equal(t, "Asking the researcher", root[0].Message.Content)
+	assert.Len(t, sess.MessagesByAgent("researcher"), 1)
+	assert.Empty(t, sess.MessagesByAgent("other"))
+
+	last := sess.LastMessageFrom("root")
+	require.NotNil(t, last)
+	assert.Equal(t, "It's found", last.Message.Content)
+	assert.Equal(t, "Found it", sess.LastMessageFrom("researcher").Message.Content)
+	assert.Nil(t, sess.LastMessageFrom("other"))
+}
+
+func TestBuildInvariantSystemMessages_DisabledBuiltinTools(t *testing.T) {
+	t.Parallel()
+
+	helper := agent.New("helper", "", agent.WithDescription("Helps"))
+	hasPrompt := func(a *agent.Agent, function string) bool {
+		for _, msg := range buildInvariantSystemMessages(a) {
+			if strings.Contains(msg.Content, "`"+function+"` function") {
+				return true
+			}
+		}
+		return false
+	}
+
+	root := agent.New("root", "", agent.WithHandoffs(helper), agent.WithSubAgents(helper))
+	assert.True(t, hasPrompt(root, "handoff"))
+	assert.True(t, hasPrompt(root, "transfer_task"))
+
+	root = agent.New("root", "", agent.WithHandoffs(helper), agent.WithSubAgents(helper), agent.WithDisabledBuiltinTools("handoff"))
+	assert.False(t, hasPrompt(root, "handoff"))
+	assert.True(t, hasPrompt(root, "transfer_task"))
+}
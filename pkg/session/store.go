@@ -16,6 +16,7 @@ import (
 	"github.com/docker/docker-agent/pkg/chat"
 	"github.com/docker/docker-agent/pkg/concurrent"
 	"github.com/docker/docker-agent/pkg/sqliteutil"
+	"github.com/docker/docker-agent/pkg/tools"
 )
 
 var (
@@ -72,6 +73,34 @@ type Summary struct {
 	CreatedAt   time.Time
 	Starred     bool
 	NumMessages int
+	// AgentName is the name of the agent that produced the most recent message.
+	AgentName string
+	Cost      float64
+	// ParentID and ToolCallID are the session and the tool call that spawned
+	// a sub-session. Both are empty for top-level sessions.
+	ParentID   string
+	ToolCallID string
+	// SubSessions are the summaries of the sub-sessions, in the order they
+	// were created, so that UIs can show sessions as a tree.
+	SubSessions []Summary
+}
+
+// nestSubSessions attaches the summaries of sub-sessions, in the order they
+// were created, to the summaries of their parents, at any depth.
+func nestSubSessions(summaries, subSessions []Summary) {
+	children := make(map[string][]Summary)
+	for _, sub := range subSessions {
+		children[sub.ParentID] = append(children[sub.ParentID], sub)
+	}
+
+	var nest func([]Summary)
+	nest = func(summaries []Summary) {
+		for i := range summaries {
+			summaries[i].SubSessions = children[summaries[i].ID]
+			nest(summaries[i].SubSessions)
+		}
+	}
+	nest(summaries)
 }
 
 // Store defines the interface for session storage
@@ -79,8 +108,17 @@ type Store interface {
 	// === Core session operations ===
 	AddSession(ctx context.Context, session *Session) error
 	GetSession(ctx context.Context, id string) (*Session, error)
+	// GetCondensedSession retrieves a session with only the items still sent
+	// to the model: its summaries and the messages that follow the last one.
+	// It's a lighter warm start for long sessions, see condenseItems.
+	GetCondensedSession(ctx context.Context, id string) (*Session, error)
 	GetSessions(ctx context.Context) ([]*Session, error)
+	// GetSessionSummaries returns summaries of the top-level sessions, most
+	// recent first. The summaries of their sub-sessions are nested in them.
 	GetSessionSummaries(ctx context.Context) ([]Summary, error)
+	// SearchSessions returns summaries of the sessions whose title or message
+	// contents contain query (case-insensitive), most recent first.
+	SearchSessions(ctx context.Context, query string) ([]Summary, error)
 	DeleteSession(ctx context.Context, id string) error
 	UpdateSession(ctx context.Context, session *Session) error // Updates metadata only (not messages/items)
 	SetSessionStarred(ctx context.Context, id string, starred bool) error
@@ -100,8 +138,16 @@ type Store interface {
 	AddSubSession(ctx context.Context, parentSessionID string, subSession *Session) error
 
 	// AddSummary adds a summary item to a session at the next position.
-	// firstKeptEntry is the index of the first message kept verbatim during compaction.
-	AddSummary(ctx context.Context, sessionID, summary string, firstKeptEntry int) error
+	AddSummary(ctx context.Context, sessionID string, summary Item) error
+
+	// UpdateSummary replaces the text and the details of the summary of a
+	// session at index, in the order of its summaries.
+	UpdateSummary(ctx context.Context, sessionID string, index int, summary Item) error
+
+	// RollbackSession removes the stored items of a session rolled back to
+	// a checkpoint beyond the ones it still has, with their sub-sessions,
+	// and updates its metadata.
+	RollbackSession(ctx context.Context, session *Session) error
 
 	// === Granular metadata updates ===
 
@@ -145,6 +191,12 @@ func (s *InMemorySessionStore) GetSession(_ context.Context, id string) (*Sessio
 	return session, nil
 }
 
+// GetCondensedSession retrieves a session by ID. The sessions are in memory
+// already: they're returned in full.
+func (s *InMemorySessionStore) GetCondensedSession(ctx context.Context, id string) (*Session, error) {
+	return s.GetSession(ctx, id)
+}
+
 func (s *InMemorySessionStore) GetSessions(_ context.Context) ([]*Session, error) {
 	sessions := make([]*Session, 0, s.sessions.Length())
 	s.sessions.Range(func(key string, value *Session) bool {
@@ -155,24 +207,72 @@ func (s *InMemorySessionStore) GetSessions(_ context.Context) ([]*Session, error
 }
 
 func (s *InMemorySessionStore) GetSessionSummaries(_ context.Context) ([]Summary, error) {
-	summaries := make([]Summary, 0, s.sessions.Length())
-	s.sessions.Range(func(_ string, value *Session) bool {
-		if value.ParentID != "" {
+	return s.summaries(func(*Session) bool { return true }), nil
+}
+
+func (s *InMemorySessionStore) SearchSessions(_ context.Context, query string) ([]Summary, error) {
+	query = strings.ToLower(query)
+	return s.summaries(func(session *Session) bool {
+		if strings.Contains(strings.ToLower(session.Title), query) {
 			return true
 		}
-		summaries = append(summaries, Summary{
+		session.mu.RLock()
+		defer session.mu.RUnlock()
+		for _, item := range session.Messages {
+			if item.IsMessage() && strings.Contains(strings.ToLower(item.Message.Message.Content), query) {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+// summaries returns the summaries of the top-level sessions accepted by match,
+// most recent first, with the summaries of their sub-sessions.
+func (s *InMemorySessionStore) summaries(match func(*Session) bool) []Summary {
+	summaries := make([]Summary, 0, s.sessions.Length())
+	var subSessions []Summary
+	s.sessions.Range(func(_ string, value *Session) bool {
+		summary := Summary{
 			ID:          value.ID,
 			Title:       value.Title,
 			CreatedAt:   value.CreatedAt,
 			Starred:     value.Starred,
 			NumMessages: value.MessageCount(),
-		})
+			AgentName:   lastAgentName(value),
+			Cost:        value.Cost,
+			ParentID:    value.ParentID,
+			ToolCallID:  value.ToolCallID,
+		}
+		switch {
+		case value.ParentID != "":
+			subSessions = append(subSessions, summary)
+		case match(value):
+			summaries = append(summaries, summary)
+		}
 		return true
 	})
 	slices.SortFunc(summaries, func(a, b Summary) int {
 		return b.CreatedAt.Compare(a.CreatedAt)
 	})
-	return summaries, nil
+	slices.SortStableFunc(subSessions, func(a, b Summary) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	nestSubSessions(summaries, subSessions)
+	return summaries
+}
+
+// lastAgentName returns the name of the agent behind the session's most recent message.
+func lastAgentName(session *Session) string {
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+
+	for i := len(session.Messages) - 1; i >= 0; i-- {
+		if item := session.Messages[i]; item.IsMessage() && item.Message.AgentName != "" {
+			return item.Message.AgentName
+		}
+	}
+	return ""
 }
 
 func (s *InMemorySessionStore) DeleteSession(_ context.Context, id string) error {
@@ -212,10 +312,14 @@ func (s *InMemorySessionStore) UpdateSession(_ context.Context, session *Session
 		InputTokens:         session.InputTokens,
 		OutputTokens:        session.OutputTokens,
 		Cost:                session.Cost,
-		Permissions:         session.Permissions,
+		Permissions:         session.PermissionsSnapshot(),
 		AgentModelOverrides: session.AgentModelOverrides,
 		CustomModelsUsed:    session.CustomModelsUsed,
+		HandoffHistory:      session.Handoffs(),
+		FileChangeLog:       session.FileChanges(),
+		Checkpoints:         session.ListCheckpoints(),
 		ParentID:            session.ParentID,
+		ToolCallID:          session.ToolCallID,
 	}
 
 	// Preserve existing messages if session already exists
@@ -305,7 +409,7 @@ func (s *InMemorySessionStore) AddSubSession(_ context.Context, parentSessionID
 }
 
 // AddSummary adds a summary item to a session at the next position.
-func (s *InMemorySessionStore) AddSummary(_ context.Context, sessionID, summary string, firstKeptEntry int) error {
+func (s *InMemorySessionStore) AddSummary(_ context.Context, sessionID string, summary Item) error {
 	if sessionID == "" {
 		return ErrEmptyID
 	}
@@ -314,11 +418,67 @@ func (s *InMemorySessionStore) AddSummary(_ context.Context, sessionID, summary
 		return ErrNotFound
 	}
 	session.mu.Lock()
-	session.Messages = append(session.Messages, Item{Summary: summary, FirstKeptEntry: firstKeptEntry})
+	session.Messages = append(session.Messages, summary)
 	session.mu.Unlock()
 	return nil
 }
 
+// UpdateSummary replaces the text and the details of the summary of a
+// session at index, in the order of its summaries.
+func (s *InMemorySessionStore) UpdateSummary(_ context.Context, sessionID string, index int, summary Item) error {
+	if sessionID == "" {
+		return ErrEmptyID
+	}
+	session, exists := s.sessions.Load(sessionID)
+	if !exists {
+		return ErrNotFound
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	var n int
+	for i, item := range session.Messages {
+		if item.Summary == "" {
+			continue
+		}
+		if n == index {
+			session.Messages[i].Summary = summary.Summary
+			session.Messages[i].SummaryInfo = summary.SummaryInfo
+			return nil
+		}
+		n++
+	}
+	return fmt.Errorf("session %s has no summary %d", sessionID, index)
+}
+
+// RollbackSession replaces the items of the stored session with the ones of
+// a session rolled back to a checkpoint, removing the sub-sessions dropped,
+// and updates its metadata.
+func (s *InMemorySessionStore) RollbackSession(ctx context.Context, session *Session) error {
+	if session.ID == "" {
+		return ErrEmptyID
+	}
+	existing, exists := s.sessions.Load(session.ID)
+	if !exists {
+		return ErrNotFound
+	}
+	if existing != session {
+		session.mu.RLock()
+		items := slices.Clone(session.Messages)
+		session.mu.RUnlock()
+
+		existing.mu.Lock()
+		dropped := existing.Messages[min(len(items), len(existing.Messages)):]
+		for _, item := range dropped {
+			if item.SubSession != nil {
+				s.sessions.Delete(item.SubSession.ID)
+			}
+		}
+		existing.Messages = items
+		existing.mu.Unlock()
+	}
+	return s.UpdateSession(ctx, session)
+}
+
 // querier is an interface that abstracts *sql.DB and *sql.Tx for query operations.
 type querier interface {
 	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
@@ -469,8 +629,8 @@ func (s *SQLiteSessionStore) AddSession(ctx context.Context, session *Session) e
 	}
 
 	permissionsJSON := ""
-	if session.Permissions != nil {
-		permBytes, err := json.Marshal(session.Permissions)
+	if perms := session.PermissionsSnapshot(); perms != nil {
+		permBytes, err := json.Marshal(perms)
 		if err != nil {
 			return err
 		}
@@ -497,6 +657,36 @@ func (s *SQLiteSessionStore) AddSession(ctx context.Context, session *Session) e
 		customModelsUsedJSON = string(customBytes)
 	}
 
+	// Marshal handoff history (default to empty array if nil)
+	handoffHistoryJSON := "[]"
+	if handoffs := session.Handoffs(); len(handoffs) > 0 {
+		handoffBytes, err := json.Marshal(handoffs)
+		if err != nil {
+			return err
+		}
+		handoffHistoryJSON = string(handoffBytes)
+	}
+
+	// Marshal file changes (default to empty array if nil)
+	fileChangesJSON := "[]"
+	if changes := session.FileChanges(); len(changes) > 0 {
+		changesBytes, err := json.Marshal(changes)
+		if err != nil {
+			return err
+		}
+		fileChangesJSON = string(changesBytes)
+	}
+
+	// Marshal checkpoints (default to empty array if nil)
+	checkpointsJSON := "[]"
+	if checkpoints := session.ListCheckpoints(); len(checkpoints) > 0 {
+		checkpointsBytes, err := json.Marshal(checkpoints)
+		if err != nil {
+			return err
+		}
+		checkpointsJSON = string(checkpointsBytes)
+	}
+
 	// Use NULL for empty parent_id to avoid foreign key constraint issues
 	var parentID any
 	if session.ParentID != "" {
@@ -513,12 +703,12 @@ func (s *SQLiteSessionStore) AddSession(ctx context.Context, session *Session) e
 		`INSERT INTO sessions (
 			id, tools_approved, input_tokens, output_tokens, title, cost, send_user_message,
 			max_iterations, working_dir, created_at, permissions, agent_model_overrides,
-			custom_models_used, thinking, parent_id
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			custom_models_used, handoff_history, file_changes, checkpoints, thinking, parent_id, tool_call_id
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		session.ID, session.ToolsApproved, session.InputTokens, session.OutputTokens, session.Title,
 		session.Cost, session.SendUserMessage, session.MaxIterations, session.WorkingDir,
 		session.CreatedAt.Format(time.RFC3339), permissionsJSON, agentModelOverridesJSON,
-		customModelsUsedJSON, false, parentID)
+		customModelsUsedJSON, handoffHistoryJSON, fileChangesJSON, checkpointsJSON, false, parentID, session.ToolCallID)
 	if err != nil {
 		return err
 	}
@@ -539,13 +729,13 @@ func scanSession(scanner interface {
 	Scan(dest ...any) error
 },
 ) (*Session, error) {
-	var toolsApprovedStr, inputTokensStr, outputTokensStr, titleStr, costStr, sendUserMessageStr, maxIterationsStr, createdAtStr, starredStr, agentModelOverridesJSON, customModelsUsedJSON string
+	var toolsApprovedStr, inputTokensStr, outputTokensStr, titleStr, costStr, sendUserMessageStr, maxIterationsStr, createdAtStr, starredStr, agentModelOverridesJSON, customModelsUsedJSON, handoffHistoryJSON, fileChangesJSON, checkpointsJSON string
 	var thinkingStr string // read from DB but not used (kept for backward compatibility)
 	var sessionID string
 	var workingDir sql.NullString
 	var permissionsJSON sql.NullString
-	var parentID sql.NullString
-	err := scanner.Scan(&sessionID, &toolsApprovedStr, &inputTokensStr, &outputTokensStr, &titleStr, &costStr, &sendUserMessageStr, &maxIterationsStr, &workingDir, &createdAtStr, &starredStr, &permissionsJSON, &agentModelOverridesJSON, &customModelsUsedJSON, &thinkingStr, &parentID)
+	var parentID, toolCallID sql.NullString
+	err := scanner.Scan(&sessionID, &toolsApprovedStr, &inputTokensStr, &outputTokensStr, &titleStr, &costStr, &sendUserMessageStr, &maxIterationsStr, &workingDir, &createdAtStr, &starredStr, &permissionsJSON, &agentModelOverridesJSON, &customModelsUsedJSON, &handoffHistoryJSON, &fileChangesJSON, &checkpointsJSON, &thinkingStr, &parentID, &toolCallID)
 	if err != nil {
 		return nil, err
 	}
@@ -617,6 +807,30 @@ func scanSession(scanner interface {
 		}
 	}
 
+	// Parse handoff history (may be empty or "[]")
+	var handoffHistory []Handoff
+	if handoffHistoryJSON != "" && handoffHistoryJSON != "[]" {
+		if err := json.Unmarshal([]byte(handoffHistoryJSON), &handoffHistory); err != nil {
+			return nil, err
+		}
+	}
+
+	// Parse file changes (may be empty or "[]")
+	var fileChanges []tools.FileChange
+	if fileChangesJSON != "" && fileChangesJSON != "[]" {
+		if err := json.Unmarshal([]byte(fileChangesJSON), &fileChanges); err != nil {
+			return nil, err
+		}
+	}
+
+	// Parse checkpoints (may be empty or "[]")
+	var checkpoints []Checkpoint
+	if checkpointsJSON != "" && checkpointsJSON != "[]" {
+		if err := json.Unmarshal([]byte(checkpointsJSON), &checkpoints); err != nil {
+			return nil, err
+		}
+	}
+
 	return &Session{
 		ID:                  sessionID,
 		Title:               titleStr,
@@ -633,18 +847,32 @@ func scanSession(scanner interface {
 		Permissions:         permissions,
 		AgentModelOverrides: agentModelOverrides,
 		CustomModelsUsed:    customModelsUsed,
+		HandoffHistory:      handoffHistory,
+		FileChangeLog:       fileChanges,
+		Checkpoints:         checkpoints,
 		ParentID:            parentID.String,
+		ToolCallID:          toolCallID.String,
 	}, nil
 }
 
 // GetSession retrieves a session by ID
 func (s *SQLiteSessionStore) GetSession(ctx context.Context, id string) (*Session, error) {
+	return s.getSession(ctx, id, false)
+}
+
+// GetCondensedSession retrieves a session by ID with only the items still
+// sent to the model, see condenseItems. The other items aren't read.
+func (s *SQLiteSessionStore) GetCondensedSession(ctx context.Context, id string) (*Session, error) {
+	return s.getSession(ctx, id, true)
+}
+
+func (s *SQLiteSessionStore) getSession(ctx context.Context, id string, condensed bool) (*Session, error) {
 	if id == "" {
 		return nil, ErrEmptyID
 	}
 
 	row := s.db.QueryRowContext(ctx,
-		"SELECT id, tools_approved, input_tokens, output_tokens, title, cost, send_user_message, max_iterations, working_dir, created_at, starred, permissions, agent_model_overrides, custom_models_used, thinking, parent_id FROM sessions WHERE id = ?", id)
+		"SELECT id, tools_approved, input_tokens, output_tokens, title, cost, send_user_message, max_iterations, working_dir, created_at, starred, permissions, agent_model_overrides, custom_models_used, handoff_history, file_changes, checkpoints, thinking, parent_id, tool_call_id FROM sessions WHERE id = ?", id)
 
 	sess, err := scanSession(row)
 	if err != nil {
@@ -654,16 +882,48 @@ func (s *SQLiteSessionStore) GetSession(ctx context.Context, id string) (*Sessio
 		return nil, err
 	}
 
-	// Load messages from session_items table
-	items, err := s.loadSessionItems(ctx, id)
+	if !condensed {
+		// Load messages from session_items table
+		items, err := s.loadSessionItems(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("loading session items: %w", err)
+		}
+		sess.Messages = items
+		return sess, nil
+	}
+
+	fromPosition, err := s.tailStart(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("finding the last summary: %w", err)
+	}
+	items, positions, err := s.loadSessionItemsFrom(ctx, s.db, id, fromPosition)
 	if err != nil {
 		return nil, fmt.Errorf("loading session items: %w", err)
 	}
-	sess.Messages = items
+	sess.Messages = condenseItems(items, positions)
 
 	return sess, nil
 }
 
+// tailStart returns the position of the first item sent to the model after
+// the last summary of a session, 0 without summary.
+func (s *SQLiteSessionStore) tailStart(ctx context.Context, sessionID string) (int, error) {
+	var position, kept int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT position, COALESCE(first_kept_entry, 0) FROM session_items
+		 WHERE session_id = ? AND item_type = 'summary' ORDER BY position DESC LIMIT 1`, sessionID).Scan(&position, &kept)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if kept > 0 && kept < position {
+		return kept, nil
+	}
+	return position + 1, nil
+}
+
 // sessionItemRow holds the raw data from a session_items row
 type sessionItemRow struct {
 	position       int
@@ -674,6 +934,7 @@ type sessionItemRow struct {
 	subsessionID   sql.NullString
 	summaryText    sql.NullString
 	firstKeptEntry int
+	summaryInfo    sql.NullString
 }
 
 // loadSessionItems loads all items for a session from the session_items table.
@@ -683,11 +944,18 @@ func (s *SQLiteSessionStore) loadSessionItems(ctx context.Context, sessionID str
 
 // loadSessionItemsWith loads items using the provided querier (db or tx).
 func (s *SQLiteSessionStore) loadSessionItemsWith(ctx context.Context, q querier, sessionID string) ([]Item, error) {
+	items, _, err := s.loadSessionItemsFrom(ctx, q, sessionID, 0)
+	return items, err
+}
+
+// loadSessionItemsFrom loads the summaries of a session and its items from
+// fromPosition on, and returns them with their positions.
+func (s *SQLiteSessionStore) loadSessionItemsFrom(ctx context.Context, q querier, sessionID string, fromPosition int) ([]Item, []int, error) {
 	rows, err := q.QueryContext(ctx,
-		`SELECT position, item_type, agent_name, message_json, implicit, subsession_id, summary_text, COALESCE(first_kept_entry, 0)
-		 FROM session_items WHERE session_id = ? ORDER BY position`, sessionID)
+		`SELECT position, item_type, agent_name, message_json, implicit, subsession_id, summary_text, COALESCE(first_kept_entry, 0), summary_info
+		 FROM session_items WHERE session_id = ? AND (position >= ? OR item_type = 'summary') ORDER BY position`, sessionID, fromPosition)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 
@@ -696,35 +964,37 @@ func (s *SQLiteSessionStore) loadSessionItemsWith(ctx context.Context, q querier
 	var rawRows []sessionItemRow
 	for rows.Next() {
 		var row sessionItemRow
-		if err := rows.Scan(&row.position, &row.itemType, &row.agentName, &row.messageJSON, &row.implicit, &row.subsessionID, &row.summaryText, &row.firstKeptEntry); err != nil {
-			return nil, err
+		if err := rows.Scan(&row.position, &row.itemType, &row.agentName, &row.messageJSON, &row.implicit, &row.subsessionID, &row.summaryText, &row.firstKeptEntry, &row.summaryInfo); err != nil {
+			return nil, nil, err
 		}
 		rawRows = append(rawRows, row)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if len(rawRows) == 0 {
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	// Now process the collected rows, making recursive calls as needed
 	var items []Item
+	var positions []int
 	for _, row := range rawRows {
 		switch row.itemType {
 		case "message":
 			var chatMsg chat.Message
 			if err := json.Unmarshal([]byte(row.messageJSON.String), &chatMsg); err != nil {
-				return nil, fmt.Errorf("unmarshaling message at position %d: %w", row.position, err)
+				return nil, nil, fmt.Errorf("unmarshaling message at position %d: %w", row.position, err)
 			}
-			items = append(items, Item{
-				Message: &Message{
-					AgentName: row.agentName.String,
-					Message:   chatMsg,
-					Implicit:  row.implicit,
-				},
-			})
+			msg := &Message{
+				AgentName: row.agentName.String,
+				Message:   chatMsg,
+				Implicit:  row.implicit,
+			}
+			msg.fillMetadata()
+			items = append(items, Item{Message: msg})
+			positions = append(positions, row.position)
 
 		case "subsession":
 			// Skip if subsession_id is NULL (can happen if the sub-session was deleted
@@ -741,22 +1011,31 @@ func (s *SQLiteSessionStore) loadSessionItemsWith(ctx context.Context, q querier
 					slog.Warn("Skipping orphaned subsession reference", "session_id", sessionID, "subsession_id", row.subsessionID.String)
 					continue
 				}
-				return nil, fmt.Errorf("getting sub-session %s: %w", row.subsessionID.String, err)
+				return nil, nil, fmt.Errorf("getting sub-session %s: %w", row.subsessionID.String, err)
 			}
 			items = append(items, Item{SubSession: subSession})
+			positions = append(positions, row.position)
 
 		case "summary":
-			items = append(items, Item{Summary: row.summaryText.String, FirstKeptEntry: row.firstKeptEntry})
+			item := Item{Summary: row.summaryText.String, FirstKeptEntry: row.firstKeptEntry}
+			if row.summaryInfo.String != "" {
+				item.SummaryInfo = &SummaryInfo{}
+				if err := json.Unmarshal([]byte(row.summaryInfo.String), item.SummaryInfo); err != nil {
+					return nil, nil, fmt.Errorf("unmarshaling summary info at position %d: %w", row.position, err)
+				}
+			}
+			items = append(items, item)
+			positions = append(positions, row.position)
 		}
 	}
 
-	return items, nil
+	return items, positions, nil
 }
 
 // loadSessionWith loads a session using the provided querier.
 func (s *SQLiteSessionStore) loadSessionWith(ctx context.Context, q querier, id string) (*Session, error) {
 	row := q.QueryRowContext(ctx,
-		"SELECT id, tools_approved, input_tokens, output_tokens, title, cost, send_user_message, max_iterations, working_dir, created_at, starred, permissions, agent_model_overrides, custom_models_used, thinking, parent_id FROM sessions WHERE id = ?", id)
+		"SELECT id, tools_approved, input_tokens, output_tokens, title, cost, send_user_message, max_iterations, working_dir, created_at, starred, permissions, agent_model_overrides, custom_models_used, handoff_history, file_changes, checkpoints, thinking, parent_id, tool_call_id FROM sessions WHERE id = ?", id)
 
 	sess, err := scanSession(row)
 	if err != nil {
@@ -779,7 +1058,7 @@ func (s *SQLiteSessionStore) loadSessionWith(ctx context.Context, q querier, id
 // GetSessions retrieves all root sessions (excludes sub-sessions)
 func (s *SQLiteSessionStore) GetSessions(ctx context.Context) ([]*Session, error) {
 	rows, err := s.db.QueryContext(ctx,
-		"SELECT id, tools_approved, input_tokens, output_tokens, title, cost, send_user_message, max_iterations, working_dir, created_at, starred, permissions, agent_model_overrides, custom_models_used, thinking, parent_id FROM sessions WHERE parent_id IS NULL OR parent_id = '' ORDER BY created_at DESC")
+		"SELECT id, tools_approved, input_tokens, output_tokens, title, cost, send_user_message, max_iterations, working_dir, created_at, starred, permissions, agent_model_overrides, custom_models_used, handoff_history, file_changes, checkpoints, thinking, parent_id, tool_call_id FROM sessions WHERE parent_id IS NULL OR parent_id = '' ORDER BY created_at DESC")
 	if err != nil {
 		return nil, err
 	}
@@ -810,15 +1089,70 @@ func (s *SQLiteSessionStore) GetSessions(ctx context.Context) ([]*Session, error
 	return sessions, nil
 }
 
-// GetSessionSummaries retrieves lightweight session metadata for listing (excludes sub-sessions).
+// summaryColumnsQuery selects the columns scanned by querySummaries.
+const summaryColumnsQuery = `SELECT s.id, s.title, s.created_at, s.starred, COALESCE(s.cost, 0),
+		(SELECT COUNT(*) FROM session_items si WHERE si.session_id = s.id AND si.item_type = 'message'),
+		COALESCE((SELECT si.agent_name FROM session_items si
+		          WHERE si.session_id = s.id AND si.item_type = 'message' AND si.agent_name != ''
+		          ORDER BY si.position DESC LIMIT 1), ''),
+		COALESCE(s.parent_id, ''), COALESCE(s.tool_call_id, '')
+	 FROM sessions s`
+
+// summariesQuery selects the columns scanned by querySummaries for top-level sessions.
+const summariesQuery = summaryColumnsQuery + `
+	 WHERE (s.parent_id IS NULL OR s.parent_id = '')`
+
+// subSessionSummariesQuery selects the columns scanned by querySummaries for
+// sub-sessions, in the order they were created.
+const subSessionSummariesQuery = summaryColumnsQuery + `
+	 WHERE s.parent_id IS NOT NULL AND s.parent_id != ''
+	 ORDER BY s.created_at, s.rowid`
+
+// GetSessionSummaries retrieves lightweight metadata of the top-level sessions
+// for listing, with the metadata of their sub-sessions nested in SubSessions.
 // This is much faster than GetSessions as it doesn't load message content.
 func (s *SQLiteSessionStore) GetSessionSummaries(ctx context.Context) ([]Summary, error) {
-	rows, err := s.db.QueryContext(ctx,
-		`SELECT s.id, s.title, s.created_at, s.starred,
-		        (SELECT COUNT(*) FROM session_items si WHERE si.session_id = s.id AND si.item_type = 'message')
-		 FROM sessions s
-		 WHERE s.parent_id IS NULL OR s.parent_id = ''
-		 ORDER BY s.created_at DESC`)
+	return s.querySummaries(ctx, summariesQuery+` ORDER BY s.created_at DESC`)
+}
+
+// SearchSessions returns summaries of the top-level sessions whose title or
+// message contents contain query. Matching is case-insensitive for ASCII.
+func (s *SQLiteSessionStore) SearchSessions(ctx context.Context, query string) ([]Summary, error) {
+	pattern := "%" + escapeLike(query) + "%"
+	return s.querySummaries(ctx, summariesQuery+`
+		 AND (s.title LIKE ? ESCAPE '\'
+		      OR EXISTS (SELECT 1 FROM session_items si
+		                 WHERE si.session_id = s.id AND si.item_type = 'message'
+		                   AND json_extract(si.message_json, '$.content') LIKE ? ESCAPE '\'))
+		 ORDER BY s.created_at DESC`, pattern, pattern)
+}
+
+// escapeLike escapes the LIKE wildcards in s so that it matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// querySummaries returns the summaries of the sessions selected by query, with
+// the summaries of their sub-sessions.
+func (s *SQLiteSessionStore) querySummaries(ctx context.Context, query string, args ...any) ([]Summary, error) {
+	summaries, err := s.scanSummaries(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(summaries) == 0 {
+		return summaries, nil
+	}
+
+	subSessions, err := s.scanSummaries(ctx, subSessionSummariesQuery)
+	if err != nil {
+		return nil, err
+	}
+	nestSubSessions(summaries, subSessions)
+	return summaries, nil
+}
+
+func (s *SQLiteSessionStore) scanSummaries(ctx context.Context, query string, args ...any) ([]Summary, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -826,9 +1160,10 @@ func (s *SQLiteSessionStore) GetSessionSummaries(ctx context.Context) ([]Summary
 
 	var summaries []Summary
 	for rows.Next() {
-		var id, title, createdAtStr, starredStr string
+		var id, title, createdAtStr, starredStr, agentName, parentID, toolCallID string
+		var cost float64
 		var numMessages int
-		if err := rows.Scan(&id, &title, &createdAtStr, &starredStr, &numMessages); err != nil {
+		if err := rows.Scan(&id, &title, &createdAtStr, &starredStr, &cost, &numMessages, &agentName, &parentID, &toolCallID); err != nil {
 			return nil, err
 		}
 		createdAt, err := time.Parse(time.RFC3339, createdAtStr)
@@ -845,6 +1180,10 @@ func (s *SQLiteSessionStore) GetSessionSummaries(ctx context.Context) ([]Summary
 			CreatedAt:   createdAt,
 			Starred:     starred,
 			NumMessages: numMessages,
+			AgentName:   agentName,
+			Cost:        cost,
+			ParentID:    parentID,
+			ToolCallID:  toolCallID,
 		})
 	}
 
@@ -887,8 +1226,8 @@ func (s *SQLiteSessionStore) UpdateSession(ctx context.Context, session *Session
 	}
 
 	permissionsJSON := ""
-	if session.Permissions != nil {
-		permBytes, err := json.Marshal(session.Permissions)
+	if perms := session.PermissionsSnapshot(); perms != nil {
+		permBytes, err := json.Marshal(perms)
 		if err != nil {
 			return err
 		}
@@ -915,6 +1254,36 @@ func (s *SQLiteSessionStore) UpdateSession(ctx context.Context, session *Session
 		customModelsUsedJSON = string(customBytes)
 	}
 
+	// Marshal handoff history (default to empty array if nil)
+	handoffHistoryJSON := "[]"
+	if handoffs := session.Handoffs(); len(handoffs) > 0 {
+		handoffBytes, err := json.Marshal(handoffs)
+		if err != nil {
+			return err
+		}
+		handoffHistoryJSON = string(handoffBytes)
+	}
+
+	// Marshal file changes (default to empty array if nil)
+	fileChangesJSON := "[]"
+	if changes := session.FileChanges(); len(changes) > 0 {
+		changesBytes, err := json.Marshal(changes)
+		if err != nil {
+			return err
+		}
+		fileChangesJSON = string(changesBytes)
+	}
+
+	// Marshal checkpoints (default to empty array if nil)
+	checkpointsJSON := "[]"
+	if checkpoints := session.ListCheckpoints(); len(checkpoints) > 0 {
+		checkpointsBytes, err := json.Marshal(checkpoints)
+		if err != nil {
+			return err
+		}
+		checkpointsJSON = string(checkpointsBytes)
+	}
+
 	// Use NULL for empty parent_id to avoid foreign key constraint issues
 	var parentID any
 	if session.ParentID != "" {
@@ -932,9 +1301,9 @@ func (s *SQLiteSessionStore) UpdateSession(ctx context.Context, session *Session
 		`INSERT INTO sessions (
 			id, tools_approved, input_tokens, output_tokens, title, cost, send_user_message,
 			max_iterations, working_dir, created_at, starred, permissions, agent_model_overrides,
-			custom_models_used, thinking, parent_id
+			custom_models_used, handoff_history, file_changes, checkpoints, thinking, parent_id, tool_call_id
 		)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		 ON CONFLICT(id) DO UPDATE SET
 		   title = excluded.title,
 		   tools_approved = excluded.tools_approved,
@@ -948,12 +1317,16 @@ func (s *SQLiteSessionStore) UpdateSession(ctx context.Context, session *Session
 		   permissions = excluded.permissions,
 		   agent_model_overrides = excluded.agent_model_overrides,
 		   custom_models_used = excluded.custom_models_used,
+		   handoff_history = excluded.handoff_history,
+		   file_changes = excluded.file_changes,
+		   checkpoints = excluded.checkpoints,
 		   thinking = excluded.thinking,
-		   parent_id = excluded.parent_id`,
+		   parent_id = excluded.parent_id,
+		   tool_call_id = excluded.tool_call_id`,
 		session.ID, session.ToolsApproved, session.InputTokens, session.OutputTokens,
 		session.Title, session.Cost, session.SendUserMessage, session.MaxIterations, session.WorkingDir,
 		session.CreatedAt.Format(time.RFC3339), session.Starred, permissionsJSON, agentModelOverridesJSON,
-		customModelsUsedJSON, false, parentID)
+		customModelsUsedJSON, handoffHistoryJSON, fileChangesJSON, checkpointsJSON, false, parentID, session.ToolCallID)
 	if err != nil {
 		return err
 	}
@@ -1092,8 +1465,8 @@ func (s *SQLiteSessionStore) AddSubSession(ctx context.Context, parentSessionID
 // addSessionTx inserts a session within a transaction.
 func (s *SQLiteSessionStore) addSessionTx(ctx context.Context, tx *sql.Tx, session *Session) error {
 	permissionsJSON := ""
-	if session.Permissions != nil {
-		permBytes, err := json.Marshal(session.Permissions)
+	if perms := session.PermissionsSnapshot(); perms != nil {
+		permBytes, err := json.Marshal(perms)
 		if err != nil {
 			return err
 		}
@@ -1118,6 +1491,33 @@ func (s *SQLiteSessionStore) addSessionTx(ctx context.Context, tx *sql.Tx, sessi
 		customModelsUsedJSON = string(customBytes)
 	}
 
+	handoffHistoryJSON := "[]"
+	if handoffs := session.Handoffs(); len(handoffs) > 0 {
+		handoffBytes, err := json.Marshal(handoffs)
+		if err != nil {
+			return err
+		}
+		handoffHistoryJSON = string(handoffBytes)
+	}
+
+	fileChangesJSON := "[]"
+	if changes := session.FileChanges(); len(changes) > 0 {
+		changesBytes, err := json.Marshal(changes)
+		if err != nil {
+			return err
+		}
+		fileChangesJSON = string(changesBytes)
+	}
+
+	checkpointsJSON := "[]"
+	if checkpoints := session.ListCheckpoints(); len(checkpoints) > 0 {
+		checkpointsBytes, err := json.Marshal(checkpoints)
+		if err != nil {
+			return err
+		}
+		checkpointsJSON = string(checkpointsBytes)
+	}
+
 	// Use NULL for empty parent_id to avoid foreign key constraint issues
 	var parentID any
 	if session.ParentID != "" {
@@ -1127,14 +1527,14 @@ func (s *SQLiteSessionStore) addSessionTx(ctx context.Context, tx *sql.Tx, sessi
 		`INSERT INTO sessions (
 			id, tools_approved, input_tokens, output_tokens, title, cost, send_user_message,
 			max_iterations, working_dir, created_at, starred, permissions, agent_model_overrides,
-			custom_models_used, thinking, parent_id
+			custom_models_used, handoff_history, file_changes, checkpoints, thinking, parent_id, tool_call_id
 		)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		session.ID, session.ToolsApproved, session.InputTokens, session.OutputTokens,
 		session.Title, session.Cost, session.SendUserMessage, session.MaxIterations,
 		session.WorkingDir, session.CreatedAt.Format(time.RFC3339), session.Starred,
-		permissionsJSON, agentModelOverridesJSON, customModelsUsedJSON, false,
-		parentID)
+		permissionsJSON, agentModelOverridesJSON, customModelsUsedJSON, handoffHistoryJSON, fileChangesJSON, checkpointsJSON, false,
+		parentID, session.ToolCallID)
 	return err
 }
 
@@ -1174,10 +1574,14 @@ func (s *SQLiteSessionStore) addItemTx(ctx context.Context, tx *sql.Tx, sessionI
 		return err
 
 	case item.Summary != "":
-		_, err := tx.ExecContext(ctx,
-			`INSERT INTO session_items (session_id, position, item_type, summary_text, first_kept_entry)
-			 VALUES (?, ?, 'summary', ?, ?)`,
-			sessionID, position, item.Summary, item.FirstKeptEntry)
+		infoJSON, err := marshalSummaryInfo(item.SummaryInfo)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO session_items (session_id, position, item_type, summary_text, first_kept_entry, summary_info)
+			 VALUES (?, ?, 'summary', ?, ?, ?)`,
+			sessionID, position, item.Summary, item.FirstKeptEntry, infoJSON)
 		return err
 
 	default:
@@ -1186,15 +1590,19 @@ func (s *SQLiteSessionStore) addItemTx(ctx context.Context, tx *sql.Tx, sessionI
 }
 
 // AddSummary adds a summary item to a session at the next position.
-func (s *SQLiteSessionStore) AddSummary(ctx context.Context, sessionID, summary string, firstKeptEntry int) error {
+func (s *SQLiteSessionStore) AddSummary(ctx context.Context, sessionID string, summary Item) error {
 	if sessionID == "" {
 		return ErrEmptyID
 	}
 
-	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO session_items (session_id, position, item_type, summary_text, first_kept_entry)
-		 VALUES (?, (SELECT COALESCE(MAX(position), -1) + 1 FROM session_items WHERE session_id = ?), 'summary', ?, ?)`,
-		sessionID, sessionID, summary, firstKeptEntry)
+	infoJSON, err := marshalSummaryInfo(summary.SummaryInfo)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO session_items (session_id, position, item_type, summary_text, first_kept_entry, summary_info)
+		 VALUES (?, (SELECT COALESCE(MAX(position), -1) + 1 FROM session_items WHERE session_id = ?), 'summary', ?, ?, ?)`,
+		sessionID, sessionID, summary.Summary, summary.FirstKeptEntry, infoJSON)
 	if err != nil {
 		return err
 	}
@@ -1202,6 +1610,93 @@ func (s *SQLiteSessionStore) AddSummary(ctx context.Context, sessionID, summary
 	return nil
 }
 
+// UpdateSummary replaces the text and the details of the summary of a
+// session at index, in the order of its summaries.
+func (s *SQLiteSessionStore) UpdateSummary(ctx context.Context, sessionID string, index int, summary Item) error {
+	if sessionID == "" {
+		return ErrEmptyID
+	}
+
+	infoJSON, err := marshalSummaryInfo(summary.SummaryInfo)
+	if err != nil {
+		return err
+	}
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE session_items SET summary_text = ?, summary_info = ?
+		 WHERE id = (SELECT id FROM session_items WHERE session_id = ? AND item_type = 'summary' ORDER BY position LIMIT 1 OFFSET ?)`,
+		summary.Summary, infoJSON, sessionID, index)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("session %s has no summary %d", sessionID, index)
+	}
+
+	return nil
+}
+
+// RollbackSession removes the stored items of a session rolled back to a
+// checkpoint beyond the ones it still has, with their sub-sessions, and
+// updates its metadata. The items that only hold a cost aren't stored: they
+// aren't counted.
+func (s *SQLiteSessionStore) RollbackSession(ctx context.Context, session *Session) error {
+	if session.ID == "" {
+		return ErrEmptyID
+	}
+
+	session.mu.RLock()
+	var kept int
+	for _, item := range session.Messages {
+		if item.Message != nil || item.SubSession != nil || item.Summary != "" {
+			kept++
+		}
+	}
+	session.mu.RUnlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// The items of the sub-sessions and their own sub-sessions are deleted
+	// along, see the cascades of migration 14.
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM sessions WHERE id IN (
+			SELECT subsession_id FROM session_items
+			WHERE id IN (SELECT id FROM session_items WHERE session_id = ? ORDER BY position LIMIT -1 OFFSET ?)
+			AND subsession_id IS NOT NULL)`,
+		session.ID, kept)
+	if err != nil {
+		return fmt.Errorf("deleting sub-sessions: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM session_items
+		 WHERE id IN (SELECT id FROM session_items WHERE session_id = ? ORDER BY position LIMIT -1 OFFSET ?)`,
+		session.ID, kept)
+	if err != nil {
+		return fmt.Errorf("deleting items: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return s.UpdateSession(ctx, session)
+}
+
+// marshalSummaryInfo returns the JSON of info, NULL when it's nil.
+func marshalSummaryInfo(info *SummaryInfo) (sql.NullString, error) {
+	if info == nil {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("marshaling summary info: %w", err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
 // UpdateSessionTokens updates only token/cost fields.
 func (s *SQLiteSessionStore) UpdateSessionTokens(ctx context.Context, sessionID string, inputTokens, outputTokens int64, cost float64) error {
 	if sessionID == "" {
@@ -13,6 +13,7 @@ import (
 
 	"github.com/docker/docker-agent/pkg/agent"
 	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/tools"
 )
 
 func TestStoreAgentName(t *testing.T) {
@@ -67,6 +68,29 @@ func TestStoreAgentName(t *testing.T) {
 	assert.Equal(t, "Another message from test-agent-2", retrievedSession.Messages[2].Message.Message.Content)
 }
 
+func TestStoreMessageMetadata(t *testing.T) {
+	tempDB := filepath.Join(t.TempDir(), "test_store_metadata.db")
+
+	store, err := NewSQLiteSessionStore(tempDB)
+	require.NoError(t, err)
+	defer store.(*SQLiteSessionStore).Close()
+
+	sess := New(WithID("metadata-session"))
+	sess.AddMessage(NewAgentMessage("root", &chat.Message{
+		Role:    chat.MessageRoleAssistant,
+		Content: "Hi",
+		Model:   "openai/gpt-4o",
+	}))
+	require.NoError(t, store.AddSession(t.Context(), sess))
+
+	retrieved, err := store.GetSession(t.Context(), "metadata-session")
+	require.NoError(t, err)
+
+	msg := retrieved.Messages[0].Message
+	assert.Equal(t, "openai/gpt-4o", msg.ModelID)
+	assert.True(t, msg.CreatedAt.Equal(sess.Messages[0].Message.CreatedAt.Truncate(time.Second)))
+}
+
 func TestStoreMultipleAgents(t *testing.T) {
 	tempDB := filepath.Join(t.TempDir(), "test_store_multi.db")
 
@@ -216,6 +240,7 @@ func TestGetSessionSummaries(t *testing.T) {
 	assert.Equal(t, "Second Session", summaries[0].Title)
 	assert.Equal(t, session2Time, summaries[0].CreatedAt)
 	assert.Equal(t, 1, summaries[0].NumMessages)
+	assert.Equal(t, "test-agent", summaries[0].AgentName)
 
 	assert.Equal(t, "session-1", summaries[1].ID)
 	assert.Equal(t, "First Session", summaries[1].Title)
@@ -223,6 +248,159 @@ func TestGetSessionSummaries(t *testing.T) {
 	assert.Equal(t, 1, summaries[1].NumMessages)
 }
 
+func TestSearchSessions(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"sqlite": func(t *testing.T) Store {
+			t.Helper()
+			store, err := NewSQLiteSessionStore(filepath.Join(t.TempDir(), "test_search_sessions.db"))
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = store.Close() })
+			return store
+		},
+		"in-memory": func(*testing.T) Store {
+			return NewInMemorySessionStore()
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			now := time.Now().UTC().Truncate(time.Second)
+
+			require.NoError(t, store.AddSession(t.Context(), &Session{
+				ID:    "deploy",
+				Title: "Deploy 100% of the cluster",
+				Messages: []Item{
+					NewMessageItem(UserMessage("How do I roll out the new release?")),
+					NewMessageItem(NewAgentMessage("ops", &chat.Message{
+						Role:    chat.MessageRoleAssistant,
+						Content: "Use a Kubernetes rolling update.",
+					})),
+				},
+				Cost:      0.25,
+				CreatedAt: now.Add(-time.Hour),
+			}))
+			require.NoError(t, store.AddSession(t.Context(), &Session{
+				ID:    "docs",
+				Title: "Write docs",
+				Messages: []Item{
+					NewMessageItem(NewAgentMessage("writer", &chat.Message{
+						Role:    chat.MessageRoleAssistant,
+						Content: "The kubernetes section is done.",
+					})),
+				},
+				CreatedAt: now,
+			}))
+
+			ids := func(summaries []Summary) []string {
+				var ids []string
+				for _, summary := range summaries {
+					ids = append(ids, summary.ID)
+				}
+				return ids
+			}
+
+			summaries, err := store.SearchSessions(t.Context(), "KUBERNETES")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"docs", "deploy"}, ids(summaries))
+			assert.Equal(t, "ops", summaries[1].AgentName)
+			assert.InDelta(t, 0.25, summaries[1].Cost, 1e-9)
+
+			summaries, err = store.SearchSessions(t.Context(), "docs")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"docs"}, ids(summaries))
+
+			// LIKE wildcards in the query are matched literally.
+			summaries, err = store.SearchSessions(t.Context(), "100%")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"deploy"}, ids(summaries))
+
+			summaries, err = store.SearchSessions(t.Context(), "rolling_update")
+			require.NoError(t, err)
+			assert.Empty(t, summaries)
+		})
+	}
+}
+
+func TestGetSessionSummaries_SubSessions(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"sqlite": func(t *testing.T) Store {
+			t.Helper()
+			store, err := NewSQLiteSessionStore(filepath.Join(t.TempDir(), "test_summaries_sub_sessions.db"))
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = store.Close() })
+			return store
+		},
+		"in-memory": func(*testing.T) Store {
+			return NewInMemorySessionStore()
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			now := time.Now().UTC().Truncate(time.Second)
+
+			require.NoError(t, store.AddSession(t.Context(), &Session{ID: "root", Title: "Fan out", CreatedAt: now}))
+			require.NoError(t, store.AddSubSession(t.Context(), "root", &Session{
+				ID:         "second",
+				Title:      "writer: draft the report",
+				ToolCallID: "call_2",
+				CreatedAt:  now.Add(2 * time.Second),
+			}))
+			require.NoError(t, store.AddSubSession(t.Context(), "root", &Session{
+				ID:         "first",
+				Title:      "researcher: compare quota APIs",
+				ToolCallID: "call_1",
+				CreatedAt:  now.Add(time.Second),
+			}))
+			require.NoError(t, store.AddSubSession(t.Context(), "first", &Session{
+				ID:         "nested",
+				Title:      "librarian: find the docs",
+				ToolCallID: "call_3",
+				CreatedAt:  now.Add(3 * time.Second),
+			}))
+
+			summaries, err := store.GetSessionSummaries(t.Context())
+			require.NoError(t, err)
+			require.Len(t, summaries, 1)
+			assert.Equal(t, "root", summaries[0].ID)
+			assert.Empty(t, summaries[0].ParentID)
+
+			// Sub-sessions are nested in the order they were created.
+			subSessions := summaries[0].SubSessions
+			require.Len(t, subSessions, 2)
+			assert.Equal(t, "first", subSessions[0].ID)
+			assert.Equal(t, "researcher: compare quota APIs", subSessions[0].Title)
+			assert.Equal(t, "root", subSessions[0].ParentID)
+			assert.Equal(t, "call_1", subSessions[0].ToolCallID)
+			assert.Equal(t, "second", subSessions[1].ID)
+			assert.Equal(t, "call_2", subSessions[1].ToolCallID)
+
+			require.Len(t, subSessions[0].SubSessions, 1)
+			assert.Equal(t, "nested", subSessions[0].SubSessions[0].ID)
+			assert.Equal(t, "first", subSessions[0].SubSessions[0].ParentID)
+			assert.Empty(t, subSessions[1].SubSessions)
+		})
+	}
+}
+
+func TestSubSessionToolCallIDRoundTrip(t *testing.T) {
+	store, err := NewSQLiteSessionStore(filepath.Join(t.TempDir(), "test_sub_session_tool_call_id.db"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	require.NoError(t, store.AddSession(t.Context(), &Session{ID: "root", CreatedAt: time.Now()}))
+	require.NoError(t, store.AddSubSession(t.Context(), "root", &Session{ID: "child", ToolCallID: "call_1", CreatedAt: time.Now()}))
+
+	loaded, err := store.GetSession(t.Context(), "root")
+	require.NoError(t, err)
+	require.Len(t, loaded.Messages, 1)
+	require.NotNil(t, loaded.Messages[0].SubSession)
+	assert.Equal(t, "root", loaded.Messages[0].SubSession.ParentID)
+	assert.Equal(t, "call_1", loaded.Messages[0].SubSession.ToolCallID)
+}
+
 func TestBranchSessionCopiesPrefix(t *testing.T) {
 	tempDB := filepath.Join(t.TempDir(), "test_branch_prefix.db")
 
@@ -629,6 +807,137 @@ func TestAgentModelOverrides_EmptyMap(t *testing.T) {
 	assert.Empty(t, retrieved.AgentModelOverrides)
 }
 
+func TestHandoffHistory_SQLite(t *testing.T) {
+	tempDB := filepath.Join(t.TempDir(), "test_handoff_history.db")
+
+	store, err := NewSQLiteSessionStore(tempDB)
+	require.NoError(t, err)
+	defer store.(*SQLiteSessionStore).Close()
+
+	session := New(WithUserMessage("Plan and build it"))
+	err = store.AddSession(t.Context(), session)
+	require.NoError(t, err)
+
+	session.AddHandoff("root", "planner")
+	session.AddMessage(NewAgentMessage("planner", &chat.Message{Role: chat.MessageRoleAssistant, Content: "Here is the plan"}))
+	session.AddHandoff("planner", "coder")
+
+	err = store.UpdateSession(t.Context(), session)
+	require.NoError(t, err)
+
+	retrieved, err := store.GetSession(t.Context(), session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []Handoff{
+		{From: "root", To: "planner", AtMessageIndex: 1},
+		{From: "planner", To: "coder", AtMessageIndex: 2},
+	}, retrieved.Handoffs())
+}
+
+func TestFileChanges_SQLite(t *testing.T) {
+	tempDB := filepath.Join(t.TempDir(), "test_file_changes.db")
+
+	store, err := NewSQLiteSessionStore(tempDB)
+	require.NoError(t, err)
+	defer store.(*SQLiteSessionStore).Close()
+
+	session := New(WithUserMessage("Rename the function"))
+	err = store.AddSession(t.Context(), session)
+	require.NoError(t, err)
+
+	session.AddFileChange(tools.FileChange{Path: "/src/a.go", Type: tools.FileModified})
+	session.AddFileChange(tools.FileChange{Path: "/src/b.go", Type: tools.FileCreated})
+
+	err = store.UpdateSession(t.Context(), session)
+	require.NoError(t, err)
+
+	retrieved, err := store.GetSession(t.Context(), session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []tools.FileChange{
+		{Path: "/src/a.go", Type: tools.FileModified},
+		{Path: "/src/b.go", Type: tools.FileCreated},
+	}, retrieved.FileChanges())
+}
+
+func TestRollbackSession(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"sqlite": func(t *testing.T) Store {
+			t.Helper()
+			store, err := NewSQLiteSessionStore(filepath.Join(t.TempDir(), "test_rollback.db"))
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = store.Close() })
+			return store
+		},
+		"in-memory": func(*testing.T) Store {
+			return NewInMemorySessionStore()
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+
+			sess := New(WithUserMessage("Fix the bug"))
+			require.NoError(t, store.AddSession(t.Context(), sess))
+			id := sess.Checkpoint("before the fix")
+			require.NoError(t, store.UpdateSession(t.Context(), sess))
+
+			msg := assistantMessage("Fixed")
+			sess.AddMessage(msg)
+			_, err := store.AddMessage(t.Context(), sess.ID, msg)
+			require.NoError(t, err)
+			child := &Session{ID: "child", CreatedAt: time.Now()}
+			sess.AddSubSession(child)
+			require.NoError(t, store.AddSubSession(t.Context(), sess.ID, child))
+
+			require.NoError(t, sess.Rollback(id))
+			require.NoError(t, store.RollbackSession(t.Context(), sess))
+
+			loaded, err := store.GetSession(t.Context(), sess.ID)
+			require.NoError(t, err)
+			require.Len(t, loaded.Messages, 1)
+			assert.Equal(t, "Fix the bug", loaded.Messages[0].Message.Message.Content)
+			checkpoints := loaded.ListCheckpoints()
+			require.Len(t, checkpoints, 1)
+			assert.Equal(t, "before the fix", checkpoints[0].Label)
+			assert.Equal(t, 1, checkpoints[0].AtMessageIndex)
+
+			_, err = store.GetSession(t.Context(), "child")
+			require.ErrorIs(t, err, ErrNotFound)
+		})
+	}
+}
+
+func TestSummaries_SQLite(t *testing.T) {
+	tempDB := filepath.Join(t.TempDir(), "test_summaries.db")
+
+	store, err := NewSQLiteSessionStore(tempDB)
+	require.NoError(t, err)
+	defer store.(*SQLiteSessionStore).Close()
+
+	sess := summarizedSession(t)
+	require.NoError(t, store.AddSession(t.Context(), sess))
+
+	retrieved, err := store.GetSession(t.Context(), sess.ID)
+	require.NoError(t, err)
+	assert.Equal(t, sess.Summaries(), retrieved.Summaries())
+
+	require.NoError(t, store.UpdateSummary(t.Context(), sess.ID, 0, Item{Summary: "better s1", SummaryInfo: &SummaryInfo{Model: "test/large"}}))
+	require.Error(t, store.UpdateSummary(t.Context(), sess.ID, 2, Item{Summary: "no such summary"}))
+
+	// The condensed session only has the summaries and the messages the
+	// model still sees.
+	condensed, err := store.GetCondensedSession(t.Context(), sess.ID)
+	require.NoError(t, err)
+	assert.Len(t, condensed.Messages, 5)
+	assert.Equal(t, conversationContents(sess), conversationContents(condensed))
+
+	summaries := condensed.Summaries()
+	require.Len(t, summaries, 2)
+	assert.Equal(t, "better s1", summaries[0].Summary)
+	assert.Equal(t, "test/large", summaries[0].Model)
+	assert.Equal(t, "s2", summaries[1].Summary)
+}
+
 func TestNewSQLiteSessionStore_RejectsNewerDatabase(t *testing.T) {
 	tempDir := t.TempDir()
 	dbPath := filepath.Join(tempDir, "test_newer_db.db")
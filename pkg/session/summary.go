@@ -0,0 +1,174 @@
+package session
+
+import (
+	"fmt"
+	"time"
+)
+
+// MessageRange is a range of the items of a session: from Start, included,
+// to End, excluded.
+type MessageRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Len returns the number of items in the range.
+func (r MessageRange) Len() int {
+	return max(0, r.End-r.Start)
+}
+
+// SummaryInfo describes a summary item.
+type SummaryInfo struct {
+	// Covered is the range of the items the summary condenses: the items
+	// sent to the model with the previous summary, up to the first message
+	// kept verbatim. The summary covers the items before them through the
+	// previous summary.
+	Covered MessageRange `json:"covered"`
+	// Messages is the number of messages in Covered.
+	Messages int `json:"messages,omitempty"`
+	// CreatedAt is the time the summary was generated.
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	// Model is the ID of the model that generated the summary.
+	Model string `json:"model,omitempty"`
+	// Tokens is the length of the summary, in tokens of that model.
+	Tokens int64 `json:"tokens,omitempty"`
+}
+
+// SummaryItem is a summary of a session, see Session.Summaries.
+type SummaryItem struct {
+	// Position is the index of the summary item in the session's Messages.
+	Position int
+	// Summary is the text of the summary.
+	Summary string
+
+	SummaryInfo
+}
+
+// Summaries returns the summaries of the session, oldest first. Only the
+// range of the items they cover is known of the summaries created before
+// their details were recorded.
+func (s *Session) Summaries() []SummaryItem {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var summaries []SummaryItem
+	for position, item := range s.Messages {
+		if item.Summary == "" {
+			continue
+		}
+		summary := SummaryItem{Position: position, Summary: item.Summary}
+		if item.SummaryInfo != nil {
+			summary.SummaryInfo = *item.SummaryInfo
+		} else {
+			summary.Covered, summary.Messages = coveredRange(s.Messages, position)
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// AddSummary appends the summary item to the session, recording the range
+// of the items it covers, and returns it.
+func (s *Session) AddSummary(item Item) SummaryItem {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var info SummaryInfo
+	if item.SummaryInfo != nil {
+		info = *item.SummaryInfo
+	}
+	position := len(s.Messages)
+	s.Messages = append(s.Messages, item)
+	info.Covered, info.Messages = coveredRange(s.Messages, position)
+	s.Messages[position].SummaryInfo = &info
+
+	return SummaryItem{Position: position, Summary: item.Summary, SummaryInfo: info}
+}
+
+// ReplaceSummary replaces the text of the summary item at position with
+// the one of item, e.g. a summary regenerated by another model. The items
+// it covers don't change, the cost of item is added to the one of the
+// summary.
+func (s *Session) ReplaceSummary(position int, item Item) (SummaryItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if position < 0 || position >= len(s.Messages) || s.Messages[position].Summary == "" {
+		return SummaryItem{}, fmt.Errorf("no summary at position %d", position)
+	}
+
+	var info SummaryInfo
+	if item.SummaryInfo != nil {
+		info = *item.SummaryInfo
+	}
+	info.Covered, info.Messages = coveredRange(s.Messages, position)
+	if old := s.Messages[position].SummaryInfo; old != nil {
+		info.Covered, info.Messages = old.Covered, old.Messages
+	}
+
+	s.Messages[position].Summary = item.Summary
+	s.Messages[position].SummaryInfo = &info
+	s.Messages[position].Cost += item.Cost
+
+	return SummaryItem{Position: position, Summary: item.Summary, SummaryInfo: info}, nil
+}
+
+// coveredRange returns the range of the items the summary at position
+// covers, and the number of messages in it.
+func coveredRange(items []Item, position int) (MessageRange, int) {
+	_, start := conversationStart(items[:position])
+	end := position
+	if kept := items[position].FirstKeptEntry; kept > 0 && kept < position {
+		end = kept
+	}
+	covered := MessageRange{Start: start, End: max(start, end)}
+
+	var messages int
+	for _, item := range items[covered.Start:covered.End] {
+		if item.IsMessage() {
+			messages++
+		}
+	}
+	return covered, messages
+}
+
+// condenseItems returns the items of a session that are still sent to the
+// model: its summaries, then the items that follow the last one's covered
+// range. positions are the positions of items in the session, items may
+// already be condensed that way.
+//
+// The summaries are moved first so that the last one is followed by the
+// items it kept verbatim. The ranges they cover are empty: the items were
+// left out.
+func condenseItems(items []Item, positions []int) []Item {
+	lastSummary, _ := conversationStart(items)
+	if lastSummary < 0 {
+		return items
+	}
+	tailStart := positions[lastSummary] + 1
+	if kept := items[lastSummary].FirstKeptEntry; kept > 0 && kept < positions[lastSummary] {
+		tailStart = kept
+	}
+
+	var summaries, tail []Item
+	for i, item := range items {
+		switch {
+		case item.Summary != "":
+			summaries = append(summaries, item)
+		case positions[i] >= tailStart:
+			tail = append(tail, item)
+		}
+	}
+
+	for i, summary := range summaries {
+		var info SummaryInfo
+		if summary.SummaryInfo != nil {
+			info = *summary.SummaryInfo
+		}
+		info.Covered = MessageRange{Start: len(summaries), End: len(summaries)}
+		summaries[i].SummaryInfo = &info
+		summaries[i].FirstKeptEntry = 0
+	}
+
+	return append(summaries, tail...)
+}
@@ -0,0 +1,138 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/chat"
+)
+
+// summarizedSession returns a session compacted twice, each time keeping
+// its last two messages verbatim.
+func summarizedSession(t *testing.T) *Session {
+	t.Helper()
+
+	sess := New()
+	addMessages := func(contents ...string) {
+		for i, content := range contents {
+			role := chat.MessageRoleUser
+			if i%2 == 1 {
+				role = chat.MessageRoleAssistant
+			}
+			sess.AddMessage(NewAgentMessage("root", &chat.Message{Role: role, Content: content}))
+		}
+	}
+
+	addMessages("m0", "m1", "m2", "m3")
+	sess.AddSummary(Item{Summary: "s1", FirstKeptEntry: 2, SummaryInfo: &SummaryInfo{Model: "test/small", Tokens: 10}})
+	addMessages("m5", "m6")
+	sess.AddSummary(Item{Summary: "s2", FirstKeptEntry: 5})
+	addMessages("m8")
+	return sess
+}
+
+// conversationContents returns the contents of the conversation sent to
+// the model.
+func conversationContents(sess *Session) []string {
+	var contents []string
+	for _, msg := range conversation(sess.GetMessages(agent.New("root", "You are a test agent"))) {
+		contents = append(contents, msg.Content)
+	}
+	return contents
+}
+
+func TestSummaries(t *testing.T) {
+	t.Parallel()
+
+	sess := summarizedSession(t)
+
+	summaries := sess.Summaries()
+	require.Len(t, summaries, 2)
+
+	assert.Equal(t, 4, summaries[0].Position)
+	assert.Equal(t, "s1", summaries[0].Summary)
+	assert.Equal(t, MessageRange{Start: 0, End: 2}, summaries[0].Covered)
+	assert.Equal(t, 2, summaries[0].Messages)
+	assert.Equal(t, "test/small", summaries[0].Model)
+	assert.Equal(t, int64(10), summaries[0].Tokens)
+
+	// The second summary covers the messages the first one kept verbatim,
+	// up to the ones it keeps itself.
+	assert.Equal(t, 7, summaries[1].Position)
+	assert.Equal(t, MessageRange{Start: 2, End: 5}, summaries[1].Covered)
+	assert.Equal(t, 2, summaries[1].Messages)
+}
+
+func TestSummaries_WithoutInfo(t *testing.T) {
+	t.Parallel()
+
+	sess := New(WithMessages([]Item{
+		NewMessageItem(UserMessage("m0")),
+		NewMessageItem(UserMessage("m1")),
+		{Summary: "s1"},
+		NewMessageItem(UserMessage("m3")),
+	}))
+
+	summaries := sess.Summaries()
+	require.Len(t, summaries, 1)
+	assert.Equal(t, MessageRange{Start: 0, End: 2}, summaries[0].Covered)
+	assert.Equal(t, 2, summaries[0].Messages)
+	assert.True(t, summaries[0].CreatedAt.IsZero())
+}
+
+func TestReplaceSummary(t *testing.T) {
+	t.Parallel()
+
+	sess := summarizedSession(t)
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	summary, err := sess.ReplaceSummary(4, Item{Summary: "better s1", Cost: 0.5, SummaryInfo: &SummaryInfo{Model: "test/large", CreatedAt: createdAt}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "better s1", summary.Summary)
+	assert.Equal(t, "test/large", summary.Model)
+	assert.Equal(t, createdAt, summary.CreatedAt)
+	assert.Equal(t, MessageRange{Start: 0, End: 2}, summary.Covered, "the summary covers the same messages")
+	assert.Equal(t, summary, sess.Summaries()[0])
+	assert.InDelta(t, 0.5, sess.Messages[4].Cost, 1e-9)
+
+	_, err = sess.ReplaceSummary(3, Item{Summary: "not a summary"})
+	require.Error(t, err)
+}
+
+func TestCondenseItems(t *testing.T) {
+	t.Parallel()
+
+	sess := summarizedSession(t)
+	condensed := New(WithMessages(condenseItems(sess.Messages, indexes(sess.Messages))))
+
+	// The model gets the same conversation.
+	assert.Equal(t, []string{"Session Summary: s2", "m5", "m6", "m8"}, conversationContents(sess))
+	assert.Equal(t, conversationContents(sess), conversationContents(condensed))
+
+	// The summaries are kept, the messages they cover are left out.
+	summaries := condensed.Summaries()
+	require.Len(t, summaries, 2)
+	assert.Equal(t, "s1", summaries[0].Summary)
+	assert.Equal(t, "test/small", summaries[0].Model)
+	assert.Equal(t, 2, summaries[0].Messages)
+	assert.Zero(t, summaries[0].Covered.Len())
+	assert.Equal(t, "s2", summaries[1].Summary)
+	assert.Zero(t, summaries[1].Covered.Len())
+	assert.Len(t, condensed.Messages, 5)
+
+	// Condensing is idempotent.
+	assert.Equal(t, condensed.Messages, condenseItems(condensed.Messages, indexes(condensed.Messages)))
+}
+
+func indexes(items []Item) []int {
+	positions := make([]int, len(items))
+	for i := range positions {
+		positions[i] = i
+	}
+	return positions
+}
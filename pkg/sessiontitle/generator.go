@@ -29,8 +29,9 @@ const (
 
 	// titleGenerationTimeout is the maximum time to wait for title generation.
 	// Title generation should be quick since we disable thinking and use low max_tokens.
-	// If the API is slow or hanging (e.g., due to server-side thinking), we should timeout.
-	titleGenerationTimeout = 30 * time.Second
+	// If the API is slow or hanging (e.g., due to server-side thinking), we should timeout
+	// rather than hold up the session or its shutdown.
+	titleGenerationTimeout = 10 * time.Second
 )
 
 // Generator generates session titles using a one-shot LLM completion.
@@ -126,7 +127,9 @@ func (g *Generator) Generate(ctx context.Context, sessionID string, userMessages
 			continue
 		}
 
-		// Drain the stream to collect the full title
+		// Drain the stream to collect the full title. Closing the stream when
+		// the context is done unblocks Recv for providers that don't watch it.
+		stopClose := context.AfterFunc(ctx, stream.Close)
 		var title strings.Builder
 		var streamErr error
 		for {
@@ -142,7 +145,12 @@ func (g *Generator) Generate(ctx context.Context, sessionID string, userMessages
 				title.WriteString(response.Choices[0].Delta.Content)
 			}
 		}
-		stream.Close()
+		if stopClose() {
+			stream.Close()
+		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
 
 		if streamErr != nil {
 			lastErr = streamErr
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"io"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -157,3 +159,56 @@ func TestGenerator_Generate_FallsBackOnEmptyOutput(t *testing.T) {
 	assert.Equal(t, 1, primary.calls)
 	assert.Equal(t, 1, fallback.calls)
 }
+
+// blockingStream blocks in Recv until it is closed, like a provider that
+// doesn't watch the context.
+type blockingStream struct {
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newBlockingStream() *blockingStream {
+	return &blockingStream{closed: make(chan struct{})}
+}
+
+func (s *blockingStream) Recv() (chat.MessageStreamResponse, error) {
+	<-s.closed
+	return chat.MessageStreamResponse{}, errors.New("stream closed")
+}
+
+func (s *blockingStream) Close() {
+	s.closeOnce.Do(func() { close(s.closed) })
+}
+
+func TestGenerator_Generate_Cancelled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	stream := newBlockingStream()
+	primary := &mockProvider{
+		id: "primary/hanging",
+		createFn: func() (chat.MessageStream, error) {
+			time.AfterFunc(10*time.Millisecond, cancel)
+			return stream, nil
+		},
+	}
+	fallback := &mockProvider{
+		id: "fallback/success",
+		createFn: func() (chat.MessageStream, error) {
+			return streamWithContent("My Title"), nil
+		},
+	}
+
+	title, err := New(primary, fallback).Generate(ctx, "sess-1", []string{"hello"})
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, title)
+	assert.Equal(t, 0, fallback.calls, "no fallback is tried once cancelled")
+
+	select {
+	case <-stream.closed:
+	default:
+		t.Fatal("the stream should be closed")
+	}
+}
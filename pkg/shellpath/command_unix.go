@@ -0,0 +1,9 @@
+//go:build !windows
+
+package shellpath
+
+import "os/exec"
+
+// SetCommandLine does nothing: command lines only need special quoting on
+// Windows.
+func SetCommandLine(*exec.Cmd, []string, string) {}
@@ -0,0 +1,17 @@
+package shellpath
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// SetCommandLine makes cmd, running command with its shell and argsPrefix,
+// pass the command line built by [WindowsCommandLine] rather than Go's
+// default quoting, which cmd.exe doesn't understand. Call it after setting
+// cmd.SysProcAttr.
+func SetCommandLine(cmd *exec.Cmd, argsPrefix []string, command string) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CmdLine = WindowsCommandLine(cmd.Args[0], argsPrefix, command)
+}
@@ -7,6 +7,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
 )
 
 // WindowsCmdExe returns the absolute path to cmd.exe on Windows using the
@@ -71,3 +72,66 @@ func defaultUnixShell() string {
 	}
 	return "/bin/sh"
 }
+
+// WindowsCommandLine returns the Windows command line running command with
+// shell and its argument prefix, as returned by [DetectWindowsShell].
+//
+// Windows passes a single command line to processes, which split it
+// themselves. PowerShell follows the Microsoft C runtime rules, which
+// [QuoteWindowsArg] implements, but cmd.exe doesn't know about backslash
+// escapes: it gets /S and the command between plain quotes, which it strips
+// before running the rest verbatim.
+func WindowsCommandLine(shell string, argsPrefix []string, command string) string {
+	args := []string{QuoteWindowsArg(shell)}
+	if isCmdExe(shell) {
+		args = append(args, "/S")
+		args = append(args, argsPrefix...)
+		args = append(args, `"`+command+`"`)
+		return strings.Join(args, " ")
+	}
+
+	for _, arg := range argsPrefix {
+		args = append(args, QuoteWindowsArg(arg))
+	}
+	args = append(args, QuoteWindowsArg(command))
+	return strings.Join(args, " ")
+}
+
+// QuoteWindowsArg quotes arg, if needed, so that programs splitting their
+// command line with the Microsoft C runtime rules (CommandLineToArgvW) get
+// it back unchanged.
+func QuoteWindowsArg(arg string) string {
+	if arg == "" {
+		return `""`
+	}
+	if !strings.ContainsAny(arg, " \t\n\v\"") {
+		return arg
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	backslashes := 0
+	for i := range len(arg) {
+		switch c := arg[i]; c {
+		case '\\':
+			backslashes++
+		case '"':
+			// Backslashes before a quote are escaped, as is the quote.
+			b.WriteString(strings.Repeat(`\`, backslashes+1))
+			backslashes = 0
+		default:
+			backslashes = 0
+		}
+		b.WriteByte(arg[i])
+	}
+	// So are the backslashes before the closing quote.
+	b.WriteString(strings.Repeat(`\`, backslashes))
+	b.WriteByte('"')
+	return b.String()
+}
+
+// isCmdExe reports whether shell is cmd.exe, whatever its directory.
+func isCmdExe(shell string) bool {
+	name := shell[strings.LastIndexAny(shell, `\/`)+1:]
+	return strings.EqualFold(name, "cmd.exe") || strings.EqualFold(name, "cmd")
+}
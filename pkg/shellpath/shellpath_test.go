@@ -101,3 +101,72 @@ func TestDetectWindowsShell_FallbackUsesAbsolutePath(t *testing.T) {
 		t.Errorf("DetectWindowsShell() args = %v, want [/C]", args)
 	}
 }
+
+func TestQuoteWindowsArg(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{arg: "", want: `""`},
+		{arg: "dir", want: "dir"},
+		{arg: `C:\Program Files\app.exe`, want: `"C:\Program Files\app.exe"`},
+		{arg: `C:\src\`, want: `C:\src\`},
+		{arg: `C:\my src\`, want: `"C:\my src\\"`},
+		{arg: `say "hi"`, want: `"say \"hi\""`},
+		{arg: `a\"b`, want: `"a\\\"b"`},
+		{arg: "a\tb", want: "\"a\tb\""},
+	}
+
+	for _, tt := range tests {
+		if got := QuoteWindowsArg(tt.arg); got != tt.want {
+			t.Errorf("QuoteWindowsArg(%q) = %s, want %s", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestWindowsCommandLine(t *testing.T) {
+	tests := []struct {
+		name       string
+		shell      string
+		argsPrefix []string
+		command    string
+		want       string
+	}{
+		{
+			name:       "cmd.exe runs the command verbatim",
+			shell:      `C:\Windows\System32\cmd.exe`,
+			argsPrefix: []string{"/C"},
+			command:    `echo "hello world" & dir "C:\Program Files\"`,
+			want:       `C:\Windows\System32\cmd.exe /S /C "echo "hello world" & dir "C:\Program Files\""`,
+		},
+		{
+			name:       "cmd.exe in a directory with spaces",
+			shell:      `C:\My Windows\System32\CMD.EXE`,
+			argsPrefix: []string{"/C"},
+			command:    "ver",
+			want:       `"C:\My Windows\System32\CMD.EXE" /S /C "ver"`,
+		},
+		{
+			name:       "powershell",
+			shell:      `C:\Program Files\PowerShell\7\pwsh.exe`,
+			argsPrefix: []string{"-NoProfile", "-NonInteractive", "-Command"},
+			command:    `Write-Output "hello world"; Get-ChildItem C:\src\`,
+			want:       `"C:\Program Files\PowerShell\7\pwsh.exe" -NoProfile -NonInteractive -Command "Write-Output \"hello world\"; Get-ChildItem C:\src\\"`,
+		},
+		{
+			name:       "powershell without spaces",
+			shell:      `C:\Windows\System32\WindowsPowerShell\v1.0\powershell.exe`,
+			argsPrefix: []string{"-NoProfile", "-NonInteractive", "-Command"},
+			command:    "Get-Date",
+			want:       `C:\Windows\System32\WindowsPowerShell\v1.0\powershell.exe -NoProfile -NonInteractive -Command Get-Date`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WindowsCommandLine(tt.shell, tt.argsPrefix, tt.command); got != tt.want {
+				t.Errorf("WindowsCommandLine() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package team
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxBlackboardKeyLength is the maximum length, in bytes, of a key.
+	MaxBlackboardKeyLength = 128
+	// MaxBlackboardValueSize is the maximum size, in bytes, of a value.
+	MaxBlackboardValueSize = 16 * 1024
+	// MaxBlackboardEntries is the maximum number of keys a blackboard holds.
+	MaxBlackboardEntries = 100
+)
+
+// BlackboardEntry is a value written to the shared context of a team.
+type BlackboardEntry struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// Author is the name of the agent that wrote the value.
+	Author    string    `json:"author,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// ExpiresAt is zero if the value never expires.
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+}
+
+func (e BlackboardEntry) expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && !now.Before(e.ExpiresAt)
+}
+
+// Blackboard is a key-value store shared by all the agents of a team, so
+// that information survives task transfers between agents.
+// It is safe for concurrent use.
+type Blackboard struct {
+	mu      sync.RWMutex
+	entries map[string]BlackboardEntry
+	now     func() time.Time
+}
+
+// NewBlackboard creates an empty blackboard.
+func NewBlackboard() *Blackboard {
+	return &Blackboard{
+		entries: make(map[string]BlackboardEntry),
+		now:     time.Now,
+	}
+}
+
+// Set writes a value. A ttl of zero keeps the value until it's overwritten.
+func (b *Blackboard) Set(key, value, author string, ttl time.Duration) (BlackboardEntry, error) {
+	switch {
+	case strings.TrimSpace(key) == "":
+		return BlackboardEntry{}, errors.New("key must not be empty")
+	case len(key) > MaxBlackboardKeyLength:
+		return BlackboardEntry{}, fmt.Errorf("key is too long: %d bytes, the maximum is %d", len(key), MaxBlackboardKeyLength)
+	case len(value) > MaxBlackboardValueSize:
+		return BlackboardEntry{}, fmt.Errorf("value of %q is too large: %d bytes, the maximum is %d", key, len(value), MaxBlackboardValueSize)
+	case ttl < 0:
+		return BlackboardEntry{}, errors.New("ttl must not be negative")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.removeExpired(now)
+
+	if _, exists := b.entries[key]; !exists && len(b.entries) >= MaxBlackboardEntries {
+		return BlackboardEntry{}, fmt.Errorf("shared context is full: it already holds %d keys", MaxBlackboardEntries)
+	}
+
+	entry := BlackboardEntry{
+		Key:       key,
+		Value:     value,
+		Author:    author,
+		UpdatedAt: now,
+	}
+	if ttl > 0 {
+		entry.ExpiresAt = now.Add(ttl)
+	}
+	b.entries[key] = entry
+
+	return entry, nil
+}
+
+// Get returns the value of a key, unless it doesn't exist or has expired.
+func (b *Blackboard) Get(key string) (BlackboardEntry, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	entry, ok := b.entries[key]
+	if !ok || entry.expired(b.now()) {
+		return BlackboardEntry{}, false
+	}
+	return entry, true
+}
+
+// List returns the entries that haven't expired, sorted by key.
+func (b *Blackboard) List() []BlackboardEntry {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	now := b.now()
+	var entries []BlackboardEntry
+	for _, key := range slices.Sorted(maps.Keys(b.entries)) {
+		if entry := b.entries[key]; !entry.expired(now) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Prompt formats the entries as a system message section, or returns an
+// empty string if the blackboard is empty.
+func (b *Blackboard) Prompt() string {
+	entries := b.List()
+	if len(entries) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Shared context: values written by the agents of your team. Use context_get, context_list and context_set to read and update them.\n")
+	for _, entry := range entries {
+		fmt.Fprintf(&sb, "\n<shared_context key=%q>\n%s\n</shared_context>", entry.Key, entry.Value)
+	}
+	return sb.String()
+}
+
+func (b *Blackboard) removeExpired(now time.Time) {
+	maps.DeleteFunc(b.entries, func(_ string, entry BlackboardEntry) bool {
+		return entry.expired(now)
+	})
+}
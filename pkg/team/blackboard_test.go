@@ -0,0 +1,145 @@
+package team
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlackboard_SetGetList(t *testing.T) {
+	t.Parallel()
+
+	b := NewBlackboard()
+
+	_, err := b.Set("plan", "1. design 2. build", "root", 0)
+	require.NoError(t, err)
+	_, err = b.Set("decision", "use postgres", "architect", 0)
+	require.NoError(t, err)
+
+	entry, ok := b.Get("plan")
+	require.True(t, ok)
+	assert.Equal(t, "1. design 2. build", entry.Value)
+	assert.Equal(t, "root", entry.Author)
+	assert.True(t, entry.ExpiresAt.IsZero())
+
+	_, ok = b.Get("missing")
+	assert.False(t, ok)
+
+	entries := b.List()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "decision", entries[0].Key)
+	assert.Equal(t, "plan", entries[1].Key)
+
+	// Overwrite
+	_, err = b.Set("plan", "1. build", "developer", 0)
+	require.NoError(t, err)
+	entry, _ = b.Get("plan")
+	assert.Equal(t, "1. build", entry.Value)
+	assert.Equal(t, "developer", entry.Author)
+	assert.Len(t, b.List(), 2)
+}
+
+func TestBlackboard_TTL(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	b := NewBlackboard()
+	b.now = func() time.Time { return now }
+
+	_, err := b.Set("status", "building", "root", time.Minute)
+	require.NoError(t, err)
+	_, err = b.Set("plan", "ship it", "root", 0)
+	require.NoError(t, err)
+
+	_, ok := b.Get("status")
+	assert.True(t, ok)
+
+	now = now.Add(time.Minute)
+
+	_, ok = b.Get("status")
+	assert.False(t, ok)
+	entries := b.List()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "plan", entries[0].Key)
+}
+
+func TestBlackboard_Limits(t *testing.T) {
+	t.Parallel()
+
+	b := NewBlackboard()
+
+	_, err := b.Set("", "value", "root", 0)
+	require.Error(t, err)
+
+	_, err = b.Set(strings.Repeat("k", MaxBlackboardKeyLength+1), "value", "root", 0)
+	require.ErrorContains(t, err, "key is too long")
+
+	_, err = b.Set("big", strings.Repeat("v", MaxBlackboardValueSize+1), "root", 0)
+	require.ErrorContains(t, err, "too large")
+
+	_, err = b.Set("key", "value", "root", -time.Second)
+	require.Error(t, err)
+
+	for i := range MaxBlackboardEntries {
+		_, err = b.Set(strings.Repeat("k", i+1), "value", "root", 0)
+		require.NoError(t, err)
+	}
+	_, err = b.Set("one-more", "value", "root", 0)
+	require.ErrorContains(t, err, "full")
+
+	// Existing keys can still be updated.
+	_, err = b.Set("k", "new value", "root", 0)
+	require.NoError(t, err)
+}
+
+func TestBlackboard_Prompt(t *testing.T) {
+	t.Parallel()
+
+	b := NewBlackboard()
+	assert.Empty(t, b.Prompt())
+
+	_, err := b.Set("plan", "ship it", "root", 0)
+	require.NoError(t, err)
+
+	prompt := b.Prompt()
+	assert.True(t, strings.HasPrefix(prompt, "Shared context:"))
+	assert.Contains(t, prompt, "<shared_context key=\"plan\">\nship it\n</shared_context>")
+}
+
+func TestBlackboard_Concurrency(t *testing.T) {
+	t.Parallel()
+
+	b := NewBlackboard()
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Go(func() {
+			key := strings.Repeat("k", i+1)
+			_, err := b.Set(key, "value", "agent", 0)
+			assert.NoError(t, err)
+			_, ok := b.Get(key)
+			assert.True(t, ok)
+			b.List()
+		})
+	}
+	wg.Wait()
+
+	assert.Len(t, b.List(), 10)
+}
+
+func TestTeam_Blackboard(t *testing.T) {
+	t.Parallel()
+
+	first := New()
+	require.NotNil(t, first.Blackboard())
+
+	second := New()
+	assert.NotSame(t, first.Blackboard(), second.Blackboard())
+
+	second.SetBlackboard(first.Blackboard())
+	assert.Same(t, first.Blackboard(), second.Blackboard())
+}
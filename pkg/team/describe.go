@@ -0,0 +1,198 @@
+package team
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// Description describes a team: its agents, their toolsets and how they
+// delegate to each other. It's meant to be serialized, e.g. for external
+// UIs or to diff configurations.
+type Description struct {
+	// DefaultAgent is the name of the agent a session starts with.
+	DefaultAgent string             `json:"default_agent"`
+	Agents       []AgentDescription `json:"agents"`
+}
+
+// AgentDescription describes an agent of a team.
+type AgentDescription struct {
+	Name           string               `json:"name"`
+	Description    string               `json:"description,omitempty"`
+	Model          string               `json:"model,omitempty"`
+	FallbackModels []string             `json:"fallback_models,omitempty"`
+	Instruction    string               `json:"instruction,omitempty"`
+	ToolSets       []ToolSetDescription `json:"toolsets,omitempty"`
+	SubAgents      []string             `json:"sub_agents,omitempty"`
+	Handoffs       []string             `json:"handoffs,omitempty"`
+	// RAG are the names of the RAG tools of the agent.
+	RAG []string `json:"rag,omitempty"`
+}
+
+// ToolSetDescription describes a toolset of an agent.
+type ToolSetDescription struct {
+	// Type is the type the toolset was configured with, e.g. "mcp", or ""
+	// for the toolsets added by the runtime.
+	Type        string            `json:"type,omitempty"`
+	Name        string            `json:"name,omitempty"`
+	Description string            `json:"description"`
+	Tools       []ToolDescription `json:"tools,omitempty"`
+	// Error is why the tools of the toolset couldn't be listed.
+	Error string `json:"error,omitempty"`
+}
+
+// ToolDescription describes a tool of a toolset.
+type ToolDescription struct {
+	Name     string `json:"name"`
+	Category string `json:"category,omitempty"`
+	ReadOnly bool   `json:"read_only"`
+}
+
+type describeOptions struct {
+	maxInstructionLength int
+	listTools            bool
+}
+
+// DescribeOpt configures Describe.
+type DescribeOpt func(*describeOptions)
+
+// WithMaxInstructionLength truncates the instructions of the agents to n
+// characters. Zero or less keeps them whole, the default.
+func WithMaxInstructionLength(n int) DescribeOpt {
+	return func(o *describeOptions) {
+		o.maxInstructionLength = n
+	}
+}
+
+// WithoutTools doesn't list the tools of the toolsets, so that they aren't
+// started.
+func WithoutTools() DescribeOpt {
+	return func(o *describeOptions) {
+		o.listTools = false
+	}
+}
+
+// Describe describes the team. Unless WithoutTools is given, the toolsets
+// are started to list their tools: callers should stop them with
+// StopToolSets. A toolset that fails doesn't fail the description, its error
+// is reported instead.
+func (t *Team) Describe(ctx context.Context, opts ...DescribeOpt) Description {
+	o := describeOptions{listTools: true}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var desc Description
+	if a, err := t.DefaultAgent(); err == nil {
+		desc.DefaultAgent = a.Name()
+	}
+	for _, a := range t.agents {
+		desc.Agents = append(desc.Agents, describeAgent(ctx, a, &o))
+	}
+	return desc
+}
+
+func describeAgent(ctx context.Context, a *agent.Agent, o *describeOptions) AgentDescription {
+	desc := AgentDescription{
+		Name:        a.Name(),
+		Description: a.Description(),
+		Instruction: truncate(a.Instruction(), o.maxInstructionLength),
+		SubAgents:   agentNames(a.SubAgents()),
+		Handoffs:    agentNames(a.Handoffs()),
+	}
+	// Agents without a model, e.g. ones only handing off, have none to describe.
+	if models := a.ConfiguredModels(); len(models) > 0 {
+		desc.Model = models[0].ID()
+	}
+	for _, model := range a.FallbackModels() {
+		desc.FallbackModels = append(desc.FallbackModels, model.ID())
+	}
+
+	for _, ts := range a.ToolSets() {
+		tsDesc := ToolSetDescription{
+			Type:        tools.ToolSetType(ts),
+			Name:        tools.ToolSetName(ts),
+			Description: tools.DescribeToolSet(ts),
+		}
+		if o.listTools {
+			toolList, err := toolSetTools(ctx, ts)
+			if err != nil {
+				tsDesc.Error = err.Error()
+			}
+			for _, tool := range a.FilterTools(toolList) {
+				tsDesc.Tools = append(tsDesc.Tools, ToolDescription{
+					Name:     tool.Name,
+					Category: tool.Category,
+					ReadOnly: tool.Annotations.ReadOnlyHint,
+				})
+				if tsDesc.Type == "rag" {
+					desc.RAG = append(desc.RAG, tool.Name)
+				}
+			}
+		}
+		desc.ToolSets = append(desc.ToolSets, tsDesc)
+	}
+
+	return desc
+}
+
+func toolSetTools(ctx context.Context, ts tools.ToolSet) ([]tools.Tool, error) {
+	if startable, ok := ts.(*tools.StartableToolSet); ok {
+		if err := startable.Start(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return ts.Tools(ctx)
+}
+
+func agentNames(agents []*agent.Agent) []string {
+	var names []string
+	for _, a := range agents {
+		names = append(names, a.Name())
+	}
+	return names
+}
+
+func truncate(s string, n int) string {
+	if n <= 0 {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
+
+// DOT returns the delegation topology of the team in the DOT language of
+// Graphviz: an edge per sub-agent, and a dashed edge per handoff. The
+// default agent is drawn in bold.
+func (d Description) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph team {\n")
+	for _, a := range d.Agents {
+		label := a.Name
+		if a.Model != "" {
+			label += "\n" + a.Model
+		}
+		fmt.Fprintf(&b, "  %s [label=%s", strconv.Quote(a.Name), strconv.Quote(label))
+		if a.Name == d.DefaultAgent {
+			b.WriteString(", style=bold")
+		}
+		b.WriteString("];\n")
+	}
+	for _, a := range d.Agents {
+		for _, sub := range a.SubAgents {
+			fmt.Fprintf(&b, "  %s -> %s;\n", strconv.Quote(a.Name), strconv.Quote(sub))
+		}
+		for _, to := range a.Handoffs {
+			fmt.Fprintf(&b, "  %s -> %s [style=dashed, label=\"handoff\"];\n", strconv.Quote(a.Name), strconv.Quote(to))
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
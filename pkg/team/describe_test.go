@@ -0,0 +1,83 @@
+package team
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/model/provider/fake"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+type describedToolSet struct {
+	tools []tools.Tool
+	err   error
+}
+
+func (ts *describedToolSet) Tools(context.Context) ([]tools.Tool, error) {
+	return ts.tools, ts.err
+}
+
+func (ts *describedToolSet) Describe() string    { return "stub" }
+func (ts *describedToolSet) ToolSetType() string { return "filesystem" }
+
+func TestDescribe(t *testing.T) {
+	t.Parallel()
+
+	readFile := tools.Tool{Name: "read_file", Category: "filesystem", Annotations: tools.ToolAnnotations{ReadOnlyHint: true}}
+	writeFile := tools.Tool{Name: "write_file", Category: "filesystem"}
+
+	helper := agent.New("helper", "Help the root agent with everything.",
+		agent.WithDescription("Helps"),
+		agent.WithToolSets(&describedToolSet{err: errors.New("boom")}),
+	)
+	reviewer := agent.New("reviewer", "Review")
+	root := agent.New("root", "Coordinate",
+		agent.WithModel(fake.NewScriptedProvider(t, "openai/gpt-4o")),
+		agent.WithToolSets(&describedToolSet{tools: []tools.Tool{readFile, writeFile}}),
+		agent.WithSubAgents(helper),
+		agent.WithHandoffs(reviewer),
+	)
+	tm := New(WithAgents(root, helper, reviewer))
+
+	desc := tm.Describe(t.Context(), WithMaxInstructionLength(4))
+	assert.Equal(t, "root", desc.DefaultAgent)
+	require.Len(t, desc.Agents, 3)
+
+	assert.Equal(t, AgentDescription{
+		Name:        "root",
+		Model:       "openai/gpt-4o",
+		Instruction: "Coor…",
+		ToolSets: []ToolSetDescription{{
+			Type:        "filesystem",
+			Description: "stub",
+			Tools: []ToolDescription{
+				{Name: "read_file", Category: "filesystem", ReadOnly: true},
+				{Name: "write_file", Category: "filesystem"},
+			},
+		}},
+		SubAgents: []string{"helper"},
+		Handoffs:  []string{"reviewer"},
+	}, desc.Agents[0])
+
+	require.Len(t, desc.Agents[1].ToolSets, 1)
+	assert.Equal(t, "boom", desc.Agents[1].ToolSets[0].Error)
+	assert.Equal(t, "Helps", desc.Agents[1].Description)
+
+	withoutTools := tm.Describe(t.Context(), WithoutTools())
+	assert.Empty(t, withoutTools.Agents[0].ToolSets[0].Tools)
+	assert.Equal(t, "Coordinate", withoutTools.Agents[0].Instruction)
+
+	assert.Equal(t, `digraph team {
+  "root" [label="root\nopenai/gpt-4o", style=bold];
+  "helper" [label="helper"];
+  "reviewer" [label="reviewer"];
+  "root" -> "helper";
+  "root" -> "reviewer" [style=dashed, label="handoff"];
+}
+`, desc.DOT())
+}
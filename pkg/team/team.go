@@ -4,16 +4,36 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
 	"strings"
+	"time"
 
 	"github.com/docker/docker-agent/pkg/agent"
 	"github.com/docker/docker-agent/pkg/config/types"
+	"github.com/docker/docker-agent/pkg/model/provider"
 	"github.com/docker/docker-agent/pkg/permissions"
+	"github.com/docker/docker-agent/pkg/tools"
 )
 
 type Team struct {
 	agents      []*agent.Agent
 	permissions *permissions.Checker
+	blackboard  *Blackboard
+	compaction  CompactionConfig
+	redactor    *tools.Redactor
+}
+
+// CompactionConfig configures how the runtime compacts the sessions of the
+// team's agents.
+type CompactionConfig struct {
+	// Model generates the summaries. The model of the agent does when nil,
+	// or when Model fails.
+	Model provider.Provider
+	// Prompt replaces the instructions of the summarization when set.
+	Prompt string
+	// KeepLastMessages is the number of most recent messages kept verbatim
+	// after the summary. Zero keeps the messages of the last 20,000 tokens.
+	KeepLastMessages int
 }
 
 type Opt func(*Team)
@@ -30,8 +50,25 @@ func WithPermissions(checker *permissions.Checker) Opt {
 	}
 }
 
+// WithCompaction sets how the sessions of the team's agents are compacted.
+func WithCompaction(compaction CompactionConfig) Opt {
+	return func(t *Team) {
+		t.compaction = compaction
+	}
+}
+
+// WithRedactor sets how the secrets in the outputs of the tools of the
+// team's agents are masked.
+func WithRedactor(redactor *tools.Redactor) Opt {
+	return func(t *Team) {
+		t.redactor = redactor
+	}
+}
+
 func New(opts ...Opt) *Team {
-	t := &Team{}
+	t := &Team{
+		blackboard: NewBlackboard(),
+	}
 	for _, opt := range opts {
 		opt(t)
 	}
@@ -112,14 +149,63 @@ func (t *Team) Size() int {
 	return len(t.agents)
 }
 
+// toolSetsStopTimeout bounds StopToolSets when ctx has no deadline.
+const toolSetsStopTimeout = 30 * time.Second
+
+// toolSetsStopGracePeriod is given to the toolsets to stop when ctx is
+// already done, e.g. when stopping after Ctrl+C.
+const toolSetsStopGracePeriod = 5 * time.Second
+
+// StopToolSets stops the started toolsets of all the agents. A toolset
+// shared by several agents is only stopped once. Every toolset is stopped
+// even if some fail; the errors are joined.
+//
+// The toolsets are always given time to stop their processes (MCP and LSP
+// servers, background jobs...) so that they don't outlive the team: when
+// ctx is already done they get a short grace period, and the whole stop is
+// bounded when ctx has no deadline.
 func (t *Team) StopToolSets(ctx context.Context) error {
-	for _, agent := range t.agents {
-		if err := agent.StopToolSets(ctx); err != nil {
-			return fmt.Errorf("failed to stop tool sets: %w", err)
+	ctx, cancel := stopContext(ctx)
+	defer cancel()
+
+	stopped := make(map[tools.ToolSet]bool)
+
+	var errs []error
+	for _, a := range t.agents {
+		for _, ts := range a.ToolSets() {
+			startable, ok := ts.(*tools.StartableToolSet)
+			if !ok || !startable.IsStarted() {
+				continue
+			}
+
+			// Agents wrap the toolsets they share in their own
+			// StartableToolSet: deduplicate on the wrapped toolset.
+			inner := startable.Unwrap()
+			if reflect.ValueOf(inner).Comparable() {
+				if stopped[inner] {
+					continue
+				}
+				stopped[inner] = true
+			}
+
+			if err := startable.Stop(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("failed to stop toolset %s of agent %s: %w", tools.DescribeToolSet(ts), a.Name(), err))
+			}
 		}
 	}
 
-	return nil
+	return errors.Join(errs...)
+}
+
+// stopContext returns the context to stop the toolsets with.
+func stopContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx.Err() != nil {
+		return context.WithTimeout(context.WithoutCancel(ctx), toolSetsStopGracePeriod)
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		return context.WithTimeout(ctx, toolSetsStopTimeout)
+	}
+	return context.WithCancel(ctx)
 }
 
 // Permissions returns the permission checker for this team.
@@ -128,9 +214,31 @@ func (t *Team) Permissions() *permissions.Checker {
 	return t.permissions
 }
 
+// Compaction returns how the sessions of the team's agents are compacted.
+func (t *Team) Compaction() CompactionConfig {
+	return t.compaction
+}
+
+// Redactor returns how the secrets in the outputs of the tools of the
+// team's agents are masked, or nil for the runtime's default.
+func (t *Team) Redactor() *tools.Redactor {
+	return t.redactor
+}
+
 // SetPermissions replaces the team's permission checker.
 // This is used to merge additional permission sources (e.g. user-level global
 // permissions) into the team's checker after construction.
 func (t *Team) SetPermissions(checker *permissions.Checker) {
 	t.permissions = checker
 }
+
+// Blackboard returns the shared context of the team.
+func (t *Team) Blackboard() *Blackboard {
+	return t.blackboard
+}
+
+// SetBlackboard replaces the team's shared context. This is used to keep
+// the shared context when the team is rebuilt from a reloaded configuration.
+func (t *Team) SetBlackboard(blackboard *Blackboard) {
+	t.blackboard = blackboard
+}
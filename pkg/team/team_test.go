@@ -0,0 +1,49 @@
+package team
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// stopRecordingToolSet records the context it is stopped with.
+type stopRecordingToolSet struct {
+	stopErr     error
+	hasDeadline bool
+}
+
+func (ts *stopRecordingToolSet) Start(context.Context) error { return nil }
+
+func (ts *stopRecordingToolSet) Stop(ctx context.Context) error {
+	ts.stopErr = ctx.Err()
+	_, ts.hasDeadline = ctx.Deadline()
+	return nil
+}
+
+func (ts *stopRecordingToolSet) Tools(context.Context) ([]tools.Tool, error) { return nil, nil }
+
+func TestStopToolSets_CancelledContext(t *testing.T) {
+	t.Parallel()
+
+	ts := &stopRecordingToolSet{}
+	root := agent.New("root", "Coordinate", agent.WithToolSets(ts))
+	tm := New(WithAgents(root))
+
+	startable, ok := root.ToolSets()[0].(*tools.StartableToolSet)
+	require.True(t, ok)
+	require.NoError(t, startable.Start(t.Context()))
+
+	// Stopping after Ctrl+C still gives the toolsets time to stop their
+	// processes, within a deadline.
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	require.NoError(t, tm.StopToolSets(ctx))
+
+	require.NoError(t, ts.stopErr)
+	assert.True(t, ts.hasDeadline)
+}
@@ -0,0 +1,43 @@
+package teamloader
+
+import (
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// WithConcurrency wraps a toolset so that it declares how many calls to its
+// tools may run at once. -1 lifts the limit, zero keeps the one the toolset
+// declares.
+func WithConcurrency(inner tools.ToolSet, concurrency int) tools.ToolSet {
+	if concurrency == 0 {
+		return inner
+	}
+
+	return &concurrencyToolset{
+		ToolSet:     inner,
+		concurrency: tools.Concurrency(max(concurrency, 0)),
+	}
+}
+
+type concurrencyToolset struct {
+	tools.ToolSet
+
+	concurrency tools.Concurrency
+}
+
+var (
+	_ tools.ConcurrencyDeclarer = (*concurrencyToolset)(nil)
+	_ tools.Instructable        = (*concurrencyToolset)(nil)
+	_ tools.Unwrapper           = (*concurrencyToolset)(nil)
+)
+
+func (t *concurrencyToolset) Unwrap() tools.ToolSet {
+	return t.ToolSet
+}
+
+func (t *concurrencyToolset) Instructions() string {
+	return tools.GetInstructions(t.ToolSet)
+}
+
+func (t *concurrencyToolset) Concurrency() tools.Concurrency {
+	return t.concurrency
+}
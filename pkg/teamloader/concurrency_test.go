@@ -0,0 +1,26 @@
+package teamloader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/docker/docker-agent/pkg/tools"
+	"github.com/docker/docker-agent/pkg/tools/builtin"
+)
+
+func TestWithConcurrency_Zero(t *testing.T) {
+	inner := &mockToolSet{}
+
+	assert.Same(t, inner, WithConcurrency(inner, 0))
+}
+
+func TestWithConcurrency_OverridesDeclaredConcurrency(t *testing.T) {
+	assert.Equal(t, tools.Serial, tools.ToolSetConcurrency(WithConcurrency(&mockToolSet{}, 1)))
+	assert.Equal(t, tools.Concurrency(4), tools.ToolSetConcurrency(WithConcurrency(&mockToolSet{}, 4)))
+
+	// -1 lifts the limit of serial toolsets.
+	lsp := builtin.NewLSPTool("gopls", nil, nil, "")
+	assert.Equal(t, tools.Serial, tools.ToolSetConcurrency(lsp))
+	assert.Equal(t, tools.Concurrent, tools.ToolSetConcurrency(WithConcurrency(lsp, -1)))
+}
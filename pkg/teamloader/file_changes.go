@@ -0,0 +1,167 @@
+package teamloader
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"path/filepath"
+	"slices"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// maxTrackedFiles is the number of files above which a directory isn't
+// tracked by WithFileChangeTracking: listing them would slow every call.
+const maxTrackedFiles = 20_000
+
+// errTooManyFiles is returned by listFiles when the directory has more than
+// maxTrackedFiles files.
+var errTooManyFiles = errors.New("too many files to track")
+
+// WithFileChangeTracking wraps a toolset whose tools change files without
+// reporting it, e.g. the shell: the files of dir are listed before and after
+// each call, and the differences are reported to the tools.FileChangeReporter
+// of the call. This is a heuristic: files are compared by size and
+// modification time, the VCS directories are skipped, and directories with
+// too many files aren't tracked. The changes can't be reverted since the
+// files aren't saved before the call.
+func WithFileChangeTracking(inner tools.ToolSet, dir string, enabled bool) tools.ToolSet {
+	if !enabled {
+		return inner
+	}
+
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+
+	return &fileChangeTrackingToolset{
+		ToolSet: inner,
+		dir:     dir,
+	}
+}
+
+type fileChangeTrackingToolset struct {
+	tools.ToolSet
+
+	dir string
+}
+
+var (
+	_ tools.Instructable = (*fileChangeTrackingToolset)(nil)
+	_ tools.Unwrapper    = (*fileChangeTrackingToolset)(nil)
+)
+
+func (t *fileChangeTrackingToolset) Unwrap() tools.ToolSet {
+	return t.ToolSet
+}
+
+func (t *fileChangeTrackingToolset) Instructions() string {
+	return tools.GetInstructions(t.ToolSet)
+}
+
+func (t *fileChangeTrackingToolset) Tools(ctx context.Context) ([]tools.Tool, error) {
+	innerTools, err := t.ToolSet.Tools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]tools.Tool, len(innerTools))
+	for i, tool := range innerTools {
+		if tool.Handler != nil {
+			tool.Handler = t.trackHandler(tool.Handler)
+		}
+		result[i] = tool
+	}
+
+	return result, nil
+}
+
+func (t *fileChangeTrackingToolset) trackHandler(handler tools.ToolHandler) tools.ToolHandler {
+	return func(ctx context.Context, toolCall tools.ToolCall) (*tools.ToolCallResult, error) {
+		if tools.FileChangeReporterFromContext(ctx) == nil {
+			return handler(ctx, toolCall)
+		}
+
+		before, err := listFiles(t.dir)
+		if err != nil {
+			slog.Debug("Not tracking the file changes of the tool call", "tool", toolCall.Function.Name, "dir", t.dir, "error", err)
+			return handler(ctx, toolCall)
+		}
+
+		res, handlerErr := handler(ctx, toolCall)
+
+		after, err := listFiles(t.dir)
+		if err != nil {
+			slog.Debug("Not tracking the file changes of the tool call", "tool", toolCall.Function.Name, "dir", t.dir, "error", err)
+			return res, handlerErr
+		}
+		for _, change := range diffFiles(before, after) {
+			tools.ReportFileChange(ctx, change)
+		}
+
+		return res, handlerErr
+	}
+}
+
+// fileStamp tells whether a file changed between two listings.
+type fileStamp struct {
+	size    int64
+	modTime int64
+}
+
+// listFiles lists the regular files under dir, by path.
+func listFiles(dir string) (map[string]fileStamp, error) {
+	files := make(map[string]fileStamp)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Skip what can't be read rather than giving up.
+			if d != nil && d.IsDir() && path != dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			switch d.Name() {
+			case ".git", ".hg", ".svn":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files[path] = fileStamp{size: info.Size(), modTime: info.ModTime().UnixNano()}
+		if len(files) > maxTrackedFiles {
+			return errTooManyFiles
+		}
+		return nil
+	})
+	return files, err
+}
+
+// diffFiles returns the changes between two listings, sorted by path.
+func diffFiles(before, after map[string]fileStamp) []tools.FileChange {
+	var changes []tools.FileChange
+	for _, path := range slices.Sorted(maps.Keys(after)) {
+		stamp, existed := before[path]
+		switch {
+		case !existed:
+			changes = append(changes, tools.FileChange{Path: path, Type: tools.FileCreated})
+		case stamp != after[path]:
+			changes = append(changes, tools.FileChange{Path: path, Type: tools.FileModified})
+		}
+	}
+	for _, path := range slices.Sorted(maps.Keys(before)) {
+		if _, exists := after[path]; !exists {
+			changes = append(changes, tools.FileChange{Path: path, Type: tools.FileDeleted})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,70 @@
+package teamloader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+type recordingFileChangeReporter struct {
+	changes []tools.FileChange
+}
+
+func (r *recordingFileChangeReporter) WillChange(string) {}
+
+func (r *recordingFileChangeReporter) Changed(change tools.FileChange) {
+	r.changes = append(r.changes, change)
+}
+
+func TestWithFileChangeTracking_Disabled(t *testing.T) {
+	inner := &mockToolSet{}
+
+	assert.Same(t, inner, WithFileChangeTracking(inner, t.TempDir(), false))
+}
+
+func TestWithFileChangeTracking_ReportsChanges(t *testing.T) {
+	dir := t.TempDir()
+	modified := filepath.Join(dir, "modified.txt")
+	deleted := filepath.Join(dir, "deleted.txt")
+	created := filepath.Join(dir, "sub", "created.txt")
+	require.NoError(t, os.WriteFile(modified, []byte("before"), 0o644))
+	require.NoError(t, os.WriteFile(deleted, []byte("gone soon"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".git"), 0o755))
+
+	inner := &mockToolSet{
+		toolsFunc: func(_ context.Context) ([]tools.Tool, error) {
+			return []tools.Tool{{
+				Name: "shell",
+				Handler: func(context.Context, tools.ToolCall) (*tools.ToolCallResult, error) {
+					require.NoError(t, os.WriteFile(modified, []byte("after, longer"), 0o644))
+					require.NoError(t, os.Remove(deleted))
+					require.NoError(t, os.MkdirAll(filepath.Dir(created), 0o755))
+					require.NoError(t, os.WriteFile(created, []byte("new"), 0o644))
+					require.NoError(t, os.WriteFile(filepath.Join(dir, ".git", "index"), []byte("ignored"), 0o644))
+					return tools.ResultSuccess("done"), nil
+				},
+			}}, nil
+		},
+	}
+
+	result, err := WithFileChangeTracking(inner, dir, true).Tools(t.Context())
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+
+	reporter := &recordingFileChangeReporter{}
+	res, err := result[0].Handler(tools.WithFileChangeReporter(t.Context(), reporter), tools.ToolCall{})
+	require.NoError(t, err)
+	assert.Equal(t, "done", res.Output)
+
+	assert.Equal(t, []tools.FileChange{
+		{Path: modified, Type: tools.FileModified},
+		{Path: created, Type: tools.FileCreated},
+		{Path: deleted, Type: tools.FileDeleted},
+	}, reporter.changes)
+}
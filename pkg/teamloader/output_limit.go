@@ -0,0 +1,58 @@
+package teamloader
+
+import (
+	"context"
+
+	"github.com/docker/docker-agent/pkg/config/latest"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// WithOutputLimit wraps a toolset so that every tool it produces carries the
+// given limit on the size of its outputs sent to the model.
+func WithOutputLimit(inner tools.ToolSet, limit *latest.ToolOutputLimit) tools.ToolSet {
+	if limit == nil || (limit.MaxBytes == 0 && limit.Truncate == "") {
+		return inner
+	}
+
+	return &outputLimitToolset{
+		ToolSet: inner,
+		limit: tools.OutputLimit{
+			MaxBytes: limit.MaxBytes,
+			Policy:   tools.TruncatePolicy(limit.Truncate),
+		},
+	}
+}
+
+type outputLimitToolset struct {
+	tools.ToolSet
+
+	limit tools.OutputLimit
+}
+
+var (
+	_ tools.Instructable = (*outputLimitToolset)(nil)
+	_ tools.Unwrapper    = (*outputLimitToolset)(nil)
+)
+
+func (o *outputLimitToolset) Unwrap() tools.ToolSet {
+	return o.ToolSet
+}
+
+func (o *outputLimitToolset) Instructions() string {
+	return tools.GetInstructions(o.ToolSet)
+}
+
+func (o *outputLimitToolset) Tools(ctx context.Context) ([]tools.Tool, error) {
+	innerTools, err := o.ToolSet.Tools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]tools.Tool, len(innerTools))
+	for i, t := range innerTools {
+		t.OutputLimit = &o.limit
+		result[i] = t
+	}
+
+	return result, nil
+}
@@ -0,0 +1,33 @@
+package teamloader
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/config/latest"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+func TestWithOutputLimit_Empty(t *testing.T) {
+	inner := &mockToolSet{}
+
+	assert.Same(t, inner, WithOutputLimit(inner, nil))
+	assert.Same(t, inner, WithOutputLimit(inner, &latest.ToolOutputLimit{}))
+}
+
+func TestWithOutputLimit_SetsLimitOnTools(t *testing.T) {
+	inner := &mockToolSet{
+		toolsFunc: func(_ context.Context) ([]tools.Tool, error) {
+			return []tools.Tool{{Name: "shell"}}, nil
+		},
+	}
+
+	wrapped := WithOutputLimit(inner, &latest.ToolOutputLimit{MaxBytes: 1024, Truncate: "tail"})
+	result, err := wrapped.Tools(t.Context())
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, &tools.OutputLimit{MaxBytes: 1024, Policy: tools.TruncateTail}, result[0].OutputLimit)
+}
@@ -0,0 +1,51 @@
+package teamloader
+
+import (
+	"context"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// WithNoRedaction wraps a toolset so that the secrets in the outputs of its
+// tools aren't masked, for tools that legitimately handle secrets.
+func WithNoRedaction(inner tools.ToolSet, noRedaction bool) tools.ToolSet {
+	if !noRedaction {
+		return inner
+	}
+
+	return &noRedactionToolset{
+		ToolSet: inner,
+	}
+}
+
+type noRedactionToolset struct {
+	tools.ToolSet
+}
+
+var (
+	_ tools.Instructable = (*noRedactionToolset)(nil)
+	_ tools.Unwrapper    = (*noRedactionToolset)(nil)
+)
+
+func (t *noRedactionToolset) Unwrap() tools.ToolSet {
+	return t.ToolSet
+}
+
+func (t *noRedactionToolset) Instructions() string {
+	return tools.GetInstructions(t.ToolSet)
+}
+
+func (t *noRedactionToolset) Tools(ctx context.Context) ([]tools.Tool, error) {
+	innerTools, err := t.ToolSet.Tools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]tools.Tool, len(innerTools))
+	for i, tool := range innerTools {
+		tool.NoRedaction = true
+		result[i] = tool
+	}
+
+	return result, nil
+}
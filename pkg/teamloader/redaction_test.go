@@ -0,0 +1,30 @@
+package teamloader
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+func TestWithNoRedaction_Disabled(t *testing.T) {
+	inner := &mockToolSet{}
+
+	assert.Same(t, inner, WithNoRedaction(inner, false))
+}
+
+func TestWithNoRedaction_SetsNoRedactionOnTools(t *testing.T) {
+	inner := &mockToolSet{
+		toolsFunc: func(_ context.Context) ([]tools.Tool, error) {
+			return []tools.Tool{{Name: "get_secret"}}, nil
+		},
+	}
+
+	result, err := WithNoRedaction(inner, true).Tools(t.Context())
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.True(t, result[0].NoRedaction)
+}
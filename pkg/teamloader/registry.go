@@ -6,8 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/docker/docker-agent/pkg/config"
@@ -54,6 +57,11 @@ func (r *ToolsetRegistry) Get(toolsetType string) (ToolsetCreator, bool) {
 	return creator, ok
 }
 
+// Types returns the toolset types that have a registered creator
+func (r *ToolsetRegistry) Types() []string {
+	return slices.Sorted(maps.Keys(r.creators))
+}
+
 // CreateTool creates a toolset using the registered creator for the given type
 func (r *ToolsetRegistry) CreateTool(ctx context.Context, toolset latest.Toolset, parentDir string, runConfig *config.RuntimeConfig, agentName string) (tools.ToolSet, error) {
 	creator, ok := r.Get(toolset.Type)
@@ -73,16 +81,21 @@ func NewDefaultToolsetRegistry() *ToolsetRegistry {
 	r.Register("shell", createShellTool)
 	r.Register("script", createScriptTool)
 	r.Register("filesystem", createFilesystemTool)
+	r.Register("git", createGitTool)
 	r.Register("fetch", createFetchTool)
 	r.Register("mcp", createMCPTool)
 	r.Register("api", createAPITool)
 	r.Register("a2a", createA2ATool)
 	r.Register("lsp", createLSPTool)
 	r.Register("user_prompt", createUserPromptTool)
+	r.Register("ask_user", createAskUserTool)
 	r.Register("openapi", createOpenAPITool)
 	r.Register("model_picker", createModelPickerTool)
 	r.Register("background_agents", createBackgroundAgentsTool)
 	r.Register("rag", createRAGTool)
+	r.Register("shared_context", createSharedContextTool)
+	r.Register("final_answer", createFinalAnswerTool)
+	r.Register("agent", createAgentTool)
 	return r
 }
 
@@ -156,18 +169,17 @@ func createMemoryTool(_ context.Context, toolset latest.Toolset, parentDir strin
 	return builtin.NewMemoryToolWithPath(db, validatedMemoryPath), nil
 }
 
-func createThinkTool(_ context.Context, _ latest.Toolset, _ string, _ *config.RuntimeConfig, _ string) (tools.ToolSet, error) {
-	return builtin.NewThinkTool(), nil
+func createThinkTool(_ context.Context, toolset latest.Toolset, _ string, _ *config.RuntimeConfig, _ string) (tools.ToolSet, error) {
+	return builtin.NewThinkTool(builtin.WithKeepThoughts(toolset.KeepThoughts)), nil
 }
 
 func createShellTool(ctx context.Context, toolset latest.Toolset, _ string, runConfig *config.RuntimeConfig, _ string) (tools.ToolSet, error) {
-	env, err := environment.ExpandAll(ctx, environment.ToValues(toolset.Env), runConfig.EnvProvider())
+	env, err := toolsetEnv(ctx, toolset, runConfig.EnvProvider())
 	if err != nil {
-		return nil, fmt.Errorf("failed to expand the tool's environment variables: %w", err)
+		return nil, err
 	}
-	env = append(env, os.Environ()...)
 
-	return builtin.NewShellTool(env, runConfig), nil
+	return builtin.NewShellTool(env, runConfig, builtin.WithPersistentSession(toolset.PersistentSession)), nil
 }
 
 func createScriptTool(ctx context.Context, toolset latest.Toolset, _ string, runConfig *config.RuntimeConfig, _ string) (tools.ToolSet, error) {
@@ -175,14 +187,47 @@ func createScriptTool(ctx context.Context, toolset latest.Toolset, _ string, run
 		return nil, errors.New("shell is required for script toolset")
 	}
 
-	env, err := environment.ExpandAll(ctx, environment.ToValues(toolset.Env), runConfig.EnvProvider())
+	env, err := toolsetEnv(ctx, toolset, runConfig.EnvProvider())
 	if err != nil {
-		return nil, fmt.Errorf("failed to expand the tool's environment variables: %w", err)
+		return nil, err
 	}
-	env = append(env, os.Environ()...)
 	return builtin.NewScriptShellTool(toolset.Shell, env)
 }
 
+// toolsetEnv returns the environment of the processes run by a shell,
+// script, mcp or lsp toolset: its env, expanded, and the process environment
+// or, if the toolset sets env_passthrough, only the variables it lists.
+func toolsetEnv(ctx context.Context, toolset latest.Toolset, envProvider environment.Provider) ([]string, error) {
+	env, err := environment.ExpandAll(ctx, environment.ToValues(toolset.Env), envProvider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand the tool's environment variables: %w", err)
+	}
+	if toolset.EnvPassthrough == nil {
+		return append(env, os.Environ()...), nil
+	}
+	return append(environment.Passthrough(ctx, toolset.EnvPassthrough, envProvider), env...), nil
+}
+
+func createGitTool(_ context.Context, toolset latest.Toolset, parentDir string, runConfig *config.RuntimeConfig, _ string) (tools.ToolSet, error) {
+	if toolset.Path != "" {
+		repoPath, err := resolveToolsetPath(toolset.Path, parentDir, runConfig)
+		if err != nil {
+			return nil, fmt.Errorf("invalid git repository path: %w", err)
+		}
+		return builtin.NewGitTool(repoPath), nil
+	}
+
+	wd := runConfig.WorkingDir
+	if wd == "" {
+		var err error
+		wd, err = os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get working directory: %w", err)
+		}
+	}
+	return builtin.NewGitTool(wd), nil
+}
+
 func createFilesystemTool(_ context.Context, toolset latest.Toolset, _ string, runConfig *config.RuntimeConfig, _ string) (tools.ToolSet, error) {
 	wd := runConfig.WorkingDir
 	if wd == "" {
@@ -240,6 +285,10 @@ func createFetchTool(_ context.Context, toolset latest.Toolset, _ string, _ *con
 
 func createMCPTool(ctx context.Context, toolset latest.Toolset, _ string, runConfig *config.RuntimeConfig, _ string) (tools.ToolSet, error) {
 	envProvider := runConfig.EnvProvider()
+	opts := []mcp.ToolsetOption{mcp.WithRoots(mcpRoots(toolset.Roots, runConfig.WorkingDir)...)}
+	if toolset.Sampling != nil {
+		opts = append(opts, mcp.WithSampling(*toolset.Sampling))
+	}
 
 	switch {
 	// MCP Server from the MCP Catalog, running with the MCP Gateway
@@ -252,7 +301,7 @@ func createMCPTool(ctx context.Context, toolset latest.Toolset, _ string, runCon
 
 		// TODO(dga): until the MCP Gateway supports oauth with docker agent, we fetch the remote url and directly connect to it.
 		if serverSpec.Type == "remote" {
-			return mcp.NewRemoteToolset(toolset.Name, serverSpec.Remote.URL, serverSpec.Remote.TransportType, nil, nil), nil
+			return mcp.NewRemoteToolset(toolset.Name, serverSpec.Remote.URL, serverSpec.Remote.TransportType, nil, nil, opts...), nil
 		}
 
 		env, err := environment.ExpandAll(ctx, environment.ToValues(toolset.Env), envProvider)
@@ -265,7 +314,7 @@ func createMCPTool(ctx context.Context, toolset latest.Toolset, _ string, runCon
 			envProvider,
 		)
 
-		return mcp.NewGatewayToolset(ctx, toolset.Name, mcpServerName, serverSpec.Secrets, toolset.Config, envProvider, runConfig.WorkingDir)
+		return mcp.NewGatewayToolset(ctx, toolset.Name, mcpServerName, serverSpec.Secrets, toolset.Config, envProvider, runConfig.WorkingDir, opts...)
 
 	// STDIO MCP Server from shell command
 	case toolset.Command != "":
@@ -280,16 +329,15 @@ func createMCPTool(ctx context.Context, toolset latest.Toolset, _ string, runCon
 			resolvedCommand = toolset.Command
 		}
 
-		env, err := environment.ExpandAll(ctx, environment.ToValues(toolset.Env), envProvider)
+		env, err := toolsetEnv(ctx, toolset, envProvider)
 		if err != nil {
-			return nil, fmt.Errorf("failed to expand the tool's environment variables: %w", err)
+			return nil, err
 		}
-		env = append(env, os.Environ()...)
 
 		// Prepend tools bin dir to PATH so child processes can find installed tools
 		env = toolinstall.PrependBinDirToEnv(env)
 
-		return mcp.NewToolsetCommand(toolset.Name, resolvedCommand, toolset.Args, env, runConfig.WorkingDir), nil
+		return mcp.NewToolsetCommand(toolset.Name, resolvedCommand, toolset.Args, env, runConfig.WorkingDir, opts...), nil
 
 	// Remote MCP Server
 	case toolset.Remote.URL != "":
@@ -298,13 +346,33 @@ func createMCPTool(ctx context.Context, toolset latest.Toolset, _ string, runCon
 		headers := expander.ExpandMap(ctx, toolset.Remote.Headers)
 		url := expander.Expand(ctx, toolset.Remote.URL, nil)
 
-		return mcp.NewRemoteToolset(toolset.Name, url, toolset.Remote.TransportType, headers, toolset.Remote.OAuth), nil
+		return mcp.NewRemoteToolset(toolset.Name, url, toolset.Remote.TransportType, headers, toolset.Remote.OAuth, opts...), nil
 
 	default:
 		return nil, errors.New("mcp toolset requires either ref, command, or remote configuration")
 	}
 }
 
+// mcpRoots returns the roots declared to an MCP server: the configured ones,
+// relative to the working directory, or the working directory itself.
+func mcpRoots(configured []string, workingDir string) []string {
+	if len(configured) == 0 {
+		if workingDir == "" {
+			return nil
+		}
+		return []string{workingDir}
+	}
+
+	roots := make([]string, 0, len(configured))
+	for _, root := range configured {
+		if !strings.HasPrefix(root, "file://") && !filepath.IsAbs(root) {
+			root = filepath.Join(workingDir, root)
+		}
+		roots = append(roots, root)
+	}
+	return roots
+}
+
 func createA2ATool(ctx context.Context, toolset latest.Toolset, _ string, runConfig *config.RuntimeConfig, _ string) (tools.ToolSet, error) {
 	expander := js.NewJsExpander(runConfig.EnvProvider())
 
@@ -314,24 +382,52 @@ func createA2ATool(ctx context.Context, toolset latest.Toolset, _ string, runCon
 }
 
 func createLSPTool(ctx context.Context, toolset latest.Toolset, _ string, runConfig *config.RuntimeConfig, _ string) (tools.ToolSet, error) {
+	command, args, fileTypes := toolset.Command, toolset.Args, toolset.FileTypes
+	var install string
+	if toolset.Preset != "" {
+		preset, ok := builtin.LSPPreset(toolset.Preset)
+		if !ok {
+			return nil, fmt.Errorf("unknown lsp preset %q, expected one of %s", toolset.Preset, strings.Join(builtin.LSPPresetNames(), ", "))
+		}
+		command = cmp.Or(command, preset.Command)
+		if args == nil {
+			args = preset.Args
+		}
+		if len(fileTypes) == 0 {
+			fileTypes = preset.FileTypes
+		}
+		install = preset.Install
+	}
+
 	// Auto-install missing command binary if needed
-	resolvedCommand, err := toolinstall.EnsureCommand(ctx, toolset.Command, toolset.Version)
+	resolvedCommand, err := toolinstall.EnsureCommand(ctx, command, toolset.Version)
 	if err != nil {
-		return nil, fmt.Errorf("resolving command %q: %w", toolset.Command, err)
+		if install != "" {
+			return nil, fmt.Errorf("resolving command %q: %w; install it with: %s", command, err, install)
+		}
+		return nil, fmt.Errorf("resolving command %q: %w", command, err)
 	}
 
-	env, err := environment.ExpandAll(ctx, environment.ToValues(toolset.Env), runConfig.EnvProvider())
+	env, err := toolsetEnv(ctx, toolset, runConfig.EnvProvider())
 	if err != nil {
-		return nil, fmt.Errorf("failed to expand the tool's environment variables: %w", err)
+		return nil, err
 	}
-	env = append(env, os.Environ()...)
 
 	// Prepend tools bin dir to PATH so child processes can find installed tools
 	env = toolinstall.PrependBinDirToEnv(env)
 
-	tool := builtin.NewLSPTool(resolvedCommand, toolset.Args, env, runConfig.WorkingDir)
-	if len(toolset.FileTypes) > 0 {
-		tool.SetFileTypes(toolset.FileTypes)
+	var opts []builtin.LSPOption
+	if toolset.EnvPassthrough != nil {
+		// env already holds the variables passed through.
+		opts = append(opts, builtin.WithLSPEnvPassthrough())
+	}
+	if len(toolset.WorkspaceFolders) > 0 {
+		opts = append(opts, builtin.WithWorkspaceFolders(toolset.WorkspaceFolders...))
+	}
+
+	tool := builtin.NewLSPTool(resolvedCommand, args, env, runConfig.WorkingDir, opts...)
+	if len(fileTypes) > 0 {
+		tool.SetFileTypes(fileTypes)
 	}
 
 	return tool, nil
@@ -341,6 +437,14 @@ func createUserPromptTool(_ context.Context, _ latest.Toolset, _ string, _ *conf
 	return builtin.NewUserPromptTool(), nil
 }
 
+func createAskUserTool(_ context.Context, toolset latest.Toolset, _ string, _ *config.RuntimeConfig, _ string) (tools.ToolSet, error) {
+	var opts []builtin.AskUserOption
+	if toolset.Timeout > 0 {
+		opts = append(opts, builtin.WithAskUserTimeout(time.Duration(toolset.Timeout)*time.Second))
+	}
+	return builtin.NewAskUserTool(opts...), nil
+}
+
 func createOpenAPITool(ctx context.Context, toolset latest.Toolset, _ string, runConfig *config.RuntimeConfig, _ string) (tools.ToolSet, error) {
 	expander := js.NewJsExpander(runConfig.EnvProvider())
 
@@ -357,6 +461,22 @@ func createModelPickerTool(_ context.Context, toolset latest.Toolset, _ string,
 	return builtin.NewModelPickerTool(toolset.Models), nil
 }
 
+func createSharedContextTool(_ context.Context, _ latest.Toolset, _ string, _ *config.RuntimeConfig, _ string) (tools.ToolSet, error) {
+	return builtin.NewSharedContextTool(), nil
+}
+
+func createFinalAnswerTool(_ context.Context, _ latest.Toolset, _ string, _ *config.RuntimeConfig, _ string) (tools.ToolSet, error) {
+	return builtin.NewFinalAnswerTool(), nil
+}
+
+func createAgentTool(_ context.Context, toolset latest.Toolset, _ string, _ *config.RuntimeConfig, _ string) (tools.ToolSet, error) {
+	var parameters any
+	if len(toolset.Parameters) > 0 {
+		parameters = toolset.Parameters
+	}
+	return builtin.NewAgentTool(toolset.Agent, toolset.Name, toolset.Description, parameters, toolset.Prompt)
+}
+
 func createBackgroundAgentsTool(_ context.Context, _ latest.Toolset, _ string, _ *config.RuntimeConfig, _ string) (tools.ToolSet, error) {
 	return agenttool.NewToolSet(), nil
 }
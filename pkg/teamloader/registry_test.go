@@ -1,6 +1,7 @@
 package teamloader
 
 import (
+	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -10,6 +11,7 @@ import (
 	"github.com/docker/docker-agent/pkg/config/latest"
 	"github.com/docker/docker-agent/pkg/environment"
 	"github.com/docker/docker-agent/pkg/tools"
+	"github.com/docker/docker-agent/pkg/tools/builtin"
 )
 
 func TestCreateShellTool(t *testing.T) {
@@ -29,6 +31,39 @@ func TestCreateShellTool(t *testing.T) {
 	require.NotNil(t, tool)
 }
 
+func TestCreateShellTool_EnvPassthrough(t *testing.T) {
+	t.Setenv("SCOPED_REGION", "eu-west-1")
+	t.Setenv("LEAKED_SECRET", "secret")
+
+	toolset := latest.Toolset{
+		Type:           "shell",
+		Env:            map[string]string{"GREETING": "hello ${NAME}"},
+		EnvPassthrough: []string{"SCOPED_*", "API_TOKEN"},
+	}
+
+	runConfig := &config.RuntimeConfig{
+		Config: config.Config{WorkingDir: t.TempDir()},
+		EnvProviderForTests: environment.NewMultiProvider(
+			environment.NewMapEnvProvider(map[string]string{"NAME": "world", "API_TOKEN": "token"}),
+			environment.NewOsEnvProvider(),
+		),
+	}
+
+	tool, err := NewDefaultToolsetRegistry().CreateTool(t.Context(), toolset, ".", runConfig, "test-agent")
+	require.NoError(t, err)
+
+	allTools, err := tool.Tools(t.Context())
+	require.NoError(t, err)
+	idx := slices.IndexFunc(allTools, func(tl tools.Tool) bool { return tl.Name == builtin.ToolNameShell })
+	require.GreaterOrEqual(t, idx, 0)
+
+	result, err := allTools[idx].Handler(t.Context(), tools.ToolCall{
+		Function: tools.FunctionCall{Arguments: `{"cmd": "echo \"[$SCOPED_REGION][$API_TOKEN][$GREETING][$LEAKED_SECRET]\""}`},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, "[eu-west-1][token][hello world][]")
+}
+
 func TestCreateMCPTool_CommandNotFound_CreatesToolsetAnyway(t *testing.T) {
 	t.Setenv("DOCKER_AGENT_TOOLS_DIR", t.TempDir())
 
@@ -70,3 +105,12 @@ func TestCreateMCPTool_BareCommandNotFound_CreatesToolsetAnyway(t *testing.T) {
 	require.NotNil(t, tool)
 	assert.Equal(t, "mcp(stdio cmd=some-nonexistent-mcp-binary)", tools.DescribeToolSet(tool))
 }
+
+func TestMCPRoots(t *testing.T) {
+	assert.Equal(t, []string{"/work"}, mcpRoots(nil, "/work"))
+	assert.Nil(t, mcpRoots(nil, ""))
+	assert.Equal(t,
+		[]string{"/work/docs", "/abs", "file:///srv"},
+		mcpRoots([]string{"docs", "/abs", "file:///srv"}, "/work"),
+	)
+}
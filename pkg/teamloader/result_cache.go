@@ -0,0 +1,52 @@
+package teamloader
+
+import (
+	"context"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// WithNoResultCache wraps a toolset so that the results of its tools are
+// never served from the runtime's tool result cache, for read-only tools
+// whose results change on their own, like the time or a web search.
+func WithNoResultCache(inner tools.ToolSet, noResultCache bool) tools.ToolSet {
+	if !noResultCache {
+		return inner
+	}
+
+	return &noResultCacheToolset{
+		ToolSet: inner,
+	}
+}
+
+type noResultCacheToolset struct {
+	tools.ToolSet
+}
+
+var (
+	_ tools.Instructable = (*noResultCacheToolset)(nil)
+	_ tools.Unwrapper    = (*noResultCacheToolset)(nil)
+)
+
+func (t *noResultCacheToolset) Unwrap() tools.ToolSet {
+	return t.ToolSet
+}
+
+func (t *noResultCacheToolset) Instructions() string {
+	return tools.GetInstructions(t.ToolSet)
+}
+
+func (t *noResultCacheToolset) Tools(ctx context.Context) ([]tools.Tool, error) {
+	innerTools, err := t.ToolSet.Tools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]tools.Tool, len(innerTools))
+	for i, tool := range innerTools {
+		tool.NoResultCache = true
+		result[i] = tool
+	}
+
+	return result, nil
+}
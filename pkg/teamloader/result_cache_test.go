@@ -0,0 +1,30 @@
+package teamloader
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+func TestWithNoResultCache_Disabled(t *testing.T) {
+	inner := &mockToolSet{}
+
+	assert.Same(t, inner, WithNoResultCache(inner, false))
+}
+
+func TestWithNoResultCache_SetsNoResultCacheOnTools(t *testing.T) {
+	inner := &mockToolSet{
+		toolsFunc: func(_ context.Context) ([]tools.Tool, error) {
+			return []tools.Tool{{Name: "now", Annotations: tools.ToolAnnotations{ReadOnlyHint: true}}}, nil
+		},
+	}
+
+	result, err := WithNoResultCache(inner, true).Tools(t.Context())
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.True(t, result[0].NoResultCache)
+}
@@ -35,6 +35,7 @@ type loadOptions struct {
 	modelOverrides  []string
 	promptFiles     []string
 	toolsetRegistry *ToolsetRegistry
+	toolsetCache    *ToolsetCache
 }
 
 type Opt func(*loadOptions) error
@@ -95,7 +96,7 @@ func LoadWithConfig(ctx context.Context, agentSource config.Source, runConfig *c
 	}
 
 	// Load the agent's configuration
-	cfg, err := config.Load(ctx, agentSource)
+	cfg, err := config.Load(ctx, agentSource, runConfig.LoadOpts()...)
 	if err != nil {
 		return nil, err
 	}
@@ -115,8 +116,18 @@ func LoadWithConfig(ctx context.Context, agentSource config.Source, runConfig *c
 		return nil, err
 	}
 
-	// Early check for required env vars before loading models and tools.
 	env := runConfig.EnvProvider()
+
+	// Report configuration problems before building anything.
+	diagnostics, err := config.Validate(*cfg, config.WithToolsetTypes(loadOpts.toolsetRegistry.Types()...), config.WithEnvLookup(ctx, env), config.WithBaseDir(agentSource.ParentDir()))
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range diagnostics {
+		slog.Warn("Agent configuration warning", "path", d.Path, "message", d.Message)
+	}
+
+	// Early check for required env vars before loading models and tools.
 	if err := config.CheckRequiredEnvVars(ctx, cfg, runConfig.ModelsGateway, env); err != nil {
 		return nil, err
 	}
@@ -131,6 +142,8 @@ func LoadWithConfig(ctx context.Context, agentSource config.Source, runConfig *c
 	var agents []*agent.Agent
 	agentsByName := make(map[string]*agent.Agent)
 
+	reuse := newToolsetReuse(loadOpts.toolsetCache)
+
 	autoModel := sync.OnceValue(func() latest.ModelConfig {
 		return config.AutoModelConfig(ctx, runConfig.ModelsGateway, env, runConfig.DefaultModel)
 	})
@@ -160,6 +173,8 @@ func LoadWithConfig(ctx context.Context, agentSource config.Source, runConfig *c
 			agent.WithAddDate(agentConfig.AddDate),
 			agent.WithAddEnvironmentInfo(agentConfig.AddEnvironmentInfo),
 			agent.WithAddDescriptionParameter(agentConfig.AddDescriptionParameter),
+			agent.WithCiteSources(agentConfig.CiteSources),
+			agent.WithRequireFinalAnswer(agentConfig.RequireFinalAnswer),
 			agent.WithAddPromptFiles(promptFiles),
 			agent.WithMaxIterations(agentConfig.MaxIterations),
 			agent.WithMaxConsecutiveToolCalls(agentConfig.MaxConsecutiveToolCalls),
@@ -167,6 +182,24 @@ func LoadWithConfig(ctx context.Context, agentSource config.Source, runConfig *c
 			agent.WithNumHistoryItems(agentConfig.NumHistoryItems),
 			agent.WithCommands(expander.ExpandCommands(ctx, agentConfig.Commands)),
 			agent.WithHooks(config.MergeHooks(agentConfig.Hooks, cliHooks)),
+			agent.WithToolCategories(agentConfig.Tools.IncludeCategories, agentConfig.Tools.ExcludeCategories),
+			agent.WithDisabledBuiltinTools(agentConfig.DisableBuiltinTools...),
+		}
+		if cfg.Vars != nil {
+			opts = append(opts,
+				agent.WithPromptVars(cfg.Vars),
+				agent.WithPromptEnv(env),
+				agent.WithPromptBaseDir(parentDir),
+				agent.WithPromptMissingKey(cfg.VarsMissingKey),
+			)
+		}
+
+		fragments, err := config.InstructionFragments(cfg, &agentConfig, agentSource.ParentDir())
+		if err != nil {
+			return nil, err
+		}
+		if len(fragments) > 0 {
+			opts = append(opts, agent.WithInstructionFragments(fragments...))
 		}
 
 		models, err := getModelsForAgent(ctx, cfg, &agentConfig, autoModel, runConfig)
@@ -198,7 +231,7 @@ func LoadWithConfig(ctx context.Context, agentSource config.Source, runConfig *c
 			)
 		}
 
-		agentTools, warnings := getToolsForAgent(ctx, &agentConfig, parentDir, runConfig, loadOpts.toolsetRegistry, configName)
+		agentTools, warnings := getToolsForAgent(ctx, &agentConfig, parentDir, runConfig, loadOpts.toolsetRegistry, reuse, configName)
 		if len(warnings) > 0 {
 			opts = append(opts, agent.WithLoadTimeWarnings(warnings))
 		}
@@ -250,6 +283,16 @@ func LoadWithConfig(ctx context.Context, agentSource config.Source, runConfig *c
 	// Create permissions checker from config
 	permChecker := permissions.NewChecker(cfg.Permissions)
 
+	compaction, err := getCompactionConfig(ctx, cfg, agentSource.ParentDir(), runConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	redactor, err := config.SecretRedactor(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	// Build agent default models map
 	agentDefaultModels := make(map[string]string)
 	for _, agent := range cfg.Agents {
@@ -258,10 +301,14 @@ func LoadWithConfig(ctx context.Context, agentSource config.Source, runConfig *c
 		}
 	}
 
+	reuse.commit()
+
 	return &LoadResult{
 		Team: team.New(
 			team.WithAgents(agents...),
 			team.WithPermissions(permChecker),
+			team.WithCompaction(compaction),
+			team.WithRedactor(redactor),
 		),
 		Models:             cfg.Models,
 		Providers:          cfg.Providers,
@@ -307,6 +354,12 @@ func getModelsForAgent(ctx context.Context, cfg *latest.Config, a *latest.AgentC
 		if maxTokens != nil {
 			opts = append(opts, options.WithMaxTokens(*maxTokens))
 		}
+		// The clients are created with the first requests, so that loading a
+		// team stays cheap. Auto model selection relies on the errors of the
+		// clients to fall back, so it creates them upfront.
+		if !isAutoModel {
+			opts = append(opts, options.WithDeferredClient())
+		}
 
 		// Pass the full models map for routing rules to resolve model references
 		model, err := provider.NewWithModels(ctx,
@@ -332,83 +385,135 @@ func getModelsForAgent(ctx context.Context, cfg *latest.Config, a *latest.AgentC
 // It uses the same resolution logic as primary models (named model, inline provider/model format).
 func getFallbackModelsForAgent(ctx context.Context, cfg *latest.Config, a *latest.AgentConfig, runConfig *config.RuntimeConfig) ([]provider.Provider, error) {
 	var fallbackModels []provider.Provider
-
-	// Obtain the singleton store once, outside the loop.
-	modelsStore, modelsStoreErr := modelsdev.NewStore()
-
 	for _, name := range a.GetFallbackModels() {
-		modelCfg, exists := cfg.Models[name]
-		if !exists {
-			// Try parsing as inline provider/model format (e.g., "openai/gpt-4o")
-			parsed, err := latest.ParseModelRef(name)
-			if err != nil {
-				return nil, fmt.Errorf("fallback model '%s' not found in configuration and is not a valid provider/model format", name)
-			}
-			modelCfg = parsed
+		model, err := newModelFromRef(ctx, cfg, name, a.StructuredOutput, runConfig)
+		if err != nil {
+			return nil, err
 		}
-		modelCfg.Name = name
+		fallbackModels = append(fallbackModels, model)
+	}
 
-		// Use max_tokens from config if specified, otherwise look up from models.dev
-		maxTokens := &defaultMaxTokens
-		if modelCfg.MaxTokens != nil {
-			maxTokens = modelCfg.MaxTokens
-		} else if modelsStoreErr == nil {
-			m, err := modelsStore.GetModel(ctx, modelCfg.Provider+"/"+modelCfg.Model)
-			if err == nil {
-				maxTokens = &m.Limit.Output
-			}
-		}
+	return fallbackModels, nil
+}
 
-		opts := []options.Opt{
-			options.WithGateway(runConfig.ModelsGateway),
-			options.WithStructuredOutput(a.StructuredOutput),
-			options.WithProviders(cfg.Providers),
-		}
-		if maxTokens != nil {
-			opts = append(opts, options.WithMaxTokens(*maxTokens))
+// newModelFromRef creates the provider of a model reference: the name of a
+// model of the configuration or an inline provider/model reference.
+func newModelFromRef(ctx context.Context, cfg *latest.Config, name string, structuredOutput *latest.StructuredOutput, runConfig *config.RuntimeConfig) (provider.Provider, error) {
+	modelCfg, exists := cfg.Models[name]
+	if !exists {
+		// Try parsing as inline provider/model format (e.g., "openai/gpt-4o")
+		parsed, err := latest.ParseModelRef(name)
+		if err != nil {
+			return nil, fmt.Errorf("model '%s' not found in configuration and is not a valid provider/model format", name)
 		}
+		modelCfg = parsed
+	}
+	modelCfg.Name = name
 
-		// Pass the full models map for routing rules to resolve model references
-		model, err := provider.NewWithModels(ctx,
-			&modelCfg,
-			cfg.Models,
-			runConfig.EnvProvider(),
-			opts...,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create fallback model '%s': %w", name, err)
+	// Use max_tokens from config if specified, otherwise look up from models.dev
+	maxTokens := &defaultMaxTokens
+	if modelCfg.MaxTokens != nil {
+		maxTokens = modelCfg.MaxTokens
+	} else if modelsStore, err := modelsdev.NewStore(); err == nil {
+		m, err := modelsStore.GetModel(ctx, modelCfg.Provider+"/"+modelCfg.Model)
+		if err == nil {
+			maxTokens = &m.Limit.Output
 		}
-		fallbackModels = append(fallbackModels, model)
 	}
 
-	return fallbackModels, nil
+	opts := []options.Opt{
+		options.WithGateway(runConfig.ModelsGateway),
+		options.WithStructuredOutput(structuredOutput),
+		options.WithProviders(cfg.Providers),
+		options.WithDeferredClient(),
+	}
+	if maxTokens != nil {
+		opts = append(opts, options.WithMaxTokens(*maxTokens))
+	}
+
+	// Pass the full models map for routing rules to resolve model references
+	model, err := provider.NewWithModels(ctx,
+		&modelCfg,
+		cfg.Models,
+		runConfig.EnvProvider(),
+		opts...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create model '%s': %w", name, err)
+	}
+	return model, nil
+}
+
+// getCompactionConfig returns how the sessions are compacted, from the
+// compaction section of the configuration.
+func getCompactionConfig(ctx context.Context, cfg *latest.Config, baseDir string, runConfig *config.RuntimeConfig) (team.CompactionConfig, error) {
+	if cfg.Compaction == nil {
+		return team.CompactionConfig{}, nil
+	}
+
+	prompt, err := config.CompactionPrompt(cfg, baseDir)
+	if err != nil {
+		return team.CompactionConfig{}, err
+	}
+	compaction := team.CompactionConfig{
+		Prompt:           prompt,
+		KeepLastMessages: cfg.Compaction.KeepLastMessages,
+	}
+	if cfg.Compaction.Model != "" {
+		if compaction.Model, err = newModelFromRef(ctx, cfg, cfg.Compaction.Model, nil, runConfig); err != nil {
+			return team.CompactionConfig{}, fmt.Errorf("compaction: %w", err)
+		}
+	}
+	return compaction, nil
 }
 
 // getToolsForAgent returns the tool definitions for an agent based on its configuration
-func getToolsForAgent(ctx context.Context, a *latest.AgentConfig, parentDir string, runConfig *config.RuntimeConfig, registry *ToolsetRegistry, configName string) ([]tools.ToolSet, []string) {
+func getToolsForAgent(ctx context.Context, a *latest.AgentConfig, parentDir string, runConfig *config.RuntimeConfig, registry *ToolsetRegistry, reuse *toolsetReuse, configName string) ([]tools.ToolSet, []string) {
 	var (
 		toolSets    []tools.ToolSet
 		warnings    []string
 		lspBackends []builtin.LSPBackend
+		fsTools     []*builtin.FilesystemTool
 	)
 
 	deferredToolset := builtin.NewDeferredToolset()
 
-	for i := range a.Toolsets {
-		toolset := a.Toolsets[i]
+	toolsets, detectionWarnings := expandLSPPresets(a.Toolsets, runConfig.WorkingDir)
+	warnings = append(warnings, detectionWarnings...)
 
-		tool, err := registry.CreateTool(ctx, toolset, parentDir, runConfig, configName)
-		if err != nil {
-			// Collect error but continue loading other toolsets
-			slog.Warn("Toolset configuration failed; skipping", "type", toolset.Type, "ref", toolset.Ref, "command", toolset.Command, "error", err)
-			warnings = append(warnings, fmt.Sprintf("toolset %s failed: %v", toolset.Type, err))
-			continue
+	for i := range toolsets {
+		toolset := toolsets[i]
+
+		cacheKey, cacheable := toolsetCacheKey(a.Name, parentDir, &toolset)
+		tool, reused := reuse.get(cacheKey)
+		if !reused {
+			var err error
+			tool, err = registry.CreateTool(ctx, toolset, parentDir, runConfig, configName)
+			if err != nil {
+				// Collect error but continue loading other toolsets
+				slog.Warn("Toolset configuration failed; skipping", "type", toolset.Type, "ref", toolset.Ref, "command", toolset.Command, "error", err)
+				warnings = append(warnings, fmt.Sprintf("toolset %s failed: %v", toolset.Type, err))
+				continue
+			}
+		}
+		if cacheable {
+			reuse.put(cacheKey, tool)
+		}
+		if fsTool, ok := tool.(*builtin.FilesystemTool); ok {
+			fsTools = append(fsTools, fsTool)
 		}
 
-		wrapped := WithToolsFilter(tool, toolset.Tools...)
+		wrapped := WithToolSetType(tool, toolset.Type)
+		wrapped = WithToolsFilter(wrapped, toolset.Tools...)
 		wrapped = WithInstructions(wrapped, toolset.Instruction)
 		wrapped = WithToon(wrapped, toolset.Toon)
 		wrapped = WithModelOverride(wrapped, toolset.Model)
+		wrapped = WithOutputLimit(wrapped, toolset.OutputLimit)
+		wrapped = WithToolTimeout(wrapped, toolset.ToolTimeout)
+		wrapped = WithConcurrency(wrapped, toolset.Concurrency)
+		wrapped = WithNoResultCache(wrapped, toolset.NoResultCache)
+		wrapped = WithNoRedaction(wrapped, toolset.NoRedaction)
+		wrapped = WithFileChangeTracking(wrapped, runConfig.WorkingDir, toolset.TrackFileChanges)
 
 		// Handle deferred tools
 		if !toolset.Defer.IsEmpty() {
@@ -445,16 +550,27 @@ func getToolsForAgent(ctx context.Context, a *latest.AgentConfig, parentDir stri
 		toolSets = append(toolSets, lspBackends[0].Toolset)
 	}
 
+	// Send the edits made with the filesystem tools to the LSP servers
+	// right away, rather than when they next poll the files.
+	for _, fsTool := range fsTools {
+		for _, b := range lspBackends {
+			fsTool.AddEditListener(b.LSP)
+		}
+	}
+
 	if deferredToolset.HasSources() {
 		toolSets = append(toolSets, deferredToolset)
 	}
 
-	if len(a.SubAgents) > 0 {
+	if len(a.SubAgents) > 0 && !slices.Contains(a.DisableBuiltinTools, builtin.ToolNameTransferTask) {
 		toolSets = append(toolSets, builtin.NewTransferTaskTool())
 	}
-	if len(a.Handoffs) > 0 {
+	if len(a.Handoffs) > 0 && !slices.Contains(a.DisableBuiltinTools, builtin.ToolNameHandoff) {
 		toolSets = append(toolSets, builtin.NewHandoffTool())
 	}
+	if a.RequireFinalAnswer && !slices.ContainsFunc(a.Toolsets, func(t latest.Toolset) bool { return t.Type == "final_answer" }) {
+		toolSets = append(toolSets, builtin.NewFinalAnswerTool())
+	}
 
 	// Wrap all tools in a single Code Mode toolset.
 	// This allows the agent to call multiple tools in a single response.
@@ -466,6 +582,38 @@ func getToolsForAgent(ctx context.Context, a *latest.AgentConfig, parentDir stri
 	return toolSets, warnings
 }
 
+// expandLSPPresets replaces the lsp toolsets with the "auto" preset by one
+// toolset per preset detected in workingDir, routed to by file type like
+// the other lsp toolsets. It also returns the warnings of the detection,
+// e.g. about the language servers to install.
+func expandLSPPresets(toolsets []latest.Toolset, workingDir string) ([]latest.Toolset, []string) {
+	if !slices.ContainsFunc(toolsets, isAutoLSPToolset) {
+		return toolsets, nil
+	}
+
+	presets, warnings := builtin.DetectLSPPresets(workingDir)
+	var expanded []latest.Toolset
+	for _, toolset := range toolsets {
+		if !isAutoLSPToolset(toolset) {
+			expanded = append(expanded, toolset)
+			continue
+		}
+		for _, preset := range presets {
+			detected := toolset
+			detected.Preset = preset.Name
+			expanded = append(expanded, detected)
+		}
+	}
+	for _, warning := range warnings {
+		slog.Warn("Language server not available", "warning", warning)
+	}
+	return expanded, warnings
+}
+
+func isAutoLSPToolset(toolset latest.Toolset) bool {
+	return toolset.Type == "lsp" && toolset.Preset == builtin.LSPPresetAuto
+}
+
 // configNameFromSource extracts a clean config name from a source name.
 // The result is "<basename>-<hash>" where basename comes from the file name
 // (e.g. "memory_agent" from "/path/to/memory_agent.yaml") and hash is a short
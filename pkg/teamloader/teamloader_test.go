@@ -23,7 +23,9 @@ import (
 // skipExamples contains example files that require cloud-specific configurations
 // (e.g., AWS profiles, GCP credentials) that can't be mocked with dummy env vars.
 var skipExamples = map[string]string{
-	"pr-reviewer-bedrock.yaml": "requires AWS profile configuration",
+	"pr-reviewer-bedrock.yaml":      "requires AWS profile configuration",
+	"openai_compatible_server.yaml": "validates a local OpenAI-compatible server at startup",
+	"eval-suite.yaml":               "is an eval test suite, not an agent",
 }
 
 func collectExamples(t *testing.T) []string {
@@ -66,7 +68,7 @@ func TestGetToolsForAgent_ContinuesOnCreateToolError(t *testing.T) {
 		EnvProviderForTests: &noEnvProvider{},
 	}
 
-	got, warnings := getToolsForAgent(t.Context(), a, ".", &runConfig, NewToolsetRegistry(), "test-config")
+	got, warnings := getToolsForAgent(t.Context(), a, ".", &runConfig, NewToolsetRegistry(), nil, "test-config")
 
 	require.Empty(t, got)
 	require.NotEmpty(t, warnings)
@@ -386,6 +388,37 @@ agents:
 	assert.Equal(t, expected, rootAgent.AddPromptFiles())
 }
 
+func TestInstructionSnippets(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "dummy")
+
+	tempDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "prompts"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "prompts", "safety.md"), []byte("Never run destructive commands.\n"), 0o644))
+	agentFile := filepath.Join(tempDir, "agent.yaml")
+	agentYAML := `snippets:
+  tone: Be concise.
+  safety:
+    file: prompts/safety.md
+agents:
+  root:
+    model: openai/gpt-4o
+    instruction: You review pull requests.
+    instruction_refs: [safety, tone]
+`
+	require.NoError(t, os.WriteFile(agentFile, []byte(agentYAML), 0o644))
+
+	agentSource, err := config.Resolve(agentFile, nil)
+	require.NoError(t, err)
+
+	team, err := Load(t.Context(), agentSource, &config.RuntimeConfig{})
+	require.NoError(t, err)
+
+	rootAgent, err := team.Agent("root")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Never run destructive commands.\n\nBe concise.\n\nYou review pull requests.", rootAgent.Instruction())
+}
+
 func TestGetToolsForAgent_MultipleLSPToolsetsAreCombined(t *testing.T) {
 	t.Parallel()
 
@@ -411,7 +444,7 @@ func TestGetToolsForAgent_MultipleLSPToolsetsAreCombined(t *testing.T) {
 		EnvProviderForTests: &noEnvProvider{},
 	}
 
-	got, warnings := getToolsForAgent(t.Context(), a, ".", &runConfig, NewDefaultToolsetRegistry(), "test-config")
+	got, warnings := getToolsForAgent(t.Context(), a, ".", &runConfig, NewDefaultToolsetRegistry(), nil, "test-config")
 	require.Empty(t, warnings)
 
 	// Should have exactly one toolset (the multiplexer)
@@ -451,7 +484,7 @@ func TestGetToolsForAgent_SingleLSPToolsetNotWrapped(t *testing.T) {
 		EnvProviderForTests: &noEnvProvider{},
 	}
 
-	got, warnings := getToolsForAgent(t.Context(), a, ".", &runConfig, NewDefaultToolsetRegistry(), "test-config")
+	got, warnings := getToolsForAgent(t.Context(), a, ".", &runConfig, NewDefaultToolsetRegistry(), nil, "test-config")
 	require.Empty(t, warnings)
 
 	// Should have exactly one toolset that provides LSP tools.
@@ -468,6 +501,23 @@ func TestGetToolsForAgent_SingleLSPToolsetNotWrapped(t *testing.T) {
 	assert.Contains(t, names, "lsp_definition")
 }
 
+func TestExpandLSPPresets(t *testing.T) {
+	t.Parallel()
+
+	toolsets := []latest.Toolset{
+		{Type: "lsp", Preset: "go"},
+		{Type: "shell"},
+	}
+	got, warnings := expandLSPPresets(toolsets, t.TempDir())
+	assert.Equal(t, toolsets, got)
+	assert.Empty(t, warnings)
+
+	// Nothing is detected in an empty directory.
+	got, warnings = expandLSPPresets(append(toolsets, latest.Toolset{Type: "lsp", Preset: "auto"}), t.TempDir())
+	assert.Equal(t, toolsets, got)
+	assert.Empty(t, warnings)
+}
+
 func TestExternalDepthContext(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,61 @@
+package teamloader
+
+import (
+	"context"
+	"time"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// WithToolTimeout wraps a toolset so that every tool it produces carries the
+// given limit, in seconds, on how long its calls may run. -1 disables the
+// limit, zero keeps the runtime's.
+func WithToolTimeout(inner tools.ToolSet, seconds int) tools.ToolSet {
+	if seconds == 0 {
+		return inner
+	}
+
+	timeout := tools.NoTimeout
+	if seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	return &toolTimeoutToolset{
+		ToolSet: inner,
+		timeout: timeout,
+	}
+}
+
+type toolTimeoutToolset struct {
+	tools.ToolSet
+
+	timeout time.Duration
+}
+
+var (
+	_ tools.Instructable = (*toolTimeoutToolset)(nil)
+	_ tools.Unwrapper    = (*toolTimeoutToolset)(nil)
+)
+
+func (t *toolTimeoutToolset) Unwrap() tools.ToolSet {
+	return t.ToolSet
+}
+
+func (t *toolTimeoutToolset) Instructions() string {
+	return tools.GetInstructions(t.ToolSet)
+}
+
+func (t *toolTimeoutToolset) Tools(ctx context.Context) ([]tools.Tool, error) {
+	innerTools, err := t.ToolSet.Tools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]tools.Tool, len(innerTools))
+	for i, tool := range innerTools {
+		tool.Timeout = t.timeout
+		result[i] = tool
+	}
+
+	return result, nil
+}
@@ -0,0 +1,35 @@
+package teamloader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+func TestWithToolTimeout_Zero(t *testing.T) {
+	inner := &mockToolSet{}
+
+	assert.Same(t, inner, WithToolTimeout(inner, 0))
+}
+
+func TestWithToolTimeout_SetsTimeoutOnTools(t *testing.T) {
+	inner := &mockToolSet{
+		toolsFunc: func(_ context.Context) ([]tools.Tool, error) {
+			return []tools.Tool{{Name: "search"}}, nil
+		},
+	}
+
+	result, err := WithToolTimeout(inner, 30).Tools(t.Context())
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, 30*time.Second, result[0].Timeout)
+
+	result, err = WithToolTimeout(inner, -1).Tools(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, tools.NoTimeout, result[0].Timeout)
+}
@@ -0,0 +1,112 @@
+package teamloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	"github.com/docker/docker-agent/pkg/config/latest"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// ToolsetCache keeps the MCP toolsets of a team across successive loads of
+// the same configuration, so that reloading a configuration doesn't restart
+// MCP servers whose definition didn't change (and doesn't make the user go
+// through OAuth again).
+//
+// A cache only holds the MCP toolsets of the last successful load.
+type ToolsetCache struct {
+	mu       sync.Mutex
+	toolsets map[string]tools.ToolSet
+}
+
+// NewToolsetCache creates an empty toolset cache.
+func NewToolsetCache() *ToolsetCache {
+	return &ToolsetCache{
+		toolsets: make(map[string]tools.ToolSet),
+	}
+}
+
+// WithToolsetCache reuses the MCP toolsets of the previous load done with the
+// same cache when their configuration is unchanged.
+func WithToolsetCache(cache *ToolsetCache) Opt {
+	return func(opts *loadOptions) error {
+		opts.toolsetCache = cache
+		return nil
+	}
+}
+
+// toolsetReuse tracks the toolsets reused or created during a single load.
+// Nothing is written back to the cache unless the load succeeds.
+type toolsetReuse struct {
+	cache *ToolsetCache
+	next  map[string]tools.ToolSet
+}
+
+func newToolsetReuse(cache *ToolsetCache) *toolsetReuse {
+	if cache == nil {
+		return nil
+	}
+	return &toolsetReuse{
+		cache: cache,
+		next:  make(map[string]tools.ToolSet),
+	}
+}
+
+// get returns the toolset created by the previous load for the same
+// configuration. A toolset is never shared by two agents of the same load.
+func (r *toolsetReuse) get(key string) (tools.ToolSet, bool) {
+	if r == nil {
+		return nil, false
+	}
+	if _, used := r.next[key]; used {
+		return nil, false
+	}
+
+	r.cache.mu.Lock()
+	defer r.cache.mu.Unlock()
+	ts, ok := r.cache.toolsets[key]
+	return ts, ok
+}
+
+func (r *toolsetReuse) put(key string, ts tools.ToolSet) {
+	if r == nil {
+		return
+	}
+	if _, used := r.next[key]; !used {
+		r.next[key] = ts
+	}
+}
+
+// commit replaces the content of the cache with the toolsets of this load.
+func (r *toolsetReuse) commit() {
+	if r == nil {
+		return
+	}
+
+	r.cache.mu.Lock()
+	defer r.cache.mu.Unlock()
+	r.cache.toolsets = r.next
+}
+
+// toolsetCacheKey identifies an MCP toolset by the agent it belongs to and
+// its full configuration.
+func toolsetCacheKey(agentName, parentDir string, toolset *latest.Toolset) (string, bool) {
+	if toolset.Type != "mcp" {
+		return "", false
+	}
+
+	buf, err := json.Marshal(toolset)
+	if err != nil {
+		return "", false
+	}
+
+	h := sha256.New()
+	h.Write([]byte(agentName))
+	h.Write([]byte{0})
+	h.Write([]byte(parentDir))
+	h.Write([]byte{0})
+	h.Write(buf)
+	return hex.EncodeToString(h.Sum(nil)), true
+}
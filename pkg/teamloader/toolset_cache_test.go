@@ -0,0 +1,65 @@
+package teamloader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/config/latest"
+)
+
+func TestToolsetCacheKey(t *testing.T) {
+	t.Parallel()
+
+	mcp := &latest.Toolset{Type: "mcp", Command: "gopls", Args: []string{"mcp"}}
+
+	key, ok := toolsetCacheKey("root", "/agents", mcp)
+	require.True(t, ok)
+
+	same, _ := toolsetCacheKey("root", "/agents", &latest.Toolset{Type: "mcp", Command: "gopls", Args: []string{"mcp"}})
+	assert.Equal(t, key, same)
+
+	otherAgent, _ := toolsetCacheKey("helper", "/agents", mcp)
+	assert.NotEqual(t, key, otherAgent)
+
+	otherArgs, _ := toolsetCacheKey("root", "/agents", &latest.Toolset{Type: "mcp", Command: "gopls", Args: []string{"serve"}})
+	assert.NotEqual(t, key, otherArgs)
+
+	_, ok = toolsetCacheKey("root", "/agents", &latest.Toolset{Type: "shell"})
+	assert.False(t, ok)
+}
+
+func TestToolsetReuse(t *testing.T) {
+	t.Parallel()
+
+	cache := NewToolsetCache()
+	ts := &mockToolSet{}
+
+	first := newToolsetReuse(cache)
+	_, ok := first.get("key")
+	assert.False(t, ok)
+	first.put("key", ts)
+	first.commit()
+
+	// A load that fails doesn't change the cache.
+	failed := newToolsetReuse(cache)
+	failed.put("other", &mockToolSet{})
+
+	second := newToolsetReuse(cache)
+	reused, ok := second.get("key")
+	require.True(t, ok)
+	assert.Same(t, ts, reused)
+	_, ok = second.get("other")
+	assert.False(t, ok)
+
+	// The same toolset isn't handed out twice in one load.
+	second.put("key", reused)
+	_, ok = second.get("key")
+	assert.False(t, ok)
+
+	// No cache, no reuse.
+	var none *toolsetReuse
+	_, ok = none.get("key")
+	assert.False(t, ok)
+}
@@ -0,0 +1,42 @@
+package teamloader
+
+import (
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// WithToolSetType wraps a toolset so that it reports the type it was
+// configured with, see tools.ToolSetType.
+func WithToolSetType(inner tools.ToolSet, toolsetType string) tools.ToolSet {
+	if toolsetType == "" {
+		return inner
+	}
+
+	return &typedToolset{
+		ToolSet:     inner,
+		toolsetType: toolsetType,
+	}
+}
+
+type typedToolset struct {
+	tools.ToolSet
+
+	toolsetType string
+}
+
+var (
+	_ tools.Instructable = (*typedToolset)(nil)
+	_ tools.Typer        = (*typedToolset)(nil)
+	_ tools.Unwrapper    = (*typedToolset)(nil)
+)
+
+func (t *typedToolset) Unwrap() tools.ToolSet {
+	return t.ToolSet
+}
+
+func (t *typedToolset) Instructions() string {
+	return tools.GetInstructions(t.ToolSet)
+}
+
+func (t *typedToolset) ToolSetType() string {
+	return t.toolsetType
+}
@@ -0,0 +1,39 @@
+// Package termcap detects whether the terminal docker-agent writes to
+// interprets ANSI escape sequences, so that styled output can fall back to
+// plain text on dumb terminals, pipes, files and legacy Windows consoles.
+package termcap
+
+import (
+	"io"
+	"os"
+
+	"github.com/mattn/go-isatty"
+)
+
+// SupportsANSI reports whether w is a terminal interpreting ANSI escape
+// sequences: colors, but also the cursor moves of the progress bars and the
+// TUI. On Windows, it enables their processing by the console, which legacy
+// consoles refuse.
+func SupportsANSI(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	return supportsANSI(os.Getenv, isatty.IsTerminal(f.Fd()), func() bool {
+		return enableVirtualTerminal(f.Fd())
+	})
+}
+
+// SupportsColor reports whether styled text, with colors or bold, should be
+// written to w: it supports ANSI escape sequences and the user didn't opt
+// out with NO_COLOR (https://no-color.org).
+func SupportsColor(w io.Writer) bool {
+	return os.Getenv("NO_COLOR") == "" && SupportsANSI(w)
+}
+
+func supportsANSI(getenv func(string) string, isTerminal bool, enableVirtualTerminal func() bool) bool {
+	if !isTerminal || getenv("TERM") == "dumb" {
+		return false
+	}
+	return enableVirtualTerminal()
+}
@@ -0,0 +1,43 @@
+package termcap
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSupportsANSI(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		env           map[string]string
+		isTerminal    bool
+		virtualTermOK bool
+		want          bool
+	}{
+		{"terminal", map[string]string{"TERM": "xterm-256color"}, true, true, true},
+		{"no TERM", nil, true, true, true},
+		{"not a terminal", map[string]string{"TERM": "xterm-256color"}, false, true, false},
+		{"dumb terminal", map[string]string{"TERM": "dumb"}, true, true, false},
+		{"legacy Windows console", nil, true, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			getenv := func(key string) string { return tt.env[key] }
+			enableVirtualTerminal := func() bool { return tt.virtualTermOK }
+			assert.Equal(t, tt.want, supportsANSI(getenv, tt.isTerminal, enableVirtualTerminal))
+		})
+	}
+}
+
+func TestSupportsANSI_NotAFile(t *testing.T) {
+	t.Parallel()
+
+	assert.False(t, SupportsANSI(&bytes.Buffer{}))
+	assert.False(t, SupportsColor(&bytes.Buffer{}))
+}
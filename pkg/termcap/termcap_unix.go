@@ -0,0 +1,9 @@
+//go:build !windows
+
+package termcap
+
+// enableVirtualTerminal does nothing: Unix terminals always interpret ANSI
+// escape sequences.
+func enableVirtualTerminal(uintptr) bool {
+	return true
+}
@@ -0,0 +1,18 @@
+package termcap
+
+import "golang.org/x/sys/windows"
+
+// enableVirtualTerminal enables the processing of ANSI escape sequences by
+// the console of fd. It fails on the consoles older than Windows 10.
+func enableVirtualTerminal(fd uintptr) bool {
+	handle := windows.Handle(fd)
+
+	var mode uint32
+	if err := windows.GetConsoleMode(handle, &mode); err != nil {
+		return false
+	}
+	if mode&windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING != 0 {
+		return true
+	}
+	return windows.SetConsoleMode(handle, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING) == nil
+}
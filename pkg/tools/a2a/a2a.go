@@ -46,6 +46,11 @@ func NewToolset(name, url string, headers map[string]string) *Toolset {
 	}
 }
 
+// Name returns the name given to the toolset in the configuration, if any.
+func (t *Toolset) Name() string {
+	return t.name
+}
+
 // Instructions returns instructions for using the A2A toolset.
 func (t *Toolset) Instructions() string {
 	t.mu.RLock()
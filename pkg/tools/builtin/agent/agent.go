@@ -474,6 +474,7 @@ view_background_agent and collect results once the task is complete.`,
 				Title:        "List Background Agents",
 				ReadOnlyHint: true,
 			},
+			NoResultCache: true,
 		},
 		{
 			Name:        ToolNameViewBackgroundAgent,
@@ -484,6 +485,7 @@ view_background_agent and collect results once the task is complete.`,
 				Title:        "View Background Agent",
 				ReadOnlyHint: true,
 			},
+			NoResultCache: true,
 		},
 		{
 			Name:        ToolNameStopBackgroundAgent,
@@ -0,0 +1,122 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// AgentToolArgs are the arguments of an agent tool created without a
+// parameters schema.
+type AgentToolArgs struct {
+	Task string `json:"task" jsonschema:"A clear and concise description of the task the agent should achieve."`
+}
+
+// AgentTool exposes an agent of the team as a tool with typed parameters.
+// Calling the tool runs the agent in a new session, with the prompt rendered
+// from the arguments, and returns its last message. The call is handled by
+// the runtime, which owns the team.
+type AgentTool struct {
+	agentName   string
+	toolName    string
+	description string
+	parameters  any
+	prompt      *template.Template
+}
+
+var _ tools.ToolSet = (*AgentTool)(nil)
+
+// NewAgentTool creates a tool named toolName that runs the agent named
+// agentName. parameters is the JSON schema of the arguments and
+// promptTemplate a text/template rendered with the arguments to build the
+// agent's task, e.g. "Summarize {{.text}} in {{.words}} words".
+//
+// Without parameters, the tool takes a single "task" argument. Without a
+// prompt template, the task is the JSON arguments.
+func NewAgentTool(agentName, toolName, description string, parameters any, promptTemplate string) (*AgentTool, error) {
+	if agentName == "" {
+		return nil, errors.New("agent tool requires an agent name")
+	}
+	if toolName == "" {
+		toolName = agentName
+	}
+	if description == "" {
+		description = fmt.Sprintf("Run the %s agent.", agentName)
+	}
+	if parameters == nil {
+		parameters = tools.MustSchemaFor[AgentToolArgs]()
+		if promptTemplate == "" {
+			promptTemplate = "{{.task}}"
+		}
+	}
+
+	t := &AgentTool{
+		agentName:   agentName,
+		toolName:    toolName,
+		description: description,
+		parameters:  parameters,
+	}
+
+	if promptTemplate != "" {
+		prompt, err := template.New(toolName).Parse(promptTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prompt template for agent tool %s: %w", toolName, err)
+		}
+		t.prompt = prompt
+	}
+
+	return t, nil
+}
+
+// AgentName returns the name of the agent run by the tool.
+func (t *AgentTool) AgentName() string {
+	return t.agentName
+}
+
+// ToolName returns the name of the tool.
+func (t *AgentTool) ToolName() string {
+	return t.toolName
+}
+
+// RenderPrompt builds the agent's task from the JSON arguments of a call.
+func (t *AgentTool) RenderPrompt(arguments string) (string, error) {
+	if strings.TrimSpace(arguments) == "" {
+		arguments = "{}"
+	}
+
+	var args map[string]any
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	if t.prompt == nil {
+		return arguments, nil
+	}
+
+	var sb strings.Builder
+	if err := t.prompt.Execute(&sb, args); err != nil {
+		return "", fmt.Errorf("rendering prompt: %w", err)
+	}
+	return sb.String(), nil
+}
+
+func (t *AgentTool) Tools(context.Context) ([]tools.Tool, error) {
+	return []tools.Tool{
+		{
+			Name:        t.toolName,
+			Category:    "agent",
+			Description: t.description,
+			Parameters:  t.parameters,
+			Annotations: tools.ToolAnnotations{
+				// The tools of the agent ask for approval themselves.
+				ReadOnlyHint: true,
+				Title:        "Run Agent " + t.agentName,
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,75 @@
+package builtin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentTool_Defaults(t *testing.T) {
+	tool, err := NewAgentTool("reviewer", "", "", nil, "")
+	require.NoError(t, err)
+
+	assert.Equal(t, "reviewer", tool.AgentName())
+	assert.Equal(t, "reviewer", tool.ToolName())
+
+	allTools, err := tool.Tools(t.Context())
+	require.NoError(t, err)
+	require.Len(t, allTools, 1)
+	assert.Equal(t, "reviewer", allTools[0].Name)
+	assert.Equal(t, "Run the reviewer agent.", allTools[0].Description)
+	assert.Nil(t, allTools[0].Handler)
+
+	prompt, err := tool.RenderPrompt(`{"task":"review main.go"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "review main.go", prompt)
+}
+
+func TestAgentTool_TypedParameters(t *testing.T) {
+	parameters := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"file":  map[string]any{"type": "string"},
+			"focus": map[string]any{"type": "string"},
+		},
+		"required": []string{"file"},
+	}
+
+	tool, err := NewAgentTool("reviewer", "review_file", "Review a file.", parameters, "Review {{.file}}{{with .focus}}, focusing on {{.}}{{end}}.")
+	require.NoError(t, err)
+
+	allTools, err := tool.Tools(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "review_file", allTools[0].Name)
+	assert.Equal(t, "Review a file.", allTools[0].Description)
+	assert.Equal(t, parameters, allTools[0].Parameters)
+
+	prompt, err := tool.RenderPrompt(`{"file":"main.go","focus":"errors"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "Review main.go, focusing on errors.", prompt)
+
+	prompt, err = tool.RenderPrompt(`{"file":"main.go"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "Review main.go.", prompt)
+
+	_, err = tool.RenderPrompt(`not json`)
+	require.Error(t, err)
+}
+
+func TestAgentTool_NoPromptTemplate(t *testing.T) {
+	tool, err := NewAgentTool("reviewer", "review", "", map[string]any{"type": "object"}, "")
+	require.NoError(t, err)
+
+	prompt, err := tool.RenderPrompt(`{"file":"main.go"}`)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"file":"main.go"}`, prompt)
+}
+
+func TestAgentTool_Errors(t *testing.T) {
+	_, err := NewAgentTool("", "review", "", nil, "")
+	require.Error(t, err)
+
+	_, err = NewAgentTool("reviewer", "review", "", nil, "{{.file")
+	require.ErrorContains(t, err, "invalid prompt template")
+}
@@ -0,0 +1,154 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+const ToolNameAskUser = "ask_user"
+
+// DefaultAskUserTimeout is how long ask_user waits for an answer by default.
+const DefaultAskUserTimeout = 10 * time.Minute
+
+// askUserAnswerField is the field of the elicitation form holding the answer.
+const askUserAnswerField = "answer"
+
+type AskUserTool struct {
+	elicitationHandler tools.ElicitationHandler
+	timeout            time.Duration
+}
+
+// Verify interface compliance
+var (
+	_ tools.ToolSet      = (*AskUserTool)(nil)
+	_ tools.Elicitable   = (*AskUserTool)(nil)
+	_ tools.Instructable = (*AskUserTool)(nil)
+)
+
+type AskUserArgs struct {
+	Question string   `json:"question" jsonschema:"The question to ask the user"`
+	Choices  []string `json:"choices,omitempty" jsonschema:"Optional list of answers the user must choose from"`
+}
+
+// AskUserOption is a functional option for configuring an AskUserTool.
+type AskUserOption func(*AskUserTool)
+
+// WithAskUserTimeout sets how long the tool waits for the user to answer
+// before giving up. Zero or less means no timeout.
+func WithAskUserTimeout(timeout time.Duration) AskUserOption {
+	return func(t *AskUserTool) {
+		t.timeout = timeout
+	}
+}
+
+func NewAskUserTool(opts ...AskUserOption) *AskUserTool {
+	t := &AskUserTool{
+		timeout: DefaultAskUserTimeout,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+func (t *AskUserTool) SetElicitationHandler(handler tools.ElicitationHandler) {
+	t.elicitationHandler = handler
+}
+
+// noUserAvailable is the result of a question nobody answered.
+func noUserAvailable(reason string) *tools.ToolCallResult {
+	return tools.ResultError("No user available to answer the question (" + reason + "). Proceed with your best judgement and state the assumptions you made.")
+}
+
+func (t *AskUserTool) askUser(ctx context.Context, params AskUserArgs) (*tools.ToolCallResult, error) {
+	if strings.TrimSpace(params.Question) == "" {
+		return tools.ResultError("question is required"), nil
+	}
+	if t.elicitationHandler == nil {
+		return noUserAvailable("no client handles questions"), nil
+	}
+
+	answer := map[string]any{
+		"type":  "string",
+		"title": "Answer",
+	}
+	if len(params.Choices) > 0 {
+		choices := make([]any, len(params.Choices))
+		for i, choice := range params.Choices {
+			choices[i] = choice
+		}
+		answer["enum"] = choices
+	}
+
+	req := &mcp.ElicitParams{
+		Message: params.Question,
+		RequestedSchema: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{askUserAnswerField: answer},
+			"required":   []any{askUserAnswerField},
+		},
+	}
+
+	askCtx := ctx
+	if t.timeout > 0 {
+		var cancel context.CancelFunc
+		askCtx, cancel = context.WithTimeout(ctx, t.timeout)
+		defer cancel()
+	}
+
+	result, err := t.elicitationHandler(askCtx, req)
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		return nil, ctx.Err()
+	case errors.Is(err, context.DeadlineExceeded):
+		return noUserAvailable(fmt.Sprintf("no answer within %s", t.timeout)), nil
+	default:
+		return noUserAvailable(err.Error()), nil
+	}
+
+	switch result.Action {
+	case tools.ElicitationActionAccept:
+		answer, _ := result.Content[askUserAnswerField].(string)
+		return tools.ResultSuccess(answer), nil
+	case tools.ElicitationActionDecline:
+		return tools.ResultError("The user declined to answer the question."), nil
+	default:
+		return tools.ResultError("The user cancelled the question."), nil
+	}
+}
+
+func (t *AskUserTool) Instructions() string {
+	return `## Ask User Tool
+
+Ask the user a clarifying question when you can't make progress without their input, e.g. an ambiguous request or a choice only they can make. Don't ask about things you can find out yourself.
+
+Give "choices" when the answer is one of a few options. The answer is returned as the tool result. When no user is available, proceed with your best judgement and state your assumptions.`
+}
+
+func (t *AskUserTool) Tools(context.Context) ([]tools.Tool, error) {
+	return []tools.Tool{
+		{
+			Name:         ToolNameAskUser,
+			Category:     "ask_user",
+			Description:  "Ask the user a clarifying question and wait for their answer. Optionally give the choices the user must pick from.",
+			Parameters:   tools.MustSchemaFor[AskUserArgs](),
+			OutputSchema: tools.MustSchemaFor[string](),
+			Handler:      tools.NewHandler(t.askUser),
+			Annotations: tools.ToolAnnotations{
+				ReadOnlyHint: true,
+				Title:        "Ask User",
+			},
+			NoResultCache: true,
+			// The question has its own timeout, see WithAskUserTimeout.
+			Timeout: tools.NoTimeout,
+		},
+	}, nil
+}
@@ -0,0 +1,107 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+func TestAskUserTool_Answer(t *testing.T) {
+	tool := NewAskUserTool()
+	tool.SetElicitationHandler(func(_ context.Context, req *mcp.ElicitParams) (tools.ElicitationResult, error) {
+		assert.Equal(t, "Which environment?", req.Message)
+		assert.Equal(t, map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"answer": map[string]any{"type": "string", "title": "Answer", "enum": []any{"staging", "production"}},
+			},
+			"required": []any{"answer"},
+		}, req.RequestedSchema)
+		return tools.ElicitationResult{
+			Action:  tools.ElicitationActionAccept,
+			Content: map[string]any{"answer": "staging"},
+		}, nil
+	})
+
+	result, err := tool.askUser(t.Context(), AskUserArgs{Question: "Which environment?", Choices: []string{"staging", "production"}})
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.Equal(t, "staging", result.Output)
+}
+
+func TestAskUserTool_Declined(t *testing.T) {
+	tool := NewAskUserTool()
+	tool.SetElicitationHandler(func(context.Context, *mcp.ElicitParams) (tools.ElicitationResult, error) {
+		return tools.ElicitationResult{Action: tools.ElicitationActionDecline}, nil
+	})
+
+	result, err := tool.askUser(t.Context(), AskUserArgs{Question: "Proceed?"})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Equal(t, "The user declined to answer the question.", result.Output)
+}
+
+func TestAskUserTool_NoUserAvailable(t *testing.T) {
+	t.Run("no handler", func(t *testing.T) {
+		result, err := NewAskUserTool().askUser(t.Context(), AskUserArgs{Question: "Proceed?"})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, result.Output, "No user available to answer the question (no client handles questions)")
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		tool := NewAskUserTool(WithAskUserTimeout(10 * time.Millisecond))
+		tool.SetElicitationHandler(func(ctx context.Context, _ *mcp.ElicitParams) (tools.ElicitationResult, error) {
+			<-ctx.Done()
+			return tools.ElicitationResult{}, ctx.Err()
+		})
+
+		result, err := tool.askUser(t.Context(), AskUserArgs{Question: "Proceed?"})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, result.Output, "No user available to answer the question (no answer within 10ms)")
+	})
+
+	t.Run("handler error", func(t *testing.T) {
+		tool := NewAskUserTool()
+		tool.SetElicitationHandler(func(context.Context, *mcp.ElicitParams) (tools.ElicitationResult, error) {
+			return tools.ElicitationResult{}, errors.New("no events channel available for elicitation")
+		})
+
+		result, err := tool.askUser(t.Context(), AskUserArgs{Question: "Proceed?"})
+		require.NoError(t, err)
+		assert.Contains(t, result.Output, "No user available to answer the question (no events channel available for elicitation)")
+	})
+}
+
+func TestAskUserTool_Cancelled(t *testing.T) {
+	tool := NewAskUserTool()
+	tool.SetElicitationHandler(func(ctx context.Context, _ *mcp.ElicitParams) (tools.ElicitationResult, error) {
+		<-ctx.Done()
+		return tools.ElicitationResult{}, ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	_, err := tool.askUser(ctx, AskUserArgs{Question: "Proceed?"})
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestAskUserTool_Tools(t *testing.T) {
+	allTools, err := NewAskUserTool().Tools(t.Context())
+	require.NoError(t, err)
+	require.Len(t, allTools, 1)
+	assert.Equal(t, ToolNameAskUser, allTools[0].Name)
+	assert.True(t, allTools[0].Annotations.ReadOnlyHint)
+
+	m, err := tools.SchemaToMap(allTools[0].Parameters)
+	require.NoError(t, err)
+	assert.Equal(t, []any{"question"}, m["required"])
+}
@@ -5,6 +5,8 @@ package builtin
 import (
 	"os"
 	"syscall"
+
+	"github.com/docker/docker-agent/pkg/procgroup"
 )
 
 type processGroup struct {
@@ -12,15 +14,15 @@ type processGroup struct {
 }
 
 func platformSpecificSysProcAttr() *syscall.SysProcAttr {
-	return &syscall.SysProcAttr{
-		Setpgid: true,
-	}
+	return procgroup.SysProcAttr()
 }
 
 func createProcessGroup(_ *os.Process) (*processGroup, error) {
 	return &processGroup{}, nil
 }
 
+// kill terminates the process group, escalating to SIGKILL if it doesn't
+// exit within procgroup.GracePeriod.
 func kill(proc *os.Process, _ *processGroup) error {
-	return syscall.Kill(-proc.Pid, syscall.SIGTERM)
+	return procgroup.Terminate(proc)
 }
@@ -7,6 +7,7 @@ import (
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/docker/docker-agent/pkg/tools"
 )
@@ -26,6 +27,8 @@ type DeferredToolset struct {
 	deferredTools  map[string]deferredToolEntry
 	activatedTools map[string]tools.Tool
 	sources        []deferredSource
+	// version is bumped whenever a tool is activated.
+	version atomic.Uint64
 }
 
 // Verify interface compliance
@@ -33,6 +36,7 @@ var (
 	_ tools.ToolSet      = (*DeferredToolset)(nil)
 	_ tools.Startable    = (*DeferredToolset)(nil)
 	_ tools.Instructable = (*DeferredToolset)(nil)
+	_ tools.Versioned    = (*DeferredToolset)(nil)
 )
 
 type deferredSource struct {
@@ -130,10 +134,16 @@ func (d *DeferredToolset) handleAddTool(_ context.Context, args AddToolArgs) (*t
 
 	delete(d.deferredTools, args.Name)
 	d.activatedTools[args.Name] = entry.tool
+	d.version.Add(1)
 
 	return tools.ResultSuccess(fmt.Sprintf("Tool '%s' has been activated and is now available for use.\n\nDescription: %s", args.Name, entry.tool.Description)), nil
 }
 
+// ToolsVersion changes whenever a tool is activated with add_tool.
+func (d *DeferredToolset) ToolsVersion() uint64 {
+	return d.version.Load()
+}
+
 func (d *DeferredToolset) Tools(context.Context) ([]tools.Tool, error) {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
@@ -150,6 +160,7 @@ func (d *DeferredToolset) Tools(context.Context) ([]tools.Tool, error) {
 				Title:        "Search Tool",
 				ReadOnlyHint: true,
 			},
+			NoResultCache: true,
 		},
 		{
 			Name:         ToolNameAddTool,
@@ -162,6 +173,7 @@ func (d *DeferredToolset) Tools(context.Context) ([]tools.Tool, error) {
 				Title:        "Add Tool",
 				ReadOnlyHint: true,
 			},
+			NoResultCache: true,
 		},
 	}
 
@@ -77,6 +77,7 @@ func TestDeferredToolset_AddTool(t *testing.T) {
 		result, err := dt.handleAddTool(ctx, AddToolArgs{Name: "tool1"})
 		require.NoError(t, err)
 		assert.Contains(t, result.Output, "has been activated")
+		assert.Equal(t, uint64(1), dt.ToolsVersion())
 
 		currentTools, err := dt.Tools(ctx)
 		require.NoError(t, err)
@@ -90,9 +91,11 @@ func TestDeferredToolset_AddTool(t *testing.T) {
 	})
 
 	t.Run("add already active tool", func(t *testing.T) {
+		version := dt.ToolsVersion()
 		result, err := dt.handleAddTool(ctx, AddToolArgs{Name: "tool1"})
 		require.NoError(t, err)
 		assert.Contains(t, result.Output, "already active")
+		assert.Equal(t, version, dt.ToolsVersion())
 	})
 
 	t.Run("add non-existent tool", func(t *testing.T) {
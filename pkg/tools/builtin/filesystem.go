@@ -1,6 +1,7 @@
 package builtin
 
 import (
+	"cmp"
 	"context"
 	"encoding/base64"
 	"encoding/json"
@@ -39,9 +40,16 @@ type PostEditConfig struct {
 	Cmd  string // Command to execute (with $path placeholder)
 }
 
+// EditListener is told about the files written by the filesystem tools,
+// e.g. an LSPTool that has to send the new content to its server.
+type EditListener interface {
+	NotifyExternalEdit(path string) error
+}
+
 type FilesystemTool struct {
 	workingDir       string
 	postEditCommands []PostEditConfig
+	editListeners    []EditListener
 	ignoreVCS        bool
 	repoMatcher      *fsx.VCSMatcher
 	repoMatcherOnce  sync.Once
@@ -51,6 +59,7 @@ type FilesystemTool struct {
 var (
 	_ tools.ToolSet      = (*FilesystemTool)(nil)
 	_ tools.Instructable = (*FilesystemTool)(nil)
+	_ tools.Previewable  = (*FilesystemTool)(nil)
 )
 
 // allowAllPaths is a no-op path filter that permits every path.
@@ -70,6 +79,12 @@ func WithIgnoreVCS(ignoreVCS bool) FileSystemOpt {
 	}
 }
 
+// AddEditListener registers a listener told about each file written or
+// edited by the tools, once the post-edit commands ran.
+func (t *FilesystemTool) AddEditListener(listener EditListener) {
+	t.editListeners = append(t.editListeners, listener)
+}
+
 func NewFilesystemTool(workingDir string, opts ...FileSystemOpt) *FilesystemTool {
 	t := &FilesystemTool{
 		workingDir: workingDir,
@@ -408,6 +423,15 @@ func (t *FilesystemTool) Tools(context.Context) ([]tools.Tool, error) {
 	}, nil
 }
 
+// notifyEditListeners tells the edit listeners that a file was written.
+func (t *FilesystemTool) notifyEditListeners(ctx context.Context, filePath string) {
+	for _, listener := range t.editListeners {
+		if err := listener.NotifyExternalEdit(filePath); err != nil {
+			slog.DebugContext(ctx, "Edit listener failed", "path", filePath, "error", err)
+		}
+	}
+}
+
 // executePostEditCommands executes any matching post-edit commands for the given file path
 func (t *FilesystemTool) executePostEditCommands(ctx context.Context, filePath string) error {
 	if len(t.postEditCommands) == 0 {
@@ -426,6 +450,7 @@ func (t *FilesystemTool) executePostEditCommands(ctx context.Context, filePath s
 
 		shell, argsPrefix := shellpath.DetectShell()
 		cmd := exec.CommandContext(ctx, shell, append(argsPrefix, postEdit.Cmd)...)
+		shellpath.SetCommandLine(cmd, argsPrefix, postEdit.Cmd)
 		cmd.Env = cmd.Environ()
 		cmd.Env = append(cmd.Env, "file="+filePath)
 
@@ -561,23 +586,25 @@ func (t *FilesystemTool) handleEditFile(ctx context.Context, args EditFileArgs)
 		return tools.ResultError(fmt.Sprintf("Error reading file: %s", err)), nil
 	}
 
-	originalContent := string(content)
-	modifiedContent := originalContent
+	modifiedContent, err := applyEdits(string(content), args.Edits)
+	if err != nil {
+		return tools.ResultError(err.Error()), nil
+	}
 
 	var changes []string
 	for i, edit := range args.Edits {
-		if !strings.Contains(modifiedContent, edit.OldText) {
-			return tools.ResultError(fmt.Sprintf("Edit %d failed: old text not found", i+1)), nil
-		}
-		modifiedContent = strings.Replace(modifiedContent, edit.OldText, edit.NewText, 1)
 		changes = append(changes, fmt.Sprintf("Edit %d: Replaced %d characters", i+1, len(edit.OldText)))
 	}
 
+	tools.WillChangeFile(ctx, resolvedPath)
 	if err := os.WriteFile(resolvedPath, []byte(modifiedContent), 0o644); err != nil {
 		return tools.ResultError(fmt.Sprintf("Error writing file: %s", err)), nil
 	}
+	tools.ReportFileChange(ctx, tools.FileChange{Path: resolvedPath, Type: tools.FileModified})
 
-	if err := t.executePostEditCommands(ctx, resolvedPath); err != nil {
+	err = t.executePostEditCommands(ctx, resolvedPath)
+	t.notifyEditListeners(ctx, resolvedPath)
+	if err != nil {
 		return tools.ResultError(fmt.Sprintf("File edited successfully but post-edit command failed: %s", err)), nil
 	}
 
@@ -588,6 +615,52 @@ func (t *FilesystemTool) handleEditFile(ctx context.Context, args EditFileArgs)
 	return tools.ResultSuccess("File edited successfully. Changes:\n" + strings.Join(changes, "\n")), nil
 }
 
+// applyEdits applies the edits of an edit_file call to content, in order.
+func applyEdits(content string, edits []Edit) (string, error) {
+	for i, edit := range edits {
+		if !strings.Contains(content, edit.OldText) {
+			return "", fmt.Errorf("Edit %d failed: old text not found", i+1) //nolint:staticcheck // Shown to the model as is.
+		}
+		content = strings.Replace(content, edit.OldText, edit.NewText, 1)
+	}
+	return content, nil
+}
+
+// Preview returns the diff of the changes a call to edit_file or write_file
+// would make, without making them.
+func (t *FilesystemTool) Preview(_ context.Context, toolCall tools.ToolCall) (*tools.Preview, error) {
+	data := cmp.Or(toolCall.Function.Arguments, "{}")
+
+	switch toolCall.Function.Name {
+	case ToolNameEditFile:
+		args, err := ParseEditFileArgs([]byte(data))
+		if err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(t.resolvePath(args.Path))
+		if err != nil {
+			return nil, err
+		}
+		modifiedContent, err := applyEdits(string(content), args.Edits)
+		if err != nil {
+			return nil, err
+		}
+		return &tools.Preview{Diff: fileDiff(args.Path, true, string(content), modifiedContent)}, nil
+	case ToolNameWriteFile:
+		var args WriteFileArgs
+		if err := json.Unmarshal([]byte(data), &args); err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(t.resolvePath(args.Path))
+		exists := err == nil
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		return &tools.Preview{Diff: fileDiff(args.Path, exists, string(content), args.Content)}, nil
+	}
+	return nil, nil
+}
+
 func (t *FilesystemTool) handleListDirectory(_ context.Context, args ListDirectoryArgs) (*tools.ToolCallResult, error) {
 	resolvedPath := t.resolvePath(args.Path)
 
@@ -907,11 +980,20 @@ func (t *FilesystemTool) handleWriteFile(ctx context.Context, args WriteFileArgs
 		return tools.ResultError(fmt.Sprintf("Error creating directory structure: %s", err)), nil
 	}
 
+	changeType := tools.FileModified
+	if _, err := os.Stat(resolvedPath); errors.Is(err, fs.ErrNotExist) {
+		changeType = tools.FileCreated
+	}
+
+	tools.WillChangeFile(ctx, resolvedPath)
 	if err := os.WriteFile(resolvedPath, []byte(args.Content), 0o644); err != nil {
 		return tools.ResultError(fmt.Sprintf("Error writing file: %s", err)), nil
 	}
+	tools.ReportFileChange(ctx, tools.FileChange{Path: resolvedPath, Type: changeType})
 
-	if err := t.executePostEditCommands(ctx, resolvedPath); err != nil {
+	err := t.executePostEditCommands(ctx, resolvedPath)
+	t.notifyEditListeners(ctx, resolvedPath)
+	if err != nil {
 		return tools.ResultError(fmt.Sprintf("File written successfully but post-edit command failed: %s", err)), nil
 	}
 
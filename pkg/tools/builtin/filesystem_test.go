@@ -13,6 +13,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/tools"
 )
 
 // initGitRepo initializes a git repository in the given directory
@@ -87,6 +89,54 @@ func TestFilesystemTool_WriteFile(t *testing.T) {
 	assert.Equal(t, content, string(writtenContent))
 }
 
+type recordingEditListener struct {
+	paths []string
+}
+
+func (l *recordingEditListener) NotifyExternalEdit(path string) error {
+	l.paths = append(l.paths, path)
+	return nil
+}
+
+func TestFilesystemTool_EditListeners(t *testing.T) {
+	t.Parallel()
+	tmpDir := t.TempDir()
+	tool := NewFilesystemTool(tmpDir)
+	listener := &recordingEditListener{}
+	tool.AddEditListener(listener)
+
+	_, err := tool.handleWriteFile(t.Context(), WriteFileArgs{Path: "main.go", Content: "package main\n"})
+	require.NoError(t, err)
+	_, err = tool.handleEditFile(t.Context(), EditFileArgs{Path: "main.go", Edits: []Edit{{OldText: "main", NewText: "app"}}})
+	require.NoError(t, err)
+
+	path := filepath.Join(tmpDir, "main.go")
+	assert.Equal(t, []string{path, path}, listener.paths)
+}
+
+func TestFilesystemTool_ReportsFileChanges(t *testing.T) {
+	t.Parallel()
+	tmpDir := t.TempDir()
+	tool := NewFilesystemTool(tmpDir)
+	reporter := &fileChangeRecorder{}
+	ctx := tools.WithFileChangeReporter(t.Context(), reporter)
+
+	_, err := tool.handleWriteFile(ctx, WriteFileArgs{Path: "main.go", Content: "package main\n"})
+	require.NoError(t, err)
+	_, err = tool.handleEditFile(ctx, EditFileArgs{Path: "main.go", Edits: []Edit{{OldText: "main", NewText: "app"}}})
+	require.NoError(t, err)
+	_, err = tool.handleWriteFile(ctx, WriteFileArgs{Path: "main.go", Content: "package other\n"})
+	require.NoError(t, err)
+
+	path := filepath.Join(tmpDir, "main.go")
+	assert.Equal(t, []string{path, path, path}, reporter.willChange)
+	assert.Equal(t, []tools.FileChange{
+		{Path: path, Type: tools.FileCreated},
+		{Path: path, Type: tools.FileModified},
+		{Path: path, Type: tools.FileModified},
+	}, reporter.changes)
+}
+
 func TestFilesystemTool_WriteFile_NestedDirectory(t *testing.T) {
 	t.Parallel()
 	tmpDir := t.TempDir()
@@ -260,6 +310,54 @@ func TestFilesystemTool_EditFile(t *testing.T) {
 	assert.Contains(t, result.Output, "old text not found")
 }
 
+func TestFilesystemTool_Preview(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	tool := NewFilesystemTool(tmpDir)
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "test.txt"), []byte("Hello World\nGoodbye World\n"), 0o644))
+
+	preview := func(name, arguments string) (*tools.Preview, error) {
+		return tool.Preview(t.Context(), tools.ToolCall{Function: tools.FunctionCall{Name: name, Arguments: arguments}})
+	}
+
+	t.Run("edit_file", func(t *testing.T) {
+		t.Parallel()
+
+		p, err := preview(ToolNameEditFile, `{"path": "test.txt", "edits": [{"oldText": "Goodbye", "newText": "See you"}]}`)
+		require.NoError(t, err)
+		assert.Equal(t, "--- test.txt\n+++ test.txt\n@@ -1,2 +1,2 @@\n Hello World\n-Goodbye World\n+See you World\n", p.Diff)
+
+		content, err := os.ReadFile(filepath.Join(tmpDir, "test.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "Hello World\nGoodbye World\n", string(content))
+	})
+
+	t.Run("edit_file with missing old text", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := preview(ToolNameEditFile, `{"path": "test.txt", "edits": [{"oldText": "Bonjour", "newText": "Salut"}]}`)
+		require.ErrorContains(t, err, "old text not found")
+	})
+
+	t.Run("write_file to a new file", func(t *testing.T) {
+		t.Parallel()
+
+		p, err := preview(ToolNameWriteFile, `{"path": "new.txt", "content": "Hello\n"}`)
+		require.NoError(t, err)
+		assert.Equal(t, "--- /dev/null\n+++ new.txt\n@@ -0,0 +1 @@\n+Hello\n", p.Diff)
+		assert.NoFileExists(t, filepath.Join(tmpDir, "new.txt"))
+	})
+
+	t.Run("other tools", func(t *testing.T) {
+		t.Parallel()
+
+		p, err := preview(ToolNameReadFile, `{"path": "test.txt"}`)
+		require.NoError(t, err)
+		assert.Nil(t, p)
+	})
+}
+
 func TestParseEditFileArgs(t *testing.T) {
 	t.Parallel()
 
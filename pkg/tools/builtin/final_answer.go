@@ -0,0 +1,65 @@
+package builtin
+
+import (
+	"context"
+	"strings"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+const ToolNameFinalAnswer = "final_answer"
+
+// FinalAnswerTool lets an agent hand over the deliverable of its turn apart
+// from the prose around it, e.g. for the agent that transferred it a task to
+// parse. The runtime reads the deliverable from the final_answer call in the
+// session: the tool itself only acknowledges it.
+type FinalAnswerTool struct{}
+
+// Verify interface compliance
+var (
+	_ tools.ToolSet      = (*FinalAnswerTool)(nil)
+	_ tools.Instructable = (*FinalAnswerTool)(nil)
+)
+
+type FinalAnswerArgs struct {
+	Content string `json:"content" jsonschema:"The deliverable, exactly as it was asked for, without explanations around it."`
+	Format  string `json:"format,omitempty" jsonschema:"The format of the content, e.g. text, markdown, json or yaml (optional)."`
+}
+
+func NewFinalAnswerTool() *FinalAnswerTool {
+	return &FinalAnswerTool{}
+}
+
+func (t *FinalAnswerTool) callTool(_ context.Context, params FinalAnswerArgs) (*tools.ToolCallResult, error) {
+	if strings.TrimSpace(params.Content) == "" {
+		return tools.ResultError("content is required: call final_answer with the deliverable"), nil
+	}
+	return tools.ResultSuccess("Final answer recorded."), nil
+}
+
+func (t *FinalAnswerTool) Instructions() string {
+	return `## Final Answer
+
+When you have the deliverable of your task, call final_answer with it:
+- Put only the deliverable in content, in the format that was asked for, without explanations around it
+- Explain your work, if needed, in your message, not in the final answer
+- Call it again to replace the final answer if you change it`
+}
+
+func (t *FinalAnswerTool) Tools(context.Context) ([]tools.Tool, error) {
+	return []tools.Tool{
+		{
+			Name:         ToolNameFinalAnswer,
+			Category:     "final_answer",
+			Description:  "Hand over the deliverable of your task, separate from your explanations. The last final answer of the turn is the result of the task.",
+			Parameters:   tools.MustSchemaFor[FinalAnswerArgs](),
+			OutputSchema: tools.MustSchemaFor[string](),
+			Handler:      tools.NewHandler(t.callTool),
+			Annotations: tools.ToolAnnotations{
+				ReadOnlyHint: true,
+				Title:        "Final Answer",
+			},
+			NoResultCache: true,
+		},
+	}, nil
+}
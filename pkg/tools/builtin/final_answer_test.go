@@ -0,0 +1,29 @@
+package builtin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFinalAnswerTool_Handler(t *testing.T) {
+	tool := NewFinalAnswerTool()
+
+	result, err := tool.callTool(t.Context(), FinalAnswerArgs{Content: `{"chapter":3}`, Format: "json"})
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.Equal(t, "Final answer recorded.", result.Output)
+
+	result, err = tool.callTool(t.Context(), FinalAnswerArgs{Content: " "})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+}
+
+func TestFinalAnswerTool_ReadOnly(t *testing.T) {
+	allTools, err := NewFinalAnswerTool().Tools(t.Context())
+	require.NoError(t, err)
+	require.Len(t, allTools, 1)
+	assert.Equal(t, ToolNameFinalAnswer, allTools[0].Name)
+	assert.True(t, allTools[0].Annotations.ReadOnlyHint)
+}
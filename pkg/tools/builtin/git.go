@@ -0,0 +1,383 @@
+package builtin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+const (
+	ToolNameGitStatus = "git_status"
+	ToolNameGitDiff   = "git_diff"
+	ToolNameGitLog    = "git_log"
+	ToolNameGitBlame  = "git_blame"
+	ToolNameGitAdd    = "git_add"
+	ToolNameGitCommit = "git_commit"
+)
+
+const (
+	// defaultGitDiffMaxLines is the number of diff lines returned when the
+	// model doesn't ask for a limit.
+	defaultGitDiffMaxLines = 500
+	// defaultGitDiffContext is the number of context lines around each hunk,
+	// as with git itself.
+	defaultGitDiffContext = 3
+	// defaultGitLogLimit is the number of commits listed by default.
+	defaultGitLogLimit = 20
+	// maxGitLogLimit is the largest number of commits listed at once.
+	maxGitLogLimit = 200
+	// maxGitTurnFiles is the number of changed files listed in the
+	// instructions of each turn.
+	maxGitTurnFiles = 20
+)
+
+// GitTool gives agents the common git commands on a repository, with
+// arguments built from typed parameters rather than command lines. Git is
+// run directly, never through a shell.
+type GitTool struct {
+	repoPath string
+}
+
+// Verify interface compliance
+var (
+	_ tools.ToolSet             = (*GitTool)(nil)
+	_ tools.Instructable        = (*GitTool)(nil)
+	_ tools.DynamicInstructable = (*GitTool)(nil)
+)
+
+type GitStatusArgs struct{}
+
+type GitDiffArgs struct {
+	Path         string `json:"path,omitempty" jsonschema:"Only show the changes of this file or directory, relative to the repository root"`
+	Staged       bool   `json:"staged,omitempty" jsonschema:"Show the staged changes instead of the unstaged ones"`
+	ContextLines *int   `json:"context_lines,omitempty" jsonschema:"Number of unchanged lines shown around each change (default: 3)"`
+	MaxLines     int    `json:"max_lines,omitempty" jsonschema:"Maximum number of lines of diff returned (default: 500)"`
+}
+
+type GitLogArgs struct {
+	Path  string `json:"path,omitempty" jsonschema:"Only list the commits changing this file or directory, following renames of a file"`
+	Limit int    `json:"limit,omitempty" jsonschema:"Maximum number of commits listed (default: 20, max: 200)"`
+}
+
+type GitBlameArgs struct {
+	Path      string `json:"path" jsonschema:"Path of the file, relative to the repository root"`
+	StartLine int    `json:"start_line,omitempty" jsonschema:"Start line of the range (1-based, default: 1)"`
+	EndLine   int    `json:"end_line,omitempty" jsonschema:"End line of the range (1-based, default: end of file)"`
+}
+
+type GitAddArgs struct {
+	Paths []string `json:"paths" jsonschema:"Paths of the files or directories to stage, relative to the repository root"`
+}
+
+type GitCommitArgs struct {
+	Message string `json:"message" jsonschema:"The commit message"`
+}
+
+// NewGitTool returns the git tools for the repository at repoPath, or the
+// repository containing it.
+func NewGitTool(repoPath string) *GitTool {
+	return &GitTool{repoPath: repoPath}
+}
+
+// run runs git with the given arguments in the repository and returns its
+// standard output. The error holds the standard error of git when it fails.
+func (t *GitTool) run(ctx context.Context, args ...string) (string, error) {
+	args = append([]string{
+		"-C", t.repoPath,
+		"--no-pager",
+		"-c", "color.ui=false",
+		// Paths are printed as they are, not quoted and escaped.
+		"-c", "core.quotepath=false",
+	}, args...)
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = append(os.Environ(),
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_EDITOR=true",
+		"GIT_OPTIONAL_LOCKS=0",
+		"LC_ALL=C",
+	)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return stdout.String(), errors.New(msg)
+		}
+		return stdout.String(), err
+	}
+	return stdout.String(), nil
+}
+
+// gitError is the result of a git command that failed.
+func gitError(command string, err error) *tools.ToolCallResult {
+	return tools.ResultError(fmt.Sprintf("git %s failed: %s", command, err))
+}
+
+func (t *GitTool) status(ctx context.Context, _ GitStatusArgs) (*tools.ToolCallResult, error) {
+	out, err := t.run(ctx, "status", "--short", "--branch", "--untracked-files=all", "--find-renames")
+	if err != nil {
+		return gitError("status", err), nil
+	}
+
+	out = strings.TrimRight(out, "\n")
+	// The first line is the branch, only followed by the changed files.
+	if !strings.Contains(out, "\n") {
+		out += "\nNothing to commit, working tree clean"
+	}
+	return tools.ResultSuccess(limitOutput(out)), nil
+}
+
+func (t *GitTool) diff(ctx context.Context, args GitDiffArgs) (*tools.ToolCallResult, error) {
+	contextLines := defaultGitDiffContext
+	if args.ContextLines != nil {
+		if *args.ContextLines < 0 {
+			return tools.ResultError("context_lines can't be negative"), nil
+		}
+		contextLines = *args.ContextLines
+	}
+	maxLines := args.MaxLines
+	if maxLines <= 0 {
+		maxLines = defaultGitDiffMaxLines
+	}
+
+	gitArgs := []string{"diff", "--no-ext-diff", "--no-textconv", "--find-renames", "-U" + strconv.Itoa(contextLines)}
+	if args.Staged {
+		gitArgs = append(gitArgs, "--cached")
+	}
+	if args.Path != "" {
+		gitArgs = append(gitArgs, "--", args.Path)
+	}
+
+	out, err := t.run(ctx, gitArgs...)
+	if err != nil {
+		return gitError("diff", err), nil
+	}
+
+	if out == "" {
+		if args.Staged {
+			return tools.ResultSuccess("No staged changes"), nil
+		}
+		return tools.ResultSuccess("No unstaged changes"), nil
+	}
+	return tools.ResultSuccess(limitOutput(truncateLines(out, maxLines))), nil
+}
+
+// truncateLines keeps the first maxLines lines of a diff, telling how many
+// were left out.
+func truncateLines(out string, maxLines int) string {
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	if len(lines) <= maxLines {
+		return strings.Join(lines, "\n")
+	}
+	return fmt.Sprintf("%s\n\n[Diff truncated: showing %d of %d lines. Diff a single path or raise max_lines to see more]",
+		strings.Join(lines[:maxLines], "\n"), maxLines, len(lines))
+}
+
+func (t *GitTool) log(ctx context.Context, args GitLogArgs) (*tools.ToolCallResult, error) {
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultGitLogLimit
+	}
+	limit = min(limit, maxGitLogLimit)
+
+	gitArgs := []string{"log", "-n", strconv.Itoa(limit), "--date=short", "--format=%h %ad %an: %s"}
+	if args.Path != "" {
+		gitArgs = append(gitArgs, "--follow", "--", args.Path)
+	}
+
+	out, err := t.run(ctx, gitArgs...)
+	if err != nil {
+		return gitError("log", err), nil
+	}
+
+	out = strings.TrimRight(out, "\n")
+	if out == "" {
+		return tools.ResultSuccess("No commits"), nil
+	}
+	return tools.ResultSuccess(limitOutput(out)), nil
+}
+
+func (t *GitTool) blame(ctx context.Context, args GitBlameArgs) (*tools.ToolCallResult, error) {
+	if args.Path == "" {
+		return tools.ResultError("path is required"), nil
+	}
+	if args.StartLine < 0 || args.EndLine < 0 {
+		return tools.ResultError("start_line and end_line must be positive"), nil
+	}
+	if args.EndLine > 0 && args.EndLine < max(args.StartLine, 1) {
+		return tools.ResultError("end_line must be greater than or equal to start_line"), nil
+	}
+
+	gitArgs := []string{"blame", "--date=short"}
+	if args.StartLine > 0 || args.EndLine > 0 {
+		lines := strconv.Itoa(max(args.StartLine, 1)) + ","
+		if args.EndLine > 0 {
+			lines += strconv.Itoa(args.EndLine)
+		}
+		gitArgs = append(gitArgs, "-L", lines)
+	}
+	gitArgs = append(gitArgs, "--", args.Path)
+
+	out, err := t.run(ctx, gitArgs...)
+	if err != nil {
+		return gitError("blame", err), nil
+	}
+	return tools.ResultSuccess(limitOutput(strings.TrimRight(out, "\n"))), nil
+}
+
+func (t *GitTool) add(ctx context.Context, args GitAddArgs) (*tools.ToolCallResult, error) {
+	if len(args.Paths) == 0 {
+		return tools.ResultError("at least one path is required"), nil
+	}
+
+	if _, err := t.run(ctx, append([]string{"add", "--"}, args.Paths...)...); err != nil {
+		return gitError("add", err), nil
+	}
+	return t.status(ctx, GitStatusArgs{})
+}
+
+func (t *GitTool) commit(ctx context.Context, args GitCommitArgs) (*tools.ToolCallResult, error) {
+	if strings.TrimSpace(args.Message) == "" {
+		return tools.ResultError("message is required"), nil
+	}
+
+	out, err := t.run(ctx, "commit", "-m", args.Message)
+	if err != nil {
+		// Git tells why there's nothing to commit on stdout.
+		if out = strings.TrimSpace(out); out != "" {
+			err = fmt.Errorf("%w\n%s", err, out)
+		}
+		return gitError("commit", err), nil
+	}
+	return tools.ResultSuccess(limitOutput(strings.TrimRight(out, "\n"))), nil
+}
+
+func (t *GitTool) Instructions() string {
+	return `## Git Tools
+
+Use the git tools, rather than running git in a shell, to inspect and commit the changes of the repository:
+- git_status lists the changed, staged and untracked files
+- git_diff shows the unstaged changes, or the staged ones with staged: true
+- git_log and git_blame tell when and why code changed
+- git_add stages files and git_commit commits the staged changes; both need the user's approval
+
+Check git_status and git_diff with staged: true before committing.`
+}
+
+// InstructionsForTurn tells the current branch and the changed files, so
+// that the model doesn't have to check the status first. It's empty when
+// the path isn't in a repository: the tools report it when they're called.
+func (t *GitTool) InstructionsForTurn(ctx context.Context, _ tools.TurnInfo) (string, error) {
+	out, err := t.run(ctx, "status", "--short", "--branch", "--find-renames")
+	if err != nil {
+		if strings.Contains(err.Error(), "not a git repository") {
+			return "", nil
+		}
+		return "", err
+	}
+
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	branch, files := strings.TrimPrefix(lines[0], "## "), lines[1:]
+
+	var b strings.Builder
+	b.WriteString("## Git Status\n\nBranch: ")
+	b.WriteString(branch)
+	if len(files) == 0 {
+		b.WriteString("\nNothing to commit, working tree clean")
+		return b.String(), nil
+	}
+
+	b.WriteString("\nChanged files:")
+	for _, file := range files[:min(len(files), maxGitTurnFiles)] {
+		b.WriteString("\n")
+		b.WriteString(file)
+	}
+	if len(files) > maxGitTurnFiles {
+		fmt.Fprintf(&b, "\n... and %d more, see git_status", len(files)-maxGitTurnFiles)
+	}
+	return b.String(), nil
+}
+
+func (t *GitTool) Tools(context.Context) ([]tools.Tool, error) {
+	return []tools.Tool{
+		{
+			Name:         ToolNameGitStatus,
+			Category:     "git",
+			Description:  "Show the current branch and the changed, staged and untracked files of the repository, in git's short format.",
+			Parameters:   tools.MustSchemaFor[GitStatusArgs](),
+			OutputSchema: tools.MustSchemaFor[string](),
+			Handler:      tools.NewHandler(t.status),
+			Annotations: tools.ToolAnnotations{
+				ReadOnlyHint: true,
+				Title:        "Git Status",
+			},
+		},
+		{
+			Name:         ToolNameGitDiff,
+			Category:     "git",
+			Description:  "Show the unstaged changes of the repository, or the staged ones, as a unified diff with file headers. Renames are detected and binary files are only listed.",
+			Parameters:   tools.MustSchemaFor[GitDiffArgs](),
+			OutputSchema: tools.MustSchemaFor[string](),
+			Handler:      tools.NewHandler(t.diff),
+			Annotations: tools.ToolAnnotations{
+				ReadOnlyHint: true,
+				Title:        "Git Diff",
+			},
+		},
+		{
+			Name:         ToolNameGitLog,
+			Category:     "git",
+			Description:  "List the latest commits of the current branch, one per line with their hash, date, author and subject.",
+			Parameters:   tools.MustSchemaFor[GitLogArgs](),
+			OutputSchema: tools.MustSchemaFor[string](),
+			Handler:      tools.NewHandler(t.log),
+			Annotations: tools.ToolAnnotations{
+				ReadOnlyHint: true,
+				Title:        "Git Log",
+			},
+		},
+		{
+			Name:         ToolNameGitBlame,
+			Category:     "git",
+			Description:  "Show the commit, author and date that last changed each line of a file, or of a range of its lines.",
+			Parameters:   tools.MustSchemaFor[GitBlameArgs](),
+			OutputSchema: tools.MustSchemaFor[string](),
+			Handler:      tools.NewHandler(t.blame),
+			Annotations: tools.ToolAnnotations{
+				ReadOnlyHint: true,
+				Title:        "Git Blame",
+			},
+		},
+		{
+			Name:         ToolNameGitAdd,
+			Category:     "git",
+			Description:  "Stage files or directories for the next commit, including deletions, and return the resulting status.",
+			Parameters:   tools.MustSchemaFor[GitAddArgs](),
+			OutputSchema: tools.MustSchemaFor[string](),
+			Handler:      tools.NewHandler(t.add),
+			Annotations: tools.ToolAnnotations{
+				Title: "Git Add",
+			},
+		},
+		{
+			Name:         ToolNameGitCommit,
+			Category:     "git",
+			Description:  "Commit the staged changes with the given message.",
+			Parameters:   tools.MustSchemaFor[GitCommitArgs](),
+			OutputSchema: tools.MustSchemaFor[string](),
+			Handler:      tools.NewHandler(t.commit),
+			Annotations: tools.ToolAnnotations{
+				Title: "Git Commit",
+			},
+		},
+	}, nil
+}
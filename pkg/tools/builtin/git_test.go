@@ -0,0 +1,262 @@
+package builtin
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// newGitRepo creates a repository with a first commit of a text file,
+// a.txt, and of a binary file, image.bin.
+func newGitRepo(t *testing.T) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q", "-b", "main")
+	runGit(t, dir, "config", "user.name", "Tester")
+	runGit(t, dir, "config", "user.email", "tester@example.com")
+	runGit(t, dir, "config", "commit.gpgsign", "false")
+
+	writeRepoFile(t, dir, "a.txt", "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\n")
+	writeRepoFile(t, dir, "image.bin", "\x89PNG\x00\x01\x02")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-q", "-m", "Initial commit")
+	return dir
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	out, err := exec.CommandContext(t.Context(), "git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	require.NoError(t, err, string(out))
+	return string(out)
+}
+
+func writeRepoFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+}
+
+func TestGitTool_Tools(t *testing.T) {
+	t.Parallel()
+
+	allTools, err := NewGitTool(t.TempDir()).Tools(t.Context())
+	require.NoError(t, err)
+
+	readOnly := map[string]bool{}
+	for _, tool := range allTools {
+		assert.Equal(t, "git", tool.Category)
+		readOnly[tool.Name] = tool.Annotations.ReadOnlyHint
+	}
+	assert.Equal(t, map[string]bool{
+		ToolNameGitStatus: true,
+		ToolNameGitDiff:   true,
+		ToolNameGitLog:    true,
+		ToolNameGitBlame:  true,
+		ToolNameGitAdd:    false,
+		ToolNameGitCommit: false,
+	}, readOnly)
+}
+
+func TestGitTool_Status(t *testing.T) {
+	t.Parallel()
+
+	dir := newGitRepo(t)
+	tool := NewGitTool(dir)
+
+	result, err := tool.status(t.Context(), GitStatusArgs{})
+	require.NoError(t, err)
+	assert.Equal(t, "## main\nNothing to commit, working tree clean", result.Output)
+
+	runGit(t, dir, "mv", "a.txt", "b.txt")
+	writeRepoFile(t, dir, "image.bin", "\x89PNG\x00\x03")
+	writeRepoFile(t, dir, "new.txt", "new\n")
+
+	result, err = tool.status(t.Context(), GitStatusArgs{})
+	require.NoError(t, err)
+	assert.Equal(t, "## main\nR  a.txt -> b.txt\n M image.bin\n?? new.txt", result.Output)
+}
+
+func TestGitTool_Diff(t *testing.T) {
+	t.Parallel()
+
+	dir := newGitRepo(t)
+	tool := NewGitTool(dir)
+
+	result, err := tool.diff(t.Context(), GitDiffArgs{})
+	require.NoError(t, err)
+	assert.Equal(t, "No unstaged changes", result.Output)
+
+	writeRepoFile(t, dir, "a.txt", "one\ntwo\nthree\nfour\nFIVE\nsix\nseven\neight\n")
+	writeRepoFile(t, dir, "image.bin", "\x89PNG\x00\x03")
+
+	result, err = tool.diff(t.Context(), GitDiffArgs{})
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, "diff --git a/a.txt b/a.txt\n")
+	assert.Contains(t, result.Output, "@@ -2,7 +2,7 @@ one\n two\n three\n four\n-five\n+FIVE\n six\n seven\n eight")
+	assert.Contains(t, result.Output, "Binary files a/image.bin and b/image.bin differ")
+
+	// Less context, for a single file.
+	zero := 0
+	result, err = tool.diff(t.Context(), GitDiffArgs{Path: "a.txt", ContextLines: &zero})
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(result.Output, "@@ -5 +5 @@ four\n-five\n+FIVE"), result.Output)
+	assert.NotContains(t, result.Output, "image.bin")
+
+	result, err = tool.diff(t.Context(), GitDiffArgs{MaxLines: 3})
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, "[Diff truncated: showing 3 of ")
+}
+
+func TestGitTool_DiffStagedRename(t *testing.T) {
+	t.Parallel()
+
+	dir := newGitRepo(t)
+	tool := NewGitTool(dir)
+
+	runGit(t, dir, "mv", "a.txt", "b.txt")
+
+	result, err := tool.diff(t.Context(), GitDiffArgs{})
+	require.NoError(t, err)
+	assert.Equal(t, "No unstaged changes", result.Output)
+
+	result, err = tool.diff(t.Context(), GitDiffArgs{Staged: true})
+	require.NoError(t, err)
+	assert.Equal(t, "diff --git a/a.txt b/b.txt\nsimilarity index 100%\nrename from a.txt\nrename to b.txt", result.Output)
+}
+
+func TestGitTool_LogFollowsRenames(t *testing.T) {
+	t.Parallel()
+
+	dir := newGitRepo(t)
+	tool := NewGitTool(dir)
+
+	runGit(t, dir, "mv", "a.txt", "b.txt")
+	runGit(t, dir, "commit", "-q", "-m", "Rename a.txt")
+	writeRepoFile(t, dir, "other.txt", "other\n")
+	runGit(t, dir, "add", "other.txt")
+	runGit(t, dir, "commit", "-q", "-m", "Add other.txt")
+
+	result, err := tool.log(t.Context(), GitLogArgs{Path: "b.txt"})
+	require.NoError(t, err)
+	lines := strings.Split(result.Output, "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "Tester: Rename a.txt")
+	assert.Contains(t, lines[1], "Tester: Initial commit")
+
+	result, err = tool.log(t.Context(), GitLogArgs{Limit: 1})
+	require.NoError(t, err)
+	assert.NotContains(t, result.Output, "\n")
+	assert.Contains(t, result.Output, "Add other.txt")
+}
+
+func TestGitTool_Blame(t *testing.T) {
+	t.Parallel()
+
+	dir := newGitRepo(t)
+	tool := NewGitTool(dir)
+
+	result, err := tool.blame(t.Context(), GitBlameArgs{Path: "a.txt", StartLine: 2, EndLine: 3})
+	require.NoError(t, err)
+	require.False(t, result.IsError, result.Output)
+	lines := strings.Split(result.Output, "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "(Tester")
+	assert.True(t, strings.HasSuffix(lines[0], "2) two"), lines[0])
+	assert.True(t, strings.HasSuffix(lines[1], "3) three"), lines[1])
+
+	result, err = tool.blame(t.Context(), GitBlameArgs{Path: "a.txt", StartLine: 3, EndLine: 2})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+
+	result, err = tool.blame(t.Context(), GitBlameArgs{Path: "missing.txt"})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.Output, "git blame failed")
+}
+
+func TestGitTool_AddAndCommit(t *testing.T) {
+	t.Parallel()
+
+	dir := newGitRepo(t)
+	tool := NewGitTool(dir)
+
+	writeRepoFile(t, dir, "a.txt", "changed\n")
+	writeRepoFile(t, dir, "-n.txt", "looks like a flag\n")
+
+	result, err := tool.add(t.Context(), GitAddArgs{Paths: []string{"a.txt", "-n.txt"}})
+	require.NoError(t, err)
+	require.False(t, result.IsError, result.Output)
+	assert.Equal(t, "## main\nA  -n.txt\nM  a.txt", result.Output)
+
+	result, err = tool.commit(t.Context(), GitCommitArgs{Message: "--amend"})
+	require.NoError(t, err)
+	require.False(t, result.IsError, result.Output)
+	assert.Contains(t, result.Output, "--amend")
+	assert.Contains(t, runGit(t, dir, "log", "--format=%s"), "--amend\nInitial commit\n")
+
+	result, err = tool.commit(t.Context(), GitCommitArgs{Message: "Nothing"})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.Output, "nothing to commit")
+
+	result, err = tool.commit(t.Context(), GitCommitArgs{})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+
+	result, err = tool.add(t.Context(), GitAddArgs{})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+}
+
+func TestGitTool_NotARepository(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	result, err := NewGitTool(t.TempDir()).status(t.Context(), GitStatusArgs{})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.Output, "not a git repository")
+}
+
+func TestGitTool_InstructionsForTurn(t *testing.T) {
+	t.Parallel()
+
+	dir := newGitRepo(t)
+	tool := NewGitTool(dir)
+
+	instructions, err := tool.InstructionsForTurn(t.Context(), tools.TurnInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, "## Git Status\n\nBranch: main\nNothing to commit, working tree clean", instructions)
+
+	runGit(t, dir, "mv", "a.txt", "b.txt")
+	for i := range maxGitTurnFiles + 2 {
+		writeRepoFile(t, dir, fmt.Sprintf("new%02d.txt", i), "new\n")
+	}
+
+	instructions, err = tool.InstructionsForTurn(t.Context(), tools.TurnInfo{})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(instructions, "## Git Status\n\nBranch: main\nChanged files:\nR  a.txt -> b.txt\n?? new00.txt\n"), instructions)
+	assert.True(t, strings.HasSuffix(instructions, "\n... and 3 more, see git_status"), instructions)
+
+	// Outside of a repository, there's nothing to tell.
+	instructions, err = NewGitTool(t.TempDir()).InstructionsForTurn(t.Context(), tools.TurnInfo{})
+	require.NoError(t, err)
+	assert.Empty(t, instructions)
+}
@@ -4,11 +4,13 @@ import (
 	"bufio"
 	"cmp"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -20,25 +22,29 @@ import (
 	"time"
 
 	"github.com/docker/docker-agent/pkg/concurrent"
+	"github.com/docker/docker-agent/pkg/environment"
+	"github.com/docker/docker-agent/pkg/procgroup"
 	"github.com/docker/docker-agent/pkg/tools"
 )
 
 const (
-	ToolNameLSPWorkspace        = "lsp_workspace"
-	ToolNameLSPHover            = "lsp_hover"
-	ToolNameLSPDefinition       = "lsp_definition"
-	ToolNameLSPReferences       = "lsp_references"
-	ToolNameLSPDocumentSymbols  = "lsp_document_symbols"
-	ToolNameLSPWorkspaceSymbols = "lsp_workspace_symbols"
-	ToolNameLSPDiagnostics      = "lsp_diagnostics"
-	ToolNameLSPRename           = "lsp_rename"
-	ToolNameLSPCodeActions      = "lsp_code_actions"
-	ToolNameLSPFormat           = "lsp_format"
-	ToolNameLSPCallHierarchy    = "lsp_call_hierarchy"
-	ToolNameLSPTypeHierarchy    = "lsp_type_hierarchy"
-	ToolNameLSPImplementations  = "lsp_implementations"
-	ToolNameLSPSignatureHelp    = "lsp_signature_help"
-	ToolNameLSPInlayHints       = "lsp_inlay_hints"
+	ToolNameLSPWorkspace            = "lsp_workspace"
+	ToolNameLSPHover                = "lsp_hover"
+	ToolNameLSPDefinition           = "lsp_definition"
+	ToolNameLSPReferences           = "lsp_references"
+	ToolNameLSPDocumentSymbols      = "lsp_document_symbols"
+	ToolNameLSPWorkspaceSymbols     = "lsp_workspace_symbols"
+	ToolNameLSPDiagnostics          = "lsp_diagnostics"
+	ToolNameLSPWorkspaceDiagnostics = "lsp_workspace_diagnostics"
+	ToolNameLSPRename               = "lsp_rename"
+	ToolNameLSPCodeActions          = "lsp_code_actions"
+	ToolNameLSPFormat               = "lsp_format"
+	ToolNameLSPCallHierarchy        = "lsp_call_hierarchy"
+	ToolNameLSPTypeHierarchy        = "lsp_type_hierarchy"
+	ToolNameLSPImplementations      = "lsp_implementations"
+	ToolNameLSPSignatureHelp        = "lsp_signature_help"
+	ToolNameLSPInlayHints           = "lsp_inlay_hints"
+	ToolNameLSPAddWorkspaceFolder   = "lsp_add_workspace_folder"
 )
 
 // LSPTool implements tools.ToolSet for connecting to any LSP server.
@@ -46,15 +52,26 @@ const (
 // the LSP server lifecycle and document state.
 type LSPTool struct {
 	handler *lspHandler
+
+	toolsOnce sync.Once
+	tools     []tools.Tool
 }
 
 // Verify interface compliance
 var (
-	_ tools.ToolSet      = (*LSPTool)(nil)
-	_ tools.Startable    = (*LSPTool)(nil)
-	_ tools.Instructable = (*LSPTool)(nil)
+	_ tools.ToolSet             = (*LSPTool)(nil)
+	_ tools.Startable           = (*LSPTool)(nil)
+	_ tools.Instructable        = (*LSPTool)(nil)
+	_ tools.Previewable         = (*LSPTool)(nil)
+	_ tools.ConcurrencyDeclarer = (*LSPTool)(nil)
 )
 
+// Concurrency is Serial: the calls of different tasks would interleave
+// their didOpen and didChange notifications to the server.
+func (t *LSPTool) Concurrency() tools.Concurrency {
+	return tools.Serial
+}
+
 type lspHandler struct {
 	mu          sync.Mutex
 	cmd         *exec.Cmd
@@ -65,18 +82,22 @@ type lspHandler struct {
 	requestID   atomic.Int64
 
 	// Configuration
-	command    string
-	args       []string
-	env        []string
-	workingDir string
-	fileTypes  []string // Empty = all files
+	command        string
+	args           []string
+	env            []string
+	envPassthrough []string // nil = the whole process environment
+	workingDir     string
+	fileTypes      []string // Empty = all files
+	// workspaceFolders are the absolute paths of the workspace roots, guarded
+	// by mu. Empty = the working directory.
+	workspaceFolders []string
 
 	// State tracking
 	diagnosticsMu      sync.RWMutex
 	diagnostics        map[string][]lspDiagnostic
 	diagnosticsVersion atomic.Int64
 	openFilesMu        sync.RWMutex
-	openFiles          map[string]int // URI -> version
+	openFiles          map[string]lspOpenFile // URI -> document state on the server
 
 	// Server info from initialization
 	serverInfo   *lspServerInfo
@@ -106,6 +127,9 @@ type lspServerCapabilities struct {
 	ImplementationProvider     any `json:"implementationProvider,omitempty"`
 	SignatureHelpProvider      any `json:"signatureHelpProvider,omitempty"`
 	InlayHintProvider          any `json:"inlayHintProvider,omitempty"`
+	DiagnosticProvider         any `json:"diagnosticProvider,omitempty"`
+
+	Workspace *lspWorkspaceServerCapabilities `json:"workspace,omitempty"`
 }
 
 // LSP message types
@@ -327,18 +351,46 @@ type lspInlayHint struct {
 	PaddingRight bool        `json:"paddingRight,omitempty"`
 }
 
+// LSPOption configures an LSPTool.
+type LSPOption func(*LSPTool)
+
+// WithLSPEnvPassthrough scopes the environment of the LSP server to the
+// tool's env and the variables of the process environment matching one of
+// the patterns, variable names or globs such as "GO*", instead of the whole
+// process environment.
+func WithLSPEnvPassthrough(patterns ...string) LSPOption {
+	return func(t *LSPTool) {
+		t.handler.envPassthrough = append([]string{}, patterns...)
+	}
+}
+
+// WithWorkspaceFolders sets the roots of the workspace, for servers handling
+// several projects at once, e.g. gopls with several modules. Relative paths
+// are relative to the working directory. Defaults to the working directory.
+func WithWorkspaceFolders(paths ...string) LSPOption {
+	return func(t *LSPTool) {
+		for _, path := range paths {
+			t.handler.workspaceFolders = append(t.handler.workspaceFolders, t.handler.absPath(path))
+		}
+	}
+}
+
 // NewLSPTool creates a new LSP tool that connects to an LSP server.
-func NewLSPTool(command string, args, env []string, workingDir string) *LSPTool {
-	return &LSPTool{
+func NewLSPTool(command string, args, env []string, workingDir string, opts ...LSPOption) *LSPTool {
+	t := &LSPTool{
 		handler: &lspHandler{
 			command:     command,
 			args:        args,
 			env:         env,
 			workingDir:  workingDir,
 			diagnostics: make(map[string][]lspDiagnostic),
-			openFiles:   make(map[string]int),
+			openFiles:   make(map[string]lspOpenFile),
 		},
 	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
 }
 
 // SetFileTypes sets the file types (extensions) that this LSP server handles.
@@ -357,10 +409,10 @@ func (t *LSPTool) Start(context.Context) error {
 	return t.handler.startLocked()
 }
 
-func (t *LSPTool) Stop(context.Context) error {
+func (t *LSPTool) Stop(ctx context.Context) error {
 	t.handler.mu.Lock()
 	defer t.handler.mu.Unlock()
-	return t.handler.stopLocked()
+	return t.handler.stopLocked(ctx)
 }
 
 func (t *LSPTool) Instructions() string {
@@ -371,6 +423,7 @@ Stateless code intelligence tools via Language Server Protocol. Just provide fil
 ## Getting Started
 
 Use lsp_workspace at the start of every session to learn about the workspace and available capabilities.
+When you find a nested project outside of the workspace folders (e.g. another Go module), use lsp_add_workspace_folder to add it.
 
 ## Read Workflow
 
@@ -386,7 +439,7 @@ Use lsp_workspace at the start of every session to learn about the workspace and
 2. **Find references**: Before modifying any symbol definition, you MUST use lsp_references to find all usages. Example: lsp_references({"file":"/path/to/file.go", "line": 42, "character": 15})
 3. **Check implementations**: Before modifying interfaces, use lsp_implementations to find all concrete implementations
 4. **Make edits**: Apply all planned changes
-5. **Check errors**: After every modification, you MUST call lsp_diagnostics on edited files. Use lsp_code_actions for suggested fixes. Ignore irrelevant hint/info diagnostics
+5. **Check errors**: After every modification, you MUST call lsp_diagnostics on edited files. Use lsp_code_actions for suggested fixes. Ignore irrelevant hint/info diagnostics. Use lsp_workspace_diagnostics to check the whole workspace, e.g. after changing an exported symbol
 6. **Format**: Once error-free, use lsp_format for consistent style
 
 ## Position Format
@@ -397,74 +450,137 @@ Line and character positions are 1-based.`
 // WorkspaceArgs is empty - the workspace tool takes no arguments.
 type WorkspaceArgs struct{}
 
-// lspTool is a shorthand for constructing a tools.Tool with common LSP defaults.
-func lspTool(name, title, description string, readOnly bool, params any, handler tools.ToolHandler) tools.Tool {
-	return tools.Tool{
-		Name:        name,
-		Category:    "lsp",
-		Description: description,
-		Parameters:  params,
-		Handler:     handler,
-		Annotations: tools.ToolAnnotations{
-			Title:        title,
-			ReadOnlyHint: readOnly,
+// lspToolDefinition is an LSP tool, with a handler to bind to the handler of
+// a toolset.
+type lspToolDefinition struct {
+	tool    tools.Tool
+	handler func(h *lspHandler) tools.ToolHandler
+}
+
+// lspTool is a shorthand for defining an LSP tool with common defaults.
+func lspTool(name, title, description string, readOnly bool, params any, handler func(h *lspHandler) tools.ToolHandler) lspToolDefinition {
+	return lspToolDefinition{
+		tool: tools.Tool{
+			Name:        name,
+			Category:    "lsp",
+			Description: description,
+			Parameters:  params,
+			Annotations: tools.ToolAnnotations{
+				Title:        title,
+				ReadOnlyHint: readOnly,
+			},
 		},
+		handler: handler,
 	}
 }
 
-func (t *LSPTool) Tools(context.Context) ([]tools.Tool, error) {
-	h := t.handler
-	return []tools.Tool{
+// lspHandlerOf returns a function binding a method of lspHandler, such as
+// (*lspHandler).hover, to a handler.
+func lspHandlerOf[T any](method func(*lspHandler, context.Context, T) (*tools.ToolCallResult, error)) func(h *lspHandler) tools.ToolHandler {
+	return func(h *lspHandler) tools.ToolHandler {
+		return tools.NewHandler(func(ctx context.Context, args T) (*tools.ToolCallResult, error) {
+			return method(h, ctx, args)
+		})
+	}
+}
+
+// lspToolDefinitions are the tools of the LSP toolsets. Their schemas and
+// descriptions are built once and shared by all the toolsets.
+var lspToolDefinitions = sync.OnceValue(func() []lspToolDefinition {
+	return []lspToolDefinition{
 		lspTool(ToolNameLSPWorkspace, "Get Workspace Info",
 			`Get workspace info and LSP server capabilities. Use at session start to discover available features. Takes no arguments.`,
-			true, tools.MustSchemaFor[WorkspaceArgs](), tools.NewHandler(h.workspace)),
+			true, tools.MustSchemaFor[WorkspaceArgs](), lspHandlerOf((*lspHandler).workspace)),
 		lspTool(ToolNameLSPHover, "Get Symbol Info",
 			`Get type signature, documentation, and hover info for a symbol at a given position.`,
-			true, tools.MustSchemaFor[PositionArgs](), tools.NewHandler(h.hover)),
+			true, tools.MustSchemaFor[PositionArgs](), lspHandlerOf((*lspHandler).hover)),
 		lspTool(ToolNameLSPDefinition, "Go to Definition",
 			`Find the definition location of a symbol. Returns file path and line number.`,
-			true, tools.MustSchemaFor[PositionArgs](), tools.NewHandler(h.definition)),
+			true, tools.MustSchemaFor[PositionArgs](), lspHandlerOf((*lspHandler).definition)),
 		lspTool(ToolNameLSPReferences, "Find References",
 			`Find all references to a symbol across the codebase. IMPORTANT: You MUST use this before modifying any symbol definition. Set include_declaration to false to exclude the definition itself.`,
-			true, tools.MustSchemaFor[ReferencesArgs](), tools.NewHandler(h.references)),
+			true, tools.MustSchemaFor[ReferencesArgs](), lspHandlerOf((*lspHandler).references)),
 		lspTool(ToolNameLSPDocumentSymbols, "List File Symbols",
 			`List all symbols (functions, types, methods, variables, etc.) defined in a file as a hierarchical list.`,
-			true, tools.MustSchemaFor[FileArgs](), tools.NewHandler(h.documentSymbols)),
+			true, tools.MustSchemaFor[FileArgs](), lspHandlerOf((*lspHandler).documentSymbols)),
 		lspTool(ToolNameLSPWorkspaceSymbols, "Search Workspace Symbols",
 			`Search for symbols across the workspace using fuzzy matching. Primary tool for locating symbols.`,
-			true, tools.MustSchemaFor[WorkspaceSymbolsArgs](), tools.NewHandler(h.workspaceSymbols)),
+			true, tools.MustSchemaFor[WorkspaceSymbolsArgs](), lspHandlerOf((*lspHandler).workspaceSymbols)),
 		lspTool(ToolNameLSPDiagnostics, "Get Diagnostics",
 			`Get compiler errors, warnings, and hints for a file. IMPORTANT: You MUST call this after every code modification on edited files. Use lsp_code_actions for suggested fixes.`,
-			true, tools.MustSchemaFor[FileArgs](), tools.NewHandler(h.getDiagnostics)),
+			true, tools.MustSchemaFor[FileArgs](), lspHandlerOf((*lspHandler).getDiagnostics)),
+		lspTool(ToolNameLSPWorkspaceDiagnostics, "Get Workspace Diagnostics",
+			`Get compiler errors, warnings, and hints for every file of the workspace, grouped per file and most severe first. The first line is a JSON summary of the counts per severity. Slower than lsp_diagnostics: use it to check the whole workspace, not a single file.`,
+			true, tools.MustSchemaFor[WorkspaceDiagnosticsArgs](), lspHandlerOf((*lspHandler).workspaceDiagnostics)),
 		lspTool(ToolNameLSPRename, "Rename Symbol",
 			`Rename a symbol across the entire workspace. WRITE operation - modifies files on disk. Run lsp_diagnostics on modified files afterward.`,
-			false, tools.MustSchemaFor[RenameArgs](), tools.NewHandler(h.rename)),
+			false, tools.MustSchemaFor[RenameArgs](), lspHandlerOf((*lspHandler).rename)),
 		lspTool(ToolNameLSPCodeActions, "Get Code Actions",
 			`Get available code actions (quick fixes, refactorings) for a line or range. Use after lsp_diagnostics reports errors.`,
-			true, tools.MustSchemaFor[CodeActionsArgs](), tools.NewHandler(h.codeActions)),
+			true, tools.MustSchemaFor[CodeActionsArgs](), lspHandlerOf((*lspHandler).codeActions)),
 		lspTool(ToolNameLSPFormat, "Format File",
 			`Format a file according to language standards. WRITE operation - modifies the file on disk. Only format after lsp_diagnostics reports no errors.`,
-			false, tools.MustSchemaFor[FileArgs](), tools.NewHandler(h.format)),
+			false, tools.MustSchemaFor[FileArgs](), lspHandlerOf((*lspHandler).format)),
 		lspTool(ToolNameLSPCallHierarchy, "Call Hierarchy",
 			`Analyze the call hierarchy of a function or method. Direction: 'incoming' (who calls this) or 'outgoing' (what this calls).`,
-			true, tools.MustSchemaFor[CallHierarchyArgs](), tools.NewHandler(h.callHierarchy)),
+			true, tools.MustSchemaFor[CallHierarchyArgs](), lspHandlerOf((*lspHandler).callHierarchy)),
 		lspTool(ToolNameLSPTypeHierarchy, "Type Hierarchy",
 			`Analyze the type hierarchy. Direction: 'supertypes' (parent types) or 'subtypes' (child types).`,
-			true, tools.MustSchemaFor[TypeHierarchyArgs](), tools.NewHandler(h.typeHierarchy)),
+			true, tools.MustSchemaFor[TypeHierarchyArgs](), lspHandlerOf((*lspHandler).typeHierarchy)),
 		lspTool(ToolNameLSPImplementations, "Find Implementations",
 			`Find all concrete implementations of an interface or abstract method. IMPORTANT: You MUST use this before modifying interfaces to find all implementations needing updates.`,
-			true, tools.MustSchemaFor[PositionArgs](), tools.NewHandler(h.implementations)),
+			true, tools.MustSchemaFor[PositionArgs](), lspHandlerOf((*lspHandler).implementations)),
 		lspTool(ToolNameLSPSignatureHelp, "Signature Help",
 			`Get function signature and parameter information at a call site. Position the cursor inside a function call's parentheses.`,
-			true, tools.MustSchemaFor[PositionArgs](), tools.NewHandler(h.signatureHelp)),
+			true, tools.MustSchemaFor[PositionArgs](), lspHandlerOf((*lspHandler).signatureHelp)),
 		lspTool(ToolNameLSPInlayHints, "Inlay Hints",
 			`Get inlay hints (type annotations, parameter names) for a file or line range. Omit start_line/end_line to get hints for the entire file.`,
-			true, tools.MustSchemaFor[InlayHintsArgs](), tools.NewHandler(h.inlayHints)),
-	}, nil
+			true, tools.MustSchemaFor[InlayHintsArgs](), lspHandlerOf((*lspHandler).inlayHints)),
+		lspTool(ToolNameLSPAddWorkspaceFolder, "Add Workspace Folder",
+			`Add a folder to the workspace of the LSP server, e.g. a nested project it doesn't know about. Use lsp_workspace to list the current folders.`,
+			true, tools.MustSchemaFor[AddWorkspaceFolderArgs](), lspHandlerOf((*lspHandler).addWorkspaceFolder)),
+	}
+})
+
+// Tools returns the LSP tools, bound to the server of the toolset. They're
+// built on the first call only.
+func (t *LSPTool) Tools(context.Context) ([]tools.Tool, error) {
+	t.toolsOnce.Do(func() {
+		definitions := lspToolDefinitions()
+		t.tools = make([]tools.Tool, len(definitions))
+		for i, definition := range definitions {
+			t.tools[i] = definition.tool
+			t.tools[i].Handler = definition.handler(t.handler)
+		}
+	})
+	return t.tools, nil
+}
+
+// Preview returns the diff of the changes a call to lsp_rename would make,
+// without making them.
+func (t *LSPTool) Preview(ctx context.Context, toolCall tools.ToolCall) (*tools.Preview, error) {
+	if toolCall.Function.Name != ToolNameLSPRename {
+		return nil, nil
+	}
+
+	var args RenameArgs
+	if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
+		return nil, err
+	}
+	return t.handler.previewRename(ctx, args)
 }
 
 // lspHandler implementation
 
+// environ returns the environment of the LSP server process.
+func (h *lspHandler) environ() []string {
+	inherited := os.Environ()
+	if h.envPassthrough != nil {
+		inherited = environment.Filter(inherited, h.envPassthrough)
+	}
+	return append(inherited, h.env...)
+}
+
 // startLocked starts the LSP server process. The caller must hold h.mu.
 // The process is managed by a background context so that it outlives any
 // single request.
@@ -479,9 +595,12 @@ func (h *lspHandler) startLocked() error {
 	// server is not killed when a caller's request context ends.
 	processCtx, processCancel := context.WithCancel(context.Background())
 
+	// The server runs in its own process group so that stopping it also
+	// stops the processes it spawned, e.g. when started through a wrapper.
 	cmd := exec.CommandContext(processCtx, h.command, h.args...)
-	cmd.Env = append(os.Environ(), h.env...)
+	cmd.Env = h.environ()
 	cmd.Dir = h.workingDir
+	procgroup.Setup(cmd)
 
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
@@ -511,19 +630,27 @@ func (h *lspHandler) startLocked() error {
 	h.stdout = bufio.NewReader(stdout)
 
 	go h.readNotifications(processCtx, stderrBuf)
+	go h.watchOpenFiles(processCtx, lspWatchInterval)
 
 	slog.Debug("LSP server started successfully")
 	return nil
 }
 
-// stopLocked shuts down the LSP server process. The caller must hold h.mu.
-func (h *lspHandler) stopLocked() error {
+// stopLocked shuts down the LSP server process. The server is asked to shut
+// down, and terminated with the processes it spawned if it doesn't before
+// ctx is done. The caller must hold h.mu.
+func (h *lspHandler) stopLocked(ctx context.Context) error {
 	if h.cmd == nil {
 		return nil
 	}
 
 	slog.Debug("Stopping LSP server")
 
+	if h.cancel != nil {
+		// Terminating the process unblocks the shutdown request below.
+		defer context.AfterFunc(ctx, h.cancel)()
+	}
+
 	if h.initialized.Load() {
 		_, _ = h.sendRequestLocked("shutdown", nil)
 		_ = h.sendNotificationLocked("exit", nil)
@@ -532,7 +659,8 @@ func (h *lspHandler) stopLocked() error {
 	h.stdin.Close()
 
 	// Cancel the process-lifetime context to stop the readNotifications
-	// goroutine and (if the process didn't exit cleanly) kill the process.
+	// goroutine and (if the process didn't exit cleanly) terminate the
+	// process group.
 	if h.cancel != nil {
 		h.cancel()
 		h.cancel = nil
@@ -545,11 +673,11 @@ func (h *lspHandler) stopLocked() error {
 	h.initialized.Store(false)
 
 	h.openFilesMu.Lock()
-	h.openFiles = make(map[string]int)
+	h.openFiles = make(map[string]lspOpenFile)
 	h.openFilesMu.Unlock()
 
 	if err != nil {
-		if _, ok := errors.AsType[*exec.ExitError](err); ok {
+		if _, ok := errors.AsType[*exec.ExitError](err); ok || errors.Is(err, context.Canceled) {
 			return nil
 		}
 		return fmt.Errorf("LSP server exited with error: %w", err)
@@ -588,11 +716,14 @@ func (h *lspHandler) ensureInitialized() error {
 // initializeLocked performs the LSP initialize/initialized handshake.
 // The caller must hold h.mu and the process must be running.
 func (h *lspHandler) initializeLocked() error {
-	rootURI := "file://" + h.workingDir
+	roots := h.rootsLocked()
+	// rootUri is for the servers that don't support workspace folders.
+	rootURI := pathToURI(roots[0])
 
 	result, err := h.sendRequestLocked("initialize", map[string]any{
-		"processId": os.Getpid(),
-		"rootUri":   rootURI,
+		"processId":        os.Getpid(),
+		"rootUri":          rootURI,
+		"workspaceFolders": workspaceFolderParams(roots),
 		"capabilities": map[string]any{
 			"textDocument": map[string]any{
 				"hover":              map[string]any{"contentFormat": []string{"markdown", "plaintext"}},
@@ -621,9 +752,10 @@ func (h *lspHandler) initializeLocked() error {
 				"inlayHint": map[string]any{"dynamicRegistration": true},
 			},
 			"workspace": map[string]any{
-				"symbol":        map[string]any{},
-				"applyEdit":     true,
-				"workspaceEdit": map[string]any{"documentChanges": true},
+				"symbol":           map[string]any{},
+				"applyEdit":        true,
+				"workspaceEdit":    map[string]any{"documentChanges": true},
+				"workspaceFolders": true,
 			},
 		},
 	})
@@ -647,7 +779,7 @@ func (h *lspHandler) initializeLocked() error {
 	}
 
 	h.initialized.Store(true)
-	slog.Debug("LSP server initialized", "rootUri", rootURI)
+	slog.Debug("LSP server initialized", "rootUri", rootURI, "workspaceFolders", len(roots))
 	return nil
 }
 
@@ -657,7 +789,12 @@ func (h *lspHandler) prepareFileRequest(ctx context.Context, file string) (strin
 		return "", fmt.Errorf("LSP initialization failed: %w", err)
 	}
 	uri := pathToURI(file)
-	if err := h.openFileOnDemand(ctx, uri); err != nil {
+	if h.isFileOpen(uri) {
+		// Catch up with the edits made by other tools since the last request.
+		if _, err := h.syncFile(uri); err != nil {
+			slog.Debug("Failed to sync file", "file", file, "error", err)
+		}
+	} else if err := h.openFileOnDemand(ctx, uri); err != nil {
 		slog.Debug("Failed to auto-open file", "file", file, "error", err)
 	}
 	return uri, nil
@@ -670,9 +807,20 @@ func (h *lspHandler) workspace(ctx context.Context, _ WorkspaceArgs) (*tools.Too
 		return tools.ResultError(fmt.Sprintf("LSP initialization failed: %s", err)), nil
 	}
 
+	h.mu.Lock()
+	roots := h.rootsLocked()
+	h.mu.Unlock()
+
 	var result strings.Builder
 	result.WriteString("Workspace Information:\n")
-	fmt.Fprintf(&result, "- Root: %s\n", h.workingDir)
+	if len(roots) == 1 {
+		fmt.Fprintf(&result, "- Root: %s\n", roots[0])
+	} else {
+		result.WriteString("- Roots:\n")
+		for _, root := range roots {
+			fmt.Fprintf(&result, "  - %s\n", root)
+		}
+	}
 	fmt.Fprintf(&result, "- LSP Command: %s\n", h.command)
 
 	if h.serverInfo != nil {
@@ -706,6 +854,8 @@ func (h *lspHandler) workspace(ctx context.Context, _ WorkspaceArgs) (*tools.Too
 		fmt.Fprintf(&result, "- Type Hierarchy: %s\n", capabilityStatus(h.capabilities.TypeHierarchyProvider))
 		fmt.Fprintf(&result, "- Signature Help: %s\n", capabilityStatus(h.capabilities.SignatureHelpProvider))
 		fmt.Fprintf(&result, "- Inlay Hints: %s\n", capabilityStatus(h.capabilities.InlayHintProvider))
+		fmt.Fprintf(&result, "- Workspace Diagnostics: %s\n", capabilityStatus(h.supportsWorkspaceDiagnostics()))
+		fmt.Fprintf(&result, "- Workspace Folders: %s\n", capabilityStatus(h.supportsWorkspaceFolderChanges()))
 	} else {
 		fmt.Fprintf(&result, "- (capabilities not available)\n")
 	}
@@ -880,11 +1030,17 @@ func (h *lspHandler) getDiagnostics(ctx context.Context, args FileArgs) (*tools.
 
 	uri := pathToURI(args.File)
 	wasOpen := h.isFileOpen(uri)
-	if err := h.openFileOnDemand(ctx, uri); err != nil {
+	var changed bool
+	if wasOpen {
+		var err error
+		if changed, err = h.syncFile(uri); err != nil {
+			slog.Debug("Failed to sync file for diagnostics", "file", args.File, "error", err)
+		}
+	} else if err := h.openFileOnDemand(ctx, uri); err != nil {
 		slog.Debug("Failed to auto-open file for diagnostics", "file", args.File, "error", err)
 	}
 
-	if !wasOpen {
+	if !wasOpen || changed {
 		h.waitForDiagnostics(ctx, 2*time.Second)
 	}
 
@@ -912,6 +1068,43 @@ func (h *lspHandler) rename(ctx context.Context, args RenameArgs) (*tools.ToolCa
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
+	edit, err := h.renameEditLocked(uri, args)
+	if err != nil {
+		return tools.ResultError(fmt.Sprintf("Rename failed: %s", err)), nil
+	}
+
+	return h.applyWorkspaceEdit(ctx, edit, args.NewName), nil
+}
+
+// previewRename returns the diff of the changes renaming a symbol would
+// make, without applying them.
+func (h *lspHandler) previewRename(ctx context.Context, args RenameArgs) (*tools.Preview, error) {
+	if args.NewName == "" {
+		return nil, errors.New("new_name is required")
+	}
+
+	uri, err := h.prepareFileRequest(ctx, args.File)
+	if err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	edit, err := h.renameEditLocked(uri, args)
+	h.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := workspaceEditDiff(edit)
+	if err != nil {
+		return nil, err
+	}
+	return &tools.Preview{Diff: diff}, nil
+}
+
+// renameEditLocked asks the server for the workspace edit renaming the
+// symbol at the position of args. The caller must hold h.mu.
+func (h *lspHandler) renameEditLocked(uri string, args RenameArgs) (*lspWorkspaceEdit, error) {
 	params := map[string]any{
 		"textDocument": map[string]any{"uri": uri},
 		"position":     map[string]any{"line": args.Line - 1, "character": args.Character - 1},
@@ -920,19 +1113,18 @@ func (h *lspHandler) rename(ctx context.Context, args RenameArgs) (*tools.ToolCa
 
 	result, err := h.sendRequestLocked("textDocument/rename", params)
 	if err != nil {
-		return tools.ResultError(fmt.Sprintf("Rename failed: %s", err)), nil
+		return nil, err
 	}
 
 	if len(result) == 0 || string(result) == "null" {
-		return tools.ResultError("Cannot rename symbol at this position"), nil
+		return nil, errors.New("cannot rename symbol at this position")
 	}
 
 	var edit lspWorkspaceEdit
 	if err := json.Unmarshal(result, &edit); err != nil {
-		return tools.ResultError(fmt.Sprintf("Failed to parse rename result: %s", err)), nil
+		return nil, fmt.Errorf("failed to parse rename result: %w", err)
 	}
-
-	return h.applyWorkspaceEdit(&edit, args.NewName), nil
+	return &edit, nil
 }
 
 func (h *lspHandler) codeActions(ctx context.Context, args CodeActionsArgs) (*tools.ToolCallResult, error) {
@@ -1011,11 +1203,11 @@ func (h *lspHandler) format(ctx context.Context, args FileArgs) (*tools.ToolCall
 		return tools.ResultSuccess("No formatting changes needed for " + args.File), nil
 	}
 
-	if err := applyTextEditsToFile(args.File, edits); err != nil {
+	if err := h.applyTextEditsToFile(ctx, args.File, nil, edits); err != nil {
 		return tools.ResultError(fmt.Sprintf("Failed to apply formatting: %s", err)), nil
 	}
 
-	if err := h.notifyFileChangeLocked(uri); err != nil {
+	if _, err := h.syncFileLocked(uri); err != nil {
 		slog.Debug("Failed to notify LSP of format changes", "error", err)
 	}
 
@@ -1201,35 +1393,49 @@ func (h *lspHandler) inlayHints(ctx context.Context, args InlayHintsArgs) (*tool
 }
 
 // applyWorkspaceEdit applies a workspace edit to files on disk and notifies
-// the LSP server of the changes so its in-memory state stays in sync.
+// the LSP server of the changes so its in-memory state stays in sync. No
+// file is written if the edits of one of them are stale.
 // The caller must hold h.mu.
-func (h *lspHandler) applyWorkspaceEdit(edit *lspWorkspaceEdit, newName string) *tools.ToolCallResult {
-	var totalChanges int
-	var modifiedFiles []string
-	fileChangeCounts := make(map[string]int)
-
-	if len(edit.DocumentChanges) > 0 {
-		for _, docEdit := range edit.DocumentChanges {
-			filePath := strings.TrimPrefix(docEdit.TextDocument.URI, "file://")
-			if err := applyTextEditsToFile(filePath, docEdit.Edits); err != nil {
-				return tools.ResultError(fmt.Sprintf("Failed to apply changes to %s: %s", filePath, err))
-			}
-			fileChangeCounts[filePath] = len(docEdit.Edits)
-			totalChanges += len(docEdit.Edits)
-			modifiedFiles = append(modifiedFiles, filePath)
+func (h *lspHandler) applyWorkspaceEdit(ctx context.Context, edit *lspWorkspaceEdit, newName string) *tools.ToolCallResult {
+	// The edits of a file listed again apply to the content its previous
+	// edits produced.
+	var fileEdits []lspFileEdit
+	prepare := func(filePath string, version *int, edits []lspTextEdit) error {
+		if i := slices.IndexFunc(fileEdits, func(e lspFileEdit) bool { return e.path == filePath }); i >= 0 {
+			fileEdits[i].content = applyTextEdits(fileEdits[i].content, edits)
+			fileEdits[i].edits += len(edits)
+			return nil
+		}
+		fileEdit, err := h.prepareTextEdits(filePath, version, edits)
+		if err != nil {
+			return err
+		}
+		fileEdits = append(fileEdits, fileEdit)
+		return nil
+	}
+	for _, docEdit := range edit.DocumentChanges {
+		filePath := uriToPath(docEdit.TextDocument.URI)
+		if err := prepare(filePath, docEdit.TextDocument.Version, docEdit.Edits); err != nil {
+			return tools.ResultError(fmt.Sprintf("Failed to apply changes to %s: %s", filePath, err))
+		}
+	}
+	for uri, edits := range edit.Changes {
+		filePath := uriToPath(uri)
+		if err := prepare(filePath, nil, edits); err != nil {
+			return tools.ResultError(fmt.Sprintf("Failed to apply changes to %s: %s", filePath, err))
 		}
 	}
 
-	if len(edit.Changes) > 0 {
-		for uri, edits := range edit.Changes {
-			filePath := strings.TrimPrefix(uri, "file://")
-			if err := applyTextEditsToFile(filePath, edits); err != nil {
-				return tools.ResultError(fmt.Sprintf("Failed to apply changes to %s: %s", filePath, err))
-			}
-			fileChangeCounts[filePath] = len(edits)
-			totalChanges += len(edits)
-			modifiedFiles = append(modifiedFiles, filePath)
+	var totalChanges int
+	var modifiedFiles []string
+	fileChangeCounts := make(map[string]int)
+	for _, fileEdit := range fileEdits {
+		if err := writeFileEdit(ctx, fileEdit); err != nil {
+			return tools.ResultError(fmt.Sprintf("Failed to apply changes to %s: %s", fileEdit.path, err))
 		}
+		fileChangeCounts[fileEdit.path] = fileEdit.edits
+		totalChanges += fileEdit.edits
+		modifiedFiles = append(modifiedFiles, fileEdit.path)
 	}
 
 	if totalChanges == 0 {
@@ -1241,7 +1447,7 @@ func (h *lspHandler) applyWorkspaceEdit(edit *lspWorkspaceEdit, newName string)
 	for _, file := range modifiedFiles {
 		uri := pathToURI(file)
 		if h.isFileOpen(uri) {
-			if err := h.notifyFileChangeLocked(uri); err != nil {
+			if _, err := h.syncFileLocked(uri); err != nil {
 				slog.Debug("Failed to notify LSP of rename changes", "file", file, "error", err)
 			}
 		}
@@ -1257,73 +1463,28 @@ func (h *lspHandler) applyWorkspaceEdit(edit *lspWorkspaceEdit, newName string)
 	return tools.ResultSuccess(result.String())
 }
 
-// applyTextEditsToFile applies LSP text edits to a file on disk
-func applyTextEditsToFile(filePath string, edits []lspTextEdit) error {
-	content, err := os.ReadFile(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
-	}
-
-	lines := strings.Split(string(content), "\n")
-
-	sortedEdits := make([]lspTextEdit, len(edits))
-	copy(sortedEdits, edits)
-	slices.SortFunc(sortedEdits, func(a, b lspTextEdit) int {
-		if a.Range.Start.Line != b.Range.Start.Line {
-			return cmp.Compare(b.Range.Start.Line, a.Range.Start.Line)
-		}
-		return cmp.Compare(b.Range.Start.Character, a.Range.Start.Character)
-	})
-
-	for _, edit := range sortedEdits {
-		lines = applyTextEdit(lines, edit)
-	}
-
-	newContent := strings.Join(lines, "\n")
-	if err := os.WriteFile(filePath, []byte(newContent), 0o644); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
-	}
-
-	return nil
-}
-
-func applyTextEdit(lines []string, edit lspTextEdit) []string {
-	startLine := edit.Range.Start.Line
-	startChar := edit.Range.Start.Character
-	endLine := edit.Range.End.Line
-	endChar := edit.Range.End.Character
-
-	if startLine >= len(lines) {
-		return lines
+// workspaceEditDiff returns the unified diff of the changes a workspace edit
+// would make to the files, sorted by path, without applying them.
+func workspaceEditDiff(edit *lspWorkspaceEdit) (string, error) {
+	editsByFile := make(map[string][]lspTextEdit)
+	for _, docEdit := range edit.DocumentChanges {
+		filePath := uriToPath(docEdit.TextDocument.URI)
+		editsByFile[filePath] = append(editsByFile[filePath], docEdit.Edits...)
 	}
-	if endLine >= len(lines) {
-		endLine = len(lines) - 1
-		endChar = len(lines[endLine])
+	for uri, edits := range edit.Changes {
+		filePath := uriToPath(uri)
+		editsByFile[filePath] = append(editsByFile[filePath], edits...)
 	}
 
-	startChar = min(startChar, len(lines[startLine]))
-	endChar = min(endChar, len(lines[endLine]))
-
-	prefix := ""
-	if startLine < len(lines) && startChar <= len(lines[startLine]) {
-		prefix = lines[startLine][:startChar]
-	}
-	suffix := ""
-	if endLine < len(lines) && endChar <= len(lines[endLine]) {
-		suffix = lines[endLine][endChar:]
-	}
-
-	newText := prefix + edit.NewText + suffix
-	newLines := strings.Split(newText, "\n")
-
-	result := make([]string, 0, len(lines)-(endLine-startLine)+len(newLines)-1)
-	result = append(result, lines[:startLine]...)
-	result = append(result, newLines...)
-	if endLine+1 < len(lines) {
-		result = append(result, lines[endLine+1:]...)
+	var diff strings.Builder
+	for _, filePath := range slices.Sorted(maps.Keys(editsByFile)) {
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", filePath, err)
+		}
+		diff.WriteString(fileDiff(filePath, true, string(content), applyTextEdits(string(content), editsByFile[filePath])))
 	}
-
-	return result
+	return diff.String(), nil
 }
 
 func formatCodeActions(file string, line int, data json.RawMessage) string {
@@ -1454,6 +1615,7 @@ func (h *lspHandler) readNotifications(ctx context.Context, stderrBuf *concurren
 
 func (h *lspHandler) processNotification(msg []byte) {
 	var notif struct {
+		ID     *int64          `json:"id"`
 		Method string          `json:"method"`
 		Params json.RawMessage `json:"params"`
 	}
@@ -1461,6 +1623,11 @@ func (h *lspHandler) processNotification(msg []byte) {
 		return
 	}
 
+	if notif.ID != nil && notif.Method == "workspace/workspaceFolders" {
+		h.replyWorkspaceFoldersLocked(*notif.ID)
+		return
+	}
+
 	if notif.Method == "textDocument/publishDiagnostics" {
 		var params struct {
 			URI         string          `json:"uri"`
@@ -1507,13 +1674,13 @@ func (h *lspHandler) openFileOnDemand(_ context.Context, uri string) error {
 		return nil
 	}
 
-	filePath := strings.TrimPrefix(uri, "file://")
+	filePath := uriToPath(uri)
 
 	if !h.handlesFile(filePath) {
 		return fmt.Errorf("LSP does not handle file type: %s", filepath.Ext(filePath))
 	}
 
-	content, err := os.ReadFile(filePath)
+	content, stamp, err := readFileState(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
@@ -1537,47 +1704,13 @@ func (h *lspHandler) openFileOnDemand(_ context.Context, uri string) error {
 	}
 
 	h.openFilesMu.Lock()
-	h.openFiles[uri] = 1
+	h.openFiles[uri] = lspOpenFile{version: 1, sum: sha256.Sum256(content), stamp: stamp}
 	h.openFilesMu.Unlock()
 
 	slog.Debug("Auto-opened file for LSP", "uri", uri, "languageId", languageID)
 	return nil
 }
 
-func (h *lspHandler) NotifyFileChange(_ context.Context, uri string) error {
-	if !h.isFileOpen(uri) {
-		return fmt.Errorf("file not open: %s", uri)
-	}
-
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
-	return h.notifyFileChangeLocked(uri)
-}
-
-// notifyFileChangeLocked re-reads a file from disk and sends a
-// textDocument/didChange notification. The caller must hold h.mu.
-func (h *lspHandler) notifyFileChangeLocked(uri string) error {
-	filePath := strings.TrimPrefix(uri, "file://")
-
-	content, err := os.ReadFile(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
-	}
-
-	h.openFilesMu.Lock()
-	h.openFiles[uri]++
-	version := h.openFiles[uri]
-	h.openFilesMu.Unlock()
-
-	changeParams := map[string]any{
-		"textDocument":   map[string]any{"uri": uri, "version": version},
-		"contentChanges": []map[string]any{{"text": string(content)}},
-	}
-
-	return h.sendNotificationLocked("textDocument/didChange", changeParams)
-}
-
 func (h *lspHandler) waitForDiagnostics(ctx context.Context, timeout time.Duration) {
 	initialVersion := h.diagnosticsVersion.Load()
 	deadline := time.After(timeout)
@@ -1598,14 +1731,6 @@ func (h *lspHandler) waitForDiagnostics(ctx context.Context, timeout time.Durati
 	}
 }
 
-func pathToURI(path string) string {
-	absPath, err := filepath.Abs(path)
-	if err != nil {
-		return "file://" + path
-	}
-	return "file://" + absPath
-}
-
 func detectLanguageID(path string) string {
 	ext := strings.ToLower(filepath.Ext(path))
 	languageMap := map[string]string{
@@ -1747,7 +1872,7 @@ func formatLocations(data json.RawMessage) string {
 
 func formatLocation(loc lspLocation) string {
 	return fmt.Sprintf("- %s:%d:%d",
-		strings.TrimPrefix(loc.URI, "file://"),
+		uriToPath(loc.URI),
 		loc.Range.Start.Line+1,
 		loc.Range.Start.Character+1)
 }
@@ -1767,7 +1892,7 @@ func formatSymbols(data json.RawMessage) string {
 		var lines []string
 		for _, s := range symbols {
 			kind := symbolKindName(s.Kind)
-			loc := strings.TrimPrefix(s.Location.URI, "file://")
+			loc := uriToPath(s.Location.URI)
 			line := fmt.Sprintf("- %s %s (%s:%d)", kind, s.Name, loc, s.Location.Range.Start.Line+1)
 			if s.ContainerName != "" {
 				line += fmt.Sprintf(" [in %s]", s.ContainerName)
@@ -1848,7 +1973,7 @@ func formatIncomingCalls(targetName string, data json.RawMessage) string {
 	var lines []string
 	lines = append(lines, fmt.Sprintf("Incoming calls to '%s':", targetName))
 	for _, call := range calls {
-		filePath := strings.TrimPrefix(call.From.URI, "file://")
+		filePath := uriToPath(call.From.URI)
 		line := call.From.Range.Start.Line + 1
 		detail := ""
 		if call.From.Detail != "" {
@@ -1879,7 +2004,7 @@ func formatOutgoingCalls(sourceName string, data json.RawMessage) string {
 	var lines []string
 	lines = append(lines, fmt.Sprintf("Outgoing calls from '%s':", sourceName))
 	for _, call := range calls {
-		filePath := strings.TrimPrefix(call.To.URI, "file://")
+		filePath := uriToPath(call.To.URI)
 		line := call.To.Range.Start.Line + 1
 		detail := ""
 		if call.To.Detail != "" {
@@ -1904,7 +2029,7 @@ func formatTypeHierarchy(typeName, direction string, data json.RawMessage) strin
 	var lines []string
 	lines = append(lines, fmt.Sprintf("%s of '%s':", direction, typeName))
 	for _, item := range items {
-		filePath := strings.TrimPrefix(item.URI, "file://")
+		filePath := uriToPath(item.URI)
 		line := item.Range.Start.Line + 1
 		detail := ""
 		if item.Detail != "" {
@@ -0,0 +1,325 @@
+package builtin
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/docker/docker-agent/pkg/fsx"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+const (
+	// maxWorkspaceDiagnosticsFiles bounds the number of files opened to
+	// collect diagnostics from servers without workspace diagnostics.
+	maxWorkspaceDiagnosticsFiles = 500
+
+	// defaultWorkspaceDiagnosticsResults is the default number of
+	// diagnostics listed by lsp_workspace_diagnostics.
+	defaultWorkspaceDiagnosticsResults = 100
+
+	// workspaceDiagnosticsTimeout bounds the time spent waiting for the
+	// server to publish the diagnostics of the files that were opened.
+	workspaceDiagnosticsTimeout = 30 * time.Second
+
+	// workspaceDiagnosticsQuietPeriod is how long the published diagnostics
+	// must stay unchanged before they are considered complete.
+	workspaceDiagnosticsQuietPeriod = 500 * time.Millisecond
+)
+
+// WorkspaceDiagnosticsArgs for getting the diagnostics of the whole workspace.
+type WorkspaceDiagnosticsArgs struct {
+	MaxResults int `json:"max_results,omitempty" jsonschema:"Maximum number of diagnostics to list, most severe first (default: 100)"`
+}
+
+// lspDiagnosticOptions is the diagnosticProvider server capability.
+type lspDiagnosticOptions struct {
+	WorkspaceDiagnostics bool `json:"workspaceDiagnostics,omitempty"`
+}
+
+type lspWorkspaceDiagnosticReport struct {
+	Items []struct {
+		Kind  string          `json:"kind"`
+		URI   string          `json:"uri"`
+		Items []lspDiagnostic `json:"items"`
+	} `json:"items"`
+}
+
+// workspaceDiagnosticsSummary is the machine-readable first line of the
+// lsp_workspace_diagnostics output.
+type workspaceDiagnosticsSummary struct {
+	Errors      int `json:"errors"`
+	Warnings    int `json:"warnings"`
+	Information int `json:"information"`
+	Hints       int `json:"hints"`
+	Files       int `json:"files"`
+}
+
+// supportsWorkspaceDiagnostics reports whether the server advertised
+// workspace/diagnostic support.
+func (h *lspHandler) supportsWorkspaceDiagnostics() bool {
+	if h.capabilities == nil || h.capabilities.DiagnosticProvider == nil {
+		return false
+	}
+
+	data, err := json.Marshal(h.capabilities.DiagnosticProvider)
+	if err != nil {
+		return false
+	}
+	var opts lspDiagnosticOptions
+	if err := json.Unmarshal(data, &opts); err != nil {
+		return false
+	}
+	return opts.WorkspaceDiagnostics
+}
+
+func (h *lspHandler) workspaceDiagnostics(ctx context.Context, args WorkspaceDiagnosticsArgs) (*tools.ToolCallResult, error) {
+	if err := h.ensureInitialized(); err != nil {
+		return tools.ResultError(fmt.Sprintf("LSP initialization failed: %s", err)), nil
+	}
+
+	maxResults := args.MaxResults
+	if maxResults <= 0 {
+		maxResults = defaultWorkspaceDiagnosticsResults
+	}
+
+	if h.supportsWorkspaceDiagnostics() {
+		diags, err := h.pullWorkspaceDiagnostics()
+		if err == nil {
+			return tools.ResultSuccess(h.formatWorkspaceDiagnostics(diags, maxResults)), nil
+		}
+		slog.Debug("Workspace diagnostics request failed, opening files instead", "error", err)
+	}
+
+	opened, err := h.openWorkspaceFiles(ctx)
+	if err != nil {
+		return tools.ResultError(fmt.Sprintf("Failed to list workspace files: %s", err)), nil
+	}
+	h.waitForPublishedDiagnostics(ctx, opened)
+
+	h.diagnosticsMu.RLock()
+	diags := make(map[string][]lspDiagnostic, len(h.diagnostics))
+	for uri, d := range h.diagnostics {
+		if h.handlesFile(uriToPath(uri)) {
+			diags[uri] = d
+		}
+	}
+	h.diagnosticsMu.RUnlock()
+
+	return tools.ResultSuccess(h.formatWorkspaceDiagnostics(diags, maxResults)), nil
+}
+
+// pullWorkspaceDiagnostics asks the server for the diagnostics of every file
+// in the workspace with a workspace/diagnostic request.
+func (h *lspHandler) pullWorkspaceDiagnostics() (map[string][]lspDiagnostic, error) {
+	h.mu.Lock()
+	result, err := h.sendRequestLocked("workspace/diagnostic", map[string]any{
+		"previousResultIds": []any{},
+	})
+	h.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var report lspWorkspaceDiagnosticReport
+	if err := json.Unmarshal(result, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse workspace diagnostics: %w", err)
+	}
+
+	diags := make(map[string][]lspDiagnostic, len(report.Items))
+	for _, item := range report.Items {
+		// "unchanged" reports only happen for previous result IDs, which
+		// are never sent.
+		if item.Kind == "full" {
+			diags[item.URI] = append(diags[item.URI], item.Items...)
+		}
+	}
+	return diags, nil
+}
+
+// openWorkspaceFiles opens the files of the working directory handled by
+// the server, skipping the ones ignored by git, so that it publishes their
+// diagnostics. It returns the number of files that were opened.
+func (h *lspHandler) openWorkspaceFiles(ctx context.Context) (int, error) {
+	root, err := filepath.Abs(h.workingDir)
+	if err != nil {
+		return 0, err
+	}
+
+	matcher, err := fsx.NewVCSMatcher(root)
+	if err != nil {
+		slog.Debug("Failed to load gitignore patterns", "path", root, "error", err)
+	}
+
+	files, err := fsx.WalkFiles(ctx, root, fsx.WalkFilesOptions{ShouldIgnore: matcher.ShouldIgnore})
+	if err != nil {
+		return 0, err
+	}
+
+	var opened int
+	for _, file := range files {
+		if opened >= maxWorkspaceDiagnosticsFiles {
+			slog.Debug("Too many files for workspace diagnostics", "max", maxWorkspaceDiagnosticsFiles)
+			break
+		}
+		path := filepath.Join(root, file)
+		if !h.handlesFile(path) {
+			continue
+		}
+
+		uri := pathToURI(path)
+		if h.isFileOpen(uri) {
+			continue
+		}
+		if err := h.openFileOnDemand(ctx, uri); err != nil {
+			slog.Debug("Failed to open file for workspace diagnostics", "file", path, "error", err)
+			continue
+		}
+		opened++
+	}
+	return opened, nil
+}
+
+// waitForPublishedDiagnostics waits for the server to stop publishing
+// diagnostics. Notifications are only read along with responses, so each
+// round sends a request the server has to answer, and reject, to read the
+// diagnostics published so far.
+func (h *lspHandler) waitForPublishedDiagnostics(ctx context.Context, opened int) {
+	deadline := time.Now().Add(workspaceDiagnosticsTimeout)
+	startVersion := h.diagnosticsVersion.Load()
+	lastVersion := startVersion
+	minWait := time.Now()
+	if opened > 0 {
+		// Leave the server time to publish the first diagnostics.
+		minWait = minWait.Add(2 * time.Second)
+	}
+
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(workspaceDiagnosticsQuietPeriod):
+		}
+
+		h.mu.Lock()
+		// Requests starting with "$/" must be answered with MethodNotFound
+		// by servers that don't implement them.
+		_, _ = h.sendRequestLocked("$/syncDiagnostics", nil)
+		h.mu.Unlock()
+
+		version := h.diagnosticsVersion.Load()
+		if version == lastVersion && (version != startVersion || time.Now().After(minWait)) {
+			return
+		}
+		lastVersion = version
+	}
+}
+
+// formatWorkspaceDiagnostics lists the diagnostics grouped per file, most
+// severe first, after a JSON summary of the counts per severity.
+func (h *lspHandler) formatWorkspaceDiagnostics(diags map[string][]lspDiagnostic, maxResults int) string {
+	type fileDiagnostics struct {
+		path  string
+		diags []lspDiagnostic
+	}
+
+	var (
+		summary workspaceDiagnosticsSummary
+		files   []fileDiagnostics
+	)
+	for uri, d := range diags {
+		if len(d) == 0 {
+			continue
+		}
+		for _, diag := range d {
+			switch diag.Severity {
+			case 2:
+				summary.Warnings++
+			case 3:
+				summary.Information++
+			case 4:
+				summary.Hints++
+			default:
+				summary.Errors++
+			}
+		}
+
+		sorted := slices.Clone(d)
+		slices.SortStableFunc(sorted, func(a, b lspDiagnostic) int {
+			return cmp.Or(
+				cmp.Compare(severityRank(a.Severity), severityRank(b.Severity)),
+				cmp.Compare(a.Range.Start.Line, b.Range.Start.Line),
+				cmp.Compare(a.Range.Start.Character, b.Range.Start.Character),
+			)
+		})
+		files = append(files, fileDiagnostics{path: h.relativePath(uriToPath(uri)), diags: sorted})
+	}
+	summary.Files = len(files)
+
+	// Files with the most severe diagnostics first.
+	slices.SortFunc(files, func(a, b fileDiagnostics) int {
+		return cmp.Or(
+			cmp.Compare(severityRank(a.diags[0].Severity), severityRank(b.diags[0].Severity)),
+			cmp.Compare(a.path, b.path),
+		)
+	})
+
+	summaryJSON, _ := json.Marshal(summary)
+
+	var result strings.Builder
+	fmt.Fprintf(&result, "Summary: %s\n", summaryJSON)
+	if len(files) == 0 {
+		result.WriteString("No diagnostics in workspace")
+		return result.String()
+	}
+
+	total := summary.Errors + summary.Warnings + summary.Information + summary.Hints
+	listed := 0
+	for _, f := range files {
+		if listed >= maxResults {
+			break
+		}
+		fmt.Fprintf(&result, "\n%s:\n", f.path)
+		for _, d := range f.diags {
+			if listed >= maxResults {
+				break
+			}
+			fmt.Fprintf(&result, "- [%s] Line %d: %s\n", diagnosticSeverityName(d.Severity), d.Range.Start.Line+1, d.Message)
+			listed++
+		}
+	}
+	if listed < total {
+		fmt.Fprintf(&result, "\n... and %d more", total-listed)
+	}
+
+	return strings.TrimSuffix(result.String(), "\n")
+}
+
+// relativePath returns path relative to the working directory when it is
+// inside it.
+func (h *lspHandler) relativePath(path string) string {
+	root, err := filepath.Abs(h.workingDir)
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
+
+// severityRank orders severities from the most to the least severe. A
+// missing severity is an error.
+func severityRank(severity int) int {
+	if severity < 1 || severity > 4 {
+		return 1
+	}
+	return severity
+}
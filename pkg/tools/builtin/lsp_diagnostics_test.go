@@ -0,0 +1,264 @@
+package builtin
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLSPServer answers the messages of an lspHandler over pipes. Handle
+// returns the result of a request, and the notifications to send before the
+// response to the next request.
+type fakeLSPServer struct {
+	handle func(method string, params json.RawMessage) (result any, notifications []any)
+
+	mu      sync.Mutex
+	methods []string
+}
+
+func startFakeLSPServer(t *testing.T, h *lspHandler, handle func(string, json.RawMessage) (any, []any)) *fakeLSPServer {
+	t.Helper()
+
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+	t.Cleanup(func() {
+		clientWriter.Close()
+		serverWriter.Close()
+	})
+
+	h.stdin = clientWriter
+	h.stdout = bufio.NewReader(clientReader)
+	h.cmd = exec.Command("true")
+	h.initialized.Store(true)
+
+	s := &fakeLSPServer{handle: handle}
+	go s.serve(bufio.NewReader(serverReader), serverWriter)
+	return s
+}
+
+func (s *fakeLSPServer) serve(r *bufio.Reader, w io.Writer) {
+	var pending []any
+	for {
+		body, err := readFakeLSPMessage(r)
+		if err != nil {
+			return
+		}
+
+		var msg struct {
+			ID     *int64          `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if json.Unmarshal(body, &msg) != nil {
+			return
+		}
+		s.mu.Lock()
+		s.methods = append(s.methods, msg.Method)
+		s.mu.Unlock()
+
+		result, notifications := s.handle(msg.Method, msg.Params)
+		pending = append(pending, notifications...)
+		if msg.ID == nil {
+			continue
+		}
+
+		for _, n := range pending {
+			writeFakeLSPMessage(w, n)
+		}
+		pending = nil
+
+		if lspErr, ok := result.(*lspError); ok {
+			writeFakeLSPMessage(w, map[string]any{"jsonrpc": "2.0", "id": *msg.ID, "error": lspErr})
+		} else {
+			writeFakeLSPMessage(w, map[string]any{"jsonrpc": "2.0", "id": *msg.ID, "result": result})
+		}
+	}
+}
+
+func (s *fakeLSPServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.methods...)
+}
+
+func readFakeLSPMessage(r *bufio.Reader) ([]byte, error) {
+	var length int
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if after, ok := strings.CutPrefix(line, "Content-Length:"); ok {
+			length, _ = strconv.Atoi(strings.TrimSpace(after))
+		}
+	}
+	body := make([]byte, length)
+	_, err := io.ReadFull(r, body)
+	return body, err
+}
+
+func writeFakeLSPMessage(w io.Writer, msg any) {
+	data, _ := json.Marshal(msg)
+	_, _ = fmt.Fprintf(w, "Content-Length: %d\r\n\r\n%s", len(data), data)
+}
+
+func diagnosticAt(line, severity int, message string) lspDiagnostic {
+	return lspDiagnostic{Range: lspRange{Start: lspPosition{Line: line}}, Severity: severity, Message: message}
+}
+
+func TestFormatWorkspaceDiagnostics(t *testing.T) {
+	t.Parallel()
+
+	tool := NewLSPTool("gopls", nil, nil, "/work")
+	result := tool.handler.formatWorkspaceDiagnostics(map[string][]lspDiagnostic{
+		"file:///work/b.go": {
+			diagnosticAt(9, 2, "unused variable"),
+			diagnosticAt(3, 1, "undefined: foo"),
+		},
+		"file:///work/a.go": {
+			diagnosticAt(0, 4, "could be simplified"),
+		},
+		"file:///work/clean.go": {},
+	}, 100)
+
+	assert.Equal(t, `Summary: {"errors":1,"warnings":1,"information":0,"hints":1,"files":2}
+
+b.go:
+- [Error] Line 4: undefined: foo
+- [Warning] Line 10: unused variable
+
+a.go:
+- [Hint] Line 1: could be simplified`, result)
+}
+
+func TestFormatWorkspaceDiagnostics_Capped(t *testing.T) {
+	t.Parallel()
+
+	tool := NewLSPTool("gopls", nil, nil, "/work")
+	result := tool.handler.formatWorkspaceDiagnostics(map[string][]lspDiagnostic{
+		"file:///work/a.go": {
+			diagnosticAt(0, 1, "first"),
+			diagnosticAt(1, 1, "second"),
+			diagnosticAt(2, 1, "third"),
+		},
+	}, 2)
+
+	assert.Contains(t, result, `"errors":3`)
+	assert.Contains(t, result, "second")
+	assert.NotContains(t, result, "third")
+	assert.True(t, strings.HasSuffix(result, "... and 1 more"))
+}
+
+func TestFormatWorkspaceDiagnostics_Empty(t *testing.T) {
+	t.Parallel()
+
+	tool := NewLSPTool("gopls", nil, nil, "/work")
+	result := tool.handler.formatWorkspaceDiagnostics(nil, 100)
+
+	assert.Equal(t, "Summary: {\"errors\":0,\"warnings\":0,\"information\":0,\"hints\":0,\"files\":0}\nNo diagnostics in workspace", result)
+}
+
+func TestLSPHandler_SupportsWorkspaceDiagnostics(t *testing.T) {
+	t.Parallel()
+
+	h := NewLSPTool("gopls", nil, nil, "/work").handler
+	assert.False(t, h.supportsWorkspaceDiagnostics())
+
+	h.capabilities = &lspServerCapabilities{DiagnosticProvider: map[string]any{"interFileDependencies": true}}
+	assert.False(t, h.supportsWorkspaceDiagnostics())
+
+	h.capabilities = &lspServerCapabilities{DiagnosticProvider: map[string]any{"workspaceDiagnostics": true}}
+	assert.True(t, h.supportsWorkspaceDiagnostics())
+}
+
+func TestLSPHandler_WorkspaceDiagnostics_Pull(t *testing.T) {
+	t.Parallel()
+
+	tool := NewLSPTool("gopls", nil, nil, "/work")
+	tool.handler.capabilities = &lspServerCapabilities{DiagnosticProvider: map[string]any{"workspaceDiagnostics": true}}
+	server := startFakeLSPServer(t, tool.handler, func(method string, _ json.RawMessage) (any, []any) {
+		if method != "workspace/diagnostic" {
+			return &lspError{Code: -32601, Message: "method not found"}, nil
+		}
+		return map[string]any{"items": []map[string]any{
+			{"kind": "full", "uri": "file:///work/main.go", "items": []lspDiagnostic{diagnosticAt(4, 1, "missing return")}},
+			{"kind": "unchanged", "uri": "file:///work/other.go", "resultId": "1"},
+		}}, nil
+	})
+
+	result, err := tool.handler.workspaceDiagnostics(t.Context(), WorkspaceDiagnosticsArgs{})
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.Contains(t, result.Output, `"errors":1`)
+	assert.Contains(t, result.Output, "main.go:\n- [Error] Line 5: missing return")
+	assert.Equal(t, []string{"workspace/diagnostic"}, server.received())
+}
+
+func TestLSPHandler_WorkspaceDiagnostics_OpensFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	initGitRepo(t, dir)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("generated.go\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "generated.go"), []byte("package main\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes\n"), 0o644))
+
+	tool := NewLSPTool("gopls", nil, nil, dir)
+	tool.SetFileTypes([]string{".go"})
+
+	var (
+		mu     sync.Mutex
+		opened []string
+	)
+	startFakeLSPServer(t, tool.handler, func(method string, params json.RawMessage) (any, []any) {
+		if method != "textDocument/didOpen" {
+			return &lspError{Code: -32601, Message: "method not found"}, nil
+		}
+
+		var p struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+		}
+		_ = json.Unmarshal(params, &p)
+		mu.Lock()
+		opened = append(opened, p.TextDocument.URI)
+		mu.Unlock()
+
+		return nil, []any{map[string]any{
+			"jsonrpc": "2.0",
+			"method":  "textDocument/publishDiagnostics",
+			"params": map[string]any{
+				"uri":         p.TextDocument.URI,
+				"diagnostics": []lspDiagnostic{diagnosticAt(0, 1, "expected declaration")},
+			},
+		}}
+	})
+
+	result, err := tool.handler.workspaceDiagnostics(t.Context(), WorkspaceDiagnosticsArgs{})
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+
+	mu.Lock()
+	assert.Equal(t, []string{pathToURI(filepath.Join(dir, "main.go"))}, opened)
+	mu.Unlock()
+
+	assert.Contains(t, result.Output, `{"errors":1,"warnings":0,"information":0,"hints":0,"files":1}`)
+	assert.Contains(t, result.Output, "main.go:\n- [Error] Line 1: expected declaration")
+}
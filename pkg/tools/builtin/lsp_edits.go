@@ -0,0 +1,250 @@
+package builtin
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// errStaleEdits is returned when the edits of the LSP server were computed
+// for another content of the file than the one on disk.
+var errStaleEdits = errors.New("stale edits")
+
+// lspFileEdit is the new content of a file, with the LSP text edits applied.
+type lspFileEdit struct {
+	path    string
+	content string
+	edits   int
+}
+
+// applyTextEditsToFile applies LSP text edits to a file on disk and reports
+// the change to the tools.FileChangeReporter of the context, see
+// prepareTextEdits and writeFileEdit.
+func (h *lspHandler) applyTextEditsToFile(ctx context.Context, filePath string, version *int, edits []lspTextEdit) error {
+	edit, err := h.prepareTextEdits(filePath, version, edits)
+	if err != nil {
+		return err
+	}
+	return writeFileEdit(ctx, edit)
+}
+
+// prepareTextEdits returns the content of a file with LSP text edits
+// applied. The edits are refused with errStaleEdits if the file isn't the
+// one the server computed them for: when the file is open on the server,
+// and version, if set, isn't the version of the document there or the file
+// changed on disk since it was last sent to the server.
+func (h *lspHandler) prepareTextEdits(filePath string, version *int, edits []lspTextEdit) (lspFileEdit, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return lspFileEdit{}, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	h.openFilesMu.RLock()
+	file, open := h.openFiles[pathToURI(filePath)]
+	h.openFilesMu.RUnlock()
+
+	if open {
+		if version != nil && *version != file.version {
+			return lspFileEdit{}, fmt.Errorf("%w: the edits are for version %d of the document, the language server has version %d; run the request again", errStaleEdits, *version, file.version)
+		}
+		if sha256.Sum256(content) != file.sum {
+			return lspFileEdit{}, fmt.Errorf("%w: the file changed on disk since the language server computed the edits; run the request again", errStaleEdits)
+		}
+	}
+
+	return lspFileEdit{
+		path:    filePath,
+		content: applyTextEdits(string(content), edits),
+		edits:   len(edits),
+	}, nil
+}
+
+// writeFileEdit writes the new content of a file, atomically, and reports
+// the change to the tools.FileChangeReporter of the context.
+func writeFileEdit(ctx context.Context, edit lspFileEdit) error {
+	tools.WillChangeFile(ctx, edit.path)
+	if err := writeFileAtomic(edit.path, []byte(edit.content)); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	tools.ReportFileChange(ctx, tools.FileChange{Path: edit.path, Type: tools.FileModified})
+
+	slog.Debug("Applied LSP text edits", "file", edit.path, "edits", edit.edits, "bytes", len(edit.content))
+	return nil
+}
+
+// writeFileAtomic replaces the content of the file at path, keeping its
+// mode. The content is written to a temporary file of the same directory,
+// renamed over the file once complete: the file is never left truncated.
+// A symbolic link is followed, so that it keeps pointing to the file.
+func writeFileAtomic(path string, content []byte) error {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
+
+// applyTextEdits returns content with the LSP text edits applied. The
+// characters of the positions are UTF-16 code units, the default encoding
+// of LSP, and the lines end with "\n", "\r\n" or "\r". The line breaks of
+// the new texts are converted to the ones of content, so that the file
+// keeps its line endings.
+func applyTextEdits(content string, edits []lspTextEdit) string {
+	lines := lineRanges(content)
+	eol := lineEnding(content)
+
+	type replacement struct {
+		start, end int
+		text       string
+		order      int
+	}
+	replacements := make([]replacement, 0, len(edits))
+	for i, edit := range edits {
+		start := positionOffset(content, lines, edit.Range.Start)
+		end := max(positionOffset(content, lines, edit.Range.End), start)
+		replacements = append(replacements, replacement{start, end, convertLineEndings(edit.NewText, eol), i})
+	}
+
+	// Texts inserted at the same position are inserted in the order of
+	// the edits.
+	slices.SortFunc(replacements, func(a, b replacement) int {
+		return cmp.Or(cmp.Compare(a.start, b.start), cmp.Compare(a.order, b.order))
+	})
+
+	var b strings.Builder
+	b.Grow(len(content))
+	offset := 0
+	for _, r := range replacements {
+		// Overlapping edits are invalid: the overlap is left as the
+		// previous edit made it.
+		start := max(r.start, offset)
+		b.WriteString(content[offset:start])
+		b.WriteString(r.text)
+		offset = max(r.end, start)
+	}
+	b.WriteString(content[offset:])
+
+	return b.String()
+}
+
+// lineRange is the byte range of a line, without its line break.
+type lineRange struct {
+	start, end int
+}
+
+// lineRanges returns the lines of content.
+func lineRanges(content string) []lineRange {
+	var lines []lineRange
+	start := 0
+	for i := 0; i < len(content); i++ {
+		switch content[i] {
+		case '\n':
+			lines = append(lines, lineRange{start, i})
+			start = i + 1
+		case '\r':
+			lines = append(lines, lineRange{start, i})
+			if i+1 < len(content) && content[i+1] == '\n' {
+				i++
+			}
+			start = i + 1
+		}
+	}
+	return append(lines, lineRange{start, len(content)})
+}
+
+// positionOffset returns the byte offset of an LSP position in content. As
+// per the LSP specification, a position past the end of its line is at the
+// end of the line, and a line past the end of the content at its end.
+func positionOffset(content string, lines []lineRange, pos lspPosition) int {
+	if pos.Line < 0 {
+		return 0
+	}
+	if pos.Line >= len(lines) {
+		return len(content)
+	}
+
+	line := lines[pos.Line]
+	units := 0
+	for i, r := range content[line.start:line.end] {
+		if units >= pos.Character {
+			return line.start + i
+		}
+		if r == utf8.RuneError {
+			units++
+		} else {
+			units += utf16.RuneLen(r)
+		}
+	}
+	return line.end
+}
+
+// lineEnding returns the first line break of content, "\n" if it has none.
+func lineEnding(content string) string {
+	i := strings.IndexAny(content, "\r\n")
+	switch {
+	case i < 0:
+		return "\n"
+	case strings.HasPrefix(content[i:], "\r\n"):
+		return "\r\n"
+	default:
+		return content[i : i+1]
+	}
+}
+
+// convertLineEndings returns text with its line breaks replaced by eol.
+func convertLineEndings(text, eol string) string {
+	if !strings.ContainsAny(text, "\r\n") {
+		return text
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	if eol != "\n" {
+		text = strings.ReplaceAll(text, "\n", eol)
+	}
+	return text
+}
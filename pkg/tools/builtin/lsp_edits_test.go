@@ -0,0 +1,233 @@
+package builtin
+
+import (
+	"crypto/sha256"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyTextEdits_LineEndings(t *testing.T) {
+	t.Parallel()
+
+	// The new lines of a CRLF file end with CRLF.
+	insert := lspTextEdit{Range: lspRange{Start: lspPosition{Line: 1}, End: lspPosition{Line: 1}}, NewText: "x\ny\n"}
+	assert.Equal(t, "a\r\nx\r\ny\r\nb\r\n", applyTextEdits("a\r\nb\r\n", []lspTextEdit{insert}))
+
+	// A position past the end of a line is at the end of the line, before
+	// its line break.
+	replace := lspTextEdit{Range: lspRange{Start: lspPosition{Line: 0}, End: lspPosition{Line: 0, Character: 100}}, NewText: "z"}
+	assert.Equal(t, "z\r\nb\r\n", applyTextEdits("a\r\nb\r\n", []lspTextEdit{replace}))
+
+	// The missing trailing newline stays missing, and a line past the end
+	// is at the end of the content.
+	appendText := lspTextEdit{Range: lspRange{Start: lspPosition{Line: 5}, End: lspPosition{Line: 5}}, NewText: "!"}
+	assert.Equal(t, "a\nb!", applyTextEdits("a\nb", []lspTextEdit{appendText}))
+	assert.Equal(t, "a\nb\n!", applyTextEdits("a\nb\n", []lspTextEdit{appendText}))
+}
+
+func TestApplyTextEdits_UTF16Positions(t *testing.T) {
+	t.Parallel()
+
+	// "é" is one UTF-16 code unit, "😀" two.
+	edit := lspTextEdit{Range: lspRange{Start: lspPosition{Character: 9}, End: lspPosition{Character: 11}}, NewText: ":)"}
+	assert.Equal(t, "héllo 😀 :)", applyTextEdits("héllo 😀 ok", []lspTextEdit{edit}))
+}
+
+func TestApplyTextEdits_InsertsAtSamePosition(t *testing.T) {
+	t.Parallel()
+
+	at := lspRange{Start: lspPosition{Character: 1}, End: lspPosition{Character: 1}}
+	edits := []lspTextEdit{{Range: at, NewText: "1"}, {Range: at, NewText: "2"}, {Range: at, NewText: "3"}}
+	assert.Equal(t, "a123b", applyTextEdits("ab", edits))
+}
+
+// randomDocument returns a document of random tokens, e.g. with mixed line
+// endings, multi-byte characters or no trailing newline, along with the
+// offsets of its token boundaries, except the ones splitting a CRLF.
+func randomDocument(rng *rand.Rand, tokens []string) (string, []int) {
+	var b strings.Builder
+	for range rng.IntN(60) {
+		b.WriteString(tokens[rng.IntN(len(tokens))])
+	}
+	doc := b.String()
+
+	offsets := []int{0}
+	for i := 1; i <= len(doc); i++ {
+		if i < len(doc) && (!utf8.RuneStart(doc[i]) || doc[i-1:i+1] == "\r\n") {
+			continue
+		}
+		offsets = append(offsets, i)
+	}
+	return doc, offsets
+}
+
+// offsetPosition returns the LSP position of the byte offset of content.
+func offsetPosition(content string, offset int) lspPosition {
+	var pos lspPosition
+	for i := 0; i < offset; {
+		switch {
+		case strings.HasPrefix(content[i:], "\r\n"):
+			pos = lspPosition{Line: pos.Line + 1}
+			i += 2
+		case content[i] == '\n' || content[i] == '\r':
+			pos = lspPosition{Line: pos.Line + 1}
+			i++
+		default:
+			r := []rune(content[i:])[0]
+			pos.Character += utf16.RuneLen(r)
+			i += len(string(r))
+		}
+	}
+	return pos
+}
+
+func TestApplyTextEdits_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	mixed := []string{"a", "z", " ", "é", "日本", "😀", "\n", "\r\n", "\r", "\t"}
+	for range 500 {
+		doc, offsets := randomDocument(rng, mixed)
+
+		assert.Equal(t, doc, applyTextEdits(doc, nil))
+
+		// Replacing a range with text without line breaks changes nothing
+		// else, whatever the line endings and characters around it.
+		i, j := offsets[rng.IntN(len(offsets))], offsets[rng.IntN(len(offsets))]
+		i, j = min(i, j), max(i, j)
+		text, _ := randomDocument(rng, []string{"b", "ü", "🎉", " "})
+		edit := lspTextEdit{Range: lspRange{Start: offsetPosition(doc, i), End: offsetPosition(doc, j)}, NewText: text}
+		require.Equal(t, doc[:i]+text+doc[j:], applyTextEdits(doc, []lspTextEdit{edit}), "replacing %q with %q in %q", doc[i:j], text, doc)
+
+		// Undoing the edit gives the document back, unless the edit joined
+		// a CR and an LF or the text it removed has line breaks.
+		edited := doc[:i] + text + doc[j:]
+		undo := lspTextEdit{Range: lspRange{Start: offsetPosition(edited, i), End: offsetPosition(edited, i+len(text))}, NewText: doc[i:j]}
+		if text != "" && !strings.ContainsAny(doc[i:j], "\r\n") {
+			require.Equal(t, doc, applyTextEdits(edited, []lspTextEdit{undo}))
+		}
+	}
+
+	// New lines get the line endings of the document, LF if it has none.
+	for _, eol := range []string{"\n", "\r\n"} {
+		for range 100 {
+			doc, offsets := randomDocument(rng, []string{"a", "é", "😀", " ", eol})
+			i := offsets[rng.IntN(len(offsets))]
+			want := eol
+			if !strings.Contains(doc, eol) {
+				want = "\n"
+			}
+			edit := lspTextEdit{Range: lspRange{Start: offsetPosition(doc, i), End: offsetPosition(doc, i)}, NewText: "x\ny\r\n"}
+			require.Equal(t, doc[:i]+"x"+want+"y"+want+doc[i:], applyTextEdits(doc, []lspTextEdit{edit}), "inserting in %q", doc)
+		}
+	}
+}
+
+// newLSPToolWithOpenFile returns an LSP tool having the file at path open,
+// with the given content and version.
+func newLSPToolWithOpenFile(t *testing.T, path, content string, version int) *LSPTool {
+	t.Helper()
+
+	tool := NewLSPTool("gopls", nil, nil, filepath.Dir(path))
+	tool.handler.openFiles[pathToURI(path)] = lspOpenFile{version: version, sum: sha256.Sum256([]byte(content))}
+	return tool
+}
+
+func TestApplyTextEditsToFile_Stale(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("func oldName() {}\n"), 0o644))
+	tool := newLSPToolWithOpenFile(t, path, "func oldName() {}\n", 2)
+
+	rename := []lspTextEdit{{
+		Range:   lspRange{Start: lspPosition{Character: 5}, End: lspPosition{Character: 12}},
+		NewText: "newName",
+	}}
+
+	// The edits are for another version of the document.
+	err := tool.handler.applyTextEditsToFile(t.Context(), path, new(1), rename)
+	require.ErrorIs(t, err, errStaleEdits)
+	assert.Contains(t, err.Error(), "version 1")
+
+	// The file changed since it was sent to the server.
+	require.NoError(t, os.WriteFile(path, []byte("// Doc\nfunc oldName() {}\n"), 0o644))
+	err = tool.handler.applyTextEditsToFile(t.Context(), path, new(2), rename)
+	require.ErrorIs(t, err, errStaleEdits)
+	assert.Contains(t, err.Error(), "changed on disk")
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "// Doc\nfunc oldName() {}\n", string(content))
+}
+
+func TestApplyWorkspaceEdit_StaleFileWritesNothing(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.go")
+	b := filepath.Join(dir, "b.go")
+	require.NoError(t, os.WriteFile(a, []byte("func oldName() {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(b, []byte("x := oldName()\n"), 0o644))
+	tool := newLSPToolWithOpenFile(t, b, "y := oldName()\n", 1)
+
+	rename := []lspTextEdit{{
+		Range:   lspRange{Start: lspPosition{Character: 5}, End: lspPosition{Character: 12}},
+		NewText: "newName",
+	}}
+	edit := &lspWorkspaceEdit{DocumentChanges: []lspTextDocumentEdit{
+		{TextDocument: lspVersionedTextDocumentIdentifier{URI: pathToURI(a)}, Edits: rename},
+		{TextDocument: lspVersionedTextDocumentIdentifier{URI: pathToURI(b)}, Edits: rename},
+	}}
+
+	result := tool.handler.applyWorkspaceEdit(t.Context(), edit, "newName")
+	require.True(t, result.IsError)
+	assert.Contains(t, result.Output, "changed on disk")
+
+	content, err := os.ReadFile(a)
+	require.NoError(t, err)
+	assert.Equal(t, "func oldName() {}\n", string(content))
+}
+
+func TestApplyTextEditsToFile_KeepsModeAndLink(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes and symbolic links are POSIX specific")
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "run.sh")
+	link := filepath.Join(dir, "link.sh")
+	require.NoError(t, os.WriteFile(path, []byte("echo hello\r\n"), 0o755))
+	require.NoError(t, os.Symlink(path, link))
+
+	tool := NewLSPTool("bash-language-server", nil, nil, dir)
+	edit := lspTextEdit{Range: lspRange{Start: lspPosition{Character: 5}, End: lspPosition{Character: 10}}, NewText: "world"}
+	require.NoError(t, tool.handler.applyTextEditsToFile(t.Context(), link, nil, []lspTextEdit{edit}))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "echo world\r\n", string(content))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+
+	info, err = os.Lstat(link)
+	require.NoError(t, err)
+	assert.Equal(t, os.ModeSymlink, info.Mode().Type())
+
+	// No temporary file is left behind.
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
@@ -34,11 +34,18 @@ type lspRouteTarget struct {
 
 // Verify interface compliance.
 var (
-	_ tools.ToolSet      = (*LSPMultiplexer)(nil)
-	_ tools.Startable    = (*LSPMultiplexer)(nil)
-	_ tools.Instructable = (*LSPMultiplexer)(nil)
+	_ tools.ToolSet             = (*LSPMultiplexer)(nil)
+	_ tools.Startable           = (*LSPMultiplexer)(nil)
+	_ tools.Instructable        = (*LSPMultiplexer)(nil)
+	_ tools.Previewable         = (*LSPMultiplexer)(nil)
+	_ tools.ConcurrencyDeclarer = (*LSPMultiplexer)(nil)
 )
 
+// Concurrency is Serial, like the one of its backends.
+func (m *LSPMultiplexer) Concurrency() tools.Concurrency {
+	return tools.Serial
+}
+
 // NewLSPMultiplexer creates a multiplexer that routes LSP tool calls
 // to the appropriate backend based on file type.
 func NewLSPMultiplexer(backends []LSPBackend) *LSPMultiplexer {
@@ -111,9 +118,10 @@ func (m *LSPMultiplexer) Tools(ctx context.Context) ([]tools.Tool, error) {
 	for _, name := range toolOrder {
 		t := seenTools[name]
 		handlers := handlersByName[name]
-		if name == ToolNameLSPWorkspace || name == ToolNameLSPWorkspaceSymbols {
+		switch name {
+		case ToolNameLSPWorkspace, ToolNameLSPWorkspaceSymbols, ToolNameLSPWorkspaceDiagnostics, ToolNameLSPAddWorkspaceFolder:
 			t.Handler = broadcastLSP(handlers)
-		} else {
+		default:
 			t.Handler = routeByFile(handlers)
 		}
 		result = append(result, t)
@@ -121,6 +129,23 @@ func (m *LSPMultiplexer) Tools(ctx context.Context) ([]tools.Tool, error) {
 	return result, nil
 }
 
+// Preview previews a tool call with the backend handling its file, like
+// routeByFile.
+func (m *LSPMultiplexer) Preview(ctx context.Context, tc tools.ToolCall) (*tools.Preview, error) {
+	var args struct {
+		File string `json:"file"`
+	}
+	if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
+		return nil, err
+	}
+	for _, b := range m.backends {
+		if args.File != "" && b.LSP.HandlesFile(args.File) {
+			return tools.PreviewToolCall(ctx, b.Toolset, tc)
+		}
+	}
+	return nil, nil
+}
+
 // routeByFile returns a handler that extracts the "file" field from the JSON
 // arguments and dispatches to the backend whose file-type filter matches.
 func routeByFile(handlers []lspRouteTarget) tools.ToolHandler {
@@ -0,0 +1,114 @@
+package builtin
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+)
+
+// LSPPresetAuto is the preset of the lsp toolsets standing for the presets
+// detected in the working directory, see DetectLSPPresets.
+const LSPPresetAuto = "auto"
+
+// LSPServerPreset is the configuration of a well-known language server.
+type LSPServerPreset struct {
+	// Name is the name of the preset, usually the language, e.g. "go".
+	Name      string
+	Command   string
+	Args      []string
+	FileTypes []string
+	// Install is the command installing the server.
+	Install string
+
+	// markers are the sets of files, at the root of a workspace, telling
+	// the preset applies: all the files of one of the sets must exist.
+	markers [][]string
+}
+
+var lspPresets = []LSPServerPreset{
+	{
+		Name:      "go",
+		Command:   "gopls",
+		FileTypes: []string{".go", ".mod"},
+		Install:   "go install golang.org/x/tools/gopls@latest",
+		markers:   [][]string{{"go.mod"}, {"go.work"}},
+	},
+	{
+		Name:      "typescript",
+		Command:   "typescript-language-server",
+		Args:      []string{"--stdio"},
+		FileTypes: []string{".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs"},
+		Install:   "npm install -g typescript-language-server typescript",
+		markers:   [][]string{{"package.json", "tsconfig.json"}, {"package.json", "jsconfig.json"}},
+	},
+	{
+		Name:      "python",
+		Command:   "pyright-langserver",
+		Args:      []string{"--stdio"},
+		FileTypes: []string{".py", ".pyi"},
+		Install:   "npm install -g pyright",
+		markers:   [][]string{{"pyproject.toml"}, {"setup.py"}, {"requirements.txt"}},
+	},
+	{
+		Name:      "rust",
+		Command:   "rust-analyzer",
+		FileTypes: []string{".rs"},
+		Install:   "rustup component add rust-analyzer",
+		markers:   [][]string{{"Cargo.toml"}},
+	},
+}
+
+// lspLookPath finds the commands of the presets, replaced in tests.
+var lspLookPath = exec.LookPath
+
+// LSPPreset returns the preset of the language server named name, e.g.
+// "go" for gopls.
+func LSPPreset(name string) (LSPServerPreset, bool) {
+	for _, preset := range lspPresets {
+		if preset.Name == name {
+			preset.Args = slices.Clone(preset.Args)
+			preset.FileTypes = slices.Clone(preset.FileTypes)
+			return preset, true
+		}
+	}
+	return LSPServerPreset{}, false
+}
+
+// LSPPresetNames returns the names of the presets.
+func LSPPresetNames() []string {
+	names := make([]string, len(lspPresets))
+	for i, preset := range lspPresets {
+		names[i] = preset.Name
+	}
+	return names
+}
+
+// DetectLSPPresets returns the presets that apply to the workspace in dir,
+// from the files at its root, e.g. go.mod for gopls, whose server is in the
+// PATH. The presets whose server is missing are left out, with a warning
+// telling how to install it.
+func DetectLSPPresets(dir string) (presets []LSPServerPreset, warnings []string) {
+	for _, preset := range lspPresets {
+		if !slices.ContainsFunc(preset.markers, func(markers []string) bool { return allExist(dir, markers) }) {
+			continue
+		}
+		if _, err := lspLookPath(preset.Command); err != nil {
+			warnings = append(warnings, fmt.Sprintf("the %s language server %s is not in the PATH, install it with: %s", preset.Name, preset.Command, preset.Install))
+			continue
+		}
+		preset, _ = LSPPreset(preset.Name)
+		presets = append(presets, preset)
+	}
+	return presets, warnings
+}
+
+func allExist(dir string, names []string) bool {
+	for _, name := range names {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return false
+		}
+	}
+	return true
+}
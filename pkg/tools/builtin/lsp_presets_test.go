@@ -0,0 +1,65 @@
+package builtin
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLSPPreset(t *testing.T) {
+	t.Parallel()
+
+	preset, ok := LSPPreset("typescript")
+	require.True(t, ok)
+	assert.Equal(t, "typescript-language-server", preset.Command)
+	assert.Equal(t, []string{"--stdio"}, preset.Args)
+	assert.Contains(t, preset.FileTypes, ".tsx")
+
+	// The presets can't be changed through the returned ones.
+	preset.Args[0] = "--socket"
+	preset, _ = LSPPreset("typescript")
+	assert.Equal(t, []string{"--stdio"}, preset.Args)
+
+	_, ok = LSPPreset("cobol")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"go", "typescript", "python", "rust"}, LSPPresetNames())
+}
+
+func TestDetectLSPPresets(t *testing.T) {
+	installed := map[string]bool{"gopls": true, "typescript-language-server": true}
+	lookPath := lspLookPath
+	t.Cleanup(func() { lspLookPath = lookPath })
+	lspLookPath = func(file string) (string, error) {
+		if installed[file] {
+			return "/usr/bin/" + file, nil
+		}
+		return "", errors.New("not found")
+	}
+
+	dir := t.TempDir()
+	for _, name := range []string{"go.mod", "package.json", "Cargo.toml"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o644))
+	}
+
+	presets, warnings := DetectLSPPresets(dir)
+
+	// package.json alone isn't a TypeScript project.
+	require.Len(t, presets, 1)
+	assert.Equal(t, "go", presets[0].Name)
+	assert.Equal(t, []string{
+		"the rust language server rust-analyzer is not in the PATH, install it with: rustup component add rust-analyzer",
+	}, warnings)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tsconfig.json"), nil, 0o644))
+	presets, _ = DetectLSPPresets(dir)
+	require.Len(t, presets, 2)
+	assert.Equal(t, "typescript", presets[1].Name)
+
+	presets, warnings = DetectLSPPresets(t.TempDir())
+	assert.Empty(t, presets)
+	assert.Empty(t, warnings)
+}
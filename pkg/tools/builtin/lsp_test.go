@@ -2,11 +2,16 @@ package builtin
 
 import (
 	"encoding/json"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/tools"
 )
 
 func TestNewLSPTool(t *testing.T) {
@@ -17,6 +22,17 @@ func TestNewLSPTool(t *testing.T) {
 	require.NotNil(t, tool.handler)
 }
 
+func TestLSPTool_EnvPassthrough(t *testing.T) {
+	t.Setenv("LSP_SCOPED_A", "a")
+	t.Setenv("LSP_LEAKED_SECRET", "secret")
+
+	tool := NewLSPTool("gopls", nil, []string{"EXPLICIT=1"}, "/tmp", WithLSPEnvPassthrough("LSP_SCOPED_*"))
+	assert.Equal(t, []string{"LSP_SCOPED_A=a", "EXPLICIT=1"}, tool.handler.environ())
+
+	tool = NewLSPTool("gopls", nil, []string{"EXPLICIT=1"}, "/tmp")
+	assert.Contains(t, tool.handler.environ(), "LSP_LEAKED_SECRET=secret")
+}
+
 func TestLSPTool_Tools(t *testing.T) {
 	t.Parallel()
 
@@ -38,6 +54,7 @@ func TestLSPTool_Tools(t *testing.T) {
 		ToolNameLSPDocumentSymbols,
 		ToolNameLSPWorkspaceSymbols,
 		ToolNameLSPDiagnostics,
+		ToolNameLSPWorkspaceDiagnostics,
 		ToolNameLSPRename,
 		ToolNameLSPCodeActions,
 		ToolNameLSPFormat,
@@ -46,6 +63,7 @@ func TestLSPTool_Tools(t *testing.T) {
 		ToolNameLSPImplementations,
 		ToolNameLSPSignatureHelp,
 		ToolNameLSPInlayHints,
+		ToolNameLSPAddWorkspaceFolder,
 	}
 
 	for _, name := range expectedTools {
@@ -56,6 +74,28 @@ func TestLSPTool_Tools(t *testing.T) {
 	assert.Len(t, tools, len(expectedTools))
 }
 
+func TestLSPTool_ToolsAreBuiltOnce(t *testing.T) {
+	t.Parallel()
+
+	first := NewLSPTool("gopls", nil, nil, "/tmp")
+	second := NewLSPTool("pyright", nil, nil, "/tmp")
+
+	tools, err := first.Tools(t.Context())
+	require.NoError(t, err)
+	again, err := first.Tools(t.Context())
+	require.NoError(t, err)
+	assert.Same(t, &tools[0], &again[0])
+
+	// The definitions are shared, the handlers are bound to each toolset.
+	others, err := second.Tools(t.Context())
+	require.NoError(t, err)
+	require.Len(t, others, len(tools))
+	for i := range tools {
+		assert.Same(t, tools[i].Parameters, others[i].Parameters, tools[i].Name)
+		assert.NotNil(t, others[i].Handler, tools[i].Name)
+	}
+}
+
 func TestLSPTool_ToolDescriptions(t *testing.T) {
 	t.Parallel()
 
@@ -280,7 +320,7 @@ func TestLSPHandler_GetDiagnostics_NoDiagnostics(t *testing.T) {
 	ctx := t.Context()
 
 	// Mark file as open to skip auto-open attempt
-	tool.handler.openFiles["file:///nonexistent.go"] = 1
+	tool.handler.openFiles["file:///nonexistent.go"] = lspOpenFile{version: 1}
 
 	result, err := tool.handler.getDiagnostics(ctx, FileArgs{File: "/nonexistent.go"})
 	require.NoError(t, err)
@@ -306,7 +346,7 @@ func TestLSPHandler_GetDiagnostics_WithDiagnostics(t *testing.T) {
 		},
 	}
 	// Mark file as open to skip auto-open attempt
-	tool.handler.openFiles["file:///test.go"] = 1
+	tool.handler.openFiles["file:///test.go"] = lspOpenFile{version: 1}
 
 	ctx := t.Context()
 	result, err := tool.handler.getDiagnostics(ctx, FileArgs{File: "/test.go"})
@@ -437,6 +477,19 @@ func TestPathToURI(t *testing.T) {
 	// Absolute path
 	uri := pathToURI("/home/user/project/main.go")
 	assert.Equal(t, "file:///home/user/project/main.go", uri)
+
+	// Windows paths
+	assert.Equal(t, "file:///C:/src/project/main.go", pathToURI(`C:\src\project\main.go`))
+	assert.Equal(t, "file:///d:/main.go", pathToURI("d:/main.go"))
+}
+
+func TestURIToPath(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "/home/user/project/main.go", uriToPath("file:///home/user/project/main.go"))
+	assert.Equal(t, "/home/user/my project/main.go", uriToPath("file:///home/user/my%20project/main.go"))
+	assert.Equal(t, filepath.FromSlash("C:/src/main.go"), uriToPath("file:///C:/src/main.go"))
+	assert.Equal(t, filepath.FromSlash("c:/src/main.go"), uriToPath("file:///c%3A/src/main.go"))
 }
 
 func TestLSPHandler_IsFileOpen(t *testing.T) {
@@ -449,7 +502,7 @@ func TestLSPHandler_IsFileOpen(t *testing.T) {
 
 	// Track a file as open
 	tool.handler.openFilesMu.Lock()
-	tool.handler.openFiles["file:///test.go"] = 1
+	tool.handler.openFiles["file:///test.go"] = lspOpenFile{version: 1}
 	tool.handler.openFilesMu.Unlock()
 
 	assert.True(t, tool.handler.isFileOpen("file:///test.go"))
@@ -535,10 +588,9 @@ func TestWorkspaceSymbolsArgs_EmptyQuery(t *testing.T) {
 	assert.Empty(t, args.Query)
 }
 
-func TestApplyTextEdit_SingleLineReplacement(t *testing.T) {
+func TestApplyTextEdits_SingleLineReplacement(t *testing.T) {
 	t.Parallel()
 
-	lines := []string{"hello world", "foo bar", "baz qux"}
 	edit := lspTextEdit{
 		Range: lspRange{
 			Start: lspPosition{Line: 1, Character: 4},
@@ -547,14 +599,103 @@ func TestApplyTextEdit_SingleLineReplacement(t *testing.T) {
 		NewText: "replaced",
 	}
 
-	result := applyTextEdit(lines, edit)
-	assert.Equal(t, []string{"hello world", "foo replaced", "baz qux"}, result)
+	result := applyTextEdits("hello world\nfoo bar\nbaz qux", []lspTextEdit{edit})
+	assert.Equal(t, "hello world\nfoo replaced\nbaz qux", result)
+}
+
+func TestApplyWorkspaceEdit_ReportsFileChanges(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.go")
+	b := filepath.Join(dir, "b.go")
+	require.NoError(t, os.WriteFile(a, []byte("func oldName() {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(b, []byte("x := oldName()\ny := oldName()\n"), 0o644))
+
+	rename := func(line, character int) lspTextEdit {
+		return lspTextEdit{
+			Range: lspRange{
+				Start: lspPosition{Line: line, Character: character},
+				End:   lspPosition{Line: line, Character: character + len("oldName")},
+			},
+			NewText: "newName",
+		}
+	}
+	edit := &lspWorkspaceEdit{DocumentChanges: []lspTextDocumentEdit{
+		{TextDocument: lspVersionedTextDocumentIdentifier{URI: pathToURI(a)}, Edits: []lspTextEdit{rename(0, 5)}},
+		{TextDocument: lspVersionedTextDocumentIdentifier{URI: pathToURI(b)}, Edits: []lspTextEdit{rename(0, 5), rename(1, 5)}},
+	}}
+
+	reporter := &fileChangeRecorder{}
+	tool := NewLSPTool("gopls", nil, nil, dir)
+	result := tool.handler.applyWorkspaceEdit(tools.WithFileChangeReporter(t.Context(), reporter), edit, "newName")
+	require.False(t, result.IsError, result.Output)
+
+	content, err := os.ReadFile(b)
+	require.NoError(t, err)
+	assert.Equal(t, "x := newName()\ny := newName()\n", string(content))
+
+	assert.Equal(t, []string{a, b}, reporter.willChange)
+	assert.Equal(t, []tools.FileChange{
+		{Path: a, Type: tools.FileModified},
+		{Path: b, Type: tools.FileModified},
+	}, reporter.changes)
+}
+
+func TestWorkspaceEditDiff(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.go")
+	b := filepath.Join(dir, "b.go")
+	require.NoError(t, os.WriteFile(a, []byte("func oldName() {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(b, []byte("x := oldName()\n"), 0o644))
+
+	rename := lspTextEdit{
+		Range: lspRange{
+			Start: lspPosition{Line: 0, Character: 5},
+			End:   lspPosition{Line: 0, Character: 5 + len("oldName")},
+		},
+		NewText: "newName",
+	}
+	edit := &lspWorkspaceEdit{Changes: map[string][]lspTextEdit{
+		pathToURI(b): {rename},
+		pathToURI(a): {rename},
+	}}
+
+	diff, err := workspaceEditDiff(edit)
+	require.NoError(t, err)
+	assert.Equal(t, "--- "+a+"\n+++ "+a+"\n@@ -1 +1 @@\n-func oldName() {}\n+func newName() {}\n"+
+		"--- "+b+"\n+++ "+b+"\n@@ -1 +1 @@\n-x := oldName()\n+x := newName()\n", diff)
+
+	// Nothing is applied.
+	content, err := os.ReadFile(a)
+	require.NoError(t, err)
+	assert.Equal(t, "func oldName() {}\n", string(content))
+}
+
+// fileChangeRecorder is a tools.FileChangeReporter recording what it's told.
+type fileChangeRecorder struct {
+	mu         sync.Mutex
+	willChange []string
+	changes    []tools.FileChange
+}
+
+func (r *fileChangeRecorder) WillChange(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.willChange = append(r.willChange, path)
+}
+
+func (r *fileChangeRecorder) Changed(change tools.FileChange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.changes = append(r.changes, change)
 }
 
-func TestApplyTextEdit_MultiLineReplacement(t *testing.T) {
+func TestApplyTextEdits_MultiLineReplacement(t *testing.T) {
 	t.Parallel()
 
-	lines := []string{"line 0", "line 1", "line 2", "line 3"}
 	edit := lspTextEdit{
 		Range: lspRange{
 			Start: lspPosition{Line: 1, Character: 5},
@@ -563,14 +704,13 @@ func TestApplyTextEdit_MultiLineReplacement(t *testing.T) {
 		NewText: "REPLACED",
 	}
 
-	result := applyTextEdit(lines, edit)
-	assert.Equal(t, []string{"line 0", "line REPLACED2", "line 3"}, result)
+	result := applyTextEdits("line 0\nline 1\nline 2\nline 3", []lspTextEdit{edit})
+	assert.Equal(t, "line 0\nline REPLACED2\nline 3", result)
 }
 
-func TestApplyTextEdit_InsertNewLine(t *testing.T) {
+func TestApplyTextEdits_InsertNewLine(t *testing.T) {
 	t.Parallel()
 
-	lines := []string{"hello", "world"}
 	edit := lspTextEdit{
 		Range: lspRange{
 			Start: lspPosition{Line: 0, Character: 5},
@@ -579,8 +719,8 @@ func TestApplyTextEdit_InsertNewLine(t *testing.T) {
 		NewText: "\nnew line\n",
 	}
 
-	result := applyTextEdit(lines, edit)
-	assert.Equal(t, []string{"hello", "new line", "", "world"}, result)
+	result := applyTextEdits("hello\nworld", []lspTextEdit{edit})
+	assert.Equal(t, "hello\nnew line\n\nworld", result)
 }
 
 func TestFormatCodeActions_Empty(t *testing.T) {
@@ -0,0 +1,184 @@
+package builtin
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// lspWatchInterval is how often the files opened on the LSP server are
+// checked for changes made on disk by other tools.
+const lspWatchInterval = 500 * time.Millisecond
+
+// lspOpenFile is the state of a document opened on the LSP server.
+type lspOpenFile struct {
+	version int
+	// sum is the hash of the content last sent to the server.
+	sum [sha256.Size]byte
+	// stamp is the state of the file on disk when it was last synced.
+	stamp fileStamp
+}
+
+// fileStamp identifies a version of a file on disk, cheaply.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func (s fileStamp) equal(other fileStamp) bool {
+	return s.modTime.Equal(other.modTime) && s.size == other.size
+}
+
+func statFile(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// readFileState reads a file along with its stamp, taken first so that a
+// write racing with the read shows up as a change on the next check.
+func readFileState(path string) ([]byte, fileStamp, error) {
+	stamp, err := statFile(path)
+	if err != nil {
+		return nil, fileStamp{}, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fileStamp{}, err
+	}
+	return content, stamp, nil
+}
+
+// NotifyExternalEdit tells the LSP server about an edit of path made outside
+// of the LSP tools, e.g. by the filesystem tools, so that the next requests
+// see the new content. Files the server doesn't have open are ignored.
+func (t *LSPTool) NotifyExternalEdit(path string) error {
+	uri := pathToURI(path)
+	if !t.handler.isFileOpen(uri) {
+		return nil
+	}
+	_, err := t.handler.syncFile(uri)
+	return err
+}
+
+// NotifyExternalEdit tells the backends handling path about an edit made
+// outside of the LSP tools.
+func (m *LSPMultiplexer) NotifyExternalEdit(path string) error {
+	for _, b := range m.backends {
+		if b.LSP.HandlesFile(path) {
+			if err := b.LSP.NotifyExternalEdit(path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// syncFile is syncFileLocked for callers not holding h.mu.
+func (h *lspHandler) syncFile(uri string) (bool, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.syncFileLocked(uri)
+}
+
+// syncFileLocked re-reads an open file from disk and, when its content
+// differs from what the server last received, sends it with a
+// textDocument/didChange notification and a new version. It reports whether
+// the content changed. The caller must hold h.mu.
+func (h *lspHandler) syncFileLocked(uri string) (bool, error) {
+	if h.cmd == nil || !h.initialized.Load() {
+		return false, nil
+	}
+
+	h.openFilesMu.RLock()
+	file, ok := h.openFiles[uri]
+	h.openFilesMu.RUnlock()
+	if !ok {
+		return false, nil
+	}
+
+	content, stamp, err := readFileState(uriToPath(uri))
+	if err != nil {
+		return false, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	sum := sha256.Sum256(content)
+	if sum == file.sum {
+		h.openFilesMu.Lock()
+		file.stamp = stamp
+		h.openFiles[uri] = file
+		h.openFilesMu.Unlock()
+		return false, nil
+	}
+
+	file.version++
+	file.sum = sum
+	file.stamp = stamp
+
+	changeParams := map[string]any{
+		"textDocument":   map[string]any{"uri": uri, "version": file.version},
+		"contentChanges": []map[string]any{{"text": string(content)}},
+	}
+	if err := h.sendNotificationLocked("textDocument/didChange", changeParams); err != nil {
+		return false, err
+	}
+
+	h.openFilesMu.Lock()
+	h.openFiles[uri] = file
+	h.openFilesMu.Unlock()
+
+	slog.Debug("Sent file changes to LSP", "uri", uri, "version", file.version)
+	return true, nil
+}
+
+// watchOpenFiles polls the files opened on the server for changes made on
+// disk. A changed file is sent once it has stayed the same for a whole
+// interval, so that a burst of writes results in a single didChange.
+func (h *lspHandler) watchOpenFiles(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// Stamps of the changed files seen at the previous tick.
+	pending := make(map[string]fileStamp)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		changed := h.changedOpenFiles()
+		for uri, stamp := range changed {
+			if previous, ok := pending[uri]; !ok || !previous.equal(stamp) {
+				continue
+			}
+			if _, err := h.syncFile(uri); err != nil {
+				slog.Debug("Failed to sync changed file", "uri", uri, "error", err)
+			}
+			delete(changed, uri)
+		}
+		pending = changed
+	}
+}
+
+// changedOpenFiles returns the current stamps of the open files that changed
+// on disk since they were last synced.
+func (h *lspHandler) changedOpenFiles() map[string]fileStamp {
+	h.openFilesMu.RLock()
+	defer h.openFilesMu.RUnlock()
+
+	changed := make(map[string]fileStamp)
+	for uri, file := range h.openFiles {
+		stamp, err := statFile(uriToPath(uri))
+		if err != nil || stamp.equal(file.stamp) {
+			continue
+		}
+		changed[uri] = stamp
+	}
+	return changed
+}
@@ -0,0 +1,153 @@
+package builtin
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// documentServer is a fake LSP server keeping the documents it's sent, whose
+// hover returns the hovered line.
+type documentServer struct {
+	*fakeLSPServer
+
+	mu       sync.Mutex
+	text     map[string]string
+	versions map[string]int
+}
+
+func startDocumentServer(t *testing.T, h *lspHandler) *documentServer {
+	t.Helper()
+
+	s := &documentServer{text: make(map[string]string), versions: make(map[string]int)}
+	s.fakeLSPServer = startFakeLSPServer(t, h, func(method string, params json.RawMessage) (any, []any) {
+		var p struct {
+			TextDocument struct {
+				URI     string `json:"uri"`
+				Version int    `json:"version"`
+				Text    string `json:"text"`
+			} `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+			Position lspPosition `json:"position"`
+		}
+		_ = json.Unmarshal(params, &p)
+		uri := p.TextDocument.URI
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		switch method {
+		case "textDocument/didOpen":
+			s.text[uri] = p.TextDocument.Text
+			s.versions[uri] = p.TextDocument.Version
+		case "textDocument/didChange":
+			s.text[uri] = p.ContentChanges[0].Text
+			s.versions[uri] = p.TextDocument.Version
+		case "textDocument/hover":
+			lines := strings.Split(s.text[uri], "\n")
+			return map[string]any{"contents": lines[p.Position.Line]}, nil
+		}
+		return nil, nil
+	})
+	return s
+}
+
+func (s *documentServer) version(uri string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.versions[uri]
+}
+
+func (s *documentServer) didChanges() int {
+	var count int
+	for _, method := range s.received() {
+		if method == "textDocument/didChange" {
+			count++
+		}
+	}
+	return count
+}
+
+func TestLSPHandler_HoverSeesExternalEdits(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(file, []byte("package main\n\nfunc Old() {}\n"), 0o644))
+
+	tool := NewLSPTool("gopls", nil, nil, dir)
+	server := startDocumentServer(t, tool.handler)
+
+	result, err := tool.handler.hover(t.Context(), PositionArgs{File: file, Line: 3, Character: 6})
+	require.NoError(t, err)
+	assert.Equal(t, "func Old() {}", result.Output)
+
+	// Edited out-of-band, e.g. by the shell tool.
+	require.NoError(t, os.WriteFile(file, []byte("package main\n\nfunc Renamed() {}\n"), 0o644))
+
+	result, err = tool.handler.hover(t.Context(), PositionArgs{File: file, Line: 3, Character: 6})
+	require.NoError(t, err)
+	assert.Equal(t, "func Renamed() {}", result.Output)
+	assert.Equal(t, 2, server.version(pathToURI(file)))
+
+	// Unchanged files aren't sent again.
+	_, err = tool.handler.hover(t.Context(), PositionArgs{File: file, Line: 3, Character: 6})
+	require.NoError(t, err)
+	assert.Equal(t, 1, server.didChanges())
+}
+
+func TestLSPTool_NotifyExternalEdit(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	other := filepath.Join(dir, "other.go")
+	require.NoError(t, os.WriteFile(file, []byte("package main\n"), 0o644))
+	require.NoError(t, os.WriteFile(other, []byte("package main\n"), 0o644))
+
+	tool := NewLSPTool("gopls", nil, nil, dir)
+	server := startDocumentServer(t, tool.handler)
+	require.NoError(t, tool.handler.openFileOnDemand(t.Context(), pathToURI(file)))
+
+	require.NoError(t, os.WriteFile(file, []byte("package main\n\nfunc main() {}\n"), 0o644))
+	require.NoError(t, tool.NotifyExternalEdit(file))
+	require.NoError(t, tool.NotifyExternalEdit(file))
+	// Files the server doesn't know about are left alone.
+	require.NoError(t, tool.NotifyExternalEdit(other))
+
+	_, err := tool.handler.sendRequestLocked("shutdown", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"textDocument/didOpen", "textDocument/didChange", "shutdown"}, server.received())
+	assert.Equal(t, 2, server.version(pathToURI(file)))
+}
+
+func TestLSPHandler_WatchOpenFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(file, []byte("package main\n"), 0o644))
+
+	tool := NewLSPTool("gopls", nil, nil, dir)
+	server := startDocumentServer(t, tool.handler)
+	uri := pathToURI(file)
+	require.NoError(t, tool.handler.openFileOnDemand(t.Context(), uri))
+
+	go tool.handler.watchOpenFiles(t.Context(), 10*time.Millisecond)
+
+	require.NoError(t, os.WriteFile(file, []byte("package main\n\nfunc main() {}\n"), 0o644))
+
+	// The change is sent without any request to the server.
+	assert.Eventually(t, func() bool {
+		return server.version(uri) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, server.didChanges())
+}
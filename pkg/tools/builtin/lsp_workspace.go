@@ -0,0 +1,160 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/docker/docker-agent/pkg/fileuri"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// AddWorkspaceFolderArgs for adding a folder to the workspace.
+type AddWorkspaceFolderArgs struct {
+	Path string `json:"path" jsonschema:"Path of the folder to add, absolute or relative to the working directory"`
+}
+
+// lspWorkspaceServerCapabilities is the workspace server capability.
+type lspWorkspaceServerCapabilities struct {
+	WorkspaceFolders *lspWorkspaceFoldersCapability `json:"workspaceFolders,omitempty"`
+}
+
+type lspWorkspaceFoldersCapability struct {
+	Supported bool `json:"supported,omitempty"`
+	// ChangeNotifications is either a bool or the ID under which the
+	// notification is registered.
+	ChangeNotifications any `json:"changeNotifications,omitempty"`
+}
+
+// supportsWorkspaceFolderChanges reports whether the server can be sent
+// workspace/didChangeWorkspaceFolders notifications.
+func (h *lspHandler) supportsWorkspaceFolderChanges() bool {
+	if h.capabilities == nil || h.capabilities.Workspace == nil || h.capabilities.Workspace.WorkspaceFolders == nil {
+		return false
+	}
+
+	folders := h.capabilities.Workspace.WorkspaceFolders
+	if !folders.Supported {
+		return false
+	}
+	switch v := folders.ChangeNotifications.(type) {
+	case bool:
+		return v
+	case string:
+		return v != ""
+	default:
+		return false
+	}
+}
+
+// absPath returns the absolute path of path, relative to the working
+// directory.
+func (h *lspHandler) absPath(path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(h.workingDir, path)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// rootsLocked returns the roots of the workspace. The caller must hold h.mu.
+func (h *lspHandler) rootsLocked() []string {
+	if len(h.workspaceFolders) > 0 {
+		return h.workspaceFolders
+	}
+	return []string{h.absPath(".")}
+}
+
+// workspaceFolderParams returns the WorkspaceFolder objects of the LSP
+// protocol for the given roots.
+func workspaceFolderParams(roots []string) []map[string]string {
+	folders := make([]map[string]string, 0, len(roots))
+	for _, root := range roots {
+		folders = append(folders, map[string]string{"uri": pathToURI(root), "name": filepath.Base(root)})
+	}
+	return folders
+}
+
+func (h *lspHandler) addWorkspaceFolder(_ context.Context, args AddWorkspaceFolderArgs) (*tools.ToolCallResult, error) {
+	if args.Path == "" {
+		return tools.ResultError("path is required"), nil
+	}
+
+	path := h.absPath(args.Path)
+	info, err := os.Stat(path)
+	if err != nil {
+		return tools.ResultError(fmt.Sprintf("Failed to add workspace folder: %s", err)), nil
+	}
+	if !info.IsDir() {
+		return tools.ResultError(fmt.Sprintf("Failed to add workspace folder: %s is not a directory", path)), nil
+	}
+
+	if err := h.ensureInitialized(); err != nil {
+		return tools.ResultError(fmt.Sprintf("LSP initialization failed: %s", err)), nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	roots := h.rootsLocked()
+	if slices.Contains(roots, path) {
+		return tools.ResultSuccess(fmt.Sprintf("%s is already a workspace folder", path)), nil
+	}
+	if !h.supportsWorkspaceFolderChanges() {
+		return tools.ResultError(fmt.Sprintf("The LSP server %s doesn't support adding workspace folders", h.command)), nil
+	}
+
+	params := map[string]any{
+		"event": map[string]any{
+			"added":   workspaceFolderParams([]string{path}),
+			"removed": []any{},
+		},
+	}
+	if err := h.sendNotificationLocked("workspace/didChangeWorkspaceFolders", params); err != nil {
+		return tools.ResultError(fmt.Sprintf("Failed to add workspace folder: %s", err)), nil
+	}
+
+	// The working directory stays a root when it was the implicit one.
+	h.workspaceFolders = append(slices.Clone(roots), path)
+
+	slog.Debug("Added LSP workspace folder", "path", path)
+	return tools.ResultSuccess(fmt.Sprintf("Added workspace folder %s", path)), nil
+}
+
+// replyWorkspaceFoldersLocked answers a workspace/workspaceFolders request
+// from the server. The caller must hold h.mu.
+func (h *lspHandler) replyWorkspaceFoldersLocked(id int64) {
+	result, err := json.Marshal(workspaceFolderParams(h.rootsLocked()))
+	if err != nil {
+		return
+	}
+	if err := h.writeMessageLocked(lspResponse{JSONRPC: "2.0", ID: id, Result: result}); err != nil {
+		slog.Debug("Failed to answer workspace folders request", "error", err)
+	}
+}
+
+// pathToURI converts a file path, made absolute, to a file URI.
+func pathToURI(path string) string {
+	if !fileuri.IsDrivePath(path) {
+		if absPath, err := filepath.Abs(path); err == nil {
+			path = absPath
+		}
+	}
+	return fileuri.FromPath(path)
+}
+
+// uriToPath converts a file URI, possibly percent-encoded as some servers
+// send them, to a file path. Other URIs are returned unchanged.
+func uriToPath(uri string) string {
+	path, err := fileuri.ToPath(uri)
+	if err != nil {
+		return uri
+	}
+	return path
+}
@@ -0,0 +1,179 @@
+package builtin
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// workspaceServer is a fake LSP server recording the workspace folders it's
+// sent.
+type workspaceServer struct {
+	*fakeLSPServer
+
+	mu     sync.Mutex
+	params map[string]json.RawMessage
+}
+
+func startWorkspaceServer(t *testing.T, h *lspHandler, capabilities map[string]any) *workspaceServer {
+	t.Helper()
+
+	s := &workspaceServer{params: make(map[string]json.RawMessage)}
+	s.fakeLSPServer = startFakeLSPServer(t, h, func(method string, params json.RawMessage) (any, []any) {
+		s.mu.Lock()
+		s.params[method] = params
+		s.mu.Unlock()
+
+		if method == "initialize" {
+			return map[string]any{"capabilities": capabilities}, nil
+		}
+		return nil, nil
+	})
+	return s
+}
+
+func (s *workspaceServer) paramsOf(t *testing.T, method string, v any) {
+	t.Helper()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	require.NoError(t, json.Unmarshal(s.params[method], v))
+}
+
+var workspaceFoldersCapabilities = map[string]any{
+	"workspace": map[string]any{
+		"workspaceFolders": map[string]any{
+			"supported":           true,
+			"changeNotifications": "workspace/didChangeWorkspaceFolders",
+		},
+	},
+}
+
+func initializeForTest(t *testing.T, h *lspHandler) {
+	t.Helper()
+
+	h.initialized.Store(false)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	require.NoError(t, h.initializeLocked())
+}
+
+func TestLSPHandler_InitializeWorkspaceFolders(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	tool := NewLSPTool("gopls", nil, nil, dir, WithWorkspaceFolders("api", filepath.Join(dir, "web")))
+	server := startWorkspaceServer(t, tool.handler, workspaceFoldersCapabilities)
+	initializeForTest(t, tool.handler)
+
+	var params struct {
+		RootURI          string              `json:"rootUri"`
+		WorkspaceFolders []map[string]string `json:"workspaceFolders"`
+		Capabilities     struct {
+			Workspace struct {
+				WorkspaceFolders bool `json:"workspaceFolders"`
+			} `json:"workspace"`
+		} `json:"capabilities"`
+	}
+	server.paramsOf(t, "initialize", &params)
+
+	assert.Equal(t, pathToURI(filepath.Join(dir, "api")), params.RootURI)
+	assert.Equal(t, []map[string]string{
+		{"uri": pathToURI(filepath.Join(dir, "api")), "name": "api"},
+		{"uri": pathToURI(filepath.Join(dir, "web")), "name": "web"},
+	}, params.WorkspaceFolders)
+	assert.True(t, params.Capabilities.Workspace.WorkspaceFolders)
+	assert.True(t, tool.handler.supportsWorkspaceFolderChanges())
+
+	result, err := tool.handler.workspace(t.Context(), WorkspaceArgs{})
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, "- Roots:\n  - "+filepath.Join(dir, "api")+"\n  - "+filepath.Join(dir, "web")+"\n")
+}
+
+func TestLSPHandler_InitializeDefaultsToWorkingDir(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	tool := NewLSPTool("gopls", nil, nil, dir)
+	server := startWorkspaceServer(t, tool.handler, map[string]any{})
+	initializeForTest(t, tool.handler)
+
+	var params struct {
+		RootURI          string              `json:"rootUri"`
+		WorkspaceFolders []map[string]string `json:"workspaceFolders"`
+	}
+	server.paramsOf(t, "initialize", &params)
+
+	assert.Equal(t, pathToURI(dir), params.RootURI)
+	assert.Equal(t, []map[string]string{{"uri": pathToURI(dir), "name": filepath.Base(dir)}}, params.WorkspaceFolders)
+
+	result, err := tool.handler.workspace(t.Context(), WorkspaceArgs{})
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, "- Root: "+dir+"\n")
+}
+
+func TestLSPHandler_AddWorkspaceFolder(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	nested := filepath.Join(dir, "tools", "linter")
+	require.NoError(t, os.MkdirAll(nested, 0o755))
+
+	tool := NewLSPTool("gopls", nil, nil, dir)
+	server := startWorkspaceServer(t, tool.handler, workspaceFoldersCapabilities)
+	initializeForTest(t, tool.handler)
+
+	result, err := tool.handler.addWorkspaceFolder(t.Context(), AddWorkspaceFolderArgs{Path: "tools/linter"})
+	require.NoError(t, err)
+	assert.False(t, result.IsError, result.Output)
+
+	// Wait for the notification to be handled.
+	_, err = tool.handler.sendRequestLocked("shutdown", nil)
+	require.NoError(t, err)
+
+	var params struct {
+		Event struct {
+			Added   []map[string]string `json:"added"`
+			Removed []map[string]string `json:"removed"`
+		} `json:"event"`
+	}
+	server.paramsOf(t, "workspace/didChangeWorkspaceFolders", &params)
+	assert.Equal(t, []map[string]string{{"uri": pathToURI(nested), "name": "linter"}}, params.Event.Added)
+	assert.Empty(t, params.Event.Removed)
+
+	result, err = tool.handler.workspace(t.Context(), WorkspaceArgs{})
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, "- Roots:\n  - "+dir+"\n  - "+nested+"\n")
+
+	// Adding it again is a no-op.
+	result, err = tool.handler.addWorkspaceFolder(t.Context(), AddWorkspaceFolderArgs{Path: nested})
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, "already a workspace folder")
+
+	result, err = tool.handler.addWorkspaceFolder(t.Context(), AddWorkspaceFolderArgs{Path: "missing"})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+}
+
+func TestLSPHandler_AddWorkspaceFolderUnsupported(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	tool := NewLSPTool("gopls", nil, nil, filepath.Dir(dir))
+	server := startWorkspaceServer(t, tool.handler, map[string]any{})
+	initializeForTest(t, tool.handler)
+
+	result, err := tool.handler.addWorkspaceFolder(t.Context(), AddWorkspaceFolderArgs{Path: dir})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.Output, "doesn't support adding workspace folders")
+
+	_, err = tool.handler.sendRequestLocked("shutdown", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"initialize", "initialized", "shutdown"}, server.received())
+}
@@ -0,0 +1,16 @@
+package builtin
+
+import (
+	"github.com/aymanbagabas/go-udiff"
+)
+
+// fileDiff returns the unified diff of the changes to the file at path, or
+// "" if there are none. A file that doesn't exist yet is diffed against
+// /dev/null, like git does.
+func fileDiff(path string, exists bool, before, after string) string {
+	from := path
+	if !exists {
+		from = "/dev/null"
+	}
+	return udiff.Unified(from, path, before, after)
+}
@@ -7,8 +7,13 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"os"
 	"slices"
+	"strings"
+	"sync"
 
+	"github.com/docker/docker-agent/pkg/chat"
 	"github.com/docker/docker-agent/pkg/rag"
 	ragtypes "github.com/docker/docker-agent/pkg/rag/types"
 	"github.com/docker/docker-agent/pkg/tools"
@@ -19,9 +24,22 @@ type RAGEventCallback func(event ragtypes.Event)
 
 // RAGTool provides document querying capabilities for a single RAG source.
 type RAGTool struct {
-	manager       *rag.Manager
-	toolName      string
+	manager  *rag.Manager
+	toolName string
+
+	callbackMu    sync.Mutex
 	eventCallback RAGEventCallback
+
+	// statusMu guards the indexing status reported by the status tool.
+	statusMu    sync.Mutex
+	started     bool
+	initialized bool
+	initErr     error
+	strategies  map[string]*ragStrategyStatus
+
+	// cancel stops the goroutines started by Start; wg waits for them.
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
 }
 
 // Verify interface compliance.
@@ -44,9 +62,12 @@ func (t *RAGTool) Name() string {
 	return t.toolName
 }
 
-// SetEventCallback sets a callback to receive RAG manager events during
-// initialization. Must be called before Start().
+// SetEventCallback sets a callback to receive the RAG manager events:
+// indexing progress and the usage of the embedding models. Set it before
+// Start() to receive the events of the initial indexing.
 func (t *RAGTool) SetEventCallback(cb RAGEventCallback) {
+	t.callbackMu.Lock()
+	defer t.callbackMu.Unlock()
 	t.eventCallback = cb
 }
 
@@ -57,32 +78,52 @@ func (t *RAGTool) Start(ctx context.Context) error {
 		return nil
 	}
 
-	// Forward RAG manager events if a callback is set.
-	if t.eventCallback != nil {
-		go t.forwardEvents(ctx)
-	}
+	ctx, t.cancel = context.WithCancel(ctx)
+
+	t.statusMu.Lock()
+	t.started = true
+	t.statusMu.Unlock()
+
+	// Track and forward RAG manager events.
+	t.wg.Go(func() { t.forwardEvents(ctx) })
 
 	if err := t.manager.Initialize(ctx); err != nil {
+		t.cancel()
+		t.wg.Wait()
+		t.statusMu.Lock()
+		t.initErr = err
+		t.statusMu.Unlock()
 		return fmt.Errorf("failed to initialize RAG manager %q: %w", t.toolName, err)
 	}
 
-	go func() {
+	t.statusMu.Lock()
+	t.initialized = true
+	t.statusMu.Unlock()
+
+	t.wg.Go(func() {
 		if err := t.manager.StartFileWatcher(ctx); err != nil {
 			slog.Error("Failed to start RAG file watcher", "tool", t.toolName, "error", err)
 		}
-	}()
+	})
 	return nil
 }
 
-// Stop closes the RAG manager and releases resources.
+// Stop stops forwarding events, closes the RAG manager, including its file
+// watchers, and releases resources.
 func (t *RAGTool) Stop(_ context.Context) error {
 	if t.manager == nil {
 		return nil
 	}
-	return t.manager.Close()
+	if t.cancel != nil {
+		t.cancel()
+	}
+	err := t.manager.Close()
+	t.wg.Wait()
+	return err
 }
 
-// forwardEvents reads events from the RAG manager and forwards them via the callback.
+// forwardEvents reads events from the RAG manager, records the indexing
+// status and forwards them via the callback, if any.
 func (t *RAGTool) forwardEvents(ctx context.Context) {
 	for {
 		select {
@@ -92,7 +133,53 @@ func (t *RAGTool) forwardEvents(ctx context.Context) {
 			if !ok {
 				return
 			}
-			t.eventCallback(event)
+			t.recordEvent(event)
+
+			t.callbackMu.Lock()
+			cb := t.eventCallback
+			t.callbackMu.Unlock()
+			if cb != nil {
+				cb(event)
+			}
+		}
+	}
+}
+
+// recordEvent updates the indexing status of the strategy sending event.
+func (t *RAGTool) recordEvent(event ragtypes.Event) {
+	t.statusMu.Lock()
+	defer t.statusMu.Unlock()
+
+	if t.strategies == nil {
+		t.strategies = make(map[string]*ragStrategyStatus)
+	}
+	status, ok := t.strategies[event.StrategyName]
+	if !ok {
+		status = &ragStrategyStatus{Name: event.StrategyName}
+		t.strategies[event.StrategyName] = status
+	}
+
+	switch event.Type {
+	case ragtypes.EventTypeIndexingStarted:
+		status.State = ragStateIndexing
+		status.IndexedFiles, status.TotalFiles = 0, 0
+	case ragtypes.EventTypeIndexingProgress:
+		status.State = ragStateIndexing
+		if event.Progress != nil {
+			status.IndexedFiles, status.TotalFiles = event.Progress.Current, event.Progress.Total
+		}
+	case ragtypes.EventTypeIndexingComplete:
+		status.State = ragStateReady
+		if event.Cache != nil {
+			status.CacheHitRate = event.Cache.HitRate()
+		}
+	case ragtypes.EventTypeUsage:
+		// Usage events carry cumulative totals.
+		status.EmbeddingTokens = event.TotalTokens
+		status.Cost = event.Cost
+	case ragtypes.EventTypeError:
+		if event.Error != nil {
+			status.Error = event.Error.Error()
 		}
 	}
 }
@@ -109,6 +196,7 @@ func (t *RAGTool) Instructions() string {
 
 type queryRAGArgs struct {
 	Query string `json:"query" jsonschema:"Search query"`
+	TopK  int    `json:"top_k,omitempty" jsonschema:"Maximum number of results to return (default: 10, max: 50)"`
 }
 
 type queryResult struct {
@@ -116,6 +204,40 @@ type queryResult struct {
 	Content    string  `json:"content" jsonschema:"Relevant document chunk content"`
 	Similarity float64 `json:"similarity" jsonschema:"Similarity score (0-1)"`
 	ChunkIndex int     `json:"chunk_index" jsonschema:"Index of the chunk within the source document"`
+	StartLine  int     `json:"start_line,omitempty" jsonschema:"First line of the chunk in the source document, when found"`
+	EndLine    int     `json:"end_line,omitempty" jsonschema:"Last line of the chunk in the source document, when found"`
+}
+
+// RAGQueryMeta is the metadata of the results of a RAG query: the chunks
+// returned, as the sources the answer of the model may draw on.
+type RAGQueryMeta struct {
+	Sources []chat.Annotation `json:"sources"`
+}
+
+// Indexing states reported by the status tool.
+const (
+	ragStateNotStarted = "not_started"
+	ragStateIndexing   = "indexing"
+	ragStateReady      = "ready"
+	ragStateFailed     = "failed"
+)
+
+type ragStatus struct {
+	Name       string              `json:"name" jsonschema:"Name of the RAG source"`
+	State      string              `json:"state" jsonschema:"Indexing state: not_started, indexing, ready or failed"`
+	Error      string              `json:"error,omitempty" jsonschema:"Why the indexing failed"`
+	Strategies []ragStrategyStatus `json:"strategies,omitempty" jsonschema:"Status of each retrieval strategy"`
+}
+
+type ragStrategyStatus struct {
+	Name            string  `json:"name" jsonschema:"Name of the strategy"`
+	State           string  `json:"state,omitempty" jsonschema:"Indexing state of the strategy"`
+	IndexedFiles    int     `json:"indexed_files,omitempty" jsonschema:"Number of files indexed so far"`
+	TotalFiles      int     `json:"total_files,omitempty" jsonschema:"Number of files to index"`
+	EmbeddingTokens int64   `json:"embedding_tokens,omitempty" jsonschema:"Tokens sent to the embedding model"`
+	Cost            float64 `json:"cost,omitempty" jsonschema:"Cost of the embeddings"`
+	CacheHitRate    float64 `json:"cache_hit_rate,omitempty" jsonschema:"Ratio of the chunks whose embeddings were cached, during the last indexing"`
+	Error           string  `json:"error,omitempty" jsonschema:"Last error of the strategy"`
 }
 
 func (t *RAGTool) Tools(context.Context) ([]tools.Tool, error) {
@@ -127,18 +249,63 @@ func (t *RAGTool) Tools(context.Context) ([]tools.Tool, error) {
 		"Provide a natural language query describing what you need. "+
 		"Returns the most relevant document chunks with file paths.", t.toolName))
 
-	return []tools.Tool{{
-		Name:         t.toolName,
-		Category:     "knowledge",
-		Description:  description,
-		Parameters:   tools.MustSchemaFor[queryRAGArgs](),
-		OutputSchema: tools.MustSchemaFor[[]queryResult](),
-		Handler:      tools.NewHandler(t.handleQueryRAG),
-		Annotations: tools.ToolAnnotations{
-			ReadOnlyHint: true,
-			Title:        "Query " + t.toolName,
+	return []tools.Tool{
+		{
+			Name:         t.toolName,
+			Category:     "knowledge",
+			Description:  description,
+			Parameters:   tools.MustSchemaFor[queryRAGArgs](),
+			OutputSchema: tools.MustSchemaFor[[]queryResult](),
+			Handler:      tools.NewHandler(t.handleQueryRAG),
+			Annotations: tools.ToolAnnotations{
+				ReadOnlyHint: true,
+				Title:        "Query " + t.toolName,
+			},
+		},
+		{
+			Name:         t.toolName + "_status",
+			Category:     "knowledge",
+			Description:  fmt.Sprintf("Reports whether the documents searched by %s are indexed, the indexing progress and the usage of the embedding model.", t.toolName),
+			OutputSchema: tools.MustSchemaFor[ragStatus](),
+			Handler:      tools.NewHandler(t.handleStatus),
+			Annotations: tools.ToolAnnotations{
+				ReadOnlyHint: true,
+				Title:        "Status of " + t.toolName,
+			},
+			NoResultCache: true,
 		},
-	}}, nil
+	}, nil
+}
+
+func (t *RAGTool) handleStatus(_ context.Context, _ map[string]any) (*tools.ToolCallResult, error) {
+	return tools.ResultJSON(t.status()), nil
+}
+
+func (t *RAGTool) status() ragStatus {
+	t.statusMu.Lock()
+	defer t.statusMu.Unlock()
+
+	status := ragStatus{Name: t.toolName, State: ragStateNotStarted}
+	for _, name := range slices.Sorted(maps.Keys(t.strategies)) {
+		strategy := *t.strategies[name]
+		status.Strategies = append(status.Strategies, strategy)
+		if strategy.State == ragStateIndexing {
+			status.State = ragStateIndexing
+		}
+	}
+
+	switch {
+	case t.initErr != nil:
+		status.State = ragStateFailed
+		status.Error = t.initErr.Error()
+	case status.State == ragStateIndexing:
+		// Re-indexing changed files.
+	case t.initialized:
+		status.State = ragStateReady
+	case t.started:
+		status.State = ragStateIndexing
+	}
+	return status
 }
 
 func (t *RAGTool) handleQueryRAG(ctx context.Context, args queryRAGArgs) (*tools.ToolCallResult, error) {
@@ -165,9 +332,25 @@ func (t *RAGTool) handleQueryRAG(ctx context.Context, args queryRAGArgs) (*tools
 		return cmp.Compare(b.Similarity, a.Similarity)
 	})
 
-	const maxResults = 10
-	if len(out) > maxResults {
-		out = out[:maxResults]
+	const defaultResults, maxResults = 10, 50
+	limit := defaultResults
+	if args.TopK > 0 {
+		limit = min(args.TopK, maxResults)
+	}
+	if len(out) > limit {
+		out = out[:limit]
+	}
+
+	sources := make(map[string]string)
+	for i := range out {
+		source, ok := sources[out[i].SourcePath]
+		if !ok {
+			if data, err := os.ReadFile(out[i].SourcePath); err == nil {
+				source = string(data)
+			}
+			sources[out[i].SourcePath] = source
+		}
+		out[i].StartLine, out[i].EndLine = chunkLines(source, out[i].Content)
 	}
 
 	resultJSON, err := json.Marshal(out)
@@ -175,5 +358,34 @@ func (t *RAGTool) handleQueryRAG(ctx context.Context, args queryRAGArgs) (*tools
 		return nil, fmt.Errorf("failed to marshal results: %w", err)
 	}
 
-	return tools.ResultSuccess(string(resultJSON)), nil
+	meta := RAGQueryMeta{Sources: make([]chat.Annotation, 0, len(out))}
+	for _, r := range out {
+		meta.Sources = append(meta.Sources, chat.Annotation{
+			RAGName:   t.toolName,
+			Path:      r.SourcePath,
+			StartLine: r.StartLine,
+			EndLine:   r.EndLine,
+			Score:     r.Similarity,
+		})
+	}
+
+	return &tools.ToolCallResult{
+		Output: string(resultJSON),
+		Meta:   meta,
+	}, nil
+}
+
+// chunkLines returns the 1-based lines of source spanned by chunk, or zeros
+// when the chunk isn't found verbatim, e.g. because it was preprocessed.
+func chunkLines(source, chunk string) (start, end int) {
+	chunk = strings.TrimRight(chunk, "\n")
+	if chunk == "" {
+		return 0, 0
+	}
+	i := strings.Index(source, chunk)
+	if i < 0 {
+		return 0, 0
+	}
+	start = strings.Count(source[:i], "\n") + 1
+	return start, start + strings.Count(chunk, "\n")
 }
@@ -2,11 +2,14 @@ package builtin
 
 import (
 	"cmp"
+	"errors"
 	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	ragtypes "github.com/docker/docker-agent/pkg/rag/types"
 )
 
 func TestRAGTool_ToolName(t *testing.T) {
@@ -36,9 +39,10 @@ func TestRAGTool_ToolName(t *testing.T) {
 
 			tools, err := tool.Tools(t.Context())
 			require.NoError(t, err)
-			require.Len(t, tools, 1)
+			require.Len(t, tools, 2)
 			assert.Equal(t, tt.expectedName, tools[0].Name)
 			assert.Equal(t, "knowledge", tools[0].Category)
+			assert.Equal(t, tt.expectedName+"_status", tools[1].Name)
 		})
 	}
 }
@@ -51,7 +55,7 @@ func TestRAGTool_DefaultDescription(t *testing.T) {
 
 	tools, err := tool.Tools(t.Context())
 	require.NoError(t, err)
-	require.Len(t, tools, 1)
+	require.Len(t, tools, 2)
 	assert.Contains(t, tools[0].Description, "test_docs")
 }
 
@@ -72,3 +76,50 @@ func TestRAGTool_SortResults(t *testing.T) {
 	assert.Equal(t, "a.txt", results[2].SourcePath)
 	assert.Equal(t, "c.txt", results[3].SourcePath)
 }
+
+func TestRAGTool_Status(t *testing.T) {
+	tool := &RAGTool{toolName: "docs"}
+	assert.Equal(t, ragStatus{Name: "docs", State: ragStateNotStarted}, tool.status())
+
+	tool.started = true
+	tool.recordEvent(ragtypes.Event{Type: ragtypes.EventTypeIndexingStarted, StrategyName: "bm25"})
+	tool.recordEvent(ragtypes.Event{Type: ragtypes.EventTypeIndexingProgress, StrategyName: "bm25", Progress: &ragtypes.Progress{Current: 3, Total: 10}})
+	tool.recordEvent(ragtypes.Event{Type: ragtypes.EventTypeIndexingComplete, StrategyName: "embeddings", Cache: &ragtypes.CacheStats{Hits: 3, Misses: 1}})
+	tool.recordEvent(ragtypes.Event{Type: ragtypes.EventTypeUsage, StrategyName: "embeddings", TotalTokens: 100, Cost: 0.01})
+	tool.recordEvent(ragtypes.Event{Type: ragtypes.EventTypeUsage, StrategyName: "embeddings", TotalTokens: 150, Cost: 0.015})
+
+	status := tool.status()
+	assert.Equal(t, ragStateIndexing, status.State)
+	assert.Equal(t, []ragStrategyStatus{
+		{Name: "bm25", State: ragStateIndexing, IndexedFiles: 3, TotalFiles: 10},
+		{Name: "embeddings", State: ragStateReady, EmbeddingTokens: 150, Cost: 0.015, CacheHitRate: 0.75},
+	}, status.Strategies)
+
+	tool.recordEvent(ragtypes.Event{Type: ragtypes.EventTypeIndexingComplete, StrategyName: "bm25"})
+	tool.initialized = true
+	assert.Equal(t, ragStateReady, tool.status().State)
+
+	tool.recordEvent(ragtypes.Event{Type: ragtypes.EventTypeError, StrategyName: "bm25", Error: errors.New("watcher failed")})
+	assert.Equal(t, "watcher failed", tool.status().Strategies[0].Error)
+
+	tool.initErr = errors.New("no such model")
+	status = tool.status()
+	assert.Equal(t, ragStateFailed, status.State)
+	assert.Equal(t, "no such model", status.Error)
+}
+
+func TestChunkLines(t *testing.T) {
+	source := "package main\n\nfunc main() {\n\tprintln(1)\n}\n"
+
+	start, end := chunkLines(source, "func main() {\n\tprintln(1)\n}\n")
+	assert.Equal(t, 3, start)
+	assert.Equal(t, 5, end)
+
+	start, end = chunkLines(source, "package main")
+	assert.Equal(t, 1, start)
+	assert.Equal(t, 1, end)
+
+	start, end = chunkLines(source, "not in the source")
+	assert.Zero(t, start)
+	assert.Zero(t, end)
+}
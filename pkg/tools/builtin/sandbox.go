@@ -0,0 +1,456 @@
+package builtin
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os/exec"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+const (
+	ToolNameRunPython = "run_python"
+	ToolNameRunScript = "run_script"
+)
+
+const (
+	sandboxWorkDir = "/workspace"
+	// sandboxLabel marks the containers started by the sandbox tool.
+	sandboxLabel = "com.docker.agent.sandbox=true"
+	// maxSandboxStream is the size of stdout and stderr kept for the model.
+	maxSandboxStream = 64 * 1024
+	// maxSandboxFileSize is the size of the largest output file returned.
+	maxSandboxFileSize = 1024 * 1024
+	// maxSandboxFiles is the number of output files returned.
+	maxSandboxFiles = 20
+)
+
+// sandboxLanguage tells how to run the code of a language.
+type sandboxLanguage struct {
+	file string
+	cmd  []string
+}
+
+var sandboxLanguages = map[string]sandboxLanguage{
+	"python":     {file: "main.py", cmd: []string{"python3"}},
+	"javascript": {file: "main.js", cmd: []string{"node"}},
+	"bash":       {file: "main.sh", cmd: []string{"bash"}},
+	"sh":         {file: "main.sh", cmd: []string{"sh"}},
+}
+
+// SandboxTool runs code in a Docker container, with no network access by
+// default, a tmpfs working directory and CPU and memory limits. The container
+// is started on the first call, reused by the following ones and removed
+// when the toolset stops.
+type SandboxTool struct {
+	image   string
+	network bool
+	mounts  []sandboxMount
+	cpus    string
+	memory  string
+	timeout time.Duration
+
+	mu          sync.Mutex
+	containerID string
+	runs        int
+}
+
+// Verify interface compliance
+var (
+	_ tools.ToolSet      = (*SandboxTool)(nil)
+	_ tools.Startable    = (*SandboxTool)(nil)
+	_ tools.Instructable = (*SandboxTool)(nil)
+)
+
+type sandboxMount struct {
+	source string
+	target string
+}
+
+type RunPythonArgs struct {
+	Code string `json:"code" jsonschema:"The Python code to run"`
+}
+
+type RunScriptArgs struct {
+	Language string `json:"language" jsonschema:"The language of the code: python, javascript, bash or sh"`
+	Code     string `json:"code" jsonschema:"The code to run"`
+}
+
+// SandboxToolOption configures a SandboxTool.
+type SandboxToolOption func(*SandboxTool)
+
+// WithSandboxNetwork gives the container access to the network.
+func WithSandboxNetwork() SandboxToolOption {
+	return func(t *SandboxTool) {
+		t.network = true
+	}
+}
+
+// WithSandboxMount mounts the host directory source read-only at target in
+// the container.
+func WithSandboxMount(source, target string) SandboxToolOption {
+	return func(t *SandboxTool) {
+		t.mounts = append(t.mounts, sandboxMount{source: source, target: target})
+	}
+}
+
+// WithSandboxResources limits the CPUs and the memory of the container, in
+// the format of docker run's --cpus and --memory flags, e.g. "1.5" and "1g".
+func WithSandboxResources(cpus, memory string) SandboxToolOption {
+	return func(t *SandboxTool) {
+		t.cpus = cpus
+		t.memory = memory
+	}
+}
+
+// WithSandboxTimeout sets how long the code may run.
+func WithSandboxTimeout(timeout time.Duration) SandboxToolOption {
+	return func(t *SandboxTool) {
+		t.timeout = timeout
+	}
+}
+
+// NewSandboxTool returns a toolset running code in containers of image. The
+// image must provide the interpreters of the languages used, and tar.
+func NewSandboxTool(image string, opts ...SandboxToolOption) *SandboxTool {
+	t := &SandboxTool{
+		image:   image,
+		cpus:    "1",
+		memory:  "512m",
+		timeout: 60 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+func (t *SandboxTool) Instructions() string {
+	return fmt.Sprintf(`## Code Sandbox
+
+Run code in an isolated container with run_python or run_script.
+- Each call runs in a fresh directory; files written elsewhere in %s may be seen by later calls
+- Files written to the directory in the OUTPUT_DIR environment variable are returned, images included
+- Execution stops after %v
+- %s`, sandboxWorkDir, t.timeout, t.networkInstruction())
+}
+
+func (t *SandboxTool) networkInstruction() string {
+	if t.network {
+		return "The network is available"
+	}
+	return "There is no network access: packages can't be installed"
+}
+
+func (t *SandboxTool) Tools(context.Context) ([]tools.Tool, error) {
+	return []tools.Tool{
+		{
+			Name:         ToolNameRunPython,
+			Category:     "sandbox",
+			Description:  "Runs Python code in an isolated container and returns its exit code, stdout, stderr and the files written to $OUTPUT_DIR.",
+			Parameters:   tools.MustSchemaFor[RunPythonArgs](),
+			OutputSchema: tools.MustSchemaFor[string](),
+			Handler: tools.NewHandler(func(ctx context.Context, args RunPythonArgs) (*tools.ToolCallResult, error) {
+				return t.run(ctx, "python", args.Code)
+			}),
+			Annotations: tools.ToolAnnotations{Title: "Run Python"},
+			// The sandbox enforces its own timeout.
+			Timeout: tools.NoTimeout,
+		},
+		{
+			Name:         ToolNameRunScript,
+			Category:     "sandbox",
+			Description:  "Runs a script in an isolated container and returns its exit code, stdout, stderr and the files written to $OUTPUT_DIR.",
+			Parameters:   tools.MustSchemaFor[RunScriptArgs](),
+			OutputSchema: tools.MustSchemaFor[string](),
+			Handler: tools.NewHandler(func(ctx context.Context, args RunScriptArgs) (*tools.ToolCallResult, error) {
+				return t.run(ctx, args.Language, args.Code)
+			}),
+			Annotations: tools.ToolAnnotations{Title: "Run Script"},
+			// The sandbox enforces its own timeout.
+			Timeout: tools.NoTimeout,
+		},
+	}, nil
+}
+
+// Start checks that the Docker CLI is available. The container itself is
+// started on the first call.
+func (t *SandboxTool) Start(context.Context) error {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return fmt.Errorf("sandbox requires the docker CLI: %w", err)
+	}
+	return nil
+}
+
+// Stop removes the container, if any.
+func (t *SandboxTool) Stop(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.removeContainer(ctx)
+}
+
+func (t *SandboxTool) run(ctx context.Context, language, code string) (*tools.ToolCallResult, error) {
+	lang, ok := sandboxLanguages[language]
+	if !ok {
+		return tools.ResultError(fmt.Sprintf("Unsupported language %q, use one of: %s", language, strings.Join(sandboxLanguageNames(), ", "))), nil
+	}
+
+	containerID, dir, err := t.prepareRun(ctx)
+	if err != nil {
+		return tools.ResultError(fmt.Sprintf("Error starting the sandbox: %s", err)), nil
+	}
+
+	write := exec.CommandContext(ctx, "docker", "exec", "-i", containerID, "sh", "-c", `mkdir -p "$1/output" && cat > "$1/$2"`, "sh", dir, lang.file)
+	write.Stdin = strings.NewReader(code)
+	if out, err := write.CombinedOutput(); err != nil {
+		return tools.ResultError(fmt.Sprintf("Error copying the code to the sandbox: %s\n%s", err, out)), nil
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	args := []string{"exec", "-w", dir, "-e", "OUTPUT_DIR=" + dir + "/output", containerID}
+	args = append(args, lang.cmd...)
+	args = append(args, lang.file)
+	cmd := exec.CommandContext(timeoutCtx, "docker", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+
+	if timeoutCtx.Err() != nil {
+		// The code may still be running in the container: start over with a
+		// new one.
+		t.resetContainer(containerID)
+		if ctx.Err() != nil {
+			return tools.ResultError("Execution cancelled"), nil
+		}
+		return tools.ResultError(fmt.Sprintf("Execution timed out after %v, the sandbox was reset\nstdout:\n%s\nstderr:\n%s",
+			t.timeout, truncateSandboxStream(stdout.String()), truncateSandboxStream(stderr.String()))), nil
+	}
+
+	exitCode := 0
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return tools.ResultError(fmt.Sprintf("Error running the code: %s", err)), nil
+		}
+		exitCode = exitErr.ExitCode()
+	}
+
+	var files []sandboxFile
+	archive, err := exec.CommandContext(ctx, "docker", "exec", containerID, "tar", "-C", dir+"/output", "-cf", "-", ".").Output()
+	if err == nil {
+		files, err = readSandboxFiles(bytes.NewReader(archive))
+	}
+	if err != nil {
+		slog.Warn("Failed to read the output files of the sandbox", "error", err)
+	}
+
+	return sandboxResult(exitCode, stdout.String(), stderr.String(), files), nil
+}
+
+// prepareRun starts the container if needed and returns it, with the
+// directory of a new run.
+func (t *SandboxTool) prepareRun(ctx context.Context) (containerID, dir string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.containerID == "" {
+		out, err := exec.CommandContext(ctx, "docker", t.containerArgs()...).Output()
+		if err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				return "", "", fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+			}
+			return "", "", err
+		}
+		t.containerID = strings.TrimSpace(string(out))
+		slog.Debug("Started sandbox container", "image", t.image, "container", t.containerID)
+	}
+
+	t.runs++
+	return t.containerID, fmt.Sprintf("%s/run-%d", sandboxWorkDir, t.runs), nil
+}
+
+// containerArgs returns the docker arguments starting the container.
+func (t *SandboxTool) containerArgs() []string {
+	args := []string{
+		"run", "-d", "--rm",
+		"--label", sandboxLabel,
+		"--tmpfs", sandboxWorkDir + ":rw,exec",
+		"--workdir", sandboxWorkDir,
+		"--cap-drop", "ALL",
+		"--security-opt", "no-new-privileges",
+		"--pids-limit", "256",
+	}
+	if !t.network {
+		args = append(args, "--network", "none")
+	}
+	if t.cpus != "" {
+		args = append(args, "--cpus", t.cpus)
+	}
+	if t.memory != "" {
+		args = append(args, "--memory", t.memory)
+	}
+	for _, m := range t.mounts {
+		args = append(args, "--mount", fmt.Sprintf("type=bind,source=%s,target=%s,readonly", m.source, m.target))
+	}
+	// Keep the container alive between calls.
+	return append(args, "--entrypoint", "sleep", t.image, "infinity")
+}
+
+// resetContainer removes the container, unless another call already did.
+func (t *SandboxTool) resetContainer(containerID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.containerID == containerID {
+		_ = t.removeContainer(context.Background())
+	}
+}
+
+func (t *SandboxTool) removeContainer(ctx context.Context) error {
+	if t.containerID == "" {
+		return nil
+	}
+
+	containerID := t.containerID
+	t.containerID = ""
+	if out, err := exec.CommandContext(ctx, "docker", "rm", "-f", containerID).CombinedOutput(); err != nil {
+		return fmt.Errorf("removing sandbox container %s: %w: %s", containerID, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func sandboxLanguageNames() []string {
+	names := make([]string, 0, len(sandboxLanguages))
+	for name := range sandboxLanguages {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// sandboxFile is a file the code wrote to its output directory.
+type sandboxFile struct {
+	name     string
+	size     int64
+	mimeType string
+	// data is nil when the file is too large to be returned.
+	data []byte
+}
+
+// readSandboxFiles reads the regular files of a tar archive of the output
+// directory.
+func readSandboxFiles(r io.Reader) ([]sandboxFile, error) {
+	var files []sandboxFile
+	tr := tar.NewReader(r)
+	for len(files) < maxSandboxFiles {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return files, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		file := sandboxFile{
+			name: strings.TrimPrefix(path.Clean(hdr.Name), "./"),
+			size: hdr.Size,
+		}
+		if hdr.Size <= maxSandboxFileSize {
+			if file.data, err = io.ReadAll(tr); err != nil {
+				return files, err
+			}
+			file.mimeType = sandboxMimeType(file.name, file.data)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+func sandboxMimeType(name string, data []byte) string {
+	mimeType := http.DetectContentType(data)
+	if mimeType == "application/octet-stream" || strings.HasPrefix(mimeType, "text/plain") {
+		if byExt := mime.TypeByExtension(path.Ext(name)); byExt != "" {
+			mimeType = byExt
+		}
+	}
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	return mimeType
+}
+
+// sandboxResult renders the outcome of a run: images are returned as images
+// and text files as resources, whose content is also appended to the output.
+func sandboxResult(exitCode int, stdout, stderr string, files []sandboxFile) *tools.ToolCallResult {
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "Exit code: %d\n", exitCode)
+	if stdout != "" {
+		fmt.Fprintf(&summary, "\nstdout:\n%s\n", truncateSandboxStream(stdout))
+	}
+	if stderr != "" {
+		fmt.Fprintf(&summary, "\nstderr:\n%s\n", truncateSandboxStream(stderr))
+	}
+	if len(files) > 0 {
+		summary.WriteString("\nOutput files:\n")
+	}
+
+	var fileContents strings.Builder
+	var images []tools.MediaContent
+	var blocks []tools.ContentBlock
+	for _, f := range files {
+		switch {
+		case f.data == nil:
+			fmt.Fprintf(&summary, "- %s (%d bytes, too large to be returned)\n", f.name, f.size)
+		case chat.IsImageMimeType(f.mimeType):
+			fmt.Fprintf(&summary, "- %s (%s, %d bytes)\n", f.name, f.mimeType, f.size)
+			image := tools.MediaContent{Data: base64.StdEncoding.EncodeToString(f.data), MimeType: f.mimeType}
+			images = append(images, image)
+			blocks = append(blocks, tools.ContentBlock{Type: tools.ContentBlockTypeImage, Image: &image})
+		case utf8.Valid(f.data):
+			fmt.Fprintf(&summary, "- %s (%s, %d bytes)\n", f.name, f.mimeType, f.size)
+			fmt.Fprintf(&fileContents, "\n--- %s ---\n%s\n", f.name, f.data)
+			blocks = append(blocks, tools.ContentBlock{
+				Type:     tools.ContentBlockTypeResource,
+				Resource: &tools.ResourceContent{URI: "sandbox:///output/" + f.name, MimeType: f.mimeType, Text: string(f.data)},
+			})
+		default:
+			fmt.Fprintf(&summary, "- %s (%s, %d bytes, binary)\n", f.name, f.mimeType, f.size)
+		}
+	}
+
+	text := strings.TrimRight(summary.String(), "\n")
+	return &tools.ToolCallResult{
+		Output:   strings.TrimRight(summary.String()+fileContents.String(), "\n"),
+		IsError:  exitCode != 0,
+		Images:   images,
+		Contents: append([]tools.ContentBlock{{Type: tools.ContentBlockTypeText, Text: text}}, blocks...),
+	}
+}
+
+func truncateSandboxStream(s string) string {
+	if len(s) <= maxSandboxStream {
+		return s
+	}
+	return s[:maxSandboxStream] + fmt.Sprintf("\n[truncated %d bytes]", len(s)-maxSandboxStream)
+}
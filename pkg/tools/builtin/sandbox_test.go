@@ -0,0 +1,144 @@
+package builtin
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+func TestSandboxTool_ContainerArgs(t *testing.T) {
+	t.Parallel()
+
+	args := NewSandboxTool("python:3-alpine").containerArgs()
+	assert.Contains(t, args, "none")
+	assert.Equal(t, []string{"--entrypoint", "sleep", "python:3-alpine", "infinity"}, args[len(args)-4:])
+	assert.Subset(t, args, []string{"--tmpfs", sandboxWorkDir + ":rw,exec", "--cpus", "1", "--memory", "512m"})
+
+	args = NewSandboxTool("python:3-alpine",
+		WithSandboxNetwork(),
+		WithSandboxResources("2", "1g"),
+		WithSandboxMount("/home/user/data", "/data"),
+	).containerArgs()
+	assert.NotContains(t, args, "--network")
+	assert.Subset(t, args, []string{"--cpus", "2", "--memory", "1g", "type=bind,source=/home/user/data,target=/data,readonly"})
+}
+
+func TestSandboxTool_Tools(t *testing.T) {
+	t.Parallel()
+
+	toolset := NewSandboxTool("python:3-alpine", WithSandboxTimeout(5*time.Second))
+	allTools, err := toolset.Tools(t.Context())
+	require.NoError(t, err)
+	require.Len(t, allTools, 2)
+	assert.Equal(t, ToolNameRunPython, allTools[0].Name)
+	assert.Equal(t, ToolNameRunScript, allTools[1].Name)
+
+	assert.Contains(t, toolset.Instructions(), "5s")
+	assert.Contains(t, toolset.Instructions(), "no network access")
+}
+
+func TestSandboxTool_UnsupportedLanguage(t *testing.T) {
+	t.Parallel()
+
+	// The language is checked before any container is started.
+	result, err := NewSandboxTool("python:3-alpine").run(t.Context(), "cobol", "DISPLAY 'HELLO'.")
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.Output, "bash, javascript, python, sh")
+}
+
+func TestSandboxResult(t *testing.T) {
+	t.Parallel()
+
+	png := []byte("\x89PNG\r\n\x1a\n0000")
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{
+		{"./plot.png", png},
+		{"./result.csv", []byte("a,b\n1,2\n")},
+		{"./blob.bin", []byte{0x00, 0xff, 0x80}},
+	} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0o644, Size: int64(len(f.data)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write(f.data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "./big.txt", Mode: 0o644, Size: maxSandboxFileSize + 1, Typeflag: tar.TypeReg}))
+	_, err := tw.Write(bytes.Repeat([]byte("x"), maxSandboxFileSize+1))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+
+	files, err := readSandboxFiles(&archive)
+	require.NoError(t, err)
+	require.Len(t, files, 4)
+
+	result := sandboxResult(1, "hello\n", "warning\n", files)
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.Output, "Exit code: 1")
+	assert.Contains(t, result.Output, "stdout:\nhello")
+	assert.Contains(t, result.Output, "stderr:\nwarning")
+	assert.Contains(t, result.Output, "- plot.png (image/png, 12 bytes)")
+	assert.Contains(t, result.Output, "--- result.csv ---\na,b\n1,2")
+	assert.Contains(t, result.Output, "- blob.bin (application/octet-stream, 3 bytes, binary)")
+	assert.Contains(t, result.Output, "- big.txt (1048577 bytes, too large to be returned)")
+
+	require.Len(t, result.Images, 1)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(png), result.Images[0].Data)
+
+	require.Len(t, result.Contents, 3)
+	assert.Equal(t, tools.ContentBlockTypeText, result.Contents[0].Type)
+	assert.NotContains(t, result.Contents[0].Text, "a,b")
+	assert.Equal(t, tools.ContentBlockTypeImage, result.Contents[1].Type)
+	assert.Equal(t, tools.ContentBlockTypeResource, result.Contents[2].Type)
+	assert.Equal(t, "a,b\n1,2\n", result.Contents[2].Resource.Text)
+}
+
+// TestSandboxTool_Docker runs code in a real container. It needs Docker and
+// is only run when DOCKER_AGENT_TEST_SANDBOX_IMAGE names an image with Python,
+// e.g. python:3-alpine.
+func TestSandboxTool_Docker(t *testing.T) {
+	image := os.Getenv("DOCKER_AGENT_TEST_SANDBOX_IMAGE")
+	if image == "" {
+		t.Skip("DOCKER_AGENT_TEST_SANDBOX_IMAGE is not set")
+	}
+
+	toolset := NewSandboxTool(image, WithSandboxTimeout(10*time.Second))
+	require.NoError(t, toolset.Start(t.Context()))
+	t.Cleanup(func() {
+		assert.NoError(t, toolset.Stop(context.Background()))
+	})
+
+	result, err := toolset.run(t.Context(), "python", `
+import os
+print("hello")
+with open(os.path.join(os.environ["OUTPUT_DIR"], "out.txt"), "w") as f:
+    f.write("written")
+`)
+	require.NoError(t, err)
+	assert.False(t, result.IsError, result.Output)
+	assert.Contains(t, result.Output, "stdout:\nhello")
+	assert.Contains(t, result.Output, "--- out.txt ---\nwritten")
+
+	result, err = toolset.run(t.Context(), "sh", "echo oops >&2; exit 3")
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.Output, "Exit code: 3")
+
+	result, err = toolset.run(t.Context(), "python", `
+import urllib.request
+urllib.request.urlopen("https://www.docker.com", timeout=2)
+`)
+	require.NoError(t, err)
+	assert.True(t, result.IsError, "the network should not be reachable")
+}
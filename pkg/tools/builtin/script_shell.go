@@ -141,6 +141,7 @@ func (t *ScriptShellTool) execute(ctx context.Context, toolConfig *latest.Script
 	shell, argsPrefix := shellpath.DetectShell()
 
 	cmd := exec.CommandContext(ctx, shell, append(argsPrefix, toolConfig.Cmd)...)
+	shellpath.SetCommandLine(cmd, argsPrefix, toolConfig.Cmd)
 	cmd.Dir = toolConfig.WorkingDir
 	cmd.Env = t.env
 	for key, value := range params {
@@ -0,0 +1,84 @@
+package builtin
+
+import (
+	"context"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+const (
+	ToolNameContextSet  = "context_set"
+	ToolNameContextGet  = "context_get"
+	ToolNameContextList = "context_list"
+)
+
+// SharedContextTool gives agents access to the shared context of their team.
+// The tools are handled by the runtime, which owns the team.
+type SharedContextTool struct{}
+
+// Verify interface compliance
+var (
+	_ tools.ToolSet      = (*SharedContextTool)(nil)
+	_ tools.Instructable = (*SharedContextTool)(nil)
+)
+
+type ContextSetArgs struct {
+	Key        string `json:"key" jsonschema:"The key to write."`
+	Value      string `json:"value" jsonschema:"The value to store. Overwrites the current value of the key."`
+	TTLSeconds int    `json:"ttl_seconds,omitempty" jsonschema:"Number of seconds after which the value expires (optional, never expires by default)."`
+}
+
+type ContextGetArgs struct {
+	Key string `json:"key" jsonschema:"The key to read."`
+}
+
+func NewSharedContextTool() *SharedContextTool {
+	return &SharedContextTool{}
+}
+
+func (t *SharedContextTool) Instructions() string {
+	return `## Shared Context
+
+The agents of your team share a key-value context that survives task transfers:
+- Use context_set to record findings, decisions and intermediate results other agents need
+- Use context_get or context_list to read what other agents wrote before redoing their work
+- Keep values short and factual, and use descriptive keys`
+}
+
+func (t *SharedContextTool) Tools(context.Context) ([]tools.Tool, error) {
+	return []tools.Tool{
+		{
+			Name:        ToolNameContextSet,
+			Category:    "shared_context",
+			Description: "Write a value to the context shared by all the agents of the team.",
+			Parameters:  tools.MustSchemaFor[ContextSetArgs](),
+			Annotations: tools.ToolAnnotations{
+				// Only the in-memory state of the team changes.
+				ReadOnlyHint: true,
+				Title:        "Set Shared Context",
+			},
+			NoResultCache: true,
+		},
+		{
+			Name:        ToolNameContextGet,
+			Category:    "shared_context",
+			Description: "Read a value from the context shared by all the agents of the team.",
+			Parameters:  tools.MustSchemaFor[ContextGetArgs](),
+			Annotations: tools.ToolAnnotations{
+				ReadOnlyHint: true,
+				Title:        "Get Shared Context",
+			},
+			NoResultCache: true,
+		},
+		{
+			Name:        ToolNameContextList,
+			Category:    "shared_context",
+			Description: "List the keys and values of the context shared by all the agents of the team.",
+			Annotations: tools.ToolAnnotations{
+				ReadOnlyHint: true,
+				Title:        "List Shared Context",
+			},
+			NoResultCache: true,
+		},
+	}, nil
+}
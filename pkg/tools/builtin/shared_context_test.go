@@ -0,0 +1,36 @@
+package builtin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+func TestSharedContextTool_Tools(t *testing.T) {
+	tool := NewSharedContextTool()
+
+	allTools, err := tool.Tools(t.Context())
+	require.NoError(t, err)
+	require.Len(t, allTools, 3)
+
+	assert.Equal(t, ToolNameContextSet, allTools[0].Name)
+	assert.Equal(t, ToolNameContextGet, allTools[1].Name)
+	assert.Equal(t, ToolNameContextList, allTools[2].Name)
+
+	// The runtime handles the calls.
+	for _, tool := range allTools {
+		assert.Equal(t, "shared_context", tool.Category)
+		assert.Nil(t, tool.Handler)
+	}
+}
+
+func TestSharedContextTool_Instructions(t *testing.T) {
+	tool := NewSharedContextTool()
+
+	instructions := tools.GetInstructions(tool)
+	assert.Contains(t, instructions, "context_set")
+	assert.Contains(t, instructions, "context_get")
+}
@@ -10,6 +10,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -17,6 +18,8 @@ import (
 
 	"github.com/docker/docker-agent/pkg/concurrent"
 	"github.com/docker/docker-agent/pkg/config"
+	"github.com/docker/docker-agent/pkg/environment"
+	"github.com/docker/docker-agent/pkg/procgroup"
 	"github.com/docker/docker-agent/pkg/shellpath"
 	"github.com/docker/docker-agent/pkg/tools"
 )
@@ -49,6 +52,18 @@ type shellHandler struct {
 	workingDir      string
 	jobs            *concurrent.Map[string, *backgroundJob]
 	jobCounter      atomic.Int64
+
+	// persistent runs the commands in a shell session that persists across
+	// calls, see WithPersistentSession.
+	persistent bool
+	// runMu serializes the commands of the session, sessionMu protects
+	// session and sessionDir, so that Stop doesn't wait for a command.
+	runMu     sync.Mutex
+	sessionMu sync.Mutex
+	session   *shellSession
+	// sessionDir is the working directory of the session, kept when the
+	// shell is restarted.
+	sessionDir string
 }
 
 // Job status constants
@@ -72,6 +87,8 @@ type backgroundJob struct {
 	status       atomic.Int32
 	exitCode     int
 	err          error
+	// done is closed once the process has exited.
+	done chan struct{}
 }
 
 // limitedWriter wraps a buffer and stops writing after maxSize bytes.
@@ -143,6 +160,11 @@ func (h *shellHandler) RunShell(ctx context.Context, params RunShellArgs) (*tool
 	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
+	if h.persistent {
+		slog.Debug("Executing shell command in the shell session", "command", params.Cmd, "cwd", params.Cwd)
+		return h.runInSession(timeoutCtx, ctx, params.Cmd, params.Cwd, timeout), nil
+	}
+
 	cwd := h.resolveWorkDir(params.Cwd)
 
 	slog.Debug("Executing native shell command", "command", params.Cmd, "cwd", cwd)
@@ -170,6 +192,7 @@ func (h *shellHandler) runNativeCommand(timeoutCtx, ctx context.Context, command
 	cmd.Env = h.env
 	cmd.Dir = cwd
 	cmd.SysProcAttr = platformSpecificSysProcAttr()
+	shellpath.SetCommandLine(cmd, h.shellArgsPrefix, command)
 	cmd.WaitDelay = waitDelayAfterShellExit
 
 	var outBuf bytes.Buffer
@@ -196,11 +219,12 @@ func (h *shellHandler) runNativeCommand(timeoutCtx, ctx context.Context, command
 		_ = kill(cmd.Process, pg)
 		// Wait for cmd.Wait() to complete so that the internal pipe-copy
 		// goroutines finish writing to outBuf before we read it.
-		// Use a grace period: if SIGTERM is ignored, escalate to SIGKILL.
+		// Use a grace period: if SIGTERM is ignored, escalate to SIGKILL
+		// for the whole group, not only the shell.
 		select {
 		case <-done:
-		case <-time.After(3 * time.Second):
-			_ = cmd.Process.Kill()
+		case <-time.After(procgroup.GracePeriod):
+			_ = procgroup.Kill(cmd.Process)
 			<-done
 		}
 	case cmdErr = <-done:
@@ -210,6 +234,75 @@ func (h *shellHandler) runNativeCommand(timeoutCtx, ctx context.Context, command
 	return tools.ResultSuccess(limitOutput(output))
 }
 
+// runInSession runs command in the shell session, starting it if needed,
+// and reports the working directory of the shell after it. The shell is
+// restarted, in the same working directory, when it exits or when a
+// command times out: the variables set by the previous commands are lost
+// then, which the result tells.
+func (h *shellHandler) runInSession(timeoutCtx, ctx context.Context, command, cwd string, timeout time.Duration) *tools.ToolCallResult {
+	h.runMu.Lock()
+	defer h.runMu.Unlock()
+
+	session, warning, err := h.ensureSession()
+	if err != nil {
+		return tools.ResultError(fmt.Sprintf("Error starting shell session: %s", err))
+	}
+
+	if cwd == "." {
+		cwd = ""
+	}
+	output, status, dir, err := session.run(timeoutCtx, command, cwd)
+
+	h.sessionMu.Lock()
+	defer h.sessionMu.Unlock()
+
+	var text string
+	switch {
+	case errors.Is(err, errShellExited):
+		h.session = nil
+		text = fmt.Sprintf("%s\n\nThe shell session exited: the variables and the shell state set by the previous commands are lost, the next command runs in a new shell.",
+			cmp.Or(strings.TrimSpace(output), "<no output>"))
+	case err != nil:
+		// The command is still running: the shell can't run the next ones.
+		_ = session.stop(context.WithoutCancel(ctx))
+		h.session = nil
+		text = formatCommandOutput(timeoutCtx, ctx, nil, output, timeout) +
+			"\n\nThe shell session was restarted: the variables and the shell state set by the previous commands are lost."
+	default:
+		h.sessionDir = dir
+		var cmdErr error
+		if status != 0 {
+			cmdErr = fmt.Errorf("exit status %d", status)
+		}
+		text = formatCommandOutput(timeoutCtx, ctx, cmdErr, output, timeout)
+	}
+
+	return tools.ResultSuccess(limitOutput(warning+text) + "\n\ncwd: " + h.sessionDir)
+}
+
+// ensureSession returns the shell session, started if needed, with a
+// warning for the result if the previous one exited unexpectedly.
+func (h *shellHandler) ensureSession() (*shellSession, string, error) {
+	h.sessionMu.Lock()
+	defer h.sessionMu.Unlock()
+
+	var warning string
+	if h.session != nil && h.session.hasExited() {
+		slog.Warn("The shell session exited, starting a new one", "cwd", h.sessionDir)
+		warning = "Warning: the shell session exited, a new one was started: the variables and the shell state set by the previous commands are lost.\n\n"
+		h.session = nil
+	}
+	if h.session == nil {
+		h.sessionDir = cmp.Or(h.sessionDir, h.workingDir)
+		session, err := startShellSession(sessionShell(h.shell), h.env, h.sessionDir)
+		if err != nil {
+			return nil, "", err
+		}
+		h.session = session
+	}
+	return h.session, warning, nil
+}
+
 func (h *shellHandler) RunShellBackground(_ context.Context, params RunShellBackgroundArgs) (*tools.ToolCallResult, error) {
 	counter := h.jobCounter.Add(1)
 	jobID := fmt.Sprintf("job_%d_%d", time.Now().Unix(), counter)
@@ -218,6 +311,7 @@ func (h *shellHandler) RunShellBackground(_ context.Context, params RunShellBack
 	cmd.Env = h.env
 	cmd.Dir = h.resolveWorkDir(params.Cwd)
 	cmd.SysProcAttr = platformSpecificSysProcAttr()
+	shellpath.SetCommandLine(cmd, h.shellArgsPrefix, params.Cmd)
 
 	job := &backgroundJob{
 		id:        jobID,
@@ -225,6 +319,7 @@ func (h *shellHandler) RunShellBackground(_ context.Context, params RunShellBack
 		cwd:       params.Cwd,
 		output:    &bytes.Buffer{},
 		startTime: time.Now(),
+		done:      make(chan struct{}),
 	}
 
 	// The limitedWriter shares the job's outputMu so that readers
@@ -257,6 +352,7 @@ func (h *shellHandler) RunShellBackground(_ context.Context, params RunShellBack
 
 func (h *shellHandler) monitorJob(job *backgroundJob, cmd *exec.Cmd) {
 	err := cmd.Wait()
+	defer close(job.done)
 
 	job.outputMu.Lock()
 	defer job.outputMu.Unlock()
@@ -361,8 +457,37 @@ func (h *shellHandler) StopBackgroundJob(_ context.Context, params StopBackgroun
 	return tools.ResultSuccess(fmt.Sprintf("Job %s stopped successfully", params.JobID)), nil
 }
 
-// NewShellTool creates a new shell tool.
-func NewShellTool(env []string, runConfig *config.RuntimeConfig) *ShellTool {
+// ShellOption configures a ShellTool.
+type ShellOption func(*ShellTool)
+
+// WithShellEnvPassthrough scopes the environment of the commands to the
+// tool's env and the variables of the process environment matching one of
+// the patterns, variable names or globs such as "AWS_*".
+func WithShellEnvPassthrough(patterns ...string) ShellOption {
+	return func(t *ShellTool) {
+		t.handler.env = append(environment.Filter(os.Environ(), patterns), t.handler.env...)
+	}
+}
+
+// WithPersistentSession runs the commands in a single shell that persists
+// across calls, rather than in a new shell each time: the working
+// directory and the variables set by a command are kept for the next ones,
+// and the results tell the working directory. The shell is stopped with
+// the tool. Persistent sessions need a POSIX shell: they aren't supported
+// on Windows, where each command keeps running in a new shell.
+func WithPersistentSession(persistent bool) ShellOption {
+	return func(t *ShellTool) {
+		if persistent && runtime.GOOS == "windows" {
+			slog.Warn("Persistent shell sessions aren't supported on Windows, each command runs in a new shell")
+			return
+		}
+		t.handler.persistent = persistent
+	}
+}
+
+// NewShellTool creates a new shell tool. The commands run with env as their
+// environment, or inherit the process environment if env is nil.
+func NewShellTool(env []string, runConfig *config.RuntimeConfig, opts ...ShellOption) *ShellTool {
 	shell, argsPrefix := detectShell()
 
 	handler := &shellHandler{
@@ -374,7 +499,11 @@ func NewShellTool(env []string, runConfig *config.RuntimeConfig) *ShellTool {
 		workingDir:      runConfig.WorkingDir,
 	}
 
-	return &ShellTool{handler: handler}
+	t := &ShellTool{handler: handler}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
 }
 
 // detectShell returns the appropriate shell and arguments based on the platform.
@@ -414,18 +543,39 @@ func formatCommandOutput(timeoutCtx, ctx context.Context, err error, rawOutput s
 }
 
 func (t *ShellTool) Instructions() string {
+	session := `- Each call runs in a fresh shell session — no state persists between calls
+- Use "cwd" parameter instead of cd within commands`
+	if t.handler.persistent {
+		session = `- All calls run in the same shell session: the working directory (cd) and the exported variables persist between calls
+- Each result ends with the current working directory, e.g. "cwd: /repo/backend"
+- A timed-out command restarts the shell in the same directory, the variables are lost`
+	}
+
 	return `## Shell Tools
 
-- Each call runs in a fresh shell session — no state persists between calls
+` + session + `
 - Default timeout: 30s. Set "timeout" for longer operations (builds, tests)
-- Use "cwd" parameter instead of cd within commands
 - Combine operations with pipes, redirections, and heredocs
 - For git commits, add trailer: git commit -m "message" -m "" -m "Assisted-By: docker-agent"
 - Non-zero exit codes return error info with output; timed-out commands are terminated
 
 ### Background Jobs
 
-Use run_background_job for long-running processes (servers, watchers). Output capped at 10MB per job. All jobs auto-terminate when the agent stops.`
+Use run_background_job for long-running processes (servers, watchers). Output capped at 10MB per job. All jobs auto-terminate when the agent stops.` + windowsShellNote(t.handler.shell)
+}
+
+// windowsShellNote tells the model to use the syntax of the Windows shells,
+// rather than the POSIX shell syntax it assumes by default.
+func windowsShellNote(shell string) string {
+	name := strings.ToLower(shell[strings.LastIndexAny(shell, `\/`)+1:])
+	switch strings.TrimSuffix(name, ".exe") {
+	case "pwsh", "powershell":
+		return "\n\nCommands run in PowerShell: use PowerShell syntax and cmdlets, not POSIX shell syntax."
+	case "cmd":
+		return "\n\nCommands run in cmd.exe: use cmd syntax and commands (dir, type, &&), not POSIX shell syntax."
+	default:
+		return ""
+	}
 }
 
 func (t *ShellTool) Tools(context.Context) ([]tools.Tool, error) {
@@ -439,6 +589,8 @@ func (t *ShellTool) Tools(context.Context) ([]tools.Tool, error) {
 			Handler:                 tools.NewHandler(t.handler.RunShell),
 			Annotations:             tools.ToolAnnotations{Title: "Shell"},
 			AddDescriptionParameter: true,
+			// Commands have their own timeout, set by the model.
+			Timeout: tools.NoTimeout,
 		},
 		{
 			Name:                    ToolNameRunShellBackground,
@@ -457,6 +609,7 @@ func (t *ShellTool) Tools(context.Context) ([]tools.Tool, error) {
 			OutputSchema:            tools.MustSchemaFor[string](),
 			Handler:                 tools.NewHandler(t.handler.ListBackgroundJobs),
 			Annotations:             tools.ToolAnnotations{Title: "List Background Jobs", ReadOnlyHint: true},
+			NoResultCache:           true,
 			AddDescriptionParameter: true,
 		},
 		{
@@ -467,6 +620,7 @@ func (t *ShellTool) Tools(context.Context) ([]tools.Tool, error) {
 			OutputSchema:            tools.MustSchemaFor[string](),
 			Handler:                 tools.NewHandler(t.handler.ViewBackgroundJob),
 			Annotations:             tools.ToolAnnotations{Title: "View Background Job Output", ReadOnlyHint: true},
+			NoResultCache:           true,
 			AddDescriptionParameter: true,
 		},
 		{
@@ -486,14 +640,35 @@ func (t *ShellTool) Start(context.Context) error {
 	return nil
 }
 
-func (t *ShellTool) Stop(context.Context) error {
-	// Terminate all running background jobs
+// Stop terminates the shell session and the running background jobs, with
+// the processes they spawned, and waits for them to exit until ctx is done.
+func (t *ShellTool) Stop(ctx context.Context) error {
+	t.handler.sessionMu.Lock()
+	session := t.handler.session
+	t.handler.session = nil
+	t.handler.sessionMu.Unlock()
+	if session != nil {
+		if err := session.stop(ctx); err != nil {
+			return err
+		}
+	}
+
+	var stopped []*backgroundJob
 	t.handler.jobs.Range(func(_ string, job *backgroundJob) bool {
 		if job.status.CompareAndSwap(statusRunning, statusStopped) {
 			_ = kill(job.process, job.processGroup)
+			stopped = append(stopped, job)
 		}
 		return true
 	})
 
+	for _, job := range stopped {
+		select {
+		case <-job.done:
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for the background jobs to exit: %w", ctx.Err())
+		}
+	}
+
 	return nil
 }
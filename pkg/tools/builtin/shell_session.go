@@ -0,0 +1,237 @@
+package builtin
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errShellExited is returned by shellSession.run when the shell exited
+// before the end of the command, e.g. because it ran `exit`.
+var errShellExited = errors.New("shell session exited")
+
+// shellSession is a long-lived shell process running the commands of the
+// shell tool one after the other, so that the working directory and the
+// variables they set persist across calls.
+//
+// The commands are written to the standard input of the shell, each
+// followed by a command printing a marker line with its exit status and
+// the working directory of the shell: the output of the command is what
+// the shell writes until then.
+type shellSession struct {
+	cmd    *exec.Cmd
+	pg     *processGroup
+	stdin  io.WriteCloser
+	marker string
+
+	outputMu sync.Mutex
+	output   bytes.Buffer
+	// wrote is signaled when output grows.
+	wrote chan struct{}
+	// readDone is closed once all the output has been read.
+	readDone chan struct{}
+	// exited is closed once the shell has exited.
+	exited chan struct{}
+}
+
+// startShellSession starts a shell session in dir.
+func startShellSession(shell string, env []string, dir string) (*shellSession, error) {
+	cmd := exec.Command(shell)
+	cmd.Env = env
+	cmd.Dir = dir
+	cmd.SysProcAttr = platformSpecificSysProcAttr()
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	// The shell writes to a file rather than to a writer: exec.Cmd.Wait
+	// then doesn't wait for the background processes of the commands,
+	// which inherit it, to close it.
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdout = w
+	cmd.Stderr = w
+
+	err = cmd.Start()
+	w.Close()
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	pg, err := createProcessGroup(cmd.Process)
+	if err != nil {
+		_ = kill(cmd.Process, pg)
+		r.Close()
+		return nil, fmt.Errorf("creating process group: %w", err)
+	}
+
+	s := &shellSession{
+		cmd:      cmd,
+		pg:       pg,
+		stdin:    stdin,
+		marker:   "__DOCKER_AGENT_" + rand.Text() + "__",
+		wrote:    make(chan struct{}, 1),
+		readDone: make(chan struct{}),
+		exited:   make(chan struct{}),
+	}
+	go s.read(r)
+	go func() {
+		_ = cmd.Wait()
+		close(s.exited)
+	}()
+
+	return s, nil
+}
+
+func (s *shellSession) read(r io.ReadCloser) {
+	defer close(s.readDone)
+	defer r.Close()
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			s.outputMu.Lock()
+			s.output.Write(buf[:n])
+			s.outputMu.Unlock()
+
+			select {
+			case s.wrote <- struct{}{}:
+			default:
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// run runs command in the shell, after changing to the directory cwd if
+// it's set, and returns its output, its exit status and the working
+// directory of the shell after it. It returns the output so far with
+// errShellExited if the shell exited, or with the error of ctx if ctx is
+// done first: the command may still be running then.
+func (s *shellSession) run(ctx context.Context, command, cwd string) (output string, status int, dir string, err error) {
+	s.outputMu.Lock()
+	s.output.Reset()
+	s.outputMu.Unlock()
+
+	// The commands don't read the standard input of the shell: it's the
+	// next commands.
+	script := "eval " + shellQuote(command) + " </dev/null"
+	if cwd != "" {
+		script = "cd -- " + shellQuote(cwd) + " && " + script
+	}
+	script += fmt.Sprintf("\nprintf '\\n%s %%s %%s\\n' \"$?\" \"$PWD\"\n", s.marker)
+
+	if _, err := io.WriteString(s.stdin, script); err != nil {
+		return "", 0, "", errShellExited
+	}
+
+	for {
+		if output, status, dir, ok := s.result(); ok {
+			return output, status, dir, nil
+		}
+
+		select {
+		case <-s.wrote:
+		case <-s.exited:
+			// Give the reader the time to read the last output, unless
+			// a background process of the command keeps the pipe open.
+			select {
+			case <-s.readDone:
+			case <-time.After(waitDelayAfterShellExit):
+			}
+			if output, status, dir, ok := s.result(); ok {
+				return output, status, dir, nil
+			}
+			return s.outputString(), 0, "", errShellExited
+		case <-ctx.Done():
+			return s.outputString(), 0, "", ctx.Err()
+		}
+	}
+}
+
+// result returns the output of the command, its exit status and the
+// working directory of the shell, once the marker line has been read.
+func (s *shellSession) result() (output string, status int, dir string, ok bool) {
+	s.outputMu.Lock()
+	defer s.outputMu.Unlock()
+
+	data := s.output.Bytes()
+	i := bytes.LastIndex(data, []byte("\n"+s.marker+" "))
+	if i < 0 {
+		return "", 0, "", false
+	}
+	line, _, complete := bytes.Cut(data[i+len(s.marker)+2:], []byte("\n"))
+	if !complete {
+		return "", 0, "", false
+	}
+	statusText, dir, _ := strings.Cut(string(line), " ")
+	status, err := strconv.Atoi(statusText)
+	if err != nil {
+		return "", 0, "", false
+	}
+	return string(data[:i]), status, dir, true
+}
+
+func (s *shellSession) outputString() string {
+	s.outputMu.Lock()
+	defer s.outputMu.Unlock()
+	return s.output.String()
+}
+
+// hasExited reports whether the shell has exited.
+func (s *shellSession) hasExited() bool {
+	select {
+	case <-s.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+// stop terminates the shell, with the processes it spawned, and waits for
+// it to exit until ctx is done.
+func (s *shellSession) stop(ctx context.Context) error {
+	_ = s.stdin.Close()
+	_ = kill(s.cmd.Process, s.pg)
+
+	select {
+	case <-s.exited:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for the shell session to exit: %w", ctx.Err())
+	}
+}
+
+// sessionShell returns the shell of the persistent sessions: shell if it's
+// a POSIX shell, /bin/sh otherwise, e.g. for fish.
+func sessionShell(shell string) string {
+	switch filepath.Base(shell) {
+	case "sh", "bash", "zsh", "dash", "ksh", "mksh", "ash":
+		return shell
+	default:
+		return "/bin/sh"
+	}
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
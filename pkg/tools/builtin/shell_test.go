@@ -3,7 +3,9 @@ package builtin
 import (
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -41,6 +43,19 @@ func TestShellTool_HandlerEcho(t *testing.T) {
 	assert.Contains(t, result.Output, "hello world")
 }
 
+func TestShellTool_EnvPassthrough(t *testing.T) {
+	t.Setenv("SHELL_SCOPED_A", "a")
+	t.Setenv("SHELL_LEAKED_SECRET", "secret")
+
+	tool := NewShellTool([]string{"EXPLICIT=1"}, &config.RuntimeConfig{Config: config.Config{WorkingDir: t.TempDir()}}, WithShellEnvPassthrough("SHELL_SCOPED_*"))
+
+	result, err := tool.handler.RunShell(t.Context(), RunShellArgs{
+		Cmd: `echo "[$SHELL_SCOPED_A][$EXPLICIT][$SHELL_LEAKED_SECRET]"`,
+	})
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, "[a][1][]")
+}
+
 func TestShellTool_HandlerWithCwd(t *testing.T) {
 	tool := NewShellTool(nil, &config.RuntimeConfig{Config: config.Config{WorkingDir: t.TempDir()}})
 	tmpDir := t.TempDir()
@@ -137,6 +152,15 @@ func TestShellTool_Instructions(t *testing.T) {
 	assert.Contains(t, instructions, "Shell Tools")
 }
 
+func TestWindowsShellNote(t *testing.T) {
+	t.Parallel()
+
+	assert.Contains(t, windowsShellNote(`C:\Program Files\PowerShell\7\pwsh.exe`), "PowerShell syntax")
+	assert.Contains(t, windowsShellNote(`C:\Windows\System32\WindowsPowerShell\v1.0\powershell.exe`), "PowerShell syntax")
+	assert.Contains(t, windowsShellNote(`C:\Windows\System32\CMD.EXE`), "cmd syntax")
+	assert.Empty(t, windowsShellNote("/bin/bash"))
+}
+
 func TestResolveWorkDir(t *testing.T) {
 	t.Parallel()
 
@@ -249,3 +273,104 @@ func TestShellTool_DetachedBackgroundedChildDoesNotBlockReturn(t *testing.T) {
 		t.Fatal("shell tool hung when command backgrounded a detached child")
 	}
 }
+
+func newPersistentShellTool(t *testing.T, workingDir string) *ShellTool {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("Persistent shell sessions need a POSIX shell")
+	}
+
+	tool := NewShellTool(nil, &config.RuntimeConfig{Config: config.Config{WorkingDir: workingDir}}, WithPersistentSession(true))
+	t.Cleanup(func() { _ = tool.Stop(t.Context()) })
+	return tool
+}
+
+func TestShellTool_PersistentSession(t *testing.T) {
+	workingDir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(workingDir, "backend"), 0o755))
+	tool := newPersistentShellTool(t, workingDir)
+
+	run := func(cmd string) string {
+		t.Helper()
+		result, err := tool.handler.RunShell(t.Context(), RunShellArgs{Cmd: cmd})
+		require.NoError(t, err)
+		return result.Output
+	}
+
+	// The working directory and the exported variables persist.
+	output := run("cd backend && export GREETING=hello")
+	assert.Equal(t, "<no output>\n\ncwd: "+filepath.Join(workingDir, "backend"), output)
+
+	output = run(`pwd; echo "$GREETING world"`)
+	assert.Contains(t, output, filepath.Join(workingDir, "backend")+"\nhello world")
+	assert.True(t, strings.HasSuffix(output, "cwd: "+filepath.Join(workingDir, "backend")))
+
+	// Errors are reported, the session goes on.
+	output = run("echo oops >&2; false")
+	assert.Contains(t, output, "Error executing command: exit status 1\nOutput: oops")
+	assert.Contains(t, run("echo $GREETING"), "hello")
+
+	// The commands don't read the commands that follow.
+	assert.Contains(t, run("cat; echo read"), "read")
+
+	// cwd changes the directory of the session.
+	result, err := tool.handler.RunShell(t.Context(), RunShellArgs{Cmd: "true", Cwd: ".."})
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(result.Output, "cwd: "+workingDir))
+}
+
+func TestShellTool_PersistentSessionRestarts(t *testing.T) {
+	workingDir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(workingDir, "sub"), 0o755))
+	tool := newPersistentShellTool(t, workingDir)
+
+	_, err := tool.handler.RunShell(t.Context(), RunShellArgs{Cmd: "cd sub && export KEPT=no"})
+	require.NoError(t, err)
+
+	// A command exiting the shell ends the session.
+	result, err := tool.handler.RunShell(t.Context(), RunShellArgs{Cmd: "echo bye; exit 3"})
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, "bye\n\nThe shell session exited")
+
+	// The next command runs in a new shell, in the same directory.
+	result, err = tool.handler.RunShell(t.Context(), RunShellArgs{Cmd: `echo "[$KEPT]"`})
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, "[]")
+	assert.True(t, strings.HasSuffix(result.Output, "cwd: "+filepath.Join(workingDir, "sub")))
+
+	// A shell killed between calls is restarted with a warning.
+	tool.handler.sessionMu.Lock()
+	require.NoError(t, tool.handler.session.cmd.Process.Kill())
+	<-tool.handler.session.exited
+	tool.handler.sessionMu.Unlock()
+
+	result, err = tool.handler.RunShell(t.Context(), RunShellArgs{Cmd: "echo again"})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(result.Output, "Warning: the shell session exited"))
+	assert.Contains(t, result.Output, "again")
+
+	// A timed out command restarts the shell.
+	result, err = tool.handler.RunShell(t.Context(), RunShellArgs{Cmd: "sleep 30", Timeout: 1})
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, "Command timed out")
+	assert.Contains(t, result.Output, "The shell session was restarted")
+
+	result, err = tool.handler.RunShell(t.Context(), RunShellArgs{Cmd: "echo after"})
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, "after")
+	assert.NotContains(t, result.Output, "Warning")
+}
+
+func TestShellTool_StopPersistentSession(t *testing.T) {
+	tool := newPersistentShellTool(t, t.TempDir())
+
+	_, err := tool.handler.RunShell(t.Context(), RunShellArgs{Cmd: "true"})
+	require.NoError(t, err)
+	session := tool.handler.session
+	require.NotNil(t, session)
+
+	require.NoError(t, tool.Stop(t.Context()))
+	assert.True(t, session.hasExited())
+	assert.Nil(t, tool.handler.session)
+	assert.Contains(t, tool.Instructions(), "All calls run in the same shell session")
+}
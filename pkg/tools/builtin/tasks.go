@@ -553,6 +553,7 @@ func (t *TasksTool) Tools(_ context.Context) ([]tools.Tool, error) {
 				Title:        "Get Task",
 				ReadOnlyHint: true,
 			},
+			NoResultCache: true,
 		},
 		{
 			Name:        ToolNameUpdateTask,
@@ -585,6 +586,7 @@ func (t *TasksTool) Tools(_ context.Context) ([]tools.Tool, error) {
 				Title:        "List Tasks",
 				ReadOnlyHint: true,
 			},
+			NoResultCache: true,
 		},
 		{
 			Name:        ToolNameNextTask,
@@ -595,6 +597,7 @@ func (t *TasksTool) Tools(_ context.Context) ([]tools.Tool, error) {
 				Title:        "Next Task",
 				ReadOnlyHint: true,
 			},
+			NoResultCache: true,
 		},
 		{
 			Name:        ToolNameAddDependency,
@@ -2,7 +2,8 @@ package builtin
 
 import (
 	"context"
-	"strings"
+	"slices"
+	"sync"
 
 	"github.com/docker/docker-agent/pkg/tools"
 )
@@ -10,7 +11,9 @@ import (
 const ToolNameThink = "think"
 
 type ThinkTool struct {
-	thoughts []string
+	mu           sync.Mutex
+	thoughts     []string
+	keepThoughts bool
 }
 
 // Verify interface compliance
@@ -23,23 +26,56 @@ type ThinkArgs struct {
 	Thought string `json:"thought" jsonschema:"The thought to think about"`
 }
 
+// ThinkOption is a functional option for configuring a ThinkTool.
+type ThinkOption func(*ThinkTool)
+
+// WithKeepThoughts keeps the thoughts of the previous turns in the
+// conversation sent to the model. By default, only the thoughts of the
+// current turn are sent.
+func WithKeepThoughts(keep bool) ThinkOption {
+	return func(t *ThinkTool) {
+		t.keepThoughts = keep
+	}
+}
+
 func (t *ThinkTool) callTool(_ context.Context, params ThinkArgs) (*tools.ToolCallResult, error) {
+	t.mu.Lock()
 	t.thoughts = append(t.thoughts, params.Thought)
-	return tools.ResultSuccess("Thoughts:\n" + strings.Join(t.thoughts, "\n")), nil
+	t.mu.Unlock()
+	return tools.ResultSuccess("Thought recorded."), nil
+}
+
+func NewThinkTool(opts ...ThinkOption) *ThinkTool {
+	t := &ThinkTool{}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// KeepThoughts reports whether the thoughts of the previous turns should stay
+// in the conversation sent to the model.
+func (t *ThinkTool) KeepThoughts() bool {
+	return t.keepThoughts
 }
 
-func NewThinkTool() *ThinkTool {
-	return &ThinkTool{}
+// Thoughts returns the thoughts recorded so far, oldest first.
+func (t *ThinkTool) Thoughts() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.thoughts)
 }
 
 func (t *ThinkTool) Instructions() string {
 	return `## Think Tool
 
-Use the think tool as a scratchpad before acting. Think to:
-- Check which rules or policies apply
-- Verify you have all required information
-- Validate planned actions before executing
-- Reason through complex multi-step problems`
+Use the think tool as a scratchpad before acting. Thoughts are private: the user doesn't see them as part of your answer and they don't change anything. Think:
+- Before a complex or multi-step sequence of tool calls, to plan the steps
+- After tool results, to check them before deciding what to do next
+- To check which rules or policies apply to the request
+- To verify you have all the required information before acting
+
+Keep each thought short and focused. Don't use it for simple, single-step requests.`
 }
 
 func (t *ThinkTool) Tools(context.Context) ([]tools.Tool, error) {
@@ -47,7 +83,7 @@ func (t *ThinkTool) Tools(context.Context) ([]tools.Tool, error) {
 		{
 			Name:         ToolNameThink,
 			Category:     "think",
-			Description:  "Use the tool to think about something. It will not obtain new information or change the database, but just append the thought to the log. Use it when complex reasoning or some cache memory is needed.",
+			Description:  "Use the tool to think about something. It will not obtain new information or change anything, but just record the thought. Use it when complex reasoning or some cache memory is needed.",
 			Parameters:   tools.MustSchemaFor[ThinkArgs](),
 			OutputSchema: tools.MustSchemaFor[string](),
 			Handler:      tools.NewHandler(t.callTool),
@@ -55,6 +91,7 @@ func (t *ThinkTool) Tools(context.Context) ([]tools.Tool, error) {
 				ReadOnlyHint: true,
 				Title:        "Think",
 			},
+			NoResultCache: true,
 		},
 	}, nil
 }
@@ -14,13 +14,24 @@ func TestThinkTool_Handler(t *testing.T) {
 
 	result, err := tool.callTool(t.Context(), ThinkArgs{Thought: "This is a test thought"})
 	require.NoError(t, err)
-	assert.Contains(t, result.Output, "This is a test thought")
+	assert.Equal(t, "Thought recorded.", result.Output)
 
-	result, err = tool.callTool(t.Context(), ThinkArgs{Thought: "Another thought"})
+	_, err = tool.callTool(t.Context(), ThinkArgs{Thought: "Another thought"})
 	require.NoError(t, err)
 
-	assert.Contains(t, result.Output, "This is a test thought")
-	assert.Contains(t, result.Output, "Another thought")
+	assert.Equal(t, []string{"This is a test thought", "Another thought"}, tool.Thoughts())
+}
+
+func TestThinkTool_KeepThoughts(t *testing.T) {
+	assert.False(t, NewThinkTool().KeepThoughts())
+	assert.True(t, NewThinkTool(WithKeepThoughts(true)).KeepThoughts())
+}
+
+func TestThinkTool_ReadOnly(t *testing.T) {
+	allTools, err := NewThinkTool().Tools(t.Context())
+	require.NoError(t, err)
+	require.Len(t, allTools, 1)
+	assert.True(t, allTools[0].Annotations.ReadOnlyHint)
 }
 
 func TestThinkTool_OutputSchema(t *testing.T) {
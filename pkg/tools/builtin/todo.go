@@ -25,8 +25,9 @@ type TodoTool struct {
 
 // Verify interface compliance
 var (
-	_ tools.ToolSet      = (*TodoTool)(nil)
-	_ tools.Instructable = (*TodoTool)(nil)
+	_ tools.ToolSet             = (*TodoTool)(nil)
+	_ tools.Instructable        = (*TodoTool)(nil)
+	_ tools.DynamicInstructable = (*TodoTool)(nil)
 )
 
 type Todo struct {
@@ -174,6 +175,22 @@ Track task progress with todos:
 - Never leave todos pending or in-progress when done`
 }
 
+// InstructionsForTurn shows the current todos, so that the model knows
+// where it is in its plan without listing them.
+func (t *TodoTool) InstructionsForTurn(context.Context, tools.TurnInfo) (string, error) {
+	todos := t.handler.storage.All()
+	if len(todos) == 0 {
+		return "", nil
+	}
+
+	var b strings.Builder
+	b.WriteString("## Current Todos\n")
+	for _, todo := range todos {
+		fmt.Fprintf(&b, "\n- [%s] %s (%s)", todo.ID, todo.Description, todo.Status)
+	}
+	return b.String(), nil
+}
+
 // addTodo creates a new todo and adds it to storage.
 func (h *todoHandler) addTodo(description string) Todo {
 	todo := Todo{
@@ -301,6 +318,7 @@ func (t *TodoTool) Tools(context.Context) ([]tools.Tool, error) {
 				Title:        "Create TODO",
 				ReadOnlyHint: true, // Technically not read-only but has practically no destructive side effects.
 			},
+			NoResultCache: true,
 		},
 		{
 			Name:         ToolNameCreateTodos,
@@ -313,6 +331,7 @@ func (t *TodoTool) Tools(context.Context) ([]tools.Tool, error) {
 				Title:        "Create TODOs",
 				ReadOnlyHint: true, // Technically not read-only but has practically no destructive side effects.
 			},
+			NoResultCache: true,
 		},
 		{
 			Name:         ToolNameUpdateTodos,
@@ -325,6 +344,7 @@ func (t *TodoTool) Tools(context.Context) ([]tools.Tool, error) {
 				Title:        "Update TODOs",
 				ReadOnlyHint: true, // Technically not read-only but has practically no destructive side effects.
 			},
+			NoResultCache: true,
 		},
 		{
 			Name:         ToolNameListTodos,
@@ -336,6 +356,7 @@ func (t *TodoTool) Tools(context.Context) ([]tools.Tool, error) {
 				Title:        "List TODOs",
 				ReadOnlyHint: true,
 			},
+			NoResultCache: true,
 		},
 	}, nil
 }
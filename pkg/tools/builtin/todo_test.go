@@ -362,3 +362,20 @@ func requireMeta(t *testing.T, result *tools.ToolCallResult, expectedLen int) {
 	require.True(t, ok, "Meta should be []Todo")
 	require.Len(t, metaTodos, expectedLen)
 }
+
+func TestTodoTool_InstructionsForTurn(t *testing.T) {
+	tool := NewTodoTool()
+
+	instructions, err := tool.InstructionsForTurn(t.Context(), tools.TurnInfo{})
+	require.NoError(t, err)
+	assert.Empty(t, instructions)
+
+	_, err = tool.handler.createTodos(t.Context(), CreateTodosArgs{Descriptions: []string{"Write the code", "Test it"}})
+	require.NoError(t, err)
+	_, err = tool.handler.updateTodos(t.Context(), UpdateTodosArgs{Updates: []TodoUpdate{{ID: "todo_1", Status: "in-progress"}}})
+	require.NoError(t, err)
+
+	instructions, err = tool.InstructionsForTurn(t.Context(), tools.TurnInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, "## Current Todos\n\n- [todo_1] Write the code (in-progress)\n- [todo_2] Test it (pending)", instructions)
+}
@@ -105,6 +105,8 @@ func (t *UserPromptTool) Tools(context.Context) ([]tools.Tool, error) {
 				ReadOnlyHint: true,
 				Title:        "User Prompt",
 			},
+			NoResultCache: true,
+			Timeout:       tools.NoTimeout,
 		},
 	}, nil
 }
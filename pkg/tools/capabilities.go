@@ -14,6 +14,49 @@ type Instructable interface {
 	Instructions() string
 }
 
+// DynamicInstructable is implemented by toolsets whose instructions depend on
+// their current state, e.g. the current plan of a todo list. Unlike
+// Instructions, InstructionsForTurn is called before each model call and its
+// result is only sent with that call. An empty section adds nothing.
+type DynamicInstructable interface {
+	InstructionsForTurn(ctx context.Context, turn TurnInfo) (string, error)
+}
+
+// TurnInfo describes the model call that dynamic instructions are built for.
+type TurnInfo struct {
+	// SessionID is the ID of the session the turn belongs to.
+	SessionID string
+	// AgentName is the name of the agent being called.
+	AgentName string
+	// WorkingDir is the working directory of the session, if any.
+	WorkingDir string
+}
+
+// Previewable is implemented by toolsets that can show what a call to one of
+// their tools would change, so that it's confirmed knowingly. Preview must
+// not change anything, and returns nil for the calls it can't preview.
+type Previewable interface {
+	Preview(ctx context.Context, toolCall ToolCall) (*Preview, error)
+}
+
+// Preview shows what a tool call would change.
+type Preview struct {
+	// Diff is a unified diff of the changes to the files.
+	Diff string `json:"diff,omitempty"`
+	// Summary describes the changes in words, for the tools whose changes
+	// aren't diffs.
+	Summary string `json:"summary,omitempty"`
+}
+
+// PreviewToolCall returns the preview of a call to one of the tools of ts,
+// or nil if ts doesn't implement Previewable.
+func PreviewToolCall(ctx context.Context, ts ToolSet, toolCall ToolCall) (*Preview, error) {
+	if p, ok := As[Previewable](ts); ok {
+		return p.Preview(ctx, toolCall)
+	}
+	return nil, nil
+}
+
 // Elicitable is implemented by toolsets that support MCP elicitation.
 type Elicitable interface {
 	SetElicitationHandler(handler ElicitationHandler)
@@ -40,6 +83,23 @@ type ChangeNotifier interface {
 	SetToolsChangedHandler(handler func())
 }
 
+// Versioned is implemented by toolsets whose tools can change while they're
+// running, e.g. after an MCP ToolListChanged notification. ToolsVersion
+// changes whenever their tools do, so that they can be cached until then.
+// The tools of the other toolsets are assumed not to change once started.
+type Versioned interface {
+	ToolsVersion() uint64
+}
+
+// ToolsVersion returns the version of the tools of ts if it implements
+// Versioned, or 0.
+func ToolsVersion(ts ToolSet) uint64 {
+	if v, ok := As[Versioned](ts); ok {
+		return v.ToolsVersion()
+	}
+	return 0
+}
+
 // ConfigureHandlers sets all applicable handlers on a toolset.
 // It checks for Elicitable and OAuthCapable interfaces and configures them.
 // This is a convenience function that handles the capability checking internally.
@@ -0,0 +1,40 @@
+package tools
+
+import "slices"
+
+// FilterByCategory returns the tools whose category is in include, when it's
+// not empty, and not in exclude. Tools without a category are only dropped
+// by a non-empty include list.
+func FilterByCategory(toolList []Tool, include, exclude []string) []Tool {
+	if len(include) == 0 && len(exclude) == 0 {
+		return toolList
+	}
+
+	var filtered []Tool
+	for _, tool := range toolList {
+		if len(include) > 0 && !slices.Contains(include, tool.Category) {
+			continue
+		}
+		if slices.Contains(exclude, tool.Category) {
+			continue
+		}
+		filtered = append(filtered, tool)
+	}
+	return filtered
+}
+
+// CountByCategory returns the number of tools of each category. Tools without
+// a category aren't counted.
+func CountByCategory(toolList []Tool) map[string]int {
+	var counts map[string]int
+	for _, tool := range toolList {
+		if tool.Category == "" {
+			continue
+		}
+		if counts == nil {
+			counts = make(map[string]int)
+		}
+		counts[tool.Category]++
+	}
+	return counts
+}
@@ -0,0 +1,33 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterByCategory(t *testing.T) {
+	t.Parallel()
+
+	toolList := []Tool{
+		{Name: "read_file", Category: "filesystem"},
+		{Name: "write_file", Category: "filesystem"},
+		{Name: "lsp_hover", Category: "lsp"},
+		{Name: "custom"},
+	}
+	names := func(toolList []Tool) []string {
+		var names []string
+		for _, tool := range toolList {
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"read_file", "write_file", "lsp_hover", "custom"}, names(FilterByCategory(toolList, nil, nil)))
+	assert.Equal(t, []string{"lsp_hover"}, names(FilterByCategory(toolList, []string{"lsp"}, nil)))
+	assert.Equal(t, []string{"lsp_hover", "custom"}, names(FilterByCategory(toolList, nil, []string{"filesystem"})))
+	assert.Empty(t, FilterByCategory(toolList, []string{"lsp"}, []string{"lsp"}))
+
+	assert.Equal(t, map[string]int{"filesystem": 2, "lsp": 1}, CountByCategory(toolList))
+	assert.Nil(t, CountByCategory(nil))
+}
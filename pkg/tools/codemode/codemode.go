@@ -42,6 +42,7 @@ type codeModeTool struct {
 var (
 	_ tools.ToolSet   = (*codeModeTool)(nil)
 	_ tools.Startable = (*codeModeTool)(nil)
+	_ tools.Versioned = (*codeModeTool)(nil)
 )
 
 type RunToolsWithJavascriptArgs struct {
@@ -52,6 +53,16 @@ func isExcludedTool(tool tools.Tool) bool {
 	return tool.Category == "todo"
 }
 
+// ToolsVersion changes whenever the tools of one of the wrapped toolsets do:
+// they're documented in the description of the tool.
+func (c *codeModeTool) ToolsVersion() uint64 {
+	var version uint64
+	for _, toolset := range c.toolsets {
+		version += tools.ToolsVersion(toolset)
+	}
+	return version
+}
+
 func (c *codeModeTool) Tools(ctx context.Context) ([]tools.Tool, error) {
 	var (
 		functionsDoc  []string
@@ -0,0 +1,47 @@
+package tools
+
+// Concurrency is the number of calls to the tools of a toolset that may run
+// at once. Zero means any number.
+type Concurrency int
+
+const (
+	// Concurrent toolsets serve any number of calls at once.
+	Concurrent Concurrency = 0
+	// Serial toolsets serve one call at a time, e.g. the ones keeping the
+	// state of a single server in sync or talking to a server that only
+	// handles one request at a time.
+	Serial Concurrency = 1
+)
+
+// ConcurrencyDeclarer is implemented by toolsets that can't serve any number
+// of calls at once. The runtime queues the calls beyond their concurrency,
+// whichever the agents and sessions making them. The other toolsets are
+// assumed to be Concurrent.
+type ConcurrencyDeclarer interface {
+	Concurrency() Concurrency
+}
+
+// ToolSetConcurrency returns the concurrency ts declares, or Concurrent if it
+// doesn't implement ConcurrencyDeclarer.
+func ToolSetConcurrency(ts ToolSet) Concurrency {
+	if c, ok := As[ConcurrencyDeclarer](ts); ok {
+		return max(c.Concurrency(), Concurrent)
+	}
+	return Concurrent
+}
+
+// Innermost returns the toolset ts wraps, through all its wrappers: the one
+// the calls to its tools end up in.
+func Innermost(ts ToolSet) ToolSet {
+	for {
+		u, ok := ts.(Unwrapper)
+		if !ok {
+			return ts
+		}
+		inner := u.Unwrap()
+		if inner == nil {
+			return ts
+		}
+		ts = inner
+	}
+}
@@ -0,0 +1,33 @@
+package tools_test
+
+import (
+	"context"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// stubSerial implements ToolSet and ConcurrencyDeclarer.
+type stubSerial struct{}
+
+func (s *stubSerial) Tools(context.Context) ([]tools.Tool, error) { return nil, nil }
+func (s *stubSerial) Concurrency() tools.Concurrency              { return tools.Serial }
+
+func TestToolSetConcurrency(t *testing.T) {
+	t.Parallel()
+
+	assert.Check(t, is.Equal(tools.ToolSetConcurrency(&stubToolSet{}), tools.Concurrent))
+	assert.Check(t, is.Equal(tools.ToolSetConcurrency(&stubSerial{}), tools.Serial))
+	assert.Check(t, is.Equal(tools.ToolSetConcurrency(tools.NewStartable(&stubSerial{})), tools.Serial))
+}
+
+func TestInnermost(t *testing.T) {
+	t.Parallel()
+
+	inner := &stubToolSet{}
+	assert.Check(t, is.Equal(tools.Innermost(inner), tools.ToolSet(inner)))
+	assert.Check(t, is.Equal(tools.Innermost(tools.NewStartable(tools.NewStartable(inner))), tools.ToolSet(inner)))
+}
@@ -0,0 +1,88 @@
+package tools
+
+import (
+	"context"
+	"slices"
+)
+
+// FileChangeType is how a tool changed a file.
+type FileChangeType string
+
+const (
+	FileCreated  FileChangeType = "created"
+	FileModified FileChangeType = "modified"
+	FileDeleted  FileChangeType = "deleted"
+	FileRenamed  FileChangeType = "renamed"
+)
+
+// FileChange is a change a tool made to a file.
+type FileChange struct {
+	Path string         `json:"path"`
+	Type FileChangeType `json:"type"`
+	// OldPath is the path the file was renamed from, for FileRenamed.
+	OldPath string `json:"old_path,omitempty"`
+}
+
+// FileChangeReporter is told about the files the tools change. The runtime
+// puts one in the context of the tool handlers, see ReportFileChange.
+type FileChangeReporter interface {
+	// WillChange is called before a file is changed, e.g. to save its
+	// content so that the change can be reverted.
+	WillChange(path string)
+	// Changed is called after a file was changed.
+	Changed(change FileChange)
+}
+
+type fileChangeReporterKey struct{}
+
+// WithFileChangeReporter returns a context carrying the reporter.
+func WithFileChangeReporter(ctx context.Context, reporter FileChangeReporter) context.Context {
+	return context.WithValue(ctx, fileChangeReporterKey{}, reporter)
+}
+
+// FileChangeReporterFromContext returns the reporter carried by the context,
+// or nil.
+func FileChangeReporterFromContext(ctx context.Context) FileChangeReporter {
+	reporter, _ := ctx.Value(fileChangeReporterKey{}).(FileChangeReporter)
+	return reporter
+}
+
+// WillChangeFile tells the reporter of the context, if any, that the file is
+// about to be changed.
+func WillChangeFile(ctx context.Context, path string) {
+	if reporter := FileChangeReporterFromContext(ctx); reporter != nil {
+		reporter.WillChange(path)
+	}
+}
+
+// ReportFileChange tells the reporter of the context, if any, that a file
+// was changed.
+func ReportFileChange(ctx context.Context, change FileChange) {
+	if reporter := FileChangeReporterFromContext(ctx); reporter != nil {
+		reporter.Changed(change)
+	}
+}
+
+// MergeFileChange adds a change to a ledger of file changes, which holds at
+// most one change per path, in the order the files were first changed. The
+// changes to a path are combined: a file created then modified stays
+// created, a file created then deleted is dropped.
+func MergeFileChange(ledger []FileChange, change FileChange) []FileChange {
+	i := slices.IndexFunc(ledger, func(c FileChange) bool { return c.Path == change.Path })
+	if i < 0 {
+		return append(ledger, change)
+	}
+
+	existing := ledger[i]
+	switch {
+	case existing.Type == FileCreated && change.Type == FileModified:
+		return ledger
+	case existing.Type == FileCreated && change.Type == FileDeleted:
+		return slices.Delete(ledger, i, i+1)
+	case existing.Type == FileDeleted && change.Type == FileCreated:
+		ledger[i].Type = FileModified
+	default:
+		ledger[i] = change
+	}
+	return ledger
+}
@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeFileChange(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		changes []FileChange
+		want    []FileChange
+	}{
+		{
+			name:    "ordered by first change",
+			changes: []FileChange{{Path: "b", Type: FileModified}, {Path: "a", Type: FileModified}, {Path: "b", Type: FileModified}},
+			want:    []FileChange{{Path: "b", Type: FileModified}, {Path: "a", Type: FileModified}},
+		},
+		{
+			name:    "created then modified",
+			changes: []FileChange{{Path: "a", Type: FileCreated}, {Path: "a", Type: FileModified}},
+			want:    []FileChange{{Path: "a", Type: FileCreated}},
+		},
+		{
+			name:    "created then deleted",
+			changes: []FileChange{{Path: "a", Type: FileCreated}, {Path: "b", Type: FileModified}, {Path: "a", Type: FileDeleted}},
+			want:    []FileChange{{Path: "b", Type: FileModified}},
+		},
+		{
+			name:    "deleted then created",
+			changes: []FileChange{{Path: "a", Type: FileDeleted}, {Path: "a", Type: FileCreated}},
+			want:    []FileChange{{Path: "a", Type: FileModified}},
+		},
+		{
+			name:    "modified then deleted",
+			changes: []FileChange{{Path: "a", Type: FileModified}, {Path: "a", Type: FileDeleted}},
+			want:    []FileChange{{Path: "a", Type: FileDeleted}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var ledger []FileChange
+			for _, change := range tt.changes {
+				ledger = MergeFileChange(ledger, change)
+			}
+			assert.Equal(t, tt.want, ledger)
+		})
+	}
+}
+
+func TestReportFileChange(t *testing.T) {
+	t.Parallel()
+
+	// Without a reporter, the changes are ignored.
+	WillChangeFile(t.Context(), "a")
+	ReportFileChange(t.Context(), FileChange{Path: "a", Type: FileModified})
+
+	reporter := &recordingReporter{}
+	ctx := WithFileChangeReporter(t.Context(), reporter)
+	WillChangeFile(ctx, "a")
+	ReportFileChange(ctx, FileChange{Path: "a", Type: FileModified})
+
+	assert.Equal(t, []string{"a"}, reporter.willChange)
+	assert.Equal(t, []FileChange{{Path: "a", Type: FileModified}}, reporter.changes)
+}
+
+type recordingReporter struct {
+	willChange []string
+	changes    []FileChange
+}
+
+func (r *recordingReporter) WillChange(path string) { r.willChange = append(r.willChange, path) }
+
+func (r *recordingReporter) Changed(change FileChange) { r.changes = append(r.changes, change) }
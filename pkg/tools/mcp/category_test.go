@@ -0,0 +1,59 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	gomcp "github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/agent"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+func newCategorizedServer() *gomcp.Server {
+	server := gomcp.NewServer(&gomcp.Implementation{Name: "workspace", Version: "1.0.0"}, nil)
+	for name, category := range map[string]string{"read": "filesystem", "write": "filesystem", "hover": "lsp", "echo": ""} {
+		tool := &gomcp.Tool{Name: name, InputSchema: map[string]any{"type": "object"}}
+		if category != "" {
+			tool.Meta = gomcp.Meta{"category": category}
+		}
+		server.AddTool(tool, func(context.Context, *gomcp.CallToolRequest) (*gomcp.CallToolResult, error) {
+			return &gomcp.CallToolResult{}, nil
+		})
+	}
+	return server
+}
+
+func TestTools_Categories(t *testing.T) {
+	ts := startInMemoryToolset(t, newCategorizedServer())
+
+	toolsList, err := ts.Tools(t.Context())
+	require.NoError(t, err)
+
+	assert.Equal(t, "filesystem", findTool(t, toolsList, "docs_read").Category)
+	assert.Equal(t, "lsp", findTool(t, toolsList, "docs_hover").Category)
+	assert.Equal(t, "mcp", findTool(t, toolsList, "docs_echo").Category)
+	assert.Equal(t, map[string]int{"filesystem": 2, "lsp": 1, "mcp": 1}, tools.CountByCategory(toolsList))
+}
+
+func TestTools_AgentCategoryFilters(t *testing.T) {
+	ts := startInMemoryToolset(t, newCategorizedServer())
+
+	toolNames := func(a *agent.Agent) []string {
+		agentTools, err := a.Tools(t.Context())
+		require.NoError(t, err)
+		var names []string
+		for _, tool := range agentTools {
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+
+	excluding := agent.New("root", "", agent.WithToolSets(ts), agent.WithToolCategories(nil, []string{"filesystem"}))
+	assert.ElementsMatch(t, []string{"docs_hover", "docs_echo"}, toolNames(excluding))
+
+	including := agent.New("root", "", agent.WithToolSets(ts), agent.WithToolCategories([]string{"lsp", "filesystem"}, nil))
+	assert.ElementsMatch(t, []string{"docs_read", "docs_write", "docs_hover"}, toolNames(including))
+}
@@ -23,7 +23,7 @@ type GatewayToolset struct {
 
 var _ tools.ToolSet = (*GatewayToolset)(nil)
 
-func NewGatewayToolset(ctx context.Context, name, mcpServerName string, secrets []gateway.Secret, config any, envProvider environment.Provider, cwd string) (*GatewayToolset, error) {
+func NewGatewayToolset(ctx context.Context, name, mcpServerName string, secrets []gateway.Secret, config any, envProvider environment.Provider, cwd string, opts ...ToolsetOption) (*GatewayToolset, error) {
 	slog.Debug("Creating MCP Gateway toolset", "name", mcpServerName)
 
 	// Make sure all the required secrets are available in the environment.
@@ -49,7 +49,7 @@ func NewGatewayToolset(ctx context.Context, name, mcpServerName string, secrets
 		"--config", fileConfig,
 	}
 
-	inner := NewToolsetCommand(name, "docker", args, nil, cwd)
+	inner := NewToolsetCommand(name, "docker", args, nil, cwd, opts...)
 	inner.description = "mcp(ref=" + mcpServerName + ")"
 
 	return &GatewayToolset{
@@ -20,6 +20,7 @@ import (
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 
+	"github.com/docker/docker-agent/pkg/chat"
 	"github.com/docker/docker-agent/pkg/config/latest"
 	"github.com/docker/docker-agent/pkg/tools"
 )
@@ -29,6 +30,8 @@ type mcpClient interface {
 	ListTools(ctx context.Context, request *mcp.ListToolsParams) iter.Seq2[*mcp.Tool, error]
 	CallTool(ctx context.Context, request *mcp.CallToolParams) (*mcp.CallToolResult, error)
 	ListPrompts(ctx context.Context, request *mcp.ListPromptsParams) iter.Seq2[*mcp.Prompt, error]
+	ListResources(ctx context.Context, request *mcp.ListResourcesParams) iter.Seq2[*mcp.Resource, error]
+	ReadResource(ctx context.Context, request *mcp.ReadResourceParams) (*mcp.ReadResourceResult, error)
 	GetPrompt(ctx context.Context, request *mcp.GetPromptParams) (*mcp.GetPromptResult, error)
 	SetElicitationHandler(handler tools.ElicitationHandler)
 	SetOAuthSuccessHandler(handler func())
@@ -63,6 +66,17 @@ type Toolset struct {
 	// following a ToolListChanged notification from the server.
 	toolsChangedHandler func()
 
+	// hasResources is true when the server advertised the resources
+	// capability, in which case the toolset adds tools to list and read them.
+	hasResources    bool
+	maxResourceSize int
+
+	// sampling is the sampling policy of the server, nil when the server
+	// may not sample. samplingHandler runs the requests it allows.
+	sampling        *latest.MCPSamplingConfig
+	samplingHandler tools.SamplingHandler
+	samplingMu      sync.RWMutex
+
 	// restarted is closed and replaced whenever the connection is
 	// successfully restarted by watchConnection, allowing callers
 	// waiting on a reconnect to be unblocked.
@@ -90,34 +104,44 @@ var (
 var (
 	_ tools.Instructable   = (*Toolset)(nil)
 	_ tools.Elicitable     = (*Toolset)(nil)
+	_ tools.Samplable      = (*Toolset)(nil)
 	_ tools.OAuthCapable   = (*Toolset)(nil)
 	_ tools.ChangeNotifier = (*Toolset)(nil)
+	_ tools.Versioned      = (*Toolset)(nil)
 )
 
 // NewToolsetCommand creates a new MCP toolset from a command.
-func NewToolsetCommand(name, command string, args, env []string, cwd string) *Toolset {
+func NewToolsetCommand(name, command string, args, env []string, cwd string, opts ...ToolsetOption) *Toolset {
 	slog.Debug("Creating Stdio MCP toolset", "command", command, "args", args)
 
 	desc := buildStdioDescription(command, args)
-	return &Toolset{
+	ts := &Toolset{
 		name:        name,
 		mcpClient:   newStdioCmdClient(command, args, env, cwd),
 		logID:       command,
 		description: desc,
 	}
+	for _, opt := range opts {
+		opt(ts)
+	}
+	return ts
 }
 
 // NewRemoteToolset creates a new MCP toolset from a remote MCP Server.
-func NewRemoteToolset(name, urlString, transport string, headers map[string]string, oauthConfig *latest.RemoteOAuthConfig) *Toolset {
+func NewRemoteToolset(name, urlString, transport string, headers map[string]string, oauthConfig *latest.RemoteOAuthConfig, opts ...ToolsetOption) *Toolset {
 	slog.Debug("Creating Remote MCP toolset", "url", urlString, "transport", transport, "headers", headers)
 
 	desc := buildRemoteDescription(urlString, transport)
-	return &Toolset{
+	ts := &Toolset{
 		name:        name,
 		mcpClient:   newRemoteClient(urlString, transport, headers, NewKeyringTokenStore(), oauthConfig),
 		logID:       urlString,
 		description: desc,
 	}
+	for _, opt := range opts {
+		opt(ts)
+	}
+	return ts
 }
 
 // errServerUnavailable is returned by doStart when the MCP server could not be
@@ -126,6 +150,11 @@ func NewRemoteToolset(name, urlString, transport string, headers map[string]stri
 // retries via ensureToolSetsAreStarted on the next conversation turn.
 var errServerUnavailable = errors.New("MCP server unavailable")
 
+// Name returns the name given to the toolset in the configuration, if any.
+func (ts *Toolset) Name() string {
+	return ts.name
+}
+
 // Describe returns a short, user-visible description of this toolset instance.
 // It never includes secrets.
 func (ts *Toolset) Describe() string {
@@ -224,6 +253,9 @@ func (ts *Toolset) doStart(ctx context.Context) error {
 			},
 		},
 	}
+	if ts.sampling != nil {
+		initRequest.Params.Capabilities.Sampling = &mcp.SamplingCapabilities{}
+	}
 
 	var result *mcp.InitializeResult
 	const maxRetries = 3
@@ -265,6 +297,7 @@ func (ts *Toolset) doStart(ctx context.Context) error {
 
 	slog.Debug("Started MCP toolset successfully", "server", ts.logID)
 	ts.instructions = result.Instructions
+	ts.hasResources = result.Capabilities != nil && result.Capabilities.Resources != nil
 
 	return nil
 }
@@ -368,6 +401,7 @@ func (ts *Toolset) Tools(ctx context.Context) ([]tools.Tool, error) {
 	}
 	// Snapshot the generation so we can detect invalidation after the unlock.
 	gen := ts.cacheGen
+	hasResources := ts.hasResources
 	ts.mu.Unlock()
 
 	slog.Debug("Listing MCP tools (cache miss)", "server", ts.logID)
@@ -380,14 +414,11 @@ func (ts *Toolset) Tools(ctx context.Context) ([]tools.Tool, error) {
 			return nil, err
 		}
 
-		name := t.Name
-		if ts.name != "" {
-			name = fmt.Sprintf("%s_%s", ts.name, name)
-		}
+		name := ts.toolName(t.Name)
 
 		tool := tools.Tool{
 			Name:         name,
-			Category:     "mcp",
+			Category:     toolCategory(t),
 			Description:  t.Description,
 			Parameters:   t.InputSchema,
 			OutputSchema: t.OutputSchema,
@@ -401,6 +432,10 @@ func (ts *Toolset) Tools(ctx context.Context) ([]tools.Tool, error) {
 		slog.Debug("Added MCP tool", "tool", name)
 	}
 
+	if hasResources {
+		toolsList = append(toolsList, ts.resourceTools()...)
+	}
+
 	slog.Debug("Listed MCP tools", "count", len(toolsList), "server", ts.logID)
 
 	ts.mu.Lock()
@@ -415,6 +450,23 @@ func (ts *Toolset) Tools(ctx context.Context) ([]tools.Tool, error) {
 	return toolsList, nil
 }
 
+// toolCategory returns the category a server gives a tool in its "category"
+// metadata, or "mcp".
+func toolCategory(t *mcp.Tool) string {
+	if category, ok := t.Meta["category"].(string); ok && category != "" {
+		return category
+	}
+	return "mcp"
+}
+
+// toolName prefixes the name of a tool with the name of the toolset, if any.
+func (ts *Toolset) toolName(name string) string {
+	if ts.name == "" {
+		return name
+	}
+	return fmt.Sprintf("%s_%s", ts.name, name)
+}
+
 // refreshToolCache fetches the tool list from the server and populates the
 // cache. It is called by the ToolListChanged notification handler so that
 // the cache is already warm by the time the runtime loop calls Tools().
@@ -565,13 +617,17 @@ func isServerUnavailableError(err error) bool {
 func processMCPContent(toolResult *mcp.CallToolResult) *tools.ToolCallResult {
 	var text strings.Builder
 	var images, audios []tools.MediaContent
+	var contents []tools.ContentBlock
 
 	for _, c := range toolResult.Content {
 		switch c := c.(type) {
 		case *mcp.TextContent:
 			text.WriteString(c.Text)
+			contents = append(contents, tools.ContentBlock{Type: tools.ContentBlockTypeText, Text: c.Text})
 		case *mcp.ImageContent:
-			images = append(images, encodeMedia(c.Data, c.MIMEType))
+			image := encodeMedia(c.Data, c.MIMEType)
+			images = append(images, image)
+			contents = append(contents, tools.ContentBlock{Type: tools.ContentBlockTypeImage, Image: &image})
 		case *mcp.AudioContent:
 			audios = append(audios, encodeMedia(c.Data, c.MIMEType))
 		case *mcp.ResourceLink:
@@ -583,6 +639,30 @@ func processMCPContent(toolResult *mcp.CallToolResult) *tools.ToolCallResult {
 			} else {
 				text.WriteString(c.URI)
 			}
+		case *mcp.EmbeddedResource:
+			if c.Resource == nil {
+				continue
+			}
+			// Images embedded as blobs are images, like screenshots.
+			if len(c.Resource.Blob) > 0 && chat.IsImageMimeType(c.Resource.MIMEType) {
+				image := encodeMedia(c.Resource.Blob, c.Resource.MIMEType)
+				images = append(images, image)
+				contents = append(contents, tools.ContentBlock{Type: tools.ContentBlockTypeImage, Image: &image})
+				continue
+			}
+			if c.Resource.Text != "" {
+				text.WriteString(c.Resource.Text)
+			} else {
+				text.WriteString(c.Resource.URI)
+			}
+			contents = append(contents, tools.ContentBlock{
+				Type: tools.ContentBlockTypeResource,
+				Resource: &tools.ResourceContent{
+					URI:      c.Resource.URI,
+					MimeType: c.Resource.MIMEType,
+					Text:     c.Resource.Text,
+				},
+			})
 		}
 	}
 
@@ -591,6 +671,7 @@ func processMCPContent(toolResult *mcp.CallToolResult) *tools.ToolCallResult {
 		IsError:           toolResult.IsError,
 		Images:            images,
 		Audios:            audios,
+		Contents:          contents,
 		StructuredContent: toolResult.StructuredContent,
 	}
 }
@@ -616,6 +697,14 @@ func (ts *Toolset) SetManagedOAuth(managed bool) {
 	ts.mcpClient.SetManagedOAuth(managed)
 }
 
+// ToolsVersion returns the generation of the tool cache: it changes when the
+// server notifies that its tools changed, and when it's restarted.
+func (ts *Toolset) ToolsVersion() uint64 {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.cacheGen
+}
+
 func (ts *Toolset) SetToolsChangedHandler(handler func()) {
 	ts.mu.Lock()
 	defer ts.mu.Unlock()
@@ -36,6 +36,14 @@ func (m *mockMCPClient) ListPrompts(context.Context, *mcp.ListPromptsParams) ite
 	return func(func(*mcp.Prompt, error) bool) {}
 }
 
+func (m *mockMCPClient) ListResources(context.Context, *mcp.ListResourcesParams) iter.Seq2[*mcp.Resource, error] {
+	return func(func(*mcp.Resource, error) bool) {}
+}
+
+func (m *mockMCPClient) ReadResource(context.Context, *mcp.ReadResourceParams) (*mcp.ReadResourceResult, error) {
+	return &mcp.ReadResourceResult{}, nil
+}
+
 func (m *mockMCPClient) GetPrompt(context.Context, *mcp.GetPromptParams) (*mcp.GetPromptResult, error) {
 	return &mcp.GetPromptResult{}, nil
 }
@@ -247,6 +255,24 @@ func TestProcessMCPContent(t *testing.T) {
 			wantOutput: "[doc](file:///path(1%29/doc.txt)",
 		},
 
+		// --- embedded resources ---
+		{
+			name:       "embedded text resource",
+			input:      callToolResult(&mcp.EmbeddedResource{Resource: &mcp.ResourceContents{URI: "file:///notes.md", MIMEType: "text/markdown", Text: "# Notes"}}),
+			wantOutput: "# Notes",
+		},
+		{
+			name:       "embedded blob resource",
+			input:      callToolResult(&mcp.EmbeddedResource{Resource: &mcp.ResourceContents{URI: "file:///data.bin", MIMEType: "application/octet-stream", Blob: []byte("bin")}}),
+			wantOutput: "file:///data.bin",
+		},
+		{
+			name:       "embedded image resource",
+			input:      callToolResult(&mcp.EmbeddedResource{Resource: &mcp.ResourceContents{URI: "file:///shot.png", MIMEType: "image/png", Blob: []byte("img")}}),
+			wantOutput: "no output",
+			wantImages: []tools.MediaContent{{Data: "aW1n", MimeType: "image/png"}},
+		},
+
 		// --- structured content ---
 		{
 			name:           "structured content passed through",
@@ -291,6 +317,24 @@ func TestProcessMCPContent(t *testing.T) {
 	}
 }
 
+func TestProcessMCPContent_Contents(t *testing.T) {
+	t.Parallel()
+
+	result := processMCPContent(callToolResult(
+		&mcp.TextContent{Text: "Here is the page"},
+		&mcp.ImageContent{Data: []byte("screenshot"), MIMEType: "image/png"},
+		&mcp.EmbeddedResource{Resource: &mcp.ResourceContents{URI: "https://example.com", MIMEType: "text/html", Text: "<html>"}},
+		&mcp.AudioContent{Data: []byte("aud"), MIMEType: "audio/wav"},
+	))
+
+	assert.Equal(t, []tools.ContentBlock{
+		{Type: tools.ContentBlockTypeText, Text: "Here is the page"},
+		{Type: tools.ContentBlockTypeImage, Image: &tools.MediaContent{Data: "c2NyZWVuc2hvdA==", MimeType: "image/png"}},
+		{Type: tools.ContentBlockTypeResource, Resource: &tools.ResourceContent{URI: "https://example.com", MimeType: "text/html", Text: "<html>"}},
+	}, result.Contents)
+	assert.Equal(t, "Here is the page<html>", result.Output)
+}
+
 // callToolResult is a helper to build a CallToolResult from content blocks.
 func callToolResult(content ...mcp.Content) *mcp.CallToolResult {
 	return &mcp.CallToolResult{Content: content}
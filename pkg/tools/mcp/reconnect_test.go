@@ -290,6 +290,14 @@ func (m *failingInitClient) ListPrompts(context.Context, *gomcp.ListPromptsParam
 	return func(func(*gomcp.Prompt, error) bool) {}
 }
 
+func (m *failingInitClient) ListResources(context.Context, *gomcp.ListResourcesParams) iter.Seq2[*gomcp.Resource, error] {
+	return func(func(*gomcp.Resource, error) bool) {}
+}
+
+func (m *failingInitClient) ReadResource(context.Context, *gomcp.ReadResourceParams) (*gomcp.ReadResourceResult, error) {
+	return &gomcp.ReadResourceResult{}, nil
+}
+
 func (m *failingInitClient) GetPrompt(context.Context, *gomcp.GetPromptParams) (*gomcp.GetPromptResult, error) {
 	return &gomcp.GetPromptResult{}, nil
 }
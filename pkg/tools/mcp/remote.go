@@ -62,11 +62,6 @@ func (c *remoteMCPClient) Initialize(ctx context.Context, _ *gomcp.InitializeReq
 	}
 
 	// Create an MCP client with elicitation support
-	impl := &gomcp.Implementation{
-		Name:    "docker agent",
-		Version: "1.0.0",
-	}
-
 	toolChanged, promptChanged := c.notificationHandlers()
 
 	opts := &gomcp.ClientOptions{
@@ -75,7 +70,7 @@ func (c *remoteMCPClient) Initialize(ctx context.Context, _ *gomcp.InitializeReq
 		PromptListChangedHandler: promptChanged,
 	}
 
-	client := gomcp.NewClient(impl, opts)
+	client := c.newClient(opts)
 
 	// Connect to the MCP server
 	session, err := client.Connect(ctx, transport, nil)
@@ -0,0 +1,177 @@
+package mcp
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+const (
+	ToolNameListResources = "list_resources"
+	ToolNameReadResource  = "read_resource"
+)
+
+// defaultMaxResourceSize is the default number of bytes of text returned by
+// the read_resource tool.
+const defaultMaxResourceSize = 100 * 1024
+
+// ResourceInfo contains metadata about a resource exposed by an MCP server.
+type ResourceInfo struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ReadResourceArgs are the arguments of the read_resource tool.
+type ReadResourceArgs struct {
+	URI string `json:"uri" jsonschema:"The URI of the resource to read, as returned by the list_resources tool."`
+}
+
+// WithMaxResourceSize limits the number of bytes of text returned by the
+// read_resource tool. Longer resources are truncated.
+func WithMaxResourceSize(size int) ToolsetOption {
+	return func(ts *Toolset) {
+		ts.maxResourceSize = size
+	}
+}
+
+// HasResources reports whether the server advertised the resources
+// capability when it was started.
+func (ts *Toolset) HasResources() bool {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.started && ts.hasResources
+}
+
+// ListResources retrieves the resources exposed by the MCP server.
+func (ts *Toolset) ListResources(ctx context.Context) ([]ResourceInfo, error) {
+	if !ts.HasResources() {
+		return nil, errors.New("toolset not started or server has no resources")
+	}
+
+	var resources []ResourceInfo
+	for r, err := range ts.mcpClient.ListResources(ctx, &mcp.ListResourcesParams{}) {
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, ResourceInfo{
+			URI:         r.URI,
+			Name:        r.Name,
+			Description: r.Description,
+			MimeType:    r.MIMEType,
+		})
+	}
+
+	slog.Debug("Listed MCP resources", "count", len(resources), "server", ts.logID)
+	return resources, nil
+}
+
+// ReadResource reads the contents of a resource from the MCP server.
+func (ts *Toolset) ReadResource(ctx context.Context, uri string) (*mcp.ReadResourceResult, error) {
+	if !ts.HasResources() {
+		return nil, errors.New("toolset not started or server has no resources")
+	}
+
+	result, err := ts.mcpClient.ReadResource(ctx, &mcp.ReadResourceParams{URI: uri})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resource %s: %w", uri, err)
+	}
+	return result, nil
+}
+
+// resourceTools returns the tools to list and read the resources of the
+// server.
+func (ts *Toolset) resourceTools() []tools.Tool {
+	return []tools.Tool{
+		{
+			Name:        ts.toolName(ToolNameListResources),
+			Category:    "mcp",
+			Description: "List the resources (files, schemas, documents...) exposed by the MCP server. Returns their URI, name, description and MIME type.",
+			Parameters:  tools.MustSchemaFor[struct{}](),
+			Handler:     ts.listResourcesTool,
+			Annotations: tools.ToolAnnotations{
+				ReadOnlyHint: true,
+				Title:        "List Resources",
+			},
+		},
+		{
+			Name:        ts.toolName(ToolNameReadResource),
+			Category:    "mcp",
+			Description: "Read the content of a resource exposed by the MCP server.",
+			Parameters:  tools.MustSchemaFor[ReadResourceArgs](),
+			Handler:     tools.NewHandler(ts.readResourceTool),
+			Annotations: tools.ToolAnnotations{
+				ReadOnlyHint: true,
+				Title:        "Read Resource",
+			},
+		},
+	}
+}
+
+func (ts *Toolset) listResourcesTool(ctx context.Context, _ tools.ToolCall) (*tools.ToolCallResult, error) {
+	resources, err := ts.ListResources(ctx)
+	if err != nil {
+		return tools.ResultError(fmt.Sprintf("failed to list resources: %s", err)), nil
+	}
+	if len(resources) == 0 {
+		return tools.ResultSuccess("No resources available."), nil
+	}
+	return tools.ResultJSON(resources), nil
+}
+
+func (ts *Toolset) readResourceTool(ctx context.Context, args ReadResourceArgs) (*tools.ToolCallResult, error) {
+	if args.URI == "" {
+		return tools.ResultError("uri is required"), nil
+	}
+
+	result, err := ts.ReadResource(ctx, args.URI)
+	if err != nil {
+		return tools.ResultError(err.Error()), nil
+	}
+
+	return tools.ResultSuccess(formatResourceContents(result.Contents, ts.maxResourceSize)), nil
+}
+
+// formatResourceContents returns text contents inline, truncated to maxSize
+// bytes, and summarizes binary contents with their size and MIME type.
+func formatResourceContents(contents []*mcp.ResourceContents, maxSize int) string {
+	if maxSize <= 0 {
+		maxSize = defaultMaxResourceSize
+	}
+
+	var parts []string
+	for _, c := range contents {
+		if c == nil {
+			continue
+		}
+		switch {
+		case len(c.Blob) > 0:
+			parts = append(parts, fmt.Sprintf("[binary resource %s: %d bytes, %s]", c.URI, len(c.Blob), cmp.Or(c.MIMEType, "unknown type")))
+		case len(c.Text) > maxSize:
+			parts = append(parts, truncateText(c.Text, maxSize)+fmt.Sprintf("\n[truncated: resource %s is %d bytes, only the first %d are shown]", c.URI, len(c.Text), maxSize))
+		default:
+			parts = append(parts, c.Text)
+		}
+	}
+	if len(parts) == 0 {
+		return "The resource is empty."
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// truncateText cuts s to at most n bytes without splitting a rune.
+func truncateText(s string, n int) string {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,156 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	gomcp "github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// inMemoryClient connects to a server through the go-sdk in-memory transport.
+type inMemoryClient struct {
+	sessionClient
+
+	transport gomcp.Transport
+}
+
+func (c *inMemoryClient) Initialize(ctx context.Context, _ *gomcp.InitializeRequest) (*gomcp.InitializeResult, error) {
+	session, err := c.newClient(&gomcp.ClientOptions{}).Connect(ctx, c.transport, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.setSession(session)
+	return session.InitializeResult(), nil
+}
+
+// startInMemoryToolset starts a toolset connected to server.
+func startInMemoryToolset(t *testing.T, server *gomcp.Server, opts ...ToolsetOption) *Toolset {
+	t.Helper()
+
+	serverTransport, clientTransport := gomcp.NewInMemoryTransports()
+	_, err := server.Connect(t.Context(), serverTransport, nil)
+	require.NoError(t, err)
+
+	ts := &Toolset{
+		name:      "docs",
+		mcpClient: &inMemoryClient{transport: clientTransport},
+		logID:     "in-memory",
+	}
+	for _, opt := range opts {
+		opt(ts)
+	}
+	require.NoError(t, ts.Start(t.Context()))
+	t.Cleanup(func() { _ = ts.Stop(context.Background()) })
+	return ts
+}
+
+func newResourceServer() *gomcp.Server {
+	server := gomcp.NewServer(&gomcp.Implementation{Name: "docs", Version: "1.0.0"}, nil)
+	server.AddResource(&gomcp.Resource{
+		URI:         "file:///README.md",
+		Name:        "README",
+		Description: "Project readme",
+		MIMEType:    "text/markdown",
+	}, func(_ context.Context, req *gomcp.ReadResourceRequest) (*gomcp.ReadResourceResult, error) {
+		return &gomcp.ReadResourceResult{Contents: []*gomcp.ResourceContents{{
+			URI:      req.Params.URI,
+			MIMEType: "text/markdown",
+			Text:     "# Docs\nThe documentation of the project.",
+		}}}, nil
+	})
+	server.AddResource(&gomcp.Resource{
+		URI:      "file:///logo.png",
+		Name:     "logo",
+		MIMEType: "image/png",
+	}, func(_ context.Context, req *gomcp.ReadResourceRequest) (*gomcp.ReadResourceResult, error) {
+		return &gomcp.ReadResourceResult{Contents: []*gomcp.ResourceContents{{
+			URI:      req.Params.URI,
+			MIMEType: "image/png",
+			Blob:     make([]byte, 2048),
+		}}}, nil
+	})
+	return server
+}
+
+func findTool(t *testing.T, toolsList []tools.Tool, name string) tools.Tool {
+	t.Helper()
+	for _, tool := range toolsList {
+		if tool.Name == name {
+			return tool
+		}
+	}
+	require.Failf(t, "tool not found", "no tool named %s", name)
+	return tools.Tool{}
+}
+
+func callResourceTool(t *testing.T, tool tools.Tool, arguments string) *tools.ToolCallResult {
+	t.Helper()
+	result, err := tool.Handler(t.Context(), tools.ToolCall{Function: tools.FunctionCall{Name: tool.Name, Arguments: arguments}})
+	require.NoError(t, err)
+	return result
+}
+
+func TestResources_ListAndRead(t *testing.T) {
+	ts := startInMemoryToolset(t, newResourceServer())
+
+	toolsList, err := ts.Tools(t.Context())
+	require.NoError(t, err)
+
+	list := callResourceTool(t, findTool(t, toolsList, "docs_list_resources"), "")
+	require.False(t, list.IsError, list.Output)
+	assert.JSONEq(t, `[
+		{"uri":"file:///README.md","name":"README","description":"Project readme","mimeType":"text/markdown"},
+		{"uri":"file:///logo.png","name":"logo","mimeType":"image/png"}
+	]`, list.Output)
+
+	read := findTool(t, toolsList, "docs_read_resource")
+
+	text := callResourceTool(t, read, `{"uri":"file:///README.md"}`)
+	require.False(t, text.IsError, text.Output)
+	assert.Equal(t, "# Docs\nThe documentation of the project.", text.Output)
+
+	blob := callResourceTool(t, read, `{"uri":"file:///logo.png"}`)
+	require.False(t, blob.IsError, blob.Output)
+	assert.Equal(t, "[binary resource file:///logo.png: 2048 bytes, image/png]", blob.Output)
+
+	missing := callResourceTool(t, read, `{"uri":"file:///missing.txt"}`)
+	assert.True(t, missing.IsError)
+}
+
+func TestResources_MaxSize(t *testing.T) {
+	ts := startInMemoryToolset(t, newResourceServer(), WithMaxResourceSize(6))
+
+	toolsList, err := ts.Tools(t.Context())
+	require.NoError(t, err)
+
+	result := callResourceTool(t, findTool(t, toolsList, "docs_read_resource"), `{"uri":"file:///README.md"}`)
+	require.False(t, result.IsError, result.Output)
+	assert.True(t, strings.HasPrefix(result.Output, "# Docs\n[truncated: resource file:///README.md is 40 bytes"), result.Output)
+}
+
+func TestResources_NotAdvertised(t *testing.T) {
+	server := gomcp.NewServer(&gomcp.Implementation{Name: "tools-only", Version: "1.0.0"}, nil)
+	server.AddTool(&gomcp.Tool{
+		Name:        "echo",
+		InputSchema: map[string]any{"type": "object"},
+	}, func(context.Context, *gomcp.CallToolRequest) (*gomcp.CallToolResult, error) {
+		return &gomcp.CallToolResult{}, nil
+	})
+	ts := startInMemoryToolset(t, server)
+
+	toolsList, err := ts.Tools(t.Context())
+	require.NoError(t, err)
+	require.Len(t, toolsList, 1)
+	assert.Equal(t, "docs_echo", toolsList[0].Name)
+	assert.False(t, ts.HasResources())
+}
+
+func TestFormatResourceContents_TruncatesOnRuneBoundary(t *testing.T) {
+	out := formatResourceContents([]*gomcp.ResourceContents{{URI: "file:///a", Text: "héllo"}}, 2)
+	assert.True(t, strings.HasPrefix(out, "h\n[truncated"), out)
+}
@@ -0,0 +1,44 @@
+package mcp
+
+import (
+	"path/filepath"
+	"strings"
+
+	gomcp "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/docker/docker-agent/pkg/fileuri"
+)
+
+// ToolsetOption configures a Toolset.
+type ToolsetOption func(*Toolset)
+
+// WithRoots declares roots to the MCP server during initialization, so that
+// servers like filesystem or git know which directories they work on. Each
+// root is a directory, relative to the current directory, or a file:// URI.
+func WithRoots(roots ...string) ToolsetOption {
+	return func(ts *Toolset) {
+		if c, ok := ts.mcpClient.(interface{ setRoots([]*gomcp.Root) }); ok {
+			c.setRoots(toMCPRoots(roots))
+		}
+	}
+}
+
+func toMCPRoots(roots []string) []*gomcp.Root {
+	var mcpRoots []*gomcp.Root
+	for _, root := range roots {
+		if strings.HasPrefix(root, "file://") {
+			mcpRoots = append(mcpRoots, &gomcp.Root{URI: root})
+			continue
+		}
+
+		path, err := filepath.Abs(root)
+		if err != nil {
+			path = filepath.Clean(root)
+		}
+		mcpRoots = append(mcpRoots, &gomcp.Root{
+			URI:  fileuri.FromPath(path),
+			Name: filepath.Base(path),
+		})
+	}
+	return mcpRoots
+}
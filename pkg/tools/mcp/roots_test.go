@@ -0,0 +1,33 @@
+package mcp
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/fileuri"
+)
+
+func TestToMCPRoots(t *testing.T) {
+	dir := t.TempDir()
+
+	roots := toMCPRoots([]string{dir, "file:///srv/data"})
+
+	require.Len(t, roots, 2)
+	assert.Equal(t, fileuri.FromPath(dir), roots[0].URI)
+	assert.Equal(t, filepath.Base(dir), roots[0].Name)
+	assert.Equal(t, "file:///srv/data", roots[1].URI)
+	assert.Empty(t, roots[1].Name)
+}
+
+func TestWithRoots(t *testing.T) {
+	ts := NewToolsetCommand("", "echo", nil, nil, "", WithRoots("/workspace"))
+
+	client, ok := ts.mcpClient.(*stdioMCPClient)
+	require.True(t, ok)
+	require.Len(t, client.roots, 1)
+	assert.Equal(t, "file:///workspace", client.roots[0].URI)
+	assert.Equal(t, "workspace", client.roots[0].Name)
+}
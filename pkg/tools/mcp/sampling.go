@@ -0,0 +1,63 @@
+package mcp
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/docker/docker-agent/pkg/config/latest"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+// WithSampling lets the MCP server request completions from the model of
+// the agent with sampling/createMessage, following cfg. Without it, the
+// sampling capability isn't declared and the requests are rejected.
+func WithSampling(cfg latest.MCPSamplingConfig) ToolsetOption {
+	return func(ts *Toolset) {
+		ts.sampling = &cfg
+		if c, ok := ts.mcpClient.(interface{ setCreateMessageHandler(createMessageHandler) }); ok {
+			c.setCreateMessageHandler(ts.handleSamplingRequest)
+		}
+	}
+}
+
+// SetSamplingHandler sets the handler that runs the sampling requests the
+// server is allowed to make.
+func (ts *Toolset) SetSamplingHandler(handler tools.SamplingHandler) {
+	ts.samplingMu.Lock()
+	ts.samplingHandler = handler
+	ts.samplingMu.Unlock()
+}
+
+// handleSamplingRequest passes the sampling requests of the server to the
+// sampling handler, with the policy of the toolset. The number of tokens
+// they may generate is capped by the max_tokens of the policy.
+func (ts *Toolset) handleSamplingRequest(ctx context.Context, req *mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+	ts.samplingMu.RLock()
+	handler := ts.samplingHandler
+	ts.samplingMu.RUnlock()
+
+	if ts.sampling == nil || handler == nil {
+		return nil, errors.New("sampling is not available")
+	}
+	if req.Params == nil {
+		return nil, errors.New("missing sampling parameters")
+	}
+
+	params := *req.Params
+	if limit := ts.sampling.MaxTokens; limit > 0 && (params.MaxTokens <= 0 || params.MaxTokens > limit) {
+		params.MaxTokens = limit
+	}
+
+	slog.Debug("Received sampling request from MCP server", "server", ts.logID, "messages", len(params.Messages), "max_tokens", params.MaxTokens)
+
+	return handler(ctx, &tools.SamplingRequest{
+		Toolset: cmp.Or(ts.name, ts.logID),
+		Model:   ts.sampling.Model,
+		Confirm: ts.sampling.Confirm,
+		Params:  &params,
+	})
+}
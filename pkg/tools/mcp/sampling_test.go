@@ -0,0 +1,59 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/config/latest"
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+func TestWithSampling(t *testing.T) {
+	ts := NewToolsetCommand("", "echo", nil, nil, "")
+	client, ok := ts.mcpClient.(*stdioMCPClient)
+	require.True(t, ok)
+	assert.Nil(t, client.createMessageHandler, "sampling must be opted into")
+
+	ts = NewToolsetCommand("", "echo", nil, nil, "", WithSampling(latest.MCPSamplingConfig{}))
+	client, ok = ts.mcpClient.(*stdioMCPClient)
+	require.True(t, ok)
+	assert.NotNil(t, client.createMessageHandler)
+}
+
+func TestHandleSamplingRequest(t *testing.T) {
+	ts := NewToolsetCommand("scraper", "echo", nil, nil, "", WithSampling(latest.MCPSamplingConfig{
+		Model:     "openai/gpt-4o-mini",
+		MaxTokens: 500,
+		Confirm:   true,
+	}))
+
+	req := &mcp.CreateMessageRequest{Params: &mcp.CreateMessageParams{
+		MaxTokens: 4000,
+		Messages:  []*mcp.SamplingMessage{{Role: "user", Content: &mcp.TextContent{Text: "Summarize"}}},
+	}}
+
+	// Without a handler, the requests are rejected.
+	_, err := ts.handleSamplingRequest(t.Context(), req)
+	require.Error(t, err)
+
+	var received *tools.SamplingRequest
+	ts.SetSamplingHandler(func(_ context.Context, req *tools.SamplingRequest) (*mcp.CreateMessageResult, error) {
+		received = req
+		return &mcp.CreateMessageResult{Content: &mcp.TextContent{Text: "summary"}, Model: req.Model, Role: "assistant"}, nil
+	})
+
+	result, err := ts.handleSamplingRequest(t.Context(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "openai/gpt-4o-mini", result.Model)
+
+	require.NotNil(t, received)
+	assert.Equal(t, "scraper", received.Toolset)
+	assert.True(t, received.Confirm)
+	assert.Equal(t, int64(500), received.Params.MaxTokens)
+	// The request of the server isn't changed.
+	assert.Equal(t, int64(4000), req.Params.MaxTokens)
+}
@@ -22,7 +22,9 @@ type sessionClient struct {
 	toolListChangedHandler   func()
 	promptListChangedHandler func()
 	elicitationHandler       tools.ElicitationHandler
+	createMessageHandler     createMessageHandler
 	oauthSuccessHandler      func()
+	roots                    []*gomcp.Root
 	mu                       sync.RWMutex
 }
 
@@ -40,6 +42,45 @@ func (c *sessionClient) getSession() *gomcp.ClientSession {
 	return c.session
 }
 
+// setRoots sets the roots declared to the server by the next Initialize.
+func (c *sessionClient) setRoots(roots []*gomcp.Root) {
+	c.mu.Lock()
+	c.roots = roots
+	c.mu.Unlock()
+}
+
+// createMessageHandler handles the sampling requests of the MCP server.
+type createMessageHandler func(context.Context, *gomcp.CreateMessageRequest) (*gomcp.CreateMessageResult, error)
+
+// setCreateMessageHandler sets the handler of the sampling requests. The
+// sampling capability is only declared, by the next Initialize, when set.
+func (c *sessionClient) setCreateMessageHandler(handler createMessageHandler) {
+	c.mu.Lock()
+	c.createMessageHandler = handler
+	c.mu.Unlock()
+}
+
+// newClient creates an MCP client that declares the roots and, if there's a
+// sampling handler, the sampling capability.
+func (c *sessionClient) newClient(opts *gomcp.ClientOptions) *gomcp.Client {
+	c.mu.RLock()
+	roots := c.roots
+	createMessage := c.createMessageHandler
+	c.mu.RUnlock()
+
+	if createMessage != nil {
+		opts.CreateMessageHandler = createMessage
+	}
+
+	client := gomcp.NewClient(&gomcp.Implementation{
+		Name:    "docker agent",
+		Version: "1.0.0",
+	}, opts)
+	client.AddRoots(roots...)
+
+	return client
+}
+
 // notificationHandlers returns ToolListChanged and PromptListChanged closures
 // suitable for gomcp.ClientOptions. They read the registered handler under the
 // read lock and invoke it if non-nil.
@@ -124,6 +165,22 @@ func (c *sessionClient) GetPrompt(ctx context.Context, request *gomcp.GetPromptP
 	return nil, errors.New("session not initialized")
 }
 
+func (c *sessionClient) ListResources(ctx context.Context, request *gomcp.ListResourcesParams) iter.Seq2[*gomcp.Resource, error] {
+	if s := c.getSession(); s != nil {
+		return s.Resources(ctx, request)
+	}
+	return func(yield func(*gomcp.Resource, error) bool) {
+		yield(nil, errors.New("session not initialized"))
+	}
+}
+
+func (c *sessionClient) ReadResource(ctx context.Context, request *gomcp.ReadResourceParams) (*gomcp.ReadResourceResult, error) {
+	if s := c.getSession(); s != nil {
+		return s.ReadResource(ctx, request)
+	}
+	return nil, errors.New("session not initialized")
+}
+
 // handleElicitationRequest forwards incoming elicitation requests from the MCP
 // server to the registered handler. It is used as the gomcp ElicitationHandler
 // callback for both stdio and remote clients.
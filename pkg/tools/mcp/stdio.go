@@ -3,12 +3,15 @@ package mcp
 import (
 	"context"
 	"errors"
+	"os"
 	"os/exec"
 	"runtime"
 
 	gomcp "github.com/modelcontextprotocol/go-sdk/mcp"
 
 	"github.com/docker/docker-agent/pkg/desktop"
+	"github.com/docker/docker-agent/pkg/environment"
+	"github.com/docker/docker-agent/pkg/procgroup"
 )
 
 type stdioMCPClient struct {
@@ -18,6 +21,22 @@ type stdioMCPClient struct {
 	args    []string
 	env     []string
 	cwd     string
+
+	// process is the server's process, the leader of its process group.
+	// It is guarded by the sessionClient's mu.
+	process *os.Process
+}
+
+// WithEnvPassthrough scopes the environment of the MCP server's process to
+// the toolset's env and the variables of the process environment matching
+// one of the patterns, variable names or globs such as "AWS_*". It only
+// applies to toolsets that run a command.
+func WithEnvPassthrough(patterns ...string) ToolsetOption {
+	return func(ts *Toolset) {
+		if c, ok := ts.mcpClient.(*stdioMCPClient); ok {
+			c.env = append(environment.Filter(os.Environ(), patterns), c.env...)
+		}
+	}
 }
 
 func newStdioCmdClient(command string, args, env []string, cwd string) *stdioMCPClient {
@@ -36,6 +55,9 @@ func (c *stdioMCPClient) Initialize(ctx context.Context, _ *gomcp.InitializeRequ
 		return nil, errors.New("Docker Desktop is not running") //nolint:staticcheck // Don't lowercase Docker Desktop
 	}
 
+	// Whatever is left of a previous server, after a restart, is stopped.
+	c.terminateProcessGroup()
+
 	toolChanged, promptChanged := c.notificationHandlers()
 
 	// Create client options with elicitation and notification support
@@ -45,22 +67,51 @@ func (c *stdioMCPClient) Initialize(ctx context.Context, _ *gomcp.InitializeRequ
 		PromptListChangedHandler: promptChanged,
 	}
 
-	client := gomcp.NewClient(&gomcp.Implementation{
-		Name:    "docker agent",
-		Version: "1.0.0",
-	}, opts)
+	client := c.newClient(opts)
 
+	// The server runs in its own process group so that closing the session
+	// also stops the processes it spawned, e.g. the node process of npx.
 	cmd := exec.CommandContext(ctx, c.command, c.args...)
 	cmd.Env = c.env
 	cmd.Dir = c.cwd
+	procgroup.Setup(cmd)
 	session, err := client.Connect(ctx, &gomcp.CommandTransport{
-		Command: cmd,
+		Command:           cmd,
+		TerminateDuration: procgroup.GracePeriod,
 	}, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	c.setSession(session)
+	c.setProcess(cmd.Process)
 
 	return session.InitializeResult(), nil
 }
+
+// Close closes the session, which stops the server, and terminates the
+// processes the server spawned that are still running.
+func (c *stdioMCPClient) Close(ctx context.Context) error {
+	err := c.sessionClient.Close(ctx)
+	c.terminateProcessGroup()
+	return err
+}
+
+func (c *stdioMCPClient) setProcess(proc *os.Process) {
+	c.mu.Lock()
+	c.process = proc
+	c.mu.Unlock()
+}
+
+// terminateProcessGroup terminates the process group of the server, if it
+// was started.
+func (c *stdioMCPClient) terminateProcessGroup() {
+	c.mu.Lock()
+	proc := c.process
+	c.process = nil
+	c.mu.Unlock()
+
+	if proc != nil {
+		_ = procgroup.Terminate(proc)
+	}
+}
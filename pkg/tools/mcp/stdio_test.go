@@ -0,0 +1,30 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithEnvPassthrough(t *testing.T) {
+	t.Setenv("MCP_SCOPED_A", "a")
+	t.Setenv("MCP_LEAKED_SECRET", "secret")
+
+	ts := NewToolsetCommand("", "echo", nil, []string{"EXPLICIT=1"}, "", WithEnvPassthrough("MCP_SCOPED_*"))
+
+	client, ok := ts.mcpClient.(*stdioMCPClient)
+	require.True(t, ok)
+	assert.Equal(t, []string{"MCP_SCOPED_A=a", "EXPLICIT=1"}, client.env)
+}
+
+func TestWithEnvPassthrough_Nothing(t *testing.T) {
+	t.Setenv("MCP_LEAKED_SECRET", "secret")
+
+	ts := NewToolsetCommand("", "echo", nil, nil, "", WithEnvPassthrough())
+
+	client, ok := ts.mcpClient.(*stdioMCPClient)
+	require.True(t, ok)
+	assert.NotNil(t, client.env, "an empty environment must not inherit the process environment")
+	assert.Empty(t, client.env)
+}
@@ -0,0 +1,121 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// TruncatePolicy is how a tool output longer than its limit is truncated
+// before being sent to the model.
+type TruncatePolicy string
+
+const (
+	// TruncateHead keeps the beginning of the output.
+	TruncateHead TruncatePolicy = "head"
+	// TruncateTail keeps the end of the output.
+	TruncateTail TruncatePolicy = "tail"
+	// TruncateHeadTail keeps both ends of the output.
+	TruncateHeadTail TruncatePolicy = "headtail"
+)
+
+// Valid reports whether p is a known policy.
+func (p TruncatePolicy) Valid() bool {
+	switch p {
+	case TruncateHead, TruncateTail, TruncateHeadTail:
+		return true
+	default:
+		return false
+	}
+}
+
+// OutputLimit limits the size of the tool outputs sent to the model.
+// A MaxBytes of zero or less means no limit.
+type OutputLimit struct {
+	MaxBytes int
+	Policy   TruncatePolicy
+}
+
+// OutputTruncation records how a tool output was truncated.
+type OutputTruncation struct {
+	Policy        TruncatePolicy `json:"policy"`
+	OriginalBytes int            `json:"original_bytes"`
+	KeptBytes     int            `json:"kept_bytes"`
+}
+
+// Truncate returns output truncated to the limit, with a marker where it was
+// cut, and how it was truncated. It returns output unchanged, and a nil
+// truncation, when it fits. Cuts are made at line boundaries when that keeps
+// at least half of what could be kept, and never split a UTF-8 character.
+func (l OutputLimit) Truncate(output string) (string, *OutputTruncation) {
+	if l.MaxBytes <= 0 || len(output) <= l.MaxBytes {
+		return output, nil
+	}
+
+	policy := l.Policy
+	if !policy.Valid() {
+		policy = TruncateHeadTail
+	}
+
+	var head, tail string
+	switch policy {
+	case TruncateHead:
+		head = keepHead(output, l.MaxBytes)
+	case TruncateTail:
+		tail = keepTail(output, l.MaxBytes)
+	default:
+		head = keepHead(output, l.MaxBytes/2)
+		tail = keepTail(output, l.MaxBytes-len(head))
+	}
+
+	cut := len(output) - len(head) - len(tail)
+	marker := fmt.Sprintf("[... %d of %d bytes truncated. Refine the request (e.g. a narrower search, a filter or a smaller range) to see the rest ...]", cut, len(output))
+
+	var b strings.Builder
+	if head != "" {
+		b.WriteString(strings.TrimRight(head, "\n"))
+		b.WriteString("\n\n")
+	}
+	b.WriteString(marker)
+	if tail != "" {
+		b.WriteString("\n\n")
+		b.WriteString(strings.TrimLeft(tail, "\n"))
+	}
+
+	return b.String(), &OutputTruncation{
+		Policy:        policy,
+		OriginalBytes: len(output),
+		KeptBytes:     len(head) + len(tail),
+	}
+}
+
+// keepHead returns the longest prefix of s of at most n bytes.
+func keepHead(s string, n int) string {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	head := s[:n]
+	if s[n] == '\n' {
+		return head
+	}
+	if i := strings.LastIndexByte(head, '\n'); i >= n/2 {
+		head = head[:i+1]
+	}
+	return head
+}
+
+// keepTail returns the longest suffix of s of at most n bytes.
+func keepTail(s string, n int) string {
+	start := len(s) - n
+	for start < len(s) && !utf8.RuneStart(s[start]) {
+		start++
+	}
+	tail := s[start:]
+	if start > 0 && s[start-1] == '\n' {
+		return tail
+	}
+	if i := strings.IndexByte(tail, '\n'); i >= 0 && i < len(tail)/2 {
+		tail = tail[i+1:]
+	}
+	return tail
+}
@@ -0,0 +1,84 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func numberedLines(n int) string {
+	var b strings.Builder
+	for i := range n {
+		fmt.Fprintf(&b, "line %02d\n", i)
+	}
+	return b.String()
+}
+
+func TestOutputLimit_Truncate(t *testing.T) {
+	output := numberedLines(20) // 160 bytes
+
+	tests := []struct {
+		policy TruncatePolicy
+		want   string
+	}{
+		{
+			policy: TruncateHead,
+			want:   "line 00\nline 01\nline 02\nline 03\nline 04\n\n[... 120 of 160 bytes truncated.",
+		},
+		{
+			policy: TruncateTail,
+			want:   "[... 120 of 160 bytes truncated.",
+		},
+		{
+			policy: TruncateHeadTail,
+			want:   "line 00\nline 01\n\n[... 120 of 160 bytes truncated.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			got, truncation := OutputLimit{MaxBytes: 40, Policy: tt.policy}.Truncate(output)
+			require.NotNil(t, truncation)
+			assert.Equal(t, OutputTruncation{Policy: tt.policy, OriginalBytes: 160, KeptBytes: 40}, *truncation)
+			assert.True(t, strings.HasPrefix(got, tt.want), "got %q", got)
+			assert.Contains(t, got, "Refine the request")
+		})
+	}
+
+	got, _ := OutputLimit{MaxBytes: 40, Policy: TruncateTail}.Truncate(output)
+	assert.True(t, strings.HasSuffix(got, "\n\nline 15\nline 16\nline 17\nline 18\nline 19\n"), "got %q", got)
+
+	got, _ = OutputLimit{MaxBytes: 40, Policy: TruncateHeadTail}.Truncate(output)
+	assert.True(t, strings.HasSuffix(got, "\n\nline 17\nline 18\nline 19\n"), "got %q", got)
+}
+
+func TestOutputLimit_TruncateWithinLimit(t *testing.T) {
+	got, truncation := OutputLimit{MaxBytes: 5, Policy: TruncateHead}.Truncate("short")
+	assert.Equal(t, "short", got)
+	assert.Nil(t, truncation)
+
+	got, truncation = OutputLimit{}.Truncate(numberedLines(1000))
+	assert.Equal(t, numberedLines(1000), got, "no limit")
+	assert.Nil(t, truncation)
+}
+
+func TestOutputLimit_TruncateKeepsRunes(t *testing.T) {
+	got, truncation := OutputLimit{MaxBytes: 5, Policy: TruncateHead}.Truncate("héllo wörld")
+	require.NotNil(t, truncation)
+	assert.True(t, strings.HasPrefix(got, "héll\n"), "got %q", got)
+	assert.Equal(t, 5, truncation.KeptBytes)
+
+	got, truncation = OutputLimit{MaxBytes: 3, Policy: TruncateTail}.Truncate("héllo wörld")
+	require.NotNil(t, truncation)
+	assert.True(t, strings.HasSuffix(got, "\nrld"), "got %q", got)
+	assert.Equal(t, 3, truncation.KeptBytes)
+}
+
+func TestOutputLimit_UnknownPolicy(t *testing.T) {
+	_, truncation := OutputLimit{MaxBytes: 40, Policy: "middle"}.Truncate(numberedLines(20))
+	require.NotNil(t, truncation)
+	assert.Equal(t, TruncateHeadTail, truncation.Policy)
+}
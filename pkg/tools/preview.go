@@ -0,0 +1,187 @@
+package tools
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxPreviewArgsSize is the size above which the arguments of a tool call
+// aren't previewed anymore. The arguments are previewed on every streamed
+// delta, so this bounds the work done for a single tool call.
+const MaxPreviewArgsSize = 32 << 10
+
+// PreviewArgs returns the arguments of a tool call while they are being
+// streamed, so that they can be displayed before the call is complete. It
+// reports whether the arguments are complete. A string value being streamed
+// is included with what was received so far, while a key without a value
+// is left out. It returns nil if the arguments aren't the beginning of a
+// JSON object, or are larger than MaxPreviewArgsSize.
+func PreviewArgs(partial string) (map[string]any, bool) {
+	repaired, complete := RepairArgs(partial)
+	if repaired == "" {
+		return nil, false
+	}
+
+	var args map[string]any
+	if err := json.Unmarshal([]byte(repaired), &args); err != nil {
+		return nil, false
+	}
+	return args, complete
+}
+
+// RepairArgs closes the unterminated strings, arrays and objects of the
+// partial JSON arguments of a tool call, and drops what can't be closed:
+// a trailing comma, a key without a value or an unfinished literal. It
+// keeps the order of the keys, unlike PreviewArgs. It reports whether the
+// arguments were already complete, and returns "" if they aren't the
+// beginning of a JSON object, or are larger than MaxPreviewArgsSize.
+func RepairArgs(partial string) (string, bool) {
+	if len(partial) > MaxPreviewArgsSize {
+		return "", false
+	}
+
+	start := strings.IndexByte(partial, '{')
+	if start < 0 || strings.TrimSpace(partial[:start]) != "" {
+		return "", false
+	}
+
+	var (
+		stackBuf [16]byte
+		// stack holds the closing delimiters of the open arrays and objects.
+		stack = stackBuf[:0]
+		// safe is where the arguments can be cut and closed.
+		safe      = start
+		expectKey bool
+
+		inString, isKey, escaped bool
+		stringStart, escapeStart int
+
+		inToken    bool
+		tokenStart int
+	)
+
+	for i := start; i < len(partial); i++ {
+		c := partial[i]
+
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+				escapeStart = i
+			case c == '"':
+				inString = false
+				if !isKey {
+					safe = i + 1
+				}
+			}
+			continue
+		}
+
+		if inToken {
+			if !isTokenEnd(c) {
+				continue
+			}
+			inToken = false
+			if !isScalar(partial[tokenStart:i]) {
+				return "", false
+			}
+			safe = i
+		}
+
+		switch c {
+		case '{':
+			stack = append(stack, '}')
+			expectKey = true
+			safe = i + 1
+		case '[':
+			stack = append(stack, ']')
+			expectKey = false
+			safe = i + 1
+		case '}', ']':
+			if len(stack) == 0 || stack[len(stack)-1] != c {
+				return "", false
+			}
+			stack = stack[:len(stack)-1]
+			expectKey = false
+			safe = i + 1
+			if len(stack) == 0 {
+				return partial[start : i+1], strings.TrimSpace(partial[i+1:]) == ""
+			}
+		case ',':
+			expectKey = stack[len(stack)-1] == '}'
+		case ':':
+			expectKey = false
+		case '"':
+			inString = true
+			stringStart = i
+			isKey = expectKey
+			expectKey = false
+		case ' ', '\t', '\n', '\r':
+		default:
+			inToken = true
+			tokenStart = i
+		}
+	}
+
+	end := safe
+	var closeString bool
+	switch {
+	case inString && !isKey:
+		// Show the string value being streamed, without its unfinished
+		// escape sequence or character.
+		end = len(partial)
+		if escapeStart > stringStart && (escaped || partial[escapeStart+1] == 'u' && end-escapeStart < 6) {
+			end = escapeStart
+		}
+		end = trimPartialRune(partial, end)
+		closeString = true
+	case inToken && isScalar(partial[tokenStart:]):
+		end = len(partial)
+	}
+
+	var repaired strings.Builder
+	repaired.Grow(end - start + len(stack) + 1)
+	repaired.WriteString(partial[start:end])
+	if closeString {
+		repaired.WriteByte('"')
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		repaired.WriteByte(stack[i])
+	}
+	return repaired.String(), false
+}
+
+func isTokenEnd(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r', ',', ':', '}', ']':
+		return true
+	default:
+		return false
+	}
+}
+
+// isScalar reports whether token is a JSON number or literal.
+func isScalar(token string) bool {
+	switch token {
+	case "true", "false", "null":
+		return true
+	}
+	return json.Valid([]byte(token))
+}
+
+// trimPartialRune returns end, moved back before the last character of
+// s[:end] if it's incomplete.
+func trimPartialRune(s string, end int) int {
+	for i := end - 1; i >= 0 && i >= end-utf8.UTFMax; i-- {
+		if utf8.RuneStart(s[i]) {
+			if !utf8.FullRuneInString(s[i:end]) {
+				return i
+			}
+			break
+		}
+	}
+	return end
+}
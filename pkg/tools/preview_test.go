@@ -0,0 +1,79 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepairArgs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		partial  string
+		want     string
+		complete bool
+	}{
+		{name: "empty", partial: "", want: ""},
+		{name: "not an object", partial: `["a"]`, want: ""},
+		{name: "opening brace", partial: "{", want: "{}"},
+		{name: "key being streamed", partial: `{"pa`, want: "{}"},
+		{name: "key without value", partial: `{"path":`, want: "{}"},
+		{name: "string value being streamed", partial: `{"path": "/tmp/foo", "content": "hel`, want: `{"path": "/tmp/foo", "content": "hel"}`},
+		{name: "trailing comma", partial: `{"path": "/tmp/foo",`, want: `{"path": "/tmp/foo"}`},
+		{name: "number", partial: `{"line": 12`, want: `{"line": 12}`},
+		{name: "unfinished number", partial: `{"line": 1.`, want: `{}`},
+		{name: "unfinished literal", partial: `{"force": tru`, want: `{}`},
+		{name: "literal", partial: `{"force": true`, want: `{"force": true}`},
+		{name: "nested", partial: `{"edits": [{"old": "a", "new": "b`, want: `{"edits": [{"old": "a", "new": "b"}]}`},
+		{name: "nested key without value", partial: `{"edits": [{"old": null, "new":`, want: `{"edits": [{"old": null}]}`},
+		{name: "escaped quote", partial: `{"msg": "hello \"world`, want: `{"msg": "hello \"world"}`},
+		{name: "unfinished escape", partial: `{"msg": "hello\`, want: `{"msg": "hello"}`},
+		{name: "unfinished unicode escape", partial: `{"msg": "caf\u00`, want: `{"msg": "caf"}`},
+		{name: "unfinished character", partial: `{"msg": "caf` + "\xc3", want: `{"msg": "caf"}`},
+		{name: "complete", partial: ` {"path": "/tmp/foo"} `, want: `{"path": "/tmp/foo"}`, complete: true},
+		{name: "content after the object", partial: `{"path": "/tmp/foo"} {`, want: `{"path": "/tmp/foo"}`},
+		{name: "mismatched delimiters", partial: `{"paths": ["a"}`, want: ""},
+		{name: "too large", partial: `{"content": "` + strings.Repeat("a", MaxPreviewArgsSize), want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, complete := RepairArgs(tt.partial)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.complete, complete)
+		})
+	}
+}
+
+func TestPreviewArgs(t *testing.T) {
+	t.Parallel()
+
+	args, complete := PreviewArgs(`{"path": "/tmp/foo", "content": "hel`)
+	assert.Equal(t, map[string]any{"path": "/tmp/foo", "content": "hel"}, args)
+	assert.False(t, complete)
+
+	args, complete = PreviewArgs(`{"path": "/tmp/foo", "lines": [1, 2]}`)
+	assert.Equal(t, map[string]any{"path": "/tmp/foo", "lines": []any{1.0, 2.0}}, args)
+	assert.True(t, complete)
+
+	args, complete = PreviewArgs(`not json`)
+	assert.Nil(t, args)
+	assert.False(t, complete)
+}
+
+// BenchmarkPreviewArgs previews the arguments of a tool call on every
+// delta, as they are streamed.
+func BenchmarkPreviewArgs(b *testing.B) {
+	arguments := `{"path": "/tmp/foo.go", "content": "` + strings.Repeat(`package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n`, 40) + `"}`
+
+	for b.Loop() {
+		for end := 8; end <= len(arguments); end += 8 {
+			PreviewArgs(arguments[:end])
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package tools
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DefaultRedactionReplacement replaces the secrets found in tool outputs.
+// {type} stands for the type of the secret, e.g. "github_token".
+const DefaultRedactionReplacement = "[REDACTED:{type}]"
+
+// SecretPattern detects one type of secret in tool outputs. When Regexp has
+// a capture group, only the text of the first one is the secret, e.g. the
+// value of "password=(\S+)".
+type SecretPattern struct {
+	Type   string
+	Regexp *regexp.Regexp
+}
+
+// BuiltinSecretPatterns detect the common token formats.
+var BuiltinSecretPatterns = []SecretPattern{
+	{
+		Type:   "private_key",
+		Regexp: regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY(?: BLOCK)?-----[\s\S]*?-----END [A-Z0-9 ]*PRIVATE KEY(?: BLOCK)?-----`),
+	},
+	{
+		Type:   "aws_access_key",
+		Regexp: regexp.MustCompile(`\b(?:AKIA|ASIA|AROA|AIDA)[0-9A-Z]{16}\b`),
+	},
+	{
+		Type:   "aws_secret_key",
+		Regexp: regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+]{40})\b`),
+	},
+	{
+		Type:   "github_token",
+		Regexp: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b`),
+	},
+	{
+		Type:   "jwt",
+		Regexp: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`),
+	},
+}
+
+// Redactor masks the secrets in tool outputs before they're sent to the
+// model and stored in the session. The zero value, and a nil Redactor,
+// mask nothing.
+type Redactor struct {
+	patterns    []SecretPattern
+	replacement string
+}
+
+// NewRedactor returns a Redactor masking the secrets matching the built-in
+// patterns, when builtin is true, and patterns. The secrets are replaced
+// with replacement, DefaultRedactionReplacement when empty.
+func NewRedactor(builtin bool, patterns []SecretPattern, replacement string) *Redactor {
+	var all []SecretPattern
+	if builtin {
+		all = append(all, BuiltinSecretPatterns...)
+	}
+	all = append(all, patterns...)
+
+	if replacement == "" {
+		replacement = DefaultRedactionReplacement
+	}
+
+	return &Redactor{
+		patterns:    all,
+		replacement: replacement,
+	}
+}
+
+// DefaultRedactor returns a Redactor masking the secrets matching the
+// built-in patterns.
+func DefaultRedactor() *Redactor {
+	return NewRedactor(true, nil, "")
+}
+
+// Redactions counts the secrets masked in an output, by type.
+type Redactions map[string]int
+
+// Total returns the number of secrets masked.
+func (r Redactions) Total() int {
+	total := 0
+	for _, n := range r {
+		total += n
+	}
+	return total
+}
+
+// Redact returns s with the secrets masked, and how many of each type were.
+// Redactions is nil when nothing was masked.
+func (r *Redactor) Redact(s string) (string, Redactions) {
+	if r == nil {
+		return s, nil
+	}
+
+	var redactions Redactions
+	for _, pattern := range r.patterns {
+		var n int
+		s, n = r.redactPattern(s, pattern)
+		if n > 0 {
+			if redactions == nil {
+				redactions = Redactions{}
+			}
+			redactions[pattern.Type] += n
+		}
+	}
+	return s, redactions
+}
+
+// redactPattern replaces the matches of pattern in s, or of its first
+// capture group if it has one, and returns how many were replaced.
+func (r *Redactor) redactPattern(s string, pattern SecretPattern) (string, int) {
+	matches := pattern.Regexp.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s, 0
+	}
+
+	replacement := strings.ReplaceAll(r.replacement, "{type}", pattern.Type)
+
+	var b strings.Builder
+	last, n := 0, 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if len(m) >= 4 {
+			start, end = m[2], m[3]
+		}
+		if start < 0 || start == end {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(replacement)
+		last = end
+		n++
+	}
+	b.WriteString(s[last:])
+	return b.String(), n
+}
+
+// RedactResult returns a copy of res with the secrets masked in its output,
+// text contents and structured content, and how many of each type were.
+// The contents are usually rendered in the output: the secrets are counted
+// in the output, or in the rest of the result when the output has none.
+// It returns res itself when nothing was masked.
+func (r *Redactor) RedactResult(res *ToolCallResult) (*ToolCallResult, Redactions) {
+	if r == nil || res == nil || len(r.patterns) == 0 {
+		return res, nil
+	}
+
+	redacted := *res
+	var redactions, others Redactions
+	redacted.Output, redactions = r.Redact(res.Output)
+
+	redact := func(s string) string {
+		s, found := r.Redact(s)
+		for typ, n := range found {
+			if others == nil {
+				others = Redactions{}
+			}
+			others[typ] += n
+		}
+		return s
+	}
+	if len(res.Contents) > 0 {
+		redacted.Contents = make([]ContentBlock, len(res.Contents))
+		for i, block := range res.Contents {
+			block.Text = redact(block.Text)
+			if block.Resource != nil {
+				resource := *block.Resource
+				resource.Text = redact(resource.Text)
+				block.Resource = &resource
+			}
+			redacted.Contents[i] = block
+		}
+	}
+	redacted.StructuredContent = redactStrings(res.StructuredContent, redact)
+
+	if redactions == nil {
+		redactions = others
+	}
+	if redactions == nil {
+		return res, nil
+	}
+	return &redacted, redactions
+}
+
+// redactStrings returns a copy of v, a JSON-decoded value, with redact
+// applied to its strings.
+func redactStrings(v any, redact func(string) string) any {
+	switch v := v.(type) {
+	case string:
+		return redact(v)
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, value := range v {
+			out[key] = redactStrings(value, redact)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, value := range v {
+			out[i] = redactStrings(value, redact)
+		}
+		return out
+	default:
+		return v
+	}
+}
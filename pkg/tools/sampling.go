@@ -0,0 +1,30 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// SamplingRequest is a request of an MCP server to sample the model of the
+// agent, along with the policy of its toolset.
+type SamplingRequest struct {
+	// Toolset is the name of the toolset of the server, or how it's
+	// started when it has no name.
+	Toolset string
+	// Model runs the request instead of the model of the agent, if set.
+	Model string
+	// Confirm asks the user before running the request.
+	Confirm bool
+
+	Params *mcp.CreateMessageParams
+}
+
+// SamplingHandler runs the sampling requests of MCP servers.
+// This allows the runtime to run them against the models of its agents.
+type SamplingHandler func(ctx context.Context, req *SamplingRequest) (*mcp.CreateMessageResult, error)
+
+// Samplable is implemented by toolsets whose servers can request sampling.
+type Samplable interface {
+	SetSamplingHandler(handler SamplingHandler)
+}
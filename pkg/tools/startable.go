@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 )
 
 // Describer can be implemented by a ToolSet to provide a short, user-visible
@@ -28,6 +29,34 @@ func DescribeToolSet(ts ToolSet) string {
 	return fmt.Sprintf("%T", ts)
 }
 
+// Namer is implemented by toolsets that have a user-defined name, e.g. the
+// name given to an MCP toolset in the configuration.
+type Namer interface {
+	Name() string
+}
+
+// ToolSetName returns the user-defined name of ts, or "" if it has none.
+func ToolSetName(ts ToolSet) string {
+	if n, ok := As[Namer](ts); ok {
+		return n.Name()
+	}
+	return ""
+}
+
+// Typer is implemented by toolsets that know the type they were configured
+// with, e.g. "filesystem" or "mcp".
+type Typer interface {
+	ToolSetType() string
+}
+
+// ToolSetType returns the type ts was configured with, or "" if it's unknown.
+func ToolSetType(ts ToolSet) string {
+	if t, ok := As[Typer](ts); ok {
+		return t.ToolSetType()
+	}
+	return ""
+}
+
 // StartableToolSet wraps a ToolSet with lazy, single-flight start semantics.
 // This is the canonical way to manage toolset lifecycle.
 type StartableToolSet struct {
@@ -35,6 +64,10 @@ type StartableToolSet struct {
 
 	mu      sync.Mutex
 	started bool
+
+	// generation is bumped whenever the toolset is started, stopped or
+	// invalidated: its tools may have changed.
+	generation atomic.Uint64
 }
 
 // NewStartable wraps a ToolSet for lazy initialization.
@@ -68,6 +101,7 @@ func (s *StartableToolSet) Start(ctx context.Context) error {
 		}
 	}
 	s.started = true
+	s.generation.Add(1)
 	return nil
 }
 
@@ -78,12 +112,25 @@ func (s *StartableToolSet) Stop(ctx context.Context) error {
 	defer s.mu.Unlock()
 
 	s.started = false
+	s.generation.Add(1)
 	if startable, ok := As[Startable](s.ToolSet); ok {
 		return startable.Stop(ctx)
 	}
 	return nil
 }
 
+// Invalidate tells the users caching the tools of the toolset that they
+// changed, for toolsets that can't report it themselves with Versioned.
+func (s *StartableToolSet) Invalidate() {
+	s.generation.Add(1)
+}
+
+// ToolsVersion changes whenever the toolset is started, stopped or
+// invalidated, or when the version of the underlying toolset changes.
+func (s *StartableToolSet) ToolsVersion() uint64 {
+	return s.generation.Load() + ToolsVersion(s.ToolSet)
+}
+
 // Unwrap returns the underlying ToolSet.
 func (s *StartableToolSet) Unwrap() ToolSet {
 	return s.ToolSet
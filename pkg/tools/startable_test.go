@@ -57,3 +57,47 @@ func TestDescribeToolSet_UnwrapsStartableAndFallsBackToTypeName(t *testing.T) {
 	wrapped := tools.NewStartable(inner)
 	assert.Check(t, is.Equal(tools.DescribeToolSet(wrapped), "*tools_test.stubToolSet"))
 }
+
+// stubVersioned implements ToolSet and Versioned.
+type stubVersioned struct{ version uint64 }
+
+func (s *stubVersioned) Tools(context.Context) ([]tools.Tool, error) { return nil, nil }
+func (s *stubVersioned) ToolsVersion() uint64                        { return s.version }
+
+func TestStartableToolSet_ToolsVersion(t *testing.T) {
+	t.Parallel()
+
+	inner := &stubVersioned{}
+	wrapped := tools.NewStartable(inner)
+
+	seen := map[uint64]bool{wrapped.ToolsVersion(): true}
+	changed := func() bool {
+		version := wrapped.ToolsVersion()
+		if seen[version] {
+			return false
+		}
+		seen[version] = true
+		return true
+	}
+
+	assert.NilError(t, wrapped.Start(t.Context()))
+	assert.Check(t, changed(), "start")
+	assert.NilError(t, wrapped.Start(t.Context()))
+	assert.Check(t, !changed(), "already started")
+
+	wrapped.Invalidate()
+	assert.Check(t, changed(), "invalidate")
+
+	inner.version++
+	assert.Check(t, changed(), "inner version")
+
+	assert.NilError(t, wrapped.Stop(t.Context()))
+	assert.Check(t, changed(), "stop")
+}
+
+func TestToolsVersion_NotVersioned(t *testing.T) {
+	t.Parallel()
+
+	assert.Check(t, is.Equal(tools.ToolsVersion(&stubToolSet{}), uint64(0)))
+	assert.Check(t, is.Equal(tools.ToolsVersion(tools.NewStartable(&stubVersioned{version: 3})), uint64(3)))
+}
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
@@ -34,6 +35,10 @@ type ToolCall struct {
 	ID       string       `json:"id,omitempty"`
 	Type     ToolType     `json:"type"`
 	Function FunctionCall `json:"function"`
+	// Index is the position of the tool call in the response, set on the
+	// streamed deltas of the providers that identify the calls by position
+	// rather than by ID.
+	Index *int `json:"index,omitempty"`
 }
 
 type FunctionCall struct {
@@ -41,6 +46,21 @@ type FunctionCall struct {
 	Arguments string `json:"arguments,omitempty"`
 }
 
+// ArgumentsEdit records the arguments of a tool call that the user edited
+// when approving it.
+type ArgumentsEdit struct {
+	Original string `json:"original"`
+	Edited   string `json:"edited"`
+}
+
+// Apply returns toolCall with the edited arguments, or unchanged if e is nil.
+func (e *ArgumentsEdit) Apply(toolCall ToolCall) ToolCall {
+	if e != nil {
+		toolCall.Function.Arguments = e.Edited
+	}
+	return toolCall
+}
+
 // MediaContent represents base64-encoded binary data (image, audio, etc.)
 // returned by a tool.
 type MediaContent struct {
@@ -56,6 +76,32 @@ type ImageContent = MediaContent
 // AudioContent is an alias kept for readability at call sites.
 type AudioContent = MediaContent
 
+// ContentBlockType is the type of a ContentBlock.
+type ContentBlockType string
+
+const (
+	ContentBlockTypeText     ContentBlockType = "text"
+	ContentBlockTypeImage    ContentBlockType = "image"
+	ContentBlockTypeResource ContentBlockType = "resource"
+)
+
+// ResourceContent is a resource embedded in a tool result.
+type ResourceContent struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	// Text is the content of text resources.
+	Text string `json:"text,omitempty"`
+}
+
+// ContentBlock is one of the blocks of content returned by a tool, in the
+// order the tool returned them.
+type ContentBlock struct {
+	Type     ContentBlockType `json:"type"`
+	Text     string           `json:"text,omitempty"`
+	Image    *MediaContent    `json:"image,omitempty"`
+	Resource *ResourceContent `json:"resource,omitempty"`
+}
+
 type ToolCallResult struct {
 	Output  string `json:"output"`
 	IsError bool   `json:"isError,omitempty"`
@@ -64,6 +110,9 @@ type ToolCallResult struct {
 	Images []MediaContent `json:"images,omitempty"`
 	// Audios contains optional audio attachments returned by the tool.
 	Audios []MediaContent `json:"audios,omitempty"`
+	// Contents holds the blocks of content returned by the tool, e.g. text,
+	// images and resources for MCP tools. Output is their text rendering.
+	Contents []ContentBlock `json:"contents,omitempty"`
 	// StructuredContent holds optional structured output returned by an MCP
 	// tool whose definition includes an OutputSchema. When non-nil it is the
 	// JSON-decoded structured result from the server.
@@ -108,6 +157,30 @@ type Tool struct {
 	// ModelOverride is the per-toolset model for the LLM turn that processes
 	// this tool's results. Set automatically from the toolset "model" field.
 	ModelOverride string `json:"-"`
+	// OutputLimit is the per-toolset limit on the size of this tool's outputs
+	// sent to the model. Set automatically from the toolset "output_limit"
+	// field; nil means the runtime's limit applies.
+	OutputLimit *OutputLimit `json:"-"`
+	// SkipArgumentsValidation skips the validation of the arguments of the
+	// calls against Parameters, for tools that accept arguments their schema
+	// doesn't describe.
+	SkipArgumentsValidation bool `json:"-"`
+	// Timeout overrides the runtime's limit on how long a call to the tool
+	// may run. Set automatically from the toolset "tool_timeout" field, or
+	// to NoTimeout by tools that wait for users or enforce their own limits.
+	Timeout time.Duration `json:"-"`
+	// NoResultCache keeps the results of the calls to a read-only tool out
+	// of the runtime's cache, for tools whose results change on their own or
+	// that have effects. Set automatically from the toolset
+	// "no_result_cache" field.
+	NoResultCache bool `json:"-"`
+	// NoRedaction keeps the outputs of the tool as they are, for tools that
+	// legitimately handle secrets, instead of masking the secrets found in
+	// them. Set automatically from the toolset "no_redaction" field.
+	NoRedaction bool `json:"-"`
 }
 
+// NoTimeout, as the Timeout of a Tool, lets its calls run as long as they need.
+const NoTimeout time.Duration = -1
+
 type ToolAnnotations mcp.ToolAnnotations
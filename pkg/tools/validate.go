@@ -0,0 +1,211 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+)
+
+// ArgumentsError lists how the arguments of a tool call don't match the
+// parameters of the tool.
+type ArgumentsError struct {
+	Violations []string
+}
+
+func (e *ArgumentsError) Error() string {
+	return "invalid arguments:\n- " + strings.Join(e.Violations, "\n- ")
+}
+
+// ValidateArguments checks the JSON arguments of a call to tool against the
+// tool's parameters, and returns an *ArgumentsError listing the violations,
+// if any. It supports the subset of JSON Schema produced by MustSchemaFor:
+// types, required properties, nested objects, array items and enums. Other
+// keywords are ignored.
+func ValidateArguments(tool Tool, arguments string) error {
+	if tool.SkipArgumentsValidation || tool.Parameters == nil {
+		return nil
+	}
+
+	schema, err := schemaAsMap(tool.Parameters)
+	if err != nil {
+		// Not a schema we understand: let the handler decide.
+		return nil
+	}
+
+	if strings.TrimSpace(arguments) == "" {
+		arguments = "{}"
+	}
+	dec := json.NewDecoder(strings.NewReader(arguments))
+	dec.UseNumber()
+	var args any
+	if err := dec.Decode(&args); err != nil {
+		return &ArgumentsError{Violations: []string{fmt.Sprintf("arguments are not valid JSON: %v", err)}}
+	}
+
+	var v validator
+	v.validate("", schema, args)
+	if len(v.violations) > 0 {
+		return &ArgumentsError{Violations: v.violations}
+	}
+	return nil
+}
+
+// schemaAsMap returns the JSON representation of a schema, without the
+// defaults SchemaToMap adds.
+func schemaAsMap(params any) (map[string]any, error) {
+	buf, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+	var m map[string]any
+	if err := dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+type validator struct {
+	violations []string
+}
+
+func (v *validator) addf(path, format string, args ...any) {
+	where := "arguments"
+	if path != "" {
+		where = fmt.Sprintf("property '%s'", path)
+	}
+	v.violations = append(v.violations, where+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *validator) validate(path string, schema map[string]any, value any) {
+	if types := schemaTypes(schema["type"]); len(types) > 0 {
+		if !slices.ContainsFunc(types, func(t string) bool { return hasType(value, t) }) {
+			// Mention null last: "expected integer or null".
+			if i := slices.Index(types, "null"); i >= 0 {
+				types = append(slices.Delete(types, i, i+1), "null")
+			}
+			v.addf(path, "expected %s, got %s", strings.Join(types, " or "), jsonType(value))
+			return
+		}
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && !slices.ContainsFunc(enum, func(e any) bool { return jsonEqual(e, value) }) {
+		allowed := make([]string, len(enum))
+		for i, e := range enum {
+			allowed[i] = jsonString(e)
+		}
+		v.addf(path, "must be one of %s, got %s", strings.Join(allowed, ", "), jsonString(value))
+		return
+	}
+
+	switch value := value.(type) {
+	case map[string]any:
+		if required, ok := schema["required"].([]any); ok {
+			for _, name := range required {
+				if name, ok := name.(string); ok {
+					if _, present := value[name]; !present {
+						v.addf(join(path, name), "required property is missing")
+					}
+				}
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		for _, name := range slices.Sorted(maps.Keys(value)) {
+			if property, ok := properties[name].(map[string]any); ok {
+				v.validate(join(path, name), property, value[name])
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range value {
+				v.validate(fmt.Sprintf("%s[%d]", path, i), items, item)
+			}
+		}
+	}
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// schemaTypes returns the types allowed by the "type" keyword, which is
+// either a type or a list of types.
+func schemaTypes(t any) []string {
+	switch t := t.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		var types []string
+		for _, t := range t {
+			if t, ok := t.(string); ok {
+				types = append(types, t)
+			}
+		}
+		return types
+	default:
+		return nil
+	}
+}
+
+func hasType(value any, t string) bool {
+	switch t {
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := n.Float64()
+		return err == nil && f == math.Trunc(f)
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	default:
+		return jsonType(value) == t
+	}
+}
+
+func jsonType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func jsonString(value any) string {
+	buf, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(buf)
+}
+
+func jsonEqual(a, b any) bool {
+	if a, ok := a.(json.Number); ok {
+		if b, ok := b.(json.Number); ok {
+			fa, errA := a.Float64()
+			fb, errB := b.Float64()
+			return errA == nil && errB == nil && fa == fb
+		}
+	}
+	return jsonString(a) == jsonString(b)
+}
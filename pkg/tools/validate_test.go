@@ -0,0 +1,143 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type validateTestArgs struct {
+	Path  string `json:"path"`
+	Line  int    `json:"line"`
+	Range struct {
+		Start int  `json:"start"`
+		End   *int `json:"end,omitempty"`
+	} `json:"range"`
+	Tags  []string `json:"tags,omitempty"`
+	Force bool     `json:"force,omitempty"`
+}
+
+func validateTestTool() Tool {
+	return Tool{Name: "edit", Parameters: MustSchemaFor[validateTestArgs]()}
+}
+
+func TestValidateArguments(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		arguments  string
+		violations []string
+	}{
+		{
+			name:      "valid",
+			arguments: `{"path":"main.go","line":3,"range":{"start":1,"end":2},"tags":["a"],"force":true}`,
+		},
+		{
+			name:      "integral float is an integer",
+			arguments: `{"path":"main.go","line":3.0,"range":{"start":1}}`,
+		},
+		{
+			name:      "wrong type",
+			arguments: `{"path":"main.go","line":"3","range":{"start":1}}`,
+			violations: []string{
+				"property 'line': expected integer, got string",
+			},
+		},
+		{
+			name:      "missing required",
+			arguments: `{"line":3,"range":{}}`,
+			violations: []string{
+				"property 'path': required property is missing",
+				"property 'range.start': required property is missing",
+			},
+		},
+		{
+			name:      "nested and array items",
+			arguments: `{"path":"main.go","line":1.5,"range":{"start":1,"end":"2"},"tags":["a",1]}`,
+			violations: []string{
+				"property 'line': expected integer, got number",
+				"property 'range.end': expected integer or null, got string",
+				"property 'tags[1]': expected string, got number",
+			},
+		},
+		{
+			name:      "not an object",
+			arguments: `["main.go"]`,
+			violations: []string{
+				"arguments: expected object, got array",
+			},
+		},
+		{
+			name:      "invalid JSON",
+			arguments: `{"path":`,
+			violations: []string{
+				"arguments are not valid JSON: unexpected EOF",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateArguments(validateTestTool(), tt.arguments)
+			if tt.violations == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			var argsErr *ArgumentsError
+			require.ErrorAs(t, err, &argsErr)
+			assert.Equal(t, tt.violations, argsErr.Violations)
+		})
+	}
+}
+
+func TestValidateArgumentsEnum(t *testing.T) {
+	t.Parallel()
+
+	tool := Tool{
+		Name: "set_mode",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"mode":  map[string]any{"type": "string", "enum": []string{"fast", "safe"}},
+				"level": map[string]any{"type": "integer", "enum": []int{1, 2}},
+			},
+		},
+	}
+
+	require.NoError(t, ValidateArguments(tool, `{"mode":"safe","level":2}`))
+
+	err := ValidateArguments(tool, `{"mode":"slow","level":3}`)
+	var argsErr *ArgumentsError
+	require.ErrorAs(t, err, &argsErr)
+	assert.Equal(t, []string{
+		`property 'level': must be one of 1, 2, got 3`,
+		`property 'mode': must be one of "fast", "safe", got "slow"`,
+	}, argsErr.Violations)
+	assert.Equal(t, "invalid arguments:\n- "+argsErr.Violations[0]+"\n- "+argsErr.Violations[1], err.Error())
+}
+
+func TestValidateArgumentsEmpty(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, ValidateArguments(Tool{Name: "list", Parameters: map[string]any{"type": "object"}}, ""))
+	require.NoError(t, ValidateArguments(Tool{Name: "noparams"}, `{"anything":1}`))
+
+	err := ValidateArguments(validateTestTool(), "")
+	var argsErr *ArgumentsError
+	require.ErrorAs(t, err, &argsErr)
+	assert.Len(t, argsErr.Violations, 3)
+}
+
+func TestValidateArgumentsSkipped(t *testing.T) {
+	t.Parallel()
+
+	tool := validateTestTool()
+	tool.SkipArgumentsValidation = true
+
+	require.NoError(t, ValidateArguments(tool, `{"line":"3"}`))
+}
@@ -73,6 +73,28 @@ func builtInSessionCommands() []Item {
 				return core.CmdHandler(messages.CompactSessionMsg{AdditionalPrompt: arg})
 			},
 		},
+		{
+			ID:           "session.checkpoint",
+			Label:        "Checkpoint",
+			SlashCommand: "/checkpoint",
+			Description:  "Save a checkpoint of the conversation to roll back to (usage: /checkpoint [label])",
+			Category:     "Session",
+			Immediate:    true,
+			Execute: func(arg string) tea.Cmd {
+				return core.CmdHandler(messages.CreateCheckpointMsg{Label: strings.TrimSpace(arg)})
+			},
+		},
+		{
+			ID:           "session.checkpoints",
+			Label:        "Checkpoints",
+			SlashCommand: "/checkpoints",
+			Description:  "List the checkpoints of the conversation",
+			Category:     "Session",
+			Immediate:    true,
+			Execute: func(string) tea.Cmd {
+				return core.CmdHandler(messages.ListCheckpointsMsg{})
+			},
+		},
 		{
 			ID:           "session.clipboard",
 			Label:        "Copy",
@@ -166,7 +188,7 @@ func builtInSessionCommands() []Item {
 			ID:           "session.export",
 			Label:        "Export",
 			SlashCommand: "/export",
-			Description:  "Export the session as HTML (usage: /export [filename])",
+			Description:  "Export the session as HTML, or as Markdown/JSON with a .md/.json filename (usage: /export [filename])",
 			Category:     "Session",
 			Immediate:    true,
 			Execute: func(arg string) tea.Cmd {
@@ -206,6 +228,17 @@ func builtInSessionCommands() []Item {
 				return core.CmdHandler(messages.ShowPermissionsDialogMsg{})
 			},
 		},
+		{
+			ID:           "session.rollback",
+			Label:        "Rollback",
+			SlashCommand: "/rollback",
+			Description:  "Roll the conversation back to a checkpoint, and the changed files with --files (usage: /rollback [checkpoint] [--files])",
+			Category:     "Session",
+			Immediate:    true,
+			Execute: func(arg string) tea.Cmd {
+				return core.CmdHandler(parseRollbackArgs(arg))
+			},
+		},
 		{
 			ID:           "session.history",
 			Label:        "Sessions",
@@ -350,6 +383,24 @@ func visibleOnly(items []Item) []Item {
 	return visible
 }
 
+// parseRollbackArgs parses the arguments of /rollback: an optional
+// checkpoint ID or label, and the --files flag anywhere.
+func parseRollbackArgs(arg string) messages.RollbackSessionMsg {
+	var (
+		msg messages.RollbackSessionMsg
+		ref []string
+	)
+	for field := range strings.FieldsSeq(arg) {
+		if field == "--files" {
+			msg.RevertFiles = true
+			continue
+		}
+		ref = append(ref, field)
+	}
+	msg.Checkpoint = strings.Join(ref, " ")
+	return msg
+}
+
 // sortByLabel returns items sorted alphabetically by label.
 func sortByLabel(items []Item) []Item {
 	slices.SortFunc(items, func(a, b Item) int {
@@ -141,3 +141,20 @@ func TestParseSlashCommand_Compact(t *testing.T) {
 		assert.Equal(t, "focus on the API design", compactMsg.AdditionalPrompt)
 	})
 }
+
+func TestParseSlashCommand_Rollback(t *testing.T) {
+	t.Parallel()
+	parser := newTestParser()
+
+	for input, want := range map[string]messages.RollbackSessionMsg{
+		"/rollback":                        {},
+		"/rollback cp2":                    {Checkpoint: "cp2"},
+		"/rollback --files":                {RevertFiles: true},
+		"/rollback before the fix --files": {Checkpoint: "before the fix", RevertFiles: true},
+		"/rollback --files  tests pass":    {Checkpoint: "tests pass", RevertFiles: true},
+	} {
+		cmd := parser.Parse(input)
+		require.NotNil(t, cmd, input)
+		assert.Equal(t, want, cmd(), input)
+	}
+}
@@ -5,8 +5,10 @@ package markdown
 import (
 	"cmp"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"unicode"
 	"unicode/utf8"
 
@@ -14,7 +16,7 @@ import (
 	"charm.land/lipgloss/v2"
 	"github.com/alecthomas/chroma/v2"
 	"github.com/alecthomas/chroma/v2/lexers"
-	runewidth "github.com/mattn/go-runewidth"
+	"github.com/clipperhouse/displaywidth"
 
 	"github.com/docker/docker-agent/pkg/tui/styles"
 )
@@ -163,6 +165,10 @@ var (
 	globalStyles     *cachedStyles
 	globalStylesOnce sync.Once
 	globalStylesMu   sync.Mutex
+
+	// stylesGeneration is incremented every time the styles are reset, so
+	// that renderers caching rendered lines know they're outdated.
+	stylesGeneration atomic.Uint64
 )
 
 // ResetStyles resets the cached markdown styles so they will be rebuilt on next use.
@@ -172,6 +178,7 @@ func ResetStyles() {
 	globalStyles = nil
 	globalStylesOnce = sync.Once{}
 	globalStylesMu.Unlock()
+	stylesGeneration.Add(1)
 
 	// Also clear chroma syntax highlighting caches
 	chromaStyleCacheMu.Lock()
@@ -258,12 +265,49 @@ func getGlobalStyles() *cachedStyles {
 // FastRenderer is a high-performance markdown renderer optimized for terminal output.
 // It directly parses and renders markdown without building an intermediate AST.
 type FastRenderer struct {
-	width int
+	width      int
+	onHeading  func(Heading)
+	hyperlinks bool
+}
+
+// Heading is a heading found while rendering a document.
+type Heading struct {
+	// Level is the heading level, from 1 to 6.
+	Level int
+	// Text is the markdown text of the heading, without the emphasis
+	// wrapping the whole heading.
+	Text string
+	// Line is the index of the first rendered line of the heading.
+	Line int
+}
+
+// FastRendererOption configures a FastRenderer.
+type FastRendererOption func(*FastRenderer)
+
+// WithHeadingCallback calls fn for each heading, in order, during a Render
+// pass, e.g. to build an outline of the document.
+func WithHeadingCallback(fn func(Heading)) FastRendererOption {
+	return func(r *FastRenderer) {
+		r.onHeading = fn
+	}
+}
+
+// WithHyperlinks renders links as clickable OSC 8 hyperlinks showing only
+// their text, instead of "text (url)". Bare URLs and autolinks are made
+// clickable too. Only enable it for terminals that support OSC 8.
+func WithHyperlinks(enabled bool) FastRendererOption {
+	return func(r *FastRenderer) {
+		r.hyperlinks = enabled
+	}
 }
 
 // NewFastRenderer creates a new fast markdown renderer with the given width.
-func NewFastRenderer(width int) *FastRenderer {
-	return &FastRenderer{width: width}
+func NewFastRenderer(width int, opts ...FastRendererOption) *FastRenderer {
+	r := &FastRenderer{width: width}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 var parserPool = sync.Pool{
@@ -284,6 +328,8 @@ func (r *FastRenderer) Render(input string) (string, error) {
 
 	p := parserPool.Get().(*parser)
 	p.reset(input, r.width)
+	p.onHeading = r.onHeading
+	p.hyperlinks = r.hyperlinks
 	result := p.parse()
 	parserPool.Put(p)
 	return padAllLines(result, r.width), nil
@@ -297,6 +343,10 @@ type parser struct {
 	out     strings.Builder
 	lines   []string
 	lineIdx int
+	// onHeading, if set, is called for each top-level heading.
+	onHeading func(Heading)
+	// hyperlinks enables OSC 8 hyperlinks.
+	hyperlinks bool
 }
 
 func (p *parser) reset(input string, width int) {
@@ -309,38 +359,49 @@ func (p *parser) reset(input string, width int) {
 		p.lines = append(p.lines, line)
 	}
 	p.lineIdx = 0
+	p.onHeading = nil
+	p.hyperlinks = false
 	p.out.Reset()
 	p.out.Grow(len(input) * 2) // Pre-allocate for styled output
 }
 
 func (p *parser) parse() string {
 	for p.lineIdx < len(p.lines) {
-		line := p.lines[p.lineIdx]
-
-		switch {
-		case p.tryCodeBlock(line):
-			// handled inside
-		case p.tryHeading(line):
-			// handled inside
-		case p.tryHorizontalRule(line):
-			// handled inside
-		case p.tryBlockquote(line):
-			// handled inside
-		case p.tryTable(line):
-			// handled inside
-		case p.tryList(line):
-			// handled inside
-		case p.tryFootnoteDefinition(line):
-			// handled inside
-		default:
-			// Regular paragraph
-			p.renderParagraph()
-		}
+		p.parseBlock()
 	}
 
 	return strings.TrimRight(p.out.String(), "\n")
 }
 
+// parseBlock parses and renders the top-level block starting at the current line.
+func (p *parser) parseBlock() {
+	line := p.lines[p.lineIdx]
+
+	switch {
+	case p.tryCodeBlock(line):
+		// handled inside
+	case p.tryDetails(line):
+		// handled inside
+	case p.tryHeading(line):
+		// handled inside
+	case p.tryHorizontalRule(line):
+		// handled inside
+	case p.tryBlockquote(line):
+		// handled inside
+	case p.tryTable(line):
+		// handled inside
+	case p.tryList(line):
+		// handled inside
+	case p.tryFootnoteDefinition(line):
+		// handled inside
+	case p.tryDefinitionList():
+		// handled inside
+	default:
+		// Regular paragraph
+		p.renderParagraph()
+	}
+}
+
 // tryCodeBlock checks for fenced code blocks (``` or ~~~)
 func (p *parser) tryCodeBlock(line string) bool {
 	trimmed := strings.TrimSpace(line)
@@ -373,7 +434,93 @@ func (p *parser) tryCodeBlock(line string) bool {
 	return true
 }
 
-// tryHeading checks for ATX-style headings (# through ######)
+// detailsIndent is the indentation of the body of <details> blocks.
+const detailsIndent = 2
+
+// isDetailsStart checks if a trimmed line starts a <details> block.
+func isDetailsStart(trimmed string) bool {
+	if !strings.HasPrefix(trimmed, "<") {
+		return false
+	}
+	tag, ok := parseHTMLTag(trimmed)
+	return ok && !tag.closing && tag.name == "details"
+}
+
+// tryDetails checks for HTML <details> blocks. They're rendered expanded:
+// the summary line with a "▸" marker, followed by the indented body.
+func (p *parser) tryDetails(line string) bool {
+	trimmed := strings.TrimLeft(line, " \t")
+	if !isDetailsStart(trimmed) {
+		return false
+	}
+
+	// Collect the lines up to the matching </details>, if any
+	var content strings.Builder
+	depth := 0
+	for p.lineIdx < len(p.lines) {
+		l := p.lines[p.lineIdx]
+		if content.Len() > 0 {
+			content.WriteByte('\n')
+		}
+		content.WriteString(l)
+		p.lineIdx++
+		depth += htmlTagDepth(l, "details")
+		if depth <= 0 {
+			break
+		}
+	}
+
+	tag, _ := parseHTMLTag(trimmed)
+	body := strings.TrimLeft(content.String(), " \t")[tag.length:]
+	if end, _ := indexClosingTag(body, "details"); end != -1 {
+		body = body[:end]
+	}
+
+	summary := "Details"
+	if trimmedBody := strings.TrimSpace(body); strings.HasPrefix(trimmedBody, "<") {
+		if tag, ok := parseHTMLTag(trimmedBody); ok && !tag.closing && tag.name == "summary" {
+			rest := trimmedBody[tag.length:]
+			if end, closeLen := indexClosingTag(rest, "summary"); end != -1 {
+				summary = strings.TrimSpace(rest[:end])
+				body = rest[end+closeLen:]
+			}
+		}
+	}
+
+	// Summary line
+	rendered := p.renderInlineWithStyle(summary, p.styles.ansiText.withBold())
+	wrapped := p.wrapText(rendered, p.width-detailsIndent)
+	for i, l := range strings.Split(wrapped, "\n") {
+		if i == 0 {
+			p.styles.ansiText.renderTo(&p.out, "▸ ")
+		} else {
+			p.out.WriteString(spaces(detailsIndent))
+		}
+		p.out.WriteString(l)
+		p.out.WriteByte('\n')
+	}
+
+	// Body, rendered as a markdown document of its own
+	if body = strings.Trim(body, "\n"); strings.TrimSpace(body) != "" {
+		sub := parserPool.Get().(*parser)
+		sub.reset(body, max(p.width-detailsIndent, 0))
+		sub.hyperlinks = p.hyperlinks
+		renderedBody := sub.parse()
+		parserPool.Put(sub)
+
+		indent := spaces(detailsIndent)
+		for l := range strings.SplitSeq(renderedBody, "\n") {
+			p.out.WriteString(indent)
+			p.out.WriteString(l)
+			p.out.WriteByte('\n')
+		}
+	}
+	p.out.WriteByte('\n')
+	return true
+}
+
+// tryHeading checks for ATX-style headings (# through ######). Setext-style
+// headings are handled by renderParagraph.
 func (p *parser) tryHeading(line string) bool {
 	trimmed := strings.TrimLeft(line, " \t")
 	if !strings.HasPrefix(trimmed, "#") {
@@ -399,6 +546,30 @@ func (p *parser) tryHeading(line string) bool {
 	// Remove trailing #s
 	content = strings.TrimRight(content, "# \t")
 
+	p.renderHeading(level, content)
+	p.lineIdx++
+	return true
+}
+
+// setextHeadingLevel returns the level of the setext heading underlined by
+// line: 1 for a line of only =, 2 for a line of only -, 0 otherwise.
+func setextHeadingLevel(line string) int {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.Trim(trimmed, trimmed[:1]) != "" {
+		return 0
+	}
+	switch trimmed[0] {
+	case '=':
+		return 1
+	case '-':
+		return 2
+	default:
+		return 0
+	}
+}
+
+// renderHeading renders a heading of the given level.
+func (p *parser) renderHeading(level int, content string) {
 	style := p.headingStyle(level)
 	ansiStyle := p.headingAnsiStyle(level)
 	prefix := p.headingPrefix(level)
@@ -425,6 +596,14 @@ func (p *parser) tryHeading(line string) bool {
 		ansiStyle = ansiStyle.withItalic()
 	}
 
+	if p.onHeading != nil {
+		p.onHeading(Heading{
+			Level: level,
+			Text:  content,
+			Line:  strings.Count(p.out.String(), "\n"),
+		})
+	}
+
 	// Use heading-aware inline rendering so styled elements restore to heading style
 	rendered := p.renderInlineWithStyle(content, ansiStyle)
 	// Calculate available width for content (accounting for prefix)
@@ -460,8 +639,6 @@ func (p *parser) tryHeading(line string) bool {
 		}
 	}
 	p.out.WriteByte('\n')
-	p.lineIdx++
-	return true
 }
 
 func (p *parser) headingStyle(level int) lipgloss.Style {
@@ -606,8 +783,9 @@ func (p *parser) renderBlockquoteCodeBlock(code, lang, indent string, availableW
 // tableCell holds pre-rendered cell data to avoid re-rendering
 type tableCell struct {
 	rendered    string // rendered with inline styles
-	width       int    // visual width (excluding ANSI codes)
+	width       int    // visual width (excluding ANSI codes), of the widest line
 	longestWord int    // width of longest single word (for minimum column width)
+	multiLine   bool   // whether the cell has hard line breaks (<br>)
 }
 
 // tableLayout holds the computed table layout parameters
@@ -858,8 +1036,10 @@ func (p *parser) tryTable(line string) bool {
 	// and minimum widths (longest single word in each column to avoid mid-word breaks)
 	desired := make([]int, numCols)
 	minWidths := make([]int, numCols)
+	multiLine := false
 	for _, row := range rows {
 		for i, cell := range row {
+			multiLine = multiLine || cell.multiLine
 			if cell.width > desired[i] {
 				desired[i] = cell.width
 			}
@@ -869,9 +1049,15 @@ func (p *parser) tryTable(line string) bool {
 		}
 	}
 
+	aligns := parseTableAlignments(separator, numCols)
+
 	// Compute viewport-fit layout
 	layout := computeTableLayout(desired, minWidths, p.width)
 	colWidths := layout.colWidths
+	// Cells with hard line breaks are rendered on multiple lines
+	if multiLine {
+		layout.needsWrap = true
+	}
 
 	// Build separator line based on fitted widths
 	sepLine := p.buildTableSeparatorLine(colWidths, layout.dividerSep)
@@ -883,16 +1069,81 @@ func (p *parser) tryTable(line string) bool {
 
 	if !layout.needsWrap {
 		// Fast path: no wrapping needed, render single-line rows
-		p.renderTableRowsFast(rows, colWidths, styledSep, styledSepLine)
+		p.renderTableRowsFast(rows, colWidths, aligns, styledSep, styledSepLine)
 	} else {
 		// Slow path: wrap cells and render multi-line rows
-		p.renderTableRowsWrapped(rows, colWidths, styledSep, styledSepLine)
+		p.renderTableRowsWrapped(rows, colWidths, aligns, styledSep, styledSepLine)
 	}
 
 	p.out.WriteByte('\n')
 	return true
 }
 
+// tableAlignment is the horizontal alignment of a table column.
+type tableAlignment uint8
+
+const (
+	alignLeft tableAlignment = iota
+	alignCenter
+	alignRight
+)
+
+// parseTableAlignments parses the alignment of each column from the markers
+// of the separator row: :--- (left), :---: (center) and ---: (right).
+func parseTableAlignments(separator string, numCols int) []tableAlignment {
+	aligns := make([]tableAlignment, numCols)
+
+	separator = strings.TrimSpace(separator)
+	separator = strings.TrimPrefix(separator, "|")
+	separator = strings.TrimSuffix(separator, "|")
+
+	col := 0
+	for spec := range strings.SplitSeq(separator, "|") {
+		if col >= numCols {
+			break
+		}
+		spec = strings.TrimSpace(spec)
+		left := strings.HasPrefix(spec, ":")
+		right := len(spec) > 1 && strings.HasSuffix(spec, ":")
+		switch {
+		case left && right:
+			aligns[col] = alignCenter
+		case right:
+			aligns[col] = alignRight
+		default:
+			aligns[col] = alignLeft
+		}
+		col++
+	}
+	return aligns
+}
+
+// writeTableCell writes the rendered content of a cell, of the given visual
+// width, padded to the column width according to the column alignment.
+func (p *parser) writeTableCell(rendered string, width, colWidth int, align tableAlignment, header bool) {
+	padding := max(colWidth-width, 0)
+	leftPadding := 0
+	switch align {
+	case alignCenter:
+		leftPadding = padding / 2
+	case alignRight:
+		leftPadding = padding
+	}
+
+	if leftPadding > 0 {
+		p.out.WriteString(spaces(leftPadding))
+	}
+	// Header row - bold
+	if header {
+		p.styles.ansiBold.renderTo(&p.out, rendered)
+	} else {
+		p.out.WriteString(rendered)
+	}
+	if padding > leftPadding {
+		p.out.WriteString(spaces(padding - leftPadding))
+	}
+}
+
 // buildTableSeparatorLine builds the horizontal separator line for table header
 func (p *parser) buildTableSeparatorLine(colWidths []int, dividerSep string) string {
 	numCols := len(colWidths)
@@ -923,7 +1174,7 @@ func buildTableBlankRow(colWidths []int, styledSep string) string {
 }
 
 // renderTableRowsFast renders table rows without wrapping (fast path)
-func (p *parser) renderTableRowsFast(rows [][]tableCell, colWidths []int, styledSep, styledSepLine string) {
+func (p *parser) renderTableRowsFast(rows [][]tableCell, colWidths []int, aligns []tableAlignment, styledSep, styledSepLine string) {
 	numCols := len(colWidths)
 	blankRow := buildTableBlankRow(colWidths, styledSep)
 
@@ -940,18 +1191,7 @@ func (p *parser) renderTableRowsFast(rows [][]tableCell, colWidths []int, styled
 				cell = row[i]
 			}
 
-			if rowIdx == 0 {
-				// Header row - bold
-				p.styles.ansiBold.renderTo(&p.out, cell.rendered)
-			} else {
-				p.out.WriteString(cell.rendered)
-			}
-
-			// Add padding
-			padding := colWidths[i] - cell.width
-			if padding > 0 {
-				p.out.WriteString(spaces(padding))
-			}
+			p.writeTableCell(cell.rendered, cell.width, colWidths[i], aligns[i], rowIdx == 0)
 
 			if i < numCols-1 {
 				p.out.WriteString(styledSep)
@@ -968,7 +1208,7 @@ func (p *parser) renderTableRowsFast(rows [][]tableCell, colWidths []int, styled
 }
 
 // renderTableRowsWrapped renders table rows with cell wrapping (slow path)
-func (p *parser) renderTableRowsWrapped(rows [][]tableCell, colWidths []int, styledSep, styledSepLine string) {
+func (p *parser) renderTableRowsWrapped(rows [][]tableCell, colWidths []int, aligns []tableAlignment, styledSep, styledSepLine string) {
 	numCols := len(colWidths)
 	blankRow := buildTableBlankRow(colWidths, styledSep)
 
@@ -989,10 +1229,14 @@ func (p *parser) renderTableRowsWrapped(rows [][]tableCell, colWidths []int, sty
 				cell = row[i]
 			}
 
-			if cell.width <= colWidths[i] {
+			switch {
+			case cell.width <= colWidths[i] && cell.multiLine:
+				// Cell fits, only split its lines
+				wrappedCells[i] = strings.Split(cell.rendered, "\n")
+			case cell.width <= colWidths[i]:
 				// Cell fits, no wrapping needed
 				wrappedCells[i] = []string{cell.rendered}
-			} else {
+			default:
 				// Wrap the cell content
 				wrapped := p.wrapText(cell.rendered, colWidths[i])
 				lines := strings.Split(wrapped, "\n")
@@ -1012,19 +1256,8 @@ func (p *parser) renderTableRowsWrapped(rows [][]tableCell, colWidths []int, sty
 					lineContent = wrappedCells[colIdx][lineIdx]
 				}
 
-				// Apply bold to header row
-				if rowIdx == 0 {
-					p.styles.ansiBold.renderTo(&p.out, lineContent)
-				} else {
-					p.out.WriteString(lineContent)
-				}
-
-				// Pad to column width
-				lineWidth := ansiStringWidth(lineContent)
-				padding := colWidths[colIdx] - lineWidth
-				if padding > 0 {
-					p.out.WriteString(spaces(padding))
-				}
+				// Each wrapped line is aligned on its own
+				p.writeTableCell(lineContent, ansiStringWidth(lineContent), colWidths[colIdx], aligns[colIdx], rowIdx == 0)
 
 				if colIdx < numCols-1 {
 					p.out.WriteString(styledSep)
@@ -1072,7 +1305,7 @@ func (p *parser) parseAndRenderTableRow(line string) []tableCell {
 		var rendered string
 		var width int
 		// Fast path: if cell has no markdown, skip full inline rendering
-		if !hasInlineMarkdown(cellText) {
+		if !hasInlineMarkdown(cellText) && (!p.hyperlinks || indexBareURL(cellText) == -1) {
 			// Apply base text style directly
 			rendered = p.styles.ansiText.render(cellText)
 			width = textWidth(cellText)
@@ -1080,10 +1313,18 @@ func (p *parser) parseAndRenderTableRow(line string) []tableCell {
 			// Use renderInlineWithWidth for markdown content
 			rendered, width = p.renderInlineWithWidth(cellText)
 		}
+		multiLine := strings.IndexByte(rendered, '\n') != -1
+		if multiLine {
+			width = 0
+			for l := range strings.SplitSeq(rendered, "\n") {
+				width = max(width, ansiStringWidth(l))
+			}
+		}
 		cells = append(cells, tableCell{
 			rendered:    rendered,
 			width:       width,
 			longestWord: longestWordWidth(cellText),
+			multiLine:   multiLine,
 		})
 		start = i + 1
 	}
@@ -1094,6 +1335,7 @@ func (p *parser) parseAndRenderTableRow(line string) []tableCell {
 type listItem struct {
 	content string
 	ordered bool
+	number  int // Source number of ordered items
 	task    bool
 	checked bool
 }
@@ -1123,12 +1365,14 @@ func parseListItem(line string) (listItem, bool) {
 	// Check ordered list (1., 2., etc.)
 	dotIdx := strings.Index(line, ".")
 	if dotIdx > 0 && dotIdx < 10 && len(line) > dotIdx+1 && line[dotIdx+1] == ' ' {
+		number := 0
 		for i := range dotIdx {
-			if !unicode.IsDigit(rune(line[i])) {
+			if line[i] < '0' || line[i] > '9' {
 				return listItem{}, false
 			}
+			number = number*10 + int(line[i]-'0')
 		}
-		return listItem{content: line[dotIdx+2:], ordered: true}, true
+		return listItem{content: line[dotIdx+2:], ordered: true, number: number}, true
 	}
 
 	return listItem{}, false
@@ -1150,6 +1394,9 @@ func (p *parser) tryList(line string) bool {
 	// Track the current list item's bullet width for continuation content (code blocks)
 	var currentBulletWidth int
 
+	// Open lists, from the outermost to the innermost one
+	var levels []listLevel
+
 	for p.lineIdx < len(p.lines) {
 		l := p.lines[p.lineIdx]
 		ltrimmed := strings.TrimLeft(l, " \t")
@@ -1202,8 +1449,9 @@ func (p *parser) tryList(line string) bool {
 			break
 		}
 
-		level := lindent / p.styles.listIndent
-		bulletIndent := spaces(level * p.styles.listIndent)
+		levels = p.enterListLevel(levels, lindent, item)
+		level := &levels[len(levels)-1]
+		bulletIndent := spaces(level.renderIndent)
 
 		var bullet string
 		switch {
@@ -1211,14 +1459,19 @@ func (p *parser) tryList(line string) bool {
 			bullet = p.styles.styleTaskTicked
 		case item.task:
 			bullet = p.styles.styleTaskUntick
+		case item.ordered:
+			// Number items consecutively from the list's first number, like
+			// CommonMark, so that "1. 1. 1." lists read 1, 2, 3
+			bullet = strconv.Itoa(level.next) + ". "
+			level.next++
 		default:
-			// Use consistent bullet for both ordered and unordered lists
 			bullet = "- "
 		}
 
 		// Calculate the width available for content (after bullet and indentation)
 		// bulletIndent is always ASCII spaces, bullet may contain unicode for task items
 		bulletWidth := len(bulletIndent) + textWidth(bullet)
+		level.contentIndent = bulletWidth
 		contentWidth := max(p.width-bulletWidth, 10) // Minimum content width of 10
 
 		// Store current list item's bullet width for code blocks
@@ -1253,6 +1506,53 @@ func (p *parser) tryList(line string) bool {
 	return true
 }
 
+// listLevel is a list open at some nesting level of tryList.
+type listLevel struct {
+	indent        int  // Indentation of the items in the source
+	renderIndent  int  // Indentation of the rendered bullets
+	contentIndent int  // Indentation of the rendered content of the last item
+	ordered       bool // Whether this is an ordered list
+	next          int  // Number of the next ordered item
+}
+
+// enterListLevel returns the open lists after the item indented by indent:
+// lists indented more deeply are closed, and the item either continues the
+// innermost list or opens a nested one.
+func (p *parser) enterListLevel(levels []listLevel, indent int, item listItem) []listLevel {
+	for len(levels) > 0 && indent < levels[len(levels)-1].indent {
+		levels = levels[:len(levels)-1]
+	}
+
+	if len(levels) > 0 {
+		innermost := &levels[len(levels)-1]
+		if indent < innermost.indent+p.styles.listIndent {
+			// Same level: a change of list type starts a new list
+			if item.ordered != innermost.ordered {
+				innermost.ordered = item.ordered
+				innermost.next = item.number
+			}
+			return levels
+		}
+	}
+
+	// Nested lists are indented under their parent's content: numbers may be
+	// wider than bullets
+	renderIndent := (indent / p.styles.listIndent) * p.styles.listIndent
+	if len(levels) > 0 {
+		parent := levels[len(levels)-1]
+		renderIndent = parent.renderIndent + p.styles.listIndent
+		if parent.ordered {
+			renderIndent = parent.contentIndent
+		}
+	}
+	return append(levels, listLevel{
+		indent:       indent,
+		renderIndent: renderIndent,
+		ordered:      item.ordered,
+		next:         item.number,
+	})
+}
+
 // tryFootnoteDefinition checks for footnote definitions [^id]: content
 func (p *parser) tryFootnoteDefinition(line string) bool {
 	trimmed := strings.TrimLeft(line, " \t")
@@ -1330,6 +1630,86 @@ func (p *parser) tryFootnoteDefinition(line string) bool {
 	return true
 }
 
+// definitionIndent is the indentation of the definitions of definition lists.
+const definitionIndent = 4
+
+// isDefinitionStart checks if a trimmed line starts a definition (": definition").
+func isDefinitionStart(trimmed string) bool {
+	return strings.HasPrefix(trimmed, ": ") && strings.TrimSpace(trimmed[2:]) != ""
+}
+
+// isDefinitionTerm checks if the line at idx is the term of a definition list
+// entry: a non-empty line directly followed by a definition.
+func (p *parser) isDefinitionTerm(idx int) bool {
+	if idx+1 >= len(p.lines) || strings.TrimSpace(p.lines[idx]) == "" {
+		return false
+	}
+	return !isDefinitionStart(strings.TrimLeft(p.lines[idx], " \t")) &&
+		isDefinitionStart(strings.TrimLeft(p.lines[idx+1], " \t"))
+}
+
+// tryDefinitionList checks for definition lists: terms, each followed by one
+// or more ": definition" lines. Terms are rendered bold and definitions are
+// indented below them.
+func (p *parser) tryDefinitionList() bool {
+	if !p.isDefinitionTerm(p.lineIdx) {
+		return false
+	}
+
+	for {
+		term := strings.TrimSpace(p.lines[p.lineIdx])
+		rendered := p.renderInlineWithStyle(term, p.styles.ansiText.withBold())
+		p.out.WriteString(p.wrapText(rendered, p.width))
+		p.out.WriteByte('\n')
+		p.lineIdx++
+
+		for p.lineIdx < len(p.lines) {
+			trimmed := strings.TrimLeft(p.lines[p.lineIdx], " \t")
+			if !isDefinitionStart(trimmed) {
+				break
+			}
+			p.lineIdx++
+
+			// Collect continuation lines (indented)
+			parts := []string{strings.TrimSpace(trimmed[2:])}
+			for p.lineIdx < len(p.lines) {
+				next := p.lines[p.lineIdx]
+				nextTrimmed := strings.TrimLeft(next, " \t")
+				if nextTrimmed == "" || len(next) == len(nextTrimmed) || isDefinitionStart(nextTrimmed) {
+					break
+				}
+				parts = append(parts, strings.TrimSpace(nextTrimmed))
+				p.lineIdx++
+			}
+
+			renderedDef := p.renderInline(strings.Join(parts, " "))
+			indent := spaces(definitionIndent)
+			for l := range strings.SplitSeq(p.wrapText(renderedDef, max(p.width-definitionIndent, 10)), "\n") {
+				p.out.WriteString(indent)
+				p.out.WriteString(l)
+				p.out.WriteByte('\n')
+			}
+		}
+
+		// The list goes on with the next term, blank lines between entries
+		// are kept
+		next := p.lineIdx
+		for next < len(p.lines) && strings.TrimSpace(p.lines[next]) == "" {
+			next++
+		}
+		if !p.isDefinitionTerm(next) {
+			break
+		}
+		if next > p.lineIdx {
+			p.out.WriteByte('\n')
+		}
+		p.lineIdx = next
+	}
+
+	p.out.WriteByte('\n')
+	return true
+}
+
 // renderListCodeBlock renders a fenced code block within a list context
 func (p *parser) renderListCodeBlock(codeIndent, bulletWidth int) {
 	// Add spacing before code block
@@ -1491,6 +1871,15 @@ func (p *parser) renderParagraph() {
 			p.lineIdx++
 			break
 		}
+		// A line of = or - under paragraph lines turns them into a setext
+		// heading. This takes precedence over horizontal rules.
+		if len(paraLines) > 0 {
+			if level := setextHeadingLevel(line); level > 0 {
+				p.lineIdx++
+				p.renderHeading(level, strings.TrimSpace(strings.Join(paraLines, " ")))
+				return
+			}
+		}
 		// Check if next line starts a block element
 		trimmed := strings.TrimLeft(line, " \t")
 		if strings.HasPrefix(trimmed, "#") ||
@@ -1498,7 +1887,9 @@ func (p *parser) renderParagraph() {
 			strings.HasPrefix(trimmed, "~~~") ||
 			strings.HasPrefix(trimmed, ">") ||
 			isListStart(trimmed) ||
-			isHorizontalRule(trimmed) {
+			isHorizontalRule(trimmed) ||
+			isDetailsStart(trimmed) ||
+			(len(paraLines) > 0 && p.isDefinitionTerm(p.lineIdx)) {
 			break
 		}
 		paraLines = append(paraLines, line)
@@ -1574,6 +1965,11 @@ func (p *parser) renderInlineWithStyleTo(out *strings.Builder, text string, rest
 	// Fast path: check if text contains any markdown characters
 	// If not, apply the restore style directly and return
 	firstMarker := strings.IndexAny(text, inlineMarkdownChars)
+	if p.hyperlinks {
+		if u := indexBareURL(text); u != -1 && (firstMarker == -1 || u < firstMarker) {
+			firstMarker = u
+		}
+	}
 	if firstMarker == -1 {
 		restoreStyle.renderTo(out, text)
 		return textWidth(text)
@@ -1596,39 +1992,69 @@ func (p *parser) renderInlineWithStyleTo(out *strings.Builder, text string, rest
 		// Check for escaped characters
 		if text[i] == '\\' && i+1 < n {
 			out.WriteByte(text[i+1])
-			width += runewidth.RuneWidth(rune(text[i+1]))
+			width += textWidth(text[i+1 : i+2])
 			i += 2
 			continue
 		}
 
+		// Check for bare URLs, only linkified with hyperlinks
+		if p.hyperlinks && text[i] == 'h' && (i == 0 || !isWord(text[i-1])) {
+			if url := bareURL(text[i:]); url != "" {
+				width += p.renderLink(out, url, url)
+				i += len(url)
+				continue
+			}
+		}
+
 		// Check for inline code
 		if text[i] == '`' {
 			end := strings.Index(text[i+1:], "`")
 			if end != -1 {
-				code := text[i+1 : i+1+end]
-				// Use flags to check if parent has formatting attributes that should carry to code
-				if restoreStyle.hasStrike || restoreStyle.hasBold {
-					// Write code style prefix, then inherited formatting, then code, then suffix
-					out.WriteString(p.styles.ansiCode.prefix)
-					if restoreStyle.hasBold {
-						out.WriteString("\x1b[1m")
-					}
-					if restoreStyle.hasStrike {
-						out.WriteString("\x1b[9m")
-					}
-					out.WriteString(code)
-					out.WriteString(p.styles.ansiCode.suffix)
-				} else {
-					p.styles.ansiCode.renderTo(out, code)
-				}
-				// Restore parent style after code (since ansiCode.suffix resets everything)
-				out.WriteString(restoreStyle.prefix)
-				width += textWidth(code)
+				width += p.renderInlineCode(out, text[i+1:i+1+end], restoreStyle)
 				i = i + 1 + end + 1
 				continue
 			}
 		}
 
+		// Check for autolinks (<https://...>)
+		if text[i] == '<' {
+			if end := strings.IndexByte(text[i:], '>'); end != -1 {
+				if url := text[i+1 : i+end]; bareURL(url) == url {
+					width += p.renderLink(out, url, url)
+					i += end + 1
+					continue
+				}
+			}
+		}
+
+		// Check for inline HTML (<br>, <b>text</b>, ...)
+		if text[i] == '<' {
+			if tag, ok := parseHTMLTag(text[i:]); ok && !tag.closing {
+				if tag.name == "br" {
+					// Hard line break, kept by wrapText
+					out.WriteByte('\n')
+					i += tag.length
+					continue
+				}
+				rest := text[i+tag.length:]
+				if end, closeLen := indexClosingTag(rest, tag.name); end != -1 && !tag.selfClosing {
+					width += p.renderHTMLElement(out, tag.name, rest[:end], restoreStyle)
+					i += tag.length + end + closeLen
+					continue
+				}
+			}
+		}
+
+		// Check for HTML entities (&amp;, &lt;, ...)
+		if text[i] == '&' {
+			if decoded, length := decodeHTMLEntity(text[i:]); length > 0 {
+				restoreStyle.renderTo(out, decoded)
+				width += textWidth(decoded)
+				i += length
+				continue
+			}
+		}
+
 		// Check for bold (**text** or __text__)
 		if i+1 < n && ((text[i] == '*' && text[i+1] == '*') || (text[i] == '_' && text[i+1] == '_')) {
 			delim := text[i : i+2]
@@ -1724,20 +2150,7 @@ func (p *parser) renderInlineWithStyleTo(out *strings.Builder, text string, rest
 				rest := text[i+closeBracket+2:]
 				closeParen := strings.Index(rest, ")")
 				if closeParen != -1 {
-					url := rest[:closeParen]
-					if linkText != url {
-						p.styles.ansiLinkText.renderTo(out, linkText)
-						out.WriteByte(' ')
-						out.WriteString(p.styles.ansiLink.prefix)
-						out.WriteByte('(')
-						out.WriteString(url)
-						out.WriteByte(')')
-						out.WriteString(p.styles.ansiLink.suffix)
-						width += textWidth(linkText) + 1 + textWidth(url) + 2 // +1 for space, +2 for parens
-					} else {
-						p.styles.ansiLink.renderTo(out, linkText)
-						width += textWidth(linkText)
-					}
+					width += p.renderLink(out, linkText, rest[:closeParen])
 					i = i + closeBracket + 2 + closeParen + 1
 					continue
 				}
@@ -1753,6 +2166,12 @@ func (p *parser) renderInlineWithStyleTo(out *strings.Builder, text string, rest
 			if i == start {
 				i++
 			}
+			// Stop before bare URLs, linkified on the next iteration
+			if p.hyperlinks {
+				if u := indexBareURL(text[start+1 : i]); u != -1 {
+					i = start + 1 + u
+				}
+			}
 			// Always apply restore style to plain text for consistent coloring
 			plainText := text[start:i]
 			restoreStyle.renderTo(out, plainText)
@@ -1763,6 +2182,311 @@ func (p *parser) renderInlineWithStyleTo(out *strings.Builder, text string, rest
 	return width
 }
 
+// renderLink writes a link and returns its visual width. With hyperlinks,
+// only the text is shown and made clickable; otherwise the URL follows the
+// text, unless they're the same.
+func (p *parser) renderLink(out *strings.Builder, text, url string) int {
+	if p.hyperlinks && isHyperlinkSafe(url) {
+		style := p.styles.ansiLinkText
+		if text == url {
+			style = p.styles.ansiLink
+		}
+		writeHyperlinkStart(out, url)
+		style.renderTo(out, text)
+		out.WriteString(hyperlinkEnd)
+		return textWidth(text)
+	}
+
+	if text == url {
+		p.styles.ansiLink.renderTo(out, text)
+		return textWidth(text)
+	}
+	p.styles.ansiLinkText.renderTo(out, text)
+	out.WriteByte(' ')
+	out.WriteString(p.styles.ansiLink.prefix)
+	out.WriteByte('(')
+	out.WriteString(url)
+	out.WriteByte(')')
+	out.WriteString(p.styles.ansiLink.suffix)
+	return textWidth(text) + 1 + textWidth(url) + 2 // +1 for space, +2 for parens
+}
+
+// hyperlinkEnd is the OSC 8 sequence ending a hyperlink.
+const hyperlinkEnd = "\x1b]8;;\x1b\\"
+
+// writeHyperlinkStart writes the OSC 8 sequence starting a hyperlink to url.
+func writeHyperlinkStart(out *strings.Builder, url string) {
+	out.WriteString("\x1b]8;;")
+	out.WriteString(url)
+	out.WriteString("\x1b\\")
+}
+
+// isHyperlink checks if an escape sequence starts or ends an OSC 8 hyperlink.
+func isHyperlink(seq string) bool {
+	return strings.HasPrefix(seq, "\x1b]8;")
+}
+
+// isHyperlinkSafe checks if url can be written in an OSC 8 sequence: it must
+// not contain spaces or control characters that would end the sequence.
+func isHyperlinkSafe(url string) bool {
+	if url == "" {
+		return false
+	}
+	for i := range len(url) {
+		if url[i] <= ' ' || url[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// bareURL returns the http(s) URL at the start of s, without trailing
+// punctuation, or "" if s doesn't start with one.
+func bareURL(s string) string {
+	var rest string
+	switch {
+	case strings.HasPrefix(s, "https://"):
+		rest = s[len("https://"):]
+	case strings.HasPrefix(s, "http://"):
+		rest = s[len("http://"):]
+	default:
+		return ""
+	}
+
+	end := len(s) - len(rest)
+	for end < len(s) && s[end] > ' ' && s[end] != 0x7f && !strings.ContainsRune("<>\"`", rune(s[end])) {
+		end++
+	}
+	url := s[:end]
+
+	// Trailing punctuation is part of the sentence, not of the URL
+	for url != "" {
+		last := url[len(url)-1]
+		if strings.IndexByte(".,;:!?'*_~", last) != -1 ||
+			(last == ')' && strings.Count(url, "(") < strings.Count(url, ")")) {
+			url = url[:len(url)-1]
+			continue
+		}
+		break
+	}
+	if len(url) == len(s)-len(rest) {
+		// Nothing after the scheme
+		return ""
+	}
+	return url
+}
+
+// indexBareURL returns the index of the first bare URL in s, or -1.
+func indexBareURL(s string) int {
+	for i := 0; i < len(s); {
+		j := strings.Index(s[i:], "http")
+		if j == -1 {
+			return -1
+		}
+		i += j
+		if (i == 0 || !isWord(s[i-1])) && bareURL(s[i:]) != "" {
+			return i
+		}
+		i += len("http")
+	}
+	return -1
+}
+
+// renderInlineCode writes inline code and returns its visual width.
+func (p *parser) renderInlineCode(out *strings.Builder, code string, restoreStyle ansiStyle) int {
+	// Use flags to check if parent has formatting attributes that should carry to code
+	if restoreStyle.hasStrike || restoreStyle.hasBold {
+		// Write code style prefix, then inherited formatting, then code, then suffix
+		out.WriteString(p.styles.ansiCode.prefix)
+		if restoreStyle.hasBold {
+			out.WriteString("\x1b[1m")
+		}
+		if restoreStyle.hasStrike {
+			out.WriteString("\x1b[9m")
+		}
+		out.WriteString(code)
+		out.WriteString(p.styles.ansiCode.suffix)
+	} else {
+		p.styles.ansiCode.renderTo(out, code)
+	}
+	// Restore parent style after code (since ansiCode.suffix resets everything)
+	out.WriteString(restoreStyle.prefix)
+	return textWidth(code)
+}
+
+// renderHTMLElement writes the content of an inline HTML element with the
+// style matching its tag and returns its visual width. The content of
+// unknown elements is kept as is.
+func (p *parser) renderHTMLElement(out *strings.Builder, name, inner string, restoreStyle ansiStyle) int {
+	switch name {
+	case "b", "strong":
+		if restoreStyle.hasBold {
+			// Bold is redundant in bold contexts (e.g., headings)
+			return p.renderInlineWithStyleTo(out, inner, restoreStyle)
+		}
+		return p.renderInlineWithStyleTo(out, inner, restoreStyle.withBold())
+	case "i", "em":
+		return p.renderInlineWithStyleTo(out, inner, restoreStyle.withItalic())
+	case "s", "strike", "del":
+		return p.renderInlineWithStyleTo(out, inner, restoreStyle.withStrikethrough())
+	case "code":
+		return p.renderInlineCode(out, decodeHTMLEntities(inner), restoreStyle)
+	default:
+		return p.renderInlineWithStyleTo(out, inner, restoreStyle)
+	}
+}
+
+// htmlTag is an HTML tag found in markdown text.
+type htmlTag struct {
+	name        string // lower-cased tag name
+	closing     bool   // </name>
+	selfClosing bool   // <name/>
+	length      int    // length of the tag, including the angle brackets
+}
+
+// parseHTMLTag parses the HTML tag at the start of s, e.g. <b>, </b> or
+// <br />. Text that merely looks like a tag, e.g. "a <b" or "Map<K, V>",
+// isn't one.
+func parseHTMLTag(s string) (htmlTag, bool) {
+	if len(s) < 3 || s[0] != '<' {
+		return htmlTag{}, false
+	}
+
+	var tag htmlTag
+	i := 1
+	if s[i] == '/' {
+		tag.closing = true
+		i++
+	}
+	start := i
+	if i >= len(s) || !isLetter(s[i]) {
+		return htmlTag{}, false
+	}
+	for i < len(s) && (isWord(s[i]) || s[i] == '-') {
+		i++
+	}
+	tag.name = strings.ToLower(s[start:i])
+
+	end := strings.IndexByte(s[i:], '>')
+	if end == -1 {
+		return htmlTag{}, false
+	}
+	attrs := s[i : i+end]
+	switch {
+	case attrs == "" || attrs == "/":
+	case attrs[0] != ' ' && attrs[0] != '\t':
+		return htmlTag{}, false
+	case strings.ContainsRune(attrs, '<'):
+		return htmlTag{}, false
+	case tag.closing && strings.TrimSpace(attrs) != "":
+		return htmlTag{}, false
+	}
+	tag.selfClosing = strings.HasSuffix(attrs, "/")
+	tag.length = i + end + 1
+	return tag, true
+}
+
+// indexClosingTag returns the index and length of the tag closing an
+// element named name in s, skipping nested elements with the same name,
+// or -1 if the element isn't closed.
+func indexClosingTag(s, name string) (int, int) {
+	depth := 1
+	for i := 0; i < len(s); {
+		j := strings.IndexByte(s[i:], '<')
+		if j == -1 {
+			break
+		}
+		i += j
+		tag, ok := parseHTMLTag(s[i:])
+		if !ok {
+			i++
+			continue
+		}
+		if tag.name == name && !tag.selfClosing {
+			if !tag.closing {
+				depth++
+			} else if depth--; depth == 0 {
+				return i, tag.length
+			}
+		}
+		i += tag.length
+	}
+	return -1, 0
+}
+
+// htmlTagDepth returns the number of elements named name opened in s minus
+// the number of the ones closed.
+func htmlTagDepth(s, name string) int {
+	depth := 0
+	for i := 0; i < len(s); {
+		j := strings.IndexByte(s[i:], '<')
+		if j == -1 {
+			break
+		}
+		i += j
+		tag, ok := parseHTMLTag(s[i:])
+		if !ok {
+			i++
+			continue
+		}
+		if tag.name == name && !tag.selfClosing {
+			if tag.closing {
+				depth--
+			} else {
+				depth++
+			}
+		}
+		i += tag.length
+	}
+	return depth
+}
+
+// htmlEntities are the HTML entities decoded in markdown text.
+var htmlEntities = []struct {
+	entity  string
+	decoded string
+}{
+	{"&amp;", "&"},
+	{"&lt;", "<"},
+	{"&gt;", ">"},
+	{"&quot;", "\""},
+	{"&nbsp;", "\u00a0"},
+	{"&#39;", "'"},
+	{"&apos;", "'"},
+}
+
+// decodeHTMLEntity decodes the HTML entity at the start of s. It returns the
+// decoded text and the length of the entity, or 0 if s doesn't start with one.
+func decodeHTMLEntity(s string) (string, int) {
+	for _, e := range htmlEntities {
+		if strings.HasPrefix(s, e.entity) {
+			return e.decoded, len(e.entity)
+		}
+	}
+	return "", 0
+}
+
+// decodeHTMLEntities decodes all the HTML entities in s.
+func decodeHTMLEntities(s string) string {
+	if !strings.Contains(s, "&") {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); {
+		if s[i] == '&' {
+			if decoded, length := decodeHTMLEntity(s[i:]); length > 0 {
+				b.WriteString(decoded)
+				i += length
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+		i++
+	}
+	return b.String()
+}
+
 // longestWordWidth returns the visual width of the longest word in text.
 // Words are separated by whitespace. Used to determine minimum column width.
 func longestWordWidth(s string) int {
@@ -1793,7 +2517,9 @@ func longestWordWidth(s string) int {
 }
 
 // textWidth calculates the visual width of plain text (no ANSI codes).
-// Optimized for ASCII-only strings which are common.
+// Text is measured by grapheme cluster, so that an emoji sequence, a flag or
+// a letter with combining marks counts as the one character the terminal
+// draws. Optimized for ASCII-only strings which are common.
 func textWidth(s string) int {
 	// Fast path for ASCII-only strings
 	isASCII := true
@@ -1807,11 +2533,16 @@ func textWidth(s string) int {
 		return len(s)
 	}
 	// Slow path for unicode
-	width := 0
-	for _, r := range s {
-		width += runewidth.RuneWidth(r)
+	return displaywidth.String(s)
+}
+
+// firstGrapheme returns the first grapheme cluster of s and its visual width.
+func firstGrapheme(s string) (string, int) {
+	g := displaywidth.StringGraphemes(s)
+	if !g.Next() {
+		return "", 0
 	}
-	return width
+	return g.Value(), g.Width()
 }
 
 func findClosingBracket(text string) int {
@@ -1831,11 +2562,16 @@ func findClosingBracket(text string) int {
 }
 
 func isWord(b byte) bool {
-	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+	return isLetter(b) || (b >= '0' && b <= '9')
+}
+
+func isLetter(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
 }
 
-// inlineMarkdownChars contains all characters that trigger inline markdown processing.
-const inlineMarkdownChars = "\\`*_~["
+// inlineMarkdownChars contains all characters that trigger inline markdown processing,
+// including the ones starting inline HTML tags and entities.
+const inlineMarkdownChars = "\\`*_~[<&"
 
 // hasInlineMarkdown checks if text contains any markdown formatting characters.
 // This allows a fast path to skip processing plain text.
@@ -1846,7 +2582,7 @@ func hasInlineMarkdown(text string) bool {
 
 func isInlineMarker(b byte) bool {
 	switch b {
-	case '\\', '`', '*', '_', '~', '[':
+	case '\\', '`', '*', '_', '~', '[', '<', '&':
 		return true
 	}
 	return false
@@ -1925,7 +2661,8 @@ func (p *parser) renderCodeBlockWithIndent(code, lang, indent string, availableW
 				remaining = contentWidth
 			}
 
-			// Single pass: track width and last whitespace within remaining
+			// Single pass: track width and last whitespace within remaining,
+			// by grapheme cluster so that none is ever split
 			lastSpacePos := -1
 			lastSpaceBytePos := -1
 			lastSpaceWidth := 0
@@ -1933,16 +2670,18 @@ func (p *parser) renderCodeBlockWithIndent(code, lang, indent string, availableW
 			width := 0
 			exceeded := false
 
-			for pos < len(segment) {
-				r, size := utf8.DecodeRuneInString(segment[pos:])
-				rw := runewidth.RuneWidth(r)
+			g := displaywidth.StringGraphemes(segment)
+			for g.Next() {
+				cluster := g.Value()
+				size := len(cluster)
+				rw := g.Width()
 
 				if width+rw > remaining {
 					exceeded = true
 					break
 				}
 
-				if r == ' ' || r == '\t' {
+				if cluster == " " || cluster == "\t" {
 					lastSpacePos = pos
 					lastSpaceBytePos = pos + size
 					lastSpaceWidth = width + rw
@@ -1979,10 +2718,10 @@ func (p *parser) renderCodeBlockWithIndent(code, lang, indent string, availableW
 				flushLine()
 			default:
 				// Nothing fits (remaining width too small) - write one char and continue
-				r, size := utf8.DecodeRuneInString(segment)
-				style.renderTo(&lineBuilder, string(r))
-				lineWidth += runewidth.RuneWidth(r)
-				segment = segment[size:]
+				cluster, rw := firstGrapheme(segment)
+				style.renderTo(&lineBuilder, cluster)
+				lineWidth += rw
+				segment = segment[len(cluster):]
 				flushLine()
 			}
 		}
@@ -2064,51 +2803,64 @@ func expandTabs(s string, currentWidth int) string {
 	}
 	var result strings.Builder
 	width := currentWidth
-	for _, r := range s {
-		if r == '\t' {
-			n := 4 - (width % 4)
-			result.WriteString(spaces(n))
-			width += n
-		} else {
-			result.WriteRune(r)
-			width += runewidth.RuneWidth(r)
+	for {
+		i := strings.IndexByte(s, '\t')
+		if i < 0 {
+			result.WriteString(s)
+			return result.String()
 		}
+		result.WriteString(s[:i])
+		width += textWidth(s[:i])
+		n := 4 - (width % 4)
+		result.WriteString(spaces(n))
+		width += n
+		s = s[i+1:]
 	}
-	return result.String()
 }
 
 // ansiStringWidth calculates display width while skipping ANSI escape sequences.
+// The text between sequences is measured with textWidth.
 func ansiStringWidth(s string) int {
 	width := 0
-	for i := 0; i < len(s); {
-		if s[i] == '\x1b' {
-			// Skip CSI sequences (e.g., \x1b[...m)
-			if i+1 < len(s) && s[i+1] == '[' {
-				i += 2
-				for i < len(s) && (s[i] < '@' || s[i] > '~') {
-					i++
-				}
-				if i < len(s) {
-					i++
-				}
-				continue
-			}
+	for s != "" {
+		i := strings.IndexByte(s, '\x1b')
+		if i < 0 {
+			return width + textWidth(s)
+		}
+		width += textWidth(s[:i])
+		// Skip CSI (e.g., \x1b[...m) and OSC (e.g., hyperlinks) sequences
+		s = s[i+ansiSequenceLen(s[i:]):]
+	}
+	return width
+}
+
+// ansiSequenceLen returns the length of the escape sequence at the start of s:
+// a CSI sequence (e.g., \x1b[1m), an OSC sequence terminated by BEL or ST
+// (e.g., \x1b]8;;url\x1b\\), or just the escape character otherwise.
+func ansiSequenceLen(s string) int {
+	if len(s) < 2 {
+		return len(s)
+	}
+	switch s[1] {
+	case '[':
+		i := 2
+		for i < len(s) && (s[i] < '@' || s[i] > '~') {
 			i++
-			continue
 		}
-		if s[i] < utf8.RuneSelf {
-			start := i
-			for i < len(s) && s[i] < utf8.RuneSelf && s[i] != '\x1b' {
-				i++
+		return min(i+1, len(s))
+	case ']':
+		for i := 2; i < len(s); i++ {
+			if s[i] == '\a' {
+				return i + 1
+			}
+			if s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '\\' {
+				return i + 2
 			}
-			width += i - start
-			continue
 		}
-		r, size := utf8.DecodeRuneInString(s[i:])
-		width += runewidth.RuneWidth(r)
-		i += size
+		return len(s)
+	default:
+		return 1
 	}
-	return width
 }
 
 // padAllLines pads each line to the target width with trailing spaces.
@@ -2307,6 +3059,19 @@ func (p *parser) wrapText(text string, width int) string {
 		return text
 	}
 
+	// Hard line breaks (e.g. from <br>) are kept: wrap each line on its own
+	if strings.IndexByte(text, '\n') != -1 {
+		var result strings.Builder
+		result.Grow(len(text) + len(text)/40)
+		for i, line := range strings.Split(text, "\n") {
+			if i > 0 {
+				result.WriteByte('\n')
+			}
+			result.WriteString(p.wrapText(line, width))
+		}
+		return result.String()
+	}
+
 	// Fast path: if the text fits in one line, return as-is
 	// This avoids expensive word splitting for short strings
 	textVisualWidth := ansiStringWidth(text)
@@ -2365,7 +3130,7 @@ func (p *parser) wrapText(text string, width int) string {
 			if currentLine.Len() > 0 {
 				// Close any active styles before line break
 				if len(stylesForWrap) > 0 {
-					currentLine.WriteString("\x1b[m")
+					writeStylesEnd(&currentLine, stylesForWrap)
 				}
 				result.WriteString(currentLine.String())
 				result.WriteByte('\n')
@@ -2400,7 +3165,7 @@ func (p *parser) wrapText(text string, width int) string {
 		if currentWidth+spaceWidth+wordWidth > width {
 			// Close any active styles before line break
 			if len(stylesForWrap) > 0 {
-				currentLine.WriteString("\x1b[m")
+				writeStylesEnd(&currentLine, stylesForWrap)
 			}
 			result.WriteString(currentLine.String())
 			result.WriteByte('\n')
@@ -2450,34 +3215,35 @@ func splitWordsWithStyles(text string) []styledWord {
 	words := make([]styledWord, 0, wordCount)
 	wordStart := -1 // Start index of current word (-1 means no word started)
 	wordWidth := 0  // Visual width of current word
+	textStart := -1 // Start index of the text of the word not measured yet
 	var currentAnsi []string
-	inAnsi := false
-	ansiStart := 0
+
+	// measure adds the width of the text of the word up to end, measured as
+	// a whole so that its grapheme clusters are never split.
+	measure := func(end int) {
+		if textStart >= 0 {
+			wordWidth += textWidth(text[textStart:end])
+			textStart = -1
+		}
+	}
 
 	for i := 0; i < len(text); {
 		if text[i] == '\x1b' {
-			// Start of ANSI sequence
+			// ANSI sequence - capture it whole, it's never split
 			if wordStart == -1 {
 				wordStart = i
 			}
-			inAnsi = true
-			ansiStart = i
-			i++
-			continue
-		}
-		if inAnsi {
-			if (text[i] >= 'a' && text[i] <= 'z') || (text[i] >= 'A' && text[i] <= 'Z') {
-				// End of ANSI sequence - capture it
-				currentAnsi = append(currentAnsi, text[ansiStart:i+1])
-				inAnsi = false
-			}
-			i++
+			measure(i)
+			n := ansiSequenceLen(text[i:])
+			currentAnsi = append(currentAnsi, text[i:i+n])
+			i += n
 			continue
 		}
 
 		if text[i] == ' ' || text[i] == '\t' {
 			// End of word
 			if wordStart >= 0 {
+				measure(i)
 				words = append(words, styledWord{
 					word:      text[wordStart:i],
 					ansiCodes: currentAnsi,
@@ -2491,17 +3257,19 @@ func splitWordsWithStyles(text string) []styledWord {
 			continue
 		}
 
-		// Regular character - decode and measure
+		// Regular character - measured with the rest of the text
 		if wordStart == -1 {
 			wordStart = i
 		}
-		r, size := utf8.DecodeRuneInString(text[i:])
-		wordWidth += runewidth.RuneWidth(r)
-		i += size
+		if textStart == -1 {
+			textStart = i
+		}
+		i++
 	}
 
 	// Don't forget the last word
 	if wordStart >= 0 {
+		measure(len(text))
 		words = append(words, styledWord{
 			word:      text[wordStart:],
 			ansiCodes: currentAnsi,
@@ -2512,14 +3280,22 @@ func splitWordsWithStyles(text string) []styledWord {
 	return words
 }
 
-// updateActiveStyles updates the list of active ANSI styles based on new codes
+// updateActiveStyles updates the list of active ANSI styles based on new codes.
+// Hyperlinks are tracked too, so that they can be ended and restarted around
+// line breaks.
 func updateActiveStyles(active, newCodes []string) []string {
 	for _, code := range newCodes {
-		// Check if this is a reset sequence
-		if code == "\x1b[m" || code == "\x1b[0m" {
+		switch {
+		case isHyperlink(code):
+			// Only one hyperlink is active at a time, SGR resets don't end it
+			active = slices.DeleteFunc(active, isHyperlink)
+			if code != hyperlinkEnd {
+				active = append(active, code)
+			}
+		case code == "\x1b[m" || code == "\x1b[0m":
 			// Clear all active styles
-			active = active[:0]
-		} else {
+			active = slices.DeleteFunc(active, func(c string) bool { return !isHyperlink(c) })
+		default:
 			// Add this style to active list
 			active = append(active, code)
 		}
@@ -2527,6 +3303,17 @@ func updateActiveStyles(active, newCodes []string) []string {
 	return active
 }
 
+// writeStylesEnd ends the active styles, and hyperlink if any, before a line break.
+func writeStylesEnd(b *strings.Builder, active []string) {
+	b.WriteString("\x1b[m")
+	if slices.ContainsFunc(active, isHyperlink) {
+		b.WriteString(hyperlinkEnd)
+	}
+}
+
+// breakWord splits a word wider than maxWidth into parts of at most maxWidth,
+// between grapheme clusters: a cluster is never split, and one wider than
+// maxWidth gets a part of its own. ANSI sequences are kept whole.
 func breakWord(word string, maxWidth int) []string {
 	if maxWidth <= 0 {
 		return []string{word}
@@ -2535,39 +3322,51 @@ func breakWord(word string, maxWidth int) []string {
 	var parts []string
 	var current strings.Builder
 	currentWidth := 0
-	inAnsi := false
-	var ansiSeq strings.Builder
+	// hyperlink is the sequence starting the active hyperlink, if any. It's
+	// ended at the end of each part and restarted on the next one.
+	var hyperlink string
 
 	for i := 0; i < len(word); {
 		if word[i] == '\x1b' {
-			inAnsi = true
-			ansiSeq.WriteByte(word[i])
-			i++
-			continue
-		}
-		if inAnsi {
-			ansiSeq.WriteByte(word[i])
-			if (word[i] >= 'a' && word[i] <= 'z') || (word[i] >= 'A' && word[i] <= 'Z') {
-				inAnsi = false
-				current.WriteString(ansiSeq.String())
-				ansiSeq.Reset()
+			// Never split ANSI sequences
+			n := ansiSequenceLen(word[i:])
+			seq := word[i : i+n]
+			if isHyperlink(seq) {
+				hyperlink = seq
+				if seq == hyperlinkEnd {
+					hyperlink = ""
+				}
 			}
-			i++
+			current.WriteString(seq)
+			i += n
 			continue
 		}
 
-		r, size := utf8.DecodeRuneInString(word[i:])
-		rw := runewidth.RuneWidth(r)
-
-		if currentWidth+rw > maxWidth && currentWidth > 0 {
-			parts = append(parts, current.String())
-			current.Reset()
-			currentWidth = 0
+		end := strings.IndexByte(word[i:], '\x1b')
+		if end < 0 {
+			end = len(word)
+		} else {
+			end += i
 		}
 
-		current.WriteRune(r)
-		currentWidth += rw
-		i += size
+		g := displaywidth.StringGraphemes(word[i:end])
+		for g.Next() {
+			rw := g.Width()
+
+			if currentWidth+rw > maxWidth && currentWidth > 0 {
+				if hyperlink != "" {
+					current.WriteString(hyperlinkEnd)
+				}
+				parts = append(parts, current.String())
+				current.Reset()
+				current.WriteString(hyperlink)
+				currentWidth = 0
+			}
+
+			current.WriteString(g.Value())
+			currentWidth += rw
+		}
+		i = end
 	}
 
 	if current.Len() > 0 {
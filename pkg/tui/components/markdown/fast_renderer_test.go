@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/charmbracelet/x/ansi"
+	"github.com/clipperhouse/displaywidth"
 	runewidth "github.com/mattn/go-runewidth"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -64,6 +65,68 @@ func TestFastRendererHeadings(t *testing.T) {
 	}
 }
 
+func TestFastRendererSetextHeadings(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"h1", "Heading 1\n=========\n\nText", "# Heading 1\n\nText"},
+		{"h2", "Heading 2\n---------\n\nText", "## Heading 2\n\nText"},
+		{"short underline", "Heading\n-", "## Heading"},
+		{"indented underline", "Heading\n  ===  ", "# Heading"},
+		{"multi-line", "A long\nheading\n===", "# A long heading"},
+		{"no blank line after", "Heading\n---\nText", "## Heading\n\nText"},
+		{"spaced rule", "Text\n- - -", "Text\n\n---"},
+		{"rule after blank line", "Text\n\n---", "Text\n\n***"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			want, err := NewFastRenderer(80).Render(tt.want)
+			require.NoError(t, err)
+			got, err := NewFastRenderer(80).Render(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+
+	// An underline without a paragraph above it is just text.
+	result, err := NewFastRenderer(80).Render("===")
+	require.NoError(t, err)
+	assert.Equal(t, "===", strings.TrimSpace(stripANSI(result)))
+}
+
+func TestFastRendererHeadingCallback(t *testing.T) {
+	t.Parallel()
+
+	input := "# Title\n\nIntro text.\n\nSection\n-------\n\n```go\n# not a heading\n```\n\n### *Details*\n"
+
+	var headings []Heading
+	r := NewFastRenderer(80, WithHeadingCallback(func(h Heading) {
+		headings = append(headings, h)
+	}))
+	result, err := r.Render(input)
+	require.NoError(t, err)
+
+	require.Len(t, headings, 3)
+	assert.Equal(t, Heading{Level: 1, Text: "Title", Line: 0}, headings[0])
+	assert.Equal(t, 2, headings[1].Level)
+	assert.Equal(t, "Section", headings[1].Text)
+	assert.Equal(t, 3, headings[2].Level)
+	assert.Equal(t, "Details", headings[2].Text)
+
+	lines := strings.Split(stripANSI(result), "\n")
+	for _, h := range headings {
+		require.Less(t, h.Line, len(lines))
+		assert.Contains(t, lines[h.Line], h.Text)
+	}
+}
+
 func TestFastRendererCodeBlocks(t *testing.T) {
 	t.Parallel()
 
@@ -395,6 +458,126 @@ func TestFastRendererLinks(t *testing.T) {
 	assert.Contains(t, plain, "example.com")
 }
 
+var hyperlinkRegex = regexp.MustCompile(`\x1b\]8;;([^\x1b]*)\x1b\\`)
+
+// hyperlinkTargets returns the URLs of the hyperlinks started in s.
+func hyperlinkTargets(s string) []string {
+	var urls []string
+	for _, m := range hyperlinkRegex.FindAllStringSubmatch(s, -1) {
+		if m[1] != "" {
+			urls = append(urls, m[1])
+		}
+	}
+	return urls
+}
+
+func TestFastRendererHyperlinks(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		plain    string
+		urls     []string
+		fallback string
+	}{
+		{
+			name:     "link",
+			input:    "Check out [this link](https://example.com)",
+			plain:    "Check out this link",
+			urls:     []string{"https://example.com"},
+			fallback: "Check out this link (https://example.com)",
+		},
+		{
+			name:     "bare URL",
+			input:    "See https://example.com/a_b_c?q=1. Or not",
+			plain:    "See https://example.com/a_b_c?q=1. Or not",
+			urls:     []string{"https://example.com/a_b_c?q=1"},
+			fallback: "See https://example.com/a_b_c?q=1. Or not",
+		},
+		{
+			name:     "bare URL in parentheses",
+			input:    "(see http://example.com/wiki/Go_(language))",
+			plain:    "(see http://example.com/wiki/Go_(language))",
+			urls:     []string{"http://example.com/wiki/Go_(language)"},
+			fallback: "(see http://example.com/wiki/Go_(language))",
+		},
+		{
+			name:     "autolink",
+			input:    "Go to <https://example.com>",
+			plain:    "Go to https://example.com",
+			urls:     []string{"https://example.com"},
+			fallback: "Go to https://example.com",
+		},
+		{
+			name:     "not a URL",
+			input:    "The http package and https:// alone",
+			plain:    "The http package and https:// alone",
+			fallback: "The http package and https:// alone",
+		},
+		{
+			name:     "URL with spaces",
+			input:    "[text](https://example.com/a b)",
+			plain:    "text (https://example.com/a b)",
+			fallback: "text (https://example.com/a b)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := NewFastRenderer(80, WithHyperlinks(true)).Render(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.urls, hyperlinkTargets(result))
+			assert.Equal(t, tt.plain, strings.TrimRight(stripANSI(hyperlinkRegex.ReplaceAllString(result, "")), " "))
+			assert.Equal(t, 80, ansiStringWidth(result))
+
+			result, err = NewFastRenderer(80).Render(tt.input)
+			require.NoError(t, err)
+			assert.NotContains(t, result, "\x1b]8;")
+			assert.Equal(t, tt.fallback, strings.TrimRight(stripANSI(result), " "))
+		})
+	}
+}
+
+func TestFastRendererHyperlinksWrapping(t *testing.T) {
+	t.Parallel()
+
+	inputs := []string{
+		"Read [the very long documentation page](https://example.com/docs) for details",
+		"Visit https://example.com/a/very/long/path/that/does/not/fit/on/one/line now",
+		"| Link |\n|---|\n| [some long link text](https://example.com) |",
+		"- item with [a long link text here](https://example.com)",
+	}
+
+	for _, input := range inputs {
+		result, err := NewFastRenderer(20, WithHyperlinks(true)).Render(input)
+		require.NoError(t, err)
+		require.NotEmpty(t, hyperlinkTargets(result), input)
+
+		// Every line ends the hyperlinks it starts and has the right width
+		for i, line := range strings.Split(result, "\n") {
+			started := len(hyperlinkTargets(line))
+			ended := strings.Count(line, hyperlinkEnd)
+			assert.Equal(t, started, ended, "line %d of %q: %q", i, input, line)
+			assert.Equal(t, 20, ansiStringWidth(line), "line %d of %q: %q", i, input, line)
+		}
+	}
+}
+
+func TestAnsiSequenceLen(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 4, ansiSequenceLen("\x1b[1mtext"))
+	assert.Equal(t, 9, ansiSequenceLen("\x1b[38;5;1mtext"))
+	assert.Equal(t, 20, ansiSequenceLen("\x1b]8;;https://a.com\x1b\\text"))
+	assert.Equal(t, 16, ansiSequenceLen("\x1b]8;;http://a.b\atext"))
+	assert.Equal(t, 6, ansiSequenceLen("\x1b]8;;h"))
+	assert.Equal(t, 1, ansiSequenceLen("\x1bx"))
+	assert.Equal(t, 5, ansiStringWidth("\x1b]8;;https://example.com\x1b\\hello\x1b]8;;\x1b\\"))
+}
+
 func TestFastRendererUnorderedLists(t *testing.T) {
 	t.Parallel()
 
@@ -428,6 +611,155 @@ func TestFastRendererOrderedLists(t *testing.T) {
 	assert.Contains(t, result, "Third")
 }
 
+func TestFastRendererOrderedListNumbering(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "source numbers",
+			input: "1. First\n2. Second\n3. Third",
+			want:  []string{"1. First", "2. Second", "3. Third"},
+		},
+		{
+			name:  "start number",
+			input: "3. Third\n4. Fourth",
+			want:  []string{"3. Third", "4. Fourth"},
+		},
+		{
+			name:  "repeated numbers",
+			input: "1. First\n1. Second\n1. Third",
+			want:  []string{"1. First", "2. Second", "3. Third"},
+		},
+		{
+			name:  "nested",
+			input: "1. First\n   1. Sub a\n   2. Sub b\n2. Second\n   1. Sub c",
+			want:  []string{"1. First", "   1. Sub a", "   2. Sub b", "2. Second", "   1. Sub c"},
+		},
+		{
+			name:  "nested bullets",
+			input: "1. First\n   - Detail\n2. Second",
+			want:  []string{"1. First", "   - Detail", "2. Second"},
+		},
+		{
+			name:  "nested under wide numbers",
+			input: "10. Tenth\n    1. Sub",
+			want:  []string{"10. Tenth", "    1. Sub"},
+		},
+		{
+			name:  "blank lines between items",
+			input: "1. First\n\n2. Second",
+			want:  []string{"1. First", "2. Second"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := NewFastRenderer(80).Render(tt.input)
+			require.NoError(t, err)
+
+			var lines []string
+			for l := range strings.SplitSeq(stripANSI(result), "\n") {
+				lines = append(lines, strings.TrimRight(l, " "))
+			}
+			assert.Equal(t, tt.want, lines)
+		})
+	}
+}
+
+func TestFastRendererOrderedListWrapping(t *testing.T) {
+	t.Parallel()
+
+	input := "9. Ninth\n10. This is a long item that wraps when rendered at a narrow width"
+
+	result, err := NewFastRenderer(30).Render(input)
+	require.NoError(t, err)
+
+	lines := strings.Split(stripANSI(result), "\n")
+	require.Greater(t, len(lines), 2)
+	assert.True(t, strings.HasPrefix(lines[0], "9. Ninth"))
+	assert.True(t, strings.HasPrefix(lines[1], "10. This"))
+
+	// Continuation lines are aligned with the content, after "10. "
+	for _, line := range lines[2:] {
+		assert.True(t, strings.HasPrefix(line, "    "), "Continuation line should be indented: %q", line)
+		assert.NotEqual(t, ' ', line[4], "Continuation line is indented too much: %q", line)
+	}
+}
+
+func TestFastRendererDefinitionLists(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "single entry",
+			input: "Term\n: Definition of the term",
+			want:  []string{"Term", "    Definition of the term"},
+		},
+		{
+			name:  "multiple definitions",
+			input: "Term\n: First definition\n: Second definition",
+			want:  []string{"Term", "    First definition", "    Second definition"},
+		},
+		{
+			name:  "multiple entries",
+			input: "Apple\n: A fruit\nCarrot\n: A vegetable\n\nPotato\n: A tuber",
+			want:  []string{"Apple", "    A fruit", "Carrot", "    A vegetable", "", "Potato", "    A tuber"},
+		},
+		{
+			name:  "continuation lines",
+			input: "Term\n: A definition\n  on two lines",
+			want:  []string{"Term", "    A definition on two lines"},
+		},
+		{
+			name:  "after a paragraph",
+			input: "Some text.\nTerm\n: Definition\n\nMore text.",
+			want:  []string{"Some text.", "", "Term", "    Definition", "", "More text."},
+		},
+		{
+			name:  "colon without term",
+			input: ": not a definition",
+			want:  []string{": not a definition"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := NewFastRenderer(80).Render(tt.input)
+			require.NoError(t, err)
+
+			var lines []string
+			for l := range strings.SplitSeq(stripANSI(result), "\n") {
+				lines = append(lines, strings.TrimRight(l, " "))
+			}
+			assert.Equal(t, tt.want, lines)
+		})
+	}
+}
+
+func TestFastRendererDefinitionTermIsBold(t *testing.T) {
+	t.Parallel()
+
+	result, err := NewFastRenderer(80).Render("Term\n: Definition")
+	require.NoError(t, err)
+
+	lines := strings.Split(result, "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "\x1b[1m")
+	assert.NotContains(t, lines[1], "\x1b[1m")
+}
+
 func TestFastRendererTaskLists(t *testing.T) {
 	t.Parallel()
 
@@ -533,6 +865,7 @@ func TestFastRendererTablesColumnAlignment(t *testing.T) {
 	tests := []struct {
 		name  string
 		input string
+		width int
 	}{
 		{
 			name: "plain text columns",
@@ -540,6 +873,7 @@ func TestFastRendererTablesColumnAlignment(t *testing.T) {
 |------|-----|------|
 | Alice | 30 | New York |
 | Bob | 25 | LA |`,
+			width: 80,
 		},
 		{
 			name: "styled content (bold, italic, code)",
@@ -548,13 +882,38 @@ func TestFastRendererTablesColumnAlignment(t *testing.T) {
 | **Bold** | Done | This is bold |
 | *Italic* | WIP | This is italic |
 | ` + "`Code`" + ` | Todo | Inline code |`,
+			width: 80,
+		},
+		{
+			name: "aligned columns",
+			input: `| Left | Center | Right |
+|:-----|:------:|------:|
+| a | **bold** | 1 |
+| longer text | b | ` + "`12345`" + ` |`,
+			width: 80,
+		},
+		{
+			name: "wide table",
+			input: `| Name | Description | Notes |
+|------|-------------|-------|
+| Alice | A very long description that cannot fit in a narrow terminal at all | Short |
+| Bob | Short | Another **long** note with ` + "`code`" + ` that needs to wrap as well |`,
+			width: 40,
+		},
+		{
+			name: "wide aligned table",
+			input: `| Name | Description | Amount |
+|:----:|:------------|-------:|
+| Alice | A very long description that cannot fit in a narrow terminal at all | 1,000 |
+| Bob | Short | 25 |`,
+			width: 40,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-			r := NewFastRenderer(80)
+			r := NewFastRenderer(tt.width)
 			result, err := r.Render(tt.input)
 			require.NoError(t, err)
 			assertTableColumnsAligned(t, result)
@@ -562,6 +921,187 @@ func TestFastRendererTablesColumnAlignment(t *testing.T) {
 	}
 }
 
+func TestFastRendererTableAlignment(t *testing.T) {
+	t.Parallel()
+
+	input := `| Left | Center | Right |
+|:-----|:------:|------:|
+| a | b | c |
+| **xx** | ` + "`yy`" + ` | zz |`
+
+	r := NewFastRenderer(80)
+	result, err := r.Render(input)
+	require.NoError(t, err)
+
+	lines := strings.Split(stripANSI(result), "\n")
+	require.GreaterOrEqual(t, len(lines), 5)
+
+	// Header, separator, first row, blank row, second row
+	assert.Equal(t, "Left │ Center │ Right", strings.TrimRight(lines[0], " "))
+	assert.Equal(t, "a    │   b    │     c", strings.TrimRight(lines[2], " "))
+	assert.Equal(t, "xx   │   yy   │    zz", strings.TrimRight(lines[4], " "))
+}
+
+func TestParseTableAlignments(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t,
+		[]tableAlignment{alignLeft, alignLeft, alignCenter, alignRight},
+		parseTableAlignments("| --- |:--- | :-: | ---: |", 4))
+	// Missing markers default to left, extra ones are ignored
+	assert.Equal(t,
+		[]tableAlignment{alignRight, alignLeft, alignLeft},
+		parseTableAlignments("---:|---", 3))
+	assert.Equal(t,
+		[]tableAlignment{alignCenter},
+		parseTableAlignments(":---:|---:", 1))
+}
+
+func TestFastRendererInlineHTML(t *testing.T) {
+	t.Parallel()
+
+	// HTML tags render like their markdown equivalent
+	sameAs := []struct {
+		html     string
+		markdown string
+	}{
+		{"<b>bold</b> text", "**bold** text"},
+		{"<strong>bold</strong> text", "**bold** text"},
+		{"<i>italic</i> text", "*italic* text"},
+		{"<em>italic</em> text", "*italic* text"},
+		{"<s>gone</s> text", "~~gone~~ text"},
+		{"<del>gone</del> text", "~~gone~~ text"},
+		{"<code>x &lt; y</code>", "`x < y`"},
+		{"<B>upper</B> case", "**upper** case"},
+		{"<b>bold</b> and <i>italic</i>", "**bold** and *italic*"},
+		{"# Title <b>bold</b>", "# Title **bold**"},
+		{"## <code>code</code> heading", "## `code` heading"},
+		{"- item <i>italic</i>", "- item *italic*"},
+	}
+	for _, tt := range sameAs {
+		want, err := NewFastRenderer(80).Render(tt.markdown)
+		require.NoError(t, err)
+		got, err := NewFastRenderer(80).Render(tt.html)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, "rendering %q", tt.html)
+	}
+
+	plain := []struct {
+		input string
+		want  string
+	}{
+		{"a &amp; b &lt;c&gt; &quot;d&quot; &#39;e&#39;", `a & b <c> "d" 'e'`},
+		{"<span class=\"x\">kept</span> content", "kept content"},
+		{"Vec<T> and Map<K, V> stay", "Vec<T> and Map<K, V> stay"},
+		{"a < b and c > d", "a < b and c > d"},
+		{"unclosed <b>tag", "unclosed <b>tag"},
+		{"&unknown; entity", "&unknown; entity"},
+		{"`<b>code</b>`", "<b>code</b>"},
+		{"first<br>second<br/>third<br />fourth", "first\nsecond\nthird\nfourth"},
+		{"# Heading<br>continued", "Heading\ncontinued"},
+	}
+	for _, tt := range plain {
+		result, err := NewFastRenderer(80).Render(tt.input)
+		require.NoError(t, err)
+
+		var lines []string
+		for l := range strings.SplitSeq(stripANSI(result), "\n") {
+			lines = append(lines, strings.TrimSpace(strings.TrimLeft(l, "#")))
+		}
+		assert.Equal(t, tt.want, strings.Join(lines, "\n"), "rendering %q", tt.input)
+	}
+}
+
+func TestFastRendererNonBreakingSpace(t *testing.T) {
+	t.Parallel()
+
+	result, err := NewFastRenderer(10).Render("aaaa bbbb&nbsp;cccc")
+	require.NoError(t, err)
+
+	lines := strings.Split(stripANSI(result), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, "bbbb\u00a0cccc", strings.TrimRight(lines[1], " "))
+}
+
+func TestFastRendererTableInlineHTML(t *testing.T) {
+	t.Parallel()
+
+	input := `| Name | Notes |
+|------|-------|
+| <b>Alice</b> | first line<br>second line |
+| Bob | a &amp; b |`
+
+	result, err := NewFastRenderer(80).Render(input)
+	require.NoError(t, err)
+	assertTableColumnsAligned(t, result)
+
+	plain := stripANSI(result)
+	assert.NotContains(t, plain, "<b>")
+	assert.NotContains(t, plain, "<br>")
+	assert.Contains(t, plain, "a & b")
+
+	lines := strings.Split(plain, "\n")
+	require.GreaterOrEqual(t, len(lines), 4)
+	assert.Equal(t, "Alice │ first line", strings.TrimRight(lines[2], " "))
+	assert.Equal(t, "      │ second line", strings.TrimRight(lines[3], " "))
+}
+
+func TestFastRendererDetails(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "summary and body",
+			input: "<details>\n<summary>Click <b>me</b></summary>\n\nHidden **text**\n\nSecond paragraph\n\n</details>\n\nAfter",
+			want:  []string{"▸ Click me", "  Hidden text", "", "  Second paragraph", "", "After"},
+		},
+		{
+			name:  "single line",
+			input: "<details><summary>Summary</summary>Body</details>",
+			want:  []string{"▸ Summary", "  Body"},
+		},
+		{
+			name:  "no summary",
+			input: "<details>\nBody\n</details>",
+			want:  []string{"▸ Details", "  Body"},
+		},
+		{
+			name:  "nested",
+			input: "<details>\n<summary>Outer</summary>\n\n<details>\n<summary>Inner</summary>\n\nDeep\n</details>\n\n</details>\nAfter",
+			want:  []string{"▸ Outer", "  ▸ Inner", "    Deep", "", "After"},
+		},
+		{
+			name:  "interrupts a paragraph",
+			input: "Before\n<details>\n<summary>Summary</summary>\nBody\n</details>",
+			want:  []string{"Before", "", "▸ Summary", "  Body"},
+		},
+		{
+			name:  "unclosed",
+			input: "<details>\n<summary>Streaming</summary>\n\nPartial",
+			want:  []string{"▸ Streaming", "  Partial"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := NewFastRenderer(80).Render(tt.input)
+			require.NoError(t, err)
+
+			var lines []string
+			for l := range strings.SplitSeq(stripANSI(result), "\n") {
+				lines = append(lines, strings.TrimRight(l, " "))
+			}
+			assert.Equal(t, tt.want, lines)
+		})
+	}
+}
+
 func TestFastRendererTableViewportWidth(t *testing.T) {
 	t.Parallel()
 
@@ -842,6 +1382,101 @@ func TestFastRendererFixedWidthRectangle(t *testing.T) {
 	}
 }
 
+// clusterFixtures are grapheme clusters of several code points: a ZWJ
+// emoji sequence, a flag, an emoji with VS16 and a letter with a combining
+// mark. Splitting one of them shows as broken characters in the terminal.
+var clusterFixtures = []string{"👩\u200d💻", "🇫🇷", "❤\ufe0f", "e\u0301"}
+
+// assertLinesFitClusters asserts that each line of rendered measures width
+// columns, as the terminal draws it, and that no grapheme cluster of the
+// input was split across lines.
+func assertLinesFitClusters(t *testing.T, input, rendered string, width int) {
+	t.Helper()
+
+	lines := strings.Split(rendered, "\n")
+	for i, line := range lines {
+		plain := stripANSI(line)
+		assert.Equal(t, width, displaywidth.String(plain), "line %d: %q", i, plain)
+	}
+	for _, cluster := range clusterFixtures {
+		found := 0
+		for _, line := range lines {
+			found += strings.Count(line, cluster)
+		}
+		assert.Equal(t, strings.Count(input, cluster), found, "cluster %q was split", cluster)
+	}
+}
+
+func TestFastRendererGraphemeClustersParagraph(t *testing.T) {
+	t.Parallel()
+
+	input := "Hello 👩\u200d💻 from 🇫🇷 with ❤\ufe0f, 日本語のテキスト and cafe\u0301. " +
+		"👩\u200d💻👩\u200d💻👩\u200d💻🇫🇷🇫🇷❤\ufe0f❤\ufe0f日本語e\u0301e\u0301e\u0301 done"
+
+	for width := 10; width <= 40; width++ {
+		r := NewFastRenderer(width)
+		result, err := r.Render(input)
+		require.NoError(t, err)
+
+		assertLinesFitClusters(t, input, result, width)
+	}
+}
+
+func TestFastRendererGraphemeClustersTable(t *testing.T) {
+	t.Parallel()
+
+	input := "| Who | What |\n" +
+		"|-----|------|\n" +
+		"| 👩\u200d💻 dev | 日本語のテキスト ❤\ufe0f 🇫🇷🇫🇷🇫🇷 |\n" +
+		"| cafe\u0301 | 👩\u200d💻👩\u200d💻👩\u200d💻 e\u0301e\u0301 中文 |"
+
+	for width := 30; width <= 60; width++ {
+		r := NewFastRenderer(width)
+		result, err := r.Render(input)
+		require.NoError(t, err)
+
+		assertLinesFitClusters(t, input, result, width)
+	}
+}
+
+func TestFastRendererGraphemeClustersCodeBlock(t *testing.T) {
+	t.Parallel()
+
+	input := "```\nfmt.Println(\"👩\u200d💻 日本語 🇫🇷❤\ufe0f\") // e\u0301e\u0301 👩\u200d💻👩\u200d💻👩\u200d💻\n```"
+
+	for width := 10; width <= 40; width++ {
+		r := NewFastRenderer(width)
+		result, err := r.Render(input)
+		require.NoError(t, err)
+
+		assertLinesFitClusters(t, input, result, width)
+	}
+}
+
+func TestBreakWordKeepsClusters(t *testing.T) {
+	t.Parallel()
+
+	word := "👩\u200d💻🇫🇷❤\ufe0f日本e\u0301e\u0301"
+	assert.Equal(t, []string{"👩\u200d💻", "🇫🇷", "❤\ufe0f", "日", "本", "e\u0301e\u0301"}, breakWord(word, 2))
+	assert.Equal(t, []string{"👩\u200d💻🇫🇷", "❤\ufe0f日", "本e\u0301e\u0301"}, breakWord(word, 4))
+
+	// A cluster wider than the maximum width gets a part of its own
+	assert.Equal(t, []string{"🇫🇷", "a"}, breakWord("🇫🇷a", 1))
+}
+
+func TestSplitWordsWithStylesWidths(t *testing.T) {
+	t.Parallel()
+
+	text := "👩\u200d💻 \x1b[1m🇫🇷🇯🇵\x1b[m cafe\u0301 日本語"
+	words := splitWordsWithStyles(text)
+	widths := make([]int, 0, len(words))
+	for _, w := range words {
+		widths = append(widths, w.width)
+	}
+	assert.Equal(t, []int{2, 4, 4, 6}, widths)
+	assert.Equal(t, 2+1+4+1+4+1+6, ansiStringWidth(text))
+}
+
 func TestFastRendererRendererInterface(t *testing.T) {
 	t.Parallel()
 
@@ -1186,6 +1821,20 @@ This is a paragraph.
 			input: "This has **bold** and *italic* text that needs proper width handling.",
 			width: 40,
 		},
+		{
+			name: "ordered list",
+			input: `9. Ninth item
+10. Tenth item with enough text to wrap at this width
+    1. Nested item with enough text to wrap as well`,
+			width: 40,
+		},
+		{
+			name: "definition list",
+			input: `Term
+: A definition with enough text to wrap at this width
+: Another definition`,
+			width: 40,
+		},
 	}
 
 	for _, tt := range tests {
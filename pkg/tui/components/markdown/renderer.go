@@ -11,9 +11,10 @@ type Renderer interface {
 	Render(input string) (string, error)
 }
 
-// NewRenderer creates a new markdown renderer with the given width.
+// NewRenderer creates a new markdown renderer with the given width. Links are
+// rendered as hyperlinks when the terminal supports them.
 func NewRenderer(width int) Renderer {
-	return NewFastRenderer(width)
+	return NewFastRenderer(width, WithHyperlinks(styles.SupportsHyperlinks()))
 }
 
 // NewGlamourRenderer creates a markdown renderer using glamour.
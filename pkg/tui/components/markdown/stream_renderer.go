@@ -0,0 +1,205 @@
+package markdown
+
+import "strings"
+
+// StreamRenderer renders markdown that arrives in chunks, e.g. a streamed
+// assistant message. Instead of re-rendering the whole content on every
+// chunk, it caches the rendered lines of the blocks that can't change
+// anymore and only re-parses the content from the last stable block
+// boundary. Its output is always the same as rendering the whole content
+// at once with a FastRenderer of the same width.
+type StreamRenderer struct {
+	width      int
+	hyperlinks bool
+
+	// source is the sanitized content appended so far.
+	source strings.Builder
+
+	// blocks are the stable blocks whose rendered lines are cached, in order.
+	blocks []streamBlock
+	// lines holds the rendered lines of the stable blocks followed by the
+	// ones of the tail of the content.
+	lines []string
+	// visible is the number of lines returned by Lines.
+	visible int
+	dirty   bool
+	// generation is the styles generation the cached lines were rendered with.
+	generation uint64
+}
+
+// streamBlock is a stable run of top-level blocks.
+type streamBlock struct {
+	// end is the offset, in the source, of the line following the blocks.
+	end int
+	// lines is the number of rendered lines up to the end of the blocks.
+	lines int
+	// trailingBlank is the number of blank lines ending the rendered blocks,
+	// which are trimmed when nothing is rendered after them.
+	trailingBlank int
+}
+
+// NewStreamRenderer creates a new streaming markdown renderer with the given
+// width. It takes the same options as NewFastRenderer, except
+// WithHeadingCallback which is ignored.
+func NewStreamRenderer(width int, opts ...FastRendererOption) *StreamRenderer {
+	return &StreamRenderer{
+		width:      width,
+		hyperlinks: NewFastRenderer(width, opts...).hyperlinks,
+		generation: stylesGeneration.Load(),
+	}
+}
+
+// Append adds a chunk of content.
+func (r *StreamRenderer) Append(delta string) {
+	if delta == "" {
+		return
+	}
+	r.source.WriteString(sanitizeForTerminal(delta))
+	r.dirty = true
+}
+
+// Reset discards the content and the cached lines.
+func (r *StreamRenderer) Reset() {
+	r.source.Reset()
+	r.InvalidateFrom(0)
+}
+
+// SetWidth changes the width of the rendered lines. All the cached lines are
+// invalidated when the width changes.
+func (r *StreamRenderer) SetWidth(width int) {
+	if width == r.width {
+		return
+	}
+	r.width = width
+	r.InvalidateFrom(0)
+}
+
+// InvalidateFrom discards the cached blocks rendered at or after the given
+// line, so that they're rendered again on the next call to Lines.
+func (r *StreamRenderer) InvalidateFrom(line int) {
+	kept := 0
+	for kept < len(r.blocks) && r.blocks[kept].lines <= line {
+		kept++
+	}
+	r.blocks = r.blocks[:kept]
+	r.dirty = true
+}
+
+// Lines returns the rendered lines of the content appended so far. The
+// returned slice must not be modified and is only valid until the next
+// call to a method of the renderer.
+func (r *StreamRenderer) Lines() []string {
+	// Lines rendered before ResetStyles use the colors of the previous theme.
+	if generation := stylesGeneration.Load(); generation != r.generation {
+		r.generation = generation
+		r.InvalidateFrom(0)
+	}
+	if r.dirty {
+		r.render()
+		r.dirty = false
+	}
+	return r.lines[:r.visible]
+}
+
+// String returns the rendered content, like FastRenderer.Render would.
+func (r *StreamRenderer) String() string {
+	return strings.Join(r.Lines(), "\n")
+}
+
+// stable returns the last stable block, or a zero block if there is none.
+func (r *StreamRenderer) stable() streamBlock {
+	if len(r.blocks) == 0 {
+		return streamBlock{}
+	}
+	return r.blocks[len(r.blocks)-1]
+}
+
+// render re-renders the content following the last stable block and caches
+// the rendered lines of the blocks that became stable.
+func (r *StreamRenderer) render() {
+	stable := r.stable()
+	tail := r.source.String()[stable.end:]
+
+	p := parserPool.Get().(*parser)
+	defer parserPool.Put(p)
+	p.reset(tail, r.width)
+	p.hyperlinks = r.hyperlinks
+
+	// A block can only be cached once the lines the parser looked at to
+	// find its end are complete: the line following it and, for lists and
+	// footnotes, the one after. The last line is never complete.
+	complete := len(p.lines) - 1
+
+	// Offset, in the tail, of each line.
+	offsets := make([]int, len(p.lines))
+	for i := 1; i < len(p.lines); i++ {
+		offsets[i] = offsets[i-1] + len(p.lines[i-1]) + 1
+	}
+
+	cachedLine, cachedOut := 0, 0
+	for p.lineIdx < len(p.lines) {
+		p.parseBlock()
+		if p.lineIdx+2 > complete {
+			continue
+		}
+		// Only cache whole lines.
+		if out := p.out.String(); out == "" || out[len(out)-1] == '\n' {
+			cachedLine, cachedOut = p.lineIdx, p.out.Len()
+		}
+	}
+
+	out := p.out.String()
+	r.lines = r.lines[:stable.lines]
+	if cachedLine > 0 {
+		cached := out[:cachedOut]
+		if cached != "" {
+			r.lines = r.appendLines(r.lines, strings.TrimSuffix(cached, "\n"))
+		}
+		trailingBlank := len(cached) - len(strings.TrimRight(cached, "\n"))
+		if trailingBlank == len(cached) {
+			// Nothing but blank lines
+			trailingBlank += stable.trailingBlank
+		} else {
+			trailingBlank--
+		}
+		stable = streamBlock{
+			end:           stable.end + offsets[cachedLine],
+			lines:         len(r.lines),
+			trailingBlank: trailingBlank,
+		}
+		r.blocks = append(r.blocks, stable)
+	}
+
+	rest := strings.TrimRight(out[cachedOut:], "\n")
+	if rest == "" {
+		r.visible = stable.lines - stable.trailingBlank
+		return
+	}
+	r.lines = r.appendLines(r.lines, rest)
+	r.visible = len(r.lines)
+}
+
+// appendLines appends the padded lines of s to lines.
+func (r *StreamRenderer) appendLines(lines []string, s string) []string {
+	for line := range strings.SplitSeq(s, "\n") {
+		lines = append(lines, padLine(line, r.width))
+	}
+	return lines
+}
+
+// padLine pads line to the target width with trailing spaces, like
+// padAllLines does for each line of a multi-line string.
+func padLine(line string, width int) string {
+	if width <= 0 {
+		return line
+	}
+	lineWidth := ansiStringWidth(line)
+	if lineWidth >= width {
+		return line
+	}
+	var b strings.Builder
+	b.Grow(len(line) + width - lineWidth)
+	b.WriteString(line)
+	writeSpaces(&b, width-lineWidth)
+	return b.String()
+}
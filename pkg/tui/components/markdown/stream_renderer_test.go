@@ -0,0 +1,187 @@
+package markdown
+
+import (
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// streamRendererDocuments are the documents the StreamRenderer must render
+// exactly like the FastRenderer, whatever the chunks they're streamed in.
+var streamRendererDocuments = map[string]string{
+	"benchmark":      benchmarkInput,
+	"table":          benchmarkTableInput,
+	"streaming":      streamingBenchmarkContent,
+	"loose list":     "Steps:\n\n- one\n\n- two\n\n  indented\n\n- three\n\nDone.\n",
+	"footnotes":      "Text[^1].\n\n[^1]: The note\n\n    continues here.\n\nAfter.\n",
+	"unclosed fence": "Intro\n\n```go\nfunc main() {\n\n}\n",
+	"blank lines":    "\n\n# Title\n\n\n\nText\n\n\n\n",
+	"crlf":           "# Title\r\n\r\n| a | b |\r\n|---|---|\r\n| 1 | 2 |\r\n\r\nText\r\n",
+	"blockquote":     "> quoted\n> more\n\n> ```\n> code\n> ```\n\nafter\n",
+	"empty fence":    "```\n```\n\n---\n\ntext",
+	"setext":         "Title\n=====\nText\nSection\n-\n\nMore\n---\n",
+	"html":           "Some <b>bold</b> &amp; <i>italic</i><br>text\n\n<details>\n<summary>More</summary>\n\n| a | b |\n|---|--:|\n| x<br>y | z |\n\n</details>\nAfter\n",
+}
+
+// renderStream appends the chunks one by one to a StreamRenderer, checking
+// that each intermediate output is the same as a one-shot render.
+func renderStream(t *testing.T, width int, chunks []string, opts ...FastRendererOption) {
+	t.Helper()
+
+	stream := NewStreamRenderer(width, opts...)
+	var content strings.Builder
+	for i, chunk := range chunks {
+		stream.Append(chunk)
+		content.WriteString(chunk)
+
+		want, err := NewFastRenderer(width, opts...).Render(content.String())
+		require.NoError(t, err)
+		require.Equal(t, want, stream.String(), "after chunk %d (%q)", i, chunk)
+	}
+}
+
+// splitRandomly splits content into chunks of random sizes.
+func splitRandomly(content string, rng *rand.Rand) []string {
+	var chunks []string
+	for content != "" {
+		n := min(1+rng.IntN(40), len(content))
+		chunks = append(chunks, content[:n])
+		content = content[n:]
+	}
+	return chunks
+}
+
+func TestStreamRendererCharByChar(t *testing.T) {
+	t.Parallel()
+
+	for name, doc := range streamRendererDocuments {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			chars := strings.Split(doc, "")
+			renderStream(t, 80, chars)
+			renderStream(t, 30, chars)
+		})
+	}
+}
+
+func TestStreamRendererRandomChunks(t *testing.T) {
+	t.Parallel()
+
+	for name, doc := range streamRendererDocuments {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rng := rand.New(rand.NewPCG(1, 2))
+			for range 20 {
+				renderStream(t, 20+rng.IntN(100), splitRandomly(doc, rng))
+			}
+			renderStream(t, 80, splitIntoStreamingChunks(doc))
+		})
+	}
+}
+
+func TestStreamRendererHyperlinks(t *testing.T) {
+	t.Parallel()
+
+	doc := "See [the docs](https://example.com/docs) and https://example.com/a_b.\n\n" +
+		"| Link |\n|---|\n| <https://example.com> |\n\n" + benchmarkInput
+	renderStream(t, 80, strings.Split(doc, ""), WithHyperlinks(true))
+	renderStream(t, 30, splitIntoStreamingChunks(doc), WithHyperlinks(true))
+}
+
+func TestStreamRendererCachesStableBlocks(t *testing.T) {
+	t.Parallel()
+
+	stream := NewStreamRenderer(80)
+	stream.Append(streamingBenchmarkContent)
+	stream.Lines()
+
+	require.NotEmpty(t, stream.blocks)
+	assert.Greater(t, stream.stable().end, len(streamingBenchmarkContent)/2, "most of the document should be cached")
+}
+
+func TestStreamRendererInvalidateFrom(t *testing.T) {
+	t.Parallel()
+
+	want, err := NewFastRenderer(80).Render(benchmarkInput)
+	require.NoError(t, err)
+
+	stream := NewStreamRenderer(80)
+	stream.Append(benchmarkInput)
+	lines := len(stream.Lines())
+	cached := len(stream.blocks)
+	require.Positive(t, cached)
+
+	stream.InvalidateFrom(lines / 2)
+	assert.Less(t, len(stream.blocks), cached)
+	assert.Equal(t, want, stream.String())
+
+	stream.InvalidateFrom(0)
+	assert.Empty(t, stream.blocks)
+	assert.Equal(t, want, stream.String())
+}
+
+func TestStreamRendererSetWidth(t *testing.T) {
+	t.Parallel()
+
+	stream := NewStreamRenderer(80)
+	stream.Append(benchmarkInput)
+	stream.Lines()
+
+	stream.SetWidth(40)
+	want, err := NewFastRenderer(40).Render(benchmarkInput)
+	require.NoError(t, err)
+	assert.Equal(t, want, stream.String())
+}
+
+func TestStreamRendererReset(t *testing.T) {
+	t.Parallel()
+
+	stream := NewStreamRenderer(80)
+	assert.Empty(t, stream.Lines())
+
+	stream.Append(benchmarkInput)
+	stream.Lines()
+
+	stream.Reset()
+	assert.Empty(t, stream.Lines())
+
+	stream.Append("# Title")
+	want, err := NewFastRenderer(80).Render("# Title")
+	require.NoError(t, err)
+	assert.Equal(t, want, stream.String())
+}
+
+func TestStreamRendererResetStyles(t *testing.T) {
+	stream := NewStreamRenderer(80)
+	stream.Append(benchmarkInput)
+	stream.Lines()
+	require.NotEmpty(t, stream.blocks)
+
+	ResetStyles()
+	stream.Lines()
+	assert.Equal(t, stylesGeneration.Load(), stream.generation)
+
+	want, err := NewFastRenderer(80).Render(benchmarkInput)
+	require.NoError(t, err)
+	assert.Equal(t, want, stream.String())
+}
+
+// BenchmarkStreamingStreamRenderer is BenchmarkStreamingFastRenderer with a
+// StreamRenderer.
+func BenchmarkStreamingStreamRenderer(b *testing.B) {
+	chunks := splitIntoStreamingChunks(streamingBenchmarkContent)
+
+	b.ResetTimer()
+	for b.Loop() {
+		stream := NewStreamRenderer(80)
+		for _, chunk := range chunks {
+			stream.Append(chunk)
+			_ = stream.Lines()
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	tea "charm.land/bubbletea/v2"
 	"github.com/charmbracelet/x/ansi"
 
+	"github.com/docker/docker-agent/pkg/chat"
 	"github.com/docker/docker-agent/pkg/tui/components/markdown"
 	"github.com/docker/docker-agent/pkg/tui/components/spinner"
 	"github.com/docker/docker-agent/pkg/tui/core/layout"
@@ -35,6 +36,11 @@ type messageModel struct {
 	selected bool
 	hovered  bool
 	spinner  spinner.Spinner
+
+	// stream renders the content of assistant messages incrementally while
+	// they're streamed; rendered is the content it has been given so far.
+	stream   *markdown.StreamRenderer
+	rendered string
 }
 
 // New creates a new message view
@@ -81,6 +87,23 @@ func (mv *messageModel) Update(msg tea.Msg) (layout.Model, tea.Cmd) {
 	return mv, nil
 }
 
+// renderMarkdown renders the markdown content of an assistant message. The
+// content only grows while the message is streamed, so only what was added
+// since the previous render is parsed again.
+func (mv *messageModel) renderMarkdown(content string, width int) string {
+	if mv.stream == nil {
+		mv.stream = markdown.NewStreamRenderer(width, markdown.WithHyperlinks(styles.SupportsHyperlinks()))
+	}
+	mv.stream.SetWidth(width)
+	if !strings.HasPrefix(content, mv.rendered) {
+		mv.stream.Reset()
+		mv.rendered = ""
+	}
+	mv.stream.Append(content[len(mv.rendered):])
+	mv.rendered = content
+	return mv.stream.String()
+}
+
 // View renders the message view
 func (mv *messageModel) View() string {
 	return mv.Render(mv.width)
@@ -135,9 +158,9 @@ func (mv *messageModel) Render(width int) string {
 			messageStyle = styles.SelectedMessageStyle
 		}
 
-		rendered, err := markdown.NewRenderer(width - messageStyle.GetHorizontalFrameSize()).Render(msg.Content)
-		if err != nil {
-			rendered = msg.Content
+		rendered := mv.renderMarkdown(msg.Content, width-messageStyle.GetHorizontalFrameSize())
+		if len(msg.Annotations) > 0 {
+			rendered += "\n" + styles.MutedStyle.Render(sourcesFooter(msg.Annotations))
 		}
 
 		var prefix string
@@ -165,6 +188,11 @@ func (mv *messageModel) Render(width int) string {
 		return msg.Content
 	case types.MessageTypeCancelled:
 		return styles.WarningStyle.Render("⚠ stream cancelled ⚠")
+	case types.MessageTypeSystemReminder:
+		// Reminders are for the model: shown muted, apart from the user messages.
+		return styles.MutedStyle.Italic(true).PaddingLeft(2).Width(width - 1).Render("⚙ Reminder: " + msg.Content)
+	case types.MessageTypeCompaction:
+		return styles.MutedStyle.Italic(true).PaddingLeft(2).Width(width - 1).Render("⋯ " + msg.Content + " ⋯")
 	case types.MessageTypeWelcome:
 		messageStyle := styles.WelcomeMessageStyle
 		// Convert explicit newlines to markdown hard line breaks (two trailing spaces)
@@ -193,6 +221,15 @@ func (mv *messageModel) Render(width int) string {
 	}
 }
 
+// sourcesFooter lists the sources of an answer, compactly.
+func sourcesFooter(annotations []chat.Annotation) string {
+	sources := make([]string, 0, len(annotations))
+	for _, a := range annotations {
+		sources = append(sources, fmt.Sprintf("[%d] %s", a.Index, a.Location()))
+	}
+	return "sources: " + strings.Join(sources, ", ")
+}
+
 func (mv *messageModel) senderPrefix(sender string) string {
 	if sender == "" {
 		return ""
@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/docker/docker-agent/pkg/chat"
 	"github.com/docker/docker-agent/pkg/tui/types"
 )
 
@@ -213,3 +214,35 @@ func TestWelcomeMessagePreservesLineBreaks(t *testing.T) {
 	plainRendered := stripANSI(rendered)
 	assert.Contains(t, plainRendered, "indented")
 }
+
+func TestStreamedAssistantMessage(t *testing.T) {
+	t.Parallel()
+
+	msg := types.Agent(types.MessageTypeAssistant, "root", "# Title\n\nSome")
+	mv := New(msg, nil)
+	mv.SetSize(80, 0)
+	assert.Contains(t, stripANSI(mv.View()), "Some")
+
+	// The content grows while it's streamed.
+	msg.Content += " **streamed** text"
+	assert.Contains(t, stripANSI(mv.View()), "Some streamed text")
+
+	// And can be replaced altogether.
+	mv.SetMessage(types.Agent(types.MessageTypeAssistant, "root", "Other content"))
+	plain := stripANSI(mv.View())
+	assert.Contains(t, plain, "Other content")
+	assert.NotContains(t, plain, "Title")
+}
+
+func TestAssistantMessageSources(t *testing.T) {
+	t.Parallel()
+
+	msg := types.Agent(types.MessageTypeAssistant, "root", "Run the installer [1].")
+	msg.Annotations = []chat.Annotation{
+		{Index: 1, RAGName: "docs", Path: "docs/install.md", StartLine: 10, EndLine: 24},
+		{Index: 2, RAGName: "docs", Path: "README.md"},
+	}
+	mv := New(msg, nil)
+	mv.SetSize(80, 0)
+	assert.Contains(t, stripANSI(mv.View()), "sources: [1] docs/install.md:10-24, [2] README.md")
+}
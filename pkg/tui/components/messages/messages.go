@@ -1,6 +1,7 @@
 package messages
 
 import (
+	"encoding/json"
 	"slices"
 	"strconv"
 	"strings"
@@ -55,7 +56,10 @@ type Model interface {
 	AddToolResult(msg *runtime.ToolCallResponseEvent, status types.ToolStatus) tea.Cmd
 	AppendToLastMessage(agentName, content string) tea.Cmd
 	AppendReasoning(agentName, content string) tea.Cmd
+	CompleteLastMessage(agentName, content string, annotations []chat.Annotation) tea.Cmd
 	AddShellOutputMessage(content string) tea.Cmd
+	AddSystemReminderMessage(agentName, content string) tea.Cmd
+	AddCompactionMarker(coveredMessages, position int) tea.Cmd
 	LoadFromSession(sess *session.Session) tea.Cmd
 
 	RemoveSpinner()
@@ -133,6 +137,10 @@ type model struct {
 
 	// Hovered URL for underline-on-hover effect (nil = no URL hovered)
 	hoveredURL *hoveredURL
+
+	// Last message finalized by CompleteLastMessage; streamed content is never
+	// appended to it
+	completedMessage *types.Message
 }
 
 // New creates a new message list component
@@ -1145,6 +1153,31 @@ func (m *model) AddShellOutputMessage(content string) tea.Cmd {
 	return m.addMessage(types.ShellOutput(content))
 }
 
+func (m *model) AddSystemReminderMessage(agentName, content string) tea.Cmd {
+	return m.addMessage(types.SystemReminder(agentName, content))
+}
+
+// AddCompactionMarker adds the marker of a compaction that condensed
+// coveredMessages messages before the first message of the session from
+// position on: the first one kept verbatim. It's added last when there is
+// no such message.
+func (m *model) AddCompactionMarker(coveredMessages, position int) tea.Cmd {
+	msg := types.Compaction(coveredMessages)
+	index := slices.IndexFunc(m.messages, func(existing *types.Message) bool {
+		return existing.SessionPosition != nil && *existing.SessionPosition >= position
+	})
+	if index < 0 {
+		return m.addMessage(msg)
+	}
+
+	m.clearSelection()
+	view := m.createMessageView(msg)
+	m.messages = slices.Insert(m.messages, index, msg)
+	m.views = slices.Insert(m.views, index, view)
+	m.invalidateAllItems()
+	return view.Init()
+}
+
 func (m *model) AddAssistantMessage() tea.Cmd {
 	return m.addMessage(types.Spinner())
 }
@@ -1263,7 +1296,17 @@ func (m *model) LoadFromSession(sess *session.Session) tea.Cmd {
 		}
 	}
 
+	// The compaction markers go before the first message kept verbatim.
+	markers := make(map[int][]int)
+	for _, summary := range sess.Summaries() {
+		markers[summary.Covered.End] = append(markers[summary.Covered.End], summary.Messages)
+	}
+
 	for pos, item := range sess.Messages {
+		for _, coveredMessages := range markers[pos] {
+			msg := types.Compaction(coveredMessages)
+			appendSessionMessage(msg, m.createMessageView(msg))
+		}
 		if !item.IsMessage() {
 			continue
 		}
@@ -1272,6 +1315,11 @@ func (m *model) LoadFromSession(sess *session.Session) tea.Cmd {
 		if smsg.Implicit {
 			continue
 		}
+		if smsg.IsSystemReminder() {
+			msg := types.SystemReminder(smsg.AgentName, smsg.Message.Content)
+			appendSessionMessage(msg, m.createMessageView(msg))
+			continue
+		}
 
 		switch smsg.Message.Role {
 		case chat.MessageRoleUser:
@@ -1300,6 +1348,7 @@ func (m *model) LoadFromSession(sess *session.Session) tea.Cmd {
 			// Step 2: Handle assistant content - this breaks the reasoning block chain
 			if hasContent {
 				msg := types.Agent(types.MessageTypeAssistant, smsg.AgentName, smsg.Message.Content)
+				msg.Annotations = smsg.Message.Annotations
 				appendSessionMessage(msg, m.createMessageView(msg))
 			}
 
@@ -1308,6 +1357,17 @@ func (m *model) LoadFromSession(sess *session.Session) tea.Cmd {
 			if hasToolCalls {
 				attachToReasoning := reasoningBlock != nil && !hasContent
 				for i, tc := range smsg.Message.ToolCalls {
+					// Thoughts are shown with the reasoning, not as tool calls.
+					if tc.Function.Name == builtin.ToolNameThink {
+						var args builtin.ThinkArgs
+						if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err == nil && args.Thought != "" {
+							block := getOrCreateReasoningBlock(smsg.AgentName)
+							block.AppendReasoning(args.Thought + "\n\n")
+							m.messages[len(m.messages)-1].Content += args.Thought + "\n\n"
+						}
+						continue
+					}
+
 					var toolDef tools.Tool
 					if i < len(smsg.Message.ToolDefinitions) {
 						toolDef = smsg.Message.ToolDefinitions[i]
@@ -1435,7 +1495,7 @@ func (m *model) AppendToLastMessage(agentName, content string) tea.Cmd {
 	lastMsg := m.messages[lastIdx]
 
 	// Append to existing assistant message from same agent
-	if lastMsg.Type == types.MessageTypeAssistant && lastMsg.Sender == agentName {
+	if lastMsg.Type == types.MessageTypeAssistant && lastMsg.Sender == agentName && lastMsg != m.completedMessage {
 		lastMsg.Content += content
 		m.views[lastIdx].(message.Model).SetMessage(lastMsg)
 		m.invalidateItem(lastIdx)
@@ -1456,7 +1516,7 @@ func (m *model) AppendReasoning(agentName, content string) tea.Cmd {
 	lastMsg := m.messages[lastIdx]
 
 	// Append to existing reasoning block for this agent
-	if lastMsg.Type == types.MessageTypeAssistantReasoningBlock && lastMsg.Sender == agentName {
+	if lastMsg.Type == types.MessageTypeAssistantReasoningBlock && lastMsg.Sender == agentName && lastMsg != m.completedMessage {
 		if block, ok := m.views[lastIdx].(*reasoningblock.Model); ok {
 			block.AppendReasoning(content)
 			lastMsg.Content += content // Keep content in sync for copying
@@ -1469,6 +1529,40 @@ func (m *model) AppendReasoning(agentName, content string) tea.Cmd {
 	return m.addReasoningBlock(agentName, content)
 }
 
+// CompleteLastMessage finalizes the message streamed by agentName once the
+// runtime reports it complete. The assistant message takes the full content of
+// the turn, with the sources it draws on, and any content streamed afterwards
+// starts a new message, even when it comes from the same agent.
+func (m *model) CompleteLastMessage(agentName, content string, annotations []chat.Annotation) tea.Cmd {
+	if len(m.messages) == 0 {
+		return nil
+	}
+
+	lastIdx := len(m.messages) - 1
+	lastMsg := m.messages[lastIdx]
+	if lastMsg.Sender != agentName {
+		return nil
+	}
+
+	switch lastMsg.Type {
+	case types.MessageTypeAssistant:
+		if (content != "" && lastMsg.Content != content) || len(annotations) > 0 {
+			if content != "" {
+				lastMsg.Content = content
+			}
+			lastMsg.Annotations = annotations
+			m.views[lastIdx].(message.Model).SetMessage(lastMsg)
+			m.invalidateItem(lastIdx)
+		}
+	case types.MessageTypeAssistantReasoningBlock:
+	default:
+		return nil
+	}
+
+	m.completedMessage = lastMsg
+	return nil
+}
+
 // addReasoningBlock creates a new reasoning block message.
 func (m *model) addReasoningBlock(agentName, content string) tea.Cmd {
 	m.clearSelection()
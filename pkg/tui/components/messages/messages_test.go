@@ -1184,3 +1184,32 @@ func TestBindingsExcludesEditKeyWhenAssistantMessageSelected(t *testing.T) {
 	}
 	assert.False(t, foundE, "Bindings should NOT include 'e' key when assistant message is selected")
 }
+
+func TestCompleteLastMessageStartsNewMessage(t *testing.T) {
+	t.Parallel()
+
+	sessionState := &service.SessionState{}
+	m := NewScrollableView(80, 24, sessionState).(*model)
+	m.SetSize(80, 24)
+
+	m.AddUserMessage("Hi")
+	m.AppendToLastMessage("root", "Hello")
+	m.AppendToLastMessage("root", " world")
+	require.Len(t, m.messages, 2)
+
+	// The completed message takes the full content of the turn.
+	m.CompleteLastMessage("root", "Hello world!", nil)
+	assert.Equal(t, "Hello world!", m.messages[1].Content)
+
+	// Content streamed by the next turn of the same agent gets its own message.
+	m.AppendToLastMessage("root", "Anything else?")
+	require.Len(t, m.messages, 3)
+	assert.Equal(t, "Hello world!", m.messages[1].Content)
+	assert.Equal(t, "Anything else?", m.messages[2].Content)
+
+	// Another agent's completion leaves the message open.
+	m.CompleteLastMessage("other", "", nil)
+	m.AppendToLastMessage("root", " Just ask.")
+	require.Len(t, m.messages, 3)
+	assert.Equal(t, "Anything else? Just ask.", m.messages[2].Content)
+}
@@ -0,0 +1,29 @@
+package sidebar
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/docker/docker-agent/pkg/runtime"
+	"github.com/docker/docker-agent/pkg/tools"
+	"github.com/docker/docker-agent/pkg/tui/service"
+)
+
+func TestFileChangesSection(t *testing.T) {
+	t.Parallel()
+
+	m := New(&service.SessionState{}).(*model)
+	assert.Empty(t, m.fileChangesSection(40))
+
+	m.Update(runtime.FileChanged("call_1", tools.FileChange{Path: "/src/a.go", Type: tools.FileModified}, "session-1", "root"))
+	m.Update(runtime.FileChanged("call_1", tools.FileChange{Path: "/src/b.go", Type: tools.FileCreated}, "session-1", "root"))
+	m.Update(runtime.FileChanged("call_2", tools.FileChange{Path: "/src/a.go", Type: tools.FileModified}, "session-1", "root"))
+
+	result := ansi.Strip(m.fileChangesSection(60))
+	assert.Contains(t, result, "Files changed (2)")
+	// The most recent change comes last. Lines are padded to the width.
+	assert.Contains(t, result, "├ + /src/b.go ")
+	assert.Contains(t, result, "└ ~ /src/a.go ")
+}
@@ -0,0 +1,38 @@
+package sidebar
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/docker/docker-agent/pkg/runtime"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/tui/service"
+)
+
+func TestHandoffSection(t *testing.T) {
+	t.Parallel()
+
+	m := New(&service.SessionState{}).(*model)
+	assert.Empty(t, m.handoffSection(40))
+
+	m.Update(runtime.AgentHandoff("planner", "coder", "session-1", []session.Handoff{
+		{From: "root", To: "planner", AtMessageIndex: 1},
+		{From: "planner", To: "coder", AtMessageIndex: 3},
+	}))
+
+	result := ansi.Strip(m.handoffSection(60))
+	assert.Contains(t, result, "Handoffs (2)")
+	assert.Contains(t, result, "root → planner → coder")
+}
+
+func TestRenderAgentChain(t *testing.T) {
+	t.Parallel()
+
+	chain := []string{"root", "planner", "coder", "reviewer"}
+
+	assert.Equal(t, "root → planner → coder → reviewer", ansi.Strip(renderAgentChain(chain, 40)))
+	// The oldest agents are elided when the chain doesn't fit.
+	assert.Equal(t, "… → coder → reviewer", ansi.Strip(renderAgentChain(chain, 22)))
+}
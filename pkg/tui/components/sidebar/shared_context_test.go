@@ -0,0 +1,30 @@
+package sidebar
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/docker/docker-agent/pkg/runtime"
+	"github.com/docker/docker-agent/pkg/tui/service"
+)
+
+func TestSharedContextSection(t *testing.T) {
+	t.Parallel()
+
+	m := New(&service.SessionState{}).(*model)
+	assert.Empty(t, m.sharedContextSection(40))
+
+	m.Update(runtime.BlackboardUpdated("plan", []runtime.BlackboardEntry{
+		{Key: "decision", Value: "use postgres"},
+		{Key: "plan", Value: "1. design\n2. build"},
+	}, "root"))
+
+	result := m.sharedContextSection(60)
+	assert.Contains(t, result, "Shared context (2)")
+	assert.Contains(t, result, "decision: use postgres")
+	// Values are shown on a single line.
+	assert.Contains(t, result, "plan: 1. design 2. build")
+	assert.Contains(t, result, "├")
+	assert.Contains(t, result, "└")
+}
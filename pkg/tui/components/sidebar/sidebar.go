@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"maps"
 	"os"
+	"path/filepath"
 	"slices"
 	"strconv"
 	"strings"
@@ -50,7 +51,7 @@ type Model interface {
 	SetAgentInfo(agentName, model, description string) tea.Cmd
 	SetTeamInfo(availableAgents []runtime.AgentDetails)
 	SetAgentSwitching(switching bool)
-	SetToolsetInfo(availableTools int, loading bool)
+	SetToolsetInfo(availableTools, failedToolsets int, loading bool)
 	SetSkillsInfo(availableSkills int)
 	SetSessionStarred(starred bool)
 	SetQueuedMessages(messages ...string)
@@ -127,6 +128,7 @@ type model struct {
 	availableAgents    []runtime.AgentDetails
 	agentSwitching     bool
 	availableTools     int
+	failedToolsets     int // toolsets whose tools are missing because they failed
 	availableSkills    int
 	toolsLoading       bool // true when more tools may still be loading
 	sessionState       *service.SessionState
@@ -152,6 +154,17 @@ type model struct {
 
 	// Agent click zones: maps content line index to agent name for click detection
 	agentClickZones map[int]string // content line -> agent name
+
+	// Entries of the team's shared context, as of the last BlackboardUpdatedEvent
+	sharedContext []runtime.BlackboardEntry
+
+	// Handoffs between agents made during the session, as of the last
+	// AgentHandoffEvent
+	handoffs []session.Handoff
+
+	// Files changed by the tools during the session, as of the last
+	// FileChangedEvent
+	fileChanges []tools.FileChange
 }
 
 // Option is a functional option for configuring the sidebar.
@@ -294,9 +307,10 @@ func (m *model) SetAgentSwitching(switching bool) {
 	m.invalidateCache()
 }
 
-// SetToolsetInfo sets the number of available tools and loading state
-func (m *model) SetToolsetInfo(availableTools int, loading bool) {
+// SetToolsetInfo sets the number of available tools and failed toolsets, and loading state
+func (m *model) SetToolsetInfo(availableTools, failedToolsets int, loading bool) {
 	m.availableTools = availableTools
+	m.failedToolsets = failedToolsets
 	m.toolsLoading = loading
 	m.invalidateCache()
 }
@@ -479,6 +493,9 @@ func (m *model) LoadFromSession(sess *session.Session) {
 		m.workingDirectory = wd
 	}
 
+	m.handoffs = sess.Handoffs()
+	m.fileChanges = sess.FileChanges()
+
 	// Session has content if it has messages or token usage
 	m.sessionHasContent = len(sess.Messages) > 0 || sess.InputTokens > 0 || sess.OutputTokens > 0
 
@@ -582,6 +599,18 @@ func (m *model) Update(msg tea.Msg) (layout.Model, tea.Cmd) {
 	case *runtime.TokenUsageEvent:
 		m.SetTokenUsage(msg)
 		return m, nil
+	case *runtime.BlackboardUpdatedEvent:
+		m.sharedContext = msg.Entries
+		m.invalidateCache()
+		return m, nil
+	case *runtime.AgentHandoffEvent:
+		m.handoffs = msg.Handoffs
+		m.invalidateCache()
+		return m, nil
+	case *runtime.FileChangedEvent:
+		m.fileChanges = tools.MergeFileChange(m.fileChanges, msg.Change)
+		m.invalidateCache()
+		return m, nil
 	case *runtime.MCPInitStartedEvent:
 		// Ignore if stream was cancelled (stale event from before cancellation)
 		if m.streamCancelled {
@@ -693,7 +722,7 @@ func (m *model) Update(msg tea.Msg) (layout.Model, tea.Cmd) {
 		if m.streamCancelled && msg.Loading {
 			return m, nil
 		}
-		m.SetToolsetInfo(msg.AvailableTools, msg.Loading)
+		m.SetToolsetInfo(msg.AvailableTools, msg.FailedToolsets, msg.Loading)
 		if msg.Loading {
 			cmd := m.startSpinner()
 			return m, cmd
@@ -922,11 +951,17 @@ func (m *model) renderSections(contentWidth int) []string {
 	appendSection(m.agentInfo(contentWidth))
 	m.buildAgentClickZones(agentSectionStart, lines)
 
+	appendSection(m.handoffSection(contentWidth))
+
 	appendSection(m.toolsetInfo(contentWidth))
 
 	m.todoComp.SetSize(contentWidth)
 	appendSection(strings.TrimSuffix(m.todoComp.Render(), "\n"))
 
+	appendSection(m.sharedContextSection(contentWidth))
+
+	appendSection(m.fileChangesSection(contentWidth))
+
 	return lines
 }
 
@@ -1144,6 +1179,129 @@ func (m *model) queueSection(contentWidth int) string {
 	return m.renderTab(title, strings.Join(lines, "\n"), contentWidth)
 }
 
+// sharedContextSection renders the keys and values of the team's shared context
+func (m *model) sharedContextSection(contentWidth int) string {
+	if len(m.sharedContext) == 0 {
+		return ""
+	}
+
+	maxEntryWidth := contentWidth - treePrefixWidth
+	var lines []string
+
+	for i, entry := range m.sharedContext {
+		var prefix string
+		if i == len(m.sharedContext)-1 {
+			prefix = styles.MutedStyle.Render("└ ")
+		} else {
+			prefix = styles.MutedStyle.Render("├ ")
+		}
+
+		value := strings.Join(strings.Fields(entry.Value), " ")
+		line := toolcommon.TruncateText(entry.Key+": "+value, maxEntryWidth)
+		lines = append(lines, prefix+line)
+	}
+
+	title := fmt.Sprintf("Shared context (%d)", len(m.sharedContext))
+	return m.renderTab(title, strings.Join(lines, "\n"), contentWidth)
+}
+
+// maxFileChangeLines is the number of changed files listed in the sidebar,
+// the most recently changed first.
+const maxFileChangeLines = 10
+
+// fileChangesSection renders the files changed by the tools, marked with "+"
+// when created, "~" when modified, "-" when deleted and "→" when renamed.
+func (m *model) fileChangesSection(contentWidth int) string {
+	if len(m.fileChanges) == 0 {
+		return ""
+	}
+
+	maxEntryWidth := contentWidth - treePrefixWidth
+	changes := m.fileChanges[max(0, len(m.fileChanges)-maxFileChangeLines):]
+
+	var lines []string
+	for i, change := range slices.Backward(changes) {
+		prefix := styles.MutedStyle.Render("├ ")
+		if i == 0 && len(m.fileChanges) <= maxFileChangeLines {
+			prefix = styles.MutedStyle.Render("└ ")
+		}
+
+		var mark string
+		switch change.Type {
+		case tools.FileCreated:
+			mark = styles.SuccessStyle.Render("+")
+		case tools.FileDeleted:
+			mark = styles.ErrorStyle.Render("-")
+		case tools.FileRenamed:
+			mark = styles.WarningStyle.Render("→")
+		default:
+			mark = styles.WarningStyle.Render("~")
+		}
+		lines = append(lines, prefix+mark+" "+toolcommon.TruncateText(displayPath(change.Path), maxEntryWidth-2))
+	}
+	if hidden := len(m.fileChanges) - len(changes); hidden > 0 {
+		lines = append(lines, styles.MutedStyle.Render(fmt.Sprintf("└ … %d more", hidden)))
+	}
+
+	title := fmt.Sprintf("Files changed (%d)", len(m.fileChanges))
+	return m.renderTab(title, strings.Join(lines, "\n"), contentWidth)
+}
+
+// displayPath shortens a path: relative to the working directory when it's
+// inside it, with the home directory replaced by "~" otherwise.
+func displayPath(path string) string {
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, path); err == nil && filepath.IsLocal(rel) {
+			return rel
+		}
+	}
+	if homeDir := paths.GetHomeDir(); homeDir != "" && strings.HasPrefix(path, homeDir) {
+		return "~" + path[len(homeDir):]
+	}
+	return path
+}
+
+// handoffSection renders the chain of agents the conversation was handed off
+// through, e.g. "root → planner → coder".
+func (m *model) handoffSection(contentWidth int) string {
+	if len(m.handoffs) == 0 {
+		return ""
+	}
+
+	var chain []string
+	for _, h := range m.handoffs {
+		if len(chain) == 0 || chain[len(chain)-1] != h.From {
+			chain = append(chain, h.From)
+		}
+		chain = append(chain, h.To)
+	}
+
+	title := fmt.Sprintf("Handoffs (%d)", len(m.handoffs))
+	return m.renderTab(title, renderAgentChain(chain, contentWidth), contentWidth)
+}
+
+// renderAgentChain renders the agents joined by arrows. The oldest agents are
+// elided when the chain doesn't fit in width.
+func renderAgentChain(chain []string, width int) string {
+	arrow := styles.MutedStyle.Render(" → ")
+
+	var line string
+	for start := range chain {
+		parts := make([]string, len(chain)-start)
+		for i, name := range chain[start:] {
+			parts[i] = styles.AgentAccentStyleFor(name).Render(name)
+		}
+		line = strings.Join(parts, arrow)
+		if start > 0 {
+			line = styles.MutedStyle.Render("…") + arrow + line
+		}
+		if lipgloss.Width(line) <= width {
+			break
+		}
+	}
+	return line
+}
+
 // agentInfo renders the current agent information
 func (m *model) agentInfo(contentWidth int) string {
 	// Read current agent from session state so sidebar updates when agent is switched
@@ -1297,6 +1455,13 @@ func (m *model) renderToolsStatus() string {
 		}
 		return m.spinner.View() + styles.TabPrimaryStyle.Render(" Loading tools…")
 	}
+	if m.failedToolsets > 0 {
+		failed := "1 toolset failed"
+		if m.failedToolsets > 1 {
+			failed = fmt.Sprintf("%d toolsets failed", m.failedToolsets)
+		}
+		return styles.WarningStyle.Render("█") + styles.TabPrimaryStyle.Render(fmt.Sprintf(" %d tools (%s)", m.availableTools, failed))
+	}
 	if m.availableTools > 0 {
 		return styles.TabAccentStyle.Render("█") + styles.TabPrimaryStyle.Render(fmt.Sprintf(" %d tools available", m.availableTools))
 	}
@@ -0,0 +1,25 @@
+package sidebar
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/docker/docker-agent/pkg/runtime"
+	"github.com/docker/docker-agent/pkg/tui/service"
+)
+
+func TestRenderToolsStatus_FailedToolsets(t *testing.T) {
+	t.Parallel()
+
+	m := New(&service.SessionState{}).(*model)
+
+	m.Update(runtime.ToolsetInfo(3, 0, false, "root"))
+	assert.Contains(t, m.renderToolsStatus(), "3 tools available")
+
+	m.Update(runtime.ToolsetInfo(3, 1, false, "root"))
+	assert.Contains(t, m.renderToolsStatus(), "3 tools (1 toolset failed)")
+
+	m.Update(runtime.ToolsetInfo(0, 2, false, "root"))
+	assert.Contains(t, m.renderToolsStatus(), "0 tools (2 toolsets failed)")
+}
@@ -17,6 +17,10 @@ type StatusBar struct {
 	help  core.KeyMapHelp
 	title string
 
+	// costEstimate is the estimated cost of the next turn, e.g.
+	// "next turn ≈ $0.03", shown before the title.
+	costEstimate string
+
 	showNewTab   bool
 	newTabStartX int
 	newTabEndX   int
@@ -74,6 +78,14 @@ func (s *StatusBar) SetShowNewTab(show bool) {
 	}
 }
 
+// SetCostEstimate sets the estimated cost of the next turn. Empty hides it.
+func (s *StatusBar) SetCostEstimate(estimate string) {
+	if s.costEstimate != estimate {
+		s.costEstimate = estimate
+		s.cacheDirty = true
+	}
+}
+
 // ClickedNewTab returns true if the given X coordinate hits the "+" button.
 func (s *StatusBar) ClickedNewTab(x int) bool {
 	return s.showNewTab && x >= s.newTabStartX && x < s.newTabEndX
@@ -98,6 +110,9 @@ func (s *StatusBar) rebuild() {
 	// Build the styled right side: optional new-tab button + title.
 	var rightW, newTabW int
 	right := styles.MutedStyle.Render(s.title)
+	if s.costEstimate != "" {
+		right = styles.SecondaryStyle.Render(s.costEstimate) + styles.MutedStyle.Render(" \u2502 ") + right
+	}
 
 	if s.showNewTab {
 		newTab := styles.MutedStyle.Render(" \u2502 ") +
@@ -152,7 +167,7 @@ func (s *StatusBar) rebuild() {
 
 // View renders the status bar.
 //
-// Layout: [ help text ...           (+ new tab)  docker agent VERSION ]
+// Layout: [ help text ...           (+ new tab)  next turn ≈ $0.03 │ docker agent VERSION ]
 func (s *StatusBar) View() string {
 	if s.cacheDirty {
 		s.rebuild()
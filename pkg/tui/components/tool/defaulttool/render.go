@@ -17,7 +17,13 @@ type kv struct {
 }
 
 func renderToolArgs(toolCall tools.ToolCall, shortWidth, width int) string {
-	args, err := decodeArguments(toolCall.Function.Arguments)
+	// Show the arguments of a streamed tool call as they come.
+	arguments := toolCall.Function.Arguments
+	if repaired, _ := tools.RepairArgs(arguments); repaired != "" {
+		arguments = repaired
+	}
+
+	args, err := decodeArguments(arguments)
 	if err != nil {
 		return ""
 	}
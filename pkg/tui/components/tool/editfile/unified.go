@@ -0,0 +1,95 @@
+package editfile
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/aymanbagabas/go-udiff"
+
+	"github.com/docker/docker-agent/pkg/tui/styles"
+)
+
+// fileHunks are the hunks of a unified diff about one file.
+type fileHunks struct {
+	path  string
+	hunks []*udiff.Hunk
+}
+
+// RenderUnifiedDiff renders a unified diff, e.g. the preview of a tool call,
+// with the syntax of each file highlighted.
+func RenderUnifiedDiff(diff string, width int) string {
+	var output strings.Builder
+	for i, file := range parseUnifiedDiff(diff) {
+		if i > 0 {
+			output.WriteString("\n\n")
+		}
+		output.WriteString(styles.BoldStyle.Render(file.path) + "\n")
+		output.WriteString(renderDiffWithSyntaxHighlight(normalizeDiff(file.hunks), file.path, width))
+	}
+	return output.String()
+}
+
+// parseUnifiedDiff splits a unified diff into the hunks of each file. The
+// line counts of the hunk headers tell the lines of the hunks apart from the
+// file headers, since a deleted line can start with "--".
+func parseUnifiedDiff(diff string) []fileHunks {
+	var (
+		files            []fileHunks
+		hunk             *udiff.Hunk
+		oldLeft, newLeft int
+	)
+
+	for line := range strings.Lines(diff) {
+		if strings.HasPrefix(line, `\`) {
+			// "\ No newline at end of file"
+			continue
+		}
+
+		if hunk != nil && (oldLeft > 0 || newLeft > 0) {
+			kind := udiff.Equal
+			switch line[0] {
+			case '-':
+				kind = udiff.Delete
+				oldLeft--
+			case '+':
+				kind = udiff.Insert
+				newLeft--
+			default:
+				oldLeft--
+				newLeft--
+			}
+			hunk.Lines = append(hunk.Lines, udiff.Line{Kind: kind, Content: line[1:]})
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			files = append(files, fileHunks{path: strings.TrimSpace(line[len("+++ "):])})
+			hunk = nil
+		case strings.HasPrefix(line, "@@ ") && len(files) > 0:
+			fields := strings.Fields(line)
+			if len(fields) < 3 {
+				continue
+			}
+			hunk = &udiff.Hunk{}
+			hunk.FromLine, oldLeft = parseHunkRange(strings.TrimPrefix(fields[1], "-"))
+			hunk.ToLine, newLeft = parseHunkRange(strings.TrimPrefix(fields[2], "+"))
+			last := &files[len(files)-1]
+			last.hunks = append(last.hunks, hunk)
+		}
+	}
+
+	return files
+}
+
+// parseHunkRange parses the "start,count" range of a hunk header. The count
+// is 1 when it's left out.
+func parseHunkRange(r string) (start, count int) {
+	startText, countText, hasCount := strings.Cut(r, ",")
+	start, _ = strconv.Atoi(startText)
+	count = 1
+	if hasCount {
+		count, _ = strconv.Atoi(countText)
+	}
+	return start, count
+}
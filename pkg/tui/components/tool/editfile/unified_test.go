@@ -0,0 +1,48 @@
+package editfile
+
+import (
+	"testing"
+
+	"github.com/aymanbagabas/go-udiff"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUnifiedDiff(t *testing.T) {
+	t.Parallel()
+
+	diff := udiff.Unified("main.go", "main.go", "package main\n\n-- old\nfunc a() {}\n", "package main\n\nfunc b() {}\n") +
+		udiff.Unified("/dev/null", "README.md", "", "# Title\n")
+
+	files := parseUnifiedDiff(diff)
+
+	require.Len(t, files, 2)
+	assert.Equal(t, "main.go", files[0].path)
+	require.Len(t, files[0].hunks, 1)
+	assert.Equal(t, 1, files[0].hunks[0].FromLine)
+	assert.Equal(t, []udiff.Line{
+		{Kind: udiff.Equal, Content: "package main\n"},
+		{Kind: udiff.Equal, Content: "\n"},
+		{Kind: udiff.Delete, Content: "-- old\n"},
+		{Kind: udiff.Delete, Content: "func a() {}\n"},
+		{Kind: udiff.Insert, Content: "func b() {}\n"},
+	}, files[0].hunks[0].Lines)
+
+	assert.Equal(t, "README.md", files[1].path)
+	require.Len(t, files[1].hunks, 1)
+	assert.Equal(t, []udiff.Line{{Kind: udiff.Insert, Content: "# Title\n"}}, files[1].hunks[0].Lines)
+}
+
+func TestParseUnifiedDiff_NoNewlineAtEndOfFile(t *testing.T) {
+	t.Parallel()
+
+	files := parseUnifiedDiff(udiff.Unified("a.txt", "a.txt", "one\ntwo", "one\nthree\n"))
+
+	require.Len(t, files, 1)
+	require.Len(t, files[0].hunks, 1)
+	assert.Equal(t, []udiff.Line{
+		{Kind: udiff.Equal, Content: "one\n"},
+		{Kind: udiff.Delete, Content: "two\n"},
+		{Kind: udiff.Insert, Content: "three\n"},
+	}, files[0].hunks[0].Lines)
+}
@@ -0,0 +1,169 @@
+// Package tooldetail provides a collapsible view of the arguments of a tool
+// call, pretty-printed and syntax-highlighted as JSON.
+package tooldetail
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+	"github.com/atotto/clipboard"
+
+	"github.com/docker/docker-agent/pkg/tools"
+	"github.com/docker/docker-agent/pkg/tui/components/markdown"
+	"github.com/docker/docker-agent/pkg/tui/components/notification"
+	"github.com/docker/docker-agent/pkg/tui/components/scrollview"
+	"github.com/docker/docker-agent/pkg/tui/styles"
+)
+
+// KeyMap defines the key bindings of the tool call detail view.
+type KeyMap struct {
+	Toggle key.Binding
+	Copy   key.Binding
+}
+
+// DefaultKeyMap returns the default key bindings.
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		Toggle: key.NewBinding(key.WithKeys("e", "E"), key.WithHelp("e", "expand/collapse")),
+		Copy:   key.NewBinding(key.WithKeys("c", "C"), key.WithHelp("c", "copy")),
+	}
+}
+
+// Model shows the arguments of a tool call. Collapsed, it is a single header
+// line; expanded, the arguments are shown below it in a scrollable view.
+type Model struct {
+	toolCall   tools.ToolCall
+	expanded   bool
+	keyMap     KeyMap
+	scrollview *scrollview.Model
+
+	// Rendered arguments, cached for bodyWidth.
+	body      []string
+	bodyWidth int
+}
+
+// New creates a tool call detail view, expanded or collapsed.
+func New(toolCall tools.ToolCall, expanded bool) *Model {
+	return &Model{
+		toolCall: toolCall,
+		expanded: expanded,
+		keyMap:   DefaultKeyMap(),
+		scrollview: scrollview.New(
+			scrollview.WithKeyMap(scrollview.ReadOnlyScrollKeyMap()),
+			scrollview.WithReserveScrollbarSpace(true),
+		),
+	}
+}
+
+// Expanded reports whether the arguments are shown.
+func (m *Model) Expanded() bool {
+	return m.expanded
+}
+
+// Toggle expands or collapses the arguments.
+func (m *Model) Toggle() {
+	m.expanded = !m.expanded
+	m.scrollview.SetScrollOffset(0)
+}
+
+// SetSize sets the width and the maximum height of the view, header included.
+func (m *Model) SetSize(width, height int) {
+	m.scrollview.SetSize(width, max(1, height-1))
+}
+
+// Update handles the toggle and copy keys and, when expanded, scrolling.
+// It reports whether msg was handled.
+func (m *Model) Update(msg tea.Msg) (handled bool, cmd tea.Cmd) {
+	if msg, ok := msg.(tea.KeyPressMsg); ok {
+		switch {
+		case key.Matches(msg, m.keyMap.Toggle):
+			m.Toggle()
+			return true, nil
+		case key.Matches(msg, m.keyMap.Copy):
+			return true, copyToClipboard(m.toolCall.Function.Arguments)
+		}
+	}
+
+	if !m.expanded {
+		return false, nil
+	}
+	return m.scrollview.Update(msg)
+}
+
+// Height returns the number of lines View renders.
+func (m *Model) Height() int {
+	if !m.expanded {
+		return 1
+	}
+	return 1 + min(len(m.renderBody()), m.scrollview.VisibleHeight())
+}
+
+// View renders the header line followed, when expanded, by the arguments.
+func (m *Model) View() string {
+	body := m.renderBody()
+
+	indicator, action := "▸", "expand"
+	if m.expanded {
+		indicator, action = "▾", "collapse"
+	}
+	header := styles.BoldStyle.Render(fmt.Sprintf("%s %s arguments", indicator, m.toolCall.Function.Name)) +
+		styles.MutedStyle.Render(fmt.Sprintf(" (%d lines)  e %s · c copy", len(body), action))
+
+	if !m.expanded {
+		return header
+	}
+
+	return header + "\n" + m.scrollview.View()
+}
+
+func (m *Model) renderBody() []string {
+	width := m.scrollview.ContentWidth()
+	if m.body != nil && m.bodyWidth == width {
+		return m.body
+	}
+
+	arguments, lang := FormatArguments(m.toolCall.Function.Arguments)
+	fence := "```"
+	for strings.Contains(arguments, fence) {
+		fence += "`"
+	}
+	source := fence + lang + "\n" + arguments + "\n" + fence
+
+	rendered, err := markdown.NewRenderer(width).Render(source)
+	if err != nil {
+		rendered = arguments
+	}
+	m.body = strings.Split(strings.TrimRight(rendered, "\n"), "\n")
+	m.bodyWidth = width
+	m.scrollview.SetContent(m.body, len(m.body))
+	return m.body
+}
+
+// FormatArguments pretty-prints the JSON arguments of a tool call and returns
+// them along with the language to highlight them as. Arguments that are not
+// valid JSON are returned unchanged, with no language.
+func FormatArguments(arguments string) (formatted, lang string) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(arguments), "", "  "); err != nil {
+		return arguments, ""
+	}
+	return buf.String(), "json"
+}
+
+// copyToClipboard copies text to the system clipboard, falling back to OSC 52
+// when no system clipboard is available (e.g. over SSH).
+func copyToClipboard(text string) tea.Cmd {
+	return tea.Sequence(
+		func() tea.Msg {
+			if err := clipboard.WriteAll(text); err != nil {
+				return tea.SetClipboard(text)()
+			}
+			return nil
+		},
+		notification.SuccessCmd("Tool arguments copied to clipboard."),
+	)
+}
@@ -0,0 +1,109 @@
+package tooldetail
+
+import (
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/docker-agent/pkg/tools"
+)
+
+func toolCall(arguments string) tools.ToolCall {
+	return tools.ToolCall{
+		ID:       "call-1",
+		Function: tools.FunctionCall{Name: "write_file", Arguments: arguments},
+	}
+}
+
+func TestFormatArguments(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		arguments string
+		want      string
+		lang      string
+	}{
+		{
+			name:      "json object",
+			arguments: `{"path":"main.go","content":"package main"}`,
+			want:      "{\n  \"path\": \"main.go\",\n  \"content\": \"package main\"\n}",
+			lang:      "json",
+		},
+		{
+			name:      "already indented",
+			arguments: "{\n    \"a\": [1, 2]\n}",
+			want:      "{\n  \"a\": [\n    1,\n    2\n  ]\n}",
+			lang:      "json",
+		},
+		{
+			name:      "invalid json",
+			arguments: `{"path": "main.go"`,
+			want:      `{"path": "main.go"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, lang := FormatArguments(tt.arguments)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.lang, lang)
+		})
+	}
+}
+
+func TestToggle(t *testing.T) {
+	t.Parallel()
+
+	m := New(toolCall(`{"path":"main.go","content":"package main"}`), false)
+	m.SetSize(80, 20)
+
+	assert.Equal(t, 1, m.Height())
+	view := ansi.Strip(m.View())
+	assert.Contains(t, view, "▸ write_file arguments")
+	assert.NotContains(t, view, `"path"`)
+
+	handled, cmd := m.Update(tea.KeyPressMsg{Code: 'e', Text: "e"})
+	require.True(t, handled)
+	assert.Nil(t, cmd)
+	require.True(t, m.Expanded())
+
+	view = ansi.Strip(m.View())
+	assert.Contains(t, view, "▾ write_file arguments")
+	assert.Contains(t, view, `"path": "main.go"`)
+	assert.Greater(t, m.Height(), 4)
+}
+
+func TestExpandedHeightIsBounded(t *testing.T) {
+	t.Parallel()
+
+	m := New(toolCall(`{"a":1,"b":2,"c":3,"d":4,"e":5,"f":6,"g":7,"h":8}`), true)
+	m.SetSize(80, 5)
+
+	assert.Equal(t, 5, m.Height())
+
+	// Scroll keys are handled when expanded...
+	handled, _ := m.Update(tea.KeyPressMsg{Code: tea.KeyPgDown})
+	assert.True(t, handled)
+
+	// ...but not when collapsed.
+	m.Toggle()
+	handled, _ = m.Update(tea.KeyPressMsg{Code: tea.KeyPgDown})
+	assert.False(t, handled)
+}
+
+func TestCopyKey(t *testing.T) {
+	t.Parallel()
+
+	m := New(toolCall(`{"path":"main.go"}`), false)
+
+	// Only check the key is handled: running the command touches the clipboard.
+	handled, cmd := m.Update(tea.KeyPressMsg{Code: 'c', Text: "c"})
+	assert.True(t, handled)
+	assert.NotNil(t, cmd)
+}
@@ -226,10 +226,10 @@ func (d *costDialog) gatherCostData() costData {
 		})
 	}
 
-	addCompactionCost := func(cost float64) {
+	addItemCost := func(label string, cost float64) {
 		data.hasPerMessageData = true
 		data.total.cost += cost
-		data.messages = append(data.messages, totalUsage{label: "compaction", cost: cost})
+		data.messages = append(data.messages, totalUsage{label: label, cost: cost})
 	}
 
 	addMarker := func(label string) {
@@ -254,8 +254,13 @@ func (d *costDialog) gatherCostData() costData {
 					addMarker("── sub-session end ──")
 				}
 			}
-			if item.Summary != "" && item.Cost > 0 {
-				addCompactionCost(item.Cost)
+			switch {
+			case item.Cost <= 0:
+			case item.Summary != "":
+				addItemCost("compaction", item.Cost)
+			case !item.IsMessage() && !item.IsSubSession():
+				// e.g. the sampling requests of MCP servers
+				addItemCost("other", item.Cost)
 			}
 		}
 	}
@@ -114,6 +114,25 @@ func NewElicitationDialog(message string, schema any, meta map[string]any) Dialo
 	return d
 }
 
+// NewElicitationDialogWithErrors creates an elicitation dialog that prompts
+// the user again after the answer was rejected. fieldErrors maps field names
+// to the reason their value was rejected; the first invalid field is focused.
+func NewElicitationDialogWithErrors(message string, schema any, meta map[string]any, fieldErrors map[string]string) Dialog {
+	d := NewElicitationDialog(message, schema, meta).(*ElicitationDialog)
+
+	firstErrorIdx := -1
+	for i, field := range d.fields {
+		if msg, ok := fieldErrors[field.Name]; ok {
+			d.fieldErrors[i] = capitalizeFirst(msg)
+			if firstErrorIdx < 0 {
+				firstErrorIdx = i
+			}
+		}
+	}
+	d.focusField(firstErrorIdx)
+	return d
+}
+
 func (d *ElicitationDialog) Init() tea.Cmd {
 	if d.hasFreeFormInput() || len(d.inputs) > 0 {
 		return textinput.Blink
@@ -284,7 +284,7 @@ func TestValidateCustomModelSpec(t *testing.T) {
 			wantErr: false,
 		},
 		{
-			name:    "valid ollama alias",
+			name:    "valid ollama provider",
 			spec:    "ollama/llama3",
 			wantErr: false,
 		},
@@ -1,6 +1,8 @@
 package dialog
 
 import (
+	"cmp"
+	"context"
 	"fmt"
 	"slices"
 	"strconv"
@@ -12,6 +14,8 @@ import (
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
 	"github.com/atotto/clipboard"
+	"github.com/junegunn/fzf/src/algo"
+	"github.com/junegunn/fzf/src/util"
 
 	"github.com/docker/docker-agent/pkg/session"
 	"github.com/docker/docker-agent/pkg/tui/components/notification"
@@ -32,6 +36,23 @@ type sessionBrowserKeyMap struct {
 	FilterStar key.Binding
 	CopyID     key.Binding
 	Delete     key.Binding
+	View       key.Binding
+}
+
+// SessionSearchFunc searches the titles and message contents of the stored sessions.
+type SessionSearchFunc func(ctx context.Context, query string) ([]session.Summary, error)
+
+// sessionSearchDelay is how long the browser waits after the last keystroke
+// before searching the session contents.
+const sessionSearchDelay = 250 * time.Millisecond
+
+// sessionSearchMsg triggers a content search once the query stopped changing.
+type sessionSearchMsg struct{ query string }
+
+// sessionSearchResultMsg carries the IDs of the sessions whose contents match query.
+type sessionSearchResultMsg struct {
+	query string
+	ids   map[string]bool
 }
 
 // Session browser dialog dimension constants
@@ -52,13 +73,23 @@ type sessionBrowserDialog struct {
 	openedAt   time.Time // when dialog was opened, for stable time display
 	starFilter int       // 0 = all, 1 = starred only, 2 = unstarred only
 
+	// Content search: sessions whose messages match contentQuery.
+	search         SessionSearchFunc
+	contentQuery   string
+	contentMatches map[string]bool
+
+	// pendingDelete is the ID of the session waiting for delete confirmation.
+	pendingDelete string
+
 	// Double-click detection
 	lastClickTime  time.Time
 	lastClickIndex int
 }
 
-// NewSessionBrowserDialog creates a new session browser dialog
-func NewSessionBrowserDialog(sessions []session.Summary) Dialog {
+// NewSessionBrowserDialog creates a new session browser dialog.
+// Titles are fuzzy-matched as the user types; when search is non-nil, the
+// message contents are searched too.
+func NewSessionBrowserDialog(sessions []session.Summary, search SessionSearchFunc) Dialog {
 	ti := textinput.New()
 	ti.Placeholder = "Type to search sessions…"
 	ti.Focus()
@@ -76,6 +107,7 @@ func NewSessionBrowserDialog(sessions []session.Summary) Dialog {
 	d := &sessionBrowserDialog{
 		textInput:  ti,
 		sessions:   nonEmptySessions,
+		search:     search,
 		scrollview: scrollview.New(scrollview.WithReserveScrollbarSpace(true)),
 		keyMap: sessionBrowserKeyMap{
 			Up:         key.NewBinding(key.WithKeys("up", "ctrl+k")),
@@ -86,6 +118,7 @@ func NewSessionBrowserDialog(sessions []session.Summary) Dialog {
 			FilterStar: key.NewBinding(key.WithKeys("ctrl+f")),
 			CopyID:     key.NewBinding(key.WithKeys("ctrl+y")),
 			Delete:     key.NewBinding(key.WithKeys("ctrl+d")),
+			View:       key.NewBinding(key.WithKeys("ctrl+o")),
 		},
 		openedAt: time.Now(),
 	}
@@ -110,11 +143,36 @@ func (d *sessionBrowserDialog) Update(msg tea.Msg) (layout.Model, tea.Cmd) {
 		return d, cmd
 
 	case tea.PasteMsg:
-		var cmd tea.Cmd
-		d.textInput, cmd = d.textInput.Update(msg)
-		d.filterSessions()
+		cmd := d.updateInput(msg)
 		return d, cmd
 
+	case sessionSearchMsg:
+		if msg.query != d.query() {
+			return d, nil
+		}
+		search := d.search
+		return d, func() tea.Msg {
+			results, err := search(context.Background(), msg.query)
+			if err != nil {
+				return nil
+			}
+			ids := make(map[string]bool, len(results))
+			for _, sess := range results {
+				ids[sess.ID] = true
+			}
+			return sessionSearchResultMsg{query: msg.query, ids: ids}
+		}
+
+	case sessionSearchResultMsg:
+		// Drop results for a query the user already changed.
+		if msg.query != d.query() {
+			return d, nil
+		}
+		d.contentQuery = msg.query
+		d.contentMatches = msg.ids
+		d.filterSessions()
+		return d, nil
+
 	case tea.MouseClickMsg:
 		// Scrollbar clicks already handled above; this handles list item clicks
 		if msg.Button == tea.MouseLeft {
@@ -140,6 +198,14 @@ func (d *sessionBrowserDialog) Update(msg tea.Msg) (layout.Model, tea.Cmd) {
 			return d, cmd
 		}
 
+		// Any key other than a second ctrl+d cancels a pending delete.
+		if d.pendingDelete != "" && !key.Matches(msg, d.keyMap.Delete) {
+			d.pendingDelete = ""
+			if key.Matches(msg, d.keyMap.Escape) {
+				return d, nil
+			}
+		}
+
 		switch {
 		case key.Matches(msg, d.keyMap.Escape):
 			return d, core.CmdHandler(CloseDialogMsg{})
@@ -199,9 +265,20 @@ func (d *sessionBrowserDialog) Update(msg tea.Msg) (layout.Model, tea.Cmd) {
 			}
 			return d, nil
 
+		case key.Matches(msg, d.keyMap.View):
+			if d.selected >= 0 && d.selected < len(d.filtered) {
+				return d, core.CmdHandler(messages.ViewSessionMsg{SessionID: d.filtered[d.selected].ID})
+			}
+			return d, nil
+
 		case key.Matches(msg, d.keyMap.Delete):
 			if d.selected >= 0 && d.selected < len(d.filtered) {
 				sessionID := d.filtered[d.selected].ID
+				if d.pendingDelete != sessionID {
+					d.pendingDelete = sessionID
+					return d, nil
+				}
+				d.pendingDelete = ""
 				d.sessions = slices.DeleteFunc(d.sessions, func(s session.Summary) bool {
 					return s.ID == sessionID
 				})
@@ -211,9 +288,7 @@ func (d *sessionBrowserDialog) Update(msg tea.Msg) (layout.Model, tea.Cmd) {
 			return d, nil
 
 		default:
-			var cmd tea.Cmd
-			d.textInput, cmd = d.textInput.Update(msg)
-			d.filterSessions()
+			cmd := d.updateInput(msg)
 			return d, cmd
 		}
 	}
@@ -221,10 +296,38 @@ func (d *sessionBrowserDialog) Update(msg tea.Msg) (layout.Model, tea.Cmd) {
 	return d, nil
 }
 
+// updateInput forwards msg to the search input and, when the query changed,
+// refilters the list and schedules a content search.
+func (d *sessionBrowserDialog) updateInput(msg tea.Msg) tea.Cmd {
+	previous := d.query()
+	var cmd tea.Cmd
+	d.textInput, cmd = d.textInput.Update(msg)
+	query := d.query()
+	if query == previous {
+		return cmd
+	}
+
+	d.filterSessions()
+	if d.search == nil || query == "" {
+		return cmd
+	}
+	return tea.Batch(cmd, tea.Tick(sessionSearchDelay, func(time.Time) tea.Msg {
+		return sessionSearchMsg{query: query}
+	}))
+}
+
+func (d *sessionBrowserDialog) query() string {
+	return strings.TrimSpace(d.textInput.Value())
+}
+
 func (d *sessionBrowserDialog) filterSessions() {
-	query := strings.ToLower(strings.TrimSpace(d.textInput.Value()))
+	query := d.query()
 
-	d.filtered = nil
+	type match struct {
+		sess  session.Summary
+		score int
+	}
+	var matches []match
 	for _, sess := range d.sessions {
 		switch d.starFilter {
 		case 1:
@@ -237,17 +340,27 @@ func (d *sessionBrowserDialog) filterSessions() {
 			}
 		}
 
-		if query != "" {
-			title := sess.Title
-			if title == "" {
-				title = "Untitled"
-			}
-			if !strings.Contains(strings.ToLower(title), query) {
-				continue
-			}
+		if query == "" {
+			matches = append(matches, match{sess: sess})
+			continue
+		}
+
+		// Fuzzy title matches rank first, then sessions whose contents
+		// matched the last completed search for this query.
+		if score, ok := fuzzyScore(sess.Title, query); ok {
+			matches = append(matches, match{sess: sess, score: score})
+		} else if d.contentQuery == query && d.contentMatches[sess.ID] {
+			matches = append(matches, match{sess: sess, score: -1})
 		}
+	}
+
+	slices.SortStableFunc(matches, func(a, b match) int {
+		return cmp.Compare(b.score, a.score)
+	})
 
-		d.filtered = append(d.filtered, sess)
+	d.filtered = nil
+	for _, m := range matches {
+		d.filtered = append(d.filtered, m.sess)
 	}
 
 	if d.selected >= len(d.filtered) {
@@ -256,6 +369,21 @@ func (d *sessionBrowserDialog) filterSessions() {
 	d.scrollview.SetScrollOffset(0)
 }
 
+// fuzzyScore fuzzy-matches query against title, case-insensitively.
+func fuzzyScore(title, query string) (int, bool) {
+	chars := util.ToChars([]byte(title))
+	result, _ := algo.FuzzyMatchV1(
+		false, // caseSensitive
+		false, // normalize
+		true,  // forward
+		&chars,
+		[]rune(strings.ToLower(query)),
+		false, // withPos
+		nil,   // slab
+	)
+	return result.Score, result.Start >= 0
+}
+
 // mouseYToSessionIndex converts a mouse Y position to a session index in the filtered list.
 // Returns -1 if the position is not on a session.
 func (d *sessionBrowserDialog) mouseYToSessionIndex(y int) int {
@@ -343,7 +471,13 @@ func (d *sessionBrowserDialog) View() string {
 
 	var idFooter string
 	if d.selected >= 0 && d.selected < len(d.filtered) {
-		idFooter = styles.MutedStyle.Render("ID: ") + styles.SecondaryStyle.Render(d.filtered[d.selected].ID)
+		sess := d.filtered[d.selected]
+		if d.pendingDelete == sess.ID {
+			idFooter = styles.ErrorStyle.Render("Delete this session? ") +
+				styles.MutedStyle.Render("ctrl+d to confirm, esc to cancel")
+		} else {
+			idFooter = styles.MutedStyle.Render("ID: ") + styles.SecondaryStyle.Render(sess.ID)
+		}
 	}
 
 	content := NewContent(regionWidth).
@@ -356,7 +490,7 @@ func (d *sessionBrowserDialog) View() string {
 		AddContent(idFooter).
 		AddSpace().
 		AddHelpKeys("↑/↓", "navigate", "ctrl+s", "star", "ctrl+f", filterDesc, "ctrl+y", "copy id", "ctrl+d", "delete").
-		AddHelpKeys("enter", "load", "esc", "close").
+		AddHelpKeys("enter", "resume", "ctrl+o", "view", "esc", "close").
 		Build()
 
 	return styles.DialogStyle.Width(dialogWidth).Render(content)
@@ -384,6 +518,12 @@ func (d *sessionBrowserDialog) renderSession(sess session.Summary, selected bool
 	}
 
 	suffix := fmt.Sprintf(" • (%d msg) • %s", sess.NumMessages, d.timeAgo(sess.CreatedAt))
+	if sess.Cost > 0 {
+		suffix = " • " + formatCost(sess.Cost) + suffix
+	}
+	if sess.AgentName != "" {
+		suffix = " • " + sess.AgentName + suffix
+	}
 
 	starWidth := 3
 	maxTitleLen := max(1, maxWidth-len(suffix)-starWidth)
@@ -1,6 +1,7 @@
 package dialog
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"testing"
@@ -11,6 +12,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/tui/messages"
 )
 
 func TestSessionBrowserNavigation(t *testing.T) {
@@ -20,7 +22,7 @@ func TestSessionBrowserNavigation(t *testing.T) {
 		{ID: "3", Title: "Session 3", CreatedAt: time.Now()},
 	}
 
-	dialog := NewSessionBrowserDialog(sessions)
+	dialog := NewSessionBrowserDialog(sessions, nil)
 	d := dialog.(*sessionBrowserDialog)
 
 	// Initialize and set window size like the TUI does
@@ -68,7 +70,7 @@ func TestSessionBrowserNavigationWithCtrl(t *testing.T) {
 		{ID: "3", Title: "Session 3", CreatedAt: time.Now()},
 	}
 
-	dialog := NewSessionBrowserDialog(sessions)
+	dialog := NewSessionBrowserDialog(sessions, nil)
 	d := dialog.(*sessionBrowserDialog)
 	d.Init()
 	d.Update(tea.WindowSizeMsg{Width: 100, Height: 50})
@@ -103,7 +105,7 @@ func TestSessionBrowserViewShowsSelection(t *testing.T) {
 		{ID: "3", Title: "Session 3", CreatedAt: time.Now()},
 	}
 
-	dialog := NewSessionBrowserDialog(sessions)
+	dialog := NewSessionBrowserDialog(sessions, nil)
 	d := dialog.(*sessionBrowserDialog)
 	d.Init()
 	d.Update(tea.WindowSizeMsg{Width: 100, Height: 50})
@@ -133,7 +135,7 @@ func TestSessionBrowserFiltersEmptySessions(t *testing.T) {
 		{ID: "5", Title: "Session 5", CreatedAt: time.Now()},
 	}
 
-	dialog := NewSessionBrowserDialog(sessions)
+	dialog := NewSessionBrowserDialog(sessions, nil)
 	d := dialog.(*sessionBrowserDialog)
 
 	// Should only have non-empty sessions
@@ -152,7 +154,7 @@ func TestSessionBrowserAllEmptySessions(t *testing.T) {
 		{ID: "2", Title: "", CreatedAt: time.Now()},
 	}
 
-	dialog := NewSessionBrowserDialog(sessions)
+	dialog := NewSessionBrowserDialog(sessions, nil)
 	d := dialog.(*sessionBrowserDialog)
 
 	// Should have no sessions
@@ -171,7 +173,7 @@ func TestSessionBrowserScrolling(t *testing.T) {
 		}
 	}
 
-	dialog := NewSessionBrowserDialog(sessions)
+	dialog := NewSessionBrowserDialog(sessions, nil)
 	d := dialog.(*sessionBrowserDialog)
 	d.Init()
 	// Set a small window size to force scrolling
@@ -211,7 +213,7 @@ func TestSessionBrowserMouseClickSelectsSession(t *testing.T) {
 		{ID: "3", Title: "Session 3", CreatedAt: time.Now()},
 	}
 
-	dialog := NewSessionBrowserDialog(sessions)
+	dialog := NewSessionBrowserDialog(sessions, nil)
 	d := dialog.(*sessionBrowserDialog)
 	d.Init()
 	d.Update(tea.WindowSizeMsg{Width: 100, Height: 50})
@@ -257,7 +259,7 @@ func TestSessionBrowserDoubleClickOpensSession(t *testing.T) {
 		{ID: "sess-3", Title: "Session 3", CreatedAt: time.Now()},
 	}
 
-	dialog := NewSessionBrowserDialog(sessions)
+	dialog := NewSessionBrowserDialog(sessions, nil)
 	d := dialog.(*sessionBrowserDialog)
 	d.Init()
 	d.Update(tea.WindowSizeMsg{Width: 100, Height: 50})
@@ -288,7 +290,7 @@ func TestSessionBrowserClickOutsideListIgnored(t *testing.T) {
 		{ID: "2", Title: "Session 2", CreatedAt: time.Now()},
 	}
 
-	dialog := NewSessionBrowserDialog(sessions)
+	dialog := NewSessionBrowserDialog(sessions, nil)
 	d := dialog.(*sessionBrowserDialog)
 	d.Init()
 	d.Update(tea.WindowSizeMsg{Width: 100, Height: 50})
@@ -306,3 +308,112 @@ func TestSessionBrowserClickOutsideListIgnored(t *testing.T) {
 	require.Equal(t, 0, d.selected, "click outside list should not change selection")
 	require.Nil(t, cmd, "click outside list should not produce a command")
 }
+
+func TestSessionBrowserFuzzyTitleSearch(t *testing.T) {
+	sessions := []session.Summary{
+		{ID: "1", Title: "Refactor the parser", CreatedAt: time.Now()},
+		{ID: "2", Title: "Fix flaky tests", CreatedAt: time.Now()},
+		{ID: "3", Title: "Release notes", CreatedAt: time.Now()},
+	}
+
+	d := NewSessionBrowserDialog(sessions, nil).(*sessionBrowserDialog)
+	d.Init()
+	d.Update(tea.WindowSizeMsg{Width: 100, Height: 50})
+
+	for _, ch := range "fxtst" {
+		d.Update(tea.KeyPressMsg{Text: string(ch)})
+	}
+
+	require.Len(t, d.filtered, 1)
+	require.Equal(t, "2", d.filtered[0].ID)
+}
+
+func TestSessionBrowserContentSearch(t *testing.T) {
+	sessions := []session.Summary{
+		{ID: "1", Title: "Deploy the cluster", CreatedAt: time.Now()},
+		{ID: "2", Title: "Write the docs", CreatedAt: time.Now()},
+		{ID: "3", Title: "Kubernetes upgrade", CreatedAt: time.Now()},
+	}
+	var searched []string
+	search := func(_ context.Context, query string) ([]session.Summary, error) {
+		searched = append(searched, query)
+		return []session.Summary{sessions[0], sessions[2]}, nil
+	}
+
+	d := NewSessionBrowserDialog(sessions, search).(*sessionBrowserDialog)
+	d.Init()
+	d.Update(tea.WindowSizeMsg{Width: 100, Height: 50})
+
+	d.textInput.SetValue("kubernetes")
+	d.filterSessions()
+	require.Len(t, d.filtered, 1, "only the title should match before the content search completes")
+
+	// Results for a query the user already changed are ignored.
+	d.Update(sessionSearchResultMsg{query: "kube", ids: map[string]bool{"2": true}})
+	require.Len(t, d.filtered, 1)
+
+	_, cmd := d.Update(sessionSearchMsg{query: "kubernetes"})
+	require.NotNil(t, cmd)
+	d.Update(cmd())
+	require.Equal(t, []string{"kubernetes"}, searched)
+
+	// Title matches rank before content matches.
+	require.Len(t, d.filtered, 2)
+	require.Equal(t, "3", d.filtered[0].ID)
+	require.Equal(t, "1", d.filtered[1].ID)
+
+	// A search scheduled for a stale query does not run.
+	_, cmd = d.Update(sessionSearchMsg{query: "kube"})
+	require.Nil(t, cmd)
+}
+
+func TestSessionBrowserDeleteNeedsConfirmation(t *testing.T) {
+	sessions := []session.Summary{
+		{ID: "sess-1", Title: "Session 1", CreatedAt: time.Now()},
+		{ID: "sess-2", Title: "Session 2", CreatedAt: time.Now()},
+	}
+
+	d := NewSessionBrowserDialog(sessions, nil).(*sessionBrowserDialog)
+	d.Init()
+	d.Update(tea.WindowSizeMsg{Width: 100, Height: 50})
+
+	ctrlD := tea.KeyPressMsg{Code: 'd', Mod: tea.ModCtrl}
+	escape := tea.KeyPressMsg{Code: tea.KeyEscape}
+
+	// First ctrl+d only asks for confirmation.
+	_, cmd := d.Update(ctrlD)
+	require.Nil(t, cmd)
+	require.Len(t, d.sessions, 2)
+	require.Contains(t, d.View(), "Delete this session?")
+
+	// Esc cancels the delete without closing the dialog.
+	_, cmd = d.Update(escape)
+	require.Nil(t, cmd)
+	require.Empty(t, d.pendingDelete)
+
+	// Pressing ctrl+d twice deletes the selected session.
+	d.Update(ctrlD)
+	_, cmd = d.Update(ctrlD)
+	require.NotNil(t, cmd)
+	require.Equal(t, messages.DeleteSessionMsg{SessionID: "sess-1"}, cmd())
+	require.Len(t, d.sessions, 1)
+	require.Equal(t, "sess-2", d.sessions[0].ID)
+}
+
+func TestSessionBrowserViewSession(t *testing.T) {
+	sessions := []session.Summary{
+		{ID: "sess-1", Title: "Session 1", AgentName: "root", Cost: 0.5, CreatedAt: time.Now()},
+	}
+
+	d := NewSessionBrowserDialog(sessions, nil).(*sessionBrowserDialog)
+	d.Init()
+	d.Update(tea.WindowSizeMsg{Width: 100, Height: 50})
+
+	view := d.View()
+	require.Contains(t, view, "root")
+	require.Contains(t, view, "$0.50")
+
+	_, cmd := d.Update(tea.KeyPressMsg{Code: 'o', Mod: tea.ModCtrl})
+	require.NotNil(t, cmd)
+	require.Equal(t, messages.ViewSessionMsg{SessionID: "sess-1"}, cmd())
+}
@@ -0,0 +1,75 @@
+package dialog
+
+import (
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/docker/docker-agent/pkg/tui/components/markdown"
+	"github.com/docker/docker-agent/pkg/tui/components/toolcommon"
+	"github.com/docker/docker-agent/pkg/tui/core/layout"
+	"github.com/docker/docker-agent/pkg/tui/messages"
+	"github.com/docker/docker-agent/pkg/tui/styles"
+)
+
+// sessionTranscriptDialog shows the transcript of a saved session, read-only.
+type sessionTranscriptDialog struct {
+	readOnlyScrollDialog
+
+	title    string
+	markdown string
+
+	// Rendered transcript, cached for renderedWidth.
+	rendered      []string
+	renderedWidth int
+}
+
+// NewSessionTranscriptDialog creates a dialog that renders the Markdown
+// transcript of a saved session.
+func NewSessionTranscriptDialog(title, transcript string) Dialog {
+	if title == "" {
+		title = "Untitled"
+	}
+	d := &sessionTranscriptDialog{
+		title:    title,
+		markdown: transcript,
+	}
+	d.readOnlyScrollDialog = newReadOnlyScrollDialog(
+		readOnlyScrollDialogSize{
+			widthPercent:  85,
+			minWidth:      60,
+			maxWidth:      120,
+			heightPercent: 85,
+			heightMax:     50,
+		},
+		d.renderContent,
+	)
+	return d
+}
+
+func (d *sessionTranscriptDialog) Update(msg tea.Msg) (layout.Model, tea.Cmd) {
+	if _, ok := msg.(messages.ThemeChangedMsg); ok {
+		// Re-render the transcript with the new theme's styles.
+		d.rendered = nil
+	}
+	_, cmd := d.readOnlyScrollDialog.Update(msg)
+	return d, cmd
+}
+
+func (d *sessionTranscriptDialog) renderContent(contentWidth, _ int) []string {
+	if d.rendered == nil || d.renderedWidth != contentWidth {
+		rendered, err := markdown.NewRenderer(contentWidth).Render(d.markdown)
+		if err != nil {
+			rendered = d.markdown
+		}
+		d.rendered = strings.Split(strings.TrimRight(rendered, "\n"), "\n")
+		d.renderedWidth = contentWidth
+	}
+
+	lines := []string{
+		styles.DialogTitleStyle.Render(toolcommon.TruncateText(d.title, contentWidth)),
+		styles.DialogSeparatorStyle.Render(strings.Repeat("─", contentWidth)),
+		"",
+	}
+	return append(lines, d.rendered...)
+}
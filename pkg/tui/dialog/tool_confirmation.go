@@ -1,10 +1,13 @@
 package dialog
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"strings"
 
 	"charm.land/bubbles/v2/key"
+	"charm.land/bubbles/v2/textarea"
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
 	"github.com/charmbracelet/x/ansi"
@@ -12,6 +15,9 @@ import (
 	"github.com/docker/docker-agent/pkg/runtime"
 	"github.com/docker/docker-agent/pkg/tools"
 	"github.com/docker/docker-agent/pkg/tui/components/messages"
+	"github.com/docker/docker-agent/pkg/tui/components/scrollview"
+	"github.com/docker/docker-agent/pkg/tui/components/tool/editfile"
+	"github.com/docker/docker-agent/pkg/tui/components/tooldetail"
 	"github.com/docker/docker-agent/pkg/tui/core"
 	"github.com/docker/docker-agent/pkg/tui/core/layout"
 	tuimessages "github.com/docker/docker-agent/pkg/tui/messages"
@@ -47,7 +53,11 @@ type toolConfirmationDialog struct {
 	keyMap            toolConfirmationKeyMap
 	sessionState      *service.SessionState
 	scrollView        messages.Model
-	permissionPattern string // cached permission pattern for this tool call
+	preview           *scrollview.Model // what the call would change; nil when the runtime can't tell
+	details           *tooldetail.Model // tool call arguments; nil when there are none
+	permissionPattern string            // cached permission pattern for this tool call
+	arguments         *textarea.Model   // the arguments being edited; nil when not editing
+	argumentsError    string            // why the edited arguments can't be sent
 }
 
 // dialogDimensions returns computed dialog width and content width.
@@ -73,26 +83,85 @@ func (d *toolConfirmationDialog) SetSize(width, height int) tea.Cmd {
 	separator := d.renderSeparator(contentWidth)
 	separatorHeight := lipgloss.Height(separator)
 
-	question := styles.DialogQuestionStyle.Width(contentWidth).Render("Do you want to allow this tool call?")
+	question := styles.DialogQuestionStyle.Width(contentWidth).Render(d.question())
 	questionHeight := lipgloss.Height(question)
 
-	options := RenderHelpKeys(contentWidth, "Y", "yes", "N", "no", "T", d.alwaysAllowHelpText(), "A", "all tools")
+	options := d.renderOptions(contentWidth)
 	optionsHeight := lipgloss.Height(options)
 
 	// Calculate available height for scroll view
 	frameHeight := styles.DialogStyle.GetVerticalFrameSize()
 	fixedContentHeight := titleHeight + separatorHeight + toolConfirmEmptyLinesBefore + questionHeight + toolConfirmEmptyLinesAfter + optionsHeight
 	availableHeight := max(maxDialogHeight-frameHeight-fixedContentHeight, toolConfirmMinScrollHeight)
+	if d.arguments != nil {
+		// Keep a line for the error of the edited arguments.
+		d.arguments.SetWidth(contentWidth)
+		d.arguments.SetHeight(max(1, availableHeight-1))
+	}
+	if d.details != nil {
+		// Expanded arguments replace the tool call view; collapsed, they take
+		// a single line below it.
+		d.details.SetSize(contentWidth, availableHeight)
+		if !d.details.Expanded() {
+			availableHeight--
+		}
+	}
 	d.scrollView.SetSize(contentWidth, availableHeight)
+	if d.preview != nil {
+		d.preview.SetSize(contentWidth, availableHeight)
+		lines := d.renderPreview(d.preview.ContentWidth())
+		d.preview.SetContent(lines, len(lines))
+	}
 
 	return nil
 }
 
+// renderPreview renders the preview of the call: its summary followed by
+// the diff of its changes, syntax-highlighted.
+func (d *toolConfirmationDialog) renderPreview(width int) []string {
+	var parts []string
+	if summary := d.msg.Preview.Summary; summary != "" {
+		parts = append(parts, lipgloss.NewStyle().Width(width).Render(summary))
+	}
+	if diff := d.msg.Preview.Diff; diff != "" {
+		parts = append(parts, editfile.RenderUnifiedDiff(diff, width))
+	}
+	return strings.Split(strings.Join(parts, "\n\n"), "\n")
+}
+
 // renderSeparator renders the separator line consistently.
 func (d *toolConfirmationDialog) renderSeparator(contentWidth int) string {
 	return RenderSeparator(contentWidth)
 }
 
+// question returns the question of the dialog.
+func (d *toolConfirmationDialog) question() string {
+	if d.arguments != nil {
+		return "Edit the arguments of the call, as JSON, and approve it."
+	}
+	return "Do you want to allow this tool call?"
+}
+
+// renderOptions renders the answers offered by the confirmation: the runtime
+// doesn't let some tools be always allowed.
+func (d *toolConfirmationDialog) renderOptions(contentWidth int) string {
+	if d.arguments != nil {
+		return RenderHelpKeys(contentWidth, "Ctrl+S", "approve", "Esc", "back")
+	}
+
+	keys := []string{"Y", "yes", "N", "no"}
+	if d.msg.Offers(runtime.ResumeTypeApproveTool) {
+		keys = append(keys, "T", d.alwaysAllowHelpText())
+	}
+	if d.msg.Offers(runtime.ResumeTypeApproveSession) {
+		keys = append(keys, "A", "all tools")
+	}
+	if d.msg.Offers(runtime.ResumeTypeApproveEdited) {
+		keys = append(keys, "E", "edit")
+	}
+	return RenderHelpKeys(contentWidth, keys...)
+}
+
 // alwaysAllowHelpText returns a descriptive help text for the "always allow" option.
 // For shell commands, it shows the command pattern (e.g., "always allow ls*").
 // For other tools, it shows "always allow <toolname>".
@@ -116,6 +185,9 @@ type toolConfirmationKeyMap struct {
 	No       key.Binding
 	All      key.Binding
 	ThisTool key.Binding
+	Edit     key.Binding
+	Submit   key.Binding
+	Back     key.Binding
 }
 
 // defaultToolConfirmationKeyMap returns default key bindings
@@ -137,6 +209,18 @@ func defaultToolConfirmationKeyMap() toolConfirmationKeyMap {
 			key.WithKeys("t", "T"),
 			key.WithHelp("T", "always allow this tool"),
 		),
+		Edit: key.NewBinding(
+			key.WithKeys("e", "E"),
+			key.WithHelp("E", "edit the arguments"),
+		),
+		Submit: key.NewBinding(
+			key.WithKeys("ctrl+s"),
+			key.WithHelp("Ctrl+S", "approve the edited arguments"),
+		),
+		Back: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("Esc", "back"),
+		),
 	}
 }
 
@@ -184,11 +268,30 @@ func NewToolConfirmationDialog(msg *runtime.ToolCallConfirmationEvent, sessionSt
 	// Build and cache the permission pattern for display and use
 	pattern := buildPermissionPattern(msg.ToolCall)
 
+	// Show what the call would change instead of the tool call when the
+	// runtime could tell.
+	var preview *scrollview.Model
+	if msg.Preview != nil {
+		preview = scrollview.New(
+			scrollview.WithKeyMap(scrollview.ReadOnlyScrollKeyMap()),
+			scrollview.WithReserveScrollbarSpace(true),
+		)
+	}
+
+	// Show the full arguments by default for tools that can change things,
+	// so that approvals are informed, unless their changes are previewed.
+	var details *tooldetail.Model
+	if msg.ToolCall.Function.Arguments != "" {
+		details = tooldetail.New(msg.ToolCall, !msg.ToolDefinition.Annotations.ReadOnlyHint && preview == nil)
+	}
+
 	return &toolConfirmationDialog{
 		msg:               msg,
 		sessionState:      sessionState,
 		keyMap:            defaultToolConfirmationKeyMap(),
 		scrollView:        scrollView,
+		preview:           preview,
+		details:           details,
 		permissionPattern: pattern,
 	}
 }
@@ -198,7 +301,7 @@ func (d *toolConfirmationDialog) Init() tea.Cmd {
 	return d.scrollView.Init()
 }
 
-// executeAction dispatches a confirmation action by key ("Y", "N", "T", "A").
+// executeAction dispatches a confirmation action by key ("Y", "N", "T", "A", "E").
 func (d *toolConfirmationDialog) executeAction(action string) (layout.Model, tea.Cmd) {
 	switch action {
 	case "Y":
@@ -211,20 +314,101 @@ func (d *toolConfirmationDialog) executeAction(action string) (layout.Model, tea
 			Model: NewToolRejectionReasonDialog(),
 		})
 	case "T":
+		if !d.msg.Offers(runtime.ResumeTypeApproveTool) {
+			return d, nil
+		}
 		return d, tea.Sequence(
 			core.CmdHandler(CloseDialogMsg{}),
 			core.CmdHandler(RuntimeResumeMsg{Request: runtime.ResumeApproveTool(d.permissionPattern)}),
 		)
 	case "A":
+		if !d.msg.Offers(runtime.ResumeTypeApproveSession) {
+			return d, nil
+		}
 		d.sessionState.SetYoloMode(true)
 		return d, tea.Sequence(
 			core.CmdHandler(CloseDialogMsg{}),
 			core.CmdHandler(RuntimeResumeMsg{Request: runtime.ResumeApproveSession()}),
 		)
+	case "E":
+		if !d.msg.Offers(runtime.ResumeTypeApproveEdited) {
+			return d, nil
+		}
+		return d, d.startEditing()
 	}
 	return d, nil
 }
 
+// startEditing switches the dialog to the edition of the arguments of the
+// call, starting from the ones of the model.
+func (d *toolConfirmationDialog) startEditing() tea.Cmd {
+	ta := textarea.New()
+	ta.SetValue(indentArguments(d.msg.ToolCall.Function.Arguments))
+	ta.Prompt = ""
+	ta.ShowLineNumbers = false
+	ta.CharLimit = 0
+	ta.SetStyles(styles.InputStyle)
+
+	d.arguments = &ta
+	d.argumentsError = ""
+	d.SetSize(d.Width(), d.Height())
+	return d.arguments.Focus()
+}
+
+// updateEditing handles the keys while the arguments are edited.
+func (d *toolConfirmationDialog) updateEditing(msg tea.KeyPressMsg) (layout.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, d.keyMap.Back):
+		d.arguments = nil
+		d.argumentsError = ""
+		d.SetSize(d.Width(), d.Height())
+		return d, nil
+	case key.Matches(msg, d.keyMap.Submit):
+		arguments, err := compactArguments(d.arguments.Value())
+		if err != nil {
+			d.argumentsError = err.Error()
+			return d, nil
+		}
+		return d, tea.Sequence(
+			core.CmdHandler(CloseDialogMsg{}),
+			core.CmdHandler(RuntimeResumeMsg{Request: runtime.ResumeApproveEdited(arguments)}),
+		)
+	}
+
+	ta, cmd := d.arguments.Update(msg)
+	d.arguments = &ta
+	d.argumentsError = ""
+	return d, cmd
+}
+
+// indentArguments returns the JSON arguments of a tool call indented for
+// edition, or as they are if they aren't valid JSON.
+func indentArguments(arguments string) string {
+	if strings.TrimSpace(arguments) == "" {
+		return "{}"
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(arguments), "", "  "); err != nil {
+		return arguments
+	}
+	return buf.String()
+}
+
+// compactArguments returns the edited arguments as compact JSON. The
+// runtime checks them against the parameters of the tool, only their syntax
+// is checked here.
+func compactArguments(arguments string) (string, error) {
+	var args map[string]any
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return "", fmt.Errorf("the arguments must be a JSON object: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(arguments)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
 // Update handles messages for the tool confirmation dialog
 func (d *toolConfirmationDialog) Update(msg tea.Msg) (layout.Model, tea.Cmd) {
 	switch msg := msg.(type) {
@@ -243,6 +427,10 @@ func (d *toolConfirmationDialog) Update(msg tea.Msg) (layout.Model, tea.Cmd) {
 			return d, cmd
 		}
 
+		if d.arguments != nil {
+			return d.updateEditing(msg)
+		}
+
 		switch {
 		case key.Matches(msg, d.keyMap.Yes):
 			return d.executeAction("Y")
@@ -252,16 +440,38 @@ func (d *toolConfirmationDialog) Update(msg tea.Msg) (layout.Model, tea.Cmd) {
 			return d.executeAction("A")
 		case key.Matches(msg, d.keyMap.ThisTool):
 			return d.executeAction("T")
+		case key.Matches(msg, d.keyMap.Edit):
+			return d.executeAction("E")
+		}
+
+		if d.details != nil {
+			if handled, cmd := d.details.Update(msg); handled {
+				// Expanding or collapsing the arguments changes the layout.
+				d.SetSize(d.Width(), d.Height())
+				return d, cmd
+			}
 		}
 
 		// Forward scrolling keys to the scroll view
 		if _, isScrollKey := core.GetScrollDirection(msg); isScrollKey {
+			if d.preview != nil {
+				_, cmd := d.preview.Update(msg)
+				return d, cmd
+			}
 			updatedScrollView, cmd := d.scrollView.Update(msg)
 			d.scrollView = updatedScrollView.(messages.Model)
 			return d, cmd
 		}
 
 	case tuimessages.WheelCoalescedMsg:
+		if d.details != nil && d.details.Expanded() {
+			_, cmd := d.details.Update(msg)
+			return d, cmd
+		}
+		if d.preview != nil {
+			_, cmd := d.preview.Update(msg)
+			return d, cmd
+		}
 		updatedScrollView, cmd := d.scrollView.Update(msg)
 		d.scrollView = updatedScrollView.(messages.Model)
 		return d, cmd
@@ -270,8 +480,12 @@ func (d *toolConfirmationDialog) Update(msg tea.Msg) (layout.Model, tea.Cmd) {
 	return d, nil
 }
 
-// handleMouseClick handles mouse clicks on the action buttons (Y/N/T/A).
+// handleMouseClick handles mouse clicks on the action buttons (Y/N/T/A/E).
 func (d *toolConfirmationDialog) handleMouseClick(msg tea.MouseClickMsg) (layout.Model, tea.Cmd) {
+	if d.arguments != nil {
+		return d, nil
+	}
+
 	dialogRow, dialogCol := d.Position()
 	renderedDialog := d.View()
 	dialogHeight := lipgloss.Height(renderedDialog)
@@ -283,7 +497,7 @@ func (d *toolConfirmationDialog) handleMouseClick(msg tea.MouseClickMsg) (layout
 
 	// Render the help keys and strip ANSI to get plain text for hit-testing.
 	_, contentWidth := d.dialogDimensions()
-	options := RenderHelpKeys(contentWidth, "Y", "yes", "N", "no", "T", d.alwaysAllowHelpText(), "A", "all tools")
+	options := d.renderOptions(contentWidth)
 	optionsPlain := ansi.Strip(options)
 
 	// Content starts after left border + padding.
@@ -300,7 +514,7 @@ func (d *toolConfirmationDialog) handleMouseClick(msg tea.MouseClickMsg) (layout
 	// Walk backward from the click position to find the nearest action key.
 	// The plain text looks like: "Y yes  N no  T always allow...  A all tools"
 	// Each region starts with its uppercase action key.
-	actionKeys := "YNTA"
+	actionKeys := "YNTAE"
 	for i := relX; i >= 0; i-- {
 		if strings.ContainsRune(actionKeys, rune(optionsPlain[i])) {
 			return d.executeAction(string(optionsPlain[i]))
@@ -322,8 +536,25 @@ func (d *toolConfirmationDialog) View() string {
 	// Separator
 	separator := d.renderSeparator(contentWidth)
 
-	// Get scrollable tool call view
-	argumentsSection := d.scrollView.View()
+	// Get scrollable tool call view or preview, or the expanded arguments
+	callView := d.scrollView.View()
+	if d.preview != nil {
+		callView = d.preview.View()
+	}
+	var argumentsSection string
+	switch {
+	case d.arguments != nil:
+		argumentsSection = d.arguments.View()
+		if d.argumentsError != "" {
+			argumentsSection = lipgloss.JoinVertical(lipgloss.Left, argumentsSection, styles.ErrorStyle.Width(contentWidth).Render(d.argumentsError))
+		}
+	case d.details != nil && d.details.Expanded():
+		argumentsSection = d.details.View()
+	case d.details != nil:
+		argumentsSection = lipgloss.JoinVertical(lipgloss.Left, callView, d.details.View())
+	default:
+		argumentsSection = callView
+	}
 
 	// Combine all parts with proper spacing
 	parts := []string{title, separator}
@@ -333,8 +564,8 @@ func (d *toolConfirmationDialog) View() string {
 	}
 
 	// Confirmation prompt
-	question := styles.DialogQuestionStyle.Width(contentWidth).Render("Do you want to allow this tool call?")
-	options := RenderHelpKeys(contentWidth, "Y", "yes", "N", "no", "T", d.alwaysAllowHelpText(), "A", "all tools")
+	question := styles.DialogQuestionStyle.Width(contentWidth).Render(d.question())
+	options := d.renderOptions(contentWidth)
 
 	parts = append(parts, "", question, "", options)
 
@@ -2,10 +2,12 @@ package tui
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/exec"
+	"path/filepath"
 	goruntime "runtime"
 	"strings"
 
@@ -15,6 +17,7 @@ import (
 	"github.com/docker/docker-agent/pkg/app"
 	"github.com/docker/docker-agent/pkg/browser"
 	"github.com/docker/docker-agent/pkg/evaluation"
+	"github.com/docker/docker-agent/pkg/runtime"
 	"github.com/docker/docker-agent/pkg/session"
 	"github.com/docker/docker-agent/pkg/shellpath"
 	"github.com/docker/docker-agent/pkg/tools"
@@ -204,6 +207,7 @@ func (m *appModel) handleDeleteSession(sessionID string) (tea.Model, tea.Cmd) {
 	if err := store.DeleteSession(context.Background(), sessionID); err != nil {
 		return m, notification.ErrorCmd("Failed to delete session: " + err.Error())
 	}
+	m.application.ForgetSession(sessionID)
 
 	return m, notification.SuccessCmd("Session deleted.")
 }
@@ -220,7 +224,16 @@ func (m *appModel) handleEvalSession(filename string) (tea.Model, tea.Cmd) {
 }
 
 func (m *appModel) handleExportSession(filename string) (tea.Model, tea.Cmd) {
-	exportFile, err := m.application.ExportHTML(context.Background(), filename)
+	var exportFile string
+	var err error
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".md":
+		exportFile, err = m.application.ExportTranscript(filename, session.ExportFormatMarkdown)
+	case ".json":
+		exportFile, err = m.application.ExportTranscript(filename, session.ExportFormatJSON)
+	default:
+		exportFile, err = m.application.ExportHTML(context.Background(), filename)
+	}
 	if err != nil {
 		return m, notification.ErrorCmd(fmt.Sprintf("Failed to export session: %v", err))
 	}
@@ -231,6 +244,61 @@ func (m *appModel) handleCompactSession(additionalPrompt string) (tea.Model, tea
 	return m, m.chatPage.CompactSession(additionalPrompt)
 }
 
+func (m *appModel) handleCreateCheckpoint(label string) (tea.Model, tea.Cmd) {
+	if m.application.Session() == nil {
+		return m, notification.InfoCmd("No active session.")
+	}
+	// The runtime confirms the checkpoint with a CheckpointCreatedEvent.
+	if _, err := m.application.CreateCheckpoint(context.Background(), label); err != nil {
+		return m, notification.ErrorCmd(fmt.Sprintf("Failed to create checkpoint: %v", err))
+	}
+	return m, nil
+}
+
+func (m *appModel) handleListCheckpoints() (tea.Model, tea.Cmd) {
+	sess := m.application.Session()
+	if sess == nil {
+		return m, notification.InfoCmd("No active session.")
+	}
+	checkpoints := sess.ListCheckpoints()
+	if len(checkpoints) == 0 {
+		return m, notification.InfoCmd("No checkpoints yet. Create one with /checkpoint [label].")
+	}
+
+	lines := make([]string, 0, len(checkpoints)+1)
+	lines = append(lines, "Checkpoints:")
+	for _, checkpoint := range checkpoints {
+		line := checkpoint.ID + "  " + checkpoint.CreatedAt.Local().Format("15:04")
+		if checkpoint.Label != "" {
+			line += "  " + checkpoint.Label
+		}
+		lines = append(lines, line)
+	}
+	return m, notification.InfoCmd(strings.Join(lines, "\n"))
+}
+
+func (m *appModel) handleRollbackSession(msg messages.RollbackSessionMsg) (tea.Model, tea.Cmd) {
+	sess := m.application.Session()
+	if sess == nil {
+		return m, notification.InfoCmd("No active session.")
+	}
+
+	// The runtime confirms the rollback with a SessionRolledBackEvent.
+	if err := m.application.RollbackSession(context.Background(), msg.Checkpoint, msg.RevertFiles); err != nil {
+		if errors.Is(err, runtime.ErrSessionRunning) {
+			return m, notification.WarningCmd("Wait for the agent to finish, or stop it, before rolling back.")
+		}
+		return m, notification.ErrorCmd(fmt.Sprintf("Failed to roll back: %v", err))
+	}
+
+	// Rebuild the per-session components to show the rolled back messages.
+	sidebarSettings := m.chatPage.GetSidebarSettings()
+	m.initSessionComponents(m.supervisor.ActiveID(), m.application, sess)
+	m.chatPage.SetSidebarSettings(sidebarSettings)
+
+	return m, m.initAndFocusComponents()
+}
+
 func (m *appModel) handleCopySessionToClipboard() (tea.Model, tea.Cmd) {
 	transcript := m.application.PlainTextTranscript()
 	if transcript == "" {
@@ -633,9 +701,21 @@ func (m *appModel) closeTranscriptCh() {
 
 func (m *appModel) handleElicitationResponse(action tools.ElicitationAction, content map[string]any) (tea.Model, tea.Cmd) {
 	if err := m.application.ResumeElicitation(context.Background(), action, content); err != nil {
+		if verr, ok := errors.AsType[*runtime.ElicitationValidationError](err); ok && m.pendingElicitation != nil {
+			// The request is still pending: ask again, showing what was wrong.
+			fieldErrors := make(map[string]string, len(verr.Violations))
+			for _, v := range verr.Violations {
+				fieldErrors[v.Field] = v.Message
+			}
+			req := m.pendingElicitation
+			return m, core.CmdHandler(dialog.OpenDialogMsg{
+				Model: dialog.NewElicitationDialogWithErrors(req.Message, req.Schema, req.Meta, fieldErrors),
+			})
+		}
 		slog.Error("Failed to resume elicitation", "action", action, "error", err)
 		return m, notification.ErrorCmd("Failed to complete server request: " + err.Error())
 	}
+	m.pendingElicitation = nil
 	return m, nil
 }
 
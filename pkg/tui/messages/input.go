@@ -22,4 +22,15 @@ type (
 
 	// OpenURLMsg opens a URL in the browser.
 	OpenURLMsg struct{ URL string }
+
+	// EstimateCostMsg estimates the cost of sending the draft of the editor,
+	// unless the draft changed again since the estimate was scheduled.
+	EstimateCostMsg struct{ Seq int }
+
+	// CostEstimatedMsg carries the estimated cost of the next turn, empty
+	// when it can't be estimated.
+	CostEstimatedMsg struct {
+		Seq      int
+		Estimate string
+	}
 )
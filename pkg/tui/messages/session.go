@@ -41,6 +41,20 @@ type (
 	// CompactSessionMsg generates a summary and compacts session history.
 	CompactSessionMsg struct{ AdditionalPrompt string }
 
+	// CreateCheckpointMsg tags the current point of the session as a checkpoint.
+	CreateCheckpointMsg struct{ Label string }
+
+	// RollbackSessionMsg rolls the session back to a checkpoint, given by ID
+	// or label; empty means the latest one. RevertFiles also restores the
+	// files changed since the checkpoint.
+	RollbackSessionMsg struct {
+		Checkpoint  string
+		RevertFiles bool
+	}
+
+	// ListCheckpointsMsg lists the checkpoints of the session.
+	ListCheckpointsMsg struct{}
+
 	// CopySessionToClipboardMsg copies the entire conversation to clipboard.
 	CopySessionToClipboardMsg struct{}
 
@@ -56,6 +70,9 @@ type (
 	// LoadSessionMsg loads a session by ID.
 	LoadSessionMsg struct{ SessionID string }
 
+	// ViewSessionMsg shows the transcript of a saved session without resuming it.
+	ViewSessionMsg struct{ SessionID string }
+
 	// ToggleSessionStarMsg toggles star on a session; empty ID means current session.
 	ToggleSessionStarMsg struct{ SessionID string }
 
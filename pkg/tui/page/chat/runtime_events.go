@@ -3,13 +3,18 @@ package chat
 import (
 	"fmt"
 	"log/slog"
+	"strconv"
+	"strings"
 	"time"
 
 	tea "charm.land/bubbletea/v2"
 
+	"github.com/docker/docker-agent/pkg/chat"
 	"github.com/docker/docker-agent/pkg/runtime"
+	"github.com/docker/docker-agent/pkg/session"
 	"github.com/docker/docker-agent/pkg/sound"
 	"github.com/docker/docker-agent/pkg/tools"
+	"github.com/docker/docker-agent/pkg/tools/builtin"
 	"github.com/docker/docker-agent/pkg/tui/components/notification"
 	"github.com/docker/docker-agent/pkg/tui/components/sidebar"
 	"github.com/docker/docker-agent/pkg/tui/core"
@@ -29,18 +34,42 @@ import (
 //   - StreamStoppedEvent  → Stop spinners, process queue, maybe exit
 //
 // Content Events:
-//   - AgentChoiceEvent         → Append text to message
-//   - AgentChoiceReasoningEvent → Append reasoning block
-//   - UserMessageEvent         → Replace loading with user message
+//   - AgentChoiceEvent           → Append text to message
+//   - AgentChoiceReasoningEvent  → Append reasoning block
+//   - AgentThoughtEvent          → Append think tool thought to reasoning block
+//   - ProviderToolEvent          → Append what a provider's built-in tool did to reasoning block
+//   - AgentMessageCompletedEvent → Finalize the streamed message
+//   - UserMessageEvent           → Replace loading with user message
+//   - MessageAddedEvent          → Show system reminders, apart from user messages
 //
 // Tool Events:
 //   - PartialToolCallEvent      → Show tool call in progress
 //   - ToolCallEvent             → Tool execution started
 //   - ToolCallConfirmationEvent → Show confirmation dialog
 //   - ToolCallResponseEvent     → Show tool result
+//   - ToolCallQueuedEvent       → Notify that a call waits for its toolset
+//
+// Think tool calls aren't shown as tool calls: their thoughts are rendered
+// with the reasoning content instead, through AgentThoughtEvent.
+//
+// Configuration:
+//   - ConfigReloadedEvent → Notify that the agent configuration was reloaded
+//
+// Checkpoints:
+//   - CheckpointCreatedEvent → Notify that the checkpoint was created
+//   - SessionRolledBackEvent → Notify the checkpoint and the files rolled back to
+//
+// Handoffs:
+//   - HandoffLoopDetectedEvent → Warn that a handoff loop was blocked
+//
+// Output guards:
+//   - GuardRejectedEvent → Warn that an answer was rejected by an output guard
+//
+// Model routing:
+//   - ModelRoutedEvent → Show the model picked by an escalating router
 //
 // Sidebar Updates (forwarded):
-//   - TokenUsageEvent, AgentInfoEvent, TeamInfoEvent, etc.
+//   - TokenUsageEvent, AgentInfoEvent, TeamInfoEvent, BlackboardUpdatedEvent, AgentHandoffEvent, FileChangedEvent, etc.
 //
 // Dialogs:
 //   - MaxIterationsReachedEvent → Show max iterations dialog
@@ -57,10 +86,28 @@ func (p *chatPage) handleRuntimeEvent(msg tea.Msg) (bool, tea.Cmd) {
 		if userconfig.Get().GetSound() {
 			sound.Play(sound.Failure)
 		}
-		return true, p.messages.AddErrorMessage(msg.Error)
+		return true, p.messages.AddErrorMessage(withHint(msg.Error, msg.Hint))
 
 	case *runtime.WarningEvent:
-		return true, notification.WarningCmd(msg.Message)
+		return true, notification.WarningCmd(withHint(msg.Message, msg.Hint))
+
+	case *runtime.ConfigReloadedEvent:
+		return true, notification.SuccessCmd("Agent configuration reloaded.")
+
+	case *runtime.CheckpointCreatedEvent:
+		return true, notification.SuccessCmd(fmt.Sprintf("Checkpoint %s created.", checkpointName(msg.Checkpoint)))
+
+	case *runtime.SessionRolledBackEvent:
+		return true, notification.SuccessCmd(rolledBackMessage(msg))
+
+	case *runtime.HandoffLoopDetectedEvent:
+		return true, notification.WarningCmd(fmt.Sprintf("Blocked a handoff loop: %s keeps handing off to %s.", msg.FromAgent, msg.ToAgent))
+
+	case *runtime.GuardRejectedEvent:
+		return true, notification.WarningCmd(guardRejectedMessage(msg))
+
+	case *runtime.ModelRoutedEvent:
+		return true, p.sidebar.SetAgentInfo(msg.AgentName, msg.Model, "")
 
 	case *runtime.ModelFallbackEvent:
 		// Update sidebar with the fallback model immediately so it reflects the switch
@@ -69,6 +116,12 @@ func (p *chatPage) handleRuntimeEvent(msg tea.Msg) (bool, tea.Cmd) {
 		fallbackMsg := fmt.Sprintf("Model %s failed (%s), switching to %s", msg.FailedModel, msg.Reason, msg.FallbackModel)
 		return true, tea.Batch(sidebarCmd, notification.WarningCmd(fallbackMsg))
 
+	case *runtime.ThrottledEvent:
+		return true, notification.InfoCmd(throttledMessage(msg))
+
+	case *runtime.MCPSamplingEvent:
+		return true, notification.InfoCmd(mcpSamplingMessage(msg))
+
 	// ===== Stream Lifecycle Events =====
 	case *runtime.StreamStartedEvent:
 		return true, p.handleStreamStarted(msg)
@@ -86,9 +139,24 @@ func (p *chatPage) handleRuntimeEvent(msg tea.Msg) (bool, tea.Cmd) {
 	case *runtime.AgentChoiceReasoningEvent:
 		return true, p.handleAgentChoiceReasoning(msg)
 
+	case *runtime.AgentThoughtEvent:
+		return true, p.handleAgentThought(msg)
+
+	case *runtime.ProviderToolEvent:
+		return true, p.handleProviderTool(msg)
+
+	case *runtime.AgentMessageCompletedEvent:
+		return true, p.handleAgentMessageCompleted(msg)
+
 	case *runtime.ShellOutputEvent:
 		return true, p.messages.AddShellOutputMessage(msg.Output)
 
+	case *runtime.MessageAddedEvent:
+		if msg.Message == nil || !msg.Message.IsSystemReminder() || msg.Message.Implicit {
+			return false, nil
+		}
+		return true, p.messages.AddSystemReminderMessage(msg.Message.AgentName, msg.Message.Message.Content)
+
 	// ===== Tool Events =====
 	case *runtime.PartialToolCallEvent:
 		return true, p.handlePartialToolCall(msg)
@@ -102,6 +170,15 @@ func (p *chatPage) handleRuntimeEvent(msg tea.Msg) (bool, tea.Cmd) {
 	case *runtime.ToolCallResponseEvent:
 		return true, p.handleToolCallResponse(msg)
 
+	case *runtime.ToolCallValidationFailedEvent:
+		if msg.ArgumentsEdit == nil {
+			return false, nil
+		}
+		return true, notification.ErrorCmd("The edited arguments were rejected: " + strings.Join(msg.Violations, "; "))
+
+	case *runtime.ToolCallQueuedEvent:
+		return true, notification.InfoCmd(fmt.Sprintf("%s is waiting for the other calls to its toolset to finish", msg.ToolCall.Function.Name))
+
 	// ===== Sidebar Info Events (forwarded) =====
 	case *runtime.TokenUsageEvent:
 		p.handleTokenUsage(msg)
@@ -127,9 +204,23 @@ func (p *chatPage) handleRuntimeEvent(msg tea.Msg) (bool, tea.Cmd) {
 	case *runtime.SessionTitleEvent:
 		return true, p.forwardToSidebar(msg)
 
+	case *runtime.BlackboardUpdatedEvent:
+		return true, p.forwardToSidebar(msg)
+
+	case *runtime.AgentHandoffEvent:
+		return true, p.forwardToSidebar(msg)
+
+	case *runtime.FileChangedEvent:
+		return true, p.forwardToSidebar(msg)
+
 	case *runtime.SessionCompactionEvent:
 		if msg.Status == "completed" {
+			var marker tea.Cmd
+			if msg.Covered != nil {
+				marker = p.messages.AddCompactionMarker(msg.CoveredMessages, msg.Covered.End)
+			}
 			return true, tea.Batch(
+				marker,
 				p.setWorking(false),
 				p.setPendingResponse(false),
 				notification.SuccessCmd("Session compacted successfully."),
@@ -163,6 +254,53 @@ func (p *chatPage) forwardToSidebar(msg tea.Msg) tea.Cmd {
 	return cmd
 }
 
+// throttledMessage describes why a request to a model is waiting.
+func throttledMessage(msg *runtime.ThrottledEvent) string {
+	if msg.Wait > 0 {
+		return fmt.Sprintf("Rate limit reached for %s, waiting %s", msg.Model, msg.Wait.Round(100*time.Millisecond))
+	}
+	return fmt.Sprintf("Too many concurrent requests to %s, waiting for one to finish", msg.Model)
+}
+
+func mcpSamplingMessage(msg *runtime.MCPSamplingEvent) string {
+	text := fmt.Sprintf("MCP server %s used %s", msg.Toolset, msg.Model)
+	if msg.Usage != nil {
+		text += fmt.Sprintf(" (%d tokens", msg.Usage.InputTokens+msg.Usage.OutputTokens)
+		if msg.Cost > 0 {
+			text += fmt.Sprintf(", $%.4f", msg.Cost)
+		}
+		text += ")"
+	}
+	return text
+}
+
+func guardRejectedMessage(msg *runtime.GuardRejectedEvent) string {
+	if msg.SubAgent != "" {
+		return fmt.Sprintf("The result of %s was rejected by an output guard of %s: %s", msg.SubAgent, msg.AgentName, msg.Reason)
+	}
+	return fmt.Sprintf("The answer of %s was rejected by an output guard: %s", msg.AgentName, msg.Reason)
+}
+
+// checkpointName is the label of a checkpoint, or its ID if it has none.
+func checkpointName(checkpoint session.Checkpoint) string {
+	if checkpoint.Label != "" {
+		return fmt.Sprintf("%q", checkpoint.Label)
+	}
+	return checkpoint.ID
+}
+
+func rolledBackMessage(msg *runtime.SessionRolledBackEvent) string {
+	text := "Rolled back to checkpoint " + checkpointName(msg.Checkpoint) + "."
+	switch n := len(msg.RevertedFiles); n {
+	case 0:
+		return text
+	case 1:
+		return text + " Reverted 1 file."
+	default:
+		return fmt.Sprintf("%s Reverted %d files.", text, n)
+	}
+}
+
 // handleTokenUsage updates sidebar and session with token usage data.
 // This handler performs side effects only and returns no command.
 func (p *chatPage) handleTokenUsage(msg *runtime.TokenUsageEvent) {
@@ -222,6 +360,59 @@ func (p *chatPage) handleAgentChoiceReasoning(msg *runtime.AgentChoiceReasoningE
 	return p.messages.AppendReasoning(msg.AgentName, msg.Content)
 }
 
+func (p *chatPage) handleAgentThought(msg *runtime.AgentThoughtEvent) tea.Cmd {
+	if p.streamCancelled {
+		return nil
+	}
+	return p.messages.AppendReasoning(msg.AgentName, msg.Thought+"\n\n")
+}
+
+func (p *chatPage) handleProviderTool(msg *runtime.ProviderToolEvent) tea.Cmd {
+	if p.streamCancelled {
+		return nil
+	}
+	return p.messages.AppendReasoning(msg.AgentName, providerToolSummary(msg.ProviderTool)+"\n\n")
+}
+
+// providerToolSummary describes what a built-in tool of the provider did,
+// e.g. the queries of a web search and the pages it found.
+func providerToolSummary(use chat.ProviderToolUse) string {
+	var b strings.Builder
+	switch use.Tool {
+	case "web_search":
+		if len(use.Queries) == 0 {
+			b.WriteString("Browsed the web")
+		} else {
+			quoted := make([]string, 0, len(use.Queries))
+			for _, query := range use.Queries {
+				quoted = append(quoted, strconv.Quote(query))
+			}
+			b.WriteString("Searched the web for " + strings.Join(quoted, ", "))
+		}
+	case "code_execution":
+		b.WriteString("Ran code")
+	default:
+		b.WriteString("Used " + use.Tool)
+	}
+	if use.Error != "" {
+		b.WriteString(" (failed: " + use.Error + ")")
+	}
+	for _, url := range use.URLs {
+		b.WriteString("\n- " + url)
+	}
+	if use.Code != "" {
+		b.WriteString("\n```\n" + strings.TrimRight(use.Code, "\n") + "\n```")
+	}
+	return b.String()
+}
+
+func (p *chatPage) handleAgentMessageCompleted(msg *runtime.AgentMessageCompletedEvent) tea.Cmd {
+	if p.streamCancelled {
+		return nil
+	}
+	return p.messages.CompleteLastMessage(msg.AgentName, msg.Content, msg.Annotations)
+}
+
 func (p *chatPage) handleStreamStopped(msg *runtime.StreamStoppedEvent) tea.Cmd {
 	slog.Debug("handleStreamStopped called",
 		"agent", msg.AgentName,
@@ -274,6 +465,9 @@ func (p *chatPage) handleStreamStopped(msg *runtime.StreamStoppedEvent) tea.Cmd
 // "pending" indicator (not animated) to show it's receiving data.
 func (p *chatPage) handlePartialToolCall(msg *runtime.PartialToolCallEvent) tea.Cmd {
 	p.setPendingResponse(false)
+	if msg.ToolCall.Function.Name == builtin.ToolNameThink {
+		return nil
+	}
 	var toolDef tools.Tool
 	if msg.ToolDefinition != nil {
 		toolDef = *msg.ToolDefinition
@@ -295,8 +489,15 @@ func (p *chatPage) handleToolCall(msg *runtime.ToolCallEvent) tea.Cmd {
 	p.setPendingResponse(false)
 	spinnerCmd := p.setWorking(true)
 	sidebarCmd := p.forwardToSidebar(msg)
+	if msg.ToolCall.Function.Name == builtin.ToolNameThink {
+		return tea.Batch(spinnerCmd, sidebarCmd)
+	}
 	toolCmd := p.messages.AddOrUpdateToolCall(msg.AgentName, msg.ToolCall, msg.ToolDefinition, types.ToolStatusRunning)
-	return tea.Batch(toolCmd, p.messages.ScrollToBottom(), spinnerCmd, sidebarCmd)
+	var editedCmd tea.Cmd
+	if msg.ArgumentsEdit != nil {
+		editedCmd = notification.InfoCmd(fmt.Sprintf("Running %s with the arguments edited by the user", msg.ToolCall.Function.Name))
+	}
+	return tea.Batch(toolCmd, p.messages.ScrollToBottom(), spinnerCmd, sidebarCmd, editedCmd)
 }
 
 func (p *chatPage) handleToolCallResponse(msg *runtime.ToolCallResponseEvent) tea.Cmd {
@@ -361,3 +562,11 @@ func (p *chatPage) handleElicitationRequest(msg *runtime.ElicitationRequestEvent
 		return tea.Batch(spinnerCmd, dialogCmd)
 	}
 }
+
+// withHint appends the hint of an error or a warning, if any, to its message.
+func withHint(message, hint string) string {
+	if hint == "" {
+		return message
+	}
+	return message + "\n\n" + hint
+}
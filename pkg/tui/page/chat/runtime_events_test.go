@@ -0,0 +1,46 @@
+package chat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/docker/docker-agent/pkg/chat"
+)
+
+func TestProviderToolSummary(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		use  chat.ProviderToolUse
+		want string
+	}{
+		{
+			name: "web search",
+			use:  chat.ProviderToolUse{Tool: "web_search", Queries: []string{"docker agent", "mcp"}, URLs: []string{"https://docs.docker.com"}},
+			want: "Searched the web for \"docker agent\", \"mcp\"\n- https://docs.docker.com",
+		},
+		{
+			name: "open page",
+			use:  chat.ProviderToolUse{Tool: "web_search", URLs: []string{"https://docs.docker.com"}},
+			want: "Browsed the web\n- https://docs.docker.com",
+		},
+		{
+			name: "code execution",
+			use:  chat.ProviderToolUse{Tool: "code_execution", Code: "print(1)\n", Output: "1"},
+			want: "Ran code\n```\nprint(1)\n```",
+		},
+		{
+			name: "failure",
+			use:  chat.ProviderToolUse{Tool: "web_search", Queries: []string{"q"}, Error: "max_uses_exceeded"},
+			want: "Searched the web for \"q\" (failed: max_uses_exceeded)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, providerToolSummary(tt.use))
+		})
+	}
+}
@@ -0,0 +1,314 @@
+package chat
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/dlclark/regexp2"
+	"gotest.tools/v3/golden"
+
+	"github.com/docker/docker-agent/pkg/app"
+	"github.com/docker/docker-agent/pkg/chat"
+	"github.com/docker/docker-agent/pkg/runtime"
+	"github.com/docker/docker-agent/pkg/session"
+	"github.com/docker/docker-agent/pkg/tools"
+	"github.com/docker/docker-agent/pkg/tui/dialog"
+	"github.com/docker/docker-agent/pkg/tui/service"
+)
+
+// Size of the terminal the snapshots are rendered in. It's wide enough for
+// the sidebar to be shown next to the messages.
+const (
+	snapshotWidth  = 120
+	snapshotHeight = 32
+)
+
+const snapshotSessionID = "snapshot-session"
+
+// spinnerFrame matches the frames of the spinners, which depend on the
+// animation ticks.
+var spinnerFrame = regexp.MustCompile(`[⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏]`)
+
+func TestMain(m *testing.M) {
+	// The syntax highlighter matches with timeouts, checked against a clock
+	// that regexp2 updates in a goroutine running a second past the last
+	// deadline. Started in a synctest bubble, the goroutine would outlive
+	// the test: start it outside of them, for the whole run.
+	re := regexp2.MustCompile(".", regexp2.None)
+	re.MatchTimeout = time.Hour
+	_, _ = re.MatchString(".")
+
+	os.Exit(m.Run())
+}
+
+// snapshotHarness feeds runtime events into a chat page and renders its
+// frames, with the dialogs the events open on top of it, like the app does.
+type snapshotHarness struct {
+	t       *testing.T
+	page    *chatPage
+	dialogs dialog.Manager
+
+	// pending holds the results of the dropped commands, which wait for a
+	// timer.
+	pending []chan tea.Msg
+}
+
+// newSnapshotHarness must be called in a synctest bubble, so that the
+// clock, and the durations the page shows, are fake.
+func newSnapshotHarness(t *testing.T) *snapshotHarness {
+	t.Helper()
+
+	sessionState := &service.SessionState{}
+	page := New(&app.App{}, sessionState).(*chatPage)
+	page.sidebar.LoadFromSession(session.New(session.WithWorkingDir("/work/project")))
+
+	h := &snapshotHarness{
+		t:       t,
+		page:    page,
+		dialogs: dialog.New(),
+	}
+	// The goroutines of the bubble must exit before the test ends: wait for
+	// the dropped commands, the fake clock advances to their timers.
+	t.Cleanup(func() {
+		for _, result := range h.pending {
+			<-result
+		}
+	})
+	h.send(
+		tea.WindowSizeMsg{Width: snapshotWidth, Height: snapshotHeight},
+		runtime.TeamInfo([]runtime.AgentDetails{{
+			Name:        "root",
+			Description: "A helpful assistant",
+			Provider:    "openai",
+			Model:       "gpt-4o",
+		}}, "root"),
+		runtime.AgentInfo("root", "openai/gpt-4o", "A helpful assistant", ""),
+	)
+	return h
+}
+
+// send passes msgs to the page, and to the dialog manager for the window
+// size, in order.
+func (h *snapshotHarness) send(msgs ...tea.Msg) {
+	h.t.Helper()
+
+	for _, msg := range msgs {
+		if size, ok := msg.(tea.WindowSizeMsg); ok {
+			_, cmd := h.dialogs.Update(size)
+			h.run(cmd)
+		}
+		_, cmd := h.page.Update(msg)
+		h.run(cmd)
+	}
+}
+
+// run runs cmd and opens the dialogs it asks for. The commands that wait,
+// such as the animation ticks, are dropped so that the frames don't depend
+// on timing, and so are the other messages, which are handled by the app.
+func (h *snapshotHarness) run(cmd tea.Cmd) {
+	if cmd == nil {
+		return
+	}
+
+	result := make(chan tea.Msg, 1)
+	go func() {
+		result <- cmd()
+	}()
+	synctest.Wait()
+
+	var msg tea.Msg
+	select {
+	case msg = <-result:
+	default:
+		h.pending = append(h.pending, result)
+		return
+	}
+
+	switch msg := msg.(type) {
+	case tea.BatchMsg:
+		for _, cmd := range msg {
+			h.run(cmd)
+		}
+	case dialog.OpenDialogMsg:
+		_, cmd := h.dialogs.Update(msg)
+		h.run(cmd)
+	}
+}
+
+// frame renders the page, with the open dialogs on top, as plain text.
+func (h *snapshotHarness) frame() string {
+	layers := []*lipgloss.Layer{lipgloss.NewLayer(h.page.View())}
+	if h.dialogs.Open() {
+		layers = append(layers, h.dialogs.GetLayers()...)
+	}
+	return normalizeFrame(lipgloss.NewCompositor(layers...).Render())
+}
+
+// assertFrame compares the current frame with the golden file name, in
+// testdata. Run the tests with -update to regenerate the golden files.
+func (h *snapshotHarness) assertFrame(name string) {
+	h.t.Helper()
+
+	if _, err := os.Stat(filepath.Join("testdata", name)); os.IsNotExist(err) && !golden.FlagUpdate() {
+		h.t.Fatalf("missing golden file %s, run the tests with -update to create it", name)
+	}
+	golden.Assert(h.t, h.frame(), name)
+}
+
+// normalizeFrame removes the styles, the spinner frames and the trailing
+// spaces of a frame.
+func normalizeFrame(frame string) string {
+	frame = spinnerFrame.ReplaceAllString(ansi.Strip(frame), "*")
+
+	lines := strings.Split(frame, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n") + "\n"
+}
+
+func TestSnapshotStreamingMarkdown(t *testing.T) {
+	t.Parallel()
+
+	synctest.Test(t, func(t *testing.T) {
+		h := newSnapshotHarness(t)
+
+		answer := []string{
+			"## Listing files\n\n",
+			"Use `ls` with a few flags:\n\n",
+			"```sh\nls -la\n```\n\n",
+			"- `-l` shows the **long** format\n",
+			"- `-a` includes the *hidden* files\n",
+		}
+
+		h.send(
+			runtime.UserMessage("How do I list all the files?", snapshotSessionID, nil),
+			runtime.StreamStarted(snapshotSessionID, "root"),
+			runtime.AgentChoice("root", snapshotSessionID, answer[0]),
+			runtime.AgentChoice("root", snapshotSessionID, answer[1]),
+			runtime.AgentChoice("root", snapshotSessionID, answer[2]),
+		)
+		h.assertFrame("streaming_markdown_partial.golden")
+
+		h.send(
+			runtime.AgentChoice("root", snapshotSessionID, answer[3]),
+			runtime.AgentChoice("root", snapshotSessionID, answer[4]),
+			runtime.AgentMessageCompleted("root", snapshotSessionID, strings.Join(answer, ""), "", nil, chat.FinishReasonStop, nil, nil),
+			runtime.StreamStopped(snapshotSessionID, "root"),
+		)
+		h.assertFrame("streaming_markdown.golden")
+	})
+}
+
+func TestSnapshotToolCallConfirmation(t *testing.T) {
+	t.Parallel()
+
+	synctest.Test(t, func(t *testing.T) {
+		h := newSnapshotHarness(t)
+
+		toolCall := tools.ToolCall{
+			ID:   "call_1",
+			Type: "function",
+			Function: tools.FunctionCall{
+				Name:      "create_issue",
+				Arguments: `{"title":"Crash on startup","labels":["bug"]}`,
+			},
+		}
+		toolDef := tools.Tool{
+			Name:        "create_issue",
+			Category:    "github",
+			Description: "Create an issue in the repository",
+			Annotations: tools.ToolAnnotations{Title: "Create Issue"},
+		}
+
+		h.send(
+			runtime.UserMessage("File a bug about the crash on startup", snapshotSessionID, nil),
+			runtime.StreamStarted(snapshotSessionID, "root"),
+			runtime.AgentChoice("root", snapshotSessionID, "I'll open an issue for it."),
+			runtime.ToolCallConfirmation(toolCall, toolDef, nil, "root"),
+		)
+		h.assertFrame("tool_call_confirmation.golden")
+	})
+}
+
+func TestSnapshotElicitationForm(t *testing.T) {
+	t.Parallel()
+
+	synctest.Test(t, func(t *testing.T) {
+		h := newSnapshotHarness(t)
+
+		schema := map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"environment": map[string]any{
+					"type":        "string",
+					"description": "Where to deploy",
+					"enum":        []any{"staging", "production"},
+				},
+				"version": map[string]any{
+					"type":        "string",
+					"description": "The version to deploy",
+				},
+				"notify": map[string]any{
+					"type":        "boolean",
+					"description": "Notify the team",
+				},
+			},
+			"required": []any{"environment", "version"},
+		}
+
+		h.send(
+			runtime.UserMessage("Deploy the app", snapshotSessionID, nil),
+			runtime.StreamStarted(snapshotSessionID, "root"),
+			runtime.AgentChoice("root", snapshotSessionID, "I need a few details first."),
+			runtime.ElicitationRequest("The deploy server needs more information.", "form", schema, "", "elicitation_1", nil, "root"),
+		)
+		h.assertFrame("elicitation_form.golden")
+	})
+}
+
+func TestSnapshotRAGProgress(t *testing.T) {
+	t.Parallel()
+
+	synctest.Test(t, func(t *testing.T) {
+		h := newSnapshotHarness(t)
+
+		h.send(
+			runtime.RAGIndexingStarted("project_docs", "bm25"),
+			runtime.RAGIndexingStarted("project_docs", "chunked-embeddings"),
+			runtime.RAGIndexingProgress("project_docs", "bm25", 42, 42, "root"),
+			runtime.RAGIndexingProgress("project_docs", "chunked-embeddings", 12, 42, "root"),
+		)
+		h.assertFrame("rag_progress.golden")
+
+		h.send(
+			runtime.RAGIndexingCompleted("project_docs", "bm25"),
+			runtime.RAGIndexingCompleted("project_docs", "chunked-embeddings"),
+		)
+		h.assertFrame("rag_progress_completed.golden")
+	})
+}
+
+func TestSnapshotMaxIterations(t *testing.T) {
+	t.Parallel()
+
+	synctest.Test(t, func(t *testing.T) {
+		h := newSnapshotHarness(t)
+
+		h.send(
+			runtime.UserMessage("Fix the flaky test", snapshotSessionID, nil),
+			runtime.StreamStarted(snapshotSessionID, "root"),
+			runtime.AgentChoice("root", snapshotSessionID, "Let me run the tests again."),
+			runtime.MaxIterationsReached(20),
+		)
+		h.assertFrame("max_iterations.golden")
+	})
+}
@@ -0,0 +1,65 @@
+package styles
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SupportsHyperlinks reports whether the terminal is known to support OSC 8
+// hyperlinks. The detection is based on the environment and can be forced
+// with FORCE_HYPERLINK=1 (or disabled with FORCE_HYPERLINK=0).
+func SupportsHyperlinks() bool {
+	return supportsHyperlinksOnce()
+}
+
+var supportsHyperlinksOnce = sync.OnceValue(func() bool {
+	return supportsHyperlinks(os.Getenv)
+})
+
+// hyperlinkTerminals are the TERM_PROGRAM values of terminals supporting OSC 8.
+var hyperlinkTerminals = []string{
+	"iTerm.app",
+	"WezTerm",
+	"vscode",
+	"ghostty",
+	"Hyper",
+	"Tabby",
+	"rio",
+}
+
+func supportsHyperlinks(getenv func(string) string) bool {
+	if force := getenv("FORCE_HYPERLINK"); force != "" {
+		return force != "0"
+	}
+
+	term := getenv("TERM")
+	switch {
+	case term == "dumb":
+		return false
+	// Multiplexers don't reliably pass the sequences through
+	case getenv("TMUX") != "", strings.HasPrefix(term, "screen"):
+		return false
+	}
+
+	for _, program := range hyperlinkTerminals {
+		if strings.EqualFold(getenv("TERM_PROGRAM"), program) {
+			return true
+		}
+	}
+
+	switch {
+	case getenv("WT_SESSION") != "", getenv("KONSOLE_VERSION") != "", getenv("KITTY_WINDOW_ID") != "":
+		return true
+	case strings.Contains(term, "kitty"), strings.Contains(term, "alacritty"), strings.Contains(term, "foot"), strings.Contains(term, "ghostty"):
+		return true
+	}
+
+	// GNOME Terminal and other VTE based terminals, since VTE 0.50
+	if vte, err := strconv.Atoi(getenv("VTE_VERSION")); err == nil && vte >= 5000 {
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,37 @@
+package styles
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSupportsHyperlinks(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		want bool
+	}{
+		{"unknown terminal", map[string]string{"TERM": "xterm-256color"}, false},
+		{"iTerm", map[string]string{"TERM_PROGRAM": "iTerm.app"}, true},
+		{"WezTerm", map[string]string{"TERM_PROGRAM": "WezTerm"}, true},
+		{"kitty", map[string]string{"TERM": "xterm-kitty"}, true},
+		{"Windows Terminal", map[string]string{"WT_SESSION": "abc"}, true},
+		{"recent VTE", map[string]string{"VTE_VERSION": "7200"}, true},
+		{"old VTE", map[string]string{"VTE_VERSION": "4600"}, false},
+		{"dumb terminal", map[string]string{"TERM": "dumb", "TERM_PROGRAM": "iTerm.app"}, false},
+		{"tmux", map[string]string{"TMUX": "/tmp/tmux", "TERM_PROGRAM": "iTerm.app"}, false},
+		{"forced", map[string]string{"FORCE_HYPERLINK": "1", "TMUX": "/tmp/tmux"}, true},
+		{"disabled", map[string]string{"FORCE_HYPERLINK": "0", "TERM_PROGRAM": "iTerm.app"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			getenv := func(key string) string { return tt.env[key] }
+			assert.Equal(t, tt.want, supportsHyperlinks(getenv))
+		})
+	}
+}
@@ -94,9 +94,19 @@ type appModel struct {
 	transcriber  *transcribe.Transcriber
 	transcriptCh chan string // bridges transcriber goroutine → Bubble Tea event loop
 
+	// pendingElicitation is the last elicitation request shown to the user,
+	// kept to prompt again when the runtime rejects the answer.
+	pendingElicitation *runtime.ElicitationRequestEvent
+
 	// Working state indicator (resize handle spinner)
 	workingSpinner spinner.Spinner
 
+	// Cost estimate of the next turn shown in the status bar: the draft it
+	// was last scheduled for, and the sequence number of that estimate, so
+	// that the results of outdated ones are dropped.
+	costEstimateDraft string
+	costEstimateSeq   int
+
 	// animFrame is the current animation frame, used to rotate the window
 	// title spinner so that tmux can detect pane activity.
 	animFrame int
@@ -722,7 +732,8 @@ func (m *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	// --- Keyboard input ---
 
 	case tea.KeyPressMsg:
-		return m.handleKeyPress(msg)
+		model, cmd := m.handleKeyPress(msg)
+		return model, tea.Batch(cmd, m.scheduleCostEstimate(false))
 
 	case tea.PasteMsg:
 		if m.dialogMgr.Open() {
@@ -740,7 +751,18 @@ func (m *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Forward paste to editor
 		editorModel, cmd := m.editor.Update(msg)
 		m.editor = editorModel.(editor.Editor)
-		return m, cmd
+		return m, tea.Batch(cmd, m.scheduleCostEstimate(false))
+
+	// --- Cost estimate ---
+
+	case messages.EstimateCostMsg:
+		return m, m.estimateCost(msg.Seq)
+
+	case messages.CostEstimatedMsg:
+		if msg.Seq == m.costEstimateSeq {
+			m.statusBar.SetCostEstimate(msg.Estimate)
+		}
+		return m, nil
 
 	// --- Mouse ---
 
@@ -881,6 +903,9 @@ func (m *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case messages.LoadSessionMsg:
 		return m.handleLoadSession(msg.SessionID)
 
+	case messages.ViewSessionMsg:
+		return m.handleViewSession(msg.SessionID)
+
 	case messages.BranchFromEditMsg:
 		return m.handleBranchFromEdit(msg)
 
@@ -906,6 +931,15 @@ func (m *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case messages.CompactSessionMsg:
 		return m.handleCompactSession(msg.AdditionalPrompt)
 
+	case messages.CreateCheckpointMsg:
+		return m.handleCreateCheckpoint(msg.Label)
+
+	case messages.ListCheckpointsMsg:
+		return m.handleListCheckpoints()
+
+	case messages.RollbackSessionMsg:
+		return m.handleRollbackSession(msg)
+
 	case messages.CopySessionToClipboardMsg:
 		return m.handleCopySessionToClipboard()
 
@@ -1036,6 +1070,9 @@ func (m *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if agentName := event.GetAgentName(); agentName != "" {
 				m.sessionState.SetCurrentAgentName(agentName)
 			}
+			if ev, ok := event.(*runtime.ElicitationRequestEvent); ok {
+				m.pendingElicitation = ev
+			}
 			updated, cmd := m.chatPage.Update(msg)
 			m.chatPage = updated.(chat.Page)
 			return m, cmd
@@ -1104,16 +1141,74 @@ func (m *appModel) handleWorkingStateChanged(msg messages.WorkingStateChangedMsg
 	// Update editor working state
 	cmds = append(cmds, m.editor.SetWorking(msg.Working))
 
-	// Start/stop working spinner
+	// Start/stop working spinner. The conversation changed, so does the
+	// cost of the next turn.
 	if msg.Working {
 		cmds = append(cmds, m.workingSpinner.Init())
 	} else {
 		m.workingSpinner.Stop()
+		cmds = append(cmds, m.scheduleCostEstimate(true))
 	}
 
 	return m, tea.Batch(cmds...)
 }
 
+// costEstimateDelay is how long the draft must stay unchanged before the
+// cost of sending it is estimated.
+const costEstimateDelay = 500 * time.Millisecond
+
+// scheduleCostEstimate schedules an estimate of the cost of the next turn
+// when the draft of the editor changed, or unconditionally when force is
+// set, e.g. when the conversation changed.
+func (m *appModel) scheduleCostEstimate(force bool) tea.Cmd {
+	draft := m.editor.Value()
+	if !force && draft == m.costEstimateDraft {
+		return nil
+	}
+	m.costEstimateDraft = draft
+	m.costEstimateSeq++
+	seq := m.costEstimateSeq
+	return tea.Tick(costEstimateDelay, func(time.Time) tea.Msg {
+		return messages.EstimateCostMsg{Seq: seq}
+	})
+}
+
+// estimateCost estimates the cost of sending the draft of the editor, unless
+// a newer estimate was scheduled since. Nothing is estimated while the agent
+// is working: the estimate is refreshed when it stops.
+func (m *appModel) estimateCost(seq int) tea.Cmd {
+	if seq != m.costEstimateSeq || m.chatPage.IsWorking() {
+		return nil
+	}
+
+	application := m.application
+	draft := m.costEstimateDraft
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		estimate, err := application.EstimateNextTurnCost(ctx, draft)
+		if err != nil {
+			slog.Debug("Failed to estimate the cost of the next turn", "error", err)
+			return messages.CostEstimatedMsg{Seq: seq}
+		}
+		return messages.CostEstimatedMsg{Seq: seq, Estimate: formatCostEstimate(estimate)}
+	}
+}
+
+// formatCostEstimate formats the cost range of the next turn for the status
+// bar, e.g. "next turn ≈ $0.01–0.03".
+func formatCostEstimate(estimate session.CostEstimate) string {
+	if estimate.MaxCost < 0.005 {
+		return "next turn < $0.01"
+	}
+	low, high := fmt.Sprintf("%.2f", estimate.MinCost), fmt.Sprintf("%.2f", estimate.MaxCost)
+	if low == high {
+		return "next turn ≈ $" + high
+	}
+	return "next turn ≈ $" + low + "–" + high
+}
+
 // handleOpenSessionBrowser opens the session browser dialog.
 func (m *appModel) handleOpenSessionBrowser() (tea.Model, tea.Cmd) {
 	store := m.application.SessionStore()
@@ -1130,7 +1225,29 @@ func (m *appModel) handleOpenSessionBrowser() (tea.Model, tea.Cmd) {
 	}
 
 	return m, core.CmdHandler(dialog.OpenDialogMsg{
-		Model: dialog.NewSessionBrowserDialog(sessions),
+		Model: dialog.NewSessionBrowserDialog(sessions, store.SearchSessions),
+	})
+}
+
+// handleViewSession shows the transcript of a saved session without resuming it.
+func (m *appModel) handleViewSession(sessionID string) (tea.Model, tea.Cmd) {
+	store := m.application.SessionStore()
+	if store == nil {
+		return m, notification.ErrorCmd("No session store configured")
+	}
+
+	sess, err := store.GetSession(context.Background(), sessionID)
+	if err != nil {
+		return m, notification.ErrorCmd(fmt.Sprintf("Failed to load session: %v", err))
+	}
+
+	var transcript strings.Builder
+	if err := sess.Export(&transcript, session.ExportFormatMarkdown); err != nil {
+		return m, notification.ErrorCmd(fmt.Sprintf("Failed to render session: %v", err))
+	}
+
+	return m, core.CmdHandler(dialog.OpenDialogMsg{
+		Model: dialog.NewSessionTranscriptDialog(sess.Title, transcript.String()),
 	})
 }
 
@@ -1422,6 +1539,7 @@ func (m *appModel) handleSwitchTab(sessionID string) (tea.Model, tea.Cmd) {
 	if m.chatPage.IsWorking() {
 		cmds = append(cmds, m.workingSpinner.Init())
 	}
+	cmds = append(cmds, m.scheduleCostEstimate(true))
 	if pendingCmd := m.replayPendingEvent(sessionID); pendingCmd != nil {
 		cmds = append(cmds, pendingCmd)
 	}
@@ -1476,6 +1594,8 @@ func (m *appModel) replayPendingEvent(sessionID string) tea.Cmd {
 
 // replayElicitationEvent opens the appropriate elicitation dialog for a pending event.
 func (m *appModel) replayElicitationEvent(ev *runtime.ElicitationRequestEvent) tea.Cmd {
+	m.pendingElicitation = ev
+
 	// Check if this is an OAuth flow
 	if ev.Meta != nil {
 		if elicitationType, ok := ev.Meta["cagent/type"].(string); ok && elicitationType == "oauth_flow" {
@@ -1,9 +1,11 @@
 package types
 
 import (
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/docker/docker-agent/pkg/chat"
 	"github.com/docker/docker-agent/pkg/tools"
 )
 
@@ -22,6 +24,8 @@ const (
 	MessageTypeToolResult
 	MessageTypeWelcome
 	MessageTypeLoading
+	MessageTypeSystemReminder
+	MessageTypeCompaction
 )
 
 const (
@@ -55,6 +59,8 @@ type Message struct {
 	// SessionPosition is the index of this message in session.Messages (when known).
 	// Used for operations like branching on edits.
 	SessionPosition *int
+	// Annotations lists the sources an assistant message draws on.
+	Annotations []chat.Annotation
 }
 
 func Agent(typ MessageType, agentName, content string) *Message {
@@ -120,6 +126,31 @@ func ToolCallMessage(agentName string, toolCall tools.ToolCall, toolDef tools.To
 	return msg
 }
 
+// SystemReminder returns a message showing a system reminder: text injected
+// into the conversation for the model, not written by the user.
+func SystemReminder(agentName, content string) *Message {
+	return &Message{
+		Type:    MessageTypeSystemReminder,
+		Sender:  agentName,
+		Content: strings.ReplaceAll(content, "\t", "    "),
+	}
+}
+
+// Compaction returns the marker of the messages condensed by a compaction
+// of the session, coveredMessages of them, 0 if unknown.
+func Compaction(coveredMessages int) *Message {
+	content := "Earlier messages condensed"
+	if coveredMessages == 1 {
+		content = "Condensed 1 message"
+	} else if coveredMessages > 1 {
+		content = "Condensed " + strconv.Itoa(coveredMessages) + " messages"
+	}
+	return &Message{
+		Type:    MessageTypeCompaction,
+		Content: content,
+	}
+}
+
 func Loading(description string) *Message {
 	return &Message{
 		Type:    MessageTypeLoading,